@@ -15,6 +15,6 @@ func main() {
 	application := app.New(embeddedTitles)
 	if err := application.Run(os.Args[1:]); err != nil {
 		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
-		os.Exit(1)
+		os.Exit(app.ExitCode(err))
 	}
 }
@@ -3,16 +3,20 @@ package app
 import (
 	"embed"
 	"fmt"
+	"io"
+	"io/fs"
 	"log/slog"
 	"os"
+	"path/filepath"
+	"time"
 
-	"github.com/hajimehoshi/ebiten/v2"
-	ebitenAudio "github.com/hajimehoshi/ebiten/v2/audio"
 	"github.com/zurustar/son-et/pkg/cli"
 	"github.com/zurustar/son-et/pkg/compiler"
+	"github.com/zurustar/son-et/pkg/eventbus"
 	"github.com/zurustar/son-et/pkg/fileutil"
 	"github.com/zurustar/son-et/pkg/graphics"
 	"github.com/zurustar/son-et/pkg/logger"
+	"github.com/zurustar/son-et/pkg/osc"
 	"github.com/zurustar/son-et/pkg/script"
 	"github.com/zurustar/son-et/pkg/title"
 	"github.com/zurustar/son-et/pkg/vm"
@@ -27,6 +31,7 @@ type Application struct {
 	titleReg      *title.FillyTitleRegistry
 	embedFS       embed.FS
 	opcodes       []compiler.OpCode // コンパイル済みOpCode
+	program       *compiler.Program // コンパイル結果（再生前の静的な検査と行の位置に使用）
 	selectedTitle *title.FillyTitle // 選択されたタイトル
 	soundFontPath string            // SoundFontファイルのパス（後方互換性のため保持）
 
@@ -34,20 +39,58 @@ type Application struct {
 	// 埋め込みファイルと外部ファイルの両方に対応
 	soundFontLocation *SoundFontLocation
 
-	// sharedAudioCtx はEbitengineのオーディオコンテキスト（一度だけ作成可能）
-	// タイトル切り替え時に再利用する
-	sharedAudioCtx *ebitenAudio.Context
+	// eventBus はVM・描画・音声の各システムのイベントを外部ツールに配信するバス
+	// タイトル切り替え後も同じバスを使用するため、購読し直す必要はない
+	eventBus *eventbus.Bus
+
+	// watchServer は変数ウォッチのリモートAPI（--watch-addr、nilの場合は起動しない）
+	watchServer *watchServer
+
+	// syncClock は時刻同期の共有時計（--sync-master / --sync-follow、nilの場合は同期しない）
+	// syncCloser は時刻同期を停止する
+	syncClock  audio.SyncClock
+	syncCloser io.Closer
+
+	// oscSender は OSCSend の送信先（--osc-allow、nilの場合は送らない）
+	oscSender *osc.Sender
+
+	// cues は再生する操作キュー（--play-cues）
+	// cueFile は操作キューを記録するファイル（--record-cues、nilの場合は記録しない）
+	cues    []vm.Cue
+	cueFile *os.File
+
+	// ウィンドウで実行するときにだけ使う状態（nogpu タグを付けたビルドでは空）
+	desktopState
 }
 
 // New Applicationを作成
 func New(embedFS embed.FS) *Application {
 	return &Application{
-		embedFS: embedFS,
+		embedFS:  embedFS,
+		eventBus: eventbus.New(),
 	}
 }
 
+// EventBus はイベントバスを返す
+// デバッグオーバーレイやリモート操作APIなどのツールは、Run の前に購読しておく
+func (app *Application) EventBus() *eventbus.Bus {
+	return app.eventBus
+}
+
 // Run アプリケーションを実行
+// --error-json が指定されている場合は、終了コードとエラーの詳細をJSONで書き出す
 func (app *Application) Run(args []string) error {
+	err := app.run(args)
+	if app.config != nil && app.config.ErrorJSONPath != "" {
+		if werr := writeErrorReport(app.config.ErrorJSONPath, app.selectedTitle, err); werr != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", werr)
+		}
+	}
+	return err
+}
+
+// run はアプリケーションを実行する（Run の本体）
+func (app *Application) run(args []string) error {
 	// 1. コマンドライン引数の解析
 	if err := app.parseArgs(args); err != nil {
 		return fmt.Errorf("failed to parse args: %w", err)
@@ -58,12 +101,58 @@ func (app *Application) Run(args []string) error {
 		return nil
 	}
 
+	// サブコマンドはタイトルを読み込まずに実行する
+	switch app.config.Command {
+	case cli.CommandLSP:
+		return app.runLSP()
+	case cli.CommandFmt:
+		return app.runFormat()
+	case cli.CommandInfo:
+		return app.runInfo()
+	case cli.CommandMigrate:
+		return app.runMigrate()
+	case cli.CommandAssets:
+		return app.runAssets()
+	case cli.CommandOpcodes:
+		return app.runOpcodes()
+	}
+
 	// 2. ロガーの初期化
 	if err := app.initLogger(); err != nil {
 		return fmt.Errorf("failed to initialize logger: %w", err)
 	}
 
 	app.log.Info("Application started")
+	if plugins := vm.PluginBuiltins(); len(plugins) > 0 {
+		app.log.Info("Plugin built-in functions registered", "functions", plugins)
+	}
+
+	// 入力マップはタイトルを選ぶ前に読み込み、誤りがあれば起動しない
+	if err := app.loadInputMap(); err != nil {
+		return err
+	}
+
+	// 変数ウォッチのリモートAPIはタイトル選択画面から起動したタイトルにも使う
+	if err := app.startWatchServer(); err != nil {
+		return err
+	}
+	defer app.watchServer.Close()
+
+	// 時刻同期はタイトル選択画面から起動したタイトルにも使う
+	if err := app.startSync(); err != nil {
+		return err
+	}
+	defer app.stopSync()
+
+	if err := app.startOSC(); err != nil {
+		return err
+	}
+	defer app.stopOSC()
+
+	if err := app.startCues(); err != nil {
+		return err
+	}
+	defer app.stopCues()
 
 	// 3. タイトルの読み込みと選択
 	selectedTitle, err := app.loadTitle()
@@ -87,6 +176,13 @@ func (app *Application) Run(args []string) error {
 
 	app.log.Info("Title selected", "name", selectedTitle.Name, "path", selectedTitle.Path, "entryFile", selectedTitle.EntryFile)
 	app.selectedTitle = selectedTitle
+	app.applyManifest(selectedTitle)
+	if app.sandboxEnabled(selectedTitle) {
+		app.log.Info("Sandbox mode enabled: file access is confined to the title directory")
+	}
+	if app.config.Compat.Strict() {
+		app.log.Info("Compatibility mode enabled: son-et extensions are disabled", "compat", app.config.Compat)
+	}
 
 	// 4. スクリプトファイルの読み込み
 	scripts, err := app.loadScripts(selectedTitle)
@@ -103,13 +199,22 @@ func (app *Application) Run(args []string) error {
 	// 5. スクリプトのコンパイル
 	opcodes, err := app.compileScripts(scripts, selectedTitle)
 	if err != nil {
-		return fmt.Errorf("failed to compile scripts: %w", err)
+		return withExitCode(compileExitCode(err), fmt.Errorf("failed to compile scripts: %w", err))
 	}
 	app.opcodes = opcodes
 
 	app.log.Info("Scripts compiled successfully", "opcode_count", len(opcodes))
 	app.log.Debug("OpCodes generated", "opcodes", formatOpCodesPreview(opcodes, 10))
 
+	// オフラインレンダリングの場合はデスクトップを実行せずにWAVを書き出す
+	if app.config.RenderAudioPath != "" {
+		if err := app.renderAudio(); err != nil {
+			return withExitCode(renderExitCode(err), fmt.Errorf("failed to render audio: %w", err))
+		}
+		app.log.Info("Application terminated normally")
+		return nil
+	}
+
 	// 6. 仮想デスクトップの実行
 	if err := app.runDesktop(); err != nil {
 		return fmt.Errorf("failed to run desktop: %w", err)
@@ -126,9 +231,78 @@ func (app *Application) parseArgs(args []string) error {
 		return err
 	}
 	app.config = config
+
+	// 表示調整の範囲はグラフィックスシステムの制限に合わせて起動前に確認する（タイトルを実行する場合のみ）
+	if config.Command == "" {
+		if err := app.displayAdjustment().Validate(); err != nil {
+			return err
+		}
+	}
 	return nil
 }
 
+// displayAdjustment はコマンドラインで指定された表示調整（--gamma/--brightness/--contrast）を返す
+func (app *Application) displayAdjustment() graphics.DisplayAdjustment {
+	return graphics.DisplayAdjustment{
+		Gamma:      app.config.Gamma,
+		Brightness: app.config.Brightness,
+		Contrast:   app.config.Contrast,
+	}
+}
+
+// reportDiagnostics は再生前にスクリプトの静的な検査の結果を標準エラー出力に表示する
+// 検査はどれも警告で、再生は続ける
+func (app *Application) reportDiagnostics(vmInstance *vm.VM) {
+	if app.program == nil || app.program.Analysis == nil {
+		return
+	}
+	app.reportUnsupportedCalls(vmInstance)
+	app.reportArgErrors(vmInstance)
+	app.reportTickBudget()
+}
+
+// reportUnsupportedCalls は再生前にスクリプト全体からエンジンが実装していない関数の呼び出しを探し、
+// 関数ごとの呼び出し回数と位置（#include されたファイルの呼び出しはそのファイルの行）を
+// 1つの互換性レポートとして標準エラー出力に表示する
+// 報告した関数は再生中に呼び出されても0を返すだけになり、その時点で停止しない
+func (app *Application) reportUnsupportedCalls(vmInstance *vm.VM) {
+	calls := app.program.Analysis.UnsupportedCalls(vmInstance.HasBuiltin)
+	if len(calls) == 0 {
+		return
+	}
+	names := make([]string, len(calls))
+	for i, call := range calls {
+		names[i] = call.Name
+	}
+	vmInstance.SetUnsupportedFunctions(names)
+	app.log.Warn("Compatibility report: the script calls functions son-et does not implement (they return 0)", "functions", names)
+	fmt.Fprint(os.Stderr, compiler.FormatUnsupportedReport(calls, app.program.Result))
+}
+
+// reportArgErrors は再生前に組み込み関数の呼び出しのうち引数の数やリテラル引数の型が誤っているものを探し、
+// 警告として標準エラー出力に表示する（位置は呼び出しを書いたファイルの行）
+// 誤った呼び出しも再生中はVMが検査してエラーをログに記録し、スクリプトは続行する
+// 引数が多すぎるだけの呼び出しは、余分な引数が無視されるため別に一覧にする
+func (app *Application) reportArgErrors(vmInstance *vm.VM) {
+	diags := app.program.Analysis.ArgErrors(vmInstance.HasBuiltin)
+	if len(diags) == 0 {
+		return
+	}
+	app.log.Warn("The script calls built-in functions with invalid or extra arguments", "calls", len(diags))
+	fmt.Fprint(os.Stderr, compiler.FormatArgReport(app.program.Result, diags))
+}
+
+// reportTickBudget は再生前に各シーケンスの1ティックあたりのオペコードの数を見積もり、
+// フレームの予算を超えそうなシーケンスを原因のループの位置とともに標準エラー出力に表示する
+func (app *Application) reportTickBudget() {
+	warnings := app.program.Analysis.TickBudgetOverruns(compiler.DefaultTickBudget)
+	if len(warnings) == 0 {
+		return
+	}
+	app.log.Warn("Some sequences may run too many opcodes in one tick", "sequences", len(warnings))
+	fmt.Fprint(os.Stderr, compiler.FormatTickBudgetReport(app.program.Result, warnings, compiler.DefaultTickBudget))
+}
+
 // initLogger ロガーを初期化
 func (app *Application) initLogger() error {
 	if err := logger.InitLogger(app.config.LogLevel); err != nil {
@@ -158,6 +332,13 @@ func (app *Application) loadTitle() (*title.FillyTitle, error) {
 
 	// タイトル選択画面の表示（必要な場合）
 	if needsSelection {
+		// GIF書き出し・オフラインレンダリングは選択画面を経由せずに単一タイトルを実行する必要がある
+		if app.config.ExportGIFPath != "" {
+			return nil, fmt.Errorf("--export-gif requires a title path")
+		}
+		if app.config.RenderAudioPath != "" {
+			return nil, fmt.Errorf("--render-audio requires a title path")
+		}
 		selectedTitle, err = app.selectTitle(app.titleReg.GetAvailableTitles())
 		if err != nil {
 			return nil, fmt.Errorf("failed to select title from menu: %w", err)
@@ -192,6 +373,12 @@ func (app *Application) selectTitle(titles []title.FillyTitle) (*title.FillyTitl
 func (app *Application) runDesktop() error {
 	app.log.Info("Starting virtual desktop")
 
+	// GIF書き出しはウィンドウを使わず、ヘッドレスモードと同じくVMだけを実行して画面をソフトウェアで描画する
+	if app.config.ExportGIFPath != "" {
+		app.log.Info("GIF export: running VM without GUI and rendering offscreen")
+		return app.runVM()
+	}
+
 	// ヘッドレスモードの場合はVMを実行
 	if app.config.Headless {
 		app.log.Info("Headless mode: running VM without GUI")
@@ -199,333 +386,13 @@ func (app *Application) runDesktop() error {
 	}
 
 	// GUIモードの場合はEbitengineのゲームループでVMとGraphicsSystemを統合
-	app.log.Info("GUI mode: running VM with Ebitengine")
-
-	// VMオプションを設定
-	opts := []vm.Option{
-		vm.WithHeadless(false),
-		vm.WithLogger(app.log),
-		vm.WithTitlePath(app.selectedTitle.Path),
-	}
-
-	// タイムアウトが指定されている場合
-	if app.config.Timeout > 0 {
-		opts = append(opts, vm.WithTimeout(app.config.Timeout))
-	}
-
-	// SoundFontパスを設定（埋め込みファイルと外部ファイルの両方に対応）
-	// Requirement 3.1, 3.2, 3.3: 優先順位に従ってSF2ファイルを検索
-	if app.soundFontLocation == nil {
-		app.soundFontLocation = findSoundFont(app.embedFS, app.selectedTitle.Path, app.selectedTitle.IsEmbedded)
-	}
-
-	if app.soundFontLocation != nil {
-		app.soundFontPath = app.soundFontLocation.Path
-		opts = append(opts, vm.WithSoundFont(app.soundFontPath))
-		app.log.Info("SoundFont configured", "path", app.soundFontPath, "embedded", app.soundFontLocation.IsEmbedded)
-	}
-
-	// VMを作成
-	vmInstance := vm.New(app.opcodes, opts...)
-
-	// オーディオシステムを初期化
-	// Requirement 2.1: FileSystemインターフェースを使用してSF2ファイルを読み込む
-	if app.soundFontLocation != nil {
-		var audioSys *audio.AudioSystem
-		var err error
-
-		// SoundFontのFileSystemを使用してオーディオシステムを初期化
-		audioSys, err = audio.NewAudioSystemWithFS(
-			app.soundFontLocation.Path,
-			vmInstance.GetEventQueue(),
-			nil, // audioCtx - 新規作成
-			app.soundFontLocation.FileSystem,
-		)
-		if err != nil {
-			app.log.Warn("Failed to initialize audio system", "error", err)
-		} else {
-			// 埋め込みタイトルの場合はMIDI/WAV用のFileSystemを設定
-			if app.selectedTitle.IsEmbedded {
-				embedFS := fileutil.NewEmbedFS(app.embedFS, app.selectedTitle.Path)
-				audioSys.SetFileSystem(embedFS)
-				app.log.Info("Audio system using embedded file system for MIDI/WAV", "basePath", app.selectedTitle.Path)
-			}
-			vmInstance.SetAudioSystem(audioSys)
-			app.log.Info("Audio system initialized")
-
-			defer func() {
-				vmInstance.ShutdownAudio()
-				app.log.Info("Audio system shut down")
-			}()
-		}
-	}
-
-	// グラフィックスシステムを初期化
-	graphicsSys := graphics.NewGraphicsSystem(
-		app.selectedTitle.Path,
-		graphics.WithLogger(app.log),
-	)
-	// 埋め込みタイトルの場合はembed.FSを設定
-	if app.selectedTitle.IsEmbedded {
-		graphicsSys.SetEmbedFS(app.embedFS)
-	}
-	vmInstance.SetGraphicsSystem(graphicsSys)
-	app.log.Info("Graphics system initialized")
-
-	// ログレベルに基づいてデバッグオーバーレイを有効化
-	graphicsSys.SetDebugOverlayFromLogLevelString(app.config.LogLevel)
-
-	defer func() {
-		graphicsSys.Shutdown()
-		app.log.Info("Graphics system shut down")
-	}()
-
-	// Ebitengineのゲームを作成
-	game := window.NewGame(window.ModeDesktop, nil, app.config.Timeout)
-
-	// 単一タイトル実行時はタイトル選択画面がないことを明示的に設定
-	// Requirements 3.1, 3.2: 単一タイトル実行中にESCキーを押すとプログラムが終了する
-	game.SetHasTitleSelection(false)
-
-	game.SetGraphicsSystem(graphicsSys)
-	game.SetVMRunner(vmInstance)
-	game.SetEventPusher(vmInstance) // マウスイベントをVMに伝達
-
-	// VMを開始する関数を設定（Ebitengine初期化後に呼び出される）
-	vmErrCh := make(chan error, 1)
-	game.SetVMStartFunc(func() {
-		app.log.Info("Starting VM execution in background (after Ebitengine init)")
-		go func() {
-			vmErrCh <- vmInstance.Run()
-		}()
-	}, vmErrCh)
-
-	// Ebitengineのゲームループを実行
-	app.log.Info("Starting Ebitengine game loop")
-	// skelton要件 3.2: ウィンドウサイズは 1024x768 ピクセル
-	ebiten.SetWindowSize(1024, 768)
-	ebiten.SetWindowTitle("son-et - FILLY interpreter")
-	ebiten.SetWindowResizingMode(ebiten.WindowResizingModeDisabled)
-
-	if err := ebiten.RunGame(game); err != nil {
-		app.log.Error("Ebitengine game loop failed", "error", err)
-		vmInstance.Stop()
-		return fmt.Errorf("game loop failed: %w", err)
-	}
-
-	// VMの終了を待つ
-	select {
-	case vmErr := <-vmErrCh:
-		if vmErr != nil {
-			app.log.Error("VM execution failed", "error", vmErr)
-			return vmErr
-		}
-	default:
-		// VMがまだ実行中の場合は停止
-		vmInstance.Stop()
-	}
-
-	app.log.Info("Desktop execution completed")
-	return nil
+	return app.runGUI()
 }
 
-// runWithSelection タイトル選択画面からデスクトップモードまでを単一のRunGameで実行
-// Ebitengineは一度RunGameが終了すると再利用できないため、
-// タイトル選択とデスクトップ実行を同じRunGame内で行う必要がある
-func (app *Application) runWithSelection(titles []title.FillyTitle) (*title.FillyTitle, error) {
-	// Gameを選択モードで作成
-	game := window.NewGame(window.ModeSelection, titles, app.config.Timeout)
-
-	// 複数タイトル環境であることを設定
-	// Requirements 2.1, 3.1, 5.1: タイトル選択画面があることを示す
-	game.SetHasTitleSelection(true)
-
-	// タイトル選択時のコールバックを設定
-	// このコールバック内でVM/GraphicsSystemをセットアップし、デスクトップモードに遷移する
-	var vmInstance *vm.VM
-	var graphicsSys *graphics.GraphicsSystem
-	var audioSys *audio.AudioSystem
-	vmErrCh := make(chan error, 1)
-
-	// タイトル終了時のリソースクリーンアップコールバックを設定
-	// Requirements 2.2, 2.3, 2.4, 4.1, 4.2, 4.3: リソースのクリーンアップ
-	game.SetOnTitleExit(func() error {
-		app.log.Info("Cleaning up resources for title exit")
-
-		// VM停止 (Requirement 4.1: VMのすべてのゴルーチンを停止)
-		if vmInstance != nil {
-			vmInstance.Stop()
-			app.log.Info("VM stopped")
-
-			// AudioSystem停止 (Requirement 4.3: すべての再生中の音声を停止)
-			// AudioSystemはVMを通じてシャットダウンする
-			if audioSys != nil {
-				vmInstance.ShutdownAudio()
-				app.log.Info("Audio system shut down")
-			}
-		}
-
-		// GraphicsSystem停止 (Requirement 4.2: すべてのスプライトとテクスチャを解放)
-		if graphicsSys != nil {
-			graphicsSys.Shutdown()
-			app.log.Info("Graphics system shut down")
-		}
-
-		// リソース参照をクリア
-		vmInstance = nil
-		graphicsSys = nil
-		audioSys = nil
-
-		return nil
-	})
-
-	game.SetOnTitleSelected(func(selectedTitle *title.FillyTitle) error {
-		app.log.Info("Title selected, setting up VM and graphics", "name", selectedTitle.Name)
-		app.selectedTitle = selectedTitle
-
-		// スクリプトの読み込みとコンパイル
-		scripts, err := app.loadScripts(selectedTitle)
-		if err != nil {
-			return fmt.Errorf("failed to load scripts: %w", err)
-		}
-		app.log.Info("Scripts loaded", "count", len(scripts))
-
-		opcodes, err := app.compileScripts(scripts, selectedTitle)
-		if err != nil {
-			return fmt.Errorf("failed to compile scripts: %w", err)
-		}
-		app.opcodes = opcodes
-		app.log.Info("Scripts compiled", "opcode_count", len(opcodes))
-
-		// VMオプションを設定
-		opts := []vm.Option{
-			vm.WithHeadless(false),
-			vm.WithLogger(app.log),
-			vm.WithTitlePath(selectedTitle.Path),
-		}
-
-		if app.config.Timeout > 0 {
-			opts = append(opts, vm.WithTimeout(app.config.Timeout))
-		}
-
-		// SoundFontパスを設定（埋め込みファイルと外部ファイルの両方に対応）
-		// Requirement 3.1, 3.2, 3.3: 優先順位に従ってSF2ファイルを検索
-		app.soundFontLocation = findSoundFont(app.embedFS, selectedTitle.Path, selectedTitle.IsEmbedded)
-
-		if app.soundFontLocation != nil {
-			app.soundFontPath = app.soundFontLocation.Path
-			opts = append(opts, vm.WithSoundFont(app.soundFontPath))
-			app.log.Info("SoundFont configured", "path", app.soundFontPath, "embedded", app.soundFontLocation.IsEmbedded)
-		}
-
-		// VMを作成
-		vmInstance = vm.New(opcodes, opts...)
-
-		// オーディオシステムを初期化
-		// Ebitengineのオーディオコンテキストは一度しか作成できないため、
-		// アプリケーションレベルで保持して再利用する
-		// Requirement 2.1: FileSystemインターフェースを使用してSF2ファイルを読み込む
-		if app.soundFontLocation != nil {
-			var err error
-			// 共有オーディオコンテキストがなければ作成
-			if app.sharedAudioCtx == nil {
-				app.sharedAudioCtx = ebitenAudio.NewContext(audio.SampleRate)
-				app.log.Info("Created shared audio context")
-			}
-			// SoundFontのFileSystemを使用してオーディオシステムを作成
-			audioSys, err = audio.NewAudioSystemWithFS(
-				app.soundFontLocation.Path,
-				vmInstance.GetEventQueue(),
-				app.sharedAudioCtx,
-				app.soundFontLocation.FileSystem,
-			)
-			if err != nil {
-				app.log.Warn("Failed to initialize audio system", "error", err)
-			} else {
-				// 埋め込みタイトルの場合はMIDI/WAV用のFileSystemを設定
-				if selectedTitle.IsEmbedded {
-					embedFS := fileutil.NewEmbedFS(app.embedFS, selectedTitle.Path)
-					audioSys.SetFileSystem(embedFS)
-					app.log.Info("Audio system using embedded file system for MIDI/WAV", "basePath", selectedTitle.Path)
-				}
-				vmInstance.SetAudioSystem(audioSys)
-				app.log.Info("Audio system initialized")
-			}
-		}
-
-		// グラフィックスシステムを初期化
-		graphicsSys = graphics.NewGraphicsSystem(
-			selectedTitle.Path,
-			graphics.WithLogger(app.log),
-		)
-		if selectedTitle.IsEmbedded {
-			graphicsSys.SetEmbedFS(app.embedFS)
-		}
-		vmInstance.SetGraphicsSystem(graphicsSys)
-		app.log.Info("Graphics system initialized")
-
-		graphicsSys.SetDebugOverlayFromLogLevelString(app.config.LogLevel)
-
-		// GameにVM/GraphicsSystemを設定
-		game.SetGraphicsSystem(graphicsSys)
-		game.SetVMRunner(vmInstance)
-		game.SetEventPusher(vmInstance)
-
-		// VMを開始する関数を設定
-		game.SetVMStartFunc(func() {
-			app.log.Info("Starting VM execution in background (after mode transition)")
-			go func() {
-				vmErrCh <- vmInstance.Run()
-			}()
-		}, vmErrCh)
-
-		return nil
-	})
-
-	// ウィンドウ設定
-	ebiten.SetWindowSize(1024, 768)
-	ebiten.SetWindowTitle("son-et - FILLY interpreter")
-	ebiten.SetWindowResizingMode(ebiten.WindowResizingModeEnabled)
-
-	// ゲームを実行（選択画面 -> デスクトップモードまで）
-	app.log.Info("Starting Ebitengine game loop (selection mode)")
-	if err := ebiten.RunGame(game); err != nil {
-		app.log.Error("Ebitengine game loop failed", "error", err)
-		if vmInstance != nil {
-			vmInstance.Stop()
-		}
-		return nil, fmt.Errorf("game loop failed: %w", err)
-	}
-
-	// モード遷移時のエラーをチェック
-	if err := game.GetTransitionError(); err != nil {
-		return nil, err
-	}
-
-	// クリーンアップ
-	if graphicsSys != nil {
-		graphicsSys.Shutdown()
-		app.log.Info("Graphics system shut down")
-	}
-	if audioSys != nil {
-		vmInstance.ShutdownAudio()
-		app.log.Info("Audio system shut down")
-	}
-
-	// VMの終了を待つ
-	select {
-	case vmErr := <-vmErrCh:
-		if vmErr != nil {
-			app.log.Error("VM execution failed", "error", vmErr)
-			return game.GetSelectedTitle(), vmErr
-		}
-	default:
-		if vmInstance != nil {
-			vmInstance.Stop()
-		}
-	}
-
-	return game.GetSelectedTitle(), nil
+// sandboxEnabled はタイトルをサンドボックスモード（--sandbox）で実行するかを返す
+// 埋め込みタイトルはバイナリに同梱された信頼済みのコンテンツで、ホストのファイルを参照しないため対象外とする
+func (app *Application) sandboxEnabled(t *title.FillyTitle) bool {
+	return app.config.Sandbox && !t.IsEmbedded
 }
 
 // runVM VMを実行
@@ -535,11 +402,25 @@ func (app *Application) runWithSelection(titles []title.FillyTitle) (*title.Fill
 func (app *Application) runVM() error {
 	app.log.Info("Creating VM", "opcode_count", len(app.opcodes))
 
+	// GIF書き出し（--export-gif）もヘッドレスモードと同じくウィンドウを使わず、音声もミュートする
+	exportGIF := app.config.ExportGIFPath != ""
+
 	// VMオプションを設定
 	opts := []vm.Option{
-		vm.WithHeadless(app.config.Headless),
+		vm.WithHeadless(app.config.Headless || exportGIF),
 		vm.WithLogger(app.log),
 		vm.WithTitlePath(app.selectedTitle.Path),
+		vm.WithSandbox(app.sandboxEnabled(app.selectedTitle)),
+		vm.WithOutputDir(app.outputDir(app.selectedTitle)),
+		vm.WithCompatMode(app.config.Compat),
+		vm.WithPowerSave(app.config.PowerSave),
+		vm.WithKeyframeInterval(app.config.KeyframeInterval),
+		vm.WithLocale(app.config.Locale),
+		vm.WithAssetDirs(titleAssetDirs(app.selectedTitle)...),
+		vm.WithAssetVars(app.titleAssetVars(app.selectedTitle)),
+		vm.WithEventBus(app.eventBus),
+		app.oscOption(),
+		app.cueOption(),
 	}
 
 	// タイムアウトが指定されている場合
@@ -547,10 +428,30 @@ func (app *Application) runVM() error {
 		opts = append(opts, vm.WithTimeout(app.config.Timeout))
 	}
 
+	// 指定したティック数で終了する場合（--exit-after-ticks）
+	if app.config.ExitAfterTicks > 0 {
+		opts = append(opts, vm.WithTickLimit(app.config.ExitAfterTicks))
+	}
+
+	// Random() の実行シードが指定されている場合（--seed）
+	if app.config.SeedSet {
+		opts = append(opts, vm.WithRandomSeed(app.config.Seed))
+	}
+
+	// チャプターまで早送りして始める場合（--chapter）
+	if app.config.Chapter != "" {
+		opts = append(opts, vm.WithStartChapter(app.config.Chapter))
+	}
+
+	// DebugBreak で一時停止する場合（--debug-break）
+	if app.config.DebugBreak {
+		opts = append(opts, vm.WithDebugger(vm.NewConsoleDebugger(os.Stdin, os.Stderr)))
+	}
+
 	// SoundFontパスを設定（埋め込みファイルと外部ファイルの両方に対応）
 	// Requirement 3.1, 3.2, 3.3: 優先順位に従ってSF2ファイルを検索
 	if app.soundFontLocation == nil {
-		app.soundFontLocation = findSoundFont(app.embedFS, app.selectedTitle.Path, app.selectedTitle.IsEmbedded)
+		app.soundFontLocation = app.findTitleSoundFont(app.selectedTitle)
 	}
 
 	if app.soundFontLocation != nil {
@@ -561,14 +462,16 @@ func (app *Application) runVM() error {
 
 	// VMを作成
 	vmInstance := vm.New(app.opcodes, opts...)
+	app.reportDiagnostics(vmInstance)
+	app.watchServer.setVM(vmInstance)
 
 	// オーディオシステムを初期化（SoundFontが設定されている場合）
 	// Requirement 2.1: FileSystemインターフェースを使用してSF2ファイルを読み込む
 	if app.soundFontLocation != nil {
-		audioSys, err := audio.NewAudioSystemWithFS(
+		audioSys, err := audio.NewAudioSystemWithOutput(
 			app.soundFontLocation.Path,
 			vmInstance.GetEventQueue(),
-			nil, // audioCtx - 新規作成
+			audio.NewDefaultOutput(),
 			app.soundFontLocation.FileSystem,
 		)
 		if err != nil {
@@ -581,6 +484,14 @@ func (app *Application) runVM() error {
 				audioSys.SetFileSystem(embedFS)
 				app.log.Info("Audio system using embedded file system for MIDI/WAV", "basePath", app.selectedTitle.Path)
 			}
+			audioSys.SetAVOffset(app.config.AVOffset)
+			audioSys.SetMIDIStrict(app.config.MIDIStrict)
+			audioSys.SetMetronome(app.config.Metronome)
+			app.applySynthQuality(audioSys)
+			app.applyAudioPowerSave(audioSys)
+			applyMIDIRemap(audioSys, app.selectedTitle)
+			app.applySyncClock(audioSys)
+			audioSys.SetEventBus(app.eventBus)
 			vmInstance.SetAudioSystem(audioSys)
 			app.log.Info("Audio system initialized")
 
@@ -594,53 +505,81 @@ func (app *Application) runVM() error {
 
 	// グラフィックスシステムを初期化
 	// 要件 10.4: ヘッドレスモードが有効のとき、描画操作をログに記録するのみで実際の描画を行わない
-	if app.config.Headless {
-		// ヘッドレスモード用のダミーGraphicsSystemを使用
-		headlessGS := graphics.NewHeadlessGraphicsSystem(
-			graphics.WithHeadlessLogger(app.log),
-			graphics.WithLogOperations(true),
-		)
-		vmInstance.SetGraphicsSystem(headlessGS)
-		app.log.Info("Headless graphics system initialized")
-
-		defer func() {
-			headlessGS.Shutdown()
-			app.log.Info("Headless graphics system shut down")
-		}()
-	} else {
-		// 通常のGraphicsSystemを使用
-		graphicsSys := graphics.NewGraphicsSystem(
-			app.selectedTitle.Path,
-			graphics.WithLogger(app.log),
-		)
-		// 埋め込みタイトルの場合はembed.FSを設定
-		if app.selectedTitle.IsEmbedded {
-			graphicsSys.SetEmbedFS(app.embedFS)
+	// GIF書き出しでは画面をソフトウェアで描画し、VMのティックごとにフレームを取り込む
+	var shutdownGraphics func()
+	var finishGIF func() error
+	if exportGIF {
+		var err error
+		finishGIF, shutdownGraphics, err = app.attachGIFExporter(vmInstance)
+		if err != nil {
+			return fmt.Errorf("failed to start GIF export: %w", err)
 		}
-		vmInstance.SetGraphicsSystem(graphicsSys)
-		app.log.Info("Graphics system initialized")
-
-		// ログレベルに基づいてデバッグオーバーレイを有効化
-		graphicsSys.SetDebugOverlayFromLogLevelString(app.config.LogLevel)
+	} else if app.config.Headless {
+		shutdownGraphics = app.attachHeadlessGraphicsSystem(vmInstance)
+	} else {
+		shutdownGraphics = app.attachGraphicsSystem(vmInstance)
+	}
+	defer shutdownGraphics()
 
-		// クリーンアップを設定
-		defer func() {
-			graphicsSys.Shutdown()
-			app.log.Info("Graphics system shut down")
-		}()
+	// 進み具合をJSONで書き出す場合（--progress）
+	var progress *progressReporter
+	if app.config.ProgressPath != "" {
+		w, closer, err := openProgressOutput(app.config.ProgressPath)
+		if err != nil {
+			return err
+		}
+		progress = newProgressReporter(vmInstance, w, closer, app.config.ProgressInterval)
+		progress.start()
 	}
 
 	// VMを実行
 	app.log.Info("Starting VM execution")
 	if err := vmInstance.Run(); err != nil {
 		app.log.Error("VM execution failed", "error", err)
-		return fmt.Errorf("VM execution failed: %w", err)
+		if progress != nil {
+			progress.finish(progressError)
+		}
+		reportTraces(os.Stderr, vmInstance, "runtime error")
+		return withExitCode(ExitRuntimeError, fmt.Errorf("VM execution failed: %w", err))
+	}
+	if vmInstance.TimedOut() {
+		if progress != nil {
+			progress.finish(progressTimeout)
+		}
+		reportTraces(os.Stderr, vmInstance, "timeout")
+		return withExitCode(ExitTimeout, ErrTimeout)
+	}
+	if progress != nil {
+		progress.finish(progressCompleted)
 	}
 
 	app.log.Info("VM execution completed")
+	if finishGIF != nil {
+		if err := finishGIF(); err != nil {
+			return fmt.Errorf("failed to export GIF: %w", err)
+		}
+	}
 	return nil
 }
 
+// attachHeadlessGraphicsSystem はヘッドレスモード用のダミーGraphicsSystemをVMに設定し、
+// 終了時に呼び出す関数を返す
+func (app *Application) attachHeadlessGraphicsSystem(vmInstance *vm.VM) (shutdown func()) {
+	headlessGS := graphics.NewHeadlessGraphicsSystem(
+		graphics.WithHeadlessLogger(app.log),
+		graphics.WithLogOperations(true),
+		graphics.WithHeadlessSandbox(app.sandboxEnabled(app.selectedTitle)),
+		graphics.WithHeadlessEventBus(app.eventBus),
+	)
+	vmInstance.SetGraphicsSystem(headlessGS)
+	app.log.Info("Headless graphics system initialized")
+
+	return func() {
+		headlessGS.Shutdown()
+		app.log.Info("Headless graphics system shut down")
+	}
+}
+
 // loadScripts スクリプトファイルを読み込む
 func (app *Application) loadScripts(selectedTitle *title.FillyTitle) ([]script.Script, error) {
 	var loader *script.Loader
@@ -669,23 +608,15 @@ func (app *Application) compileScripts(scripts []script.Script, selectedTitle *t
 
 		// プリプロセッサを使用してエントリーポイントからコンパイル
 		// Requirement 16.1: Preprocessor starts processing from entry point file.
-		var opcodes []compiler.OpCode
-		var result *compiler.PreprocessResult
-		var err error
-
-		if selectedTitle.IsEmbedded {
-			// 埋め込みタイトルの場合はembed.FSを使用
-			opcodes, result, err = compiler.CompileWithPreprocessorFS(selectedTitle.Path, selectedTitle.EntryFile, app.embedFS)
-		} else {
-			opcodes, result, err = compiler.CompileWithPreprocessor(selectedTitle.Path, selectedTitle.EntryFile)
-		}
+		program, err := compiler.CompileProgram(selectedTitle.Path, selectedTitle.EntryFile, app.scriptFS(selectedTitle), app.titleCompileOptions(selectedTitle))
 		if err != nil {
 			app.log.Error("Compilation with preprocessor failed", "file", selectedTitle.EntryFile, "error", err)
 			return nil, err
 		}
 
-		app.log.Info("Preprocessor completed", "included_files", result.IncludedFiles)
-		return opcodes, nil
+		app.log.Info("Preprocessor completed", "included_files", program.Result.IncludedFiles)
+		app.program = program
+		return program.OpCodes, nil
 	}
 
 	// mainエントリーポイントを探してコンパイル
@@ -700,23 +631,42 @@ func (app *Application) compileScripts(scripts []script.Script, selectedTitle *t
 	app.log.Info("Main entry point found, using preprocessor", "file", mainInfo.FileName)
 
 	// プリプロセッサを使用してmainエントリーポイントからコンパイル
-	var opcodes []compiler.OpCode
-	var result *compiler.PreprocessResult
-
-	if selectedTitle.IsEmbedded {
-		// 埋め込みタイトルの場合はembed.FSを使用
-		opcodes, result, err = compiler.CompileWithPreprocessorFS(selectedTitle.Path, mainInfo.FileName, app.embedFS)
-	} else {
-		opcodes, result, err = compiler.CompileWithPreprocessor(selectedTitle.Path, mainInfo.FileName)
-	}
+	program, err := compiler.CompileProgram(selectedTitle.Path, mainInfo.FileName, app.scriptFS(selectedTitle), app.titleCompileOptions(selectedTitle))
 	if err != nil {
 		// Requirement 13.3: When compilation fails, display error message.
 		app.log.Error("Compilation with preprocessor failed", "error", err)
 		return nil, err
 	}
 
-	app.log.Info("Preprocessor completed", "included_files", result.IncludedFiles)
-	return opcodes, nil
+	app.log.Info("Preprocessor completed", "included_files", program.Result.IncludedFiles)
+	app.program = program
+	return program.OpCodes, nil
+}
+
+// scriptFS はプリプロセッサがスクリプトを読み込むファイルシステムを返す
+// 埋め込みタイトルの場合はembed.FSを使用し、外部タイトルの場合は nil（実ファイルシステム）を返す
+func (app *Application) scriptFS(selectedTitle *title.FillyTitle) fs.FS {
+	if selectedTitle.IsEmbedded {
+		return app.embedFS
+	}
+	return nil
+}
+
+// compileOptions はコマンドラインの設定からコンパイルオプションを作成する
+func (app *Application) compileOptions() compiler.CompileOptions {
+	return compiler.CompileOptions{Compat: app.config.Compat}
+}
+
+// titleCompileOptions はタイトルのコンパイルに使うオプションを作成する
+// 外部タイトルではタイトル内の .sonet-cache をコード生成キャッシュとして使う。
+// サンドボックスモードでは、タイトルに同梱されたキャッシュでソースと異なるOpCodeを
+// 実行させられないよう、キャッシュを使わない。
+func (app *Application) titleCompileOptions(selectedTitle *title.FillyTitle) compiler.CompileOptions {
+	opts := app.compileOptions()
+	if !app.config.NoCache && !app.config.Sandbox && !selectedTitle.IsEmbedded {
+		opts.CacheDir = filepath.Join(selectedTitle.Path, compiler.CacheDirName)
+	}
+	return opts
 }
 
 // truncate 文字列を指定した長さで切り詰める
@@ -753,3 +703,29 @@ func formatOpCodesPreview(opcodes []compiler.OpCode, maxCount int) string {
 
 	return "[" + result + "]"
 }
+
+// powerSaveAudioBuffer は --power-save で音声のプレーヤーが先に用意しておく音声の長さ
+// 長いほどシンセサイザーとデコーダーを起こす回数が減るが、音量の変更や一時停止が遅れて聞こえる
+const powerSaveAudioBuffer = 250 * time.Millisecond
+
+// applyAudioPowerSave は --power-save の場合に音声のバッファを大きくする
+func (app *Application) applyAudioPowerSave(audioSys *audio.AudioSystem) {
+	if !app.config.PowerSave {
+		return
+	}
+	audioSys.SetBufferSize(powerSaveAudioBuffer)
+	app.log.Info("Audio power save", "buffer", powerSaveAudioBuffer)
+}
+
+// applySynthQuality はコマンドラインで指定したMIDIのシンセサイザーの品質を設定する（指定がない場合は何もしない）
+func (app *Application) applySynthQuality(audioSys *audio.AudioSystem) {
+	if !app.config.NoSynthEffects && app.config.SynthPolyphony == 0 {
+		return
+	}
+	polyphony, err := audioSys.SetSynthQuality(!app.config.NoSynthEffects, app.config.SynthPolyphony)
+	if err != nil {
+		app.log.Warn("Failed to set synthesizer quality", "error", err)
+		return
+	}
+	app.log.Info("Synthesizer quality", "effects", !app.config.NoSynthEffects, "polyphony", polyphony)
+}
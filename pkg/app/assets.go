@@ -0,0 +1,38 @@
+package app
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/zurustar/son-et/pkg/assetconv"
+	"github.com/zurustar/son-et/pkg/logger"
+)
+
+// runAssets はタイトルのアセットを一括変換する（son-et assets convert）。
+//
+// 変換したタイトルを別のディレクトリに書き出し、変更の一覧を標準出力に書き出す。
+// 変換できなかったファイルはそのまま複製し、ログに警告を書き込む。
+// ログは標準出力の一覧と混ざらないよう標準エラー出力に書き込む。
+func (app *Application) runAssets() error {
+	if err := logger.InitLoggerWithWriter(app.config.LogLevel, os.Stderr); err != nil {
+		return fmt.Errorf("failed to initialize logger: %w", err)
+	}
+	app.log = logger.GetLogger()
+
+	report, err := assetconv.Convert(app.config.TitlePath, app.config.AssetsOutDir, assetconv.Options{
+		MaxWidth:  app.config.AssetsMaxWidth,
+		MaxHeight: app.config.AssetsMaxHeight,
+		PPQ:       app.config.AssetsPPQ,
+	})
+	if err != nil {
+		return fmt.Errorf("assets convert: %w", err)
+	}
+	for _, c := range report.Changes {
+		if c.Err != "" {
+			app.log.Warn("Asset not converted", "path", c.Path, "reason", c.Err)
+		}
+	}
+
+	app.log.Info("Asset conversion finished", "files", report.Files, "changes", len(report.Changes), "output", report.Output)
+	return report.WriteText(os.Stdout)
+}
@@ -0,0 +1,57 @@
+package app
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/zurustar/son-et/pkg/vm"
+)
+
+// startCues は --play-cues のキューファイルを読み込み、--record-cues のキューファイルを作成する
+// タイトル選択画面から起動したタイトルにも同じキューを使う
+func (app *Application) startCues() error {
+	if path := app.config.PlayCuesPath; path != "" {
+		f, err := os.Open(path)
+		if err != nil {
+			return fmt.Errorf("failed to open cue file: %w", err)
+		}
+		cues, err := vm.ParseCues(f)
+		f.Close()
+		if err != nil {
+			return fmt.Errorf("%s: %w", path, err)
+		}
+		app.cues = cues
+		app.log.Info("Operator cues loaded", "path", path, "cues", len(cues))
+	}
+
+	// 再生するファイルを読み込んでから作成するため、同じファイルに記録し直すこともできる
+	if path := app.config.RecordCuesPath; path != "" {
+		f, err := os.Create(path)
+		if err != nil {
+			return fmt.Errorf("failed to create cue file: %w", err)
+		}
+		app.cueFile = f
+		app.log.Info("Recording operator cues", "path", path)
+	}
+	return nil
+}
+
+// stopCues はキューファイルを閉じる
+func (app *Application) stopCues() {
+	if app.cueFile == nil {
+		return
+	}
+	if err := app.cueFile.Close(); err != nil {
+		app.log.Warn("Failed to close cue file", "error", err)
+	}
+	app.cueFile = nil
+}
+
+// cueOption はキューの再生と記録に使う VM のオプションを返す
+func (app *Application) cueOption() vm.Option {
+	if app.cueFile == nil {
+		// nil の *os.File をインターフェースに入れると記録しないと判定できないため
+		return vm.WithCues(app.cues, nil)
+	}
+	return vm.WithCues(app.cues, app.cueFile)
+}
@@ -0,0 +1,677 @@
+//go:build !nogpu
+
+// desktop.go はEbitengineのウィンドウでタイトルを実行する
+// nogpu タグを付けてビルドした場合は含まれず、ヘッドレスモードだけを実行できる（desktop_nogpu.go）
+package app
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	ebitenAudio "github.com/hajimehoshi/ebiten/v2/audio"
+	"github.com/zurustar/son-et/pkg/compiler"
+	"github.com/zurustar/son-et/pkg/fileutil"
+	"github.com/zurustar/son-et/pkg/graphics"
+	"github.com/zurustar/son-et/pkg/title"
+	"github.com/zurustar/son-et/pkg/vm"
+	"github.com/zurustar/son-et/pkg/vm/audio"
+	"github.com/zurustar/son-et/pkg/window"
+)
+
+// desktopState はウィンドウで実行するときにだけ使う Application の状態
+type desktopState struct {
+	// sharedAudioCtx はEbitengineのオーディオコンテキスト（一度だけ作成可能）
+	// タイトル切り替え時に再利用する
+	sharedAudioCtx *ebitenAudio.Context
+
+	// inputMap はゲームパッドのボタンとキー入力の対応（--input-map、nilの場合は割り当てない）
+	inputMap *window.InputMap
+}
+
+// attachGraphicsSystem は通常のGraphicsSystemをVMに設定し、終了時に呼び出す関数を返す
+func (app *Application) attachGraphicsSystem(vmInstance *vm.VM) (shutdown func()) {
+	graphicsSys := graphics.NewGraphicsSystem(
+		app.selectedTitle.Path,
+		graphics.WithLogger(app.log),
+		graphics.WithSandbox(app.sandboxEnabled(app.selectedTitle)),
+		graphics.WithAssetDirs(titleAssetDirs(app.selectedTitle)...),
+		graphics.WithAssetVars(app.titleAssetVars(app.selectedTitle)),
+		graphics.WithPaletteEmulation(app.config.Palette256),
+		graphics.WithEventBus(app.eventBus),
+		graphics.WithDisplayAdjustment(app.displayAdjustment()),
+		graphics.WithAssetStreaming(int64(app.config.StreamAssetsMB)<<20),
+		graphics.WithPowerSave(app.config.PowerSave),
+		graphics.WithStateDiff(app.config.DebugStateDiff),
+		graphics.WithQualityGovernor(app.qualityBudget(app.selectedTitle)),
+	)
+	// 埋め込みタイトルの場合はembed.FSを設定
+	if app.selectedTitle.IsEmbedded {
+		graphicsSys.SetEmbedFS(app.embedFS)
+	}
+	app.prefetchPictures(graphicsSys)
+	vmInstance.SetGraphicsSystem(graphicsSys)
+	if app.config.DebugStateDiff {
+		vmInstance.SetDispatchObserver(graphicsSys.ObserveState)
+	}
+	app.log.Info("Graphics system initialized")
+
+	// ログレベルに基づいてデバッグオーバーレイを有効化
+	graphicsSys.SetDebugOverlayFromLogLevelString(app.config.LogLevel)
+
+	return func() {
+		graphicsSys.Shutdown()
+		app.log.Info("Graphics system shut down")
+	}
+}
+
+// applyFrameRate はタイトルの #info FPS と --tps/--fps から決めたフレームレートをゲームに設定する
+// t が nil の場合（タイトル選択画面）は既定のフレームレートとなる
+func (app *Application) applyFrameRate(game *window.Game, t *title.FillyTitle) {
+	scriptFPS := 0
+	if t != nil && t.Metadata != nil {
+		scriptFPS = t.Metadata.FPS
+	}
+	tps, fps := window.ResolveFrameRate(scriptFPS, app.config.TPS, app.config.FPS)
+	game.SetFrameRate(tps, fps)
+	app.log.Info("Frame rate configured", "tps", tps, "fps", fps, "scriptFPS", scriptFPS)
+}
+
+// qualityBudget は --adaptive-quality の描画品質の調整に使う1フレームの時間を返す
+// 描画の間隔（FPS）を予算とし、その間の Update と Draw の時間を比べる。無効な場合は 0 を返す。
+func (app *Application) qualityBudget(t *title.FillyTitle) time.Duration {
+	if !app.config.AutoQuality {
+		return 0
+	}
+	scriptFPS := 0
+	if t != nil && t.Metadata != nil {
+		scriptFPS = t.Metadata.FPS
+	}
+	_, fps := window.ResolveFrameRate(scriptFPS, app.config.TPS, app.config.FPS)
+	return time.Second / time.Duration(fps)
+}
+
+// applyScaleMode は --scale-mode で指定された仮想デスクトップの拡大方法をゲームに設定する
+func (app *Application) applyScaleMode(game *window.Game) {
+	mode, err := window.ParseScaleMode(app.config.ScaleMode)
+	if err != nil {
+		app.log.Warn("Ignoring scale mode", "error", err)
+		return
+	}
+	game.SetScaleMode(mode)
+}
+
+// loadInputMap は --input-map で指定された入力マップを読み込む
+// キーの指定は OnKey と同じ形式で解析する
+func (app *Application) loadInputMap() error {
+	if app.config.InputMapPath == "" {
+		return nil
+	}
+	data, err := os.ReadFile(app.config.InputMapPath)
+	if err != nil {
+		return fmt.Errorf("failed to read input map: %w", err)
+	}
+	m, err := window.ParseInputMap(data, vm.ParseKeySpec)
+	if err != nil {
+		return fmt.Errorf("%s: %w", app.config.InputMapPath, err)
+	}
+	app.inputMap = m
+	app.log.Info("Input map loaded", "path", app.config.InputMapPath, "standardButtons", len(m.Standard), "rawButtons", len(m.Raw))
+	return nil
+}
+
+// prefetchPictures はスクリプト中で文字列リテラルとして指定された画像を先読みする
+// 低速なメディアでも、LoadPicの時点でディスクの読み込みとデコードを待たずに済むようにする
+func (app *Application) prefetchPictures(gs *graphics.GraphicsSystem) {
+	pictures := compiler.AssetPaths(compiler.CollectAssets(app.opcodes), compiler.AssetPicture)
+	gs.PrefetchPictures(pictures)
+}
+
+// runGUI はEbitengineのゲームループでVMとGraphicsSystemを統合して実行する
+func (app *Application) runGUI() error {
+	app.log.Info("GUI mode: running VM with Ebitengine")
+
+	// VMオプションを設定
+	opts := []vm.Option{
+		vm.WithHeadless(false),
+		vm.WithLogger(app.log),
+		vm.WithTitlePath(app.selectedTitle.Path),
+		vm.WithSandbox(app.sandboxEnabled(app.selectedTitle)),
+		vm.WithOutputDir(app.outputDir(app.selectedTitle)),
+		vm.WithCompatMode(app.config.Compat),
+		vm.WithPowerSave(app.config.PowerSave),
+		vm.WithKeyframeInterval(app.config.KeyframeInterval),
+		vm.WithLocale(app.config.Locale),
+		vm.WithAssetDirs(titleAssetDirs(app.selectedTitle)...),
+		vm.WithAssetVars(app.titleAssetVars(app.selectedTitle)),
+		vm.WithEventBus(app.eventBus),
+		app.oscOption(),
+		app.cueOption(),
+	}
+
+	// タイムアウトが指定されている場合
+	if app.config.Timeout > 0 {
+		opts = append(opts, vm.WithTimeout(app.config.Timeout))
+	}
+
+	// Random() の実行シードが指定されている場合（--seed）
+	if app.config.SeedSet {
+		opts = append(opts, vm.WithRandomSeed(app.config.Seed))
+	}
+
+	// チャプターまで早送りして始める場合（--chapter）
+	if app.config.Chapter != "" {
+		opts = append(opts, vm.WithStartChapter(app.config.Chapter))
+	}
+
+	// DebugBreak で一時停止する場合（--debug-break）
+	if app.config.DebugBreak {
+		opts = append(opts, vm.WithDebugger(vm.NewConsoleDebugger(os.Stdin, os.Stderr)))
+	}
+
+	// SoundFontパスを設定（埋め込みファイルと外部ファイルの両方に対応）
+	// Requirement 3.1, 3.2, 3.3: 優先順位に従ってSF2ファイルを検索
+	if app.soundFontLocation == nil {
+		app.soundFontLocation = app.findTitleSoundFont(app.selectedTitle)
+	}
+
+	if app.soundFontLocation != nil {
+		app.soundFontPath = app.soundFontLocation.Path
+		opts = append(opts, vm.WithSoundFont(app.soundFontPath))
+		app.log.Info("SoundFont configured", "path", app.soundFontPath, "embedded", app.soundFontLocation.IsEmbedded)
+	}
+
+	// VMを作成
+	vmInstance := vm.New(app.opcodes, opts...)
+	app.reportDiagnostics(vmInstance)
+	app.watchServer.setVM(vmInstance)
+
+	// オーディオシステムを初期化
+	// Requirement 2.1: FileSystemインターフェースを使用してSF2ファイルを読み込む
+	if app.soundFontLocation != nil {
+		var audioSys *audio.AudioSystem
+		var err error
+
+		// SoundFontのFileSystemを使用してオーディオシステムを初期化
+		audioSys, err = audio.NewAudioSystemWithOutput(
+			app.soundFontLocation.Path,
+			vmInstance.GetEventQueue(),
+			audio.NewDefaultOutput(),
+			app.soundFontLocation.FileSystem,
+		)
+		if err != nil {
+			app.log.Warn("Failed to initialize audio system", "error", err)
+		} else {
+			// 埋め込みタイトルの場合はMIDI/WAV用のFileSystemを設定
+			if app.selectedTitle.IsEmbedded {
+				embedFS := fileutil.NewEmbedFS(app.embedFS, app.selectedTitle.Path)
+				audioSys.SetFileSystem(embedFS)
+				app.log.Info("Audio system using embedded file system for MIDI/WAV", "basePath", app.selectedTitle.Path)
+			}
+			audioSys.SetAVOffset(app.config.AVOffset)
+			audioSys.SetMIDIStrict(app.config.MIDIStrict)
+			audioSys.SetMetronome(app.config.Metronome)
+			app.applySynthQuality(audioSys)
+			app.applyAudioPowerSave(audioSys)
+			applyMIDIRemap(audioSys, app.selectedTitle)
+			app.applySyncClock(audioSys)
+			audioSys.SetEventBus(app.eventBus)
+			vmInstance.SetAudioSystem(audioSys)
+			app.log.Info("Audio system initialized")
+
+			defer func() {
+				vmInstance.ShutdownAudio()
+				app.log.Info("Audio system shut down")
+			}()
+		}
+	}
+
+	// グラフィックスシステムを初期化
+	graphicsSys := graphics.NewGraphicsSystem(
+		app.selectedTitle.Path,
+		graphics.WithLogger(app.log),
+		graphics.WithSandbox(app.sandboxEnabled(app.selectedTitle)),
+		graphics.WithAssetDirs(titleAssetDirs(app.selectedTitle)...),
+		graphics.WithAssetVars(app.titleAssetVars(app.selectedTitle)),
+		graphics.WithPaletteEmulation(app.config.Palette256),
+		graphics.WithEventBus(app.eventBus),
+		graphics.WithDisplayAdjustment(app.displayAdjustment()),
+		graphics.WithAssetStreaming(int64(app.config.StreamAssetsMB)<<20),
+		graphics.WithPowerSave(app.config.PowerSave),
+		graphics.WithStateDiff(app.config.DebugStateDiff),
+		graphics.WithQualityGovernor(app.qualityBudget(app.selectedTitle)),
+	)
+	// 埋め込みタイトルの場合はembed.FSを設定
+	if app.selectedTitle.IsEmbedded {
+		graphicsSys.SetEmbedFS(app.embedFS)
+	}
+	app.prefetchPictures(graphicsSys)
+	vmInstance.SetGraphicsSystem(graphicsSys)
+	if app.config.DebugStateDiff {
+		// フレーム間の差分に、VMが変更したときのMIDIティックを付ける（--debug-state-diff）
+		vmInstance.SetDispatchObserver(graphicsSys.ObserveState)
+	}
+	app.log.Info("Graphics system initialized")
+
+	// ログレベルに基づいてデバッグオーバーレイを有効化
+	graphicsSys.SetDebugOverlayFromLogLevelString(app.config.LogLevel)
+
+	defer func() {
+		graphicsSys.Shutdown()
+		app.log.Info("Graphics system shut down")
+	}()
+
+	// Ebitengineのゲームを作成
+	game := window.NewGame(window.ModeDesktop, nil, app.config.Timeout)
+	app.applyFrameRate(game, app.selectedTitle)
+	game.SetPowerSave(app.config.PowerSave)
+	app.applyScaleMode(game)
+	game.SetInputMap(app.inputMap)
+
+	// 単一タイトル実行時はタイトル選択画面がないことを明示的に設定
+	// Requirements 3.1, 3.2: 単一タイトル実行中にESCキーを押すとプログラムが終了する
+	game.SetHasTitleSelection(false)
+
+	game.SetGraphicsSystem(graphicsSys)
+	game.SetVMRunner(vmInstance)
+	game.SetEventPusher(vmInstance)  // マウスイベントをVMに伝達
+	game.SetTimeScaler(vmInstance)   // 時間スケールのホットキー（スロー再生・早送り）
+	game.SetAVCalibrator(vmInstance) // A/Vオフセットの調整画面
+	game.SetVariableWatcher(vmWatcher{vmInstance})
+	game.SetCommandRunner(vmInstance) // オペレーターのコンソール（~ キー）
+	if app.config.PauseOnBlur {
+		game.SetFocusPauser(vmInstance) // フォーカス喪失時に一時停止
+	}
+	if app.config.MetronomeFlash {
+		game.SetMetronomeFlash(vmInstance) // 4分音符ごとに画面の左上に四角形を表示
+	}
+	// ポーズメニュー（Esc キー）
+	game.SetPauseMenuTarget(vmInstance)
+	applyPauseMenuStyle(game, app.selectedTitle)
+
+	// VMを開始する関数を設定（Ebitengine初期化後に呼び出される）
+	vmErrCh := make(chan error, 1)
+	game.SetVMStartFunc(func() {
+		app.log.Info("Starting VM execution in background (after Ebitengine init)")
+		go func() {
+			vmErrCh <- vmInstance.Run()
+		}()
+	}, vmErrCh)
+
+	// Ebitengineのゲームループを実行
+	app.log.Info("Starting Ebitengine game loop")
+	// skelton要件 3.2: ウィンドウサイズは 1024x768 ピクセル（プロジェクトマニフェストの resolution で変更できる）
+	ebiten.SetWindowSize(titleWindowSize(app.selectedTitle))
+	ebiten.SetWindowTitle(titleWindowTitle(app.selectedTitle))
+	ebiten.SetWindowResizingMode(ebiten.WindowResizingModeDisabled)
+	// ウィンドウを閉じる操作は Game.Update で処理する（タイトルの終了処理 OnExit を実行するため）
+	ebiten.SetWindowClosingHandled(true)
+
+	if err := ebiten.RunGame(game); err != nil {
+		app.log.Error("Ebitengine game loop failed", "error", err)
+		vmInstance.Stop()
+		return fmt.Errorf("game loop failed: %w", err)
+	}
+
+	// VMの終了を待つ
+	select {
+	case vmErr := <-vmErrCh:
+		if vmErr != nil {
+			app.log.Error("VM execution failed", "error", vmErr)
+			reportTraces(os.Stderr, vmInstance, "runtime error")
+			return withExitCode(ExitRuntimeError, vmErr)
+		}
+	default:
+		// VMがまだ実行中の場合は停止
+		vmInstance.Stop()
+	}
+
+	if game.TimedOut() {
+		reportTraces(os.Stderr, vmInstance, "timeout")
+		return withExitCode(ExitTimeout, ErrTimeout)
+	}
+
+	app.log.Info("Desktop execution completed")
+	return nil
+}
+
+// runWithSelection タイトル選択画面からデスクトップモードまでを単一のRunGameで実行
+// Ebitengineは一度RunGameが終了すると再利用できないため、
+// タイトル選択とデスクトップ実行を同じRunGame内で行う必要がある
+func (app *Application) runWithSelection(titles []title.FillyTitle) (*title.FillyTitle, error) {
+	// Gameを選択モードで作成
+	game := window.NewGame(window.ModeSelection, titles, app.config.Timeout)
+	app.applyFrameRate(game, nil)
+	game.SetPowerSave(app.config.PowerSave)
+	app.applyScaleMode(game)
+	game.SetInputMap(app.inputMap)
+
+	// 複数タイトル環境であることを設定
+	// Requirements 2.1, 3.1, 5.1: タイトル選択画面があることを示す
+	game.SetHasTitleSelection(true)
+
+	// タイトル選択時のコールバックを設定
+	// このコールバック内でVM/GraphicsSystemをセットアップし、デスクトップモードに遷移する
+	var vmInstance *vm.VM
+	var graphicsSys *graphics.GraphicsSystem
+	var audioSys *audio.AudioSystem
+	vmErrCh := make(chan error, 1)
+
+	// タイトル終了時のリソースクリーンアップコールバックを設定
+	// Requirements 2.2, 2.3, 2.4, 4.1, 4.2, 4.3: リソースのクリーンアップ
+	game.SetOnTitleExit(func() error {
+		app.log.Info("Cleaning up resources for title exit")
+
+		// VM停止 (Requirement 4.1: VMのすべてのゴルーチンを停止)
+		if vmInstance != nil {
+			vmInstance.Stop()
+			app.log.Info("VM stopped")
+
+			// AudioSystem停止 (Requirement 4.3: すべての再生中の音声を停止)
+			// AudioSystemはVMを通じてシャットダウンする
+			if audioSys != nil {
+				vmInstance.ShutdownAudio()
+				app.log.Info("Audio system shut down")
+			}
+		}
+
+		// GraphicsSystem停止 (Requirement 4.2: すべてのスプライトとテクスチャを解放)
+		if graphicsSys != nil {
+			graphicsSys.Shutdown()
+			app.log.Info("Graphics system shut down")
+		}
+
+		// リソース参照をクリア
+		vmInstance = nil
+		graphicsSys = nil
+		audioSys = nil
+
+		// タイトル選択画面は既定のフレームレートに戻す
+		app.applyFrameRate(game, nil)
+
+		return nil
+	})
+
+	game.SetOnTitleSelected(func(selectedTitle *title.FillyTitle) error {
+		app.log.Info("Title selected, setting up VM and graphics", "name", selectedTitle.Name)
+		app.selectedTitle = selectedTitle
+		app.applyManifest(selectedTitle)
+		app.applyFrameRate(game, selectedTitle)
+
+		// スクリプトの読み込みとコンパイル
+		scripts, err := app.loadScripts(selectedTitle)
+		if err != nil {
+			return fmt.Errorf("failed to load scripts: %w", err)
+		}
+		app.log.Info("Scripts loaded", "count", len(scripts))
+
+		opcodes, err := app.compileScripts(scripts, selectedTitle)
+		if err != nil {
+			return withExitCode(compileExitCode(err), fmt.Errorf("failed to compile scripts: %w", err))
+		}
+		app.opcodes = opcodes
+		app.log.Info("Scripts compiled", "opcode_count", len(opcodes))
+
+		// VMオプションを設定
+		opts := []vm.Option{
+			vm.WithHeadless(false),
+			vm.WithLogger(app.log),
+			vm.WithTitlePath(selectedTitle.Path),
+			vm.WithSandbox(app.sandboxEnabled(selectedTitle)),
+			vm.WithOutputDir(app.outputDir(selectedTitle)),
+			vm.WithCompatMode(app.config.Compat),
+			vm.WithPowerSave(app.config.PowerSave),
+			vm.WithKeyframeInterval(app.config.KeyframeInterval),
+			vm.WithLocale(app.config.Locale),
+			vm.WithAssetDirs(titleAssetDirs(selectedTitle)...),
+			vm.WithAssetVars(app.titleAssetVars(selectedTitle)),
+			vm.WithEventBus(app.eventBus),
+			app.oscOption(),
+			app.cueOption(),
+		}
+
+		if app.config.Timeout > 0 {
+			opts = append(opts, vm.WithTimeout(app.config.Timeout))
+		}
+		if app.config.SeedSet {
+			opts = append(opts, vm.WithRandomSeed(app.config.Seed))
+		}
+		if app.config.Chapter != "" {
+			opts = append(opts, vm.WithStartChapter(app.config.Chapter))
+		}
+		if app.config.DebugBreak {
+			opts = append(opts, vm.WithDebugger(vm.NewConsoleDebugger(os.Stdin, os.Stderr)))
+		}
+
+		// SoundFontパスを設定（埋め込みファイルと外部ファイルの両方に対応）
+		// Requirement 3.1, 3.2, 3.3: 優先順位に従ってSF2ファイルを検索
+		app.soundFontLocation = app.findTitleSoundFont(selectedTitle)
+
+		if app.soundFontLocation != nil {
+			app.soundFontPath = app.soundFontLocation.Path
+			opts = append(opts, vm.WithSoundFont(app.soundFontPath))
+			app.log.Info("SoundFont configured", "path", app.soundFontPath, "embedded", app.soundFontLocation.IsEmbedded)
+		}
+
+		// VMを作成
+		vmInstance = vm.New(opcodes, opts...)
+		app.reportDiagnostics(vmInstance)
+		app.watchServer.setVM(vmInstance)
+
+		// オーディオシステムを初期化
+		// Ebitengineのオーディオコンテキストは一度しか作成できないため、
+		// アプリケーションレベルで保持して再利用する
+		// Requirement 2.1: FileSystemインターフェースを使用してSF2ファイルを読み込む
+		if app.soundFontLocation != nil {
+			var err error
+			// 共有オーディオコンテキストがなければ作成
+			if app.sharedAudioCtx == nil {
+				app.sharedAudioCtx = ebitenAudio.NewContext(audio.SampleRate)
+				app.log.Info("Created shared audio context")
+			}
+			// SoundFontのFileSystemを使用してオーディオシステムを作成
+			audioSys, err = audio.NewAudioSystemWithFS(
+				app.soundFontLocation.Path,
+				vmInstance.GetEventQueue(),
+				app.sharedAudioCtx,
+				app.soundFontLocation.FileSystem,
+			)
+			if err != nil {
+				app.log.Warn("Failed to initialize audio system", "error", err)
+			} else {
+				// 埋め込みタイトルの場合はMIDI/WAV用のFileSystemを設定
+				if selectedTitle.IsEmbedded {
+					embedFS := fileutil.NewEmbedFS(app.embedFS, selectedTitle.Path)
+					audioSys.SetFileSystem(embedFS)
+					app.log.Info("Audio system using embedded file system for MIDI/WAV", "basePath", selectedTitle.Path)
+				}
+				audioSys.SetAVOffset(app.config.AVOffset)
+				audioSys.SetMIDIStrict(app.config.MIDIStrict)
+				audioSys.SetMetronome(app.config.Metronome)
+				app.applySynthQuality(audioSys)
+				app.applyAudioPowerSave(audioSys)
+				applyMIDIRemap(audioSys, selectedTitle)
+				app.applySyncClock(audioSys)
+				audioSys.SetEventBus(app.eventBus)
+				vmInstance.SetAudioSystem(audioSys)
+				app.log.Info("Audio system initialized")
+			}
+		}
+
+		// グラフィックスシステムを初期化
+		graphicsSys = graphics.NewGraphicsSystem(
+			selectedTitle.Path,
+			graphics.WithLogger(app.log),
+			graphics.WithSandbox(app.sandboxEnabled(selectedTitle)),
+			graphics.WithAssetDirs(titleAssetDirs(selectedTitle)...),
+			graphics.WithAssetVars(app.titleAssetVars(selectedTitle)),
+			graphics.WithPaletteEmulation(app.config.Palette256),
+			graphics.WithEventBus(app.eventBus),
+			graphics.WithDisplayAdjustment(app.displayAdjustment()),
+			graphics.WithAssetStreaming(int64(app.config.StreamAssetsMB)<<20),
+			graphics.WithPowerSave(app.config.PowerSave),
+			graphics.WithStateDiff(app.config.DebugStateDiff),
+			graphics.WithQualityGovernor(app.qualityBudget(selectedTitle)),
+		)
+		if selectedTitle.IsEmbedded {
+			graphicsSys.SetEmbedFS(app.embedFS)
+		}
+		app.prefetchPictures(graphicsSys)
+		vmInstance.SetGraphicsSystem(graphicsSys)
+		if app.config.DebugStateDiff {
+			vmInstance.SetDispatchObserver(graphicsSys.ObserveState)
+		}
+		app.log.Info("Graphics system initialized")
+
+		graphicsSys.SetDebugOverlayFromLogLevelString(app.config.LogLevel)
+
+		// GameにVM/GraphicsSystemを設定
+		game.SetGraphicsSystem(graphicsSys)
+		game.SetVMRunner(vmInstance)
+		game.SetEventPusher(vmInstance)
+		game.SetTimeScaler(vmInstance)
+		game.SetAVCalibrator(vmInstance)
+		game.SetVariableWatcher(vmWatcher{vmInstance})
+		game.SetCommandRunner(vmInstance)
+		if app.config.PauseOnBlur {
+			game.SetFocusPauser(vmInstance)
+		}
+		if app.config.MetronomeFlash {
+			game.SetMetronomeFlash(vmInstance)
+		}
+		game.SetPauseMenuTarget(vmInstance)
+		applyPauseMenuStyle(game, selectedTitle)
+
+		// VMを開始する関数を設定
+		game.SetVMStartFunc(func() {
+			app.log.Info("Starting VM execution in background (after mode transition)")
+			go func() {
+				vmErrCh <- vmInstance.Run()
+			}()
+		}, vmErrCh)
+
+		return nil
+	})
+
+	// ウィンドウ設定
+	ebiten.SetWindowSize(window.DefaultWidth, window.DefaultHeight)
+	ebiten.SetWindowTitle(window.DefaultTitle)
+	ebiten.SetWindowResizingMode(ebiten.WindowResizingModeEnabled)
+	// ウィンドウを閉じる操作は Game.Update で処理する（タイトルの終了処理 OnExit を実行するため）
+	ebiten.SetWindowClosingHandled(true)
+
+	// ゲームを実行（選択画面 -> デスクトップモードまで）
+	app.log.Info("Starting Ebitengine game loop (selection mode)")
+	if err := ebiten.RunGame(game); err != nil {
+		app.log.Error("Ebitengine game loop failed", "error", err)
+		if vmInstance != nil {
+			vmInstance.Stop()
+		}
+		return nil, fmt.Errorf("game loop failed: %w", err)
+	}
+
+	// モード遷移時のエラーをチェック
+	if err := game.GetTransitionError(); err != nil {
+		return nil, err
+	}
+
+	// クリーンアップ
+	if graphicsSys != nil {
+		graphicsSys.Shutdown()
+		app.log.Info("Graphics system shut down")
+	}
+	if audioSys != nil {
+		vmInstance.ShutdownAudio()
+		app.log.Info("Audio system shut down")
+	}
+
+	// VMの終了を待つ
+	select {
+	case vmErr := <-vmErrCh:
+		if vmErr != nil {
+			app.log.Error("VM execution failed", "error", vmErr)
+			return game.GetSelectedTitle(), vmErr
+		}
+	default:
+		if vmInstance != nil {
+			vmInstance.Stop()
+		}
+	}
+
+	return game.GetSelectedTitle(), nil
+}
+
+// applyPauseMenuStyle はプロジェクトマニフェストの pause_menu の設定をポーズメニューに適用する
+func applyPauseMenuStyle(game *window.Game, t *title.FillyTitle) {
+	style := window.PauseMenuStyle{}
+	if t != nil && t.Manifest != nil {
+		m := t.Manifest
+		style.Disabled = m.NoPauseMenu
+		if m.PauseMenuBackgroundColor != nil {
+			style.Background = *m.PauseMenuBackgroundColor
+		}
+		if m.PauseMenuSelectedColor != nil {
+			style.Selected = *m.PauseMenuSelectedColor
+		}
+	}
+	game.SetPauseMenuStyle(style)
+}
+
+// vmWatcher はVMのグローバル変数をウォッチパネル（window.VariableWatcher）に渡す
+type vmWatcher struct {
+	vm *vm.VM
+}
+
+// WatchVariables はウォッチパネルに表示する変数を返す
+func (w vmWatcher) WatchVariables() []window.WatchVariable {
+	vars := w.vm.WatchVariables()
+	result := make([]window.WatchVariable, len(vars))
+	for i, v := range vars {
+		result[i] = toWindowWatchVariable(v)
+	}
+	return result
+}
+
+// toWindowWatchVariable はVMの変数をウォッチパネルの表示用に変換する
+func toWindowWatchVariable(v vm.WatchedVariable) window.WatchVariable {
+	w := window.WatchVariable{Name: v.Name, Numeric: v.Numeric()}
+	switch value := v.Value.(type) {
+	case int64:
+		w.Value = strconv.FormatInt(value, 10)
+		w.Number = float64(value)
+		w.Integer = true
+	case float64:
+		w.Value = strconv.FormatFloat(value, 'g', -1, 64)
+		w.Number = value
+	case string:
+		w.Value = strconv.Quote(value)
+	case *vm.Array:
+		w.Value = fmt.Sprintf("array[%d]", value.Len())
+	default:
+		w.Value = fmt.Sprint(value)
+	}
+	return w
+}
+
+// SetWatchVariable はウォッチパネルで変更した値をVMに設定する
+func (w vmWatcher) SetWatchVariable(name string, value float64) error {
+	_, err := w.vm.SetWatchedVariable(name, value)
+	return err
+}
+
+// TraceLines はウォッチパネルに表示する実行トレースを返す（シーケンスごとの見出しと、最後に実行した文）
+func (w vmWatcher) TraceLines() []string {
+	return traceLines(w.vm.SequenceTraces())
+}
+
+// traceLines は実行トレースを1行ずつの文字列にする
+func traceLines(traces []vm.SequenceTrace) []string {
+	var lines []string
+	for _, t := range traces {
+		lines = append(lines, strings.Split(strings.TrimSuffix(t.String(), "\n"), "\n")...)
+	}
+	return lines
+}
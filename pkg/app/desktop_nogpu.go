@@ -0,0 +1,43 @@
+//go:build nogpu
+
+// desktop_nogpu.go は nogpu タグを付けたビルドでウィンドウでの実行の代わりに使う
+// Ebitengine を含まないため、GL・X11・サウンドデバイスのないサーバーでもビルドして実行できる。
+// タイトルはヘッドレスモード（--headless）とGIF書き出し（--export-gif）でだけ実行でき、音声は実時間で進むが出力されない。
+package app
+
+import (
+	"errors"
+
+	"github.com/zurustar/son-et/pkg/title"
+	"github.com/zurustar/son-et/pkg/vm"
+)
+
+// ErrNoWindow はウィンドウを持たないビルド（nogpu タグ）でウィンドウを使う実行を指定した場合のエラー
+var ErrNoWindow = errors.New("this build has no window support (built with -tags nogpu); run with --headless")
+
+// desktopState はウィンドウで実行するときにだけ使う Application の状態（nogpu のビルドでは持たない）
+type desktopState struct{}
+
+// runGUI はウィンドウを持たないため、エラーを返す
+func (app *Application) runGUI() error {
+	return ErrNoWindow
+}
+
+// runWithSelection はタイトル選択画面を表示できないため、エラーを返す
+func (app *Application) runWithSelection(titles []title.FillyTitle) (*title.FillyTitle, error) {
+	return nil, ErrNoWindow
+}
+
+// attachGraphicsSystem は描画できないため、ヘッドレスモードと同じダミーのGraphicsSystemを使う
+func (app *Application) attachGraphicsSystem(vmInstance *vm.VM) (shutdown func()) {
+	app.log.Warn("No window support in this build; drawing operations are only logged")
+	return app.attachHeadlessGraphicsSystem(vmInstance)
+}
+
+// loadInputMap はゲームパッドを使わないため、入力マップを読み込まない
+func (app *Application) loadInputMap() error {
+	if app.config.InputMapPath != "" {
+		app.log.Warn("Ignoring input map: no window support in this build", "path", app.config.InputMapPath)
+	}
+	return nil
+}
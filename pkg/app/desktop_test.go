@@ -0,0 +1,42 @@
+//go:build !nogpu
+
+package app
+
+import (
+	"testing"
+
+	"github.com/zurustar/son-et/pkg/opcode"
+	"github.com/zurustar/son-et/pkg/vm"
+)
+
+func TestToWindowWatchVariable(t *testing.T) {
+	tests := []struct {
+		value   any
+		display string
+		numeric bool
+		integer bool
+	}{
+		{int64(42), "42", true, true},
+		{0.25, "0.25", true, false},
+		{"hi", `"hi"`, false, false},
+		{vm.NewArray(3), "array[3]", false, false},
+	}
+	for _, tt := range tests {
+		w := toWindowWatchVariable(vm.WatchedVariable{Name: "v", Value: tt.value})
+		if w.Value != tt.display || w.Numeric != tt.numeric || w.Integer != tt.integer {
+			t.Errorf("toWindowWatchVariable(%v) = %+v", tt.value, w)
+		}
+	}
+}
+
+func TestVMWatcherTraceLines(t *testing.T) {
+	v := newWatchTestVM(t)
+	if _, err := v.Execute(opcode.OpCode{Cmd: opcode.Call, Args: []any{"StrLen", opcode.Variable("name")}}); err != nil {
+		t.Fatal(err)
+	}
+
+	lines := vmWatcher{v}.TraceLines()
+	if len(lines) != 2 || lines[0] != "sequence 0 (main)" || lines[1] != `  StrLen("demo")` {
+		t.Errorf("TraceLines = %q", lines)
+	}
+}
@@ -0,0 +1,91 @@
+package app
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/zurustar/son-et/pkg/compiler"
+	"github.com/zurustar/son-et/pkg/title"
+	"github.com/zurustar/son-et/pkg/vm"
+)
+
+// エラーの要約の出力（--error-json）
+// 大量のタイトルを順に実行するバッチが、ログの文章を解析せずに失敗の種類と位置を分類できるよう、
+// 終了時に終了コードとエラーの詳細を1つのJSONで書き出す。正常終了の場合も status が "ok" のレポートを書く。
+//
+//	{"exit_code":2,"status":"parse_error","title":"/titles/demo","message":"...",
+//	 "errors":[{"phase":"parser","message":"expected ')'","line":12,"column":5}]}
+
+// errorReport は --error-json で書き出す内容
+type errorReport struct {
+	ExitCode int           `json:"exit_code"`
+	Status   string        `json:"status"`          // 終了コードの名前（ok, parse_error, timeout など）
+	Title    string        `json:"title,omitempty"` // タイトルのパス
+	Entry    string        `json:"entry,omitempty"` // エントリーポイントのTFYファイル
+	Message  string        `json:"message,omitempty"`
+	Errors   []errorDetail `json:"errors,omitempty"` // 位置の分かるコンパイル・実行時のエラー
+}
+
+// errorDetail は位置の分かる1つのエラー
+type errorDetail struct {
+	Phase   string `json:"phase"`          // lexer, parser, compiler, runtime
+	Type    string `json:"type,omitempty"` // 実行時エラーの種類（UNDEFINED_FUNCTION など）
+	Message string `json:"message"`
+	File    string `json:"file,omitempty"`
+	Line    int    `json:"line,omitempty"`
+	Column  int    `json:"column,omitempty"`
+}
+
+// newErrorReport は Run の結果からレポートを作成する
+func newErrorReport(t *title.FillyTitle, err error) errorReport {
+	code := ExitCode(err)
+	report := errorReport{ExitCode: code, Status: exitStatus(code)}
+	if t != nil {
+		report.Title = t.Path
+		report.Entry = t.EntryFile
+	}
+	if err != nil {
+		report.Message = err.Error()
+		report.Errors = collectErrorDetails(err)
+	}
+	return report
+}
+
+// collectErrorDetails はエラーの連鎖（errors.Join を含む）から位置の分かるエラーを集める
+func collectErrorDetails(err error) []errorDetail {
+	var details []errorDetail
+	var walk func(error)
+	walk = func(err error) {
+		switch e := err.(type) {
+		case *compiler.CompileError:
+			details = append(details, errorDetail{Phase: e.Phase, Message: e.Message, Line: e.Line, Column: e.Column})
+		case *vm.RuntimeError:
+			d := errorDetail{Phase: "runtime", Type: string(e.Type), Message: e.Message, File: e.File}
+			if e.Line >= 0 {
+				d.Line = e.Line
+			}
+			details = append(details, d)
+		case interface{ Unwrap() []error }:
+			for _, inner := range e.Unwrap() {
+				walk(inner)
+			}
+		case interface{ Unwrap() error }:
+			walk(e.Unwrap())
+		}
+	}
+	walk(err)
+	return details
+}
+
+// writeErrorReport は Run の結果のレポートを path に書き出す
+func writeErrorReport(path string, t *title.FillyTitle, err error) error {
+	data, merr := json.MarshalIndent(newErrorReport(t, err), "", "  ")
+	if merr != nil {
+		return fmt.Errorf("failed to encode error report: %w", merr)
+	}
+	if werr := os.WriteFile(path, append(data, '\n'), 0644); werr != nil {
+		return fmt.Errorf("failed to write error report: %w", werr)
+	}
+	return nil
+}
@@ -0,0 +1,79 @@
+package app
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/zurustar/son-et/pkg/compiler"
+	"github.com/zurustar/son-et/pkg/title"
+	"github.com/zurustar/son-et/pkg/vm"
+)
+
+func TestNewErrorReport(t *testing.T) {
+	tt := &title.FillyTitle{Path: "/titles/demo", EntryFile: "MAIN.TFY"}
+
+	report := newErrorReport(tt, nil)
+	if report.ExitCode != ExitOK || report.Status != "ok" || report.Message != "" || report.Errors != nil {
+		t.Errorf("report for success = %+v", report)
+	}
+	if report.Title != "/titles/demo" || report.Entry != "MAIN.TFY" {
+		t.Errorf("title, entry = %q, %q", report.Title, report.Entry)
+	}
+
+	compileErr := withExitCode(ExitParseError, fmt.Errorf("failed to compile scripts: %w",
+		fmt.Errorf("compilation failed: %w", errors.Join(
+			&compiler.CompileError{Phase: "parser", Message: "expected ')'", Line: 12, Column: 5},
+			&compiler.CompileError{Phase: "parser", Message: "unexpected EOF", Line: 20, Column: 1},
+		))))
+	report = newErrorReport(tt, compileErr)
+	want := []errorDetail{
+		{Phase: "parser", Message: "expected ')'", Line: 12, Column: 5},
+		{Phase: "parser", Message: "unexpected EOF", Line: 20, Column: 1},
+	}
+	if report.ExitCode != ExitParseError || report.Status != "parse_error" || !reflect.DeepEqual(report.Errors, want) {
+		t.Errorf("report for a parse error = %+v", report)
+	}
+
+	runtimeErr := withExitCode(ExitRuntimeError, fmt.Errorf("VM execution failed: %w",
+		vm.NewRuntimeError(vm.ErrorUndefinedFunc, "Foo is not defined")))
+	report = newErrorReport(nil, runtimeErr)
+	want = []errorDetail{{Phase: "runtime", Type: "UNDEFINED_FUNCTION", Message: "Foo is not defined"}}
+	if report.Status != "runtime_error" || !reflect.DeepEqual(report.Errors, want) || report.Title != "" {
+		t.Errorf("report for a runtime error = %+v", report)
+	}
+
+	report = newErrorReport(tt, withExitCode(ExitTimeout, ErrTimeout))
+	if report.ExitCode != ExitTimeout || report.Status != "timeout" || report.Errors != nil {
+		t.Errorf("report for a timeout = %+v", report)
+	}
+}
+
+func TestWriteErrorReport(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "report.json")
+	if err := writeErrorReport(path, nil, fmt.Errorf("failed to load title: %w", os.ErrNotExist)); err != nil {
+		t.Fatalf("writeErrorReport failed: %v", err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got map[string]any
+	if err := json.Unmarshal(data, &got); err != nil {
+		t.Fatalf("invalid JSON: %v\n%s", err, data)
+	}
+	if got["exit_code"] != float64(ExitAssetMissing) || got["status"] != "asset_missing" {
+		t.Errorf("report = %v", got)
+	}
+	if _, ok := got["errors"]; ok {
+		t.Errorf("errors should be omitted without located errors: %v", got)
+	}
+
+	if err := writeErrorReport(filepath.Join(t.TempDir(), "none", "report.json"), nil, nil); err == nil {
+		t.Error("expected an error for a missing directory")
+	}
+}
@@ -0,0 +1,86 @@
+package app
+
+import (
+	"errors"
+	"io/fs"
+
+	"github.com/zurustar/son-et/pkg/compiler"
+)
+
+// 終了コード（CIや大量のタイトルを実行するバッチで実行結果を区別するために使用する）
+const (
+	ExitOK           = 0 // 正常終了（--exit-after-ticks のティック数に達した場合を含む）
+	ExitError        = 1 // その他のエラー（引数の誤りなど）
+	ExitParseError   = 2 // スクリプトの字句解析・構文解析に失敗した
+	ExitCodegenError = 3 // スクリプトのOpCodeの生成に失敗した
+	ExitRuntimeError = 4 // スクリプトの実行中に致命的なエラーが発生した
+	ExitTimeout      = 5 // --timeout の時間が経過した
+	ExitAssetMissing = 6 // タイトル・スクリプト・SoundFont・MIDIなど実行に必要なファイルが見つからない
+)
+
+// ErrTimeout は --timeout の時間が経過してタイトルの実行を終了したことを表す
+var ErrTimeout = errors.New("execution timed out")
+
+// exitError は終了コードを持つエラー
+type exitError struct {
+	code int
+	err  error
+}
+
+func (e *exitError) Error() string { return e.err.Error() }
+func (e *exitError) Unwrap() error { return e.err }
+
+// withExitCode は err に終了コードを付ける
+func withExitCode(code int, err error) error {
+	return &exitError{code: code, err: err}
+}
+
+// ExitCode returns the process exit status for an error returned by Run
+// (ExitOK for nil, ExitAssetMissing for missing files, ExitError for other
+// errors without a specific code).
+func ExitCode(err error) int {
+	if err == nil {
+		return ExitOK
+	}
+	var e *exitError
+	if errors.As(err, &e) {
+		return e.code
+	}
+	if errors.Is(err, fs.ErrNotExist) {
+		return ExitAssetMissing
+	}
+	return ExitError
+}
+
+// compileExitCode はスクリプトのコンパイルのエラーの終了コードを返す
+// エントリーポイントや #include のファイルがない場合は ExitAssetMissing、
+// OpCodeの生成での誤りは ExitCodegenError、それ以外は ExitParseError となる
+func compileExitCode(err error) int {
+	if errors.Is(err, fs.ErrNotExist) {
+		return ExitAssetMissing
+	}
+	var ce *compiler.CompileError
+	if errors.As(err, &ce) && ce.Phase == "compiler" {
+		return ExitCodegenError
+	}
+	return ExitParseError
+}
+
+// exitStatus は終了コードの名前（--error-json の status）を返す
+func exitStatus(code int) string {
+	switch code {
+	case ExitOK:
+		return "ok"
+	case ExitParseError:
+		return "parse_error"
+	case ExitCodegenError:
+		return "codegen_error"
+	case ExitRuntimeError:
+		return "runtime_error"
+	case ExitTimeout:
+		return "timeout"
+	case ExitAssetMissing:
+		return "asset_missing"
+	}
+	return "error"
+}
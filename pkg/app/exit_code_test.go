@@ -0,0 +1,79 @@
+package app
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"testing"
+
+	"github.com/zurustar/son-et/pkg/compiler"
+)
+
+func TestExitCode(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want int
+	}{
+		{"正常終了", nil, ExitOK},
+		{"その他のエラー", errors.New("no titles available"), ExitError},
+		{"タイムアウト", withExitCode(ExitTimeout, ErrTimeout), ExitTimeout},
+		{"ラップされた構文エラー", fmt.Errorf("failed: %w", withExitCode(ExitParseError, errors.New("parser error"))), ExitParseError},
+		{"実行時エラー", withExitCode(ExitRuntimeError, errors.New("fatal")), ExitRuntimeError},
+		{"見つからないファイル", fmt.Errorf("failed to load title: %w", fs.ErrNotExist), ExitAssetMissing},
+		{"コード生成のエラー", withExitCode(ExitCodegenError, errors.New("codegen")), ExitCodegenError},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ExitCode(tt.err); got != tt.want {
+				t.Errorf("ExitCode(%v) = %d, want %d", tt.err, got, tt.want)
+			}
+		})
+	}
+
+	// 終了コードを付けてもエラーメッセージと元のエラーは変わらない
+	err := withExitCode(ExitTimeout, ErrTimeout)
+	if err.Error() != ErrTimeout.Error() || !errors.Is(err, ErrTimeout) {
+		t.Errorf("withExitCode should keep the wrapped error, got %v", err)
+	}
+}
+
+func TestExitCodeValues(t *testing.T) {
+	// バッチ実行で使う終了コードの値は変えない
+	codes := map[string]int{
+		"ok": ExitOK, "error": ExitError, "parse": ExitParseError, "codegen": ExitCodegenError,
+		"runtime": ExitRuntimeError, "timeout": ExitTimeout, "asset": ExitAssetMissing,
+	}
+	want := map[string]int{"ok": 0, "error": 1, "parse": 2, "codegen": 3, "runtime": 4, "timeout": 5, "asset": 6}
+	for name, code := range codes {
+		if code != want[name] {
+			t.Errorf("exit code %s = %d, want %d", name, code, want[name])
+		}
+	}
+}
+
+func TestCompileExitCode(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want int
+	}{
+		{"構文エラー", fmt.Errorf("compilation failed: %w", errors.Join(
+			&compiler.CompileError{Phase: "parser", Message: "expected ')'"},
+			&compiler.CompileError{Phase: "parser", Message: "unexpected EOF"},
+		)), ExitParseError},
+		{"字句解析のエラー", &compiler.CompileError{Phase: "lexer", Message: "illegal character"}, ExitParseError},
+		{"コード生成のエラー", fmt.Errorf("compilation failed: %w", errors.Join(
+			&compiler.CompileError{Phase: "compiler", Message: "unknown node"},
+		)), ExitCodegenError},
+		{"mainがない", errors.New("no main function found"), ExitParseError},
+		{"#includeのファイルがない", fmt.Errorf("preprocessing failed: failed to read file SUB.TFY: %w", fs.ErrNotExist), ExitAssetMissing},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := compileExitCode(tt.err); got != tt.want {
+				t.Errorf("compileExitCode(%v) = %d, want %d", tt.err, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,161 @@
+package app
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"unicode/utf8"
+
+	"golang.org/x/text/encoding/japanese"
+
+	"github.com/zurustar/son-et/pkg/compiler/formatter"
+	"github.com/zurustar/son-et/pkg/logger"
+)
+
+// ErrNotFormatted は --check で整形されていないファイルが見つかった場合のエラー
+var ErrNotFormatted = errors.New("some files are not formatted")
+
+// runFormat はTFYファイルを整形する（son-et fmt）。
+//
+// 既定では整形結果を標準出力に書き出し、-w では元のファイルに書き戻し、
+// --check では整形が必要なファイルのパスを一覧表示する。
+// ログは標準出力の整形結果と混ざらないよう標準エラー出力に書き込む。
+func (app *Application) runFormat() error {
+	if err := logger.InitLoggerWithWriter(app.config.LogLevel, os.Stderr); err != nil {
+		return fmt.Errorf("failed to initialize logger: %w", err)
+	}
+	app.log = logger.GetLogger()
+
+	files, err := collectScriptFiles(app.config.FmtPaths)
+	if err != nil {
+		return err
+	}
+
+	var unformatted, failed int
+	for _, path := range files {
+		changed, err := app.formatFile(path)
+		if err != nil {
+			app.log.Error("Failed to format file", "path", path, "error", err)
+			failed++
+			continue
+		}
+		if changed && app.config.FmtCheck {
+			fmt.Println(path)
+			unformatted++
+		}
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("fmt: %d file(s) could not be formatted", failed)
+	}
+	if unformatted > 0 {
+		return fmt.Errorf("fmt: %w (%d file(s))", ErrNotFormatted, unformatted)
+	}
+	return nil
+}
+
+// formatFile は1つのファイルを整形し、整形によって内容が変わるかを返す
+func (app *Application) formatFile(path string) (bool, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return false, err
+	}
+	source, sjis, err := decodeScript(data)
+	if err != nil {
+		return false, err
+	}
+
+	formatted, err := formatter.Format(source)
+	if err != nil {
+		return false, err
+	}
+	changed := formatted != source
+
+	switch {
+	case app.config.FmtCheck:
+		// 一覧表示は呼び出し元で行う
+	case app.config.FmtWrite:
+		if !changed {
+			return false, nil
+		}
+		out, err := encodeScript(formatted, sjis)
+		if err != nil {
+			return false, err
+		}
+		info, err := os.Stat(path)
+		if err != nil {
+			return false, err
+		}
+		if err := os.WriteFile(path, out, info.Mode().Perm()); err != nil {
+			return false, err
+		}
+		app.log.Info("Formatted", "path", path)
+	default:
+		out, err := encodeScript(formatted, sjis)
+		if err != nil {
+			return false, err
+		}
+		if _, err := os.Stdout.Write(out); err != nil {
+			return false, err
+		}
+	}
+	return changed, nil
+}
+
+// collectScriptFiles は指定されたパスから整形対象のファイルを集める
+// ディレクトリは配下の .tfy ファイル（大文字・小文字を区別しない）を再帰的に対象とする
+func collectScriptFiles(paths []string) ([]string, error) {
+	var files []string
+	for _, path := range paths {
+		info, err := os.Stat(path)
+		if err != nil {
+			return nil, err
+		}
+		if !info.IsDir() {
+			files = append(files, path)
+			continue
+		}
+		err = filepath.WalkDir(path, func(p string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if !d.IsDir() && strings.EqualFold(filepath.Ext(p), ".tfy") {
+				files = append(files, p)
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return files, nil
+}
+
+// decodeScript はスクリプトのバイト列を文字列に変換する
+// 既存のタイトルはShift-JISで書かれているため、UTF-8として妥当でない場合はShift-JISとして扱う
+// 戻り値の sjis は書き戻すときにShift-JISへ再変換する必要があるかを表す
+func decodeScript(data []byte) (string, bool, error) {
+	if utf8.Valid(data) {
+		return string(data), false, nil
+	}
+	decoded, err := japanese.ShiftJIS.NewDecoder().Bytes(data)
+	if err != nil {
+		return "", false, fmt.Errorf("failed to decode Shift-JIS: %w", err)
+	}
+	return string(decoded), true, nil
+}
+
+// encodeScript は整形結果を元のファイルと同じ文字コードのバイト列に変換する
+func encodeScript(text string, sjis bool) ([]byte, error) {
+	if !sjis {
+		return []byte(text), nil
+	}
+	encoded, err := japanese.ShiftJIS.NewEncoder().String(text)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode Shift-JIS: %w", err)
+	}
+	return []byte(encoded), nil
+}
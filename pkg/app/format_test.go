@@ -0,0 +1,72 @@
+package app
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"golang.org/x/text/encoding/japanese"
+)
+
+func TestDecodeEncodeScript(t *testing.T) {
+	const text = "mes(TIME) {\n  s = \"こんにちは\"\n}\n"
+
+	t.Run("Shift-JIS", func(t *testing.T) {
+		data, err := japanese.ShiftJIS.NewEncoder().String(text)
+		if err != nil {
+			t.Fatal(err)
+		}
+		decoded, sjis, err := decodeScript([]byte(data))
+		if err != nil {
+			t.Fatalf("decodeScript returned error: %v", err)
+		}
+		if decoded != text || !sjis {
+			t.Errorf("decodeScript = %q, %v; want %q, true", decoded, sjis, text)
+		}
+		encoded, err := encodeScript(decoded, sjis)
+		if err != nil {
+			t.Fatalf("encodeScript returned error: %v", err)
+		}
+		if string(encoded) != data {
+			t.Error("Shift-JIS should round-trip")
+		}
+	})
+
+	t.Run("UTF-8", func(t *testing.T) {
+		decoded, sjis, err := decodeScript([]byte(text))
+		if err != nil {
+			t.Fatalf("decodeScript returned error: %v", err)
+		}
+		if decoded != text || sjis {
+			t.Errorf("decodeScript = %q, %v; want %q, false", decoded, sjis, text)
+		}
+	})
+}
+
+func TestCollectScriptFiles(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"MAIN.TFY", "sub/scene.tfy", "sub/PIC.BMP", "README.txt"} {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, nil, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	explicit := filepath.Join(dir, "README.txt")
+
+	files, err := collectScriptFiles([]string{dir, explicit})
+	if err != nil {
+		t.Fatalf("collectScriptFiles returned error: %v", err)
+	}
+	want := []string{filepath.Join(dir, "MAIN.TFY"), filepath.Join(dir, "sub", "scene.tfy"), explicit}
+	if !reflect.DeepEqual(files, want) {
+		t.Errorf("files = %v, want %v", files, want)
+	}
+
+	if _, err := collectScriptFiles([]string{filepath.Join(dir, "missing")}); err == nil {
+		t.Error("expected error for a missing path")
+	}
+}
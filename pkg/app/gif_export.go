@@ -0,0 +1,96 @@
+package app
+
+import (
+	"image"
+	"time"
+
+	"github.com/zurustar/son-et/pkg/fileutil"
+	"github.com/zurustar/son-et/pkg/gifexport"
+	"github.com/zurustar/son-et/pkg/graphics"
+	"github.com/zurustar/son-et/pkg/vm"
+)
+
+// attachGIFExporter は --export-gif 用に、画面をソフトウェアで描画するヘッドレスの
+// GraphicsSystemをVMに設定し、ティックごとにフレームを取り込むようにする。
+// 範囲の終端に達するとVMを停止する。VMの終了後に finish でGIFを書き出し、shutdown で後始末する。
+func (app *Application) attachGIFExporter(vmInstance *vm.VM) (finish func() error, shutdown func(), err error) {
+	width, height := titleWindowSize(app.selectedTitle)
+	headlessGS := graphics.NewHeadlessGraphicsSystem(
+		graphics.WithHeadlessLogger(app.log),
+		graphics.WithLogOperations(false),
+		graphics.WithHeadlessSandbox(app.sandboxEnabled(app.selectedTitle)),
+		graphics.WithHeadlessEventBus(app.eventBus),
+		graphics.WithHeadlessVirtualSize(width, height),
+		graphics.WithHeadlessRendering(app.gifExportFS()),
+	)
+
+	onTick, finish, err := app.newGIFExporter(headlessGS.Frame, vmInstance.Stop)
+	if err != nil {
+		return nil, nil, err
+	}
+	vmInstance.SetGraphicsSystem(headlessGS)
+	vmInstance.SetTickObserver(onTick)
+	app.log.Info("GIF export enabled", "range", app.config.ExportGIFRange.String(), "fps", app.config.ExportGIFFPS)
+
+	return finish, func() {
+		headlessGS.Shutdown()
+		app.log.Info("Headless graphics system shut down")
+	}, nil
+}
+
+// gifExportFS はGIF書き出しで画像ファイルを読み込む FileSystem を返す
+// 通常のGraphicsSystemと同じく、変数を展開し、マニフェストの assets も探す
+func (app *Application) gifExportFS() fileutil.FileSystem {
+	t := app.selectedTitle
+	var fsys fileutil.FileSystem
+	switch {
+	case t.IsEmbedded:
+		fsys = fileutil.NewEmbedFS(app.embedFS, t.Path)
+	case app.sandboxEnabled(t):
+		fsys = fileutil.NewConfinedRealFS(t.Path)
+	default:
+		fsys = fileutil.NewRealFS(t.Path)
+	}
+	return fileutil.WithVars(fileutil.WithSearchDirs(fsys, titleAssetDirs(t)), app.titleAssetVars(t))
+}
+
+// newGIFExporter は --export-gif 用に、VMのティックごとに呼び出す関数と、終了後にGIFを書き出す関数を作成する。
+// 経過時間は壁時計ではなくティック数（1ティック = gifexport.TickDuration）から求めるため、
+// 同じ範囲を指定すれば実行ごとに同じフレームが選ばれる。範囲の終端に達すると stop を一度だけ呼び出す。
+func (app *Application) newGIFExporter(frame func() *image.RGBA, stop func()) (func(ticks int64), func() error, error) {
+	recorder, err := gifexport.NewRecorder(app.config.ExportGIFRange, app.config.ExportGIFFPS)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	stopped := false
+	onTick := func(ticks int64) {
+		if stopped {
+			return
+		}
+		elapsed := time.Duration(ticks) * gifexport.TickDuration
+
+		// ティック n の画面は、直前のティックからこのティックまで（終端を含まない）の取り込み時刻に使う
+		// 画面はティックごとにしか変わらないため、該当する取り込み時刻の数だけ同じフレームを追加する
+		if n := recorder.Due(elapsed - time.Nanosecond); n > 0 {
+			img := frame()
+			for range n {
+				recorder.AddFrame(img)
+			}
+		}
+		if recorder.Done(elapsed) {
+			stopped = true
+			stop()
+		}
+	}
+
+	finish := func() error {
+		if err := recorder.WriteFile(app.config.ExportGIFPath); err != nil {
+			return err
+		}
+		app.log.Info("GIF exported", "path", app.config.ExportGIFPath, "frames", recorder.FrameCount(), "range", app.config.ExportGIFRange.String())
+		return nil
+	}
+
+	return onTick, finish, nil
+}
@@ -0,0 +1,138 @@
+package app
+
+import (
+	"image"
+	"image/gif"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/zurustar/son-et/pkg/cli"
+	"github.com/zurustar/son-et/pkg/compiler"
+	"github.com/zurustar/son-et/pkg/gifexport"
+	"github.com/zurustar/son-et/pkg/logger"
+	"github.com/zurustar/son-et/pkg/title"
+	"github.com/zurustar/son-et/pkg/vm"
+	"github.com/zurustar/son-et/pkg/vm/audio"
+)
+
+func newGIFExportTestApp(t *testing.T, rng gifexport.Range, fps int) *Application {
+	t.Helper()
+	return &Application{
+		config: &cli.Config{
+			ExportGIFPath:  filepath.Join(t.TempDir(), "out.gif"),
+			ExportGIFRange: rng,
+			ExportGIFFPS:   fps,
+		},
+		log:           logger.GetLogger(),
+		selectedTitle: &title.FillyTitle{Path: t.TempDir()},
+	}
+}
+
+// TestGIFExporterTicks tests that frames are chosen by the tick count, not the wall clock.
+func TestGIFExporterTicks(t *testing.T) {
+	tests := []struct {
+		name       string
+		rng        gifexport.Range
+		fps        int
+		wantFrames int
+		wantStopAt int64
+	}{
+		// 0〜0.5秒を20fpsで取り込むと、ティックと同じ間隔の10フレーム
+		{"ティックと同じFPS", gifexport.Range{Start: 0, End: 500 * time.Millisecond}, 20, 10, 10},
+		// 10fpsでは2ティックに1フレーム
+		{"低いFPS", gifexport.Range{Start: 0, End: 500 * time.Millisecond}, 10, 5, 10},
+		// ティックより高いFPSでは同じフレームを繰り返し、再生時間を範囲の長さに揃える
+		{"高いFPS", gifexport.Range{Start: 0, End: 500 * time.Millisecond}, 40, 20, 10},
+		{"途中から", gifexport.Range{Start: 200 * time.Millisecond, End: 400 * time.Millisecond}, 20, 4, 8},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app := newGIFExportTestApp(t, tt.rng, tt.fps)
+			frame := image.NewRGBA(image.Rect(0, 0, 4, 4))
+
+			var stops []int64
+			var tick int64
+			onTick, finish, err := app.newGIFExporter(
+				func() *image.RGBA { return frame },
+				func() { stops = append(stops, tick) },
+			)
+			if err != nil {
+				t.Fatal(err)
+			}
+			// 停止後に届いたティックは無視される
+			for tick = 1; tick <= 20; tick++ {
+				onTick(tick)
+			}
+			if len(stops) != 1 || stops[0] != tt.wantStopAt {
+				t.Errorf("stop called at ticks %v, want once at %d", stops, tt.wantStopAt)
+			}
+
+			if err := finish(); err != nil {
+				t.Fatal(err)
+			}
+			if got := countGIFFrames(t, app.config.ExportGIFPath); got != tt.wantFrames {
+				t.Errorf("frames = %d, want %d", got, tt.wantFrames)
+			}
+		})
+	}
+}
+
+// TestGIFExportDeterministic tests that running a script with the same range gives
+// the same frames on every run.
+func TestGIFExportDeterministic(t *testing.T) {
+	opcodes, errs := compiler.Compile(`
+main() {
+	mes(TIME) {
+		n = n + 1
+	}
+}
+`)
+	if len(errs) > 0 {
+		t.Fatalf("compile failed: %v", errs)
+	}
+
+	rng := gifexport.Range{Start: 0, End: 10 * gifexport.TickDuration}
+	for run := range 2 {
+		app := newGIFExportTestApp(t, rng, 20)
+		vmInstance := vm.New(opcodes, vm.WithHeadless(true), vm.WithTimeout(5*time.Second))
+		finish, shutdown, err := app.attachGIFExporter(vmInstance)
+		if err != nil {
+			t.Fatal(err)
+		}
+		// TIMEイベントは実時間で発生させ、フレームの選び方が壁時計に左右されないことを確かめる
+		timer := audio.NewTimer(audio.DefaultTimerInterval, vmInstance.GetEventQueue())
+		timer.Start()
+		err = vmInstance.Run()
+		timer.Stop()
+		shutdown()
+		if err != nil {
+			t.Fatalf("run %d: %v", run, err)
+		}
+		if vmInstance.TimedOut() {
+			t.Fatalf("run %d: the export did not stop the VM at the end of the range", run)
+		}
+		if err := finish(); err != nil {
+			t.Fatalf("run %d: %v", run, err)
+		}
+		if got := countGIFFrames(t, app.config.ExportGIFPath); got != 10 {
+			t.Errorf("run %d: frames = %d, want 10", run, got)
+		}
+	}
+}
+
+// countGIFFrames は書き出したGIFのフレーム数を返す
+func countGIFFrames(t *testing.T, path string) int {
+	t.Helper()
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	g, err := gif.DecodeAll(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return len(g.Image)
+}
@@ -0,0 +1,42 @@
+package app
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/zurustar/son-et/pkg/logger"
+	"github.com/zurustar/son-et/pkg/titleinfo"
+)
+
+// runInfo はタイトルを実行せずに読み込み、情報を表示する（son-et info）。
+//
+// 結果は標準出力に書き出し（--json ではJSON）、見つからないアセットの警告は
+// 結果と混ざらないよう標準エラー出力のログに書き込む。
+// アセットが見つからなくても、目録作成を続けられるよう失敗にはしない。
+func (app *Application) runInfo() error {
+	if err := logger.InitLoggerWithWriter(app.config.LogLevel, os.Stderr); err != nil {
+		return fmt.Errorf("failed to initialize logger: %w", err)
+	}
+	app.log = logger.GetLogger()
+
+	info, err := titleinfo.Inspect(app.config.TitlePath, app.config.EntryFile, app.config.Compat)
+	if err != nil {
+		return fmt.Errorf("info: %w", err)
+	}
+	if info.CompileError != "" {
+		app.log.Warn("Failed to compile the title", "error", info.CompileError)
+	}
+	for _, a := range info.Assets {
+		if a.Missing {
+			app.log.Warn("Asset not found", "kind", a.Kind, "path", a.Path)
+		}
+	}
+
+	if app.config.InfoJSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(info)
+	}
+	return info.WriteText(os.Stdout)
+}
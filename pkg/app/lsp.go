@@ -0,0 +1,28 @@
+package app
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/zurustar/son-et/pkg/logger"
+	"github.com/zurustar/son-et/pkg/lsp"
+)
+
+// runLSP はstdio上でLanguage Server Protocolのサーバーを実行する（son-et lsp）。
+//
+// 標準出力はプロトコルに使用するため、ログは標準エラー出力に書き込む。
+// タイトルの読み込みや仮想デスクトップの起動は行わない。
+func (app *Application) runLSP() error {
+	if err := logger.InitLoggerWithWriter(app.config.LogLevel, os.Stderr); err != nil {
+		return fmt.Errorf("failed to initialize logger: %w", err)
+	}
+	app.log = logger.GetLogger()
+	app.log.Info("LSP server started")
+
+	if err := lsp.NewServer(os.Stdin, os.Stdout, lsp.WithLogger(app.log)).Run(); err != nil {
+		return fmt.Errorf("lsp server: %w", err)
+	}
+
+	app.log.Info("LSP server terminated normally")
+	return nil
+}
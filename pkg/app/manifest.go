@@ -0,0 +1,83 @@
+package app
+
+import (
+	"maps"
+
+	"github.com/zurustar/son-et/pkg/title"
+	"github.com/zurustar/son-et/pkg/vm/audio"
+	"github.com/zurustar/son-et/pkg/window"
+)
+
+// applyManifest はタイトルのプロジェクトマニフェスト（project.yaml / project.json）の設定を適用する
+// 互換モードは --compat が指定されていない場合にだけマニフェストの値を使う
+func (app *Application) applyManifest(t *title.FillyTitle) {
+	if t == nil || t.Manifest == nil {
+		return
+	}
+	m := t.Manifest
+	app.log.Info("Project manifest loaded", "path", m.Path, "entry", m.Entry, "soundfont", m.SoundFont, "resolution", m.Resolution, "compat", m.Compat, "assets", m.Assets, "midiRemap", m.MIDIRemap)
+	if !app.config.CompatSet {
+		app.config.Compat = m.CompatMode
+	}
+}
+
+// findTitleSoundFont はタイトルの SoundFont を探す
+// プロジェクトマニフェストで soundfont が指定されている場合は検索せずにそれを使う。
+// どこにも見つからない場合はダウンロード済みのものを使う（--fetch-soundfont の場合はダウンロードする）
+func (app *Application) findTitleSoundFont(t *title.FillyTitle) *SoundFontLocation {
+	if t.Manifest != nil && t.Manifest.SoundFont != "" {
+		return &SoundFontLocation{Path: t.Manifest.SoundFont}
+	}
+	if loc := findSoundFont(app.embedFS, t.Path, t.IsEmbedded); loc != nil {
+		return loc
+	}
+	return app.findDownloadedSoundFont()
+}
+
+// applyMIDIRemap はプロジェクトマニフェストの midi_remap（MIDIの音色・バンク・ドラムの音の置き換え）を設定する
+func applyMIDIRemap(audioSys *audio.AudioSystem, t *title.FillyTitle) {
+	if t == nil || t.Manifest == nil || t.Manifest.MIDIRemaps == nil {
+		return
+	}
+	table := t.Manifest.MIDIRemaps
+	audioSys.SetMIDIRemap(&audio.MIDIRemap{
+		Programs:  table.Programs,
+		Banks:     table.Banks,
+		DrumNotes: table.DrumNotes,
+	})
+}
+
+// titleAssetDirs はプロジェクトマニフェストの assets（画像・音声を探すディレクトリ）を返す
+func titleAssetDirs(t *title.FillyTitle) []string {
+	if t == nil || t.Manifest == nil {
+		return nil
+	}
+	return t.Manifest.Assets
+}
+
+// titleAssetVars はアセットのファイル名に展開する変数を返す
+// プロジェクトマニフェストの vars に、コマンドラインの -A の値を上書きしたもの
+func (app *Application) titleAssetVars(t *title.FillyTitle) map[string]string {
+	vars := make(map[string]string)
+	if t != nil && t.Manifest != nil {
+		maps.Copy(vars, t.Manifest.AssetVars)
+	}
+	maps.Copy(vars, app.config.AssetVars)
+	return vars
+}
+
+// titleWindowSize はウィンドウの大きさを返す（プロジェクトマニフェストの resolution、なければ既定値）
+func titleWindowSize(t *title.FillyTitle) (int, int) {
+	if t != nil && t.Manifest != nil && t.Manifest.Width > 0 {
+		return t.Manifest.Width, t.Manifest.Height
+	}
+	return window.DefaultWidth, window.DefaultHeight
+}
+
+// titleWindowTitle はウィンドウのタイトルを返す（プロジェクトマニフェストの title、なければ既定値）
+func titleWindowTitle(t *title.FillyTitle) string {
+	if t != nil && t.Manifest != nil && t.Manifest.Title != "" {
+		return t.Manifest.Title
+	}
+	return window.DefaultTitle
+}
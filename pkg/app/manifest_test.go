@@ -0,0 +1,75 @@
+package app
+
+import (
+	"testing"
+
+	"github.com/zurustar/son-et/pkg/cli"
+	"github.com/zurustar/son-et/pkg/compat"
+	"github.com/zurustar/son-et/pkg/logger"
+	"github.com/zurustar/son-et/pkg/title"
+	"github.com/zurustar/son-et/pkg/window"
+)
+
+func TestApplyManifest_Compat(t *testing.T) {
+	manifested := &title.FillyTitle{Manifest: &title.Manifest{Compat: "filly97", CompatMode: compat.FILLY97}}
+
+	app := &Application{config: &cli.Config{}, log: logger.GetLogger()}
+	app.applyManifest(manifested)
+	if app.config.Compat != compat.FILLY97 {
+		t.Errorf("Compat = %v, want filly97 from the manifest", app.config.Compat)
+	}
+
+	// --compat が指定されている場合はマニフェストより優先される
+	app = &Application{config: &cli.Config{Compat: compat.Extended, CompatSet: true}, log: logger.GetLogger()}
+	app.applyManifest(manifested)
+	if app.config.Compat != compat.Extended {
+		t.Errorf("Compat = %v, want extended from --compat", app.config.Compat)
+	}
+}
+
+func TestFindTitleSoundFont_Manifest(t *testing.T) {
+	app := &Application{config: &cli.Config{}}
+	tl := &title.FillyTitle{Path: t.TempDir(), Manifest: &title.Manifest{SoundFont: "/titles/demo/sound/GM.sf2"}}
+	loc := app.findTitleSoundFont(tl)
+	if loc == nil || loc.Path != "/titles/demo/sound/GM.sf2" || loc.IsEmbedded {
+		t.Errorf("findTitleSoundFont = %+v, want the manifest soundfont", loc)
+	}
+}
+
+func TestTitleWindowSettings(t *testing.T) {
+	if w, h := titleWindowSize(&title.FillyTitle{}); w != window.DefaultWidth || h != window.DefaultHeight {
+		t.Errorf("titleWindowSize without manifest = %dx%d, want default", w, h)
+	}
+	if got := titleWindowTitle(nil); got != window.DefaultTitle {
+		t.Errorf("titleWindowTitle(nil) = %q, want default", got)
+	}
+
+	tl := &title.FillyTitle{Manifest: &title.Manifest{Title: "Demo", Width: 640, Height: 480, Assets: []string{"pics"}}}
+	if w, h := titleWindowSize(tl); w != 640 || h != 480 {
+		t.Errorf("titleWindowSize = %dx%d, want 640x480", w, h)
+	}
+	if got := titleWindowTitle(tl); got != "Demo" {
+		t.Errorf("titleWindowTitle = %q, want Demo", got)
+	}
+	if got := titleAssetDirs(tl); len(got) != 1 || got[0] != "pics" {
+		t.Errorf("titleAssetDirs = %v, want [pics]", got)
+	}
+}
+
+func TestTitleAssetVars(t *testing.T) {
+	tl := &title.FillyTitle{Manifest: &title.Manifest{AssetVars: map[string]string{"ASSETS": "orig", "SE": "wav"}}}
+
+	app := &Application{config: &cli.Config{AssetVars: map[string]string{"ASSETS": "hires"}}}
+	got := app.titleAssetVars(tl)
+	if got["ASSETS"] != "hires" || got["SE"] != "wav" {
+		t.Errorf("titleAssetVars = %v, want -A to override the manifest", got)
+	}
+	if tl.Manifest.AssetVars["ASSETS"] != "orig" {
+		t.Error("titleAssetVars modified the manifest")
+	}
+
+	app = &Application{config: &cli.Config{}}
+	if got := app.titleAssetVars(&title.FillyTitle{}); len(got) != 0 {
+		t.Errorf("titleAssetVars without vars = %v, want empty", got)
+	}
+}
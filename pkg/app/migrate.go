@@ -0,0 +1,104 @@
+package app
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/zurustar/son-et/pkg/compiler/lexer"
+	"github.com/zurustar/son-et/pkg/compiler/migrate"
+	"github.com/zurustar/son-et/pkg/compiler/parser"
+	"github.com/zurustar/son-et/pkg/logger"
+)
+
+// backupSuffix は son-et migrate が書き換える前のファイルを残すときの拡張子
+const backupSuffix = ".bak"
+
+// runMigrate はタイトルのTFYファイルの古い書き方を書き換える（son-et migrate）。
+//
+// 書き換えの差分を unified diff 形式で標準出力に書き出し、元のファイルを .bak として
+// 残してから書き換える。--dry-run では差分を表示するだけでファイルは変更しない。
+// ログは標準出力の差分と混ざらないよう標準エラー出力に書き込む。
+func (app *Application) runMigrate() error {
+	if err := logger.InitLoggerWithWriter(app.config.LogLevel, os.Stderr); err != nil {
+		return fmt.Errorf("failed to initialize logger: %w", err)
+	}
+	app.log = logger.GetLogger()
+
+	files, err := collectScriptFiles(app.config.MigratePaths)
+	if err != nil {
+		return err
+	}
+
+	var migrated, failed int
+	for _, path := range files {
+		changed, err := app.migrateFile(path)
+		if err != nil {
+			app.log.Error("Failed to migrate file", "path", path, "error", err)
+			failed++
+			continue
+		}
+		if changed {
+			migrated++
+		}
+	}
+
+	app.log.Info("Migration finished", "files", len(files), "changed", migrated, "dryRun", app.config.MigrateDryRun)
+	if failed > 0 {
+		return fmt.Errorf("migrate: %d file(s) could not be migrated", failed)
+	}
+	return nil
+}
+
+// migrateFile は1つのファイルを書き換え、内容が変わったかを返す
+func (app *Application) migrateFile(path string) (bool, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return false, err
+	}
+	source, sjis, err := decodeScript(data)
+	if err != nil {
+		return false, err
+	}
+
+	result, changes := migrate.Migrate(source)
+	if len(changes) == 0 {
+		return false, nil
+	}
+	for _, c := range changes {
+		app.log.Debug("Migration change", "path", path, "line", c.Line, "rule", c.Rule, "old", c.Old, "new", c.New)
+	}
+	if _, err := os.Stdout.WriteString(migrate.UnifiedDiff(path, source, result)); err != nil {
+		return false, err
+	}
+
+	// 自動で直せない書き方が残っている場合は知らせる（書き換えは行う）
+	if _, errs := parser.New(lexer.New(result)).ParseProgram(); len(errs) > 0 {
+		app.log.Warn("The script still has syntax errors after migration", "path", path, "error", errs[0])
+	}
+
+	if app.config.MigrateDryRun {
+		return true, nil
+	}
+
+	out, err := encodeScript(result, sjis)
+	if err != nil {
+		return false, err
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return false, err
+	}
+	// 以前の実行で残したバックアップ（元のファイル）を上書きしない
+	backup := path + backupSuffix
+	if _, err := os.Stat(backup); err == nil {
+		return false, fmt.Errorf("backup file %s already exists", backup)
+	}
+	if err := os.WriteFile(backup, data, info.Mode().Perm()); err != nil {
+		return false, err
+	}
+	if err := os.WriteFile(path, out, info.Mode().Perm()); err != nil {
+		return false, err
+	}
+	app.log.Info("Migrated", "path", path, "changes", len(changes), "backup", backup)
+	return true, nil
+}
@@ -0,0 +1,78 @@
+package app
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"golang.org/x/text/encoding/japanese"
+
+	"github.com/zurustar/son-et/pkg/cli"
+	"github.com/zurustar/son-et/pkg/logger"
+)
+
+func TestMigrateFile(t *testing.T) {
+	const legacy = "main() {\r\n　PlayWAV(\"効果音.WAV\")\r\n}\r\n"
+	const want = "main() {\r\n  PlayWAVE(\"効果音.WAV\")\r\n}\r\n"
+	data, err := japanese.ShiftJIS.NewEncoder().String(legacy)
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "MAIN.TFY")
+	if err := os.WriteFile(path, []byte(data), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	// --dry-run ではファイルを変更しない
+	app := &Application{config: &cli.Config{MigrateDryRun: true}, log: logger.GetLogger()}
+	changed, err := app.migrateFile(path)
+	if err != nil || !changed {
+		t.Fatalf("migrateFile (dry run) = %v, %v; want true, nil", changed, err)
+	}
+	if got, _ := os.ReadFile(path); string(got) != data {
+		t.Error("dry run should not modify the file")
+	}
+	if _, err := os.Stat(path + backupSuffix); !os.IsNotExist(err) {
+		t.Error("dry run should not write a backup")
+	}
+
+	// 書き換えはShift-JISのまま行い、元のファイルを .bak に残す
+	app.config.MigrateDryRun = false
+	if changed, err := app.migrateFile(path); err != nil || !changed {
+		t.Fatalf("migrateFile = %v, %v; want true, nil", changed, err)
+	}
+	got, _ := os.ReadFile(path)
+	decoded, sjis, err := decodeScript(got)
+	if err != nil || !sjis || decoded != want {
+		t.Errorf("migrated file = %q (sjis %v, err %v), want %q", decoded, sjis, err, want)
+	}
+	if backup, _ := os.ReadFile(path + backupSuffix); string(backup) != data {
+		t.Error("backup should hold the original file")
+	}
+
+	// 書き換え済みのファイルは変更しない
+	if changed, err := app.migrateFile(path); err != nil || changed {
+		t.Errorf("migrateFile on a migrated file = %v, %v; want false, nil", changed, err)
+	}
+}
+
+func TestMigrateFileKeepsExistingBackup(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "MAIN.TFY")
+	if err := os.WriteFile(path, []byte("PlayMID(\"A.MID\")\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path+backupSuffix, []byte("original"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	app := &Application{config: &cli.Config{}, log: logger.GetLogger()}
+	if _, err := app.migrateFile(path); err == nil {
+		t.Error("expected error when a backup already exists")
+	}
+	if backup, _ := os.ReadFile(path + backupSuffix); string(backup) != "original" {
+		t.Error("the existing backup should not be overwritten")
+	}
+	if got, _ := os.ReadFile(path); string(got) != "PlayMID(\"A.MID\")\n" {
+		t.Error("the file should not be modified when the backup cannot be written")
+	}
+}
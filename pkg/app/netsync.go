@@ -0,0 +1,47 @@
+package app
+
+import (
+	"fmt"
+
+	"github.com/zurustar/son-et/pkg/netsync"
+	"github.com/zurustar/son-et/pkg/vm/audio"
+)
+
+// startSync は --sync-master または --sync-follow の時刻同期を開始する
+// タイトル選択画面から起動したタイトルにも同じ共有時計を使う
+func (app *Application) startSync() error {
+	switch {
+	case app.config.SyncMasterAddr != "":
+		master, err := netsync.Listen(app.config.SyncMasterAddr, netsync.WithLogger(app.log))
+		if err != nil {
+			return fmt.Errorf("failed to start sync master: %w", err)
+		}
+		app.syncClock, app.syncCloser = master, master
+	case app.config.SyncFollowAddr != "":
+		follower, err := netsync.Follow(app.config.SyncFollowAddr, netsync.WithLogger(app.log))
+		if err != nil {
+			return fmt.Errorf("failed to follow sync master: %w", err)
+		}
+		app.syncClock, app.syncCloser = follower, follower
+	}
+	return nil
+}
+
+// stopSync は時刻同期を終了する
+func (app *Application) stopSync() {
+	if app.syncCloser == nil {
+		return
+	}
+	if err := app.syncCloser.Close(); err != nil {
+		app.log.Debug("Failed to stop clock sync", "error", err)
+	}
+	app.syncClock, app.syncCloser = nil, nil
+}
+
+// applySyncClock はオーディオシステムのTIMEタイマーを共有時計に合わせる（時刻同期を使わない場合は何もしない）
+func (app *Application) applySyncClock(audioSys *audio.AudioSystem) {
+	if app.syncClock == nil {
+		return
+	}
+	audioSys.SetSyncClock(app.syncClock)
+}
@@ -0,0 +1,39 @@
+package app
+
+import (
+	"testing"
+
+	"github.com/zurustar/son-et/pkg/cli"
+	"github.com/zurustar/son-et/pkg/logger"
+	"github.com/zurustar/son-et/pkg/netsync"
+)
+
+func TestStartSync(t *testing.T) {
+	// 時刻同期を指定しない場合は何もしない
+	app := &Application{config: &cli.Config{}, log: logger.GetLogger()}
+	if err := app.startSync(); err != nil || app.syncClock != nil {
+		t.Fatalf("startSync without flags: clock = %v, err = %v", app.syncClock, err)
+	}
+
+	master := &Application{config: &cli.Config{SyncMasterAddr: "127.0.0.1:0"}, log: logger.GetLogger()}
+	if err := master.startSync(); err != nil {
+		t.Fatalf("startSync as master failed: %v", err)
+	}
+	defer master.stopSync()
+	if _, ok := master.syncClock.Elapsed(); !ok {
+		t.Error("master clock should always be synchronized")
+	}
+
+	addr := master.syncClock.(*netsync.Master).Addr().String()
+	follower := &Application{config: &cli.Config{SyncFollowAddr: addr}, log: logger.GetLogger()}
+	if err := follower.startSync(); err != nil {
+		t.Fatalf("startSync as follower failed: %v", err)
+	}
+	if _, ok := follower.syncClock.(*netsync.Follower); !ok {
+		t.Errorf("follower clock = %T, want *netsync.Follower", follower.syncClock)
+	}
+	follower.stopSync()
+	if follower.syncClock != nil || follower.syncCloser != nil {
+		t.Error("stopSync should clear the clock")
+	}
+}
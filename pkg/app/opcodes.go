@@ -0,0 +1,20 @@
+package app
+
+import (
+	"encoding/json"
+	"os"
+
+	"github.com/zurustar/son-et/pkg/opcodeinfo"
+)
+
+// runOpcodes はVMの命令セットの一覧を標準出力に書き出す（son-et opcodes）。
+// --json ではJSON、それ以外はMarkdownの表で書き出す。
+func (app *Application) runOpcodes() error {
+	info := opcodeinfo.Collect()
+	if app.config.OpcodesJSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(info)
+	}
+	return info.WriteMarkdown(os.Stdout)
+}
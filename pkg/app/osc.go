@@ -0,0 +1,43 @@
+package app
+
+import (
+	"fmt"
+
+	"github.com/zurustar/son-et/pkg/osc"
+	"github.com/zurustar/son-et/pkg/vm"
+)
+
+// startOSC は --osc-allow の送信先へのOSC出力を準備する（指定がない場合は何もしない）
+// タイトル選択画面から起動したタイトルにも同じ送信先と送信の制限を使う
+func (app *Application) startOSC() error {
+	if len(app.config.OSCAllow) == 0 {
+		return nil
+	}
+	sender, err := osc.NewSender(app.config.OSCAllow)
+	if err != nil {
+		return fmt.Errorf("failed to start OSC output: %w", err)
+	}
+	app.oscSender = sender
+	app.log.Info("OSC output enabled", "destinations", sender.Targets(), "ratePerSecond", osc.DefaultRate)
+	return nil
+}
+
+// stopOSC はOSCの送信先への接続を閉じる
+func (app *Application) stopOSC() {
+	if app.oscSender == nil {
+		return
+	}
+	if err := app.oscSender.Close(); err != nil {
+		app.log.Debug("Failed to close OSC output", "error", err)
+	}
+	app.oscSender = nil
+}
+
+// oscOption は OSCSend の送信に使う VM のオプションを返す
+func (app *Application) oscOption() vm.Option {
+	if app.oscSender == nil {
+		// nil の *osc.Sender をインターフェースに入れると OSCSend が無効と判定できないため
+		return vm.WithOSCSender(nil)
+	}
+	return vm.WithOSCSender(app.oscSender)
+}
@@ -0,0 +1,28 @@
+package app
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/zurustar/son-et/pkg/cli"
+	"github.com/zurustar/son-et/pkg/logger"
+)
+
+func TestStartOSC(t *testing.T) {
+	app := &Application{config: &cli.Config{}, log: logger.GetLogger()}
+	if err := app.startOSC(); err != nil || app.oscSender != nil {
+		t.Fatalf("startOSC without --osc-allow: sender = %v, err = %v", app.oscSender, err)
+	}
+
+	app.config.OSCAllow = []string{"127.0.0.1:9000", "127.0.0.1:9001"}
+	if err := app.startOSC(); err != nil {
+		t.Fatalf("startOSC failed: %v", err)
+	}
+	if got := app.oscSender.Targets(); !reflect.DeepEqual(got, app.config.OSCAllow) {
+		t.Errorf("Targets() = %v, want %v", got, app.config.OSCAllow)
+	}
+	app.stopOSC()
+	if app.oscSender != nil {
+		t.Error("stopOSC should clear the sender")
+	}
+}
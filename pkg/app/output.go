@@ -0,0 +1,22 @@
+package app
+
+import (
+	"path/filepath"
+
+	"github.com/zurustar/son-et/pkg/title"
+)
+
+// defaultOutputDir は --output-dir を省略した場合に SaveSprite が書き出す、タイトルディレクトリ内のディレクトリ
+const defaultOutputDir = "output"
+
+// outputDir は SaveSprite が画像を書き出すディレクトリを返す
+// 埋め込みタイトルはタイトルディレクトリがないため、--output-dir を指定しない限り書き出さない（空を返す）
+func (app *Application) outputDir(t *title.FillyTitle) string {
+	if app.config.OutputDir != "" {
+		return app.config.OutputDir
+	}
+	if t.IsEmbedded || t.Path == "" {
+		return ""
+	}
+	return filepath.Join(t.Path, defaultOutputDir)
+}
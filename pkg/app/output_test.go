@@ -0,0 +1,26 @@
+package app
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/zurustar/son-et/pkg/cli"
+	"github.com/zurustar/son-et/pkg/title"
+)
+
+func TestOutputDir(t *testing.T) {
+	app := &Application{config: &cli.Config{}}
+	dir := t.TempDir()
+	if got := app.outputDir(&title.FillyTitle{Path: dir}); got != filepath.Join(dir, "output") {
+		t.Errorf("outputDir = %q, want the output directory in the title", got)
+	}
+	if got := app.outputDir(&title.FillyTitle{IsEmbedded: true}); got != "" {
+		t.Errorf("outputDir for an embedded title = %q, want none", got)
+	}
+
+	// --output-dir はタイトルの種類によらず優先される
+	app = &Application{config: &cli.Config{OutputDir: "/tmp/renders"}}
+	if got := app.outputDir(&title.FillyTitle{IsEmbedded: true}); got != "/tmp/renders" {
+		t.Errorf("outputDir = %q, want --output-dir", got)
+	}
+}
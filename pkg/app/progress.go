@@ -0,0 +1,137 @@
+package app
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/zurustar/son-et/pkg/vm"
+)
+
+// 進み具合の出力（--progress）
+// ヘッドレスモードで、実行の進み具合を1行1レコードのJSON（NDJSON）で一定間隔ごとに書き出す。
+// CIのテストハーネスは、ログの文章を解析せずに長時間の実行を監視し、ティックや最後に実行した文が
+// 進まなくなった実行を見分けて終了できる。実行が終わると state が running 以外の最後のレコードを書く。
+//
+//	{"state":"running","tick":120,"elapsed":2.01,"midi_tick":480,"sequences":3,"last_sequence":2,"last_opcode":"MovePic(1, 0, 0, 640, 480, 2, 0, 0)"}
+
+// progressFDPrefix はファイルではなくファイルディスクリプタに書き出す出力先の接頭辞（fd:3 など）
+const progressFDPrefix = "fd:"
+
+// 最後のレコードの state
+const (
+	progressRunning   = "running"
+	progressCompleted = "completed" // 正常終了（--exit-after-ticks に達した場合を含む）
+	progressTimeout   = "timeout"
+	progressError     = "error"
+)
+
+// progressRecord は進み具合の1つのレコード
+type progressRecord struct {
+	State        string  `json:"state"`
+	Tick         int64   `json:"tick"`
+	Elapsed      float64 `json:"elapsed"` // 実行を始めてからの秒数
+	MIDITick     int     `json:"midi_tick"`
+	Sequences    int     `json:"sequences"`
+	LastSequence int     `json:"last_sequence"`
+	LastOpcode   string  `json:"last_opcode,omitempty"`
+}
+
+// progressReporter はVMの進み具合を一定間隔ごとに書き出す
+type progressReporter struct {
+	vm       *vm.VM
+	enc      *json.Encoder
+	out      io.Closer // nil の場合は閉じない（標準出力・標準エラー出力）
+	started  time.Time
+	interval time.Duration
+
+	stop chan struct{}
+	done chan struct{} // 書き出すゴルーチンが終わると閉じる
+}
+
+// openProgressOutput は進み具合の出力先を開く
+// dest はファイルのパス、または "fd:N"（親プロセスから受け継いだファイルディスクリプタ）
+func openProgressOutput(dest string) (io.Writer, io.Closer, error) {
+	fdText, isFD := strings.CutPrefix(dest, progressFDPrefix)
+	if !isFD {
+		f, err := os.Create(dest)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to create progress file: %w", err)
+		}
+		return f, f, nil
+	}
+	fd, err := strconv.Atoi(fdText)
+	if err != nil || fd < 0 {
+		return nil, nil, fmt.Errorf("invalid progress file descriptor: %q", dest)
+	}
+	switch fd {
+	case 1:
+		return os.Stdout, nil, nil
+	case 2:
+		return os.Stderr, nil, nil
+	}
+	f := os.NewFile(uintptr(fd), dest)
+	if f == nil {
+		return nil, nil, fmt.Errorf("invalid progress file descriptor: %q", dest)
+	}
+	return f, f, nil
+}
+
+// newProgressReporter は w に書き出す進み具合の出力を作る（start で書き出しを始める）
+func newProgressReporter(v *vm.VM, w io.Writer, closer io.Closer, interval time.Duration) *progressReporter {
+	return &progressReporter{
+		vm:       v,
+		enc:      json.NewEncoder(w),
+		out:      closer,
+		interval: interval,
+		stop:     make(chan struct{}),
+		done:     make(chan struct{}),
+	}
+}
+
+// start は interval ごとにレコードを書き出し始める
+func (r *progressReporter) start() {
+	r.started = time.Now()
+	go func() {
+		defer close(r.done)
+		ticker := time.NewTicker(r.interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				r.write(progressRunning)
+			case <-r.stop:
+				return
+			}
+		}
+	}()
+}
+
+// finish は書き出しを止め、state の最後のレコードを書いて出力先を閉じる
+func (r *progressReporter) finish(state string) {
+	close(r.stop)
+	<-r.done
+	r.write(state)
+	if r.out != nil {
+		r.out.Close()
+	}
+}
+
+// write はVMの進み具合を1行のレコードとして書き出す
+// 書き出しの失敗（ハーネスがパイプを閉じたなど）は実行に影響させない
+func (r *progressReporter) write(state string) {
+	p := r.vm.Progress()
+	r.enc.Encode(progressRecord{
+		State:        state,
+		Tick:         p.Ticks,
+		Elapsed:      time.Since(r.started).Round(time.Millisecond).Seconds(),
+		MIDITick:     p.MIDITick,
+		Sequences:    p.Sequences,
+		LastSequence: p.LastSequence,
+		LastOpcode:   p.LastStatement,
+	})
+}
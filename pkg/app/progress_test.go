@@ -0,0 +1,71 @@
+package app
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/zurustar/son-et/pkg/opcode"
+	"github.com/zurustar/son-et/pkg/vm"
+)
+
+// TestProgressReporter tests that records are written as NDJSON and the last one has the final state.
+func TestProgressReporter(t *testing.T) {
+	var buf bytes.Buffer
+	r := newProgressReporter(vm.New([]opcode.OpCode{}), &buf, nil, time.Millisecond)
+	r.start()
+	time.Sleep(20 * time.Millisecond)
+	r.finish(progressTimeout)
+
+	lines := strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+	if len(lines) < 2 {
+		t.Fatalf("expected running records and a final record, got %q", buf.String())
+	}
+	var records []progressRecord
+	for _, line := range lines {
+		var rec progressRecord
+		if err := json.Unmarshal([]byte(line), &rec); err != nil {
+			t.Fatalf("invalid JSON line %q: %v", line, err)
+		}
+		records = append(records, rec)
+	}
+	for _, rec := range records[:len(records)-1] {
+		if rec.State != progressRunning {
+			t.Errorf("state = %q, want running", rec.State)
+		}
+	}
+	last := records[len(records)-1]
+	if last.State != progressTimeout || last.MIDITick != -1 || last.Tick != 0 {
+		t.Errorf("last record = %+v, want timeout with no ticks", last)
+	}
+	if !strings.Contains(lines[0], `"midi_tick":-1`) || strings.Contains(lines[0], "last_opcode") {
+		t.Errorf("unexpected record format: %s", lines[0])
+	}
+}
+
+// TestOpenProgressOutput tests the file and file descriptor destinations.
+func TestOpenProgressOutput(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "progress.jsonl")
+	w, closer, err := openProgressOutput(path)
+	if err != nil {
+		t.Fatalf("openProgressOutput(file) failed: %v", err)
+	}
+	w.Write([]byte("{}\n"))
+	closer.Close()
+	if data, _ := os.ReadFile(path); string(data) != "{}\n" {
+		t.Errorf("file content = %q", data)
+	}
+
+	if w, closer, err := openProgressOutput("fd:2"); err != nil || w != os.Stderr || closer != nil {
+		t.Errorf("fd:2 = (%v, %v, %v), want stderr without a closer", w, closer, err)
+	}
+	for _, dest := range []string{"fd:x", "fd:-1", filepath.Join(t.TempDir(), "missing", "p.jsonl")} {
+		if _, _, err := openProgressOutput(dest); err == nil {
+			t.Errorf("openProgressOutput(%q): expected an error", dest)
+		}
+	}
+}
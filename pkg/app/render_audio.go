@@ -0,0 +1,142 @@
+package app
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/zurustar/son-et/pkg/fileutil"
+	"github.com/zurustar/son-et/pkg/graphics"
+	"github.com/zurustar/son-et/pkg/vm"
+	"github.com/zurustar/son-et/pkg/vm/audio"
+)
+
+// renderAudioWaitTimeout はタイムアウト未指定時に、スクリプトがPlayMIDIを呼び出すまで待つ最大時間
+const renderAudioWaitTimeout = 30 * time.Second
+
+// renderAudioProgressInterval は進捗をログに出力する間隔（レンダリング済みの音声の長さ）
+const renderAudioProgressInterval = 10 * time.Second
+
+// renderAudio はタイトルが演奏するMIDIをオフラインでレンダリングし、WAVファイルに書き出す（--render-audio）。
+//
+// VMをヘッドレス・ミュートで実行し、スクリプトが最初にPlayMIDIを呼び出した時点でVMを停止する。
+// その後、呼び出されたMIDIファイル全体を実時間より速く合成して書き出す。
+// 書き出したWAVはMIDIの先頭から始まるため、単体でも動画と合わせても使用できる。
+func (app *Application) renderAudio() error {
+	app.log.Info("Rendering audio offline", "output", app.config.RenderAudioPath)
+
+	if app.soundFontLocation == nil {
+		app.soundFontLocation = app.findTitleSoundFont(app.selectedTitle)
+	}
+	if app.soundFontLocation == nil {
+		return audio.ErrNoSoundFont
+	}
+	app.soundFontPath = app.soundFontLocation.Path
+
+	vmInstance := vm.New(app.opcodes,
+		vm.WithHeadless(true),
+		vm.WithLogger(app.log),
+		vm.WithTitlePath(app.selectedTitle.Path),
+		vm.WithSoundFont(app.soundFontPath),
+		vm.WithSandbox(app.sandboxEnabled(app.selectedTitle)),
+		vm.WithAssetDirs(titleAssetDirs(app.selectedTitle)...),
+		vm.WithAssetVars(app.titleAssetVars(app.selectedTitle)),
+	)
+	app.reportDiagnostics(vmInstance)
+
+	audioSys, err := audio.NewAudioSystemWithOutput(
+		app.soundFontLocation.Path,
+		vmInstance.GetEventQueue(),
+		audio.NewDefaultOutput(),
+		app.soundFontLocation.FileSystem,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to initialize audio system: %w", err)
+	}
+	if app.selectedTitle.IsEmbedded {
+		audioSys.SetFileSystem(fileutil.NewEmbedFS(app.embedFS, app.selectedTitle.Path))
+	}
+	audioSys.SetMIDIStrict(app.config.MIDIStrict)
+	app.applySynthQuality(audioSys)
+	applyMIDIRemap(audioSys, app.selectedTitle)
+
+	// 最初にPlayMIDIで指定されたファイルをレンダリング対象とする
+	// （ヘッドレスモードのVMは音声をミュートするため、実際の再生音は出力されない）
+	midiCh := make(chan string, 1)
+	audioSys.SetOnPlayMIDI(func(filename string) {
+		select {
+		case midiCh <- filename:
+		default:
+		}
+	})
+	vmInstance.SetAudioSystem(audioSys)
+	defer vmInstance.ShutdownAudio()
+
+	headlessGS := graphics.NewHeadlessGraphicsSystem(
+		graphics.WithHeadlessLogger(app.log),
+		graphics.WithHeadlessSandbox(app.sandboxEnabled(app.selectedTitle)),
+	)
+	vmInstance.SetGraphicsSystem(headlessGS)
+	defer headlessGS.Shutdown()
+
+	wait := renderAudioWaitTimeout
+	if app.config.Timeout > 0 {
+		wait = app.config.Timeout
+	}
+
+	vmErrCh := make(chan error, 1)
+	go func() {
+		vmErrCh <- vmInstance.Run()
+	}()
+
+	var midiFile string
+	select {
+	case midiFile = <-midiCh:
+	case err := <-vmErrCh:
+		if err != nil {
+			return fmt.Errorf("VM execution failed before PlayMIDI: %w", err)
+		}
+		return fmt.Errorf("script finished without calling PlayMIDI")
+	case <-time.After(wait):
+		vmInstance.Stop()
+		return fmt.Errorf("script did not call PlayMIDI within %v", wait)
+	}
+	vmInstance.Stop()
+	audioSys.StopMIDI()
+
+	app.log.Info("Rendering MIDI", "file", midiFile)
+
+	out, err := os.Create(app.config.RenderAudioPath)
+	if err != nil {
+		return fmt.Errorf("failed to create %s: %w", app.config.RenderAudioPath, err)
+	}
+
+	start := time.Now()
+	nextReport := renderAudioProgressInterval
+	length, err := audioSys.RenderMIDI(midiFile, out, func(fillyTick int, rendered, total time.Duration) {
+		if rendered >= nextReport {
+			nextReport += renderAudioProgressInterval
+			app.log.Info("Rendering progress", "rendered", rendered.Round(time.Second), "total", total.Round(time.Second), "tick", fillyTick)
+		}
+	})
+	if err != nil {
+		out.Close()
+		return fmt.Errorf("failed to render %s: %w", midiFile, err)
+	}
+	if err := out.Close(); err != nil {
+		return fmt.Errorf("failed to write %s: %w", app.config.RenderAudioPath, err)
+	}
+
+	app.log.Info("Audio rendered", "output", app.config.RenderAudioPath, "length", length.Round(time.Millisecond), "elapsed", time.Since(start).Round(time.Millisecond))
+	return nil
+}
+
+// renderExitCode はオフラインレンダリングのエラーの終了コードを返す
+// SoundFont やMIDIファイルがない場合は ExitAssetMissing となる
+func renderExitCode(err error) int {
+	if errors.Is(err, audio.ErrNoSoundFont) || errors.Is(err, audio.ErrSoundFontNotFound) || errors.Is(err, audio.ErrMIDIFileNotFound) {
+		return ExitAssetMissing
+	}
+	return ExitError
+}
@@ -0,0 +1,239 @@
+package app
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// DefaultSoundFontURL は --fetch-soundfont で既定でダウンロードするSoundFont
+// GeneralUser GS（S. Christian Collins、自由に使用・再配布できるGM音源）
+const DefaultSoundFontURL = "https://github.com/mrbumpy409/GeneralUser-GS/raw/main/GeneralUser-GS.sf2"
+
+const (
+	// soundFontCacheSubDir はユーザーのキャッシュディレクトリ内のSoundFontの保存先
+	soundFontCacheSubDir = "son-et/soundfonts"
+	// soundFontChecksumExt はダウンロードしたSoundFontのSHA-256を記録するファイルの拡張子
+	soundFontChecksumExt = ".sha256"
+	// maxSoundFontDownloadSize はダウンロードするSoundFontの大きさの上限
+	maxSoundFontDownloadSize = 512 << 20
+	// soundFontDownloadTimeout はダウンロード全体の制限時間
+	soundFontDownloadTimeout = 10 * time.Minute
+)
+
+// ErrSoundFontChecksum はSoundFontのSHA-256が期待した値と一致しない場合のエラー
+var ErrSoundFontChecksum = errors.New("soundfont checksum mismatch")
+
+// soundFontSource はダウンロードするSoundFont（--soundfont-url / --soundfont-sha256）
+type soundFontSource struct {
+	URL    string
+	SHA256 string // 16進数（空の場合はダウンロード時に検証しない）
+}
+
+// soundFontSource は設定からダウンロードするSoundFontを返す
+func (app *Application) soundFontSource() soundFontSource {
+	src := soundFontSource{URL: app.config.SoundFontURL, SHA256: app.config.SoundFontSHA256}
+	if src.URL == "" {
+		src.URL = DefaultSoundFontURL
+	}
+	return src
+}
+
+// soundFontCacheDir はダウンロードしたSoundFontを保存するディレクトリを返す
+// OSのユーザーキャッシュディレクトリ（例: ~/.cache, ~/Library/Caches）配下に置く。
+func soundFontCacheDir() (string, error) {
+	cacheDir, err := os.UserCacheDir()
+	if err != nil {
+		return "", fmt.Errorf("cannot determine user cache dir: %w", err)
+	}
+	return filepath.Join(cacheDir, filepath.FromSlash(soundFontCacheSubDir)), nil
+}
+
+// soundFontCacheName はURLからキャッシュに保存するファイル名を決める
+// URLのファイル名が .sf2 でない場合は DefaultSoundFontName を使う
+func soundFontCacheName(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err == nil {
+		name := path.Base(u.Path)
+		if strings.EqualFold(path.Ext(name), ".sf2") && !strings.ContainsAny(name, `/\`) {
+			return name
+		}
+	}
+	return DefaultSoundFontName
+}
+
+// findCachedSoundFont はキャッシュディレクトリからSoundFontを探す
+// src のファイル名のものを優先し、なければ名前順で最初の .sf2 を返す（見つからない場合は空文字列）
+func findCachedSoundFont(dir string, src soundFontSource) string {
+	preferred := filepath.Join(dir, soundFontCacheName(src.URL))
+	if info, err := os.Stat(preferred); err == nil && !info.IsDir() {
+		return preferred
+	}
+	if src.SHA256 != "" {
+		// 別のSoundFontを指定された場合は、キャッシュにある他のSoundFontで代用しない
+		return ""
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return ""
+	}
+	var names []string
+	for _, e := range entries {
+		if !e.IsDir() && strings.EqualFold(filepath.Ext(e.Name()), ".sf2") {
+			names = append(names, e.Name())
+		}
+	}
+	if len(names) == 0 {
+		return ""
+	}
+	sort.Strings(names)
+	return filepath.Join(dir, names[0])
+}
+
+// verifyCachedSoundFont はキャッシュのSoundFontのSHA-256を確認する
+// 期待する値は want、空の場合はダウンロード時に記録した値を使う（記録がない場合は確認しない）
+func verifyCachedSoundFont(sfPath, want string) error {
+	if want == "" {
+		recorded, err := os.ReadFile(sfPath + soundFontChecksumExt)
+		if err != nil {
+			return nil
+		}
+		want = strings.TrimSpace(string(recorded))
+	}
+	f, err := os.Open(sfPath)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return err
+	}
+	if got := hex.EncodeToString(h.Sum(nil)); !strings.EqualFold(got, want) {
+		return fmt.Errorf("%w: %s has %s, want %s", ErrSoundFontChecksum, sfPath, got, want)
+	}
+	return nil
+}
+
+// fetchSoundFont は src のSoundFontをダウンロードして dir に保存し、保存したパスを返す
+//
+// 一時ファイルに書き込み、SoundFontの形式（RIFF sfbk）とSHA-256を確認してから名前を変えるため、
+// 中断したダウンロードや誤ったファイルがキャッシュに残ることはない。
+// SHA-256は .sha256 ファイルに記録し、次回以降にキャッシュを使うときに確認する。
+func fetchSoundFont(client *http.Client, src soundFontSource, dir string, log *slog.Logger) (string, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", fmt.Errorf("failed to create soundfont cache dir: %w", err)
+	}
+
+	log.Info("Downloading SoundFont", "url", src.URL, "dir", dir)
+	resp, err := client.Get(src.URL)
+	if err != nil {
+		return "", fmt.Errorf("failed to download soundfont: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("failed to download soundfont: %s: %s", src.URL, resp.Status)
+	}
+
+	tmp, err := os.CreateTemp(dir, "download-*.tmp")
+	if err != nil {
+		return "", fmt.Errorf("failed to create soundfont file: %w", err)
+	}
+	defer os.Remove(tmp.Name()) // 名前を変えた後は何もしない
+
+	h := sha256.New()
+	header := &prefixWriter{limit: soundFontHeaderSize}
+	n, err := io.Copy(io.MultiWriter(tmp, h, header), io.LimitReader(resp.Body, maxSoundFontDownloadSize+1))
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to download soundfont: %w", err)
+	}
+	if n > maxSoundFontDownloadSize {
+		return "", fmt.Errorf("soundfont download exceeds %d MB", maxSoundFontDownloadSize>>20)
+	}
+	if !isSoundFontHeader(header.buf) {
+		return "", fmt.Errorf("downloaded file is not a SoundFont (.sf2): %s", src.URL)
+	}
+	sum := hex.EncodeToString(h.Sum(nil))
+	if src.SHA256 != "" && !strings.EqualFold(sum, src.SHA256) {
+		return "", fmt.Errorf("%w: %s has %s, want %s", ErrSoundFontChecksum, src.URL, sum, src.SHA256)
+	}
+
+	dest := filepath.Join(dir, soundFontCacheName(src.URL))
+	if err := os.WriteFile(dest+soundFontChecksumExt, []byte(sum+"\n"), 0644); err != nil {
+		return "", fmt.Errorf("failed to record soundfont checksum: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), dest); err != nil {
+		return "", fmt.Errorf("failed to save soundfont: %w", err)
+	}
+	log.Info("SoundFont downloaded", "path", dest, "bytes", n, "sha256", sum)
+	return dest, nil
+}
+
+// soundFontHeaderSize はSoundFontの形式の確認に使う先頭のバイト数（"RIFF" + 大きさ + "sfbk"）
+const soundFontHeaderSize = 12
+
+// isSoundFontHeader はファイルの先頭がSoundFont（RIFF形式の sfbk）かどうかを返す
+func isSoundFontHeader(b []byte) bool {
+	return len(b) >= soundFontHeaderSize && bytes.Equal(b[0:4], []byte("RIFF")) && bytes.Equal(b[8:12], []byte("sfbk"))
+}
+
+// prefixWriter は書き込まれた内容の先頭 limit バイトを保持する
+type prefixWriter struct {
+	buf   []byte
+	limit int
+}
+
+func (w *prefixWriter) Write(p []byte) (int, error) {
+	if rest := w.limit - len(w.buf); rest > 0 {
+		w.buf = append(w.buf, p[:min(rest, len(p))]...)
+	}
+	return len(p), nil
+}
+
+// findDownloadedSoundFont はダウンロード済みのSoundFontを探し、なければ --fetch-soundfont の場合にダウンロードする
+// 見つからずダウンロードもしない場合は nil を返す
+func (app *Application) findDownloadedSoundFont() *SoundFontLocation {
+	dir, err := soundFontCacheDir()
+	if err != nil {
+		app.log.Warn("SoundFont cache is unavailable", "error", err)
+		return nil
+	}
+	src := app.soundFontSource()
+	if cached := findCachedSoundFont(dir, src); cached != "" {
+		want := ""
+		if filepath.Base(cached) == soundFontCacheName(src.URL) {
+			want = src.SHA256
+		}
+		err := verifyCachedSoundFont(cached, want)
+		if err == nil {
+			return &SoundFontLocation{Path: cached}
+		}
+		app.log.Warn("Cached SoundFont is corrupted or outdated", "path", cached, "error", err)
+	}
+	if !app.config.FetchSoundFont {
+		return nil
+	}
+
+	client := &http.Client{Timeout: soundFontDownloadTimeout}
+	sfPath, err := fetchSoundFont(client, src, dir, app.log)
+	if err != nil {
+		app.log.Error("SoundFont download failed", "error", err)
+		return nil
+	}
+	return &SoundFontLocation{Path: sfPath}
+}
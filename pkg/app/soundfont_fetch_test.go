@@ -0,0 +1,152 @@
+package app
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/zurustar/son-et/pkg/logger"
+)
+
+// testSoundFontData は RIFF sfbk で始まる最小のSoundFontの内容
+var testSoundFontData = []byte("RIFF\x04\x00\x00\x00sfbkLIST")
+
+func testSoundFontSHA256(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+func serveSoundFont(t *testing.T, data []byte) *httptest.Server {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/missing.sf2" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write(data)
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestFetchSoundFont(t *testing.T) {
+	srv := serveSoundFont(t, testSoundFontData)
+	dir := filepath.Join(t.TempDir(), "cache")
+
+	src := soundFontSource{URL: srv.URL + "/fonts/Test.sf2", SHA256: testSoundFontSHA256(testSoundFontData)}
+	path, err := fetchSoundFont(srv.Client(), src, dir, logger.GetLogger())
+	if err != nil {
+		t.Fatalf("fetchSoundFont failed: %v", err)
+	}
+	if path != filepath.Join(dir, "Test.sf2") {
+		t.Errorf("path = %q, want Test.sf2 in the cache dir", path)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil || string(data) != string(testSoundFontData) {
+		t.Errorf("saved soundfont = %q, %v", data, err)
+	}
+	if err := verifyCachedSoundFont(path, ""); err != nil {
+		t.Errorf("verifyCachedSoundFont with recorded checksum failed: %v", err)
+	}
+
+	// 一時ファイルが残っていないこと
+	entries, _ := os.ReadDir(dir)
+	if len(entries) != 2 {
+		t.Errorf("cache dir has %d entries, want the soundfont and its checksum", len(entries))
+	}
+}
+
+func TestFetchSoundFont_Errors(t *testing.T) {
+	tests := []struct {
+		name string
+		data []byte
+		path string
+		sum  string
+		is   error
+	}{
+		{"checksum mismatch", testSoundFontData, "/a.sf2", testSoundFontSHA256([]byte("other")), ErrSoundFontChecksum},
+		{"not a soundfont", []byte("<html>not found</html>"), "/a.sf2", "", nil},
+		{"http error", testSoundFontData, "/missing.sf2", "", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := serveSoundFont(t, tt.data)
+			dir := t.TempDir()
+			_, err := fetchSoundFont(srv.Client(), soundFontSource{URL: srv.URL + tt.path, SHA256: tt.sum}, dir, logger.GetLogger())
+			if err == nil {
+				t.Fatal("fetchSoundFont should fail")
+			}
+			if tt.is != nil && !errors.Is(err, tt.is) {
+				t.Errorf("error = %v, want %v", err, tt.is)
+			}
+			if found := findCachedSoundFont(dir, soundFontSource{URL: srv.URL + tt.path}); found != "" {
+				t.Errorf("failed download left %s in the cache", found)
+			}
+		})
+	}
+}
+
+func TestVerifyCachedSoundFont(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, DefaultSoundFontName)
+	if err := os.WriteFile(path, testSoundFontData, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	// 記録がない場合は確認しない（手動で置いたSoundFont）
+	if err := verifyCachedSoundFont(path, ""); err != nil {
+		t.Errorf("verifyCachedSoundFont without record = %v, want nil", err)
+	}
+	if err := verifyCachedSoundFont(path, testSoundFontSHA256(testSoundFontData)); err != nil {
+		t.Errorf("verifyCachedSoundFont with matching checksum = %v", err)
+	}
+
+	// ダウンロード後に壊れたファイル
+	if err := os.WriteFile(path+soundFontChecksumExt, []byte(testSoundFontSHA256([]byte("original"))+"\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := verifyCachedSoundFont(path, ""); !errors.Is(err, ErrSoundFontChecksum) {
+		t.Errorf("verifyCachedSoundFont with corrupted file = %v, want ErrSoundFontChecksum", err)
+	}
+}
+
+func TestFindCachedSoundFont(t *testing.T) {
+	dir := t.TempDir()
+	if got := findCachedSoundFont(dir, soundFontSource{URL: DefaultSoundFontURL}); got != "" {
+		t.Errorf("findCachedSoundFont in empty dir = %q", got)
+	}
+	for _, name := range []string{"b.sf2", "a.sf2", "notes.txt"} {
+		if err := os.WriteFile(filepath.Join(dir, name), testSoundFontData, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if got := findCachedSoundFont(dir, soundFontSource{URL: "https://example.com/b.sf2"}); got != filepath.Join(dir, "b.sf2") {
+		t.Errorf("findCachedSoundFont = %q, want b.sf2 matching the URL", got)
+	}
+	if got := findCachedSoundFont(dir, soundFontSource{URL: DefaultSoundFontURL}); got != filepath.Join(dir, "a.sf2") {
+		t.Errorf("findCachedSoundFont = %q, want the first .sf2 by name", got)
+	}
+	if got := findCachedSoundFont(dir, soundFontSource{URL: DefaultSoundFontURL, SHA256: testSoundFontSHA256(nil)}); got != "" {
+		t.Errorf("findCachedSoundFont with a pinned checksum = %q, want no substitute", got)
+	}
+}
+
+func TestSoundFontCacheName(t *testing.T) {
+	tests := map[string]string{
+		DefaultSoundFontURL:                         DefaultSoundFontName,
+		"https://example.com/fonts/Piano.SF2?dl=1":  "Piano.SF2",
+		"https://example.com/download?id=soundfont": DefaultSoundFontName,
+		"https://example.com/":                      DefaultSoundFontName,
+	}
+	for url, want := range tests {
+		if got := soundFontCacheName(url); got != want {
+			t.Errorf("soundFontCacheName(%q) = %q, want %q", url, got, want)
+		}
+	}
+}
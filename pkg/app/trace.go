@@ -0,0 +1,22 @@
+package app
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/zurustar/son-et/pkg/vm"
+)
+
+// reportTraces はタイトルが実行時エラーやタイムアウトで終了したときに、シーケンスごとの
+// 最後に実行した文を w（標準エラー出力）に表示する。予期しない Wait で止まったシーケンスが
+// 何をしていたかを確認できる
+func reportTraces(w io.Writer, vmInstance *vm.VM, reason string) {
+	traces := vmInstance.SequenceTraces()
+	if len(traces) == 0 {
+		return
+	}
+	fmt.Fprintf(w, "Last executed statements (%s):\n", reason)
+	for _, t := range traces {
+		fmt.Fprint(w, t.String())
+	}
+}
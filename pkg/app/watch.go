@@ -0,0 +1,328 @@
+package app
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/zurustar/son-et/pkg/vm"
+)
+
+// maxWatchRequestSize は変数ウォッチAPIが受け付けるリクエストの大きさの上限
+const maxWatchRequestSize = 4 << 10
+
+// watchReadHeaderTimeout は変数ウォッチAPIがリクエストヘッダーを待つ時間
+const watchReadHeaderTimeout = 5 * time.Second
+
+// watchServer は変数ウォッチのリモートAPI（--watch-addr）
+//
+//	GET /vars         グローバル変数の一覧（[{"name": "x", "value": 10, "numeric": true}, ...]）
+//	GET /vars/{name}  1つの変数
+//	PUT /vars/{name}  数値の変数を変更する（本文は 12 または {"value": 12}）
+//	GET /trace        シーケンスごとの最後に実行した文（[{"sequence": 1, "event": "TIME", "entries": ["Wait 3", ...]}, ...]）
+//	POST /command     オペレーターのコマンドを実行する（本文は "seek 1200" などの1行、{"output": "..."} を返す）
+//	GET /memory       ピクチャーのメモリと、?idle=秒（既定30）以上表示されていないピクチャー（リークの候補）
+//
+// POST /command はGUIのコンソール（~ キー）と同じ vm.RunCommand で実行する。
+//
+// タイトル選択画面から別のタイトルを起動するとVMが入れ替わるため、対象のVMは setVM で差し替える。
+type watchServer struct {
+	mu       sync.Mutex
+	vm       *vm.VM // nilの場合はタイトルの実行前
+	server   *http.Server
+	listener net.Listener
+	log      *slog.Logger
+}
+
+// watchVariableJSON は変数ウォッチAPIが返す変数
+type watchVariableJSON struct {
+	Name    string `json:"name"`
+	Value   any    `json:"value"`
+	Numeric bool   `json:"numeric"`
+}
+
+// traceJSON は変数ウォッチAPIが返すシーケンスの実行トレース
+type traceJSON struct {
+	Sequence int      `json:"sequence"`
+	Event    string   `json:"event,omitempty"`
+	Entries  []string `json:"entries"`
+}
+
+// commandJSON は POST /command が返すコマンドの結果
+type commandJSON struct {
+	Output string `json:"output"`
+}
+
+// memoryJSON は GET /memory が返すピクチャーのメモリとリークの候補（時間は秒）
+type memoryJSON struct {
+	Pictures     int         `json:"pictures"`
+	PictureBytes int64       `json:"picture_bytes"`
+	HeapBytes    uint64      `json:"heap_bytes"`
+	IdleSeconds  float64     `json:"idle_seconds"`
+	Leaks        []imageJSON `json:"leaks"`
+}
+
+// imageJSON は GET /memory が返すリークの候補のピクチャー
+type imageJSON struct {
+	PicID       int     `json:"pic_id"`
+	Width       int     `json:"width"`
+	Height      int     `json:"height"`
+	Bytes       int64   `json:"bytes"`
+	Site        string  `json:"site,omitempty"`
+	AgeSeconds  float64 `json:"age_seconds"`
+	IdleSeconds float64 `json:"idle_seconds"`
+	Windows     []int   `json:"windows,omitempty"`
+	Casts       []int   `json:"casts,omitempty"`
+}
+
+// startWatchServer は --watch-addr が指定されている場合に変数ウォッチAPIを起動する
+func (app *Application) startWatchServer() error {
+	if app.config.WatchAddr == "" {
+		return nil
+	}
+	listener, err := net.Listen("tcp", app.config.WatchAddr)
+	if err != nil {
+		return fmt.Errorf("failed to start watch API: %w", err)
+	}
+	app.watchServer = newWatchServer(listener, app.log)
+	app.log.Info("Watch API listening", "addr", listener.Addr().String())
+	return nil
+}
+
+// newWatchServer は listener で変数ウォッチAPIを起動する
+func newWatchServer(listener net.Listener, log *slog.Logger) *watchServer {
+	s := &watchServer{listener: listener, log: log}
+	s.server = &http.Server{Handler: s.handler(), ReadHeaderTimeout: watchReadHeaderTimeout}
+	go func() {
+		if err := s.server.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Error("Watch API stopped", "error", err)
+		}
+	}()
+	return s
+}
+
+// setVM は変数ウォッチAPIの対象のVMを設定する（s が nil の場合は何もしない）
+func (s *watchServer) setVM(v *vm.VM) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.vm = v
+}
+
+// Close は変数ウォッチAPIを停止する（s が nil の場合は何もしない）
+func (s *watchServer) Close() error {
+	if s == nil {
+		return nil
+	}
+	return s.server.Close()
+}
+
+func (s *watchServer) currentVM() *vm.VM {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.vm
+}
+
+func (s *watchServer) handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /vars", s.handleList)
+	mux.HandleFunc("GET /vars/{name}", s.handleGet)
+	mux.HandleFunc("PUT /vars/{name}", s.handleSet)
+	mux.HandleFunc("GET /trace", s.handleTrace)
+	mux.HandleFunc("POST /command", s.handleCommand)
+	mux.HandleFunc("GET /memory", s.handleMemory)
+	return mux
+}
+
+func (s *watchServer) handleList(w http.ResponseWriter, r *http.Request) {
+	v := s.currentVM()
+	if v == nil {
+		http.Error(w, "no title is running", http.StatusServiceUnavailable)
+		return
+	}
+	vars := v.WatchVariables()
+	result := make([]watchVariableJSON, len(vars))
+	for i, wv := range vars {
+		result[i] = toWatchVariableJSON(wv)
+	}
+	writeWatchJSON(w, result)
+}
+
+func (s *watchServer) handleGet(w http.ResponseWriter, r *http.Request) {
+	v := s.currentVM()
+	if v == nil {
+		http.Error(w, "no title is running", http.StatusServiceUnavailable)
+		return
+	}
+	wv, ok := findWatchedVariable(v, r.PathValue("name"))
+	if !ok {
+		http.Error(w, "variable not found", http.StatusNotFound)
+		return
+	}
+	writeWatchJSON(w, toWatchVariableJSON(wv))
+}
+
+func (s *watchServer) handleSet(w http.ResponseWriter, r *http.Request) {
+	v := s.currentVM()
+	if v == nil {
+		http.Error(w, "no title is running", http.StatusServiceUnavailable)
+		return
+	}
+	name := r.PathValue("name")
+	if _, ok := findWatchedVariable(v, name); !ok {
+		http.Error(w, "variable not found", http.StatusNotFound)
+		return
+	}
+	body, err := io.ReadAll(io.LimitReader(r.Body, maxWatchRequestSize))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	value, err := parseWatchValue(body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	stored, err := v.SetWatchedVariable(name, value)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	s.log.Info("Variable changed from the watch API", "name", name, "value", stored, "remote", r.RemoteAddr)
+	writeWatchJSON(w, watchVariableJSON{Name: name, Value: stored, Numeric: true})
+}
+
+func (s *watchServer) handleTrace(w http.ResponseWriter, r *http.Request) {
+	v := s.currentVM()
+	if v == nil {
+		http.Error(w, "no title is running", http.StatusServiceUnavailable)
+		return
+	}
+	traces := v.SequenceTraces()
+	result := make([]traceJSON, len(traces))
+	for i, t := range traces {
+		entries := make([]string, len(t.Entries))
+		for j, e := range t.Entries {
+			entries[j] = e.String()
+		}
+		result[i] = traceJSON{Sequence: t.Sequence, Event: string(t.EventType), Entries: entries}
+	}
+	writeWatchJSON(w, result)
+}
+
+func (s *watchServer) handleCommand(w http.ResponseWriter, r *http.Request) {
+	v := s.currentVM()
+	if v == nil {
+		http.Error(w, "no title is running", http.StatusServiceUnavailable)
+		return
+	}
+	body, err := io.ReadAll(io.LimitReader(r.Body, maxWatchRequestSize))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	line := strings.TrimSpace(string(body))
+	out, err := v.RunCommand(line)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	s.log.Info("Command run from the watch API", "command", line, "remote", r.RemoteAddr)
+	writeWatchJSON(w, commandJSON{Output: out})
+}
+
+func (s *watchServer) handleMemory(w http.ResponseWriter, r *http.Request) {
+	v := s.currentVM()
+	if v == nil {
+		http.Error(w, "no title is running", http.StatusServiceUnavailable)
+		return
+	}
+	var idle time.Duration
+	if q := r.URL.Query().Get("idle"); q != "" {
+		seconds, err := strconv.ParseFloat(q, 64)
+		if err != nil || seconds <= 0 {
+			http.Error(w, "idle must be a positive number of seconds", http.StatusBadRequest)
+			return
+		}
+		idle = time.Duration(seconds * float64(time.Second))
+	}
+	report, err := v.ImageReport(idle)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusServiceUnavailable)
+		return
+	}
+	result := memoryJSON{
+		Pictures:     report.Pictures,
+		PictureBytes: report.PictureBytes,
+		HeapBytes:    report.HeapBytes,
+		IdleSeconds:  report.Idle.Seconds(),
+		Leaks:        make([]imageJSON, len(report.Leaks)),
+	}
+	for i, u := range report.Leaks {
+		result.Leaks[i] = imageJSON{
+			PicID:       u.PicID,
+			Width:       u.Width,
+			Height:      u.Height,
+			Bytes:       u.Bytes,
+			Site:        u.Site,
+			AgeSeconds:  u.Age.Seconds(),
+			IdleSeconds: u.Idle.Seconds(),
+			Windows:     u.Windows,
+			Casts:       u.Casts,
+		}
+	}
+	writeWatchJSON(w, result)
+}
+
+// findWatchedVariable はVMのグローバル変数から name を探す
+func findWatchedVariable(v *vm.VM, name string) (vm.WatchedVariable, bool) {
+	for _, wv := range v.WatchVariables() {
+		if wv.Name == name {
+			return wv, true
+		}
+	}
+	return vm.WatchedVariable{}, false
+}
+
+// parseWatchValue はPUTの本文（数値または {"value": 数値}）を解析する
+func parseWatchValue(body []byte) (float64, error) {
+	body = bytes.TrimSpace(body)
+	var value float64
+	if err := json.Unmarshal(body, &value); err == nil {
+		return value, nil
+	}
+	var obj struct {
+		Value *float64 `json:"value"`
+	}
+	if err := json.Unmarshal(body, &obj); err != nil || obj.Value == nil {
+		return 0, fmt.Errorf(`body must be a number or {"value": number}`)
+	}
+	return *obj.Value, nil
+}
+
+// toWatchVariableJSON はVMの変数をJSONで返す形に変換する（配列は要素のリスト）
+func toWatchVariableJSON(v vm.WatchedVariable) watchVariableJSON {
+	value := v.Value
+	if arr, ok := value.(*vm.Array); ok {
+		value = arr.ToSlice()
+	}
+	return watchVariableJSON{Name: v.Name, Value: value, Numeric: v.Numeric()}
+}
+
+func writeWatchJSON(w http.ResponseWriter, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,242 @@
+package app
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/zurustar/son-et/pkg/graphics"
+	"github.com/zurustar/son-et/pkg/logger"
+	"github.com/zurustar/son-et/pkg/opcode"
+	"github.com/zurustar/son-et/pkg/vm"
+)
+
+// newWatchTestVM はグローバル変数を設定したVMを作成する
+func newWatchTestVM(t *testing.T) *vm.VM {
+	t.Helper()
+	v := vm.New([]opcode.OpCode{})
+	v.GetGlobalScope().Set("count", int64(3))
+	v.GetGlobalScope().Set("speed", 1.5)
+	v.GetGlobalScope().Set("name", "demo")
+	return v
+}
+
+func TestParseWatchValue(t *testing.T) {
+	for body, want := range map[string]float64{"12": 12, " -1.5\n": -1.5, `{"value": 7}`: 7} {
+		got, err := parseWatchValue([]byte(body))
+		if err != nil || got != want {
+			t.Errorf("parseWatchValue(%q) = %v, %v; want %v", body, got, err, want)
+		}
+	}
+	for _, body := range []string{"", `"12"`, `{"val": 1}`, `{"value": "x"}`} {
+		if _, err := parseWatchValue([]byte(body)); err == nil {
+			t.Errorf("parseWatchValue(%q) should fail", body)
+		}
+	}
+}
+
+func TestWatchServer(t *testing.T) {
+	s := &watchServer{log: logger.GetLogger()}
+	srv := httptest.NewServer(s.handler())
+	defer srv.Close()
+
+	// タイトルの実行前
+	resp, err := http.Get(srv.URL + "/vars")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusServiceUnavailable {
+		t.Errorf("GET /vars before a title = %d, want 503", resp.StatusCode)
+	}
+
+	v := newWatchTestVM(t)
+	s.setVM(v)
+
+	resp, err = http.Get(srv.URL + "/vars")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var vars []watchVariableJSON
+	if err := json.NewDecoder(resp.Body).Decode(&vars); err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if len(vars) != 3 || vars[0].Name != "count" || !vars[0].Numeric || vars[1].Name != "name" || vars[1].Numeric {
+		t.Errorf("GET /vars = %+v", vars)
+	}
+
+	put := func(name, body string) int {
+		req, _ := http.NewRequest(http.MethodPut, srv.URL+"/vars/"+name, strings.NewReader(body))
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+	if code := put("count", `{"value": 9.6}`); code != http.StatusOK {
+		t.Errorf("PUT /vars/count = %d", code)
+	}
+	if got, _ := v.GetGlobalScope().Get("count"); got != int64(10) {
+		t.Errorf("count = %v, want 10", got)
+	}
+	if code := put("speed", "0.5"); code != http.StatusOK {
+		t.Errorf("PUT /vars/speed = %d", code)
+	}
+	if got, _ := v.GetGlobalScope().Get("speed"); got != 0.5 {
+		t.Errorf("speed = %v, want 0.5", got)
+	}
+	for _, tc := range []struct {
+		name, body string
+		code       int
+	}{
+		{"missing", "1", http.StatusNotFound},
+		{"name", "1", http.StatusBadRequest},
+		{"count", "abc", http.StatusBadRequest},
+	} {
+		if code := put(tc.name, tc.body); code != tc.code {
+			t.Errorf("PUT /vars/%s %s = %d, want %d", tc.name, tc.body, code, tc.code)
+		}
+	}
+
+	resp, err = http.Get(srv.URL + "/vars/speed")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var one watchVariableJSON
+	json.NewDecoder(resp.Body).Decode(&one)
+	resp.Body.Close()
+	if one.Name != "speed" || one.Value != 0.5 {
+		t.Errorf("GET /vars/speed = %+v", one)
+	}
+}
+
+func TestWatchServerNil(t *testing.T) {
+	var s *watchServer
+	s.setVM(nil)
+	if err := s.Close(); err != nil {
+		t.Errorf("Close on nil watchServer = %v", err)
+	}
+}
+
+func TestWatchServerTrace(t *testing.T) {
+	s := &watchServer{log: logger.GetLogger()}
+	srv := httptest.NewServer(s.handler())
+	defer srv.Close()
+
+	v := newWatchTestVM(t)
+	if _, err := v.Execute(opcode.OpCode{Cmd: opcode.Call, Args: []any{"StrLen", opcode.Variable("name")}}); err != nil {
+		t.Fatal(err)
+	}
+	s.setVM(v)
+
+	resp, err := http.Get(srv.URL + "/trace")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var traces []traceJSON
+	if err := json.NewDecoder(resp.Body).Decode(&traces); err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if len(traces) != 1 || traces[0].Sequence != 0 || len(traces[0].Entries) != 1 || traces[0].Entries[0] != `StrLen("demo")` {
+		t.Errorf("GET /trace = %+v", traces)
+	}
+
+	var buf strings.Builder
+	reportTraces(&buf, v, "timeout")
+	if !strings.HasPrefix(buf.String(), "Last executed statements (timeout):\n") {
+		t.Errorf("reportTraces = %q", buf.String())
+	}
+}
+
+func TestWatchServerCommand(t *testing.T) {
+	s := &watchServer{log: logger.GetLogger()}
+	srv := httptest.NewServer(s.handler())
+	defer srv.Close()
+
+	post := func(body string) (int, string) {
+		t.Helper()
+		resp, err := http.Post(srv.URL+"/command", "text/plain", strings.NewReader(body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			return resp.StatusCode, ""
+		}
+		var result commandJSON
+		if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+			t.Fatal(err)
+		}
+		return resp.StatusCode, result.Output
+	}
+
+	if code, _ := post("get count"); code != http.StatusServiceUnavailable {
+		t.Errorf("POST /command before a title = %d, want 503", code)
+	}
+
+	s.setVM(newWatchTestVM(t))
+	if code, out := post("set count 9\n"); code != http.StatusOK || out != "count = 9" {
+		t.Errorf("POST /command set = %d %q", code, out)
+	}
+	if code, out := post("get count"); code != http.StatusOK || out != "count = 9" {
+		t.Errorf("POST /command get = %d %q", code, out)
+	}
+	if code, _ := post("explode"); code != http.StatusBadRequest {
+		t.Errorf("POST /command with an unknown command = %d, want 400", code)
+	}
+}
+
+func TestWatchServerMemory(t *testing.T) {
+	s := &watchServer{log: logger.GetLogger()}
+	srv := httptest.NewServer(s.handler())
+	defer srv.Close()
+
+	get := func(query string) (int, memoryJSON) {
+		t.Helper()
+		resp, err := http.Get(srv.URL + "/memory" + query)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		var result memoryJSON
+		if resp.StatusCode == http.StatusOK {
+			if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+				t.Fatal(err)
+			}
+		}
+		return resp.StatusCode, result
+	}
+
+	if code, _ := get(""); code != http.StatusServiceUnavailable {
+		t.Errorf("GET /memory before a title = %d, want 503", code)
+	}
+
+	v := newWatchTestVM(t)
+	s.setVM(v)
+	if code, _ := get(""); code != http.StatusServiceUnavailable {
+		t.Errorf("GET /memory without graphics = %d, want 503", code)
+	}
+
+	gs := graphics.NewHeadlessGraphicsSystem(graphics.WithLogOperations(false))
+	v.SetGraphicsSystem(gs)
+	if _, err := gs.CreatePic(10, 20); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(2 * time.Millisecond) // 作成してから idle 以上経過させる
+	code, result := get("?idle=0.001")
+	if code != http.StatusOK || result.Pictures != 1 || result.PictureBytes != 10*20*4 || result.IdleSeconds != 0.001 {
+		t.Fatalf("GET /memory = %d %+v", code, result)
+	}
+	if len(result.Leaks) != 1 || result.Leaks[0].PicID != 0 || result.Leaks[0].Site != "sequence 0" {
+		t.Errorf("leaks = %+v", result.Leaks)
+	}
+	if code, _ := get("?idle=-1"); code != http.StatusBadRequest {
+		t.Errorf("GET /memory with a negative idle = %d, want 400", code)
+	}
+}
@@ -0,0 +1,223 @@
+// Package assetconv はタイトルのアセットを一括変換する（son-et assets convert）。
+//
+// タイトルのディレクトリを別の出力ディレクトリに複製しながら、次の変換を行う。
+// 元のディレクトリのファイルは変更しない。
+//
+//   - BMP: RLE圧縮・OS/2形式・16ビットなど、標準のデコーダーで読めないBMPを
+//     非圧縮のBMP（256色以下は8ビットのパレット形式、それ以外は24ビットか32ビット）に書き直す
+//   - 画像（BMP・PNG）: 仮想デスクトップより大きい画像を縦横比を保って縮小する
+//   - MIDI: 分解能（PPQ）を揃えて書き直す（テンポと演奏時間は変わらない）
+//   - ファイル名: UTF-8として読めないShift-JISのファイル名をUTF-8に変換する
+//
+// それ以外のファイルはそのまま複製する。変換できなかったファイルもそのまま複製し、
+// 理由をレポートに記録する。
+package assetconv
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// DefaultPPQ は MIDI を書き直すときの既定の分解能（四分音符あたりのティック数）
+const DefaultPPQ = 480
+
+// Options は変換の設定
+type Options struct {
+	MaxWidth  int // これより幅の大きい画像を縮小する（0は縮小しない）
+	MaxHeight int // これより高さの大きい画像を縮小する（0は縮小しない）
+	PPQ       int // MIDIの分解能（0は変更しない）
+}
+
+// Change は1つのファイルの変換の結果
+type Change struct {
+	Path   string   // 元のファイル（タイトルのディレクトリからの相対パス）
+	Output string   // 出力したファイル（出力ディレクトリからの相対パス）
+	Notes  []string // 変更の内容
+	Err    string   // 変換できずにそのまま複製した理由（空の場合は変換した）
+}
+
+// Report は変換の結果
+type Report struct {
+	Source  string   // タイトルのディレクトリ
+	Output  string   // 出力ディレクトリ
+	Files   int      // 出力したファイルの数
+	Changes []Change // 変更したファイルと変換できなかったファイル
+}
+
+// Convert は src のタイトルを dst に変換して複製する。
+// dst は存在しないか空のディレクトリで、src の中や src を含む場所は指定できない。
+func Convert(src, dst string, opts Options) (*Report, error) {
+	src, err := filepath.Abs(src)
+	if err != nil {
+		return nil, err
+	}
+	dst, err = filepath.Abs(dst)
+	if err != nil {
+		return nil, err
+	}
+	if info, err := os.Stat(src); err != nil {
+		return nil, err
+	} else if !info.IsDir() {
+		return nil, fmt.Errorf("%s is not a directory", src)
+	}
+	if within(dst, src) || within(src, dst) {
+		return nil, fmt.Errorf("output directory %s must be outside the title directory %s", dst, src)
+	}
+	if err := checkEmptyDir(dst); err != nil {
+		return nil, err
+	}
+
+	report := &Report{Source: src, Output: dst}
+	var files []string
+	err = filepath.WalkDir(src, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(src, path)
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			return os.MkdirAll(filepath.Join(dst, transliteratePath(rel)), 0o755)
+		}
+		if !d.Type().IsRegular() {
+			report.Changes = append(report.Changes, Change{Path: rel, Err: "not a regular file, skipped"})
+			return nil
+		}
+		files = append(files, rel)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// 名前を変換しないファイルを先に登録し、変換した名前がそれらと重ならないようにする
+	used := make(map[string]string) // 出力するパス（小文字）→ 元のパス
+	for _, rel := range files {
+		if transliteratePath(rel) == rel {
+			used[strings.ToLower(rel)] = rel
+		}
+	}
+	for _, rel := range files {
+		if err := report.convertFile(src, dst, rel, opts, used); err != nil {
+			return nil, err
+		}
+	}
+	return report, nil
+}
+
+// convertFile は1つのファイルを変換して出力ディレクトリに書き出す
+func (r *Report) convertFile(src, dst, rel string, opts Options, used map[string]string) error {
+	path := filepath.Join(src, rel)
+	info, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	change := Change{Path: rel, Output: transliteratePath(rel)}
+	if change.Output != rel {
+		// 大文字小文字を区別しない検索で、変換後の名前が別のファイルと重ならないようにする
+		if other, ok := used[strings.ToLower(change.Output)]; ok {
+			change.Output = rel
+			change.Err = fmt.Sprintf("converted file name is used by %s, name left unchanged", strings.ToValidUTF8(other, "?"))
+		} else {
+			change.Notes = append(change.Notes, "file name converted from Shift-JIS")
+			used[strings.ToLower(change.Output)] = rel
+		}
+	}
+
+	out, notes, err := convertData(rel, data, opts)
+	switch {
+	case err != nil:
+		change.Err = joinNotes(change.Err, "left unchanged: "+err.Error())
+	case out != nil:
+		data = out
+		change.Notes = append(change.Notes, notes...)
+	}
+
+	if err := os.WriteFile(filepath.Join(dst, change.Output), data, info.Mode().Perm()); err != nil {
+		return err
+	}
+	r.Files++
+	if len(change.Notes) > 0 || change.Err != "" {
+		r.Changes = append(r.Changes, change)
+	}
+	return nil
+}
+
+// convertData は拡張子に応じてファイルの内容を変換する
+// 変更がない場合は nil を返す
+func convertData(name string, data []byte, opts Options) ([]byte, []string, error) {
+	switch strings.ToLower(filepath.Ext(name)) {
+	case ".bmp":
+		return convertBMP(data, opts)
+	case ".png":
+		return convertPNG(data, opts)
+	case ".mid", ".midi":
+		return convertMIDI(data, opts.PPQ)
+	}
+	return nil, nil, nil
+}
+
+// WriteText は変換の結果を人が読む形式で書き出す
+func (r *Report) WriteText(out io.Writer) error {
+	w := &strings.Builder{}
+	fmt.Fprintf(w, "Source: %s\nOutput: %s\n", r.Source, r.Output)
+
+	failed := 0
+	for _, c := range r.Changes {
+		name := strings.ToValidUTF8(filepath.ToSlash(c.Path), "?")
+		if c.Output != c.Path {
+			name += " -> " + filepath.ToSlash(c.Output)
+		}
+		fmt.Fprintf(w, "  %s\n", name)
+		for _, n := range c.Notes {
+			fmt.Fprintf(w, "      %s\n", n)
+		}
+		if c.Err != "" {
+			fmt.Fprintf(w, "      %s\n", c.Err)
+			failed++
+		}
+	}
+	fmt.Fprintf(w, "%d files, %d changed, %d with problems\n", r.Files, len(r.Changes)-failed, failed)
+	_, err := io.WriteString(out, w.String())
+	return err
+}
+
+// joinNotes は空でない説明を "; " でつなげる
+func joinNotes(a, b string) string {
+	if a == "" {
+		return b
+	}
+	return a + "; " + b
+}
+
+// within は path が dir またはその中にあるかを返す
+func within(path, dir string) bool {
+	rel, err := filepath.Rel(dir, path)
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
+// checkEmptyDir は dir が存在しないか空のディレクトリであることを確認する
+func checkEmptyDir(dir string) error {
+	entries, err := os.ReadDir(dir)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if len(entries) > 0 {
+		return fmt.Errorf("output directory %s is not empty", dir)
+	}
+	return nil
+}
@@ -0,0 +1,305 @@
+package assetconv
+
+import (
+	"bytes"
+	"encoding/binary"
+	"image"
+	"image/color"
+	"image/png"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"golang.org/x/image/bmp"
+)
+
+// rle8BMP は1色で塗りつぶした w×h のRLE8圧縮のBMPを作る
+func rle8BMP(w, h int, c color.RGBA) []byte {
+	var pixels []byte
+	for range h {
+		for x := 0; x < w; x += 255 {
+			pixels = append(pixels, byte(min(255, w-x)), 1)
+		}
+		pixels = append(pixels, 0, 0) // 行の終わり
+	}
+	pixels = append(pixels, 0, 1) // 画像の終わり
+
+	palette := []byte{0, 0, 0, 0, c.B, c.G, c.R, 0}
+	offset := 14 + 40 + len(palette)
+	b := []byte("BM")
+	b = binary.LittleEndian.AppendUint32(b, uint32(offset+len(pixels)))
+	b = binary.LittleEndian.AppendUint32(b, 0)
+	b = binary.LittleEndian.AppendUint32(b, uint32(offset))
+	b = binary.LittleEndian.AppendUint32(b, 40)
+	b = binary.LittleEndian.AppendUint32(b, uint32(w))
+	b = binary.LittleEndian.AppendUint32(b, uint32(h))
+	b = binary.LittleEndian.AppendUint16(b, 1)
+	b = binary.LittleEndian.AppendUint16(b, 8)
+	b = binary.LittleEndian.AppendUint32(b, 1) // BI_RLE8
+	b = binary.LittleEndian.AppendUint32(b, uint32(len(pixels)))
+	b = binary.LittleEndian.AppendUint32(b, 0)
+	b = binary.LittleEndian.AppendUint32(b, 0)
+	b = binary.LittleEndian.AppendUint32(b, 2)
+	b = binary.LittleEndian.AppendUint32(b, 0)
+	b = append(b, palette...)
+	return append(b, pixels...)
+}
+
+// encodeBMP は画像を非圧縮のBMPにする
+func encodeBMP(t *testing.T, img image.Image) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := bmp.Encode(&buf, img); err != nil {
+		t.Fatalf("bmp.Encode failed: %v", err)
+	}
+	return buf.Bytes()
+}
+
+// writeFiles は dir にファイルを書き込む
+func writeFiles(t *testing.T, dir string, files map[string][]byte) {
+	t.Helper()
+	for name, data := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, data, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestConvertBMP(t *testing.T) {
+	red := color.RGBA{R: 0xff, A: 0xff}
+
+	t.Run("RLE8 is normalized", func(t *testing.T) {
+		out, notes, err := convertBMP(rle8BMP(4, 2, red), Options{MaxWidth: 1024, MaxHeight: 768})
+		if err != nil {
+			t.Fatalf("convertBMP failed: %v", err)
+		}
+		if len(notes) != 1 || notes[0] != "8-bit RLE8 BMP normalized to uncompressed 8-bit BMP" {
+			t.Errorf("notes = %v", notes)
+		}
+		img, err := bmp.Decode(bytes.NewReader(out))
+		if err != nil {
+			t.Fatalf("the standard decoder cannot read the output: %v", err)
+		}
+		if _, ok := img.(*image.Paletted); !ok {
+			t.Errorf("output is %T, want a paletted image", img)
+		}
+		if got := color.RGBAModel.Convert(img.At(3, 1)); got != red || img.Bounds().Dx() != 4 {
+			t.Errorf("pixel = %v, size %v; want red 4x2", got, img.Bounds())
+		}
+	})
+
+	t.Run("oversized image keeps its palette", func(t *testing.T) {
+		palette := color.Palette{color.RGBA{A: 0xff}, red, color.RGBA{G: 0xff, A: 0xff}}
+		src := image.NewPaletted(image.Rect(0, 0, 200, 100), palette)
+		for i := range src.Pix {
+			src.Pix[i] = uint8(i % 3)
+		}
+		out, notes, err := convertBMP(encodeBMP(t, src), Options{MaxWidth: 50, MaxHeight: 50})
+		if err != nil {
+			t.Fatalf("convertBMP failed: %v", err)
+		}
+		if len(notes) != 1 || notes[0] != "downscaled 200x100 to 50x25" {
+			t.Errorf("notes = %v", notes)
+		}
+		img, _ := bmp.Decode(bytes.NewReader(out))
+		p, ok := img.(*image.Paletted)
+		if !ok || p.Bounds().Dx() != 50 || p.Bounds().Dy() != 25 {
+			t.Fatalf("output = %T %v, want a 50x25 paletted image", img, img.Bounds())
+		}
+		for _, idx := range p.Pix {
+			if idx > 2 {
+				t.Fatalf("downscaling introduced color index %d", idx)
+			}
+		}
+	})
+
+	t.Run("readable image of the right size is unchanged", func(t *testing.T) {
+		src := image.NewRGBA(image.Rect(0, 0, 8, 8))
+		out, notes, err := convertBMP(encodeBMP(t, src), Options{MaxWidth: 1024, MaxHeight: 768})
+		if out != nil || notes != nil || err != nil {
+			t.Errorf("convertBMP = %d bytes, %v, %v; want no change", len(out), notes, err)
+		}
+	})
+
+	t.Run("broken file", func(t *testing.T) {
+		if _, _, err := convertBMP([]byte("BMbroken"), Options{}); err == nil {
+			t.Error("expected error")
+		}
+	})
+}
+
+func TestReducePalette(t *testing.T) {
+	img := image.NewRGBA(image.Rect(0, 0, 300, 1))
+	for x := range 300 {
+		img.Set(x, 0, color.RGBA{R: uint8(x % 256), G: uint8(x / 256), A: 0xff})
+	}
+	if _, ok := reducePalette(img).(*image.Paletted); ok {
+		t.Error("an image of 300 colors should not be paletted")
+	}
+	if _, ok := reducePalette(img.SubImage(image.Rect(0, 0, 256, 1))).(*image.Paletted); !ok {
+		t.Error("an image of 256 colors should be paletted")
+	}
+	img.Set(0, 0, color.RGBA{})
+	if _, ok := reducePalette(img.SubImage(image.Rect(0, 0, 10, 1))).(*image.Paletted); ok {
+		t.Error("an image with transparent pixels should not be paletted")
+	}
+}
+
+func TestConvertPNG(t *testing.T) {
+	var buf bytes.Buffer
+	png.Encode(&buf, image.NewRGBA(image.Rect(0, 0, 2048, 512)))
+	out, notes, err := convertPNG(buf.Bytes(), Options{MaxWidth: 1024, MaxHeight: 768})
+	if err != nil {
+		t.Fatalf("convertPNG failed: %v", err)
+	}
+	if len(notes) != 1 || notes[0] != "downscaled 2048x512 to 1024x256" {
+		t.Errorf("notes = %v", notes)
+	}
+	if cfg, err := png.DecodeConfig(bytes.NewReader(out)); err != nil || cfg.Width != 1024 || cfg.Height != 256 {
+		t.Errorf("output = %+v, %v; want 1024x256", cfg, err)
+	}
+
+	if out, _, err := convertPNG(buf.Bytes(), Options{}); out != nil || err != nil {
+		t.Errorf("no maximum size = %d bytes, %v; want no change", len(out), err)
+	}
+}
+
+func TestTransliterateName(t *testing.T) {
+	sjis := "\x89\xe6\x91\x9c.BMP" // 画像.BMP
+	tests := []struct {
+		name, want string
+	}{
+		{"TITLE.BMP", "TITLE.BMP"},
+		{"画像.BMP", "画像.BMP"},
+		{sjis, "画像.BMP"},
+		{"\xff\xfe.BMP", "\xff\xfe.BMP"}, // Shift-JISとしても読めない
+	}
+	for _, tt := range tests {
+		if got := transliterateName(tt.name); got != tt.want {
+			t.Errorf("transliterateName(%q) = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+	if got, want := transliteratePath(filepath.Join(sjis, sjis)), filepath.Join("画像.BMP", "画像.BMP"); got != want {
+		t.Errorf("transliteratePath = %q, want %q", got, want)
+	}
+}
+
+func TestConvert(t *testing.T) {
+	src := t.TempDir()
+	red := color.RGBA{R: 0xff, A: 0xff}
+	writeFiles(t, src, map[string][]byte{
+		"MAIN.TFY":        []byte("main() {}\n"),
+		"PIC/TITLE.BMP":   rle8BMP(4, 4, red),
+		"PIC/OK.BMP":      encodeBMP(t, image.NewRGBA(image.Rect(0, 0, 4, 4))),
+		"PIC/BROKEN.BMP":  []byte("BMbroken"),
+		"MIDI/SONG.MID":   smf(96, []byte{0x00, 0xff, 0x2f, 0x00}),
+		"MIDI/README.TXT": []byte("notes"),
+	})
+	dst := filepath.Join(t.TempDir(), "out")
+
+	report, err := Convert(src, dst, Options{MaxWidth: 1024, MaxHeight: 768, PPQ: DefaultPPQ})
+	if err != nil {
+		t.Fatalf("Convert failed: %v", err)
+	}
+	if report.Files != 6 {
+		t.Errorf("Files = %d, want 6", report.Files)
+	}
+
+	changes := make(map[string]Change)
+	for _, c := range report.Changes {
+		changes[filepath.ToSlash(c.Path)] = c
+	}
+	if len(changes) != 3 {
+		t.Errorf("changes = %+v, want TITLE.BMP, BROKEN.BMP and SONG.MID", report.Changes)
+	}
+	if c := changes["PIC/TITLE.BMP"]; len(c.Notes) != 1 || c.Err != "" {
+		t.Errorf("TITLE.BMP = %+v", c)
+	}
+	if c := changes["PIC/BROKEN.BMP"]; !strings.HasPrefix(c.Err, "left unchanged: ") {
+		t.Errorf("BROKEN.BMP = %+v, want left unchanged", c)
+	}
+	if c := changes["MIDI/SONG.MID"]; len(c.Notes) != 1 || c.Notes[0] != "PPQ 96 changed to 480" {
+		t.Errorf("SONG.MID = %+v", c)
+	}
+
+	// 変更しないファイルと変換できなかったファイルはそのまま複製する
+	for _, name := range []string{"MAIN.TFY", "PIC/OK.BMP", "PIC/BROKEN.BMP", "MIDI/README.TXT"} {
+		want, _ := os.ReadFile(filepath.Join(src, name))
+		if got, err := os.ReadFile(filepath.Join(dst, name)); err != nil || !bytes.Equal(got, want) {
+			t.Errorf("%s was not copied: %v", name, err)
+		}
+	}
+	if data, _ := os.ReadFile(filepath.Join(dst, "MIDI/SONG.MID")); binary.BigEndian.Uint16(data[12:14]) != DefaultPPQ {
+		t.Errorf("SONG.MID was not converted: % x", data)
+	}
+	// 元のファイルは変更しない
+	if data, _ := os.ReadFile(filepath.Join(src, "PIC/TITLE.BMP")); !bytes.Equal(data, rle8BMP(4, 4, red)) {
+		t.Error("the source file was changed")
+	}
+
+	var text strings.Builder
+	if err := report.WriteText(&text); err != nil {
+		t.Fatalf("WriteText failed: %v", err)
+	}
+	if !strings.Contains(text.String(), "PIC/TITLE.BMP\n      8-bit RLE8 BMP normalized") || !strings.HasSuffix(text.String(), "6 files, 2 changed, 1 with problems\n") {
+		t.Errorf("report:\n%s", text.String())
+	}
+}
+
+func TestConvertShiftJISNames(t *testing.T) {
+	src := t.TempDir()
+	sjis := "\x89\xe6\x91\x9c.TXT" // 画像.TXT
+	if err := os.WriteFile(filepath.Join(src, sjis), []byte("data"), 0o644); err != nil {
+		t.Skipf("the file system does not accept Shift-JIS file names: %v", err)
+	}
+	writeFiles(t, src, map[string][]byte{"画像.txt": []byte("other")})
+
+	report, err := Convert(src, filepath.Join(t.TempDir(), "out"), Options{})
+	if err != nil {
+		t.Fatalf("Convert failed: %v", err)
+	}
+	// 画像.txt と大文字小文字だけが違う名前になるため、変換しない
+	if len(report.Changes) != 1 || report.Changes[0].Output != sjis || !strings.Contains(report.Changes[0].Err, "name left unchanged") {
+		t.Errorf("changes = %+v, want the Shift-JIS name left unchanged", report.Changes)
+	}
+
+	src = t.TempDir()
+	os.WriteFile(filepath.Join(src, sjis), []byte("data"), 0o644)
+	dst := filepath.Join(t.TempDir(), "out")
+	report, err = Convert(src, dst, Options{})
+	if err != nil {
+		t.Fatalf("Convert failed: %v", err)
+	}
+	if len(report.Changes) != 1 || report.Changes[0].Output != "画像.TXT" {
+		t.Errorf("changes = %+v, want 画像.TXT", report.Changes)
+	}
+	if _, err := os.Stat(filepath.Join(dst, "画像.TXT")); err != nil {
+		t.Errorf("the converted name was not written: %v", err)
+	}
+}
+
+func TestConvertOutputDirectory(t *testing.T) {
+	src := t.TempDir()
+	writeFiles(t, src, map[string][]byte{"MAIN.TFY": []byte("main() {}\n")})
+
+	if _, err := Convert(src, filepath.Join(src, "out"), Options{}); err == nil {
+		t.Error("an output directory inside the title should be rejected")
+	}
+	if _, err := Convert(src, filepath.Dir(src), Options{}); err == nil {
+		t.Error("an output directory containing the title should be rejected")
+	}
+
+	dst := t.TempDir()
+	writeFiles(t, dst, map[string][]byte{"OLD.TXT": nil})
+	if _, err := Convert(src, dst, Options{}); err == nil || !strings.Contains(err.Error(), "not empty") {
+		t.Errorf("non-empty output directory: %v", err)
+	}
+	if _, err := Convert(filepath.Join(src, "MAIN.TFY"), t.TempDir(), Options{}); err == nil {
+		t.Error("a file as the title should be rejected")
+	}
+}
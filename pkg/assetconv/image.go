@@ -0,0 +1,181 @@
+package assetconv
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+
+	"golang.org/x/image/bmp"
+	"golang.org/x/image/draw"
+
+	"github.com/zurustar/son-et/pkg/graphics"
+)
+
+// maxPaletteColors は8ビットのパレット形式で書き出せる色数
+const maxPaletteColors = 256
+
+// BMPのヘッダーの読み取りに使う位置と大きさ
+const (
+	bmpInfoHeaderOffset = 14 // BITMAPFILEHEADER の後ろ
+	bmpCoreHeaderSize   = 12 // OS/2 1.x 形式の情報ヘッダー
+	bmpInfoHeaderSize   = 40 // BITMAPINFOHEADER
+)
+
+// bmpCompressionNames はBMPの圧縮方式の名前
+var bmpCompressionNames = map[uint32]string{
+	1: "RLE8",
+	2: "RLE4",
+	3: "bitfields",
+}
+
+// convertBMP は標準のデコーダーで読めないBMPを非圧縮のBMPに書き直し、大きすぎる画像を縮小する
+// FILLYの透明色は色で指定するため、縮小しても色が変わらないよう最近傍補間を使う
+func convertBMP(data []byte, opts Options) ([]byte, []string, error) {
+	var notes []string
+	img, err := bmp.Decode(bytes.NewReader(data))
+	if rle, _ := graphics.IsBMPRLECompressedFromBytes(data); err != nil || rle {
+		// エンジンが実行時に使うデコーダーで読む
+		if img, err = graphics.DecodeBMPFromBytes(data); err != nil {
+			return nil, nil, err
+		}
+		img = reducePalette(img)
+		notes = append(notes, fmt.Sprintf("%s normalized to uncompressed %s BMP", describeBMP(data), describeDepth(img)))
+	}
+
+	if scaled, ok := downscale(img, opts, draw.NearestNeighbor); ok {
+		notes = append(notes, scaleNote(img, scaled))
+		img = scaled
+	}
+	if len(notes) == 0 {
+		return nil, nil, nil
+	}
+
+	var buf bytes.Buffer
+	if err := bmp.Encode(&buf, img); err != nil {
+		return nil, nil, err
+	}
+	return buf.Bytes(), notes, nil
+}
+
+// convertPNG は大きすぎるPNG画像を縮小する
+// パレット形式の画像は色が変わらないよう最近傍補間、それ以外は滑らかに縮小する
+func convertPNG(data []byte, opts Options) ([]byte, []string, error) {
+	img, err := png.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, nil, err
+	}
+	var interp draw.Interpolator = draw.CatmullRom
+	if _, ok := img.(*image.Paletted); ok {
+		interp = draw.NearestNeighbor
+	}
+	scaled, ok := downscale(img, opts, interp)
+	if !ok {
+		return nil, nil, nil
+	}
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, scaled); err != nil {
+		return nil, nil, err
+	}
+	return buf.Bytes(), []string{scaleNote(img, scaled)}, nil
+}
+
+// downscale は opts の大きさに収まらない画像を縦横比を保って縮小する
+// パレット形式の画像は同じパレットのまま縮小する
+func downscale(img image.Image, opts Options, interp draw.Interpolator) (image.Image, bool) {
+	b := img.Bounds()
+	w, h := b.Dx(), b.Dy()
+	if opts.MaxWidth <= 0 || opts.MaxHeight <= 0 || (w <= opts.MaxWidth && h <= opts.MaxHeight) {
+		return nil, false
+	}
+
+	scale := min(float64(opts.MaxWidth)/float64(w), float64(opts.MaxHeight)/float64(h))
+	rect := image.Rect(0, 0, max(1, int(float64(w)*scale)), max(1, int(float64(h)*scale)))
+	var dst draw.Image
+	if p, ok := img.(*image.Paletted); ok {
+		dst = image.NewPaletted(rect, p.Palette)
+	} else {
+		dst = image.NewRGBA(rect)
+	}
+	interp.Scale(dst, rect, img, b, draw.Src, nil)
+	return dst, true
+}
+
+// reducePalette は256色以下の不透明な画像をパレット形式にする（パレットは現れた順の色）
+// 256色を超える画像や透明な部分のある画像はそのまま返す
+func reducePalette(img image.Image) image.Image {
+	b := img.Bounds()
+	index := make(map[color.RGBA]uint8)
+	var palette color.Palette
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		for x := b.Min.X; x < b.Max.X; x++ {
+			c := color.RGBAModel.Convert(img.At(x, y)).(color.RGBA)
+			if c.A != 0xff {
+				return img
+			}
+			if _, ok := index[c]; ok {
+				continue
+			}
+			if len(palette) == maxPaletteColors {
+				return img
+			}
+			index[c] = uint8(len(palette))
+			palette = append(palette, c)
+		}
+	}
+
+	p := image.NewPaletted(b, palette)
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		for x := b.Min.X; x < b.Max.X; x++ {
+			p.SetColorIndex(x, y, index[color.RGBAModel.Convert(img.At(x, y)).(color.RGBA)])
+		}
+	}
+	return p
+}
+
+// describeBMP はBMPの形式（ビット数・圧縮方式・OS/2形式）を説明する文字列を返す
+func describeBMP(data []byte) string {
+	if len(data) < bmpInfoHeaderOffset+4 {
+		return "BMP"
+	}
+	header := data[bmpInfoHeaderOffset:]
+	size := binary.LittleEndian.Uint32(header)
+	var bitCount uint16
+	var compression uint32
+	switch {
+	case size == bmpCoreHeaderSize && len(header) >= bmpCoreHeaderSize:
+		bitCount = binary.LittleEndian.Uint16(header[10:])
+	case len(header) >= 20:
+		bitCount = binary.LittleEndian.Uint16(header[14:])
+		compression = binary.LittleEndian.Uint32(header[16:])
+	}
+
+	desc := fmt.Sprintf("%d-bit", bitCount)
+	if name, ok := bmpCompressionNames[compression]; ok {
+		desc += " " + name
+	}
+	if size < bmpInfoHeaderSize {
+		desc += " OS/2"
+	}
+	return desc + " BMP"
+}
+
+// describeDepth は書き出すBMPのビット数を説明する文字列を返す
+// （bmp.Encode はパレット形式を8ビット、不透明な画像を24ビット、それ以外を32ビットで書き出す）
+func describeDepth(img image.Image) string {
+	if _, ok := img.(*image.Paletted); ok {
+		return "8-bit"
+	}
+	if o, ok := img.(interface{ Opaque() bool }); ok && o.Opaque() {
+		return "24-bit"
+	}
+	return "32-bit"
+}
+
+// scaleNote は縮小の説明を返す
+func scaleNote(from, to image.Image) string {
+	return fmt.Sprintf("downscaled %dx%d to %dx%d", from.Bounds().Dx(), from.Bounds().Dy(), to.Bounds().Dx(), to.Bounds().Dy())
+}
@@ -0,0 +1,170 @@
+package assetconv
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+)
+
+// SMF（スタンダードMIDIファイル）のチャンク
+const (
+	smfHeaderID     = "MThd"
+	smfTrackID      = "MTrk"
+	smfChunkHeader  = 8      // チャンクのIDと長さ
+	smfHeaderLength = 6      // ヘッダーチャンクの長さ（形式、トラック数、分解能）
+	smfSMPTE        = 0x8000 // 分解能の最上位ビットが立っている場合はSMPTE形式（タイムコード）
+)
+
+// errTruncatedMIDI はSMFが途中で終わっている場合のエラー
+var errTruncatedMIDI = errors.New("truncated MIDI file")
+
+// convertMIDI はSMFの分解能（PPQ）を ppq に変えて書き直す
+// 各イベントの時刻をティックの比で換算するため、テンポと演奏時間は変わらない。
+// イベントの内容（ランニングステータスを含む）はそのまま残す。
+func convertMIDI(data []byte, ppq int) ([]byte, []string, error) {
+	if ppq <= 0 {
+		return nil, nil, nil
+	}
+	if len(data) < smfChunkHeader+smfHeaderLength || string(data[:4]) != smfHeaderID {
+		return nil, nil, errors.New("not a standard MIDI file")
+	}
+	headerEnd := smfChunkHeader + int(binary.BigEndian.Uint32(data[4:8]))
+	if headerEnd < smfChunkHeader+smfHeaderLength || headerEnd > len(data) {
+		return nil, nil, errTruncatedMIDI
+	}
+	division := int(binary.BigEndian.Uint16(data[12:14]))
+	switch {
+	case division&smfSMPTE != 0:
+		return nil, nil, errors.New("SMPTE time division cannot be converted to PPQ")
+	case division == 0:
+		return nil, nil, errors.New("invalid time division 0")
+	case division == ppq:
+		return nil, nil, nil
+	}
+
+	out := append([]byte(nil), data[:headerEnd]...)
+	binary.BigEndian.PutUint16(out[12:14], uint16(ppq))
+	for pos := headerEnd; pos < len(data); {
+		if pos+smfChunkHeader > len(data) {
+			return nil, nil, errTruncatedMIDI
+		}
+		start := pos
+		end := pos + smfChunkHeader + int(binary.BigEndian.Uint32(data[pos+4:pos+8]))
+		if end > len(data) || end < pos {
+			return nil, nil, errTruncatedMIDI
+		}
+		pos = end
+
+		// トラック以外のチャンクはそのまま残す
+		if string(data[start:start+4]) != smfTrackID {
+			out = append(out, data[start:end]...)
+			continue
+		}
+		track, err := rescaleTrack(data[start+smfChunkHeader:end], division, ppq)
+		if err != nil {
+			return nil, nil, err
+		}
+		out = append(out, smfTrackID...)
+		out = binary.BigEndian.AppendUint32(out, uint32(len(track)))
+		out = append(out, track...)
+	}
+
+	note := fmt.Sprintf("PPQ %d changed to %d", division, ppq)
+	if ppq < division {
+		note += " (timing rounded to the coarser resolution)"
+	}
+	return out, []string{note}, nil
+}
+
+// rescaleTrack はトラックのイベントの時刻を from から to の分解能に換算する
+// 換算は開始からの時刻で行うため、丸めの誤差は積み重ならない。
+func rescaleTrack(track []byte, from, to int) ([]byte, error) {
+	var out []byte
+	var running byte // ランニングステータス
+	var tick, prev int64
+	for i := 0; i < len(track); {
+		delta, n, err := readVarLen(track[i:])
+		if err != nil {
+			return nil, err
+		}
+		tick += int64(delta)
+		i += n
+		if i >= len(track) {
+			return nil, errTruncatedMIDI
+		}
+
+		start := i
+		switch status := track[i]; {
+		case status == 0xff: // メタイベント（種類、長さ、データ）
+			if i+2 > len(track) {
+				return nil, errTruncatedMIDI
+			}
+			length, n, err := readVarLen(track[i+2:])
+			if err != nil {
+				return nil, err
+			}
+			i += 2 + n + int(length)
+		case status == 0xf0 || status == 0xf7: // システムエクスクルーシブ（長さ、データ）
+			length, n, err := readVarLen(track[i+1:])
+			if err != nil {
+				return nil, err
+			}
+			i += 1 + n + int(length)
+		case status >= 0xf0:
+			return nil, fmt.Errorf("unexpected status byte 0x%02X in track", status)
+		case status >= 0x80:
+			running = status
+			i += 1 + channelDataLen(status)
+		default:
+			if running == 0 {
+				return nil, errors.New("data byte without status in track")
+			}
+			i += channelDataLen(running)
+		}
+		if i > len(track) {
+			return nil, errTruncatedMIDI
+		}
+
+		scaled := (tick*int64(to) + int64(from)/2) / int64(from)
+		out = appendVarLen(out, uint32(scaled-prev))
+		out = append(out, track[start:i]...)
+		prev = scaled
+	}
+	return out, nil
+}
+
+// channelDataLen はチャンネルメッセージのデータバイトの数を返す
+func channelDataLen(status byte) int {
+	switch status & 0xf0 {
+	case 0xc0, 0xd0: // プログラムチェンジ、チャンネルプレッシャー
+		return 1
+	}
+	return 2
+}
+
+// readVarLen はSMFの可変長数値（最大4バイト）を読み、値と読んだバイト数を返す
+func readVarLen(b []byte) (uint32, int, error) {
+	var v uint32
+	for i := 0; i < 4; i++ {
+		if i >= len(b) {
+			return 0, 0, errTruncatedMIDI
+		}
+		v = v<<7 | uint32(b[i]&0x7f)
+		if b[i]&0x80 == 0 {
+			return v, i + 1, nil
+		}
+	}
+	return 0, 0, errors.New("variable-length quantity longer than 4 bytes")
+}
+
+// appendVarLen はSMFの可変長数値を b に追加する
+func appendVarLen(b []byte, v uint32) []byte {
+	var buf [4]byte
+	n := len(buf) - 1
+	buf[n] = byte(v & 0x7f)
+	for v >>= 7; v > 0; v >>= 7 {
+		n--
+		buf[n] = byte(v&0x7f) | 0x80
+	}
+	return append(b, buf[n:]...)
+}
@@ -0,0 +1,142 @@
+package assetconv
+
+import (
+	"bytes"
+	"encoding/binary"
+	"strings"
+	"testing"
+)
+
+// smf は分解能 division のSMFを作る（tracks はトラックの中身）
+func smf(division int, tracks ...[]byte) []byte {
+	b := []byte(smfHeaderID)
+	b = binary.BigEndian.AppendUint32(b, smfHeaderLength)
+	b = binary.BigEndian.AppendUint16(b, 1)
+	b = binary.BigEndian.AppendUint16(b, uint16(len(tracks)))
+	b = binary.BigEndian.AppendUint16(b, uint16(division))
+	for _, t := range tracks {
+		b = append(b, smfTrackID...)
+		b = binary.BigEndian.AppendUint32(b, uint32(len(t)))
+		b = append(b, t...)
+	}
+	return b
+}
+
+func TestVarLen(t *testing.T) {
+	for _, v := range []uint32{0, 0x40, 0x7f, 0x80, 0x2000, 0x3fff, 0x4000, 0x0fffffff} {
+		b := appendVarLen(nil, v)
+		got, n, err := readVarLen(b)
+		if err != nil || got != v || n != len(b) {
+			t.Errorf("readVarLen(appendVarLen(%#x)) = %#x, %d, %v (encoded % x)", v, got, n, err, b)
+		}
+	}
+	if _, _, err := readVarLen([]byte{0x81, 0x80}); err == nil {
+		t.Error("truncated quantity should fail")
+	}
+	if _, _, err := readVarLen([]byte{0x81, 0x80, 0x80, 0x80, 0x00}); err == nil {
+		t.Error("5-byte quantity should fail")
+	}
+}
+
+func TestConvertMIDI(t *testing.T) {
+	track := []byte{
+		0x00, 0xff, 0x51, 0x03, 0x07, 0xa1, 0x20, // テンポ
+		0x00, 0xf0, 0x02, 0x7e, 0xf7, // システムエクスクルーシブ
+		0x00, 0xc0, 0x05, // プログラムチェンジ（データ1バイト）
+		0x00, 0x90, 0x3c, 0x64, // ノートオン
+		0x60, 0x3c, 0x00, // ランニングステータスのノートオフ（96ティック後）
+		0x81, 0x40, 0x80, 0x3e, 0x00, // 192ティック後
+		0x00, 0xff, 0x2f, 0x00, // トラックの終わり
+	}
+	data := smf(96, track)
+
+	out, notes, err := convertMIDI(data, 480)
+	if err != nil {
+		t.Fatalf("convertMIDI failed: %v", err)
+	}
+	if len(notes) != 1 || notes[0] != "PPQ 96 changed to 480" {
+		t.Errorf("notes = %v", notes)
+	}
+	want := smf(480, []byte{
+		0x00, 0xff, 0x51, 0x03, 0x07, 0xa1, 0x20,
+		0x00, 0xf0, 0x02, 0x7e, 0xf7,
+		0x00, 0xc0, 0x05,
+		0x00, 0x90, 0x3c, 0x64,
+		0x83, 0x60, 0x3c, 0x00, // 480
+		0x87, 0x40, 0x80, 0x3e, 0x00, // 960
+		0x00, 0xff, 0x2f, 0x00,
+	})
+	if !bytes.Equal(out, want) {
+		t.Errorf("convertMIDI =\n% x\nwant\n% x", out, want)
+	}
+
+	// 元に戻すと同じファイルになる
+	back, _, err := convertMIDI(out, 96)
+	if err != nil || !bytes.Equal(back, data) {
+		t.Errorf("converting back = % x, %v; want % x", back, err, data)
+	}
+}
+
+func TestConvertMIDIRounding(t *testing.T) {
+	// 3ティックごとのイベントを分解能 480 から 96 にすると、開始からの時刻で丸める
+	var track []byte
+	for range 5 {
+		track = append(track, 0x03, 0xc0, 0x01)
+	}
+	out, notes, err := convertMIDI(smf(480, track), 96)
+	if err != nil {
+		t.Fatalf("convertMIDI failed: %v", err)
+	}
+	if !strings.Contains(notes[0], "rounded") {
+		t.Errorf("notes = %v, want a note about rounding", notes)
+	}
+	// 3, 6, 9, 12, 15 → 0.6, 1.2, 1.8, 2.4, 3.0 → 1, 1, 2, 2, 3
+	want := smf(96, []byte{0x01, 0xc0, 0x01, 0x00, 0xc0, 0x01, 0x01, 0xc0, 0x01, 0x00, 0xc0, 0x01, 0x01, 0xc0, 0x01})
+	if !bytes.Equal(out, want) {
+		t.Errorf("convertMIDI =\n% x\nwant\n% x", out, want)
+	}
+}
+
+func TestConvertMIDIUnchanged(t *testing.T) {
+	data := smf(480, []byte{0x00, 0xff, 0x2f, 0x00})
+	if out, notes, err := convertMIDI(data, 480); out != nil || notes != nil || err != nil {
+		t.Errorf("same PPQ = %v, %v, %v; want no change", out, notes, err)
+	}
+	if out, _, err := convertMIDI(data, 0); out != nil || err != nil {
+		t.Errorf("PPQ 0 = %v, %v; want no change", out, err)
+	}
+}
+
+func TestConvertMIDIKeepsOtherChunks(t *testing.T) {
+	data := smf(96, []byte{0x00, 0xff, 0x2f, 0x00})
+	data = append(data, "XFIH"...)
+	data = binary.BigEndian.AppendUint32(data, 2)
+	data = append(data, 0x12, 0x34)
+
+	out, _, err := convertMIDI(data, 480)
+	if err != nil {
+		t.Fatalf("convertMIDI failed: %v", err)
+	}
+	if !bytes.HasSuffix(out, []byte{'X', 'F', 'I', 'H', 0, 0, 0, 2, 0x12, 0x34}) {
+		t.Errorf("unknown chunk was not kept: % x", out)
+	}
+}
+
+func TestConvertMIDIErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		data []byte
+	}{
+		{"not MIDI", []byte("RIFF0000WAVEfmt ")},
+		{"SMPTE", smf(0xe728, []byte{0x00, 0xff, 0x2f, 0x00})},
+		{"zero division", smf(0, []byte{0x00, 0xff, 0x2f, 0x00})},
+		{"truncated track", smf(96, []byte{0x00, 0x90, 0x3c})},
+		{"data without status", smf(96, []byte{0x00, 0x3c, 0x64})},
+		{"truncated chunk", smf(96, []byte{0x00, 0xff, 0x2f, 0x00})[:20]},
+	}
+	for _, tt := range tests {
+		if _, _, err := convertMIDI(tt.data, 480); err == nil {
+			t.Errorf("%s: expected error", tt.name)
+		}
+	}
+}
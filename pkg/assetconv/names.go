@@ -0,0 +1,33 @@
+package assetconv
+
+import (
+	"path/filepath"
+	"strings"
+	"unicode/utf8"
+
+	"golang.org/x/text/encoding/japanese"
+)
+
+// transliteratePath はパスの各要素を transliterateName で変換する
+func transliteratePath(rel string) string {
+	parts := strings.Split(rel, string(filepath.Separator))
+	for i, p := range parts {
+		parts[i] = transliterateName(p)
+	}
+	return filepath.Join(parts...)
+}
+
+// transliterateName はUTF-8として読めないファイル名をShift-JISとして読み、UTF-8にする
+// Windowsで作られたアーカイブを展開すると、ファイル名がShift-JISのまま残り、
+// UTF-8で読み込んだスクリプトのファイル名と一致しなくなるため。
+// Shift-JISとしても読めない名前はそのまま返す。
+func transliterateName(name string) string {
+	if utf8.ValidString(name) {
+		return name
+	}
+	decoded, err := japanese.ShiftJIS.NewDecoder().String(name)
+	if err != nil || strings.ContainsRune(decoded, utf8.RuneError) {
+		return name
+	}
+	return decoded
+}
@@ -1,23 +1,206 @@
 package cli
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
 	"flag"
 	"fmt"
+	"net"
+	"net/url"
 	"os"
 	"path/filepath"
 	"strconv"
 	"strings"
 	"time"
+
+	"github.com/zurustar/son-et/pkg/compat"
+	"github.com/zurustar/son-et/pkg/fileutil"
+	"github.com/zurustar/son-et/pkg/gifexport"
+	"golang.org/x/text/language"
+)
+
+// サブコマンド（最初の引数で指定する）
+const (
+	CommandLSP     = "lsp"     // stdio上でLanguage Server Protocolのサーバーを実行する
+	CommandFmt     = "fmt"     // TFYファイルを整形する
+	CommandInfo    = "info"    // タイトルを実行せずに情報を表示する
+	CommandMigrate = "migrate" // 古いFILLYの書き方をパーサーが受け付ける形に書き換える
+	CommandAssets  = "assets"  // タイトルのアセットを一括変換する（son-et assets convert）
+	CommandOpcodes = "opcodes" // VMの命令セット（OpCodeと組み込み関数の引数）の一覧を出力する
+)
+
+// son-et assets の操作
+const assetsConvert = "convert"
+
+// son-et assets convert の既定値
+const (
+	defaultAssetsMaxWidth  = 1024 // 仮想デスクトップの幅（これより大きい画像を縮小する）
+	defaultAssetsMaxHeight = 768  // 仮想デスクトップの高さ
+	defaultAssetsPPQ       = 480  // MIDIの分解能（assetconv.DefaultPPQ と同じ値）
+	maxAssetsPPQ           = 0x7fff
+	assetsOutSuffix        = "-converted" // --out を省略した場合の出力ディレクトリの接尾辞
 )
 
+// maxAVOffset は --av-offset で指定できるオフセットの絶対値の上限（audio.MaxAVOffset と同じ値）
+const maxAVOffset = time.Second
+
+// --synth-polyphony で指定できる同時発音数の範囲（audio.MinSynthPolyphony〜audio.MaxSynthPolyphony と同じ値）
+const (
+	minSynthPolyphony = 8
+	maxSynthPolyphony = 256
+)
+
+// --progress の進み具合を書き出す間隔
+const (
+	defaultProgressInterval = time.Second
+	minProgressInterval     = 10 * time.Millisecond
+)
+
+// --keyframe-interval のキーフレームの間隔の下限
+const minKeyframeInterval = time.Second
+
+// commands は使用できるサブコマンドの一覧
+var commands = map[string]bool{
+	CommandLSP:     true,
+	CommandFmt:     true,
+	CommandInfo:    true,
+	CommandMigrate: true,
+	CommandAssets:  true,
+	CommandOpcodes: true,
+}
+
 // Config はコマンドライン引数から解析された設定を保持する
 type Config struct {
-	TitlePath string        // FILLYタイトルのパス（ディレクトリ）
-	EntryFile string        // エントリーポイントファイル名（TFYファイル指定時）
-	Timeout   time.Duration // タイムアウト時間（0は無制限）
-	LogLevel  string        // ログレベル（debug, info, warn, error）
-	Headless  bool          // ヘッドレスモード
-	ShowHelp  bool          // ヘルプ表示フラグ
+	Command        string        // サブコマンド（空の場合はタイトルを実行する）
+	TitlePath      string        // FILLYタイトルのパス（ディレクトリ）
+	EntryFile      string        // エントリーポイントファイル名（TFYファイル指定時）
+	Timeout        time.Duration // タイムアウト時間（0は無制限）
+	LogLevel       string        // ログレベル（debug, info, warn, error）
+	Headless       bool          // ヘッドレスモード
+	ShowHelp       bool          // ヘルプ表示フラグ
+	PauseOnBlur    bool          // ウィンドウのフォーカス喪失時に一時停止・ミュートする
+	Sandbox        bool          // サンドボックスモード（ファイルアクセスをタイトル内に制限し、リソースを制限する）
+	Palette256     bool          // 256色表示エミュレーション（画面を256色のパレットに量子化する）
+	Seed           uint64        // Random() の実行シード（SeedSet が false の場合は実行ごとにランダム）
+	SeedSet        bool          // --seed が指定されたか
+	TPS            int           // 1秒あたりの更新回数（0は既定値の60）
+	FPS            int           // 1秒あたりの描画回数の上限（0は上限なし。#info FPS より大きい値は無視）
+	Compat         compat.Mode   // 互換モード（filly97 は拡張機能を無効にし、オリジナルのFILLYの動作を再現する）
+	CompatSet      bool          // --compat が指定されたか（指定されていない場合はプロジェクトマニフェストの compat を使う）
+	DebugBreak     bool          // DebugBreak でシーケンスを一時停止し、ローカル変数を標準エラー出力に表示する
+	DebugStateDiff bool          // 描画したフレームごとにスプライトの状態の変更と、変更したときのMIDIティックをログに記録する
+	NoCache        bool          // コード生成キャッシュ（タイトル内の .sonet-cache）を使わない
+	AVOffset       time.Duration // 音声に対する映像（MIDI_TIME）の遅れ（Bluetoothスピーカーなどの遅延の補正）
+	NoSynthEffects bool          // MIDIのシンセサイザーのリバーブ・コーラスを無効にする（低性能な端末向け）
+	SynthPolyphony int           // MIDIのシンセサイザーの同時発音数（0は既定値）
+	MIDIStrict     bool          // 壊れたMIDIファイルを修復せず、再生をエラーにする
+	AutoQuality    bool          // フレームの時間が足りない場合に影・縁取り・粒子の描画を自動的に省く
+	PowerSave      bool          // 画面が静止している間TPSを下げ、音声のバッファと画像のデコードを省電力にする
+	Metronome      bool          // MIDIの4分音符ごとにクリック音を重ねる（スクリプトの作成時に拍との一致を確かめる）
+	MetronomeFlash bool          // MIDIの4分音符ごとに画面の左上に四角形を表示する
+
+	// 表示調整（画面全体に最後に適用する。プロジェクターでの補正など）
+	Gamma      float64 // ガンマ値（1は変化なし）
+	Brightness float64 // 明るさ（-1〜1、0は変化なし）
+	Contrast   float64 // コントラスト（1は変化なし）
+
+	// GIF書き出し（--export-gif start:end output.gif）
+	ExportGIFPath  string          // 出力するGIFファイルのパス（空の場合は書き出さない）
+	ExportGIFRange gifexport.Range // 取り込む時間範囲
+	ExportGIFFPS   int             // GIFのフレームレート
+
+	RenderAudioPath string // MIDIをオフラインでレンダリングして書き出すWAVファイルのパス（空の場合は書き出さない）
+
+	ExitAfterTicks int64 // ヘッドレスモードで指定したティック数を処理した後に終了する（0は無制限。実行環境の速さによらない）
+
+	// 進み具合の出力（--progress）: ヘッドレスモードで実行の進み具合をJSONの行で書き出す
+	ProgressPath     string        // 出力先のファイルのパス、または "fd:N"（空の場合は書き出さない）
+	ProgressInterval time.Duration // レコードを書き出す間隔
+
+	ErrorJSONPath string // 終了時に終了コードとエラーの詳細をJSONで書き出すファイルのパス（空の場合は書き出さない）
+
+	Chapter string // 早送りして始めるチャプター（Chapter("name") の名前、空の場合は最初から）
+
+	KeyframeInterval time.Duration // 巻き戻し用のキーフレームを作成する間隔（0は作成しない）
+
+	InputMapPath string // ゲームパッドのボタンをキー入力に割り当てる入力マップ（JSON）のパス（空の場合は割り当てない）
+
+	StreamAssetsMB int // 画像のストリーミング読み込みで、デコード済み画像をキャッシュする上限（MB、0はストリーミングしない）
+
+	// SoundFontのダウンロード（--fetch-soundfont）
+	FetchSoundFont  bool   // SoundFontが見つからない場合にダウンロードしてユーザーのキャッシュディレクトリに保存する
+	SoundFontURL    string // ダウンロードするSoundFontのURL（空の場合は既定のURL）
+	SoundFontSHA256 string // ダウンロードするSoundFontのSHA-256（16進数、空の場合はダウンロード時の値をキャッシュに記録して以後の検証に使う）
+
+	WatchAddr string // 変数ウォッチのリモートAPIを待ち受けるアドレス（例: 127.0.0.1:8123、空の場合は起動しない）
+
+	// 複数台の時刻同期（どちらか一方のみ指定できる）
+	SyncMasterAddr string // マスターとしてフォロワーからの問い合わせを待ち受けるUDPアドレス（例: :7400）
+	SyncFollowAddr string // フォロワーとして時計を合わせるマスターのUDPアドレス（例: 192.168.0.10:7400）
+
+	OSCAllow []string // OSCSend で送信できるUDPの送信先（host:port、空の場合はOSCを送らない）
+
+	// 操作キュー（コンソールやリモートAPIからのコマンドの記録と再生）
+	RecordCuesPath string // 実行中のオペレーターのコマンドを時刻付きで記録するキューファイルのパス（空の場合は記録しない）
+	PlayCuesPath   string // 記録したコマンドを同じ時刻に実行するキューファイルのパス（空の場合は再生しない）
+
+	ScaleMode string // 仮想デスクトップの拡大方法（auto, crisp, smooth、空の場合は auto）
+
+	OutputDir string // SaveSprite で画像を書き出すディレクトリ（空の場合はタイトルディレクトリ内の output）
+
+	Locale string // FormatNumber・FormatDate のロケール（BCP 47 のタグ、空の場合は環境変数から調べる）
+
+	AssetVars map[string]string // アセットパスの変数（-A 名前=値、プロジェクトマニフェストの vars より優先）
+
+	// 整形（son-et fmt）
+	FmtCheck bool     // 整形が必要なファイルを一覧表示し、1つでもあれば失敗する（CI向け）
+	FmtWrite bool     // 整形結果を元のファイルに書き戻す
+	FmtPaths []string // 整形するTFYファイルまたはディレクトリ
+
+	InfoJSON bool // son-et info の結果をJSONで出力する
+
+	OpcodesJSON bool // son-et opcodes の一覧をJSONで出力する（false の場合はMarkdown）
+
+	// 書き換え（son-et migrate）
+	MigrateDryRun bool     // 差分を表示するだけでファイルを書き換えない
+	MigratePaths  []string // 書き換えるTFYファイルまたはタイトルのディレクトリ
+
+	// アセットの変換（son-et assets convert、変換するタイトルは TitlePath）
+	AssetsOutDir    string // 変換したタイトルを書き出すディレクトリ
+	AssetsMaxWidth  int    // これより大きい画像を縮小する幅（0は縮小しない）
+	AssetsMaxHeight int    // これより大きい画像を縮小する高さ（0は縮小しない）
+	AssetsPPQ       int    // MIDIを書き直す分解能（0は変更しない）
+}
+
+// boolFlags は値を取らないフラグの一覧（reorderArgsで次の引数を値として扱わないために使用）
+var boolFlags = map[string]bool{
+	"-h":                 true,
+	"--help":             true,
+	"--headless":         true,
+	"--pause-on-blur":    true,
+	"--sandbox":          true,
+	"--palette-256":      true,
+	"--debug-break":      true,
+	"--debug-state-diff": true,
+	"--no-cache":         true,
+	"--no-synth-effects": true,
+	"--midi-strict":      true,
+	"--adaptive-quality": true,
+	"--power-save":       true,
+	"--metronome":        true,
+	"--metronome-flash":  true,
+	"--check":            true,
+	"-w":                 true,
+	"--write":            true,
+	"--json":             true,
+	"--fetch-soundfont":  true,
+	"--dry-run":          true,
+}
+
+// exportGIFFlags は範囲と出力ファイルの2つの値を取るフラグ（--export-gif start:end output.gif）
+var exportGIFFlags = map[string]bool{
+	"-export-gif":  true,
+	"--export-gif": true,
 }
 
 // ParseArgs コマンドライン引数を解析してConfigを返す
@@ -25,19 +208,155 @@ type Config struct {
 // Requirement 12.8: System supports enabling headless mode via environment variable.
 // Requirement 13.5: System supports timeout specification via command line flag.
 func ParseArgs(args []string) (*Config, error) {
+	config := &Config{}
+
+	// サブコマンドは最初の引数でのみ指定できる
+	if len(args) > 0 && commands[args[0]] {
+		config.Command = args[0]
+		args = args[1:]
+	}
+	switch config.Command {
+	case CommandFmt:
+		return parseFmtArgs(args, config)
+	case CommandInfo:
+		return parseInfoArgs(args, config)
+	case CommandMigrate:
+		return parseMigrateArgs(args, config)
+	case CommandAssets:
+		return parseAssetsArgs(args, config)
+	case CommandOpcodes:
+		return parseOpcodesArgs(args, config)
+	}
+
+	// 2つの値を取る --export-gif は flag パッケージで扱えないため先に取り出す
+	args, err := extractExportGIF(args, config)
+	if err != nil {
+		return nil, err
+	}
+
 	// 引数を並べ替え：フラグを前に、位置引数を後ろに
 	reorderedArgs := reorderArgs(args)
 
 	fs := flag.NewFlagSet("son-et", flag.ContinueOnError)
 
-	config := &Config{}
-
 	var timeoutSec int
 	fs.IntVar(&timeoutSec, "timeout", 0, "タイムアウト時間（秒）")
 	fs.IntVar(&timeoutSec, "t", 0, "タイムアウト時間（秒）（短縮形）")
 	fs.StringVar(&config.LogLevel, "log-level", "info", "ログレベル（debug, info, warn, error）")
 	fs.StringVar(&config.LogLevel, "l", "info", "ログレベル（短縮形）")
 	fs.BoolVar(&config.Headless, "headless", false, "ヘッドレスモード")
+	fs.BoolVar(&config.PauseOnBlur, "pause-on-blur", false, "フォーカス喪失時に一時停止")
+	fs.BoolVar(&config.Sandbox, "sandbox", false, "サンドボックスモード")
+	fs.BoolVar(&config.Palette256, "palette-256", false, "256色表示エミュレーション")
+	fs.BoolVar(&config.DebugBreak, "debug-break", false, "DebugBreakで一時停止")
+	fs.BoolVar(&config.DebugStateDiff, "debug-state-diff", false, "フレーム間のスプライトの状態の差分をログに記録")
+	fs.BoolVar(&config.NoCache, "no-cache", false, "コード生成キャッシュを使わない")
+	fs.Func("seed", "Random() の実行シード", func(value string) error {
+		seed, err := strconv.ParseUint(value, 0, 64)
+		if err != nil {
+			return fmt.Errorf("seed must be a non-negative integer, got %s", value)
+		}
+		config.Seed = seed
+		config.SeedSet = true
+		return nil
+	})
+	fs.Func("compat", "互換モード（filly97, extended）", func(value string) error {
+		mode, err := compat.Parse(value)
+		if err != nil {
+			return err
+		}
+		config.Compat = mode
+		config.CompatSet = true
+		return nil
+	})
+	fs.Func("av-offset", "音声に対する映像の遅れ（例: 40ms）", func(value string) error {
+		offset, err := parseAVOffset(value)
+		if err != nil {
+			return err
+		}
+		config.AVOffset = offset
+		return nil
+	})
+	fs.BoolVar(&config.NoSynthEffects, "no-synth-effects", false, "MIDIのリバーブ・コーラスを無効にする")
+	fs.IntVar(&config.SynthPolyphony, "synth-polyphony", 0, "MIDIの同時発音数")
+	fs.BoolVar(&config.MIDIStrict, "midi-strict", false, "壊れたMIDIファイルを修復せずにエラーにする")
+	fs.BoolVar(&config.AutoQuality, "adaptive-quality", false, "フレームの時間が足りない場合に描画品質を自動的に下げる")
+	fs.BoolVar(&config.PowerSave, "power-save", false, "画面が静止している間のCPU使用率を下げる（バッテリー駆動の端末向け）")
+	fs.BoolVar(&config.Metronome, "metronome", false, "MIDIの4分音符ごとにクリック音を重ねる")
+	fs.BoolVar(&config.MetronomeFlash, "metronome-flash", false, "MIDIの4分音符ごとに画面の左上に四角形を表示する")
+	fs.Int64Var(&config.ExitAfterTicks, "exit-after-ticks", 0, "指定したティック数の後に終了する（ヘッドレスモード）")
+	fs.StringVar(&config.ProgressPath, "progress", "", "進み具合をJSONの行で書き出す先（ファイルまたは fd:N）")
+	fs.DurationVar(&config.ProgressInterval, "progress-interval", defaultProgressInterval, "進み具合を書き出す間隔")
+	fs.StringVar(&config.ErrorJSONPath, "error-json", "", "終了コードとエラーの詳細を書き出すJSONファイル")
+	fs.StringVar(&config.Chapter, "chapter", "", "指定したチャプターまで早送りして始める")
+	fs.DurationVar(&config.KeyframeInterval, "keyframe-interval", 0, "巻き戻し用のキーフレームを作成する間隔")
+	fs.IntVar(&config.TPS, "tps", 0, "1秒あたりの更新回数")
+	fs.IntVar(&config.FPS, "fps", 0, "1秒あたりの描画回数の上限")
+	fs.Float64Var(&config.Gamma, "gamma", 1, "ガンマ値")
+	fs.Float64Var(&config.Brightness, "brightness", 0, "明るさ")
+	fs.Float64Var(&config.Contrast, "contrast", 1, "コントラスト")
+	fs.IntVar(&config.ExportGIFFPS, "export-gif-fps", gifexport.DefaultFPS, "GIFのフレームレート")
+	fs.StringVar(&config.RenderAudioPath, "render-audio", "", "MIDIをオフラインでWAVに書き出す")
+	fs.StringVar(&config.InputMapPath, "input-map", "", "ゲームパッドのボタンをキー入力に割り当てる入力マップ（JSON）")
+	fs.IntVar(&config.StreamAssetsMB, "stream-assets", 0, "画像をストリーミング読み込みする（キャッシュの上限、MB）")
+	fs.BoolVar(&config.FetchSoundFont, "fetch-soundfont", false, "SoundFontが見つからない場合にダウンロードする")
+	fs.StringVar(&config.SoundFontURL, "soundfont-url", "", "ダウンロードするSoundFontのURL")
+	fs.StringVar(&config.SoundFontSHA256, "soundfont-sha256", "", "ダウンロードするSoundFontのSHA-256")
+	fs.Func("watch-addr", "変数ウォッチのリモートAPIを待ち受けるアドレス（例: 127.0.0.1:8123）", func(value string) error {
+		if _, _, err := net.SplitHostPort(value); err != nil {
+			return fmt.Errorf("watch-addr must be host:port such as 127.0.0.1:8123, got %s", value)
+		}
+		config.WatchAddr = value
+		return nil
+	})
+	fs.Func("sync-master", "時刻同期のマスターとして待ち受けるUDPアドレス（例: :7400）", func(value string) error {
+		if _, _, err := net.SplitHostPort(value); err != nil {
+			return fmt.Errorf("sync-master must be host:port such as :7400, got %s", value)
+		}
+		config.SyncMasterAddr = value
+		return nil
+	})
+	fs.Func("sync-follow", "時計を合わせるマスターのUDPアドレス（例: 192.168.0.10:7400）", func(value string) error {
+		if host, _, err := net.SplitHostPort(value); err != nil || host == "" {
+			return fmt.Errorf("sync-follow must be host:port such as 192.168.0.10:7400, got %s", value)
+		}
+		config.SyncFollowAddr = value
+		return nil
+	})
+	fs.Func("osc-allow", "OSCSend で送信を許可する送信先（例: 127.0.0.1:9000、カンマ区切りまたは複数回指定）", func(value string) error {
+		for _, addr := range strings.Split(value, ",") {
+			addr = strings.TrimSpace(addr)
+			if host, _, err := net.SplitHostPort(addr); err != nil || host == "" {
+				return fmt.Errorf("osc-allow must be host:port such as 127.0.0.1:9000, got %s", addr)
+			}
+			config.OSCAllow = append(config.OSCAllow, addr)
+		}
+		return nil
+	})
+	fs.StringVar(&config.RecordCuesPath, "record-cues", "", "オペレーターのコマンドを時刻付きで記録するキューファイル")
+	fs.StringVar(&config.PlayCuesPath, "play-cues", "", "記録したコマンドを同じ時刻に実行するキューファイル")
+	fs.StringVar(&config.ScaleMode, "scale-mode", "auto", "仮想デスクトップの拡大方法（auto, crisp, smooth）")
+	fs.StringVar(&config.OutputDir, "output-dir", "", "SaveSprite で画像を書き出すディレクトリ")
+	fs.Func("locale", "FormatNumber・FormatDate のロケール（例: ja-JP、en-US、ja-JP-u-ca-japanese）", func(value string) error {
+		if _, err := language.Parse(value); err != nil {
+			return fmt.Errorf("locale must be a BCP 47 tag such as ja-JP or en-US, got %s", value)
+		}
+		config.Locale = value
+		return nil
+	})
+	assetVar := func(value string) error {
+		name, v, err := fileutil.ParseVar(value)
+		if err != nil {
+			return err
+		}
+		if config.AssetVars == nil {
+			config.AssetVars = make(map[string]string)
+		}
+		config.AssetVars[name] = v
+		return nil
+	}
+	fs.Func("asset-var", "アセットパスの変数（名前=値、複数回指定）", assetVar)
+	fs.Func("A", "アセットパスの変数（短縮形）", assetVar)
 	fs.BoolVar(&config.ShowHelp, "help", false, "ヘルプを表示")
 	fs.BoolVar(&config.ShowHelp, "h", false, "ヘルプを表示（短縮形）")
 
@@ -75,6 +394,25 @@ func ParseArgs(args []string) (*Config, error) {
 	}
 	config.Timeout = time.Duration(timeoutSec) * time.Second
 
+	// ティック数での終了はVMだけを実行するヘッドレスモードでのみ使用できる
+	if config.ExitAfterTicks < 0 {
+		return nil, fmt.Errorf("exit-after-ticks must be non-negative, got %d", config.ExitAfterTicks)
+	}
+	if config.ExitAfterTicks > 0 && (!config.Headless || config.ExportGIFPath != "") {
+		return nil, fmt.Errorf("exit-after-ticks requires --headless and cannot be used with --export-gif")
+	}
+
+	// 進み具合の出力もVMだけを実行するヘッドレスモードでのみ使用できる
+	if config.ProgressPath != "" && (!config.Headless || config.ExportGIFPath != "") {
+		return nil, fmt.Errorf("progress requires --headless and cannot be used with --export-gif")
+	}
+	if config.ProgressInterval < minProgressInterval {
+		return nil, fmt.Errorf("progress-interval must be at least %v, got %v", minProgressInterval, config.ProgressInterval)
+	}
+	if config.KeyframeInterval != 0 && config.KeyframeInterval < minKeyframeInterval {
+		return nil, fmt.Errorf("keyframe-interval must be 0 or at least %v, got %v", minKeyframeInterval, config.KeyframeInterval)
+	}
+
 	// ログレベルの検証
 	validLogLevels := map[string]bool{
 		"debug": true,
@@ -86,22 +424,301 @@ func ParseArgs(args []string) (*Config, error) {
 		return nil, fmt.Errorf("invalid log level: %s (must be debug, info, warn, or error)", config.LogLevel)
 	}
 
+	// 拡大方法の検証
+	validScaleModes := map[string]bool{
+		"auto":   true,
+		"crisp":  true,
+		"smooth": true,
+	}
+	if !validScaleModes[config.ScaleMode] {
+		return nil, fmt.Errorf("invalid scale mode: %s (must be auto, crisp, or smooth)", config.ScaleMode)
+	}
+
+	// TPS/FPSの検証（上限を超える値はウィンドウ側で切り詰める）
+	if config.TPS < 0 {
+		return nil, fmt.Errorf("tps must be non-negative, got %d", config.TPS)
+	}
+	if config.FPS < 0 {
+		return nil, fmt.Errorf("fps must be non-negative, got %d", config.FPS)
+	}
+
+	// ストリーミング読み込みのキャッシュの上限の検証
+	if config.SynthPolyphony != 0 && (config.SynthPolyphony < minSynthPolyphony || config.SynthPolyphony > maxSynthPolyphony) {
+		return nil, fmt.Errorf("synth-polyphony must be between %d and %d, got %d", minSynthPolyphony, maxSynthPolyphony, config.SynthPolyphony)
+	}
+	if config.StreamAssetsMB < 0 {
+		return nil, fmt.Errorf("stream-assets must be non-negative, got %d", config.StreamAssetsMB)
+	}
+
+	// GIFのフレームレートの検証
+	if config.ExportGIFFPS <= 0 || config.ExportGIFFPS > gifexport.MaxFPS {
+		return nil, fmt.Errorf("export-gif-fps must be 1-%d, got %d", gifexport.MaxFPS, config.ExportGIFFPS)
+	}
+
+	// オフラインレンダリングの出力形式の検証（WAVのみ対応）
+	if config.RenderAudioPath != "" && !strings.EqualFold(filepath.Ext(config.RenderAudioPath), ".wav") {
+		return nil, fmt.Errorf("render-audio supports only .wav output, got %s", config.RenderAudioPath)
+	}
+
+	// SoundFontのダウンロード元の検証
+	if err := validateSoundFontSource(config); err != nil {
+		return nil, err
+	}
+
+	// 時刻同期はマスターかフォロワーのどちらか一方
+	if config.SyncMasterAddr != "" && config.SyncFollowAddr != "" {
+		return nil, fmt.Errorf("sync-master and sync-follow cannot be used together")
+	}
+
 	// 位置引数（FILLYタイトルのパス）
 	if fs.NArg() > 0 {
-		path := fs.Arg(0)
+		setTitlePath(config, fs.Arg(0))
+	}
 
-		// TFYファイルが指定された場合、ディレクトリとエントリーファイルに分離
-		if strings.HasSuffix(strings.ToLower(path), ".tfy") {
-			config.TitlePath = filepath.Dir(path)
-			config.EntryFile = filepath.Base(path)
-		} else {
-			config.TitlePath = path
+	return config, nil
+}
+
+// validateSoundFontSource は --soundfont-url と --soundfont-sha256 を検証する
+// どちらも --fetch-soundfont と一緒に指定する必要がある
+func validateSoundFontSource(config *Config) error {
+	if (config.SoundFontURL != "" || config.SoundFontSHA256 != "") && !config.FetchSoundFont {
+		return fmt.Errorf("soundfont-url and soundfont-sha256 require --fetch-soundfont")
+	}
+	if config.SoundFontURL != "" {
+		u, err := url.Parse(config.SoundFontURL)
+		if err != nil || (u.Scheme != "https" && u.Scheme != "http") || u.Host == "" {
+			return fmt.Errorf("soundfont-url must be an http or https URL, got %s", config.SoundFontURL)
+		}
+	}
+	if config.SoundFontSHA256 != "" {
+		sum, err := hex.DecodeString(config.SoundFontSHA256)
+		if err != nil || len(sum) != sha256.Size {
+			return fmt.Errorf("soundfont-sha256 must be %d hexadecimal digits, got %s", sha256.Size*2, config.SoundFontSHA256)
 		}
+		config.SoundFontSHA256 = strings.ToLower(config.SoundFontSHA256)
+	}
+	return nil
+}
+
+// parseAVOffset は --av-offset の値を解析する
+// 単位のない数値はミリ秒として扱う（"40" は "40ms" と同じ）
+func parseAVOffset(value string) (time.Duration, error) {
+	offset, err := time.ParseDuration(value)
+	if err != nil {
+		ms, convErr := strconv.Atoi(value)
+		if convErr != nil {
+			return 0, fmt.Errorf("av-offset must be a duration such as 40ms or -20ms, got %s", value)
+		}
+		offset = time.Duration(ms) * time.Millisecond
+	}
+	if offset < -maxAVOffset || offset > maxAVOffset {
+		return 0, fmt.Errorf("av-offset must be between -%v and %v, got %s", maxAVOffset, maxAVOffset, value)
+	}
+	return offset, nil
+}
+
+// setTitlePath はタイトルのパスを設定する
+// TFYファイルが指定された場合、ディレクトリとエントリーファイルに分離する
+func setTitlePath(config *Config, path string) {
+	if strings.HasSuffix(strings.ToLower(path), ".tfy") {
+		config.TitlePath = filepath.Dir(path)
+		config.EntryFile = filepath.Base(path)
+	} else {
+		config.TitlePath = path
+	}
+}
+
+// parseInfoArgs は son-et info の引数（フラグとタイトルのパス）を解析する
+func parseInfoArgs(args []string, config *Config) (*Config, error) {
+	fs := flag.NewFlagSet("son-et info", flag.ContinueOnError)
+	fs.BoolVar(&config.InfoJSON, "json", false, "JSONで出力")
+	fs.Func("compat", "互換モード（filly97, extended）", func(value string) error {
+		mode, err := compat.Parse(value)
+		if err != nil {
+			return err
+		}
+		config.Compat = mode
+		config.CompatSet = true
+		return nil
+	})
+	fs.StringVar(&config.LogLevel, "log-level", "warn", "ログレベル（debug, info, warn, error）")
+	fs.BoolVar(&config.ShowHelp, "help", false, "ヘルプを表示")
+	fs.BoolVar(&config.ShowHelp, "h", false, "ヘルプを表示（短縮形）")
+
+	if err := fs.Parse(reorderArgs(args)); err != nil {
+		return nil, err
+	}
+	if config.ShowHelp {
+		return config, nil
 	}
+	if fs.NArg() != 1 {
+		return nil, fmt.Errorf("info: specify exactly one title path")
+	}
+	setTitlePath(config, fs.Arg(0))
+	return config, nil
+}
+
+// parseOpcodesArgs は son-et opcodes の引数（出力形式）を解析する
+func parseOpcodesArgs(args []string, config *Config) (*Config, error) {
+	var markdown bool
+	fs := flag.NewFlagSet("son-et opcodes", flag.ContinueOnError)
+	fs.BoolVar(&config.OpcodesJSON, "json", false, "JSONで出力")
+	fs.BoolVar(&markdown, "markdown", false, "Markdownの表で出力（既定）")
+	fs.BoolVar(&config.ShowHelp, "help", false, "ヘルプを表示")
+	fs.BoolVar(&config.ShowHelp, "h", false, "ヘルプを表示（短縮形）")
 
+	if err := fs.Parse(args); err != nil {
+		return nil, err
+	}
+	if config.ShowHelp {
+		return config, nil
+	}
+	if config.OpcodesJSON && markdown {
+		return nil, fmt.Errorf("opcodes: --json and --markdown cannot be used together")
+	}
+	if fs.NArg() != 0 {
+		return nil, fmt.Errorf("opcodes: unexpected argument %s", fs.Arg(0))
+	}
 	return config, nil
 }
 
+// parseFmtArgs は son-et fmt の引数（フラグと整形するパス）を解析する
+func parseFmtArgs(args []string, config *Config) (*Config, error) {
+	fs := flag.NewFlagSet("son-et fmt", flag.ContinueOnError)
+	fs.BoolVar(&config.FmtCheck, "check", false, "整形が必要なファイルを一覧表示")
+	fs.BoolVar(&config.FmtWrite, "w", false, "整形結果をファイルに書き戻す")
+	fs.BoolVar(&config.FmtWrite, "write", false, "整形結果をファイルに書き戻す")
+	fs.StringVar(&config.LogLevel, "log-level", "info", "ログレベル（debug, info, warn, error）")
+	fs.BoolVar(&config.ShowHelp, "help", false, "ヘルプを表示")
+	fs.BoolVar(&config.ShowHelp, "h", false, "ヘルプを表示（短縮形）")
+
+	if err := fs.Parse(reorderArgs(args)); err != nil {
+		return nil, err
+	}
+	if config.ShowHelp {
+		return config, nil
+	}
+
+	if config.FmtCheck && config.FmtWrite {
+		return nil, fmt.Errorf("fmt: --check and -w cannot be used together")
+	}
+	config.FmtPaths = fs.Args()
+	if len(config.FmtPaths) == 0 {
+		return nil, fmt.Errorf("fmt: no files or directories specified")
+	}
+	return config, nil
+}
+
+// parseMigrateArgs は son-et migrate の引数（フラグと書き換えるパス）を解析する
+func parseMigrateArgs(args []string, config *Config) (*Config, error) {
+	fs := flag.NewFlagSet("son-et migrate", flag.ContinueOnError)
+	fs.BoolVar(&config.MigrateDryRun, "dry-run", false, "差分を表示するだけでファイルを書き換えない")
+	fs.StringVar(&config.LogLevel, "log-level", "info", "ログレベル（debug, info, warn, error）")
+	fs.BoolVar(&config.ShowHelp, "help", false, "ヘルプを表示")
+	fs.BoolVar(&config.ShowHelp, "h", false, "ヘルプを表示（短縮形）")
+
+	if err := fs.Parse(reorderArgs(args)); err != nil {
+		return nil, err
+	}
+	if config.ShowHelp {
+		return config, nil
+	}
+
+	config.MigratePaths = fs.Args()
+	if len(config.MigratePaths) == 0 {
+		return nil, fmt.Errorf("migrate: no title directory or files specified")
+	}
+	return config, nil
+}
+
+// parseAssetsArgs は son-et assets の引数（操作、フラグと変換するタイトルのディレクトリ）を解析する
+func parseAssetsArgs(args []string, config *Config) (*Config, error) {
+	if len(args) > 0 && (args[0] == "-h" || args[0] == "--help") {
+		config.ShowHelp = true
+		return config, nil
+	}
+	if len(args) == 0 || args[0] != assetsConvert {
+		return nil, fmt.Errorf("assets: specify an action (convert)")
+	}
+
+	fs := flag.NewFlagSet("son-et assets convert", flag.ContinueOnError)
+	fs.StringVar(&config.AssetsOutDir, "out", "", "変換したタイトルを書き出すディレクトリ")
+	fs.StringVar(&config.AssetsOutDir, "o", "", "変換したタイトルを書き出すディレクトリ（短縮形）")
+	config.AssetsMaxWidth, config.AssetsMaxHeight = defaultAssetsMaxWidth, defaultAssetsMaxHeight
+	fs.Func("max-size", "これより大きい画像を縮小する（幅x高さ、0は縮小しない）", func(value string) error {
+		if value == "0" {
+			config.AssetsMaxWidth, config.AssetsMaxHeight = 0, 0
+			return nil
+		}
+		ws, hs, _ := strings.Cut(strings.ToLower(value), "x")
+		w, errW := strconv.Atoi(ws)
+		h, errH := strconv.Atoi(hs)
+		if errW != nil || errH != nil || w < 1 || h < 1 {
+			return fmt.Errorf("max-size must be WIDTHxHEIGHT such as 1024x768 or 0, got %s", value)
+		}
+		config.AssetsMaxWidth, config.AssetsMaxHeight = w, h
+		return nil
+	})
+	fs.IntVar(&config.AssetsPPQ, "ppq", defaultAssetsPPQ, "MIDIを書き直す分解能（0は変更しない）")
+	fs.StringVar(&config.LogLevel, "log-level", "info", "ログレベル（debug, info, warn, error）")
+	fs.BoolVar(&config.ShowHelp, "help", false, "ヘルプを表示")
+	fs.BoolVar(&config.ShowHelp, "h", false, "ヘルプを表示（短縮形）")
+
+	if err := fs.Parse(reorderArgs(args[1:])); err != nil {
+		return nil, err
+	}
+	if config.ShowHelp {
+		return config, nil
+	}
+	if config.AssetsPPQ < 0 || config.AssetsPPQ > maxAssetsPPQ {
+		return nil, fmt.Errorf("ppq must be 0-%d, got %d", maxAssetsPPQ, config.AssetsPPQ)
+	}
+	if fs.NArg() != 1 {
+		return nil, fmt.Errorf("assets convert: specify exactly one title directory")
+	}
+	config.TitlePath = fs.Arg(0)
+	if config.AssetsOutDir == "" {
+		config.AssetsOutDir = filepath.Clean(config.TitlePath) + assetsOutSuffix
+	}
+	return config, nil
+}
+
+// extractExportGIF は --export-gif start:end output.gif を引数から取り出してConfigに設定し、
+// 残りの引数を返す。--export-gif=start:end output.gif の形式も受け付ける。
+func extractExportGIF(args []string, config *Config) ([]string, error) {
+	var rest []string
+	for i := 0; i < len(args); i++ {
+		name, value, hasValue := strings.Cut(args[i], "=")
+		if !exportGIFFlags[name] {
+			rest = append(rest, args[i])
+			continue
+		}
+
+		// 範囲（=で指定されていない場合は次の引数）
+		if !hasValue {
+			if i+1 >= len(args) {
+				return nil, fmt.Errorf("%s requires start:end and output file", name)
+			}
+			i++
+			value = args[i]
+		}
+		rng, err := gifexport.ParseRange(value)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", name, err)
+		}
+
+		// 出力ファイル
+		if i+1 >= len(args) || strings.HasPrefix(args[i+1], "-") {
+			return nil, fmt.Errorf("%s requires an output file after the range", name)
+		}
+		i++
+
+		config.ExportGIFRange = rng
+		config.ExportGIFPath = args[i]
+	}
+	return rest, nil
+}
+
 // reorderArgs 引数を並べ替えて、フラグを前に、位置引数を後ろに配置する
 func reorderArgs(args []string) []string {
 	var flags []string
@@ -115,10 +732,10 @@ func reorderArgs(args []string) []string {
 			flags = append(flags, arg)
 
 			// 次の引数が値である可能性をチェック
-			// （-t 5 のような場合）
-			if i+1 < len(args) && len(args[i+1]) > 0 && args[i+1][0] != '-' {
+			// （-t 5 のような場合。--compat=filly97 のように値を含む場合は除く）
+			if !strings.Contains(arg, "=") && i+1 < len(args) && len(args[i+1]) > 0 && args[i+1][0] != '-' {
 				// ブール型フラグでない場合は次の引数も追加
-				if arg != "-h" && arg != "--help" && arg != "--headless" {
+				if !boolFlags[arg] {
 					i++
 					flags = append(flags, args[i])
 				}
@@ -139,6 +756,40 @@ func PrintHelp() {
 
 Usage:
   son-et [options] [title-path]
+  son-et lsp [options]
+  son-et fmt [--check | -w] <file-or-dir>...
+  son-et info [--json] <title-path>
+  son-et migrate [--dry-run] <title-path-or-file>...
+  son-et assets convert [--out <dir>] [--max-size <WxH>] [--ppq <n>] <title-path>
+  son-et opcodes [--json | --markdown]
+
+Commands:
+  lsp           エディタ向けのLanguage Server Protocolサーバーをstdio上で実行
+                診断（構文エラー）、関数と#includeの定義へのジャンプ、組み込み関数のホバー表示を提供
+  fmt           TFYファイルをインデント・空白を統一した形に整形（コメントは保持）
+                既定では整形結果を標準出力に出力。ディレクトリを指定すると配下のTFYファイルを対象とする
+                --check: 整形が必要なファイルを一覧表示し、1つでもあれば終了コード1で終了（CI向け）
+                -w:      整形結果を元のファイルに書き戻す（文字コード・改行コードは維持）
+  info          タイトルを実行せずに読み込み、#info のタイトル・作者、TFYファイルの一覧、
+                参照しているアセットの大きさ（見つからないものは MISSING）、MIDIの長さとPPQ、
+                OpCodeの数を表示。アセットが見つからない場合は警告を標準エラー出力に表示
+                --json:  結果をJSONで出力（アーカイブの目録作成向け）
+  migrate       オリジナルのFILLYが受け付けていた古い書き方を、son-et のパーサーが受け付ける形に
+                書き換える（識別子の間の全角スペース、組み込み関数の旧表記、for の見出しの
+                抜けたセミコロン）。変更の差分を標準出力に表示し、元のファイルを .bak として残す
+                --dry-run: 差分を表示するだけでファイルを書き換えない
+  assets convert
+                タイトルのアセットを一括変換して、別のディレクトリに書き出す（元のファイルは変更しない）
+                RLE圧縮・OS/2形式などのBMPを非圧縮に書き直し、仮想デスクトップより大きい画像を縮小し、
+                MIDIの分解能を揃え、Shift-JISのファイル名をUTF-8にする。変更の一覧を標準出力に表示
+                --out <dir>:      書き出すディレクトリ（デフォルト: <title-path>-converted、空であること）
+                --max-size <WxH>: これより大きい画像を縮小する（デフォルト: 1024x768、0で縮小しない）
+                --ppq <n>:        MIDIを書き直す分解能（デフォルト: 480、0で変更しない）
+  opcodes       VMの命令セット（OpCodeのコマンドと Args の並び、組み込み関数の引数の型・必須の数・
+                不正な引数の場合の戻り値・son-et拡張かどうか）を、コンパイラとVMが引数の検査に使う
+                表から作って標準出力に出力（LSP・リンター・変換ツール向け）
+                --json:     JSONで出力
+                --markdown: Markdownの表で出力（既定）
 
 Arguments:
   title-path    FILLYタイトルのディレクトリパス、またはエントリーTFYファイルのパス（省略可）
@@ -149,8 +800,106 @@ Options:
   -t, --timeout <seconds>     指定秒数後にプログラムを終了（デフォルト: 無制限）
   -l, --log-level <level>     ログレベル: debug, info, warn, error（デフォルト: info）
   --headless                  ヘッドレスモード（GUIなし）
+  --exit-after-ticks <n>      ヘッドレスモードで n ティック（TIMEイベント。mes(TIME) がないタイトルでは
+                              MIDI_TIMEイベント）を処理した直後に終了する。--timeout と異なり実行環境の
+                              速さによらず同じ時点で終了するため、CIでの実行結果の比較に使用する
+  --progress <file|fd:N>      ヘッドレスモードで、実行の進み具合（ティック数、経過秒数、MIDIティック、
+                              mes() ブロックの数、最後に実行した文）を1行1レコードのJSONで書き出す
+                              fd:3 のように指定すると親プロセスから受け継いだファイルディスクリプタに書く
+  --progress-interval <d>     進み具合を書き出す間隔（デフォルト: 1s、最小: 10ms）
+  --error-json <file>         終了時に終了コードとエラーの種類・メッセージ・位置（行・列）をJSONで書き出す
+                              正常終了の場合も書き出す。大量のタイトルを実行するバッチでの失敗の分類用
+  --chapter <name>            スクリプトを早送りで実行し、Chapter("name") の位置から再生を始める
+  --keyframe-interval <d>     指定した間隔（例: 10s、最小: 1s）ごとにスクリプトと描画の状態のキーフレームを
+                              作成し、rewind コマンドや通り過ぎたチャプターへの chapter コマンドで後ろに
+                              戻れるようにする（デフォルト: 0 = 作成しない。画像を複製するためメモリを使う）
+  --pause-on-blur             ウィンドウのフォーカスを失っている間、時間の進行を止めて音声をミュート
+  --sandbox                   サンドボックスモード（インターネットから入手したタイトルを安全に実行）
+                              ファイルアクセスをタイトルディレクトリ内に制限し、Shell/MCIを無効化、
+                              メモリ使用量とキャスト数を制限
+  --palette-256               256色表示エミュレーション（90年代のWindowsの256色画面を再現）
+                              すべての描画結果を256色のパレットに量子化し、SetPalette/CyclePaletteによる
+                              パレットアニメーションを反映
+  --compat=<mode>             互換モード（デフォルト: extended）
+                              filly97: オリジナルのFILLYと同じ動作（拡張関数・実数・拡張イベントを無効にし、
+                              整数演算と16bitカラーでの色の丸めを再現）
+                              extended: son-et の拡張機能を有効にする
+  --tps <n>                   1秒あたりの更新回数（1〜240、デフォルト: 60）。入力やフェードの処理回数
+  --fps <n>                   1秒あたりの描画回数の上限（1〜240）。タイトルが #info FPS で指定した値より
+                              小さい場合に適用。TIMEイベントは実時間で発生するため進行速度は変わらない
+  --gamma <value>             画面全体のガンマ値（0.1〜5、デフォルト: 1）。1より大きいと中間調が明るくなる
+  --brightness <value>        画面全体の明るさ（-1〜1、デフォルト: 0）
+  --contrast <value>          画面全体のコントラスト（0〜4、デフォルト: 1）
+  --debug-break               スクリプトの DebugBreak("label") で実行を一時停止し、ローカル変数を表示
+                              Enterキー（標準入力）で再開。指定しない場合 DebugBreak は何もしない
+  --debug-state-diff          描画したフレームごとに、変わったスプライト（移動・作成・削除・アルファ・表示）と
+                              VMが変更したときのMIDIティックをログに記録する（映像と音声のずれの調査用）
+  --av-offset <duration>      音声に対して映像（MIDI_TIMEのイベント）を遅らせる時間（-1s〜1s、例: 40ms）
+                              Bluetoothスピーカーや表示の遅延を補正する。実行中は \ キーで調整画面を開き、
+                              [ と ] キーで5msずつ調整できる
+  --no-synth-effects          MIDIのリバーブ・コーラスを無効にする（低性能な端末でCPUの負荷を下げる）
+  --synth-polyphony <n>       MIDIの同時発音数（8〜256、デフォルト: 64）。少ないほどCPUの負荷が下がる
+  --midi-strict               壊れたMIDIファイル（ランニングステータスの誤り、途中で切れたトラック、
+                              トラックの終わりがないもの）を修復せず、再生をエラーにする。既定では問題の
+                              位置をログに記録し、読める部分を再生する
+  --adaptive-quality          更新と描画がフレームの時間を超え続けた場合に、影・縁取りとスプライトプールの
+                              粒子の描画を段階的に省き、余裕が戻ったら元に戻す（低性能な端末向け）
+  --power-save                画面が静止して入力もない間はTPSを10に下げ、音声のバッファを大きくし、
+                              画像のバックグラウンドのデコードを1枚ずつにする（バッテリー駆動のキオスク向け）
+  --metronome                 MIDI_TIMEを送るMIDIの4分音符ごとにクリック音を重ねる（テンポの変化と
+                              時間スケールに従う）。スクリプトの作成時に映像のきっかけが拍に合うかを確かめる
+  --metronome-flash           MIDIの4分音符ごとに画面の左上に赤い四角形を表示する（--av-offset の分だけ
+                              遅らせる）。GIFに書き出すフレームには含めない
+  --no-cache                  コード生成キャッシュを使わない（既定ではコンパイル結果をタイトル内の
+                              .sonet-cache に保存し、TFYファイルが変わっていなければ次回の起動で再利用）
+  --seed <n>                  Random() の実行シード（リプレイやテストで結果を再現する）
+                              省略時は実行ごとにランダムに選び、ログに出力
+  --export-gif <start:end> <output.gif>
+                              指定した時間範囲の画面をアニメーションGIFとして書き出して終了
+                              時間は秒（2, 2.5s）、ミリ秒（1500ms）、ティック（40t）で指定
+  --export-gif-fps <fps>      GIFのフレームレート（1〜50、デフォルト: 10）
+  --render-audio <output.wav> タイトルが演奏するMIDIを実時間より速くオフラインで合成し、WAVに書き出して終了
+  --input-map <map.json>      ゲームパッドのボタンをキー入力に割り当てる（キオスク端末のボタンで操作する）
+                              例: {"buttons": {"A": "ENTER", "START": "SPACE", "DPAD_UP": "UP"}}
+  --stream-assets <MB>        画像をストリーミング読み込みする（数百MBの画像を含むタイトル向け）
+                              LoadPic はデコードを待たずに戻り、デコードが終わるまで灰色で表示する。
+                              <MB> はデコード済み画像をキャッシュする上限で、超えると古いものから破棄
+  --fetch-soundfont           SoundFont（.sf2）が見つからない場合に、自由なライセンスのGM音源
+                              （GeneralUser GS）をダウンロードしてユーザーのキャッシュディレクトリに保存する
+                              次回からはダウンロードせずにキャッシュを使う
+  --soundfont-url <url>       --fetch-soundfont でダウンロードするSoundFontのURL
+  --soundfont-sha256 <hex>    ダウンロードしたSoundFontのSHA-256（一致しない場合は保存しない）
+  --watch-addr <host:port>    変数ウォッチのリモートAPI（HTTP）を起動する（例: 127.0.0.1:8123）
+                              GET /vars でグローバル変数の一覧、PUT /vars/<name> で数値の変数を変更する
+                              実行中は ; キーでウォッチパネルを開き、矢印キーで変数を選んで増減できる
+  --sync-master <host:port>   複数台の同期再生のマスターになり、UDPで時刻の問い合わせを待ち受ける（例: :7400）
+  --sync-follow <host:port>   マスターの時計に合わせてTIMEイベントを発生させる（例: 192.168.0.10:7400）
+                              フォロワーを先に起動しておくと、全台のTIMEイベントが同じ時刻に発生する
+  --osc-allow <host:port,...> OSCSend でOSCメッセージを送信できる送信先（例: 127.0.0.1:9000）
+                              指定しない場合、OSCSend は何も送らない。送信先ごとに毎秒100メッセージまで
+  --record-cues <file>        コンソールやリモートAPI（POST /command）から実行したコマンド（seek, vol, pause など）を
+                              起動からの秒数とともにキューファイルに記録する（例: 12.500 vol 0.5）
+  --play-cues <file>          キューファイルのコマンドを記録した時刻に実行する（リハーサルした操作を自動化する）
+                              --record-cues と同時に指定すると、再生したコマンドと新しい操作の両方を記録する
+  --scale-mode <mode>         仮想デスクトップをウィンドウに拡大する方法（デフォルト: auto）
+                              crisp: 整数倍の最近傍補間でドットをぼかさない（余白は黒帯）
+                              smooth: 常に線形補間でウィンドウいっぱいに拡大する
+                              高DPIの画面では物理ピクセルに対して拡大する
+  --output-dir <dir>          SaveSprite で画像を書き出すディレクトリ（デフォルト: タイトルディレクトリ内の output）
+  --locale <tag>              FormatNumber・FormatDate の数値・日付の書式のロケール（例: ja-JP、en-US、de-DE）
+                              ja-JP-u-ca-japanese で和暦（令和8年）にする。省略時は環境変数 LC_ALL・LC_TIME・LANG から調べる
+  -A, --asset-var <name=value> アセットのファイル名の ${name} を value に置き換える（複数回指定できる）
+                              例: -A ASSETS=hires で "${ASSETS}/pic01.bmp" は hires/pic01.bmp になる
+                              プロジェクトマニフェストの vars より優先する
   -h, --help                  このヘルプを表示
 
+Exit Status:
+  0  正常終了（--exit-after-ticks のティック数に達した場合を含む）
+  1  その他のエラー（引数の誤り、タイトルが見つからないなど）
+  2  --timeout の時間が経過した
+  3  スクリプトの構文エラー・コンパイルエラー
+  4  スクリプトの実行中の致命的なエラー
+
 Environment Variables:
   HEADLESS=1                  ヘッドレスモードを有効化
   TIMEOUT=<seconds>           タイムアウト時間（秒）
@@ -161,7 +910,30 @@ Examples:
   son-et /path/to/title/MAIN.TFY  エントリーファイルを明示的に指定
   son-et --timeout 10             10秒後に自動終了
   son-et --headless               ヘッドレスモードで実行
+  son-et --headless --exit-after-ticks 600 /path/to/title  600ティック後に終了（CI向け）
+  son-et --pause-on-blur /path/to/title  フォーカス喪失時に一時停止
+  son-et --sandbox /path/to/title  サンドボックスモードで実行
+  son-et --palette-256 /path/to/title  256色表示で実行
+  son-et --seed 42 /path/to/title  Random() の結果を固定して実行
+  son-et --debug-break /path/to/title  DebugBreak で止めながら実行
+  son-et --fps 30 /path/to/title  描画を30FPSに制限して実行（低速なマシン向け）
+  son-et --compat=filly97 /path/to/title  オリジナルのFILLYと同じ動作で実行
+  son-et --gamma 1.8 --brightness 0.1 /path/to/title  暗いプロジェクター向けに明るく表示
+  son-et --export-gif 2:5 out.gif /path/to/title  2秒〜5秒の画面をGIFに書き出す
+  son-et --render-audio song.wav /path/to/title    MIDIをWAVに書き出す
+  son-et --input-map kiosk.json /path/to/title     ゲームパッドのボタンで操作する
+  son-et --av-offset 40ms /path/to/title  Bluetoothスピーカーの遅延に合わせて映像を遅らせる
+  son-et --stream-assets 256 /path/to/title  画像をストリーミング読み込みする（キャッシュは256MBまで）
+  son-et --fetch-soundfont /path/to/title  SoundFontがなければダウンロードして実行
+  son-et --watch-addr 127.0.0.1:8123 /path/to/title  変数を外部から確認・変更しながら実行
   son-et --log-level debug        デバッグログを有効化
+  son-et lsp                      LSPサーバーを起動（ログは標準エラー出力）
+  son-et fmt -w /path/to/title    タイトル内のTFYファイルを整形して書き戻す
+  son-et fmt --check /path/to/title  整形されていないファイルを検出（CI向け）
+  son-et info --json /path/to/title  タイトルの情報をJSONで出力
+  son-et migrate --dry-run /path/to/title  古い書き方の書き換えを差分で確認する
+  son-et assets convert --out /tmp/title /path/to/title  アセットを変換したタイトルを書き出す
+  son-et opcodes --json > opcodes.json  命令セットの一覧をJSONで書き出す
   HEADLESS=1 son-et /path/to/title  環境変数でヘッドレスモード
 `)
 }
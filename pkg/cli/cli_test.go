@@ -2,8 +2,13 @@ package cli
 
 import (
 	"os"
+	"reflect"
+	"strings"
 	"testing"
 	"time"
+
+	"github.com/zurustar/son-et/pkg/compat"
+	"github.com/zurustar/son-et/pkg/gifexport"
 )
 
 func TestParseArgs_ValidArgs(t *testing.T) {
@@ -390,3 +395,1212 @@ func TestParseArgs_EnvironmentVariables(t *testing.T) {
 		})
 	}
 }
+
+func TestParseArgs_PauseOnBlur(t *testing.T) {
+	tests := []struct {
+		name          string
+		args          []string
+		wantPause     bool
+		wantTitlePath string
+	}{
+		{
+			name:      "デフォルトは無効",
+			args:      []string{},
+			wantPause: false,
+		},
+		{
+			name:      "--pause-on-blur指定",
+			args:      []string{"--pause-on-blur"},
+			wantPause: true,
+		},
+		{
+			name:          "タイトルパスの前に指定",
+			args:          []string{"--pause-on-blur", "/path/to/title"},
+			wantPause:     true,
+			wantTitlePath: "/path/to/title",
+		},
+		{
+			name:          "タイトルパスの後に指定",
+			args:          []string{"/path/to/title", "--pause-on-blur"},
+			wantPause:     true,
+			wantTitlePath: "/path/to/title",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config, err := ParseArgs(tt.args)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if config.PauseOnBlur != tt.wantPause {
+				t.Errorf("PauseOnBlur = %v, want %v", config.PauseOnBlur, tt.wantPause)
+			}
+			if config.TitlePath != tt.wantTitlePath {
+				t.Errorf("TitlePath = %q, want %q", config.TitlePath, tt.wantTitlePath)
+			}
+		})
+	}
+}
+
+func TestParseArgs_Sandbox(t *testing.T) {
+	tests := []struct {
+		name          string
+		args          []string
+		wantSandbox   bool
+		wantTitlePath string
+	}{
+		{
+			name:        "デフォルトは無効",
+			args:        []string{},
+			wantSandbox: false,
+		},
+		{
+			name:          "タイトルパスの前に指定",
+			args:          []string{"--sandbox", "/path/to/title"},
+			wantSandbox:   true,
+			wantTitlePath: "/path/to/title",
+		},
+		{
+			name:          "タイトルパスの後に指定",
+			args:          []string{"/path/to/title", "--sandbox"},
+			wantSandbox:   true,
+			wantTitlePath: "/path/to/title",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config, err := ParseArgs(tt.args)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if config.Sandbox != tt.wantSandbox {
+				t.Errorf("Sandbox = %v, want %v", config.Sandbox, tt.wantSandbox)
+			}
+			if config.TitlePath != tt.wantTitlePath {
+				t.Errorf("TitlePath = %q, want %q", config.TitlePath, tt.wantTitlePath)
+			}
+		})
+	}
+}
+
+func TestParseArgs_Palette256(t *testing.T) {
+	config, err := ParseArgs([]string{"/path/to/title"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if config.Palette256 {
+		t.Error("Palette256 should be disabled by default")
+	}
+
+	config, err = ParseArgs([]string{"--palette-256", "/path/to/title"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !config.Palette256 {
+		t.Error("Palette256 should be enabled")
+	}
+	if config.TitlePath != "/path/to/title" {
+		t.Errorf("TitlePath = %q, want /path/to/title", config.TitlePath)
+	}
+}
+
+func TestParseArgs_DebugBreak(t *testing.T) {
+	config, err := ParseArgs([]string{"/path/to/title"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if config.DebugBreak {
+		t.Error("DebugBreak should be disabled by default")
+	}
+
+	config, err = ParseArgs([]string{"--debug-break", "/path/to/title"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !config.DebugBreak {
+		t.Error("DebugBreak should be enabled")
+	}
+	if config.TitlePath != "/path/to/title" {
+		t.Errorf("TitlePath = %q, want /path/to/title", config.TitlePath)
+	}
+}
+
+func TestParseArgs_DebugStateDiff(t *testing.T) {
+	config, err := ParseArgs([]string{"/path/to/title"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if config.DebugStateDiff {
+		t.Error("DebugStateDiff should be disabled by default")
+	}
+
+	config, err = ParseArgs([]string{"--debug-state-diff", "/path/to/title"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !config.DebugStateDiff {
+		t.Error("DebugStateDiff should be enabled")
+	}
+}
+
+func TestParseArgs_AdaptiveQuality(t *testing.T) {
+	config, err := ParseArgs([]string{"/path/to/title"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if config.AutoQuality {
+		t.Error("AutoQuality should be disabled by default")
+	}
+
+	config, err = ParseArgs([]string{"--adaptive-quality", "/path/to/title"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !config.AutoQuality {
+		t.Error("AutoQuality should be enabled")
+	}
+	if config.TitlePath != "/path/to/title" {
+		t.Errorf("TitlePath = %q, want /path/to/title", config.TitlePath)
+	}
+}
+
+func TestParseArgs_PowerSave(t *testing.T) {
+	config, err := ParseArgs([]string{"/path/to/title"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if config.PowerSave {
+		t.Error("PowerSave should be disabled by default")
+	}
+
+	config, err = ParseArgs([]string{"--power-save", "/path/to/title"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !config.PowerSave || config.TitlePath != "/path/to/title" {
+		t.Errorf("PowerSave = %v, TitlePath = %q, want true, /path/to/title", config.PowerSave, config.TitlePath)
+	}
+}
+
+func TestParseArgs_Metronome(t *testing.T) {
+	config, err := ParseArgs([]string{"/path/to/title"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if config.Metronome || config.MetronomeFlash {
+		t.Error("the metronome should be disabled by default")
+	}
+
+	config, err = ParseArgs([]string{"--metronome", "--metronome-flash", "/path/to/title"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !config.Metronome || !config.MetronomeFlash || config.TitlePath != "/path/to/title" {
+		t.Errorf("Metronome = %v, MetronomeFlash = %v, TitlePath = %q, want true, true, /path/to/title",
+			config.Metronome, config.MetronomeFlash, config.TitlePath)
+	}
+}
+
+func TestParseArgs_Locale(t *testing.T) {
+	config, err := ParseArgs([]string{"/path/to/title"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if config.Locale != "" {
+		t.Errorf("Locale = %q, want empty by default", config.Locale)
+	}
+
+	config, err = ParseArgs([]string{"--locale", "ja-JP-u-ca-japanese", "/path/to/title"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if config.Locale != "ja-JP-u-ca-japanese" {
+		t.Errorf("Locale = %q, want ja-JP-u-ca-japanese", config.Locale)
+	}
+
+	if _, err := ParseArgs([]string{"--locale", "not a locale", "/path/to/title"}); err == nil {
+		t.Error("expected an error for an invalid locale")
+	}
+}
+
+func TestParseArgs_NoCache(t *testing.T) {
+	config, err := ParseArgs([]string{"/path/to/title"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if config.NoCache {
+		t.Error("NoCache should be disabled by default")
+	}
+
+	config, err = ParseArgs([]string{"--no-cache", "/path/to/title"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !config.NoCache || config.TitlePath != "/path/to/title" {
+		t.Errorf("unexpected config: NoCache=%v TitlePath=%q", config.NoCache, config.TitlePath)
+	}
+}
+
+func TestParseArgs_Command(t *testing.T) {
+	tests := []struct {
+		name          string
+		args          []string
+		wantCommand   string
+		wantLogLevel  string
+		wantTitlePath string
+	}{
+		{
+			name:         "サブコマンドなし",
+			args:         []string{},
+			wantCommand:  "",
+			wantLogLevel: "info",
+		},
+		{
+			name:         "lsp",
+			args:         []string{"lsp"},
+			wantCommand:  CommandLSP,
+			wantLogLevel: "info",
+		},
+		{
+			name:         "lspにオプションを指定",
+			args:         []string{"lsp", "--log-level", "debug"},
+			wantCommand:  CommandLSP,
+			wantLogLevel: "debug",
+		},
+		{
+			name:          "最初の引数以外はタイトルパスとして扱う",
+			args:          []string{"--headless", "lsp"},
+			wantCommand:   "",
+			wantLogLevel:  "info",
+			wantTitlePath: "lsp",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config, err := ParseArgs(tt.args)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if config.Command != tt.wantCommand {
+				t.Errorf("Command = %q, want %q", config.Command, tt.wantCommand)
+			}
+			if config.LogLevel != tt.wantLogLevel {
+				t.Errorf("LogLevel = %q, want %q", config.LogLevel, tt.wantLogLevel)
+			}
+			if config.TitlePath != tt.wantTitlePath {
+				t.Errorf("TitlePath = %q, want %q", config.TitlePath, tt.wantTitlePath)
+			}
+		})
+	}
+}
+
+func TestParseArgs_Fmt(t *testing.T) {
+	tests := []struct {
+		name      string
+		args      []string
+		wantCheck bool
+		wantWrite bool
+		wantPaths []string
+	}{
+		{
+			name:      "標準出力に出力",
+			args:      []string{"fmt", "MAIN.TFY"},
+			wantPaths: []string{"MAIN.TFY"},
+		},
+		{
+			name:      "--check",
+			args:      []string{"fmt", "--check", "title"},
+			wantCheck: true,
+			wantPaths: []string{"title"},
+		},
+		{
+			name:      "-w と複数のパス",
+			args:      []string{"fmt", "A.TFY", "-w", "B.TFY"},
+			wantWrite: true,
+			wantPaths: []string{"A.TFY", "B.TFY"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config, err := ParseArgs(tt.args)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if config.Command != CommandFmt {
+				t.Errorf("Command = %q, want %q", config.Command, CommandFmt)
+			}
+			if config.FmtCheck != tt.wantCheck || config.FmtWrite != tt.wantWrite {
+				t.Errorf("FmtCheck = %v, FmtWrite = %v; want %v, %v", config.FmtCheck, config.FmtWrite, tt.wantCheck, tt.wantWrite)
+			}
+			if !reflect.DeepEqual(config.FmtPaths, tt.wantPaths) {
+				t.Errorf("FmtPaths = %v, want %v", config.FmtPaths, tt.wantPaths)
+			}
+		})
+	}
+}
+
+func TestParseArgs_FmtInvalid(t *testing.T) {
+	tests := []struct {
+		name string
+		args []string
+	}{
+		{"パスなし", []string{"fmt"}},
+		{"--check と -w の同時指定", []string{"fmt", "--check", "-w", "MAIN.TFY"}},
+		{"実行用のオプション", []string{"fmt", "--headless", "MAIN.TFY"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := ParseArgs(tt.args); err == nil {
+				t.Error("expected error")
+			}
+		})
+	}
+}
+
+func TestParseArgs_Migrate(t *testing.T) {
+	config, err := ParseArgs([]string{"migrate", "/titles/demo", "--dry-run", "EXTRA.TFY"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if config.Command != CommandMigrate || !config.MigrateDryRun {
+		t.Errorf("Command = %q, MigrateDryRun = %v; want migrate, true", config.Command, config.MigrateDryRun)
+	}
+	if want := []string{"/titles/demo", "EXTRA.TFY"}; !reflect.DeepEqual(config.MigratePaths, want) {
+		t.Errorf("MigratePaths = %v, want %v", config.MigratePaths, want)
+	}
+
+	for _, args := range [][]string{{"migrate"}, {"migrate", "--headless", "/titles/demo"}} {
+		if _, err := ParseArgs(args); err == nil {
+			t.Errorf("ParseArgs(%v): expected error", args)
+		}
+	}
+}
+
+func TestParseArgs_Assets(t *testing.T) {
+	config, err := ParseArgs([]string{"assets", "convert", "/titles/demo/"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if config.Command != CommandAssets || config.TitlePath != "/titles/demo/" || config.AssetsOutDir != "/titles/demo-converted" {
+		t.Errorf("Command = %q, TitlePath = %q, AssetsOutDir = %q", config.Command, config.TitlePath, config.AssetsOutDir)
+	}
+	if config.AssetsMaxWidth != 1024 || config.AssetsMaxHeight != 768 || config.AssetsPPQ != 480 {
+		t.Errorf("defaults = %dx%d, PPQ %d; want 1024x768, PPQ 480", config.AssetsMaxWidth, config.AssetsMaxHeight, config.AssetsPPQ)
+	}
+
+	config, err = ParseArgs([]string{"assets", "convert", "/titles/demo", "-o", "/tmp/out", "--max-size", "640x480", "--ppq=96"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if config.AssetsOutDir != "/tmp/out" || config.AssetsMaxWidth != 640 || config.AssetsMaxHeight != 480 || config.AssetsPPQ != 96 {
+		t.Errorf("AssetsOutDir = %q, max size %dx%d, PPQ %d", config.AssetsOutDir, config.AssetsMaxWidth, config.AssetsMaxHeight, config.AssetsPPQ)
+	}
+
+	config, err = ParseArgs([]string{"assets", "convert", "--max-size", "0", "--ppq", "0", "/titles/demo"})
+	if err != nil || config.AssetsMaxWidth != 0 || config.AssetsMaxHeight != 0 || config.AssetsPPQ != 0 {
+		t.Errorf("--max-size 0 --ppq 0 = %+v, %v; want no downscaling and no PPQ change", config, err)
+	}
+
+	if config, err := ParseArgs([]string{"assets", "--help"}); err != nil || !config.ShowHelp {
+		t.Errorf("assets --help = %v, %v", config, err)
+	}
+
+	for _, args := range [][]string{
+		{"assets"},
+		{"assets", "shrink", "/titles/demo"},
+		{"assets", "convert"},
+		{"assets", "convert", "/titles/a", "/titles/b"},
+		{"assets", "convert", "--max-size", "1024", "/titles/demo"},
+		{"assets", "convert", "--max-size", "0x768", "/titles/demo"},
+		{"assets", "convert", "--ppq", "40000", "/titles/demo"},
+	} {
+		if _, err := ParseArgs(args); err == nil {
+			t.Errorf("ParseArgs(%v): expected error", args)
+		}
+	}
+}
+
+func TestParseArgs_Opcodes(t *testing.T) {
+	tests := []struct {
+		args     []string
+		wantJSON bool
+	}{
+		{[]string{"opcodes"}, false},
+		{[]string{"opcodes", "--markdown"}, false},
+		{[]string{"opcodes", "--json"}, true},
+	}
+	for _, tt := range tests {
+		config, err := ParseArgs(tt.args)
+		if err != nil {
+			t.Fatalf("ParseArgs(%v): unexpected error: %v", tt.args, err)
+		}
+		if config.Command != CommandOpcodes || config.OpcodesJSON != tt.wantJSON {
+			t.Errorf("ParseArgs(%v): Command = %q, OpcodesJSON = %v; want %q, %v", tt.args, config.Command, config.OpcodesJSON, CommandOpcodes, tt.wantJSON)
+		}
+	}
+
+	for _, args := range [][]string{
+		{"opcodes", "--json", "--markdown"},
+		{"opcodes", "/titles/demo"},
+		{"opcodes", "--headless"},
+	} {
+		if _, err := ParseArgs(args); err == nil {
+			t.Errorf("ParseArgs(%v): expected error", args)
+		}
+	}
+}
+
+func TestParseArgs_Info(t *testing.T) {
+	tests := []struct {
+		name      string
+		args      []string
+		wantPath  string
+		wantEntry string
+		wantJSON  bool
+	}{
+		{
+			name:     "ディレクトリ",
+			args:     []string{"info", "/titles/demo"},
+			wantPath: "/titles/demo",
+		},
+		{
+			name:      "TFYファイルと --json",
+			args:      []string{"info", "/titles/demo/MAIN.TFY", "--json"},
+			wantPath:  "/titles/demo",
+			wantEntry: "MAIN.TFY",
+			wantJSON:  true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config, err := ParseArgs(tt.args)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if config.Command != CommandInfo {
+				t.Errorf("Command = %q, want %q", config.Command, CommandInfo)
+			}
+			if config.TitlePath != tt.wantPath || config.EntryFile != tt.wantEntry {
+				t.Errorf("TitlePath = %q, EntryFile = %q; want %q, %q", config.TitlePath, config.EntryFile, tt.wantPath, tt.wantEntry)
+			}
+			if config.InfoJSON != tt.wantJSON {
+				t.Errorf("InfoJSON = %v, want %v", config.InfoJSON, tt.wantJSON)
+			}
+		})
+	}
+}
+
+func TestParseArgs_InfoInvalid(t *testing.T) {
+	tests := []struct {
+		name string
+		args []string
+	}{
+		{"パスなし", []string{"info"}},
+		{"複数のパス", []string{"info", "a", "b"}},
+		{"実行用のオプション", []string{"info", "--headless", "a"}},
+		{"不正な互換モード", []string{"info", "--compat=win95", "a"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := ParseArgs(tt.args); err == nil {
+				t.Error("expected error")
+			}
+		})
+	}
+}
+
+func TestParseArgs_ExportGIF(t *testing.T) {
+	tests := []struct {
+		name          string
+		args          []string
+		wantPath      string
+		wantRange     gifexport.Range
+		wantFPS       int
+		wantTitlePath string
+	}{
+		{
+			name:    "デフォルトは書き出さない",
+			args:    []string{},
+			wantFPS: gifexport.DefaultFPS,
+		},
+		{
+			name:      "秒で範囲を指定",
+			args:      []string{"--export-gif", "2:5", "out.gif"},
+			wantPath:  "out.gif",
+			wantRange: gifexport.Range{Start: 2 * time.Second, End: 5 * time.Second},
+			wantFPS:   gifexport.DefaultFPS,
+		},
+		{
+			name:          "ティックで範囲を指定しタイトルパスを続ける",
+			args:          []string{"--export-gif", "40t:100t", "out.gif", "/path/to/title"},
+			wantPath:      "out.gif",
+			wantRange:     gifexport.Range{Start: 2 * time.Second, End: 5 * time.Second},
+			wantFPS:       gifexport.DefaultFPS,
+			wantTitlePath: "/path/to/title",
+		},
+		{
+			name:          "=形式とFPS指定",
+			args:          []string{"/path/to/title", "--export-gif=0:1.5", "anim.gif", "--export-gif-fps", "20"},
+			wantPath:      "anim.gif",
+			wantRange:     gifexport.Range{Start: 0, End: 1500 * time.Millisecond},
+			wantFPS:       20,
+			wantTitlePath: "/path/to/title",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config, err := ParseArgs(tt.args)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if config.ExportGIFPath != tt.wantPath {
+				t.Errorf("ExportGIFPath = %q, want %q", config.ExportGIFPath, tt.wantPath)
+			}
+			if config.ExportGIFRange != tt.wantRange {
+				t.Errorf("ExportGIFRange = %v, want %v", config.ExportGIFRange, tt.wantRange)
+			}
+			if config.ExportGIFFPS != tt.wantFPS {
+				t.Errorf("ExportGIFFPS = %d, want %d", config.ExportGIFFPS, tt.wantFPS)
+			}
+			if config.TitlePath != tt.wantTitlePath {
+				t.Errorf("TitlePath = %q, want %q", config.TitlePath, tt.wantTitlePath)
+			}
+		})
+	}
+}
+
+func TestParseArgs_ExportGIFInvalid(t *testing.T) {
+	tests := []struct {
+		name string
+		args []string
+	}{
+		{"範囲なし", []string{"--export-gif"}},
+		{"出力ファイルなし", []string{"--export-gif", "2:5"}},
+		{"出力ファイルの代わりにフラグ", []string{"--export-gif", "2:5", "--headless"}},
+		{"不正な範囲", []string{"--export-gif", "5:2", "out.gif"}},
+		{"FPSが0", []string{"--export-gif", "2:5", "out.gif", "--export-gif-fps", "0"}},
+		{"FPSが上限超過", []string{"--export-gif-fps", "100"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := ParseArgs(tt.args); err == nil {
+				t.Error("expected error, got nil")
+			}
+		})
+	}
+}
+
+func TestParseArgs_RenderAudio(t *testing.T) {
+	tests := []struct {
+		name          string
+		args          []string
+		wantPath      string
+		wantTitlePath string
+		wantErr       bool
+	}{
+		{
+			name:     "デフォルトは書き出さない",
+			args:     []string{},
+			wantPath: "",
+		},
+		{
+			name:          "WAVファイルを指定",
+			args:          []string{"--render-audio", "song.wav", "/path/to/title"},
+			wantPath:      "song.wav",
+			wantTitlePath: "/path/to/title",
+		},
+		{
+			name:          "拡張子の大文字小文字は区別しない",
+			args:          []string{"/path/to/title", "--render-audio", "SONG.WAV"},
+			wantPath:      "SONG.WAV",
+			wantTitlePath: "/path/to/title",
+		},
+		{
+			name:    "WAV以外はエラー",
+			args:    []string{"--render-audio", "song.flac"},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config, err := ParseArgs(tt.args)
+			if tt.wantErr {
+				if err == nil {
+					t.Error("expected error, got nil")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if config.RenderAudioPath != tt.wantPath {
+				t.Errorf("RenderAudioPath = %q, want %q", config.RenderAudioPath, tt.wantPath)
+			}
+			if config.TitlePath != tt.wantTitlePath {
+				t.Errorf("TitlePath = %q, want %q", config.TitlePath, tt.wantTitlePath)
+			}
+		})
+	}
+}
+
+func TestParseArgs_Seed(t *testing.T) {
+	config, err := ParseArgs([]string{"/path/to/title"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if config.SeedSet {
+		t.Error("SeedSet should be false by default")
+	}
+
+	config, err = ParseArgs([]string{"/path/to/title", "--seed", "42"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !config.SeedSet || config.Seed != 42 {
+		t.Errorf("Seed = %d (set %v), want 42", config.Seed, config.SeedSet)
+	}
+	if config.TitlePath != "/path/to/title" {
+		t.Errorf("TitlePath = %q, want /path/to/title", config.TitlePath)
+	}
+
+	config, err = ParseArgs([]string{"--seed", "0x10"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if config.Seed != 16 {
+		t.Errorf("Seed = %d, want 16", config.Seed)
+	}
+
+	for _, value := range []string{"-1", "abc"} {
+		if _, err := ParseArgs([]string{"--seed", value}); err == nil {
+			t.Errorf("expected error for --seed %s", value)
+		}
+	}
+}
+
+func TestParseArgs_Compat(t *testing.T) {
+	config, err := ParseArgs([]string{"/path/to/title"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if config.Compat != compat.Extended {
+		t.Errorf("Compat = %v, want extended by default", config.Compat)
+	}
+	if config.CompatSet {
+		t.Error("CompatSet should be false without --compat")
+	}
+
+	for _, args := range [][]string{
+		{"--compat=filly97", "/path/to/title"},
+		{"--compat", "FILLY97", "/path/to/title"},
+		{"/path/to/title", "--compat=filly97"},
+	} {
+		config, err := ParseArgs(args)
+		if err != nil {
+			t.Fatalf("%v: unexpected error: %v", args, err)
+		}
+		if config.Compat != compat.FILLY97 {
+			t.Errorf("%v: Compat = %v, want filly97", args, config.Compat)
+		}
+		if !config.CompatSet {
+			t.Errorf("%v: CompatSet should be true", args)
+		}
+		if config.TitlePath != "/path/to/title" {
+			t.Errorf("%v: TitlePath = %q, want /path/to/title", args, config.TitlePath)
+		}
+	}
+
+	if _, err := ParseArgs([]string{"--compat=filly95", "/path/to/title"}); err == nil {
+		t.Error("expected error for unknown compatibility mode")
+	}
+}
+
+func TestParseArgs_FetchSoundFont(t *testing.T) {
+	sum := strings.Repeat("AB", 32)
+	config, err := ParseArgs([]string{"--fetch-soundfont", "/path/to/title", "--soundfont-url", "https://example.com/GM.sf2", "--soundfont-sha256", sum})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !config.FetchSoundFont || config.SoundFontURL != "https://example.com/GM.sf2" {
+		t.Errorf("config = %+v", config)
+	}
+	if config.SoundFontSHA256 != strings.ToLower(sum) {
+		t.Errorf("SoundFontSHA256 = %q, want lowercase", config.SoundFontSHA256)
+	}
+	if config.TitlePath != "/path/to/title" {
+		t.Errorf("TitlePath = %q, want /path/to/title", config.TitlePath)
+	}
+
+	for _, args := range [][]string{
+		{"--soundfont-url", "https://example.com/GM.sf2", "/path/to/title"},
+		{"--fetch-soundfont", "--soundfont-url", "ftp://example.com/GM.sf2"},
+		{"--fetch-soundfont", "--soundfont-url", "GM.sf2"},
+		{"--fetch-soundfont", "--soundfont-sha256", "abcd"},
+		{"--fetch-soundfont", "--soundfont-sha256", strings.Repeat("zz", 32)},
+	} {
+		if _, err := ParseArgs(args); err == nil {
+			t.Errorf("%v: expected error", args)
+		}
+	}
+}
+
+func TestParseArgs_WatchAddr(t *testing.T) {
+	config, err := ParseArgs([]string{"--watch-addr", "127.0.0.1:8123", "/path/to/title"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if config.WatchAddr != "127.0.0.1:8123" || config.TitlePath != "/path/to/title" {
+		t.Errorf("WatchAddr = %q, TitlePath = %q", config.WatchAddr, config.TitlePath)
+	}
+
+	config, err = ParseArgs([]string{"--watch-addr=:0"})
+	if err != nil || config.WatchAddr != ":0" {
+		t.Errorf("--watch-addr=:0: %q, %v", config.WatchAddr, err)
+	}
+
+	for _, addr := range []string{"8123", "localhost", "http://127.0.0.1:8123"} {
+		if _, err := ParseArgs([]string{"--watch-addr", addr}); err == nil {
+			t.Errorf("--watch-addr %s: expected error", addr)
+		}
+	}
+}
+
+func TestParseArgs_FrameRate(t *testing.T) {
+	config, err := ParseArgs([]string{"/path/to/title"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if config.TPS != 0 || config.FPS != 0 {
+		t.Errorf("TPS/FPS = %d/%d, want 0/0 by default", config.TPS, config.FPS)
+	}
+
+	config, err = ParseArgs([]string{"--tps", "120", "--fps", "30", "/path/to/title"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if config.TPS != 120 || config.FPS != 30 {
+		t.Errorf("TPS/FPS = %d/%d, want 120/30", config.TPS, config.FPS)
+	}
+
+	for _, args := range [][]string{{"--tps", "-1"}, {"--fps", "-30"}} {
+		if _, err := ParseArgs(args); err == nil {
+			t.Errorf("expected error for %v", args)
+		}
+	}
+}
+
+func TestParseArgs_DisplayAdjustment(t *testing.T) {
+	config, err := ParseArgs([]string{"/path/to/title"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if config.Gamma != 1 || config.Brightness != 0 || config.Contrast != 1 {
+		t.Errorf("defaults = gamma %v, brightness %v, contrast %v; want 1, 0, 1", config.Gamma, config.Brightness, config.Contrast)
+	}
+
+	config, err = ParseArgs([]string{"--gamma", "1.8", "--brightness", "-0.2", "--contrast", "1.5", "/path/to/title"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if config.Gamma != 1.8 || config.Brightness != -0.2 || config.Contrast != 1.5 {
+		t.Errorf("got gamma %v, brightness %v, contrast %v", config.Gamma, config.Brightness, config.Contrast)
+	}
+	if config.TitlePath != "/path/to/title" {
+		t.Errorf("TitlePath = %q, want /path/to/title", config.TitlePath)
+	}
+
+	if _, err := ParseArgs([]string{"--gamma", "bright"}); err == nil {
+		t.Error("expected error for non-numeric gamma")
+	}
+}
+
+func TestParseArgs_InputMap(t *testing.T) {
+	config, err := ParseArgs([]string{"/path/to/title"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if config.InputMapPath != "" {
+		t.Errorf("InputMapPath = %q, want empty by default", config.InputMapPath)
+	}
+
+	config, err = ParseArgs([]string{"/path/to/title", "--input-map", "kiosk.json"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if config.InputMapPath != "kiosk.json" {
+		t.Errorf("InputMapPath = %q, want kiosk.json", config.InputMapPath)
+	}
+	if config.TitlePath != "/path/to/title" {
+		t.Errorf("TitlePath = %q, want /path/to/title", config.TitlePath)
+	}
+}
+
+func TestParseArgs_StreamAssets(t *testing.T) {
+	config, err := ParseArgs([]string{"/path/to/title"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if config.StreamAssetsMB != 0 {
+		t.Errorf("StreamAssetsMB = %d, want 0 by default", config.StreamAssetsMB)
+	}
+
+	config, err = ParseArgs([]string{"/path/to/title", "--stream-assets", "128"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if config.StreamAssetsMB != 128 {
+		t.Errorf("StreamAssetsMB = %d, want 128", config.StreamAssetsMB)
+	}
+	if config.TitlePath != "/path/to/title" {
+		t.Errorf("TitlePath = %q, want /path/to/title", config.TitlePath)
+	}
+
+	if _, err := ParseArgs([]string{"--stream-assets", "-1", "/path/to/title"}); err == nil {
+		t.Error("expected error for negative stream-assets")
+	}
+}
+
+func TestParseArgs_AVOffset(t *testing.T) {
+	tests := []struct {
+		name    string
+		args    []string
+		want    time.Duration
+		wantErr bool
+	}{
+		{name: "既定値は0", args: []string{"/path/to/title"}, want: 0},
+		{name: "ミリ秒", args: []string{"--av-offset", "40ms", "/path/to/title"}, want: 40 * time.Millisecond},
+		{name: "負の値", args: []string{"--av-offset", "-20ms", "/path/to/title"}, want: -20 * time.Millisecond},
+		{name: "単位なしはミリ秒", args: []string{"--av-offset=25", "/path/to/title"}, want: 25 * time.Millisecond},
+		{name: "上限", args: []string{"--av-offset", "1s", "/path/to/title"}, want: time.Second},
+		{name: "範囲外", args: []string{"--av-offset", "1500ms"}, wantErr: true},
+		{name: "不正な値", args: []string{"--av-offset", "soon"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config, err := ParseArgs(tt.args)
+			if tt.wantErr {
+				if err == nil {
+					t.Error("expected error, got nil")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if config.AVOffset != tt.want {
+				t.Errorf("AVOffset = %v, want %v", config.AVOffset, tt.want)
+			}
+			if config.TitlePath != "/path/to/title" {
+				t.Errorf("TitlePath = %q, want /path/to/title", config.TitlePath)
+			}
+		})
+	}
+}
+
+func TestParseArgs_SynthQuality(t *testing.T) {
+	tests := []struct {
+		name          string
+		args          []string
+		wantNoEffects bool
+		wantPolyphony int
+		wantErr       bool
+	}{
+		{name: "既定値", args: []string{"/path/to/title"}},
+		{name: "エフェクトなし", args: []string{"--no-synth-effects", "/path/to/title"}, wantNoEffects: true},
+		{name: "同時発音数", args: []string{"--synth-polyphony", "24", "/path/to/title"}, wantPolyphony: 24},
+		{name: "両方", args: []string{"/path/to/title", "--no-synth-effects", "--synth-polyphony=256"}, wantNoEffects: true, wantPolyphony: 256},
+		{name: "少なすぎる", args: []string{"--synth-polyphony", "4", "/path/to/title"}, wantErr: true},
+		{name: "多すぎる", args: []string{"--synth-polyphony", "512", "/path/to/title"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config, err := ParseArgs(tt.args)
+			if tt.wantErr {
+				if err == nil {
+					t.Error("expected error, got nil")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if config.NoSynthEffects != tt.wantNoEffects || config.SynthPolyphony != tt.wantPolyphony {
+				t.Errorf("NoSynthEffects = %v, SynthPolyphony = %d, want %v, %d",
+					config.NoSynthEffects, config.SynthPolyphony, tt.wantNoEffects, tt.wantPolyphony)
+			}
+			if config.TitlePath != "/path/to/title" {
+				t.Errorf("TitlePath = %q, want /path/to/title", config.TitlePath)
+			}
+		})
+	}
+}
+
+func TestParseArgs_ExitAfterTicks(t *testing.T) {
+	t.Setenv("HEADLESS", "")
+
+	tests := []struct {
+		name    string
+		args    []string
+		want    int64
+		wantErr bool
+	}{
+		{name: "既定値は0", args: []string{"/path/to/title"}, want: 0},
+		{name: "ヘッドレスモード", args: []string{"--headless", "--exit-after-ticks", "600", "/path/to/title"}, want: 600},
+		{name: "ヘッドレスモードが必要", args: []string{"--exit-after-ticks", "600", "/path/to/title"}, wantErr: true},
+		{name: "GIF書き出しとは併用できない", args: []string{"--headless", "--exit-after-ticks", "10", "--export-gif", "0:1", "out.gif", "/path/to/title"}, wantErr: true},
+		{name: "負の値", args: []string{"--headless", "--exit-after-ticks", "-1", "/path/to/title"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config, err := ParseArgs(tt.args)
+			if tt.wantErr {
+				if err == nil {
+					t.Error("expected error, got nil")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if config.ExitAfterTicks != tt.want {
+				t.Errorf("ExitAfterTicks = %d, want %d", config.ExitAfterTicks, tt.want)
+			}
+		})
+	}
+
+	// 環境変数でヘッドレスモードを有効にした場合も使用できる
+	t.Setenv("HEADLESS", "1")
+	if _, err := ParseArgs([]string{"--exit-after-ticks", "5", "/path/to/title"}); err != nil {
+		t.Errorf("HEADLESS=1 should allow exit-after-ticks: %v", err)
+	}
+}
+
+func TestParseArgs_Progress(t *testing.T) {
+	t.Setenv("HEADLESS", "")
+
+	config, err := ParseArgs([]string{"/path/to/title"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if config.ProgressPath != "" || config.ProgressInterval != defaultProgressInterval {
+		t.Errorf("defaults = %q, %v, want empty and %v", config.ProgressPath, config.ProgressInterval, defaultProgressInterval)
+	}
+
+	config, err = ParseArgs([]string{"--headless", "--progress", "fd:3", "--progress-interval", "250ms", "/path/to/title"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if config.ProgressPath != "fd:3" || config.ProgressInterval != 250*time.Millisecond {
+		t.Errorf("ProgressPath, ProgressInterval = %q, %v, want fd:3, 250ms", config.ProgressPath, config.ProgressInterval)
+	}
+
+	for _, args := range [][]string{
+		{"--progress", "p.jsonl", "/path/to/title"},                                                 // ヘッドレスモードが必要
+		{"--headless", "--progress", "p.jsonl", "--export-gif", "0:1", "out.gif", "/path/to/title"}, // GIF書き出しとは併用できない
+		{"--headless", "--progress", "p.jsonl", "--progress-interval", "1ms", "/path/to/title"},     // 間隔が短すぎる
+	} {
+		if _, err := ParseArgs(args); err == nil {
+			t.Errorf("ParseArgs(%v): expected error", args)
+		}
+	}
+}
+
+func TestParseArgs_ErrorJSON(t *testing.T) {
+	config, err := ParseArgs([]string{"/path/to/title"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if config.ErrorJSONPath != "" {
+		t.Errorf("ErrorJSONPath = %q, want empty by default", config.ErrorJSONPath)
+	}
+
+	config, err = ParseArgs([]string{"--error-json", "report.json", "/path/to/title"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if config.ErrorJSONPath != "report.json" || config.TitlePath != "/path/to/title" {
+		t.Errorf("ErrorJSONPath, TitlePath = %q, %q, want report.json, /path/to/title", config.ErrorJSONPath, config.TitlePath)
+	}
+}
+
+func TestParseArgs_Sync(t *testing.T) {
+	config, err := ParseArgs([]string{"--sync-master", ":7400", "/path/to/title"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if config.SyncMasterAddr != ":7400" || config.SyncFollowAddr != "" || config.TitlePath != "/path/to/title" {
+		t.Errorf("SyncMasterAddr = %q, SyncFollowAddr = %q, TitlePath = %q", config.SyncMasterAddr, config.SyncFollowAddr, config.TitlePath)
+	}
+
+	config, err = ParseArgs([]string{"--sync-follow=192.168.0.10:7400"})
+	if err != nil || config.SyncFollowAddr != "192.168.0.10:7400" {
+		t.Errorf("--sync-follow: %q, %v", config.SyncFollowAddr, err)
+	}
+
+	invalid := [][]string{
+		{"--sync-master", "7400"},
+		{"--sync-follow", ":7400"},
+		{"--sync-follow", "192.168.0.10"},
+		{"--sync-master", ":7400", "--sync-follow", "192.168.0.10:7400"},
+	}
+	for _, args := range invalid {
+		if _, err := ParseArgs(args); err == nil {
+			t.Errorf("ParseArgs(%v): expected error", args)
+		}
+	}
+}
+
+func TestParseArgs_OSCAllow(t *testing.T) {
+	config, err := ParseArgs([]string{"--osc-allow", "127.0.0.1:9000, 192.168.0.20:8000", "--osc-allow=lights.local:7700", "/path/to/title"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := []string{"127.0.0.1:9000", "192.168.0.20:8000", "lights.local:7700"}
+	if !reflect.DeepEqual(config.OSCAllow, want) {
+		t.Errorf("OSCAllow = %v, want %v", config.OSCAllow, want)
+	}
+
+	for _, value := range []string{"9000", ":9000", "127.0.0.1:9000,"} {
+		if _, err := ParseArgs([]string{"--osc-allow", value}); err == nil {
+			t.Errorf("--osc-allow %q: expected error", value)
+		}
+	}
+}
+
+func TestParseArgs_Cues(t *testing.T) {
+	config, err := ParseArgs([]string{"--play-cues", "rehearsal.cue", "--record-cues=show.cue", "/path/to/title"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if config.PlayCuesPath != "rehearsal.cue" {
+		t.Errorf("PlayCuesPath = %q, want rehearsal.cue", config.PlayCuesPath)
+	}
+	if config.RecordCuesPath != "show.cue" {
+		t.Errorf("RecordCuesPath = %q, want show.cue", config.RecordCuesPath)
+	}
+	if config.TitlePath != "/path/to/title" {
+		t.Errorf("TitlePath = %q, want /path/to/title", config.TitlePath)
+	}
+}
+
+func TestParseArgs_AssetVars(t *testing.T) {
+	config, err := ParseArgs([]string{"-A", "ASSETS=hires", "/path/to/title", "--asset-var=EXT=PNG", "-A", "ASSETS=remaster"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := map[string]string{"ASSETS": "remaster", "EXT": "PNG"} // 後の指定が優先
+	if !reflect.DeepEqual(config.AssetVars, want) || config.TitlePath != "/path/to/title" {
+		t.Errorf("AssetVars = %v, TitlePath = %q; want %v", config.AssetVars, config.TitlePath, want)
+	}
+
+	for _, value := range []string{"ASSETS", "=hires", "MY-ASSETS=hires"} {
+		if _, err := ParseArgs([]string{"-A", value}); err == nil {
+			t.Errorf("-A %q: expected error", value)
+		}
+	}
+}
+
+func TestParseArgs_ScaleMode(t *testing.T) {
+	config, err := ParseArgs([]string{"/path/to/title"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if config.ScaleMode != "auto" {
+		t.Errorf("default ScaleMode = %q, want auto", config.ScaleMode)
+	}
+
+	for _, mode := range []string{"auto", "crisp", "smooth"} {
+		config, err := ParseArgs([]string{"--scale-mode", mode, "/path/to/title"})
+		if err != nil {
+			t.Fatalf("--scale-mode %s: unexpected error: %v", mode, err)
+		}
+		if config.ScaleMode != mode || config.TitlePath != "/path/to/title" {
+			t.Errorf("ScaleMode = %q, TitlePath = %q; want %s", config.ScaleMode, config.TitlePath, mode)
+		}
+	}
+
+	if _, err := ParseArgs([]string{"--scale-mode", "sharp"}); err == nil {
+		t.Error("expected error for an invalid scale mode")
+	}
+}
+
+func TestParseArgs_OutputDir(t *testing.T) {
+	config, err := ParseArgs([]string{"/path/to/title", "--output-dir", "/tmp/renders"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if config.OutputDir != "/tmp/renders" || config.TitlePath != "/path/to/title" {
+		t.Errorf("OutputDir = %q, TitlePath = %q", config.OutputDir, config.TitlePath)
+	}
+}
+
+func TestParseArgs_Chapter(t *testing.T) {
+	config, err := ParseArgs([]string{"/path/to/title"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if config.Chapter != "" {
+		t.Errorf("Chapter = %q, want empty", config.Chapter)
+	}
+
+	config, err = ParseArgs([]string{"--chapter", "verse2", "/path/to/title"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if config.Chapter != "verse2" {
+		t.Errorf("Chapter = %q, want verse2", config.Chapter)
+	}
+}
+
+func TestParseArgs_KeyframeInterval(t *testing.T) {
+	config, err := ParseArgs([]string{"/path/to/title"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if config.KeyframeInterval != 0 {
+		t.Errorf("KeyframeInterval = %v, want 0 (disabled)", config.KeyframeInterval)
+	}
+
+	config, err = ParseArgs([]string{"--keyframe-interval", "10s", "/path/to/title"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if config.KeyframeInterval != 10*time.Second {
+		t.Errorf("KeyframeInterval = %v, want 10s", config.KeyframeInterval)
+	}
+
+	if _, err := ParseArgs([]string{"--keyframe-interval", "500ms", "/path/to/title"}); err == nil {
+		t.Error("expected an error for an interval shorter than 1s")
+	}
+}
+
+func TestParseArgs_MIDIStrict(t *testing.T) {
+	config, err := ParseArgs([]string{"/path/to/title"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if config.MIDIStrict {
+		t.Error("MIDIStrict should be false by default")
+	}
+
+	config, err = ParseArgs([]string{"--midi-strict", "/path/to/title"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !config.MIDIStrict || config.TitlePath != "/path/to/title" {
+		t.Errorf("MIDIStrict = %v, TitlePath = %q, want true, /path/to/title", config.MIDIStrict, config.TitlePath)
+	}
+}
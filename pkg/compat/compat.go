@@ -0,0 +1,233 @@
+// Package compat defines the compatibility mode (--compat) shared by the
+// compiler and the VM.
+//
+// The extended mode (default) enables the features son-et adds on top of
+// FILLY: floating point values, input handler builtins, display effects and
+// so on. The filly97 mode disables all of them and reproduces the quirks of
+// the original FILLY runtime (integer-only arithmetic, High Color rounding
+// of colors) so that titles behave exactly as they did on Windows 95/98.
+package compat
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Mode is the compatibility mode.
+type Mode int
+
+const (
+	// Extended enables son-et's extensions (default).
+	Extended Mode = iota
+	// FILLY97 disables extensions and reproduces the original FILLY quirks.
+	FILLY97
+)
+
+// モード名（--compat の値）
+const (
+	nameExtended = "extended"
+	nameFILLY97  = "filly97"
+)
+
+// Names returns the accepted --compat values.
+func Names() []string {
+	return []string{nameFILLY97, nameExtended}
+}
+
+// Parse parses a --compat value (case-insensitive). An empty string is Extended.
+func Parse(s string) (Mode, error) {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "", nameExtended:
+		return Extended, nil
+	case nameFILLY97:
+		return FILLY97, nil
+	}
+	return Extended, fmt.Errorf("unknown compatibility mode %q (want %s)", s, strings.Join(Names(), " or "))
+}
+
+// String returns the --compat value for the mode.
+func (m Mode) String() string {
+	if m == FILLY97 {
+		return nameFILLY97
+	}
+	return nameExtended
+}
+
+// Strict reports whether extensions are disabled.
+func (m Mode) Strict() bool {
+	return m == FILLY97
+}
+
+// extensionBuiltins は son-et で追加した組み込み関数（小文字）
+// filly97 モードでは未定義の関数として扱われる
+var extensionBuiltins = map[string]bool{
+	// 永続化
+	"savevalue": true,
+	"loadvalue": true,
+	// デバッグ
+	"debugbreak": true,
+	// 終了処理
+	"onexit": true,
+	// 画像の非同期読み込み
+	"loadpicasync":  true,
+	"putflipbook":   true,
+	"changepicture": true,
+	// 入力ハンドラ
+	"onkey":         true,
+	"onclick":       true,
+	"onspriteclick": true,
+	"onspritetick":  true,
+	"onnote":        true,
+	"bindnote":      true,
+	// OSC出力
+	"oscsend": true,
+	// カラオケ
+	"highlighttext": true,
+	"karaoke":       true,
+	// テキスト計測・縦書き
+	"textwidth":     true,
+	"textheight":    true,
+	"textdirection": true,
+	// 画面効果・パレット・シェーダー
+	"fadeout":       true,
+	"fadein":        true,
+	"setpalette":    true,
+	"getpalette":    true,
+	"cyclepalette":  true,
+	"resetpalette":  true,
+	"setgamma":      true,
+	"setbrightness": true,
+	"setcontrast":   true,
+	// ミキサー
+	"setvolume": true,
+	"getvolume": true,
+	"setmute":   true,
+	// ダッキング
+	"playvoice":  true,
+	"setducking": true,
+	// シンセサイザーの品質
+	"setsynthquality": true,
+	// MIDIポート
+	"playmidiport": true,
+	"stopmidiport": true,
+	"midiclock":    true,
+	"setmidiclock": true,
+	// スプライトプール
+	"createspritepool": true,
+	"setpoolsprite":    true,
+	"scatterpool":      true,
+	"setpoolvelocity":  true,
+	"steppool":         true,
+	"delspritepool":    true,
+	// マスク
+	"setcastmask":    true,
+	"setcastmaskpic": true,
+	"delcastmask":    true,
+	"setwinmask":     true,
+	"setwinmaskpic":  true,
+	"delwinmask":     true,
+	// 影・縁取り
+	"setshadow":  true,
+	"delshadow":  true,
+	"setoutline": true,
+	"deloutline": true,
+	"shake":      true,
+	"flash":      true,
+	// パスに沿った移動
+	"definepath":    true,
+	"delpath":       true,
+	"movealongpath": true,
+	// カメラ
+	"setcamera":  true,
+	"cameramove": true,
+	// 描画範囲・9分割
+	"setsourcerect": true,
+	"delsourcerect": true,
+	"setnineslice":  true,
+	"delnineslice":  true,
+	// マウスカーソル
+	"setcursor":      true,
+	"setcursorclick": true,
+	"delcursor":      true,
+	"showsyscursor":  true,
+	// アプリケーションウィンドウ
+	"setwindowtitle":  true,
+	"setwindowicon":   true,
+	"setwindowsize":   true,
+	"getdisplayscale": true,
+	// 実行環境の問い合わせ
+	"getscreenwidth":  true,
+	"getscreenheight": true,
+	"getplatform":     true,
+	"isheadless":      true,
+	"getlang":         true,
+	"hassoundfont":    true,
+	// ロケールに合わせた書式
+	"formatnumber": true,
+	"formatdate":   true,
+	// 画像の書き出し
+	"savesprite": true,
+	// 重なり順
+	"bringwintofront":  true,
+	"sendwintoback":    true,
+	"bringcasttofront": true,
+	"sendcasttoback":   true,
+	// ティックの扱い
+	"settickpolicy":   true,
+	"getdroppedticks": true,
+	// 複数の条件の待ち
+	"waitany":       true,
+	"getwaitresult": true,
+	// タイマー
+	"settimer":  true,
+	"killtimer": true,
+	// 引数付きのシーケンス
+	"spawn":   true,
+	"kill":    true,
+	"sendmes": true,
+	// チャプター
+	"chapter": true,
+}
+
+// extensionEvents は son-et で追加したイベント型
+var extensionEvents = map[string]bool{
+	"FADE_END":   true,
+	"MIDI_NOTE":  true,
+	"MIDI_LYRIC": true,
+	"PIC_READY":  true,
+	"TIMER":      true,
+}
+
+// IsExtensionBuiltin reports whether name (case-insensitive) is a builtin added by son-et.
+func IsExtensionBuiltin(name string) bool {
+	return extensionBuiltins[strings.ToLower(name)]
+}
+
+// IsExtensionEvent reports whether eventType (case-insensitive) is an event type added by son-et.
+func IsExtensionEvent(eventType string) bool {
+	return extensionEvents[strings.ToUpper(eventType)]
+}
+
+// High Color（16bit, RGB565）の各チャンネルのビット数
+const (
+	highColorRedBits   = 5
+	highColorGreenBits = 6
+	highColorBlueBits  = 5
+)
+
+// RoundColor rounds a 0xRRGGBB color to the precision of a 16-bit High Color
+// display, as the original FILLY did on the typical desktop of the time.
+// Each channel is truncated to its RGB565 width and expanded back by bit
+// replication, so 0xFFFFFF and 0x000000 are unchanged.
+func RoundColor(c int) int {
+	r := roundChannel((c>>16)&0xFF, highColorRedBits)
+	g := roundChannel((c>>8)&0xFF, highColorGreenBits)
+	b := roundChannel(c&0xFF, highColorBlueBits)
+	return r<<16 | g<<8 | b
+}
+
+// roundChannel は8bitのチャンネル値を bits ビットに落としてから8bitに戻す
+func roundChannel(v, bits int) int {
+	v >>= 8 - bits
+	return v<<(8-bits) | v>>(2*bits-8)
+}
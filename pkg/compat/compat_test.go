@@ -0,0 +1,78 @@
+package compat
+
+import "testing"
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		input   string
+		want    Mode
+		wantErr bool
+	}{
+		{"", Extended, false},
+		{"extended", Extended, false},
+		{"filly97", FILLY97, false},
+		{"FILLY97", FILLY97, false},
+		{" Extended ", Extended, false},
+		{"filly95", Extended, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			got, err := Parse(tt.input)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Parse(%q) error = %v, wantErr %v", tt.input, err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("Parse(%q) = %v, want %v", tt.input, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestModeString(t *testing.T) {
+	for _, name := range Names() {
+		m, err := Parse(name)
+		if err != nil {
+			t.Fatalf("Parse(%q) failed: %v", name, err)
+		}
+		if m.String() != name {
+			t.Errorf("Parse(%q).String() = %q", name, m.String())
+		}
+	}
+	if Extended.Strict() || !FILLY97.Strict() {
+		t.Error("only FILLY97 should be strict")
+	}
+}
+
+func TestIsExtension(t *testing.T) {
+	if !IsExtensionBuiltin("OnKey") || !IsExtensionBuiltin("setgamma") {
+		t.Error("OnKey and SetGamma should be extension builtins")
+	}
+	if IsExtensionBuiltin("LoadPic") || IsExtensionBuiltin("ArraySize") {
+		t.Error("original FILLY builtins should not be extensions")
+	}
+	if !IsExtensionEvent("MIDI_NOTE") || !IsExtensionEvent("fade_end") {
+		t.Error("MIDI_NOTE and FADE_END should be extension events")
+	}
+	if IsExtensionEvent("TIME") || IsExtensionEvent("MIDI_TIME") {
+		t.Error("original FILLY events should not be extensions")
+	}
+}
+
+func TestRoundColor(t *testing.T) {
+	tests := []struct {
+		input int
+		want  int
+	}{
+		{0x000000, 0x000000},
+		{0xFFFFFF, 0xFFFFFF},
+		{0xFF0000, 0xFF0000},
+		{0x808080, 0x848284},
+		{0x123456, 0x103452},
+		{0x070307, 0x000000},
+	}
+	for _, tt := range tests {
+		if got := RoundColor(tt.input); got != tt.want {
+			t.Errorf("RoundColor(0x%06X) = 0x%06X, want 0x%06X", tt.input, got, tt.want)
+		}
+	}
+}
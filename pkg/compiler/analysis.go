@@ -0,0 +1,54 @@
+package compiler
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/zurustar/son-et/pkg/compiler/compiler"
+	"github.com/zurustar/son-et/pkg/compiler/lexer"
+	"github.com/zurustar/son-et/pkg/compiler/parser"
+)
+
+// Analysis holds what the static checks before playback (unsupported functions,
+// argument errors and the tick budget) need from a script. It is collected from the
+// AST the compiler builds anyway, so the checks do not parse the script again.
+// Lines are in the preprocessed source.
+type Analysis struct {
+	Functions []string                 // Functions defined by the script
+	Calls     []compiler.CallSite      // Every function call, in source order
+	Args      []compiler.ArgDiagnostic // Built-in calls with invalid arguments, in source order
+	Ticks     []TickEstimate           // Heaviest tick of every sequence, in source order
+}
+
+// newAnalysis collects the analysis of a program compiled by c.
+func newAnalysis(program *parser.Program, c *compiler.Compiler) *Analysis {
+	return &Analysis{
+		Functions: c.DefinedFunctions(),
+		Calls:     c.CallSites(),
+		Args:      c.ArgDiagnostics(),
+		Ticks:     EstimateTicks(program),
+	}
+}
+
+// Analyze parses and compiles source and returns its analysis.
+// Use CompileProgram to analyze a script while compiling it.
+func Analyze(source string, opts CompileOptions) (*Analysis, error) {
+	p := parser.New(lexer.New(source))
+	p.SetCompatMode(opts.Compat)
+	program, errs := p.ParseProgram()
+	if len(errs) > 0 {
+		return nil, fmt.Errorf("failed to parse script: %w", errs[0])
+	}
+	c := compiler.New()
+	c.Compile(program)
+	return newAnalysis(program, c), nil
+}
+
+// defined returns the set of lower-case names of the functions defined by the script.
+func (a *Analysis) defined() map[string]bool {
+	defined := make(map[string]bool, len(a.Functions))
+	for _, name := range a.Functions {
+		defined[strings.ToLower(name)] = true
+	}
+	return defined
+}
@@ -0,0 +1,67 @@
+package compiler
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/zurustar/son-et/pkg/compiler/compiler"
+)
+
+// FindArgErrors collects the built-in function calls across the whole script whose
+// argument count or literal argument types do not match the function's signature
+// (opcode.Signature).
+//
+// Functions the script defines itself and functions isBuiltin rejects (unsupported
+// functions) are skipped. Variable and expression arguments are checked by the VM at
+// run time. The result is in source order.
+func FindArgErrors(source string, opts CompileOptions, isBuiltin func(name string) bool) ([]compiler.ArgDiagnostic, error) {
+	a, err := Analyze(source, opts)
+	if err != nil {
+		return nil, err
+	}
+	return a.ArgErrors(isBuiltin), nil
+}
+
+// ArgErrors returns the built-in calls with invalid arguments (see FindArgErrors).
+func (a *Analysis) ArgErrors(isBuiltin func(name string) bool) []compiler.ArgDiagnostic {
+	defined := a.defined()
+	var diags []compiler.ArgDiagnostic
+	for _, d := range a.Args {
+		if defined[strings.ToLower(d.Err.Func)] || !isBuiltin(d.Err.Func) {
+			continue
+		}
+		diags = append(diags, d)
+	}
+	return diags
+}
+
+// FormatArgReport formats the argument errors as a multi-line report of
+// "<error> at <file>:<line>" lines (an empty string if there are none).
+// Calls that only pass extra arguments are listed separately: the VM ignores the
+// extra arguments, as FILLY did, so they are warnings rather than invalid calls.
+// src maps the lines back to the files they came from; if it is nil, the lines of
+// the preprocessed source are reported.
+func FormatArgReport(src *PreprocessResult, diags []compiler.ArgDiagnostic) string {
+	var invalid, extra []compiler.ArgDiagnostic
+	for _, d := range diags {
+		if d.Err.ExtraArgs() {
+			extra = append(extra, d)
+		} else {
+			invalid = append(invalid, d)
+		}
+	}
+
+	var sb strings.Builder
+	section := func(title string, diags []compiler.ArgDiagnostic) {
+		if len(diags) == 0 {
+			return
+		}
+		fmt.Fprintf(&sb, "%d call(s) %s:\n", len(diags), title)
+		for _, d := range diags {
+			fmt.Fprintf(&sb, "  %v at %s\n", d.Err, src.Position(d.Line))
+		}
+	}
+	section("with invalid arguments", invalid)
+	section("with extra arguments (ignored)", extra)
+	return sb.String()
+}
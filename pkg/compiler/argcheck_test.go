@@ -0,0 +1,89 @@
+package compiler
+
+import (
+	"strings"
+	"testing"
+)
+
+// TestFindArgErrors tests that invalid built-in arguments are collected across the whole script.
+func TestFindArgErrors(t *testing.T) {
+	source := `main() {
+	p = LoadPic(1);
+	MovePic(p, "abc", 0, 0, 0, 0, 0, 0);
+	CreatePic("x");
+	Sparkle("x");
+	DelPic(p, 1);
+}
+
+createpic(name) {
+}
+`
+	builtins := map[string]bool{"loadpic": true, "movepic": true, "createpic": true, "delpic": true}
+	isBuiltin := func(name string) bool { return builtins[strings.ToLower(name)] }
+
+	diags, err := FindArgErrors(source, CompileOptions{}, isBuiltin)
+	if err != nil {
+		t.Fatalf("FindArgErrors failed: %v", err)
+	}
+	// CreatePic is defined by the script and Sparkle is not a built-in function
+	if len(diags) != 3 {
+		t.Fatalf("expected 3 diagnostics, got %v", diags)
+	}
+
+	report := FormatArgReport(nil, diags)
+	want := `2 call(s) with invalid arguments:
+  LoadPic(): arg 1 expects string, got int 1 at line 2
+  MovePic(): arg 2 expects int, got string "abc" at line 3
+1 call(s) with extra arguments (ignored):
+  DelPic(): expects 1 argument, got 2 at line 6
+`
+	if report != want {
+		t.Errorf("report = %q, want %q", report, want)
+	}
+}
+
+// TestFindArgErrors_None tests that a script with valid arguments has no report.
+func TestFindArgErrors_None(t *testing.T) {
+	diags, err := FindArgErrors(`main() { p = LoadPic("a.bmp"); }`, CompileOptions{}, func(string) bool { return true })
+	if err != nil {
+		t.Fatalf("FindArgErrors failed: %v", err)
+	}
+	if len(diags) != 0 {
+		t.Errorf("expected no diagnostics, got %v", diags)
+	}
+	if report := FormatArgReport(nil, diags); report != "" {
+		t.Errorf("expected an empty report, got %q", report)
+	}
+}
+
+// TestFindArgErrors_ParseError tests that parse errors are returned.
+func TestFindArgErrors_ParseError(t *testing.T) {
+	if _, err := FindArgErrors(`x = = 5;`, CompileOptions{}, func(string) bool { return true }); err == nil {
+		t.Error("expected a parse error")
+	}
+}
+
+// TestFormatArgReport_Includes tests that calls in included files are reported at
+// their line in that file rather than in the preprocessed source.
+func TestFormatArgReport_Includes(t *testing.T) {
+	dir := t.TempDir()
+	writeCacheTestFile(t, dir, "START.TFY", "#include \"LIB.TFY\"\nmain() {\n\tp = LoadPic(1);\n}\n")
+	writeCacheTestFile(t, dir, "LIB.TFY", "// library\nShow() {\n\tMovePic(1, \"abc\", 0, 0, 0, 0, 0, 0);\n}\n")
+
+	_, result, err := CompileWithPreprocessor(dir, "START.TFY")
+	if err != nil {
+		t.Fatalf("CompileWithPreprocessor failed: %v", err)
+	}
+	diags, err := FindArgErrors(result.Source, CompileOptions{}, func(string) bool { return true })
+	if err != nil {
+		t.Fatalf("FindArgErrors failed: %v", err)
+	}
+	report := FormatArgReport(result, diags)
+	want := `2 call(s) with invalid arguments:
+  MovePic(): arg 2 expects int, got string "abc" at LIB.TFY:3
+  LoadPic(): arg 1 expects string, got int 1 at START.TFY:3
+`
+	if report != want {
+		t.Errorf("report = %q, want %q", report, want)
+	}
+}
@@ -0,0 +1,103 @@
+package compiler
+
+import (
+	"strings"
+
+	"github.com/zurustar/son-et/pkg/opcode"
+)
+
+// AssetKind is the kind of an asset referenced by a script.
+type AssetKind int
+
+const (
+	AssetPicture AssetKind = iota // Image loaded by LoadPic
+	AssetMIDI                     // MIDI played by PlayMIDI
+	AssetWAVE                     // WAV played by PlayWAVE
+)
+
+// String returns the name of the asset kind.
+func (k AssetKind) String() string {
+	switch k {
+	case AssetPicture:
+		return "picture"
+	case AssetMIDI:
+		return "midi"
+	case AssetWAVE:
+		return "wave"
+	}
+	return "unknown"
+}
+
+// Asset is an asset referenced by a literal file name in the script.
+type Asset struct {
+	Kind AssetKind
+	Path string // File name as written in the script (kept as is, even with a leading "/")
+}
+
+// assetBuiltins maps the builtins that take a file name as their first argument
+// (lowercase) to the kind of asset they load.
+var assetBuiltins = map[string]AssetKind{
+	"loadpic":  AssetPicture,
+	"playmidi": AssetMIDI,
+	"playwave": AssetWAVE,
+}
+
+// CollectAssets collects the assets whose file name is passed as a string literal.
+//
+// It walks the OpCodes in order, including function bodies, mes() blocks and control
+// statements, and returns only the first occurrence of each asset. Calls that pass the
+// file name in a variable or expression are not known until run time and are skipped.
+// The engine uses the list to preload assets asynchronously before they are needed.
+func CollectAssets(ops []OpCode) []Asset {
+	var assets []Asset
+	seen := make(map[Asset]bool)
+	var walk func(ops []opcode.OpCode)
+	var walkArg func(arg any)
+
+	walkArg = func(arg any) {
+		switch a := arg.(type) {
+		case opcode.OpCode:
+			walk([]opcode.OpCode{a})
+		case []opcode.OpCode:
+			walk(a)
+		case []any:
+			for _, v := range a {
+				walkArg(v)
+			}
+		}
+	}
+
+	walk = func(ops []opcode.OpCode) {
+		for _, op := range ops {
+			if op.Cmd == opcode.Call && len(op.Args) >= 2 {
+				name, _ := op.Args[0].(string)
+				kind, isAsset := assetBuiltins[strings.ToLower(name)]
+				// opcode.Variable is a distinct type from string, so only literals match
+				if path, isLiteral := op.Args[1].(string); isAsset && isLiteral && path != "" {
+					asset := Asset{Kind: kind, Path: path}
+					if !seen[asset] {
+						seen[asset] = true
+						assets = append(assets, asset)
+					}
+				}
+			}
+			for _, arg := range op.Args {
+				walkArg(arg)
+			}
+		}
+	}
+
+	walk(ops)
+	return assets
+}
+
+// AssetPaths returns the file names of the assets of the given kind in order.
+func AssetPaths(assets []Asset, kind AssetKind) []string {
+	var paths []string
+	for _, a := range assets {
+		if a.Kind == kind {
+			paths = append(paths, a.Path)
+		}
+	}
+	return paths
+}
@@ -0,0 +1,59 @@
+package compiler
+
+import (
+	"reflect"
+	"testing"
+)
+
+// TestCollectAssets tests that literal asset paths are collected from every nesting level.
+func TestCollectAssets(t *testing.T) {
+	source := `
+main() {
+	p = LoadPic("title.bmp");
+	PlayMIDI("/bgm.mid");
+	name = "dynamic.bmp";
+	LoadPic(name);
+	if (p > 0) {
+		LoadPic("branch.bmp");
+	}
+	mes(TIME) {
+		step {
+			loadpic("event.bmp");,
+			PlayWAVE("se.wav");,
+			LoadPic("title.bmp");,
+			end_step;
+			del_me;
+		}
+	}
+}
+
+sub() {
+	for (i = 0; i < 3; i = i + 1) {
+		LoadPic("loop.bmp");
+	}
+}
+`
+	ops, errs := Compile(source)
+	if len(errs) > 0 {
+		t.Fatalf("compile failed: %v", errs)
+	}
+
+	got := CollectAssets(ops)
+	want := []Asset{
+		{AssetPicture, "title.bmp"},
+		{AssetMIDI, "/bgm.mid"},
+		{AssetPicture, "branch.bmp"},
+		{AssetPicture, "event.bmp"},
+		{AssetWAVE, "se.wav"},
+		{AssetPicture, "loop.bmp"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("CollectAssets() = %v, want %v", got, want)
+	}
+
+	pictures := AssetPaths(got, AssetPicture)
+	wantPictures := []string{"title.bmp", "branch.bmp", "event.bmp", "loop.bmp"}
+	if !reflect.DeepEqual(pictures, wantPictures) {
+		t.Errorf("AssetPaths(picture) = %v, want %v", pictures, wantPictures)
+	}
+}
@@ -0,0 +1,705 @@
+package compiler
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/gob"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"runtime/debug"
+	"strings"
+	"sync"
+
+	"github.com/zurustar/son-et/pkg/compat"
+	"github.com/zurustar/son-et/pkg/compiler/compiler"
+	"github.com/zurustar/son-et/pkg/compiler/lexer"
+	"github.com/zurustar/son-et/pkg/compiler/parser"
+	"github.com/zurustar/son-et/pkg/compiler/preprocessor"
+	"github.com/zurustar/son-et/pkg/fileutil"
+	"github.com/zurustar/son-et/pkg/opcode"
+)
+
+// CacheDirName is the name of the codegen cache directory created in the title directory.
+const CacheDirName = ".sonet-cache"
+
+// cacheFormatVersion is bumped whenever the OpCode layout or the cache file format changes,
+// so that caches written by an older compiler are ignored.
+const cacheFormatVersion = 2
+
+// cacheFileExt is the extension of a cache file.
+const cacheFileExt = ".gob"
+
+// The cache holds two kinds of files:
+//
+//   - one per source file (fileEntry), keyed by the hash of its content, the compiler
+//     version and the compatibility mode. It holds the OpCode and the analysis of the
+//     file compiled on its own, so editing one file only recompiles that file.
+//   - one per entry file (cacheEntry), listing the files the preprocessor read for it
+//     and how they were put together, so a run where nothing changed neither
+//     preprocesses, lexes nor parses anything.
+
+// cacheEntry is the cache file of an entry file.
+// It holds the hash of every file the preprocessor read (the entry file, its #include
+// files and the companion .INI) and the preprocessing result, which records where each
+// file was inserted.
+type cacheEntry struct {
+	Version   string
+	Compat    compat.Mode
+	EntryFile string
+	Files     []cachedFile
+	Result    PreprocessResult
+	Constants cachedSegment // The named constants appended by the preprocessor
+}
+
+// cachedFile is a file the preprocessor read.
+type cachedFile struct {
+	Name    string
+	Hash    string // SHA-256 of the file content (empty if Missing)
+	Missing bool   // The file did not exist (e.g. no companion .INI)
+}
+
+// fileEntry is the cache file of a source file.
+// A file is compiled in segments split at its #include directives: the top-level
+// statements before the first directive, between two directives and after the last
+// one. The segments of the included files go in between when the program is assembled.
+type fileEntry struct {
+	Segments []cachedSegment
+}
+
+// cachedSegment is the OpCode and analysis of consecutive top-level statements.
+// Lines are those of the source file (of the appended source for the constants).
+type cachedSegment struct {
+	OpCodes   []cachedOp
+	Functions []string
+	Calls     []compiler.CallSite
+	Args      []compiler.ArgDiagnostic
+	Ticks     tickSummary
+}
+
+// compilerVersion identifies the compiler that generated the OpCode:
+// the cache format version plus the module version and VCS revision of the binary.
+var compilerVersion = sync.OnceValue(func() string {
+	parts := []string{fmt.Sprintf("v%d", cacheFormatVersion)}
+	if info, ok := debug.ReadBuildInfo(); ok {
+		parts = append(parts, info.GoVersion, info.Main.Version)
+		for _, s := range info.Settings {
+			if s.Key == "vcs.revision" || s.Key == "vcs.time" {
+				parts = append(parts, s.Value)
+			}
+		}
+	}
+	return strings.Join(parts, " ")
+})
+
+// cacheFilePath returns the cache file path for an entry file.
+// Entry file names are matched case-insensitively, like the files themselves.
+func cacheFilePath(cacheDir, entryFile string) string {
+	sum := sha256.Sum256([]byte(strings.ToUpper(filepath.Clean(entryFile))))
+	return filepath.Join(cacheDir, hex.EncodeToString(sum[:8])+cacheFileExt)
+}
+
+// fileCachePath returns the cache file path for a source file with the given content hash.
+func fileCachePath(cacheDir, hash string, opts CompileOptions) string {
+	sum := sha256.Sum256([]byte(fmt.Sprintf("%s\x00%v\x00%s", compilerVersion(), opts.Compat, hash)))
+	return filepath.Join(cacheDir, "src-"+hex.EncodeToString(sum[:8])+cacheFileExt)
+}
+
+// hashContent returns the hex SHA-256 of data.
+func hashContent(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// readCacheFile decodes a cache file into v.
+func readCacheFile(path string, v any) bool {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return false
+	}
+	return gob.NewDecoder(bytes.NewReader(data)).Decode(v) == nil
+}
+
+// writeCacheFile encodes v into a cache file.
+func writeCacheFile(path string, v any) error {
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(v); err != nil {
+		return fmt.Errorf("failed to encode codegen cache: %w", err)
+	}
+	dir := filepath.Dir(path)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return fmt.Errorf("failed to create codegen cache directory: %w", err)
+	}
+
+	// Write to a temporary file and rename it, so another run never reads a partly written file
+	tmp, err := os.CreateTemp(dir, filepath.Base(path)+".*")
+	if err != nil {
+		return fmt.Errorf("failed to write codegen cache: %w", err)
+	}
+	if _, err := tmp.Write(buf.Bytes()); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return fmt.Errorf("failed to write codegen cache: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return fmt.Errorf("failed to write codegen cache: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		os.Remove(tmp.Name())
+		return fmt.Errorf("failed to write codegen cache: %w", err)
+	}
+	return nil
+}
+
+// loadCache returns the program compiled from the entry file if none of the files the
+// preprocessor read for it changed and the cache files of its source files are present.
+// Otherwise (or if the cache is unreadable) ok is false.
+func loadCache(cacheDir string, fsys fileutil.FileSystem, entryFile string, opts CompileOptions) (program *Program, ok bool) {
+	var entry cacheEntry
+	if !readCacheFile(cacheFilePath(cacheDir, entryFile), &entry) {
+		return nil, false
+	}
+	if entry.Version != compilerVersion() || entry.Compat != opts.Compat || !strings.EqualFold(entry.EntryFile, entryFile) {
+		return nil, false
+	}
+	hashes := make(map[string]string, len(entry.Files))
+	for _, f := range entry.Files {
+		content, err := fsys.ReadFile(f.Name)
+		if f.Missing {
+			if !errors.Is(err, fs.ErrNotExist) {
+				return nil, false
+			}
+			continue
+		}
+		if err != nil || hashContent(content) != f.Hash {
+			return nil, false
+		}
+		hashes[cacheFileKey(f.Name)] = f.Hash
+	}
+
+	files := make(map[string]*fileEntry, len(entry.Result.Files))
+	for _, f := range entry.Result.Files {
+		hash, ok := hashes[cacheFileKey(f.Name)]
+		if !ok {
+			return nil, false
+		}
+		var fe fileEntry
+		if !readCacheFile(fileCachePath(cacheDir, hash, opts), &fe) {
+			return nil, false
+		}
+		files[f.Name] = &fe
+	}
+	program, err := assemble(&entry.Result, files, entry.Constants)
+	if err != nil {
+		return nil, false
+	}
+	return program, true
+}
+
+// storeCache writes the cache file of the entry file.
+// files are the files the preprocessor read (recorded by hashingFS).
+func storeCache(cacheDir, entryFile string, files []cachedFile, opts CompileOptions, result *PreprocessResult, constants cachedSegment) error {
+	return writeCacheFile(cacheFilePath(cacheDir, entryFile), &cacheEntry{
+		Version:   compilerVersion(),
+		Compat:    opts.Compat,
+		EntryFile: entryFile,
+		Files:     files,
+		Result:    *result,
+		Constants: constants,
+	})
+}
+
+// cacheFileKey returns the key matching file names case-insensitively, like the files themselves.
+func cacheFileKey(name string) string {
+	return strings.ToUpper(filepath.Clean(name))
+}
+
+// hashingFS records the hash of every file read through it,
+// so the cache is keyed by exactly the files the preprocessor consulted.
+type hashingFS struct {
+	fileutil.FileSystem
+	files []cachedFile
+	data  map[string][]byte // Content of the files read, by cacheFileKey
+	seen  map[string]bool
+}
+
+// newHashingFS wraps fsys to record the files read through ReadFile.
+func newHashingFS(fsys fileutil.FileSystem) *hashingFS {
+	return &hashingFS{FileSystem: fsys, data: make(map[string][]byte), seen: make(map[string]bool)}
+}
+
+// ReadFile reads the file and records its hash (or that it does not exist).
+func (h *hashingFS) ReadFile(name string) ([]byte, error) {
+	data, err := h.FileSystem.ReadFile(name)
+	key := cacheFileKey(name)
+	if h.seen[key] {
+		return data, err
+	}
+	switch {
+	case err == nil:
+		h.seen[key] = true
+		h.data[key] = data
+		h.files = append(h.files, cachedFile{Name: name, Hash: hashContent(data)})
+	case errors.Is(err, fs.ErrNotExist):
+		h.seen[key] = true
+		h.files = append(h.files, cachedFile{Name: name, Missing: true})
+	}
+	return data, err
+}
+
+// compileWithCache compiles the entry file through the codegen cache in opts.CacheDir.
+// If no file changed, the program is assembled from the cache without preprocessing,
+// lexing or parsing. Otherwise only the changed source files are compiled.
+// Failing to write the cache (e.g. a read-only title directory) is not an error.
+func compileWithCache(dirPath, entryFile string, opts CompileOptions) (*Program, *PreprocessResult, error) {
+	realFS := fileutil.NewRealFS(dirPath)
+	if program, ok := loadCache(opts.CacheDir, realFS, entryFile, opts); ok {
+		return program, program.Result, nil
+	}
+
+	hfs := newHashingFS(realFS)
+	result, err := preprocessor.NewWithFileSystem(hfs).PreprocessFile(entryFile)
+	if err != nil {
+		return nil, nil, fmt.Errorf("preprocessing failed: %w", err)
+	}
+
+	// Compile the files not in the cache, each on its own
+	files := make(map[string]*fileEntry, len(result.Files))
+	compiled := make(map[string]*fileEntry)
+	for _, f := range result.Files {
+		data := hfs.data[cacheFileKey(f.Name)]
+		path := fileCachePath(opts.CacheDir, hashContent(data), opts)
+		var fe fileEntry
+		if readCacheFile(path, &fe) {
+			files[f.Name] = &fe
+			continue
+		}
+		entry, ok := compileFile(preprocessor.DecodeSource(data), f, opts)
+		if !ok {
+			return compileUncached(result, opts)
+		}
+		files[f.Name] = entry
+		compiled[path] = entry
+	}
+	constants, ok := compileSegmentSource(result.AddedSource(), opts)
+	if !ok {
+		return compileUncached(result, opts)
+	}
+
+	// Write the cache before assembling, which rewrites the lines
+	for path, entry := range compiled {
+		_ = writeCacheFile(path, entry)
+	}
+	_ = storeCache(opts.CacheDir, entryFile, hfs.files, opts, result, constants)
+
+	program, err := assemble(result, files, constants)
+	if err != nil {
+		return compileUncached(result, opts)
+	}
+	return program, result, nil
+}
+
+// compileUncached compiles the preprocessed source as a whole, for scripts whose files
+// cannot be compiled on their own (see compileFile) or that have errors.
+// Nothing is cached.
+func compileUncached(result *PreprocessResult, opts CompileOptions) (*Program, *PreprocessResult, error) {
+	opcodes, analysis, errs := compileSource(result.Source, opts)
+	if len(errs) > 0 {
+		return nil, result, fmt.Errorf("compilation failed: %w", errors.Join(errs...))
+	}
+	opcodes = compiler.Optimize(opcodes, result.ConstantNames())
+	return &Program{OpCodes: opcodes, Result: result, Analysis: analysis}, result, nil
+}
+
+// compileFile compiles a source file on its own, in segments split at its top-level
+// #include directives (see fileEntry).
+// ok is false if the file does not compile on its own, or if its top-level directives
+// are not the ones the preprocessor expanded (e.g. a directive inside a function); the
+// preprocessed source is then compiled as a whole.
+func compileFile(source string, file preprocessor.SourceFile, opts CompileOptions) (*fileEntry, bool) {
+	program, ok := parseSegmentSource(source, opts)
+	if !ok {
+		return nil, false
+	}
+	var chunks [][]parser.Statement
+	start := 0
+	for i, stmt := range program.Statements {
+		if _, ok := stmt.(*parser.IncludeDirective); ok {
+			chunks = append(chunks, program.Statements[start:i])
+			start = i + 1
+		}
+	}
+	chunks = append(chunks, program.Statements[start:])
+	if len(chunks) != len(file.Includes)+1 {
+		return nil, false
+	}
+
+	entry := &fileEntry{Segments: make([]cachedSegment, len(chunks))}
+	for i, chunk := range chunks {
+		if entry.Segments[i], ok = compileSegment(chunk); !ok {
+			return nil, false
+		}
+	}
+	return entry, true
+}
+
+// compileSegmentSource compiles source (the appended constants) as a single segment.
+func compileSegmentSource(source string, opts CompileOptions) (cachedSegment, bool) {
+	program, ok := parseSegmentSource(source, opts)
+	if !ok {
+		return cachedSegment{}, false
+	}
+	return compileSegment(program.Statements)
+}
+
+// parseSegmentSource parses source for compileFile and compileSegmentSource.
+func parseSegmentSource(source string, opts CompileOptions) (*parser.Program, bool) {
+	p := parser.New(lexer.New(source))
+	p.SetCompatMode(opts.Compat)
+	program, errs := p.ParseProgram()
+	return program, len(errs) == 0
+}
+
+// compileSegment compiles top-level statements into a segment.
+// The compiler keeps no state from one top-level statement to the next, so the
+// segments of a program put together are the OpCode of the whole program.
+func compileSegment(statements []parser.Statement) (cachedSegment, bool) {
+	c := compiler.New()
+	ops, errs := c.Compile(&parser.Program{Statements: statements})
+	if len(errs) > 0 {
+		return cachedSegment{}, false
+	}
+	encoded, err := encodeOps(ops)
+	if err != nil {
+		return cachedSegment{}, false
+	}
+	return cachedSegment{
+		OpCodes:   encoded,
+		Functions: c.DefinedFunctions(),
+		Calls:     c.CallSites(),
+		Args:      c.ArgDiagnostics(),
+		Ticks:     summarizeTicks(statements),
+	}, true
+}
+
+// assemble puts the segments of the source files together in the order the preprocessor
+// inserted the files, which is the order compiling the preprocessed source generates
+// them in, and maps their lines to the lines of result.Source.
+func assemble(result *PreprocessResult, files map[string]*fileEntry, constants cachedSegment) (*Program, error) {
+	if len(result.Files) == 0 {
+		return nil, errors.New("codegen cache: no source files")
+	}
+	a := &assembler{
+		files: files,
+		lines: make(map[preprocessor.LineOrigin]int, len(result.Lines)),
+	}
+	for i, origin := range result.Lines {
+		a.lines[origin] = i + 1
+	}
+	if err := a.file(result.Files[0].Name, result.Files); err != nil {
+		return nil, err
+	}
+	added := len(result.Lines)
+	if err := a.segment(constants, func(line int) int { return line + added }); err != nil {
+		return nil, err
+	}
+
+	a.analysis.Ticks = a.ticks.estimate()
+	return &Program{OpCodes: compiler.Optimize(a.ops, result.ConstantNames()), Result: result, Analysis: &a.analysis}, nil
+}
+
+// assembler implements assemble.
+type assembler struct {
+	files    map[string]*fileEntry
+	lines    map[preprocessor.LineOrigin]int // Line of the preprocessed source by origin
+	ops      []opcode.OpCode
+	analysis Analysis
+	ticks    tickSummary
+}
+
+// file adds the segments of a file with the files included by it in between.
+func (a *assembler) file(name string, sources []preprocessor.SourceFile) error {
+	var source *preprocessor.SourceFile
+	for i := range sources {
+		if sources[i].Name == name {
+			source = &sources[i]
+			break
+		}
+	}
+	entry := a.files[name]
+	if source == nil || entry == nil || len(entry.Segments) != len(source.Includes)+1 {
+		return fmt.Errorf("codegen cache: no segments for %s", name)
+	}
+	line := func(l int) int { return a.lines[preprocessor.LineOrigin{File: name, Line: l}] }
+	for i, seg := range entry.Segments {
+		if err := a.segment(seg, line); err != nil {
+			return err
+		}
+		if i < len(source.Includes) && source.Includes[i] != "" {
+			if err := a.file(source.Includes[i], sources); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// segment adds a segment, mapping its lines with line.
+func (a *assembler) segment(seg cachedSegment, line func(int) int) error {
+	ops, err := decodeOps(seg.OpCodes)
+	if err != nil {
+		return err
+	}
+	remapOpLines(ops, line)
+	a.ops = append(a.ops, ops...)
+	a.analysis.Functions = append(a.analysis.Functions, seg.Functions...)
+	for _, call := range seg.Calls {
+		call.Line = line(call.Line)
+		a.analysis.Calls = append(a.analysis.Calls, call)
+	}
+	for _, d := range seg.Args {
+		d.Line = line(d.Line)
+		a.analysis.Args = append(a.analysis.Args, d)
+	}
+	a.ticks.append(seg.Ticks.remap(line))
+	return nil
+}
+
+// remapOpLines maps the source lines recorded in OpCode (the line of DebugBreak) with line.
+func remapOpLines(ops []opcode.OpCode, line func(int) int) {
+	for i := range ops {
+		if ops[i].Cmd == opcode.DebugBreak && len(ops[i].Args) == 2 {
+			if l, ok := ops[i].Args[1].(int); ok {
+				ops[i].Args[1] = line(l)
+			}
+		}
+		for _, arg := range ops[i].Args {
+			remapArgLines(arg, line)
+		}
+	}
+}
+
+// remapArgLines maps the source lines recorded in the OpCode inside an argument.
+func remapArgLines(arg any, line func(int) int) {
+	switch a := arg.(type) {
+	case opcode.OpCode:
+		// OpCode arguments are values: remap the slice they share with the argument
+		remapOpLines([]opcode.OpCode{a}, line)
+	case []opcode.OpCode:
+		remapOpLines(a, line)
+	case []any:
+		for _, v := range a {
+			remapArgLines(v, line)
+		}
+	case map[string]any:
+		for _, v := range a {
+			remapArgLines(v, line)
+		}
+	}
+}
+
+// cachedOp is an OpCode in the cache file.
+//
+// OpCode.Args holds values of several types behind any, and gob neither keeps the
+// concrete types without registration nor distinguishes nil from empty slices.
+// The cache therefore stores the arguments as a tagged tree, so the VM gets back
+// exactly the OpCode the compiler generated.
+type cachedOp struct {
+	Cmd  opcode.Cmd
+	Args cachedList
+}
+
+// cachedList is a slice in the cache file (Nil distinguishes a nil slice from an empty one).
+type cachedList struct {
+	Nil   bool
+	Items []cachedArg
+}
+
+// cachedArgKind is the type of a cached argument.
+type cachedArgKind uint8
+
+const (
+	argNil cachedArgKind = iota
+	argString
+	argInt
+	argInt64
+	argFloat
+	argBool
+	argVariable
+	argOpCode
+	argOpCodes
+	argList
+	argMap
+)
+
+// cachedArg is an OpCode argument in the cache file.
+type cachedArg struct {
+	Kind  cachedArgKind
+	Str   string // argString, argVariable
+	Int   int64  // argInt, argInt64, argBool (0/1)
+	Float float64
+	Ops   []cachedOp // argOpCode (1 element), argOpCodes
+	Nil   bool       // argOpCodes: nil slice
+	List  cachedList // argList, argMap (values)
+	Keys  []string   // argMap
+}
+
+// encodeOps converts OpCode to the cache representation.
+// An argument of a type the cache does not know is an error (the OpCode is not cached).
+func encodeOps(ops []opcode.OpCode) ([]cachedOp, error) {
+	encoded := make([]cachedOp, len(ops))
+	for i, op := range ops {
+		args, err := encodeList(op.Args)
+		if err != nil {
+			return nil, err
+		}
+		encoded[i] = cachedOp{Cmd: op.Cmd, Args: args}
+	}
+	return encoded, nil
+}
+
+// encodeList converts an argument slice to the cache representation.
+func encodeList(values []any) (cachedList, error) {
+	list := cachedList{Nil: values == nil, Items: make([]cachedArg, len(values))}
+	for i, v := range values {
+		arg, err := encodeArg(v)
+		if err != nil {
+			return cachedList{}, err
+		}
+		list.Items[i] = arg
+	}
+	return list, nil
+}
+
+// encodeArg converts an argument to the cache representation.
+func encodeArg(v any) (cachedArg, error) {
+	switch a := v.(type) {
+	case nil:
+		return cachedArg{Kind: argNil}, nil
+	case string:
+		return cachedArg{Kind: argString, Str: a}, nil
+	case opcode.Variable:
+		return cachedArg{Kind: argVariable, Str: string(a)}, nil
+	case int:
+		return cachedArg{Kind: argInt, Int: int64(a)}, nil
+	case int64:
+		return cachedArg{Kind: argInt64, Int: a}, nil
+	case float64:
+		return cachedArg{Kind: argFloat, Float: a}, nil
+	case bool:
+		arg := cachedArg{Kind: argBool}
+		if a {
+			arg.Int = 1
+		}
+		return arg, nil
+	case opcode.OpCode:
+		ops, err := encodeOps([]opcode.OpCode{a})
+		return cachedArg{Kind: argOpCode, Ops: ops}, err
+	case []opcode.OpCode:
+		ops, err := encodeOps(a)
+		return cachedArg{Kind: argOpCodes, Ops: ops, Nil: a == nil}, err
+	case []any:
+		list, err := encodeList(a)
+		return cachedArg{Kind: argList, List: list}, err
+	case map[string]any:
+		keys := make([]string, 0, len(a))
+		values := make([]any, 0, len(a))
+		for k, v := range a {
+			keys = append(keys, k)
+			values = append(values, v)
+		}
+		list, err := encodeList(values)
+		return cachedArg{Kind: argMap, Keys: keys, List: list}, err
+	}
+	return cachedArg{}, fmt.Errorf("codegen cache: unsupported OpCode argument type %T", v)
+}
+
+// decodeOps restores OpCode from the cache representation.
+func decodeOps(encoded []cachedOp) ([]opcode.OpCode, error) {
+	if encoded == nil {
+		return nil, nil
+	}
+	ops := make([]opcode.OpCode, len(encoded))
+	for i, op := range encoded {
+		args, err := decodeList(op.Args)
+		if err != nil {
+			return nil, err
+		}
+		ops[i] = opcode.OpCode{Cmd: op.Cmd, Args: args}
+	}
+	return ops, nil
+}
+
+// decodeList restores an argument slice from the cache representation.
+func decodeList(list cachedList) ([]any, error) {
+	if list.Nil {
+		return nil, nil
+	}
+	values := make([]any, len(list.Items))
+	for i, item := range list.Items {
+		v, err := decodeArg(item)
+		if err != nil {
+			return nil, err
+		}
+		values[i] = v
+	}
+	return values, nil
+}
+
+// decodeArg restores an argument from the cache representation.
+func decodeArg(arg cachedArg) (any, error) {
+	switch arg.Kind {
+	case argNil:
+		return nil, nil
+	case argString:
+		return arg.Str, nil
+	case argVariable:
+		return opcode.Variable(arg.Str), nil
+	case argInt:
+		return int(arg.Int), nil
+	case argInt64:
+		return arg.Int, nil
+	case argFloat:
+		return arg.Float, nil
+	case argBool:
+		return arg.Int != 0, nil
+	case argOpCode:
+		if len(arg.Ops) != 1 {
+			return nil, errors.New("codegen cache: malformed OpCode argument")
+		}
+		ops, err := decodeOps(arg.Ops)
+		if err != nil {
+			return nil, err
+		}
+		return ops[0], nil
+	case argOpCodes:
+		if arg.Nil {
+			return []opcode.OpCode(nil), nil
+		}
+		ops, err := decodeOps(arg.Ops)
+		if ops == nil && err == nil {
+			ops = []opcode.OpCode{}
+		}
+		return ops, err
+	case argList:
+		return decodeList(arg.List)
+	case argMap:
+		values, err := decodeList(arg.List)
+		if err != nil {
+			return nil, err
+		}
+		if len(values) != len(arg.Keys) {
+			return nil, errors.New("codegen cache: malformed map argument")
+		}
+		m := make(map[string]any, len(arg.Keys))
+		for i, k := range arg.Keys {
+			m[k] = values[i]
+		}
+		return m, nil
+	}
+	return nil, fmt.Errorf("codegen cache: unknown argument kind %d", arg.Kind)
+}
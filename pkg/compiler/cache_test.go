@@ -0,0 +1,240 @@
+package compiler
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/zurustar/son-et/pkg/compat"
+	"github.com/zurustar/son-et/pkg/fileutil"
+)
+
+// cacheTestMain holds the kinds of arguments the compiler generates (arrays, switch,
+// mes, floats, DebugBreak) so the cache round trip can check their types are kept.
+// The #include directives are at the top and between the statements, so the segments
+// of the included files go at both places.
+const cacheTestMain = `#include "sub.tfy"
+int arr[];
+#include "loop.tfy"
+
+main() {
+	x = 1.5;
+	arr[0] = "text";
+	switch (x) {
+	case 1:
+		sub();
+		break;
+	default:
+		x = -x;
+	}
+	mes(TIME) {
+		step(10) {
+			DebugBreak("loop");,
+			end_step;
+			del_me;
+		}
+	}
+}
+`
+
+const cacheTestSub = `sub() {
+	LoadPic("back.bmp");
+}
+`
+
+const cacheTestLoop = `spin() {
+	DebugBreak("spin");
+	while (1) {
+		DelPic(1, 2);
+	}
+}
+`
+
+// writeCacheTestTitle creates a title for the cache tests.
+func writeCacheTestTitle(t *testing.T) string {
+	t.Helper()
+	dir := t.TempDir()
+	writeCacheTestFile(t, dir, "MAIN.TFY", cacheTestMain)
+	writeCacheTestFile(t, dir, "SUB.TFY", cacheTestSub)
+	writeCacheTestFile(t, dir, "LOOP.TFY", cacheTestLoop)
+	return dir
+}
+
+func writeCacheTestFile(t *testing.T, dir, name, content string) {
+	t.Helper()
+	if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// checkCachedProgram compares a program compiled through the cache with the program
+// compiled without it.
+func checkCachedProgram(t *testing.T, got, want *Program) {
+	t.Helper()
+	if !reflect.DeepEqual(got.OpCodes, want.OpCodes) {
+		t.Errorf("cached OpCode differs:\n got %#v\nwant %#v", got.OpCodes, want.OpCodes)
+	}
+	if got.Result.Source != want.Result.Source || !reflect.DeepEqual(got.Result.IncludedFiles, want.Result.IncludedFiles) {
+		t.Errorf("cached preprocess result differs: %+v", got.Result)
+	}
+	if !reflect.DeepEqual(got.Analysis.Functions, want.Analysis.Functions) ||
+		!reflect.DeepEqual(got.Analysis.Calls, want.Analysis.Calls) ||
+		!reflect.DeepEqual(got.Analysis.Ticks, want.Analysis.Ticks) {
+		t.Errorf("cached analysis differs:\n got %+v\nwant %+v", got.Analysis, want.Analysis)
+	}
+	// ArgError is compared by its report: the cache does not keep the signature pointer
+	if g, w := FormatArgReport(got.Result, got.Analysis.Args), FormatArgReport(want.Result, want.Analysis.Args); g != w {
+		t.Errorf("cached argument report differs:\n got %q\nwant %q", g, w)
+	}
+}
+
+// countSourceCacheFiles returns the number of source file cache files.
+func countSourceCacheFiles(t *testing.T, cacheDir string) int {
+	t.Helper()
+	files, err := filepath.Glob(filepath.Join(cacheDir, "src-*"+cacheFileExt))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return len(files)
+}
+
+func TestCompileWithCache(t *testing.T) {
+	dir := writeCacheTestTitle(t)
+	opts := CompileOptions{CacheDir: filepath.Join(dir, CacheDirName)}
+
+	want, err := CompileProgram(dir, "MAIN.TFY", nil, CompileOptions{})
+	if err != nil {
+		t.Fatalf("compile failed: %v", err)
+	}
+	if len(want.Analysis.Args) == 0 || len(want.Analysis.Ticks) == 0 {
+		t.Fatalf("the test script should have argument diagnostics and ticks: %+v", want.Analysis)
+	}
+
+	// The first run compiles every file and writes the cache
+	program, err := CompileProgram(dir, "MAIN.TFY", nil, opts)
+	if err != nil {
+		t.Fatalf("compile with cache failed: %v", err)
+	}
+	checkCachedProgram(t, program, want)
+	cacheFile := cacheFilePath(opts.CacheDir, "MAIN.TFY")
+	if _, err := os.Stat(cacheFile); err != nil {
+		t.Fatalf("expected a cache file: %v", err)
+	}
+	if n := countSourceCacheFiles(t, opts.CacheDir); n != 3 {
+		t.Fatalf("expected a cache file per source file, got %d", n)
+	}
+
+	// The second run assembles the program from the cache (the entry file name is
+	// matched case-insensitively)
+	cached, ok := loadCache(opts.CacheDir, fileutil.NewRealFS(dir), "main.tfy", opts)
+	if !ok {
+		t.Fatal("expected a cache hit")
+	}
+	checkCachedProgram(t, cached, want)
+
+	t.Run("included file changed", func(t *testing.T) {
+		writeCacheTestFile(t, dir, "SUB.TFY", "sub() {\n\tLoadPic(\"other.bmp\");\n}\n")
+		if _, ok := loadCache(opts.CacheDir, fileutil.NewRealFS(dir), "MAIN.TFY", opts); ok {
+			t.Error("expected a cache miss after an included file changed")
+		}
+		program, err := CompileProgram(dir, "MAIN.TFY", nil, opts)
+		if err != nil {
+			t.Fatalf("recompile failed: %v", err)
+		}
+		want, err := CompileProgram(dir, "MAIN.TFY", nil, CompileOptions{})
+		if err != nil {
+			t.Fatalf("compile failed: %v", err)
+		}
+		checkCachedProgram(t, program, want)
+		if assets := AssetPaths(CollectAssets(program.OpCodes), AssetPicture); len(assets) != 1 || assets[0] != "other.bmp" {
+			t.Errorf("expected the recompiled OpCode to use the new file, got %v", assets)
+		}
+		// Only the changed file was compiled again: the cache of the others is reused
+		if n := countSourceCacheFiles(t, opts.CacheDir); n != 4 {
+			t.Errorf("expected one new source file cache, got %d files", n)
+		}
+		if _, ok := loadCache(opts.CacheDir, fileutil.NewRealFS(dir), "MAIN.TFY", opts); !ok {
+			t.Error("expected the cache to be refreshed")
+		}
+	})
+
+	t.Run("companion ini added", func(t *testing.T) {
+		writeCacheTestFile(t, dir, "MAIN.INI", "[constants]\nMAXLINE=24\n")
+		if _, ok := loadCache(opts.CacheDir, fileutil.NewRealFS(dir), "MAIN.TFY", opts); ok {
+			t.Error("expected a cache miss after the companion .INI was added")
+		}
+		program, err := CompileProgram(dir, "MAIN.TFY", nil, opts)
+		if err != nil {
+			t.Fatalf("recompile failed: %v", err)
+		}
+		want, err := CompileProgram(dir, "MAIN.TFY", nil, CompileOptions{})
+		if err != nil {
+			t.Fatalf("compile failed: %v", err)
+		}
+		checkCachedProgram(t, program, want)
+		cached, ok := loadCache(opts.CacheDir, fileutil.NewRealFS(dir), "MAIN.TFY", opts)
+		if !ok {
+			t.Fatal("expected a cache hit")
+		}
+		checkCachedProgram(t, cached, want)
+	})
+
+	t.Run("compat mode", func(t *testing.T) {
+		if _, err := CompileProgram(dir, "MAIN.TFY", nil, opts); err != nil {
+			t.Fatalf("compile failed: %v", err)
+		}
+		filly97 := opts
+		filly97.Compat = compat.FILLY97
+		if _, ok := loadCache(opts.CacheDir, fileutil.NewRealFS(dir), "MAIN.TFY", filly97); ok {
+			t.Error("expected a cache miss for another compat mode")
+		}
+	})
+
+	t.Run("corrupt cache", func(t *testing.T) {
+		if err := os.WriteFile(cacheFile, []byte("not a cache"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		if _, ok := loadCache(opts.CacheDir, fileutil.NewRealFS(dir), "MAIN.TFY", opts); ok {
+			t.Error("expected a corrupt cache to be ignored")
+		}
+		if _, err := CompileProgram(dir, "MAIN.TFY", nil, opts); err != nil {
+			t.Errorf("a corrupt cache should not fail the compilation: %v", err)
+		}
+	})
+}
+
+// TestCompileWithCacheWholeProgram checks that a script whose files do not compile on
+// their own (here an #include inside a function) is compiled as a whole and not cached.
+func TestCompileWithCacheWholeProgram(t *testing.T) {
+	dir := t.TempDir()
+	writeCacheTestFile(t, dir, "MAIN.TFY", "main() {\n#include \"body.tfy\"\n}\n")
+	writeCacheTestFile(t, dir, "BODY.TFY", "LoadPic(\"back.bmp\");\nDebugBreak(\"body\");\n")
+	opts := CompileOptions{CacheDir: filepath.Join(dir, CacheDirName)}
+
+	want, err := CompileProgram(dir, "MAIN.TFY", nil, CompileOptions{})
+	if err != nil {
+		t.Fatalf("compile failed: %v", err)
+	}
+	program, err := CompileProgram(dir, "MAIN.TFY", nil, opts)
+	if err != nil {
+		t.Fatalf("compile with cache failed: %v", err)
+	}
+	checkCachedProgram(t, program, want)
+	if _, err := os.Stat(cacheFilePath(opts.CacheDir, "MAIN.TFY")); !os.IsNotExist(err) {
+		t.Error("a script compiled as a whole should not be cached")
+	}
+}
+
+func TestCompileWithCacheErrors(t *testing.T) {
+	dir := t.TempDir()
+	writeCacheTestFile(t, dir, "MAIN.TFY", "main() {\n\tx = = 5;\n}\n")
+	opts := CompileOptions{CacheDir: filepath.Join(dir, CacheDirName)}
+
+	if _, err := CompileProgram(dir, "MAIN.TFY", nil, opts); err == nil {
+		t.Fatal("expected a compilation error")
+	}
+	if _, err := os.Stat(cacheFilePath(opts.CacheDir, "MAIN.TFY")); !os.IsNotExist(err) {
+		t.Error("a failed compilation should not be cached")
+	}
+}
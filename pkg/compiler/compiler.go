@@ -12,9 +12,11 @@
 // - CompileDirectory: Loads and compiles all scripts from a directory
 // - FindMainScript: Finds the script containing the main function entry point
 // - CompileWithEntryPoint: Compiles scripts starting from the main entry point
+// - CompileProgram: Compiles from an entry file and returns the analysis for the static checks
 package compiler
 
 import (
+	"errors"
 	"fmt"
 	"io"
 	"io/fs"
@@ -24,6 +26,7 @@ import (
 	"golang.org/x/text/encoding/japanese"
 	"golang.org/x/text/transform"
 
+	"github.com/zurustar/son-et/pkg/compat"
 	"github.com/zurustar/son-et/pkg/compiler/compiler"
 	"github.com/zurustar/son-et/pkg/compiler/lexer"
 	"github.com/zurustar/son-et/pkg/compiler/parser"
@@ -36,6 +39,13 @@ import (
 type CompileOptions struct {
 	// Debug includes debug information in the output
 	Debug bool
+	// Compat is the compatibility mode (--compat).
+	// compat.FILLY97 rejects son-et's syntax extensions such as floating point literals.
+	Compat compat.Mode
+	// CacheDir is the codegen cache directory (usually CacheDirName in the title directory).
+	// When set, CompileWithPreprocessorOptions reuses the OpCode generated by an earlier run
+	// if none of the source files changed. It is ignored for embedded file systems.
+	CacheDir string
 }
 
 // Compile compiles source code to OpCode.
@@ -54,11 +64,19 @@ type CompileOptions struct {
 // Requirement 5.6: System collects all errors and returns them to caller.
 // Requirement 10.2: CompileString function accepts script content as string.
 func Compile(source string) ([]opcode.OpCode, []error) {
+	opcodes, _, errs := compileSource(source, CompileOptions{})
+	return opcodes, errs
+}
+
+// compileSource runs the lexer → parser → compiler pipeline with the given options.
+// On success it also returns the analysis of the script for the static checks.
+func compileSource(source string, opts CompileOptions) ([]opcode.OpCode, *Analysis, []error) {
 	// Phase 1: Lexical analysis
 	l := lexer.New(source)
 
 	// Phase 2: Syntax analysis
 	p := parser.New(l)
+	p.SetCompatMode(opts.Compat)
 	program, parseErrs := p.ParseProgram()
 
 	// Requirement 6.3: If any phase fails, stop pipeline and return accumulated errors
@@ -73,7 +91,7 @@ func Compile(source string) ([]opcode.OpCode, []error) {
 				compileErrors = append(compileErrors, err)
 			}
 		}
-		return nil, compileErrors
+		return nil, nil, compileErrors
 	}
 
 	// Phase 3: OpCode generation
@@ -92,11 +110,11 @@ func Compile(source string) ([]opcode.OpCode, []error) {
 				compileErrors = append(compileErrors, err)
 			}
 		}
-		return nil, compileErrors
+		return nil, nil, compileErrors
 	}
 
 	// Requirement 6.4: Return generated OpCode sequence on success
-	return opcodes, nil
+	return opcodes, newAnalysis(program, c), nil
 }
 
 // CompileFile compiles a file to OpCode.
@@ -149,8 +167,8 @@ func CompileWithOptions(source string, opts CompileOptions) ([]opcode.OpCode, []
 	// When Debug is true, additional debug information could be included
 	// in the OpCode output (e.g., source line numbers, variable names).
 
-	// For now, delegate to the standard Compile function
-	opcodes, errs := Compile(source)
+	// Compat is applied by the parser; the rest of the pipeline is the same as Compile.
+	opcodes, _, errs := compileSource(source, opts)
 
 	if opts.Debug && len(errs) == 0 {
 		// Future: Add debug information to opcodes
@@ -421,7 +439,7 @@ func CompileWithEntryPoint(scripts []script.Script) ([]opcode.OpCode, error) {
 	// First, compile the main script
 	mainOpCodes, errs := Compile(mainInfo.Script.Content)
 	if len(errs) > 0 {
-		return nil, fmt.Errorf("failed to compile main script %s: %v", mainInfo.FileName, errs[0])
+		return nil, fmt.Errorf("failed to compile main script %s: %w", mainInfo.FileName, errors.Join(errs...))
 	}
 	allOpCodes = append(allOpCodes, mainOpCodes...)
 
@@ -434,7 +452,7 @@ func CompileWithEntryPoint(scripts []script.Script) ([]opcode.OpCode, error) {
 
 		opcodes, errs := Compile(s.Content)
 		if len(errs) > 0 {
-			return nil, fmt.Errorf("failed to compile script %s: %v", s.FileName, errs[0])
+			return nil, fmt.Errorf("failed to compile script %s: %w", s.FileName, errors.Join(errs...))
 		}
 		allOpCodes = append(allOpCodes, opcodes...)
 	}
@@ -486,22 +504,7 @@ type PreprocessResult = preprocessor.PreprocessResult
 // Requirement 16.3: Preprocessor processes included files recursively.
 // Requirement 16.6: Preprocessor outputs single combined source code.
 func CompileWithPreprocessor(dirPath string, entryFile string) ([]opcode.OpCode, *PreprocessResult, error) {
-	// Create preprocessor
-	p := preprocessor.New(dirPath)
-
-	// Preprocess the entry file
-	result, err := p.PreprocessFile(entryFile)
-	if err != nil {
-		return nil, nil, fmt.Errorf("preprocessing failed: %w", err)
-	}
-
-	// Compile the preprocessed source
-	opcodes, errs := Compile(result.Source)
-	if len(errs) > 0 {
-		return nil, result, fmt.Errorf("compilation failed: %v", errs[0])
-	}
-
-	return opcodes, result, nil
+	return CompileWithPreprocessorOptions(dirPath, entryFile, nil, CompileOptions{})
 }
 
 // CompileWithPreprocessorFS compiles a script using the preprocessor with a custom file system.
@@ -517,8 +520,59 @@ func CompileWithPreprocessor(dirPath string, entryFile string) ([]opcode.OpCode,
 //   - *PreprocessResult: The preprocessing result (included files list)
 //   - error: Error if preprocessing or compilation failed
 func CompileWithPreprocessorFS(dirPath string, entryFile string, fsys fs.FS) ([]opcode.OpCode, *PreprocessResult, error) {
-	// Create preprocessor with custom file system
-	p := preprocessor.NewWithFS(dirPath, fsys)
+	return CompileWithPreprocessorOptions(dirPath, entryFile, fsys, CompileOptions{})
+}
+
+// CompileWithPreprocessorOptions compiles a script using the preprocessor with compilation options.
+// If fsys is nil, files are read from the real file system (same as CompileWithPreprocessor);
+// otherwise fsys is used (same as CompileWithPreprocessorFS).
+//
+// Parameters:
+//   - dirPath: Path to the directory containing .TFY script files
+//   - entryFile: The entry point file name (relative to dirPath)
+//   - fsys: The file system to use, or nil for the real file system
+//   - opts: Compilation options (opts.CacheDir enables the codegen cache)
+//
+// Returns:
+//   - []opcode.OpCode: The compiled OpCode sequence
+//   - *PreprocessResult: The preprocessing result (included files list)
+//   - error: Error if preprocessing or compilation failed
+func CompileWithPreprocessorOptions(dirPath string, entryFile string, fsys fs.FS, opts CompileOptions) ([]opcode.OpCode, *PreprocessResult, error) {
+	program, result, err := compileProgram(dirPath, entryFile, fsys, opts)
+	if err != nil {
+		return nil, result, err
+	}
+	return program.OpCodes, program.Result, nil
+}
+
+// Program is a script compiled from its entry file by CompileProgram.
+type Program struct {
+	OpCodes  []opcode.OpCode
+	Result   *PreprocessResult // Preprocessing result (included files, line origins)
+	Analysis *Analysis         // What the static checks need (lines in Result.Source)
+}
+
+// CompileProgram compiles a script like CompileWithPreprocessorOptions and also returns
+// the analysis the static checks before playback need, collected while compiling.
+func CompileProgram(dirPath string, entryFile string, fsys fs.FS, opts CompileOptions) (*Program, error) {
+	program, _, err := compileProgram(dirPath, entryFile, fsys, opts)
+	return program, err
+}
+
+// compileProgram implements CompileProgram. The preprocessing result is also returned
+// when the compilation fails.
+func compileProgram(dirPath string, entryFile string, fsys fs.FS, opts CompileOptions) (*Program, *PreprocessResult, error) {
+	if opts.CacheDir != "" && fsys == nil {
+		return compileWithCache(dirPath, entryFile, opts)
+	}
+
+	// Create preprocessor
+	var p *preprocessor.Preprocessor
+	if fsys != nil {
+		p = preprocessor.NewWithFS(dirPath, fsys)
+	} else {
+		p = preprocessor.New(dirPath)
+	}
 
 	// Preprocess the entry file
 	result, err := p.PreprocessFile(entryFile)
@@ -527,12 +581,15 @@ func CompileWithPreprocessorFS(dirPath string, entryFile string, fsys fs.FS) ([]
 	}
 
 	// Compile the preprocessed source
-	opcodes, errs := Compile(result.Source)
+	opcodes, analysis, errs := compileSource(result.Source, opts)
 	if len(errs) > 0 {
-		return nil, result, fmt.Errorf("compilation failed: %v", errs[0])
+		return nil, result, fmt.Errorf("compilation failed: %w", errors.Join(errs...))
 	}
 
-	return opcodes, result, nil
+	// The preprocessed source is the whole program, so named constants can be propagated safely
+	opcodes = compiler.Optimize(opcodes, result.ConstantNames())
+
+	return &Program{OpCodes: opcodes, Result: result, Analysis: analysis}, result, nil
 }
 
 // convertShiftJISToUTF8 converts Shift-JIS encoded data to UTF-8.
@@ -589,6 +646,9 @@ const (
 	OpWait                 = opcode.Wait
 	OpSetStep              = opcode.SetStep
 	OpDefineFunction       = opcode.DefineFunction
+	OpDebugBreak           = opcode.DebugBreak
+	OpWaitAny              = opcode.WaitAny
+	OpTry                  = opcode.Try
 )
 
 // Re-export error types from sub-packages for convenience
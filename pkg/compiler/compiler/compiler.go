@@ -3,7 +3,9 @@
 package compiler
 
 import (
+	"errors"
 	"fmt"
+	"strings"
 
 	"github.com/zurustar/son-et/pkg/compiler/parser"
 	"github.com/zurustar/son-et/pkg/opcode"
@@ -34,9 +36,33 @@ func NewCompilerError(message string, line, column int) *CompilerError {
 	}
 }
 
+// CallSite is a function call found while compiling.
+type CallSite struct {
+	Name string // Function name as written in the script
+	Line int    // Source line of the call
+}
+
+// ArgDiagnostic is a built-in function call whose arguments do not match the
+// function's signature (see opcode.Signature), found while compiling.
+type ArgDiagnostic struct {
+	Err  *opcode.ArgError
+	Line int // Source line of the call
+}
+
+// Error implements the error interface.
+func (d ArgDiagnostic) Error() string {
+	return fmt.Sprintf("%v at line %d", d.Err, d.Line)
+}
+
 // Compiler generates OpCode from an AST.
 type Compiler struct {
 	errors []*CompilerError
+	// calls records every function call in source order (used to report unsupported builtins).
+	calls []CallSite
+	// argDiagnostics records built-in calls with invalid arguments, in source order.
+	argDiagnostics []ArgDiagnostic
+	// functions records the names of the functions defined by the script.
+	functions []string
 }
 
 // New creates a new Compiler.
@@ -78,6 +104,28 @@ func (c *Compiler) Errors() []*CompilerError {
 	return c.errors
 }
 
+// CallSites returns every function call compiled so far, in source order.
+func (c *Compiler) CallSites() []CallSite {
+	return c.calls
+}
+
+// DefinedFunctions returns the names of the functions defined by the script.
+func (c *Compiler) DefinedFunctions() []string {
+	return c.functions
+}
+
+// ArgDiagnostics returns the built-in calls compiled so far whose argument count or
+// literal arguments do not match the function's signature, in source order.
+// Calls to functions the script defines itself are included; callers filter them out.
+func (c *Compiler) ArgDiagnostics() []ArgDiagnostic {
+	return c.argDiagnostics
+}
+
+// recordCall records a function call for CallSites.
+func (c *Compiler) recordCall(ce *parser.CallExpression) {
+	c.calls = append(c.calls, CallSite{Name: ce.Function, Line: ce.Token.Line})
+}
+
 // addError adds an error message to the compiler's error list with location information.
 // Requirement 5.5: Compiler reports unknown AST node types in error messages.
 func (c *Compiler) addError(line, column int, format string, args ...any) {
@@ -111,6 +159,8 @@ func getStatementLocation(stmt parser.Statement) (int, int) {
 		return s.Token.Line, s.Token.Column
 	case *parser.SwitchStatement:
 		return s.Token.Line, s.Token.Column
+	case *parser.TryStatement:
+		return s.Token.Line, s.Token.Column
 	case *parser.MesStatement:
 		return s.Token.Line, s.Token.Column
 	case *parser.StepStatement:
@@ -175,6 +225,8 @@ func (c *Compiler) compileStatement(stmt parser.Statement) []opcode.OpCode {
 		return c.compileWhileStatement(s)
 	case *parser.SwitchStatement:
 		return c.compileSwitchStatement(s)
+	case *parser.TryStatement:
+		return c.compileTryStatement(s)
 	case *parser.MesStatement:
 		return c.compileMesStatement(s)
 	case *parser.StepStatement:
@@ -281,6 +333,8 @@ func (c *Compiler) compileVarDeclaration(vd *parser.VarDeclaration) []opcode.OpC
 // compileFunctionStatement compiles a function definition.
 // It generates an OpDefineFunction with the function name, parameters, and compiled body.
 func (c *Compiler) compileFunctionStatement(fs *parser.FunctionStatement) []opcode.OpCode {
+	c.functions = append(c.functions, fs.Name)
+
 	// Compile the function body
 	var bodyOpcodes []opcode.OpCode
 	if fs.Body != nil {
@@ -329,8 +383,14 @@ func (c *Compiler) compileBlockStatement(bs *parser.BlockStatement) []opcode.OpC
 // For simple assignment: generates OpAssign with opcode.Variable(name) and compiled value.
 // For array assignment: generates OpArrayAssign with array name, index, and value.
 func (c *Compiler) compileAssignStatement(as *parser.AssignStatement) []opcode.OpCode {
-	value := c.compileExpression(as.Value)
+	if ce, ok := as.Value.(*parser.CallExpression); ok && isWaitAny(ce) {
+		return c.compileWaitAnyAssign(as, ce)
+	}
+	return c.compileAssignValue(as, c.compileExpression(as.Value))
+}
 
+// compileAssignValue compiles the assignment of an already compiled value to the target of as.
+func (c *Compiler) compileAssignValue(as *parser.AssignStatement, value any) []opcode.OpCode {
 	switch target := as.Name.(type) {
 	case *parser.Identifier:
 		// Simple variable assignment: x = value
@@ -384,13 +444,16 @@ func (c *Compiler) compileExpressionStatement(es *parser.ExpressionStatement) []
 
 	// Check if the expression is a function call
 	if ce, ok := es.Expression.(*parser.CallExpression); ok {
-		// Generate OpCall for function calls
-		args := []any{ce.Function}
-		for _, arg := range ce.Arguments {
-			args = append(args, c.compileExpression(arg))
+		c.recordCall(ce)
+		if isDebugBreak(ce) {
+			return []opcode.OpCode{c.compileDebugBreak(ce)}
+		}
+		if isWaitAny(ce) {
+			return []opcode.OpCode{c.compileWaitAny(ce)}
 		}
+		// Generate OpCall for function calls
 		return []opcode.OpCode{
-			{Cmd: opcode.Call, Args: args},
+			{Cmd: opcode.Call, Args: c.compileCallArgs(ce)},
 		}
 	}
 
@@ -563,6 +626,52 @@ func (c *Compiler) compileWhileStatement(ws *parser.WhileStatement) []opcode.OpC
 	}
 }
 
+// compileTryStatement compiles a try statement.
+// Generates OpTry with the try block, the catch block and the error variable
+// ("" when catch has no variable). A missing catch compiles to an empty block.
+//
+// Example: try { p = LoadPic("a.bmp") } catch (e) { p = -1 }
+// opcode.OpCode{
+//
+//	Cmd: opcode.Try,
+//	Args: []any{
+//	    // try block
+//	    []opcode.OpCode{{Cmd: opcode.Assign, Args: []any{opcode.Variable("p"), opcode.OpCode{Cmd: opcode.Call, Args: []any{"LoadPic", "a.bmp"}}}}},
+//	    // catch block
+//	    []opcode.OpCode{{Cmd: opcode.Assign, Args: []any{opcode.Variable("p"), -1}}},
+//	    // error variable
+//	    opcode.Variable("e"),
+//	},
+//
+// }
+func (c *Compiler) compileTryStatement(ts *parser.TryStatement) []opcode.OpCode {
+	tryBlock := []opcode.OpCode{}
+	if ts.Body != nil {
+		tryBlock = c.compileBlockStatement(ts.Body)
+	}
+
+	catchBlock := []opcode.OpCode{}
+	if ts.Catch != nil {
+		catchBlock = c.compileBlockStatement(ts.Catch)
+	}
+
+	errorVar := opcode.Variable("")
+	if ts.ErrorVar != nil {
+		errorVar = opcode.Variable(ts.ErrorVar.Value)
+	}
+
+	return []opcode.OpCode{
+		{
+			Cmd: opcode.Try,
+			Args: []any{
+				tryBlock,
+				catchBlock,
+				errorVar,
+			},
+		},
+	}
+}
+
 // compileSwitchStatement compiles a switch statement.
 // Generates OpSwitch with value, cases array, and optional default block.
 //
@@ -784,16 +893,102 @@ func (c *Compiler) compileUnaryExpression(ue *parser.UnaryExpression) any {
 // compileCallExpression compiles a function call expression.
 // Returns an OpCode with OpCall command.
 func (c *Compiler) compileCallExpression(ce *parser.CallExpression) any {
+	c.recordCall(ce)
+	if isDebugBreak(ce) {
+		return c.compileDebugBreak(ce)
+	}
+	return opcode.OpCode{
+		Cmd:  opcode.Call,
+		Args: c.compileCallArgs(ce),
+	}
+}
+
+// compileCallArgs compiles the OpCall arguments of a call: [functionName, arg1, arg2, ...].
+// Calls to built-in functions are checked against the signature table; mismatches are
+// recorded as ArgDiagnostics (the call is still compiled, as the VM checks again at run time).
+func (c *Compiler) compileCallArgs(ce *parser.CallExpression) []any {
 	args := []any{ce.Function}
 	for _, arg := range ce.Arguments {
 		args = append(args, c.compileExpression(arg))
 	}
+	if sig, ok := opcode.LookupSignature(ce.Function); ok {
+		err := sig.CheckCount(len(ce.Arguments))
+		if err == nil {
+			_, err = sig.ConvertArgs(args[1:])
+		}
+		var argErr *opcode.ArgError
+		if errors.As(err, &argErr) {
+			argErr.Func = ce.Function
+			c.argDiagnostics = append(c.argDiagnostics, ArgDiagnostic{Err: argErr, Line: ce.Token.Line})
+		}
+	}
+	return args
+}
+
+// debugBreakFunction は DebugBreak 命令としてコンパイルする関数名（大文字小文字を区別しない）
+const debugBreakFunction = "DebugBreak"
+
+// isDebugBreak reports whether the call is DebugBreak(...).
+func isDebugBreak(ce *parser.CallExpression) bool {
+	return strings.EqualFold(ce.Function, debugBreakFunction)
+}
+
+// compileDebugBreak compiles DebugBreak("label") into OpDebugBreak.
+// The source line is recorded so that the debugger can report where the sequence stopped.
+// Arguments after the label are ignored.
+//
+// Example: DebugBreak("loop")
+// Generates: opcode.OpCode{Cmd: opcode.DebugBreak, Args: []any{"loop", 12}}
+func (c *Compiler) compileDebugBreak(ce *parser.CallExpression) opcode.OpCode {
+	var label any = ""
+	if len(ce.Arguments) > 0 {
+		label = c.compileExpression(ce.Arguments[0])
+	}
 	return opcode.OpCode{
-		Cmd:  opcode.Call,
-		Args: args,
+		Cmd:  opcode.DebugBreak,
+		Args: []any{label, ce.Token.Line},
+	}
+}
+
+// waitAnyFunction は WaitAny 命令としてコンパイルする関数名（大文字小文字を区別しない）
+const waitAnyFunction = "WaitAny"
+
+// waitResultFunction は WaitAny で起きた条件を返す組み込み関数
+const waitResultFunction = "GetWaitResult"
+
+// isWaitAny reports whether the call is WaitAny(...).
+func isWaitAny(ce *parser.CallExpression) bool {
+	return strings.EqualFold(ce.Function, waitAnyFunction)
+}
+
+// compileWaitAny compiles WaitAny(ticks, "KEY", ...) into OpWaitAny.
+// The arguments are checked against the WaitAny signature like a built-in call.
+//
+// Example: WaitAny(40, "KEY", "MIDI_END")
+// Generates: opcode.OpCode{Cmd: opcode.WaitAny, Args: []any{40, "KEY", "MIDI_END"}}
+func (c *Compiler) compileWaitAny(ce *parser.CallExpression) opcode.OpCode {
+	args := c.compileCallArgs(ce)
+	return opcode.OpCode{
+		Cmd:  opcode.WaitAny,
+		Args: args[1:],
 	}
 }
 
+// compileWaitAnyAssign compiles r = WaitAny(...) into OpWaitAny followed by r = GetWaitResult().
+// The sequence pauses at OpWaitAny, so the result can only be assigned once it resumes.
+//
+// Example: r = WaitAny(40, "KEY")
+// Generates:
+//
+//	opcode.OpCode{Cmd: opcode.WaitAny, Args: []any{40, "KEY"}}
+//	opcode.OpCode{Cmd: opcode.Assign, Args: []any{opcode.Variable("r"), opcode.OpCode{Cmd: opcode.Call, Args: []any{"GetWaitResult"}}}}
+func (c *Compiler) compileWaitAnyAssign(as *parser.AssignStatement, ce *parser.CallExpression) []opcode.OpCode {
+	c.recordCall(ce)
+	wait := c.compileWaitAny(ce)
+	result := opcode.OpCode{Cmd: opcode.Call, Args: []any{waitResultFunction}}
+	return append([]opcode.OpCode{wait}, c.compileAssignValue(as, result)...)
+}
+
 // compileIndexExpression compiles an array index expression.
 // Returns an OpCode with OpArrayAccess command.
 func (c *Compiler) compileIndexExpression(ie *parser.IndexExpression) any {
@@ -3,6 +3,7 @@ package compiler
 
 import (
 	"reflect"
+	"strings"
 	"testing"
 
 	"github.com/zurustar/son-et/pkg/compiler/lexer"
@@ -27,6 +28,25 @@ func TestCompileSimpleAssignment(t *testing.T) {
 				},
 			},
 		},
+		{
+			name:  "hexadecimal, binary and character literals",
+			input: "x = 0x1F + 0b1010 + 'A'",
+			expected: []opcode.OpCode{
+				{
+					Cmd: opcode.Assign,
+					Args: []any{
+						opcode.Variable("x"),
+						opcode.OpCode{
+							Cmd: opcode.BinaryOp,
+							Args: []any{"+", opcode.OpCode{
+								Cmd:  opcode.BinaryOp,
+								Args: []any{"+", int64(31), int64(10)},
+							}, int64(65)},
+						},
+					},
+				},
+			},
+		},
 		{
 			name:  "simple string assignment",
 			input: `s = "hello"`,
@@ -1028,6 +1048,91 @@ func TestCompileWhileStatement(t *testing.T) {
 	}
 }
 
+// TestCompileTryStatement tests try statement OpCode generation.
+func TestCompileTryStatement(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected []opcode.OpCode
+	}{
+		{
+			name:  "try with catch variable",
+			input: `try { p = LoadPic("a.bmp"); } catch (e) { p = -1; }`,
+			expected: []opcode.OpCode{
+				{
+					Cmd: opcode.Try,
+					Args: []any{
+						[]opcode.OpCode{
+							{Cmd: opcode.Assign, Args: []any{
+								opcode.Variable("p"),
+								opcode.OpCode{Cmd: opcode.Call, Args: []any{"LoadPic", "a.bmp"}},
+							}},
+						},
+						[]opcode.OpCode{
+							{Cmd: opcode.Assign, Args: []any{
+								opcode.Variable("p"),
+								opcode.OpCode{Cmd: opcode.UnaryOp, Args: []any{"-", int64(1)}},
+							}},
+						},
+						opcode.Variable("e"),
+					},
+				},
+			},
+		},
+		{
+			name:  "try without catch",
+			input: `try { PlayMIDI("a.mid"); }`,
+			expected: []opcode.OpCode{
+				{
+					Cmd: opcode.Try,
+					Args: []any{
+						[]opcode.OpCode{
+							{Cmd: opcode.Call, Args: []any{"PlayMIDI", "a.mid"}},
+						},
+						[]opcode.OpCode{},
+						opcode.Variable(""),
+					},
+				},
+			},
+		},
+		{
+			name:  "catch without variable",
+			input: `try { f(); } catch { g(); }`,
+			expected: []opcode.OpCode{
+				{
+					Cmd: opcode.Try,
+					Args: []any{
+						[]opcode.OpCode{{Cmd: opcode.Call, Args: []any{"f"}}},
+						[]opcode.OpCode{{Cmd: opcode.Call, Args: []any{"g"}}},
+						opcode.Variable(""),
+					},
+				},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l := lexer.New(tt.input)
+			p := parser.New(l)
+			program, errs := p.ParseProgram()
+			if len(errs) > 0 {
+				t.Fatalf("parser errors: %v", errs)
+			}
+
+			c := New()
+			opcodes, compileErrs := c.Compile(program)
+			if len(compileErrs) > 0 {
+				t.Fatalf("compiler errors: %v", compileErrs)
+			}
+
+			if !reflect.DeepEqual(opcodes, tt.expected) {
+				t.Errorf("opcodes mismatch:\ngot:      %#v\nexpected: %#v", opcodes, tt.expected)
+			}
+		})
+	}
+}
+
 // TestCompileSwitchStatement tests switch statement OpCode generation.
 func TestCompileSwitchStatement(t *testing.T) {
 	tests := []struct {
@@ -1600,3 +1705,154 @@ func TestCompileStepStatementInMes(t *testing.T) {
 		t.Errorf("opcodes mismatch:\ngot:      %#v\nexpected: %#v", opcodes, expected)
 	}
 }
+
+// TestCompileDebugBreak tests that DebugBreak compiles to OpDebugBreak with the source line.
+func TestCompileDebugBreak(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected []opcode.OpCode
+	}{
+		{
+			name:  "statement with label",
+			input: "x = 1\nDebugBreak(\"start\")",
+			expected: []opcode.OpCode{
+				{Cmd: opcode.Assign, Args: []any{opcode.Variable("x"), int64(1)}},
+				{Cmd: opcode.DebugBreak, Args: []any{"start", 2}},
+			},
+		},
+		{
+			name:  "without label, case-insensitive",
+			input: `debugbreak()`,
+			expected: []opcode.OpCode{
+				{Cmd: opcode.DebugBreak, Args: []any{"", 1}},
+			},
+		},
+		{
+			name:  "label expression",
+			input: `DebugBreak(name)`,
+			expected: []opcode.OpCode{
+				{Cmd: opcode.DebugBreak, Args: []any{opcode.Variable("name"), 1}},
+			},
+		},
+		{
+			name:  "used as an expression",
+			input: `r = DebugBreak("x")`,
+			expected: []opcode.OpCode{
+				{Cmd: opcode.Assign, Args: []any{opcode.Variable("r"), opcode.OpCode{Cmd: opcode.DebugBreak, Args: []any{"x", 1}}}},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l := lexer.New(tt.input)
+			p := parser.New(l)
+			program, errs := p.ParseProgram()
+			if len(errs) > 0 {
+				t.Fatalf("parser errors: %v", errs)
+			}
+
+			c := New()
+			opcodes, compileErrs := c.Compile(program)
+			if len(compileErrs) > 0 {
+				t.Fatalf("compiler errors: %v", compileErrs)
+			}
+
+			if !reflect.DeepEqual(opcodes, tt.expected) {
+				t.Errorf("opcodes mismatch:\ngot:      %#v\nexpected: %#v", opcodes, tt.expected)
+			}
+		})
+	}
+}
+
+// TestCompileWaitAny tests that WaitAny compiles to OpWaitAny, followed by GetWaitResult() when assigned.
+func TestCompileWaitAny(t *testing.T) {
+	waitAny := opcode.OpCode{Cmd: opcode.WaitAny, Args: []any{int64(40), "KEY", "MIDI_END"}}
+	result := opcode.OpCode{Cmd: opcode.Call, Args: []any{"GetWaitResult"}}
+	tests := []struct {
+		name     string
+		input    string
+		expected []opcode.OpCode
+	}{
+		{
+			name:     "statement",
+			input:    `WaitAny(40, "KEY", "MIDI_END")`,
+			expected: []opcode.OpCode{waitAny},
+		},
+		{
+			name:  "assigned to a variable, case-insensitive",
+			input: `r = waitany(40, "KEY", "MIDI_END")`,
+			expected: []opcode.OpCode{
+				{Cmd: opcode.WaitAny, Args: []any{int64(40), "KEY", "MIDI_END"}},
+				{Cmd: opcode.Assign, Args: []any{opcode.Variable("r"), result}},
+			},
+		},
+		{
+			name:  "assigned to an array element",
+			input: `a[i] = WaitAny(40, "KEY", "MIDI_END")`,
+			expected: []opcode.OpCode{
+				waitAny,
+				{Cmd: opcode.ArrayAssign, Args: []any{opcode.Variable("a"), opcode.Variable("i"), result}},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			program, errs := parser.New(lexer.New(tt.input)).ParseProgram()
+			if len(errs) > 0 {
+				t.Fatalf("parser errors: %v", errs)
+			}
+
+			c := New()
+			opcodes, compileErrs := c.Compile(program)
+			if len(compileErrs) > 0 {
+				t.Fatalf("compiler errors: %v", compileErrs)
+			}
+			if !reflect.DeepEqual(opcodes, tt.expected) {
+				t.Errorf("opcodes mismatch:\ngot:      %#v\nexpected: %#v", opcodes, tt.expected)
+			}
+			if calls := c.CallSites(); len(calls) != 1 || !strings.EqualFold(calls[0].Name, "WaitAny") {
+				t.Errorf("calls = %v, want only WaitAny", calls)
+			}
+		})
+	}
+}
+
+// TestCompileArgDiagnostics tests that built-in calls are checked against their signatures.
+func TestCompileArgDiagnostics(t *testing.T) {
+	input := `main() {
+	p = LoadPic("a.bmp");
+	q = loadpic(5);
+	MovePic(p);
+	MovePic(p, "0", 0, 0, 0, 0, 0, 0);
+	MovePic(p, "abc", 0, 0, 0, 0, 0, 0);
+	MovePic(p, q, x + 1, -5, 0, 0, 0, 0);
+	Unknown("x", 1);
+}`
+	l := lexer.New(input)
+	p := parser.New(l)
+	program, errs := p.ParseProgram()
+	if len(errs) > 0 {
+		t.Fatalf("parser errors: %v", errs)
+	}
+	c := New()
+	if _, compileErrs := c.Compile(program); len(compileErrs) > 0 {
+		t.Fatalf("compiler errors: %v", compileErrs)
+	}
+
+	want := []string{
+		`loadpic(): arg 1 expects string, got int 5 at line 3`,
+		`MovePic(): expects 2 to 10 arguments, got 1 at line 4`,
+		`MovePic(): arg 2 expects int, got string "abc" at line 6`,
+	}
+	diags := c.ArgDiagnostics()
+	got := make([]string, len(diags))
+	for i, d := range diags {
+		got[i] = d.Error()
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ArgDiagnostics = %q, want %q", got, want)
+	}
+}
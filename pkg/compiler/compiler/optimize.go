@@ -0,0 +1,583 @@
+package compiler
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/zurustar/son-et/pkg/opcode"
+)
+
+// Optimize simplifies the generated OpCode before it is executed, so the VM does less
+// work per tick for scripts with large unrolled loops and constant expressions.
+//
+//   - Constant folding: integer arithmetic, comparison, logical and unary operations on
+//     literals are evaluated with the VM's rules; string concatenations of literals such as
+//     "BG" + 3 + ".BMP" become a single asset path.
+//   - Constant propagation: a named constant (one of the given names, which the
+//     preprocessor injects for #info CONST/COLOR and the companion INI file) that is
+//     assigned a literal once, before any code runs, is replaced by its value where it
+//     is read. Other globals stay variables, so the watch panel and the console can
+//     change them while the script runs.
+//   - Dead-code elimination: branches of if/while/for whose condition is a constant
+//     false are removed, and statements after break/continue/return in a block are dropped.
+//
+// The result behaves exactly like the input: division by zero, waits inside blocks and
+// step() detection (see blockHasSetStep) are left for the VM.
+func Optimize(ops []opcode.OpCode, constants []string) []opcode.OpCode {
+	o := &optimizer{constants: propagatableConstants(ops, constants)}
+	return o.block(ops, topLevelBlock)
+}
+
+// blockKind tells how the VM runs a block, which decides which statements are unreachable.
+type blockKind int
+
+const (
+	// topLevelBlock is run statement by statement by the program or an event handler,
+	// which ignore break/continue/return markers.
+	topLevelBlock blockKind = iota
+	// functionBody stops at return only.
+	functionBody
+	// nestedBlock (if/loop/switch bodies) stops at break, continue and return.
+	nestedBlock
+)
+
+// handlerParams are the variables the VM sets when it dispatches an event.
+var handlerParams = []opcode.Variable{"MesP1", "MesP2", "MesP3", "MesP4"}
+
+type optimizer struct {
+	constants map[opcode.Variable]any
+}
+
+// block optimizes the statements of a block.
+func (o *optimizer) block(ops []opcode.OpCode, kind blockKind) []opcode.OpCode {
+	var out []opcode.OpCode
+	for i, op := range ops {
+		for _, stmt := range o.statement(op) {
+			out = append(out, stmt)
+			if terminates(stmt, kind) && !blockHasSetStep(ops[i+1:]) {
+				return out
+			}
+		}
+	}
+	return out
+}
+
+// statement optimizes one statement; it may become zero or several statements.
+func (o *optimizer) statement(op opcode.OpCode) []opcode.OpCode {
+	switch op.Cmd {
+	case opcode.If:
+		return o.ifStatement(op)
+	case opcode.While:
+		if len(op.Args) < 2 {
+			return []opcode.OpCode{op}
+		}
+		cond := o.expr(op.Args[0])
+		body := blockArg(op.Args[1])
+		if isConstantFalse(cond) && !blockHasSetStep(body) {
+			return nil
+		}
+		return []opcode.OpCode{{Cmd: op.Cmd, Args: []any{cond, o.block(body, nestedBlock)}}}
+	case opcode.For:
+		if len(op.Args) < 4 {
+			return []opcode.OpCode{op}
+		}
+		init := o.block(blockArg(op.Args[0]), nestedBlock)
+		cond := op.Args[1]
+		if cond != nil {
+			cond = o.expr(cond)
+		}
+		post, body := blockArg(op.Args[2]), blockArg(op.Args[3])
+		if isConstantFalse(cond) && inlinable(init) && !blockHasSetStep(post) && !blockHasSetStep(body) {
+			return init
+		}
+		return []opcode.OpCode{{Cmd: op.Cmd, Args: []any{init, cond, o.block(post, nestedBlock), o.block(body, nestedBlock)}}}
+	case opcode.Switch:
+		return []opcode.OpCode{o.switchStatement(op)}
+	case opcode.Try:
+		// The error variable is an assignment target, not an expression
+		if len(op.Args) < 3 {
+			return []opcode.OpCode{op}
+		}
+		return []opcode.OpCode{{Cmd: op.Cmd, Args: []any{o.block(blockArg(op.Args[0]), nestedBlock), o.block(blockArg(op.Args[1]), nestedBlock), op.Args[2]}}}
+	case opcode.DefineFunction:
+		if len(op.Args) < 3 {
+			return []opcode.OpCode{op}
+		}
+		return []opcode.OpCode{{Cmd: op.Cmd, Args: []any{op.Args[0], op.Args[1], o.block(blockArg(op.Args[2]), functionBody)}}}
+	case opcode.RegisterEventHandler:
+		if len(op.Args) < 2 {
+			return []opcode.OpCode{op}
+		}
+		return []opcode.OpCode{{Cmd: op.Cmd, Args: []any{op.Args[0], o.block(blockArg(op.Args[1]), topLevelBlock)}}}
+	case opcode.BinaryOp, opcode.UnaryOp:
+		// An expression statement that folds to a literal has no effect
+		if v := o.expr(op); isLiteral(v) {
+			return nil
+		} else if folded, ok := v.(opcode.OpCode); ok {
+			return []opcode.OpCode{folded}
+		}
+		return []opcode.OpCode{op}
+	default:
+		return []opcode.OpCode{o.operands(op)}
+	}
+}
+
+// ifStatement removes the branch that a constant condition never takes.
+// The taken branch replaces the if statement when it does not wait: a Wait inside a
+// block resumes the sequence after the whole if statement, so moving it out would change
+// where the sequence continues.
+func (o *optimizer) ifStatement(op opcode.OpCode) []opcode.OpCode {
+	if len(op.Args) < 2 {
+		return []opcode.OpCode{op}
+	}
+	cond := o.expr(op.Args[0])
+	thenBlock := o.block(blockArg(op.Args[1]), nestedBlock)
+	var elseBlock []opcode.OpCode
+	if len(op.Args) >= 3 {
+		elseBlock = o.block(blockArg(op.Args[2]), nestedBlock)
+	}
+
+	if isLiteral(cond) {
+		taken, skipped := thenBlock, elseBlock
+		if !truthy(cond) {
+			taken, skipped = elseBlock, thenBlock
+		}
+		if !blockHasSetStep(skipped) {
+			if inlinable(taken) {
+				return taken
+			}
+			if truthy(cond) {
+				elseBlock = []opcode.OpCode{}
+			} else {
+				thenBlock = []opcode.OpCode{}
+			}
+		}
+	}
+	if elseBlock == nil {
+		elseBlock = []opcode.OpCode{}
+	}
+	return []opcode.OpCode{{Cmd: op.Cmd, Args: []any{cond, thenBlock, elseBlock}}}
+}
+
+// switchStatement optimizes the value and the case bodies of a switch statement.
+func (o *optimizer) switchStatement(op opcode.OpCode) opcode.OpCode {
+	if len(op.Args) < 3 {
+		return op
+	}
+	clauses, _ := op.Args[1].([]any)
+	cases := make([]any, 0, len(clauses))
+	for _, c := range clauses {
+		clause, ok := c.(map[string]any)
+		if !ok {
+			cases = append(cases, c)
+			continue
+		}
+		cases = append(cases, map[string]any{
+			"value": o.expr(clause["value"]),
+			"body":  o.block(blockArg(clause["body"]), nestedBlock),
+		})
+	}
+	return opcode.OpCode{Cmd: op.Cmd, Args: []any{o.expr(op.Args[0]), cases, o.block(blockArg(op.Args[2]), nestedBlock)}}
+}
+
+// operands optimizes the operands of a statement or expression, leaving the function
+// name of a call, assignment targets and array variables as they are.
+func (o *optimizer) operands(op opcode.OpCode) opcode.OpCode {
+	args := make([]any, len(op.Args))
+	for i, arg := range op.Args {
+		switch {
+		case i == 0 && (op.Cmd == opcode.Call || op.Cmd == opcode.Assign || op.Cmd == opcode.ArrayAssign ||
+			op.Cmd == opcode.ArrayAccess || op.Cmd == opcode.BinaryOp || op.Cmd == opcode.UnaryOp):
+			args[i] = arg
+		default:
+			args[i] = o.expr(arg)
+		}
+	}
+	return opcode.OpCode{Cmd: op.Cmd, Args: args}
+}
+
+// expr optimizes an expression and returns a literal when it is constant.
+func (o *optimizer) expr(v any) any {
+	switch e := v.(type) {
+	case opcode.Variable:
+		if c, ok := o.constants[e]; ok {
+			return c
+		}
+		return e
+	case opcode.OpCode:
+		op := o.operands(e)
+		switch op.Cmd {
+		case opcode.BinaryOp:
+			if len(op.Args) == 3 {
+				if operator, ok := op.Args[0].(string); ok {
+					if folded, ok := foldBinary(operator, op.Args[1], op.Args[2]); ok {
+						return folded
+					}
+				}
+			}
+		case opcode.UnaryOp:
+			if len(op.Args) == 2 {
+				if operator, ok := op.Args[0].(string); ok {
+					if folded, ok := foldUnary(operator, op.Args[1]); ok {
+						return folded
+					}
+				}
+			}
+		}
+		return op
+	default:
+		return v
+	}
+}
+
+// foldBinary evaluates a binary operation on two literals as the VM does
+// (executeBinaryOp). It returns false if the operands are not literals or the VM
+// would report an error (division by zero, operands it cannot convert).
+func foldBinary(operator string, left, right any) (any, bool) {
+	if !isLiteral(left) || !isLiteral(right) {
+		return nil, false
+	}
+	_, leftIsString := left.(string)
+	_, rightIsString := right.(string)
+
+	switch operator {
+	case "+", "-", "*", "/", "%":
+		if leftIsString || rightIsString {
+			if operator == "+" {
+				return literalString(left) + literalString(right), true
+			}
+			return nil, false
+		}
+		if isFloatLiteral(left) || isFloatLiteral(right) {
+			// Floating point arithmetic depends on the VM's compatibility mode
+			return nil, false
+		}
+		return foldIntArithmetic(operator, left.(int64), right.(int64))
+	case "==", "!=", "<", "<=", ">", ">=":
+		if leftIsString && rightIsString {
+			return boolLiteral(compare(operator, strings.Compare(left.(string), right.(string)))), true
+		}
+		if leftIsString || rightIsString {
+			return nil, false
+		}
+		if isFloatLiteral(left) || isFloatLiteral(right) {
+			l, r := toFloat(left), toFloat(right)
+			switch {
+			case l < r:
+				return boolLiteral(compare(operator, -1)), true
+			case l > r:
+				return boolLiteral(compare(operator, 1)), true
+			case l == r:
+				return boolLiteral(compare(operator, 0)), true
+			}
+			return boolLiteral(operator == "!="), true // NaN
+		}
+		l, r := left.(int64), right.(int64)
+		switch {
+		case l < r:
+			return boolLiteral(compare(operator, -1)), true
+		case l > r:
+			return boolLiteral(compare(operator, 1)), true
+		}
+		return boolLiteral(compare(operator, 0)), true
+	case "&&":
+		return boolLiteral(truthy(left) && truthy(right)), true
+	case "||":
+		return boolLiteral(truthy(left) || truthy(right)), true
+	}
+	return nil, false
+}
+
+// foldIntArithmetic evaluates integer arithmetic; division by zero is left to the VM, which logs it.
+func foldIntArithmetic(operator string, l, r int64) (any, bool) {
+	switch operator {
+	case "+":
+		return l + r, true
+	case "-":
+		return l - r, true
+	case "*":
+		return l * r, true
+	case "/":
+		if r != 0 {
+			return l / r, true
+		}
+	case "%":
+		if r != 0 {
+			return l % r, true
+		}
+	}
+	return nil, false
+}
+
+// foldUnary evaluates a unary operation on a literal as the VM does (executeUnaryOp).
+func foldUnary(operator string, operand any) (any, bool) {
+	if !isLiteral(operand) {
+		return nil, false
+	}
+	switch operator {
+	case "-":
+		switch n := operand.(type) {
+		case int64:
+			return -n, true
+		case float64:
+			return -n, true
+		}
+	case "!":
+		return boolLiteral(!truthy(operand)), true
+	}
+	return nil, false
+}
+
+// compare reports whether the result of a three-way comparison satisfies operator.
+func compare(operator string, cmp int) bool {
+	switch operator {
+	case "==":
+		return cmp == 0
+	case "!=":
+		return cmp != 0
+	case "<":
+		return cmp < 0
+	case "<=":
+		return cmp <= 0
+	case ">":
+		return cmp > 0
+	default: // ">="
+		return cmp >= 0
+	}
+}
+
+// isLiteral reports whether v is a literal value produced by the compiler.
+func isLiteral(v any) bool {
+	switch v.(type) {
+	case int64, float64, string:
+		return true
+	}
+	return false
+}
+
+func isFloatLiteral(v any) bool {
+	_, ok := v.(float64)
+	return ok
+}
+
+// toFloat converts a numeric literal to float64.
+func toFloat(v any) float64 {
+	if f, ok := v.(float64); ok {
+		return f
+	}
+	return float64(v.(int64))
+}
+
+// literalString converts a literal to a string as string concatenation in the VM does.
+func literalString(v any) string {
+	switch val := v.(type) {
+	case string:
+		return val
+	case float64:
+		return fmt.Sprintf("%g", val)
+	default:
+		return fmt.Sprintf("%d", val)
+	}
+}
+
+// truthy reports whether a literal is true in a condition (non-zero or non-empty).
+func truthy(v any) bool {
+	switch val := v.(type) {
+	case int64:
+		return val != 0
+	case float64:
+		return val != 0
+	case string:
+		return val != ""
+	}
+	return v != nil
+}
+
+// boolLiteral converts a boolean to FILLY's integer representation.
+func boolLiteral(b bool) int64 {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+// isConstantFalse reports whether a loop condition is a literal that is false.
+// A missing condition (for(;;)) is not.
+func isConstantFalse(cond any) bool {
+	return isLiteral(cond) && !truthy(cond)
+}
+
+// blockArg returns a block argument of a control statement.
+func blockArg(v any) []opcode.OpCode {
+	ops, _ := v.([]opcode.OpCode)
+	return ops
+}
+
+// terminates reports whether statements after op in a block of the given kind never run.
+func terminates(op opcode.OpCode, kind blockKind) bool {
+	switch kind {
+	case nestedBlock:
+		return op.Cmd == opcode.Break || op.Cmd == opcode.Continue || isReturn(op)
+	case functionBody:
+		return isReturn(op)
+	}
+	return false
+}
+
+func isReturn(op opcode.OpCode) bool {
+	if op.Cmd != opcode.Call || len(op.Args) == 0 {
+		return false
+	}
+	name, _ := op.Args[0].(string)
+	return name == "return"
+}
+
+// inlinable reports whether a block can replace the statement that contains it:
+// it must not pause the sequence (Wait, WaitAny, step commas, DebugBreak) at any depth.
+func inlinable(ops []opcode.OpCode) bool {
+	for _, op := range ops {
+		switch op.Cmd {
+		case opcode.Wait, opcode.SetStep, opcode.DebugBreak, opcode.WaitAny:
+			return false
+		case opcode.Call:
+			if name, _ := op.Args[0].(string); strings.EqualFold(name, "wait") || strings.EqualFold(name, "waitany") {
+				return false
+			}
+		case opcode.DefineFunction, opcode.RegisterEventHandler:
+			continue
+		}
+		for _, arg := range op.Args {
+			if child, ok := arg.([]opcode.OpCode); ok && !inlinable(child) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// blockHasSetStep reports whether a block contains step() at any depth. The VM decides
+// whether an event handler is removed when it completes by looking for SetStep in its
+// body, so code containing it is never removed even if it cannot run.
+func blockHasSetStep(ops []opcode.OpCode) bool {
+	for _, op := range ops {
+		if op.Cmd == opcode.SetStep {
+			return true
+		}
+		for _, arg := range op.Args {
+			if child, ok := arg.([]opcode.OpCode); ok && blockHasSetStep(child) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// propagatableConstants finds the named constants whose value is known before any code
+// runs: literals assigned at the start of the program (before any statement other than
+// assignments and function definitions), that are never assigned again, are not the
+// name of a function parameter (which would shadow them) and are not named by a string
+// literal (built-ins such as BindNote assign variables by name).
+func propagatableConstants(ops []opcode.OpCode, names []string) map[opcode.Variable]any {
+	named := make(map[opcode.Variable]bool, len(names))
+	for _, name := range names {
+		named[opcode.Variable(name)] = true
+	}
+	candidates := make(map[opcode.Variable]any)
+	for _, op := range ops {
+		if op.Cmd == opcode.DefineFunction {
+			continue
+		}
+		if op.Cmd != opcode.Assign || len(op.Args) != 2 || !isLiteral(op.Args[1]) {
+			break
+		}
+		if name, ok := op.Args[0].(opcode.Variable); ok && named[name] {
+			candidates[name] = op.Args[1]
+		}
+	}
+	if len(candidates) == 0 {
+		return nil
+	}
+
+	assigned := make(map[opcode.Variable]int)
+	excluded := make(map[opcode.Variable]bool)
+	for _, name := range handlerParams {
+		excluded[name] = true
+	}
+	var strs []string
+	walkOps(ops, func(op opcode.OpCode) {
+		switch op.Cmd {
+		case opcode.Assign, opcode.ArrayAssign:
+			if name, ok := op.Args[0].(opcode.Variable); ok {
+				assigned[name]++
+			}
+		case opcode.Try:
+			// catch assigns the error message to its variable
+			if len(op.Args) >= 3 {
+				if name, ok := op.Args[2].(opcode.Variable); ok && name != "" {
+					excluded[name] = true
+				}
+			}
+		case opcode.DefineFunction:
+			params, _ := op.Args[1].([]any)
+			for _, p := range params {
+				if info, ok := p.(map[string]any); ok {
+					if name, ok := info["name"].(string); ok {
+						excluded[opcode.Variable(name)] = true
+					}
+				}
+			}
+		case opcode.Call:
+			for _, arg := range op.Args[1:] {
+				if s, ok := arg.(string); ok {
+					strs = append(strs, s)
+				}
+			}
+		}
+	})
+	for _, s := range strs {
+		for name := range candidates {
+			if strings.EqualFold(s, string(name)) {
+				excluded[name] = true
+			}
+		}
+	}
+
+	constants := make(map[opcode.Variable]any)
+	for name, value := range candidates {
+		if assigned[name] == 1 && !excluded[name] {
+			constants[name] = value
+		}
+	}
+	return constants
+}
+
+// walkOps calls fn for every OpCode in ops, including nested blocks and expressions.
+func walkOps(ops []opcode.OpCode, fn func(opcode.OpCode)) {
+	for _, op := range ops {
+		walkOp(op, fn)
+	}
+}
+
+func walkOp(op opcode.OpCode, fn func(opcode.OpCode)) {
+	fn(op)
+	for _, arg := range op.Args {
+		walkValue(arg, fn)
+	}
+}
+
+func walkValue(v any, fn func(opcode.OpCode)) {
+	switch val := v.(type) {
+	case opcode.OpCode:
+		walkOp(val, fn)
+	case []opcode.OpCode:
+		walkOps(val, fn)
+	case []any:
+		for _, item := range val {
+			walkValue(item, fn)
+		}
+	case map[string]any:
+		for _, item := range val {
+			walkValue(item, fn)
+		}
+	}
+}
@@ -0,0 +1,225 @@
+package compiler
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/zurustar/son-et/pkg/compiler/lexer"
+	"github.com/zurustar/son-et/pkg/compiler/parser"
+	"github.com/zurustar/son-et/pkg/opcode"
+)
+
+// compileForOptimize compiles source without optimization.
+func compileForOptimize(t *testing.T, source string) []opcode.OpCode {
+	t.Helper()
+	p := parser.New(lexer.New(source))
+	program, errs := p.ParseProgram()
+	if len(errs) > 0 {
+		t.Fatalf("parse errors for %q: %v", source, errs)
+	}
+	ops, errs := New().Compile(program)
+	if len(errs) > 0 {
+		t.Fatalf("compile errors for %q: %v", source, errs)
+	}
+	return ops
+}
+
+// TestOptimize tests that Optimize produces the same OpCode as the hand-simplified script.
+func TestOptimize(t *testing.T) {
+	tests := []struct {
+		name      string
+		input     string
+		expected  string
+		constants []string // named constants injected by the preprocessor
+	}{
+		{
+			name:     "integer arithmetic",
+			input:    "x = 2 + 3 * 4 - 10 / 2 % 3",
+			expected: "x = 12",
+		},
+		{
+			name:     "asset path concatenation",
+			input:    `LoadPic("BG" + 3 + ".BMP")`,
+			expected: `LoadPic("BG3.BMP")`,
+		},
+		{
+			name:     "comparison and logic",
+			input:    `x = (1 < 2) && ("a" == "a") || !5`,
+			expected: "x = 1",
+		},
+		{
+			name:     "unary minus",
+			input:    "x = -(2 + 3) + 10",
+			expected: "x = 5",
+		},
+		{
+			name:     "partial folding keeps variables",
+			input:    "x = y + 2 * 3",
+			expected: "x = y + 6",
+		},
+		{
+			name:     "division by zero is left to the VM",
+			input:    "x = 1 / 0",
+			expected: "x = 1 / 0",
+		},
+		{
+			name:     "floating point arithmetic is left to the VM",
+			input:    "x = 1.5 + 1",
+			expected: "x = 1.5 + 1",
+		},
+		{
+			name:     "mixed string and number comparison is left to the VM",
+			input:    `x = "1" == 1`,
+			expected: `x = "1" == 1`,
+		},
+		{
+			name:     "constant true if is inlined",
+			input:    "if (1) { x = 1 } else { x = 2 }",
+			expected: "x = 1",
+		},
+		{
+			name:     "constant false if without else is removed",
+			input:    "if (0) { x = 1 }",
+			expected: "",
+		},
+		{
+			name:     "if containing a wait keeps its statement",
+			input:    "if (1) { x = 1 Wait(2) } else { x = 2 }",
+			expected: "if (1) { x = 1 Wait(2) }",
+		},
+		{
+			name:     "constant false while is removed",
+			input:    "while (0) { x = x + 1 }",
+			expected: "",
+		},
+		{
+			name:     "constant false for keeps its initializer",
+			input:    "for (i = 0; 0; i = i + 1) { x = 1 }",
+			expected: "i = 0",
+		},
+		{
+			name:     "statements after break are removed",
+			input:    "while (x) { y = 1 break y = 2 }",
+			expected: "while (x) { y = 1 break }",
+		},
+		{
+			name:     "statements after return in a function are removed",
+			input:    "f() { return 0 x = 1 }",
+			expected: "f() { return 0 }",
+		},
+		{
+			name:     "top level statements after break are kept",
+			input:    "break x = 1",
+			expected: "break x = 1",
+		},
+		{
+			name:      "named constant is propagated",
+			input:     `DIR = "DATA/" LoadPic(DIR + "A.BMP") x = DIR`,
+			expected:  `DIR = "DATA/" LoadPic("DATA/A.BMP") x = "DATA/"`,
+			constants: []string{"DIR"},
+		},
+		{
+			name:      "named constant enables dead code elimination",
+			input:     "DEBUG = 0 if (DEBUG) { Dump() }",
+			expected:  "DEBUG = 0",
+			constants: []string{"DEBUG"},
+		},
+		{
+			name:     "global that is not a named constant is not propagated",
+			input:    "SPEED = 5 x = SPEED * 2",
+			expected: "SPEED = 5 x = SPEED * 2",
+		},
+		{
+			name:      "reassigned global is not propagated",
+			input:     "n = 1 n = n + 1 x = n",
+			expected:  "n = 1 n = n + 1 x = n",
+			constants: []string{"n"},
+		},
+		{
+			name:      "global assigned after other code is not propagated",
+			input:     "Init() n = 1 x = n",
+			expected:  "Init() n = 1 x = n",
+			constants: []string{"n"},
+		},
+		{
+			name:      "global shadowed by a parameter is not propagated",
+			input:     "n = 1 f(n) { x = n }",
+			expected:  "n = 1 f(n) { x = n }",
+			constants: []string{"n"},
+		},
+		{
+			name:      "global named by a string is not propagated",
+			input:     `n = 1 BindNote("n") x = n`,
+			expected:  `n = 1 BindNote("n") x = n`,
+			constants: []string{"n"},
+		},
+		{
+			name:     "try and catch blocks are folded",
+			input:    "try { x = 1 + 2 } catch (e) { y = 2 * 3 }",
+			expected: "try { x = 3 } catch (e) { y = 6 }",
+		},
+		{
+			name:      "global assigned by catch is not propagated",
+			input:     "e = 1 try { f() } catch (e) { x = e }",
+			expected:  "e = 1 try { f() } catch (e) { x = e }",
+			constants: []string{"e"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Optimize(compileForOptimize(t, tt.input), tt.constants)
+			expected := compileForOptimize(t, tt.expected)
+			if len(got) == 0 && len(expected) == 0 {
+				return
+			}
+			if !reflect.DeepEqual(got, expected) {
+				t.Errorf("Optimize(%q)\n got: %#v\nwant: %#v", tt.input, got, expected)
+			}
+		})
+	}
+}
+
+// TestOptimizeKeepsStepBlocks tests that code containing step() is never removed,
+// because the VM decides whether an event handler stays registered from its body.
+func TestOptimizeKeepsStepBlocks(t *testing.T) {
+	ops := []opcode.OpCode{{
+		Cmd: opcode.RegisterEventHandler,
+		Args: []any{"TIME", []opcode.OpCode{{
+			Cmd: opcode.If,
+			Args: []any{
+				int64(0),
+				[]opcode.OpCode{{Cmd: opcode.SetStep, Args: []any{int64(1)}}},
+				[]opcode.OpCode{},
+			},
+		}}},
+	}}
+
+	got := Optimize(ops, nil)
+	if !blockHasSetStep(got) {
+		t.Errorf("SetStep was removed: %#v", got)
+	}
+}
+
+// TestOptimizeExpressionStatement tests that an expression statement that folds to a
+// literal is dropped.
+func TestOptimizeExpressionStatement(t *testing.T) {
+	ops := []opcode.OpCode{
+		{Cmd: opcode.BinaryOp, Args: []any{"+", int64(1), int64(2)}},
+		{Cmd: opcode.Call, Args: []any{"f"}},
+	}
+	got := Optimize(ops, nil)
+	if len(got) != 1 || got[0].Cmd != opcode.Call {
+		t.Errorf("Optimize = %#v, want only the call", got)
+	}
+}
+
+// TestOptimizeDoesNotModifyInput tests that the input OpCode is left unchanged.
+func TestOptimizeDoesNotModifyInput(t *testing.T) {
+	ops := compileForOptimize(t, "x = 1 + 2 if (1) { y = 3 * 4 }")
+	before := compileForOptimize(t, "x = 1 + 2 if (1) { y = 3 * 4 }")
+	Optimize(ops, []string{"x"})
+	if !reflect.DeepEqual(ops, before) {
+		t.Errorf("input was modified:\n got: %#v\nwant: %#v", ops, before)
+	}
+}
@@ -2,13 +2,19 @@
 package compiler
 
 import (
+	"errors"
 	"os"
 	"path/filepath"
+	"reflect"
 	"strings"
 	"testing"
+	"testing/fstest"
 
+	"github.com/zurustar/son-et/pkg/compat"
+	"github.com/zurustar/son-et/pkg/compiler/preprocessor"
 	"github.com/zurustar/son-et/pkg/opcode"
 	"github.com/zurustar/son-et/pkg/script"
+	"github.com/zurustar/son-et/pkg/vm"
 )
 
 // TestCompile tests the Compile function with various source code inputs.
@@ -770,3 +776,164 @@ func TestCompileResultType(t *testing.T) {
 		t.Errorf("CompileResult.Errors expected nil, got %v", result.Errors)
 	}
 }
+
+// TestCompileWithPreprocessorNamedConstants tests that #info constants become global assignments.
+func TestCompileWithPreprocessorNamedConstants(t *testing.T) {
+	dir := t.TempDir()
+	source := "#info CONST MAXLINE 24\n#info COLOR BTNFACE 192,192,192\nmain() {\n  x = MAXLINE\n}\n"
+	if err := os.WriteFile(filepath.Join(dir, "main.tfy"), []byte(source), 0644); err != nil {
+		t.Fatalf("failed to write file: %v", err)
+	}
+
+	opcodes, result, err := CompileWithPreprocessor(dir, "main.tfy")
+	if err != nil {
+		t.Fatalf("CompileWithPreprocessor failed: %v", err)
+	}
+	if len(result.Constants) != 2 {
+		t.Fatalf("expected 2 constants, got %d", len(result.Constants))
+	}
+
+	assigned := make(map[string]any)
+	for _, op := range opcodes {
+		if op.Cmd == opcode.Assign && len(op.Args) == 2 {
+			if name, ok := op.Args[0].(opcode.Variable); ok {
+				assigned[string(name)] = op.Args[1]
+			}
+		}
+	}
+	if assigned["MAXLINE"] != int64(24) {
+		t.Errorf("MAXLINE should be assigned 24 at global scope, got %v", assigned["MAXLINE"])
+	}
+	if assigned["BTNFACE"] != int64(0xC0C0C0) {
+		t.Errorf("BTNFACE should be assigned 0xC0C0C0 at global scope, got %v", assigned["BTNFACE"])
+	}
+}
+
+// TestNamedConstantsReadByMain tests that main() sees the named constants the preprocessor
+// appends to the end of the source. The source is compiled without optimization, so that
+// main() reads the globals at run time instead of the propagated values.
+func TestNamedConstantsReadByMain(t *testing.T) {
+	dir := t.TempDir()
+	source := "#info CONST MAXLINE 24\n#info CONST TITLE \"ROBOT\"\nint lines;\nstr title;\nmain() {\n  lines = MAXLINE * 2\n  title = TITLE\n}\n"
+	if err := os.WriteFile(filepath.Join(dir, "main.tfy"), []byte(source), 0644); err != nil {
+		t.Fatalf("failed to write file: %v", err)
+	}
+
+	result, err := preprocessor.New(dir).PreprocessFile("main.tfy")
+	if err != nil {
+		t.Fatalf("PreprocessFile failed: %v", err)
+	}
+	opcodes, errs := Compile(result.Source)
+	if len(errs) > 0 {
+		t.Fatalf("Compile failed: %v", errs)
+	}
+
+	v := vm.New(opcodes)
+	if err := v.Run(); err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	if got, _ := v.GetGlobalScope().Get("lines"); got != int64(48) {
+		t.Errorf("lines = %v, want 48", got)
+	}
+	if got, _ := v.GetGlobalScope().Get("title"); got != "ROBOT" {
+		t.Errorf("title = %v, want ROBOT", got)
+	}
+}
+
+// TestCompileWithPreprocessorKeepsWatchedGlobals tests that a global assigned a literal,
+// which is not a named constant, stays a variable after optimization, so editing it from
+// the watch panel changes what the script reads.
+func TestCompileWithPreprocessorKeepsWatchedGlobals(t *testing.T) {
+	dir := t.TempDir()
+	source := "SPEED = 5\nint x;\nMove() {\n  x = SPEED * 2\n}\nmain() {\n  Move()\n}\n"
+	if err := os.WriteFile(filepath.Join(dir, "main.tfy"), []byte(source), 0644); err != nil {
+		t.Fatalf("failed to write file: %v", err)
+	}
+
+	opcodes, _, err := CompileWithPreprocessor(dir, "main.tfy")
+	if err != nil {
+		t.Fatalf("CompileWithPreprocessor failed: %v", err)
+	}
+	v := vm.New(opcodes)
+	if err := v.Run(); err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	if got, _ := v.GetGlobalScope().Get("x"); got != int64(10) {
+		t.Fatalf("x = %v, want 10", got)
+	}
+
+	if _, err := v.SetWatchedVariable("SPEED", 7); err != nil {
+		t.Fatalf("SetWatchedVariable failed: %v", err)
+	}
+	if _, err := v.Execute(opcode.OpCode{Cmd: opcode.Call, Args: []any{"Move"}}); err != nil {
+		t.Fatalf("Move() failed: %v", err)
+	}
+	if got, _ := v.GetGlobalScope().Get("x"); got != int64(14) {
+		t.Errorf("x = %v after setting SPEED to 7, want 14", got)
+	}
+}
+
+// TestCompileWithPreprocessorOptimizes tests that named constants are propagated into the
+// program and the OpCode is optimized.
+func TestCompileWithPreprocessorOptimizes(t *testing.T) {
+	dir := t.TempDir()
+	source := "#info CONST DEBUG 0\n#info CONST SCENE 3\nmain() {\n  if (DEBUG) { Dump() }\n  LoadPic(\"BG\" + SCENE + \".BMP\")\n}\n"
+	if err := os.WriteFile(filepath.Join(dir, "main.tfy"), []byte(source), 0644); err != nil {
+		t.Fatalf("failed to write file: %v", err)
+	}
+
+	opcodes, _, err := CompileWithPreprocessor(dir, "main.tfy")
+	if err != nil {
+		t.Fatalf("CompileWithPreprocessor failed: %v", err)
+	}
+	var body []opcode.OpCode
+	for _, op := range opcodes {
+		if op.Cmd == opcode.DefineFunction && op.Args[0] == "main" {
+			body = op.Args[2].([]opcode.OpCode)
+		}
+	}
+	want := []opcode.OpCode{{Cmd: opcode.Call, Args: []any{"LoadPic", "BG3.BMP"}}}
+	if !reflect.DeepEqual(body, want) {
+		t.Errorf("main body = %#v, want %#v", body, want)
+	}
+}
+
+func TestCompileWithPreprocessorOptionsCompat(t *testing.T) {
+	dir := t.TempDir()
+	source := "main() {\n  x = 0.5\n}\n"
+	if err := os.WriteFile(filepath.Join(dir, "main.tfy"), []byte(source), 0644); err != nil {
+		t.Fatalf("failed to write file: %v", err)
+	}
+
+	if _, _, err := CompileWithPreprocessorOptions(dir, "main.tfy", nil, CompileOptions{}); err != nil {
+		t.Fatalf("extended mode should accept float literals: %v", err)
+	}
+
+	fsys := fstest.MapFS{"title/main.tfy": {Data: []byte(source)}}
+	_, _, err := CompileWithPreprocessorOptions("title", "main.tfy", fsys, CompileOptions{Compat: compat.FILLY97})
+	if err == nil || !strings.Contains(err.Error(), "floating point literal") {
+		t.Errorf("filly97 mode should reject float literals, got %v", err)
+	}
+}
+
+func TestCompileWithPreprocessorReportsAllSyntaxErrors(t *testing.T) {
+	dir := t.TempDir()
+	source := "main() {\n  x = (1 + ;\n  y = 2;\n  z = ];\n}\n"
+	if err := os.WriteFile(filepath.Join(dir, "main.tfy"), []byte(source), 0644); err != nil {
+		t.Fatalf("failed to write file: %v", err)
+	}
+
+	_, _, err := CompileWithPreprocessor(dir, "main.tfy")
+	if err == nil {
+		t.Fatal("expected a compilation error")
+	}
+	for _, want := range []string{"line 2, column 12", "line 4, column 7"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("error should report %s, got:\n%v", want, err)
+		}
+	}
+	var ce *CompileError
+	if !errors.As(err, &ce) {
+		t.Errorf("error should wrap a *CompileError, got %T", err)
+	}
+}
@@ -0,0 +1,273 @@
+// Package formatter re-emits FILLY scripts (.TFY files) in a canonical layout (son-et fmt).
+//
+// The formatter works on the lexer's token stream with comments retained, so comments,
+// string literals and numbers are reproduced exactly as written. It normalizes:
+//   - indentation (IndentWidth spaces per brace level, case bodies one level deeper)
+//   - spacing between tokens (binary operators, commas, keywords, braces)
+//   - blank lines (runs of blank lines collapse to one; leading/trailing ones are removed)
+//   - trailing whitespace and the final newline
+//
+// Line breaks between tokens are preserved, so the formatter never joins or splits statements.
+// Sources with lexical or syntax errors are rejected rather than formatted.
+package formatter
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/zurustar/son-et/pkg/compiler/lexer"
+	"github.com/zurustar/son-et/pkg/compiler/parser"
+)
+
+// IndentWidth はブロック1段あたりのインデント幅（スペース数）
+const IndentWidth = 4
+
+// ErrSyntax はソースに字句・構文エラーがあり、整形できない場合のエラー
+var ErrSyntax = errors.New("source has syntax errors")
+
+// Format はソースを整形して返す
+// 改行コード（CRLF/LF）は入力に合わせる
+func Format(source string) (string, error) {
+	crlf := strings.Contains(source, "\r\n")
+	src := strings.ReplaceAll(source, "\r\n", "\n")
+
+	if err := validate(src); err != nil {
+		return "", err
+	}
+
+	tokens, _ := lexer.NewWithComments(src).TokenizeWithErrors()
+	out := newPrinter(src).print(tokens)
+
+	// 整形によってトークン列が変わっていないことを確認する（コメント以外）
+	if !sameTokens(src, out) {
+		return "", fmt.Errorf("internal error: formatting changed the token stream")
+	}
+
+	if crlf {
+		out = strings.ReplaceAll(out, "\n", "\r\n")
+	}
+	return out, nil
+}
+
+// validate はソースに字句・構文エラーが無いことを確認する
+func validate(src string) error {
+	_, lexErrs := lexer.New(src).TokenizeWithErrors()
+	if len(lexErrs) > 0 {
+		return fmt.Errorf("%w: %v", ErrSyntax, lexErrs[0])
+	}
+	_, parseErrs := parser.New(lexer.New(src)).ParseProgram()
+	if len(parseErrs) > 0 {
+		return fmt.Errorf("%w: %v", ErrSyntax, parseErrs[0])
+	}
+	return nil
+}
+
+// sameTokens は2つのソースのトークン列（コメントを除く）が同じかを返す
+func sameTokens(a, b string) bool {
+	ta, _ := lexer.New(a).Tokenize()
+	tb, _ := lexer.New(b).Tokenize()
+	if len(ta) != len(tb) {
+		return false
+	}
+	for i := range ta {
+		if ta[i].Type != tb[i].Type || ta[i].Literal != tb[i].Literal {
+			return false
+		}
+	}
+	return true
+}
+
+// blockState はブレースで囲まれたブロックの整形状態
+type blockState struct {
+	inCase bool // case/default ラベルの後（本体を1段深くインデントする）
+}
+
+// indent はブロック内の行に加えるインデントの段数を返す
+func (b blockState) indent() int {
+	if b.inCase {
+		return 2
+	}
+	return 1
+}
+
+// printer はトークン列を整形済みのテキストに変換する
+type printer struct {
+	src    string
+	sb     strings.Builder
+	blocks []blockState // 開いているブロック（外側から順）
+	parens int          // 開いている丸括弧・角括弧の数（行の継続のインデントに使用）
+
+	line      strings.Builder // 出力中の行
+	lineStart bool            // 行にまだトークンを出力していない
+	blank     bool            // 直前に空行を出力した（または出力の先頭）
+	prev      lexer.Token     // 直前のトークン
+	prevUnary bool            // 直前のトークンが単項演算子
+	prevCode  lexer.Token     // コメントを除く直前のトークン（単項演算子の判定に使用）
+	prevLine  int             // 直前のトークンが終わった行
+}
+
+func newPrinter(src string) *printer {
+	return &printer{src: src, lineStart: true, blank: true}
+}
+
+// print はトークン列を整形して返す
+func (p *printer) print(tokens []lexer.Token) string {
+	for i, tok := range tokens {
+		if tok.Type == lexer.TOKEN_EOF {
+			break
+		}
+
+		if i > 0 && tok.Line > p.prevLine {
+			p.newline(tok.Line - p.prevLine - 1)
+		}
+
+		text := p.src[tok.Offset:tok.End]
+		if tok.Type == lexer.TOKEN_COMMENT {
+			text = tok.Literal
+		}
+
+		if p.lineStart {
+			p.startLine(tok)
+		} else if needsSpace(p.prev, p.prevUnary, tok) {
+			p.line.WriteByte(' ')
+		}
+		p.line.WriteString(text)
+		p.lineStart = false
+
+		p.prevUnary = tok.Type == lexer.TOKEN_MINUS && isUnary(p.prevCode)
+		p.prev = tok
+		if tok.Type != lexer.TOKEN_COMMENT {
+			p.prevCode = tok
+		}
+		p.prevLine = tok.Line + strings.Count(text, "\n")
+		p.track(tok)
+	}
+	p.flushLine()
+	return p.sb.String()
+}
+
+// newline は現在の行を出力し、元のソースに空行があった場合は1行だけ空行を出力する
+func (p *printer) newline(blankLines int) {
+	p.flushLine()
+	if blankLines > 0 && !p.blank {
+		p.sb.WriteByte('\n')
+		p.blank = true
+	}
+}
+
+// flushLine は出力中の行を末尾の空白を除いて出力する
+func (p *printer) flushLine() {
+	if p.lineStart {
+		return
+	}
+	p.sb.WriteString(strings.TrimRight(p.line.String(), " \t"))
+	p.sb.WriteByte('\n')
+	p.line.Reset()
+	p.lineStart = true
+	p.blank = false
+}
+
+// startLine は行頭のトークンに合わせてインデントを出力する
+func (p *printer) startLine(tok lexer.Token) {
+	switch tok.Type {
+	case lexer.TOKEN_INFO, lexer.TOKEN_INCLUDE, lexer.TOKEN_DEFINE, lexer.TOKEN_DIRECTIVE:
+		// ディレクティブは常に行頭に置く
+		return
+	}
+
+	depth := p.parens
+	for _, b := range p.blocks {
+		depth += b.indent()
+	}
+	if n := len(p.blocks); n > 0 {
+		switch tok.Type {
+		case lexer.TOKEN_RBRACE:
+			// 閉じ括弧は対応する開き括弧の行と揃える
+			depth -= p.blocks[n-1].indent()
+		case lexer.TOKEN_CASE, lexer.TOKEN_DEFAULT:
+			// case ラベルは switch の本体の深さに置く
+			if p.blocks[n-1].inCase {
+				depth--
+			}
+		}
+	}
+	if tok.Type == lexer.TOKEN_RPAREN || tok.Type == lexer.TOKEN_RBRACKET {
+		depth--
+	}
+	if depth > 0 {
+		p.line.WriteString(strings.Repeat(" ", depth*IndentWidth))
+	}
+}
+
+// track はブロック・括弧の深さと case ラベルの状態を更新する
+func (p *printer) track(tok lexer.Token) {
+	switch tok.Type {
+	case lexer.TOKEN_LBRACE:
+		p.blocks = append(p.blocks, blockState{})
+	case lexer.TOKEN_RBRACE:
+		if len(p.blocks) > 0 {
+			p.blocks = p.blocks[:len(p.blocks)-1]
+		}
+	case lexer.TOKEN_LPAREN, lexer.TOKEN_LBRACKET:
+		p.parens++
+	case lexer.TOKEN_RPAREN, lexer.TOKEN_RBRACKET:
+		if p.parens > 0 {
+			p.parens--
+		}
+	case lexer.TOKEN_CASE, lexer.TOKEN_DEFAULT:
+		if n := len(p.blocks); n > 0 {
+			p.blocks[n-1].inCase = true
+		}
+	}
+}
+
+// needsSpace は同じ行で prev の後に cur を出力するときに空白を挟むかを返す
+// prevUnary は prev が単項演算子（符号の - や !）として使われているかを表す
+func needsSpace(prev lexer.Token, prevUnary bool, cur lexer.Token) bool {
+	if cur.Type == lexer.TOKEN_COMMENT {
+		return true
+	}
+
+	switch cur.Type {
+	case lexer.TOKEN_COMMA, lexer.TOKEN_SEMICOLON, lexer.TOKEN_RPAREN, lexer.TOKEN_RBRACKET, lexer.TOKEN_COLON:
+		return false
+	}
+
+	switch prev.Type {
+	case lexer.TOKEN_LPAREN, lexer.TOKEN_LBRACKET, lexer.TOKEN_NOT:
+		return false
+	case lexer.TOKEN_COMMA:
+		// step 内の連続したカンマ（ウェイト）は詰めて書く
+		return cur.Type != lexer.TOKEN_COMMA
+	}
+	if prevUnary {
+		return false
+	}
+
+	switch cur.Type {
+	case lexer.TOKEN_LPAREN:
+		// 関数の呼び出し・定義と mes()/step() は括弧を詰め、if/for/while/switch の後は空白を入れる
+		switch prev.Type {
+		case lexer.TOKEN_IDENT, lexer.TOKEN_RBRACKET, lexer.TOKEN_MES, lexer.TOKEN_STEP:
+			return false
+		}
+	case lexer.TOKEN_LBRACKET:
+		return prev.Type != lexer.TOKEN_IDENT && prev.Type != lexer.TOKEN_RBRACKET
+	}
+	return true
+}
+
+// isUnary は before の直後の - が単項演算子（符号）として使われるかを返す
+// before.Type が TOKEN_ILLEGAL の場合はソースの先頭を表す
+func isUnary(before lexer.Token) bool {
+	if before.Type.IsOperator() {
+		return true
+	}
+	switch before.Type {
+	case lexer.TOKEN_ILLEGAL, lexer.TOKEN_LPAREN, lexer.TOKEN_LBRACKET, lexer.TOKEN_COMMA, lexer.TOKEN_SEMICOLON,
+		lexer.TOKEN_COLON, lexer.TOKEN_LBRACE, lexer.TOKEN_RBRACE, lexer.TOKEN_RETURN, lexer.TOKEN_CASE:
+		return true
+	}
+	return false
+}
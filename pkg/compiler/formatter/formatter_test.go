@@ -0,0 +1,125 @@
+package formatter
+
+import (
+	"errors"
+	"testing"
+)
+
+// TestFormat tests indentation, spacing and blank line normalization.
+func TestFormat(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  string
+	}{
+		{
+			name:  "indentation and spacing",
+			input: "main(){\nx=1+2*(3-4)\nif(x!=1){\n\t\tMoveCast(1,2,3)\n}else{\nx=-1\n}\n}\n",
+			want:  "main() {\n    x = 1 + 2 * (3 - 4)\n    if (x != 1) {\n        MoveCast(1, 2, 3)\n    } else {\n        x = -1\n    }\n}\n",
+		},
+		{
+			name:  "blank lines and trailing whitespace",
+			input: "\n\nmain() {   \n  x = 1\n\n\n\n  y = 2\n}\n\n\n",
+			want:  "main() {\n    x = 1\n\n    y = 2\n}\n",
+		},
+		{
+			name:  "comments are preserved",
+			input: "// header\nmain() {\n// inside\n  x = 1   // trailing\n  /* block\n     comment */\n}\n",
+			want:  "// header\nmain() {\n    // inside\n    x = 1 // trailing\n    /* block\n     comment */\n}\n",
+		},
+		{
+			name:  "switch and case",
+			input: "main() {\nswitch (x) {\ncase 1:\ny = 1\nbreak\ndefault:\nif (y) {\ny = 2\n}\n}\n}\n",
+			want:  "main() {\n    switch (x) {\n        case 1:\n            y = 1\n            break\n        default:\n            if (y) {\n                y = 2\n            }\n    }\n}\n",
+		},
+		{
+			name:  "mes and step",
+			input: "main() {\nmes ( TIME ) {\nstep ( 8 ) {\nMoveCast(1, 2) ,,,\nend_step\n}\n}\n}\n",
+			want:  "main() {\n    mes(TIME) {\n        step(8) {\n            MoveCast(1, 2),,,\n            end_step\n        }\n    }\n}\n",
+		},
+		{
+			name:  "continuation lines",
+			input: "main() {\nMoveCast(1,\n2,\n3\n)\n}\n",
+			want:  "main() {\n    MoveCast(1,\n        2,\n        3\n    )\n}\n",
+		},
+		{
+			name:  "directives and arrays",
+			input: "  #info INAM \"title\"\n#include \"common.tfy\"\nint a[10]\nmain() {\na [ 1 ] = a[2]\n}\n",
+			want:  "#info INAM \"title\"\n#include \"common.tfy\"\nint a[10]\nmain() {\n    a[1] = a[2]\n}\n",
+		},
+		{
+			name:  "unary operators",
+			input: "main() {\nx = - 1\ny = x - 1\nz = f(-x, !y)\nreturn -1\n}\n",
+			want:  "main() {\n    x = -1\n    y = x - 1\n    z = f(-x, !y)\n    return -1\n}\n",
+		},
+		{
+			name:  "integer literal forms are kept",
+			input: "main() {\nc = 0x1F+0b1010\nif (k=='A'||k=='\\n') {\nk=' '\n}\n}\n",
+			want:  "main() {\n    c = 0x1F + 0b1010\n    if (k == 'A' || k == '\\n') {\n        k = ' '\n    }\n}\n",
+		},
+		{
+			name:  "missing final newline",
+			input: "main() {\n}",
+			want:  "main() {\n}\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Format(tt.input)
+			if err != nil {
+				t.Fatalf("Format returned error: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("Format() =\n%s\nwant\n%s", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestFormatIdempotent tests that formatting formatted output does not change it.
+func TestFormatIdempotent(t *testing.T) {
+	input := "// header\nint x,y\nmain(){\nswitch(x){\ncase 1:\nMoveCast(1,\n2)  // c\n}\nmes(CLICK){\nx=-x\n}\n}\n"
+
+	first, err := Format(input)
+	if err != nil {
+		t.Fatalf("Format returned error: %v", err)
+	}
+	second, err := Format(first)
+	if err != nil {
+		t.Fatalf("Format of formatted output returned error: %v", err)
+	}
+	if first != second {
+		t.Errorf("Format is not idempotent:\n%s\nthen\n%s", first, second)
+	}
+}
+
+// TestFormatCRLF tests that CRLF line endings are preserved.
+func TestFormatCRLF(t *testing.T) {
+	got, err := Format("main(){\r\nx=1\r\n}\r\n")
+	if err != nil {
+		t.Fatalf("Format returned error: %v", err)
+	}
+	if want := "main() {\r\n    x = 1\r\n}\r\n"; got != want {
+		t.Errorf("Format() = %q, want %q", got, want)
+	}
+}
+
+// TestFormatSyntaxError tests that sources with errors are rejected.
+func TestFormatSyntaxError(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+	}{
+		{"parse error", "main() {\n  x = (1 + \n}\n"},
+		{"illegal character", "main() {\n  x = 1 @\n}\n"},
+		{"unterminated string", "main() {\n  s = \"abc\n}\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := Format(tt.input); !errors.Is(err, ErrSyntax) {
+				t.Errorf("expected ErrSyntax, got %v", err)
+			}
+		})
+	}
+}
@@ -4,6 +4,7 @@ package lexer
 
 import (
 	"fmt"
+	"unicode/utf8"
 )
 
 // Lexer performs lexical analysis on FILLY source code.
@@ -15,6 +16,10 @@ type Lexer struct {
 	line         int    // current line number (1-indexed)
 	column       int    // current column number (1-indexed)
 
+	// keepComments makes NextToken return comments as TOKEN_COMMENT tokens
+	// instead of skipping them (see NewWithComments).
+	keepComments bool
+
 	// errors accumulates non-token lexical errors detected while scanning
 	// (e.g. unterminated string/comment). ILLEGAL characters are reported
 	// separately via TokenizeWithErrors, so they are NOT added here to avoid
@@ -39,6 +44,15 @@ func New(input string) *Lexer {
 	return l
 }
 
+// NewWithComments creates a new Lexer that returns comments as TOKEN_COMMENT tokens
+// instead of skipping them. The literal is the comment text including its delimiters.
+// Tools that re-emit source code (son-et fmt) use this to preserve comments.
+func NewWithComments(input string) *Lexer {
+	l := New(input)
+	l.keepComments = true
+	return l
+}
+
 // readChar reads the next character and advances the position.
 func (l *Lexer) readChar() {
 	if l.readPosition >= len(l.input) {
@@ -118,7 +132,7 @@ func (l *Lexer) skipWhitespaceAndComments() {
 		}
 
 		// Check for comments
-		if l.ch == '/' {
+		if l.ch == '/' && !l.keepComments {
 			if l.peekChar() == '/' {
 				// Single-line comment
 				l.skipSingleLineComment()
@@ -151,6 +165,11 @@ func isHexDigit(ch byte) bool {
 	return isDigit(ch) || (ch >= 'a' && ch <= 'f') || (ch >= 'A' && ch <= 'F')
 }
 
+// isBinaryDigit returns true if the character is a binary digit.
+func isBinaryDigit(ch byte) bool {
+	return ch == '0' || ch == '1'
+}
+
 // readIdentifier reads an identifier or keyword from the input.
 // Identifiers can contain letters, digits, and underscores, but must start with a letter or underscore.
 // Requirement 2.2: Keywords are identified case-insensitively.
@@ -163,8 +182,8 @@ func (l *Lexer) readIdentifier() string {
 	return l.input[startPos:l.position]
 }
 
-// readNumber reads a numeric literal (decimal, hexadecimal, or floating point).
-// Requirement 2.4: Integer literals (decimal or 0x-prefixed hexadecimal) create INT tokens.
+// readNumber reads a numeric literal (decimal, hexadecimal, binary, or floating point).
+// Requirement 2.4: Integer literals (decimal, 0x-prefixed hexadecimal or 0b-prefixed binary) create INT tokens.
 // Requirement 2.5: Floating point literals create FLOAT tokens.
 func (l *Lexer) readNumber() Token {
 	startLine := l.line
@@ -172,33 +191,40 @@ func (l *Lexer) readNumber() Token {
 
 	// Check for hexadecimal (0x or 0X prefix)
 	if l.ch == '0' && (l.peekChar() == 'x' || l.peekChar() == 'X') {
-		return l.readHexNumber(startLine, startColumn)
+		return l.readPrefixedNumber(startLine, startColumn, isHexDigit)
+	}
+
+	// Check for binary (0b or 0B prefix)
+	if l.ch == '0' && (l.peekChar() == 'b' || l.peekChar() == 'B') {
+		return l.readPrefixedNumber(startLine, startColumn, isBinaryDigit)
 	}
 
 	// Read decimal number (may include floating point)
 	return l.readDecimalNumber(startLine, startColumn)
 }
 
-// readHexNumber reads a hexadecimal integer literal (0x or 0X prefix).
+// readPrefixedNumber reads a hexadecimal (0x) or binary (0b) integer literal.
+// isValid reports the digits of the base. The literal keeps its prefix; the parser
+// converts it to the value.
 // Requirement 2.4: Hexadecimal integers with 0x/0X prefix create INT tokens.
-func (l *Lexer) readHexNumber(startLine, startColumn int) Token {
+func (l *Lexer) readPrefixedNumber(startLine, startColumn int, isValid func(byte) bool) Token {
 	startPos := l.position
 
 	// Skip '0'
 	l.readChar()
-	// Skip 'x' or 'X'
+	// Skip the base letter ('x', 'X', 'b' or 'B')
 	l.readChar()
 
-	// Read hex digits
+	// Read the digits
 	digitCount := 0
-	for isHexDigit(l.ch) {
+	for isValid(l.ch) {
 		l.readChar()
 		digitCount++
 	}
 
 	literal := l.input[startPos:l.position]
 
-	// "0x" with no following hex digits is not a valid integer literal.
+	// "0x" or "0b" with no following digits is not a valid integer literal.
 	// Return ILLEGAL here so it's reported at the lexer stage rather than
 	// producing an INT token that only fails later in the parser.
 	if digitCount == 0 {
@@ -369,20 +395,9 @@ func (l *Lexer) readString() Token {
 		if l.ch == '\\' {
 			// Handle escape sequences
 			l.readChar()
-			switch l.ch {
-			case 'n':
-				result = append(result, '\n')
-			case 't':
-				result = append(result, '\t')
-			case '\\':
-				result = append(result, '\\')
-			case '"':
-				result = append(result, '"')
-			case 'r':
-				result = append(result, '\r')
-			case '0':
-				result = append(result, 0)
-			default:
+			if ch, ok := escapeChar(l.ch); ok {
+				result = append(result, ch)
+			} else {
 				// Unknown escape sequence - keep the backslash and character
 				result = append(result, '\\')
 				result = append(result, l.ch)
@@ -409,6 +424,112 @@ func (l *Lexer) readString() Token {
 	}
 }
 
+// readComment reads a single-line or multi-line comment as a TOKEN_COMMENT token.
+// The literal keeps the // or /* */ delimiters; a trailing carriage return is not included.
+func (l *Lexer) readComment() Token {
+	startLine := l.line
+	startColumn := l.column
+	startPos := l.position
+
+	if l.peekChar() == '/' {
+		l.skipSingleLineComment()
+	} else {
+		l.skipMultiLineComment()
+	}
+
+	end := l.position
+	if end > len(l.input) {
+		end = len(l.input)
+	}
+	literal := l.input[startPos:end]
+	if n := len(literal); n > 0 && literal[n-1] == '\r' {
+		literal = literal[:n-1]
+	}
+
+	return Token{
+		Type:    TOKEN_COMMENT,
+		Literal: literal,
+		Line:    startLine,
+		Column:  startColumn,
+	}
+}
+
+// readCharLiteral reads a character literal enclosed in single quotes ('A', '\n').
+// It creates an INT token whose literal is the source text including the quotes,
+// so formatting keeps the form; CharLiteralValue converts it to the character code.
+func (l *Lexer) readCharLiteral() Token {
+	startLine := l.line
+	startColumn := l.column
+	startPos := l.position
+
+	// Skip the opening single quote
+	l.readChar()
+	for l.ch != '\'' && l.ch != '\n' && l.ch != 0 {
+		if l.ch == '\\' && l.peekChar() != '\n' && l.peekChar() != 0 {
+			l.readChar()
+		}
+		l.readChar()
+	}
+
+	// A quote not closed on the same line is not a character literal
+	if l.ch != '\'' {
+		end := min(l.position, len(l.input))
+		return Token{Type: TOKEN_ILLEGAL, Literal: l.input[startPos:end], Line: startLine, Column: startColumn}
+	}
+	// Skip the closing single quote
+	l.readChar()
+
+	// The parser reports literals that are not exactly one character ('', 'AB')
+	return Token{Type: TOKEN_INT, Literal: l.input[startPos:l.position], Line: startLine, Column: startColumn}
+}
+
+// CharLiteralValue returns the character code of a character literal including
+// its quotes: the Unicode code point of the character, or of the escape sequence
+// (\n, \t, \r, \0, \\, \', \").
+func CharLiteralValue(literal string) (int64, error) {
+	if literal == "''" {
+		return 0, fmt.Errorf("empty character literal")
+	}
+	if len(literal) < 3 || literal[0] != '\'' || literal[len(literal)-1] != '\'' {
+		return 0, fmt.Errorf("invalid character literal %s", literal)
+	}
+	body := literal[1 : len(literal)-1]
+	if body == `\'` {
+		return '\'', nil
+	}
+	if body[0] == '\\' {
+		if len(body) == 2 {
+			if ch, ok := escapeChar(body[1]); ok {
+				return int64(ch), nil
+			}
+		}
+		return 0, fmt.Errorf("unknown escape sequence in character literal %s", literal)
+	}
+	r, size := utf8.DecodeRuneInString(body)
+	if r == utf8.RuneError || size != len(body) {
+		return 0, fmt.Errorf("character literal %s must contain exactly one character", literal)
+	}
+	return int64(r), nil
+}
+
+// escapeChar returns the character of an escape sequence of string and character
+// literals (the character after the backslash).
+func escapeChar(ch byte) (byte, bool) {
+	switch ch {
+	case 'n':
+		return '\n', true
+	case 't':
+		return '\t', true
+	case 'r':
+		return '\r', true
+	case '0':
+		return 0, true
+	case '\\', '"':
+		return ch, true
+	}
+	return 0, false
+}
+
 // newToken creates a new token with the given type and literal.
 func (l *Lexer) newToken(tokenType TokenType, literal string) Token {
 	return Token{
@@ -420,14 +541,24 @@ func (l *Lexer) newToken(tokenType TokenType, literal string) Token {
 }
 
 // NextToken returns the next token from the input.
-// This method implements the core lexical analysis logic.
 // Requirement 2.14: All tokens include line and column numbers for error reporting.
+// Offset and End record the token's byte range so tools can recover the original text.
 func (l *Lexer) NextToken() Token {
-	var tok Token
-
 	// Skip whitespace and comments (Requirements 2.9, 2.10, 2.11)
 	l.skipWhitespaceAndComments()
 
+	start := l.position
+	tok := l.scanToken()
+	tok.Offset = min(start, len(l.input))
+	tok.End = min(l.position, len(l.input))
+	return tok
+}
+
+// scanToken scans the token starting at the current character.
+// This method implements the core lexical analysis logic.
+func (l *Lexer) scanToken() Token {
+	var tok Token
+
 	// Record position for token
 	tok.Line = l.line
 	tok.Column = l.column
@@ -442,6 +573,10 @@ func (l *Lexer) NextToken() Token {
 	case '"':
 		return l.readString()
 
+	// Character literal ('A'), an integer
+	case '\'':
+		return l.readCharLiteral()
+
 	// Arithmetic operators (Requirement 2.7)
 	case '+':
 		tok = l.newToken(TOKEN_PLUS, "+")
@@ -450,8 +585,11 @@ func (l *Lexer) NextToken() Token {
 	case '*':
 		tok = l.newToken(TOKEN_ASTERISK, "*")
 	case '/':
-		// Note: Comments are already handled by skipWhitespaceAndComments,
-		// so if we reach here, it's a division operator
+		// Note: Comments are already skipped by skipWhitespaceAndComments unless
+		// the lexer keeps them; otherwise this is a division operator
+		if l.keepComments && (l.peekChar() == '/' || l.peekChar() == '*') {
+			return l.readComment()
+		}
 		tok = l.newToken(TOKEN_SLASH, "/")
 	case '%':
 		tok = l.newToken(TOKEN_PERCENT, "%")
@@ -30,6 +30,33 @@ func TestHexPrefixWithoutDigitsIsIllegal(t *testing.T) {
 	}
 }
 
+// TestBinaryPrefixWithoutDigitsIsIllegal tests that "0b" with no binary digits is
+// reported like a bare "0x".
+func TestBinaryPrefixWithoutDigitsIsIllegal(t *testing.T) {
+	if tok := New("0b").NextToken(); tok.Type != TOKEN_ILLEGAL {
+		t.Errorf("expected TOKEN_ILLEGAL for \"0b\", got %v (literal %q)", tok.Type, tok.Literal)
+	}
+	if _, errs := New("v = 0b2").TokenizeWithErrors(); len(errs) == 0 {
+		t.Error("expected a lexer error for \"0b2\"")
+	}
+}
+
+// TestUnterminatedCharacterLiteral tests that a quote not closed on the same line is
+// reported once and does not swallow the following lines.
+func TestUnterminatedCharacterLiteral(t *testing.T) {
+	l := New("x = 'A\ny = 1")
+	tokens, errs := l.TokenizeWithErrors()
+	if len(errs) != 1 {
+		t.Errorf("expected one lexer error, got %v", errs)
+	}
+	if tokens[2].Type != TOKEN_ILLEGAL || tokens[2].Literal != "'A" {
+		t.Errorf("expected ILLEGAL 'A, got %v %q", tokens[2].Type, tokens[2].Literal)
+	}
+	if tokens[3].Type != TOKEN_IDENT || tokens[3].Line != 2 {
+		t.Errorf("expected y on line 2, got %v %q on line %d", tokens[3].Type, tokens[3].Literal, tokens[3].Line)
+	}
+}
+
 // TestUnterminatedConstructsAreReported is a regression test for the phase-1
 // minor finding: unterminated string/comment were silently accepted. The token
 // output is unchanged, but Errors()/TokenizeWithErrors must now report them.
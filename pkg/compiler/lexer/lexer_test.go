@@ -316,6 +316,85 @@ func TestHexadecimalIntegerLiterals(t *testing.T) {
 	}
 }
 
+// TestBinaryIntegerLiterals tests parsing of binary integer literals.
+// Validates Requirement 2.4: Integer literals (0b-prefixed binary) create INT tokens.
+func TestBinaryIntegerLiterals(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"0b0", "0b0"},
+		{"0b1010", "0b1010"},
+		{"0B11", "0B11"},
+		{"0b102", "0b10"}, // 2 is not a binary digit
+	}
+
+	for _, tt := range tests {
+		l := New(tt.input)
+		tok := l.NextToken()
+
+		if tok.Type != TOKEN_INT {
+			t.Errorf("input=%q: expected type=TOKEN_INT, got=%v", tt.input, tok.Type)
+		}
+		if tok.Literal != tt.expected {
+			t.Errorf("input=%q: expected literal=%q, got=%q", tt.input, tt.expected, tok.Literal)
+		}
+	}
+}
+
+// TestCharacterLiterals tests that character literals create INT tokens keeping the quotes.
+func TestCharacterLiterals(t *testing.T) {
+	tests := []string{`'A'`, `' '`, `'\n'`, `'\''`, `'\\'`, `'あ'`, `''`, `'AB'`}
+
+	for _, input := range tests {
+		l := New(input + " x")
+		tok := l.NextToken()
+
+		if tok.Type != TOKEN_INT {
+			t.Errorf("input=%q: expected type=TOKEN_INT, got=%v", input, tok.Type)
+		}
+		if tok.Literal != input {
+			t.Errorf("input=%q: expected literal=%q, got=%q", input, input, tok.Literal)
+		}
+		if next := l.NextToken(); next.Type != TOKEN_IDENT || next.Literal != "x" {
+			t.Errorf("input=%q: expected IDENT x after the literal, got %v %q", input, next.Type, next.Literal)
+		}
+	}
+}
+
+func TestCharLiteralValue(t *testing.T) {
+	tests := []struct {
+		literal string
+		want    int64
+		wantErr bool
+	}{
+		{`'A'`, 65, false},
+		{`'0'`, 48, false},
+		{`'\n'`, 10, false},
+		{`'\t'`, 9, false},
+		{`'\0'`, 0, false},
+		{`'\''`, 39, false},
+		{`'\\'`, 92, false},
+		{`'"'`, 34, false},
+		{`'あ'`, 0x3042, false},
+		{`''`, 0, true},
+		{`'AB'`, 0, true},
+		{`'\q'`, 0, true},
+		{`A`, 0, true},
+	}
+
+	for _, tt := range tests {
+		got, err := CharLiteralValue(tt.literal)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("CharLiteralValue(%s) error = %v, wantErr %v", tt.literal, err, tt.wantErr)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("CharLiteralValue(%s) = %d, want %d", tt.literal, got, tt.want)
+		}
+	}
+}
+
 // TestFloatingPointLiterals tests parsing of floating point literals.
 // Validates Requirement 2.5: Floating point literals create FLOAT tokens.
 func TestFloatingPointLiterals(t *testing.T) {
@@ -1355,3 +1434,57 @@ y`
 		t.Errorf("y line: expected=6, got=%d", tok2.Line)
 	}
 }
+
+// TestNewWithComments tests that comments are returned as tokens when requested.
+func TestNewWithComments(t *testing.T) {
+	input := "x = 1 // trailing\r\n/* block\ncomment */ y"
+
+	expected := []struct {
+		typ     TokenType
+		literal string
+		line    int
+	}{
+		{TOKEN_IDENT, "x", 1},
+		{TOKEN_ASSIGN, "=", 1},
+		{TOKEN_INT, "1", 1},
+		{TOKEN_COMMENT, "// trailing", 1},
+		{TOKEN_COMMENT, "/* block\ncomment */", 2},
+		{TOKEN_IDENT, "y", 3},
+		{TOKEN_EOF, "", 3},
+	}
+
+	l := NewWithComments(input)
+	for i, exp := range expected {
+		tok := l.NextToken()
+		if tok.Type != exp.typ || tok.Literal != exp.literal || tok.Line != exp.line {
+			t.Errorf("token[%d] = %s %q line %d, want %s %q line %d",
+				i, tok.Type, tok.Literal, tok.Line, exp.typ, exp.literal, exp.line)
+		}
+	}
+
+	// New は従来通りコメントを読み飛ばす
+	tok := New(input[len("x = 1 "):]).NextToken()
+	if tok.Type != TOKEN_IDENT || tok.Literal != "y" {
+		t.Errorf("New should skip comments, got %s %q", tok.Type, tok.Literal)
+	}
+}
+
+// TestTokenOffsets tests that tokens record their byte range in the source.
+func TestTokenOffsets(t *testing.T) {
+	input := `s = "あ" + 0x1F // c`
+
+	tokens, err := NewWithComments(input).Tokenize()
+	if err != nil {
+		t.Fatalf("Tokenize returned error: %v", err)
+	}
+
+	expected := []string{"s", "=", `"あ"`, "+", "0x1F", "// c", ""}
+	if len(tokens) != len(expected) {
+		t.Fatalf("expected %d tokens, got %d", len(expected), len(tokens))
+	}
+	for i, want := range expected {
+		if got := input[tokens[i].Offset:tokens[i].End]; got != want {
+			t.Errorf("token[%d] source = %q, want %q", i, got, want)
+		}
+	}
+}
@@ -73,6 +73,8 @@ const (
 	TOKEN_DEL_ME    // del_me
 	TOKEN_DEL_US    // del_us
 	TOKEN_DEL_ALL   // del_all
+	TOKEN_TRY       // try
+	TOKEN_CATCH     // catch
 )
 
 // Token represents a lexical token.
@@ -81,6 +83,8 @@ type Token struct {
 	Literal string
 	Line    int
 	Column  int
+	Offset  int // byte offset of the first character in the source
+	End     int // byte offset just past the last character in the source
 }
 
 // tokenTypeNames maps TokenType to its string representation.
@@ -150,6 +154,8 @@ var tokenTypeNames = map[TokenType]string{
 	TOKEN_DEL_ME:    "del_me",
 	TOKEN_DEL_US:    "del_us",
 	TOKEN_DEL_ALL:   "del_all",
+	TOKEN_TRY:       "try",
+	TOKEN_CATCH:     "catch",
 }
 
 // String returns a string representation of the token type.
@@ -162,7 +168,7 @@ func (t TokenType) String() string {
 
 // IsKeyword returns true if the token type is a keyword.
 func (t TokenType) IsKeyword() bool {
-	return t >= TOKEN_INT_TYPE && t <= TOKEN_DEL_ALL
+	return t >= TOKEN_INT_TYPE && t <= TOKEN_CATCH
 }
 
 // IsOperator returns true if the token type is an operator.
@@ -177,6 +183,8 @@ func (t TokenType) IsLiteral() bool {
 
 // keywords maps keyword strings (lowercase) to their TokenType.
 // All keywords are stored in lowercase for case-insensitive matching.
+// try and catch are not listed: they are lexed as identifiers and recognized by the
+// parser only where a try statement can appear.
 var keywords = map[string]TokenType{
 	"int":      TOKEN_INT_TYPE,
 	"str":      TOKEN_STR_TYPE,
@@ -64,6 +64,8 @@ func TestTokenTypeString(t *testing.T) {
 		{TOKEN_DEL_ME, "del_me"},
 		{TOKEN_DEL_US, "del_us"},
 		{TOKEN_DEL_ALL, "del_all"},
+		{TOKEN_TRY, "try"},
+		{TOKEN_CATCH, "catch"},
 	}
 
 	for _, tt := range tests {
@@ -94,6 +96,8 @@ func TestTokenTypeIsKeyword(t *testing.T) {
 		{TOKEN_MES, true},
 		{TOKEN_STEP, true},
 		{TOKEN_DEL_ALL, true},
+		{TOKEN_TRY, true},
+		{TOKEN_CATCH, true},
 		{TOKEN_IDENT, false},
 		{TOKEN_INT, false},
 		{TOKEN_PLUS, false},
@@ -203,6 +207,8 @@ func TestLookupIdent(t *testing.T) {
 		{"lowercase del_me", "del_me", TOKEN_DEL_ME},
 		{"lowercase del_us", "del_us", TOKEN_DEL_US},
 		{"lowercase del_all", "del_all", TOKEN_DEL_ALL},
+		{"try is not reserved", "try", TOKEN_IDENT},
+		{"catch is not reserved", "catch", TOKEN_IDENT},
 
 		// Uppercase keywords (case-insensitive)
 		{"uppercase MES", "MES", TOKEN_MES},
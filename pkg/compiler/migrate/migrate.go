@@ -0,0 +1,367 @@
+// Package migrate rewrites legacy FILLY scripts (.TFY files) that the original runtime
+// accepted but the son-et parser rejects (son-et migrate).
+//
+// Each rule rewrites only the text it has to, so comments, layout and string literals
+// are kept as written, and no rule adds or removes lines: a change is always reported
+// on the line of the original source where it was made. The rules are:
+//   - full-width spaces (U+3000) between tokens become two ASCII spaces (the same width)
+//   - legacy spellings of built-in functions are renamed to the current name
+//   - missing semicolons in for headers, such as for(i=0 i<10 i=i+1), are inserted
+//
+// String literals, comments and preprocessor directives are never modified.
+package migrate
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/zurustar/son-et/pkg/compiler/lexer"
+)
+
+// Rule は書き換えの規則の名前
+type Rule string
+
+const (
+	RuleFullWidthSpace Rule = "full-width-space" // 全角スペースを半角スペースにする
+	RuleLegacyBuiltin  Rule = "legacy-builtin"   // 組み込み関数の旧表記を現在の名前にする
+	RuleForSemicolon   Rule = "for-semicolon"    // for の見出しの抜けたセミコロンを補う
+)
+
+// fullWidthSpace は全角スペース（U+3000）
+const fullWidthSpace = "　"
+
+// legacyBuiltins は組み込み関数の旧表記（小文字）と現在の名前
+// オリジナルのFILLYの古い版や解説書で使われていた綴り。スクリプトで同じ名前の関数を
+// 定義している場合は書き換えない。
+var legacyBuiltins = map[string]string{
+	"playwav": "PlayWAVE",
+	"playmid": "PlayMIDI",
+}
+
+// Change は書き換えた1箇所
+type Change struct {
+	Line int    // 元のソースでの行番号
+	Rule Rule   // 適用した規則
+	Old  string // 書き換える前のテキスト
+	New  string // 書き換えた後のテキスト
+}
+
+// String は変更を "行: 規則: 旧 -> 新" の形式で返す
+func (c Change) String() string {
+	return fmt.Sprintf("%d: %s: %q -> %q", c.Line, c.Rule, c.Old, c.New)
+}
+
+// edit はソースのバイト範囲 [offset, end) の置き換え
+type edit struct {
+	offset, end int
+	text        string
+	line        int
+	rule        Rule
+}
+
+// Migrate はソースに全ての規則を適用し、書き換えたソースと変更の一覧（行順）を返す
+// 変更がない場合はソースをそのまま返す。
+func Migrate(source string) (string, []Change) {
+	var changes []Change
+	for _, rule := range []func(string, []lexer.Token) []edit{
+		fullWidthSpaces,
+		legacyBuiltinNames,
+		forSemicolons,
+	} {
+		tokens, _ := lexer.NewWithComments(source).TokenizeWithErrors()
+		edits := rule(source, tokens)
+		for _, e := range edits {
+			changes = append(changes, Change{Line: e.line, Rule: e.rule, Old: source[e.offset:e.end], New: e.text})
+		}
+		source = apply(source, edits)
+	}
+	sort.SliceStable(changes, func(i, j int) bool { return changes[i].Line < changes[j].Line })
+	return source, changes
+}
+
+// apply はソースに置き換えを適用する（edits は重ならず、位置の順に並んでいること）
+func apply(source string, edits []edit) string {
+	if len(edits) == 0 {
+		return source
+	}
+	var sb strings.Builder
+	pos := 0
+	for _, e := range edits {
+		sb.WriteString(source[pos:e.offset])
+		sb.WriteString(e.text)
+		pos = e.end
+	}
+	sb.WriteString(source[pos:])
+	return sb.String()
+}
+
+// protected はそのまま残すトークン（文字列・コメント・プリプロセッサ指令）かを返す
+func protected(tok lexer.Token) bool {
+	switch tok.Type {
+	case lexer.TOKEN_STRING, lexer.TOKEN_COMMENT,
+		lexer.TOKEN_DIRECTIVE, lexer.TOKEN_INFO, lexer.TOKEN_INCLUDE, lexer.TOKEN_DEFINE:
+		return true
+	}
+	return false
+}
+
+// fullWidthSpaces は文字列・コメント・プリプロセッサ指令の外にある全角スペースを半角スペースにする
+// オリジナルのFILLYは全角スペースを空白として扱うが、son-et の字句解析では不正な文字になる。
+// インデントの幅が変わらないよう、全角スペース1つを半角スペース2つにする。
+func fullWidthSpaces(source string, tokens []lexer.Token) []edit {
+	var edits []edit
+	pos, line := 0, 1
+	scan := func(end int) {
+		for pos < end {
+			i := strings.Index(source[pos:end], fullWidthSpace)
+			if i < 0 {
+				line += strings.Count(source[pos:end], "\n")
+				pos = end
+				return
+			}
+			line += strings.Count(source[pos:pos+i], "\n")
+			pos += i
+			edits = append(edits, edit{offset: pos, end: pos + len(fullWidthSpace), text: "  ", line: line, rule: RuleFullWidthSpace})
+			pos += len(fullWidthSpace)
+		}
+	}
+	for _, tok := range tokens {
+		if !protected(tok) || tok.Offset < pos {
+			continue
+		}
+		scan(tok.Offset)
+		line += strings.Count(source[tok.Offset:tok.End], "\n")
+		pos = tok.End
+	}
+	scan(len(source))
+	return edits
+}
+
+// legacyBuiltinNames は組み込み関数の旧表記での呼び出しを現在の名前にする
+func legacyBuiltinNames(source string, tokens []lexer.Token) []edit {
+	defined := definedFunctions(tokens)
+	var edits []edit
+	for i, tok := range tokens {
+		if tok.Type != lexer.TOKEN_IDENT || i+1 >= len(tokens) || tokens[i+1].Type != lexer.TOKEN_LPAREN {
+			continue
+		}
+		key := strings.ToLower(tok.Literal)
+		name, ok := legacyBuiltins[key]
+		if !ok || defined[key] {
+			continue
+		}
+		edits = append(edits, edit{offset: tok.Offset, end: tok.End, text: name, line: tok.Line, rule: RuleLegacyBuiltin})
+	}
+	return edits
+}
+
+// definedFunctions はスクリプトで定義されている関数の名前（小文字）を返す
+// 「名前(...) {」の形をした箇所を関数の定義とみなす。
+func definedFunctions(tokens []lexer.Token) map[string]bool {
+	defined := make(map[string]bool)
+	for i, tok := range tokens {
+		if tok.Type != lexer.TOKEN_IDENT || i+1 >= len(tokens) || tokens[i+1].Type != lexer.TOKEN_LPAREN {
+			continue
+		}
+		closing := matchingParen(tokens, i+1)
+		if closing >= 0 && closing+1 < len(tokens) && tokens[closing+1].Type == lexer.TOKEN_LBRACE {
+			defined[strings.ToLower(tok.Literal)] = true
+		}
+	}
+	return defined
+}
+
+// matchingParen は tokens[open]（左丸括弧）に対応する右丸括弧の位置を返す（ない場合は -1）
+func matchingParen(tokens []lexer.Token, open int) int {
+	depth := 0
+	for i := open; i < len(tokens); i++ {
+		switch tokens[i].Type {
+		case lexer.TOKEN_LPAREN:
+			depth++
+		case lexer.TOKEN_RPAREN:
+			depth--
+			if depth == 0 {
+				return i
+			}
+		case lexer.TOKEN_LBRACE, lexer.TOKEN_RBRACE, lexer.TOKEN_EOF:
+			return -1
+		}
+	}
+	return -1
+}
+
+// forSemicolons は for の見出しで抜けているセミコロンを補う
+// オリジナルのFILLYは for(i=0 i<10 i=i+1) のように区切りのない見出しも受け付けた。
+// 見出しの中で値（名前・数値・文字列・閉じ括弧）の直後に値の始まり（名前・数値・文字列・!）が
+// 続く箇所を文の区切りとみなし、区切りがちょうど足りない数だけ見つかった場合だけ補う。
+func forSemicolons(source string, tokens []lexer.Token) []edit {
+	var edits []edit
+	for i, tok := range tokens {
+		if tok.Type != lexer.TOKEN_FOR {
+			continue
+		}
+		open := nextCode(tokens, i+1)
+		if open < 0 || tokens[open].Type != lexer.TOKEN_LPAREN {
+			continue
+		}
+		closing := matchingParen(tokens, open)
+		if closing < 0 {
+			continue
+		}
+
+		semicolons := 0
+		var gaps []int // 区切りとみなす箇所の直前のトークン
+		depth, prev := 0, -1
+		for j := open + 1; j < closing; j++ {
+			t := tokens[j]
+			if t.Type == lexer.TOKEN_COMMENT {
+				continue
+			}
+			if depth == 0 && prev >= 0 && endsValue(tokens[prev]) && startsValue(t) {
+				gaps = append(gaps, prev)
+			}
+			switch t.Type {
+			case lexer.TOKEN_LPAREN, lexer.TOKEN_LBRACKET:
+				depth++
+			case lexer.TOKEN_RPAREN, lexer.TOKEN_RBRACKET:
+				depth--
+			case lexer.TOKEN_SEMICOLON:
+				if depth == 0 {
+					semicolons++
+				}
+			}
+			prev = j
+		}
+		if semicolons >= 2 || semicolons+len(gaps) != 2 {
+			continue
+		}
+		for _, g := range gaps {
+			end := tokens[g].End
+			edits = append(edits, edit{offset: end, end: end, text: ";", line: tokens[g].Line, rule: RuleForSemicolon})
+		}
+	}
+	return edits
+}
+
+// nextCode は i 以降で最初のコメントでないトークンの位置を返す（ない場合は -1）
+func nextCode(tokens []lexer.Token, i int) int {
+	for ; i < len(tokens); i++ {
+		if tokens[i].Type != lexer.TOKEN_COMMENT {
+			return i
+		}
+	}
+	return -1
+}
+
+// endsValue はトークンが値の終わりになりうるかを返す
+func endsValue(tok lexer.Token) bool {
+	switch tok.Type {
+	case lexer.TOKEN_IDENT, lexer.TOKEN_INT, lexer.TOKEN_FLOAT, lexer.TOKEN_STRING,
+		lexer.TOKEN_RPAREN, lexer.TOKEN_RBRACKET:
+		return true
+	}
+	return false
+}
+
+// startsValue はトークンが値（文）の始まりになりうるかを返す
+// 単項の - は二項演算子と区別できないため含めない。
+func startsValue(tok lexer.Token) bool {
+	switch tok.Type {
+	case lexer.TOKEN_IDENT, lexer.TOKEN_INT, lexer.TOKEN_FLOAT, lexer.TOKEN_STRING, lexer.TOKEN_NOT:
+		return true
+	}
+	return false
+}
+
+// UnifiedDiff は before から after への変更を unified diff 形式で返す（変更がない場合は空文字列）
+// name はヘッダーに示すファイル名。Migrate は行を増減しないので、行ごとに比較する。
+// 行数が異なる場合は全体を1つのハンクとして示す。
+func UnifiedDiff(name, before, after string) string {
+	if before == after {
+		return ""
+	}
+	a := splitLines(before)
+	b := splitLines(after)
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "--- %s\n+++ %s\n", name, name)
+	if len(a) != len(b) {
+		writeHunk(&sb, a, b, 0, len(a), 0, len(b))
+		return sb.String()
+	}
+
+	// 変更のある行を前後 diffContext 行の文脈ごとにまとめ、文脈が重なるハンクはつなげる
+	start, end := -1, -1
+	for i := range a {
+		if a[i] == b[i] {
+			continue
+		}
+		from, to := max(0, i-diffContext), min(len(a), i+1+diffContext)
+		if start >= 0 && from > end {
+			writeAlignedHunk(&sb, a, b, start, end)
+			start = -1
+		}
+		if start < 0 {
+			start = from
+		}
+		end = to
+	}
+	if start >= 0 {
+		writeAlignedHunk(&sb, a, b, start, end)
+	}
+	return sb.String()
+}
+
+// diffContext は unified diff で変更の前後に示す行数
+const diffContext = 3
+
+// splitLines はテキストを行（改行を含む）に分ける
+func splitLines(s string) []string {
+	if s == "" {
+		return nil
+	}
+	return strings.SplitAfter(strings.TrimSuffix(s, "\n"), "\n")
+}
+
+// writeAlignedHunk は行数が同じ a と b の [start, end) の行のハンクを書き出す
+func writeAlignedHunk(sb *strings.Builder, a, b []string, start, end int) {
+	fmt.Fprintf(sb, "@@ -%d,%d +%d,%d @@\n", start+1, end-start, start+1, end-start)
+	for i := start; i < end; {
+		if a[i] == b[i] {
+			writeLine(sb, ' ', a[i])
+			i++
+			continue
+		}
+		// 連続する変更行は削除をまとめてから追加をまとめて示す
+		j := i
+		for j < end && a[j] != b[j] {
+			j++
+		}
+		for k := i; k < j; k++ {
+			writeLine(sb, '-', a[k])
+		}
+		for k := i; k < j; k++ {
+			writeLine(sb, '+', b[k])
+		}
+		i = j
+	}
+}
+
+// writeHunk は a[aStart:aEnd] を b[bStart:bEnd] に置き換えるハンクを書き出す
+func writeHunk(sb *strings.Builder, a, b []string, aStart, aEnd, bStart, bEnd int) {
+	fmt.Fprintf(sb, "@@ -%d,%d +%d,%d @@\n", aStart+1, aEnd-aStart, bStart+1, bEnd-bStart)
+	for _, line := range a[aStart:aEnd] {
+		writeLine(sb, '-', line)
+	}
+	for _, line := range b[bStart:bEnd] {
+		writeLine(sb, '+', line)
+	}
+}
+
+// writeLine は diff の1行を書き出す（改行のない最終行にも改行を補う）
+func writeLine(sb *strings.Builder, prefix byte, line string) {
+	sb.WriteByte(prefix)
+	sb.WriteString(strings.TrimSuffix(strings.TrimSuffix(line, "\n"), "\r"))
+	sb.WriteByte('\n')
+}
@@ -0,0 +1,181 @@
+package migrate
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/zurustar/son-et/pkg/compiler/lexer"
+	"github.com/zurustar/son-et/pkg/compiler/parser"
+)
+
+func TestMigrate(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+		rules    []Rule
+	}{
+		{
+			name:     "full-width spaces between tokens",
+			input:    "main()\n{\n　　x　= 1\n}\n",
+			expected: "main()\n{\n    x  = 1\n}\n",
+			rules:    []Rule{RuleFullWidthSpace, RuleFullWidthSpace, RuleFullWidthSpace},
+		},
+		{
+			name:     "full-width spaces in strings and comments are kept",
+			input:    "s = \"あ　い\" // コメント　です\n/* 　 */ x = 1\n",
+			expected: "s = \"あ　い\" // コメント　です\n/* 　 */ x = 1\n",
+		},
+		{
+			name:     "full-width spaces in #info are kept",
+			input:    "#info INAM \"タイトル　名\"\nx　= 1\n",
+			expected: "#info INAM \"タイトル　名\"\nx  = 1\n",
+			rules:    []Rule{RuleFullWidthSpace},
+		},
+		{
+			name:     "legacy built-in spellings",
+			input:    "PlayWAV(\"A.WAV\")\nplaymid(\"B.MID\")\n",
+			expected: "PlayWAVE(\"A.WAV\")\nPlayMIDI(\"B.MID\")\n",
+			rules:    []Rule{RuleLegacyBuiltin, RuleLegacyBuiltin},
+		},
+		{
+			name:     "script functions with a legacy name are not renamed",
+			input:    "PlayWAV(f) {\n}\nPlayWAV(\"A.WAV\")\n",
+			expected: "PlayWAV(f) {\n}\nPlayWAV(\"A.WAV\")\n",
+		},
+		{
+			name:     "variables with a legacy name are not renamed",
+			input:    "playmid = 1\n",
+			expected: "playmid = 1\n",
+		},
+		{
+			name:     "missing for semicolons",
+			input:    "for(i=0 i<10 i=i+1) {\n}\n",
+			expected: "for(i=0; i<10; i=i+1) {\n}\n",
+			rules:    []Rule{RuleForSemicolon, RuleForSemicolon},
+		},
+		{
+			name:     "one missing for semicolon",
+			input:    "for (i = 0; i < n[2] i = i + 1) {\n}\n",
+			expected: "for (i = 0; i < n[2]; i = i + 1) {\n}\n",
+			rules:    []Rule{RuleForSemicolon},
+		},
+		{
+			name:     "complete for headers are kept",
+			input:    "for (i = 0; i < Max(a, b); i = i + 1) {\n}\n",
+			expected: "for (i = 0; i < Max(a, b); i = i + 1) {\n}\n",
+		},
+		{
+			name:     "ambiguous for headers are kept",
+			input:    "for (i = 0 i < 10 - j) {\n}\n",
+			expected: "for (i = 0 i < 10 - j) {\n}\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, changes := Migrate(tt.input)
+			if got != tt.expected {
+				t.Errorf("Migrate(%q)\n got: %q\nwant: %q", tt.input, got, tt.expected)
+			}
+			var rules []Rule
+			for _, c := range changes {
+				rules = append(rules, c.Rule)
+			}
+			if !reflect.DeepEqual(rules, tt.rules) {
+				t.Errorf("rules = %v, want %v", rules, tt.rules)
+			}
+		})
+	}
+}
+
+// TestMigrateParses tests that a legacy script the parser rejects parses after migration,
+// and that the changes are reported on the lines of the original source.
+func TestMigrateParses(t *testing.T) {
+	input := "main() {\n" +
+		"　for(i=0 i<3 i=i+1) {\n" +
+		"　　PlayWAV(\"A.WAV\")\n" +
+		"　}\n" +
+		"}\n"
+	if _, errs := parser.New(lexer.New(input)).ParseProgram(); len(errs) == 0 {
+		t.Fatal("the legacy script should not parse before migration")
+	}
+
+	got, changes := Migrate(input)
+	if _, errs := parser.New(lexer.New(got)).ParseProgram(); len(errs) > 0 {
+		t.Fatalf("migrated script has errors: %v\n%s", errs, got)
+	}
+
+	var lines []string
+	for _, c := range changes {
+		lines = append(lines, c.String())
+	}
+	want := []string{
+		`2: full-width-space: "\u3000" -> "  "`,
+		`2: for-semicolon: "" -> ";"`,
+		`2: for-semicolon: "" -> ";"`,
+		`3: full-width-space: "\u3000" -> "  "`,
+		`3: full-width-space: "\u3000" -> "  "`,
+		`3: legacy-builtin: "PlayWAV" -> "PlayWAVE"`,
+		`4: full-width-space: "\u3000" -> "  "`,
+	}
+	if !reflect.DeepEqual(lines, want) {
+		t.Errorf("changes =\n%s\nwant\n%s", strings.Join(lines, "\n"), strings.Join(want, "\n"))
+	}
+}
+
+func TestMigrateKeepsCRLF(t *testing.T) {
+	got, _ := Migrate("x　= 1\r\nPlayMID(\"A.MID\")\r\n")
+	if got != "x  = 1\r\nPlayMIDI(\"A.MID\")\r\n" {
+		t.Errorf("Migrate = %q", got)
+	}
+}
+
+func TestUnifiedDiff(t *testing.T) {
+	if got := UnifiedDiff("A.TFY", "x = 1\n", "x = 1\n"); got != "" {
+		t.Errorf("diff of the same text = %q, want empty", got)
+	}
+
+	var before, after []string
+	for i := 1; i <= 20; i++ {
+		line := "line" + strings.Repeat("x", i)
+		before = append(before, line)
+		switch i {
+		case 2, 3, 16:
+			line += " changed"
+		}
+		after = append(after, line)
+	}
+	got := UnifiedDiff("A.TFY", strings.Join(before, "\n")+"\n", strings.Join(after, "\n")+"\n")
+	want := "--- A.TFY\n+++ A.TFY\n" +
+		"@@ -1,6 +1,6 @@\n" +
+		" linex\n" +
+		"-linexx\n" +
+		"-linexxx\n" +
+		"+linexx changed\n" +
+		"+linexxx changed\n" +
+		" linexxxx\n" +
+		" linexxxxx\n" +
+		" linexxxxxx\n" +
+		"@@ -13,7 +13,7 @@\n" +
+		" " + before[12] + "\n" +
+		" " + before[13] + "\n" +
+		" " + before[14] + "\n" +
+		"-" + before[15] + "\n" +
+		"+" + after[15] + "\n" +
+		" " + before[16] + "\n" +
+		" " + before[17] + "\n" +
+		" " + before[18] + "\n"
+	if got != want {
+		t.Errorf("UnifiedDiff =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestUnifiedDiffDifferentLineCounts(t *testing.T) {
+	got := UnifiedDiff("A.TFY", "a\nb\n", "a\n")
+	want := "--- A.TFY\n+++ A.TFY\n@@ -1,2 +1,1 @@\n-a\n-b\n+a\n"
+	if got != want {
+		t.Errorf("UnifiedDiff = %q, want %q", got, want)
+	}
+}
@@ -25,6 +25,7 @@ type Expression interface {
 // Program is the root node of the AST.
 type Program struct {
 	Statements []Statement
+	Comments   []lexer.Token // comments in source order (only when lexed with lexer.NewWithComments)
 }
 
 // TokenLiteral returns the literal value of the first statement's token.
@@ -188,6 +189,20 @@ type CaseClause struct {
 func (cc *CaseClause) statementNode()       {}
 func (cc *CaseClause) TokenLiteral() string { return cc.Token.Literal }
 
+// TryStatement represents a try statement.
+// Example: try { body } catch (err) { handler }
+// An error in the body skips the rest of the body and runs the catch block
+// with the error message in ErrorVar. The catch clause and its variable are optional.
+type TryStatement struct {
+	Token    lexer.Token
+	Body     *BlockStatement
+	ErrorVar *Identifier     // nil if catch has no variable
+	Catch    *BlockStatement // nil if catch is omitted
+}
+
+func (ts *TryStatement) statementNode()       {}
+func (ts *TryStatement) TokenLiteral() string { return ts.Token.Literal }
+
 // MesStatement represents a mes (event handler) statement.
 // Example: mes(MIDI_TIME) { body }
 type MesStatement struct {
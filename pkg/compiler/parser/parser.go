@@ -7,6 +7,7 @@ import (
 	"strconv"
 	"strings"
 
+	"github.com/zurustar/son-et/pkg/compat"
 	"github.com/zurustar/son-et/pkg/compiler/lexer"
 )
 
@@ -81,6 +82,15 @@ type Parser struct {
 	errors []*ParserError
 	source string // original source code for error context
 
+	// recovering is set after a syntax error until the parser resynchronizes at the
+	// next statement boundary; further syntax errors are suppressed in the meantime
+	// because they are usually consequences of the first one.
+	recovering bool
+
+	comments []lexer.Token // comments retained by the lexer, in source order
+
+	compat compat.Mode // compatibility mode (filly97 rejects son-et's syntax extensions)
+
 	// Pratt parser function maps
 	prefixParseFns map[lexer.TokenType]prefixParseFn
 	infixParseFns  map[lexer.TokenType]infixParseFn
@@ -95,9 +105,16 @@ func New(l *lexer.Lexer) *Parser {
 		infixParseFns:  make(map[lexer.TokenType]infixParseFn),
 	}
 
-	// Tokenize all input
+	// Tokenize all input. Comments (returned only by lexer.NewWithComments)
+	// are retained separately so they do not interfere with parsing.
 	tokens, _ := l.Tokenize()
-	p.tokens = tokens
+	for _, tok := range tokens {
+		if tok.Type == lexer.TOKEN_COMMENT {
+			p.comments = append(p.comments, tok)
+			continue
+		}
+		p.tokens = append(p.tokens, tok)
+	}
 
 	// Surface non-token lexical errors (unterminated string/comment) as parser
 	// errors so they are not silently discarded. ILLEGAL characters are not
@@ -226,19 +243,97 @@ func (p *Parser) addErrorAtCurrent(msg string) {
 	p.addError(msg, tok.Line, tok.Column)
 }
 
+// syntaxError adds an error for a token sequence the parser cannot make sense of and
+// enters recovery mode (see synchronize). While recovering, errors are not recorded.
+func (p *Parser) syntaxError(msg string, line, column int) {
+	if p.recovering {
+		return
+	}
+	p.addError(msg, line, column)
+	p.recovering = true
+}
+
 // peekError adds an error for unexpected token type.
 // Requirement 5.2: Parser reports syntax errors with expected/actual token types, line, and column.
 func (p *Parser) peekError(t lexer.TokenType) {
 	tok := p.peekToken()
 	msg := fmt.Sprintf("expected %s, got %s", t.String(), tok.Type.String())
-	p.addError(msg, tok.Line, tok.Column)
+	p.syntaxError(msg, tok.Line, tok.Column)
 }
 
 // noPrefixParseFnError adds an error for missing prefix parse function.
 func (p *Parser) noPrefixParseFnError(t lexer.TokenType) {
 	tok := p.curToken()
 	msg := fmt.Sprintf("no prefix parse function for %s found", t.String())
-	p.addError(msg, tok.Line, tok.Column)
+	p.syntaxError(msg, tok.Line, tok.Column)
+}
+
+// statementStarts is the set of tokens that begin a statement. After a syntax error,
+// such a token at the beginning of a line is taken as the start of the next statement.
+var statementStarts = map[lexer.TokenType]bool{
+	lexer.TOKEN_IDENT:     true,
+	lexer.TOKEN_INT_TYPE:  true,
+	lexer.TOKEN_STR_TYPE:  true,
+	lexer.TOKEN_REAL_TYPE: true,
+	lexer.TOKEN_IF:        true,
+	lexer.TOKEN_FOR:       true,
+	lexer.TOKEN_WHILE:     true,
+	lexer.TOKEN_SWITCH:    true,
+	lexer.TOKEN_MES:       true,
+	lexer.TOKEN_STEP:      true,
+	lexer.TOKEN_BREAK:     true,
+	lexer.TOKEN_CONTINUE:  true,
+	lexer.TOKEN_RETURN:    true,
+	lexer.TOKEN_DEL_ME:    true,
+	lexer.TOKEN_DEL_US:    true,
+	lexer.TOKEN_DEL_ALL:   true,
+	lexer.TOKEN_END_STEP:  true,
+	lexer.TOKEN_CASE:      true,
+	lexer.TOKEN_DEFAULT:   true,
+	lexer.TOKEN_INFO:      true,
+	lexer.TOKEN_INCLUDE:   true,
+	lexer.TOKEN_DEFINE:    true,
+	lexer.TOKEN_DIRECTIVE: true,
+}
+
+// synchronize leaves recovery mode by skipping the rest of the statement in which a
+// syntax error occurred, so that parsing continues with the next statement and a
+// single run reports every syntax problem in the file instead of a cascade from the first.
+//
+// The parser stops on the current token when it is a semicolon, or before a closing
+// brace that ends the enclosing block, or before a statement keyword or identifier at
+// the beginning of a later line. Parentheses and braces opened while skipping are
+// skipped as a whole. Callers advance past the current token as after any statement.
+func (p *Parser) synchronize() {
+	p.recovering = false
+	depth := 0
+	for {
+		cur, peek := p.curToken(), p.peekToken()
+		switch cur.Type {
+		case lexer.TOKEN_EOF:
+			return
+		case lexer.TOKEN_LPAREN, lexer.TOKEN_LBRACE:
+			depth++
+		case lexer.TOKEN_RPAREN, lexer.TOKEN_RBRACE:
+			depth = max(depth-1, 0)
+		case lexer.TOKEN_SEMICOLON:
+			if depth == 0 {
+				return
+			}
+		}
+		if peek.Type == lexer.TOKEN_EOF {
+			return
+		}
+		if depth == 0 {
+			if peek.Type == lexer.TOKEN_RBRACE {
+				return
+			}
+			if peek.Line > cur.Line && statementStarts[peek.Type] {
+				return
+			}
+		}
+		p.nextToken()
+	}
 }
 
 // ============================================================================
@@ -251,6 +346,7 @@ func (p *Parser) noPrefixParseFnError(t lexer.TokenType) {
 func (p *Parser) ParseProgram() (*Program, []error) {
 	program := &Program{
 		Statements: []Statement{},
+		Comments:   p.comments,
 	}
 
 	for !p.curTokenIs(lexer.TOKEN_EOF) {
@@ -258,6 +354,9 @@ func (p *Parser) ParseProgram() (*Program, []error) {
 		if stmt != nil {
 			program.Statements = append(program.Statements, stmt)
 		}
+		if p.recovering {
+			p.synchronize()
+		}
 		p.nextToken()
 	}
 
@@ -313,6 +412,9 @@ func (p *Parser) parseStatement() Statement {
 	case lexer.TOKEN_LBRACE:
 		return p.parseBlockStatement()
 	case lexer.TOKEN_IDENT:
+		if isContextualKeyword(p.curToken(), "try") && p.peekTokenIs(lexer.TOKEN_LBRACE) {
+			return p.parseTryStatement()
+		}
 		return p.parseIdentifierStatement()
 	// Requirement 9.5: del_me, del_us, del_all are treated as function calls
 	case lexer.TOKEN_DEL_ME, lexer.TOKEN_DEL_US, lexer.TOKEN_DEL_ALL:
@@ -547,6 +649,9 @@ func (p *Parser) parseBlockStatement() *BlockStatement {
 		if stmt != nil {
 			block.Statements = append(block.Statements, stmt)
 		}
+		if p.recovering {
+			p.synchronize()
+		}
 		p.nextToken()
 	}
 
@@ -589,7 +694,8 @@ func (p *Parser) parseIdentifier() Expression {
 }
 
 // parseIntegerLiteral parses an integer literal expression.
-// Requirement 2.4: Integer literals (decimal or 0x-prefixed hexadecimal).
+// Requirement 2.4: Integer literals (decimal, 0x-prefixed hexadecimal, 0b-prefixed binary
+// or a character literal such as 'A').
 func (p *Parser) parseIntegerLiteral() Expression {
 	lit := &IntegerLiteral{Token: p.curToken()}
 
@@ -597,10 +703,18 @@ func (p *Parser) parseIntegerLiteral() Expression {
 	var value int64
 	var err error
 
-	// Check for hexadecimal
-	if strings.HasPrefix(strings.ToLower(literal), "0x") {
+	// Check for hexadecimal, binary and character literals
+	switch {
+	case strings.HasPrefix(strings.ToLower(literal), "0x"):
 		value, err = strconv.ParseInt(literal[2:], 16, 64)
-	} else {
+	case strings.HasPrefix(strings.ToLower(literal), "0b"):
+		value, err = strconv.ParseInt(literal[2:], 2, 64)
+	case strings.HasPrefix(literal, "'"):
+		if value, err = lexer.CharLiteralValue(literal); err != nil {
+			p.addError(err.Error(), p.curToken().Line, p.curToken().Column)
+			return nil
+		}
+	default:
 		value, err = strconv.ParseInt(literal, 10, 64)
 	}
 
@@ -615,11 +729,25 @@ func (p *Parser) parseIntegerLiteral() Expression {
 	return lit
 }
 
+// SetCompatMode sets the compatibility mode. Call it before ParseProgram.
+// In compat.FILLY97 mode, floating point literals and mes() blocks for
+// son-et's extension events are reported as errors.
+func (p *Parser) SetCompatMode(mode compat.Mode) {
+	p.compat = mode
+}
+
 // parseFloatLiteral parses a floating point literal expression.
 // Requirement 2.5: Floating point literals.
 func (p *Parser) parseFloatLiteral() Expression {
 	lit := &FloatLiteral{Token: p.curToken()}
 
+	if p.compat.Strict() {
+		tok := p.curToken()
+		msg := fmt.Sprintf("floating point literal %s is not supported in %s mode", tok.Literal, p.compat)
+		p.addError(msg, tok.Line, tok.Column)
+		return nil
+	}
+
 	value, err := strconv.ParseFloat(p.curToken().Literal, 64)
 	if err != nil {
 		tok := p.curToken()
@@ -691,7 +819,7 @@ func (p *Parser) parseCallExpression(function Expression) Expression {
 	if !ok {
 		tok := p.curToken()
 		msg := "expected identifier for function call"
-		p.addError(msg, tok.Line, tok.Column)
+		p.syntaxError(msg, tok.Line, tok.Column)
 		return nil
 	}
 
@@ -1171,6 +1299,54 @@ func (p *Parser) parseWhileStatement() Statement {
 	return stmt
 }
 
+// isContextualKeyword reports whether tok is the identifier word, which is a keyword only
+// in some positions. try and catch are lexed as identifiers so that existing scripts can
+// keep using them as variable and function names.
+func isContextualKeyword(tok lexer.Token, word string) bool {
+	return tok.Type == lexer.TOKEN_IDENT && strings.EqualFold(tok.Literal, word)
+}
+
+// parseTryStatement parses a try statement.
+// try { body } catch (err) { handler } - the catch clause and its variable are optional.
+// Unlike if and while, the blocks must have braces. try starts a try statement only when
+// followed by {, and catch is a catch clause only right after the try block.
+func (p *Parser) parseTryStatement() Statement {
+	tok := p.curToken()
+	tok.Type = lexer.TOKEN_TRY
+	stmt := &TryStatement{Token: tok}
+	if p.compat.Strict() {
+		p.addError(fmt.Sprintf("try statement is not supported in %s mode", p.compat), tok.Line, tok.Column)
+	}
+
+	if !p.expectPeek(lexer.TOKEN_LBRACE) {
+		return nil
+	}
+	stmt.Body = p.parseBlockStatement()
+
+	if !isContextualKeyword(p.peekToken(), "catch") {
+		return stmt
+	}
+	p.nextToken() // move to catch
+
+	if p.peekTokenIs(lexer.TOKEN_LPAREN) {
+		p.nextToken()
+		if !p.expectPeek(lexer.TOKEN_IDENT) {
+			return nil
+		}
+		stmt.ErrorVar = &Identifier{Token: p.curToken(), Value: p.curToken().Literal}
+		if !p.expectPeek(lexer.TOKEN_RPAREN) {
+			return nil
+		}
+	}
+
+	if !p.expectPeek(lexer.TOKEN_LBRACE) {
+		return nil
+	}
+	stmt.Catch = p.parseBlockStatement()
+
+	return stmt
+}
+
 // parseSwitchStatement parses a switch statement.
 // Requirement 3.10: Switch statements with value, case clauses, and optional default.
 // TODO: 詳細はdocs/unimplemented-features.mdを参照
@@ -1215,6 +1391,9 @@ func (p *Parser) parseSwitchStatement() Statement {
 				if s != nil {
 					stmt.Default.Statements = append(stmt.Default.Statements, s)
 				}
+				if p.recovering {
+					p.synchronize()
+				}
 				p.nextToken()
 			}
 		} else {
@@ -1244,6 +1423,9 @@ func (p *Parser) parseCaseClause() *CaseClause {
 		if stmt != nil {
 			clause.Body = append(clause.Body, stmt)
 		}
+		if p.recovering {
+			p.synchronize()
+		}
 		p.nextToken()
 	}
 
@@ -1265,6 +1447,11 @@ func (p *Parser) parseMesStatement() Statement {
 		return nil
 	}
 	stmt.EventType = p.curToken().Literal
+	if p.compat.Strict() && compat.IsExtensionEvent(stmt.EventType) {
+		tok := p.curToken()
+		msg := fmt.Sprintf("event type %s is not supported in %s mode", stmt.EventType, p.compat)
+		p.addError(msg, tok.Line, tok.Column)
+	}
 
 	if !p.expectPeek(lexer.TOKEN_RPAREN) {
 		return nil
@@ -1378,6 +1565,11 @@ func (p *Parser) parseStepBody() *StepBody {
 
 		// Parse statement
 		stmt := p.parseStatement()
+		if p.recovering {
+			p.synchronize()
+			p.nextToken()
+			continue
+		}
 		if stmt == nil {
 			// parseStatement returned nil (e.g., hit } after skipping semicolons)
 			continue
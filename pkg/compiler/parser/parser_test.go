@@ -2,8 +2,11 @@
 package parser
 
 import (
+	"slices"
+	"strings"
 	"testing"
 
+	"github.com/zurustar/son-et/pkg/compat"
 	"github.com/zurustar/son-et/pkg/compiler/lexer"
 )
 
@@ -66,6 +69,12 @@ func TestParseIntegerLiteral(t *testing.T) {
 		{"0x10", 16},
 		{"0xFF", 255},
 		{"0xABCD", 43981},
+		{"0b1010", 10},
+		{"0B11", 3},
+		{"'A'", 65},
+		{`'\n'`, 10},
+		{`'\''`, 39},
+		{"'あ'", 0x3042},
 	}
 
 	for _, tt := range tests {
@@ -101,6 +110,16 @@ func TestParseIntegerLiteral(t *testing.T) {
 	}
 }
 
+// TestParseInvalidCharLiteral tests that a character literal must contain exactly one character.
+func TestParseInvalidCharLiteral(t *testing.T) {
+	for _, input := range []string{"x = ''", "x = 'AB'", `x = '\q'`} {
+		_, errs := New(lexer.New(input)).ParseProgram()
+		if len(errs) == 0 || !strings.Contains(errs[0].Error(), "character literal") {
+			t.Errorf("input %q: expected a character literal error, got %v", input, errs)
+		}
+	}
+}
+
 // TestParseStringLiteral tests parsing string literals.
 func TestParseStringLiteral(t *testing.T) {
 	input := `"hello world"`
@@ -702,6 +721,96 @@ func TestParseWhileStatement(t *testing.T) {
 	}
 }
 
+// TestParseTryStatement tests parsing try statements with and without a catch clause.
+func TestParseTryStatement(t *testing.T) {
+	tests := []struct {
+		name         string
+		input        string
+		wantVar      string
+		wantCatch    bool
+		wantBodyLen  int
+		wantCatchLen int
+	}{
+		{"catch with variable", `try { p = LoadPic("a.bmp"); x = 1; } catch (e) { p = -1; }`, "e", true, 2, 1},
+		{"catch without variable", `try { PlayMIDI("a.mid"); } catch { x = 0; }`, "", true, 1, 1},
+		{"no catch", `try { PlayMIDI("a.mid"); }`, "", false, 1, 0},
+		{"uppercase", `TRY { PlayMIDI("a.mid"); } CATCH (Err) { }`, "Err", true, 1, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			program, errs := New(lexer.New(tt.input)).ParseProgram()
+			if len(errs) > 0 {
+				t.Fatalf("unexpected errors: %v", errs)
+			}
+			if len(program.Statements) != 1 {
+				t.Fatalf("expected 1 statement, got %d", len(program.Statements))
+			}
+			stmt, ok := program.Statements[0].(*TryStatement)
+			if !ok {
+				t.Fatalf("expected TryStatement, got %T", program.Statements[0])
+			}
+			if len(stmt.Body.Statements) != tt.wantBodyLen {
+				t.Errorf("body has %d statements, want %d", len(stmt.Body.Statements), tt.wantBodyLen)
+			}
+			if (stmt.Catch != nil) != tt.wantCatch {
+				t.Fatalf("Catch = %v, want present: %v", stmt.Catch, tt.wantCatch)
+			}
+			if stmt.Catch != nil && len(stmt.Catch.Statements) != tt.wantCatchLen {
+				t.Errorf("catch has %d statements, want %d", len(stmt.Catch.Statements), tt.wantCatchLen)
+			}
+			gotVar := ""
+			if stmt.ErrorVar != nil {
+				gotVar = stmt.ErrorVar.Value
+			}
+			if gotVar != tt.wantVar {
+				t.Errorf("ErrorVar = %q, want %q", gotVar, tt.wantVar)
+			}
+		})
+	}
+}
+
+// TestParseTryCatchAsIdentifiers tests that try and catch are still usable as variable and
+// function names, as in scripts written before the try statement existed.
+func TestParseTryCatchAsIdentifiers(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+	}{
+		{"variable", "int try;\ntry = 3;\nTry = try + 1;"},
+		{"function", "Catch(x) {\n  catch = x;\n}\nmain() {\n  Catch(1);\n  CATCH(try);\n}"},
+	}
+	for _, tt := range tests {
+		for _, mode := range []compat.Mode{compat.Extended, compat.FILLY97} {
+			t.Run(tt.name+"/"+mode.String(), func(t *testing.T) {
+				p := New(lexer.New(tt.input))
+				p.SetCompatMode(mode)
+				program, errs := p.ParseProgram()
+				if len(errs) > 0 {
+					t.Fatalf("unexpected errors: %v", errs)
+				}
+				for _, stmt := range program.Statements {
+					if _, ok := stmt.(*TryStatement); ok {
+						t.Error("try and catch should be parsed as identifiers")
+					}
+				}
+			})
+		}
+	}
+}
+
+// TestParseTryStatementErrors tests syntax errors in try statements.
+func TestParseTryStatementErrors(t *testing.T) {
+	for _, input := range []string{
+		`try { x = 1; } catch (1) { }`,
+		`try { x = 1; } catch (e { }`,
+		`try { x = 1; } catch x = 0;`,
+	} {
+		if _, errs := New(lexer.New(input)).ParseProgram(); len(errs) == 0 {
+			t.Errorf("%q: expected a syntax error", input)
+		}
+	}
+}
+
 // TestParseFunctionDefinition tests parsing function definitions.
 // Requirement 3.4: Function definitions (name(params){body}) create FunctionStatement nodes
 // Requirement 9.9: Function definitions without 'function' keyword
@@ -4248,3 +4357,131 @@ func TestSwitchEdgeCases(t *testing.T) {
 		}
 	})
 }
+
+// TestParseProgramRetainsComments tests that comments are kept on the program with their positions.
+func TestParseProgramRetainsComments(t *testing.T) {
+	input := "// header\nmain() {\n  x = 1 /* inline */\n}\n"
+
+	p := New(lexer.NewWithComments(input))
+	program, errs := p.ParseProgram()
+	if len(errs) > 0 {
+		t.Fatalf("unexpected errors: %v", errs)
+	}
+	if len(program.Statements) != 1 {
+		t.Fatalf("expected 1 statement, got %d", len(program.Statements))
+	}
+
+	if len(program.Comments) != 2 {
+		t.Fatalf("expected 2 comments, got %d", len(program.Comments))
+	}
+	if program.Comments[0].Literal != "// header" || program.Comments[0].Line != 1 {
+		t.Errorf("comment[0] = %q line %d", program.Comments[0].Literal, program.Comments[0].Line)
+	}
+	if program.Comments[1].Literal != "/* inline */" || program.Comments[1].Line != 3 {
+		t.Errorf("comment[1] = %q line %d", program.Comments[1].Literal, program.Comments[1].Line)
+	}
+}
+
+func TestParseCompatMode(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		wantErr string // filly97 モードでのエラーメッセージの一部（空ならエラーなし）
+	}{
+		{"integer literal", "main() { x = 1 / 2 }", ""},
+		{"float literal", "main() { x = 1.5 }", "floating point literal 1.5"},
+		{"original event", "main() { mes(TIME) { x = 1 } }", ""},
+		{"extension event", "main() { mes(MIDI_NOTE) { x = 1 } }", "event type MIDI_NOTE"},
+		{"extension event lowercase", "main() { mes(fade_end) { x = 1 } }", "event type fade_end"},
+		{"try statement", "main() { try { x = 1 } catch (e) { x = 2 } }", "try statement"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// extended モードではすべて受け付ける
+			if _, errs := New(lexer.New(tt.input)).ParseProgram(); len(errs) > 0 {
+				t.Fatalf("extended: unexpected errors: %v", errs)
+			}
+
+			p := New(lexer.New(tt.input))
+			p.SetCompatMode(compat.FILLY97)
+			_, errs := p.ParseProgram()
+			if tt.wantErr == "" {
+				if len(errs) > 0 {
+					t.Fatalf("filly97: unexpected errors: %v", errs)
+				}
+				return
+			}
+			if len(errs) == 0 || !strings.Contains(errs[0].Error(), tt.wantErr) {
+				t.Fatalf("filly97: errors = %v, want %q", errs, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestParseErrorRecovery(t *testing.T) {
+	tests := []struct {
+		name      string
+		input     string
+		wantLines []int // エラーが報告される行（連鎖したエラーは含まない）
+	}{
+		{
+			"statements without semicolons",
+			"main() {\n  x = (1 +\n  y = 2\n  if (x > ) { z = 3 }\n  w = 4\n}\n",
+			[]int{4, 4}, // 閉じていない括弧は次の行の式と続けて解析され、if で検出される
+		},
+		{
+			"statements with semicolons",
+			"main() {\n  x = (1 + ;\n  y = 2;\n  PutCast(1, 2;\n  w = ];\n}\n",
+			[]int{2, 4, 5},
+		},
+		{
+			"next function",
+			"main() {\n  x = ];\n}\n\nfoo(a) {\n  a = );\n  return a;\n}\n",
+			[]int{2, 6},
+		},
+		{
+			"switch cases",
+			"main() {\n  switch (a) {\n  case 1:\n    b = (;\n  case 2:\n    d = ];\n  default:\n    e = );\n  }\n}\n",
+			[]int{4, 6, 8},
+		},
+		{
+			"step body",
+			"main() {\n  step(10) {\n    x = 1 +,,\n    y = 2,,\n    z = ),\n  }\n}\n",
+			[]int{3, 5},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := New(lexer.New(tt.input))
+			program, _ := p.ParseProgram()
+			var lines []int
+			for _, e := range p.Errors() {
+				lines = append(lines, e.Line)
+			}
+			if !slices.Equal(lines, tt.wantLines) {
+				t.Errorf("error lines = %v, want %v (errors: %v)", lines, tt.wantLines, p.Errors())
+			}
+			if len(program.Statements) == 0 {
+				t.Error("statements after the errors should still be parsed")
+			}
+		})
+	}
+}
+
+func TestParseErrorRecoveryKeepsLaterStatements(t *testing.T) {
+	input := "main() {\n  x = (1 +\n  y = 2\n  w = 4\n}\n"
+	program, errs := New(lexer.New(input)).ParseProgram()
+	if len(errs) != 1 {
+		t.Fatalf("expected 1 error, got %v", errs)
+	}
+
+	fn, ok := program.Statements[0].(*FunctionStatement)
+	if !ok {
+		t.Fatalf("expected FunctionStatement, got %T", program.Statements[0])
+	}
+	// エラーのある文以降の文（w = 4）は通常どおり解析される
+	last, ok := fn.Body.Statements[len(fn.Body.Statements)-1].(*AssignStatement)
+	if name, _ := last.Name.(*Identifier); !ok || name == nil || name.Value != "w" {
+		t.Errorf("last statement = %#v, want w = 4", fn.Body.Statements[len(fn.Body.Statements)-1])
+	}
+}
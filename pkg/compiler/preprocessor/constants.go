@@ -0,0 +1,302 @@
+package preprocessor
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/zurustar/son-et/pkg/compiler/lexer"
+)
+
+// Keys of the #info directives that define named constants and colors,
+// e.g. #info CONST MAXLINE 24
+//
+//	#info COLOR BTNFACE 192,192,192
+const (
+	infoKeyConst = "CONST"
+	infoKeyColor = "COLOR"
+)
+
+// Section names of the companion INI file (case-insensitive).
+// The companion file has the entry file's name with the .INI extension (MAIN.TFY → MAIN.INI).
+const (
+	iniSectionConstants = "constants"
+	iniSectionColors    = "colors"
+	companionIniExt     = ".INI"
+)
+
+// maxColorComponent is the maximum value of each RGB component.
+const maxColorComponent = 255
+
+// NamedConstant is a named constant defined by #info or the companion INI file.
+// The preprocessor injects it as a global variable assignment at the end of the source.
+// The VM runs top-level assignments before calling main() (see vm.collectFunctionDefinitions),
+// so the constant can be used anywhere in the script.
+type NamedConstant struct {
+	Name   string // Variable name
+	Value  string // Literal to inject (an integer literal or a quoted string literal)
+	Origin string // Where it was defined ("#info" or the INI file name)
+}
+
+// constantTable holds named constants in definition order.
+// A name defined again is overwritten by the later definition.
+type constantTable struct {
+	order  []string
+	values map[string]NamedConstant
+}
+
+func newConstantTable() *constantTable {
+	return &constantTable{values: make(map[string]NamedConstant)}
+}
+
+func (t *constantTable) set(c NamedConstant) {
+	key := strings.ToUpper(c.Name)
+	if _, exists := t.values[key]; !exists {
+		t.order = append(t.order, key)
+	}
+	t.values[key] = c
+}
+
+func (t *constantTable) list() []NamedConstant {
+	result := make([]NamedConstant, 0, len(t.order))
+	for _, key := range t.order {
+		result = append(result, t.values[key])
+	}
+	return result
+}
+
+// collectConstants collects named constants from the companion INI file and #info directives.
+// The INI file is read first, so definitions in the script take precedence.
+func (p *Preprocessor) collectConstants(entryFile, source string) ([]NamedConstant, error) {
+	table := newConstantTable()
+
+	if err := p.loadCompanionIni(entryFile, table); err != nil {
+		return nil, err
+	}
+	if err := collectInfoConstants(source, table); err != nil {
+		return nil, err
+	}
+
+	return table.list(), nil
+}
+
+// CompanionIniFile returns the name of the entry file's companion INI file (MAIN.TFY → MAIN.INI).
+func CompanionIniFile(entryFile string) string {
+	return strings.TrimSuffix(entryFile, filepath.Ext(entryFile)) + companionIniExt
+}
+
+// loadCompanionIni reads constants from the companion INI file of the entry file.
+// It does nothing if the file does not exist.
+func (p *Preprocessor) loadCompanionIni(entryFile string, table *constantTable) error {
+	iniFile := CompanionIniFile(entryFile)
+
+	data, err := p.fs.ReadFile(iniFile)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil
+		}
+		return fmt.Errorf("failed to read %s: %w", iniFile, err)
+	}
+
+	section := ""
+	scanner := bufio.NewScanner(strings.NewReader(string(data)))
+	lineNum := 0
+	for scanner.Scan() {
+		lineNum++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || line[0] == ';' || line[0] == '#' {
+			continue
+		}
+		if line[0] == '[' && line[len(line)-1] == ']' {
+			section = strings.ToLower(strings.TrimSpace(line[1 : len(line)-1]))
+			continue
+		}
+		if section != iniSectionConstants && section != iniSectionColors {
+			continue
+		}
+
+		name, value, ok := strings.Cut(line, "=")
+		if !ok {
+			return fmt.Errorf("%s:%d: expected NAME=value", iniFile, lineNum)
+		}
+		name = strings.TrimSpace(name)
+		value = strings.TrimSpace(value)
+
+		var c NamedConstant
+		if section == iniSectionColors {
+			c, err = newColorConstant(name, value)
+		} else {
+			c, err = newValueConstant(name, value)
+		}
+		if err != nil {
+			return fmt.Errorf("%s:%d: %w", iniFile, lineNum, err)
+		}
+		c.Origin = iniFile
+		table.set(c)
+	}
+
+	return scanner.Err()
+}
+
+// collectInfoConstants reads constants from #info CONST and #info COLOR in the source.
+// It uses the lexer, so "#info" inside comments and string literals is ignored.
+func collectInfoConstants(source string, table *constantTable) error {
+	l := lexer.New(source)
+	for {
+		tok := l.NextToken()
+		if tok.Type == lexer.TOKEN_EOF {
+			return nil
+		}
+		if tok.Type != lexer.TOKEN_INFO {
+			continue
+		}
+
+		// Literal has the form "#info KEY rest"
+		rest := strings.TrimSpace(strings.TrimPrefix(tok.Literal, "#info"))
+		key, args := cutField(rest)
+		key = strings.ToUpper(key)
+		if key != infoKeyConst && key != infoKeyColor {
+			continue // Metadata such as INAM
+		}
+
+		name, value := cutField(args)
+
+		var c NamedConstant
+		var err error
+		if key == infoKeyColor {
+			c, err = newColorConstant(name, value)
+		} else {
+			c, err = newValueConstant(name, value)
+		}
+		if err != nil {
+			return fmt.Errorf("invalid #info %s at line %d: %w", key, tok.Line, err)
+		}
+		c.Origin = "#info"
+		table.set(c)
+	}
+}
+
+// cutField returns the first whitespace-separated field and the rest, both trimmed.
+func cutField(s string) (string, string) {
+	s = strings.TrimSpace(s)
+	i := strings.IndexAny(s, " \t")
+	if i < 0 {
+		return s, ""
+	}
+	return s[:i], strings.TrimSpace(s[i+1:])
+}
+
+// newValueConstant creates an integer or string constant.
+// The value is a decimal or hexadecimal (0x) integer, or a quoted string.
+func newValueConstant(name, value string) (NamedConstant, error) {
+	if err := validateConstantName(name); err != nil {
+		return NamedConstant{}, err
+	}
+	if value == "" {
+		return NamedConstant{}, fmt.Errorf("constant %s has no value", name)
+	}
+
+	if len(value) >= 2 && value[0] == '"' && value[len(value)-1] == '"' {
+		if strings.ContainsAny(value[1:len(value)-1], "\"\n\r") {
+			return NamedConstant{}, fmt.Errorf("constant %s: invalid string value %s", name, value)
+		}
+		return NamedConstant{Name: name, Value: value}, nil
+	}
+
+	n, err := strconv.ParseInt(value, 0, 64)
+	if err != nil {
+		return NamedConstant{}, fmt.Errorf("constant %s: value must be an integer or quoted string, got %q", name, value)
+	}
+	return NamedConstant{Name: name, Value: strconv.FormatInt(n, 10)}, nil
+}
+
+// newColorConstant creates a color constant.
+// The value is "R,G,B" (each 0-255), "0xRRGGBB" or "#RRGGBB", and is converted
+// to the FILLY color representation (the integer 0xRRGGBB).
+func newColorConstant(name, value string) (NamedConstant, error) {
+	if err := validateConstantName(name); err != nil {
+		return NamedConstant{}, err
+	}
+
+	rgb, err := parseColorValue(value)
+	if err != nil {
+		return NamedConstant{}, fmt.Errorf("color %s: %w", name, err)
+	}
+	return NamedConstant{Name: name, Value: strconv.FormatInt(rgb, 10)}, nil
+}
+
+// parseColorValue converts a color value to the integer 0xRRGGBB.
+func parseColorValue(value string) (int64, error) {
+	if strings.Contains(value, ",") {
+		parts := strings.Split(value, ",")
+		if len(parts) != 3 {
+			return 0, fmt.Errorf("expected R,G,B, got %q", value)
+		}
+		var rgb int64
+		for _, part := range parts {
+			n, err := strconv.Atoi(strings.TrimSpace(part))
+			if err != nil || n < 0 || n > maxColorComponent {
+				return 0, fmt.Errorf("color component must be 0-%d, got %q", maxColorComponent, strings.TrimSpace(part))
+			}
+			rgb = rgb<<8 | int64(n)
+		}
+		return rgb, nil
+	}
+
+	hex := value
+	switch {
+	case strings.HasPrefix(hex, "#"):
+		hex = hex[1:]
+	case strings.HasPrefix(hex, "0x"), strings.HasPrefix(hex, "0X"):
+		hex = hex[2:]
+	default:
+		return 0, fmt.Errorf("expected R,G,B, 0xRRGGBB or #RRGGBB, got %q", value)
+	}
+	if len(hex) != 6 {
+		return 0, fmt.Errorf("expected 6 hex digits, got %q", value)
+	}
+	rgb, err := strconv.ParseInt(hex, 16, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid hex color %q", value)
+	}
+	return rgb, nil
+}
+
+// validateConstantName checks that the constant name is a valid FILLY identifier.
+func validateConstantName(name string) error {
+	if name == "" {
+		return errors.New("constant name is empty")
+	}
+	for i, r := range name {
+		isAlpha := (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || r == '_'
+		isDigit := r >= '0' && r <= '9'
+		if !isAlpha && !(isDigit && i > 0) {
+			return fmt.Errorf("invalid constant name %q", name)
+		}
+	}
+	return nil
+}
+
+// injectConstants appends the constants to the end of the source as global variable
+// assignments. They go at the end so that the line numbers of the user's source
+// (and so error positions) do not change.
+func injectConstants(source string, constants []NamedConstant) string {
+	if len(constants) == 0 {
+		return source
+	}
+
+	var sb strings.Builder
+	sb.WriteString(source)
+	if !strings.HasSuffix(source, "\n") {
+		sb.WriteString("\n")
+	}
+	sb.WriteString("// Named constants injected by preprocessor\n")
+	for _, c := range constants {
+		fmt.Fprintf(&sb, "%s = %s;\n", c.Name, c.Value)
+	}
+	return sb.String()
+}
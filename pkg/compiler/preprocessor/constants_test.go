@@ -0,0 +1,210 @@
+package preprocessor
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// helperWriteFiles writes the given files into a temporary directory and returns its path.
+func helperWriteFiles(t *testing.T, files map[string]string) string {
+	t.Helper()
+	dir := t.TempDir()
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write %s: %v", name, err)
+		}
+	}
+	return dir
+}
+
+// TestPreprocessorInfoConstants tests that #info CONST/COLOR are injected as global assignments.
+func TestPreprocessorInfoConstants(t *testing.T) {
+	dir := helperWriteFiles(t, map[string]string{
+		"main.tfy": `#info INAM "Title"
+#info CONST MAXLINE 24
+#info CONST MASK 0xFF
+#info CONST GREETING "hello world"
+#info COLOR BTNFACE 192,192,192
+#info COLOR HILIGHT #FF8000
+main() {
+}
+`,
+	})
+
+	result, err := New(dir).PreprocessFile("main.tfy")
+	if err != nil {
+		t.Fatalf("PreprocessFile failed: %v", err)
+	}
+
+	expected := []NamedConstant{
+		{Name: "MAXLINE", Value: "24", Origin: "#info"},
+		{Name: "MASK", Value: "255", Origin: "#info"},
+		{Name: "GREETING", Value: `"hello world"`, Origin: "#info"},
+		{Name: "BTNFACE", Value: "12632256", Origin: "#info"},
+		{Name: "HILIGHT", Value: "16744448", Origin: "#info"},
+	}
+	if len(result.Constants) != len(expected) {
+		t.Fatalf("Expected %d constants, got %d: %+v", len(expected), len(result.Constants), result.Constants)
+	}
+	for i, c := range expected {
+		if result.Constants[i] != c {
+			t.Errorf("Constants[%d] = %+v, expected %+v", i, result.Constants[i], c)
+		}
+		assign := c.Name + " = " + c.Value + ";"
+		if !strings.Contains(result.Source, assign) {
+			t.Errorf("Expected source to contain %q", assign)
+		}
+	}
+
+	// Metadata such as INAM does not become a constant
+	if strings.Contains(result.Source, "INAM =") {
+		t.Error("Metadata #info keys should not be injected")
+	}
+}
+
+// TestPreprocessorConstantsKeepLineNumbers tests that injection does not shift user source lines.
+func TestPreprocessorConstantsKeepLineNumbers(t *testing.T) {
+	source := "#info CONST A 1\nmain() {\n}\n"
+	dir := helperWriteFiles(t, map[string]string{"main.tfy": source})
+
+	result, err := New(dir).PreprocessFile("main.tfy")
+	if err != nil {
+		t.Fatalf("PreprocessFile failed: %v", err)
+	}
+	if !strings.HasPrefix(result.Source, source) {
+		t.Errorf("Original source should be kept at the beginning, got: %q", result.Source)
+	}
+}
+
+// TestPreprocessorCompanionIni tests loading constants and colors from the companion .INI file.
+func TestPreprocessorCompanionIni(t *testing.T) {
+	dir := helperWriteFiles(t, map[string]string{
+		"MAIN.TFY": "#info CONST SPEED 5\nmain() {\n}\n",
+		"MAIN.INI": `; system colors
+[Colors]
+COLOR_WINDOW = 255,255,255
+COLOR_BTNTEXT = 0x000000
+
+[Other]
+IGNORED = 1
+
+[Constants]
+SPEED = 1
+LABEL = "START"
+`,
+	})
+
+	result, err := New(dir).PreprocessFile("MAIN.TFY")
+	if err != nil {
+		t.Fatalf("PreprocessFile failed: %v", err)
+	}
+
+	got := make(map[string]NamedConstant)
+	for _, c := range result.Constants {
+		got[c.Name] = c
+	}
+
+	if got["COLOR_WINDOW"].Value != "16777215" {
+		t.Errorf("COLOR_WINDOW = %q, expected 16777215", got["COLOR_WINDOW"].Value)
+	}
+	if got["COLOR_BTNTEXT"].Value != "0" {
+		t.Errorf("COLOR_BTNTEXT = %q, expected 0", got["COLOR_BTNTEXT"].Value)
+	}
+	if got["LABEL"].Value != `"START"` {
+		t.Errorf("LABEL = %q, expected \"START\"", got["LABEL"].Value)
+	}
+	if _, ok := got["IGNORED"]; ok {
+		t.Error("Keys outside [Constants]/[Colors] should be ignored")
+	}
+
+	// #info in the script takes precedence over the INI file
+	if got["SPEED"].Value != "5" || got["SPEED"].Origin != "#info" {
+		t.Errorf("SPEED = %+v, expected #info value 5", got["SPEED"])
+	}
+}
+
+// TestPreprocessorNoConstants tests that sources without constants are unchanged.
+func TestPreprocessorNoConstants(t *testing.T) {
+	source := "main() {\n}\n"
+	dir := helperWriteFiles(t, map[string]string{"main.tfy": source})
+
+	result, err := New(dir).PreprocessFile("main.tfy")
+	if err != nil {
+		t.Fatalf("PreprocessFile failed: %v", err)
+	}
+	if result.Source != source {
+		t.Errorf("Source should be unchanged, got: %q", result.Source)
+	}
+	if len(result.Constants) != 0 {
+		t.Errorf("Expected no constants, got %+v", result.Constants)
+	}
+}
+
+// TestPreprocessorInvalidConstants tests that malformed definitions are reported.
+func TestPreprocessorInvalidConstants(t *testing.T) {
+	tests := []struct {
+		name  string
+		files map[string]string
+	}{
+		{"missing name", map[string]string{"main.tfy": "#info CONST\n"}},
+		{"missing value", map[string]string{"main.tfy": "#info CONST A\n"}},
+		{"invalid name", map[string]string{"main.tfy": "#info CONST 1A 1\n"}},
+		{"invalid value", map[string]string{"main.tfy": "#info CONST A abc\n"}},
+		{"color component out of range", map[string]string{"main.tfy": "#info COLOR A 256,0,0\n"}},
+		{"wrong number of color components", map[string]string{"main.tfy": "#info COLOR A 1,2\n"}},
+		{"wrong number of hex color digits", map[string]string{"main.tfy": "#info COLOR A #FFF\n"}},
+		{"malformed INI line", map[string]string{"main.tfy": "", "main.INI": "[Constants]\nBROKEN\n"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := helperWriteFiles(t, tt.files)
+			if _, err := New(dir).PreprocessFile("main.tfy"); err == nil {
+				t.Error("Expected error, got nil")
+			}
+		})
+	}
+}
+
+// TestPreprocessorInfoConstantsInComment tests that #info inside comments is ignored.
+func TestPreprocessorInfoConstantsInComment(t *testing.T) {
+	dir := helperWriteFiles(t, map[string]string{
+		"main.tfy": "// #info CONST A 1\n/* #info CONST B 2 */\nmain() {\n}\n",
+	})
+
+	result, err := New(dir).PreprocessFile("main.tfy")
+	if err != nil {
+		t.Fatalf("PreprocessFile failed: %v", err)
+	}
+	if len(result.Constants) != 0 {
+		t.Errorf("Expected no constants, got %+v", result.Constants)
+	}
+}
+
+// TestParseColorValue tests the supported color formats.
+func TestParseColorValue(t *testing.T) {
+	tests := []struct {
+		value    string
+		expected int64
+	}{
+		{"0,0,0", 0},
+		{"255, 0, 0", 0xFF0000},
+		{"0,255,0", 0x00FF00},
+		{"0x0000FF", 0x0000FF},
+		{"#C0C0C0", 0xC0C0C0},
+		{"0Xffffff", 0xFFFFFF},
+	}
+
+	for _, tt := range tests {
+		got, err := parseColorValue(tt.value)
+		if err != nil {
+			t.Errorf("parseColorValue(%q) failed: %v", tt.value, err)
+			continue
+		}
+		if got != tt.expected {
+			t.Errorf("parseColorValue(%q) = %#x, expected %#x", tt.value, got, tt.expected)
+		}
+	}
+}
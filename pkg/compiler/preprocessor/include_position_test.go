@@ -71,3 +71,76 @@ func TestMultipleIncludesStillWork(t *testing.T) {
 		t.Errorf("an #include directive was left unprocessed:\n%s", res.Source)
 	}
 }
+
+// TestLineOrigins tests that every line of the output is mapped back to the file
+// and line it came from, including included files without a trailing newline.
+func TestLineOrigins(t *testing.T) {
+	mfs := fstest.MapFS{
+		"main.tfy": {Data: []byte("int x;\n#include \"a.tfy\"\nint y; #include \"b.tfy\"\nint z;")},
+		"a.tfy":    {Data: []byte("A1;\nA2;")},
+		"b.tfy":    {Data: []byte("B1;\n")},
+	}
+	res, err := NewWithFS("", mfs).PreprocessFile("main.tfy")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	want := []LineOrigin{
+		{"main.tfy", 1}, {"a.tfy", 1}, {"a.tfy", 2}, {"main.tfy", 3}, {"b.tfy", 1}, {"main.tfy", 4},
+	}
+	lines := strings.Split(res.Source, "\n")
+	if len(res.Lines) != len(want) || len(lines) != len(want) {
+		t.Fatalf("Lines = %v for source %q, want %v", res.Lines, res.Source, want)
+	}
+	for i, origin := range want {
+		if res.Lines[i] != origin {
+			t.Errorf("line %d (%q) from %v, want %v", i+1, lines[i], res.Lines[i], origin)
+		}
+	}
+
+	if got := res.Position(3); got != "a.tfy:2" {
+		t.Errorf("Position(3) = %q, want a.tfy:2", got)
+	}
+	if got := res.Position(99); got != "line 99" {
+		t.Errorf("Position(99) = %q, want line 99", got)
+	}
+	if got := (*PreprocessResult)(nil).Position(5); got != "line 5" {
+		t.Errorf("Position on a nil result = %q, want line 5", got)
+	}
+}
+
+// TestSourceFiles tests that the files are listed with what each #include directive
+// was replaced by, and that AddedSource returns the appended constants.
+func TestSourceFiles(t *testing.T) {
+	mfs := fstest.MapFS{
+		"main.tfy": {Data: []byte("#include \"a.tfy\"\n#info CONST MAXLINE 24\n#include \"b.tfy\"\nint z;\n")},
+		"a.tfy":    {Data: []byte("#include \"b.tfy\"\nA;\n")},
+		"b.tfy":    {Data: []byte("B;\n")},
+	}
+	res, err := NewWithFS("", mfs).PreprocessFile("main.tfy")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	want := []SourceFile{
+		{Name: "main.tfy", Includes: []string{"a.tfy", ""}},
+		{Name: "a.tfy", Includes: []string{"b.tfy"}},
+		{Name: "b.tfy"},
+	}
+	if len(res.Files) != len(want) {
+		t.Fatalf("Files = %+v, want %+v", res.Files, want)
+	}
+	for i, f := range want {
+		got := res.Files[i]
+		if got.Name != f.Name || strings.Join(got.Includes, ",") != strings.Join(f.Includes, ",") || len(got.Includes) != len(f.Includes) {
+			t.Errorf("Files[%d] = %+v, want %+v", i, got, f)
+		}
+	}
+
+	if got, want := res.AddedSource(), "// Named constants injected by preprocessor\nMAXLINE = 24;\n"; got != want {
+		t.Errorf("AddedSource() = %q, want %q", got, want)
+	}
+	if got := (&PreprocessResult{Source: "int x;\n", Lines: []LineOrigin{{"main.tfy", 1}}}).AddedSource(); got != "" {
+		t.Errorf("AddedSource() without constants = %q, want empty", got)
+	}
+}
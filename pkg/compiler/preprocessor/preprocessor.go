@@ -7,6 +7,7 @@ import (
 	"io"
 	"io/fs"
 	"path/filepath"
+	"sort"
 	"strings"
 
 	"github.com/zurustar/son-et/pkg/compiler/lexer"
@@ -21,6 +22,7 @@ type Preprocessor struct {
 	includedFiles  map[string]bool     // Set of already included files (include guard)
 	includeStack   []string            // Stack for circular reference detection
 	processedFiles []string            // List of processed files in order
+	files          []SourceFile        // Processed files with their #include directives
 }
 
 // PreprocessResult contains the result of preprocessing.
@@ -29,6 +31,73 @@ type PreprocessResult struct {
 	Source string
 	// IncludedFiles is the list of files that were included (in order of inclusion)
 	IncludedFiles []string
+	// Constants is the list of named constants injected as global variables
+	// (from #info CONST/COLOR directives and the companion .INI file)
+	Constants []NamedConstant
+	// Lines is the origin of each line of Source (Lines[i] is line i+1).
+	// The lines appended for the named constants have no entry.
+	Lines []LineOrigin
+	// Files lists the processed files (in the order of IncludedFiles) with what each of
+	// their #include directives was replaced by.
+	Files []SourceFile
+}
+
+// SourceFile is a file read by the preprocessor.
+type SourceFile struct {
+	// Name is the file name as written in the #include directive (or the entry file)
+	Name string
+	// Includes has an element for each #include directive of the file, in order: the
+	// name of the file inserted in its place, or "" if nothing was inserted (the file
+	// was already included, or the directive has no file name).
+	Includes []string
+}
+
+// LineOrigin is the file and line a line of the preprocessed source came from.
+type LineOrigin struct {
+	File string // File name as written in the #include directive (or the entry file)
+	Line int    // 1-based line in the file
+}
+
+// Origin returns the file and line that line of Source came from.
+// ok is false for lines the preprocessor added itself.
+func (r *PreprocessResult) Origin(line int) (origin LineOrigin, ok bool) {
+	if r == nil || line < 1 || line > len(r.Lines) {
+		return LineOrigin{}, false
+	}
+	return r.Lines[line-1], true
+}
+
+// AddedSource returns the part of Source the preprocessor added itself (the named
+// constants), that is everything after the lines listed in Lines.
+func (r *PreprocessResult) AddedSource() string {
+	offset := 0
+	for range r.Lines {
+		next := strings.IndexByte(r.Source[offset:], '\n')
+		if next < 0 {
+			return ""
+		}
+		offset += next + 1
+	}
+	return r.Source[offset:]
+}
+
+// ConstantNames returns the names of the named constants injected into Source.
+func (r *PreprocessResult) ConstantNames() []string {
+	names := make([]string, len(r.Constants))
+	for i, c := range r.Constants {
+		names[i] = c.Name
+	}
+	return names
+}
+
+// Position formats line of Source as "FILE:LINE" in the file it came from,
+// for reports about the preprocessed source. Lines without an origin (and any
+// line of a nil result) are formatted as "line N".
+func (r *PreprocessResult) Position(line int) string {
+	if origin, ok := r.Origin(line); ok {
+		return fmt.Sprintf("%s:%d", origin.File, origin.Line)
+	}
+	return fmt.Sprintf("line %d", line)
 }
 
 // New creates a new Preprocessor with the given base directory.
@@ -76,26 +145,40 @@ func NewWithFileSystem(fsys fileutil.FileSystem) *Preprocessor {
 // Requirement 16.3: Preprocessor processes included files recursively.
 // Requirement 16.4: Preprocessor detects circular references.
 // Requirement 16.5: Preprocessor prevents duplicate includes (include guard).
+//
+// Named constants defined with #info CONST/COLOR or in the companion .INI file
+// (same name as the entry file) are appended to the source as global assignments.
 func (p *Preprocessor) PreprocessFile(entryFile string) (*PreprocessResult, error) {
 	// Reset state
 	p.includedFiles = make(map[string]bool)
 	p.includeStack = []string{}
 	p.processedFiles = []string{}
+	p.files = nil
 
 	// Process the entry file
-	source, err := p.processFile(entryFile)
+	source, lines, err := p.processFile(entryFile)
+	if err != nil {
+		return nil, err
+	}
+
+	// Inject named constants as global variables
+	constants, err := p.collectConstants(entryFile, source)
 	if err != nil {
 		return nil, err
 	}
 
 	return &PreprocessResult{
-		Source:        source,
+		Source:        injectConstants(source, constants),
 		IncludedFiles: p.processedFiles,
+		Constants:     constants,
+		Lines:         lines,
+		Files:         p.files,
 	}, nil
 }
 
 // processFile processes a single file, expanding #include directives.
-func (p *Preprocessor) processFile(filename string) (string, error) {
+// It returns the expanded source and the origin of each of its lines.
+func (p *Preprocessor) processFile(filename string) (string, []LineOrigin, error) {
 	// Normalize the filename
 	normalizedName := normalizeFilename(filename)
 
@@ -103,7 +186,7 @@ func (p *Preprocessor) processFile(filename string) (string, error) {
 	// Requirement 16.4: Preprocessor detects circular references.
 	for _, stackFile := range p.includeStack {
 		if normalizeFilename(stackFile) == normalizedName {
-			return "", fmt.Errorf("circular include detected: %s -> %s",
+			return "", nil, fmt.Errorf("circular include detected: %s -> %s",
 				strings.Join(p.includeStack, " -> "), filename)
 		}
 	}
@@ -111,7 +194,7 @@ func (p *Preprocessor) processFile(filename string) (string, error) {
 	// Check include guard
 	// Requirement 16.5: Preprocessor prevents duplicate includes.
 	if p.includedFiles[normalizedName] {
-		return "", nil // Already included, skip
+		return "", nil, nil // Already included, skip
 	}
 
 	// Mark as included
@@ -125,20 +208,16 @@ func (p *Preprocessor) processFile(filename string) (string, error) {
 	content, err := p.readFileWithEncoding(filename)
 	if err != nil {
 		// Requirement 16.9: Preprocessor reports error if file not found.
-		return "", fmt.Errorf("failed to read file %s: %w", filename, err)
+		return "", nil, fmt.Errorf("failed to read file %s: %w", filename, err)
 	}
 
 	// Record the processed file
 	p.processedFiles = append(p.processedFiles, filename)
+	p.files = append(p.files, SourceFile{Name: filename})
 
 	// Process #include directives
 	// Requirement 16.2: Preprocessor expands #include directives.
-	result, err := p.expandIncludes(content)
-	if err != nil {
-		return "", err
-	}
-
-	return result, nil
+	return p.expandIncludes(len(p.files)-1, content)
 }
 
 // expandIncludes expands #include directives in the source code.
@@ -149,16 +228,40 @@ func (p *Preprocessor) processFile(filename string) (string, error) {
 // We locate each directive by converting the token's (Line, Column) to a byte
 // offset, rather than doing a naive textual search for "#include" (which would
 // wrongly match occurrences inside comments/strings).
-func (p *Preprocessor) expandIncludes(source string) (string, error) {
+//
+// Each included file starts on a line of its own and ends with a newline, so that
+// every line of the result comes from exactly one line of one file (see LineOrigin).
+// file is the index of the file in p.files, whose Includes are recorded here.
+func (p *Preprocessor) expandIncludes(file int, source string) (string, []LineOrigin, error) {
+	filename := p.files[file].Name
+
 	// Use lexer to find #include directives
 	l := lexer.New(source)
 
 	lineOffsets := computeLineOffsets(source)
 
 	var result strings.Builder
+	var lines []LineOrigin
 	lastPos := 0
 	sourceBytes := []byte(source)
 
+	// write copies source[from:to] to the result, recording the origin of each line it starts
+	atLineStart := true
+	write := func(from, to int) {
+		line := lineAt(lineOffsets, from)
+		for i := from; i < to; i++ {
+			if atLineStart {
+				lines = append(lines, LineOrigin{File: filename, Line: line})
+				atLineStart = false
+			}
+			if sourceBytes[i] == '\n' {
+				line++
+				atLineStart = true
+			}
+		}
+		result.Write(sourceBytes[from:to])
+	}
+
 	for {
 		tok := l.NextToken()
 		if tok.Type == lexer.TOKEN_EOF {
@@ -170,6 +273,7 @@ func (p *Preprocessor) expandIncludes(source string) (string, error) {
 			// Format: #include "filename" or #include <filename>
 			includeFile := extractIncludeFilename(tok.Literal)
 			if includeFile == "" {
+				p.files[file].Includes = append(p.files[file].Includes, "")
 				continue // Invalid include directive, skip
 			}
 
@@ -178,21 +282,38 @@ func (p *Preprocessor) expandIncludes(source string) (string, error) {
 			directiveStart := byteOffsetFor(lineOffsets, tok.Line, tok.Column, len(sourceBytes))
 			if directiveStart < lastPos {
 				// Positions should be monotonic; if not, skip defensively.
+				p.files[file].Includes = append(p.files[file].Includes, "")
 				continue
 			}
 
 			// Add content before the directive (preserves comments, indentation, etc.)
-			result.Write(sourceBytes[lastPos:directiveStart])
+			write(lastPos, directiveStart)
 
 			// Process the included file
 			// Requirement 16.3: Preprocessor processes included files recursively.
-			includedContent, err := p.processFile(includeFile)
+			processed := len(p.files)
+			includedContent, includedLines, err := p.processFile(includeFile)
 			if err != nil {
-				return "", err
+				return "", nil, err
 			}
+			inserted := ""
+			if len(p.files) > processed {
+				inserted = p.files[processed].Name
+			}
+			p.files[file].Includes = append(p.files[file].Includes, inserted)
 
 			// Add the included content
-			result.WriteString(includedContent)
+			if includedContent != "" {
+				if !atLineStart {
+					result.WriteString("\n")
+				}
+				result.WriteString(includedContent)
+				lines = append(lines, includedLines...)
+				if !strings.HasSuffix(includedContent, "\n") {
+					result.WriteString("\n")
+				}
+				atLineStart = true
+			}
 
 			// Find the end of the directive line
 			directiveEnd := findLineEnd(source, directiveStart)
@@ -201,11 +322,9 @@ func (p *Preprocessor) expandIncludes(source string) (string, error) {
 	}
 
 	// Add remaining content
-	if lastPos < len(sourceBytes) {
-		result.Write(sourceBytes[lastPos:])
-	}
+	write(lastPos, len(sourceBytes))
 
-	return result.String(), nil
+	return result.String(), lines, nil
 }
 
 // computeLineOffsets returns the byte offset at which each line starts.
@@ -221,6 +340,11 @@ func computeLineOffsets(source string) []int {
 	return offsets
 }
 
+// lineAt returns the 1-based line containing the byte offset.
+func lineAt(lineOffsets []int, offset int) int {
+	return sort.SearchInts(lineOffsets, offset+1)
+}
+
 // byteOffsetFor converts a 1-based (line, column) position into a byte offset.
 // The lexer counts columns per byte, so column maps directly to a byte offset
 // within the line. Out-of-range inputs are clamped to [0, max].
@@ -300,15 +424,20 @@ func (p *Preprocessor) readFileWithEncoding(filename string) (string, error) {
 	if err != nil {
 		return "", err
 	}
+	return DecodeSource(data), nil
+}
 
-	// Try to convert from Shift-JIS to UTF-8
+// DecodeSource converts the content of a script file from Shift-JIS to UTF-8,
+// the way the preprocessor reads files. If the conversion fails, the data is
+// returned unchanged.
+func DecodeSource(data []byte) string {
 	decoder := japanese.ShiftJIS.NewDecoder()
 	reader := transform.NewReader(strings.NewReader(string(data)), decoder)
 	utf8Data, err := io.ReadAll(reader)
 	if err != nil {
 		// If conversion fails, return original data
-		return string(data), nil
+		return string(data)
 	}
 
-	return string(utf8Data), nil
+	return string(utf8Data)
 }
@@ -0,0 +1,616 @@
+package compiler
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/zurustar/son-et/pkg/compiler/parser"
+)
+
+// Tick budget estimation
+// A sequence (main and each mes block) runs everything from one wait (a step comma,
+// Wait or WaitAny) to the next in a single tick. The estimator counts the opcodes run
+// in one tick, multiplying loop bodies by their iteration counts, and warns about the
+// sequences likely to exceed the frame budget, pointing at the loops responsible.
+// It is a static guard against stutter at run time; the numbers are worst-case estimates.
+
+// DefaultTickBudget is the number of opcodes a tick can run without delaying the frame.
+const DefaultTickBudget = 50000
+
+// unknownLoopIterations is the assumed iteration count of loops whose count cannot be
+// determined statically (while loops and for loops over variables).
+const unknownLoopIterations = 100
+
+// TickEstimate is the estimated heaviest tick of a sequence.
+type TickEstimate struct {
+	Sequence string // "main", "mes(TIME)", ...
+	Line     int    // Line of the sequence (in the preprocessed source)
+	Ops      int    // Estimated opcodes run in the heaviest tick
+	Loops    []int  // Lines of the loops run without a wait in that tick (ascending)
+}
+
+// TickBudgetWarning is a sequence whose heaviest tick likely exceeds the budget.
+type TickBudgetWarning = TickEstimate
+
+// FindTickBudgetOverruns parses the script and collects the sequences likely to run
+// more than budget opcodes in one tick (see CheckTickBudget).
+func FindTickBudgetOverruns(source string, opts CompileOptions, budget int) ([]TickBudgetWarning, error) {
+	a, err := Analyze(source, opts)
+	if err != nil {
+		return nil, err
+	}
+	return a.TickBudgetOverruns(budget), nil
+}
+
+// TickBudgetOverruns returns the sequences likely to run more than budget opcodes in
+// one tick (see CheckTickBudget).
+func (a *Analysis) TickBudgetOverruns(budget int) []TickBudgetWarning {
+	return overBudget(a.Ticks, budget)
+}
+
+// CheckTickBudget estimates the opcodes the main function and each mes block of the
+// program run from one wait to the next, and returns those exceeding budget in source order.
+//
+// Calls of functions defined by the script include the body of the callee.
+// Loops with an unknown count are counted as unknownLoopIterations iterations.
+func CheckTickBudget(program *parser.Program, budget int) []TickBudgetWarning {
+	return overBudget(EstimateTicks(program), budget)
+}
+
+// overBudget returns the estimates exceeding budget.
+func overBudget(estimates []TickEstimate, budget int) []TickBudgetWarning {
+	var warnings []TickBudgetWarning
+	for _, est := range estimates {
+		if est.Ops > budget {
+			warnings = append(warnings, est)
+		}
+	}
+	return warnings
+}
+
+// EstimateTicks estimates the heaviest tick of the main function and each mes block of
+// the program, in source order (see CheckTickBudget).
+func EstimateTicks(program *parser.Program) []TickEstimate {
+	return summarizeTicks(program.Statements).estimate()
+}
+
+// FormatTickBudgetReport formats the sequences likely to exceed the budget as a
+// multi-line report (an empty string if there are none). src maps the lines back to
+// the files they came from; if it is nil, the lines of the preprocessed source are reported.
+func FormatTickBudgetReport(src *PreprocessResult, warnings []TickBudgetWarning, budget int) string {
+	if len(warnings) == 0 {
+		return ""
+	}
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "%d sequence(s) may exceed the tick budget of %d opcodes:\n", len(warnings), budget)
+	for _, w := range warnings {
+		fmt.Fprintf(&sb, "  %s at %s: about %d opcodes in one tick", w.Sequence, src.Position(w.Line), w.Ops)
+		if len(w.Loops) > 0 {
+			positions := make([]string, len(w.Loops))
+			for i, line := range w.Loops {
+				positions[i] = src.Position(line)
+			}
+			fmt.Fprintf(&sb, " (loops without a wait at %s)", strings.Join(positions, ", "))
+		}
+		sb.WriteString("\n")
+	}
+	return sb.String()
+}
+
+// collectMes collects the mes statements in a block (including nested ifs, loops and
+// so on, and mes statements inside mes bodies) in source order.
+func collectMes(block *parser.BlockStatement) []*parser.MesStatement {
+	var found []*parser.MesStatement
+	var walk func(stmt parser.Statement)
+	walkBlock := func(b *parser.BlockStatement) {
+		if b != nil {
+			for _, s := range b.Statements {
+				walk(s)
+			}
+		}
+	}
+	walk = func(stmt parser.Statement) {
+		switch s := stmt.(type) {
+		case *parser.MesStatement:
+			found = append(found, s)
+			walkBlock(s.Body)
+		case *parser.BlockStatement:
+			walkBlock(s)
+		case *parser.IfStatement:
+			walkBlock(s.Consequence)
+			if s.Alternative != nil {
+				walk(s.Alternative)
+			}
+		case *parser.ForStatement:
+			walkBlock(s.Body)
+		case *parser.WhileStatement:
+			walkBlock(s.Body)
+		case *parser.TryStatement:
+			walkBlock(s.Body)
+			walkBlock(s.Catch)
+		case *parser.SwitchStatement:
+			for _, c := range s.Cases {
+				for _, cs := range c.Body {
+					walk(cs)
+				}
+			}
+			walkBlock(s.Default)
+		case *parser.StepStatement:
+			if s.Body != nil {
+				for _, cmd := range s.Body.Commands {
+					if cmd.Statement != nil {
+						walk(cmd.Statement)
+					}
+				}
+			}
+		}
+	}
+	walkBlock(block)
+	return found
+}
+
+// tickSegment is a stretch of execution without a wait.
+type tickSegment struct {
+	ops   int
+	loops []int // Lines of the loops run without a wait
+}
+
+func (s tickSegment) plus(o tickSegment) tickSegment {
+	return tickSegment{ops: s.ops + o.ops, loops: append(slices.Clone(s.loops), o.loops...)}
+}
+
+// sortedLoops returns the loop lines without duplicates, in ascending order.
+func (s tickSegment) sortedLoops() []int {
+	loops := slices.Clone(s.loops)
+	slices.Sort(loops)
+	return slices.Compact(loops)
+}
+
+// heavier returns the segment with more opcodes.
+func heavier(a, b tickSegment) tickSegment {
+	if b.ops > a.ops {
+		return b
+	}
+	return a
+}
+
+// tickCost is the number of opcodes per tick run by a statement or expression.
+// Without a wait only head is used. With waits, head runs up to the first wait, tail
+// after the last one, and peak is the heaviest stretch from one wait to the next in between.
+type tickCost struct {
+	head, tail, peak tickSegment
+	waits            bool
+}
+
+// ops is n opcodes without a wait.
+func ops(n int) tickCost {
+	return tickCost{head: tickSegment{ops: n}}
+}
+
+// tailOrHead returns the segment that joins what runs next.
+func (c tickCost) tailOrHead() tickSegment {
+	if c.waits {
+		return c.tail
+	}
+	return c.head
+}
+
+// worst returns the heaviest tick.
+func (c tickCost) worst() tickSegment {
+	if !c.waits {
+		return c.head
+	}
+	return heavier(heavier(c.head, c.tail), c.peak)
+}
+
+// then returns the cost of running next after c.
+func (c tickCost) then(next tickCost) tickCost {
+	switch {
+	case !c.waits && !next.waits:
+		return tickCost{head: c.head.plus(next.head)}
+	case !c.waits:
+		return tickCost{head: c.head.plus(next.head), tail: next.tail, peak: next.peak, waits: true}
+	case !next.waits:
+		return tickCost{head: c.head, tail: c.tail.plus(next.head), peak: c.peak, waits: true}
+	}
+	return tickCost{head: c.head, tail: next.tail, peak: heavier(heavier(c.peak, next.peak), c.tail.plus(next.head)), waits: true}
+}
+
+// either returns the worst-case cost of running one of two branches.
+func (c tickCost) either(other tickCost) tickCost {
+	if !c.waits && !other.waits {
+		return tickCost{head: heavier(c.head, other.head)}
+	}
+	return tickCost{
+		head:  heavier(c.head, other.head),
+		tail:  heavier(c.tailOrHead(), other.tailOrHead()),
+		peak:  heavier(c.peak, other.peak),
+		waits: true,
+	}
+}
+
+// loop returns the cost of running the body iterations times (line is the line of the loop).
+// If the body waits, the stretch from the last wait of one iteration to the first wait of
+// the next one is a tick.
+func (c tickCost) loop(iterations, line int) tickCost {
+	if !c.waits {
+		return tickCost{head: tickSegment{
+			ops:   c.head.ops * iterations,
+			loops: append([]int{line}, c.head.loops...),
+		}}
+	}
+	return tickCost{head: c.head, tail: c.tail, peak: heavier(c.peak, c.tail.plus(c.head)), waits: true}
+}
+
+// wait is the cost of a wait (the tick ends here).
+var wait = tickCost{waits: true}
+
+// tickKind is the kind of a tickNode.
+type tickKind uint8
+
+const (
+	tickOps    tickKind = iota // Ops opcodes without a wait
+	tickWait                   // A wait
+	tickSeq                    // Nodes run in order
+	tickEither                 // One of the two Nodes runs
+	tickLoop                   // Nodes[0] runs Iterations times
+	tickCall                   // A call of Func: the body of the function if the script defines it, a wait for Wait and WaitAny
+)
+
+// tickNode is the cost of a statement or expression with the calls left unresolved,
+// so that the functions of one source file can be summarized (and cached) without
+// the rest of the script. The fields are exported for the codegen cache.
+type tickNode struct {
+	Kind       tickKind
+	Ops        int        // tickOps
+	Func       string     // tickCall
+	Iterations int        // tickLoop
+	Line       int        // tickLoop: line of the loop
+	Nodes      []tickNode // tickSeq, tickEither, tickLoop
+}
+
+// opsNode is n opcodes without a wait.
+func opsNode(n int) tickNode {
+	return tickNode{Kind: tickOps, Ops: n}
+}
+
+// seqNode runs the nodes in order. Adjacent opcodes are merged and empty sequences dropped.
+func seqNode(nodes ...tickNode) tickNode {
+	var merged []tickNode
+	for _, n := range nodes {
+		switch {
+		case n.Kind == tickSeq && len(n.Nodes) == 0:
+		case n.Kind == tickOps && len(merged) > 0 && merged[len(merged)-1].Kind == tickOps:
+			merged[len(merged)-1].Ops += n.Ops
+		default:
+			merged = append(merged, n)
+		}
+	}
+	if len(merged) == 1 {
+		return merged[0]
+	}
+	return tickNode{Kind: tickSeq, Nodes: merged}
+}
+
+// remap returns a copy of n with the lines of its loops mapped with line.
+func (n tickNode) remap(line func(int) int) tickNode {
+	if n.Kind == tickLoop {
+		n.Line = line(n.Line)
+	}
+	if n.Nodes != nil {
+		nodes := make([]tickNode, len(n.Nodes))
+		for i, node := range n.Nodes {
+			nodes[i] = node.remap(line)
+		}
+		n.Nodes = nodes
+	}
+	return n
+}
+
+// tickSummary is the tick cost of the functions and sequences defined by top-level
+// statements, in source order. Lines are those of the statements.
+type tickSummary struct {
+	Functions []tickFunction
+	Sequences []tickSequence
+}
+
+// tickFunction is the cost of the body of a function defined by the script.
+type tickFunction struct {
+	Name string
+	Body tickNode
+}
+
+// tickSequence is the cost of a sequence: the main function or a mes block.
+type tickSequence struct {
+	Name string // "main", "mes(TIME)", ...
+	Line int
+	Body tickNode
+}
+
+// summarizeTicks summarizes the functions defined by the statements and the
+// sequences in them.
+func summarizeTicks(statements []parser.Statement) tickSummary {
+	var summary tickSummary
+	for _, stmt := range statements {
+		fn, ok := stmt.(*parser.FunctionStatement)
+		if !ok {
+			continue
+		}
+		summary.Functions = append(summary.Functions, tickFunction{Name: fn.Name, Body: blockCost(fn.Body)})
+		if fn.Body == nil {
+			continue
+		}
+		if strings.EqualFold(fn.Name, "main") {
+			summary.Sequences = append(summary.Sequences, tickSequence{Name: "main", Line: fn.Token.Line, Body: tickNode{Kind: tickCall, Func: fn.Name}})
+		}
+		for _, mes := range collectMes(fn.Body) {
+			summary.Sequences = append(summary.Sequences, tickSequence{Name: "mes(" + mes.EventType + ")", Line: mes.Token.Line, Body: blockCost(mes.Body)})
+		}
+	}
+	return summary
+}
+
+// append adds the functions and sequences of other, which follow s in the script.
+func (s *tickSummary) append(other tickSummary) {
+	s.Functions = append(s.Functions, other.Functions...)
+	s.Sequences = append(s.Sequences, other.Sequences...)
+}
+
+// remap returns a copy of s with its lines mapped with line.
+func (s tickSummary) remap(line func(int) int) tickSummary {
+	var remapped tickSummary
+	for _, fn := range s.Functions {
+		remapped.Functions = append(remapped.Functions, tickFunction{Name: fn.Name, Body: fn.Body.remap(line)})
+	}
+	for _, seq := range s.Sequences {
+		remapped.Sequences = append(remapped.Sequences, tickSequence{Name: seq.Name, Line: line(seq.Line), Body: seq.Body.remap(line)})
+	}
+	return remapped
+}
+
+// estimate returns the heaviest tick of every sequence of a whole script, ordered by line.
+func (s tickSummary) estimate() []TickEstimate {
+	e := &tickEstimator{
+		functions: make(map[string]tickNode),
+		memo:      make(map[string]tickCost),
+		active:    make(map[string]bool),
+	}
+	for _, fn := range s.Functions {
+		e.functions[strings.ToLower(fn.Name)] = fn.Body
+	}
+
+	var estimates []TickEstimate
+	for _, seq := range s.Sequences {
+		worst := e.cost(seq.Body).worst()
+		estimates = append(estimates, TickEstimate{Sequence: seq.Name, Line: seq.Line, Ops: worst.ops, Loops: worst.sortedLoops()})
+	}
+	slices.SortStableFunc(estimates, func(a, b TickEstimate) int { return a.Line - b.Line })
+	return estimates
+}
+
+// tickEstimator resolves the calls of tick nodes against the script's functions.
+type tickEstimator struct {
+	functions map[string]tickNode // Lower-case function name → cost of the body
+	memo      map[string]tickCost // Costs of the functions estimated so far
+	active    map[string]bool     // Functions being estimated (recursive calls do not count the body)
+}
+
+// function returns the cost of the body of a function defined by the script (0 if there is no definition).
+func (e *tickEstimator) function(name string) tickCost {
+	key := strings.ToLower(name)
+	if cost, ok := e.memo[key]; ok {
+		return cost
+	}
+	body, ok := e.functions[key]
+	if !ok || e.active[key] {
+		return tickCost{}
+	}
+	e.active[key] = true
+	cost := e.cost(body)
+	delete(e.active, key)
+	e.memo[key] = cost
+	return cost
+}
+
+// cost returns the cost of a node.
+func (e *tickEstimator) cost(n tickNode) tickCost {
+	switch n.Kind {
+	case tickOps:
+		return ops(n.Ops)
+	case tickWait:
+		return wait
+	case tickSeq:
+		cost := tickCost{}
+		for _, node := range n.Nodes {
+			cost = cost.then(e.cost(node))
+		}
+		return cost
+	case tickEither:
+		return e.cost(n.Nodes[0]).either(e.cost(n.Nodes[1]))
+	case tickLoop:
+		return e.cost(n.Nodes[0]).loop(n.Iterations, n.Line)
+	case tickCall:
+		if _, defined := e.functions[strings.ToLower(n.Func)]; defined {
+			return e.function(n.Func)
+		}
+		if strings.EqualFold(n.Func, "Wait") || strings.EqualFold(n.Func, "WaitAny") {
+			return wait
+		}
+	}
+	return tickCost{}
+}
+
+// blockCost returns the cost of running the statements of a block in order.
+func blockCost(b *parser.BlockStatement) tickNode {
+	if b == nil {
+		return seqNode()
+	}
+	return statementsCost(b.Statements)
+}
+
+func statementsCost(stmts []parser.Statement) tickNode {
+	nodes := make([]tickNode, len(stmts))
+	for i, s := range stmts {
+		nodes[i] = statementCost(s)
+	}
+	return seqNode(nodes...)
+}
+
+// statementCost returns the cost of running a statement.
+// Each statement and expression node counts as one opcode. A mes statement only counts
+// its registration (its body is a sequence of its own).
+func statementCost(stmt parser.Statement) tickNode {
+	switch s := stmt.(type) {
+	case *parser.ExpressionStatement:
+		return expressionCost(s.Expression)
+	case *parser.AssignStatement:
+		return seqNode(expressionCost(s.Name), expressionCost(s.Value), opsNode(1))
+	case *parser.BlockStatement:
+		return blockCost(s)
+	case *parser.IfStatement:
+		alt := seqNode()
+		if s.Alternative != nil {
+			alt = statementCost(s.Alternative)
+		}
+		branches := tickNode{Kind: tickEither, Nodes: []tickNode{blockCost(s.Consequence), alt}}
+		return seqNode(expressionCost(s.Condition), opsNode(1), branches)
+	case *parser.ForStatement:
+		init, post := seqNode(), seqNode()
+		if s.Init != nil {
+			init = statementCost(s.Init)
+		}
+		if s.Post != nil {
+			post = statementCost(s.Post)
+		}
+		body := seqNode(expressionCost(s.Condition), opsNode(1), blockCost(s.Body), post)
+		return seqNode(init, tickNode{Kind: tickLoop, Iterations: forIterations(s), Line: s.Token.Line, Nodes: []tickNode{body}})
+	case *parser.WhileStatement:
+		body := seqNode(expressionCost(s.Condition), opsNode(1), blockCost(s.Body))
+		return tickNode{Kind: tickLoop, Iterations: unknownLoopIterations, Line: s.Token.Line, Nodes: []tickNode{body}}
+	case *parser.TryStatement:
+		// On failure part of the try block and the catch block run; estimate the whole try block
+		return seqNode(opsNode(1), blockCost(s.Body), blockCost(s.Catch))
+	case *parser.SwitchStatement:
+		cases := blockCost(s.Default)
+		for _, c := range s.Cases {
+			cases = tickNode{Kind: tickEither, Nodes: []tickNode{cases, seqNode(expressionCost(c.Value), statementsCost(c.Body))}}
+		}
+		return seqNode(expressionCost(s.Value), opsNode(1), cases)
+	case *parser.StepStatement:
+		nodes := []tickNode{expressionCost(s.Count), opsNode(1)}
+		if s.Body != nil {
+			for _, cmd := range s.Body.Commands {
+				if cmd.Statement != nil {
+					nodes = append(nodes, statementCost(cmd.Statement))
+				}
+				if cmd.WaitCount > 0 {
+					nodes = append(nodes, tickNode{Kind: tickWait})
+				}
+			}
+		}
+		return seqNode(nodes...)
+	case *parser.ReturnStatement:
+		return seqNode(expressionCost(s.ReturnValue), opsNode(1))
+	case nil:
+		return seqNode()
+	}
+	// mes registration, variable declarations, break, continue, labels, ...
+	return opsNode(1)
+}
+
+// expressionCost returns the cost of evaluating an expression (calls of script functions include the body).
+func expressionCost(expr parser.Expression) tickNode {
+	switch x := expr.(type) {
+	case nil:
+		return seqNode()
+	case *parser.BinaryExpression:
+		return seqNode(expressionCost(x.Left), expressionCost(x.Right), opsNode(1))
+	case *parser.UnaryExpression:
+		return seqNode(expressionCost(x.Right), opsNode(1))
+	case *parser.IndexExpression:
+		return seqNode(expressionCost(x.Left), expressionCost(x.Index), opsNode(1))
+	case *parser.CallExpression:
+		nodes := make([]tickNode, 0, len(x.Arguments)+2)
+		for _, arg := range x.Arguments {
+			nodes = append(nodes, expressionCost(arg))
+		}
+		return seqNode(append(nodes, opsNode(1), tickNode{Kind: tickCall, Func: x.Function})...)
+	}
+	return opsNode(1)
+}
+
+// forIterations returns the iteration count of a for statement.
+// For the form for(i=a; i<b; i=i+k) (comparison <, <=, >, >= or !=, increment + or - an
+// integer) with literal a, b and k it returns the count, otherwise unknownLoopIterations.
+func forIterations(s *parser.ForStatement) int {
+	init, ok := s.Init.(*parser.AssignStatement)
+	if !ok {
+		return unknownLoopIterations
+	}
+	v, ok := init.Name.(*parser.Identifier)
+	if !ok {
+		return unknownLoopIterations
+	}
+	start, ok := init.Value.(*parser.IntegerLiteral)
+	if !ok {
+		return unknownLoopIterations
+	}
+	cond, ok := s.Condition.(*parser.BinaryExpression)
+	if !ok || !isIdentifier(cond.Left, v.Value) {
+		return unknownLoopIterations
+	}
+	end, ok := cond.Right.(*parser.IntegerLiteral)
+	if !ok {
+		return unknownLoopIterations
+	}
+	post, ok := s.Post.(*parser.AssignStatement)
+	if !ok || !isIdentifier(post.Name, v.Value) {
+		return unknownLoopIterations
+	}
+	inc, ok := post.Value.(*parser.BinaryExpression)
+	if !ok || !isIdentifier(inc.Left, v.Value) {
+		return unknownLoopIterations
+	}
+	k, ok := inc.Right.(*parser.IntegerLiteral)
+	if !ok || k.Value <= 0 {
+		return unknownLoopIterations
+	}
+	step := k.Value
+	switch inc.Operator {
+	case "+":
+	case "-":
+		step = -step
+	default:
+		return unknownLoopIterations
+	}
+
+	span := end.Value - start.Value
+	switch cond.Operator {
+	case "<", "!=":
+	case "<=":
+		span++
+	case ">":
+	case ">=":
+		span--
+	default:
+		return unknownLoopIterations
+	}
+	if span == 0 || (span > 0) != (step > 0) {
+		return 0
+	}
+	if step < 0 {
+		span, step = -span, -step
+	}
+	n := (span + step - 1) / step
+	if n > 1<<30 {
+		return 1 << 30
+	}
+	return int(n)
+}
+
+// isIdentifier reports whether the expression is the variable name (case-insensitive).
+func isIdentifier(expr parser.Expression, name string) bool {
+	id, ok := expr.(*parser.Identifier)
+	return ok && strings.EqualFold(id.Value, name)
+}
@@ -0,0 +1,214 @@
+package compiler
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/zurustar/son-et/pkg/compiler/lexer"
+	"github.com/zurustar/son-et/pkg/compiler/parser"
+)
+
+// parseTickBudgetSource parses source for the tick budget tests.
+func parseTickBudgetSource(t *testing.T, source string) *parser.Program {
+	t.Helper()
+	program, errs := parser.New(lexer.New(source)).ParseProgram()
+	if len(errs) > 0 {
+		t.Fatalf("parse failed: %v", errs)
+	}
+	return program
+}
+
+// TestFindTickBudgetOverruns tests that a nested loop without a wait is reported with its loop lines.
+func TestFindTickBudgetOverruns(t *testing.T) {
+	source := `main() {
+	for (i = 0; i < 1000; i = i + 1) {
+		for (j = 0; j < 1000; j = j + 1) {
+			x = i + j;
+		}
+	}
+}
+`
+	warnings, err := FindTickBudgetOverruns(source, CompileOptions{}, DefaultTickBudget)
+	if err != nil {
+		t.Fatalf("FindTickBudgetOverruns failed: %v", err)
+	}
+	if len(warnings) != 1 {
+		t.Fatalf("expected 1 warning, got %v", warnings)
+	}
+	w := warnings[0]
+	if w.Sequence != "main" || w.Line != 1 {
+		t.Errorf("warning = %+v, want main at line 1", w)
+	}
+	if w.Ops < 1000*1000 {
+		t.Errorf("Ops = %d, want at least 1000000", w.Ops)
+	}
+	if !reflect.DeepEqual(w.Loops, []int{2, 3}) {
+		t.Errorf("Loops = %v, want [2 3]", w.Loops)
+	}
+
+	report := FormatTickBudgetReport(nil, warnings, DefaultTickBudget)
+	if !strings.HasPrefix(report, "1 sequence(s) may exceed the tick budget of 50000 opcodes:\n") {
+		t.Errorf("unexpected report header: %q", report)
+	}
+	if !strings.Contains(report, "main at line 1") || !strings.Contains(report, "(loops without a wait at line 2, line 3)") {
+		t.Errorf("unexpected report: %q", report)
+	}
+}
+
+// TestFindTickBudgetOverruns_None tests that a light script has no report.
+func TestFindTickBudgetOverruns_None(t *testing.T) {
+	warnings, err := FindTickBudgetOverruns(`main() { for (i = 0; i < 10; i = i + 1) { x = i; } }`, CompileOptions{}, DefaultTickBudget)
+	if err != nil {
+		t.Fatalf("FindTickBudgetOverruns failed: %v", err)
+	}
+	if len(warnings) != 0 {
+		t.Errorf("expected no warnings, got %v", warnings)
+	}
+	if report := FormatTickBudgetReport(nil, warnings, DefaultTickBudget); report != "" {
+		t.Errorf("expected an empty report, got %q", report)
+	}
+}
+
+// TestFormatTickBudgetReport_Includes tests that a loop in an included file is reported
+// at its line in that file rather than in the preprocessed source.
+func TestFormatTickBudgetReport_Includes(t *testing.T) {
+	dir := t.TempDir()
+	writeCacheTestFile(t, dir, "START.TFY", "#include \"LIB.TFY\"\nmain() {\n\tFill();\n}\n")
+	writeCacheTestFile(t, dir, "LIB.TFY", "// library\nFill() {\n\tfor (i = 0; i < 1000000; i = i + 1) {\n\t\tx = i;\n\t}\n}\n")
+
+	program, err := CompileProgram(dir, "START.TFY", nil, CompileOptions{})
+	if err != nil {
+		t.Fatalf("CompileProgram failed: %v", err)
+	}
+	warnings := program.Analysis.TickBudgetOverruns(DefaultTickBudget)
+	report := FormatTickBudgetReport(program.Result, warnings, DefaultTickBudget)
+	if !strings.Contains(report, "main at START.TFY:2") || !strings.Contains(report, "(loops without a wait at LIB.TFY:3)") {
+		t.Errorf("unexpected report: %q", report)
+	}
+}
+
+// TestFindTickBudgetOverruns_ParseError tests that parse errors are returned.
+func TestFindTickBudgetOverruns_ParseError(t *testing.T) {
+	if _, err := FindTickBudgetOverruns(`x = = 5;`, CompileOptions{}, DefaultTickBudget); err == nil {
+		t.Error("expected a parse error")
+	}
+}
+
+// TestCheckTickBudget_WaitSplitsTicks tests that a wait inside the loop body splits the work into ticks.
+func TestCheckTickBudget_WaitSplitsTicks(t *testing.T) {
+	program := parseTickBudgetSource(t, `main() {
+	while (1) {
+		for (j = 0; j < 50; j = j + 1) {
+			x = j;
+		}
+		Wait(1);
+	}
+}
+`)
+	if warnings := CheckTickBudget(program, 1000); len(warnings) != 0 {
+		t.Errorf("expected no warnings with a Wait in the loop, got %v", warnings)
+	}
+	// With a lower budget only the inner loop between two waits is reported as the cause
+	warnings := CheckTickBudget(program, 100)
+	if len(warnings) != 1 || !reflect.DeepEqual(warnings[0].Loops, []int{3}) {
+		t.Errorf("warnings = %+v, want one warning for the loop at line 3", warnings)
+	}
+}
+
+// TestCheckTickBudget_StepWaits tests that the commas of a step block are wait points.
+func TestCheckTickBudget_StepWaits(t *testing.T) {
+	program := parseTickBudgetSource(t, `main() {
+	mes(TIME) {
+		step(1) {
+			Heavy();,
+			Heavy();,
+			end_step;
+		}
+	}
+}
+
+Heavy() {
+	for (i = 0; i < 300; i = i + 1) {
+		x = i;
+	}
+}
+`)
+	// One Heavy() call is about 3600 opcodes (300 × 12); two calls never share a tick
+	warnings := CheckTickBudget(program, 5000)
+	if len(warnings) != 0 {
+		t.Errorf("expected each Heavy() call in its own tick, got %v", warnings)
+	}
+	warnings = CheckTickBudget(program, 3000)
+	if len(warnings) != 1 {
+		t.Fatalf("expected 1 warning, got %v", warnings)
+	}
+	if w := warnings[0]; w.Sequence != "mes(TIME)" || w.Line != 2 || !reflect.DeepEqual(w.Loops, []int{12}) {
+		t.Errorf("warning = %+v, want mes(TIME) at line 2 with the loop at line 12", w)
+	}
+}
+
+// TestCheckTickBudget_UnknownIterations tests that loops with unknown counts use unknownLoopIterations.
+func TestCheckTickBudget_UnknownIterations(t *testing.T) {
+	program := parseTickBudgetSource(t, `main() {
+	n = 5;
+	for (i = 0; i < n; i = i + 1) {
+		x = i;
+	}
+}
+`)
+	warnings := CheckTickBudget(program, 0)
+	if len(warnings) != 1 {
+		t.Fatalf("expected 1 warning, got %v", warnings)
+	}
+	if warnings[0].Ops < unknownLoopIterations {
+		t.Errorf("Ops = %d, want at least %d", warnings[0].Ops, unknownLoopIterations)
+	}
+}
+
+// TestCheckTickBudget_Recursion tests that recursive functions do not loop forever.
+func TestCheckTickBudget_Recursion(t *testing.T) {
+	program := parseTickBudgetSource(t, `main() {
+	f(3);
+}
+
+f(n) {
+	if (n > 0) {
+		f(n - 1);
+	}
+}
+`)
+	if warnings := CheckTickBudget(program, DefaultTickBudget); len(warnings) != 0 {
+		t.Errorf("expected no warnings, got %v", warnings)
+	}
+}
+
+// TestForIterations tests the iteration count of for loops with literal bounds.
+func TestForIterations(t *testing.T) {
+	for _, tt := range []struct {
+		loop string
+		want int
+	}{
+		{"for (i = 0; i < 10; i = i + 1) {}", 10},
+		{"for (i = 0; i <= 10; i = i + 1) {}", 11},
+		{"for (i = 0; i < 10; i = i + 3) {}", 4},
+		{"for (i = 10; i > 0; i = i - 1) {}", 10},
+		{"for (i = 10; i >= 0; i = i - 2) {}", 6},
+		{"for (i = 0; i != 8; i = i + 2) {}", 4},
+		{"for (i = 5; i < 0; i = i + 1) {}", 0},
+		{"for (i = 0; i < n; i = i + 1) {}", unknownLoopIterations},
+		{"for (i = 0; i < 10; i = i * 2) {}", unknownLoopIterations},
+	} {
+		t.Run(tt.loop, func(t *testing.T) {
+			program := parseTickBudgetSource(t, "main() { "+tt.loop+" }")
+			fn := program.Statements[0].(*parser.FunctionStatement)
+			loop, ok := fn.Body.Statements[0].(*parser.ForStatement)
+			if !ok {
+				t.Fatalf("expected a for statement, got %T", fn.Body.Statements[0])
+			}
+			if got := forIterations(loop); got != tt.want {
+				t.Errorf("forIterations = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
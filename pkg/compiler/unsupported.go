@@ -0,0 +1,79 @@
+package compiler
+
+import (
+	"fmt"
+	"strings"
+)
+
+// UnsupportedCall collects the calls of a function the engine does not implement.
+type UnsupportedCall struct {
+	Name  string // Function name as first written in the script
+	Lines []int  // Lines of the calls in the preprocessed source, in source order
+}
+
+// Count returns the number of calls.
+func (u UnsupportedCall) Count() int {
+	return len(u.Lines)
+}
+
+// FindUnsupportedCalls collects the calls of functions that are neither built-in nor
+// defined by the script, across the whole script (function names are case-insensitive).
+//
+// An undefined function is only noticed when playback reaches the call, so the engine
+// shows this list as a compatibility report before playback instead.
+// isBuiltin reports whether a name is a built-in function (usually VM.HasBuiltin).
+// The result is ordered by first call.
+func FindUnsupportedCalls(source string, opts CompileOptions, isBuiltin func(name string) bool) ([]UnsupportedCall, error) {
+	a, err := Analyze(source, opts)
+	if err != nil {
+		return nil, err
+	}
+	return a.UnsupportedCalls(isBuiltin), nil
+}
+
+// UnsupportedCalls returns the calls of functions that are neither built-in nor defined
+// by the script (see FindUnsupportedCalls).
+func (a *Analysis) UnsupportedCalls(isBuiltin func(name string) bool) []UnsupportedCall {
+	defined := a.defined()
+	var calls []UnsupportedCall
+	index := make(map[string]int)
+	for _, site := range a.Calls {
+		key := strings.ToLower(site.Name)
+		if defined[key] || isBuiltin(site.Name) {
+			continue
+		}
+		i, ok := index[key]
+		if !ok {
+			i = len(calls)
+			index[key] = i
+			calls = append(calls, UnsupportedCall{Name: site.Name})
+		}
+		calls[i].Lines = append(calls[i].Lines, site.Line)
+	}
+	return calls
+}
+
+// FormatUnsupportedReport formats the unsupported functions as a multi-line compatibility
+// report giving the number of calls and their positions for each function (an empty
+// string if there are none). src maps the lines back to the files they came from;
+// if it is nil, the lines of the preprocessed source are reported.
+func FormatUnsupportedReport(calls []UnsupportedCall, src *PreprocessResult) string {
+	if len(calls) == 0 {
+		return ""
+	}
+	total := 0
+	for _, call := range calls {
+		total += call.Count()
+	}
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "%d unsupported function(s), %d call(s):\n", len(calls), total)
+	for _, call := range calls {
+		positions := make([]string, len(call.Lines))
+		for i, line := range call.Lines {
+			positions[i] = src.Position(line)
+		}
+		fmt.Fprintf(&sb, "  %s: %d call(s) at %s\n", call.Name, call.Count(), strings.Join(positions, ", "))
+	}
+	return sb.String()
+}
@@ -0,0 +1,103 @@
+package compiler
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// TestFindUnsupportedCalls tests that unknown functions are collected across the whole script.
+func TestFindUnsupportedCalls(t *testing.T) {
+	source := `main() {
+	p = LoadPic("a.bmp");
+	MCIPlay("x");
+	helper(Sparkle(p));
+	mes(TIME) {
+		step {
+			mciplay("y");,
+			del_me;
+		}
+	}
+}
+
+Helper(v) {
+	MCIPlay(v);
+}
+`
+	builtins := map[string]bool{"loadpic": true, "del_me": true}
+	isBuiltin := func(name string) bool { return builtins[strings.ToLower(name)] }
+
+	calls, err := FindUnsupportedCalls(source, CompileOptions{}, isBuiltin)
+	if err != nil {
+		t.Fatalf("FindUnsupportedCalls failed: %v", err)
+	}
+
+	want := []UnsupportedCall{
+		{Name: "MCIPlay", Lines: []int{3, 7, 14}},
+		{Name: "Sparkle", Lines: []int{4}},
+	}
+	if !reflect.DeepEqual(calls, want) {
+		t.Errorf("calls = %+v, want %+v", calls, want)
+	}
+	if calls[0].Count() != 3 {
+		t.Errorf("Count() = %d, want 3", calls[0].Count())
+	}
+}
+
+// TestFindUnsupportedCalls_None tests that a script using only known functions has no report.
+func TestFindUnsupportedCalls_None(t *testing.T) {
+	calls, err := FindUnsupportedCalls(`main() { f(); } f() { }`, CompileOptions{}, func(string) bool { return false })
+	if err != nil {
+		t.Fatalf("FindUnsupportedCalls failed: %v", err)
+	}
+	if len(calls) != 0 {
+		t.Errorf("expected no unsupported calls, got %+v", calls)
+	}
+	if report := FormatUnsupportedReport(calls, nil); report != "" {
+		t.Errorf("expected an empty report, got %q", report)
+	}
+}
+
+// TestFindUnsupportedCalls_ParseError tests that parse errors are returned.
+func TestFindUnsupportedCalls_ParseError(t *testing.T) {
+	if _, err := FindUnsupportedCalls(`x = = 5;`, CompileOptions{}, func(string) bool { return true }); err == nil {
+		t.Error("expected a parse error")
+	}
+}
+
+// TestFormatUnsupportedReport tests the consolidated report format.
+func TestFormatUnsupportedReport(t *testing.T) {
+	report := FormatUnsupportedReport([]UnsupportedCall{
+		{Name: "MCIPlay", Lines: []int{3, 7}},
+		{Name: "Sparkle", Lines: []int{4}},
+	}, nil)
+	want := "2 unsupported function(s), 3 call(s):\n" +
+		"  MCIPlay: 2 call(s) at line 3, line 7\n" +
+		"  Sparkle: 1 call(s) at line 4\n"
+	if report != want {
+		t.Errorf("report = %q, want %q", report, want)
+	}
+}
+
+// TestFormatUnsupportedReport_Includes tests that calls in included files are reported
+// at their line in that file rather than in the preprocessed source.
+func TestFormatUnsupportedReport_Includes(t *testing.T) {
+	dir := t.TempDir()
+	writeCacheTestFile(t, dir, "MAIN.TFY", "#include \"LIB.TFY\"\nmain() {\n\tMCIPlay(\"x\");\n}\n")
+	writeCacheTestFile(t, dir, "LIB.TFY", "// library\nPlay() {\n\tMCIPlay(\"y\");\n}\n")
+
+	_, result, err := CompileWithPreprocessor(dir, "MAIN.TFY")
+	if err != nil {
+		t.Fatalf("CompileWithPreprocessor failed: %v", err)
+	}
+	calls, err := FindUnsupportedCalls(result.Source, CompileOptions{}, func(string) bool { return false })
+	if err != nil {
+		t.Fatalf("FindUnsupportedCalls failed: %v", err)
+	}
+	report := FormatUnsupportedReport(calls, result)
+	want := "1 unsupported function(s), 2 call(s):\n" +
+		"  MCIPlay: 2 call(s) at LIB.TFY:3, MAIN.TFY:3\n"
+	if report != want {
+		t.Errorf("report = %q, want %q", report, want)
+	}
+}
@@ -0,0 +1,136 @@
+// Package engine is the extension API of son-et: it adds built-in functions implemented
+// in Go to the interpreter without modifying it, for example to control DMX lighting or
+// to talk to other software from a title.
+//
+// An extension is an ordinary Go package that registers its functions in init:
+//
+//	package dmx
+//
+//	import (
+//		"github.com/zurustar/son-et/pkg/engine"
+//		"github.com/zurustar/son-et/pkg/vm"
+//	)
+//
+//	func init() {
+//		engine.MustRegisterBuiltin("DMXSet", dmxSet, engine.WithArgs(2, engine.ArgInt, engine.ArgInt))
+//	}
+//
+//	func dmxSet(v *vm.VM, args []any) (any, error) {
+//		channel, value := args[0].(int64), args[1].(int64)
+//		...
+//	}
+//
+// and is linked into the son-et binary by a file in cmd/son-et guarded by a build tag:
+//
+//	//go:build dmx
+//
+//	package main
+//
+//	import _ "example.com/sonet-dmx"
+//
+// Building with "go build -tags dmx ./cmd/son-et" then makes DMXSet(1, 255) callable
+// from scripts; building without the tag leaves the engine unchanged.
+package engine
+
+import (
+	"fmt"
+
+	"github.com/zurustar/son-et/pkg/compiler/lexer"
+	"github.com/zurustar/son-et/pkg/opcode"
+	"github.com/zurustar/son-et/pkg/vm"
+)
+
+// Builtin is the Go implementation of a built-in function.
+//
+// args are the evaluated arguments, already converted to the declared argument types
+// (int64 for ArgInt, float64 for ArgNumber, string for ArgString). The result is the
+// value of the call; nil is 0. A returned error is logged and the call returns 0, so the
+// script keeps running.
+type Builtin = vm.BuiltinFunc
+
+// Argument types for WithArgs.
+const (
+	ArgAny    = opcode.ArgAny
+	ArgInt    = opcode.ArgInt
+	ArgNumber = opcode.ArgNumber
+	ArgString = opcode.ArgString
+)
+
+// BuiltinOption configures a built-in function registered with RegisterBuiltin.
+type BuiltinOption func(*opcode.Signature)
+
+// WithArgs declares the argument types; the first required arguments must be given.
+// The compiler reports calls with other argument counts or literal arguments of the
+// wrong type, and the VM converts and checks the evaluated arguments before the call.
+// Without WithArgs, the function accepts any number of arguments of any type.
+func WithArgs(required int, types ...opcode.ArgType) BuiltinOption {
+	return func(sig *opcode.Signature) {
+		sig.Args = types
+		sig.Required = required
+		sig.Variadic = false
+	}
+}
+
+// WithVariadic makes the last declared argument type repeat without limit.
+func WithVariadic() BuiltinOption {
+	return func(sig *opcode.Signature) {
+		sig.Variadic = true
+	}
+}
+
+// WithInvalidResult sets the value the call returns without running the function when
+// an argument has the wrong type (0 by default).
+func WithInvalidResult(result any) BuiltinOption {
+	return func(sig *opcode.Signature) {
+		sig.Invalid = result
+	}
+}
+
+// RegisterBuiltin registers a built-in function for every VM created afterwards, so it
+// is usually called from an init function. Scripts call it case-insensitively like the
+// engine's own functions, and the compatibility report does not list it as unsupported.
+//
+// It fails if name is not an identifier, is a keyword, is already a built-in function,
+// or the options declare inconsistent argument counts.
+func RegisterBuiltin(name string, fn Builtin, opts ...BuiltinOption) error {
+	if !isIdentifier(name) {
+		return fmt.Errorf("invalid built-in function name %q", name)
+	}
+	if lexer.LookupIdent(name) != lexer.TOKEN_IDENT {
+		return fmt.Errorf("built-in function name %s is a keyword", name)
+	}
+	if fn == nil {
+		return fmt.Errorf("built-in function %s has no implementation", name)
+	}
+
+	sig := opcode.Signature{Name: name, Args: []opcode.ArgType{opcode.ArgAny}, Variadic: true}
+	for _, opt := range opts {
+		opt(&sig)
+	}
+	if err := opcode.RegisterSignature(sig); err != nil {
+		return fmt.Errorf("failed to register built-in function: %w", err)
+	}
+	// 同名の関数がないことは RegisterSignature で確認済み
+	return vm.RegisterPluginBuiltin(name, fn)
+}
+
+// MustRegisterBuiltin is like RegisterBuiltin but panics if the function cannot be registered.
+func MustRegisterBuiltin(name string, fn Builtin, opts ...BuiltinOption) {
+	if err := RegisterBuiltin(name, fn, opts...); err != nil {
+		panic(err)
+	}
+}
+
+// isIdentifier は name がスクリプトの関数名として書ける識別子かどうかを返す
+func isIdentifier(name string) bool {
+	if name == "" {
+		return false
+	}
+	for i, ch := range []byte(name) {
+		letter := (ch >= 'a' && ch <= 'z') || (ch >= 'A' && ch <= 'Z') || ch == '_'
+		if !letter && (i == 0 || ch < '0' || ch > '9') {
+			return false
+		}
+	}
+	return true
+}
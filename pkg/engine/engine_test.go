@@ -0,0 +1,97 @@
+package engine
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/zurustar/son-et/pkg/compat"
+	"github.com/zurustar/son-et/pkg/compiler"
+	"github.com/zurustar/son-et/pkg/opcode"
+	"github.com/zurustar/son-et/pkg/vm"
+)
+
+func TestRegisterBuiltin(t *testing.T) {
+	var got []any
+	err := RegisterBuiltin("TestLightSet", func(v *vm.VM, args []any) (any, error) {
+		got = args
+		return args[0].(int64) * 2, nil
+	}, WithArgs(1, ArgInt, ArgString))
+	if err != nil {
+		t.Fatalf("RegisterBuiltin failed: %v", err)
+	}
+	if !slices.Contains(vm.PluginBuiltins(), "TestLightSet") {
+		t.Errorf("PluginBuiltins() = %v", vm.PluginBuiltins())
+	}
+
+	// 登録した関数は大文字小文字を区別せずに呼び出せ、引数はシグネチャに従って変換される
+	source := "int x;\nmain() {\n  x = testlightset(\"21\", \"dimmer\");\n}\n"
+	ops, errs := compiler.Compile(source)
+	if len(errs) > 0 {
+		t.Fatalf("Compile failed: %v", errs)
+	}
+	for _, mode := range []compat.Mode{compat.Extended, compat.FILLY97} {
+		got = nil
+		v := vm.New(ops, vm.WithCompatMode(mode))
+		if err := v.Run(); err != nil {
+			t.Fatalf("%v: Run failed: %v", mode, err)
+		}
+		if x, _ := v.GetGlobalScope().Get("x"); x != int64(42) {
+			t.Errorf("%v: x = %v, want 42", mode, x)
+		}
+		if len(got) != 2 || got[0] != int64(21) || got[1] != "dimmer" {
+			t.Errorf("%v: args = %#v", mode, got)
+		}
+	}
+
+	// 互換性レポートには未実装の関数として表示されない
+	calls, err := compiler.FindUnsupportedCalls(source, compiler.CompileOptions{}, vm.New(nil).HasBuiltin)
+	if err != nil || len(calls) != 0 {
+		t.Errorf("FindUnsupportedCalls = %v, %v; want no unsupported calls", calls, err)
+	}
+}
+
+func TestRegisterBuiltinDefaultSignature(t *testing.T) {
+	MustRegisterBuiltin("TestOscNote", func(v *vm.VM, args []any) (any, error) { return nil, nil })
+	sig, ok := opcode.LookupSignature("TestOscNote")
+	if !ok || sig.MaxArgs() != -1 || sig.Required != 0 {
+		t.Errorf("signature = %+v, %v; want any number of arguments", sig, ok)
+	}
+}
+
+func TestRegisterBuiltinErrors(t *testing.T) {
+	noop := func(v *vm.VM, args []any) (any, error) { return nil, nil }
+	MustRegisterBuiltin("TestDuplicate", noop)
+
+	tests := []struct {
+		name string
+		fn   Builtin
+		opts []BuiltinOption
+	}{
+		{"", noop, nil},
+		{"1Light", noop, nil},
+		{"Light-Set", noop, nil},
+		{"mes", noop, nil},
+		{"LoadPic", noop, nil},
+		{"testduplicate", noop, nil},
+		{"TestNoFunc", nil, nil},
+		{"TestBadArgs", noop, []BuiltinOption{WithArgs(2, ArgInt)}},
+	}
+	for _, tt := range tests {
+		if err := RegisterBuiltin(tt.name, tt.fn, tt.opts...); err == nil {
+			t.Errorf("RegisterBuiltin(%q): expected error", tt.name)
+		}
+	}
+	if _, ok := opcode.LookupSignature("TestBadArgs"); ok {
+		t.Error("a rejected function should not leave a signature")
+	}
+}
+
+func TestIsIdentifier(t *testing.T) {
+	for name, want := range map[string]bool{
+		"DMXSet": true, "_x": true, "osc2": true, "": false, "2osc": false, "a b": false, "ライト": false,
+	} {
+		if got := isIdentifier(name); got != want {
+			t.Errorf("isIdentifier(%q) = %v, want %v", name, got, want)
+		}
+	}
+}
@@ -0,0 +1,346 @@
+// Package enginetest provides test doubles for testing titles run by the engine.
+//
+// FakeRenderer implements the VM's graphics system interface without drawing
+// anything and records every change of a window or cast (position, render
+// order, visibility) together with the tick at which it happened, so tests can
+// assert on motion ("cast 5 reached x=100 by tick 320") without comparing pixels.
+package enginetest
+
+import (
+	"image/color"
+	"io"
+	"log/slog"
+	"sort"
+	"sync"
+
+	"github.com/zurustar/son-et/pkg/graphics"
+)
+
+// SpriteKind は記録したスプライトの種類
+type SpriteKind int
+
+const (
+	KindCast   SpriteKind = iota // PutCast で配置したキャスト
+	KindWindow                   // OpenWin で開いたウィンドウ
+)
+
+// String はスプライトの種類名を返す
+func (k SpriteKind) String() string {
+	if k == KindWindow {
+		return "window"
+	}
+	return "cast"
+}
+
+// 記録する操作の種類
+const (
+	OpPut     = "put"     // 配置された（ウィンドウの場合は開かれた）
+	OpMove    = "move"    // 位置・ピクチャー・表示状態が変わった
+	OpRestack = "restack" // 重なり順だけが変わった
+	OpDelete  = "delete"  // 削除された（ウィンドウの場合は閉じられた）
+)
+
+// 記録する不透明度
+// FILLYのキャストとウィンドウは半透明にならないため、表示中は不透明、削除後は透明となる
+const (
+	AlphaOpaque = 1.0
+	AlphaHidden = 0.0
+)
+
+// DrawCall はスプライトの状態の変化の記録
+type DrawCall struct {
+	Tick  int        // 変化したティック（FakeRenderer.Tick の値）
+	Op    string     // OpPut, OpMove, OpRestack, OpDelete
+	Kind  SpriteKind // キャストかウィンドウか
+	ID    int        // キャスト番号またはウィンドウ番号
+	WinID int        // キャストが属するウィンドウ（ウィンドウの場合は ID と同じ）
+	PicID int        // 表示しているピクチャー
+	X, Y  int        // 位置（キャストはウィンドウ内の座標、ウィンドウは仮想デスクトップの座標）
+	Z     int        // 重なり順（大きいほど手前。キャストは同じウィンドウの中での順序）
+	Alpha float64    // 不透明度（AlphaOpaque または AlphaHidden）
+}
+
+// spriteKey はスプライトを識別するキー
+type spriteKey struct {
+	kind SpriteKind
+	id   int
+}
+
+// FakeRenderer は描画を行わず、ウィンドウとキャストの変化を記録するグラフィックスシステム
+//
+// ピクチャー・ウィンドウ・キャストの管理は graphics.HeadlessGraphicsSystem に任せ、
+// 状態を変える操作のたびに前回との差分を DrawCall として記録する。
+// ウィンドウを閉じたときに削除されるキャストや、重なり順の変更で順序が変わった
+// 他のキャストも記録される。
+type FakeRenderer struct {
+	*graphics.HeadlessGraphicsSystem
+
+	mu    sync.Mutex
+	tick  int
+	calls []DrawCall
+	last  map[spriteKey]DrawCall // 最後に記録した各スプライトの状態
+}
+
+// New は FakeRenderer を作成する
+// opts は内部の HeadlessGraphicsSystem に渡す（仮想デスクトップのサイズなど）。
+// ログは既定で破棄する。
+func New(opts ...graphics.HeadlessOption) *FakeRenderer {
+	defaults := []graphics.HeadlessOption{
+		graphics.WithHeadlessLogger(slog.New(slog.NewTextHandler(io.Discard, nil))),
+		graphics.WithLogOperations(false),
+	}
+	return &FakeRenderer{
+		HeadlessGraphicsSystem: graphics.NewHeadlessGraphicsSystem(append(defaults, opts...)...),
+		last:                   make(map[spriteKey]DrawCall),
+	}
+}
+
+// ===== Tick =====
+
+// Tick は現在のティックを返す
+func (f *FakeRenderer) Tick() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.tick
+}
+
+// SetTick は現在のティックを設定する（以降の記録に使われる）
+func (f *FakeRenderer) SetTick(tick int) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.tick = tick
+}
+
+// Advance はティックを n 進める
+func (f *FakeRenderer) Advance(n int) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.tick += n
+}
+
+// Update はゲームループから1フレームごとに呼び出され、ティックを1進める
+func (f *FakeRenderer) Update() error {
+	f.Advance(1)
+	return nil
+}
+
+// ===== Queries =====
+
+// Calls は記録したすべての変化を記録順に返す
+func (f *FakeRenderer) Calls() []DrawCall {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	calls := make([]DrawCall, len(f.calls))
+	copy(calls, f.calls)
+	return calls
+}
+
+// CallsFor は指定したスプライトの変化を記録順に返す
+func (f *FakeRenderer) CallsFor(kind SpriteKind, id int) []DrawCall {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var calls []DrawCall
+	for _, c := range f.calls {
+		if c.Kind == kind && c.ID == id {
+			calls = append(calls, c)
+		}
+	}
+	return calls
+}
+
+// StateAt は指定したティックの終わりの時点でのスプライトの状態を返す
+// それまでに一度も配置されていない場合は false を返す
+func (f *FakeRenderer) StateAt(kind SpriteKind, id, tick int) (DrawCall, bool) {
+	var state DrawCall
+	found := false
+	for _, c := range f.CallsFor(kind, id) {
+		if c.Tick > tick {
+			break
+		}
+		state, found = c, true
+	}
+	return state, found
+}
+
+// FirstTick は cond を満たす変化が最初に記録されたティックを返す
+func (f *FakeRenderer) FirstTick(kind SpriteKind, id int, cond func(DrawCall) bool) (int, bool) {
+	for _, c := range f.CallsFor(kind, id) {
+		if cond(c) {
+			return c.Tick, true
+		}
+	}
+	return 0, false
+}
+
+// ReachedBy はスプライトが指定したティックまでに cond を満たす状態になったかを返す
+// 例: キャスト5が320ティックまでに x=100 に到達したか
+//
+//	r.ReachedBy(enginetest.KindCast, 5, 320, func(c enginetest.DrawCall) bool { return c.X >= 100 })
+func (f *FakeRenderer) ReachedBy(kind SpriteKind, id, tick int, cond func(DrawCall) bool) bool {
+	first, ok := f.FirstTick(kind, id, cond)
+	return ok && first <= tick
+}
+
+// Reset は記録を消去する（ティックとスプライトの状態はそのまま）
+func (f *FakeRenderer) Reset() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.calls = nil
+}
+
+// ===== Recording =====
+
+// record は現在のウィンドウとキャストの状態を前回と比較し、変化を記録する
+func (f *FakeRenderer) record() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	current := make(map[spriteKey]DrawCall)
+	for _, w := range f.GetWindows() {
+		current[spriteKey{KindWindow, w.ID}] = DrawCall{
+			Kind: KindWindow, ID: w.ID, WinID: w.ID, PicID: w.PicID,
+			X: w.X, Y: w.Y, Z: w.ZOrder, Alpha: visibleAlpha(w.Visible),
+		}
+	}
+	for _, c := range f.GetCasts() {
+		current[spriteKey{KindCast, c.ID}] = DrawCall{
+			Kind: KindCast, ID: c.ID, WinID: c.WinID, PicID: c.PicID,
+			X: c.X, Y: c.Y, Z: c.ZOrder, Alpha: visibleAlpha(c.Visible),
+		}
+	}
+
+	// ウィンドウを先に、同じ種類の中では番号順に記録する
+	for _, kind := range []SpriteKind{KindWindow, KindCast} {
+		for _, key := range sortedKeys(current, f.last, kind) {
+			now, exists := current[key]
+			prev, existed := f.last[key]
+			switch {
+			case exists && !existed:
+				now.Op = OpPut
+			case !exists && existed:
+				now = prev
+				now.Op = OpDelete
+				now.Alpha = AlphaHidden
+			case now.X != prev.X || now.Y != prev.Y || now.PicID != prev.PicID || now.WinID != prev.WinID || now.Alpha != prev.Alpha:
+				now.Op = OpMove
+			case now.Z != prev.Z:
+				now.Op = OpRestack
+			default:
+				continue
+			}
+			now.Tick = f.tick
+			f.calls = append(f.calls, now)
+		}
+	}
+	f.last = current
+}
+
+// sortedKeys は a と b に含まれる指定した種類のキーを番号順に返す
+func sortedKeys(a, b map[spriteKey]DrawCall, kind SpriteKind) []spriteKey {
+	seen := make(map[int]bool)
+	var ids []int
+	for _, m := range []map[spriteKey]DrawCall{a, b} {
+		for key := range m {
+			if key.kind == kind && !seen[key.id] {
+				seen[key.id] = true
+				ids = append(ids, key.id)
+			}
+		}
+	}
+	sort.Ints(ids)
+	keys := make([]spriteKey, 0, len(ids))
+	for _, id := range ids {
+		keys = append(keys, spriteKey{kind, id})
+	}
+	return keys
+}
+
+// visibleAlpha は表示状態を不透明度に変換する
+func visibleAlpha(visible bool) float64 {
+	if visible {
+		return AlphaOpaque
+	}
+	return AlphaHidden
+}
+
+// ===== Window Management =====
+
+// OpenWin はウィンドウを開き、記録する
+func (f *FakeRenderer) OpenWin(picID int, opts ...any) (int, error) {
+	defer f.record()
+	return f.HeadlessGraphicsSystem.OpenWin(picID, opts...)
+}
+
+// MoveWin はウィンドウを移動し、記録する
+func (f *FakeRenderer) MoveWin(id int, opts ...any) error {
+	defer f.record()
+	return f.HeadlessGraphicsSystem.MoveWin(id, opts...)
+}
+
+// CloseWin はウィンドウを閉じ、ウィンドウとそのキャストの削除を記録する
+func (f *FakeRenderer) CloseWin(id int) error {
+	defer f.record()
+	return f.HeadlessGraphicsSystem.CloseWin(id)
+}
+
+// CloseWinAll はすべてのウィンドウを閉じ、削除を記録する
+func (f *FakeRenderer) CloseWinAll() {
+	defer f.record()
+	f.HeadlessGraphicsSystem.CloseWinAll()
+}
+
+// BringWinToFront はウィンドウを最前面に移動し、記録する
+func (f *FakeRenderer) BringWinToFront(id int) error {
+	defer f.record()
+	return f.HeadlessGraphicsSystem.BringWinToFront(id)
+}
+
+// SendWinToBack はウィンドウを最背面に移動し、記録する
+func (f *FakeRenderer) SendWinToBack(id int) error {
+	defer f.record()
+	return f.HeadlessGraphicsSystem.SendWinToBack(id)
+}
+
+// ===== Cast Management =====
+
+// PutCast はキャストを配置し、記録する
+func (f *FakeRenderer) PutCast(winID, picID, x, y, srcX, srcY, w, h int) (int, error) {
+	defer f.record()
+	return f.HeadlessGraphicsSystem.PutCast(winID, picID, x, y, srcX, srcY, w, h)
+}
+
+// PutCastWithTransColor は透明色付きでキャストを配置し、記録する
+func (f *FakeRenderer) PutCastWithTransColor(winID, picID, x, y, srcX, srcY, w, h int, transColor color.Color) (int, error) {
+	defer f.record()
+	return f.HeadlessGraphicsSystem.PutCastWithTransColor(winID, picID, x, y, srcX, srcY, w, h, transColor)
+}
+
+// MoveCast はキャストを移動し、記録する
+func (f *FakeRenderer) MoveCast(id int, opts ...any) error {
+	defer f.record()
+	return f.HeadlessGraphicsSystem.MoveCast(id, opts...)
+}
+
+// MoveCastWithOptions はキャストを移動し、記録する
+func (f *FakeRenderer) MoveCastWithOptions(id int, opts ...graphics.CastOption) error {
+	defer f.record()
+	return f.HeadlessGraphicsSystem.MoveCastWithOptions(id, opts...)
+}
+
+// DelCast はキャストを削除し、記録する
+func (f *FakeRenderer) DelCast(id int) error {
+	defer f.record()
+	return f.HeadlessGraphicsSystem.DelCast(id)
+}
+
+// BringCastToFront はキャストを最前面に移動し、順序が変わったキャストを記録する
+func (f *FakeRenderer) BringCastToFront(id int) error {
+	defer f.record()
+	return f.HeadlessGraphicsSystem.BringCastToFront(id)
+}
+
+// SendCastToBack はキャストを最背面に移動し、順序が変わったキャストを記録する
+func (f *FakeRenderer) SendCastToBack(id int) error {
+	defer f.record()
+	return f.HeadlessGraphicsSystem.SendCastToBack(id)
+}
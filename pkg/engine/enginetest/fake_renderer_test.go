@@ -0,0 +1,121 @@
+package enginetest
+
+import (
+	"testing"
+
+	"github.com/zurustar/son-et/pkg/vm"
+)
+
+// FakeRenderer は VM のグラフィックスシステムとして使える
+var _ vm.GraphicsSystemInterface = (*FakeRenderer)(nil)
+
+func TestFakeRenderer_RecordsCastMotion(t *testing.T) {
+	r := New()
+	picID, _ := r.CreatePic(320, 240)
+	winID, _ := r.OpenWin(picID, 0, 0)
+	castID, err := r.PutCast(winID, picID, 0, 10, 0, 0, 16, 16)
+	if err != nil {
+		t.Fatalf("PutCast failed: %v", err)
+	}
+
+	// 1ティックごとに20ピクセルずつ右へ動かす
+	for range 5 {
+		r.Advance(1)
+		state, _ := r.StateAt(KindCast, castID, r.Tick())
+		if err := r.MoveCast(castID, state.X+20, 10); err != nil {
+			t.Fatalf("MoveCast failed: %v", err)
+		}
+	}
+
+	calls := r.CallsFor(KindCast, castID)
+	if len(calls) != 6 {
+		t.Fatalf("expected 6 calls (put + 5 moves), got %d: %+v", len(calls), calls)
+	}
+	if calls[0].Op != OpPut || calls[0].Tick != 0 || calls[0].WinID != winID || calls[0].Alpha != AlphaOpaque {
+		t.Errorf("first call = %+v, want put at tick 0", calls[0])
+	}
+
+	reached := func(c DrawCall) bool { return c.X >= 100 }
+	if tick, ok := r.FirstTick(KindCast, castID, reached); !ok || tick != 5 {
+		t.Errorf("FirstTick(x>=100) = %d, %v; want 5", tick, ok)
+	}
+	if !r.ReachedBy(KindCast, castID, 5, reached) {
+		t.Error("cast should reach x=100 by tick 5")
+	}
+	if r.ReachedBy(KindCast, castID, 4, reached) {
+		t.Error("cast should not reach x=100 by tick 4")
+	}
+	if state, ok := r.StateAt(KindCast, castID, 2); !ok || state.X != 40 {
+		t.Errorf("StateAt(tick 2) = %+v, %v; want x=40", state, ok)
+	}
+	if _, ok := r.StateAt(KindCast, castID+1, 5); ok {
+		t.Error("StateAt should report casts that were never put")
+	}
+}
+
+func TestFakeRenderer_UnchangedMoveIsNotRecorded(t *testing.T) {
+	r := New()
+	picID, _ := r.CreatePic(320, 240)
+	winID, _ := r.OpenWin(picID, 0, 0)
+	castID, _ := r.PutCast(winID, picID, 5, 5, 0, 0, 16, 16)
+	_ = r.MoveCast(castID, 5, 5)
+
+	if calls := r.CallsFor(KindCast, castID); len(calls) != 1 {
+		t.Errorf("expected only the put to be recorded, got %+v", calls)
+	}
+}
+
+func TestFakeRenderer_RecordsRestackAndDelete(t *testing.T) {
+	r := New()
+	picID, _ := r.CreatePic(320, 240)
+	winID, _ := r.OpenWin(picID, 0, 0)
+	back, _ := r.PutCast(winID, picID, 0, 0, 0, 0, 16, 16)
+	front, _ := r.PutCast(winID, picID, 0, 0, 0, 0, 16, 16)
+
+	r.SetTick(10)
+	if err := r.BringCastToFront(back); err != nil {
+		t.Fatalf("BringCastToFront failed: %v", err)
+	}
+	backState, _ := r.StateAt(KindCast, back, 10)
+	frontState, _ := r.StateAt(KindCast, front, 10)
+	if backState.Op != OpRestack || frontState.Op != OpRestack {
+		t.Errorf("both casts should be restacked: %+v / %+v", backState, frontState)
+	}
+	if backState.Z <= frontState.Z {
+		t.Errorf("cast %d should be in front (z %d <= %d)", back, backState.Z, frontState.Z)
+	}
+
+	// ウィンドウを閉じると、そのキャストも削除として記録される
+	r.SetTick(20)
+	if err := r.CloseWin(winID); err != nil {
+		t.Fatalf("CloseWin failed: %v", err)
+	}
+	for _, id := range []int{back, front} {
+		state, _ := r.StateAt(KindCast, id, 20)
+		if state.Op != OpDelete || state.Tick != 20 || state.Alpha != AlphaHidden {
+			t.Errorf("cast %d state = %+v, want delete at tick 20", id, state)
+		}
+	}
+	if state, _ := r.StateAt(KindWindow, winID, 20); state.Op != OpDelete {
+		t.Errorf("window state = %+v, want delete", state)
+	}
+}
+
+func TestFakeRenderer_UpdateAdvancesTick(t *testing.T) {
+	r := New()
+	for range 3 {
+		if err := r.Update(); err != nil {
+			t.Fatalf("Update failed: %v", err)
+		}
+	}
+	if r.Tick() != 3 {
+		t.Errorf("Tick() = %d, want 3", r.Tick())
+	}
+
+	picID, _ := r.CreatePic(320, 240)
+	_, _ = r.OpenWin(picID, 0, 0)
+	r.Reset()
+	if len(r.Calls()) != 0 {
+		t.Errorf("Reset should clear the log, got %+v", r.Calls())
+	}
+}
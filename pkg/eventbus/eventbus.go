@@ -0,0 +1,169 @@
+// Package eventbus provides a publish/subscribe bus for engine events.
+//
+// Subsystems publish messages on dot-separated topics grouped by category
+// ("vm.event.TIME", "audio.midi.play", "sprite.cast.put"). External tools such as
+// the debug overlay, a remote-control API or a trace recorder subscribe with
+// wildcard patterns and receive copies of the messages; subscribing never
+// removes events from the script's event queue.
+//
+// Delivery never blocks the publisher: each subscription has its own buffer,
+// and messages that do not fit are dropped and counted.
+package eventbus
+
+import (
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// DefaultBufferSize は購読ごとのバッファの既定サイズ（メッセージ数）
+const DefaultBufferSize = 256
+
+// トピックのカテゴリ
+const (
+	CategoryVM     = "vm"     // VMのイベントキュー（vm.event.<TYPE>）
+	CategoryAudio  = "audio"  // MIDI・WAVの再生状態（audio.midi.play など）
+	CategorySprite = "sprite" // ウィンドウ・キャストの操作（sprite.cast.put など）
+)
+
+// wildcard はパターン中で任意のセグメントに一致する記号
+const wildcard = "*"
+
+// Message はバスに発行されるメッセージ
+// Data は購読者間で共有されるため、購読者は変更してはならない
+type Message struct {
+	Topic string
+	Time  time.Time
+	Data  map[string]any
+}
+
+// Bus はトピックごとにメッセージを配信するバス
+// nil の *Bus への Publish は何もしないため、バスを使用しない構成でも呼び出し側で確認する必要はない
+type Bus struct {
+	subs  []*Subscription
+	count atomic.Int32 // 購読数（購読者がいない場合に Publish を素早く返すために使用）
+	mu    sync.RWMutex
+}
+
+// New は新しい Bus を作成する
+func New() *Bus {
+	return &Bus{}
+}
+
+// Subscribe はパターンに一致するトピックのメッセージを受け取る購読を作成する
+//
+// パターンはトピックと同じくドットで区切り、"*" は任意の1セグメントに一致する。
+// 末尾の "*" は1つ以上の残りのセグメントに一致するため、"audio.*" は
+// "audio.midi.play" にも一致する。"*" だけのパターンはすべてのトピックに一致する。
+// bufferSize が0以下の場合は DefaultBufferSize を使用する。
+func (b *Bus) Subscribe(pattern string, bufferSize int) *Subscription {
+	if bufferSize <= 0 {
+		bufferSize = DefaultBufferSize
+	}
+	s := &Subscription{
+		pattern: strings.Split(pattern, "."),
+		ch:      make(chan Message, bufferSize),
+		bus:     b,
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.subs = append(b.subs, s)
+	b.count.Add(1)
+	return s
+}
+
+// Publish はトピックにメッセージを発行する
+// 一致する購読のバッファが一杯の場合、その購読ではメッセージを破棄する（発行側はブロックしない）
+func (b *Bus) Publish(topic string, data map[string]any) {
+	if b == nil || b.count.Load() == 0 {
+		return
+	}
+	msg := Message{Topic: topic, Time: time.Now(), Data: data}
+	segments := strings.Split(topic, ".")
+
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	for _, s := range b.subs {
+		if !matchSegments(s.pattern, segments) {
+			continue
+		}
+		select {
+		case s.ch <- msg:
+		default:
+			s.dropped.Add(1)
+		}
+	}
+}
+
+// HasSubscribers は購読が1つ以上あるかを返す
+// メッセージの作成にコストがかかる場合、発行前の確認に使用する
+func (b *Bus) HasSubscribers() bool {
+	return b != nil && b.count.Load() > 0
+}
+
+// unsubscribe は購読を取り除き、チャネルを閉じる
+func (b *Bus) unsubscribe(target *Subscription) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for i, s := range b.subs {
+		if s == target {
+			b.subs = append(b.subs[:i], b.subs[i+1:]...)
+			b.count.Add(-1)
+			close(s.ch)
+			return
+		}
+	}
+}
+
+// Subscription はバスの購読
+type Subscription struct {
+	pattern []string
+	ch      chan Message
+	dropped atomic.Uint64
+	bus     *Bus
+	once    sync.Once
+}
+
+// C はメッセージを受け取るチャネルを返す
+// Unsubscribe を呼び出すとチャネルは閉じられる
+func (s *Subscription) C() <-chan Message {
+	return s.ch
+}
+
+// Dropped はバッファが一杯で破棄されたメッセージの数を返す
+func (s *Subscription) Dropped() uint64 {
+	return s.dropped.Load()
+}
+
+// Unsubscribe は購読を終了する（複数回呼び出してもよい）
+func (s *Subscription) Unsubscribe() {
+	s.once.Do(func() {
+		s.bus.unsubscribe(s)
+	})
+}
+
+// Match はトピックがパターンに一致するかを返す（パターンの書式は Subscribe を参照）
+func Match(pattern, topic string) bool {
+	return matchSegments(strings.Split(pattern, "."), strings.Split(topic, "."))
+}
+
+// matchSegments はセグメントに分割したパターンとトピックを照合する
+func matchSegments(pattern, topic []string) bool {
+	for i, p := range pattern {
+		if i >= len(topic) {
+			return false
+		}
+		if p == wildcard {
+			if i == len(pattern)-1 {
+				return true
+			}
+			continue
+		}
+		if p != topic[i] {
+			return false
+		}
+	}
+	return len(pattern) == len(topic)
+}
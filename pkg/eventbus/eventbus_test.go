@@ -0,0 +1,103 @@
+package eventbus
+
+import (
+	"testing"
+)
+
+// TestMatch tests topic matching with wildcard patterns.
+func TestMatch(t *testing.T) {
+	tests := []struct {
+		pattern string
+		topic   string
+		want    bool
+	}{
+		{"*", "vm.event.TIME", true},
+		{"vm.*", "vm.event.TIME", true},
+		{"vm.*", "vm", false},
+		{"vm.event.*", "vm.event.TIME", true},
+		{"vm.event.TIME", "vm.event.TIME", true},
+		{"vm.event.TIME", "vm.event.MIDI_END", false},
+		{"*.cast.put", "sprite.cast.put", true},
+		{"*.cast.put", "sprite.cast.move", false},
+		{"sprite.*.put", "sprite.cast.put", true},
+		{"sprite.*.put", "sprite.cast.put.extra", false},
+		{"audio.*", "sprite.cast.put", false},
+		{"audio.midi", "audio.midi.play", false},
+	}
+	for _, tt := range tests {
+		if got := Match(tt.pattern, tt.topic); got != tt.want {
+			t.Errorf("Match(%q, %q) = %v, want %v", tt.pattern, tt.topic, got, tt.want)
+		}
+	}
+}
+
+// TestPublishSubscribe tests that every matching subscription receives a copy of the message.
+func TestPublishSubscribe(t *testing.T) {
+	bus := New()
+	all := bus.Subscribe("*", 0)
+	audio := bus.Subscribe("audio.*", 0)
+
+	bus.Publish("audio.midi.play", map[string]any{"file": "a.mid"})
+	bus.Publish("vm.event.TIME", nil)
+
+	if got := len(all.C()); got != 2 {
+		t.Errorf("wildcard subscription received %d messages, want 2", got)
+	}
+	if got := len(audio.C()); got != 1 {
+		t.Fatalf("audio subscription received %d messages, want 1", got)
+	}
+	msg := <-audio.C()
+	if msg.Topic != "audio.midi.play" || msg.Data["file"] != "a.mid" || msg.Time.IsZero() {
+		t.Errorf("unexpected message: %+v", msg)
+	}
+}
+
+// TestPublishDropsWhenFull tests that a full subscription drops messages instead of blocking.
+func TestPublishDropsWhenFull(t *testing.T) {
+	bus := New()
+	slow := bus.Subscribe("*", 2)
+	fast := bus.Subscribe("*", 10)
+
+	for i := 0; i < 5; i++ {
+		bus.Publish("vm.event.TIME", nil)
+	}
+
+	if got := len(slow.C()); got != 2 {
+		t.Errorf("slow subscription buffered %d messages, want 2", got)
+	}
+	if slow.Dropped() != 3 {
+		t.Errorf("slow subscription dropped %d messages, want 3", slow.Dropped())
+	}
+	if len(fast.C()) != 5 || fast.Dropped() != 0 {
+		t.Errorf("fast subscription should receive every message, got %d (dropped %d)", len(fast.C()), fast.Dropped())
+	}
+}
+
+// TestUnsubscribe tests that unsubscribing closes the channel and stops delivery.
+func TestUnsubscribe(t *testing.T) {
+	bus := New()
+	s := bus.Subscribe("*", 0)
+	if !bus.HasSubscribers() {
+		t.Fatal("bus should have a subscriber")
+	}
+
+	s.Unsubscribe()
+	s.Unsubscribe() // 2回目の呼び出しは何もしない
+
+	if bus.HasSubscribers() {
+		t.Error("bus should have no subscribers after Unsubscribe")
+	}
+	if _, ok := <-s.C(); ok {
+		t.Error("channel should be closed after Unsubscribe")
+	}
+	bus.Publish("vm.event.TIME", nil) // 閉じたチャネルに送信しない
+}
+
+// TestNilBus tests that publishing on a nil bus is a no-op.
+func TestNilBus(t *testing.T) {
+	var bus *Bus
+	bus.Publish("vm.event.TIME", nil)
+	if bus.HasSubscribers() {
+		t.Error("nil bus should have no subscribers")
+	}
+}
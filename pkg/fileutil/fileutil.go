@@ -2,6 +2,7 @@
 package fileutil
 
 import (
+	"errors"
 	"fmt"
 	"io/fs"
 	"os"
@@ -18,7 +19,7 @@ import (
 //
 // Returns:
 //   - string: The actual path to the file if found
-//   - error: Error if the file is not found or if there's an I/O error
+//   - error: Error if the file is not found (wraps fs.ErrNotExist) or if there's an I/O error
 //
 // Example:
 //
@@ -46,7 +47,7 @@ func FindFileCaseInsensitive(dir, filename string) (string, error) {
 		}
 	}
 
-	return "", fmt.Errorf("file not found: %s (searched in %s)", filename, dir)
+	return "", fmt.Errorf("file not found: %s (searched in %s): %w", filename, dir, fs.ErrNotExist)
 }
 
 // FindFileCaseInsensitiveFS searches for a file with the given name in the specified directory
@@ -60,7 +61,7 @@ func FindFileCaseInsensitive(dir, filename string) (string, error) {
 //
 // Returns:
 //   - string: The actual path to the file if found
-//   - error: Error if the file is not found or if there's an I/O error
+//   - error: Error if the file is not found (wraps fs.ErrNotExist) or if there's an I/O error
 func FindFileCaseInsensitiveFS(fsys fs.FS, dir, filename string) (string, error) {
 	// Normalize the search filename to lowercase for comparison
 	searchName := strings.ToLower(filename)
@@ -84,7 +85,66 @@ func FindFileCaseInsensitiveFS(fsys fs.FS, dir, filename string) (string, error)
 		}
 	}
 
-	return "", fmt.Errorf("file not found: %s (searched in %s)", filename, dir)
+	return "", fmt.Errorf("file not found: %s (searched in %s): %w", filename, dir, fs.ErrNotExist)
 }
 
+// ErrOutsideBase is returned by ConfinePath when a path resolves outside its base directory.
+var ErrOutsideBase = errors.New("path is outside the base directory")
 
+// ConfinePath reports whether path stays inside base after resolving symbolic links.
+// The lexical checks done by callers (rejecting ".." and absolute paths) cannot catch
+// a symlink inside base that points elsewhere; this function follows such links.
+//
+// path does not need to exist (e.g. a file about to be created): the longest existing
+// ancestor is resolved and the remaining components are appended unchanged.
+//
+// Returns nil if path is inside base, an error wrapping ErrOutsideBase if it escapes,
+// or another error if base cannot be resolved.
+func ConfinePath(base, path string) error {
+	realBase, err := filepath.EvalSymlinks(base)
+	if err != nil {
+		return fmt.Errorf("cannot resolve base directory %s: %w", base, err)
+	}
+	realBase, err = filepath.Abs(realBase)
+	if err != nil {
+		return fmt.Errorf("cannot resolve base directory %s: %w", base, err)
+	}
+
+	realPath, err := evalExistingPrefix(path)
+	if err != nil {
+		return fmt.Errorf("cannot resolve path %s: %w", path, err)
+	}
+
+	rel, err := filepath.Rel(realBase, realPath)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return fmt.Errorf("%s: %w", path, ErrOutsideBase)
+	}
+	return nil
+}
+
+// evalExistingPrefix resolves symbolic links in the longest existing ancestor of path
+// and returns the absolute result with the non-existent remainder appended.
+func evalExistingPrefix(path string) (string, error) {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return "", err
+	}
+
+	var rest []string
+	current := abs
+	for {
+		resolved, err := filepath.EvalSymlinks(current)
+		if err == nil {
+			return filepath.Join(append([]string{resolved}, rest...)...), nil
+		}
+		if !errors.Is(err, fs.ErrNotExist) {
+			return "", err
+		}
+		parent := filepath.Dir(current)
+		if parent == current {
+			return abs, nil
+		}
+		rest = append([]string{filepath.Base(current)}, rest...)
+		current = parent
+	}
+}
@@ -1,6 +1,8 @@
 package fileutil
 
 import (
+	"errors"
+	"io/fs"
 	"os"
 	"path/filepath"
 	"testing"
@@ -90,10 +92,217 @@ func TestFindFileCaseInsensitive(t *testing.T) {
 			} else {
 				if err == nil {
 					t.Errorf("Expected error for non-existent file, but got path: %s", path)
+				} else if !errors.Is(err, fs.ErrNotExist) {
+					t.Errorf("Expected error to wrap fs.ErrNotExist, got: %v", err)
 				}
 			}
 		})
 	}
 }
 
+func TestConfinePath(t *testing.T) {
+	base := t.TempDir()
+	outside := t.TempDir()
 
+	if err := os.MkdirAll(filepath.Join(base, "data"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(base, "data", "score.txt"), []byte("1"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(outside, "secret.txt"), []byte("secret"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(outside, filepath.Join(base, "link")); err != nil {
+		t.Skipf("symlinks not supported: %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		path    string
+		outside bool
+	}{
+		{"existing file", filepath.Join(base, "data", "score.txt"), false},
+		{"new file", filepath.Join(base, "data", "new.txt"), false},
+		{"new directory", filepath.Join(base, "newdir", "new.txt"), false},
+		{"base itself", base, false},
+		{"symlink to outside", filepath.Join(base, "link", "secret.txt"), true},
+		{"new file through symlink", filepath.Join(base, "link", "new.txt"), true},
+		{"outside directory", filepath.Join(outside, "secret.txt"), true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ConfinePath(base, tt.path)
+			if tt.outside {
+				if !errors.Is(err, ErrOutsideBase) {
+					t.Errorf("ConfinePath(%s) = %v, want ErrOutsideBase", tt.path, err)
+				}
+			} else if err != nil {
+				t.Errorf("ConfinePath(%s) = %v, want nil", tt.path, err)
+			}
+		})
+	}
+}
+
+func TestConfinedRealFS(t *testing.T) {
+	base := t.TempDir()
+	outside := t.TempDir()
+
+	if err := os.WriteFile(filepath.Join(base, "IMAGE.BMP"), []byte("bmp"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(outside, "secret.txt"), []byte("secret"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(filepath.Join(outside, "secret.txt"), filepath.Join(base, "secret.txt")); err != nil {
+		t.Skipf("symlinks not supported: %v", err)
+	}
+
+	confined := NewConfinedRealFS(base)
+	if !confined.IsConfined() || NewRealFS(base).IsConfined() {
+		t.Error("IsConfined should be true only for NewConfinedRealFS")
+	}
+	if _, err := confined.ReadFile("image.bmp"); err != nil {
+		t.Errorf("confined ReadFile of a file inside base failed: %v", err)
+	}
+	if _, err := confined.ReadFile("secret.txt"); !errors.Is(err, ErrOutsideBase) {
+		t.Errorf("confined ReadFile through symlink = %v, want ErrOutsideBase", err)
+	}
+	if _, err := confined.Open("secret.txt"); !errors.Is(err, ErrOutsideBase) {
+		t.Errorf("confined Open through symlink = %v, want ErrOutsideBase", err)
+	}
+
+	// 通常のRealFSはシンボリックリンクを辿る（従来の動作）
+	if _, err := NewRealFS(base).ReadFile("secret.txt"); err != nil {
+		t.Errorf("unconfined ReadFile through symlink failed: %v", err)
+	}
+}
+
+func TestWithSearchDirs(t *testing.T) {
+	base := t.TempDir()
+	for _, dir := range []string{"pics", "hires"} {
+		if err := os.Mkdir(filepath.Join(base, dir), 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for name, content := range map[string]string{
+		"TOP.BMP":        "top",
+		"pics/A.BMP":     "pics",
+		"hires/A.BMP":    "hires",
+		"hires/B.BMP":    "hires-b",
+		"pics/TOP.BMP":   "shadowed",
+		"pics/sub.txt":   "sub",
+		"hires/ONLY.MID": "midi",
+	} {
+		if err := os.WriteFile(filepath.Join(base, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	realFS := NewRealFS(base)
+	if got := WithSearchDirs(realFS, nil); got != FileSystem(realFS) {
+		t.Error("expected WithSearchDirs without directories to return the file system unchanged")
+	}
+
+	fsys := WithSearchDirs(realFS, []string{"pics", "hires"})
+	for name, want := range map[string]string{
+		"top.bmp":  "top",     // タイトルのディレクトリを優先
+		"a.bmp":    "pics",    // 先に指定したディレクトリを優先
+		"/B.BMP":   "hires-b", // 先頭の "/" はタイトルのディレクトリからのパス
+		"only.mid": "midi",
+		"SUB.TXT":  "sub",
+	} {
+		data, err := fsys.ReadFile(name)
+		if err != nil || string(data) != want {
+			t.Errorf("ReadFile(%q) = (%q, %v), want %q", name, data, err, want)
+		}
+		f, err := fsys.Open(name)
+		if err != nil {
+			t.Errorf("Open(%q) failed: %v", name, err)
+			continue
+		}
+		f.Close()
+	}
+
+	if _, err := fsys.ReadFile("missing.bmp"); err == nil {
+		t.Error("expected an error for a file that is in none of the directories")
+	}
+	if fsys.BasePath() != base || fsys.IsEmbedded() {
+		t.Error("expected the other methods to be delegated to the wrapped file system")
+	}
+}
+
+func TestParseVar(t *testing.T) {
+	name, value, err := ParseVar("ASSETS=hires/pics")
+	if err != nil || name != "ASSETS" || value != "hires/pics" {
+		t.Errorf("ParseVar = (%q, %q, %v)", name, value, err)
+	}
+	if name, value, err := ParseVar("_X1="); err != nil || name != "_X1" || value != "" {
+		t.Errorf("ParseVar with an empty value = (%q, %q, %v)", name, value, err)
+	}
+	for _, invalid := range []string{"ASSETS", "=hires", "1X=a", "A-B=c", "A B=c"} {
+		if _, _, err := ParseVar(invalid); err == nil {
+			t.Errorf("ParseVar(%q) should fail", invalid)
+		}
+	}
+}
+
+func TestExpandVars(t *testing.T) {
+	vars := map[string]string{"ASSETS": "hires", "EXT": "BMP"}
+	tests := []struct {
+		in, want string
+		wantErr  bool
+	}{
+		{in: "pic01.bmp", want: "pic01.bmp"},
+		{in: "${ASSETS}/pic01.bmp", want: "hires/pic01.bmp"},
+		{in: "${ASSETS}/pic01.${EXT}", want: "hires/pic01.BMP"},
+		{in: "$ASSETS/a.bmp", want: "$ASSETS/a.bmp"}, // a "$" without "{" is kept
+		{in: "${MISSING}/a.bmp", wantErr: true},
+		{in: "${ASSETS/a.bmp", wantErr: true},
+		{in: "${assets}/a.bmp", wantErr: true}, // variable names are case-sensitive
+	}
+	for _, tt := range tests {
+		got, err := ExpandVars(tt.in, vars)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("ExpandVars(%q) = (%q, %v), want %q (error: %v)", tt.in, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+func TestWithVars(t *testing.T) {
+	base := t.TempDir()
+	if err := os.Mkdir(filepath.Join(base, "hires"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(base, "hires", "A.BMP"), []byte("hires"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	realFS := NewRealFS(base)
+	if got := WithVars(realFS, nil); got != FileSystem(realFS) {
+		t.Error("expected WithVars without variables to return the file system unchanged")
+	}
+
+	fsys := WithVars(realFS, map[string]string{"ASSETS": "hires"})
+	if data, err := fsys.ReadFile("${ASSETS}/a.bmp"); err != nil || string(data) != "hires" {
+		t.Errorf("ReadFile = (%q, %v), want %q", data, err, "hires")
+	}
+	f, err := fsys.Open("${ASSETS}/A.BMP")
+	if err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+	f.Close()
+	if entries, err := fsys.ReadDir("${ASSETS}"); err != nil || len(entries) != 1 {
+		t.Errorf("ReadDir = (%d entries, %v), want 1", len(entries), err)
+	}
+	if _, err := fsys.FindFile("${ASSETS}", "a.bmp"); err != nil {
+		t.Errorf("FindFile failed: %v", err)
+	}
+
+	_, err = fsys.ReadFile("${OTHER}/a.bmp")
+	var pathErr *fs.PathError
+	if !errors.As(err, &pathErr) {
+		t.Errorf("undefined variable: expected a *fs.PathError, got %v", err)
+	}
+}
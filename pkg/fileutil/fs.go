@@ -29,6 +29,7 @@ type FileSystem interface {
 // RealFS は実ファイルシステムへのアクセスを提供する
 type RealFS struct {
 	basePath string
+	confined bool // シンボリックリンクを辿った先も basePath 内に制限する（サンドボックスモード）
 }
 
 // NewRealFS は実ファイルシステム用のFileSystemを作成する
@@ -36,12 +37,21 @@ func NewRealFS(basePath string) *RealFS {
 	return &RealFS{basePath: basePath}
 }
 
+// NewConfinedRealFS は basePath の外を指すシンボリックリンクを辿らない実ファイルシステムを作成する
+// サンドボックスモードで、ダウンロードしたタイトルからホストのファイルを読ませないために使用する
+func NewConfinedRealFS(basePath string) *RealFS {
+	return &RealFS{basePath: basePath, confined: true}
+}
+
 func (r *RealFS) Open(name string) (fs.File, error) {
 	path := r.resolvePath(name)
 	actualPath, err := r.findFileCaseInsensitive(path)
 	if err != nil {
 		return nil, err
 	}
+	if err := r.confine(actualPath); err != nil {
+		return nil, err
+	}
 	return os.Open(actualPath)
 }
 
@@ -51,14 +61,28 @@ func (r *RealFS) ReadFile(name string) ([]byte, error) {
 	if err != nil {
 		return nil, err
 	}
+	if err := r.confine(actualPath); err != nil {
+		return nil, err
+	}
 	return os.ReadFile(actualPath)
 }
 
 func (r *RealFS) ReadDir(name string) ([]fs.DirEntry, error) {
 	path := r.resolvePath(name)
+	if err := r.confine(path); err != nil {
+		return nil, err
+	}
 	return os.ReadDir(path)
 }
 
+// confine は confined が有効な場合に、path が basePath の外を指していないかを確認する
+func (r *RealFS) confine(path string) error {
+	if !r.confined || r.basePath == "" {
+		return nil
+	}
+	return ConfinePath(r.basePath, path)
+}
+
 func (r *RealFS) FindFile(dir, filename string) (string, error) {
 	searchDir := dir
 	if r.basePath != "" && !filepath.IsAbs(dir) {
@@ -75,6 +99,11 @@ func (r *RealFS) IsEmbedded() bool {
 	return false
 }
 
+// IsConfined はシンボリックリンクを辿った先も basePath 内に制限しているかを返す
+func (r *RealFS) IsConfined() bool {
+	return r.confined
+}
+
 func (r *RealFS) resolvePath(name string) string {
 	// 先頭の "/" や "\" を除去
 	cleanName := strings.TrimPrefix(strings.TrimPrefix(name, "/"), "\\")
@@ -0,0 +1,66 @@
+package fileutil
+
+import (
+	"io/fs"
+	"path"
+	"strings"
+)
+
+// SearchFS は見つからないファイルを追加のディレクトリからも探す FileSystem
+// プロジェクトマニフェストの assets で指定したディレクトリにアセットを置けるようにする。
+// ファイル名はまずそのまま探し、見つからない場合に各ディレクトリを先頭から順に探す。
+type SearchFS struct {
+	FileSystem
+	dirs []string // ベースパスからの相対パス（"/" 区切り）
+}
+
+// WithSearchDirs は fsys で見つからないファイルを dirs からも探す FileSystem を返す
+// dirs が空の場合は fsys をそのまま返す
+func WithSearchDirs(fsys FileSystem, dirs []string) FileSystem {
+	if len(dirs) == 0 {
+		return fsys
+	}
+	clean := make([]string, len(dirs))
+	for i, dir := range dirs {
+		clean[i] = strings.ReplaceAll(dir, "\\", "/")
+	}
+	return &SearchFS{FileSystem: fsys, dirs: clean}
+}
+
+// Open はファイルを開く（見つからない場合は追加のディレクトリから探す）
+func (s *SearchFS) Open(name string) (fs.File, error) {
+	f, err := s.FileSystem.Open(name)
+	if err == nil {
+		return f, nil
+	}
+	for _, candidate := range s.candidates(name) {
+		if f, cerr := s.FileSystem.Open(candidate); cerr == nil {
+			return f, nil
+		}
+	}
+	return nil, err
+}
+
+// ReadFile はファイルの内容を読み込む（見つからない場合は追加のディレクトリから探す）
+func (s *SearchFS) ReadFile(name string) ([]byte, error) {
+	data, err := s.FileSystem.ReadFile(name)
+	if err == nil {
+		return data, nil
+	}
+	for _, candidate := range s.candidates(name) {
+		if data, cerr := s.FileSystem.ReadFile(candidate); cerr == nil {
+			return data, nil
+		}
+	}
+	return nil, err
+}
+
+// candidates は追加のディレクトリでの name のパスを探す順に返す
+func (s *SearchFS) candidates(name string) []string {
+	name = strings.TrimLeft(strings.ReplaceAll(name, "\\", "/"), "/")
+	paths := make([]string, len(s.dirs))
+	for i, dir := range s.dirs {
+		paths[i] = path.Join(dir, name)
+	}
+	return paths
+}
@@ -0,0 +1,128 @@
+package fileutil
+
+import (
+	"fmt"
+	"io/fs"
+	"strings"
+)
+
+// Asset path variables
+//
+// A variable in a script's file name, as in "${ASSETS}/pic01.bmp", is replaced with the
+// value given by the project manifest's vars or by -A ASSETS=hires on the command line
+// before the file is looked up. This runs the same script with different assets, such
+// as a high-resolution and the original version. A "$" not followed by "{" is kept as
+// part of the file name.
+
+// ParseVar parses a variable definition of the form "NAME=value".
+// The name starts with a letter or underscore and contains only letters, digits and underscores.
+func ParseVar(s string) (name, value string, err error) {
+	name, value, ok := strings.Cut(s, "=")
+	if !ok {
+		return "", "", fmt.Errorf("asset variable must be NAME=value, got %q", s)
+	}
+	if !validVarName(name) {
+		return "", "", fmt.Errorf("invalid asset variable name %q (letters, digits and _ only)", name)
+	}
+	return name, value, nil
+}
+
+// validVarName reports whether name is a valid variable name.
+func validVarName(name string) bool {
+	if name == "" {
+		return false
+	}
+	for i, r := range name {
+		switch {
+		case r == '_', 'A' <= r && r <= 'Z', 'a' <= r && r <= 'z':
+		case '0' <= r && r <= '9' && i > 0:
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// ExpandVars replaces each ${NAME} in name with its value in vars.
+// It returns an error for undefined variables and an unterminated "${".
+func ExpandVars(name string, vars map[string]string) (string, error) {
+	if !strings.Contains(name, "${") {
+		return name, nil
+	}
+	var b strings.Builder
+	rest := name
+	for {
+		start := strings.Index(rest, "${")
+		if start < 0 {
+			b.WriteString(rest)
+			return b.String(), nil
+		}
+		end := strings.IndexByte(rest[start:], '}')
+		if end < 0 {
+			return "", fmt.Errorf("unterminated asset variable in %q", name)
+		}
+		varName := rest[start+2 : start+end]
+		value, ok := vars[varName]
+		if !ok {
+			return "", fmt.Errorf("undefined asset variable ${%s} in %q", varName, name)
+		}
+		b.WriteString(rest[:start])
+		b.WriteString(value)
+		rest = rest[start+end+1:]
+	}
+}
+
+// VarsFS is a FileSystem that expands ${NAME} in file names before opening them.
+type VarsFS struct {
+	FileSystem
+	vars map[string]string
+}
+
+// WithVars returns a FileSystem that expands file name variables with vars.
+// It returns fsys itself if vars is empty.
+func WithVars(fsys FileSystem, vars map[string]string) FileSystem {
+	if len(vars) == 0 {
+		return fsys
+	}
+	return &VarsFS{FileSystem: fsys, vars: vars}
+}
+
+// Open opens the file after expanding variables.
+func (v *VarsFS) Open(name string) (fs.File, error) {
+	expanded, err := ExpandVars(name, v.vars)
+	if err != nil {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: err}
+	}
+	return v.FileSystem.Open(expanded)
+}
+
+// ReadFile reads the file after expanding variables.
+func (v *VarsFS) ReadFile(name string) ([]byte, error) {
+	expanded, err := ExpandVars(name, v.vars)
+	if err != nil {
+		return nil, &fs.PathError{Op: "read", Path: name, Err: err}
+	}
+	return v.FileSystem.ReadFile(expanded)
+}
+
+// ReadDir reads the directory after expanding variables.
+func (v *VarsFS) ReadDir(name string) ([]fs.DirEntry, error) {
+	expanded, err := ExpandVars(name, v.vars)
+	if err != nil {
+		return nil, &fs.PathError{Op: "readdir", Path: name, Err: err}
+	}
+	return v.FileSystem.ReadDir(expanded)
+}
+
+// FindFile looks up the file after expanding variables in dir and filename.
+func (v *VarsFS) FindFile(dir, filename string) (string, error) {
+	expandedDir, err := ExpandVars(dir, v.vars)
+	if err != nil {
+		return "", err
+	}
+	expandedName, err := ExpandVars(filename, v.vars)
+	if err != nil {
+		return "", err
+	}
+	return v.FileSystem.FindFile(expandedDir, expandedName)
+}
@@ -0,0 +1,208 @@
+// Package gifexport は仮想デスクトップの描画結果をアニメーションGIFとして書き出す機能を提供する。
+//
+// --export-gif start:end output.gif で指定された時間範囲のフレームを一定のFPSで取り込み、
+// 256色に減色してアニメーションGIFを生成する。
+// フレームの取り込み（ヘッドレスモードのソフトウェア描画による画面の合成）は呼び出し側が行い、
+// このパッケージは範囲の解析・取り込みタイミングの判定・減色・エンコードのみを担当する。
+package gifexport
+
+import (
+	"errors"
+	"fmt"
+	"image"
+	"image/gif"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DefaultFPS はGIFの既定のフレームレート
+const DefaultFPS = 10
+
+// MaxFPS はGIFで指定できる最大フレームレート。
+// GIFのフレーム遅延は1/100秒単位のため、これより大きい値は表現できない。
+const MaxFPS = 50
+
+// TickDuration は範囲指定でティック（"t"サフィックス）を使った場合の1ティックの長さ。
+// TIMEイベントの既定間隔（audio.DefaultTimerInterval）と同じ値。
+const TickDuration = 50 * time.Millisecond
+
+// gifDelayUnitsPerSecond はGIFのフレーム遅延の単位（1/100秒）
+const gifDelayUnitsPerSecond = 100
+
+// Range は書き出す時間範囲（VM開始からの経過時間）
+type Range struct {
+	Start time.Duration
+	End   time.Duration
+}
+
+// Duration は範囲の長さを返す
+func (r Range) Duration() time.Duration {
+	return r.End - r.Start
+}
+
+// String は範囲を "start:end" 形式で返す
+func (r Range) String() string {
+	return fmt.Sprintf("%s:%s", r.Start, r.End)
+}
+
+// ParseRange は "start:end" 形式の範囲指定を解析する。
+// 各端点は以下の形式で指定できる:
+//   - "2" / "2.5s": 秒
+//   - "1500ms": ミリ秒
+//   - "40t": ティック（TIMEイベント単位、1ティック=50ms）
+func ParseRange(s string) (Range, error) {
+	startStr, endStr, ok := strings.Cut(s, ":")
+	if !ok {
+		return Range{}, fmt.Errorf("invalid range %q: expected start:end", s)
+	}
+
+	start, err := parsePoint(startStr)
+	if err != nil {
+		return Range{}, fmt.Errorf("invalid range start %q: %w", startStr, err)
+	}
+	end, err := parsePoint(endStr)
+	if err != nil {
+		return Range{}, fmt.Errorf("invalid range end %q: %w", endStr, err)
+	}
+	if end <= start {
+		return Range{}, fmt.Errorf("invalid range %q: end must be after start", s)
+	}
+
+	return Range{Start: start, End: end}, nil
+}
+
+// parsePoint は範囲の端点を時間に変換する
+func parsePoint(s string) (time.Duration, error) {
+	s = strings.TrimSpace(strings.ToLower(s))
+	if s == "" {
+		return 0, errors.New("empty value")
+	}
+
+	var d time.Duration
+	switch {
+	case strings.HasSuffix(s, "ms"):
+		n, err := strconv.ParseFloat(strings.TrimSuffix(s, "ms"), 64)
+		if err != nil {
+			return 0, err
+		}
+		d = time.Duration(n * float64(time.Millisecond))
+	case strings.HasSuffix(s, "t"):
+		n, err := strconv.Atoi(strings.TrimSuffix(s, "t"))
+		if err != nil {
+			return 0, err
+		}
+		d = time.Duration(n) * TickDuration
+	default:
+		n, err := strconv.ParseFloat(strings.TrimSuffix(s, "s"), 64)
+		if err != nil {
+			return 0, err
+		}
+		d = time.Duration(n * float64(time.Second))
+	}
+
+	if d < 0 {
+		return 0, errors.New("must be non-negative")
+	}
+	return d, nil
+}
+
+// Recorder は指定範囲のフレームを一定間隔で取り込み、GIFとして書き出す。
+// 呼び出し側は画面が変わるたびに Due（または ShouldCapture）で取り込みが必要か確認し、
+// 必要なら AddFrame で画面イメージを渡す。Done が true になったら取り込みを終了する。
+type Recorder struct {
+	rng      Range
+	fps      int
+	interval time.Duration
+	next     time.Duration // 次に取り込む経過時間
+	frames   []*image.Paletted
+	delays   []int
+}
+
+// NewRecorder は指定した範囲とFPSでRecorderを作成する。
+// fps が0以下の場合は DefaultFPS を使用する。
+func NewRecorder(rng Range, fps int) (*Recorder, error) {
+	if fps <= 0 {
+		fps = DefaultFPS
+	}
+	if fps > MaxFPS {
+		return nil, fmt.Errorf("fps must be at most %d, got %d", MaxFPS, fps)
+	}
+	if rng.End <= rng.Start {
+		return nil, fmt.Errorf("invalid range %s: end must be after start", rng)
+	}
+
+	return &Recorder{
+		rng:      rng,
+		fps:      fps,
+		interval: time.Second / time.Duration(fps),
+		next:     rng.Start,
+	}, nil
+}
+
+// ShouldCapture は経過時間 elapsed の時点でフレームを取り込むべきかどうかを返す。
+// 描画が遅れて複数の取り込み時刻を過ぎた場合も、取り込むのは1フレームだけ。
+func (r *Recorder) ShouldCapture(elapsed time.Duration) bool {
+	return r.Due(elapsed) > 0
+}
+
+// Due は経過時間 elapsed までに過ぎた取り込み時刻の数を返し、次の取り込み時刻を進める。
+// 画面がティックごとにしか変わらない場合、同じフレームをこの数だけ追加すれば、
+// FPSがティックの頻度より高くてもGIFの再生時間が範囲の長さと揃う。
+func (r *Recorder) Due(elapsed time.Duration) int {
+	if elapsed < r.rng.Start || elapsed >= r.rng.End || elapsed < r.next {
+		return 0
+	}
+
+	n := 0
+	for r.next <= elapsed {
+		r.next += r.interval
+		n++
+	}
+	return n
+}
+
+// AddFrame はフレームを256色に減色して追加する
+func (r *Recorder) AddFrame(img image.Image) {
+	r.frames = append(r.frames, Quantize(img, MaxColors))
+	r.delays = append(r.delays, gifDelayUnitsPerSecond/r.fps)
+}
+
+// Done は範囲の終端に達したかどうかを返す
+func (r *Recorder) Done(elapsed time.Duration) bool {
+	return elapsed >= r.rng.End
+}
+
+// FrameCount は取り込んだフレーム数を返す
+func (r *Recorder) FrameCount() int {
+	return len(r.frames)
+}
+
+// Encode は取り込んだフレームをアニメーションGIFとして書き出す
+func (r *Recorder) Encode(w io.Writer) error {
+	if len(r.frames) == 0 {
+		return errors.New("no frames captured")
+	}
+
+	return gif.EncodeAll(w, &gif.GIF{
+		Image:     r.frames,
+		Delay:     r.delays,
+		LoopCount: 0, // 無限ループ
+	})
+}
+
+// WriteFile は取り込んだフレームをGIFファイルに書き出す
+func (r *Recorder) WriteFile(path string) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("failed to create %s: %w", path, err)
+	}
+
+	if err := r.Encode(f); err != nil {
+		f.Close()
+		return fmt.Errorf("failed to encode %s: %w", path, err)
+	}
+	return f.Close()
+}
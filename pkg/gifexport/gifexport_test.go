@@ -0,0 +1,228 @@
+package gifexport
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/gif"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// helperSolidImage creates an RGBA image filled with a single color.
+func helperSolidImage(w, h int, c color.RGBA) *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			img.SetRGBA(x, y, c)
+		}
+	}
+	return img
+}
+
+// TestParseRange tests the supported range formats.
+func TestParseRange(t *testing.T) {
+	tests := []struct {
+		input string
+		start time.Duration
+		end   time.Duration
+	}{
+		{"2:5", 2 * time.Second, 5 * time.Second},
+		{"0:1.5", 0, 1500 * time.Millisecond},
+		{"2s:5.5s", 2 * time.Second, 5500 * time.Millisecond},
+		{"1500ms:3000ms", 1500 * time.Millisecond, 3 * time.Second},
+		{"40t:100t", 2 * time.Second, 5 * time.Second},
+		{"1:60t", time.Second, 3 * time.Second},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			rng, err := ParseRange(tt.input)
+			if err != nil {
+				t.Fatalf("ParseRange(%q) failed: %v", tt.input, err)
+			}
+			if rng.Start != tt.start || rng.End != tt.end {
+				t.Errorf("ParseRange(%q) = %v, expected %v:%v", tt.input, rng, tt.start, tt.end)
+			}
+		})
+	}
+}
+
+// TestParseRange_Invalid tests that malformed ranges are rejected.
+func TestParseRange_Invalid(t *testing.T) {
+	tests := []string{
+		"",
+		"5",
+		":5",
+		"2:",
+		"abc:5",
+		"5:2",
+		"3:3",
+		"-1:2",
+		"1.5t:3t",
+	}
+
+	for _, input := range tests {
+		if _, err := ParseRange(input); err == nil {
+			t.Errorf("ParseRange(%q) should fail", input)
+		}
+	}
+}
+
+// TestNewRecorder tests FPS defaults and validation.
+func TestNewRecorder(t *testing.T) {
+	rng := Range{Start: 0, End: time.Second}
+
+	rec, err := NewRecorder(rng, 0)
+	if err != nil {
+		t.Fatalf("NewRecorder failed: %v", err)
+	}
+	if rec.fps != DefaultFPS {
+		t.Errorf("fps = %d, expected default %d", rec.fps, DefaultFPS)
+	}
+
+	if _, err := NewRecorder(rng, MaxFPS+1); err == nil {
+		t.Error("NewRecorder should reject fps above MaxFPS")
+	}
+	if _, err := NewRecorder(Range{Start: time.Second, End: time.Second}, DefaultFPS); err == nil {
+		t.Error("NewRecorder should reject an empty range")
+	}
+}
+
+// TestRecorderShouldCapture tests that frames are captured at the requested rate within the range.
+func TestRecorderShouldCapture(t *testing.T) {
+	rec, err := NewRecorder(Range{Start: time.Second, End: 2 * time.Second}, 10)
+	if err != nil {
+		t.Fatalf("NewRecorder failed: %v", err)
+	}
+
+	// 60FPSで描画された場合をシミュレートする
+	frameTime := time.Second / 60
+	captured := 0
+	for elapsed := time.Duration(0); !rec.Done(elapsed); elapsed += frameTime {
+		if rec.ShouldCapture(elapsed) {
+			if elapsed < time.Second || elapsed >= 2*time.Second {
+				t.Errorf("captured outside range at %v", elapsed)
+			}
+			captured++
+		}
+	}
+
+	if captured != 10 {
+		t.Errorf("captured %d frames, expected 10", captured)
+	}
+}
+
+// TestRecorderShouldCapture_SlowRendering tests that a stalled renderer does not produce a burst of frames.
+func TestRecorderShouldCapture_SlowRendering(t *testing.T) {
+	rec, err := NewRecorder(Range{Start: 0, End: time.Second}, 10)
+	if err != nil {
+		t.Fatalf("NewRecorder failed: %v", err)
+	}
+
+	if !rec.ShouldCapture(0) {
+		t.Fatal("first frame should be captured")
+	}
+	// 500ms描画が止まった後は1フレームだけ取り込む
+	if !rec.ShouldCapture(500 * time.Millisecond) {
+		t.Fatal("frame after stall should be captured")
+	}
+	if rec.ShouldCapture(510 * time.Millisecond) {
+		t.Error("should not capture again before the next interval")
+	}
+	if !rec.ShouldCapture(600 * time.Millisecond) {
+		t.Error("should capture at the next interval")
+	}
+}
+
+// TestRecorderDue tests that Due counts every capture time passed since the last call.
+func TestRecorderDue(t *testing.T) {
+	rec, err := NewRecorder(Range{Start: 0, End: time.Second}, 50)
+	if err != nil {
+		t.Fatalf("NewRecorder failed: %v", err)
+	}
+
+	// 50FPS（20ms間隔）で50msのティックごとに呼び出すと、0ms, 20ms, 40ms の3つが過ぎている
+	if n := rec.Due(50 * time.Millisecond); n != 3 {
+		t.Errorf("Due(50ms) = %d, want 3", n)
+	}
+	if n := rec.Due(100 * time.Millisecond); n != 3 {
+		t.Errorf("Due(100ms) = %d, want 3", n)
+	}
+	if n := rec.Due(110 * time.Millisecond); n != 0 {
+		t.Errorf("Due(110ms) = %d, want 0 before the next capture time", n)
+	}
+	if n := rec.Due(time.Second); n != 0 {
+		t.Errorf("Due at the end of the range = %d, want 0", n)
+	}
+}
+
+// TestRecorderEncode tests that captured frames are written as an animated GIF.
+func TestRecorderEncode(t *testing.T) {
+	rec, err := NewRecorder(Range{Start: 0, End: time.Second}, 20)
+	if err != nil {
+		t.Fatalf("NewRecorder failed: %v", err)
+	}
+
+	rec.AddFrame(helperSolidImage(8, 6, color.RGBA{R: 255, A: 255}))
+	rec.AddFrame(helperSolidImage(8, 6, color.RGBA{B: 255, A: 255}))
+	if rec.FrameCount() != 2 {
+		t.Fatalf("FrameCount = %d, expected 2", rec.FrameCount())
+	}
+
+	var buf bytes.Buffer
+	if err := rec.Encode(&buf); err != nil {
+		t.Fatalf("Encode failed: %v", err)
+	}
+
+	decoded, err := gif.DecodeAll(&buf)
+	if err != nil {
+		t.Fatalf("DecodeAll failed: %v", err)
+	}
+	if len(decoded.Image) != 2 {
+		t.Fatalf("decoded %d frames, expected 2", len(decoded.Image))
+	}
+	if decoded.Delay[0] != 5 {
+		t.Errorf("delay = %d, expected 5 (1/100s units at 20fps)", decoded.Delay[0])
+	}
+	if decoded.Config.Width != 8 || decoded.Config.Height != 6 {
+		t.Errorf("size = %dx%d, expected 8x6", decoded.Config.Width, decoded.Config.Height)
+	}
+
+	r, g, b, _ := decoded.Image[1].At(0, 0).RGBA()
+	if r != 0 || g != 0 || b>>8 != 255 {
+		t.Errorf("second frame color = (%d,%d,%d), expected blue", r>>8, g>>8, b>>8)
+	}
+}
+
+// TestRecorderEncode_NoFrames tests that encoding without frames is an error.
+func TestRecorderEncode_NoFrames(t *testing.T) {
+	rec, err := NewRecorder(Range{Start: 0, End: time.Second}, DefaultFPS)
+	if err != nil {
+		t.Fatalf("NewRecorder failed: %v", err)
+	}
+
+	var buf bytes.Buffer
+	if err := rec.Encode(&buf); err == nil {
+		t.Error("Encode should fail without frames")
+	}
+}
+
+// TestRecorderWriteFile tests writing the GIF to disk.
+func TestRecorderWriteFile(t *testing.T) {
+	rec, err := NewRecorder(Range{Start: 0, End: time.Second}, DefaultFPS)
+	if err != nil {
+		t.Fatalf("NewRecorder failed: %v", err)
+	}
+	rec.AddFrame(helperSolidImage(4, 4, color.RGBA{G: 255, A: 255}))
+
+	path := filepath.Join(t.TempDir(), "out.gif")
+	if err := rec.WriteFile(path); err != nil {
+		t.Fatalf("WriteFile failed: %v", err)
+	}
+
+	if err := rec.WriteFile(filepath.Join(t.TempDir(), "missing", "out.gif")); err == nil {
+		t.Error("WriteFile should fail for a missing directory")
+	}
+}
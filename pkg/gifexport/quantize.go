@@ -0,0 +1,234 @@
+package gifexport
+
+import (
+	"image"
+	"image/color"
+	"sort"
+)
+
+// MaxColors はGIFの1フレームで使用できる最大色数
+const MaxColors = 256
+
+// 色を集計するときのRGB各成分のビット数（5ビット = 32階調）
+const (
+	bucketBits  = 5
+	bucketShift = 8 - bucketBits
+)
+
+// colorKey は不透明なRGB値を1つの整数にまとめたもの
+type colorKey uint32
+
+func keyOf(r, g, b uint8) colorKey {
+	return colorKey(r)<<16 | colorKey(g)<<8 | colorKey(b)
+}
+
+func bucketOf(r, g, b uint8) colorKey {
+	return colorKey(r>>bucketShift)<<(2*bucketBits) | colorKey(g>>bucketShift)<<bucketBits | colorKey(b>>bucketShift)
+}
+
+// bucketStat は量子化バケットごとの色の合計と画素数
+type bucketStat struct {
+	rSum, gSum, bSum uint64
+	count            uint64
+}
+
+// Quantize は画像を最大 maxColors 色のパレット画像に変換する。
+//
+// 使用色数が maxColors 以下の場合（256色のFILLY素材など）はそのままの色でパレットを作るため劣化しない。
+// それを超える場合は各成分を5ビットに丸めたバケットで色を集計し、
+// メディアンカット法で作成したパレットの最も近い色に割り当てる。
+// アルファは無視する（GIFは不透明な画面のキャプチャに使用するため）。
+func Quantize(img image.Image, maxColors int) *image.Paletted {
+	if maxColors <= 0 || maxColors > MaxColors {
+		maxColors = MaxColors
+	}
+
+	bounds := img.Bounds()
+	palette := buildPalette(img, maxColors)
+	out := image.NewPaletted(bounds, palette)
+
+	// 同じ色の最近傍探索を繰り返さないようキャッシュする
+	cache := make(map[colorKey]uint8)
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			r, g, b := rgbAt(img, x, y)
+			key := keyOf(r, g, b)
+			idx, ok := cache[key]
+			if !ok {
+				idx = nearestIndex(palette, r, g, b)
+				cache[key] = idx
+			}
+			out.Pix[out.PixOffset(x, y)] = idx
+		}
+	}
+	return out
+}
+
+// buildPalette は画像の色からパレットを作成する
+func buildPalette(img image.Image, maxColors int) color.Palette {
+	bounds := img.Bounds()
+
+	exact := make(map[colorKey]struct{})
+	buckets := make(map[colorKey]*bucketStat)
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			r, g, b := rgbAt(img, x, y)
+			if len(exact) <= maxColors {
+				exact[keyOf(r, g, b)] = struct{}{}
+			}
+
+			bk := bucketOf(r, g, b)
+			st := buckets[bk]
+			if st == nil {
+				st = &bucketStat{}
+				buckets[bk] = st
+			}
+			st.rSum += uint64(r)
+			st.gSum += uint64(g)
+			st.bSum += uint64(b)
+			st.count++
+		}
+	}
+
+	// 色数がパレットに収まる場合は元の色をそのまま使う
+	if len(exact) <= maxColors {
+		keys := make([]colorKey, 0, len(exact))
+		for k := range exact {
+			keys = append(keys, k)
+		}
+		sort.Slice(keys, func(i, j int) bool { return keys[i] < keys[j] })
+
+		palette := make(color.Palette, 0, len(keys))
+		for _, k := range keys {
+			palette = append(palette, color.RGBA{R: uint8(k >> 16), G: uint8(k >> 8), B: uint8(k), A: 0xFF})
+		}
+		if len(palette) == 0 {
+			palette = append(palette, color.RGBA{A: 0xFF})
+		}
+		return palette
+	}
+
+	stats := make([]*bucketStat, 0, len(buckets))
+	for _, st := range buckets {
+		stats = append(stats, st)
+	}
+	// mapの走査順に依存せず結果が決定的になるよう平均色で並べておく
+	sort.Slice(stats, func(i, j int) bool { return avgKey(stats[i]) < avgKey(stats[j]) })
+
+	boxes := medianCut(stats, maxColors)
+	palette := make(color.Palette, 0, len(boxes))
+	for _, box := range boxes {
+		var total bucketStat
+		for _, st := range box {
+			total.rSum += st.rSum
+			total.gSum += st.gSum
+			total.bSum += st.bSum
+			total.count += st.count
+		}
+		k := avgKey(&total)
+		palette = append(palette, color.RGBA{R: uint8(k >> 16), G: uint8(k >> 8), B: uint8(k), A: 0xFF})
+	}
+	return palette
+}
+
+// medianCut はバケットの集合を最大 maxBoxes 個の箱に分割する（メディアンカット法）。
+// 色の広がりが最も大きい箱を、その成分の画素数の中央で2つに分ける操作を繰り返す。
+func medianCut(stats []*bucketStat, maxBoxes int) [][]*bucketStat {
+	boxes := [][]*bucketStat{stats}
+	for len(boxes) < maxBoxes {
+		target, channel, widest := -1, 0, 0
+		for i, box := range boxes {
+			if len(box) < 2 {
+				continue
+			}
+			ch, width := widestChannel(box)
+			if width > widest {
+				target, channel, widest = i, ch, width
+			}
+		}
+		if target < 0 {
+			break // これ以上分割できない
+		}
+
+		box := boxes[target]
+		sort.SliceStable(box, func(i, j int) bool {
+			return channelOf(box[i], channel) < channelOf(box[j], channel)
+		})
+
+		var total, acc uint64
+		for _, st := range box {
+			total += st.count
+		}
+		split := 1
+		for i, st := range box[:len(box)-1] {
+			acc += st.count
+			split = i + 1
+			if acc*2 >= total {
+				break
+			}
+		}
+
+		boxes[target] = box[:split]
+		boxes = append(boxes, box[split:])
+	}
+	return boxes
+}
+
+// widestChannel は箱の中で値の範囲が最も広い成分（0=R, 1=G, 2=B）とその幅を返す
+func widestChannel(box []*bucketStat) (int, int) {
+	bestChannel, bestWidth := 0, 0
+	for ch := 0; ch < 3; ch++ {
+		lo, hi := 255, 0
+		for _, st := range box {
+			v := channelOf(st, ch)
+			lo = min(lo, v)
+			hi = max(hi, v)
+		}
+		if hi-lo > bestWidth {
+			bestChannel, bestWidth = ch, hi-lo
+		}
+	}
+	return bestChannel, bestWidth
+}
+
+// channelOf はバケットの平均色の指定成分を返す
+func channelOf(st *bucketStat, ch int) int {
+	k := avgKey(st)
+	return int(k>>(16-8*ch)) & 0xFF
+}
+
+// avgKey はバケットの平均色を返す
+func avgKey(st *bucketStat) colorKey {
+	return keyOf(uint8(st.rSum/st.count), uint8(st.gSum/st.count), uint8(st.bSum/st.count))
+}
+
+// rgbAt は指定位置の8ビットRGB値を返す
+func rgbAt(img image.Image, x, y int) (uint8, uint8, uint8) {
+	if rgba, ok := img.(*image.RGBA); ok {
+		i := rgba.PixOffset(x, y)
+		return rgba.Pix[i], rgba.Pix[i+1], rgba.Pix[i+2]
+	}
+	r, g, b, _ := img.At(x, y).RGBA()
+	return uint8(r >> 8), uint8(g >> 8), uint8(b >> 8)
+}
+
+// nearestIndex はパレット中で最も近い色のインデックスを返す
+func nearestIndex(palette color.Palette, r, g, b uint8) uint8 {
+	best := 0
+	bestDist := -1
+	for i, c := range palette {
+		pc := c.(color.RGBA)
+		dr := int(pc.R) - int(r)
+		dg := int(pc.G) - int(g)
+		db := int(pc.B) - int(b)
+		dist := dr*dr + dg*dg + db*db
+		if bestDist < 0 || dist < bestDist {
+			best = i
+			bestDist = dist
+			if dist == 0 {
+				break
+			}
+		}
+	}
+	return uint8(best)
+}
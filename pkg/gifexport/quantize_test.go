@@ -0,0 +1,93 @@
+package gifexport
+
+import (
+	"image"
+	"image/color"
+	"testing"
+)
+
+// TestQuantize_ExactColors tests that images with few colors keep their exact colors.
+func TestQuantize_ExactColors(t *testing.T) {
+	img := image.NewRGBA(image.Rect(0, 0, 3, 1))
+	colors := []color.RGBA{
+		{R: 192, G: 192, B: 192, A: 255},
+		{R: 1, G: 2, B: 3, A: 255},
+		{R: 255, G: 128, B: 0, A: 255},
+	}
+	for x, c := range colors {
+		img.SetRGBA(x, 0, c)
+	}
+
+	out := Quantize(img, MaxColors)
+	if len(out.Palette) != len(colors) {
+		t.Fatalf("palette size = %d, expected %d", len(out.Palette), len(colors))
+	}
+	for x, c := range colors {
+		got := out.At(x, 0).(color.RGBA)
+		if got != c {
+			t.Errorf("pixel %d = %v, expected %v", x, got, c)
+		}
+	}
+}
+
+// TestQuantize_ManyColors tests that images with more than 256 colors are reduced to the limit.
+func TestQuantize_ManyColors(t *testing.T) {
+	// 256x256のグラデーション（65536色）
+	const size = 256
+	img := image.NewRGBA(image.Rect(0, 0, size, size))
+	for y := 0; y < size; y++ {
+		for x := 0; x < size; x++ {
+			img.SetRGBA(x, y, color.RGBA{R: uint8(x), G: uint8(y), B: 128, A: 255})
+		}
+	}
+
+	out := Quantize(img, MaxColors)
+	if len(out.Palette) > MaxColors {
+		t.Fatalf("palette size = %d, expected at most %d", len(out.Palette), MaxColors)
+	}
+
+	// 減色後の色は元の色に近いこと
+	const maxChannelError = 48
+	for y := 0; y < size; y += 17 {
+		for x := 0; x < size; x += 17 {
+			got := out.At(x, y).(color.RGBA)
+			if absDiff(got.R, uint8(x)) > maxChannelError || absDiff(got.G, uint8(y)) > maxChannelError {
+				t.Errorf("pixel (%d,%d) = %v, too far from original", x, y, got)
+			}
+		}
+	}
+}
+
+// TestQuantize_MaxColorsLimit tests that a smaller limit is honored.
+func TestQuantize_MaxColorsLimit(t *testing.T) {
+	img := image.NewRGBA(image.Rect(0, 0, 64, 1))
+	for x := 0; x < 64; x++ {
+		img.SetRGBA(x, 0, color.RGBA{R: uint8(x * 4), A: 255})
+	}
+
+	out := Quantize(img, 4)
+	if len(out.Palette) > 4 {
+		t.Errorf("palette size = %d, expected at most 4", len(out.Palette))
+	}
+}
+
+// TestQuantize_Offset tests images whose bounds do not start at the origin.
+func TestQuantize_Offset(t *testing.T) {
+	img := image.NewRGBA(image.Rect(10, 20, 12, 22))
+	img.SetRGBA(11, 21, color.RGBA{R: 255, A: 255})
+
+	out := Quantize(img, MaxColors)
+	if out.Bounds() != img.Bounds() {
+		t.Fatalf("bounds = %v, expected %v", out.Bounds(), img.Bounds())
+	}
+	if got := out.At(11, 21).(color.RGBA); got.R != 255 {
+		t.Errorf("pixel = %v, expected red", got)
+	}
+}
+
+func absDiff(a, b uint8) int {
+	if a > b {
+		return int(a - b)
+	}
+	return int(b - a)
+}
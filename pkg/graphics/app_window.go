@@ -0,0 +1,35 @@
+package graphics
+
+import (
+	"fmt"
+	"image"
+)
+
+// maxAppWindowSize は SetWindowSize で指定できるOSのウィンドウの幅・高さの上限
+const maxAppWindowSize = 8192
+
+// AppWindowSettings はスクリプトが変更したOSのウィンドウの設定
+// （SetWindowTitle / SetWindowIcon / SetWindowSize）
+// 空・0 の項目は変更しない（既定のタイトル、アイコンなし、1024x768 のまま）。
+type AppWindowSettings struct {
+	Title         string
+	IconFile      string // アイコンにした画像ファイル
+	Width, Height int
+}
+
+// appWindowState はOSのウィンドウの設定の反映待ちの状態
+// 実際の反映はゲームループの Update で行う
+type appWindowState struct {
+	settings AppWindowSettings
+	icon     image.Image // SetWindowIcon で読み込んだ画像
+	dirty    bool        // 反映していない変更がある
+	scale    float64     // 実効の表示スケール（0はまだ描画していない）
+}
+
+// checkAppWindowSize はOSのウィンドウのサイズを検証する
+func checkAppWindowSize(width, height int) error {
+	if width <= 0 || height <= 0 || width > maxAppWindowSize || height > maxAppWindowSize {
+		return fmt.Errorf("invalid window size: %dx%d (must be 1-%d)", width, height, maxAppWindowSize)
+	}
+	return nil
+}
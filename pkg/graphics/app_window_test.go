@@ -0,0 +1,85 @@
+//go:build !nogpu
+
+package graphics
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestAppWindowSettings(t *testing.T) {
+	tmpDir := t.TempDir()
+	createTestBMP(t, filepath.Join(tmpDir, "icon.bmp"), 32, 32)
+	gs := NewGraphicsSystem(tmpDir)
+
+	if got := gs.AppWindowSettings(); got != (AppWindowSettings{}) {
+		t.Fatalf("initial settings = %+v, want none", got)
+	}
+
+	gs.SetWindowTitle("My Title")
+	if err := gs.SetWindowIcon("/ICON.BMP"); err != nil {
+		t.Fatalf("SetWindowIcon failed: %v", err)
+	}
+	if err := gs.SetWindowSize(800, 600); err != nil {
+		t.Fatalf("SetWindowSize failed: %v", err)
+	}
+
+	want := AppWindowSettings{Title: "My Title", IconFile: "/ICON.BMP", Width: 800, Height: 600}
+	if got := gs.AppWindowSettings(); got != want {
+		t.Errorf("settings = %+v, want %+v", got, want)
+	}
+	if !gs.appWindow.dirty || gs.appWindow.icon == nil || gs.appWindow.icon.Bounds().Dx() != 32 {
+		t.Error("the changes should be pending until the next Update")
+	}
+}
+
+func TestAppWindowSettingsErrors(t *testing.T) {
+	gs := NewGraphicsSystem(t.TempDir())
+
+	if err := gs.SetWindowIcon("missing.bmp"); err == nil {
+		t.Error("expected error for a missing icon file")
+	}
+	for _, size := range [][2]int{{0, 600}, {800, -1}, {maxAppWindowSize + 1, 600}} {
+		if err := gs.SetWindowSize(size[0], size[1]); err == nil {
+			t.Errorf("expected error for window size %dx%d", size[0], size[1])
+		}
+	}
+	if gs.appWindow.dirty {
+		t.Error("failed calls should not leave pending changes")
+	}
+}
+
+func TestHeadlessAppWindowSettings(t *testing.T) {
+	hgs := NewHeadlessGraphicsSystem()
+
+	hgs.SetWindowTitle("My Title")
+	if err := hgs.SetWindowIcon("icon.bmp"); err != nil {
+		t.Fatalf("SetWindowIcon failed: %v", err)
+	}
+	if err := hgs.SetWindowSize(640, 480); err != nil {
+		t.Fatalf("SetWindowSize failed: %v", err)
+	}
+	if err := hgs.SetWindowSize(0, 0); err == nil {
+		t.Error("expected error for an invalid window size")
+	}
+
+	want := AppWindowSettings{Title: "My Title", IconFile: "icon.bmp", Width: 640, Height: 480}
+	if got := hgs.AppWindowSettings(); got != want {
+		t.Errorf("settings = %+v, want %+v", got, want)
+	}
+}
+
+func TestDisplayScale(t *testing.T) {
+	gs := NewGraphicsSystem(t.TempDir())
+	if got := gs.DisplayScale(); got != 1 {
+		t.Errorf("DisplayScale before drawing = %v, want 1", got)
+	}
+	gs.SetDisplayScale(2)
+	if got := gs.DisplayScale(); got != 2 {
+		t.Errorf("DisplayScale = %v, want 2", got)
+	}
+
+	if got := NewHeadlessGraphicsSystem().DisplayScale(); got != 1 {
+		t.Errorf("headless DisplayScale = %v, want 1", got)
+	}
+}
@@ -0,0 +1,336 @@
+//go:build !nogpu
+
+package graphics
+
+import (
+	"container/list"
+	"fmt"
+	"image"
+	"image/color"
+	"io"
+	"log/slog"
+	"os"
+	"runtime"
+	"sync"
+
+	"github.com/hajimehoshi/ebiten/v2"
+
+	"github.com/zurustar/son-et/pkg/fileutil"
+)
+
+// maxStreamWorkers はストリーミング読み込みで同時にデコードする画像の数の上限
+const maxStreamWorkers = 4
+
+// powerSaveDecodeWorkers は省電力モード（WithPowerSave）でバックグラウンドで同時にデコードする画像の数
+const powerSaveDecodeWorkers = 1
+
+// streamPlaceholderColor はデコードが終わるまでピクチャーを塗りつぶす色
+var streamPlaceholderColor = color.RGBA{0x80, 0x80, 0x80, 0xFF}
+
+// assetStream は画像のストリーミング読み込みの状態
+//
+// LoadPic はファイルをメモリマップしてヘッダーからサイズだけを読み取り、
+// プレースホルダーで塗りつぶしたピクチャーをすぐに返す。デコードはワーカーで行い、
+// 完了したものからゲームループ（GraphicsSystem.Update）でピクチャーに書き込む。
+// 画素が必要な操作（MovePic、PutCast など）はデコードの完了を待つ。
+//
+// デコード済みの画像は同じファイルを再び読み込むときのためにキャッシュし、
+// 合計サイズが budget を超えたら最も長く使っていないものから破棄する。
+type assetStream struct {
+	budget int64
+	sem    chan struct{} // 同時にデコードする数を制限する
+	log    *slog.Logger
+
+	mu      sync.Mutex
+	lru     *list.List               // *cachedPicture（先頭が最も最近使ったもの）
+	entries map[string]*list.Element // キーは小文字のファイル名
+	used    int64                    // キャッシュしている画像の合計バイト数
+}
+
+// cachedPicture はキャッシュしているデコード済みの画像
+type cachedPicture struct {
+	key string
+	img *image.RGBA
+}
+
+// newAssetStream は budget バイトまでキャッシュし、同時に workers 枚までデコードするストリーミング読み込みを作成する
+func newAssetStream(budget int64, workers int, log *slog.Logger) *assetStream {
+	return &assetStream{
+		budget:  budget,
+		sem:     make(chan struct{}, max(workers, 1)),
+		log:     log,
+		lru:     list.New(),
+		entries: make(map[string]*list.Element),
+	}
+}
+
+// get はキャッシュしている画像を返す（ない場合は nil）
+func (s *assetStream) get(key string) *image.RGBA {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	elem, ok := s.entries[key]
+	if !ok {
+		return nil
+	}
+	s.lru.MoveToFront(elem)
+	return elem.Value.(*cachedPicture).img
+}
+
+// put はデコードした画像をキャッシュし、上限を超えた分を古いものから破棄する
+// 1枚で上限を超える画像はキャッシュしない
+func (s *assetStream) put(key string, img *image.RGBA) {
+	size := int64(len(img.Pix))
+	if size > s.budget {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if elem, ok := s.entries[key]; ok {
+		s.used -= int64(len(elem.Value.(*cachedPicture).img.Pix))
+		s.lru.Remove(elem)
+	}
+	s.entries[key] = s.lru.PushFront(&cachedPicture{key: key, img: img})
+	s.used += size
+
+	for s.used > s.budget {
+		oldest := s.lru.Back()
+		cp := oldest.Value.(*cachedPicture)
+		s.lru.Remove(oldest)
+		delete(s.entries, cp.key)
+		s.used -= int64(len(cp.img.Pix))
+		s.log.Debug("Asset stream: evicted decoded picture", "filename", cp.key, "bytes", len(cp.img.Pix))
+	}
+}
+
+// decode はファイルの内容をワーカーでデコードし、完了したら entry.done を閉じる
+// release はデコード後に呼び出す（メモリマップの解除）
+func (s *assetStream) decode(key, searchFilename string, data []byte, release func(), entry *prefetchedPicture) {
+	go func() {
+		s.sem <- struct{}{}
+		defer func() { <-s.sem }()
+
+		entry.img, entry.err = decodePictureData(data, searchFilename, s.log)
+		release()
+		if entry.err == nil {
+			s.put(key, entry.img)
+		}
+		close(entry.done)
+	}()
+}
+
+// EnableStreaming は画像のストリーミング読み込みを有効にする（assetStream を参照）
+// budget はデコード済み画像のキャッシュの上限（バイト数）
+func (pm *PictureManager) EnableStreaming(budget int64) {
+	pm.mu.Lock()
+	defer pm.mu.Unlock()
+	pm.stream = newAssetStream(budget, pm.streamWorkers(), pm.log)
+}
+
+// streamWorkers はストリーミング読み込み・LoadPicAsync で同時にデコードする画像の数を返す
+// 呼び出し元は pm.mu のロックを保持していること
+func (pm *PictureManager) streamWorkers() int {
+	return pm.limitDecodeWorkers(min(runtime.NumCPU(), maxStreamWorkers))
+}
+
+// limitDecodeWorkers は同時にデコードする画像の数 n を SetDecodeWorkers の上限で制限する
+// 呼び出し元は pm.mu のロックを保持していること
+func (pm *PictureManager) limitDecodeWorkers(n int) int {
+	if pm.decodeWorkers > 0 {
+		return min(n, pm.decodeWorkers)
+	}
+	return n
+}
+
+// SetDecodeWorkers はバックグラウンド（ストリーミング読み込み・LoadPicAsync・先読み）で
+// 同時にデコードする画像の数の上限を設定する（0は既定値）
+// 画像の読み込みを始める前に呼び出すこと
+func (pm *PictureManager) SetDecodeWorkers(n int) {
+	pm.mu.Lock()
+	defer pm.mu.Unlock()
+	pm.decodeWorkers = max(n, 0)
+	// WithAssetStreaming の後に呼び出された場合も上限を適用する
+	if pm.stream != nil {
+		pm.stream.sem = make(chan struct{}, pm.streamWorkers())
+	}
+}
+
+// openStreamed はストリーミング読み込み（または LoadPicAsync）でピクチャーの元になる画像を用意する
+// デコード済みの画像（キャッシュまたは先読み）があればそれを返し、
+// なければファイルのヘッダーからサイズを読み取って stream のワーカーでデコードを開始し、完了待ちの entry を返す
+// 呼び出し元は pm.mu のロックを保持していること
+func (pm *PictureManager) openStreamed(stream *assetStream, searchFilename string) (img *image.RGBA, pending *prefetchedPicture, width, height int, err error) {
+	key := prefetchKey(searchFilename)
+
+	// 先読みの結果はデコード中でもそのまま使う
+	if entry, ok := pm.prefetched[key]; ok {
+		delete(pm.prefetched, key)
+		select {
+		case <-entry.done:
+			if entry.err == nil {
+				return entry.img, nil, entry.img.Bounds().Dx(), entry.img.Bounds().Dy(), nil
+			}
+			entry = nil
+		default:
+		}
+		if entry != nil {
+			width, height, err = pm.readPictureSize(searchFilename)
+			if err != nil {
+				return nil, nil, 0, 0, err
+			}
+			return nil, entry, width, height, nil
+		}
+	}
+
+	if cached := stream.get(key); cached != nil {
+		return cached, nil, cached.Bounds().Dx(), cached.Bounds().Dy(), nil
+	}
+
+	data, release, err := mapPictureFile(pm.files(), searchFilename)
+	if err != nil {
+		return nil, nil, 0, 0, err
+	}
+	width, height, err = pictureSize(data)
+	if err != nil {
+		release()
+		return nil, nil, 0, 0, err
+	}
+	entry := &prefetchedPicture{done: make(chan struct{})}
+	stream.decode(key, searchFilename, data, release, entry)
+	return nil, entry, width, height, nil
+}
+
+// readPictureSize はファイルのヘッダーから画像のサイズを読み取る
+func (pm *PictureManager) readPictureSize(searchFilename string) (int, int, error) {
+	data, release, err := mapPictureFile(pm.files(), searchFilename)
+	if err != nil {
+		return 0, 0, err
+	}
+	defer release()
+	return pictureSize(data)
+}
+
+// finishStreamed はデコードの完了を待ち、画素をピクチャーに書き込む
+// デコードに失敗した場合はプレースホルダーのまま（元の背景画像は透明）にする
+// 呼び出し元は gs.mu のロックを保持していること
+func (pm *PictureManager) finishStreamed(pic *Picture) {
+	entry := pic.pending
+	<-entry.done
+	pic.pending = nil
+
+	err := entry.err
+	if err == nil {
+		if b := entry.img.Bounds(); b.Dx() == pic.Width && b.Dy() == pic.Height {
+			pic.Image.WritePixels(entry.img.Pix)
+			pic.OriginalImage = entry.img
+			return
+		}
+		err = fmt.Errorf("decoded size %dx%d does not match header size %dx%d",
+			entry.img.Bounds().Dx(), entry.img.Bounds().Dy(), pic.Width, pic.Height)
+	}
+	pm.log.Error("LoadPic: failed to decode streamed picture", "pictureID", pic.ID, "error", err)
+	pic.OriginalImage = image.NewRGBA(image.Rect(0, 0, pic.Width, pic.Height))
+}
+
+// finishReadyStreamed はデコードが完了したピクチャーに画素を書き込む（待たない）
+// 書き込んだピクチャーがあれば true を返す
+// 呼び出し元は gs.mu のロックを保持していること
+func (pm *PictureManager) finishReadyStreamed() bool {
+	pm.mu.RLock()
+	defer pm.mu.RUnlock()
+
+	finished := false
+	for _, pic := range pm.pictures {
+		if pic.pending == nil {
+			continue
+		}
+		select {
+		case <-pic.pending.done:
+			pm.finishStreamed(pic)
+			finished = true
+		default:
+		}
+	}
+	return finished
+}
+
+// pendingDecodes は指定されたピクチャーのうちデコード中のものの完了待ちの entry を返す
+// 呼び出し元は gs.mu の読み取りロックを保持していること
+func (pm *PictureManager) pendingDecodes(ids []int) []*prefetchedPicture {
+	pm.mu.RLock()
+	defer pm.mu.RUnlock()
+
+	var pending []*prefetchedPicture
+	for _, id := range ids {
+		if pic, ok := pm.pictures[id]; ok && pic.pending != nil {
+			pending = append(pending, pic.pending)
+		}
+	}
+	return pending
+}
+
+// newPlaceholderImage はデコードが終わるまで表示するプレースホルダーの画像を作成する
+func newPlaceholderImage(width, height int) *ebiten.Image {
+	img := ebiten.NewImage(width, height)
+	img.Fill(streamPlaceholderColor)
+	return img
+}
+
+// awaitPictures は指定されたピクチャーのデコードが終わるまで待つ（ストリーミング読み込み）
+// gs.mu のロックを保持せずに待つため、待っている間もフレームの描画は止まらない
+// 呼び出し元は gs.mu のロックを保持していないこと
+func (gs *GraphicsSystem) awaitPictures(ids ...int) {
+	gs.mu.RLock()
+	pending := gs.pictures.pendingDecodes(ids)
+	gs.mu.RUnlock()
+	for _, entry := range pending {
+		<-entry.done
+	}
+}
+
+// WithAssetStreaming は画像のストリーミング読み込みを有効にする（--stream-assets）
+// 数百MBのBMPを含むタイトル向けで、LoadPic はデコードを待たずに戻り、
+// デコードが終わるまでピクチャーはプレースホルダーの色で表示される。
+// budget はデコード済み画像のキャッシュの上限（バイト数、0以下の場合はストリーミングしない）
+func WithAssetStreaming(budget int64) Option {
+	return func(gs *GraphicsSystem) {
+		if budget > 0 {
+			gs.pictures.EnableStreaming(budget)
+		}
+	}
+}
+
+// WithPowerSave は省電力モード（--power-save）で画像のバックグラウンドのデコードを
+// powerSaveDecodeWorkers 枚ずつに制限し、CPU の使用率の急な上昇を抑える
+func WithPowerSave(enabled bool) Option {
+	return func(gs *GraphicsSystem) {
+		if enabled {
+			gs.pictures.SetDecodeWorkers(powerSaveDecodeWorkers)
+		}
+	}
+}
+
+// mapPictureFile はファイルの内容を返す
+// 実ファイルはメモリマップし（対応するOSの場合）、それ以外は読み込む。
+// 返された release は内容を使い終わったら必ず呼び出すこと
+func mapPictureFile(fsys fileutil.FileSystem, searchFilename string) ([]byte, func(), error) {
+	file, err := fsys.Open(searchFilename)
+	if err != nil {
+		return nil, nil, fmt.Errorf("file not found: %s", searchFilename)
+	}
+	if f, ok := file.(*os.File); ok {
+		if data, err := mmapFile(f); err == nil {
+			return data, func() {
+				munmapFile(data)
+				f.Close()
+			}, nil
+		}
+	}
+	defer file.Close()
+
+	data, err := io.ReadAll(file)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read file: %w", err)
+	}
+	return data, func() {}, nil
+}
@@ -0,0 +1,324 @@
+//go:build !nogpu
+
+package graphics
+
+import (
+	"bytes"
+	"encoding/binary"
+	"image"
+	"image/color"
+	"image/png"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/zurustar/son-et/pkg/fileutil"
+)
+
+// bmpHeaderWithSize は幅・高さだけを設定したBMPヘッダーを返す（pictureSize のテスト用）
+func bmpHeaderWithSize(infoHeaderSize uint32, width, height int32) []byte {
+	data := make([]byte, 54)
+	data[0], data[1] = 'B', 'M'
+	binary.LittleEndian.PutUint32(data[bmpInfoHeaderSizeOffset:], infoHeaderSize)
+	if infoHeaderSize == bmpCoreHeaderSize {
+		binary.LittleEndian.PutUint16(data[bmpWidthOffset:], uint16(width))
+		binary.LittleEndian.PutUint16(data[bmpCoreHeightOffset:], uint16(height))
+	} else {
+		binary.LittleEndian.PutUint32(data[bmpWidthOffset:], uint32(width))
+		binary.LittleEndian.PutUint32(data[bmpHeightOffset:], uint32(height))
+	}
+	return data
+}
+
+func TestPictureSize(t *testing.T) {
+	tmpDir := t.TempDir()
+	createTestBMP(t, filepath.Join(tmpDir, "a.bmp"), 40, 30)
+	bmpData, err := os.ReadFile(filepath.Join(tmpDir, "a.bmp"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var pngData bytes.Buffer
+	if err := png.Encode(&pngData, image.NewRGBA(image.Rect(0, 0, 7, 5))); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name          string
+		data          []byte
+		width, height int
+		wantErr       bool
+	}{
+		{"BMP", bmpData, 40, 30, false},
+		{"top-down BMP", bmpHeaderWithSize(40, 64, -48), 64, 48, false},
+		{"OS/2 BMP", bmpHeaderWithSize(bmpCoreHeaderSize, 320, 200), 320, 200, false},
+		{"PNG", pngData.Bytes(), 7, 5, false},
+		{"zero width", bmpHeaderWithSize(40, 0, 10), 0, 0, true},
+		{"too large", bmpHeaderWithSize(40, maxBMPDimension+1, 10), 0, 0, true},
+		{"not an image", []byte("hello"), 0, 0, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w, h, err := pictureSize(tt.data)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, wantErr %v", err, tt.wantErr)
+			}
+			if w != tt.width || h != tt.height {
+				t.Errorf("size = %dx%d, want %dx%d", w, h, tt.width, tt.height)
+			}
+		})
+	}
+}
+
+func TestMapPictureFile(t *testing.T) {
+	tmpDir := t.TempDir()
+	createTestBMP(t, filepath.Join(tmpDir, "Pic.bmp"), 8, 8)
+	want, err := os.ReadFile(filepath.Join(tmpDir, "Pic.bmp"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	data, release, err := mapPictureFile(fileutil.NewRealFS(tmpDir), "pic.bmp")
+	if err != nil {
+		t.Fatalf("mapPictureFile failed: %v", err)
+	}
+	if !bytes.Equal(data, want) {
+		t.Error("mapped content does not match the file")
+	}
+	release()
+
+	if _, _, err := mapPictureFile(fileutil.NewRealFS(tmpDir), "missing.bmp"); err == nil {
+		t.Error("expected error for a missing file")
+	}
+}
+
+func TestAssetStreamEvictsLeastRecentlyUsed(t *testing.T) {
+	img := func() *image.RGBA { return image.NewRGBA(image.Rect(0, 0, 4, 4)) } // 64バイト
+	s := newAssetStream(128, 1, slog.Default())
+
+	s.put("a.bmp", img())
+	s.put("b.bmp", img())
+	s.get("a.bmp") // a を最近使ったものにする
+	s.put("c.bmp", img())
+
+	if s.get("b.bmp") != nil {
+		t.Error("b.bmp should be evicted as the least recently used picture")
+	}
+	if s.get("a.bmp") == nil || s.get("c.bmp") == nil {
+		t.Error("a.bmp and c.bmp should stay cached")
+	}
+	if s.used != 128 {
+		t.Errorf("used = %d, want 128", s.used)
+	}
+
+	// 1枚で上限を超える画像はキャッシュしない
+	s.put("big.bmp", image.NewRGBA(image.Rect(0, 0, 16, 16)))
+	if s.get("big.bmp") != nil || s.get("a.bmp") == nil {
+		t.Error("an oversized picture should not be cached or evict others")
+	}
+}
+
+func TestLoadPicStreaming(t *testing.T) {
+	tmpDir := t.TempDir()
+	createTestBMP(t, filepath.Join(tmpDir, "big.bmp"), 40, 30)
+
+	pm := NewPictureManager(tmpDir)
+	pm.EnableStreaming(1 << 20)
+
+	id, err := pm.LoadPic("/BIG.bmp")
+	if err != nil {
+		t.Fatalf("LoadPic failed: %v", err)
+	}
+	// サイズはデコードを待たずに分かる
+	if w, h := pm.PicWidth(id), pm.PicHeight(id); w != 40 || h != 30 {
+		t.Errorf("size = %dx%d, want 40x30", w, h)
+	}
+
+	pic, err := pm.GetPicWithoutLock(id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pic.pending != nil || pic.OriginalImage == nil {
+		t.Fatal("GetPicWithoutLock should wait for the decode")
+	}
+	if got, want := pic.OriginalImage.At(10, 20), (color.RGBA{10, 20, 128, 255}); got != want {
+		t.Errorf("pixel = %v, want %v", got, want)
+	}
+
+	// デコード済みの画像はキャッシュから再利用する
+	if err := os.Remove(filepath.Join(tmpDir, "big.bmp")); err != nil {
+		t.Fatal(err)
+	}
+	id2, err := pm.LoadPic("big.bmp")
+	if err != nil {
+		t.Fatalf("LoadPic should use the cached picture: %v", err)
+	}
+	pic2, _ := pm.lookupPicWithoutLock(id2)
+	if pic2.pending != nil || pic2.OriginalImage == nil {
+		t.Error("a cached picture should not be decoded again")
+	}
+}
+
+func TestLoadPicStreamingErrors(t *testing.T) {
+	tmpDir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(tmpDir, "broken.bmp"), []byte("BM not really"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	pm := NewPictureManager(tmpDir)
+	pm.EnableStreaming(1 << 20)
+
+	if _, err := pm.LoadPic("missing.bmp"); err == nil {
+		t.Error("expected error for a missing file")
+	}
+	if _, err := pm.LoadPic("broken.bmp"); err == nil {
+		t.Error("expected error for a file without a valid header")
+	}
+	if pm.Count() != 0 {
+		t.Errorf("Count = %d, want 0", pm.Count())
+	}
+}
+
+func TestLoadPicStreamingDecodeFailureKeepsPlaceholder(t *testing.T) {
+	tmpDir := t.TempDir()
+	// ヘッダーは正しいが画素データがない
+	if err := os.WriteFile(filepath.Join(tmpDir, "truncated.bmp"), bmpHeaderWithSize(40, 16, 16), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	pm := NewPictureManager(tmpDir)
+	pm.EnableStreaming(1 << 20)
+
+	id, err := pm.LoadPic("truncated.bmp")
+	if err != nil {
+		t.Fatalf("LoadPic should succeed with the header only: %v", err)
+	}
+	pic, err := pm.GetPicWithoutLock(id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pic.pending != nil {
+		t.Error("pending should be cleared after a failed decode")
+	}
+	if pic.OriginalImage == nil || pic.OriginalImage.Bounds().Dx() != 16 {
+		t.Error("a failed decode should leave a blank original image of the header size")
+	}
+}
+
+func TestGraphicsSystemUpdateFinishesStreamedPictures(t *testing.T) {
+	tmpDir := t.TempDir()
+	createTestBMP(t, filepath.Join(tmpDir, "bg.bmp"), 20, 10)
+
+	gs := NewGraphicsSystem(tmpDir, WithAssetStreaming(1<<20))
+	id, err := gs.LoadPic("bg.bmp")
+	if err != nil {
+		t.Fatalf("LoadPic failed: %v", err)
+	}
+
+	gs.awaitPictures(id)
+	gs.sceneDirty.Store(false)
+	if err := gs.Update(); err != nil {
+		t.Fatal(err)
+	}
+
+	pic, _ := gs.pictures.lookupPicWithoutLock(id)
+	if pic.pending != nil {
+		t.Error("Update should write the decoded pixels into the picture")
+	}
+	if !gs.NeedsRedraw() {
+		t.Error("finishing a streamed picture should request a redraw")
+	}
+}
+
+func TestWithAssetStreamingDisabled(t *testing.T) {
+	gs := NewGraphicsSystem("", WithAssetStreaming(0))
+	if gs.pictures.stream != nil {
+		t.Error("a zero budget should not enable streaming")
+	}
+}
+
+func TestWithPowerSaveLimitsDecodeWorkers(t *testing.T) {
+	// WithAssetStreaming の後に指定しても、作成済みのストリーミング読み込みに適用する
+	gs := NewGraphicsSystem("", WithAssetStreaming(1<<20), WithPowerSave(true))
+	if got := cap(gs.pictures.stream.sem); got != powerSaveDecodeWorkers {
+		t.Errorf("stream workers = %d, want %d", got, powerSaveDecodeWorkers)
+	}
+	if got := gs.pictures.limitDecodeWorkers(prefetchWorkers); got != powerSaveDecodeWorkers {
+		t.Errorf("prefetch workers = %d, want %d", got, powerSaveDecodeWorkers)
+	}
+
+	gs = NewGraphicsSystem("", WithPowerSave(false))
+	if got := gs.pictures.limitDecodeWorkers(prefetchWorkers); got != prefetchWorkers {
+		t.Errorf("prefetch workers without power save = %d, want %d", got, prefetchWorkers)
+	}
+}
+
+func TestLoadPicAsyncWithoutStreaming(t *testing.T) {
+	tmpDir := t.TempDir()
+	createTestBMP(t, filepath.Join(tmpDir, "big.bmp"), 40, 30)
+
+	pm := NewPictureManager(tmpDir)
+	id, pending, err := pm.LoadPicAsync("big.bmp")
+	if err != nil {
+		t.Fatalf("LoadPicAsync failed: %v", err)
+	}
+	if pending == nil {
+		t.Fatal("LoadPicAsync should decode on a worker")
+	}
+	if w, h := pm.PicWidth(id), pm.PicHeight(id); w != 40 || h != 30 {
+		t.Errorf("size = %dx%d, want 40x30", w, h)
+	}
+	<-pending.done
+	if pending.err != nil {
+		t.Fatalf("decode failed: %v", pending.err)
+	}
+
+	// ストリーミング読み込みが無効の場合はキャッシュしない
+	if pm.async.get(prefetchKey("big.bmp")) != nil {
+		t.Error("async decodes should not be cached without streaming")
+	}
+	// LoadPic は引き続きデコードを待つ
+	id2, err := pm.LoadPic("big.bmp")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pic, _ := pm.lookupPicWithoutLock(id2); pic.pending != nil {
+		t.Error("LoadPic should stay synchronous")
+	}
+}
+
+func TestGraphicsSystemLoadPicAsync(t *testing.T) {
+	tmpDir := t.TempDir()
+	createTestBMP(t, filepath.Join(tmpDir, "bg.bmp"), 20, 10)
+	if err := os.WriteFile(filepath.Join(tmpDir, "truncated.bmp"), bmpHeaderWithSize(40, 16, 16), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	gs := NewGraphicsSystem(tmpDir)
+	type result struct {
+		id  int
+		err error
+	}
+	done := make(chan result, 2)
+	onDone := func(id int, err error) { done <- result{id, err} }
+
+	id, err := gs.LoadPicAsync("bg.bmp", onDone)
+	if err != nil {
+		t.Fatalf("LoadPicAsync failed: %v", err)
+	}
+	if r := <-done; r.id != id || r.err != nil {
+		t.Errorf("onDone(%d, %v), want (%d, nil)", r.id, r.err, id)
+	}
+
+	badID, err := gs.LoadPicAsync("truncated.bmp", onDone)
+	if err != nil {
+		t.Fatalf("LoadPicAsync should succeed with the header only: %v", err)
+	}
+	if r := <-done; r.id != badID || r.err == nil {
+		t.Errorf("onDone(%d, %v), want a decode error for %d", r.id, r.err, badID)
+	}
+
+	if _, err := gs.LoadPicAsync("missing.bmp", onDone); err == nil {
+		t.Error("expected error for a missing file")
+	}
+}
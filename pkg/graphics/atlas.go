@@ -0,0 +1,180 @@
+//go:build !nogpu
+
+// atlas.go は小さなキャストの画像を共有のテクスチャ（アトラス）にまとめて配置する
+//
+// 小さなBMPを数百枚使うタイトルでは、キャストごとに切り出した画像が小さなテクスチャに分かれ、
+// 描画のたびにテクスチャが切り替わる。キャストの画像をアトラスのページの一部（SubImage）として
+// 作成すると、同じページのキャストはテクスチャを切り替えずにまとめて描画される。
+// Ebitengine も内部で小さな画像を自動的にまとめるが、切り出しで描画先に使った画像は、
+// 描画元として何度も使われるまで自動のアトラスに戻らない。
+//
+// ピクチャーはスクリプトから描画先として使われ、(0, 0) を左上とする座標で読み書きされるため、
+// アトラスには配置しない（SubImage を描画先にすると、座標はページの座標になる）。
+package graphics
+
+import (
+	"image"
+	"sync"
+
+	"github.com/hajimehoshi/ebiten/v2"
+)
+
+// アトラスの大きさ
+const (
+	atlasPageSize     = 1024 // 1ページの幅と高さ
+	atlasMaxImageSize = 128  // アトラスに配置する画像の幅と高さの上限（これより大きい画像は個別に作成する）
+	atlasPadding      = 1    // 画像の間の余白（拡大描画で隣の画像の色が混ざらないようにする）
+)
+
+// atlasShelf はページの中の棚（高さの揃った1行）
+type atlasShelf struct {
+	y, height int // 棚の上端と高さ
+	x         int // 次の画像を置く左端
+}
+
+// shelfPacker は矩形を棚に左から順に詰めて配置する
+// 解放した領域は再利用せず、ページの画像がすべて解放されたらページごと破棄する
+type shelfPacker struct {
+	size    int // ページの幅と高さ
+	shelves []atlasShelf
+	bottom  int // 次の棚の上端
+}
+
+// allocate は w×h の領域を確保する（ページに空きがない場合は false）
+// 高さの近い棚を優先し、なければ新しい棚を作る。新しい棚を作れない場合は高さの合う棚に詰める。
+func (p *shelfPacker) allocate(w, h int) (image.Rectangle, bool) {
+	pw, ph := w+atlasPadding, h+atlasPadding
+	if pw > p.size || ph > p.size {
+		return image.Rectangle{}, false
+	}
+
+	shelf := p.findShelf(pw, ph, true)
+	if shelf < 0 && p.bottom+ph <= p.size {
+		p.shelves = append(p.shelves, atlasShelf{y: p.bottom, height: ph})
+		p.bottom += ph
+		shelf = len(p.shelves) - 1
+	}
+	if shelf < 0 {
+		shelf = p.findShelf(pw, ph, false)
+	}
+	if shelf < 0 {
+		return image.Rectangle{}, false
+	}
+
+	s := &p.shelves[shelf]
+	r := image.Rect(s.x, s.y, s.x+w, s.y+h)
+	s.x += pw
+	return r, true
+}
+
+// findShelf は pw×ph の領域を置ける棚のうち最も低い棚を返す（ない場合は -1）
+// tight の場合は、高さが ph の2倍未満の棚だけを対象にする（低い画像で高い棚を無駄にしない）
+func (p *shelfPacker) findShelf(pw, ph int, tight bool) int {
+	best := -1
+	for i, s := range p.shelves {
+		if ph > s.height || s.x+pw > p.size || (tight && s.height >= ph*2) {
+			continue
+		}
+		if best < 0 || s.height < p.shelves[best].height {
+			best = i
+		}
+	}
+	return best
+}
+
+// atlasPage はアトラスの1ページ
+type atlasPage struct {
+	image  *ebiten.Image
+	packer shelfPacker
+	live   int // 解放されていない画像の数
+}
+
+// castAtlas はキャストの画像を配置するアトラス
+type castAtlas struct {
+	pages   []*atlasPage
+	regions map[*ebiten.Image]*atlasPage // アトラスに作成した画像 → ページ
+	mu      sync.Mutex
+}
+
+// newCastAtlas は空のアトラスを作成する（ページは画像を作成するときに作る）
+func newCastAtlas() *castAtlas {
+	return &castAtlas{regions: make(map[*ebiten.Image]*atlasPage)}
+}
+
+// newImage は w×h の画像をアトラスのページの一部として作成する
+// 大きな画像とアトラスがない場合（a が nil）は、通常の画像として作成する
+func (a *castAtlas) newImage(w, h int) *ebiten.Image {
+	if a == nil || w > atlasMaxImageSize || h > atlasMaxImageSize {
+		return ebiten.NewImage(w, h)
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	for _, page := range a.pages {
+		if r, ok := page.packer.allocate(w, h); ok {
+			return a.placeLocked(page, r)
+		}
+	}
+	page := &atlasPage{
+		image:  ebiten.NewImage(atlasPageSize, atlasPageSize),
+		packer: shelfPacker{size: atlasPageSize},
+	}
+	a.pages = append(a.pages, page)
+	r, _ := page.packer.allocate(w, h)
+	return a.placeLocked(page, r)
+}
+
+// placeLocked はページの領域 r を画像として登録する
+// 呼び出し元は a.mu のロックを保持していること
+func (a *castAtlas) placeLocked(page *atlasPage, r image.Rectangle) *ebiten.Image {
+	img := page.image.SubImage(r).(*ebiten.Image)
+	page.live++
+	a.regions[img] = page
+	return img
+}
+
+// release はアトラスに作成した画像の領域を解放する（アトラスの画像でない場合は何もしない）
+// ページの画像がすべて解放されたら、ページのテクスチャを解放する
+func (a *castAtlas) release(img *ebiten.Image) {
+	if a == nil || img == nil {
+		return
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	page, ok := a.regions[img]
+	if !ok {
+		return
+	}
+	delete(a.regions, img)
+	page.live--
+	if page.live > 0 {
+		return
+	}
+	page.image.Deallocate()
+	for i, p := range a.pages {
+		if p == page {
+			a.pages = append(a.pages[:i], a.pages[i+1:]...)
+			break
+		}
+	}
+}
+
+// pageCount はアトラスのページ数を返す
+func (a *castAtlas) pageCount() int {
+	if a == nil {
+		return 0
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return len(a.pages)
+}
+
+// copyImage は src を dst の左上に描画する
+// dst がアトラスの画像（SubImage）の場合、描画先の座標はページの座標になるため、dst の位置だけずらす
+func copyImage(dst, src *ebiten.Image) {
+	op := &ebiten.DrawImageOptions{}
+	origin := dst.Bounds().Min
+	op.GeoM.Translate(float64(origin.X), float64(origin.Y))
+	dst.DrawImage(src, op)
+}
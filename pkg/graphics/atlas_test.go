@@ -0,0 +1,127 @@
+//go:build !nogpu
+
+package graphics
+
+import (
+	"image"
+	"testing"
+
+	"github.com/hajimehoshi/ebiten/v2"
+)
+
+// TestShelfPacker_Allocate は棚に左から順に詰めて配置することをテストする
+func TestShelfPacker_Allocate(t *testing.T) {
+	p := &shelfPacker{size: 64}
+
+	tests := []struct {
+		w, h int
+		want image.Rectangle
+	}{
+		{10, 10, image.Rect(0, 0, 10, 10)},
+		{20, 8, image.Rect(11, 0, 31, 8)},   // 同じ棚に詰める
+		{10, 30, image.Rect(0, 11, 10, 41)}, // 高い画像は新しい棚
+		{4, 4, image.Rect(0, 42, 4, 46)},    // 低い画像で高い棚を無駄にしない
+	}
+	for _, tt := range tests {
+		got, ok := p.allocate(tt.w, tt.h)
+		if !ok || got != tt.want {
+			t.Errorf("allocate(%d, %d) = %v, %v; want %v, true", tt.w, tt.h, got, ok, tt.want)
+		}
+	}
+}
+
+// TestShelfPacker_Full はページに空きがない場合に false を返すことをテストする
+func TestShelfPacker_Full(t *testing.T) {
+	p := &shelfPacker{size: 32}
+
+	if _, ok := p.allocate(32, 4); ok {
+		t.Error("allocate should fail for an image wider than the page with padding")
+	}
+	for i := range 4 {
+		if _, ok := p.allocate(15, 15); !ok {
+			t.Fatalf("allocate #%d failed, want 4 images of 15x15 in a 32x32 page", i)
+		}
+	}
+	if _, ok := p.allocate(15, 15); ok {
+		t.Error("allocate should fail when the page is full")
+	}
+	// 高さの合う棚の残りには詰められる
+	p2 := &shelfPacker{size: 32}
+	p2.allocate(10, 30)
+	if r, ok := p2.allocate(4, 4); !ok || r.Min != image.Pt(11, 0) {
+		t.Errorf("allocate(4, 4) = %v, %v; want a region in the remaining shelf", r, ok)
+	}
+}
+
+// TestCastAtlas_NewImage は小さな画像をページにまとめて配置することをテストする
+func TestCastAtlas_NewImage(t *testing.T) {
+	a := newCastAtlas()
+
+	small1 := a.newImage(16, 16)
+	small2 := a.newImage(8, 24)
+	if a.pageCount() != 1 {
+		t.Fatalf("pageCount() = %d, want 1", a.pageCount())
+	}
+	if got := small2.Bounds().Size(); got != image.Pt(8, 24) {
+		t.Errorf("image size = %v, want (8,24)", got)
+	}
+	if small1.Bounds().Overlaps(small2.Bounds()) {
+		t.Errorf("images overlap: %v and %v", small1.Bounds(), small2.Bounds())
+	}
+
+	// 大きな画像はアトラスに配置しない
+	large := a.newImage(atlasMaxImageSize+1, 4)
+	if large.Bounds().Min != image.Pt(0, 0) || a.pageCount() != 1 {
+		t.Errorf("large image should be created outside the atlas")
+	}
+}
+
+// TestCastAtlas_Release はすべての画像を解放するとページを破棄することをテストする
+func TestCastAtlas_Release(t *testing.T) {
+	a := newCastAtlas()
+	img1 := a.newImage(16, 16)
+	img2 := a.newImage(16, 16)
+
+	a.release(img1)
+	a.release(img1) // 2回目は何もしない
+	if a.pageCount() != 1 {
+		t.Errorf("pageCount() = %d after releasing 1 of 2 images, want 1", a.pageCount())
+	}
+	a.release(ebiten.NewImage(4, 4)) // アトラスの画像でない場合は何もしない
+	a.release(img2)
+	if a.pageCount() != 0 {
+		t.Errorf("pageCount() = %d after releasing all images, want 0", a.pageCount())
+	}
+
+	// nil のアトラスは通常の画像を作成する
+	var none *castAtlas
+	if img := none.newImage(4, 4); img == nil || img.Bounds() != image.Rect(0, 0, 4, 4) {
+		t.Errorf("nil atlas newImage = %v, want a 4x4 image", img)
+	}
+	none.release(img2)
+}
+
+// TestCastSpriteManager_AtlasRelease はキャストの削除でアトラスの領域を解放することをテストする
+func TestCastSpriteManager_AtlasRelease(t *testing.T) {
+	sm := NewSpriteManager()
+	csm := NewCastSpriteManager(sm)
+	src := ebiten.NewImage(64, 64)
+
+	csm.CreateCastSprite(&Cast{ID: 1, Width: 16, Height: 16, Visible: true}, src, 0)
+	csm.CreateCastSprite(&Cast{ID: 2, SrcX: 16, Width: 16, Height: 16, Visible: true}, src, 0)
+	if got := csm.atlas.pageCount(); got != 1 {
+		t.Fatalf("pageCount() = %d, want 1", got)
+	}
+	if got := csm.GetCastSprite(2).GetSprite().Image().Bounds().Size(); got != image.Pt(16, 16) {
+		t.Errorf("cast image size = %v, want (16,16)", got)
+	}
+
+	csm.RemoveCastSprite(1)
+	if got := csm.atlas.pageCount(); got != 1 {
+		t.Errorf("pageCount() = %d with 1 cast left, want 1", got)
+	}
+	csm.Clear()
+	if got := csm.atlas.pageCount(); got != 0 {
+		t.Errorf("pageCount() = %d after Clear, want 0", got)
+	}
+}
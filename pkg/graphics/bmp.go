@@ -1,11 +1,19 @@
 // Package graphics provides BMP image decoding with RLE compression support.
-// Go標準ライブラリの image/bmp はRLE圧縮をサポートしていないため、
-// カスタムデコーダーを実装する。
+// Go標準ライブラリの image/bmp（golang.org/x/image/bmp）はRLE圧縮やOS/2形式のヘッダーを
+// サポートしていないため、カスタムデコーダーを実装する。
 //
 // BMP圧縮方式:
-//   - BI_RGB (0): 非圧縮
+//   - BI_RGB (0): 非圧縮（1, 4, 8, 16, 24, 32ビット）
 //   - BI_RLE8 (1): 8ビットRLE圧縮
 //   - BI_RLE4 (2): 4ビットRLE圧縮
+//   - BI_BITFIELDS (3): 16, 32ビットのビットマスク指定
+//
+// 情報ヘッダー:
+//   - BITMAPCOREHEADER (12バイト): OS/2 1.x 形式。幅・高さは16ビット、パレットは1色3バイト
+//   - OS/2 2.x 形式 (16〜64バイト): BITMAPINFOHEADER と同じ並びで、省略したフィールドは0
+//   - BITMAPINFOHEADER (40バイト) と V2〜V5 (52, 56, 108, 124バイト)
+//
+// 高さが負の場合はトップダウン形式（先頭の行が画像の上端）として扱う。
 //
 // 要件 1.10.1: RLE圧縮されたBMP形式（RLE8、RLE4）をサポートする
 // 要件 1.10.2: 非圧縮BMP形式をサポートする
@@ -18,13 +26,29 @@ import (
 	"image"
 	"image/color"
 	"io"
+	"math/bits"
 )
 
 // BMP圧縮方式の定数
 const (
-	biRGB  = 0 // 非圧縮
-	biRLE8 = 1 // 8ビットRLE圧縮
-	biRLE4 = 2 // 4ビットRLE圧縮
+	biRGB       = 0 // 非圧縮
+	biRLE8      = 1 // 8ビットRLE圧縮
+	biRLE4      = 2 // 4ビットRLE圧縮
+	biBitfields = 3 // ビットマスク指定（OS/2 2.x 形式ではハフマン圧縮）
+)
+
+// maxBMPDimension はBMPの幅・高さの上限（65536px、実用上十分な上限）
+const maxBMPDimension = 1 << 16
+
+// BMPのヘッダーの大きさ
+const (
+	bmpFileHeaderSize    = 14  // BITMAPFILEHEADER
+	bmpInfoHeaderSize    = 40  // BITMAPINFOHEADER
+	bmpV2HeaderSize      = 52  // BITMAPV2INFOHEADER（RGBのマスクを含む）
+	bmpV3HeaderSize      = 56  // BITMAPV3INFOHEADER（アルファのマスクを含む）
+	bmpV4HeaderSize      = 108 // BITMAPV4HEADER
+	bmpMinOS2HeaderSize  = 16  // OS/2 2.x 形式の情報ヘッダーの最小の大きさ
+	bmpMaxInfoHeaderSize = 124 // BITMAPV5HEADER
 )
 
 // BMPファイルヘッダー (14バイト)
@@ -37,12 +61,13 @@ type bmpFileHeader struct {
 }
 
 // BMP情報ヘッダー (BITMAPINFOHEADER, 40バイト)
+// OS/2 形式のヘッダーもこの形に読み替える
 type bmpInfoHeader struct {
 	HeaderSize      uint32 // ヘッダーサイズ (40)
 	Width           int32  // 画像の幅
 	Height          int32  // 画像の高さ (負の場合はトップダウン)
 	Planes          uint16 // プレーン数 (常に1)
-	BitCount        uint16 // ビット深度 (1, 4, 8, 24, 32)
+	BitCount        uint16 // ビット深度 (1, 4, 8, 16, 24, 32)
 	Compression     uint32 // 圧縮方式
 	ImageSize       uint32 // 画像データサイズ
 	XPixelsPerMeter int32  // 水平解像度
@@ -51,7 +76,80 @@ type bmpInfoHeader struct {
 	ColorsImportant uint32 // 重要な色数
 }
 
-// DecodeBMP はBMPファイルをデコードする（RLE圧縮対応）
+// bmpMasks は16・32ビットBMPの各色のビットマスク（Aが0の場合は不透明）
+type bmpMasks struct {
+	R, G, B, A uint32
+}
+
+// 16・32ビットの非圧縮BMPのビットマスク（32ビットの4バイト目は使わない）
+var (
+	bmpMasks16 = bmpMasks{R: 0x7C00, G: 0x03E0, B: 0x001F}       // 5-5-5
+	bmpMasks32 = bmpMasks{R: 0xFF0000, G: 0x00FF00, B: 0x0000FF} // 8-8-8
+)
+
+// bmpHeader はデコードに使うBMPのヘッダーの情報
+type bmpHeader struct {
+	info         bmpInfoHeader
+	core         bool // OS/2 1.x 形式（BITMAPCOREHEADER）
+	os2          bool // OS/2 2.x 形式
+	masks        bmpMasks
+	paletteEntry int // パレットの1色のバイト数（OS/2 1.x 形式は3、それ以外は4）
+}
+
+// readBMPInfoHeader は情報ヘッダーを読み込み、読み込んだバイト数を返す
+func readBMPInfoHeader(r io.Reader) (bmpHeader, int, error) {
+	var sizeBuf [4]byte
+	if _, err := io.ReadFull(r, sizeBuf[:]); err != nil {
+		return bmpHeader{}, 0, fmt.Errorf("failed to read BMP info header: %w", err)
+	}
+	size := binary.LittleEndian.Uint32(sizeBuf[:])
+
+	if size == bmpCoreHeaderSize {
+		// OS/2 1.x: 幅・高さ (uint16)、プレーン数、ビット深度のみ
+		var core struct {
+			Width, Height, Planes, BitCount uint16
+		}
+		if err := binary.Read(r, binary.LittleEndian, &core); err != nil {
+			return bmpHeader{}, 0, fmt.Errorf("failed to read BMP core header: %w", err)
+		}
+		h := bmpHeader{core: true, paletteEntry: 3}
+		h.info = bmpInfoHeader{
+			HeaderSize: size,
+			Width:      int32(core.Width),
+			Height:     int32(core.Height),
+			Planes:     core.Planes,
+			BitCount:   core.BitCount,
+		}
+		return h, int(size), nil
+	}
+
+	if size < bmpMinOS2HeaderSize || size > bmpMaxInfoHeaderSize {
+		return bmpHeader{}, 0, fmt.Errorf("unsupported BMP info header size: %d", size)
+	}
+
+	// 情報ヘッダーの残りを読み込む。40バイトより短い OS/2 2.x 形式のヘッダーは、省略したフィールドを0とする
+	raw := make([]byte, max(size, bmpV3HeaderSize))
+	copy(raw, sizeBuf[:])
+	if _, err := io.ReadFull(r, raw[4:size]); err != nil {
+		return bmpHeader{}, 0, fmt.Errorf("failed to read BMP info header: %w", err)
+	}
+	h := bmpHeader{paletteEntry: 4}
+	if err := binary.Read(bytes.NewReader(raw), binary.LittleEndian, &h.info); err != nil {
+		return bmpHeader{}, 0, fmt.Errorf("failed to read BMP info header: %w", err)
+	}
+	h.os2 = size != bmpInfoHeaderSize && size != bmpV2HeaderSize && size != bmpV3HeaderSize && size < bmpV4HeaderSize
+	if size >= bmpV2HeaderSize && !h.os2 {
+		h.masks = bmpMasks{
+			R: binary.LittleEndian.Uint32(raw[40:]),
+			G: binary.LittleEndian.Uint32(raw[44:]),
+			B: binary.LittleEndian.Uint32(raw[48:]),
+			A: binary.LittleEndian.Uint32(raw[52:]),
+		}
+	}
+	return h, int(size), nil
+}
+
+// DecodeBMP はBMPファイルをデコードする（RLE圧縮・OS/2形式対応）
 func DecodeBMP(r io.Reader) (image.Image, error) {
 	// ファイルヘッダーを読み込む
 	var fileHeader bmpFileHeader
@@ -65,13 +163,21 @@ func DecodeBMP(r io.Reader) (image.Image, error) {
 	}
 
 	// 情報ヘッダーを読み込む
-	var infoHeader bmpInfoHeader
-	if err := binary.Read(r, binary.LittleEndian, &infoHeader); err != nil {
-		return nil, fmt.Errorf("failed to read BMP info header: %w", err)
+	header, headerSize, err := readBMPInfoHeader(r)
+	if err != nil {
+		return nil, err
 	}
+	infoHeader := header.info
+	currentPos := bmpFileHeaderSize + headerSize
 
 	// サポートするビット深度を確認
-	if infoHeader.BitCount != 8 && infoHeader.BitCount != 4 && infoHeader.BitCount != 24 {
+	switch infoHeader.BitCount {
+	case 1, 4, 8, 24:
+	case 16, 32:
+		if header.core {
+			return nil, fmt.Errorf("unsupported bit depth for OS/2 BMP: %d", infoHeader.BitCount)
+		}
+	default:
 		return nil, fmt.Errorf("unsupported bit depth: %d", infoHeader.BitCount)
 	}
 
@@ -79,6 +185,12 @@ func DecodeBMP(r io.Reader) (image.Image, error) {
 	switch infoHeader.Compression {
 	case biRGB:
 		// 非圧縮
+		switch infoHeader.BitCount {
+		case 16:
+			header.masks = bmpMasks16
+		case 32:
+			header.masks = bmpMasks32
+		}
 	case biRLE8:
 		if infoHeader.BitCount != 8 {
 			return nil, fmt.Errorf("RLE8 compression requires 8-bit depth, got %d", infoHeader.BitCount)
@@ -87,6 +199,22 @@ func DecodeBMP(r io.Reader) (image.Image, error) {
 		if infoHeader.BitCount != 4 {
 			return nil, fmt.Errorf("RLE4 compression requires 4-bit depth, got %d", infoHeader.BitCount)
 		}
+	case biBitfields:
+		if header.os2 {
+			return nil, fmt.Errorf("unsupported compression for OS/2 BMP: %d (Huffman 1D)", infoHeader.Compression)
+		}
+		if infoHeader.BitCount != 16 && infoHeader.BitCount != 32 {
+			return nil, fmt.Errorf("BITFIELDS compression requires 16 or 32-bit depth, got %d", infoHeader.BitCount)
+		}
+		if infoHeader.HeaderSize == bmpInfoHeaderSize {
+			// BITMAPINFOHEADER の場合、RGBのマスクは情報ヘッダーの直後にある
+			var masks [3]uint32
+			if err := binary.Read(r, binary.LittleEndian, &masks); err != nil {
+				return nil, fmt.Errorf("failed to read BMP bit masks: %w", err)
+			}
+			header.masks = bmpMasks{R: masks[0], G: masks[1], B: masks[2]}
+			currentPos += len(masks) * 4
+		}
 	default:
 		return nil, fmt.Errorf("unsupported compression: %d", infoHeader.Compression)
 	}
@@ -103,7 +231,6 @@ func DecodeBMP(r io.Reader) (image.Image, error) {
 	// 寸法を検証する。幅・高さはファイル内の値をそのまま信頼してはならない。
 	// 負値は行サイズ計算で make([]byte, 負) を引き起こしクラッシュし、
 	// 過大値は image.NewRGBA の巨大確保でOOM/panicになるため、ここで弾く。
-	const maxBMPDimension = 1 << 16 // 65536px（実用上十分な上限）
 	if width <= 0 || height <= 0 {
 		return nil, fmt.Errorf("invalid BMP dimensions: %dx%d", width, height)
 	}
@@ -111,17 +238,22 @@ func DecodeBMP(r io.Reader) (image.Image, error) {
 		return nil, fmt.Errorf("BMP dimensions too large: %dx%d (max %d)", width, height, maxBMPDimension)
 	}
 
-	// カラーパレットを読み込む（8ビット、4ビットの場合）
+	// カラーパレットを読み込む（1, 4, 8ビットの場合）
 	var palette color.Palette
 	if infoHeader.BitCount <= 8 {
+		maxColors := 1 << infoHeader.BitCount
 		paletteSize := int(infoHeader.ColorsUsed)
-		if paletteSize == 0 {
-			paletteSize = 1 << infoHeader.BitCount
+		if paletteSize == 0 || paletteSize > maxColors {
+			paletteSize = maxColors
+		}
+		// OS/2 1.x 形式には使用色数がないため、画像データの位置までの色だけを読む
+		if header.core && int(fileHeader.DataOffset) > currentPos {
+			paletteSize = min(paletteSize, (int(fileHeader.DataOffset)-currentPos)/header.paletteEntry)
 		}
 		palette = make(color.Palette, paletteSize)
+		entry := make([]byte, header.paletteEntry) // BGR または BGRA
 		for i := 0; i < paletteSize; i++ {
-			var entry [4]byte // BGRA
-			if _, err := io.ReadFull(r, entry[:]); err != nil {
+			if _, err := io.ReadFull(r, entry); err != nil {
 				return nil, fmt.Errorf("failed to read palette entry %d: %w", i, err)
 			}
 			palette[i] = color.RGBA{
@@ -131,11 +263,10 @@ func DecodeBMP(r io.Reader) (image.Image, error) {
 				A: 255,
 			}
 		}
+		currentPos += paletteSize * header.paletteEntry
 	}
 
 	// 画像データの開始位置までスキップ
-	// 既に読み込んだバイト数: 14 (ファイルヘッダー) + 40 (情報ヘッダー) + パレットサイズ
-	currentPos := 14 + 40 + len(palette)*4
 	skipBytes := int(fileHeader.DataOffset) - currentPos
 	if skipBytes > 0 {
 		if _, err := io.CopyN(io.Discard, r, int64(skipBytes)); err != nil {
@@ -145,19 +276,20 @@ func DecodeBMP(r io.Reader) (image.Image, error) {
 
 	// 画像を作成
 	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	canvas := &bmpCanvas{img: img, width: width, height: height, topDown: topDown, palette: palette}
 
 	// 圧縮方式に応じてデコード
 	switch infoHeader.Compression {
-	case biRGB:
-		if err := decodeRGB(r, img, width, height, int(infoHeader.BitCount), palette, topDown); err != nil {
+	case biRGB, biBitfields:
+		if err := decodeRGB(r, canvas, int(infoHeader.BitCount), header.masks); err != nil {
 			return nil, err
 		}
 	case biRLE8:
-		if err := decodeRLE8(r, img, width, height, palette, topDown); err != nil {
+		if err := decodeRLE8(r, canvas); err != nil {
 			return nil, err
 		}
 	case biRLE4:
-		if err := decodeRLE4(r, img, width, height, palette, topDown); err != nil {
+		if err := decodeRLE4(r, canvas); err != nil {
 			return nil, err
 		}
 	}
@@ -165,60 +297,68 @@ func DecodeBMP(r io.Reader) (image.Image, error) {
 	return img, nil
 }
 
-// decodeRGB は非圧縮BMPをデコードする
-func decodeRGB(r io.Reader, img *image.RGBA, width, height, bitCount int, palette color.Palette, topDown bool) error {
-	// 行のパディングを計算（4バイト境界）
-	var rowSize int
-	switch bitCount {
-	case 8:
-		rowSize = (width + 3) &^ 3
-	case 4:
-		rowSize = ((width + 1) / 2)
-		rowSize = (rowSize + 3) &^ 3
-	case 24:
-		rowSize = (width*3 + 3) &^ 3
+// bmpCanvas はBMPの行の順序（ボトムアップ・トップダウン）を考慮して画素を書き込む
+type bmpCanvas struct {
+	img           *image.RGBA
+	width, height int
+	topDown       bool
+	palette       color.Palette
+}
+
+// set はファイル内の行 y（先頭の行が0）の x の位置に色を書き込む（画像の外は無視する）
+func (c *bmpCanvas) set(x, y int, col color.RGBA) {
+	if x < 0 || x >= c.width || y < 0 || y >= c.height {
+		return
+	}
+	// BMPはボトムアップ形式（topDownでない場合）
+	if !c.topDown {
+		y = c.height - 1 - y
 	}
+	i := c.img.PixOffset(x, y)
+	c.img.Pix[i], c.img.Pix[i+1], c.img.Pix[i+2], c.img.Pix[i+3] = col.R, col.G, col.B, col.A
+}
 
-	rowData := make([]byte, rowSize)
+// setIndex はパレットの色を書き込む（パレットにない番号は無視する）
+func (c *bmpCanvas) setIndex(x, y int, idx uint8) {
+	if int(idx) < len(c.palette) {
+		c.set(x, y, c.palette[idx].(color.RGBA))
+	}
+}
 
-	for y := 0; y < height; y++ {
-		// BMPはボトムアップ形式（topDownでない場合）
-		destY := y
-		if !topDown {
-			destY = height - 1 - y
-		}
+// decodeRGB は非圧縮BMP（BI_RGB・BI_BITFIELDS）をデコードする
+func decodeRGB(r io.Reader, c *bmpCanvas, bitCount int, masks bmpMasks) error {
+	// 行のパディングを計算（4バイト境界）
+	rowSize := ((c.width*bitCount + 31) / 32) * 4
+	rowData := make([]byte, rowSize)
 
+	for y := 0; y < c.height; y++ {
 		if _, err := io.ReadFull(r, rowData); err != nil {
 			return fmt.Errorf("failed to read row %d: %w", y, err)
 		}
 
 		switch bitCount {
-		case 8:
-			for x := 0; x < width; x++ {
-				idx := rowData[x]
-				if int(idx) < len(palette) {
-					img.Set(x, destY, palette[idx])
-				}
+		case 1, 4, 8:
+			// 1バイトに 8/bitCount ピクセル（上位ビットが左）
+			perByte := 8 / bitCount
+			mask := byte(1<<bitCount - 1)
+			for x := 0; x < c.width; x++ {
+				shift := (perByte - 1 - x%perByte) * bitCount
+				c.setIndex(x, y, rowData[x/perByte]>>shift&mask)
 			}
-		case 4:
-			for x := 0; x < width; x++ {
-				byteIdx := x / 2
-				var idx uint8
-				if x%2 == 0 {
-					idx = rowData[byteIdx] >> 4
-				} else {
-					idx = rowData[byteIdx] & 0x0F
-				}
-				if int(idx) < len(palette) {
-					img.Set(x, destY, palette[idx])
-				}
+		case 16:
+			for x := 0; x < c.width; x++ {
+				c.set(x, y, masks.color(uint32(binary.LittleEndian.Uint16(rowData[x*2:]))))
 			}
 		case 24:
-			for x := 0; x < width; x++ {
+			for x := 0; x < c.width; x++ {
 				b := rowData[x*3]
 				g := rowData[x*3+1]
 				r := rowData[x*3+2]
-				img.Set(x, destY, color.RGBA{R: r, G: g, B: b, A: 255})
+				c.set(x, y, color.RGBA{R: r, G: g, B: b, A: 255})
+			}
+		case 32:
+			for x := 0; x < c.width; x++ {
+				c.set(x, y, masks.color(binary.LittleEndian.Uint32(rowData[x*4:])))
 			}
 		}
 	}
@@ -226,6 +366,27 @@ func decodeRGB(r io.Reader, img *image.RGBA, width, height, bitCount int, palett
 	return nil
 }
 
+// color はビットマスクに従って画素の値を色にする
+// アルファのマスクがない場合は不透明とする
+func (m bmpMasks) color(v uint32) color.RGBA {
+	a := uint8(255)
+	if m.A != 0 {
+		a = maskChannel(v, m.A)
+	}
+	return color.RGBA{R: maskChannel(v, m.R), G: maskChannel(v, m.G), B: maskChannel(v, m.B), A: a}
+}
+
+// maskChannel はビットマスクの部分を取り出し、0〜255 に拡大する
+func maskChannel(v, mask uint32) uint8 {
+	if mask == 0 {
+		return 0
+	}
+	shift := bits.TrailingZeros32(mask)
+	width := bits.OnesCount32(mask)
+	maxValue := uint64(1)<<width - 1
+	return uint8(uint64((v&mask)>>shift) * 255 / maxValue)
+}
+
 // decodeRLE8 はRLE8圧縮BMPをデコードする
 // RLE8エンコーディング:
 //   - 2バイトペアを読み取る
@@ -235,7 +396,9 @@ func decodeRGB(r io.Reader, img *image.RGBA, width, height, bitCount int, palett
 //   - 2番目のバイトが1: ビットマップ終了 (End of Bitmap)
 //   - 2番目のバイトが2: デルタ（位置移動）
 //   - それ以外: 絶対モード（2番目のバイト個のピクセルをそのまま読み取る）
-func decodeRLE8(r io.Reader, img *image.RGBA, width, height int, palette color.Palette, topDown bool) error {
+//
+// デルタで飛ばしたピクセルは透明のままにする。
+func decodeRLE8(r io.Reader, c *bmpCanvas) error {
 	x, y := 0, 0
 
 	for {
@@ -253,15 +416,7 @@ func decodeRLE8(r io.Reader, img *image.RGBA, width, height int, palette color.P
 		if count > 0 {
 			// エンコードモード: valueをcount回繰り返す
 			for i := 0; i < count; i++ {
-				if x < width && y < height {
-					destY := y
-					if !topDown {
-						destY = height - 1 - y
-					}
-					if int(value) < len(palette) {
-						img.Set(x, destY, palette[value])
-					}
-				}
+				c.setIndex(x, y, value)
 				x++
 			}
 		} else {
@@ -285,32 +440,16 @@ func decodeRLE8(r io.Reader, img *image.RGBA, width, height int, palette color.P
 			default:
 				// 絶対モード: value個のピクセルをそのまま読み取る
 				absCount := int(value)
-				absData := make([]byte, absCount)
+				// 絶対モードは2バイト境界にパディングされる
+				absData := make([]byte, (absCount+1)&^1)
 				if _, err := io.ReadFull(r, absData); err != nil {
 					return fmt.Errorf("failed to read RLE8 absolute data: %w", err)
 				}
 
 				for i := 0; i < absCount; i++ {
-					if x < width && y < height {
-						destY := y
-						if !topDown {
-							destY = height - 1 - y
-						}
-						idx := absData[i]
-						if int(idx) < len(palette) {
-							img.Set(x, destY, palette[idx])
-						}
-					}
+					c.setIndex(x, y, absData[i])
 					x++
 				}
-
-				// 絶対モードは2バイト境界にパディングされる
-				if absCount%2 != 0 {
-					var padding [1]byte
-					if _, err := io.ReadFull(r, padding[:]); err != nil {
-						return fmt.Errorf("failed to read RLE8 padding: %w", err)
-					}
-				}
 			}
 		}
 	}
@@ -327,7 +466,9 @@ func decodeRLE8(r io.Reader, img *image.RGBA, width, height int, palette color.P
 //   - 2番目のバイトが1: ビットマップ終了 (End of Bitmap)
 //   - 2番目のバイトが2: デルタ（位置移動）
 //   - それ以外: 絶対モード（2番目のバイト個のピクセルをそのまま読み取る）
-func decodeRLE4(r io.Reader, img *image.RGBA, width, height int, palette color.Palette, topDown bool) error {
+//
+// デルタで飛ばしたピクセルは透明のままにする。
+func decodeRLE4(r io.Reader, c *bmpCanvas) error {
 	x, y := 0, 0
 
 	for {
@@ -348,20 +489,10 @@ func decodeRLE4(r io.Reader, img *image.RGBA, width, height int, palette color.P
 			lowNibble := value & 0x0F
 
 			for i := 0; i < count; i++ {
-				if x < width && y < height {
-					destY := y
-					if !topDown {
-						destY = height - 1 - y
-					}
-					var idx uint8
-					if i%2 == 0 {
-						idx = highNibble
-					} else {
-						idx = lowNibble
-					}
-					if int(idx) < len(palette) {
-						img.Set(x, destY, palette[idx])
-					}
+				if i%2 == 0 {
+					c.setIndex(x, y, highNibble)
+				} else {
+					c.setIndex(x, y, lowNibble)
 				}
 				x++
 			}
@@ -386,40 +517,22 @@ func decodeRLE4(r io.Reader, img *image.RGBA, width, height int, palette color.P
 			default:
 				// 絶対モード: value個のピクセルをそのまま読み取る
 				absCount := int(value)
-				// 必要なバイト数を計算（2ピクセルで1バイト）
+				// 必要なバイト数を計算（2ピクセルで1バイト）し、2バイト境界にパディングされる
 				absBytes := (absCount + 1) / 2
-				absData := make([]byte, absBytes)
+				absData := make([]byte, (absBytes+1)&^1)
 				if _, err := io.ReadFull(r, absData); err != nil {
 					return fmt.Errorf("failed to read RLE4 absolute data: %w", err)
 				}
 
 				for i := 0; i < absCount; i++ {
-					if x < width && y < height {
-						destY := y
-						if !topDown {
-							destY = height - 1 - y
-						}
-						byteIdx := i / 2
-						var idx uint8
-						if i%2 == 0 {
-							idx = absData[byteIdx] >> 4
-						} else {
-							idx = absData[byteIdx] & 0x0F
-						}
-						if int(idx) < len(palette) {
-							img.Set(x, destY, palette[idx])
-						}
+					byteIdx := i / 2
+					if i%2 == 0 {
+						c.setIndex(x, y, absData[byteIdx]>>4)
+					} else {
+						c.setIndex(x, y, absData[byteIdx]&0x0F)
 					}
 					x++
 				}
-
-				// 絶対モードは2バイト境界にパディングされる
-				if absBytes%2 != 0 {
-					var padding [1]byte
-					if _, err := io.ReadFull(r, padding[:]); err != nil {
-						return fmt.Errorf("failed to read RLE4 padding: %w", err)
-					}
-				}
 			}
 		}
 	}
@@ -427,6 +540,32 @@ func decodeRLE4(r io.Reader, img *image.RGBA, width, height int, palette color.P
 	return nil
 }
 
+// bmpRLEFromHeader はBMPの先頭のバイト列からRLE圧縮かどうかを判定する
+// OS/2 1.x 形式（BITMAPCOREHEADER）には圧縮方式がないため、常に非圧縮とする
+func bmpRLEFromHeader(data []byte) (bool, error) {
+	if len(data) < bmpFileHeaderSize+4 {
+		return false, fmt.Errorf("data too short for BMP header")
+	}
+
+	// シグネチャを確認
+	if data[0] != 'B' || data[1] != 'M' {
+		return false, nil // BMPファイルではない
+	}
+
+	// 圧縮方式は情報ヘッダーの16バイト目（オフセット 30 = 14 + 16）
+	const compressionOffset = bmpFileHeaderSize + 16
+	if headerSize := binary.LittleEndian.Uint32(data[bmpFileHeaderSize:]); headerSize < 20 {
+		return false, nil // 圧縮方式のフィールドがない（OS/2 1.x 形式など）
+	}
+	if len(data) < compressionOffset+4 {
+		return false, fmt.Errorf("data too short for BMP header")
+	}
+	compression := binary.LittleEndian.Uint32(data[compressionOffset:])
+
+	// RLE圧縮かどうかを判定
+	return compression == biRLE8 || compression == biRLE4, nil
+}
+
 // IsBMPRLECompressed はBMPファイルがRLE圧縮されているかどうかを判定する
 // ファイルの先頭を読み取り、圧縮方式を確認する
 func IsBMPRLECompressed(r io.ReadSeeker) (bool, error) {
@@ -441,22 +580,10 @@ func IsBMPRLECompressed(r io.ReadSeeker) (bool, error) {
 		return false, err
 	}
 
-	// ファイルヘッダーを読み込む
-	var fileHeader bmpFileHeader
-	if err := binary.Read(r, binary.LittleEndian, &fileHeader); err != nil {
-		r.Seek(pos, io.SeekStart) // 元の位置に戻す
-		return false, err
-	}
-
-	// シグネチャを確認
-	if fileHeader.Signature[0] != 'B' || fileHeader.Signature[1] != 'M' {
-		r.Seek(pos, io.SeekStart) // 元の位置に戻す
-		return false, nil         // BMPファイルではない
-	}
-
-	// 情報ヘッダーを読み込む
-	var infoHeader bmpInfoHeader
-	if err := binary.Read(r, binary.LittleEndian, &infoHeader); err != nil {
+	// ファイルヘッダーと情報ヘッダーの圧縮方式までを読み込む
+	header := make([]byte, bmpFileHeaderSize+20)
+	n, err := io.ReadFull(r, header)
+	if err != nil && err != io.ErrUnexpectedEOF {
 		r.Seek(pos, io.SeekStart) // 元の位置に戻す
 		return false, err
 	}
@@ -467,28 +594,53 @@ func IsBMPRLECompressed(r io.ReadSeeker) (bool, error) {
 	}
 
 	// RLE圧縮かどうかを判定
-	return infoHeader.Compression == biRLE8 || infoHeader.Compression == biRLE4, nil
+	return bmpRLEFromHeader(header[:n])
 }
 
 // IsBMPRLECompressedFromBytes はバイト配列からBMPがRLE圧縮されているかどうかを判定する
 func IsBMPRLECompressedFromBytes(data []byte) (bool, error) {
-	if len(data) < 54 { // 14 (file header) + 40 (info header)
-		return false, fmt.Errorf("data too short for BMP header")
-	}
-
-	// シグネチャを確認
-	if data[0] != 'B' || data[1] != 'M' {
-		return false, nil // BMPファイルではない
-	}
-
-	// 圧縮方式を読み取る（オフセット 30 = 14 + 16）
-	compression := binary.LittleEndian.Uint32(data[30:34])
-
-	// RLE圧縮かどうかを判定
-	return compression == biRLE8 || compression == biRLE4, nil
+	return bmpRLEFromHeader(data)
 }
 
 // DecodeBMPFromBytes はバイト配列からBMPをデコードする
 func DecodeBMPFromBytes(data []byte) (image.Image, error) {
 	return DecodeBMP(bytes.NewReader(data))
 }
+
+// BMPヘッダーのサイズの位置
+const (
+	bmpInfoHeaderSizeOffset = 14 // 情報ヘッダーのサイズ（ファイルヘッダーの直後）
+	bmpWidthOffset          = 18
+	bmpHeightOffset         = 22
+	bmpCoreHeaderSize       = 12 // OS/2 形式の情報ヘッダー（幅・高さは16ビット）
+	bmpCoreHeightOffset     = 20
+)
+
+// pictureSize はファイルの内容のヘッダーから画像のサイズを読み取る（デコードはしない）
+// RLE圧縮BMPは標準のデコーダーが対応していないため、BMPはヘッダーを直接読む
+func pictureSize(data []byte) (int, int, error) {
+	if len(data) >= bmpHeightOffset+4 && data[0] == 'B' && data[1] == 'M' {
+		var width, height int
+		if binary.LittleEndian.Uint32(data[bmpInfoHeaderSizeOffset:]) == bmpCoreHeaderSize {
+			width = int(binary.LittleEndian.Uint16(data[bmpWidthOffset:]))
+			height = int(binary.LittleEndian.Uint16(data[bmpCoreHeightOffset:]))
+		} else {
+			width = int(int32(binary.LittleEndian.Uint32(data[bmpWidthOffset:])))
+			height = int(int32(binary.LittleEndian.Uint32(data[bmpHeightOffset:])))
+		}
+		height = max(height, -height) // 負の高さはトップダウン形式
+		if width <= 0 || height <= 0 || width > maxBMPDimension || height > maxBMPDimension {
+			return 0, 0, fmt.Errorf("invalid BMP dimensions: %dx%d", width, height)
+		}
+		return width, height, nil
+	}
+
+	config, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to read image header: %w", err)
+	}
+	if config.Width <= 0 || config.Height <= 0 {
+		return 0, 0, fmt.Errorf("invalid image dimensions: %dx%d", config.Width, config.Height)
+	}
+	return config.Width, config.Height, nil
+}
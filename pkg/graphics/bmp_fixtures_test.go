@@ -0,0 +1,182 @@
+//go:build !nogpu
+
+package graphics
+
+import (
+	"image"
+	"image/color"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// testdata/bmp のBMPファイルは、同じ5x3の画像をさまざまなヘッダー・ビット深度・圧縮方式で保存したもの
+// 各行の文字は画素の色（K=黒, R=赤, G=緑, B=青, W=白, .=透明）
+var (
+	bmpFixtureImage = []string{
+		"RGBKW",
+		"WKRGB",
+		"KKKRR",
+	}
+	// 1ビットのファイルは黒と白のみ
+	bmpFixtureBinaryImage = []string{
+		"WKWKW",
+		"KWKWK",
+		"WWWKK",
+	}
+	// rle8_delta.bmp はデルタで飛ばした画素と、ビットマップ終了で省略した行が透明になる
+	bmpFixtureDeltaImage = []string{
+		".....",
+		"WWWWW",
+		"RR..B",
+	}
+)
+
+var bmpFixtureColors = map[byte]color.RGBA{
+	'K': {0, 0, 0, 255},
+	'R': {255, 0, 0, 255},
+	'G': {0, 255, 0, 255},
+	'B': {0, 0, 255, 255},
+	'W': {255, 255, 255, 255},
+	'.': {},
+}
+
+// TestDecodeBMP_Fixtures はBMPの各形式のファイルが同じ画像にデコードされることをテストする
+func TestDecodeBMP_Fixtures(t *testing.T) {
+	tests := []struct {
+		file string
+		rle  bool
+		want []string
+	}{
+		// BITMAPINFOHEADER
+		{"rgb1.bmp", false, bmpFixtureBinaryImage},
+		{"rgb4.bmp", false, bmpFixtureImage},
+		{"rgb8.bmp", false, bmpFixtureImage},
+		{"rgb8_topdown.bmp", false, bmpFixtureImage},
+		{"rgb8_gap.bmp", false, bmpFixtureImage},
+		{"rgb16_555.bmp", false, bmpFixtureImage},
+		{"bitfields16_565.bmp", false, bmpFixtureImage},
+		{"rgb24.bmp", false, bmpFixtureImage},
+		{"rgb24_topdown.bmp", false, bmpFixtureImage},
+		{"rgb32.bmp", false, bmpFixtureImage},
+		// BITMAPV4HEADER / BITMAPV5HEADER（パレットは長い情報ヘッダーの後にある）
+		{"v4_bitfields32_alpha.bmp", false, bmpFixtureImage},
+		{"v5_rgb8.bmp", false, bmpFixtureImage},
+		// RLE圧縮
+		{"rle8.bmp", true, bmpFixtureImage},
+		{"rle8_topdown.bmp", true, bmpFixtureImage},
+		{"rle8_delta.bmp", true, bmpFixtureDeltaImage},
+		{"rle4.bmp", true, bmpFixtureImage},
+		// OS/2 1.x（BITMAPCOREHEADER）
+		{"os2_core1.bmp", false, bmpFixtureBinaryImage},
+		{"os2_core4.bmp", false, bmpFixtureImage},
+		{"os2_core8.bmp", false, bmpFixtureImage},
+		{"os2_core24.bmp", false, bmpFixtureImage},
+		// OS/2 2.x
+		{"os2v2_rle8.bmp", true, bmpFixtureImage},
+		{"os2v2_short4.bmp", false, bmpFixtureImage},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.file, func(t *testing.T) {
+			data, err := os.ReadFile(filepath.Join("testdata", "bmp", tt.file))
+			if err != nil {
+				t.Fatalf("failed to read fixture: %v", err)
+			}
+			isRLE, err := IsBMPRLECompressedFromBytes(data)
+			if err != nil {
+				t.Fatalf("IsBMPRLECompressedFromBytes failed: %v", err)
+			}
+			if isRLE != tt.rle {
+				t.Errorf("IsBMPRLECompressedFromBytes = %v, want %v", isRLE, tt.rle)
+			}
+
+			img, err := DecodeBMPFromBytes(data)
+			if err != nil {
+				t.Fatalf("DecodeBMPFromBytes failed: %v", err)
+			}
+			assertBMPFixtureImage(t, img, tt.want)
+		})
+	}
+}
+
+// TestDecodeBMP_UnsupportedFixtures はサポートしない形式がパニックせずにエラーになることをテストする
+func TestDecodeBMP_UnsupportedFixtures(t *testing.T) {
+	for _, file := range []string{"os2v2_huffman.bmp"} {
+		t.Run(file, func(t *testing.T) {
+			data, err := os.ReadFile(filepath.Join("testdata", "bmp", file))
+			if err != nil {
+				t.Fatalf("failed to read fixture: %v", err)
+			}
+			if _, err := DecodeBMPFromBytes(data); err == nil {
+				t.Error("expected an error for an unsupported BMP")
+			}
+		})
+	}
+}
+
+// TestDecodeBMP_Truncated は途中で切れたファイルがパニックせずにエラーになることをテストする
+func TestDecodeBMP_Truncated(t *testing.T) {
+	for _, file := range []string{"rgb8.bmp", "os2_core8.bmp", "v5_rgb8.bmp", "bitfields16_565.bmp"} {
+		data, err := os.ReadFile(filepath.Join("testdata", "bmp", file))
+		if err != nil {
+			t.Fatalf("failed to read fixture: %v", err)
+		}
+		for n := 0; n < len(data); n++ {
+			if _, err := DecodeBMPFromBytes(data[:n]); err == nil {
+				t.Errorf("%s truncated to %d bytes: expected an error", file, n)
+			}
+		}
+	}
+}
+
+// TestDecodePictureData_BMPFallback は標準デコーダーが対応していないBMPをカスタムデコーダーで読むことをテストする
+func TestDecodePictureData_BMPFallback(t *testing.T) {
+	for _, file := range []string{"os2_core8.bmp", "rgb16_555.bmp", "rle4.bmp"} {
+		data, err := os.ReadFile(filepath.Join("testdata", "bmp", file))
+		if err != nil {
+			t.Fatalf("failed to read fixture: %v", err)
+		}
+		img, err := decodePictureData(data, file, slog.Default())
+		if err != nil {
+			t.Fatalf("%s: decodePictureData failed: %v", file, err)
+		}
+		assertBMPFixtureImage(t, img, bmpFixtureImage)
+	}
+}
+
+// TestMaskChannel はビットマスクの値が0〜255に拡大されることをテストする
+func TestMaskChannel(t *testing.T) {
+	tests := []struct {
+		v, mask uint32
+		want    uint8
+	}{
+		{0x7C00, 0x7C00, 255},
+		{0x4000, 0x7C00, 131}, // 16/31
+		{0x07E0, 0x07E0, 255},
+		{0x12345678, 0, 0},
+		{0xFF000000, 0xFF000000, 255},
+	}
+	for _, tt := range tests {
+		if got := maskChannel(tt.v, tt.mask); got != tt.want {
+			t.Errorf("maskChannel(%#x, %#x) = %d, want %d", tt.v, tt.mask, got, tt.want)
+		}
+	}
+}
+
+// assertBMPFixtureImage は画像の各画素が want の色であることを確認する
+func assertBMPFixtureImage(t *testing.T, img image.Image, want []string) {
+	t.Helper()
+	if b := img.Bounds(); b.Dx() != len(want[0]) || b.Dy() != len(want) {
+		t.Fatalf("bounds = %v, want %dx%d", b, len(want[0]), len(want))
+	}
+	for y, row := range want {
+		for x := 0; x < len(row); x++ {
+			wantColor := bmpFixtureColors[row[x]]
+			if got := color.RGBAModel.Convert(img.At(x, y)).(color.RGBA); got != wantColor {
+				t.Errorf("pixel (%d,%d) = %v, want %c %v", x, y, got, row[x], wantColor)
+			}
+		}
+	}
+}
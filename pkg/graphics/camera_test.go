@@ -0,0 +1,136 @@
+//go:build !nogpu
+
+package graphics
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func TestCameraTransformRoundTrip(t *testing.T) {
+	cam := Camera{X: 100, Y: 80, Zoom: 2}
+	// カメラの位置は画面の中央に表示される
+	if x, y := cam.toScreen(100, 80, 640, 480); x != 320 || y != 240 {
+		t.Errorf("toScreen(center) = (%v, %v), want (320, 240)", x, y)
+	}
+	if x, y := cam.toScreen(110, 70, 640, 480); x != 340 || y != 220 {
+		t.Errorf("toScreen(110, 70) = (%v, %v), want (340, 220)", x, y)
+	}
+	for _, pt := range [][2]float64{{0, 0}, {320, 240}, {639, 479}, {17.5, 300}} {
+		sx, sy := cam.toScene(pt[0], pt[1], 640, 480)
+		x, y := cam.toScreen(sx, sy, 640, 480)
+		if math.Abs(x-pt[0]) > 1e-9 || math.Abs(y-pt[1]) > 1e-9 {
+			t.Errorf("toScreen(toScene(%v)) = (%v, %v)", pt, x, y)
+		}
+	}
+
+	// 既定のカメラは座標を変えない
+	def := defaultCamera(640, 480)
+	if x, y := def.toScene(12, 34, 640, 480); x != 12 || y != 34 {
+		t.Errorf("default camera toScene(12, 34) = (%v, %v)", x, y)
+	}
+}
+
+func TestCameraValidate(t *testing.T) {
+	tests := []struct {
+		cam     Camera
+		wantErr bool
+	}{
+		{Camera{Zoom: 1}, false},
+		{Camera{X: -50, Y: 1000, Zoom: MinCameraZoom}, false},
+		{Camera{Zoom: MaxCameraZoom}, false},
+		{Camera{Zoom: 0}, true},
+		{Camera{Zoom: MaxCameraZoom + 1}, true},
+		{Camera{Zoom: math.NaN()}, true},
+		{Camera{X: math.NaN(), Zoom: 1}, true},
+	}
+	for _, tt := range tests {
+		if err := tt.cam.validate(); (err != nil) != tt.wantErr {
+			t.Errorf("validate(%+v) = %v, wantErr %v", tt.cam, err, tt.wantErr)
+		}
+	}
+}
+
+func TestCameraTween(t *testing.T) {
+	tw := &cameraTween{
+		from:   Camera{X: 0, Y: 0, Zoom: 1},
+		to:     Camera{X: 100, Y: 50, Zoom: 4},
+		easing: EaseLinear,
+		frames: 4,
+	}
+	if got := tw.current(); got != tw.from {
+		t.Errorf("frame 0 = %+v, want %+v", got, tw.from)
+	}
+	tw.frame = 2
+	got := tw.current()
+	// ズームは比率で補間する（1 と 4 の中間は 2）
+	if got.X != 50 || got.Y != 25 || math.Abs(got.Zoom-2) > 1e-9 {
+		t.Errorf("frame 2 = %+v, want {50 25 2}", got)
+	}
+	tw.frame = 4
+	if got := tw.current(); got != tw.to {
+		t.Errorf("frame 4 = %+v, want %+v", got, tw.to)
+	}
+}
+
+func TestHeadlessCamera(t *testing.T) {
+	hgs := NewHeadlessGraphicsSystem()
+	if got, want := hgs.Camera(), defaultCamera(hgs.virtualWidth, hgs.virtualHeight); got != want {
+		t.Errorf("initial camera = %+v, want %+v", got, want)
+	}
+	if err := hgs.SetCamera(&Camera{X: 10, Y: 20, Zoom: 0.1}); err == nil {
+		t.Error("expected an error for a zoom out of range")
+	}
+	want := Camera{X: 200, Y: 100, Zoom: 3}
+	if err := hgs.MoveCamera(want, 0, EaseInOut); err != nil {
+		t.Fatalf("MoveCamera: %v", err)
+	}
+	if got := hgs.Camera(); got != want {
+		t.Errorf("camera after MoveCamera = %+v, want %+v", got, want)
+	}
+	if err := hgs.MoveCamera(want, 0, Easing(9)); err == nil {
+		t.Error("expected an error for an unknown easing")
+	}
+	if err := hgs.SetCamera(nil); err != nil {
+		t.Fatalf("SetCamera(nil): %v", err)
+	}
+	if got, want := hgs.Camera(), defaultCamera(hgs.virtualWidth, hgs.virtualHeight); got != want {
+		t.Errorf("camera after reset = %+v, want %+v", got, want)
+	}
+}
+
+func TestGraphicsSystemCamera(t *testing.T) {
+	gs := NewGraphicsSystem("")
+	if x, y := gs.SceneFromScreen(100, 50); x != 100 || y != 50 {
+		t.Errorf("SceneFromScreen without a camera = (%d, %d), want (100, 50)", x, y)
+	}
+
+	cx, cy := float64(gs.virtualWidth)/2, float64(gs.virtualHeight)/2
+	if err := gs.SetCamera(&Camera{X: cx, Y: cy, Zoom: 2}); err != nil {
+		t.Fatalf("SetCamera: %v", err)
+	}
+	// 2倍にズームすると、画面の中央から離れた点は中央に半分だけ近づく
+	if x, y := gs.SceneFromScreen(int(cx)+100, int(cy)-40); x != int(cx)+50 || y != int(cy)-20 {
+		t.Errorf("SceneFromScreen with zoom 2 = (%d, %d), want (%d, %d)", x, y, int(cx)+50, int(cy)-20)
+	}
+	if err := gs.SetCamera(&Camera{Zoom: 100}); err == nil {
+		t.Error("expected an error for a zoom out of range")
+	}
+
+	// 既定のカメラに戻す移動が終わると、カメラはなくなる
+	if err := gs.MoveCamera(defaultCamera(gs.virtualWidth, gs.virtualHeight), 100*time.Millisecond, EaseIn); err != nil {
+		t.Fatalf("MoveCamera: %v", err)
+	}
+	frames := gs.cameraMove.frames
+	for range frames - 1 {
+		gs.updateCamera()
+	}
+	if gs.cameraMove == nil || gs.camera == nil || gs.camera.Zoom <= 1 {
+		t.Fatalf("camera finished too early: move=%+v camera=%+v", gs.cameraMove, gs.camera)
+	}
+	gs.updateCamera()
+	if gs.cameraMove != nil || gs.camera != nil {
+		t.Errorf("camera after the move = (%+v, %+v), want (nil, nil)", gs.cameraMove, gs.camera)
+	}
+}
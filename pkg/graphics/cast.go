@@ -81,6 +81,16 @@ func NewCastManager() *CastManager {
 	}
 }
 
+// SetMaxCasts はキャストの最大数を設定する（サンドボックスモードで使用）
+// 既定の上限（1024）より大きい値は無視する
+func (cm *CastManager) SetMaxCasts(max int) {
+	cm.mu.Lock()
+	defer cm.mu.Unlock()
+	if max > 0 && max < cm.maxID {
+		cm.maxID = max
+	}
+}
+
 // SetLayerManager は削除されました
 // Deprecated: スプライトシステム移行により不要になった
 
@@ -234,6 +244,41 @@ func (cm *CastManager) DeleteCastsByWindow(winID int) {
 	}
 }
 
+// Restack はキャストを同じウィンドウのキャストの中で最前面（toFront）または最背面に移動する
+// ウィンドウ内のキャストが使用しているZ順序の値を並べ替え後の順に割り当て直し、
+// ウィンドウ内のキャストのIDを奥から手前の順に返す
+func (cm *CastManager) Restack(castID int, toFront bool) ([]int, error) {
+	cm.mu.Lock()
+	defer cm.mu.Unlock()
+
+	target, exists := cm.casts[castID]
+	if !exists {
+		return nil, fmt.Errorf("cast not found: %d", castID)
+	}
+
+	siblings := make([]*Cast, 0)
+	for _, cast := range cm.casts {
+		if cast.WinID == target.WinID {
+			siblings = append(siblings, cast)
+		}
+	}
+	sort.Slice(siblings, func(i, j int) bool {
+		return siblings[i].ZOrder < siblings[j].ZOrder
+	})
+
+	ids := make([]int, len(siblings))
+	slots := make([]int, len(siblings))
+	for i, cast := range siblings {
+		ids[i] = cast.ID
+		slots[i] = cast.ZOrder
+	}
+	order := restack(ids, castID, toFront)
+	for i, id := range order {
+		cm.casts[id].ZOrder = slots[i]
+	}
+	return order, nil
+}
+
 // GetCastsOrdered はすべてのキャストをZ順序でソートして返す
 func (cm *CastManager) GetCastsOrdered() []*Cast {
 	cm.mu.RLock()
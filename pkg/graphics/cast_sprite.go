@@ -1,3 +1,5 @@
+//go:build !nogpu
+
 // Package graphics provides sprite-based rendering system.
 package graphics
 
@@ -36,6 +38,8 @@ type CastSprite struct {
 	cachedImage *ebiten.Image // 透明色処理済みのキャッシュ画像
 	dirty       bool          // キャッシュが無効かどうか
 
+	atlas *castAtlas // スプライトの画像を作成するアトラス
+
 	mu sync.RWMutex
 }
 
@@ -43,6 +47,7 @@ type CastSprite struct {
 type CastSpriteManager struct {
 	castSprites   map[int]*CastSprite // castID -> CastSprite
 	spriteManager *SpriteManager
+	atlas         *castAtlas // 小さなキャストの画像をまとめて配置するアトラス
 	mu            sync.RWMutex
 }
 
@@ -51,6 +56,7 @@ func NewCastSpriteManager(sm *SpriteManager) *CastSpriteManager {
 	return &CastSpriteManager{
 		castSprites:   make(map[int]*CastSprite),
 		spriteManager: sm,
+		atlas:         newCastAtlas(),
 	}
 }
 
@@ -96,6 +102,7 @@ func (csm *CastSpriteManager) CreateCastSprite(
 		hasTransColor: cast.HasTransColor,
 		cachedImage:   img,
 		dirty:         false,
+		atlas:         csm.atlas,
 	}
 
 	csm.castSprites[cast.ID] = cs
@@ -188,6 +195,7 @@ func (csm *CastSpriteManager) CreateCastSpriteWithTransColor(
 		hasTransColor: transColor != nil,
 		cachedImage:   img, // 透明色処理済みの画像をキャッシュ
 		dirty:         false,
+		atlas:         csm.atlas,
 	}
 
 	// 透明色が設定されている場合、customDraw関数を設定
@@ -273,9 +281,9 @@ func (csm *CastSpriteManager) extractCastImage(srcImage *ebiten.Image, cast *Cas
 	srcRect := image.Rect(srcX, srcY, srcX+srcW, srcY+srcH)
 	subImg := srcImage.SubImage(srcRect).(*ebiten.Image)
 
-	// 新しい画像にコピー
-	img := ebiten.NewImage(srcW, srcH)
-	img.DrawImage(subImg, nil)
+	// 新しい画像にコピー（小さな画像はアトラスに配置する）
+	img := csm.atlas.newImage(srcW, srcH)
+	copyImage(img, subImg)
 
 	return img
 }
@@ -419,11 +427,16 @@ func (csm *CastSpriteManager) RemoveCastSprite(castID int) {
 		return
 	}
 
-	// スプライトを削除
+	csm.removeLocked(castID, cs)
+}
+
+// removeLocked はCastSpriteのスプライトを削除し、アトラスの領域を解放する
+// 呼び出し元は csm.mu のロックを保持していること
+func (csm *CastSpriteManager) removeLocked(castID int, cs *CastSprite) {
 	if cs.sprite != nil {
 		csm.spriteManager.RemoveSprite(cs.sprite.ID())
+		csm.atlas.release(cs.sprite.Image())
 	}
-
 	delete(csm.castSprites, castID)
 }
 
@@ -440,11 +453,7 @@ func (csm *CastSpriteManager) RemoveCastSpritesByWindow(winID int) {
 	}
 
 	for _, castID := range toDelete {
-		cs := csm.castSprites[castID]
-		if cs.sprite != nil {
-			csm.spriteManager.RemoveSprite(cs.sprite.ID())
-		}
-		delete(csm.castSprites, castID)
+		csm.removeLocked(castID, csm.castSprites[castID])
 	}
 }
 
@@ -454,10 +463,7 @@ func (csm *CastSpriteManager) Clear() {
 	defer csm.mu.Unlock()
 
 	for castID, cs := range csm.castSprites {
-		if cs.sprite != nil {
-			csm.spriteManager.RemoveSprite(cs.sprite.ID())
-		}
-		delete(csm.castSprites, castID)
+		csm.removeLocked(castID, cs)
 	}
 }
 
@@ -654,25 +660,25 @@ func (cs *CastSprite) setupCustomDrawLocked() {
 	// 透明色処理済みの画像をキャッシュとして作成
 	// これにより毎フレームの透明色処理を避ける
 	cs.rebuildTransparentCacheLocked()
+	cs.sprite.SetCustomDraw(cs.drawCachedImage)
+}
 
-	// クロージャでCastSpriteへの参照を保持
-	cs.sprite.SetCustomDraw(func(screen *ebiten.Image, x, y float64, alpha float32) {
-		cs.mu.RLock()
-		cachedImg := cs.cachedImage
-		cs.mu.RUnlock()
+// drawCachedImage はキャッシュ済みの透明色処理画像を描画するカスタム描画関数
+func (cs *CastSprite) drawCachedImage(screen *ebiten.Image, x, y float64, alpha float32) {
+	cs.mu.RLock()
+	cachedImg := cs.cachedImage
+	cs.mu.RUnlock()
 
-		if cachedImg == nil {
-			return
-		}
+	if cachedImg == nil {
+		return
+	}
 
-		// キャッシュ済みの透明色処理画像を描画
-		op := &ebiten.DrawImageOptions{}
-		op.GeoM.Translate(x, y)
-		if alpha < 1.0 {
-			op.ColorScale.ScaleAlpha(alpha)
-		}
-		screen.DrawImage(cachedImg, op)
-	})
+	op := &ebiten.DrawImageOptions{}
+	op.GeoM.Translate(x, y)
+	if alpha < 1.0 {
+		op.ColorScale.ScaleAlpha(alpha)
+	}
+	screen.DrawImage(cachedImg, op)
 }
 
 // rebuildTransparentCacheLocked は透明色処理済みのキャッシュ画像を再構築する（ロック済み版）
@@ -779,9 +785,11 @@ func (cs *CastSprite) RebuildCache(srcImage *ebiten.Image) {
 		return
 	}
 
-	// スプライトの画像を更新
+	// スプライトの画像を更新し、前の画像のアトラスの領域を解放する
 	if cs.sprite != nil {
+		old := cs.sprite.Image()
 		cs.sprite.SetImage(img)
+		cs.atlas.release(old)
 	}
 
 	// 透明色が設定されている場合、透明色処理済みのキャッシュを再構築
@@ -831,9 +839,9 @@ func (cs *CastSprite) extractImage(srcImage *ebiten.Image) *ebiten.Image {
 	srcRect := image.Rect(srcX, srcY, srcX+srcW, srcY+srcH)
 	subImg := srcImage.SubImage(srcRect).(*ebiten.Image)
 
-	// 新しい画像にコピー
-	img := ebiten.NewImage(srcW, srcH)
-	img.DrawImage(subImg, nil)
+	// 新しい画像にコピー（小さな画像はアトラスに配置する）
+	img := cs.atlas.newImage(srcW, srcH)
+	copyImage(img, subImg)
 
 	return img
 }
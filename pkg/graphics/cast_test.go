@@ -14,6 +14,27 @@ func TestNewCastManager(t *testing.T) {
 	}
 }
 
+func TestCastManagerSetMaxCasts(t *testing.T) {
+	cm := NewCastManager()
+	cm.SetMaxCasts(2)
+
+	for i := 0; i < 2; i++ {
+		if _, err := cm.PutCast(0, 1, 0, 0, 0, 0, 8, 8); err != nil {
+			t.Fatalf("PutCast %d failed: %v", i, err)
+		}
+	}
+	if _, err := cm.PutCast(0, 1, 0, 0, 0, 0, 8, 8); err == nil {
+		t.Error("PutCast beyond the limit should fail")
+	}
+
+	// 既定の上限より大きい値は無視される
+	cm = NewCastManager()
+	cm.SetMaxCasts(4096)
+	if cm.maxID != 1024 {
+		t.Errorf("maxID = %d, want 1024", cm.maxID)
+	}
+}
+
 func TestPutCast(t *testing.T) {
 	cm := NewCastManager()
 
@@ -257,3 +278,37 @@ func TestCastManagerWithoutLayerManager(t *testing.T) {
 		t.Fatalf("DelCast failed: %v", err)
 	}
 }
+
+func TestCastManagerRestack(t *testing.T) {
+	cm := NewCastManager()
+	c0, _ := cm.PutCast(0, 1, 0, 0, 0, 0, 10, 10)
+	other, _ := cm.PutCast(1, 1, 0, 0, 0, 0, 10, 10)
+	c1, _ := cm.PutCast(0, 1, 0, 0, 0, 0, 10, 10)
+	c2, _ := cm.PutCast(0, 1, 0, 0, 0, 0, 10, 10)
+
+	order, err := cm.Restack(c0, true)
+	if err != nil {
+		t.Fatalf("Restack failed: %v", err)
+	}
+	if len(order) != 3 || order[0] != c1 || order[1] != c2 || order[2] != c0 {
+		t.Errorf("order = %v, want [%d %d %d]", order, c1, c2, c0)
+	}
+	casts := cm.GetCastsByWindow(0)
+	if casts[2].ID != c0 {
+		t.Errorf("GetCastsByWindow should reflect the new order, front = %d", casts[2].ID)
+	}
+
+	order, _ = cm.Restack(c2, false)
+	if order[0] != c2 || order[1] != c1 || order[2] != c0 {
+		t.Errorf("order = %v, want [%d %d %d]", order, c2, c1, c0)
+	}
+
+	// 他のウィンドウのキャストのZ順序は変わらない
+	if cast, _ := cm.GetCast(other); cast.ZOrder != 1 {
+		t.Errorf("cast in another window ZOrder = %d, want 1", cast.ZOrder)
+	}
+
+	if _, err := cm.Restack(99, true); err == nil {
+		t.Error("expected error for unknown cast")
+	}
+}
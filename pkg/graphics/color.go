@@ -1,6 +1,7 @@
 package graphics
 
 import (
+	"fmt"
 	"image/color"
 )
 
@@ -26,3 +27,15 @@ func ColorToInt(c color.Color) int {
 	return int(r>>8)<<16 | int(g>>8)<<8 | int(b>>8)
 }
 
+// fadeColorFrom はフェード色の引数を color.RGBA に変換する
+func fadeColorFrom(c any) (color.RGBA, error) {
+	switch v := c.(type) {
+	case int:
+		return ColorFromInt(v).(color.RGBA), nil
+	case color.Color:
+		r, g, b, _ := v.RGBA()
+		return color.RGBA{R: uint8(r >> 8), G: uint8(g >> 8), B: uint8(b >> 8), A: 0xFF}, nil
+	default:
+		return color.RGBA{}, fmt.Errorf("invalid color type: %T", c)
+	}
+}
@@ -0,0 +1,31 @@
+package graphics
+
+import (
+	"fmt"
+	"image/color"
+)
+
+// Cursor はマウスに追従するカスタムカーソル（SetCursor）
+// ピクチャーの内容は設定時にコピーするため、設定後にピクチャーを削除・変更してもカーソルは変わらない。
+type Cursor struct {
+	PicID      int
+	HotX, HotY int         // ホットスポット（マウスの位置に合わせるピクチャー内の点）
+	TransColor color.Color // 透明色（nil の場合は透明色なし）
+}
+
+// CursorClick はカスタムカーソルのクリックアニメーション（SetCursorClick）
+// ピクチャーを横に Frames 等分したコマを、左ボタンを押したときに Ticks フレームずつ1回再生する。
+// ホットスポットと透明色は通常のカーソルと同じものを使う。
+type CursorClick struct {
+	PicID  int
+	Frames int
+	Ticks  int
+}
+
+// checkCursorClick はクリックアニメーションのコマ数とフレーム数を検証する
+func checkCursorClick(click *CursorClick) error {
+	if click.Frames < 1 || click.Ticks < 1 {
+		return fmt.Errorf("invalid cursor click animation: %d frames, %d ticks per frame", click.Frames, click.Ticks)
+	}
+	return nil
+}
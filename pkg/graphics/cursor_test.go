@@ -0,0 +1,105 @@
+//go:build !nogpu
+
+package graphics
+
+import (
+	"errors"
+	"image/color"
+	"testing"
+
+	"github.com/hajimehoshi/ebiten/v2"
+)
+
+func TestCursorStateClickAnimation(t *testing.T) {
+	normal := ebiten.NewImage(4, 4)
+	frames := []*ebiten.Image{ebiten.NewImage(4, 4), ebiten.NewImage(4, 4)}
+	// システムのカーソルは非表示に切り替え済みとして、ゲームループ外で ebiten.SetCursorMode を呼ばない
+	gs := &GraphicsSystem{cursor: &cursorState{image: normal}, sysCursorApplied: true}
+
+	// クリックアニメーションがない場合、押しても通常の画像のまま
+	gs.TrackMouse(10, 20, true)
+	if gs.cursor.playing || gs.cursor.currentImage() != normal {
+		t.Fatal("expected no animation without SetCursorClick")
+	}
+	if !gs.NeedsRedraw() {
+		t.Error("expected moving the mouse to request a redraw")
+	}
+
+	gs.cursor.clickImgs = frames
+	gs.cursor.ticks = 2
+	gs.TrackMouse(10, 20, true)
+	want := []*ebiten.Image{frames[0], frames[0], frames[1], frames[1]}
+	for i, img := range want {
+		if got := gs.cursor.currentImage(); got != img {
+			t.Fatalf("tick %d: unexpected frame", i)
+		}
+		gs.updateCursor()
+	}
+	if gs.cursor.playing || gs.cursor.currentImage() != normal {
+		t.Error("expected the animation to play once and return to the normal image")
+	}
+}
+
+func TestTrackMouseWithoutCursor(t *testing.T) {
+	gs := &GraphicsSystem{}
+	gs.TrackMouse(5, 6, true)
+	if !gs.mouseKnown || gs.mouseX != 5 || gs.mouseY != 6 {
+		t.Errorf("expected the mouse position to be recorded, got (%d, %d)", gs.mouseX, gs.mouseY)
+	}
+	if gs.NeedsRedraw() {
+		t.Error("moving the mouse without a custom cursor should not request a redraw")
+	}
+}
+
+func TestCheckCursorClick(t *testing.T) {
+	if err := checkCursorClick(&CursorClick{Frames: 3, Ticks: 1}); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	for _, click := range []CursorClick{{Frames: 0, Ticks: 1}, {Frames: 2, Ticks: 0}} {
+		if err := checkCursorClick(&click); err == nil {
+			t.Errorf("expected an error for %+v", click)
+		}
+	}
+}
+
+func TestHeadlessGraphicsSystem_Cursor(t *testing.T) {
+	hgs := NewHeadlessGraphicsSystem(WithLogOperations(false))
+	picID, _ := hgs.CreatePic(32, 16)
+
+	if err := hgs.SetCursorClick(&CursorClick{PicID: picID, Frames: 2, Ticks: 1}); err == nil {
+		t.Error("expected an error without a custom cursor")
+	}
+
+	c := &Cursor{PicID: picID, HotX: 3, HotY: 4, TransColor: color.RGBA{0, 0, 0, 0xFF}}
+	if err := hgs.SetCursor(c); err != nil {
+		t.Fatalf("SetCursor failed: %v", err)
+	}
+	c.HotX = 100 // 呼び出し元の変更は反映されない
+	if err := hgs.SetCursorClick(&CursorClick{PicID: picID, Frames: 4, Ticks: 2}); err != nil {
+		t.Fatalf("SetCursorClick failed: %v", err)
+	}
+	cursor, click, sysVisible := hgs.CursorState()
+	if cursor == nil || cursor.HotX != 3 || click == nil || click.Frames != 4 || sysVisible {
+		t.Errorf("unexpected cursor state: %+v %+v sysVisible=%v", cursor, click, sysVisible)
+	}
+
+	if err := hgs.SetCursorClick(&CursorClick{PicID: picID, Frames: 64, Ticks: 1}); err == nil {
+		t.Error("expected an error for more frames than the picture width")
+	}
+	if err := hgs.SetCursor(&Cursor{PicID: 999}); !errors.Is(err, ErrPictureNotFound) {
+		t.Errorf("expected ErrPictureNotFound, got %v", err)
+	}
+
+	// 解除するとクリックアニメーションも解除され、システムのカーソルが表示される
+	if err := hgs.SetCursor(nil); err != nil {
+		t.Fatalf("SetCursor(nil) failed: %v", err)
+	}
+	if cursor, click, sysVisible := hgs.CursorState(); cursor != nil || click != nil || !sysVisible {
+		t.Errorf("unexpected cursor state after removal: %+v %+v sysVisible=%v", cursor, click, sysVisible)
+	}
+
+	hgs.SetSystemCursorVisible(false)
+	if _, _, sysVisible := hgs.CursorState(); sysVisible {
+		t.Error("expected the system cursor to be hidden")
+	}
+}
@@ -0,0 +1,131 @@
+package graphics
+
+import (
+	"fmt"
+	"math"
+	"sync"
+)
+
+// 表示調整（ガンマ・明るさ・コントラスト）の既定値と範囲
+const (
+	DefaultGamma      = 1.0
+	DefaultBrightness = 0.0
+	DefaultContrast   = 1.0
+
+	MinGamma      = 0.1
+	MaxGamma      = 5.0
+	MinBrightness = -1.0
+	MaxBrightness = 1.0
+	MinContrast   = 0.0
+	MaxContrast   = 4.0
+)
+
+// DisplayAdjustment は画面全体に最後に適用する表示調整の設定
+//
+// 各チャンネルの値 v（0.0〜1.0）は次の順に変換される:
+//   - ガンマ補正: v^(1/Gamma)（1より大きいと中間調が明るくなる）
+//   - コントラスト: (v-0.5)*Contrast + 0.5（0で灰色一色、1で変化なし）
+//   - 明るさ: v + Brightness（-1で黒、1で白）
+type DisplayAdjustment struct {
+	Gamma      float64
+	Brightness float64
+	Contrast   float64
+}
+
+// DefaultDisplayAdjustment は画面を変化させない表示調整を返す
+func DefaultDisplayAdjustment() DisplayAdjustment {
+	return DisplayAdjustment{Gamma: DefaultGamma, Brightness: DefaultBrightness, Contrast: DefaultContrast}
+}
+
+// Validate は各値が範囲内かを確認する
+func (a DisplayAdjustment) Validate() error {
+	if err := checkAdjustRange("gamma", a.Gamma, MinGamma, MaxGamma); err != nil {
+		return err
+	}
+	if err := checkAdjustRange("brightness", a.Brightness, MinBrightness, MaxBrightness); err != nil {
+		return err
+	}
+	return checkAdjustRange("contrast", a.Contrast, MinContrast, MaxContrast)
+}
+
+// IsIdentity は表示調整が画面を変化させないかを返す
+func (a DisplayAdjustment) IsIdentity() bool {
+	return a == DefaultDisplayAdjustment()
+}
+
+// Apply はチャンネルの値 v（0.0〜1.0）に表示調整を適用する
+// 描画時はシェーダーで同じ計算を行う（graphics_display_adjust.go の displayAdjustShaderSource）
+func (a DisplayAdjustment) Apply(v float64) float64 {
+	v = math.Pow(clamp01(v), 1/a.Gamma)
+	v = (v-0.5)*a.Contrast + 0.5
+	return clamp01(v + a.Brightness)
+}
+
+// checkAdjustRange は表示調整の値が範囲内かを確認する
+func checkAdjustRange(name string, v, minV, maxV float64) error {
+	if math.IsNaN(v) || v < minV || v > maxV {
+		return fmt.Errorf("%s out of range: %g (%g-%g)", name, v, minV, maxV)
+	}
+	return nil
+}
+
+func clamp01(v float64) float64 {
+	return math.Max(0, math.Min(1, v))
+}
+
+// DisplayAdjuster は表示調整の設定を管理する
+type DisplayAdjuster struct {
+	settings DisplayAdjustment
+	mu       sync.Mutex
+}
+
+// NewDisplayAdjuster は画面を変化させない設定で DisplayAdjuster を作成する
+func NewDisplayAdjuster() *DisplayAdjuster {
+	return &DisplayAdjuster{settings: DefaultDisplayAdjustment()}
+}
+
+// Settings は現在の設定を返す
+func (d *DisplayAdjuster) Settings() DisplayAdjustment {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.settings
+}
+
+// Set は設定を変更する（範囲外の値を含む場合は変更しない）
+func (d *DisplayAdjuster) Set(a DisplayAdjustment) error {
+	if err := a.Validate(); err != nil {
+		return err
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.settings = a
+	return nil
+}
+
+// update は現在の設定を fn で変更する
+func (d *DisplayAdjuster) update(fn func(a *DisplayAdjustment)) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	a := d.settings
+	fn(&a)
+	if err := a.Validate(); err != nil {
+		return err
+	}
+	d.settings = a
+	return nil
+}
+
+// SetGamma はガンマ値を変更する
+func (d *DisplayAdjuster) SetGamma(v float64) error {
+	return d.update(func(a *DisplayAdjustment) { a.Gamma = v })
+}
+
+// SetBrightness は明るさを変更する
+func (d *DisplayAdjuster) SetBrightness(v float64) error {
+	return d.update(func(a *DisplayAdjustment) { a.Brightness = v })
+}
+
+// SetContrast はコントラストを変更する
+func (d *DisplayAdjuster) SetContrast(v float64) error {
+	return d.update(func(a *DisplayAdjustment) { a.Contrast = v })
+}
@@ -0,0 +1,96 @@
+package graphics
+
+import (
+	"math"
+	"testing"
+)
+
+// TestDisplayAdjustmentApply tests the per-channel gamma, contrast and brightness transform.
+func TestDisplayAdjustmentApply(t *testing.T) {
+	tests := []struct {
+		name   string
+		adjust DisplayAdjustment
+		in     float64
+		want   float64
+	}{
+		{"identity", DefaultDisplayAdjustment(), 0.3, 0.3},
+		{"gamma brightens midtones", DisplayAdjustment{Gamma: 2, Contrast: 1}, 0.25, 0.5},
+		{"gamma keeps black", DisplayAdjustment{Gamma: 2, Contrast: 1}, 0, 0},
+		{"gamma keeps white", DisplayAdjustment{Gamma: 2, Contrast: 1}, 1, 1},
+		{"brightness darkens", DisplayAdjustment{Gamma: 1, Brightness: -0.5, Contrast: 1}, 0.8, 0.3},
+		{"brightness clamps", DisplayAdjustment{Gamma: 1, Brightness: -1, Contrast: 1}, 0.8, 0},
+		{"contrast zero is gray", DisplayAdjustment{Gamma: 1, Contrast: 0}, 0.9, 0.5},
+		{"contrast stretches", DisplayAdjustment{Gamma: 1, Contrast: 2}, 0.6, 0.7},
+		{"contrast clamps", DisplayAdjustment{Gamma: 1, Contrast: 2}, 0.9, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.adjust.Apply(tt.in); math.Abs(got-tt.want) > 1e-9 {
+				t.Errorf("Apply(%v) = %v, want %v", tt.in, got, tt.want)
+			}
+		})
+	}
+}
+
+// TestDisplayAdjustmentValidate tests the range checks.
+func TestDisplayAdjustmentValidate(t *testing.T) {
+	if err := DefaultDisplayAdjustment().Validate(); err != nil {
+		t.Errorf("default adjustment should be valid: %v", err)
+	}
+	invalid := []DisplayAdjustment{
+		{Gamma: 0, Contrast: 1},
+		{Gamma: MaxGamma + 1, Contrast: 1},
+		{Gamma: 1, Brightness: -1.5, Contrast: 1},
+		{Gamma: 1, Contrast: -1},
+		{Gamma: math.NaN(), Contrast: 1},
+	}
+	for _, a := range invalid {
+		if err := a.Validate(); err == nil {
+			t.Errorf("expected error for %+v", a)
+		}
+	}
+}
+
+// TestDisplayAdjuster tests updating individual settings.
+func TestDisplayAdjuster(t *testing.T) {
+	d := NewDisplayAdjuster()
+	if !d.Settings().IsIdentity() {
+		t.Fatal("new adjuster should not change the screen")
+	}
+
+	if err := d.SetGamma(2.2); err != nil {
+		t.Fatalf("SetGamma failed: %v", err)
+	}
+	if err := d.SetBrightness(-0.25); err != nil {
+		t.Fatalf("SetBrightness failed: %v", err)
+	}
+	if err := d.SetContrast(1.5); err != nil {
+		t.Fatalf("SetContrast failed: %v", err)
+	}
+	want := DisplayAdjustment{Gamma: 2.2, Brightness: -0.25, Contrast: 1.5}
+	if got := d.Settings(); got != want {
+		t.Errorf("Settings() = %+v, want %+v", got, want)
+	}
+
+	// 範囲外の値は拒否し、設定を変更しない
+	if err := d.SetBrightness(2); err == nil {
+		t.Error("expected error for brightness 2")
+	}
+	if got := d.Settings(); got != want {
+		t.Errorf("invalid value changed settings: %+v", got)
+	}
+}
+
+// TestHeadlessGraphicsSystem_DisplayAdjustment tests that headless mode keeps the adjustment state.
+func TestHeadlessGraphicsSystem_DisplayAdjustment(t *testing.T) {
+	hgs := NewHeadlessGraphicsSystem()
+	if err := hgs.SetBrightness(-0.8); err != nil {
+		t.Fatalf("SetBrightness failed: %v", err)
+	}
+	if got := hgs.GetDisplayAdjustment().Brightness; got != -0.8 {
+		t.Errorf("brightness = %v, want -0.8", got)
+	}
+	if err := hgs.SetGamma(0); err == nil {
+		t.Error("expected error for gamma 0")
+	}
+}
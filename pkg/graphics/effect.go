@@ -0,0 +1,58 @@
+package graphics
+
+import (
+	"fmt"
+	"image/color"
+)
+
+// MaxOutlineWidth は縁取りの太さの上限（ピクセル）
+const MaxOutlineWidth = 8
+
+// Shadow はキャストの影（SetShadow）
+// キャストの不透明な部分の形を (DX, DY) だけずらして Color で塗り、キャストの下に描画する。
+type Shadow struct {
+	DX, DY int
+	Color  color.Color
+	Alpha  float64 // 影の不透明度（0.0〜1.0）
+}
+
+// Outline はキャストの縁取り（SetOutline）
+// キャストの不透明な部分の周囲 Width ピクセルを Color で塗り、キャストの下に描画する。
+type Outline struct {
+	Color color.Color
+	Width int // 縁取りの太さ（1〜MaxOutlineWidth）
+}
+
+// checkShadow は影の設定を検証する（nil は解除として常に有効）
+func checkShadow(shadow *Shadow) error {
+	if shadow != nil && (shadow.Alpha < 0 || shadow.Alpha > 1) {
+		return fmt.Errorf("invalid shadow alpha: %v", shadow.Alpha)
+	}
+	return nil
+}
+
+// checkOutline は縁取りの設定を検証する（nil は解除として常に有効）
+func checkOutline(outline *Outline) error {
+	if outline != nil && (outline.Width < 1 || outline.Width > MaxOutlineWidth) {
+		return fmt.Errorf("invalid outline width: %d (must be 1-%d)", outline.Width, MaxOutlineWidth)
+	}
+	return nil
+}
+
+// copyShadow は呼び出し元の変更が反映されないように影の設定を複製する
+func copyShadow(shadow *Shadow) *Shadow {
+	if shadow == nil {
+		return nil
+	}
+	c := *shadow
+	return &c
+}
+
+// copyOutline は呼び出し元の変更が反映されないように縁取りの設定を複製する
+func copyOutline(outline *Outline) *Outline {
+	if outline == nil {
+		return nil
+	}
+	c := *outline
+	return &c
+}
@@ -12,6 +12,12 @@ var (
 	// ErrCastNotFound はキャストが見つからない場合のエラー
 	ErrCastNotFound = errors.New("cast not found")
 
+	// ErrSpritePoolNotFound はスプライトプールが見つからない場合のエラー
+	ErrSpritePoolNotFound = errors.New("sprite pool not found")
+
 	// ErrResourceLimitExceeded はリソース制限を超えた場合のエラー
 	ErrResourceLimitExceeded = errors.New("resource limit exceeded")
+
+	// ErrKeyframeBusy はデコード中のピクチャーや実行中のシーンチェンジがあり、キーフレームを作成できない場合のエラー
+	ErrKeyframeBusy = errors.New("graphics state is changing, keyframe not taken")
 )
@@ -0,0 +1,40 @@
+// events.go はイベントバス（pkg/eventbus）への描画操作の発行を提供する
+// デバッグオーバーレイやトレース記録などの外部ツールが "sprite.*" を購読して、
+// ウィンドウ・キャストの操作を観察できるようにする
+package graphics
+
+import (
+	"github.com/zurustar/son-et/pkg/eventbus"
+)
+
+// イベントバスに発行するトピック
+const (
+	TopicWindowOpen    = eventbus.CategorySprite + ".window.open"
+	TopicWindowClose   = eventbus.CategorySprite + ".window.close"
+	TopicWindowRestack = eventbus.CategorySprite + ".window.restack"
+	TopicCastPut       = eventbus.CategorySprite + ".cast.put"
+	TopicCastMove      = eventbus.CategorySprite + ".cast.move"
+	TopicCastDelete    = eventbus.CategorySprite + ".cast.delete"
+	TopicCastRestack   = eventbus.CategorySprite + ".cast.restack"
+)
+
+// WithHeadlessEventBus はウィンドウ・キャストの操作を発行するイベントバスを設定する
+func WithHeadlessEventBus(bus *eventbus.Bus) HeadlessOption {
+	return func(hgs *HeadlessGraphicsSystem) {
+		hgs.bus = bus
+	}
+}
+
+// castEventData はキャストの状態をイベントのデータに変換する
+func castEventData(id, winID, picID, x, y int) map[string]any {
+	return map[string]any{"castID": id, "winID": winID, "picID": picID, "x": x, "y": y}
+}
+
+// publishCast はキャストの現在の状態をイベントバスに発行する
+// 呼び出し元は hgs.castMu のロックを保持していること
+func (hgs *HeadlessGraphicsSystem) publishCast(topic string, cast *HeadlessCast) {
+	if !hgs.bus.HasSubscribers() {
+		return
+	}
+	hgs.bus.Publish(topic, castEventData(cast.ID, cast.WinID, cast.PicID, cast.X, cast.Y))
+}
@@ -0,0 +1,161 @@
+package graphics
+
+import (
+	"fmt"
+	"image"
+	"strings"
+	"sync"
+	"unicode/utf8"
+
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/gofont/gomono"
+	"golang.org/x/image/font/opentype"
+	"golang.org/x/image/math/fixed"
+)
+
+// FontPitch は文字の幅の扱い（固定ピッチ・プロポーショナル）
+// 値はWindowsのLOGFONTのピッチ（DEFAULT_PITCH, FIXED_PITCH, VARIABLE_PITCH）と同じ
+type FontPitch int
+
+const (
+	// PitchDefault はフォント名からピッチを決める（ＭＳ ゴシックなどの等幅フォントは固定ピッチ）
+	PitchDefault FontPitch = 0
+	// PitchFixed は半角文字をフォントサイズの半分、全角文字をフォントサイズの幅で描画する
+	PitchFixed FontPitch = 1
+	// PitchProportional は読み込んだフォントの文字幅で描画する
+	PitchProportional FontPitch = 2
+)
+
+// String はピッチの名前を返す
+func (p FontPitch) String() string {
+	switch p {
+	case PitchFixed:
+		return "fixed"
+	case PitchProportional:
+		return "proportional"
+	}
+	return "default"
+}
+
+// ParseFontPitch はスクリプトで指定したピッチ（0〜2 または "default", "fixed", "proportional"）を解析する
+func ParseFontPitch(v any) (FontPitch, error) {
+	if s, ok := v.(string); ok {
+		switch strings.ToLower(strings.TrimSpace(s)) {
+		case "default", "":
+			return PitchDefault, nil
+		case "fixed":
+			return PitchFixed, nil
+		case "proportional", "variable":
+			return PitchProportional, nil
+		}
+		return PitchDefault, fmt.Errorf("unknown font pitch %q (want fixed or proportional)", s)
+	}
+	n, ok := toIntFromAny(v)
+	if !ok || n < int(PitchDefault) || n > int(PitchProportional) {
+		return PitchDefault, fmt.Errorf("font pitch must be 0 (default), 1 (fixed) or 2 (proportional), got %v", v)
+	}
+	return FontPitch(n), nil
+}
+
+// fixedPitchFonts は既定で固定ピッチとして描画するフォント（小文字で比較する）
+// FILLYのタイトルは ＭＳ ゴシックなどの等幅フォントで桁をそろえていることが多いが、
+// 代わりに使うヒラギノやNoto Sans JPは英数字がプロポーショナルのため、固定ピッチで描画して桁をそろえる
+var fixedPitchFonts = map[string]bool{
+	"ｍｓ ゴシック":     true,
+	"ｍｓ 明朝":       true,
+	"ms gothic":   true,
+	"ms mincho":   true,
+	"courier":     true,
+	"courier new": true,
+	"fixedsys":    true,
+	"terminal":    true,
+}
+
+// resolvePitch は PitchDefault をフォント名から固定ピッチかプロポーショナルに決める
+func resolvePitch(name string, pitch FontPitch) FontPitch {
+	if pitch != PitchDefault {
+		return pitch
+	}
+	if fixedPitchFonts[strings.ToLower(name)] {
+		return PitchFixed
+	}
+	return PitchProportional
+}
+
+// isHalfWidthRune は固定ピッチで半角（フォントサイズの半分の幅）として扱う文字かどうかを返す
+func isHalfWidthRune(r rune) bool {
+	return r < utf8.RuneSelf || (r >= halfwidthKanaFirst && r <= halfwidthKanaLast)
+}
+
+// fixedPitchFace は読み込んだフォントの各文字を固定幅のセルの中央に配置するフォントフェイス
+// 半角文字は half、全角文字は half の2倍の幅で進む
+type fixedPitchFace struct {
+	font.Face
+	half fixed.Int26_6
+}
+
+// newFixedPitchFace は size のフォントを固定ピッチで描画するフォントフェイスを作成する
+func newFixedPitchFace(face font.Face, size int) *fixedPitchFace {
+	return &fixedPitchFace{Face: face, half: fixed.I(max(size/2, 1))}
+}
+
+func (f *fixedPitchFace) cellWidth(r rune) fixed.Int26_6 {
+	if isHalfWidthRune(r) {
+		return f.half
+	}
+	return f.half * 2
+}
+
+// Glyph は文字をセルの中央に描画する
+func (f *fixedPitchFace) Glyph(dot fixed.Point26_6, r rune) (image.Rectangle, image.Image, image.Point, fixed.Int26_6, bool) {
+	cell := f.cellWidth(r)
+	if advance, ok := f.Face.GlyphAdvance(r); ok {
+		dot.X += (cell - advance) / 2
+	}
+	dr, mask, maskp, _, ok := f.Face.Glyph(dot, r)
+	return dr, mask, maskp, cell, ok
+}
+
+// GlyphBounds は Glyph と同じ位置の文字の境界を返す
+func (f *fixedPitchFace) GlyphBounds(r rune) (fixed.Rectangle26_6, fixed.Int26_6, bool) {
+	cell := f.cellWidth(r)
+	bounds, advance, ok := f.Face.GlyphBounds(r)
+	offset := (cell - advance) / 2
+	bounds.Min.X += offset
+	bounds.Max.X += offset
+	return bounds, cell, ok
+}
+
+// GlyphAdvance はセルの幅を返す
+func (f *fixedPitchFace) GlyphAdvance(r rune) (fixed.Int26_6, bool) {
+	_, ok := f.Face.GlyphAdvance(r)
+	return f.cellWidth(r), ok
+}
+
+// Kern は固定ピッチでは常に0
+func (f *fixedPitchFace) Kern(r0, r1 rune) fixed.Int26_6 {
+	return 0
+}
+
+// 固定ピッチのフォールバックフォント（Go Mono）
+// 指定したフォントが見つからない環境でも、固定ピッチの英数字の桁がそろうようにする
+var (
+	fixedFallbackOnce sync.Once
+	fixedFallbackFont *opentype.Font
+	fixedFallbackErr  error
+)
+
+// fixedPitchFallbackFace は size の固定ピッチのフォールバックフォントを作成する
+func fixedPitchFallbackFace(size int) (font.Face, error) {
+	fixedFallbackOnce.Do(func() {
+		fixedFallbackFont, fixedFallbackErr = opentype.Parse(gomono.TTF)
+	})
+	if fixedFallbackErr != nil {
+		return nil, fixedFallbackErr
+	}
+	return opentype.NewFace(fixedFallbackFont, &opentype.FaceOptions{
+		Size:    float64(size),
+		DPI:     72,
+		Hinting: font.HintingFull,
+	})
+}
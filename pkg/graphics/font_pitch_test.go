@@ -0,0 +1,142 @@
+//go:build !nogpu
+
+package graphics
+
+import (
+	"testing"
+
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/basicfont"
+	"golang.org/x/image/math/fixed"
+)
+
+func TestParseFontPitch(t *testing.T) {
+	tests := []struct {
+		in      any
+		want    FontPitch
+		wantErr bool
+	}{
+		{int64(0), PitchDefault, false},
+		{int64(1), PitchFixed, false},
+		{2, PitchProportional, false},
+		{"Fixed", PitchFixed, false},
+		{" proportional ", PitchProportional, false},
+		{"variable", PitchProportional, false},
+		{"default", PitchDefault, false},
+		{int64(3), PitchDefault, true},
+		{int64(-1), PitchDefault, true},
+		{"wide", PitchDefault, true},
+	}
+	for _, tt := range tests {
+		got, err := ParseFontPitch(tt.in)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ParseFontPitch(%v) error = %v, wantErr %v", tt.in, err, tt.wantErr)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("ParseFontPitch(%v) = %v, want %v", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestResolvePitch(t *testing.T) {
+	tests := []struct {
+		name  string
+		pitch FontPitch
+		want  FontPitch
+	}{
+		{"ＭＳ ゴシック", PitchDefault, PitchFixed},
+		{"MS Gothic", PitchDefault, PitchFixed},
+		{"ＭＳ Ｐゴシック", PitchDefault, PitchProportional},
+		{"default", PitchDefault, PitchProportional},
+		{"ＭＳ ゴシック", PitchProportional, PitchProportional},
+		{"ＭＳ Ｐゴシック", PitchFixed, PitchFixed},
+	}
+	for _, tt := range tests {
+		if got := resolvePitch(tt.name, tt.pitch); got != tt.want {
+			t.Errorf("resolvePitch(%q, %v) = %v, want %v", tt.name, tt.pitch, got, tt.want)
+		}
+	}
+}
+
+func TestFixedPitchFace_Advance(t *testing.T) {
+	fallback, err := fixedPitchFallbackFace(16)
+	if err != nil {
+		t.Fatalf("fixedPitchFallbackFace failed: %v", err)
+	}
+	for name, base := range map[string]font.Face{"fallback": fallback, "basicfont": basicfont.Face7x13} {
+		t.Run(name, func(t *testing.T) {
+			face := newFixedPitchFace(base, 16)
+
+			// 半角文字は8ピクセル、全角文字・記号は16ピクセルで進む
+			if w := font.MeasureString(face, "iW|.").Ceil(); w != 32 {
+				t.Errorf("width of 4 half-width runes = %d, want 32", w)
+			}
+			if w := font.MeasureString(face, "あ─ｱ").Ceil(); w != 40 {
+				t.Errorf("width of 2 full-width runes and 1 half-width kana = %d, want 40", w)
+			}
+			if k := face.Kern('A', 'V'); k != 0 {
+				t.Errorf("Kern = %v, want 0", k)
+			}
+		})
+	}
+}
+
+func TestFixedPitchFace_GlyphCentered(t *testing.T) {
+	face := newFixedPitchFace(basicfont.Face7x13, 20)
+	bounds, advance, _ := face.GlyphBounds('A')
+	if advance != fixed.I(10) {
+		t.Errorf("advance = %v, want 10", advance)
+	}
+	// basicfont の幅7の文字を幅10のセルの中央に置く
+	_, baseAdvance, _ := basicfont.Face7x13.GlyphBounds('A')
+	offset := (fixed.I(10) - baseAdvance) / 2
+	baseBounds, _, _ := basicfont.Face7x13.GlyphBounds('A')
+	if bounds.Min.X != baseBounds.Min.X+offset {
+		t.Errorf("bounds.Min.X = %v, want %v", bounds.Min.X, baseBounds.Min.X+offset)
+	}
+
+	dr, _, _, glyphAdvance, ok := face.Glyph(fixed.P(0, 13), 'A')
+	if !ok {
+		t.Fatal("Glyph returned !ok")
+	}
+	if glyphAdvance != fixed.I(10) {
+		t.Errorf("Glyph advance = %v, want 10", glyphAdvance)
+	}
+	if dr.Min.X != offset.Round() {
+		t.Errorf("glyph drawn at x=%d, want %d", dr.Min.X, offset.Round())
+	}
+}
+
+func TestTextRenderer_FaceForPitch(t *testing.T) {
+	tr := NewTextRenderer()
+	if face := tr.FaceForPitch(PitchDefault); face != basicfont.Face7x13 {
+		t.Errorf("default font with PitchDefault should be proportional, got %T", face)
+	}
+
+	tr.SetFont("NonExistentFont", 16)
+	face := tr.FaceForPitch(PitchFixed)
+	if _, ok := face.(*fixedPitchFace); !ok {
+		t.Fatalf("PitchFixed face = %T, want *fixedPitchFace", face)
+	}
+	if tr.FaceForPitch(PitchFixed) != face {
+		t.Error("fixed-pitch face should be cached until SetFont")
+	}
+	if w := font.MeasureString(face, "+--+").Ceil(); w != 32 {
+		t.Errorf("width = %d, want 32", w)
+	}
+
+	width, _ := tr.TextExtentWithPitch("+--+", "", 0, PitchFixed)
+	if width != 32 {
+		t.Errorf("TextExtentWithPitch fixed = %d, want 32", width)
+	}
+	width, _ = tr.TextExtentWithPitch("+--+", "NonExistentFont", 20, PitchFixed)
+	if width != 40 {
+		t.Errorf("TextExtentWithPitch fixed with font = %d, want 40", width)
+	}
+
+	tr.SetFont("NonExistentFont", 24)
+	if tr.FaceForPitch(PitchFixed) == face {
+		t.Error("SetFont should reset the fixed-pitch face")
+	}
+}
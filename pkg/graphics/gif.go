@@ -0,0 +1,64 @@
+package graphics
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	"image/draw"
+	"image/gif"
+)
+
+// decodeGIFFrames はアニメーションGIFのすべてのフレームを、論理画面の大きさの画像にデコードする
+// 各フレームは前のフレームの上に合成し、フレームの処分方法（disposal）を適用する。
+// delays は各フレームの表示時間（1/100秒単位、GIFの値のまま）
+func decodeGIFFrames(data []byte) (frames []*image.RGBA, delays []int, err error) {
+	g, err := gif.DecodeAll(bytes.NewReader(data))
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(g.Image) == 0 {
+		return nil, nil, errors.New("GIF has no frames")
+	}
+
+	screen := image.Rect(0, 0, g.Config.Width, g.Config.Height)
+	if screen.Empty() {
+		// 論理画面の大きさがない場合はすべてのフレームを含む大きさにする
+		for _, frame := range g.Image {
+			screen = screen.Union(frame.Bounds())
+		}
+		screen = image.Rectangle{Max: screen.Max}
+	}
+	canvas := image.NewRGBA(screen)
+	for i, frame := range g.Image {
+		var disposal byte
+		if i < len(g.Disposal) {
+			disposal = g.Disposal[i]
+		}
+		var previous *image.RGBA
+		if disposal == gif.DisposalPrevious {
+			previous = cloneRGBA(canvas)
+		}
+
+		draw.Draw(canvas, frame.Bounds(), frame, frame.Bounds().Min, draw.Over)
+		frames = append(frames, cloneRGBA(canvas))
+
+		switch disposal {
+		case gif.DisposalBackground:
+			// 背景は透明として扱う（ブラウザと同じ）
+			draw.Draw(canvas, frame.Bounds(), image.Transparent, image.Point{}, draw.Src)
+		case gif.DisposalPrevious:
+			canvas = previous
+		}
+	}
+
+	delays = make([]int, len(frames))
+	copy(delays, g.Delay)
+	return frames, delays, nil
+}
+
+// cloneRGBA は img の複製を返す
+func cloneRGBA(img *image.RGBA) *image.RGBA {
+	clone := image.NewRGBA(img.Rect)
+	copy(clone.Pix, img.Pix)
+	return clone
+}
@@ -0,0 +1,80 @@
+package graphics
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/gif"
+	"slices"
+	"testing"
+)
+
+// encodeTestGIF encodes a 4x2 GIF whose frames fill the given rectangles with
+// red and blue in turn, with the given disposal methods.
+func encodeTestGIF(t *testing.T, rects []image.Rectangle, disposal []byte) []byte {
+	t.Helper()
+	palette := color.Palette{color.Transparent, color.RGBA{255, 0, 0, 255}, color.RGBA{0, 0, 255, 255}}
+	g := &gif.GIF{Config: image.Config{Width: 4, Height: 2, ColorModel: palette}, Disposal: disposal}
+	for i, r := range rects {
+		frame := image.NewPaletted(r, palette)
+		for j := range frame.Pix {
+			frame.Pix[j] = uint8(i%2 + 1)
+		}
+		g.Image = append(g.Image, frame)
+		g.Delay = append(g.Delay, (i+1)*10)
+	}
+	var buf bytes.Buffer
+	if err := gif.EncodeAll(&buf, g); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+// TestDecodeGIFFrames tests that frames are composed over the previous ones.
+func TestDecodeGIFFrames(t *testing.T) {
+	data := encodeTestGIF(t, []image.Rectangle{image.Rect(0, 0, 4, 2), image.Rect(2, 0, 4, 2)}, []byte{gif.DisposalNone, gif.DisposalNone})
+	frames, delays, err := decodeGIFFrames(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(frames) != 2 || !slices.Equal(delays, []int{10, 20}) {
+		t.Fatalf("got %d frames with delays %v, want 2 frames with delays [10 20]", len(frames), delays)
+	}
+	if frames[1].Bounds() != image.Rect(0, 0, 4, 2) {
+		t.Errorf("frame bounds = %v, want the logical screen", frames[1].Bounds())
+	}
+	// 2番目のフレームは右半分だけを描き、左半分は前のフレームのまま
+	if got := frames[1].RGBAAt(0, 0); got != (color.RGBA{255, 0, 0, 255}) {
+		t.Errorf("left pixel = %v, want red from the first frame", got)
+	}
+	if got := frames[1].RGBAAt(3, 0); got != (color.RGBA{0, 0, 255, 255}) {
+		t.Errorf("right pixel = %v, want blue", got)
+	}
+	if got := frames[0].RGBAAt(3, 0); got != (color.RGBA{255, 0, 0, 255}) {
+		t.Errorf("the first frame was changed by the second: %v", got)
+	}
+}
+
+// TestDecodeGIFFramesDisposal tests the background and previous disposal methods.
+func TestDecodeGIFFramesDisposal(t *testing.T) {
+	rects := []image.Rectangle{image.Rect(0, 0, 2, 2), image.Rect(2, 0, 4, 2), image.Rect(0, 0, 1, 1)}
+	data := encodeTestGIF(t, rects, []byte{gif.DisposalBackground, gif.DisposalPrevious, gif.DisposalNone})
+	frames, _, err := decodeGIFFrames(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 1番目のフレームは背景に戻すため、2番目のフレームの左半分は透明
+	if got := frames[1].RGBAAt(0, 1); got.A != 0 {
+		t.Errorf("frame 2 left pixel = %v, want transparent", got)
+	}
+	// 2番目のフレームは前の状態に戻すため、3番目のフレームの右半分は透明
+	if got := frames[2].RGBAAt(3, 0); got.A != 0 {
+		t.Errorf("frame 3 right pixel = %v, want transparent", got)
+	}
+}
+
+func TestDecodeGIFFramesInvalid(t *testing.T) {
+	if _, _, err := decodeGIFFrames([]byte("not a gif")); err == nil {
+		t.Error("expected an error")
+	}
+}
@@ -0,0 +1,189 @@
+//go:build !nogpu
+
+package graphics
+
+import (
+	"sync"
+	"time"
+
+	"github.com/zurustar/son-et/pkg/eventbus"
+)
+
+// フレーム時間に応じた描画品質の自動調整（--adaptive-quality）
+//
+// Update と Draw にかかった時間がフレームの予算を何フレームも続けて超えた場合、
+// 負荷の大きい描画を段階的に省き、余裕が戻ったら元に戻す。
+// テキストは TextWrite の時点で一度だけピクチャーに描画するため（毎フレームの負荷ではない）、
+// アンチエイリアスは調整の対象にしない。
+
+// QualityLevel は描画品質の段階（大きいほど省く描画が多い）
+type QualityLevel int
+
+const (
+	QualityFull             QualityLevel = iota // すべて描画する
+	QualityNoEffects                            // 影と縁取りを描画しない（フラッシュは描画する）
+	QualityHalfParticles                        // さらにスプライトプールの粒子を半分だけ描画する
+	QualityQuarterParticles                     // さらにスプライトプールの粒子を1/4だけ描画する
+)
+
+// 品質を変えるまでのフレーム数
+const (
+	governorDegradeFrames = 10  // 予算を超えたフレームがこれだけ続いたら品質を下げる
+	governorRestoreFrames = 180 // 余裕のあるフレームがこれだけ続いたら品質を上げる（60TPSで3秒）
+)
+
+// governorHeadroom は品質を上げるために必要なフレーム時間の予算に対する割合
+// 品質を上げた直後にまた予算を超えて、上げ下げを繰り返さないようにする
+const governorHeadroom = 0.6
+
+// TopicQualityChange は描画品質が変わったときに発行するトピック
+const TopicQualityChange = eventbus.CategorySprite + ".quality"
+
+// String は品質の段階の名前を返す
+func (l QualityLevel) String() string {
+	switch l {
+	case QualityFull:
+		return "full"
+	case QualityNoEffects:
+		return "no-effects"
+	case QualityHalfParticles:
+		return "half-particles"
+	case QualityQuarterParticles:
+		return "quarter-particles"
+	}
+	return "unknown"
+}
+
+// drawsEffects は影と縁取りを描画するかを返す
+func (l QualityLevel) drawsEffects() bool {
+	return l < QualityNoEffects
+}
+
+// particleStride はスプライトプールの粒子を何個に1個描画するかを返す
+func (l QualityLevel) particleStride() int {
+	switch l {
+	case QualityHalfParticles:
+		return 2
+	case QualityQuarterParticles:
+		return 4
+	}
+	return 1
+}
+
+// qualityGovernor はフレーム時間を測り、描画品質の段階を決める
+type qualityGovernor struct {
+	mu     sync.Mutex
+	budget time.Duration // 1フレームに使える時間
+	level  QualityLevel
+	spent  time.Duration // 現在のフレームで Update と Draw にかかった時間
+	over   int           // 予算を超えたフレームが続いた数
+	under  int           // 余裕のあるフレームが続いた数
+}
+
+// newQualityGovernor は予算 budget の qualityGovernor を作成する
+func newQualityGovernor(budget time.Duration) *qualityGovernor {
+	return &qualityGovernor{budget: budget}
+}
+
+// add は現在のフレームにかかった時間を加える
+func (g *qualityGovernor) add(d time.Duration) {
+	g.mu.Lock()
+	g.spent += d
+	g.mu.Unlock()
+}
+
+// endFrame は現在のフレームの時間を記録して次のフレームの測定を始める
+// 品質の段階が変わった場合は changed が true になる
+func (g *qualityGovernor) endFrame() (frame time.Duration, level QualityLevel, changed bool) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	frame = g.spent
+	g.spent = 0
+	switch {
+	case frame > g.budget:
+		g.over++
+		g.under = 0
+		if g.over >= governorDegradeFrames && g.level < QualityQuarterParticles {
+			g.level++
+			g.over = 0
+			changed = true
+		}
+	case float64(frame) < float64(g.budget)*governorHeadroom:
+		g.under++
+		g.over = 0
+		if g.under >= governorRestoreFrames && g.level > QualityFull {
+			g.level--
+			g.under = 0
+			changed = true
+		}
+	default:
+		g.over = 0
+		g.under = 0
+	}
+	return frame, g.level, changed
+}
+
+// WithQualityGovernor はフレーム時間に応じた描画品質の自動調整を設定する
+// budget は1フレームに使える時間（1秒 / フレームレート）で、0 の場合は調整しない。
+func WithQualityGovernor(budget time.Duration) Option {
+	return func(gs *GraphicsSystem) {
+		if budget > 0 {
+			gs.governor = newQualityGovernor(budget)
+		} else {
+			gs.governor = nil
+		}
+	}
+}
+
+// QualityLevel は現在の描画品質の段階を返す（調整しない場合は QualityFull）
+func (gs *GraphicsSystem) QualityLevel() QualityLevel {
+	if gs.governor == nil {
+		return QualityFull
+	}
+	gs.governor.mu.Lock()
+	defer gs.governor.mu.Unlock()
+	return gs.governor.level
+}
+
+// measureFrame は start から現在までの時間を現在のフレームの時間に加える
+func (gs *GraphicsSystem) measureFrame(start time.Time) {
+	if gs.governor != nil {
+		gs.governor.add(time.Since(start))
+	}
+}
+
+// endGovernorFrame はフレームの時間を記録し、品質の段階が変わった場合は描画に反映する
+// Draw の最後に呼び出す
+func (gs *GraphicsSystem) endGovernorFrame(start time.Time) {
+	if gs.governor == nil {
+		return
+	}
+	gs.measureFrame(start)
+	frame, level, changed := gs.governor.endFrame()
+	if !changed {
+		return
+	}
+	gs.applyQualityLevel(level)
+	gs.log.Info("Quality governor changed the rendering quality",
+		"level", level.String(), "frameTime", frame, "budget", gs.governor.budget)
+	if gs.bus.HasSubscribers() {
+		gs.bus.Publish(TopicQualityChange, map[string]any{
+			"level":     int(level),
+			"name":      level.String(),
+			"frameTime": frame.Seconds(),
+			"budget":    gs.governor.budget.Seconds(),
+		})
+	}
+}
+
+// applyQualityLevel は品質の段階をスプライトの描画に反映する
+func (gs *GraphicsSystem) applyQualityLevel(level QualityLevel) {
+	if gs.spriteManager != nil {
+		gs.spriteManager.SetQualityLevel(level)
+	}
+	if gs.spritePools != nil {
+		gs.spritePools.setParticleStride(level.particleStride())
+	}
+	gs.invalidate()
+}
@@ -0,0 +1,132 @@
+//go:build !nogpu
+
+package graphics
+
+import (
+	"testing"
+	"time"
+)
+
+// runFrames は governor に frame の時間のフレームを n 回記録し、最後の段階と変わった回数を返す
+func runFrames(g *qualityGovernor, frame time.Duration, n int) (QualityLevel, int) {
+	changes := 0
+	var level QualityLevel
+	for range n {
+		g.add(frame)
+		var changed bool
+		_, level, changed = g.endFrame()
+		if changed {
+			changes++
+		}
+	}
+	return level, changes
+}
+
+// TestQualityGovernorDegrades tests that the quality drops one level per run of
+// over-budget frames and stops at the lowest level.
+func TestQualityGovernorDegrades(t *testing.T) {
+	g := newQualityGovernor(16 * time.Millisecond)
+
+	if level, changes := runFrames(g, 20*time.Millisecond, governorDegradeFrames-1); level != QualityFull || changes != 0 {
+		t.Fatalf("level = %v after %d slow frames, want full", level, governorDegradeFrames-1)
+	}
+	if level, changes := runFrames(g, 20*time.Millisecond, 1); level != QualityNoEffects || changes != 1 {
+		t.Fatalf("level = %v, want no-effects", level)
+	}
+	if level, _ := runFrames(g, 20*time.Millisecond, governorDegradeFrames*10); level != QualityQuarterParticles {
+		t.Errorf("level = %v, want quarter-particles after many slow frames", level)
+	}
+}
+
+// TestQualityGovernorSpikes tests that isolated slow frames do not lower the quality.
+func TestQualityGovernorSpikes(t *testing.T) {
+	g := newQualityGovernor(16 * time.Millisecond)
+	for range 20 {
+		runFrames(g, 30*time.Millisecond, governorDegradeFrames-1)
+		runFrames(g, 12*time.Millisecond, 1)
+	}
+	if level := g.level; level != QualityFull {
+		t.Errorf("level = %v, want full", level)
+	}
+}
+
+// TestQualityGovernorRestores tests that the quality returns one level at a time only
+// after a long run of frames with headroom.
+func TestQualityGovernorRestores(t *testing.T) {
+	g := newQualityGovernor(16 * time.Millisecond)
+	runFrames(g, 20*time.Millisecond, governorDegradeFrames*2)
+	if g.level != QualityHalfParticles {
+		t.Fatalf("level = %v, want half-particles", g.level)
+	}
+
+	// 予算に近いフレームでは戻さない
+	if level, _ := runFrames(g, 14*time.Millisecond, governorRestoreFrames*2); level != QualityHalfParticles {
+		t.Errorf("level = %v after frames near the budget, want half-particles", level)
+	}
+	if level, _ := runFrames(g, 5*time.Millisecond, governorRestoreFrames); level != QualityNoEffects {
+		t.Errorf("level = %v, want no-effects", level)
+	}
+	if level, _ := runFrames(g, 5*time.Millisecond, governorRestoreFrames*5); level != QualityFull {
+		t.Errorf("level = %v, want full", level)
+	}
+}
+
+// TestQualityGovernorAccumulates tests that the Update and Draw times of a frame are added up.
+func TestQualityGovernorAccumulates(t *testing.T) {
+	g := newQualityGovernor(16 * time.Millisecond)
+	g.add(10 * time.Millisecond)
+	g.add(8 * time.Millisecond)
+	if frame, _, _ := g.endFrame(); frame != 18*time.Millisecond {
+		t.Errorf("frame = %v, want 18ms", frame)
+	}
+	if frame, _, _ := g.endFrame(); frame != 0 {
+		t.Errorf("frame = %v after endFrame, want 0", frame)
+	}
+}
+
+func TestQualityLevelSettings(t *testing.T) {
+	tests := []struct {
+		level   QualityLevel
+		effects bool
+		stride  int
+	}{
+		{QualityFull, true, 1},
+		{QualityNoEffects, false, 1},
+		{QualityHalfParticles, false, 2},
+		{QualityQuarterParticles, false, 4},
+	}
+	for _, tt := range tests {
+		if got := tt.level.drawsEffects(); got != tt.effects {
+			t.Errorf("%v.drawsEffects() = %v, want %v", tt.level, got, tt.effects)
+		}
+		if got := tt.level.particleStride(); got != tt.stride {
+			t.Errorf("%v.particleStride() = %d, want %d", tt.level, got, tt.stride)
+		}
+	}
+}
+
+// TestGraphicsSystemQualityLevel tests that a level change reaches the sprite manager
+// and the sprite pools.
+func TestGraphicsSystemQualityLevel(t *testing.T) {
+	gs := NewGraphicsSystem("", WithQualityGovernor(16*time.Millisecond))
+	if gs.QualityLevel() != QualityFull {
+		t.Fatalf("QualityLevel() = %v, want full", gs.QualityLevel())
+	}
+	for range governorDegradeFrames * 2 {
+		gs.governor.add(20 * time.Millisecond)
+		gs.endGovernorFrame(time.Now())
+	}
+	if gs.QualityLevel() != QualityHalfParticles {
+		t.Fatalf("QualityLevel() = %v, want half-particles", gs.QualityLevel())
+	}
+	if gs.spriteManager.quality != QualityHalfParticles {
+		t.Errorf("sprite manager quality = %v, want half-particles", gs.spriteManager.quality)
+	}
+	if gs.spritePools.stride != 2 {
+		t.Errorf("sprite pool stride = %d, want 2", gs.spritePools.stride)
+	}
+
+	if NewGraphicsSystem("").QualityLevel() != QualityFull {
+		t.Error("QualityLevel() without a governor should be full")
+	}
+}
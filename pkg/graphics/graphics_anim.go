@@ -0,0 +1,86 @@
+//go:build !nogpu
+
+// graphics_anim.go はアニメーションGIFのフレームの読み込み（LoadPicFrames）を提供する
+package graphics
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/hajimehoshi/ebiten/v2"
+)
+
+// LoadPicFrames loads every frame of an animated GIF as a picture of the size of
+// the GIF, and returns the picture IDs with the display time of each frame in
+// 1/100 s. Each frame is composed over the previous ones, so any frame can be shown alone.
+func (gs *GraphicsSystem) LoadPicFrames(filename string) ([]int, []int, error) {
+	gs.mu.Lock()
+	defer gs.mu.Unlock()
+
+	picIDs, delays, err := gs.pictures.loadFrames(filename)
+	if err != nil {
+		return nil, nil, err
+	}
+	for _, picID := range picIDs {
+		gs.createPictureSpriteOnLoad(picID, filename)
+	}
+	return picIDs, delays, nil
+}
+
+// loadFrames はアニメーションGIFを読み込み、フレームごとにピクチャーを作成する
+// すべてのフレームを作成できない場合（ピクチャー数・メモリの上限）は、ピクチャーを作成せずにエラーを返す
+func (pm *PictureManager) loadFrames(filename string) ([]int, []int, error) {
+	pm.mu.Lock()
+	defer pm.mu.Unlock()
+
+	// LoadPic と同じく、"/" で始まるパスはタイトルディレクトリからの相対パスとして扱う
+	searchFilename := strings.TrimLeft(filename, "/\\")
+	file, err := pm.files().Open(searchFilename)
+	if err != nil {
+		return nil, nil, fmt.Errorf("file not found: %s", searchFilename)
+	}
+	data, err := io.ReadAll(file)
+	file.Close()
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read file: %w", err)
+	}
+	frames, delays, err := decodeGIFFrames(data)
+	if err != nil {
+		pm.log.Error("LoadPicFrames: failed to decode GIF", "filename", filename, "error", err)
+		return nil, nil, fmt.Errorf("failed to decode GIF: %w", err)
+	}
+
+	width, height := frames[0].Rect.Dx(), frames[0].Rect.Dy()
+	if len(pm.pictures)+len(frames) > pm.maxID {
+		pm.log.Error("LoadPicFrames: resource limit exceeded", "filename", filename, "frames", len(frames), "limit", pm.maxID)
+		return nil, nil, fmt.Errorf("picture limit reached: %d (%d frames)", pm.maxID, len(frames))
+	}
+	if err := pm.checkPixelBudget(width, height*len(frames)); err != nil {
+		pm.log.Error("LoadPicFrames: resource limit exceeded", "filename", filename, "error", err)
+		return nil, nil, err
+	}
+
+	picIDs := make([]int, len(frames))
+	for i, frame := range frames {
+		picID := pm.nextID
+		pm.nextID++
+		pm.pictures[picID] = &Picture{
+			ID:            picID,
+			Image:         ebiten.NewImageFromImage(frame),
+			OriginalImage: frame,
+			Width:         width,
+			Height:        height,
+		}
+		pm.tracker.add(picID, width, height, time.Now())
+		picIDs[i] = picID
+	}
+
+	pm.log.Info("LoadPicFrames: loaded frames",
+		"filename", filename,
+		"pictureIDs", picIDs,
+		"width", width,
+		"height", height)
+	return picIDs, delays, nil
+}
@@ -0,0 +1,98 @@
+//go:build !nogpu
+
+// graphics_app_window.go はOSのウィンドウの設定（SetWindowTitle/SetWindowIcon/SetWindowSize）の GraphicsSystem のメソッドを提供する
+package graphics
+
+import (
+	"image"
+	"strings"
+
+	"github.com/hajimehoshi/ebiten/v2"
+)
+
+// SetWindowTitle はOSのウィンドウのタイトルを設定する
+func (gs *GraphicsSystem) SetWindowTitle(title string) {
+	gs.mu.Lock()
+	defer gs.mu.Unlock()
+	gs.appWindow.settings.Title = title
+	gs.appWindow.dirty = true
+	gs.log.Debug("SetWindowTitle", "title", title)
+}
+
+// SetWindowIcon は画像ファイル（BMP/PNG）をOSのウィンドウのアイコンに設定する
+// ファイル名は LoadPic と同じくタイトルディレクトリからの相対パスで指定する
+func (gs *GraphicsSystem) SetWindowIcon(filename string) error {
+	gs.mu.Lock()
+	defer gs.mu.Unlock()
+
+	searchFilename := strings.TrimLeft(filename, "/\\")
+	icon, err := decodePictureFile(gs.pictures.fs, searchFilename, gs.log)
+	if err != nil {
+		return err
+	}
+	gs.appWindow.settings.IconFile = filename
+	gs.appWindow.icon = icon
+	gs.appWindow.dirty = true
+	gs.log.Debug("SetWindowIcon", "filename", filename, "width", icon.Bounds().Dx(), "height", icon.Bounds().Dy())
+	return nil
+}
+
+// SetWindowSize はOSのウィンドウの大きさを設定する
+// 仮想デスクトップ（1024x768）は変わらず、ウィンドウに合わせて拡大・縮小して表示する
+func (gs *GraphicsSystem) SetWindowSize(width, height int) error {
+	if err := checkAppWindowSize(width, height); err != nil {
+		return err
+	}
+	gs.mu.Lock()
+	defer gs.mu.Unlock()
+	gs.appWindow.settings.Width, gs.appWindow.settings.Height = width, height
+	gs.appWindow.dirty = true
+	gs.log.Debug("SetWindowSize", "width", width, "height", height)
+	return nil
+}
+
+// AppWindowSettings はスクリプトが変更したOSのウィンドウの設定を返す
+func (gs *GraphicsSystem) AppWindowSettings() AppWindowSettings {
+	gs.mu.RLock()
+	defer gs.mu.RUnlock()
+	return gs.appWindow.settings
+}
+
+// SetDisplayScale は実効の表示スケール（仮想デスクトップの1ピクセルを表示する物理ピクセル数）を記録する
+// ゲームループが、ウィンドウの大きさや画面の拡大率（高DPI）が変わるたびに呼び出す
+// スケールが hiresMinDisplayScale 以上の場合、以後の LoadPic は @2x の画像も読み込む
+func (gs *GraphicsSystem) SetDisplayScale(scale float64) {
+	gs.mu.Lock()
+	defer gs.mu.Unlock()
+	gs.appWindow.scale = scale
+	gs.pictures.SetPreferHires(prefersHires(scale))
+}
+
+// DisplayScale は実効の表示スケールを返す（まだ描画していない場合は1）
+func (gs *GraphicsSystem) DisplayScale() float64 {
+	gs.mu.RLock()
+	defer gs.mu.RUnlock()
+	if gs.appWindow.scale <= 0 {
+		return 1
+	}
+	return gs.appWindow.scale
+}
+
+// updateAppWindow はOSのウィンドウの設定の変更を反映する
+// 呼び出し元は gs.mu のロックを保持していること
+func (gs *GraphicsSystem) updateAppWindow() {
+	aw := &gs.appWindow
+	if !aw.dirty {
+		return
+	}
+	aw.dirty = false
+	if aw.settings.Title != "" {
+		ebiten.SetWindowTitle(aw.settings.Title)
+	}
+	if aw.icon != nil {
+		ebiten.SetWindowIcon([]image.Image{aw.icon})
+	}
+	if aw.settings.Width > 0 && aw.settings.Height > 0 {
+		ebiten.SetWindowSize(aw.settings.Width, aw.settings.Height)
+	}
+}
@@ -0,0 +1,137 @@
+//go:build !nogpu
+
+// graphics_camera.go はカメラ（SetCamera/CameraMove）の GraphicsSystem のメソッドを提供する
+package graphics
+
+import (
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/hajimehoshi/ebiten/v2"
+)
+
+// Camera は現在のカメラを返す（設定していない場合は仮想デスクトップ全体を表示するカメラ）
+func (gs *GraphicsSystem) Camera() Camera {
+	gs.mu.RLock()
+	defer gs.mu.RUnlock()
+	return gs.currentCamera()
+}
+
+// currentCamera は現在のカメラを返す
+// 呼び出し元は gs.mu のロックを保持していること
+func (gs *GraphicsSystem) currentCamera() Camera {
+	if gs.camera == nil {
+		return defaultCamera(gs.virtualWidth, gs.virtualHeight)
+	}
+	return *gs.camera
+}
+
+// SetCamera はカメラを設定する（nil の場合は仮想デスクトップ全体の表示に戻す）
+// 実行中のカメラの移動は止まる。
+func (gs *GraphicsSystem) SetCamera(cam *Camera) error {
+	if cam != nil {
+		if err := cam.validate(); err != nil {
+			return err
+		}
+	}
+	gs.mu.Lock()
+	defer gs.mu.Unlock()
+	gs.invalidate()
+
+	gs.cameraMove = nil
+	if cam == nil || *cam == defaultCamera(gs.virtualWidth, gs.virtualHeight) {
+		gs.camera = nil
+		gs.log.Debug("Camera reset")
+		return nil
+	}
+	c := *cam
+	gs.camera = &c
+	gs.log.Debug("Camera set", "x", c.X, "y", c.Y, "zoom", c.Zoom)
+	return nil
+}
+
+// MoveCamera は現在のカメラから cam まで duration の間にカメラを動かす
+// 実行中の移動は置き換えられる。duration が 0 以下の場合はすぐに cam にする。
+func (gs *GraphicsSystem) MoveCamera(cam Camera, duration time.Duration, easing Easing) error {
+	if !ValidEasing(easing) {
+		return fmt.Errorf("invalid easing: %d", easing)
+	}
+	if err := cam.validate(); err != nil {
+		return err
+	}
+	if duration <= 0 {
+		return gs.SetCamera(&cam)
+	}
+	gs.mu.Lock()
+	defer gs.mu.Unlock()
+	gs.invalidate()
+
+	from := gs.currentCamera()
+	gs.cameraMove = &cameraTween{from: from, to: cam, easing: easing, frames: durationFrames(duration)}
+	gs.camera = &from
+	gs.log.Debug("Camera move started", "x", cam.X, "y", cam.Y, "zoom", cam.Zoom, "frames", gs.cameraMove.frames, "easing", easing)
+	return nil
+}
+
+// updateCamera はカメラの移動を1フレーム進める
+// 呼び出し元は gs.mu のロックを保持していること
+func (gs *GraphicsSystem) updateCamera() {
+	if gs.cameraMove == nil {
+		return
+	}
+	gs.cameraMove.frame = min(gs.cameraMove.frame+1, gs.cameraMove.frames)
+	c := gs.cameraMove.current()
+	gs.camera = &c
+	if gs.cameraMove.frame >= gs.cameraMove.frames {
+		gs.cameraMove = nil
+		if c == defaultCamera(gs.virtualWidth, gs.virtualHeight) {
+			gs.camera = nil
+		}
+	}
+}
+
+// SceneFromScreen は画面上の仮想デスクトップ座標をカメラの逆変換で仮想デスクトップの座標にする
+// マウスのイベントの座標をキャストの判定に使う座標にするために使用する
+func (gs *GraphicsSystem) SceneFromScreen(x, y int) (int, int) {
+	gs.mu.RLock()
+	defer gs.mu.RUnlock()
+	if gs.camera == nil {
+		return x, y
+	}
+	sx, sy := gs.camera.toScene(float64(x), float64(y), gs.virtualWidth, gs.virtualHeight)
+	return int(math.Floor(sx)), int(math.Floor(sy))
+}
+
+// drawScene はすべてのスプライト（keep が nil でない場合は keep が true を返すスプライト）を描画する
+// カメラを設定している場合はバッファに描画してから、カメラの変換をかけて画面に描画する
+func (gs *GraphicsSystem) drawScene(screen *ebiten.Image, keep func(s *Sprite) bool) {
+	if gs.spriteManager == nil {
+		return
+	}
+	if gs.camera == nil {
+		gs.spriteManager.drawSprites(screen, keep)
+		return
+	}
+
+	bounds := screen.Bounds()
+	if gs.cameraBuffer == nil || gs.cameraBuffer.Bounds().Size() != bounds.Size() {
+		if gs.cameraBuffer != nil {
+			gs.cameraBuffer.Deallocate()
+		}
+		gs.cameraBuffer = ebiten.NewImage(bounds.Dx(), bounds.Dy())
+	}
+	gs.cameraBuffer.Clear()
+	gs.spriteManager.drawSprites(gs.cameraBuffer, keep)
+
+	c := *gs.camera
+	opts := &ebiten.DrawImageOptions{}
+	opts.GeoM.Translate(-c.X, -c.Y)
+	opts.GeoM.Scale(c.Zoom, c.Zoom)
+	opts.GeoM.Translate(float64(gs.virtualWidth)/2, float64(gs.virtualHeight)/2)
+	if c.Zoom != 1 {
+		opts.Filter = ebiten.FilterLinear
+	}
+	screen.Clear()
+	screen.DrawImage(gs.cameraBuffer, opts)
+}
@@ -1,3 +1,5 @@
+//go:build !nogpu
+
 // graphics_core.go はGraphicsSystemのコア機能を提供する
 // 構造体定義、コンストラクタ、更新ループ、シャットダウン、
 // オプション関数、ゲッターメソッド、ピクチャー操作を含む
@@ -9,7 +11,11 @@ import (
 	"io/fs"
 	"log/slog"
 	"sync"
+	"sync/atomic"
+	"time"
 
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/zurustar/son-et/pkg/eventbus"
 	"github.com/zurustar/son-et/pkg/fileutil"
 )
 
@@ -21,6 +27,13 @@ type GraphicsSystem struct {
 	casts                *CastManager
 	textRenderer         *TextRenderer
 	sceneChanges         *SceneChangeManager
+	fade                 *screenFade         // 画面全体のフェード（FadeOut/FadeIn）、実行していない場合は nil
+	shakes               map[int]*castShake  // キャストID → 実行中の揺れ（Shake）
+	flashes              map[int]*castFlash  // キャストID → 実行中のフラッシュ（Flash）
+	motions              map[int]*castMotion // キャストID → 実行中のパスに沿った移動（MoveAlongPath）
+	palette              *Palette            // 256色表示エミュレーションのパレット（SetPalette/CyclePalette）
+	display              *DisplayAdjuster    // ガンマ・明るさ・コントラストの表示調整（SetGamma/SetBrightness/SetContrast）
+	displayBuffer        *ebiten.Image       // 表示調整の前の画面（Draw からのみ使用する）
 	debugOverlay         *DebugOverlay
 	spriteManager        *SpriteManager        // スプライトシステム要件 3.1〜3.6: SpriteManagerを統合
 	windowSpriteManager  *WindowSpriteManager  // スプライトシステム要件 7.1〜7.3: WindowSpriteManagerを統合
@@ -28,9 +41,20 @@ type GraphicsSystem struct {
 	castSpriteManager    *CastSpriteManager    // スプライトシステム要件 8.1〜8.4: CastSpriteManagerを統合
 	textSpriteManager    *TextSpriteManager    // スプライトシステム要件 5.1〜5.5: TextSpriteManagerを統合
 	shapeSpriteManager   *ShapeSpriteManager   // スプライトシステム要件 9.1〜9.3: ShapeSpriteManagerを統合
+	spritePools          *SpritePoolManager    // パーティクル演出用のスプライトプール（CreateSpritePool）
+
+	// マウスカーソル（SetCursor / ShowSysCursor）
+	cursor           *cursorState // カスタムカーソル（nil の場合はなし）
+	mouseX, mouseY   int          // マウスの位置（仮想デスクトップ座標、TrackMouse で更新）
+	mouseKnown       bool         // マウスの位置を受け取ったか
+	sysCursorHidden  bool         // スクリプトがシステムのカーソルを非表示にしたか
+	sysCursorApplied bool         // システムのカーソルを実際に非表示にしているか
+
+	// OSのウィンドウのタイトル・アイコン・大きさ（SetWindowTitle / SetWindowIcon / SetWindowSize）
+	appWindow appWindowState
 
 	// パフォーマンス測定（タスク 7.1, 7.2, 7.3）
-	fpsCounter     *FPSCounter          // FPS測定
+	fpsCounter     *FPSCounter           // FPS測定
 	statsCollector *SpriteStatsCollector // スプライト統計収集
 
 	// 仮想デスクトップ
@@ -41,6 +65,42 @@ type GraphicsSystem struct {
 	paintColor color.Color
 	lineSize   int
 
+	// サンドボックスモード（--sandbox）
+	sandbox bool
+
+	// 256色表示エミュレーション（--palette-256）
+	paletteEmulation bool
+
+	// 表示調整のシェーダーを使用できない場合の警告を一度だけ記録する
+	displayWarnOnce sync.Once
+
+	// 前回の Draw 以降にシーンが変更されたかどうか（NeedsRedraw）
+	sceneDirty atomic.Bool
+
+	// 次の Draw で書き出す画像（SaveSnapshot）と合成のレイヤーの書き出し先（CaptureLayers）
+	snapshots     []snapshotRequest
+	layerCaptures []string
+	snapshotMu    sync.Mutex
+
+	// フレーム間のスプライトの状態の差分ログ（--debug-state-diff、nil の場合は記録しない）
+	stateDiff *stateDiff
+
+	// フレーム時間に応じた描画品質の自動調整（--adaptive-quality、nil の場合は調整しない）
+	governor *qualityGovernor
+
+	// 仮想デスクトップ全体のパンとズーム（SetCamera/CameraMove）
+	camera       *Camera       // 現在のカメラ（nil の場合は仮想デスクトップ全体をそのまま表示する）
+	cameraMove   *cameraTween  // 実行中のカメラの移動（CameraMove）、実行していない場合は nil
+	cameraBuffer *ebiten.Image // カメラの変換前の画面（Draw からのみ使用する）
+
+	// @2x の画像を使った画面（HiresFrame、Draw からのみ使用する）
+	hiresFrame *ebiten.Image // 仮想デスクトップの縦横2倍の画面
+	hiresLayer *ebiten.Image // @2x の画像を持たないスプライトを等倍で描く作業用の画像
+	hiresReady bool          // 直前の Draw で hiresFrame を描いたか
+
+	// ウィンドウ・キャストの操作を発行するイベントバス（nil の場合は発行しない）
+	bus *eventbus.Bus
+
 	// ログ
 	log *slog.Logger
 	mu  sync.RWMutex
@@ -71,6 +131,31 @@ func WithBasePath(basePath string) Option {
 	}
 }
 
+// WithAssetDirs は画像ファイルが見つからない場合に探すディレクトリを設定する
+// ディレクトリは基準パスからの相対パスで、プロジェクトマニフェストの assets に対応する
+func WithAssetDirs(dirs ...string) Option {
+	return func(gs *GraphicsSystem) {
+		gs.pictures.SetAssetDirs(dirs)
+	}
+}
+
+// WithAssetVars は画像のファイル名の ${名前} に展開する変数を設定する
+// プロジェクトマニフェストの vars とコマンドラインの -A に対応する
+func WithAssetVars(vars map[string]string) Option {
+	return func(gs *GraphicsSystem) {
+		gs.pictures.SetAssetVars(vars)
+	}
+}
+
+// WithSandbox はサンドボックスモードを設定する
+// 有効な場合、画像ファイルの読み込みをタイトルディレクトリ内に制限し（シンボリックリンクを含む）、
+// ピクチャーのメモリ量とキャスト数を SandboxMaxPicturePixels / SandboxMaxCasts までに制限する
+func WithSandbox(enabled bool) Option {
+	return func(gs *GraphicsSystem) {
+		gs.sandbox = enabled
+	}
+}
+
 // WithDebugOverlay はデバッグオーバーレイの有効/無効を設定する
 // 要件 15.7, 15.8: ログレベルに基づいた表示/非表示の切り替え
 func WithDebugOverlay(enabled bool) Option {
@@ -110,6 +195,8 @@ func NewGraphicsSystem(basePath string, opts ...Option) *GraphicsSystem {
 	gs.casts = NewCastManager()
 	gs.textRenderer = NewTextRenderer()
 	gs.sceneChanges = NewSceneChangeManager()
+	gs.palette = NewPalette()
+	gs.display = NewDisplayAdjuster()
 	gs.debugOverlay = NewDebugOverlay()
 	gs.spriteManager = NewSpriteManager()                               // スプライトシステム要件 3.1〜3.6: SpriteManagerを初期化
 	gs.windowSpriteManager = NewWindowSpriteManager(gs.spriteManager)   // スプライトシステム要件 7.1〜7.3: WindowSpriteManagerを初期化
@@ -117,6 +204,7 @@ func NewGraphicsSystem(basePath string, opts ...Option) *GraphicsSystem {
 	gs.castSpriteManager = NewCastSpriteManager(gs.spriteManager)       // スプライトシステム要件 8.1〜8.4: CastSpriteManagerを初期化
 	gs.textSpriteManager = NewTextSpriteManager(gs.spriteManager)       // スプライトシステム要件 5.1〜5.5: TextSpriteManagerを初期化
 	gs.shapeSpriteManager = NewShapeSpriteManager(gs.spriteManager)     // スプライトシステム要件 9.1〜9.3: ShapeSpriteManagerを初期化
+	gs.spritePools = NewSpritePoolManager(gs.spriteManager)
 
 	// パフォーマンス測定（タスク 7.1, 7.2, 7.3）
 	gs.fpsCounter = NewFPSCounter()
@@ -125,11 +213,20 @@ func NewGraphicsSystem(basePath string, opts ...Option) *GraphicsSystem {
 	// スプライトシステム移行: LayerManagerは不要になった
 	// CastManagerとTextRendererへのLayerManager設定は不要
 
+	// 最初のフレームは必ず描画する
+	gs.sceneDirty.Store(true)
+
 	// オプションを適用
 	for _, opt := range opts {
 		opt(gs)
 	}
 
+	// サンドボックスモードの制限を適用（WithBasePathによるファイルシステムの設定後に行う）
+	if gs.sandbox {
+		gs.pictures.EnableSandbox(SandboxMaxPicturePixels)
+		gs.casts.SetMaxCasts(SandboxMaxCasts)
+	}
+
 	gs.log.Info("GraphicsSystem initialized",
 		"virtualWidth", gs.virtualWidth,
 		"virtualHeight", gs.virtualHeight,
@@ -163,12 +260,46 @@ func (gs *GraphicsSystem) dumpSpriteState(operation string) {
 // Update はゲームループから呼び出され、コマンドキューを処理する
 // Ebitengineのメインスレッドで実行される
 func (gs *GraphicsSystem) Update() error {
+	start := time.Now()
+	defer gs.measureFrame(start)
 	gs.mu.Lock()
-	defer gs.mu.Unlock()
+
+	// シーンチェンジやフェード、ヒット演出、パスに沿った移動、カメラの移動の進行中は毎フレーム画面が変わる
+	if gs.sceneChanges.HasActiveChanges() || gs.fade != nil || gs.hasHitEffects() || len(gs.motions) > 0 || gs.cameraMove != nil {
+		gs.invalidate()
+	}
 
 	// シーンチェンジを更新（要件 13.11: 非同期実行）
 	gs.sceneChanges.Update()
 
+	// 画面フェードを更新（完了コールバックはロックを解放してから呼び出す）
+	onFadeDone := gs.updateFade()
+
+	// キャストの揺れとフラッシュを更新
+	gs.updateHitEffects()
+
+	// パスに沿ったキャストの移動を進める
+	gs.updateMotions()
+
+	// カメラの移動を進める
+	gs.updateCamera()
+
+	// クリックアニメーションとシステムのカーソルの表示を更新
+	gs.updateCursor()
+
+	// スクリプトが変更したOSのウィンドウの設定を反映する
+	gs.updateAppWindow()
+
+	// ストリーミング読み込みでデコードが終わったピクチャーをプレースホルダーから差し替える
+	if gs.pictures.finishReadyStreamed() {
+		gs.invalidate()
+	}
+
+	gs.mu.Unlock()
+
+	if onFadeDone != nil {
+		onFadeDone()
+	}
 	return nil
 }
 
@@ -274,18 +405,53 @@ func (gs *GraphicsSystem) LoadPic(filename string) (int, error) {
 	if err != nil {
 		return picID, err
 	}
+	gs.createPictureSpriteOnLoad(picID, filename)
+	return picID, nil
+}
+
+// LoadPicAsync loads a picture like LoadPic, but decodes it on a worker pool instead of the
+// calling goroutine, so that large images do not stall the script or the frame.
+// The picture shows a placeholder until decoding finishes; drawing operations that read its
+// pixels wait for it. onDone is called once, from another goroutine, when decoding finishes
+// (err is non-nil if decoding failed and the picture stays blank).
+func (gs *GraphicsSystem) LoadPicAsync(filename string, onDone func(picID int, err error)) (int, error) {
+	gs.mu.Lock()
+	picID, pending, err := gs.pictures.LoadPicAsync(filename)
+	if err != nil {
+		gs.mu.Unlock()
+		return picID, err
+	}
+	gs.createPictureSpriteOnLoad(picID, filename)
+	gs.mu.Unlock()
+
+	if onDone != nil {
+		go func() {
+			var decodeErr error
+			if pending != nil {
+				<-pending.done
+				decodeErr = pending.err
+			}
+			onDone(picID, decodeErr)
+		}()
+	}
+	return picID, nil
+}
+
+// createPictureSpriteOnLoad creates the hidden PictureSprite of a loaded picture.
+// The caller must hold gs.mu.
+func (gs *GraphicsSystem) createPictureSpriteOnLoad(picID int, filename string) {
 
 	// 要件 11.1: LoadPicが呼び出されたとき、非表示のPictureSpriteを作成する
 	// これにより、ウインドウに関連付けられる前でもキャストやテキストの親として機能できる
+	// スプライトは画像を参照するだけなので、ストリーミング読み込みのデコードは待たない
 	if gs.pictureSpriteManager != nil {
-		pic, picErr := gs.pictures.GetPicWithoutLock(picID)
+		pic, picErr := gs.pictures.lookupPicWithoutLock(picID)
 		if picErr == nil && pic != nil && pic.Image != nil {
 			gs.pictureSpriteManager.CreatePictureSpriteOnLoad(pic.Image, picID, pic.Width, pic.Height)
+			gs.attachPictureHires(picID)
 			gs.log.Debug("LoadPic: created PictureSprite on load", "picID", picID, "filename", filename)
 		}
 	}
-
-	return picID, nil
 }
 
 // CreatePic creates a new empty picture
@@ -362,11 +528,13 @@ func (gs *GraphicsSystem) CreatePicWithSize(srcID, width, height int) (int, erro
 func (gs *GraphicsSystem) DelPic(id int) error {
 	gs.mu.Lock()
 	defer gs.mu.Unlock()
+	gs.invalidate()
 
 	// 要件 30.1-30.3: PictureSpriteを削除する
 	if gs.pictureSpriteManager != nil {
 		gs.pictureSpriteManager.FreePictureSprite(id)
 	}
+	gs.spritePools.RemovePoolsByPicID(id)
 
 	return gs.pictures.DelPic(id)
 }
@@ -0,0 +1,168 @@
+//go:build !nogpu
+
+// graphics_cursor.go はカスタムカーソル（SetCursor/SetCursorClick）の描画と GraphicsSystem のメソッドを提供する
+package graphics
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+
+	"github.com/hajimehoshi/ebiten/v2"
+)
+
+// cursorState はカスタムカーソルの描画状態
+type cursorState struct {
+	cursor    Cursor
+	image     *ebiten.Image
+	clickImgs []*ebiten.Image // クリックアニメーションのコマ（nil の場合はなし）
+	clickTick int             // クリックアニメーションの経過フレーム数
+	playing   bool            // クリックアニメーションを再生中
+	ticks     int             // クリックアニメーションの1コマのフレーム数
+}
+
+// currentImage は現在表示するカーソルの画像を返す
+func (cs *cursorState) currentImage() *ebiten.Image {
+	if cs.playing && len(cs.clickImgs) > 0 {
+		frame := min(cs.clickTick/cs.ticks, len(cs.clickImgs)-1)
+		return cs.clickImgs[frame]
+	}
+	return cs.image
+}
+
+// copyCursorImage はピクチャーの矩形を透明色を適用してコピーする
+func copyCursorImage(src *ebiten.Image, r image.Rectangle, transColor color.Color) *ebiten.Image {
+	sub := src.SubImage(r).(*ebiten.Image)
+	img := ebiten.NewImage(r.Dx(), r.Dy())
+	if transColor != nil {
+		applyColorKeyToImage(img, sub, transColor)
+	} else {
+		img.DrawImage(sub, nil)
+	}
+	return img
+}
+
+// SetCursor はマウスに追従するカスタムカーソルを設定する（nil で解除）
+// カスタムカーソルを表示している間、システムのカーソルは表示しない。
+// 設定し直すとクリックアニメーションは解除される。
+func (gs *GraphicsSystem) SetCursor(c *Cursor) error {
+	gs.mu.Lock()
+	defer gs.mu.Unlock()
+	gs.invalidate()
+
+	if c == nil {
+		gs.cursor = nil
+		gs.log.Debug("SetCursor: custom cursor removed")
+		return nil
+	}
+	pic, err := gs.pictures.GetPicWithoutLock(c.PicID)
+	if err != nil {
+		return err
+	}
+	gs.cursor = &cursorState{
+		cursor: *c,
+		image:  copyCursorImage(pic.Image, image.Rect(0, 0, pic.Width, pic.Height), c.TransColor),
+	}
+	gs.log.Debug("SetCursor", "picID", c.PicID, "hotX", c.HotX, "hotY", c.HotY)
+	return nil
+}
+
+// SetCursorClick はカスタムカーソルのクリックアニメーションを設定する（nil で解除）
+// カスタムカーソルが設定されていない場合はエラーを返す。
+func (gs *GraphicsSystem) SetCursorClick(click *CursorClick) error {
+	gs.mu.Lock()
+	defer gs.mu.Unlock()
+
+	if gs.cursor == nil {
+		return fmt.Errorf("no custom cursor is set")
+	}
+	gs.cursor.playing = false
+	if click == nil {
+		gs.cursor.clickImgs = nil
+		return nil
+	}
+	if err := checkCursorClick(click); err != nil {
+		return err
+	}
+	pic, err := gs.pictures.GetPicWithoutLock(click.PicID)
+	if err != nil {
+		return err
+	}
+	frameW := pic.Width / click.Frames
+	if frameW == 0 {
+		return fmt.Errorf("picture %d (width %d) is too narrow for %d frames", click.PicID, pic.Width, click.Frames)
+	}
+	imgs := make([]*ebiten.Image, click.Frames)
+	for i := range imgs {
+		r := image.Rect(i*frameW, 0, (i+1)*frameW, pic.Height)
+		imgs[i] = copyCursorImage(pic.Image, r, gs.cursor.cursor.TransColor)
+	}
+	gs.cursor.clickImgs = imgs
+	gs.cursor.ticks = click.Ticks
+	gs.log.Debug("SetCursorClick", "picID", click.PicID, "frames", click.Frames, "ticks", click.Ticks)
+	return nil
+}
+
+// SetSystemCursorVisible はシステムのマウスカーソルを表示するかを設定する
+// カスタムカーソルを表示している間は、この設定にかかわらずシステムのカーソルを表示しない。
+// 実際の切り替えはゲームループの Update で行う。
+func (gs *GraphicsSystem) SetSystemCursorVisible(visible bool) {
+	gs.mu.Lock()
+	defer gs.mu.Unlock()
+	gs.sysCursorHidden = !visible
+}
+
+// TrackMouse はマウスの位置（仮想デスクトップ座標）を受け取る
+// pressed はこのフレームで左ボタンが押されたかどうかで、クリックアニメーションを開始する。
+// ゲームループから毎フレーム呼び出される（window.CursorTracker）。
+func (gs *GraphicsSystem) TrackMouse(x, y int, pressed bool) {
+	gs.mu.Lock()
+	defer gs.mu.Unlock()
+
+	moved := !gs.mouseKnown || x != gs.mouseX || y != gs.mouseY
+	gs.mouseX, gs.mouseY, gs.mouseKnown = x, y, true
+	if gs.cursor == nil {
+		return
+	}
+	if moved {
+		gs.invalidate()
+	}
+	if pressed && len(gs.cursor.clickImgs) > 0 {
+		gs.cursor.playing = true
+		gs.cursor.clickTick = 0
+		gs.invalidate()
+	}
+}
+
+// updateCursor はクリックアニメーションを1フレーム進め、システムのカーソルの表示を切り替える
+// 呼び出し元は gs.mu のロックを保持していること
+func (gs *GraphicsSystem) updateCursor() {
+	if cs := gs.cursor; cs != nil && cs.playing {
+		cs.clickTick++
+		if cs.clickTick >= cs.ticks*len(cs.clickImgs) {
+			cs.playing = false
+		}
+		gs.invalidate()
+	}
+
+	hidden := gs.sysCursorHidden || gs.cursor != nil
+	if hidden != gs.sysCursorApplied {
+		gs.sysCursorApplied = hidden
+		if hidden {
+			ebiten.SetCursorMode(ebiten.CursorModeHidden)
+		} else {
+			ebiten.SetCursorMode(ebiten.CursorModeVisible)
+		}
+	}
+}
+
+// drawCursor はカスタムカーソルをマウスの位置に描画する
+// 呼び出し元は gs.mu の読み取りロックを保持していること
+func (gs *GraphicsSystem) drawCursor(screen *ebiten.Image) {
+	if gs.cursor == nil || !gs.mouseKnown {
+		return
+	}
+	opts := &ebiten.DrawImageOptions{}
+	opts.GeoM.Translate(float64(gs.mouseX-gs.cursor.cursor.HotX), float64(gs.mouseY-gs.cursor.cursor.HotY))
+	screen.DrawImage(gs.cursor.currentImage(), opts)
+}
@@ -0,0 +1,114 @@
+//go:build !nogpu
+
+// graphics_display_adjust.go は表示調整（ガンマ・明るさ・コントラスト）のシェーダーによる描画と GraphicsSystem のメソッドを提供する
+package graphics
+
+import (
+	"sync"
+
+	"github.com/hajimehoshi/ebiten/v2"
+)
+
+// displayAdjustShaderSource は表示調整を行うKageシェーダー
+// 画面はアルファ乗算済みのため、アルファで割ってから調整し、再び乗算する
+const displayAdjustShaderSource = `//kage:unit pixels
+
+package main
+
+var Gamma float
+var Brightness float
+var Contrast float
+
+func Fragment(dstPos vec4, srcPos vec2, color vec4) vec4 {
+	c := imageSrc0At(srcPos)
+	if c.a == 0 {
+		return c
+	}
+	rgb := clamp(c.rgb/c.a, 0, 1)
+	rgb = pow(rgb, vec3(1/Gamma))
+	rgb = (rgb-0.5)*Contrast + 0.5 + Brightness
+	return vec4(clamp(rgb, 0, 1)*c.a, c.a)
+}
+`
+
+// displayAdjustShader はシェーダーを最初の使用時にコンパイルする
+var displayAdjustShader = sync.OnceValues(func() (*ebiten.Shader, error) {
+	return ebiten.NewShader([]byte(displayAdjustShaderSource))
+})
+
+// applyDisplayAdjustment は画面全体に表示調整を適用する（設定が既定値の場合は何もしない）
+// 調整前の画面は gs.displayBuffer にコピーする（画面サイズが変わった場合は作り直す）
+func (gs *GraphicsSystem) applyDisplayAdjustment(screen *ebiten.Image) error {
+	a := gs.display.Settings()
+	if a.IsIdentity() {
+		return nil
+	}
+	shader, err := displayAdjustShader()
+	if err != nil {
+		return err
+	}
+
+	bounds := screen.Bounds()
+	if gs.displayBuffer == nil || gs.displayBuffer.Bounds().Size() != bounds.Size() {
+		if gs.displayBuffer != nil {
+			gs.displayBuffer.Deallocate()
+		}
+		gs.displayBuffer = ebiten.NewImage(bounds.Dx(), bounds.Dy())
+	}
+	gs.displayBuffer.Clear()
+	gs.displayBuffer.DrawImage(screen, nil)
+
+	opts := &ebiten.DrawRectShaderOptions{}
+	opts.Images[0] = gs.displayBuffer
+	opts.Blend = ebiten.BlendCopy
+	opts.Uniforms = map[string]any{
+		"Gamma":      float32(a.Gamma),
+		"Brightness": float32(a.Brightness),
+		"Contrast":   float32(a.Contrast),
+	}
+	screen.DrawRectShader(bounds.Dx(), bounds.Dy(), shader, opts)
+	return nil
+}
+
+// WithDisplayAdjustment は起動時の表示調整を設定する（--gamma/--brightness/--contrast）
+// 範囲外の値を含む場合は警告を記録し、既定値のままにする
+func WithDisplayAdjustment(a DisplayAdjustment) Option {
+	return func(gs *GraphicsSystem) {
+		if err := gs.display.Set(a); err != nil {
+			gs.log.Warn("Invalid display adjustment ignored", "error", err)
+		}
+	}
+}
+
+// SetGamma は画面全体のガンマ値を変更する（MinGamma〜MaxGamma、既定値は1）
+func (gs *GraphicsSystem) SetGamma(v float64) error {
+	gs.invalidate()
+	return gs.display.SetGamma(v)
+}
+
+// SetBrightness は画面全体の明るさを変更する（-1〜1、既定値は0）
+func (gs *GraphicsSystem) SetBrightness(v float64) error {
+	gs.invalidate()
+	return gs.display.SetBrightness(v)
+}
+
+// SetContrast は画面全体のコントラストを変更する（0〜MaxContrast、既定値は1）
+func (gs *GraphicsSystem) SetContrast(v float64) error {
+	gs.invalidate()
+	return gs.display.SetContrast(v)
+}
+
+// GetDisplayAdjustment は現在の表示調整を返す
+func (gs *GraphicsSystem) GetDisplayAdjustment() DisplayAdjustment {
+	return gs.display.Settings()
+}
+
+// drawDisplayAdjustment は画面全体に表示調整を適用する
+// シェーダーを使用できない場合は一度だけ警告を記録し、調整せずに表示する
+func (gs *GraphicsSystem) drawDisplayAdjustment(screen *ebiten.Image) {
+	if err := gs.applyDisplayAdjustment(screen); err != nil {
+		gs.displayWarnOnce.Do(func() {
+			gs.log.Warn("Display adjustment unavailable", "error", err)
+		})
+	}
+}
@@ -1,3 +1,5 @@
+//go:build !nogpu
+
 // graphics_draw.go は描画ロジックを提供する
 // Draw()メイン描画メソッド、描画ヘルパー、デバッグオーバーレイ、
 // 座標変換、テキスト・図形描画、転送メソッドを含む
@@ -8,6 +10,7 @@ import (
 	"image"
 	"image/color"
 	"log/slog"
+	"time"
 
 	"github.com/hajimehoshi/ebiten/v2"
 	"github.com/hajimehoshi/ebiten/v2/vector"
@@ -17,14 +20,63 @@ import (
 // Ebitengineのメインスレッドで実行される
 // スプライトシステム要件 14.1: SpriteManager.Draw()ベースの描画
 func (gs *GraphicsSystem) Draw(screen *ebiten.Image) {
+	start := time.Now()
 	gs.mu.RLock()
 	defer gs.mu.RUnlock()
+	// --adaptive-quality: Update と Draw にかかった時間から描画品質を調整する
+	defer gs.endGovernorFrame(start)
+
+	// 描画中の変更は次のフレームで反映されるよう、描画前にフラグをクリアする
+	gs.sceneDirty.Store(false)
+
+	// CaptureLayers: スプライトを描画する前の画面を背景のレイヤーとして読み出しておく
+	layers := gs.beginLayerCapture(screen)
+
+	// @2x の画像を持つスプライトがある場合は、等倍の画面とは別に @2x の画面（HiresFrame）も描く
+	hires := gs.beginHiresFrame(screen)
 
 	// スプライトシステム要件 14.1: SpriteManager.Draw()ベースの描画
-	// すべてのスプライトをZ_Path順で描画する
-	if gs.spriteManager != nil {
-		gs.spriteManager.Draw(screen)
+	// すべてのスプライトをZ_Path順で描画する（カメラを設定している場合は変換して描画する）
+	gs.drawScene(screen, nil)
+
+	// --debug-state-diff: 描画したスプライトの前のフレームからの変更を記録する
+	gs.logStateDiff()
+
+	// 画面フェードのオーバーレイはすべてのスプライトの上に合成する
+	gs.drawFade(screen)
+
+	// SaveSprite の画面の書き出しは、カーソルと表示調整を含めない
+	gs.captureSnapshots(screen)
+
+	// カスタムカーソルはフェードで暗転した画面の上にも表示する
+	gs.drawCursor(screen)
+
+	// 256色表示エミュレーションはフェードを含む最終的な画面に適用する
+	gs.drawPalette(screen)
+
+	// 表示調整（プロジェクター向けの補正やスクリプトからの暗転演出）は最後に適用する
+	gs.drawDisplayAdjustment(screen)
+
+	// CaptureLayers: 各レイヤーを描き直し、最終的な画面とともに書き出す
+	gs.captureLayers(layers, screen)
+
+	gs.endHiresFrame(hires)
+}
+
+// invalidate はシーンが変更されたことを記録し、次のフレームで画面を描き直させる
+func (gs *GraphicsSystem) invalidate() {
+	gs.sceneDirty.Store(true)
+}
+
+// NeedsRedraw は前回の Draw 以降に画面の内容が変わったかを返す
+// ウィンドウ・キャスト・ピクチャー・テキストの変更、シーンチェンジやフェードの進行、
+// パレットや表示調整の変更があった場合に true を返す。
+// false の場合、前回描画した画面をそのまま表示し続けてよい（静止したスライドショーでの負荷削減）。
+func (gs *GraphicsSystem) NeedsRedraw() bool {
+	if gs.sceneDirty.Load() {
+		return true
 	}
+	return gs.spriteManager != nil && gs.spriteManager.IsDirty()
 }
 
 // drawCastsForWindow はウィンドウに属するキャストを描画する
@@ -389,6 +441,7 @@ func (gs *GraphicsSystem) SetDebugOverlayEnabled(enabled bool) {
 
 // updateDebugDrawCallback はデバッグ描画コールバックを更新する
 func (gs *GraphicsSystem) updateDebugDrawCallback(enabled bool) {
+	gs.invalidate()
 	if gs.spriteManager == nil {
 		return
 	}
@@ -589,10 +642,45 @@ func (gs *GraphicsSystem) DrawScaled(screen *ebiten.Image, virtualScreen *ebiten
 // テキスト描画メソッド
 // ============================================================================
 
-// TextWrite writes text to a picture
+// TextWrite writes text to a picture with the default pitch of the current font
 func (gs *GraphicsSystem) TextWrite(picID, x, y int, text string) error {
+	return gs.TextWriteWithPitch(picID, x, y, text, PitchDefault)
+}
+
+// TextWriteWithPitch writes text to a picture, drawing it with fixed-pitch
+// or proportional metrics (PitchDefault decides from the current font name)
+func (gs *GraphicsSystem) TextWriteWithPitch(picID, x, y int, text string, pitch FontPitch) error {
+	return gs.TextWriteWithLayout(picID, x, y, text, pitch, TextLayout{})
+}
+
+// TextWriteWithLayout writes text like TextWriteWithPitch, adding layout.Tracking
+// pixels after each character and layout.LineSpacing pixels between the lines of
+// text containing line breaks (negative values tighten the layout)
+func (gs *GraphicsSystem) TextWriteWithLayout(picID, x, y int, text string, pitch FontPitch, layout TextLayout) error {
+	return gs.textWrite(picID, x, y, text, pitch, layout, TextHighlight{})
+}
+
+// TextWriteHighlighted writes text like TextWrite, drawing its first count
+// characters in highlightColor (int 0xRRGGBB or color.Color) for karaoke display
+func (gs *GraphicsSystem) TextWriteHighlighted(picID, x, y int, text string, count int, highlightColor any) error {
+	var c color.Color
+	switch v := highlightColor.(type) {
+	case int:
+		c = ColorFromInt(v)
+	case color.Color:
+		c = v
+	default:
+		return fmt.Errorf("invalid color type: %T", highlightColor)
+	}
+	return gs.textWrite(picID, x, y, text, PitchDefault, TextLayout{}, TextHighlight{Count: count, Color: c})
+}
+
+// textWrite は TextWriteWithLayout と TextWriteHighlighted の共通処理
+func (gs *GraphicsSystem) textWrite(picID, x, y int, text string, pitch FontPitch, layout TextLayout, highlight TextHighlight) error {
+	gs.awaitPictures(picID)
 	gs.mu.Lock()
 	defer gs.mu.Unlock()
+	gs.invalidate()
 
 	pic, err := gs.pictures.GetPicWithoutLock(picID)
 	if err != nil {
@@ -608,7 +696,7 @@ func (gs *GraphicsSystem) TextWrite(picID, x, y int, text string) error {
 
 	if gs.textSpriteManager != nil {
 		textSettings := gs.textRenderer.GetTextSettings()
-		face := gs.textRenderer.GetFace()
+		face := gs.textRenderer.FaceForLayout(pitch, layout)
 
 		var parentSprite *Sprite
 		if gs.pictureSpriteManager != nil {
@@ -632,13 +720,16 @@ func (gs *GraphicsSystem) TextWrite(picID, x, y int, text string) error {
 			zOrder,
 			parentSprite,
 			textSettings.BackMode,
+			textSettings.Direction,
+			highlight,
 		)
 		if ts != nil && parentSprite != nil {
 			parentSprite.AddChild(ts.GetSprite())
 		}
 		gs.log.Debug("TextWrite: created TextSprite", "picID", picID, "text", text, "x", x, "y", y, "hasParent", parentSprite != nil)
 	} else {
-		if err := gs.textRenderer.TextWrite(pic, x, y, text); err != nil {
+		gs.dropHires(picID, pic)
+		if err := gs.textRenderer.TextWriteWithLayout(pic, x, y, text, pitch, layout, highlight); err != nil {
 			return err
 		}
 
@@ -652,6 +743,28 @@ func (gs *GraphicsSystem) TextWrite(picID, x, y int, text string) error {
 	return nil
 }
 
+// TextExtent returns the width and height of text as TextWrite would render it.
+// An empty fontName measures with the current font (set by SetFont).
+func (gs *GraphicsSystem) TextExtent(text, fontName string, size int) (int, int) {
+	return gs.TextExtentWithPitch(text, fontName, size, PitchDefault)
+}
+
+// TextExtentWithPitch is TextExtent with the pitch passed to TextWriteWithPitch.
+func (gs *GraphicsSystem) TextExtentWithPitch(text, fontName string, size int, pitch FontPitch) (int, int) {
+	gs.mu.RLock()
+	defer gs.mu.RUnlock()
+	return gs.textRenderer.TextExtentWithPitch(text, fontName, size, pitch)
+}
+
+// TextExtentWithLayout is TextExtent with the pitch and layout passed to TextWriteWithLayout.
+// For text containing line breaks, it returns the width of the longest line and the
+// height of all lines.
+func (gs *GraphicsSystem) TextExtentWithLayout(text, fontName string, size int, pitch FontPitch, layout TextLayout) (int, int) {
+	gs.mu.RLock()
+	defer gs.mu.RUnlock()
+	return gs.textRenderer.TextExtentWithLayout(text, fontName, size, pitch, layout)
+}
+
 // SetFont sets the font
 func (gs *GraphicsSystem) SetFont(name string, size int, opts ...any) error {
 	gs.mu.Lock()
@@ -733,6 +846,15 @@ func (gs *GraphicsSystem) SetBackMode(mode int) error {
 	return nil
 }
 
+// SetTextDirection sets whether TextWrite draws horizontally or in a vertical
+// (tategaki) column
+func (gs *GraphicsSystem) SetTextDirection(direction TextDirection) error {
+	gs.mu.Lock()
+	defer gs.mu.Unlock()
+	gs.textRenderer.SetTextDirection(direction)
+	return nil
+}
+
 // ============================================================================
 // 図形描画メソッド
 // ============================================================================
@@ -0,0 +1,28 @@
+//go:build !nogpu
+
+// graphics_events.go はウィンドウ・キャストの操作のイベントバスへの発行（GraphicsSystem）を提供する
+package graphics
+
+import (
+	"github.com/zurustar/son-et/pkg/eventbus"
+)
+
+// WithEventBus はウィンドウ・キャストの操作を発行するイベントバスを設定する
+func WithEventBus(bus *eventbus.Bus) Option {
+	return func(gs *GraphicsSystem) {
+		gs.bus = bus
+	}
+}
+
+// publishCast はキャストの現在の状態をイベントバスに発行する
+// 呼び出し元は gs.mu のロックを保持していること
+func (gs *GraphicsSystem) publishCast(topic string, castID int) {
+	if !gs.bus.HasSubscribers() {
+		return
+	}
+	cast, err := gs.casts.GetCast(castID)
+	if err != nil || cast == nil {
+		return
+	}
+	gs.bus.Publish(topic, castEventData(cast.ID, cast.WinID, cast.PicID, cast.X, cast.Y))
+}
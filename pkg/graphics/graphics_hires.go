@@ -0,0 +1,232 @@
+//go:build !nogpu
+
+// graphics_hires.go は @2x の画像（hires.go）の GraphicsSystem のメソッドと @2x の画面の描画を提供する
+//
+// ピクチャーの @2x の画像は、スクリプトがピクチャーを書き換えても等倍の画像と同じ内容を保つ。
+// 通常の MovePic は @2x の画像にも同じ転送を行い、それ以外の書き換え（シーンチェンジ、図形の描画、
+// スプライトを使わない TextWrite）では @2x の画像を捨てて等倍の画像だけを使う。
+package graphics
+
+import (
+	"image"
+
+	"github.com/hajimehoshi/ebiten/v2"
+)
+
+// HiresFrame は直前の Draw で描いた @2x の画面（仮想デスクトップの縦横2倍の大きさ）を返す
+// @2x の画像を持つスプライトがない場合や、@2x で描けない表示（カメラ、256色表示エミュレーション、
+// 表示調整、デバッグ描画）を使っている場合、実効の表示スケールが小さい場合は nil を返す。
+// ゲームループは nil でない場合、この画面を等倍の画面に重ねて最終的な画面に描く。
+func (gs *GraphicsSystem) HiresFrame() *ebiten.Image {
+	gs.mu.RLock()
+	defer gs.mu.RUnlock()
+	if !gs.hiresReady {
+		return nil
+	}
+	return gs.hiresFrame
+}
+
+// hiresFrameEnabled は @2x の画面を描くかを返す
+// 呼び出し元は gs.mu のロックを保持していること
+func (gs *GraphicsSystem) hiresFrameEnabled() bool {
+	return prefersHires(gs.appWindow.scale) &&
+		gs.camera == nil &&
+		!gs.paletteEmulation &&
+		gs.display.Settings().IsIdentity() &&
+		gs.spriteManager != nil &&
+		gs.spriteManager.hasHires()
+}
+
+// beginHiresFrame は @2x の画面の描画を始め、スプライトを描画する前の screen（背景）を
+// 等倍の作業用の画像に写しておく。@2x の画面を描かない場合は false を返す。
+// 呼び出し元は gs.mu の読み取りロックを保持していること（Draw からのみ使用する）
+func (gs *GraphicsSystem) beginHiresFrame(screen *ebiten.Image) bool {
+	gs.hiresReady = false
+	if !gs.hiresFrameEnabled() {
+		return false
+	}
+
+	size := screen.Bounds().Size()
+	if gs.hiresLayer == nil || gs.hiresLayer.Bounds().Size() != size {
+		if gs.hiresLayer != nil {
+			gs.hiresLayer.Deallocate()
+			gs.hiresFrame.Deallocate()
+		}
+		gs.hiresLayer = ebiten.NewImage(size.X, size.Y)
+		gs.hiresFrame = ebiten.NewImage(size.X*hiresVariantScale, size.Y*hiresVariantScale)
+	}
+	gs.hiresLayer.Clear()
+	gs.hiresLayer.DrawImage(screen, nil)
+	return true
+}
+
+// endHiresFrame は @2x の画面にスプライト、フェード、カスタムカーソルを描画する
+// 等倍の画面と同じ順序で描き、@2x の画像を持たないスプライトは等倍で描いて拡大する
+// 呼び出し元は gs.mu の読み取りロックを保持していること（Draw からのみ使用する）
+func (gs *GraphicsSystem) endHiresFrame(begun bool) {
+	if !begun {
+		return
+	}
+	gs.hiresFrame.Clear()
+	if !gs.spriteManager.drawSpritesHires(gs.hiresFrame, gs.hiresLayer) {
+		return
+	}
+	gs.drawFade(gs.hiresFrame)
+	gs.drawCursor(gs.hiresLayer)
+	flushHiresLayer(gs.hiresFrame, gs.hiresLayer)
+	gs.hiresReady = true
+}
+
+// hasHires は @2x の画像を持つ表示中のスプライトがあるかを返す
+func (sm *SpriteManager) hasHires() bool {
+	sm.mu.RLock()
+	defer sm.mu.RUnlock()
+	for _, s := range sm.sprites {
+		if s.hires != nil && s.IsEffectivelyVisible() {
+			return true
+		}
+	}
+	return false
+}
+
+// drawSpritesHires はすべての可視スプライトをZ_Path順で @2x の画面（frame）に描画する
+// @2x の画像を持つスプライトはそのまま frame に描き、それ以外は等倍の layer に描いてから
+// 次に @2x の画像を描く前に拡大して frame に重ねる。layer には背景を描いておくこと。
+// デバッグ描画が有効な場合は描画せずに false を返す。
+func (sm *SpriteManager) drawSpritesHires(frame, layer *ebiten.Image) bool {
+	items, debugCallback, drawsEffects := sm.snapshotDrawItems(func(*Sprite) bool { return true })
+	if debugCallback != nil {
+		return false
+	}
+	for _, item := range items {
+		if !item.drawsHires() {
+			sm.drawItem(layer, item, drawsEffects)
+			continue
+		}
+		flushHiresLayer(frame, layer)
+		op := &ebiten.DrawImageOptions{}
+		op.GeoM.Translate(item.x*hiresVariantScale, item.y*hiresVariantScale)
+		if item.alpha < 1.0 {
+			op.ColorScale.ScaleAlpha(float32(item.alpha))
+		}
+		frame.DrawImage(item.hires, op)
+	}
+	flushHiresLayer(frame, layer)
+	return true
+}
+
+// drawsHires はスプライトを @2x の画像で描くかを返す
+// 画像の一部だけを描く場合やマスク・影などの演出がある場合は等倍で描く
+func (item spriteDrawItem) drawsHires() bool {
+	return item.hires != nil &&
+		!item.clipped &&
+		item.shadow == nil &&
+		item.outline == nil &&
+		item.flash == nil &&
+		item.source == nil &&
+		item.nineSlice == nil &&
+		item.crossfade == nil
+}
+
+// flushHiresLayer は等倍の layer を最近傍補間で拡大して frame に重ね、layer を消去する
+func flushHiresLayer(frame, layer *ebiten.Image) {
+	op := &ebiten.DrawImageOptions{Filter: ebiten.FilterNearest}
+	op.GeoM.Scale(hiresVariantScale, hiresVariantScale)
+	frame.DrawImage(layer, op)
+	layer.Clear()
+}
+
+// attachPictureHires はピクチャーの画像をそのまま表示する PictureSprite に @2x の画像を設定する
+// （ピクチャーが @2x の画像を持たない場合は解除する）
+// 呼び出し元は gs.mu のロックを保持していること
+func (gs *GraphicsSystem) attachPictureHires(picID int) {
+	if gs.pictureSpriteManager == nil {
+		return
+	}
+	pic, err := gs.pictures.lookupPicWithoutLock(picID)
+	if err != nil || pic.Image == nil {
+		return
+	}
+	for _, ps := range gs.pictureSpriteManager.GetPictureSprites(picID) {
+		if sprite := ps.GetSprite(); sprite != nil && sprite.Image() == pic.Image {
+			sprite.SetHiresImage(pic.Hires)
+		}
+	}
+}
+
+// dropHires はピクチャーの @2x の画像を捨てる（等倍の画像だけを書き換えた場合に使用する）
+// 呼び出し元は gs.mu のロックを保持していること
+func (gs *GraphicsSystem) dropHires(picID int, pic *Picture) {
+	if pic.Hires == nil {
+		return
+	}
+	pic.Hires = nil
+	gs.attachPictureHires(picID)
+	gs.log.Debug("Dropped @2x variant of modified picture", "picID", picID)
+}
+
+// transferHires は MovePic の転送（srcRect の範囲を dst の (dstX, dstY) へ）を @2x の画像にも行う
+// 転送元も転送先も @2x の画像を持たない場合は何もしない。転送先が持たない場合は等倍の画像を
+// 拡大して作り、転送元が持たない場合は等倍の画像を拡大して転送する。
+// 等倍の画像に転送する前に呼び出すこと。呼び出し元は gs.mu のロックを保持していること
+func (gs *GraphicsSystem) transferHires(srcPic *Picture, dstID int, dstPic *Picture, srcRect image.Rectangle, dstX, dstY int) {
+	if srcPic.Hires == nil && dstPic.Hires == nil {
+		return
+	}
+	if dstPic.Hires == nil {
+		dstPic.Hires = upscaleImage(dstPic.Image)
+		defer gs.attachPictureHires(dstID)
+	}
+
+	op := &ebiten.DrawImageOptions{Filter: ebiten.FilterNearest}
+	src := srcPic.Hires
+	if src != nil {
+		src = src.SubImage(hiresRect(srcRect)).(*ebiten.Image)
+	} else {
+		src = srcPic.Image.SubImage(srcRect).(*ebiten.Image)
+		op.GeoM.Scale(hiresVariantScale, hiresVariantScale)
+	}
+	op.GeoM.Translate(float64(dstX*hiresVariantScale), float64(dstY*hiresVariantScale))
+	dstPic.Hires.DrawImage(src, op)
+}
+
+// attachCastHires はキャストのスプライトにピクチャーの @2x の画像から切り出した画像を設定する
+// ピクチャーが @2x の画像を持たない場合は解除する。透明色は等倍の画像と同じく処理する。
+// 呼び出し元は gs.mu のロックを保持していること
+func (gs *GraphicsSystem) attachCastHires(cs *CastSprite, srcPic *Picture) {
+	if cs == nil || cs.GetSprite() == nil {
+		return
+	}
+	sprite := cs.GetSprite()
+	cast := cs.GetCast()
+	if srcPic == nil || srcPic.Hires == nil || cast == nil {
+		sprite.SetHiresImage(nil)
+		return
+	}
+	// 等倍の画像と同じく、ピクチャーの外側は切り捨てる
+	rect := image.Rect(cast.SrcX, cast.SrcY, cast.SrcX+cast.Width, cast.SrcY+cast.Height).
+		Intersect(image.Rect(0, 0, srcPic.Width, srcPic.Height))
+	if rect.Empty() {
+		sprite.SetHiresImage(nil)
+		return
+	}
+	src := srcPic.Hires.SubImage(hiresRect(rect)).(*ebiten.Image)
+	img := ebiten.NewImage(src.Bounds().Dx(), src.Bounds().Dy())
+	if cs.HasTransColor() {
+		applyColorKeyToImage(img, src, cs.GetTransColor())
+	} else {
+		copyImage(img, src)
+	}
+	sprite.SetHiresImage(img)
+}
+
+// upscaleImage は img を最近傍補間で縦横2倍にした画像を返す
+func upscaleImage(img *ebiten.Image) *ebiten.Image {
+	b := img.Bounds()
+	dst := ebiten.NewImage(b.Dx()*hiresVariantScale, b.Dy()*hiresVariantScale)
+	op := &ebiten.DrawImageOptions{Filter: ebiten.FilterNearest}
+	op.GeoM.Translate(float64(-b.Min.X), float64(-b.Min.Y))
+	op.GeoM.Scale(hiresVariantScale, hiresVariantScale)
+	dst.DrawImage(img, op)
+	return dst
+}
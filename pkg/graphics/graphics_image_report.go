@@ -0,0 +1,37 @@
+//go:build !nogpu
+
+// graphics_image_report.go はピクチャーの使用状況の報告（ImageReport）の GraphicsSystem のメソッドを提供する
+package graphics
+
+import (
+	"time"
+)
+
+// SetImageSite sets the function naming the script statement that creates a picture
+// (recorded for the leak report).
+func (gs *GraphicsSystem) SetImageSite(site func() string) {
+	gs.pictures.tracker.setSite(site)
+}
+
+// SampleImageUsage records which pictures are shown by a visible window or cast at now.
+func (gs *GraphicsSystem) SampleImageUsage(now time.Time) {
+	gs.pictures.tracker.sample(gs.shownPictures(), now)
+}
+
+// ImageReport returns the picture memory and the pictures not shown for idle or longer.
+func (gs *GraphicsSystem) ImageReport(idle time.Duration, now time.Time) *ImageReport {
+	gs.SampleImageUsage(now)
+	return gs.pictures.tracker.report(idle, now)
+}
+
+// shownPictures は表示中のウィンドウ・キャストが参照するピクチャーを返す
+func (gs *GraphicsSystem) shownPictures() map[int]*imageViewers {
+	var windows, casts []pictureRef
+	for _, w := range gs.windows.GetWindowsOrdered() {
+		windows = append(windows, pictureRef{id: w.ID, picID: w.PicID, winID: -1, visible: w.Visible})
+	}
+	for _, c := range gs.casts.GetCastsOrdered() {
+		casts = append(casts, pictureRef{id: c.ID, picID: c.PicID, winID: c.WinID, visible: c.Visible})
+	}
+	return shownPictures(windows, casts)
+}
@@ -0,0 +1,613 @@
+//go:build !nogpu
+
+// graphics_keyframe.go は GraphicsSystem のキーフレーム（keyframe.go）を提供する
+//
+// キーフレームはピクチャー・ウィンドウ・キャストとそれらを描くスプライトの木を丸ごと複製する。
+// 画像はすべて GPU 上で複製し、同じ画像を共有していたもの（背景の PictureSprite とピクチャーなど）は
+// 複製でも同じ画像を共有する。マスク・クロスフェードの元の絵など書き換えられない画像は共有する。
+// フェードとシーンチェンジは完了をスクリプトに知らせるため、実行中はキーフレームを作成しない。
+package graphics
+
+import (
+	"fmt"
+	"image/color"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"golang.org/x/image/font"
+)
+
+// graphicsKeyframe は GraphicsSystem のキーフレーム
+type graphicsKeyframe struct {
+	state  *graphicsState
+	images []*ebiten.Image // キーフレームのために複製した画像（Release で解放する）
+}
+
+// Release はキーフレームのために複製した画像を解放する
+func (kf *graphicsKeyframe) Release() {
+	for _, img := range kf.images {
+		img.Deallocate()
+	}
+	kf.images = nil
+	kf.state = nil
+}
+
+// graphicsState は GraphicsSystem の各マネージャーの内容
+// liveState は現在の内容を参照し、clone はそれを複製する
+type graphicsState struct {
+	pictures  map[int]*Picture
+	nextPicID int
+	tracked   map[int]trackedImage
+
+	windows        map[int]*Window
+	nextWinID      int
+	nextWinZOrder  int
+	defaultCaption string
+
+	casts          map[int]*Cast
+	nextCastID     int
+	nextCastZOrder int
+
+	sprites      map[int]*Sprite
+	nextSpriteID int
+	zCounters    map[int]int
+
+	windowSprites       map[int]*WindowSprite
+	pictureSprites      map[int][]*PictureSprite
+	backgroundSprites   map[int]*PictureSprite
+	nextPictureSpriteID int
+	pictureZOffsets     map[int]int
+	castSprites         map[int]*CastSprite
+	textSprites         map[int][]*TextSprite
+	nextTextSpriteID    int
+	textZOffsets        map[int]int
+	shapeSprites        map[int][]*ShapeSprite
+	nextShapeSpriteID   int
+	pools               map[int]*SpritePool
+	nextPoolID          int
+
+	shakes     map[int]castShake
+	flashes    map[int]castFlash
+	motions    map[int]castMotion
+	camera     *Camera
+	cameraMove *cameraTween
+
+	paintColor color.Color
+	lineSize   int
+	text       textRendererState
+}
+
+// textRendererState は TextRenderer のフォントとテキストの設定
+type textRendererState struct {
+	font      FontSettings
+	settings  TextSettings
+	face      font.Face
+	fixedFace font.Face
+	loaded    bool
+}
+
+// CaptureKeyframe は描画の状態を複製する
+// フェードやシーンチェンジの実行中は ErrKeyframeBusy を返す（終わってから作成し直すこと）
+func (gs *GraphicsSystem) CaptureKeyframe() (Keyframe, error) {
+	gs.mu.Lock()
+	defer gs.mu.Unlock()
+
+	if gs.fade != nil || gs.sceneChanges.HasActiveChanges() {
+		return nil, ErrKeyframeBusy
+	}
+	c := newKeyframeCloner()
+	kf := &graphicsKeyframe{state: gs.liveState().clone(c)}
+	for _, img := range c.images {
+		kf.images = append(kf.images, img)
+	}
+	gs.log.Debug("Keyframe captured", "pictures", len(kf.state.pictures), "sprites", len(kf.state.sprites), "images", len(kf.images))
+	return kf, nil
+}
+
+// RestoreKeyframe は描画の状態を CaptureKeyframe で複製した時点に戻す
+// キーフレームはもう一度複製して使うため、同じキーフレームに何度でも戻せる。
+// 実行中のシーンチェンジは取り消し、パレット・表示調整・カーソル・OSのウィンドウの設定は戻さない。
+func (gs *GraphicsSystem) RestoreKeyframe(keyframe Keyframe) error {
+	kf, ok := keyframe.(*graphicsKeyframe)
+	if !ok || kf.state == nil {
+		return fmt.Errorf("keyframe %T was not taken by this graphics system or has been released", keyframe)
+	}
+
+	gs.mu.Lock()
+	defer gs.mu.Unlock()
+	gs.invalidate()
+
+	state := kf.state.clone(newKeyframeCloner())
+	old := gs.liveState()
+	gs.sceneChanges.Clear()
+	gs.fade = nil
+	gs.install(state)
+	gs.releaseState(old)
+
+	gs.log.Debug("Keyframe restored", "pictures", len(state.pictures), "sprites", len(state.sprites))
+	return nil
+}
+
+// liveState は各マネージャーの現在の内容を返す（マップは複製し、要素は共有する）
+// デコード中のピクチャーはデコードの完了を待つ。呼び出し元は gs.mu のロックを保持していること
+func (gs *GraphicsSystem) liveState() *graphicsState {
+	st := &graphicsState{}
+
+	pm := gs.pictures
+	pm.mu.Lock()
+	st.pictures = make(map[int]*Picture, len(pm.pictures))
+	for id, pic := range pm.pictures {
+		if pic.pending != nil {
+			pm.finishStreamed(pic)
+		}
+		st.pictures[id] = pic
+	}
+	st.nextPicID = pm.nextID
+	pm.mu.Unlock()
+	st.tracked = pm.tracker.snapshot()
+
+	wm := gs.windows
+	wm.mu.RLock()
+	st.windows = copyMap(wm.windows)
+	st.nextWinID, st.nextWinZOrder, st.defaultCaption = wm.nextID, wm.nextZOrder, wm.defaultCaption
+	wm.mu.RUnlock()
+
+	cm := gs.casts
+	cm.mu.RLock()
+	st.casts = copyMap(cm.casts)
+	st.nextCastID, st.nextCastZOrder = cm.nextID, cm.nextZOrder
+	cm.mu.RUnlock()
+
+	sm := gs.spriteManager
+	sm.mu.RLock()
+	st.sprites = copyMap(sm.sprites)
+	st.nextSpriteID = sm.nextID
+	sm.mu.RUnlock()
+	sm.zOrderCounter.mu.RLock()
+	st.zCounters = copyMap(sm.zOrderCounter.counters)
+	sm.zOrderCounter.mu.RUnlock()
+
+	wsm := gs.windowSpriteManager
+	wsm.mu.RLock()
+	st.windowSprites = copyMap(wsm.windowSprites)
+	wsm.mu.RUnlock()
+
+	psm := gs.pictureSpriteManager
+	psm.mu.RLock()
+	st.pictureSprites = copyMap(psm.pictureSprites)
+	st.backgroundSprites = copyMap(psm.pictureSpriteMap)
+	st.nextPictureSpriteID, st.pictureZOffsets = psm.nextID, copyMap(psm.zOffsets)
+	psm.mu.RUnlock()
+
+	csm := gs.castSpriteManager
+	csm.mu.RLock()
+	st.castSprites = copyMap(csm.castSprites)
+	csm.mu.RUnlock()
+
+	tsm := gs.textSpriteManager
+	tsm.mu.RLock()
+	st.textSprites = copyMap(tsm.textSprites)
+	st.nextTextSpriteID, st.textZOffsets = tsm.nextID, copyMap(tsm.zOffsets)
+	tsm.mu.RUnlock()
+
+	ssm := gs.shapeSpriteManager
+	ssm.mu.RLock()
+	st.shapeSprites = copyMap(ssm.shapeSprites)
+	st.nextShapeSpriteID = ssm.nextID
+	ssm.mu.RUnlock()
+
+	spm := gs.spritePools
+	spm.mu.RLock()
+	st.pools = copyMap(spm.pools)
+	st.nextPoolID = spm.nextID
+	spm.mu.RUnlock()
+
+	st.shakes = derefMap(gs.shakes)
+	st.flashes = derefMap(gs.flashes)
+	st.motions = derefMap(gs.motions)
+	st.camera, st.cameraMove = gs.camera, gs.cameraMove
+	st.paintColor, st.lineSize = gs.paintColor, gs.lineSize
+	st.text = gs.textRenderer.keyframeState()
+	return st
+}
+
+// install は各マネージャーの内容を st に置き換える
+// 呼び出し元は gs.mu のロックを保持していること
+func (gs *GraphicsSystem) install(st *graphicsState) {
+	pm := gs.pictures
+	pm.mu.Lock()
+	pm.pictures, pm.nextID = st.pictures, st.nextPicID
+	pm.mu.Unlock()
+	pm.tracker.restore(st.tracked)
+
+	wm := gs.windows
+	wm.mu.Lock()
+	wm.windows, wm.nextID, wm.nextZOrder, wm.defaultCaption = st.windows, st.nextWinID, st.nextWinZOrder, st.defaultCaption
+	wm.mu.Unlock()
+
+	cm := gs.casts
+	cm.mu.Lock()
+	cm.casts, cm.nextID, cm.nextZOrder = st.casts, st.nextCastID, st.nextCastZOrder
+	cm.mu.Unlock()
+
+	sm := gs.spriteManager
+	sm.mu.Lock()
+	sm.sprites, sm.nextID = st.sprites, st.nextSpriteID
+	sm.sorted, sm.needSort = nil, true
+	sm.mu.Unlock()
+	sm.zOrderCounter.mu.Lock()
+	sm.zOrderCounter.counters = st.zCounters
+	sm.zOrderCounter.mu.Unlock()
+
+	wsm := gs.windowSpriteManager
+	wsm.mu.Lock()
+	wsm.windowSprites = st.windowSprites
+	wsm.mu.Unlock()
+
+	psm := gs.pictureSpriteManager
+	psm.mu.Lock()
+	psm.pictureSprites, psm.pictureSpriteMap = st.pictureSprites, st.backgroundSprites
+	psm.nextID, psm.zOffsets = st.nextPictureSpriteID, st.pictureZOffsets
+	psm.mu.Unlock()
+
+	csm := gs.castSpriteManager
+	csm.mu.Lock()
+	csm.castSprites = st.castSprites
+	for _, cs := range st.castSprites {
+		cs.atlas = csm.atlas
+	}
+	csm.mu.Unlock()
+
+	tsm := gs.textSpriteManager
+	tsm.mu.Lock()
+	tsm.textSprites, tsm.nextID, tsm.zOffsets = st.textSprites, st.nextTextSpriteID, st.textZOffsets
+	tsm.mu.Unlock()
+
+	ssm := gs.shapeSpriteManager
+	ssm.mu.Lock()
+	ssm.shapeSprites, ssm.nextID = st.shapeSprites, st.nextShapeSpriteID
+	ssm.mu.Unlock()
+
+	spm := gs.spritePools
+	spm.mu.Lock()
+	spm.pools, spm.nextID = st.pools, st.nextPoolID
+	for _, sp := range st.pools {
+		sp.stride = spm.stride
+	}
+	spm.mu.Unlock()
+
+	gs.shakes = refMap(st.shakes)
+	gs.flashes = refMap(st.flashes)
+	gs.motions = refMap(st.motions)
+	gs.camera, gs.cameraMove = st.camera, st.cameraMove
+	gs.paintColor, gs.lineSize = st.paintColor, st.lineSize
+	gs.textRenderer.restoreKeyframeState(st.text)
+}
+
+// releaseState は install で置き換えた内容のピクチャーの画像とキャストのアトラスの領域を解放する
+// 呼び出し元は gs.mu のロックを保持していること
+func (gs *GraphicsSystem) releaseState(st *graphicsState) {
+	for _, pic := range st.pictures {
+		for _, img := range []*ebiten.Image{pic.Image, pic.BackBuffer, pic.Hires} {
+			if img != nil {
+				img.Deallocate()
+			}
+		}
+	}
+	for _, cs := range st.castSprites {
+		if cs.sprite != nil {
+			gs.castSpriteManager.atlas.release(cs.sprite.Image())
+		}
+	}
+}
+
+// clone は st を複製する（画像は c の中で一度だけ複製する）
+func (st *graphicsState) clone(c *keyframeCloner) *graphicsState {
+	d := *st
+
+	d.pictures = make(map[int]*Picture, len(st.pictures))
+	for id, pic := range st.pictures {
+		p := *pic
+		p.Image, p.BackBuffer, p.Hires = c.image(pic.Image), c.image(pic.BackBuffer), c.image(pic.Hires)
+		d.pictures[id] = &p
+	}
+	d.tracked = copyMap(st.tracked)
+
+	d.windows = make(map[int]*Window, len(st.windows))
+	for id, win := range st.windows {
+		d.windows[id] = c.window(win)
+	}
+	d.casts = make(map[int]*Cast, len(st.casts))
+	for id, cast := range st.casts {
+		d.casts[id] = c.cast(cast)
+	}
+
+	d.sprites = make(map[int]*Sprite, len(st.sprites))
+	for id, s := range st.sprites {
+		d.sprites[id] = c.sprite(s)
+	}
+	d.zCounters = copyMap(st.zCounters)
+
+	d.windowSprites = make(map[int]*WindowSprite, len(st.windowSprites))
+	for id, ws := range st.windowSprites {
+		d.windowSprites[id] = c.windowSprite(ws)
+	}
+
+	pictureSprites := make(map[*PictureSprite]*PictureSprite)
+	clonePictureSprite := func(ps *PictureSprite) *PictureSprite {
+		if cp, ok := pictureSprites[ps]; ok {
+			return cp
+		}
+		cp := *ps
+		cp.sprite = c.sprite(ps.sprite)
+		pictureSprites[ps] = &cp
+		return &cp
+	}
+	d.pictureSprites = make(map[int][]*PictureSprite, len(st.pictureSprites))
+	for id, list := range st.pictureSprites {
+		d.pictureSprites[id] = cloneList(list, clonePictureSprite)
+	}
+	d.backgroundSprites = make(map[int]*PictureSprite, len(st.backgroundSprites))
+	for id, ps := range st.backgroundSprites {
+		d.backgroundSprites[id] = clonePictureSprite(ps)
+	}
+	d.pictureZOffsets = copyMap(st.pictureZOffsets)
+
+	d.castSprites = make(map[int]*CastSprite, len(st.castSprites))
+	for id, cs := range st.castSprites {
+		d.castSprites[id] = c.castSprite(cs)
+	}
+	d.textSprites = make(map[int][]*TextSprite, len(st.textSprites))
+	for id, list := range st.textSprites {
+		d.textSprites[id] = cloneList(list, c.textSprite)
+	}
+	d.textZOffsets = copyMap(st.textZOffsets)
+	d.shapeSprites = make(map[int][]*ShapeSprite, len(st.shapeSprites))
+	for id, list := range st.shapeSprites {
+		d.shapeSprites[id] = cloneList(list, c.shapeSprite)
+	}
+	d.pools = make(map[int]*SpritePool, len(st.pools))
+	for id, sp := range st.pools {
+		d.pools[id] = c.pool(sp)
+	}
+
+	d.shakes, d.flashes, d.motions = copyMap(st.shakes), copyMap(st.flashes), copyMap(st.motions)
+	if st.camera != nil {
+		cam := *st.camera
+		d.camera = &cam
+	}
+	if st.cameraMove != nil {
+		move := *st.cameraMove
+		d.cameraMove = &move
+	}
+	return &d
+}
+
+// keyframeCloner はキーフレームの複製で、複数から参照されるものを一度だけ複製する
+type keyframeCloner struct {
+	images  map[*ebiten.Image]*ebiten.Image
+	sprites map[*Sprite]*Sprite
+	windows map[*Window]*Window
+	casts   map[*Cast]*Cast
+}
+
+func newKeyframeCloner() *keyframeCloner {
+	return &keyframeCloner{
+		images:  make(map[*ebiten.Image]*ebiten.Image),
+		sprites: make(map[*Sprite]*Sprite),
+		windows: make(map[*Window]*Window),
+		casts:   make(map[*Cast]*Cast),
+	}
+}
+
+// image は画像を GPU 上で複製する（アトラスの画像は通常の画像として複製する）
+func (c *keyframeCloner) image(img *ebiten.Image) *ebiten.Image {
+	if img == nil {
+		return nil
+	}
+	if cp, ok := c.images[img]; ok {
+		return cp
+	}
+	b := img.Bounds()
+	cp := ebiten.NewImage(b.Dx(), b.Dy())
+	copyImage(cp, img)
+	c.images[img] = cp
+	return cp
+}
+
+// sprite はスプライトを親子関係ごと複製する
+// カスタム描画関数はスプライトを持つ CastSprite・SpritePool の複製が設定し直す
+func (c *keyframeCloner) sprite(s *Sprite) *Sprite {
+	if s == nil {
+		return nil
+	}
+	if cp, ok := c.sprites[s]; ok {
+		return cp
+	}
+	cp := *s
+	c.sprites[s] = &cp
+	cp.image, cp.hires = c.image(s.image), c.image(s.hires)
+	cp.parent = c.sprite(s.parent)
+	cp.children = cloneList(s.children, c.sprite)
+	cp.customDraw = nil
+	if s.source != nil {
+		r := *s.source
+		cp.source = &r
+	}
+	if s.nineSlice != nil {
+		ns := *s.nineSlice
+		cp.nineSlice = &ns
+	}
+	return &cp
+}
+
+func (c *keyframeCloner) window(w *Window) *Window {
+	if w == nil {
+		return nil
+	}
+	if cp, ok := c.windows[w]; ok {
+		return cp
+	}
+	cp := *w
+	cp.Casts = append([]int(nil), w.Casts...)
+	c.windows[w] = &cp
+	return &cp
+}
+
+func (c *keyframeCloner) cast(cast *Cast) *Cast {
+	if cast == nil {
+		return nil
+	}
+	if cp, ok := c.casts[cast]; ok {
+		return cp
+	}
+	cp := *cast
+	c.casts[cast] = &cp
+	return &cp
+}
+
+func (c *keyframeCloner) windowSprite(ws *WindowSprite) *WindowSprite {
+	ws.mu.RLock()
+	defer ws.mu.RUnlock()
+	return &WindowSprite{
+		window:          c.window(ws.window),
+		sprite:          c.sprite(ws.sprite),
+		borderThickness: ws.borderThickness,
+		titleBarHeight:  ws.titleBarHeight,
+		children:        cloneList(ws.children, c.sprite),
+	}
+}
+
+func (c *keyframeCloner) castSprite(cs *CastSprite) *CastSprite {
+	cs.mu.RLock()
+	defer cs.mu.RUnlock()
+	cp := &CastSprite{
+		cast:          c.cast(cs.cast),
+		sprite:        c.sprite(cs.sprite),
+		srcPicID:      cs.srcPicID,
+		srcImage:      c.image(cs.srcImage),
+		lastSrcX:      cs.lastSrcX,
+		lastSrcY:      cs.lastSrcY,
+		lastWidth:     cs.lastWidth,
+		lastHeight:    cs.lastHeight,
+		transColor:    cs.transColor,
+		hasTransColor: cs.hasTransColor,
+		cachedImage:   c.image(cs.cachedImage),
+		dirty:         cs.dirty,
+	}
+	if cs.sprite != nil && cs.sprite.customDraw != nil {
+		cp.sprite.SetCustomDraw(cp.drawCachedImage)
+	}
+	return cp
+}
+
+func (c *keyframeCloner) textSprite(ts *TextSprite) *TextSprite {
+	ts.mu.RLock()
+	defer ts.mu.RUnlock()
+	return &TextSprite{
+		sprite:    c.sprite(ts.sprite),
+		picID:     ts.picID,
+		text:      ts.text,
+		x:         ts.x,
+		y:         ts.y,
+		textColor: ts.textColor,
+		bgColor:   ts.bgColor,
+		face:      ts.face,
+		direction: ts.direction,
+	}
+}
+
+func (c *keyframeCloner) shapeSprite(ss *ShapeSprite) *ShapeSprite {
+	ss.mu.RLock()
+	defer ss.mu.RUnlock()
+	return &ShapeSprite{
+		sprite:    c.sprite(ss.sprite),
+		shapeType: ss.shapeType,
+		color:     ss.color,
+		lineSize:  ss.lineSize,
+		x1:        ss.x1,
+		y1:        ss.y1,
+		x2:        ss.x2,
+		y2:        ss.y2,
+		radius:    ss.radius,
+		fillMode:  ss.fillMode,
+		picID:     ss.picID,
+		destX:     ss.destX,
+		destY:     ss.destY,
+	}
+}
+
+func (c *keyframeCloner) pool(sp *SpritePool) *SpritePool {
+	sp.mu.Lock()
+	defer sp.mu.Unlock()
+	cp := &SpritePool{
+		id:     sp.id,
+		picID:  sp.picID,
+		image:  c.image(sp.image),
+		sprite: c.sprite(sp.sprite),
+		set:    sp.set.clone(),
+		stride: sp.stride,
+	}
+	cp.sprite.SetCustomDraw(cp.draw)
+	return cp
+}
+
+// keyframeState は現在のフォントとテキストの設定を返す
+func (tr *TextRenderer) keyframeState() textRendererState {
+	tr.mu.RLock()
+	defer tr.mu.RUnlock()
+	return textRendererState{font: *tr.font, settings: *tr.settings, face: tr.face, fixedFace: tr.fixedFace, loaded: tr.loaded}
+}
+
+// restoreKeyframeState はフォントとテキストの設定を keyframeState で返した時点に戻す
+func (tr *TextRenderer) restoreKeyframeState(st textRendererState) {
+	tr.mu.Lock()
+	defer tr.mu.Unlock()
+	f, s := st.font, st.settings
+	tr.font, tr.settings = &f, &s
+	tr.face, tr.fixedFace, tr.loaded = st.face, st.fixedFace, st.loaded
+}
+
+// copyMap はマップを複製する（nil は nil のまま）
+func copyMap[K comparable, V any](m map[K]V) map[K]V {
+	if m == nil {
+		return nil
+	}
+	cp := make(map[K]V, len(m))
+	for k, v := range m {
+		cp[k] = v
+	}
+	return cp
+}
+
+// derefMap は演出の状態のマップを値のマップにする
+func derefMap[V any](m map[int]*V) map[int]V {
+	values := make(map[int]V, len(m))
+	for k, v := range m {
+		values[k] = *v
+	}
+	return values
+}
+
+// refMap は値のマップを演出の状態のマップにする（空の場合は nil）
+func refMap[V any](m map[int]V) map[int]*V {
+	if len(m) == 0 {
+		return nil
+	}
+	refs := make(map[int]*V, len(m))
+	for k, v := range m {
+		refs[k] = &v
+	}
+	return refs
+}
+
+// cloneList はスライスの要素をそれぞれ複製する
+func cloneList[T any](list []*T, clone func(*T) *T) []*T {
+	if list == nil {
+		return nil
+	}
+	cp := make([]*T, len(list))
+	for i, v := range list {
+		cp[i] = clone(v)
+	}
+	return cp
+}
@@ -0,0 +1,170 @@
+//go:build !nogpu
+
+// graphics_layers.go は合成のレイヤーの書き出し（CaptureLayers）の GraphicsSystem のメソッドを提供する
+package graphics
+
+import (
+	"fmt"
+	"image"
+	"path/filepath"
+
+	"github.com/hajimehoshi/ebiten/v2"
+)
+
+// layerCapture は Draw の途中で読み出した、書き出しを待つ合成のレイヤー
+type layerCapture struct {
+	dirs       []string
+	background *image.RGBA
+}
+
+// CaptureLayers writes each compositing layer of the next frame to a PNG file in dir,
+// creating it if needed: the background, the windows, the sprites of each z-band
+// (the z order of the window they belong to), the text, the overlay (fade and
+// cursor) and the final frame, numbered in compositing order. Like SaveSnapshot,
+// the images are read when the next frame is drawn, so write errors are logged.
+func (gs *GraphicsSystem) CaptureLayers(dir string) error {
+	gs.snapshotMu.Lock()
+	defer gs.snapshotMu.Unlock()
+	if len(gs.layerCaptures) >= maxPendingSnapshots {
+		return fmt.Errorf("too many pending layer captures (%d), wait for the next frame", maxPendingSnapshots)
+	}
+	gs.layerCaptures = append(gs.layerCaptures, dir)
+	gs.invalidate()
+	return nil
+}
+
+// beginLayerCapture は CaptureLayers の要求があれば、スプライトを描画する前の screen を読み出す
+// 要求がない場合は nil を返す
+func (gs *GraphicsSystem) beginLayerCapture(screen *ebiten.Image) *layerCapture {
+	gs.snapshotMu.Lock()
+	dirs := gs.layerCaptures
+	gs.layerCaptures = nil
+	gs.snapshotMu.Unlock()
+
+	if len(dirs) == 0 {
+		return nil
+	}
+	return &layerCapture{dirs: dirs, background: readScreen(screen)}
+}
+
+// captureLayers は各レイヤーを作業用の画像に描き直し、screen（最終的な画面）とともに書き出す
+// 呼び出し元は gs.mu の読み取りロックを保持していること
+func (gs *GraphicsSystem) captureLayers(lc *layerCapture, screen *ebiten.Image) {
+	if lc == nil {
+		return
+	}
+	type capturedLayer struct {
+		name string
+		img  *image.RGBA
+	}
+	captured := []capturedLayer{{layerBackground, lc.background}}
+
+	size := screen.Bounds().Size()
+	buf := ebiten.NewImage(size.X, size.Y)
+	defer buf.Deallocate()
+
+	named := gs.namedSpriteLayers()
+	layerOf := func(s *Sprite) string {
+		if name, ok := named[s.ID()]; ok {
+			return name
+		}
+		return spriteBandLayer(zBand(s))
+	}
+	for _, name := range sceneLayers(gs.spriteManager.zBands(named)) {
+		buf.Clear()
+		gs.drawScene(buf, func(s *Sprite) bool { return layerOf(s) == name })
+		captured = append(captured, capturedLayer{name, readScreen(buf)})
+	}
+
+	buf.Clear()
+	gs.drawFade(buf)
+	gs.drawCursor(buf)
+	captured = append(captured, capturedLayer{layerOverlay, readScreen(buf)})
+	captured = append(captured, capturedLayer{layerFrame, readScreen(screen)})
+
+	for _, dir := range lc.dirs {
+		failed := false
+		for i, layer := range captured {
+			path := filepath.Join(dir, layerFileName(i, layer.name))
+			if err := writePNG(path, layer.img); err != nil {
+				gs.log.Error("CaptureLayers failed", "path", path, "error", err)
+				failed = true
+				break
+			}
+		}
+		if !failed {
+			gs.log.Info("CaptureLayers: layers saved", "dir", dir, "layers", len(captured), "width", size.X, "height", size.Y)
+		}
+	}
+}
+
+// namedSpriteLayers はウィンドウ・ピクチャー・テキストのスプライトのIDとレイヤーの名前を返す
+// 呼び出し元は gs.mu のロックを保持していること
+func (gs *GraphicsSystem) namedSpriteLayers() map[int]string {
+	layers := make(map[int]string)
+	if wsm := gs.windowSpriteManager; wsm != nil {
+		wsm.mu.RLock()
+		for _, ws := range wsm.windowSprites {
+			if s := ws.GetSprite(); s != nil {
+				layers[s.ID()] = layerWindows
+			}
+		}
+		wsm.mu.RUnlock()
+	}
+	if psm := gs.pictureSpriteManager; psm != nil {
+		psm.mu.RLock()
+		for _, sprites := range psm.pictureSprites {
+			for _, ps := range sprites {
+				if s := ps.GetSprite(); s != nil {
+					layers[s.ID()] = layerWindows
+				}
+			}
+		}
+		psm.mu.RUnlock()
+	}
+	if tsm := gs.textSpriteManager; tsm != nil {
+		tsm.mu.RLock()
+		for _, sprites := range tsm.textSprites {
+			for _, ts := range sprites {
+				if s := ts.GetSprite(); s != nil {
+					layers[s.ID()] = layerText
+				}
+			}
+		}
+		tsm.mu.RUnlock()
+	}
+	return layers
+}
+
+// zBands は named に含まれない可視スプライトの Z_Path の先頭（Z帯）を返す
+func (sm *SpriteManager) zBands(named map[int]string) []int {
+	if sm == nil {
+		return nil
+	}
+	sm.mu.RLock()
+	defer sm.mu.RUnlock()
+
+	var bands []int
+	for id, s := range sm.sprites {
+		if _, ok := named[id]; ok || s.zPath == nil || !s.IsEffectivelyVisible() {
+			continue
+		}
+		bands = append(bands, zBand(s))
+	}
+	return bands
+}
+
+// zBand はスプライトの Z_Path の先頭（属するウィンドウのZ順序）を返す
+func zBand(s *Sprite) int {
+	if s.zPath == nil || len(s.zPath.path) == 0 {
+		return 0
+	}
+	return s.zPath.path[0]
+}
+
+// readScreen は img のピクセルを読み出す
+func readScreen(img *ebiten.Image) *image.RGBA {
+	rgba := image.NewRGBA(image.Rectangle{Max: img.Bounds().Size()})
+	img.ReadPixels(rgba.Pix)
+	return rgba
+}
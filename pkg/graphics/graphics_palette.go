@@ -0,0 +1,77 @@
+//go:build !nogpu
+
+// graphics_palette.go は256色表示エミュレーション（SetPalette/CyclePalette）の描画と GraphicsSystem のメソッドを提供する
+package graphics
+
+import (
+	"github.com/hajimehoshi/ebiten/v2"
+)
+
+// apply は画面全体をパレットの色に変換する
+func (p *Palette) apply(screen *ebiten.Image) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	bounds := screen.Bounds()
+	size := bounds.Dx() * bounds.Dy() * 4
+	if cap(p.pixels) < size {
+		p.pixels = make([]byte, size)
+	}
+	pix := p.pixels[:size]
+	screen.ReadPixels(pix)
+	p.quantizeLocked(pix)
+	screen.WritePixels(pix)
+}
+
+// WithPaletteEmulation は256色表示エミュレーションの有効/無効を設定する
+// 有効な場合、すべての描画（フェードを含む）の後に画面全体を256色のパレットに量子化する
+func WithPaletteEmulation(enabled bool) Option {
+	return func(gs *GraphicsSystem) {
+		gs.paletteEmulation = enabled
+	}
+}
+
+// SetPaletteColor はパレット番号 index の表示色を変更する（c は 0xRRGGBB 形式の int または color.Color）
+// 256色表示エミュレーションが無効な場合もパレットの状態は更新する
+func (gs *GraphicsSystem) SetPaletteColor(index int, c any) error {
+	paletteColor, err := fadeColorFrom(c)
+	if err != nil {
+		return err
+	}
+	gs.invalidate()
+	return gs.palette.Set(index, paletteColor)
+}
+
+// GetPaletteColor はパレット番号 index の現在の表示色を 0xRRGGBB 形式で返す
+func (gs *GraphicsSystem) GetPaletteColor(index int) (int, error) {
+	c, err := gs.palette.Get(index)
+	if err != nil {
+		return 0, err
+	}
+	return ColorToInt(c), nil
+}
+
+// CyclePalette はパレット番号 start から count 個の表示色を step だけ回転させる
+func (gs *GraphicsSystem) CyclePalette(start, count, step int) error {
+	gs.invalidate()
+	return gs.palette.Cycle(start, count, step)
+}
+
+// ResetPalette は表示色を既定のパレットに戻す
+func (gs *GraphicsSystem) ResetPalette() {
+	gs.invalidate()
+	gs.palette.Reset()
+}
+
+// IsPaletteEmulationEnabled は256色表示エミュレーションが有効かを返す
+func (gs *GraphicsSystem) IsPaletteEmulationEnabled() bool {
+	return gs.paletteEmulation
+}
+
+// drawPalette は256色表示エミュレーションが有効な場合に画面をパレットの色に変換する
+func (gs *GraphicsSystem) drawPalette(screen *ebiten.Image) {
+	if !gs.paletteEmulation {
+		return
+	}
+	gs.palette.apply(screen)
+}
@@ -0,0 +1,81 @@
+//go:build !nogpu
+
+// graphics_path.go はパスに沿ったキャストの移動（MoveAlongPath）の GraphicsSystem のメソッドを提供する
+package graphics
+
+import (
+	"fmt"
+	"math"
+	"time"
+)
+
+// castMotion はパスに沿ったキャストの移動（MoveAlongPath）の状態
+type castMotion struct {
+	path   *Path
+	easing Easing
+	frames int // 移動にかけるフレーム数
+	frame  int // 経過フレーム数
+}
+
+// position は現在のフレームでのキャストの位置を返す
+func (m *castMotion) position() (int, int) {
+	pt := m.path.PointAt(m.easing.Apply(float64(m.frame) / float64(m.frames)))
+	return int(math.Round(pt.X)), int(math.Round(pt.Y))
+}
+
+// MoveCastAlongPath はキャストを duration の間にパスの始点から終点まで動かす
+// 実行中の移動は置き換えられる。path が nil か duration が 0 の場合は移動を止め、キャストはその位置に残る。
+// 移動中のキャストの位置は毎フレーム更新されるため、CastAt などは移動中の位置を使う。
+func (gs *GraphicsSystem) MoveCastAlongPath(id int, path *Path, duration time.Duration, easing Easing) error {
+	if !ValidEasing(easing) {
+		return fmt.Errorf("invalid easing: %d", easing)
+	}
+	gs.mu.Lock()
+	defer gs.mu.Unlock()
+	gs.invalidate()
+
+	if _, err := gs.casts.GetCast(id); err != nil {
+		return err
+	}
+	if path == nil || duration <= 0 {
+		delete(gs.motions, id)
+		return nil
+	}
+	if gs.motions == nil {
+		gs.motions = make(map[int]*castMotion)
+	}
+	m := &castMotion{path: path, easing: easing, frames: durationFrames(duration)}
+	gs.motions[id] = m
+	x, y := m.position()
+	gs.moveCastTo(id, x, y)
+	gs.log.Debug("Cast path motion started", "castID", id, "points", len(path.points), "frames", m.frames, "easing", easing)
+	return nil
+}
+
+// updateMotions はパスに沿った移動を1フレーム進める
+// 終わった移動と、削除されたキャストの移動は取り除く
+// 呼び出し元は gs.mu のロックを保持していること
+func (gs *GraphicsSystem) updateMotions() {
+	for id, m := range gs.motions {
+		if _, err := gs.casts.GetCast(id); err != nil {
+			delete(gs.motions, id)
+			continue
+		}
+		m.frame = min(m.frame+1, m.frames)
+		x, y := m.position()
+		gs.moveCastTo(id, x, y)
+		if m.frame >= m.frames {
+			delete(gs.motions, id)
+			gs.publishCast(TopicCastMove, id)
+		}
+	}
+}
+
+// moveCastTo はキャストの位置を変更する
+// 呼び出し元は gs.mu のロックを保持していること
+func (gs *GraphicsSystem) moveCastTo(id, x, y int) {
+	if err := gs.casts.MoveCast(id, WithCastPosition(x, y)); err != nil {
+		return
+	}
+	gs.updateCastSprite(id)
+}
@@ -0,0 +1,75 @@
+//go:build !nogpu
+
+// graphics_snapshot.go は画面・キャストの画像の書き出し（SaveSnapshot）の GraphicsSystem のメソッドを提供する
+package graphics
+
+import (
+	"fmt"
+	"image"
+
+	"github.com/hajimehoshi/ebiten/v2"
+)
+
+// SaveSnapshot writes the composited screen (castID SnapshotScreen) or the image of
+// a cast to a PNG file at path, creating its directory if needed. The image is
+// captured and written when the next frame is drawn, since the GPU images can only
+// be read there, so write errors are logged rather than returned.
+func (gs *GraphicsSystem) SaveSnapshot(castID int, path string) error {
+	if castID != SnapshotScreen {
+		gs.mu.RLock()
+		_, err := gs.casts.GetCast(castID)
+		gs.mu.RUnlock()
+		if err != nil {
+			return err
+		}
+	}
+
+	gs.snapshotMu.Lock()
+	defer gs.snapshotMu.Unlock()
+	if len(gs.snapshots) >= maxPendingSnapshots {
+		return fmt.Errorf("too many pending snapshots (%d), wait for the next frame", maxPendingSnapshots)
+	}
+	gs.snapshots = append(gs.snapshots, snapshotRequest{castID: castID, path: path})
+	gs.invalidate()
+	return nil
+}
+
+// captureSnapshots は SaveSnapshot で要求された画像を screen またはキャストから読み出して書き出す
+// 呼び出し元は gs.mu の読み取りロックを保持していること
+func (gs *GraphicsSystem) captureSnapshots(screen *ebiten.Image) {
+	gs.snapshotMu.Lock()
+	requests := gs.snapshots
+	gs.snapshots = nil
+	gs.snapshotMu.Unlock()
+
+	for _, req := range requests {
+		src := screen
+		if req.castID != SnapshotScreen {
+			src = gs.castSnapshotImage(req.castID)
+			if src == nil {
+				gs.log.Warn("SaveSprite: cast has no image", "castID", req.castID, "path", req.path)
+				continue
+			}
+		}
+		size := src.Bounds().Size()
+		img := image.NewRGBA(image.Rectangle{Max: size})
+		src.ReadPixels(img.Pix)
+		if err := writePNG(req.path, img); err != nil {
+			gs.log.Error("SaveSprite failed", "path", req.path, "error", err)
+			continue
+		}
+		gs.log.Info("SaveSprite: image saved", "path", req.path, "castID", req.castID, "width", size.X, "height", size.Y)
+	}
+}
+
+// castSnapshotImage はキャストのスプライトの画像（透明色を処理した画像）を返す
+func (gs *GraphicsSystem) castSnapshotImage(castID int) *ebiten.Image {
+	if gs.castSpriteManager == nil {
+		return nil
+	}
+	cs := gs.castSpriteManager.GetCastSprite(castID)
+	if cs == nil || cs.GetSprite() == nil {
+		return nil
+	}
+	return cs.GetSprite().Image()
+}
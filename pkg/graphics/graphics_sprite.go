@@ -1,3 +1,5 @@
+//go:build !nogpu
+
 // graphics_sprite.go はスプライト管理メソッドを提供する
 // PutCast, MoveCast, DelCast、スプライト収集・更新メソッドを含む
 package graphics
@@ -11,8 +13,10 @@ import (
 // スプライトシステム要件 8.1: キャストをスプライトとして作成する
 // 要件 9.2: ピクチャ内にキャストが配置されたとき、キャストをピクチャの子スプライトとして管理する
 func (gs *GraphicsSystem) PutCast(srcPicID, dstPicID, x, y, srcX, srcY, w, h int) (int, error) {
+	gs.awaitPictures(srcPicID, dstPicID)
 	gs.mu.Lock()
 	defer gs.mu.Unlock()
+	gs.invalidate()
 
 	// 配置先ピクチャーからウインドウIDを逆引き
 	winID, err := gs.windows.GetWinByPicID(dstPicID)
@@ -47,12 +51,14 @@ func (gs *GraphicsSystem) PutCast(srcPicID, dstPicID, x, y, srcX, srcY, w, h int
 				if cs != nil && parentSprite != nil {
 					parentSprite.AddChild(cs.GetSprite())
 				}
+				gs.attachCastHires(cs, srcPic)
 				gs.log.Debug("PutCast: created CastSprite", "castID", castID, "srcPicID", srcPicID, "dstPicID", dstPicID, "winID", winID, "hasParent", parentSprite != nil)
 			}
 		}
 	}
 
 	gs.dumpSpriteState(fmt.Sprintf("PutCast(castID=%d, srcPicID=%d, dstPicID=%d)", castID, srcPicID, dstPicID))
+	gs.publishCast(TopicCastPut, castID)
 
 	return castID, nil
 }
@@ -60,8 +66,10 @@ func (gs *GraphicsSystem) PutCast(srcPicID, dstPicID, x, y, srcX, srcY, w, h int
 // PutCastWithTransColor places a cast on a picture with transparent color
 // スプライトシステム要件 8.1, 8.4: キャストをスプライトとして作成し、透明色処理をサポートする
 func (gs *GraphicsSystem) PutCastWithTransColor(srcPicID, dstPicID, x, y, srcX, srcY, w, h int, transColor color.Color) (int, error) {
+	gs.awaitPictures(srcPicID, dstPicID)
 	gs.mu.Lock()
 	defer gs.mu.Unlock()
+	gs.invalidate()
 
 	winID, err := gs.windows.GetWinByPicID(dstPicID)
 	if err != nil {
@@ -95,12 +103,14 @@ func (gs *GraphicsSystem) PutCastWithTransColor(srcPicID, dstPicID, x, y, srcX,
 				if cs != nil && parentSprite != nil {
 					parentSprite.AddChild(cs.GetSprite())
 				}
+				gs.attachCastHires(cs, srcPic)
 				gs.log.Debug("PutCastWithTransColor: created CastSprite", "castID", castID, "srcPicID", srcPicID, "dstPicID", dstPicID, "winID", winID, "hasParent", parentSprite != nil)
 			}
 		}
 	}
 
 	gs.dumpSpriteState(fmt.Sprintf("PutCastWithTransColor(castID=%d, srcPicID=%d, dstPicID=%d)", castID, srcPicID, dstPicID))
+	gs.publishCast(TopicCastPut, castID)
 
 	return castID, nil
 }
@@ -110,6 +120,7 @@ func (gs *GraphicsSystem) PutCastWithTransColor(srcPicID, dstPicID, x, y, srcX,
 func (gs *GraphicsSystem) MoveCast(id int, opts ...any) error {
 	gs.mu.Lock()
 	defer gs.mu.Unlock()
+	gs.invalidate()
 
 	castOpts := make([]CastOption, 0)
 
@@ -150,6 +161,7 @@ func (gs *GraphicsSystem) MoveCast(id int, opts ...any) error {
 	}
 
 	gs.updateCastSprite(id)
+	gs.publishCast(TopicCastMove, id)
 
 	return nil
 }
@@ -158,12 +170,14 @@ func (gs *GraphicsSystem) MoveCast(id int, opts ...any) error {
 func (gs *GraphicsSystem) MoveCastWithOptions(id int, opts ...CastOption) error {
 	gs.mu.Lock()
 	defer gs.mu.Unlock()
+	gs.invalidate()
 
 	if err := gs.casts.MoveCast(id, opts...); err != nil {
 		return err
 	}
 
 	gs.updateCastSprite(id)
+	gs.publishCast(TopicCastMove, id)
 
 	return nil
 }
@@ -196,6 +210,7 @@ func (gs *GraphicsSystem) updateCastSprite(castID int) {
 		srcPic, err := gs.pictures.GetPicWithoutLock(cast.PicID)
 		if err == nil && srcPic != nil && srcPic.Image != nil {
 			cs.RebuildCache(srcPic.Image)
+			gs.attachCastHires(cs, srcPic)
 		}
 	}
 
@@ -207,6 +222,7 @@ func (gs *GraphicsSystem) updateCastSprite(castID int) {
 func (gs *GraphicsSystem) DelCast(id int) error {
 	gs.mu.Lock()
 	defer gs.mu.Unlock()
+	gs.invalidate()
 
 	if gs.castSpriteManager != nil {
 		cs := gs.castSpriteManager.GetCastSprite(id)
@@ -222,7 +238,103 @@ func (gs *GraphicsSystem) DelCast(id int) error {
 		gs.castSpriteManager.RemoveCastSprite(id)
 	}
 
-	return gs.casts.DelCast(id)
+	if err := gs.casts.DelCast(id); err != nil {
+		return err
+	}
+	gs.bus.Publish(TopicCastDelete, map[string]any{"castID": id})
+	return nil
+}
+
+// BringCastToFront はキャストを同じウィンドウのキャストの中で最前面に移動する
+func (gs *GraphicsSystem) BringCastToFront(id int) error {
+	return gs.restackCast(id, true)
+}
+
+// SendCastToBack はキャストを同じウィンドウのキャストの中で最背面に移動する
+// ウィンドウの背景のピクチャーより奥には移動しない
+func (gs *GraphicsSystem) SendCastToBack(id int) error {
+	return gs.restackCast(id, false)
+}
+
+// restackCast はキャストのZ順序を変更し、キャストスプライトのZ_Pathに反映する
+// キャストスプライトは同じ親（配置先のピクチャー）を持つキャストスプライトの間でのみ並べ替える
+func (gs *GraphicsSystem) restackCast(id int, toFront bool) error {
+	gs.mu.Lock()
+	defer gs.mu.Unlock()
+	gs.invalidate()
+
+	order, err := gs.casts.Restack(id, toFront)
+	if err != nil {
+		return err
+	}
+	if gs.castSpriteManager == nil {
+		return nil
+	}
+	target := gs.castSpriteManager.GetCastSprite(id)
+	if target == nil {
+		return nil
+	}
+
+	parent := target.GetSprite().Parent()
+	spriteIDs := make([]int, 0, len(order))
+	for _, castID := range order {
+		cs := gs.castSpriteManager.GetCastSprite(castID)
+		if cs != nil && cs.GetSprite().Parent() == parent {
+			spriteIDs = append(spriteIDs, cs.GetSprite().ID())
+		}
+	}
+	if err := gs.spriteManager.RestackSiblings(spriteIDs); err != nil {
+		return err
+	}
+	gs.log.Debug("Cast restacked", "castID", id, "toFront", toFront)
+	gs.bus.Publish(TopicCastRestack, map[string]any{"castID": id, "toFront": toFront})
+	return nil
+}
+
+// CastAt は仮想デスクトップ座標 (x, y) にある最前面のキャストIDを返す
+// 手前のウィンドウから順に調べ、ウィンドウ内ではZ順序の大きいキャストを優先する。
+// 手前のウィンドウのコンテンツ領域に隠れたキャストはヒットしない。
+// キャストが見つからない場合は -1 を返す（OnSpriteClickのヒット判定に使用）
+func (gs *GraphicsSystem) CastAt(x, y int) int {
+	gs.mu.RLock()
+	defer gs.mu.RUnlock()
+
+	cc := GetDefaultCoordinateConverter()
+	windows := gs.windows.GetWindowsOrdered()
+	for i := len(windows) - 1; i >= 0; i-- {
+		win := windows[i]
+		if !win.Visible {
+			continue
+		}
+
+		// ウィンドウのコンテンツ領域外であれば次のウィンドウへ
+		contentX, contentY := cc.WindowToContent(win.X, win.Y)
+		width, height := win.Width, win.Height
+		if width == 0 {
+			width = gs.pictures.PicWidth(win.PicID)
+		}
+		if height == 0 {
+			height = gs.pictures.PicHeight(win.PicID)
+		}
+		if x < contentX || y < contentY || x >= contentX+width || y >= contentY+height {
+			continue
+		}
+
+		casts := gs.casts.GetCastsByWindow(win.ID)
+		for j := len(casts) - 1; j >= 0; j-- {
+			cast := casts[j]
+			if !cast.Visible {
+				continue
+			}
+			castX, castY := cc.CalculateDrawPositionInt(win, cast.X, cast.Y)
+			castW, castH := gs.castDrawnSize(cast)
+			if x >= castX && y >= castY && x < castX+castW && y < castY+castH {
+				return cast.ID
+			}
+		}
+		return -1
+	}
+	return -1
 }
 
 // collectAllSpritesForWindow はウィンドウに属するすべてのスプライトを収集する
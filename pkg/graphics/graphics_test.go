@@ -1,3 +1,5 @@
+//go:build !nogpu
+
 package graphics
 
 import (
@@ -87,6 +89,45 @@ func TestGraphicsSystemDraw(t *testing.T) {
 	gs.Draw(screen)
 }
 
+func TestGraphicsSystemNeedsRedraw(t *testing.T) {
+	gs := NewGraphicsSystem("")
+	screen := ebiten.NewImage(1024, 768)
+
+	if !gs.NeedsRedraw() {
+		t.Error("first frame should need a redraw")
+	}
+	gs.Draw(screen)
+	_ = gs.Update()
+	if gs.NeedsRedraw() {
+		t.Error("static scene should not need a redraw")
+	}
+
+	// ピクチャーへの描画は画面を変更する
+	picID, _ := gs.CreatePic(100, 100)
+	if err := gs.DrawRectOnPic(picID, 0, 0, 10, 10, 0); err != nil {
+		t.Fatalf("DrawRectOnPic failed: %v", err)
+	}
+	if !gs.NeedsRedraw() {
+		t.Error("drawing on a picture should need a redraw")
+	}
+	gs.Draw(screen)
+
+	// 表示調整の変更も画面を変更する
+	_ = gs.SetGamma(2)
+	if !gs.NeedsRedraw() {
+		t.Error("changing the gamma should need a redraw")
+	}
+	gs.Draw(screen)
+
+	// フェードが進んだフレームは描き直す
+	_ = gs.Fade(true, 0, 0, nil)
+	gs.Draw(screen)
+	_ = gs.Update()
+	if !gs.NeedsRedraw() {
+		t.Error("a fade step should need a redraw")
+	}
+}
+
 func TestGraphicsSystemShutdown(t *testing.T) {
 	gs := NewGraphicsSystem("")
 
@@ -1,3 +1,5 @@
+//go:build !nogpu
+
 // graphics_window.go はウィンドウ管理メソッドを提供する
 // OpenWin, CloseWin, MoveWin, CloseWinAll、
 // ウィンドウオプション解析、ウィンドウ情報取得、タイトル設定を含む
@@ -13,6 +15,7 @@ import (
 func (gs *GraphicsSystem) OpenWin(picID int, opts ...any) (int, error) {
 	gs.mu.Lock()
 	defer gs.mu.Unlock()
+	gs.invalidate()
 
 	// Convert any options to WinOption
 	winOpts, hasPosition := gs.parseWinOptions(opts)
@@ -31,11 +34,12 @@ func (gs *GraphicsSystem) OpenWin(picID int, opts ...any) (int, error) {
 	}
 
 	// ウィンドウのサイズを取得（設定されていない場合はピクチャーのサイズを使用）
+	// ウィンドウはピクチャーの画像を参照するだけなので、ストリーミング読み込みのデコードは待たない
 	width := win.Width
 	height := win.Height
 	var pic *Picture
 	if width <= 0 || height <= 0 {
-		pic, err = gs.pictures.GetPicWithoutLock(picID)
+		pic, err = gs.pictures.lookupPicWithoutLock(picID)
 		if err == nil {
 			width = pic.Width
 			height = pic.Height
@@ -44,7 +48,7 @@ func (gs *GraphicsSystem) OpenWin(picID int, opts ...any) (int, error) {
 			height = 480
 		}
 	} else {
-		pic, _ = gs.pictures.GetPicWithoutLock(picID)
+		pic, _ = gs.pictures.lookupPicWithoutLock(picID)
 	}
 
 	// 要件 3.1.1: 位置指定がない場合はセンタリングする
@@ -95,6 +99,7 @@ func (gs *GraphicsSystem) OpenWin(picID int, opts ...any) (int, error) {
 			if ps != nil {
 				ps.GetSprite().SetParent(ws.GetSprite())
 				ws.AddChild(ps.GetSprite())
+				gs.attachPictureHires(picID)
 
 				if ws.GetSprite().GetZPath() != nil {
 					localZOrder := gs.spriteManager.GetZOrderCounter().GetNext(ws.GetSprite().ID())
@@ -116,6 +121,7 @@ func (gs *GraphicsSystem) OpenWin(picID int, opts ...any) (int, error) {
 	gs.log.Debug("OpenWin: window opened", "winID", winID, "width", width, "height", height)
 
 	gs.dumpSpriteState(fmt.Sprintf("OpenWin(winID=%d, picID=%d)", winID, picID))
+	gs.bus.Publish(TopicWindowOpen, map[string]any{"winID": winID, "picID": picID, "x": win.X, "y": win.Y, "width": width, "height": height})
 
 	return winID, nil
 }
@@ -162,6 +168,7 @@ func (gs *GraphicsSystem) parseWinOptions(opts []any) ([]WinOption, bool) {
 func (gs *GraphicsSystem) MoveWin(id int, opts ...any) error {
 	gs.mu.Lock()
 	defer gs.mu.Unlock()
+	gs.invalidate()
 
 	winOpts := make([]WinOption, 0)
 
@@ -221,7 +228,7 @@ func (gs *GraphicsSystem) updateWindowSpriteForMoveWin(winID, newPicID int) {
 		return
 	}
 
-	newPic, err := gs.pictures.GetPicWithoutLock(newPicID)
+	newPic, err := gs.pictures.lookupPicWithoutLock(newPicID)
 	if err != nil {
 		gs.log.Warn("MoveWin: new picture not found", "picID", newPicID, "error", err)
 		return
@@ -283,6 +290,7 @@ func (gs *GraphicsSystem) updateWindowSpriteForMoveWin(winID, newPicID int) {
 func (gs *GraphicsSystem) CloseWin(id int) error {
 	gs.mu.Lock()
 	defer gs.mu.Unlock()
+	gs.invalidate()
 
 	if gs.castSpriteManager != nil {
 		gs.castSpriteManager.RemoveCastSpritesByWindow(id)
@@ -291,18 +299,28 @@ func (gs *GraphicsSystem) CloseWin(id int) error {
 
 	gs.casts.DeleteCastsByWindow(id)
 
+	// ウィンドウのピクチャーに配置したスプライトプールもキャストと同様に削除する
+	if win, err := gs.windows.GetWin(id); err == nil {
+		gs.spritePools.RemovePoolsByPicID(win.PicID)
+	}
+
 	if gs.windowSpriteManager != nil {
 		gs.windowSpriteManager.RemoveWindowSprite(id)
 		gs.log.Debug("CloseWin: deleted WindowSprite", "winID", id)
 	}
 
-	return gs.windows.CloseWin(id)
+	if err := gs.windows.CloseWin(id); err != nil {
+		return err
+	}
+	gs.bus.Publish(TopicWindowClose, map[string]any{"winID": id})
+	return nil
 }
 
 // CloseWinAll closes all windows
 func (gs *GraphicsSystem) CloseWinAll() {
 	gs.mu.Lock()
 	defer gs.mu.Unlock()
+	gs.invalidate()
 
 	windows := gs.windows.GetWindowsOrdered()
 	for _, win := range windows {
@@ -327,14 +345,55 @@ func (gs *GraphicsSystem) CloseWinAll() {
 		gs.log.Debug("CloseWinAll: deleted all TextSprites")
 	}
 
+	gs.spritePools.Clear()
+
 	gs.windows.CloseWinAll()
 	gs.log.Debug("CloseWinAll: deleted all WindowLayerSets", "windowCount", len(windows))
 }
 
+// BringWinToFront はウィンドウを最前面に移動する
+// 描画順はZ_Pathの先頭要素（ウィンドウのZ順序）で決まるため、ウィンドウ内のスプライトもまとめて移動する
+func (gs *GraphicsSystem) BringWinToFront(id int) error {
+	return gs.restackWindow(id, true)
+}
+
+// SendWinToBack はウィンドウを最背面に移動する
+func (gs *GraphicsSystem) SendWinToBack(id int) error {
+	return gs.restackWindow(id, false)
+}
+
+// restackWindow はウィンドウのZ順序を変更し、ウィンドウスプライトと子スプライトのZ_Pathに反映する
+func (gs *GraphicsSystem) restackWindow(id int, toFront bool) error {
+	gs.mu.Lock()
+	defer gs.mu.Unlock()
+	gs.invalidate()
+
+	var zOrder int
+	var err error
+	if toFront {
+		zOrder, err = gs.windows.BringToFront(id)
+	} else {
+		zOrder, err = gs.windows.SendToBack(id)
+	}
+	if err != nil {
+		return err
+	}
+
+	if gs.windowSpriteManager != nil {
+		if ws := gs.windowSpriteManager.GetWindowSprite(id); ws != nil {
+			ws.UpdateWindowZOrder(zOrder, gs.spriteManager)
+		}
+	}
+	gs.log.Debug("Window restacked", "winID", id, "toFront", toFront, "zOrder", zOrder)
+	gs.bus.Publish(TopicWindowRestack, map[string]any{"winID": id, "toFront": toFront, "zOrder": zOrder})
+	return nil
+}
+
 // CapTitle sets the caption of a window
 func (gs *GraphicsSystem) CapTitle(id int, title string) error {
 	gs.mu.Lock()
 	defer gs.mu.Unlock()
+	gs.invalidate()
 	return gs.windows.CapTitle(id, title)
 }
 
@@ -342,6 +401,7 @@ func (gs *GraphicsSystem) CapTitle(id int, title string) error {
 func (gs *GraphicsSystem) CapTitleAll(title string) {
 	gs.mu.Lock()
 	defer gs.mu.Unlock()
+	gs.invalidate()
 	gs.windows.CapTitleAll(title)
 }
 
@@ -3,10 +3,18 @@
 package graphics
 
 import (
+	"errors"
 	"fmt"
+	"image"
 	"image/color"
 	"log/slog"
+	"math"
+	"sort"
 	"sync"
+	"time"
+
+	"github.com/zurustar/son-et/pkg/eventbus"
+	"github.com/zurustar/son-et/pkg/fileutil"
 )
 
 // OperationRecord は描画操作の記録を表す
@@ -17,12 +25,14 @@ type OperationRecord struct {
 
 // HeadlessGraphicsSystem はヘッドレスモード用のダミー描画システム
 // 要件 10.4: ヘッドレスモードが有効のとき、描画操作をログに記録するのみで実際の描画を行わない
+// WithHeadlessRendering を指定した場合はピクチャーの画素を保持し、CPUで描画する（headless_render.go）
 type HeadlessGraphicsSystem struct {
 	// ピクチャー管理（メモリ上のデータのみ）
 	pictures    map[int]*HeadlessPicture
 	nextPicID   int
 	maxPictures int
 	pictureMu   sync.RWMutex
+	images      *imageTracker // 作成した文と表示の状況（リークの報告用）
 
 	// ウィンドウ管理
 	windows        map[int]*HeadlessWindow
@@ -38,12 +48,18 @@ type HeadlessGraphicsSystem struct {
 	maxCasts   int
 	castMu     sync.RWMutex
 
+	// スプライトプール管理（粒子の状態のみ）
+	pools      map[int]*particleSet
+	nextPoolID int
+	poolMu     sync.Mutex
+
 	// 描画状態
 	paintColor color.Color
 	lineSize   int
 	textColor  color.Color
 	bgColor    color.Color
 	backMode   int
+	direction  TextDirection
 	fontName   string
 	fontSize   int
 
@@ -51,6 +67,36 @@ type HeadlessGraphicsSystem struct {
 	virtualWidth  int
 	virtualHeight int
 
+	// 256色表示エミュレーションのパレット（描画はしないが状態は保持する）
+	palette *Palette
+
+	// 表示調整（描画はしないが状態は保持する）
+	display *DisplayAdjuster
+
+	// マウスカーソル（描画はしないが状態は保持する）
+	cursor          *Cursor
+	cursorClick     *CursorClick
+	sysCursorHidden bool
+	cursorMu        sync.Mutex
+
+	// OSのウィンドウの設定（ウィンドウはないが状態は保持する）
+	appWindow   AppWindowSettings
+	appWindowMu sync.Mutex
+
+	// カメラ（描画はしないが状態は保持する、nil の場合は仮想デスクトップ全体）
+	camera   *Camera
+	cameraMu sync.Mutex
+
+	// ウィンドウ・キャストの操作を発行するイベントバス（nil の場合は発行しない）
+	bus *eventbus.Bus
+
+	// ソフトウェア描画で画像ファイルを読み込むファイルシステム（nil の場合は描画しない、WithHeadlessRendering）
+	renderFS fileutil.FileSystem
+
+	// 画面フェード（完了コールバックを呼び出すタイマー）
+	fadeTimer *time.Timer
+	fadeMu    sync.Mutex
+
 	// ログ
 	log              *slog.Logger
 	logOperations    bool // 描画操作をログに記録するかどうか
@@ -59,11 +105,19 @@ type HeadlessGraphicsSystem struct {
 	historyMu        sync.RWMutex
 }
 
+// 半角カタカナの範囲（TextExtentで半角として扱う）
+const (
+	halfwidthKanaFirst = 0xFF61
+	halfwidthKanaLast  = 0xFF9F
+)
+
 // HeadlessPicture はヘッドレスモード用のピクチャー
 type HeadlessPicture struct {
 	ID     int
 	Width  int
 	Height int
+
+	pixels *image.RGBA // ソフトウェア描画の画素（WithHeadlessRendering の場合のみ、nilの場合は持たない）
 }
 
 // HeadlessWindow はヘッドレスモード用のウィンドウ
@@ -79,6 +133,7 @@ type HeadlessWindow struct {
 	Caption string
 	Visible bool
 	ZOrder  int
+	Mask    *Mask // SetWinMask で設定されたマスク（nilの場合はなし）
 }
 
 // HeadlessCast はヘッドレスモード用のキャスト
@@ -93,6 +148,16 @@ type HeadlessCast struct {
 	Height  int
 	Visible bool
 	ZOrder  int
+	Mask    *Mask            // SetCastMask で設定されたマスク（nilの場合はなし）
+	Shadow  *Shadow          // SetCastShadow で設定された影（nilの場合はなし）
+	Outline *Outline         // SetCastOutline で設定された縁取り（nilの場合はなし）
+	Source  *image.Rectangle // SetCastSourceRect で設定された描画する範囲（nilの場合は画像全体）
+	Slice   *NineSlice       // SetCastNineSlice で設定された9分割の伸縮（nilの場合はなし）
+
+	// ソフトウェア描画で使う描画元・配置先のピクチャーと透明色（nilの場合は透明色なし）
+	srcPicID   int
+	dstPicID   int
+	transColor color.Color
 }
 
 // HeadlessOption は HeadlessGraphicsSystem のオプションを設定する関数型
@@ -120,6 +185,16 @@ func WithLogOperations(enabled bool) HeadlessOption {
 	}
 }
 
+// WithHeadlessSandbox はサンドボックスモードのキャスト数の制限を設定する
+// ヘッドレスモードは（ソフトウェア描画を除き）ファイルを読まず画像も確保しないため、キャスト数のみを通常モードと揃える
+func WithHeadlessSandbox(enabled bool) HeadlessOption {
+	return func(hgs *HeadlessGraphicsSystem) {
+		if enabled {
+			hgs.maxCasts = SandboxMaxCasts
+		}
+	}
+}
+
 // WithRecordHistory は操作履歴の記録を有効/無効にする
 func WithRecordHistory(enabled bool) HeadlessOption {
 	return func(hgs *HeadlessGraphicsSystem) {
@@ -140,19 +215,24 @@ func NewHeadlessGraphicsSystem(opts ...HeadlessOption) *HeadlessGraphicsSystem {
 		casts:            make(map[int]*HeadlessCast),
 		nextCastID:       0,
 		maxCasts:         1024, // 要件 9.7
+		pools:            make(map[int]*particleSet),
+		nextPoolID:       1,
 		paintColor:       color.RGBA{255, 255, 255, 255},
 		lineSize:         1,
 		textColor:        color.RGBA{255, 255, 255, 255},
 		bgColor:          color.RGBA{0, 0, 0, 255},
 		backMode:         0,
 		fontName:         "default",
-		fontSize:         12,
+		fontSize:         defaultFontSize,
 		virtualWidth:     1024,
 		virtualHeight:    768,
 		log:              slog.Default(),
 		logOperations:    true,
 		recordHistory:    false,
 		operationHistory: make([]OperationRecord, 0),
+		palette:          NewPalette(),
+		display:          NewDisplayAdjuster(),
+		images:           newImageTracker(),
 	}
 
 	// オプションを適用
@@ -227,6 +307,13 @@ func (hgs *HeadlessGraphicsSystem) Draw(screen any) {
 
 // Shutdown はGraphicsSystemをシャットダウンする
 func (hgs *HeadlessGraphicsSystem) Shutdown() {
+	hgs.fadeMu.Lock()
+	if hgs.fadeTimer != nil {
+		hgs.fadeTimer.Stop()
+		hgs.fadeTimer = nil
+	}
+	hgs.fadeMu.Unlock()
+
 	hgs.log.Info("HeadlessGraphicsSystem shutdown")
 }
 
@@ -249,7 +336,7 @@ func (hgs *HeadlessGraphicsSystem) GetWindowCount() int {
 
 // ===== Picture Management =====
 
-// LoadPic はピクチャーを読み込む（ヘッドレスモードではダミーピクチャーを作成）
+// LoadPic はピクチャーを読み込む（ソフトウェア描画が無効の場合はダミーピクチャーを作成）
 func (hgs *HeadlessGraphicsSystem) LoadPic(filename string) (int, error) {
 	hgs.pictureMu.Lock()
 	defer hgs.pictureMu.Unlock()
@@ -260,21 +347,54 @@ func (hgs *HeadlessGraphicsSystem) LoadPic(filename string) (int, error) {
 		return -1, fmt.Errorf("resource limit reached: max %d pictures", hgs.maxPictures)
 	}
 
-	// ダミーピクチャーを作成（デフォルトサイズ）
-	id := hgs.nextPicID
-	hgs.nextPicID++
-
+	// ソフトウェア描画では画像を読み込み、それ以外はダミーピクチャーを作成（デフォルトサイズ）
 	pic := &HeadlessPicture{
-		ID:     id,
 		Width:  640, // デフォルトサイズ
 		Height: 480,
 	}
+	if hgs.rendering() {
+		pixels, err := hgs.decodePic(filename)
+		if err != nil {
+			hgs.log.Error("LoadPic: failed to load image", "filename", filename, "error", err)
+			return -1, err
+		}
+		pic.pixels = pixels
+		pic.Width, pic.Height = pixels.Bounds().Dx(), pixels.Bounds().Dy()
+	}
+
+	id := hgs.nextPicID
+	hgs.nextPicID++
+	pic.ID = id
 	hgs.pictures[id] = pic
+	hgs.images.add(id, pic.Width, pic.Height, time.Now())
 
 	hgs.logOperation("LoadPic", "filename", filename, "picID", id)
 	return id, nil
 }
 
+// LoadPicFrames はアニメーションGIFのフレームをピクチャーとして読み込む
+// ヘッドレスモードではフレームを分解しないため、LoadPic で読み込んだピクチャーの1フレームとして扱う
+func (hgs *HeadlessGraphicsSystem) LoadPicFrames(filename string) ([]int, []int, error) {
+	id, err := hgs.LoadPic(filename)
+	if err != nil {
+		return nil, nil, err
+	}
+	return []int{id}, []int{0}, nil
+}
+
+// LoadPicAsync はピクチャーを読み込む（ヘッドレスモードでは LoadPic で読み込むため、すぐに完了する）
+// 通常モードと同じく onDone は別の goroutine から呼び出す
+func (hgs *HeadlessGraphicsSystem) LoadPicAsync(filename string, onDone func(picID int, err error)) (int, error) {
+	id, err := hgs.LoadPic(filename)
+	if err != nil {
+		return id, err
+	}
+	if onDone != nil {
+		go onDone(id, nil)
+	}
+	return id, nil
+}
+
 // CreatePic は空のピクチャーを作成する
 func (hgs *HeadlessGraphicsSystem) CreatePic(width, height int) (int, error) {
 	hgs.pictureMu.Lock()
@@ -293,8 +413,10 @@ func (hgs *HeadlessGraphicsSystem) CreatePic(width, height int) (int, error) {
 		ID:     id,
 		Width:  width,
 		Height: height,
+		pixels: hgs.blankPixels(width, height),
 	}
 	hgs.pictures[id] = pic
+	hgs.images.add(id, pic.Width, pic.Height, time.Now())
 
 	hgs.logOperation("CreatePic", "width", width, "height", height, "picID", id)
 	return id, nil
@@ -325,8 +447,10 @@ func (hgs *HeadlessGraphicsSystem) CreatePicFrom(srcID int) (int, error) {
 		ID:     id,
 		Width:  srcPic.Width,
 		Height: srcPic.Height,
+		pixels: hgs.blankPixels(srcPic.Width, srcPic.Height),
 	}
 	hgs.pictures[id] = pic
+	hgs.images.add(id, pic.Width, pic.Height, time.Now())
 
 	hgs.logOperation("CreatePicFrom", "srcID", srcID, "picID", id)
 	return id, nil
@@ -365,8 +489,10 @@ func (hgs *HeadlessGraphicsSystem) CreatePicWithSize(srcID, width, height int) (
 		ID:     id,
 		Width:  width,
 		Height: height,
+		pixels: hgs.blankPixels(width, height),
 	}
 	hgs.pictures[id] = pic
+	hgs.images.add(id, pic.Width, pic.Height, time.Now())
 
 	hgs.logOperation("CreatePicWithSize", "srcID", srcID, "width", width, "height", height, "picID", id)
 	return id, nil
@@ -383,6 +509,7 @@ func (hgs *HeadlessGraphicsSystem) DelPic(id int) error {
 	}
 
 	delete(hgs.pictures, id)
+	hgs.images.remove(id)
 	hgs.logOperation("DelPic", "picID", id)
 	return nil
 }
@@ -413,13 +540,14 @@ func (hgs *HeadlessGraphicsSystem) PicHeight(id int) int {
 
 // ===== Picture Transfer =====
 
-// MovePic はピクチャー間で画像を転送する（ヘッドレスモードではログのみ）
+// MovePic はピクチャー間で画像を転送する（ソフトウェア描画が無効の場合はログのみ）
 func (hgs *HeadlessGraphicsSystem) MovePic(srcID, srcX, srcY, width, height, dstID, dstX, dstY, mode int) error {
 	hgs.logOperation("MovePic",
 		"srcID", srcID, "srcX", srcX, "srcY", srcY,
 		"width", width, "height", height,
 		"dstID", dstID, "dstX", dstX, "dstY", dstY,
 		"mode", mode)
+	hgs.renderMovePic(srcID, srcX, srcY, width, height, dstID, dstX, dstY, mode)
 	return nil
 }
 
@@ -430,6 +558,7 @@ func (hgs *HeadlessGraphicsSystem) MovePicWithSpeed(srcID, srcX, srcY, width, he
 		"width", width, "height", height,
 		"dstID", dstID, "dstX", dstX, "dstY", dstY,
 		"mode", mode, "speed", speed)
+	hgs.renderMovePic(srcID, srcX, srcY, width, height, dstID, dstX, dstY, mode)
 	return nil
 }
 
@@ -440,6 +569,7 @@ func (hgs *HeadlessGraphicsSystem) MoveSPic(srcID, srcX, srcY, srcW, srcH, dstID
 		"srcW", srcW, "srcH", srcH,
 		"dstID", dstID, "dstX", dstX, "dstY", dstY,
 		"dstW", dstW, "dstH", dstH)
+	hgs.renderScaled(srcID, srcX, srcY, srcW, srcH, dstID, dstX, dstY, dstW, dstH)
 	return nil
 }
 
@@ -450,6 +580,9 @@ func (hgs *HeadlessGraphicsSystem) TransPic(srcID, srcX, srcY, width, height, ds
 		"width", width, "height", height,
 		"dstID", dstID, "dstX", dstX, "dstY", dstY,
 		"transColor", transColor)
+	if c, ok := colorFromAny(transColor); ok {
+		hgs.renderTransfer(srcID, srcX, srcY, width, height, dstID, dstX, dstY, c, false)
+	}
 	return nil
 }
 
@@ -459,6 +592,7 @@ func (hgs *HeadlessGraphicsSystem) ReversePic(srcID, srcX, srcY, width, height,
 		"srcID", srcID, "srcX", srcX, "srcY", srcY,
 		"width", width, "height", height,
 		"dstID", dstID, "dstX", dstX, "dstY", dstY)
+	hgs.renderTransfer(srcID, srcX, srcY, width, height, dstID, dstX, dstY, nil, true)
 	return nil
 }
 
@@ -495,6 +629,12 @@ func (hgs *HeadlessGraphicsSystem) OpenWin(picID int, opts ...any) (int, error)
 	}
 	hgs.nextZOrder++
 
+	// サイズの指定がない場合は通常モードと同じくピクチャー全体を表示する
+	if pic, ok := hgs.pictures[picID]; ok {
+		win.Width = pic.Width
+		win.Height = pic.Height
+	}
+
 	// オプションを解析
 	hasPosition := false
 	if len(opts) >= 2 {
@@ -551,6 +691,7 @@ func (hgs *HeadlessGraphicsSystem) OpenWin(picID int, opts ...any) (int, error)
 	hgs.windows[id] = win
 
 	hgs.logOperation("OpenWin", "picID", picID, "winID", id, "opts", opts)
+	hgs.bus.Publish(TopicWindowOpen, map[string]any{"winID": id, "picID": picID, "x": win.X, "y": win.Y, "width": win.Width, "height": win.Height})
 	return id, nil
 }
 
@@ -622,6 +763,7 @@ func (hgs *HeadlessGraphicsSystem) CloseWin(id int) error {
 	hgs.castMu.Unlock()
 
 	hgs.logOperation("CloseWin", "winID", id)
+	hgs.bus.Publish(TopicWindowClose, map[string]any{"winID": id})
 	return nil
 }
 
@@ -712,6 +854,116 @@ func (hgs *HeadlessGraphicsSystem) GetWinByPicID(picID int) (int, error) {
 	return foundWin.ID, nil
 }
 
+// ===== Sprite Pool Management =====
+
+// CreateSpritePool はスプライトプールを作成する（粒子の状態のみ保持する）
+func (hgs *HeadlessGraphicsSystem) CreateSpritePool(srcPicID, dstPicID, n int, transColor color.Color) (int, error) {
+	hgs.pictureMu.RLock()
+	srcPic, srcOK := hgs.pictures[srcPicID]
+	dstPic, dstOK := hgs.pictures[dstPicID]
+	hgs.pictureMu.RUnlock()
+	if !srcOK {
+		return -1, fmt.Errorf("%w: %d", ErrPictureNotFound, srcPicID)
+	}
+	if !dstOK {
+		return -1, fmt.Errorf("%w: %d", ErrPictureNotFound, dstPicID)
+	}
+
+	set, err := newParticleSet(n, srcPic.Width, srcPic.Height, dstPic.Width, dstPic.Height)
+	if err != nil {
+		return -1, err
+	}
+
+	hgs.poolMu.Lock()
+	defer hgs.poolMu.Unlock()
+	id := hgs.nextPoolID
+	hgs.nextPoolID++
+	hgs.pools[id] = set
+
+	hgs.logOperation("CreateSpritePool",
+		"srcPicID", srcPicID, "dstPicID", dstPicID,
+		"size", n, "transColor", transColor, "poolID", id)
+	return id, nil
+}
+
+// pool はスプライトプールを取得する（hgs.poolMu を保持して呼び出すこと）
+func (hgs *HeadlessGraphicsSystem) pool(poolID int) (*particleSet, error) {
+	set, ok := hgs.pools[poolID]
+	if !ok {
+		return nil, fmt.Errorf("%w: %d", ErrSpritePoolNotFound, poolID)
+	}
+	return set, nil
+}
+
+// SetPoolParticle はスプライトプールの粒子の位置と可視性を設定する
+func (hgs *HeadlessGraphicsSystem) SetPoolParticle(poolID, index int, x, y float64, visible bool) error {
+	hgs.poolMu.Lock()
+	defer hgs.poolMu.Unlock()
+	set, err := hgs.pool(poolID)
+	if err != nil {
+		return err
+	}
+	return set.set(index, x, y, visible)
+}
+
+// SetPoolVelocity はスプライトプールの粒子の速度を設定する（index が負の場合はすべての粒子）
+func (hgs *HeadlessGraphicsSystem) SetPoolVelocity(poolID, index int, vx, vy float64) error {
+	hgs.poolMu.Lock()
+	defer hgs.poolMu.Unlock()
+	set, err := hgs.pool(poolID)
+	if err != nil {
+		return err
+	}
+	return set.setVelocity(index, vx, vy)
+}
+
+// StepSpritePool はスプライトプールのすべての粒子を速度の分だけ進める
+func (hgs *HeadlessGraphicsSystem) StepSpritePool(poolID int) error {
+	hgs.poolMu.Lock()
+	defer hgs.poolMu.Unlock()
+	set, err := hgs.pool(poolID)
+	if err != nil {
+		return err
+	}
+	set.step()
+	return nil
+}
+
+// SpritePoolSize はスプライトプールの粒子の数を返す（存在しない場合は0）
+func (hgs *HeadlessGraphicsSystem) SpritePoolSize(poolID int) int {
+	hgs.poolMu.Lock()
+	defer hgs.poolMu.Unlock()
+	set, err := hgs.pool(poolID)
+	if err != nil {
+		return 0
+	}
+	return len(set.particles)
+}
+
+// GetPoolParticle はスプライトプールの粒子の位置と可視性を返す（テスト用）
+func (hgs *HeadlessGraphicsSystem) GetPoolParticle(poolID, index int) (x, y float64, visible bool, ok bool) {
+	hgs.poolMu.Lock()
+	defer hgs.poolMu.Unlock()
+	set, err := hgs.pool(poolID)
+	if err != nil || set.checkIndex(index) != nil {
+		return 0, 0, false, false
+	}
+	p := set.particles[index]
+	return p.x, p.y, p.visible, true
+}
+
+// DelSpritePool はスプライトプールを削除する
+func (hgs *HeadlessGraphicsSystem) DelSpritePool(poolID int) error {
+	hgs.poolMu.Lock()
+	defer hgs.poolMu.Unlock()
+	if _, err := hgs.pool(poolID); err != nil {
+		return err
+	}
+	delete(hgs.pools, poolID)
+	hgs.logOperation("DelSpritePool", "poolID", poolID)
+	return nil
+}
+
 // ===== Cast Management =====
 
 // PutCast はキャストを配置する
@@ -745,6 +997,10 @@ func (hgs *HeadlessGraphicsSystem) PutCastWithTransColor(winID, picID, x, y, src
 		Height:  h,
 		Visible: true,
 		ZOrder:  id, // 簡易的にIDをZOrderとして使用
+
+		srcPicID:   winID,
+		dstPicID:   picID,
+		transColor: transColor,
 	}
 	hgs.casts[id] = cast
 
@@ -755,6 +1011,7 @@ func (hgs *HeadlessGraphicsSystem) PutCastWithTransColor(winID, picID, x, y, src
 		"w", w, "h", h,
 		"transColor", transColor,
 		"castID", id)
+	hgs.publishCast(TopicCastPut, cast)
 	return id, nil
 }
 
@@ -798,6 +1055,7 @@ func (hgs *HeadlessGraphicsSystem) MoveCast(id int, opts ...any) error {
 			if x, ok := toIntFromAny(opts[1]); ok {
 				if y, ok := toIntFromAny(opts[2]); ok {
 					cast.PicID = picID
+					cast.srcPicID = picID
 					cast.X = x
 					cast.Y = y
 				}
@@ -806,6 +1064,7 @@ func (hgs *HeadlessGraphicsSystem) MoveCast(id int, opts ...any) error {
 	}
 
 	hgs.logOperation("MoveCast", "castID", id, "opts", opts)
+	hgs.publishCast(TopicCastMove, cast)
 	return nil
 }
 
@@ -838,7 +1097,10 @@ func (hgs *HeadlessGraphicsSystem) MoveCastWithOptions(id int, opts ...CastOptio
 		opt(tempCast)
 	}
 
-	// 更新を適用
+	// 更新を適用（ピクチャーを変更した場合は描画元のピクチャーを差し替える）
+	if tempCast.PicID != cast.PicID {
+		cast.srcPicID = tempCast.PicID
+	}
 	cast.PicID = tempCast.PicID
 	cast.X = tempCast.X
 	cast.Y = tempCast.Y
@@ -848,6 +1110,7 @@ func (hgs *HeadlessGraphicsSystem) MoveCastWithOptions(id int, opts ...CastOptio
 	cast.Height = tempCast.Height
 
 	hgs.logOperation("MoveCastWithOptions", "castID", id)
+	hgs.publishCast(TopicCastMove, cast)
 	return nil
 }
 
@@ -863,14 +1126,346 @@ func (hgs *HeadlessGraphicsSystem) DelCast(id int) error {
 
 	delete(hgs.casts, id)
 	hgs.logOperation("DelCast", "castID", id)
+	hgs.bus.Publish(TopicCastDelete, map[string]any{"castID": id})
+	return nil
+}
+
+// SetCastMask はキャストのマスクを設定する（nil で解除、状態のみ保持する）
+func (hgs *HeadlessGraphicsSystem) SetCastMask(id int, mask *Mask) error {
+	if err := hgs.checkMask(mask); err != nil {
+		return err
+	}
+
+	hgs.castMu.Lock()
+	defer hgs.castMu.Unlock()
+
+	cast, ok := hgs.casts[id]
+	if !ok {
+		hgs.log.Warn("SetCastMask: cast not found", "castID", id)
+		return fmt.Errorf("%w: %d", ErrCastNotFound, id)
+	}
+	cast.Mask = copyMask(mask)
+	hgs.logOperation("SetCastMask", "castID", id, "mask", mask)
+	return nil
+}
+
+// SetWinMask はウィンドウのマスクを設定する（nil で解除、状態のみ保持する）
+func (hgs *HeadlessGraphicsSystem) SetWinMask(id int, mask *Mask) error {
+	if err := hgs.checkMask(mask); err != nil {
+		return err
+	}
+
+	hgs.windowMu.Lock()
+	defer hgs.windowMu.Unlock()
+
+	win, ok := hgs.windows[id]
+	if !ok {
+		hgs.log.Warn("SetWinMask: window not found", "winID", id)
+		return fmt.Errorf("%w: %d", ErrWindowNotFound, id)
+	}
+	win.Mask = copyMask(mask)
+	hgs.logOperation("SetWinMask", "winID", id, "mask", mask)
+	return nil
+}
+
+// SetCastShadow はキャストの影を設定する（nil で解除、状態のみ保持する）
+func (hgs *HeadlessGraphicsSystem) SetCastShadow(id int, shadow *Shadow) error {
+	if err := checkShadow(shadow); err != nil {
+		return err
+	}
+
+	hgs.castMu.Lock()
+	defer hgs.castMu.Unlock()
+
+	cast, ok := hgs.casts[id]
+	if !ok {
+		hgs.log.Warn("SetCastShadow: cast not found", "castID", id)
+		return fmt.Errorf("%w: %d", ErrCastNotFound, id)
+	}
+	cast.Shadow = copyShadow(shadow)
+	hgs.logOperation("SetCastShadow", "castID", id, "shadow", shadow)
+	return nil
+}
+
+// SetCastOutline はキャストの縁取りを設定する（nil で解除、状態のみ保持する）
+func (hgs *HeadlessGraphicsSystem) SetCastOutline(id int, outline *Outline) error {
+	if err := checkOutline(outline); err != nil {
+		return err
+	}
+
+	hgs.castMu.Lock()
+	defer hgs.castMu.Unlock()
+
+	cast, ok := hgs.casts[id]
+	if !ok {
+		hgs.log.Warn("SetCastOutline: cast not found", "castID", id)
+		return fmt.Errorf("%w: %d", ErrCastNotFound, id)
+	}
+	cast.Outline = copyOutline(outline)
+	hgs.logOperation("SetCastOutline", "castID", id, "outline", outline)
+	return nil
+}
+
+// SetCastSourceRect はキャストの描画する範囲を設定する（nil で解除、状態のみ保持する）
+func (hgs *HeadlessGraphicsSystem) SetCastSourceRect(id int, rect *image.Rectangle) error {
+	if err := checkSourceRect(rect); err != nil {
+		return err
+	}
+
+	hgs.castMu.Lock()
+	defer hgs.castMu.Unlock()
+
+	cast, ok := hgs.casts[id]
+	if !ok {
+		hgs.log.Warn("SetCastSourceRect: cast not found", "castID", id)
+		return fmt.Errorf("%w: %d", ErrCastNotFound, id)
+	}
+	cast.Source = copySourceRect(rect)
+	hgs.logOperation("SetCastSourceRect", "castID", id, "rect", rect)
+	return nil
+}
+
+// SetCastNineSlice はキャストの9分割の伸縮を設定する（nil で解除、状態のみ保持する）
+func (hgs *HeadlessGraphicsSystem) SetCastNineSlice(id int, ns *NineSlice) error {
+	if err := checkNineSlice(ns); err != nil {
+		return err
+	}
+
+	hgs.castMu.Lock()
+	defer hgs.castMu.Unlock()
+
+	cast, ok := hgs.casts[id]
+	if !ok {
+		hgs.log.Warn("SetCastNineSlice: cast not found", "castID", id)
+		return fmt.Errorf("%w: %d", ErrCastNotFound, id)
+	}
+	cast.Slice = copyNineSlice(ns)
+	hgs.logOperation("SetCastNineSlice", "castID", id, "nineSlice", ns)
+	return nil
+}
+
+// ShakeCast はキャストを揺らす（ヘッドレスモードでは描画しない）
+func (hgs *HeadlessGraphicsSystem) ShakeCast(id, amplitude int, duration time.Duration) error {
+	if amplitude < 0 {
+		return fmt.Errorf("invalid shake amplitude: %d", amplitude)
+	}
+	return hgs.logCastEffect("ShakeCast", id, "amplitude", amplitude, "duration", duration)
+}
+
+// FlashCast はキャストを光らせる（ヘッドレスモードでは描画しない）
+func (hgs *HeadlessGraphicsSystem) FlashCast(id int, c color.Color, duration time.Duration) error {
+	return hgs.logCastEffect("FlashCast", id, "color", fmt.Sprintf("0x%06X", ColorToInt(c)), "duration", duration)
+}
+
+// StartCastCrossfade はキャストのクロスフェードを始める（ヘッドレスモードでは描画しない）
+func (hgs *HeadlessGraphicsSystem) StartCastCrossfade(id int) error {
+	return hgs.logCastEffect("StartCastCrossfade", id)
+}
+
+// SetCastCrossfade はキャストのクロスフェードの進み具合を設定する（ヘッドレスモードでは描画しない）
+func (hgs *HeadlessGraphicsSystem) SetCastCrossfade(id int, progress float64) error {
+	if progress < 0 {
+		return fmt.Errorf("invalid crossfade progress: %g", progress)
+	}
+	return hgs.logCastEffect("SetCastCrossfade", id, "progress", progress)
+}
+
+// MoveCastAlongPath はキャストをパスの終点に移動する（ヘッドレスモードでは途中のフレームを描画しない）
+// スクリプトから見たキャストの位置が移動の後と同じになるよう、すぐに終点に置く。
+func (hgs *HeadlessGraphicsSystem) MoveCastAlongPath(id int, path *Path, duration time.Duration, easing Easing) error {
+	if !ValidEasing(easing) {
+		return fmt.Errorf("invalid easing: %d", easing)
+	}
+	if path == nil || duration <= 0 {
+		return hgs.logCastEffect("MoveCastAlongPath", id, "stop", true)
+	}
+	if err := hgs.logCastEffect("MoveCastAlongPath", id, "points", len(path.points), "duration", duration, "easing", easing); err != nil {
+		return err
+	}
+	end := path.End()
+	hgs.castMu.Lock()
+	if cast, ok := hgs.casts[id]; ok {
+		cast.X, cast.Y = int(math.Round(end.X)), int(math.Round(end.Y))
+	}
+	hgs.castMu.Unlock()
+	return nil
+}
+
+// logCastEffect はキャストがあることを確認して演出の操作を記録する
+func (hgs *HeadlessGraphicsSystem) logCastEffect(operation string, id int, args ...any) error {
+	hgs.castMu.RLock()
+	_, ok := hgs.casts[id]
+	hgs.castMu.RUnlock()
+	if !ok {
+		hgs.log.Warn(operation+": cast not found", "castID", id)
+		return fmt.Errorf("%w: %d", ErrCastNotFound, id)
+	}
+	hgs.logOperation(operation, append([]any{"castID", id}, args...)...)
+	return nil
+}
+
+// checkMask はマスクの大きさとピクチャーを検証する
+func (hgs *HeadlessGraphicsSystem) checkMask(mask *Mask) error {
+	switch {
+	case mask == nil:
+		return nil
+	case mask.UsePic:
+		hgs.pictureMu.RLock()
+		_, ok := hgs.pictures[mask.PicID]
+		hgs.pictureMu.RUnlock()
+		if !ok {
+			return fmt.Errorf("%w: %d", ErrPictureNotFound, mask.PicID)
+		}
+	case mask.Width < 0 || mask.Height < 0:
+		return fmt.Errorf("invalid mask size: %dx%d", mask.Width, mask.Height)
+	}
 	return nil
 }
 
+// SetCursor はカスタムカーソルを設定する（nil で解除、状態のみ保持する）
+func (hgs *HeadlessGraphicsSystem) SetCursor(c *Cursor) error {
+	if c != nil {
+		hgs.pictureMu.RLock()
+		_, ok := hgs.pictures[c.PicID]
+		hgs.pictureMu.RUnlock()
+		if !ok {
+			return fmt.Errorf("%w: %d", ErrPictureNotFound, c.PicID)
+		}
+		copied := *c
+		c = &copied
+	}
+
+	hgs.cursorMu.Lock()
+	defer hgs.cursorMu.Unlock()
+	hgs.cursor = c
+	hgs.cursorClick = nil
+	hgs.logOperation("SetCursor", "cursor", c)
+	return nil
+}
+
+// SetCursorClick はカスタムカーソルのクリックアニメーションを設定する（nil で解除、状態のみ保持する）
+func (hgs *HeadlessGraphicsSystem) SetCursorClick(click *CursorClick) error {
+	if click != nil {
+		if err := checkCursorClick(click); err != nil {
+			return err
+		}
+		hgs.pictureMu.RLock()
+		pic, ok := hgs.pictures[click.PicID]
+		hgs.pictureMu.RUnlock()
+		if !ok {
+			return fmt.Errorf("%w: %d", ErrPictureNotFound, click.PicID)
+		}
+		if pic.Width < click.Frames {
+			return fmt.Errorf("picture %d (width %d) is too narrow for %d frames", click.PicID, pic.Width, click.Frames)
+		}
+		copied := *click
+		click = &copied
+	}
+
+	hgs.cursorMu.Lock()
+	defer hgs.cursorMu.Unlock()
+	if hgs.cursor == nil {
+		return fmt.Errorf("no custom cursor is set")
+	}
+	hgs.cursorClick = click
+	hgs.logOperation("SetCursorClick", "click", click)
+	return nil
+}
+
+// SetSystemCursorVisible はシステムのマウスカーソルを表示するかを設定する（状態のみ保持する）
+func (hgs *HeadlessGraphicsSystem) SetSystemCursorVisible(visible bool) {
+	hgs.cursorMu.Lock()
+	defer hgs.cursorMu.Unlock()
+	hgs.sysCursorHidden = !visible
+	hgs.logOperation("SetSystemCursorVisible", "visible", visible)
+}
+
+// CursorState はカスタムカーソル、クリックアニメーション、システムのカーソルを表示するかを返す
+// （カスタムカーソルを表示している間はシステムのカーソルを表示しない）
+func (hgs *HeadlessGraphicsSystem) CursorState() (cursor *Cursor, click *CursorClick, sysCursorVisible bool) {
+	hgs.cursorMu.Lock()
+	defer hgs.cursorMu.Unlock()
+	return hgs.cursor, hgs.cursorClick, !hgs.sysCursorHidden && hgs.cursor == nil
+}
+
+// copyMask は呼び出し元と共有しないようにマスクをコピーする
+func copyMask(mask *Mask) *Mask {
+	if mask == nil {
+		return nil
+	}
+	m := *mask
+	return &m
+}
+
+// GetCast はキャストの現在の状態のコピーを返す（テストダブルや外部ツール向け）
+func (hgs *HeadlessGraphicsSystem) GetCast(id int) (HeadlessCast, bool) {
+	hgs.castMu.RLock()
+	defer hgs.castMu.RUnlock()
+
+	cast, ok := hgs.casts[id]
+	if !ok {
+		return HeadlessCast{}, false
+	}
+	return *cast, true
+}
+
+// GetCasts はすべてのキャストの現在の状態のコピーをID順に返す
+func (hgs *HeadlessGraphicsSystem) GetCasts() []HeadlessCast {
+	hgs.castMu.RLock()
+	defer hgs.castMu.RUnlock()
+
+	casts := make([]HeadlessCast, 0, len(hgs.casts))
+	for _, cast := range hgs.casts {
+		casts = append(casts, *cast)
+	}
+	sort.Slice(casts, func(i, j int) bool { return casts[i].ID < casts[j].ID })
+	return casts
+}
+
+// GetWindows はすべてのウィンドウの現在の状態のコピーをID順に返す
+func (hgs *HeadlessGraphicsSystem) GetWindows() []HeadlessWindow {
+	hgs.windowMu.RLock()
+	defer hgs.windowMu.RUnlock()
+
+	windows := make([]HeadlessWindow, 0, len(hgs.windows))
+	for _, win := range hgs.windows {
+		windows = append(windows, *win)
+	}
+	sort.Slice(windows, func(i, j int) bool { return windows[i].ID < windows[j].ID })
+	return windows
+}
+
+// CastAt は指定座標にあるキャストIDを返す
+// ヘッドレスモードではマウス入力が発生しないため、常に -1 を返す
+func (hgs *HeadlessGraphicsSystem) CastAt(x, y int) int {
+	return -1
+}
+
 // ===== Text Rendering =====
 
-// TextWrite はテキストを描画する（ヘッドレスモードではログのみ）
+// TextWrite はテキストを描画する（ソフトウェア描画が無効の場合はログのみ）
 func (hgs *HeadlessGraphicsSystem) TextWrite(picID, x, y int, text string) error {
-	hgs.logOperation("TextWrite", "picID", picID, "x", x, "y", y, "text", text)
+	return hgs.TextWriteWithPitch(picID, x, y, text, PitchDefault)
+}
+
+// TextWriteWithPitch はピッチを指定してテキストを描画する（ソフトウェア描画が無効の場合はログのみ）
+func (hgs *HeadlessGraphicsSystem) TextWriteWithPitch(picID, x, y int, text string, pitch FontPitch) error {
+	return hgs.TextWriteWithLayout(picID, x, y, text, pitch, TextLayout{})
+}
+
+// TextWriteWithLayout は字間と行間を指定してテキストを描画する（ソフトウェア描画が無効の場合はログのみ）
+func (hgs *HeadlessGraphicsSystem) TextWriteWithLayout(picID, x, y int, text string, pitch FontPitch, layout TextLayout) error {
+	hgs.logOperation("TextWrite", "picID", picID, "x", x, "y", y, "text", text, "pitch", pitch,
+		"tracking", layout.Tracking, "lineSpacing", layout.LineSpacing)
+	hgs.renderText(picID, x, y, text, layout, TextHighlight{})
+	return nil
+}
+
+// TextWriteHighlighted は先頭の文字を強調してテキストを描画する（ソフトウェア描画が無効の場合はログのみ）
+func (hgs *HeadlessGraphicsSystem) TextWriteHighlighted(picID, x, y int, text string, count int, highlightColor any) error {
+	hgs.logOperation("TextWriteHighlighted", "picID", picID, "x", x, "y", y, "text", text, "count", count, "color", highlightColor)
+	c, _ := colorFromAny(highlightColor)
+	hgs.renderText(picID, x, y, text, TextLayout{}, TextHighlight{Count: count, Color: c})
 	return nil
 }
 
@@ -882,6 +1477,51 @@ func (hgs *HeadlessGraphicsSystem) SetFont(name string, size int, opts ...any) e
 	return nil
 }
 
+// TextExtent はテキストの幅と高さの概算値を返す
+// ヘッドレスモードではフォントを読み込まないため、半角文字をサイズの半分、
+// 全角文字をサイズと同じ幅とみなし、高さはフォントサイズとする
+func (hgs *HeadlessGraphicsSystem) TextExtent(text, fontName string, size int) (int, int) {
+	return hgs.TextExtentWithPitch(text, fontName, size, PitchDefault)
+}
+
+// TextExtentWithPitch はピッチを指定してテキストの幅と高さの概算値を返す
+func (hgs *HeadlessGraphicsSystem) TextExtentWithPitch(text, fontName string, size int, pitch FontPitch) (int, int) {
+	return hgs.TextExtentWithLayout(text, fontName, size, pitch, TextLayout{})
+}
+
+// TextExtentWithLayout は字間と行間を指定してテキストの幅と高さの概算値を返す
+// ヘッドレスモードではピッチに関係なく、常に固定ピッチの幅を返す
+// 縦書きでは幅と高さを入れ替える（列の幅がサイズ、高さが文字の幅の合計）
+// 改行を含む文字列では、最も長い行の幅にフォントサイズと行間で決まる行の送りを加える
+func (hgs *HeadlessGraphicsSystem) TextExtentWithLayout(text, fontName string, size int, pitch FontPitch, layout TextLayout) (int, int) {
+	if fontName == "" {
+		size = hgs.fontSize
+	}
+	size = fontRenderSize(size)
+	layout = layout.clamped()
+
+	lines := textLines(text)
+	width := 0
+	for _, line := range lines {
+		lineWidth := 0
+		for _, r := range line {
+			if isHalfWidthRune(r) {
+				lineWidth += size / 2
+			} else {
+				lineWidth += size
+			}
+			lineWidth += layout.Tracking
+		}
+		width = max(width, lineWidth)
+	}
+	lineAdvance := max(size+layout.LineSpacing, 1)
+	blockHeight := size + (len(lines)-1)*lineAdvance
+	if hgs.direction == TextVertical {
+		return blockHeight, width
+	}
+	return width, blockHeight
+}
+
 // SetTextColor はテキスト色を設定する
 func (hgs *HeadlessGraphicsSystem) SetTextColor(c any) error {
 	switch v := c.(type) {
@@ -913,29 +1553,42 @@ func (hgs *HeadlessGraphicsSystem) SetBackMode(mode int) error {
 	return nil
 }
 
+// SetTextDirection は文字列の向き（横書き・縦書き）を設定する
+func (hgs *HeadlessGraphicsSystem) SetTextDirection(direction TextDirection) error {
+	hgs.direction = direction
+	hgs.logOperation("SetTextDirection", "direction", direction)
+	return nil
+}
+
 // ===== Drawing Primitives =====
 
-// DrawLine は直線を描画する（ヘッドレスモードではログのみ）
+// DrawLine は直線を描画する（ソフトウェア描画が無効の場合はログのみ）
 func (hgs *HeadlessGraphicsSystem) DrawLine(picID, x1, y1, x2, y2 int) error {
 	hgs.logOperation("DrawLine", "picID", picID, "x1", x1, "y1", y1, "x2", x2, "y2", y2)
+	hgs.renderLine(picID, x1, y1, x2, y2)
 	return nil
 }
 
-// DrawRect は矩形を描画する（ヘッドレスモードではログのみ）
+// DrawRect は矩形を描画する（ソフトウェア描画が無効の場合はログのみ）
 func (hgs *HeadlessGraphicsSystem) DrawRect(picID, x1, y1, x2, y2, fillMode int) error {
 	hgs.logOperation("DrawRect", "picID", picID, "x1", x1, "y1", y1, "x2", x2, "y2", y2, "fillMode", fillMode)
+	hgs.renderRect(picID, x1, y1, x2, y2, fillMode)
 	return nil
 }
 
-// FillRect は矩形を塗りつぶす（ヘッドレスモードではログのみ）
+// FillRect は矩形を塗りつぶす（ソフトウェア描画が無効の場合はログのみ）
 func (hgs *HeadlessGraphicsSystem) FillRect(picID, x1, y1, x2, y2 int, c any) error {
 	hgs.logOperation("FillRect", "picID", picID, "x1", x1, "y1", y1, "x2", x2, "y2", y2, "color", c)
+	if fill, ok := colorFromAny(c); ok {
+		hgs.renderFill(picID, image.Rect(x1, y1, x2, y2), fill)
+	}
 	return nil
 }
 
-// DrawCircle は円を描画する（ヘッドレスモードではログのみ）
+// DrawCircle は円を描画する（ソフトウェア描画が無効の場合はログのみ）
 func (hgs *HeadlessGraphicsSystem) DrawCircle(picID, x, y, radius, fillMode int) error {
 	hgs.logOperation("DrawCircle", "picID", picID, "x", x, "y", y, "radius", radius, "fillMode", fillMode)
+	hgs.renderCircle(picID, x, y, radius, fillMode)
 	return nil
 }
 
@@ -957,8 +1610,308 @@ func (hgs *HeadlessGraphicsSystem) SetPaintColor(c any) error {
 	return nil
 }
 
-// GetColor は指定座標のピクセル色を取得する（ヘッドレスモードでは0を返す）
+// GetColor は指定座標のピクセル色を取得する（ソフトウェア描画が無効の場合は0を返す）
 func (hgs *HeadlessGraphicsSystem) GetColor(picID, x, y int) (int, error) {
 	hgs.logOperation("GetColor", "picID", picID, "x", x, "y", y)
-	return 0, nil
+	return hgs.pixelColor(picID, x, y), nil
+}
+
+// ===== Screen Fade =====
+
+// Fade は画面全体のフェードを開始する（ヘッドレスモードでは描画しない）
+// 完了イベントのタイミングを通常モードと揃えるため、duration 経過後に onDone を呼び出す
+// 実行中のフェードは置き換えられ、その完了コールバックは呼び出されない
+func (hgs *HeadlessGraphicsSystem) Fade(fadeOut bool, c any, duration time.Duration, onDone func()) error {
+	if _, err := fadeColorFrom(c); err != nil {
+		return err
+	}
+	hgs.logOperation("Fade", "fadeOut", fadeOut, "color", c, "duration", duration)
+
+	hgs.fadeMu.Lock()
+	defer hgs.fadeMu.Unlock()
+
+	if hgs.fadeTimer != nil {
+		hgs.fadeTimer.Stop()
+		hgs.fadeTimer = nil
+	}
+	if onDone != nil {
+		hgs.fadeTimer = time.AfterFunc(duration, onDone)
+	}
+	return nil
+}
+
+// ===== Render Order =====
+
+// BringWinToFront はウィンドウを最前面に移動する
+func (hgs *HeadlessGraphicsSystem) BringWinToFront(id int) error {
+	return hgs.restackWindow(id, true)
+}
+
+// SendWinToBack はウィンドウを最背面に移動する
+func (hgs *HeadlessGraphicsSystem) SendWinToBack(id int) error {
+	return hgs.restackWindow(id, false)
+}
+
+// restackWindow はウィンドウのZ順序を変更する
+func (hgs *HeadlessGraphicsSystem) restackWindow(id int, toFront bool) error {
+	hgs.windowMu.Lock()
+	defer hgs.windowMu.Unlock()
+
+	win, ok := hgs.windows[id]
+	if !ok {
+		return fmt.Errorf("window not found: %d", id)
+	}
+	if toFront {
+		win.ZOrder = hgs.nextZOrder
+		hgs.nextZOrder++
+	} else {
+		minZOrder := win.ZOrder
+		for _, other := range hgs.windows {
+			if other.ID != id && other.ZOrder <= minZOrder {
+				minZOrder = other.ZOrder - 1
+			}
+		}
+		win.ZOrder = minZOrder
+	}
+	hgs.logOperation("RestackWindow", "winID", id, "toFront", toFront, "zOrder", win.ZOrder)
+	hgs.bus.Publish(TopicWindowRestack, map[string]any{"winID": id, "toFront": toFront, "zOrder": win.ZOrder})
+	return nil
+}
+
+// BringCastToFront はキャストを同じウィンドウのキャストの中で最前面に移動する
+func (hgs *HeadlessGraphicsSystem) BringCastToFront(id int) error {
+	return hgs.restackCast(id, true)
+}
+
+// SendCastToBack はキャストを同じウィンドウのキャストの中で最背面に移動する
+func (hgs *HeadlessGraphicsSystem) SendCastToBack(id int) error {
+	return hgs.restackCast(id, false)
+}
+
+// restackCast は同じウィンドウのキャストが使用しているZ順序の値を並べ替え後の順に割り当て直す
+func (hgs *HeadlessGraphicsSystem) restackCast(id int, toFront bool) error {
+	hgs.castMu.Lock()
+	defer hgs.castMu.Unlock()
+
+	target, ok := hgs.casts[id]
+	if !ok {
+		return fmt.Errorf("cast not found: %d", id)
+	}
+
+	siblings := make([]*HeadlessCast, 0)
+	for _, cast := range hgs.casts {
+		if cast.WinID == target.WinID {
+			siblings = append(siblings, cast)
+		}
+	}
+	sort.Slice(siblings, func(i, j int) bool {
+		return siblings[i].ZOrder < siblings[j].ZOrder
+	})
+
+	ids := make([]int, len(siblings))
+	slots := make([]int, len(siblings))
+	for i, cast := range siblings {
+		ids[i] = cast.ID
+		slots[i] = cast.ZOrder
+	}
+	for i, castID := range restack(ids, id, toFront) {
+		hgs.casts[castID].ZOrder = slots[i]
+	}
+	hgs.logOperation("RestackCast", "castID", id, "toFront", toFront)
+	hgs.bus.Publish(TopicCastRestack, map[string]any{"castID": id, "toFront": toFront})
+	return nil
+}
+
+// ===== Palette =====
+
+// SetPaletteColor はパレット番号 index の表示色を変更する（ヘッドレスモードでは状態のみ更新）
+func (hgs *HeadlessGraphicsSystem) SetPaletteColor(index int, c any) error {
+	paletteColor, err := fadeColorFrom(c)
+	if err != nil {
+		return err
+	}
+	hgs.logOperation("SetPaletteColor", "index", index, "color", c)
+	return hgs.palette.Set(index, paletteColor)
+}
+
+// GetPaletteColor はパレット番号 index の現在の表示色を 0xRRGGBB 形式で返す
+func (hgs *HeadlessGraphicsSystem) GetPaletteColor(index int) (int, error) {
+	c, err := hgs.palette.Get(index)
+	if err != nil {
+		return 0, err
+	}
+	return ColorToInt(c), nil
+}
+
+// CyclePalette はパレット番号 start から count 個の表示色を step だけ回転させる
+func (hgs *HeadlessGraphicsSystem) CyclePalette(start, count, step int) error {
+	hgs.logOperation("CyclePalette", "start", start, "count", count, "step", step)
+	return hgs.palette.Cycle(start, count, step)
+}
+
+// ResetPalette は表示色を既定のパレットに戻す
+func (hgs *HeadlessGraphicsSystem) ResetPalette() {
+	hgs.logOperation("ResetPalette")
+	hgs.palette.Reset()
+}
+
+// ===== Display Adjustment =====
+
+// SetGamma はガンマ値を変更する（ヘッドレスモードでは状態のみ更新）
+func (hgs *HeadlessGraphicsSystem) SetGamma(v float64) error {
+	hgs.logOperation("SetGamma", "gamma", v)
+	return hgs.display.SetGamma(v)
+}
+
+// SetBrightness は明るさを変更する（ヘッドレスモードでは状態のみ更新）
+func (hgs *HeadlessGraphicsSystem) SetBrightness(v float64) error {
+	hgs.logOperation("SetBrightness", "brightness", v)
+	return hgs.display.SetBrightness(v)
+}
+
+// SetContrast はコントラストを変更する（ヘッドレスモードでは状態のみ更新）
+func (hgs *HeadlessGraphicsSystem) SetContrast(v float64) error {
+	hgs.logOperation("SetContrast", "contrast", v)
+	return hgs.display.SetContrast(v)
+}
+
+// GetDisplayAdjustment は現在の表示調整を返す
+func (hgs *HeadlessGraphicsSystem) GetDisplayAdjustment() DisplayAdjustment {
+	return hgs.display.Settings()
+}
+
+// SetWindowTitle はOSのウィンドウのタイトルを設定する（状態のみ保持する）
+func (hgs *HeadlessGraphicsSystem) SetWindowTitle(title string) {
+	hgs.appWindowMu.Lock()
+	defer hgs.appWindowMu.Unlock()
+	hgs.appWindow.Title = title
+	hgs.logOperation("SetWindowTitle", "title", title)
+}
+
+// SetWindowIcon はOSのウィンドウのアイコンを設定する（ファイル名のみ保持する）
+func (hgs *HeadlessGraphicsSystem) SetWindowIcon(filename string) error {
+	hgs.appWindowMu.Lock()
+	defer hgs.appWindowMu.Unlock()
+	hgs.appWindow.IconFile = filename
+	hgs.logOperation("SetWindowIcon", "filename", filename)
+	return nil
+}
+
+// SetWindowSize はOSのウィンドウの大きさを設定する（状態のみ保持する）
+func (hgs *HeadlessGraphicsSystem) SetWindowSize(width, height int) error {
+	if err := checkAppWindowSize(width, height); err != nil {
+		return err
+	}
+	hgs.appWindowMu.Lock()
+	defer hgs.appWindowMu.Unlock()
+	hgs.appWindow.Width, hgs.appWindow.Height = width, height
+	hgs.logOperation("SetWindowSize", "width", width, "height", height)
+	return nil
+}
+
+// SaveSnapshot は画面またはキャストの画像を書き出す（ヘッドレスモードでは描画しないため、ログのみ）
+func (hgs *HeadlessGraphicsSystem) SaveSnapshot(castID int, path string) error {
+	if castID != SnapshotScreen {
+		hgs.castMu.RLock()
+		_, ok := hgs.casts[castID]
+		hgs.castMu.RUnlock()
+		if !ok {
+			return fmt.Errorf("cast not found: %d", castID)
+		}
+	}
+	hgs.logOperation("SaveSnapshot", "castID", castID, "path", path)
+	return nil
+}
+
+// CaptureLayers は合成のレイヤーを書き出す
+// ヘッドレスモードでは合成しないため、書き出すレイヤーがなくエラーを返す
+func (hgs *HeadlessGraphicsSystem) CaptureLayers(dir string) error {
+	hgs.logOperation("CaptureLayers", "dir", dir)
+	return errors.New("layer capture is not available in headless mode")
+}
+
+// AppWindowSettings はスクリプトが変更したOSのウィンドウの設定を返す
+func (hgs *HeadlessGraphicsSystem) AppWindowSettings() AppWindowSettings {
+	hgs.appWindowMu.Lock()
+	defer hgs.appWindowMu.Unlock()
+	return hgs.appWindow
+}
+
+// DisplayScale は実効の表示スケールを返す（画面がないため常に1）
+func (hgs *HeadlessGraphicsSystem) DisplayScale() float64 {
+	return 1
+}
+
+// SetImageSite はピクチャーを作成した文を返す関数を設定する（リークの報告用）
+func (hgs *HeadlessGraphicsSystem) SetImageSite(site func() string) {
+	hgs.images.setSite(site)
+}
+
+// SampleImageUsage は now の時点で表示中のウィンドウ・キャストが参照するピクチャーを記録する
+func (hgs *HeadlessGraphicsSystem) SampleImageUsage(now time.Time) {
+	var windows, casts []pictureRef
+	hgs.windowMu.RLock()
+	for _, w := range hgs.windows {
+		windows = append(windows, pictureRef{id: w.ID, picID: w.PicID, winID: -1, visible: w.Visible})
+	}
+	hgs.windowMu.RUnlock()
+	hgs.castMu.RLock()
+	for _, c := range hgs.casts {
+		casts = append(casts, pictureRef{id: c.ID, picID: c.PicID, winID: c.WinID, visible: c.Visible})
+	}
+	hgs.castMu.RUnlock()
+	hgs.images.sample(shownPictures(windows, casts), now)
+}
+
+// ImageReport はピクチャーのメモリと、idle 以上表示されていないピクチャーを返す
+// ヘッドレスモードの LoadPic は画像を読み込まず 640x480 として扱うため、メモリは目安にとどまる
+func (hgs *HeadlessGraphicsSystem) ImageReport(idle time.Duration, now time.Time) *ImageReport {
+	hgs.SampleImageUsage(now)
+	return hgs.images.report(idle, now)
+}
+
+// Camera は現在のカメラを返す
+func (hgs *HeadlessGraphicsSystem) Camera() Camera {
+	hgs.cameraMu.Lock()
+	defer hgs.cameraMu.Unlock()
+	if hgs.camera == nil {
+		return defaultCamera(hgs.virtualWidth, hgs.virtualHeight)
+	}
+	return *hgs.camera
+}
+
+// SetCamera はカメラを設定する（ヘッドレスモードでは状態のみ更新）
+func (hgs *HeadlessGraphicsSystem) SetCamera(cam *Camera) error {
+	if cam == nil {
+		hgs.logOperation("SetCamera", "reset", true)
+	} else {
+		if err := cam.validate(); err != nil {
+			return err
+		}
+		hgs.logOperation("SetCamera", "x", cam.X, "y", cam.Y, "zoom", cam.Zoom)
+	}
+	hgs.cameraMu.Lock()
+	defer hgs.cameraMu.Unlock()
+	if cam == nil {
+		hgs.camera = nil
+	} else {
+		c := *cam
+		hgs.camera = &c
+	}
+	return nil
+}
+
+// MoveCamera はカメラを移動先にする（ヘッドレスモードでは途中のフレームを描画しない）
+func (hgs *HeadlessGraphicsSystem) MoveCamera(cam Camera, duration time.Duration, easing Easing) error {
+	if !ValidEasing(easing) {
+		return fmt.Errorf("invalid easing: %d", easing)
+	}
+	if err := cam.validate(); err != nil {
+		return err
+	}
+	hgs.logOperation("MoveCamera", "x", cam.X, "y", cam.Y, "zoom", cam.Zoom, "duration", duration, "easing", easing)
+	hgs.cameraMu.Lock()
+	defer hgs.cameraMu.Unlock()
+	hgs.camera = &cam
+	return nil
 }
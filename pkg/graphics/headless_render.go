@@ -0,0 +1,433 @@
+// headless_render.go はヘッドレスモードのソフトウェア描画を提供する（WithHeadlessRendering）
+//
+// ウィンドウを持たない環境でも仮想デスクトップの画面を取り込めるように（--export-gif）、
+// ピクチャーの画素をメモリ上に保持して転送・図形・テキストをCPUで描画し、Frame でウィンドウとキャストを合成する。
+// 通常モードの描画を簡略化したもので、次の点が異なる:
+//   - シーンチェンジ（MovePic のモード2〜9）は途中の状態を描画せず、すぐに転送後の状態になる
+//   - テキストとキャプションは組み込みのビットマップフォント（basicfont）で描画する
+//     （取り込んだ画面がマシンにインストールされたフォントに左右されないようにするため）
+//   - カメラ、マスク、影・縁取りなどの効果、スプライトプール、画面フェード、表示調整は描画しない
+package graphics
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+	"sort"
+	"strings"
+
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/basicfont"
+	"golang.org/x/image/math/fixed"
+
+	"github.com/zurustar/son-et/pkg/fileutil"
+)
+
+// headlessFace はソフトウェア描画でテキストとキャプションに使うフォント
+var headlessFace font.Face = basicfont.Face7x13
+
+// ウィンドウ装飾の色（通常モードの drawWindowDecorationOnImage と同じ Windows 3.1 風の色）
+var (
+	headlessTitleBarColor  = color.RGBA{0, 0, 128, 255}
+	headlessBorderColor    = color.RGBA{192, 192, 192, 255}
+	headlessHighlightColor = color.RGBA{255, 255, 255, 255}
+	headlessShadowColor    = color.RGBA{0, 0, 0, 255}
+)
+
+// headlessTransparentColor は MovePic のモード1で除外する透明色（通常モードの DefaultTransparentColor と同じ黒）
+var headlessTransparentColor = color.RGBA{0, 0, 0, 255}
+
+// WithHeadlessRendering はソフトウェア描画を有効にする
+// LoadPic は fsys から画像を読み込み、描画操作はピクチャーの画素に反映され、Frame で画面を取り込める
+func WithHeadlessRendering(fsys fileutil.FileSystem) HeadlessOption {
+	return func(hgs *HeadlessGraphicsSystem) {
+		hgs.renderFS = fsys
+	}
+}
+
+// rendering はソフトウェア描画が有効かどうかを返す
+func (hgs *HeadlessGraphicsSystem) rendering() bool {
+	return hgs.renderFS != nil
+}
+
+// decodePic は画像ファイルを読み込んでデコードする
+// 通常モードと同じく "/" で始まるパスはタイトルディレクトリからの相対パスとして扱う
+func (hgs *HeadlessGraphicsSystem) decodePic(filename string) (*image.RGBA, error) {
+	searchFilename := strings.TrimLeft(filename, "/\\")
+	return decodePictureFile(hgs.renderFS, searchFilename, hgs.log)
+}
+
+// blankPixels は width x height の透明な画素を返す（ソフトウェア描画が無効の場合は nil）
+func (hgs *HeadlessGraphicsSystem) blankPixels(width, height int) *image.RGBA {
+	if !hgs.rendering() {
+		return nil
+	}
+	return image.NewRGBA(image.Rect(0, 0, max(width, 0), max(height, 0)))
+}
+
+// pixels はピクチャーの画素を返す（ピクチャーがないかソフトウェア描画が無効の場合は nil）
+// 呼び出し元は hgs.pictureMu のロックを保持していること
+func (hgs *HeadlessGraphicsSystem) pixels(picID int) *image.RGBA {
+	if pic, ok := hgs.pictures[picID]; ok {
+		return pic.pixels
+	}
+	return nil
+}
+
+// colorFromAny はスクリプトから渡された色（0xRRGGBB の整数または color.Color）を変換する
+func colorFromAny(c any) (color.Color, bool) {
+	switch v := c.(type) {
+	case int:
+		return ColorFromInt(v), true
+	case color.Color:
+		return v, true
+	}
+	return nil, false
+}
+
+// renderTransfer はピクチャー間で画素を転送する
+// transColor が nil でない場合はその色の画素を転送しない。mirror の場合は左右反転して転送する
+func (hgs *HeadlessGraphicsSystem) renderTransfer(srcID, srcX, srcY, width, height, dstID, dstX, dstY int, transColor color.Color, mirror bool) {
+	if !hgs.rendering() {
+		return
+	}
+	hgs.pictureMu.Lock()
+	defer hgs.pictureMu.Unlock()
+
+	src, dst := hgs.pixels(srcID), hgs.pixels(dstID)
+	if src == nil || dst == nil {
+		return
+	}
+	// 同じピクチャー内の転送で重なった範囲を壊さないように、転送元を複製してから書き込む
+	if srcID == dstID {
+		src = cloneRGBA(src)
+	}
+	var key color.RGBA
+	if transColor != nil {
+		key = color.RGBAModel.Convert(transColor).(color.RGBA)
+	}
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			sx := srcX + x
+			if mirror {
+				sx = srcX + width - 1 - x
+			}
+			if !image.Pt(sx, srcY+y).In(src.Bounds()) || !image.Pt(dstX+x, dstY+y).In(dst.Bounds()) {
+				continue
+			}
+			c := src.RGBAAt(sx, srcY+y)
+			if transColor != nil && sameRGB(c, key) {
+				continue
+			}
+			dst.SetRGBA(dstX+x, dstY+y, c)
+		}
+	}
+}
+
+// renderMovePic は MovePic の転送を描画する（モード1は黒を透明色とし、シーンチェンジはすぐに転送後の状態にする）
+func (hgs *HeadlessGraphicsSystem) renderMovePic(srcID, srcX, srcY, width, height, dstID, dstX, dstY, mode int) {
+	var transColor color.Color
+	if mode == 1 {
+		transColor = headlessTransparentColor
+	}
+	hgs.renderTransfer(srcID, srcX, srcY, width, height, dstID, dstX, dstY, transColor, false)
+}
+
+// renderScaled は転送元の範囲を転送先の範囲の大きさに拡大縮小して転送する（最近傍補間）
+func (hgs *HeadlessGraphicsSystem) renderScaled(srcID, srcX, srcY, srcW, srcH, dstID, dstX, dstY, dstW, dstH int) {
+	if !hgs.rendering() || srcW <= 0 || srcH <= 0 || dstW <= 0 || dstH <= 0 {
+		return
+	}
+	hgs.pictureMu.Lock()
+	defer hgs.pictureMu.Unlock()
+
+	src, dst := hgs.pixels(srcID), hgs.pixels(dstID)
+	if src == nil || dst == nil {
+		return
+	}
+	if srcID == dstID {
+		src = cloneRGBA(src)
+	}
+	for y := 0; y < dstH; y++ {
+		for x := 0; x < dstW; x++ {
+			sp := image.Pt(srcX+x*srcW/dstW, srcY+y*srcH/dstH)
+			dp := image.Pt(dstX+x, dstY+y)
+			if sp.In(src.Bounds()) && dp.In(dst.Bounds()) {
+				dst.SetRGBA(dp.X, dp.Y, src.RGBAAt(sp.X, sp.Y))
+			}
+		}
+	}
+}
+
+// renderText は現在のテキストの設定でピクチャーに文字列を描画する
+// 背景モードが不透明（0）の場合は、通常モードと同じく先に文字列の範囲を背景色で塗りつぶす
+func (hgs *HeadlessGraphicsSystem) renderText(picID, x, y int, text string, layout TextLayout, highlight TextHighlight) {
+	if !hgs.rendering() {
+		return
+	}
+	hgs.pictureMu.Lock()
+	defer hgs.pictureMu.Unlock()
+
+	dst := hgs.pixels(picID)
+	if dst == nil {
+		return
+	}
+	face := newLayoutFace(headlessFace, getFontHeight(headlessFace), layout)
+
+	if hgs.backMode == 0 {
+		width, height := horizontalTextExtent(face, text)
+		if hgs.direction == TextVertical {
+			width, height = verticalTextExtent(face, text)
+		}
+		bgRect := image.Rect(x, y, x+width, y+height)
+		// 非常に大きなフォントサイズはピクチャーを背景色で塗りつぶす用途に使われる（TextRenderer を参照）
+		if hgs.fontSize > largeFontThreshold {
+			bgRect = dst.Bounds()
+		}
+		draw.Draw(dst, bgRect, image.NewUniform(hgs.bgColor), image.Point{}, draw.Src)
+	}
+
+	textY := y + headlessFace.Metrics().Ascent.Ceil()
+	if hgs.direction == TextVertical {
+		textY = y
+	}
+	drawHighlightedText(dst, hgs.textColor, face, x, textY, text, hgs.direction, highlight)
+}
+
+// renderFill はピクチャーの範囲を c で塗りつぶす
+func (hgs *HeadlessGraphicsSystem) renderFill(picID int, rect image.Rectangle, c color.Color) {
+	if !hgs.rendering() || c == nil {
+		return
+	}
+	hgs.pictureMu.Lock()
+	defer hgs.pictureMu.Unlock()
+
+	if dst := hgs.pixels(picID); dst != nil {
+		draw.Draw(dst, rect.Canon(), image.NewUniform(c), image.Point{}, draw.Src)
+	}
+}
+
+// renderLine はピクチャーに現在の線の太さと描画色で直線を描画する
+func (hgs *HeadlessGraphicsSystem) renderLine(picID, x1, y1, x2, y2 int) {
+	if !hgs.rendering() {
+		return
+	}
+	hgs.pictureMu.Lock()
+	defer hgs.pictureMu.Unlock()
+
+	if dst := hgs.pixels(picID); dst != nil {
+		plotLine(dst, x1, y1, x2, y2, max(hgs.lineSize, 1), hgs.paintColor)
+	}
+}
+
+// renderRect は矩形を描画する（fillMode: 0=塗りつぶし, 1=輪郭のみ、通常モードの DrawRect と同じ）
+func (hgs *HeadlessGraphicsSystem) renderRect(picID, x1, y1, x2, y2, fillMode int) {
+	rect := image.Rect(x1, y1, x2, y2)
+	if fillMode != 1 {
+		hgs.renderFill(picID, rect, hgs.paintColor)
+		return
+	}
+	r := rect.Canon()
+	hgs.renderLine(picID, r.Min.X, r.Min.Y, r.Max.X-1, r.Min.Y)
+	hgs.renderLine(picID, r.Min.X, r.Max.Y-1, r.Max.X-1, r.Max.Y-1)
+	hgs.renderLine(picID, r.Min.X, r.Min.Y, r.Min.X, r.Max.Y-1)
+	hgs.renderLine(picID, r.Max.X-1, r.Min.Y, r.Max.X-1, r.Max.Y-1)
+}
+
+// renderCircle は円を描画する（fillMode: 2=塗りつぶし, それ以外=輪郭のみ、通常モードの DrawCircle と同じ）
+func (hgs *HeadlessGraphicsSystem) renderCircle(picID, cx, cy, radius, fillMode int) {
+	if !hgs.rendering() || radius <= 0 {
+		return
+	}
+	hgs.pictureMu.Lock()
+	defer hgs.pictureMu.Unlock()
+
+	dst := hgs.pixels(picID)
+	if dst == nil {
+		return
+	}
+	c := color.RGBAModel.Convert(hgs.paintColor).(color.RGBA)
+	outer := radius * radius
+	inner := max(radius-max(hgs.lineSize, 1), 0)
+	for y := -radius; y <= radius; y++ {
+		for x := -radius; x <= radius; x++ {
+			d := x*x + y*y
+			if d > outer || (fillMode != 2 && d < inner*inner) {
+				continue
+			}
+			if p := image.Pt(cx+x, cy+y); p.In(dst.Bounds()) {
+				dst.SetRGBA(p.X, p.Y, c)
+			}
+		}
+	}
+}
+
+// pixelColor はピクチャーの座標の色を 0xRRGGBB 形式で返す（範囲外の場合は0）
+func (hgs *HeadlessGraphicsSystem) pixelColor(picID, x, y int) int {
+	hgs.pictureMu.RLock()
+	defer hgs.pictureMu.RUnlock()
+
+	dst := hgs.pixels(picID)
+	if dst == nil || !image.Pt(x, y).In(dst.Bounds()) {
+		return 0
+	}
+	return ColorToInt(dst.RGBAAt(x, y))
+}
+
+// Frame は仮想デスクトップの現在の画面を合成して返す（WithHeadlessRendering が必要、無効の場合は nil）
+// 表示中のウィンドウをZ順序の奥から描画し、各ウィンドウには装飾・背景色・ピクチャー・キャストの順に描画する
+func (hgs *HeadlessGraphicsSystem) Frame() *image.RGBA {
+	if !hgs.rendering() {
+		return nil
+	}
+	frame := image.NewRGBA(image.Rect(0, 0, hgs.virtualWidth, hgs.virtualHeight))
+	draw.Draw(frame, frame.Bounds(), image.NewUniform(color.Black), image.Point{}, draw.Src)
+
+	windows := hgs.GetWindows()
+	sort.SliceStable(windows, func(i, j int) bool { return windows[i].ZOrder < windows[j].ZOrder })
+
+	hgs.castMu.RLock()
+	casts := make([]HeadlessCast, 0, len(hgs.casts))
+	for _, cast := range hgs.casts {
+		if cast.Visible {
+			casts = append(casts, *cast)
+		}
+	}
+	hgs.castMu.RUnlock()
+	sort.Slice(casts, func(i, j int) bool {
+		if casts[i].ZOrder != casts[j].ZOrder {
+			return casts[i].ZOrder < casts[j].ZOrder
+		}
+		return casts[i].ID < casts[j].ID
+	})
+
+	hgs.pictureMu.RLock()
+	defer hgs.pictureMu.RUnlock()
+	for _, win := range windows {
+		if win.Visible {
+			hgs.composeWindow(frame, win, casts)
+		}
+	}
+	return frame
+}
+
+// composeWindow はウィンドウを frame に描画する
+// 呼び出し元は hgs.pictureMu のロックを保持していること
+func (hgs *HeadlessGraphicsSystem) composeWindow(frame *image.RGBA, win HeadlessWindow, casts []HeadlessCast) {
+	pic := hgs.pixels(win.PicID)
+	width, height := win.Width, win.Height
+	if pic != nil {
+		if width <= 0 {
+			width = pic.Bounds().Dx()
+		}
+		if height <= 0 {
+			height = pic.Bounds().Dy()
+		}
+	}
+
+	// 装飾（枠・立体効果・タイトルバー・キャプション）
+	outer := image.Rect(0, 0, width+BorderThickness*2, height+BorderThickness*2+TitleBarHeight).Add(image.Pt(win.X, win.Y))
+	fillRect(frame, outer, headlessBorderColor)
+	fillRect(frame, image.Rect(outer.Min.X, outer.Min.Y, outer.Max.X, outer.Min.Y+1), headlessHighlightColor)
+	fillRect(frame, image.Rect(outer.Min.X, outer.Min.Y, outer.Min.X+1, outer.Max.Y), headlessHighlightColor)
+	fillRect(frame, image.Rect(outer.Min.X, outer.Max.Y-1, outer.Max.X, outer.Max.Y), headlessShadowColor)
+	fillRect(frame, image.Rect(outer.Max.X-1, outer.Min.Y, outer.Max.X, outer.Max.Y), headlessShadowColor)
+	titleBar := image.Rect(0, 0, width, TitleBarHeight).Add(outer.Min.Add(image.Pt(BorderThickness, BorderThickness)))
+	fillRect(frame, titleBar, headlessTitleBarColor)
+	if win.Caption != "" {
+		drawer := &font.Drawer{
+			Dst:  frame.SubImage(titleBar).(*image.RGBA),
+			Src:  image.NewUniform(color.White),
+			Face: headlessFace,
+			Dot:  fixed.P(titleBar.Min.X+4, titleBar.Min.Y+2+12),
+		}
+		drawer.DrawString(win.Caption)
+	}
+
+	// コンテンツ領域（背景色・ピクチャー・キャスト）
+	content := image.Rect(0, 0, width, height).Add(image.Pt(titleBar.Min.X, titleBar.Max.Y))
+	canvas, ok := frame.SubImage(content).(*image.RGBA)
+	if !ok || canvas.Bounds().Empty() {
+		return
+	}
+	if win.BgColor != nil {
+		fillRect(canvas, content, win.BgColor)
+	}
+	if pic == nil {
+		return
+	}
+	origin := content.Min.Sub(image.Pt(win.PicX, win.PicY))
+	draw.Draw(canvas, pic.Bounds().Add(origin), pic, image.Point{}, draw.Over)
+
+	for _, cast := range casts {
+		if cast.dstPicID != win.PicID {
+			continue
+		}
+		src := hgs.pixels(cast.srcPicID)
+		if src == nil {
+			continue
+		}
+		rect := image.Rect(cast.SrcX, cast.SrcY, cast.SrcX+cast.Width, cast.SrcY+cast.Height)
+		drawKeyed(canvas, origin.Add(image.Pt(cast.X, cast.Y)), src, rect, cast.transColor)
+	}
+}
+
+// drawKeyed は src の rect の範囲を dst の at の位置に描画する
+// transColor が nil でない場合はその色の画素を描画しない（キャストの透明色）
+func drawKeyed(dst *image.RGBA, at image.Point, src *image.RGBA, rect image.Rectangle, transColor color.Color) {
+	rect = rect.Intersect(src.Bounds())
+	if transColor == nil {
+		draw.Draw(dst, image.Rectangle{Min: at, Max: at.Add(rect.Size())}, src, rect.Min, draw.Over)
+		return
+	}
+	key := color.RGBAModel.Convert(transColor).(color.RGBA)
+	for y := rect.Min.Y; y < rect.Max.Y; y++ {
+		for x := rect.Min.X; x < rect.Max.X; x++ {
+			c := src.RGBAAt(x, y)
+			if sameRGB(c, key) || c.A == 0 {
+				continue
+			}
+			if p := at.Add(image.Pt(x-rect.Min.X, y-rect.Min.Y)); p.In(dst.Bounds()) {
+				dst.SetRGBA(p.X, p.Y, c)
+			}
+		}
+	}
+}
+
+// plotLine は太さ size の直線を描画する（ブレゼンハムのアルゴリズムで点を打ち、各点に size 四方の正方形を置く）
+func plotLine(dst *image.RGBA, x1, y1, x2, y2, size int, c color.Color) {
+	dx, dy := x2-x1, y1-y2
+	sx, sy := 1, 1
+	if dx < 0 {
+		dx, sx = -dx, -1
+	}
+	if dy > 0 {
+		dy, sy = -dy, -1
+	}
+	half := (size - 1) / 2
+	for err := dx + dy; ; {
+		fillRect(dst, image.Rect(x1-half, y1-half, x1-half+size, y1-half+size), c)
+		if x1 == x2 && y1 == y2 {
+			return
+		}
+		e2 := 2 * err
+		if e2 >= dy {
+			err += dy
+			x1 += sx
+		}
+		if e2 <= dx {
+			err += dx
+			y1 += sy
+		}
+	}
+}
+
+// fillRect は dst の rect の範囲を c で塗りつぶす
+func fillRect(dst *image.RGBA, rect image.Rectangle, c color.Color) {
+	draw.Draw(dst, rect, image.NewUniform(c), image.Point{}, draw.Src)
+}
+
+// sameRGB は2つの色のRGB成分が等しいかどうかを返す（アルファは比較しない）
+func sameRGB(a, b color.RGBA) bool {
+	return a.R == b.R && a.G == b.G && a.B == b.B
+}
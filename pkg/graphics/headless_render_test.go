@@ -0,0 +1,165 @@
+package graphics
+
+import (
+	"image"
+	"image/color"
+	"image/png"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/zurustar/son-et/pkg/fileutil"
+)
+
+// writeTestPNG は width x height を c で塗りつぶしたPNGファイルを dir に書き出す
+func writeTestPNG(t *testing.T, dir, name string, width, height int, c color.Color) {
+	t.Helper()
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	fillRect(img, img.Bounds(), c)
+	f, err := os.Create(filepath.Join(dir, name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	if err := png.Encode(f, img); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// newRenderingHeadless はソフトウェア描画を有効にした HeadlessGraphicsSystem を作成する
+// dir には 40x30 の赤い back.png と 8x8 の緑の sprite.png を置く
+func newRenderingHeadless(t *testing.T) *HeadlessGraphicsSystem {
+	t.Helper()
+	dir := t.TempDir()
+	writeTestPNG(t, dir, "back.png", 40, 30, color.RGBA{255, 0, 0, 255})
+	writeTestPNG(t, dir, "sprite.png", 8, 8, color.RGBA{0, 255, 0, 255})
+	return NewHeadlessGraphicsSystem(
+		WithLogOperations(false),
+		WithHeadlessVirtualSize(200, 150),
+		WithHeadlessRendering(fileutil.NewRealFS(dir)),
+	)
+}
+
+func TestHeadlessRenderingLoadPic(t *testing.T) {
+	hgs := newRenderingHeadless(t)
+
+	picID, err := hgs.LoadPic("/back.png")
+	if err != nil {
+		t.Fatalf("LoadPic failed: %v", err)
+	}
+	if w, h := hgs.PicWidth(picID), hgs.PicHeight(picID); w != 40 || h != 30 {
+		t.Errorf("picture size = %dx%d, want 40x30", w, h)
+	}
+	if c, _ := hgs.GetColor(picID, 5, 5); c != 0xFF0000 {
+		t.Errorf("GetColor = 0x%06X, want 0xFF0000", c)
+	}
+
+	if _, err := hgs.LoadPic("missing.png"); err == nil {
+		t.Error("LoadPic of a missing file should fail")
+	}
+}
+
+func TestHeadlessRenderingDrawing(t *testing.T) {
+	hgs := newRenderingHeadless(t)
+	back, _ := hgs.LoadPic("back.png")
+	sprite, _ := hgs.LoadPic("sprite.png")
+
+	hgs.MovePic(sprite, 0, 0, 8, 8, back, 2, 2, 0)
+	if c, _ := hgs.GetColor(back, 5, 5); c != 0x00FF00 {
+		t.Errorf("after MovePic: GetColor = 0x%06X, want 0x00FF00", c)
+	}
+
+	hgs.FillRect(back, 10, 10, 20, 20, 0x0000FF)
+	if c, _ := hgs.GetColor(back, 15, 15); c != 0x0000FF {
+		t.Errorf("after FillRect: GetColor = 0x%06X, want 0x0000FF", c)
+	}
+	if c, _ := hgs.GetColor(back, 20, 20); c != 0xFF0000 {
+		t.Errorf("FillRect should not include the bottom-right corner: GetColor = 0x%06X", c)
+	}
+
+	// 黒を透明色として転送すると、転送先の色が残る
+	black, _ := hgs.CreatePic(4, 4)
+	hgs.FillRect(black, 0, 0, 4, 4, 0x000000)
+	hgs.TransPic(black, 0, 0, 4, 4, back, 30, 0, 0x000000)
+	if c, _ := hgs.GetColor(back, 31, 1); c != 0xFF0000 {
+		t.Errorf("TransPic copied the transparent color: GetColor = 0x%06X", c)
+	}
+
+	hgs.SetPaintColor(0xFFFFFF)
+	hgs.DrawLine(back, 0, 29, 39, 29)
+	if c, _ := hgs.GetColor(back, 20, 29); c != 0xFFFFFF {
+		t.Errorf("after DrawLine: GetColor = 0x%06X, want 0xFFFFFF", c)
+	}
+}
+
+func TestHeadlessRenderingFrame(t *testing.T) {
+	hgs := newRenderingHeadless(t)
+	back, _ := hgs.LoadPic("back.png")
+	sprite, _ := hgs.LoadPic("sprite.png")
+
+	if _, err := hgs.OpenWin(back, 10, 20); err != nil {
+		t.Fatalf("OpenWin failed: %v", err)
+	}
+	castID, err := hgs.PutCastWithTransColor(sprite, back, 5, 5, 0, 0, 8, 8, nil)
+	if err != nil {
+		t.Fatalf("PutCast failed: %v", err)
+	}
+
+	frame := hgs.Frame()
+	if frame.Bounds() != image.Rect(0, 0, 200, 150) {
+		t.Fatalf("frame bounds = %v, want the virtual desktop", frame.Bounds())
+	}
+
+	// ピクチャーはウィンドウの枠とタイトルバーの内側に描画される
+	contentX, contentY := 10+BorderThickness, 20+BorderThickness+TitleBarHeight
+	tests := []struct {
+		name string
+		x, y int
+		want color.RGBA
+	}{
+		{"desktop", 0, 0, color.RGBA{0, 0, 0, 255}},
+		{"title bar", contentX + 1, 20 + BorderThickness + 1, headlessTitleBarColor},
+		{"picture", contentX, contentY, color.RGBA{255, 0, 0, 255}},
+		{"cast", contentX + 5, contentY + 5, color.RGBA{0, 255, 0, 255}},
+		{"outside the content area", contentX + 40, contentY, headlessBorderColor},
+	}
+	for _, tt := range tests {
+		if got := frame.RGBAAt(tt.x, tt.y); got != tt.want {
+			t.Errorf("%s: pixel (%d, %d) = %v, want %v", tt.name, tt.x, tt.y, got, tt.want)
+		}
+	}
+
+	// キャストを移動すると次のフレームに反映される
+	hgs.MoveCast(castID, 20, 5)
+	frame = hgs.Frame()
+	if got := frame.RGBAAt(contentX+5, contentY+5); got != (color.RGBA{255, 0, 0, 255}) {
+		t.Errorf("old cast position = %v, want the picture", got)
+	}
+	if got := frame.RGBAAt(contentX+20, contentY+5); got != (color.RGBA{0, 255, 0, 255}) {
+		t.Errorf("new cast position = %v, want the cast", got)
+	}
+}
+
+func TestHeadlessRenderingKeyframe(t *testing.T) {
+	hgs := newRenderingHeadless(t)
+	back, _ := hgs.LoadPic("back.png")
+
+	kf, err := hgs.CaptureKeyframe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	hgs.FillRect(back, 0, 0, 40, 30, 0x0000FF)
+	if err := hgs.RestoreKeyframe(kf); err != nil {
+		t.Fatal(err)
+	}
+	if c, _ := hgs.GetColor(back, 0, 0); c != 0xFF0000 {
+		t.Errorf("after RestoreKeyframe: GetColor = 0x%06X, want 0xFF0000", c)
+	}
+}
+
+func TestHeadlessWithoutRendering(t *testing.T) {
+	hgs := NewHeadlessGraphicsSystem(WithLogOperations(false))
+	if frame := hgs.Frame(); frame != nil {
+		t.Errorf("Frame() without WithHeadlessRendering = %v, want nil", frame.Bounds())
+	}
+}
@@ -4,6 +4,9 @@ import (
 	"log/slog"
 	"os"
 	"testing"
+	"time"
+
+	"github.com/zurustar/son-et/pkg/eventbus"
 )
 
 func TestNewHeadlessGraphicsSystem(t *testing.T) {
@@ -178,6 +181,39 @@ func TestHeadlessGraphicsSystem_CastManagement(t *testing.T) {
 	}
 }
 
+func TestHeadlessGraphicsSystem_GetCasts(t *testing.T) {
+	hgs := NewHeadlessGraphicsSystem(WithLogOperations(false))
+	picID, _ := hgs.CreatePic(640, 480)
+	winID, _ := hgs.OpenWin(picID, 10, 20)
+	first, _ := hgs.PutCast(winID, picID, 10, 20, 0, 0, 32, 32)
+	second, _ := hgs.PutCast(winID, picID, 30, 40, 0, 0, 32, 32)
+	_ = hgs.MoveCast(first, 50, 60)
+
+	cast, ok := hgs.GetCast(first)
+	if !ok || cast.X != 50 || cast.Y != 60 {
+		t.Errorf("GetCast(%d) = %+v, %v; want position (50, 60)", first, cast, ok)
+	}
+	// コピーを返すため、変更しても内部の状態は変わらない
+	cast.X = 0
+	if again, _ := hgs.GetCast(first); again.X != 50 {
+		t.Error("GetCast should return a copy")
+	}
+
+	casts := hgs.GetCasts()
+	if len(casts) != 2 || casts[0].ID != first || casts[1].ID != second {
+		t.Errorf("GetCasts() = %+v, want casts %d and %d in ID order", casts, first, second)
+	}
+	windows := hgs.GetWindows()
+	if len(windows) != 1 || windows[0].ID != winID || windows[0].X != 10 {
+		t.Errorf("GetWindows() = %+v", windows)
+	}
+
+	_ = hgs.DelCast(first)
+	if _, ok := hgs.GetCast(first); ok {
+		t.Error("GetCast should report deleted casts as missing")
+	}
+}
+
 func TestHeadlessGraphicsSystem_CloseWinAll(t *testing.T) {
 	hgs := NewHeadlessGraphicsSystem(WithLogOperations(false))
 
@@ -275,6 +311,68 @@ func TestHeadlessGraphicsSystem_TextOperations(t *testing.T) {
 	}
 }
 
+func TestHeadlessGraphicsSystem_TextExtent(t *testing.T) {
+	hgs := NewHeadlessGraphicsSystem(WithLogOperations(false))
+	hgs.SetFont("Arial", 20)
+
+	// 現在のフォントサイズ: 半角は半分の幅
+	width, height := hgs.TextExtent("abc", "", 0)
+	if width != 30 || height != 20 {
+		t.Errorf("expected 30x20, got %dx%d", width, height)
+	}
+
+	// フォント指定: 全角はサイズと同じ幅
+	width, height = hgs.TextExtent("あいa", "MS Gothic", 16)
+	if width != 40 || height != 16 {
+		t.Errorf("expected 40x16, got %dx%d", width, height)
+	}
+}
+
+func TestHeadlessGraphicsSystem_TextExtentVertical(t *testing.T) {
+	hgs := NewHeadlessGraphicsSystem(WithLogOperations(false))
+	hgs.SetFont("ＭＳ 明朝", 20)
+	hgs.SetTextDirection(TextVertical)
+
+	// 縦書き: 幅は列の幅（サイズ）、高さは文字の幅の合計
+	width, height := hgs.TextExtent("春はab", "", 0)
+	if width != 20 || height != 60 {
+		t.Errorf("expected 20x60, got %dx%d", width, height)
+	}
+
+	hgs.SetTextDirection(TextHorizontal)
+	if width, height = hgs.TextExtent("春はab", "", 0); width != 60 || height != 20 {
+		t.Errorf("expected 60x20 after switching back, got %dx%d", width, height)
+	}
+}
+
+func TestHeadlessGraphicsSystem_Fade(t *testing.T) {
+	hgs := NewHeadlessGraphicsSystem(WithLogOperations(false))
+	defer hgs.Shutdown()
+
+	done := make(chan struct{}, 2)
+	if err := hgs.Fade(true, 0x000000, time.Millisecond, func() { done <- struct{}{} }); err != nil {
+		t.Fatalf("Fade failed: %v", err)
+	}
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("fade callback was not called")
+	}
+
+	// 新しいフェードは実行中のフェードを置き換える
+	hgs.Fade(true, 0x000000, time.Hour, func() { done <- struct{}{} })
+	hgs.Fade(false, 0x000000, time.Millisecond, func() { done <- struct{}{} })
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("fade callback was not called")
+	}
+
+	if err := hgs.Fade(true, "black", time.Millisecond, nil); err == nil {
+		t.Error("Fade with invalid color should return error")
+	}
+}
+
 func TestHeadlessGraphicsSystem_PictureTransfer(t *testing.T) {
 	hgs := NewHeadlessGraphicsSystem(WithLogOperations(false))
 
@@ -370,3 +468,79 @@ func TestHeadlessGraphicsSystem_OperationHistoryDisabled(t *testing.T) {
 		t.Errorf("expected 0 operations when history disabled, got %d", len(history))
 	}
 }
+
+func TestHeadlessGraphicsSystem_RenderOrder(t *testing.T) {
+	hgs := NewHeadlessGraphicsSystem(WithLogOperations(false))
+	pic, _ := hgs.CreatePic(100, 100)
+	win0, _ := hgs.OpenWin(pic)
+	win1, _ := hgs.OpenWin(pic)
+
+	if err := hgs.BringWinToFront(win0); err != nil {
+		t.Fatalf("BringWinToFront failed: %v", err)
+	}
+	if hgs.windows[win0].ZOrder <= hgs.windows[win1].ZOrder {
+		t.Error("win0 should be in front of win1")
+	}
+	if err := hgs.SendWinToBack(win0); err != nil {
+		t.Fatalf("SendWinToBack failed: %v", err)
+	}
+	if hgs.windows[win0].ZOrder >= hgs.windows[win1].ZOrder {
+		t.Error("win0 should be behind win1")
+	}
+
+	c0, _ := hgs.PutCast(win0, pic, 0, 0, 0, 0, 10, 10)
+	c1, _ := hgs.PutCast(win0, pic, 0, 0, 0, 0, 10, 10)
+	if err := hgs.BringCastToFront(c0); err != nil {
+		t.Fatalf("BringCastToFront failed: %v", err)
+	}
+	if hgs.casts[c0].ZOrder <= hgs.casts[c1].ZOrder {
+		t.Error("cast 0 should be in front of cast 1")
+	}
+	if err := hgs.SendCastToBack(c0); err != nil {
+		t.Fatalf("SendCastToBack failed: %v", err)
+	}
+	if hgs.casts[c0].ZOrder >= hgs.casts[c1].ZOrder {
+		t.Error("cast 0 should be behind cast 1")
+	}
+
+	if err := hgs.BringWinToFront(99); err == nil {
+		t.Error("expected error for unknown window")
+	}
+	if err := hgs.SendCastToBack(99); err == nil {
+		t.Error("expected error for unknown cast")
+	}
+}
+
+func TestHeadlessGraphicsSystem_EventBus(t *testing.T) {
+	bus := eventbus.New()
+	sub := bus.Subscribe("sprite.cast.*", 0)
+	hgs := NewHeadlessGraphicsSystem(WithHeadlessEventBus(bus))
+
+	pic, _ := hgs.CreatePic(100, 100)
+	win, _ := hgs.OpenWin(pic)
+	castID, _ := hgs.PutCast(win, pic, 10, 20, 0, 0, 10, 10)
+	if err := hgs.MoveCast(castID, 30, 40); err != nil {
+		t.Fatalf("MoveCast failed: %v", err)
+	}
+	if err := hgs.DelCast(castID); err != nil {
+		t.Fatalf("DelCast failed: %v", err)
+	}
+
+	var topics []string
+	for len(sub.C()) > 0 {
+		msg := <-sub.C()
+		topics = append(topics, msg.Topic)
+		if msg.Topic == TopicCastMove && (msg.Data["x"] != 30 || msg.Data["y"] != 40) {
+			t.Errorf("move message should carry the new position, got %v", msg.Data)
+		}
+	}
+	want := []string{TopicCastPut, TopicCastMove, TopicCastDelete}
+	if len(topics) != len(want) {
+		t.Fatalf("topics = %v, want %v", topics, want)
+	}
+	for i := range want {
+		if topics[i] != want[i] {
+			t.Errorf("topics[%d] = %q, want %q", i, topics[i], want[i])
+		}
+	}
+}
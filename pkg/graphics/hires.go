@@ -0,0 +1,70 @@
+// hires.go は高解像度（@2x）の画像の選び方を提供する
+//
+// プロジェクトは pic01.bmp の隣に縦横2倍の大きさの pic01@2x.bmp（PNG も可）を置ける。
+// 実効の表示スケールが hiresMinDisplayScale 以上の場合、LoadPic は @2x の画像も読み込み、
+// ゲームループは仮想デスクトップの1ピクセルを2ピクセルとして描いた画面を表示する。
+// スクリプトから見えるピクチャーの大きさや座標は変わらない。
+package graphics
+
+import (
+	"image"
+	"path/filepath"
+	"strings"
+)
+
+const (
+	// hiresVariantScale は @2x の画像の倍率
+	hiresVariantScale = 2
+	// hiresVariantSuffix は @2x の画像のファイル名で拡張子の前に付ける文字列
+	hiresVariantSuffix = "@2x"
+	// hiresMinDisplayScale は @2x の画像を使う実効の表示スケールの下限
+	// これより小さい場合は @2x の画像を縮小して表示することになり、等倍の画像と見分けがつかない
+	hiresMinDisplayScale = 1.5
+)
+
+// hiresVariantName は filename の @2x の画像のファイル名を返す（例: pic01.bmp → pic01@2x.bmp）
+func hiresVariantName(filename string) string {
+	ext := filepath.Ext(filename)
+	return strings.TrimSuffix(filename, ext) + hiresVariantSuffix + ext
+}
+
+// prefersHires は実効の表示スケールで @2x の画像を使うかを返す
+func prefersHires(displayScale float64) bool {
+	return displayScale >= hiresMinDisplayScale
+}
+
+// isHiresVariantOf は hires の大きさが base のちょうど hiresVariantScale 倍かを返す
+func isHiresVariantOf(hires, base image.Rectangle) bool {
+	return hires.Dx() == base.Dx()*hiresVariantScale && hires.Dy() == base.Dy()*hiresVariantScale
+}
+
+// hiresRect は等倍の画像の矩形 r に対応する @2x の画像の矩形を返す
+func hiresRect(r image.Rectangle) image.Rectangle {
+	return image.Rectangle{Min: r.Min.Mul(hiresVariantScale), Max: r.Max.Mul(hiresVariantScale)}
+}
+
+// downscaleHires は @2x の画像を 2x2 ピクセルの平均で等倍の画像にする
+// 等倍の画像がなく @2x の画像だけがある場合に、スクリプトから見えるピクチャーとして使う
+func downscaleHires(src *image.RGBA) *image.RGBA {
+	b := src.Bounds()
+	dst := image.NewRGBA(image.Rect(0, 0, b.Dx()/hiresVariantScale, b.Dy()/hiresVariantScale))
+	const n = hiresVariantScale * hiresVariantScale
+	for y := range dst.Rect.Dy() {
+		for x := range dst.Rect.Dx() {
+			var sum [4]int
+			for dy := range hiresVariantScale {
+				for dx := range hiresVariantScale {
+					i := src.PixOffset(b.Min.X+x*hiresVariantScale+dx, b.Min.Y+y*hiresVariantScale+dy)
+					for c := range sum {
+						sum[c] += int(src.Pix[i+c])
+					}
+				}
+			}
+			i := dst.PixOffset(x, y)
+			for c := range sum {
+				dst.Pix[i+c] = uint8((sum[c] + n/2) / n)
+			}
+		}
+	}
+	return dst
+}
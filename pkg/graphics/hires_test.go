@@ -0,0 +1,89 @@
+package graphics
+
+import (
+	"image"
+	"image/color"
+	"testing"
+)
+
+func TestHiresVariantName(t *testing.T) {
+	tests := []struct {
+		filename string
+		want     string
+	}{
+		{"pic01.bmp", "pic01@2x.bmp"},
+		{"images/BG.PNG", "images/BG@2x.PNG"},
+		{"title.v2.bmp", "title.v2@2x.bmp"},
+		{"noext", "noext@2x"},
+	}
+	for _, tt := range tests {
+		if got := hiresVariantName(tt.filename); got != tt.want {
+			t.Errorf("hiresVariantName(%q) = %q, want %q", tt.filename, got, tt.want)
+		}
+	}
+}
+
+func TestPrefersHires(t *testing.T) {
+	for _, tt := range []struct {
+		scale float64
+		want  bool
+	}{
+		{0, false},
+		{1, false},
+		{1.49, false},
+		{1.5, true},
+		{2, true},
+		{3, true},
+	} {
+		if got := prefersHires(tt.scale); got != tt.want {
+			t.Errorf("prefersHires(%v) = %v, want %v", tt.scale, got, tt.want)
+		}
+	}
+}
+
+func TestIsHiresVariantOf(t *testing.T) {
+	base := image.Rect(0, 0, 320, 240)
+	if !isHiresVariantOf(image.Rect(0, 0, 640, 480), base) {
+		t.Error("640x480 should be the @2x variant of 320x240")
+	}
+	for _, r := range []image.Rectangle{
+		image.Rect(0, 0, 320, 240),
+		image.Rect(0, 0, 640, 481),
+		image.Rect(0, 0, 960, 720),
+	} {
+		if isHiresVariantOf(r, base) {
+			t.Errorf("%v should not be the @2x variant of %v", r, base)
+		}
+	}
+}
+
+func TestHiresRect(t *testing.T) {
+	if got := hiresRect(image.Rect(10, 20, 42, 52)); got != image.Rect(20, 40, 84, 104) {
+		t.Errorf("hiresRect = %v, want (20,40)-(84,104)", got)
+	}
+}
+
+func TestDownscaleHires(t *testing.T) {
+	src := image.NewRGBA(image.Rect(0, 0, 4, 2))
+	// 左の 2x2 は白と黒が半分ずつ、右の 2x2 は赤
+	src.Set(0, 0, color.RGBA{255, 255, 255, 255})
+	src.Set(1, 0, color.RGBA{0, 0, 0, 255})
+	src.Set(0, 1, color.RGBA{0, 0, 0, 255})
+	src.Set(1, 1, color.RGBA{255, 255, 255, 255})
+	for y := range 2 {
+		for x := 2; x < 4; x++ {
+			src.Set(x, y, color.RGBA{255, 0, 0, 255})
+		}
+	}
+
+	dst := downscaleHires(src)
+	if got := dst.Bounds(); got != image.Rect(0, 0, 2, 1) {
+		t.Fatalf("bounds = %v, want 2x1", got)
+	}
+	if got := dst.RGBAAt(0, 0); got != (color.RGBA{128, 128, 128, 255}) {
+		t.Errorf("left pixel = %v, want the average gray", got)
+	}
+	if got := dst.RGBAAt(1, 0); got != (color.RGBA{255, 0, 0, 255}) {
+		t.Errorf("right pixel = %v, want red", got)
+	}
+}
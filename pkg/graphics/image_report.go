@@ -0,0 +1,244 @@
+package graphics
+
+import (
+	"fmt"
+	"runtime"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// 画像のメモリ使用量とリークの候補
+// ピクチャー（LoadPic・CreatePic などで作成した画像）ごとに、作成した文と最後に表示された時刻を記録する。
+// 表示中のウィンドウ・キャストから参照されないまま一定時間が過ぎたピクチャーを、
+// DelPic の忘れの候補として報告する（MovePic の素材として使い続けている画像も候補に含まれる）
+
+// DefaultLeakIdle は表示されていないピクチャーをリークの候補とするまでの既定の時間
+const DefaultLeakIdle = 30 * time.Second
+
+// bytesPerPixel はピクチャーの1ピクセルあたりのメモリ（RGBA）
+const bytesPerPixel = 4
+
+// ImageUsage はピクチャー1枚のメモリ使用量と表示の状況
+type ImageUsage struct {
+	PicID   int
+	Width   int
+	Height  int
+	Bytes   int64         // 画像のメモリ（幅×高さ×4）
+	Site    string        // 作成した文（例: `sequence 2: LoadPic("BG.BMP")`）
+	Age     time.Duration // 作成してからの時間
+	Idle    time.Duration // 表示中のウィンドウ・キャストから参照されていない時間（表示中は0）
+	Windows []int         // 最後に表示していたウィンドウ
+	Casts   []int         // 最後に表示していたキャスト
+}
+
+// ImageReport は画像のメモリ使用量とリークの候補の報告
+type ImageReport struct {
+	Pictures     int           // 作成されているピクチャー数
+	PictureBytes int64         // ピクチャーのメモリの合計
+	HeapBytes    uint64        // Goのヒープの使用量
+	Idle         time.Duration // リークの候補とする表示されていない時間
+	Leaks        []ImageUsage  // リークの候補（表示されていない時間の長い順）
+}
+
+// String は報告を1行ずつの文字列にする（オペレーターのコンソール用）
+func (r *ImageReport) String() string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "%d pictures, %s (heap %s)\n", r.Pictures, formatBytes(r.PictureBytes), formatBytes(int64(r.HeapBytes)))
+	if len(r.Leaks) == 0 {
+		fmt.Fprintf(&sb, "no pictures hidden for %v or more", r.Idle)
+		return sb.String()
+	}
+	fmt.Fprintf(&sb, "%d pictures hidden for %v or more:", len(r.Leaks), r.Idle)
+	for _, u := range r.Leaks {
+		fmt.Fprintf(&sb, "\n  pic %d %dx%d %s hidden %v", u.PicID, u.Width, u.Height, formatBytes(u.Bytes), u.Idle.Round(time.Second))
+		if u.Site != "" {
+			fmt.Fprintf(&sb, " from %s", u.Site)
+		}
+		if len(u.Windows) > 0 || len(u.Casts) > 0 {
+			fmt.Fprintf(&sb, " (last shown by windows %v casts %v)", u.Windows, u.Casts)
+		}
+	}
+	return sb.String()
+}
+
+// formatBytes はバイト数をKB・MB単位で表す
+func formatBytes(n int64) string {
+	switch {
+	case n >= 1<<20:
+		return fmt.Sprintf("%.1fMB", float64(n)/(1<<20))
+	case n >= 1<<10:
+		return fmt.Sprintf("%.1fKB", float64(n)/(1<<10))
+	}
+	return fmt.Sprintf("%dB", n)
+}
+
+// pictureRef はピクチャーを表示するウィンドウ・キャスト（ウィンドウの winID は -1）
+type pictureRef struct {
+	id, picID, winID int
+	visible          bool
+}
+
+// imageViewers はピクチャーを表示しているウィンドウとキャスト
+type imageViewers struct {
+	windows, casts []int
+}
+
+// shownPictures は表示中のウィンドウと、表示中のウィンドウにある表示中のキャストが参照するピクチャーを返す
+func shownPictures(windows, casts []pictureRef) map[int]*imageViewers {
+	shown := make(map[int]*imageViewers)
+	viewers := func(picID int) *imageViewers {
+		v := shown[picID]
+		if v == nil {
+			v = &imageViewers{}
+			shown[picID] = v
+		}
+		return v
+	}
+	visibleWindows := make(map[int]bool)
+	for _, w := range windows {
+		if w.visible {
+			visibleWindows[w.id] = true
+			v := viewers(w.picID)
+			v.windows = append(v.windows, w.id)
+		}
+	}
+	for _, c := range casts {
+		if c.visible && visibleWindows[c.winID] {
+			v := viewers(c.picID)
+			v.casts = append(v.casts, c.id)
+		}
+	}
+	for _, v := range shown {
+		slices.Sort(v.windows)
+		slices.Sort(v.casts)
+	}
+	return shown
+}
+
+// trackedImage は作成したピクチャーの記録
+type trackedImage struct {
+	width, height int
+	site          string
+	created       time.Time
+	lastShown     time.Time // 最後に表示されていた時刻（作成後に一度も表示されていない場合は作成した時刻）
+	shown         bool
+	windows       []int
+	casts         []int
+}
+
+// imageTracker は作成したピクチャーとその表示の状況を記録する
+type imageTracker struct {
+	mu     sync.Mutex
+	site   func() string // ピクチャーを作成した文を返す（nilの場合は記録しない）
+	images map[int]*trackedImage
+}
+
+func newImageTracker() *imageTracker {
+	return &imageTracker{images: make(map[int]*trackedImage)}
+}
+
+// setSite はピクチャーを作成した文を返す関数を設定する
+func (t *imageTracker) setSite(site func() string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.site = site
+}
+
+// add は作成したピクチャーを記録する
+func (t *imageTracker) add(picID, width, height int, now time.Time) {
+	t.mu.Lock()
+	site := t.site
+	t.mu.Unlock()
+
+	// site は VM の状態を読むため、ロックを解放してから呼び出す
+	img := &trackedImage{width: width, height: height, created: now, lastShown: now}
+	if site != nil {
+		img.site = site()
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.images[picID] = img
+}
+
+// remove は削除したピクチャーの記録を消す
+func (t *imageTracker) remove(picID int) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	delete(t.images, picID)
+}
+
+// snapshot は記録の複製を返す（キーフレーム用）
+func (t *imageTracker) snapshot() map[int]trackedImage {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	images := make(map[int]trackedImage, len(t.images))
+	for picID, img := range t.images {
+		images[picID] = *img
+	}
+	return images
+}
+
+// restore は記録を snapshot で複製した時点に戻す
+func (t *imageTracker) restore(images map[int]trackedImage) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.images = make(map[int]*trackedImage, len(images))
+	for picID, img := range images {
+		t.images[picID] = &img
+	}
+}
+
+// sample は now の時点で表示されているピクチャーを記録する
+func (t *imageTracker) sample(shown map[int]*imageViewers, now time.Time) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for picID, img := range t.images {
+		v, ok := shown[picID]
+		img.shown = ok
+		if ok {
+			img.lastShown = now
+			img.windows, img.casts = v.windows, v.casts
+		}
+	}
+}
+
+// report は記録しているピクチャーのメモリと、idle 以上表示されていないピクチャーを返す
+func (t *imageTracker) report(idle time.Duration, now time.Time) *ImageReport {
+	var mem runtime.MemStats
+	runtime.ReadMemStats(&mem)
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	r := &ImageReport{Pictures: len(t.images), HeapBytes: mem.HeapAlloc, Idle: idle, Leaks: []ImageUsage{}}
+	for picID, img := range t.images {
+		u := ImageUsage{
+			PicID:   picID,
+			Width:   img.width,
+			Height:  img.height,
+			Bytes:   int64(img.width) * int64(img.height) * bytesPerPixel,
+			Site:    img.site,
+			Age:     now.Sub(img.created),
+			Windows: img.windows,
+			Casts:   img.casts,
+		}
+		r.PictureBytes += u.Bytes
+		if img.shown {
+			continue
+		}
+		u.Idle = now.Sub(img.lastShown)
+		if u.Idle >= idle {
+			r.Leaks = append(r.Leaks, u)
+		}
+	}
+	sort.Slice(r.Leaks, func(i, j int) bool {
+		if r.Leaks[i].Idle != r.Leaks[j].Idle {
+			return r.Leaks[i].Idle > r.Leaks[j].Idle
+		}
+		return r.Leaks[i].PicID < r.Leaks[j].PicID
+	})
+	return r
+}
@@ -0,0 +1,101 @@
+package graphics
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestShownPictures(t *testing.T) {
+	windows := []pictureRef{
+		{id: 0, picID: 10, winID: -1, visible: true},
+		{id: 1, picID: 11, winID: -1, visible: false},
+	}
+	casts := []pictureRef{
+		{id: 5, picID: 20, winID: 0, visible: true},
+		{id: 3, picID: 20, winID: 0, visible: true},
+		{id: 6, picID: 21, winID: 0, visible: false}, // 非表示のキャスト
+		{id: 7, picID: 22, winID: 1, visible: true},  // 非表示のウィンドウのキャスト
+	}
+	shown := shownPictures(windows, casts)
+	if len(shown) != 2 {
+		t.Fatalf("shown pictures = %v, want 10 and 20", shown)
+	}
+	if v := shown[10]; v == nil || len(v.windows) != 1 || v.windows[0] != 0 {
+		t.Errorf("picture 10 viewers = %+v", v)
+	}
+	if v := shown[20]; v == nil || len(v.casts) != 2 || v.casts[0] != 3 || v.casts[1] != 5 {
+		t.Errorf("picture 20 viewers = %+v, want casts [3 5]", v)
+	}
+}
+
+func TestImageTrackerReport(t *testing.T) {
+	tracker := newImageTracker()
+	tracker.setSite(func() string { return `sequence 0: LoadPic("BG.BMP")` })
+
+	start := time.Now()
+	tracker.add(0, 640, 480, start)
+	tracker.add(1, 32, 32, start)
+	tracker.add(2, 16, 16, start.Add(20*time.Second))
+
+	// 0 は最初から表示し、1 は10秒後まで表示する
+	tracker.sample(map[int]*imageViewers{0: {windows: []int{0}}, 1: {casts: []int{4}}}, start)
+	tracker.sample(map[int]*imageViewers{0: {windows: []int{0}}, 1: {casts: []int{4}}}, start.Add(10*time.Second))
+	tracker.sample(map[int]*imageViewers{0: {windows: []int{0}}}, start.Add(20*time.Second))
+
+	r := tracker.report(15*time.Second, start.Add(40*time.Second))
+	if r.Pictures != 3 || r.PictureBytes != (640*480+32*32+16*16)*bytesPerPixel {
+		t.Errorf("report = %d pictures, %d bytes", r.Pictures, r.PictureBytes)
+	}
+	if len(r.Leaks) != 2 || r.Leaks[0].PicID != 1 || r.Leaks[1].PicID != 2 {
+		t.Fatalf("leaks = %+v, want pictures 1 and 2, longest hidden first", r.Leaks)
+	}
+	leak := r.Leaks[0]
+	if leak.Idle != 30*time.Second || leak.Age != 40*time.Second || leak.Site != `sequence 0: LoadPic("BG.BMP")` {
+		t.Errorf("leak = %+v", leak)
+	}
+	if len(leak.Casts) != 1 || leak.Casts[0] != 4 {
+		t.Errorf("last shown by casts %v, want [4]", leak.Casts)
+	}
+
+	tracker.remove(1)
+	if r := tracker.report(15*time.Second, start.Add(40*time.Second)); r.Pictures != 2 || len(r.Leaks) != 1 {
+		t.Errorf("after remove: %d pictures, %d leaks", r.Pictures, len(r.Leaks))
+	}
+}
+
+func TestImageReportString(t *testing.T) {
+	r := &ImageReport{Pictures: 2, PictureBytes: 3 << 20, HeapBytes: 2048, Idle: 30 * time.Second}
+	if got := r.String(); got != "2 pictures, 3.0MB (heap 2.0KB)\nno pictures hidden for 30s or more" {
+		t.Errorf("String() = %q", got)
+	}
+	r.Leaks = []ImageUsage{{PicID: 4, Width: 8, Height: 8, Bytes: 256, Site: "sequence 1: CreatePic(8, 8)", Idle: 45 * time.Second, Casts: []int{2}}}
+	want := "1 pictures hidden for 30s or more:\n  pic 4 8x8 256B hidden 45s from sequence 1: CreatePic(8, 8) (last shown by windows [] casts [2])"
+	if got := r.String(); !strings.HasSuffix(got, want) {
+		t.Errorf("String() = %q, want suffix %q", got, want)
+	}
+}
+
+func TestHeadlessImageReport(t *testing.T) {
+	hgs := NewHeadlessGraphicsSystem(WithLogOperations(false))
+	hgs.SetImageSite(func() string { return "sequence 0" })
+
+	shown, _ := hgs.CreatePic(100, 100)
+	hidden, _ := hgs.CreatePic(50, 50)
+	if _, err := hgs.OpenWin(shown); err != nil {
+		t.Fatal(err)
+	}
+
+	now := time.Now().Add(time.Minute)
+	r := hgs.ImageReport(DefaultLeakIdle, now)
+	if r.Pictures != 2 || len(r.Leaks) != 1 || r.Leaks[0].PicID != hidden || r.Leaks[0].Site != "sequence 0" {
+		t.Fatalf("report = %+v", r)
+	}
+
+	if err := hgs.DelPic(hidden); err != nil {
+		t.Fatal(err)
+	}
+	if r := hgs.ImageReport(DefaultLeakIdle, now); r.Pictures != 1 || len(r.Leaks) != 0 {
+		t.Errorf("after DelPic: %d pictures, leaks %+v", r.Pictures, r.Leaks)
+	}
+}
@@ -1,3 +1,5 @@
+//go:build !nogpu
+
 // Package graphics provides integration tests for the drawing loop.
 // These tests verify that the graphics system can properly render windows, pictures, and casts.
 package graphics
@@ -424,6 +426,43 @@ func TestIntegrationCastsByWindow(t *testing.T) {
 	t.Logf("Casts by window test passed")
 }
 
+// TestIntegrationCastAt tests hit-testing casts at virtual desktop coordinates.
+// 座標にはウィンドウ装飾（枠・タイトルバー）のオフセットが含まれる
+func TestIntegrationCastAt(t *testing.T) {
+	gs := NewGraphicsSystem("")
+
+	bgPicID1, _ := gs.CreatePic(200, 200)
+	bgPicID2, _ := gs.CreatePic(200, 200)
+	spritePicID, _ := gs.CreatePic(50, 50)
+
+	gs.OpenWin(bgPicID1, 0, 0, 200, 200, 0, 0, 0)
+	gs.OpenWin(bgPicID2, 300, 0, 200, 200, 0, 0, 0)
+
+	cast1ID, _ := gs.PutCast(spritePicID, bgPicID1, 10, 10, 0, 0, 50, 50)
+	cast2ID, _ := gs.PutCast(spritePicID, bgPicID1, 50, 50, 0, 0, 50, 50)
+	cast3ID, _ := gs.PutCast(spritePicID, bgPicID2, 20, 20, 0, 0, 50, 50)
+
+	contentX, contentY := BorderThickness, BorderThickness+TitleBarHeight
+	tests := []struct {
+		name string
+		x, y int
+		want int
+	}{
+		{"cast 1", contentX + 15, contentY + 15, cast1ID},
+		{"overlap picks front cast", contentX + 55, contentY + 55, cast2ID},
+		{"cast in second window", 300 + contentX + 25, contentY + 25, cast3ID},
+		{"window background", contentX + 150, contentY + 150, -1},
+		{"title bar", 15, 10, -1},
+		{"desktop", 250, 300, -1},
+	}
+
+	for _, tt := range tests {
+		if got := gs.CastAt(tt.x, tt.y); got != tt.want {
+			t.Errorf("%s: CastAt(%d, %d) = %d, want %d", tt.name, tt.x, tt.y, got, tt.want)
+		}
+	}
+}
+
 // TestIntegrationDrawWithPicOffset tests drawing with picture offset (PicX, PicY).
 func TestIntegrationDrawWithPicOffset(t *testing.T) {
 	gs := NewGraphicsSystem("")
@@ -0,0 +1,164 @@
+// keyframe.go は描画の状態のキーフレーム（巻き戻し用の複製）を提供する
+//
+// VMは一定のティックごとにスクリプトの状態とともに描画の状態を複製し（CaptureKeyframe）、
+// 後方へのシークではいちばん近いキーフレームに戻してから（RestoreKeyframe）目的の位置まで早送りする。
+// キーフレームは何度でも戻せるように、戻すたびにもう一度複製して使う。
+package graphics
+
+import (
+	"fmt"
+	"image/color"
+)
+
+// Keyframe は CaptureKeyframe で複製した描画の状態
+type Keyframe interface {
+	// Release はキーフレームが保持する画像を解放する（以後 RestoreKeyframe には使えない）
+	Release()
+}
+
+// headlessKeyframe は HeadlessGraphicsSystem のキーフレーム
+type headlessKeyframe struct {
+	pictures  map[int]HeadlessPicture
+	nextPicID int
+	images    map[int]trackedImage
+
+	windows        map[int]HeadlessWindow
+	nextWinID      int
+	nextZOrder     int
+	defaultCaption string
+
+	casts      map[int]HeadlessCast
+	nextCastID int
+
+	pools      map[int]*particleSet
+	nextPoolID int
+
+	paintColor, textColor, bgColor color.Color
+	lineSize, backMode, fontSize   int
+	direction                      TextDirection
+	fontName                       string
+
+	camera *Camera
+}
+
+// Release は何もしない（ヘッドレスモードのキーフレームが持つ画素はGCで解放される）
+func (kf *headlessKeyframe) Release() {}
+
+// clone はピクチャーの複製を返す（ソフトウェア描画の画素も複製する）
+func (pic *HeadlessPicture) clone() HeadlessPicture {
+	c := *pic
+	if pic.pixels != nil {
+		c.pixels = cloneRGBA(pic.pixels)
+	}
+	return c
+}
+
+// CaptureKeyframe はピクチャー・ウィンドウ・キャスト・スプライトプール・描画の設定・カメラを複製する
+func (hgs *HeadlessGraphicsSystem) CaptureKeyframe() (Keyframe, error) {
+	kf := &headlessKeyframe{
+		pictures: make(map[int]HeadlessPicture),
+		windows:  make(map[int]HeadlessWindow),
+		casts:    make(map[int]HeadlessCast),
+		pools:    make(map[int]*particleSet),
+	}
+
+	hgs.pictureMu.RLock()
+	for id, pic := range hgs.pictures {
+		kf.pictures[id] = pic.clone()
+	}
+	kf.nextPicID = hgs.nextPicID
+	hgs.pictureMu.RUnlock()
+	kf.images = hgs.images.snapshot()
+
+	hgs.windowMu.RLock()
+	for id, win := range hgs.windows {
+		kf.windows[id] = *win
+	}
+	kf.nextWinID, kf.nextZOrder, kf.defaultCaption = hgs.nextWinID, hgs.nextZOrder, hgs.defaultCaption
+	hgs.windowMu.RUnlock()
+
+	hgs.castMu.RLock()
+	for id, cast := range hgs.casts {
+		kf.casts[id] = *cast
+	}
+	kf.nextCastID = hgs.nextCastID
+	hgs.castMu.RUnlock()
+
+	hgs.poolMu.Lock()
+	for id, set := range hgs.pools {
+		kf.pools[id] = set.clone()
+	}
+	kf.nextPoolID = hgs.nextPoolID
+	hgs.poolMu.Unlock()
+
+	kf.paintColor, kf.textColor, kf.bgColor = hgs.paintColor, hgs.textColor, hgs.bgColor
+	kf.lineSize, kf.backMode, kf.fontSize = hgs.lineSize, hgs.backMode, hgs.fontSize
+	kf.direction, kf.fontName = hgs.direction, hgs.fontName
+
+	hgs.cameraMu.Lock()
+	if hgs.camera != nil {
+		c := *hgs.camera
+		kf.camera = &c
+	}
+	hgs.cameraMu.Unlock()
+
+	hgs.logOperation("CaptureKeyframe", "pictures", len(kf.pictures), "windows", len(kf.windows), "casts", len(kf.casts))
+	return kf, nil
+}
+
+// RestoreKeyframe は状態を CaptureKeyframe で複製した時点に戻す
+func (hgs *HeadlessGraphicsSystem) RestoreKeyframe(keyframe Keyframe) error {
+	kf, ok := keyframe.(*headlessKeyframe)
+	if !ok {
+		return fmt.Errorf("keyframe %T was not taken by the headless graphics system", keyframe)
+	}
+
+	hgs.pictureMu.Lock()
+	hgs.pictures = make(map[int]*HeadlessPicture, len(kf.pictures))
+	for id, pic := range kf.pictures {
+		restored := pic.clone()
+		hgs.pictures[id] = &restored
+	}
+	hgs.nextPicID = kf.nextPicID
+	hgs.pictureMu.Unlock()
+	hgs.images.restore(kf.images)
+
+	hgs.windowMu.Lock()
+	hgs.windows = make(map[int]*HeadlessWindow, len(kf.windows))
+	for id, win := range kf.windows {
+		hgs.windows[id] = &win
+	}
+	hgs.nextWinID, hgs.nextZOrder, hgs.defaultCaption = kf.nextWinID, kf.nextZOrder, kf.defaultCaption
+	hgs.windowMu.Unlock()
+
+	hgs.castMu.Lock()
+	hgs.casts = make(map[int]*HeadlessCast, len(kf.casts))
+	for id, cast := range kf.casts {
+		hgs.casts[id] = &cast
+	}
+	hgs.nextCastID = kf.nextCastID
+	hgs.castMu.Unlock()
+
+	hgs.poolMu.Lock()
+	hgs.pools = make(map[int]*particleSet, len(kf.pools))
+	for id, set := range kf.pools {
+		hgs.pools[id] = set.clone()
+	}
+	hgs.nextPoolID = kf.nextPoolID
+	hgs.poolMu.Unlock()
+
+	hgs.paintColor, hgs.textColor, hgs.bgColor = kf.paintColor, kf.textColor, kf.bgColor
+	hgs.lineSize, hgs.backMode, hgs.fontSize = kf.lineSize, kf.backMode, kf.fontSize
+	hgs.direction, hgs.fontName = kf.direction, kf.fontName
+
+	hgs.cameraMu.Lock()
+	hgs.camera = nil
+	if kf.camera != nil {
+		c := *kf.camera
+		hgs.camera = &c
+	}
+	hgs.cameraMu.Unlock()
+
+	hgs.logOperation("RestoreKeyframe", "pictures", len(kf.pictures), "windows", len(kf.windows), "casts", len(kf.casts))
+	return nil
+}
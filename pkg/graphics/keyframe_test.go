@@ -0,0 +1,94 @@
+package graphics
+
+import (
+	"testing"
+)
+
+// keyframeTestSystem はピクチャー・ウィンドウ・キャスト・スプライトプールを1つずつ持つヘッドレスの描画システムを返す
+func keyframeTestSystem(t *testing.T) (*HeadlessGraphicsSystem, int, int) {
+	t.Helper()
+	hgs := NewHeadlessGraphicsSystem(WithLogOperations(false))
+	picID, _ := hgs.CreatePic(320, 240)
+	if _, err := hgs.OpenWin(picID); err != nil {
+		t.Fatal(err)
+	}
+	castID, err := hgs.PutCast(picID, picID, 10, 20, 0, 0, 32, 32)
+	if err != nil {
+		t.Fatal(err)
+	}
+	poolID, err := hgs.CreateSpritePool(picID, picID, 4, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := hgs.SetPoolParticle(poolID, 0, 5, 6, true); err != nil {
+		t.Fatal(err)
+	}
+	return hgs, castID, poolID
+}
+
+func TestHeadlessKeyframe(t *testing.T) {
+	hgs, castID, poolID := keyframeTestSystem(t)
+	if err := hgs.SetCamera(&Camera{X: 100, Y: 50, Zoom: 2}); err != nil {
+		t.Fatal(err)
+	}
+
+	kf, err := hgs.CaptureKeyframe()
+	if err != nil {
+		t.Fatalf("CaptureKeyframe failed: %v", err)
+	}
+
+	// キーフレームの後の変更
+	if err := hgs.MoveCast(castID, 100, 120); err != nil {
+		t.Fatal(err)
+	}
+	if err := hgs.SetPoolParticle(poolID, 0, 50, 60, false); err != nil {
+		t.Fatal(err)
+	}
+	newPic, _ := hgs.CreatePic(16, 16)
+	if _, err := hgs.OpenWin(newPic); err != nil {
+		t.Fatal(err)
+	}
+	if err := hgs.SetCamera(nil); err != nil {
+		t.Fatal(err)
+	}
+
+	// 同じキーフレームに2回戻せる
+	for range 2 {
+		if err := hgs.RestoreKeyframe(kf); err != nil {
+			t.Fatalf("RestoreKeyframe failed: %v", err)
+		}
+		casts := hgs.GetCasts()
+		if len(casts) != 1 || casts[0].X != 10 || casts[0].Y != 20 {
+			t.Errorf("casts = %+v, want the cast at (10, 20)", casts)
+		}
+		if hgs.GetWindowCount() != 1 || hgs.PicWidth(newPic) != 0 {
+			t.Errorf("%d windows, picture %d width %d; want the picture and window created later removed",
+				hgs.GetWindowCount(), newPic, hgs.PicWidth(newPic))
+		}
+		if p := hgs.pools[poolID].particles[0]; p.x != 5 || p.y != 6 || !p.visible {
+			t.Errorf("particle = %+v, want visible at (5, 6)", p)
+		}
+		if hgs.camera == nil || *hgs.camera != (Camera{X: 100, Y: 50, Zoom: 2}) {
+			t.Errorf("camera = %+v, want the camera of the keyframe", hgs.camera)
+		}
+
+		// 戻した後の変更はキーフレームに影響しない
+		if err := hgs.MoveCast(castID, 1, 2); err != nil {
+			t.Fatal(err)
+		}
+		hgs.pools[poolID].particles[0].x = 99
+	}
+
+	// 新しいIDはキーフレームの時点から割り当てる
+	if id, _ := hgs.CreatePic(8, 8); id != newPic {
+		t.Errorf("CreatePic after restore = %d, want %d", id, newPic)
+	}
+	kf.Release()
+}
+
+func TestHeadlessKeyframeWrongType(t *testing.T) {
+	hgs := NewHeadlessGraphicsSystem(WithLogOperations(false))
+	if err := hgs.RestoreKeyframe(nil); err == nil {
+		t.Error("expected an error for a keyframe not taken by the headless graphics system")
+	}
+}
@@ -0,0 +1,45 @@
+package graphics
+
+import (
+	"fmt"
+	"slices"
+)
+
+// 合成のレイヤー（CaptureLayers）
+//
+// CaptureLayers は次のフレームを合成する途中の画像をレイヤーごとに PNG ファイルに書き出す。
+// z の誤り・クリップ・アルファなどの描画の不具合を、どのレイヤーで起きているかに切り分けるために使う。
+
+// CaptureLayers で書き出すレイヤーの名前
+const (
+	layerBackground = "background" // スプライトを描画する前の画面（デスクトップの背景）
+	layerWindows    = "windows"    // ウィンドウとピクチャーのスプライト
+	layerText       = "text"       // テキストのスプライト
+	layerOverlay    = "overlay"    // 画面フェードとカスタムカーソル（透明な画像に描画する）
+	layerFrame      = "frame"      // パレット・表示調整まで適用した最終的な画面
+)
+
+// spriteBandLayer は Z_Path の先頭（ウィンドウのZ順序）が band のスプライトのレイヤーの名前を返す
+// キャスト・図形など、ウィンドウ・ピクチャー・テキスト以外のスプライトはこのレイヤーに入る
+func spriteBandLayer(band int) string {
+	return fmt.Sprintf("sprites-z%d", band)
+}
+
+// sceneLayers はスプライトを描画するレイヤーの名前を書き出す順に返す
+// bands はスプライトのあるZ帯（順不同、重複してもよい）
+func sceneLayers(bands []int) []string {
+	bands = slices.Clone(bands)
+	slices.Sort(bands)
+	bands = slices.Compact(bands)
+
+	layers := []string{layerWindows}
+	for _, band := range bands {
+		layers = append(layers, spriteBandLayer(band))
+	}
+	return append(layers, layerText)
+}
+
+// layerFileName は合成する順で index 番目のレイヤーのファイル名（"02-sprites-z1.png"）を返す
+func layerFileName(index int, layer string) string {
+	return fmt.Sprintf("%02d-%s.png", index, layer)
+}
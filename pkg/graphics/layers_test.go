@@ -0,0 +1,25 @@
+package graphics
+
+import (
+	"slices"
+	"testing"
+)
+
+// TestSceneLayers tests that the z-bands are sorted, deduplicated and placed
+// between the windows and the text.
+func TestSceneLayers(t *testing.T) {
+	got := sceneLayers([]int{3, 1, 3, 0})
+	want := []string{"windows", "sprites-z0", "sprites-z1", "sprites-z3", "text"}
+	if !slices.Equal(got, want) {
+		t.Errorf("sceneLayers = %v, want %v", got, want)
+	}
+	if got := sceneLayers(nil); !slices.Equal(got, []string{"windows", "text"}) {
+		t.Errorf("sceneLayers(nil) = %v", got)
+	}
+}
+
+func TestLayerFileName(t *testing.T) {
+	if got := layerFileName(2, spriteBandLayer(1)); got != "02-sprites-z1.png" {
+		t.Errorf("layerFileName = %q", got)
+	}
+}
@@ -0,0 +1,11 @@
+package graphics
+
+// Mask はキャスト・ウィンドウの表示範囲を制限するマスク（SetCastMask / SetWinMask）
+// 座標はキャストの左上、ウィンドウの場合はコンテンツ領域の左上からの相対座標。
+// マスクの外側は表示されない。ウィンドウのマスクはウィンドウ内のキャストにも適用される。
+type Mask struct {
+	X, Y          int
+	Width, Height int  // 矩形マスクの大きさ（ピクチャーマスクでは無視し、ピクチャーの大きさを使う）
+	PicID         int  // ピクチャーマスクのピクチャー番号（UsePic が true の場合）
+	UsePic        bool // true の場合、ピクチャーの明るさを不透明度として使う（白は表示、黒は非表示）
+}
@@ -0,0 +1,16 @@
+//go:build !(linux || darwin || freebsd || netbsd || openbsd || dragonfly)
+
+package graphics
+
+import (
+	"errors"
+	"os"
+)
+
+// mmapFile はメモリマップに対応していないOSでは常にエラーを返す（ファイルを読み込んで使う）
+func mmapFile(*os.File) ([]byte, error) {
+	return nil, errors.New("memory mapping is not supported on this platform")
+}
+
+// munmapFile は何もしない
+func munmapFile([]byte) {}
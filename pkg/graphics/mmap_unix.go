@@ -0,0 +1,28 @@
+//go:build linux || darwin || freebsd || netbsd || openbsd || dragonfly
+
+package graphics
+
+import (
+	"fmt"
+	"os"
+	"syscall"
+)
+
+// mmapFile はファイル全体を読み取り専用でメモリマップする
+// 解除は munmapFile で行う
+func mmapFile(f *os.File) ([]byte, error) {
+	info, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+	size := info.Size()
+	if size <= 0 || int64(int(size)) != size {
+		return nil, fmt.Errorf("cannot map file of size %d", size)
+	}
+	return syscall.Mmap(int(f.Fd()), 0, int(size), syscall.PROT_READ, syscall.MAP_SHARED)
+}
+
+// munmapFile は mmapFile でマップした内容を解除する
+func munmapFile(data []byte) {
+	_ = syscall.Munmap(data)
+}
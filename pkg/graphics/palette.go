@@ -0,0 +1,213 @@
+package graphics
+
+import (
+	"fmt"
+	"image/color"
+	"sync"
+)
+
+// PaletteSize は256色表示エミュレーションのパレットの色数
+const PaletteSize = 256
+
+// パレットの量子化に使用するルックアップテーブルの精度（1チャンネルあたりのビット数）
+// 5ビット（32段階）×3チャンネルで32768エントリ
+const (
+	paletteLUTBits  = 5
+	paletteLUTShift = 8 - paletteLUTBits
+	paletteLUTSize  = 1 << (paletteLUTBits * 3)
+)
+
+// Windowsの256色表示で予約されている静的20色（先頭10色と末尾10色）
+var (
+	systemColorsLow = [...]color.RGBA{
+		{0x00, 0x00, 0x00, 0xFF}, {0x80, 0x00, 0x00, 0xFF}, {0x00, 0x80, 0x00, 0xFF}, {0x80, 0x80, 0x00, 0xFF},
+		{0x00, 0x00, 0x80, 0xFF}, {0x80, 0x00, 0x80, 0xFF}, {0x00, 0x80, 0x80, 0xFF}, {0xC0, 0xC0, 0xC0, 0xFF},
+		{0xC0, 0xDC, 0xC0, 0xFF}, {0xA6, 0xCA, 0xF0, 0xFF},
+	}
+	systemColorsHigh = [...]color.RGBA{
+		{0xFF, 0xFB, 0xF0, 0xFF}, {0xA0, 0xA0, 0xA4, 0xFF}, {0x80, 0x80, 0x80, 0xFF}, {0xFF, 0x00, 0x00, 0xFF},
+		{0x00, 0xFF, 0x00, 0xFF}, {0xFF, 0xFF, 0x00, 0xFF}, {0x00, 0x00, 0xFF, 0xFF}, {0xFF, 0x00, 0xFF, 0xFF},
+		{0x00, 0xFF, 0xFF, 0xFF}, {0xFF, 0xFF, 0xFF, 0xFF},
+	}
+)
+
+// 静的20色の間に置くカラーキューブ（6×6×6）と灰色のグラデーション
+const (
+	paletteCubeLevels = 6
+	paletteCubeStep   = 0x33
+	paletteGrayLevels = PaletteSize - len(systemColorsLow) - len(systemColorsHigh) - paletteCubeLevels*paletteCubeLevels*paletteCubeLevels
+)
+
+// defaultPalette は90年代のWindowsの256色表示に近い既定のパレットを返す
+// 静的20色 + 6×6×6のカラーキューブ + 灰色のグラデーションで構成する
+func defaultPalette() [PaletteSize]color.RGBA {
+	var p [PaletteSize]color.RGBA
+	i := 0
+	for _, c := range systemColorsLow {
+		p[i] = c
+		i++
+	}
+	for r := 0; r < paletteCubeLevels; r++ {
+		for g := 0; g < paletteCubeLevels; g++ {
+			for b := 0; b < paletteCubeLevels; b++ {
+				p[i] = color.RGBA{uint8(r * paletteCubeStep), uint8(g * paletteCubeStep), uint8(b * paletteCubeStep), 0xFF}
+				i++
+			}
+		}
+	}
+	for n := 1; n <= paletteGrayLevels; n++ {
+		v := uint8(n * 0xFF / (paletteGrayLevels + 1))
+		p[i] = color.RGBA{v, v, v, 0xFF}
+		i++
+	}
+	for _, c := range systemColorsHigh {
+		p[i] = c
+		i++
+	}
+	return p
+}
+
+// Palette は256色表示エミュレーションのパレットを管理する
+//
+// 画面の各ピクセルは既定のパレットで最も近い色の番号に変換され、
+// その番号の現在の色で表示される。パレットアニメーション（SetPalette/CyclePalette）は
+// 番号の割り当てを変えずに色だけを変えるため、当時の256色表示と同様に
+// 同じ番号のピクセルがまとめて色を変える。
+type Palette struct {
+	base   [PaletteSize]color.RGBA // 量子化の基準にする色（変更しない）
+	colors [PaletteSize]color.RGBA // 表示に使用する現在の色
+	lut    []uint8                 // RGB → パレット番号（最初の量子化時に作成）
+	pixels []byte                  // 画面のピクセルを読み出すバッファ
+	mu     sync.Mutex
+}
+
+// NewPalette は既定のパレットで新しい Palette を作成する
+func NewPalette() *Palette {
+	p := &Palette{base: defaultPalette()}
+	p.colors = p.base
+	return p
+}
+
+// Set はパレット番号 index の表示色を変更する
+func (p *Palette) Set(index int, c color.Color) error {
+	if err := checkPaletteIndex(index); err != nil {
+		return err
+	}
+	r, g, b, _ := c.RGBA()
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.colors[index] = color.RGBA{uint8(r >> 8), uint8(g >> 8), uint8(b >> 8), 0xFF}
+	return nil
+}
+
+// Get はパレット番号 index の現在の表示色を返す
+func (p *Palette) Get(index int) (color.RGBA, error) {
+	if err := checkPaletteIndex(index); err != nil {
+		return color.RGBA{}, err
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.colors[index], nil
+}
+
+// Cycle はパレット番号 start から count 個の表示色を step だけ回転させる（カラーサイクリング）
+// step が正の場合は番号の大きい方へ、負の場合は小さい方へ色が移動する
+func (p *Palette) Cycle(start, count, step int) error {
+	if count <= 0 {
+		return fmt.Errorf("palette cycle count must be positive: %d", count)
+	}
+	if err := checkPaletteIndex(start); err != nil {
+		return err
+	}
+	if err := checkPaletteIndex(start + count - 1); err != nil {
+		return err
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	shift := ((step % count) + count) % count
+	if shift == 0 {
+		return nil
+	}
+	var rotated [PaletteSize]color.RGBA
+	for i := 0; i < count; i++ {
+		rotated[(i+shift)%count] = p.colors[start+i]
+	}
+	copy(p.colors[start:start+count], rotated[:count])
+	return nil
+}
+
+// Reset は表示色を既定のパレットに戻す
+func (p *Palette) Reset() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.colors = p.base
+}
+
+// Quantize はRGBAのピクセル列をパレットの色に変換する（ピクセル列を直接書き換える）
+// 各ピクセルは不透明として扱う
+func (p *Palette) Quantize(pix []byte) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.quantizeLocked(pix)
+}
+
+// quantizeLocked は Quantize の本体。呼び出し元は p.mu のロックを保持していること
+func (p *Palette) quantizeLocked(pix []byte) {
+	for i := 0; i+3 < len(pix); i += 4 {
+		c := p.colors[p.lutIndex(pix[i], pix[i+1], pix[i+2])]
+		pix[i] = c.R
+		pix[i+1] = c.G
+		pix[i+2] = c.B
+		pix[i+3] = 0xFF
+	}
+}
+
+// lutIndex は色 (r, g, b) に割り当てられたパレット番号を返す
+func (p *Palette) lutIndex(r, g, b uint8) int {
+	if p.lut == nil {
+		p.lut = p.buildLUT()
+	}
+	key := int(r>>paletteLUTShift)<<(paletteLUTBits*2) |
+		int(g>>paletteLUTShift)<<paletteLUTBits |
+		int(b>>paletteLUTShift)
+	return int(p.lut[key])
+}
+
+// buildLUT は量子化の各区画の中央の色に最も近い基準色の番号を求める
+func (p *Palette) buildLUT() []uint8 {
+	lut := make([]uint8, paletteLUTSize)
+	mask := 1<<paletteLUTBits - 1
+	half := 1 << (paletteLUTShift - 1)
+	for key := range lut {
+		r := (key>>(paletteLUTBits*2))&mask<<paletteLUTShift | half
+		g := (key>>paletteLUTBits)&mask<<paletteLUTShift | half
+		b := key&mask<<paletteLUTShift | half
+		lut[key] = uint8(p.nearest(r, g, b))
+	}
+	return lut
+}
+
+// nearest は基準色のうち (r, g, b) に最も近い色の番号を返す
+func (p *Palette) nearest(r, g, b int) int {
+	best, bestDist := 0, -1
+	for i, c := range p.base {
+		dr, dg, db := r-int(c.R), g-int(c.G), b-int(c.B)
+		dist := dr*dr + dg*dg + db*db
+		if bestDist < 0 || dist < bestDist {
+			best, bestDist = i, dist
+		}
+	}
+	return best
+}
+
+// checkPaletteIndex はパレット番号が範囲内かを確認する
+func checkPaletteIndex(index int) error {
+	if index < 0 || index >= PaletteSize {
+		return fmt.Errorf("palette index out of range: %d (0-%d)", index, PaletteSize-1)
+	}
+	return nil
+}
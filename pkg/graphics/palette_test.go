@@ -0,0 +1,127 @@
+package graphics
+
+import (
+	"image/color"
+	"testing"
+)
+
+// TestDefaultPalette tests the layout of the default 256-color palette.
+func TestDefaultPalette(t *testing.T) {
+	p := defaultPalette()
+
+	if p[0] != (color.RGBA{0, 0, 0, 0xFF}) {
+		t.Errorf("entry 0 = %v, want black", p[0])
+	}
+	if p[PaletteSize-1] != (color.RGBA{0xFF, 0xFF, 0xFF, 0xFF}) {
+		t.Errorf("entry 255 = %v, want white", p[PaletteSize-1])
+	}
+	if paletteGrayLevels != 20 {
+		t.Errorf("gray levels = %d, want 20", paletteGrayLevels)
+	}
+
+	// すべてのエントリが設定されている（黒はシステム色とキューブの先頭の2つだけ）
+	blacks := 0
+	for _, c := range p {
+		if c.A != 0xFF {
+			t.Fatalf("entry %v is not opaque", c)
+		}
+		if c == (color.RGBA{0, 0, 0, 0xFF}) {
+			blacks++
+		}
+	}
+	if blacks != 2 {
+		t.Errorf("black entries = %d, want 2", blacks)
+	}
+}
+
+// TestPaletteQuantize tests mapping pixels to the nearest palette color.
+func TestPaletteQuantize(t *testing.T) {
+	p := NewPalette()
+
+	pix := []byte{
+		0xFE, 0x01, 0x02, 0x80, // ほぼ赤 → 赤（不透明になる）
+		0x31, 0x67, 0x98, 0xFF, // キューブの (0x33, 0x66, 0x99) に近い色
+		0x7F, 0x7F, 0x81, 0xFF, // 灰色 → システム色の 0x808080
+	}
+	p.Quantize(pix)
+
+	want := []byte{
+		0xFF, 0x00, 0x00, 0xFF,
+		0x33, 0x66, 0x99, 0xFF,
+		0x80, 0x80, 0x80, 0xFF,
+	}
+	for i := range want {
+		if pix[i] != want[i] {
+			t.Fatalf("quantized pixels = % X, want % X", pix, want)
+		}
+	}
+}
+
+// TestPaletteAnimation tests that changing a palette entry recolors pixels mapped to it.
+func TestPaletteAnimation(t *testing.T) {
+	p := NewPalette()
+	red := p.lutIndex(0xFF, 0x00, 0x00)
+
+	if err := p.Set(red, color.RGBA{0x00, 0x00, 0xFF, 0xFF}); err != nil {
+		t.Fatalf("Set returned error: %v", err)
+	}
+	pix := []byte{0xFF, 0x00, 0x00, 0xFF}
+	p.Quantize(pix)
+	if pix[0] != 0x00 || pix[2] != 0xFF {
+		t.Errorf("red pixel should be shown with the new entry color, got % X", pix)
+	}
+
+	p.Reset()
+	pix = []byte{0xFF, 0x00, 0x00, 0xFF}
+	p.Quantize(pix)
+	if pix[0] != 0xFF || pix[2] != 0x00 {
+		t.Errorf("Reset should restore the default colors, got % X", pix)
+	}
+}
+
+// TestPaletteCycle tests rotating a range of palette entries.
+func TestPaletteCycle(t *testing.T) {
+	p := NewPalette()
+	a, _ := p.Get(10)
+	b, _ := p.Get(11)
+	c, _ := p.Get(12)
+
+	if err := p.Cycle(10, 3, 1); err != nil {
+		t.Fatalf("Cycle returned error: %v", err)
+	}
+	got := [3]color.RGBA{}
+	for i := range got {
+		got[i], _ = p.Get(10 + i)
+	}
+	if got != [3]color.RGBA{c, a, b} {
+		t.Errorf("after Cycle(+1) = %v, want %v", got, [3]color.RGBA{c, a, b})
+	}
+
+	// -1 で元に戻る（step は count を法として扱う）
+	p.Cycle(10, 3, -1)
+	if got, _ := p.Get(10); got != a {
+		t.Errorf("after Cycle(-1) entry 10 = %v, want %v", got, a)
+	}
+	p.Cycle(10, 3, 3)
+	if got, _ := p.Get(10); got != a {
+		t.Errorf("Cycle by count should be a no-op, entry 10 = %v", got)
+	}
+}
+
+// TestPaletteInvalidArguments tests range checks of palette operations.
+func TestPaletteInvalidArguments(t *testing.T) {
+	p := NewPalette()
+
+	if err := p.Set(PaletteSize, color.RGBA{}); err == nil {
+		t.Error("Set(256) should fail")
+	}
+	if _, err := p.Get(-1); err == nil {
+		t.Error("Get(-1) should fail")
+	}
+	if err := p.Cycle(250, 10, 1); err == nil {
+		t.Error("Cycle past the end of the palette should fail")
+	}
+	if err := p.Cycle(0, 0, 1); err == nil {
+		t.Error("Cycle with zero count should fail")
+	}
+}
@@ -0,0 +1,99 @@
+package graphics
+
+import (
+	"fmt"
+	"math"
+)
+
+// MaxSpritePoolSize は1つのスプライトプールの粒子数の上限
+// 1回の DrawTriangles で描画できる頂点数（uint16のインデックス）に収まるようにする
+const MaxSpritePoolSize = 4096
+
+// poolParticle はスプライトプールの粒子1つの状態
+type poolParticle struct {
+	x, y    float64 // 配置先ピクチャー内の位置（左上）
+	vx, vy  float64 // StepSpritePool で1回に進む量
+	visible bool
+}
+
+// particleSet はスプライトプールの粒子の状態と移動の計算を保持する
+// 描画を伴わないため、HeadlessGraphicsSystem でも使用する
+type particleSet struct {
+	particles    []poolParticle
+	itemW, itemH int // 粒子の画像サイズ
+	areaW, areaH int // 移動範囲（配置先ピクチャーのサイズ）
+}
+
+// newParticleSet は n 個の非表示の粒子を作成する
+func newParticleSet(n, itemW, itemH, areaW, areaH int) (*particleSet, error) {
+	if n <= 0 || n > MaxSpritePoolSize {
+		return nil, fmt.Errorf("sprite pool size %d out of range (1-%d)", n, MaxSpritePoolSize)
+	}
+	return &particleSet{
+		particles: make([]poolParticle, n),
+		itemW:     itemW,
+		itemH:     itemH,
+		areaW:     areaW,
+		areaH:     areaH,
+	}, nil
+}
+
+// clone は粒子の状態を複製する（キーフレーム用）
+func (ps *particleSet) clone() *particleSet {
+	c := *ps
+	c.particles = append([]poolParticle(nil), ps.particles...)
+	return &c
+}
+
+// checkIndex は粒子の番号を検証する
+func (ps *particleSet) checkIndex(index int) error {
+	if index < 0 || index >= len(ps.particles) {
+		return fmt.Errorf("sprite pool index %d out of range (0-%d)", index, len(ps.particles)-1)
+	}
+	return nil
+}
+
+// set は粒子の位置と可視性を設定する
+func (ps *particleSet) set(index int, x, y float64, visible bool) error {
+	if err := ps.checkIndex(index); err != nil {
+		return err
+	}
+	p := &ps.particles[index]
+	p.x, p.y, p.visible = x, y, visible
+	return nil
+}
+
+// setVelocity は粒子の速度を設定する（index が負の場合はすべての粒子）
+func (ps *particleSet) setVelocity(index int, vx, vy float64) error {
+	if index < 0 {
+		for i := range ps.particles {
+			ps.particles[i].vx, ps.particles[i].vy = vx, vy
+		}
+		return nil
+	}
+	if err := ps.checkIndex(index); err != nil {
+		return err
+	}
+	ps.particles[index].vx, ps.particles[index].vy = vx, vy
+	return nil
+}
+
+// step はすべての粒子を速度の分だけ進める
+// 移動範囲の外に完全に出た粒子は反対側から入ってくる（雪や花びらが降り続ける）
+func (ps *particleSet) step() {
+	for i := range ps.particles {
+		p := &ps.particles[i]
+		p.x = wrapCoord(p.x+p.vx, ps.itemW, ps.areaW)
+		p.y = wrapCoord(p.y+p.vy, ps.itemH, ps.areaH)
+	}
+}
+
+// wrapCoord は座標 v を [-size, area) の範囲に折り返す
+func wrapCoord(v float64, size, area int) float64 {
+	period := float64(area + size)
+	if period <= 0 {
+		return v
+	}
+	lo := -float64(size)
+	return lo + math.Mod(math.Mod(v-lo, period)+period, period)
+}
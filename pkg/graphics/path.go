@@ -0,0 +1,184 @@
+package graphics
+
+import (
+	"fmt"
+	"math"
+	"sort"
+)
+
+// パスに沿ったキャストの移動（DefinePath/MoveAlongPath）
+//
+// スクリプトで曲線の動きを作るには MoveCast の直線の移動を何十個も並べる必要があるため、
+// 通過する点（Catmull-Rom スプライン）または制御点（3次ベジェ曲線）から曲線を作り、
+// グラフィックスシステムが毎フレームその上の位置にキャストを動かす。
+// 曲線の長さに沿って進むため、点の間隔が不揃いでも速さは一定になる（イージングを除く）。
+
+// PathKind はパスの曲線の種類
+type PathKind int
+
+const (
+	// PathCatmullRom はすべての点を通る Catmull-Rom スプライン（2点以上）
+	PathCatmullRom PathKind = iota
+	// PathBezier は3次ベジェ曲線をつないだ曲線（始点, 制御点1, 制御点2, 終点, 制御点1, 制御点2, 終点, ...）
+	// 点の数は 3n+1 で、始点と各区間の終点を通る
+	PathBezier
+)
+
+// Easing は時間に対する進み方
+type Easing int
+
+const (
+	EaseLinear  Easing = iota // 一定の速さ
+	EaseIn                    // ゆっくり始まり、加速する
+	EaseOut                   // 速く始まり、減速して止まる
+	EaseInOut                 // ゆっくり始まり、ゆっくり止まる
+	easingCount               // Easing の種類の数
+)
+
+// pathSamplesPerSegment は曲線の長さを求めるために1区間を折れ線で近似する点の数
+const pathSamplesPerSegment = 32
+
+// PathPoint はパスの点（仮想デスクトップではなくキャストの位置と同じ座標）
+type PathPoint struct {
+	X, Y float64
+}
+
+// Path は長さに沿って位置を求められる曲線
+// 作成後は変更しないため、複数のキャストの移動で共有できる
+type Path struct {
+	kind    PathKind
+	points  []PathPoint // 定義した点
+	samples []PathPoint // 曲線を近似する折れ線の点
+	lengths []float64   // 折れ線の始点から各点までの長さ
+}
+
+// NewPath は点の並びから曲線を作る
+func NewPath(kind PathKind, points []PathPoint) (*Path, error) {
+	switch kind {
+	case PathCatmullRom:
+		if len(points) < 2 {
+			return nil, fmt.Errorf("a Catmull-Rom path requires at least 2 points, got %d", len(points))
+		}
+	case PathBezier:
+		if len(points) < 4 || (len(points)-1)%3 != 0 {
+			return nil, fmt.Errorf("a Bezier path requires 3n+1 points (4, 7, 10, ...), got %d", len(points))
+		}
+	default:
+		return nil, fmt.Errorf("unknown path kind: %d", kind)
+	}
+
+	p := &Path{kind: kind, points: append([]PathPoint(nil), points...)}
+	segments := p.segmentCount()
+	p.samples = make([]PathPoint, 0, segments*pathSamplesPerSegment+1)
+	for seg := 0; seg < segments; seg++ {
+		for i := 0; i < pathSamplesPerSegment; i++ {
+			p.samples = append(p.samples, p.segmentPoint(seg, float64(i)/pathSamplesPerSegment))
+		}
+	}
+	p.samples = append(p.samples, points[len(points)-1])
+
+	p.lengths = make([]float64, len(p.samples))
+	for i := 1; i < len(p.samples); i++ {
+		a, b := p.samples[i-1], p.samples[i]
+		p.lengths[i] = p.lengths[i-1] + math.Hypot(b.X-a.X, b.Y-a.Y)
+	}
+	return p, nil
+}
+
+// Kind はパスの曲線の種類を返す
+func (p *Path) Kind() PathKind {
+	return p.kind
+}
+
+// Points はパスを定義した点を返す
+func (p *Path) Points() []PathPoint {
+	return append([]PathPoint(nil), p.points...)
+}
+
+// Length は曲線の長さ（折れ線で近似した値）を返す
+func (p *Path) Length() float64 {
+	return p.lengths[len(p.lengths)-1]
+}
+
+// Start は曲線の始点を返す
+func (p *Path) Start() PathPoint {
+	return p.points[0]
+}
+
+// End は曲線の終点を返す
+func (p *Path) End() PathPoint {
+	return p.points[len(p.points)-1]
+}
+
+// PointAt は曲線の長さに対する割合 t（0.0〜1.0）の位置を返す
+func (p *Path) PointAt(t float64) PathPoint {
+	total := p.Length()
+	if t <= 0 || total == 0 {
+		return p.Start()
+	}
+	if t >= 1 {
+		return p.End()
+	}
+	target := t * total
+	i := sort.SearchFloat64s(p.lengths, target) // lengths[i-1] < target <= lengths[i]
+	a, b := p.samples[i-1], p.samples[i]
+	f := (target - p.lengths[i-1]) / (p.lengths[i] - p.lengths[i-1])
+	return PathPoint{X: a.X + (b.X-a.X)*f, Y: a.Y + (b.Y-a.Y)*f}
+}
+
+// segmentCount は曲線の区間の数を返す
+func (p *Path) segmentCount() int {
+	if p.kind == PathBezier {
+		return (len(p.points) - 1) / 3
+	}
+	return len(p.points) - 1
+}
+
+// segmentPoint は区間 seg の中の媒介変数 u（0.0〜1.0）の位置を返す
+func (p *Path) segmentPoint(seg int, u float64) PathPoint {
+	if p.kind == PathBezier {
+		p0, p1, p2, p3 := p.points[seg*3], p.points[seg*3+1], p.points[seg*3+2], p.points[seg*3+3]
+		v := 1 - u
+		b0, b1, b2, b3 := v*v*v, 3*v*v*u, 3*v*u*u, u*u*u
+		return PathPoint{
+			X: b0*p0.X + b1*p1.X + b2*p2.X + b3*p3.X,
+			Y: b0*p0.Y + b1*p1.Y + b2*p2.Y + b3*p3.Y,
+		}
+	}
+
+	// Catmull-Rom: 区間の前後の点を使う（両端は端の点を繰り返す）
+	p1, p2 := p.points[seg], p.points[seg+1]
+	p0, p3 := p1, p2
+	if seg > 0 {
+		p0 = p.points[seg-1]
+	}
+	if seg+2 < len(p.points) {
+		p3 = p.points[seg+2]
+	}
+	catmullRom := func(a, b, c, d float64) float64 {
+		return 0.5 * (2*b + (c-a)*u + (2*a-5*b+4*c-d)*u*u + (3*b-a-3*c+d)*u*u*u)
+	}
+	return PathPoint{X: catmullRom(p0.X, p1.X, p2.X, p3.X), Y: catmullRom(p0.Y, p1.Y, p2.Y, p3.Y)}
+}
+
+// ValidEasing は e が定義されたイージングかどうかを返す
+func ValidEasing(e Easing) bool {
+	return e >= EaseLinear && e < easingCount
+}
+
+// Apply は経過時間の割合 t（0.0〜1.0）を、イージングを適用した進み具合にする
+func (e Easing) Apply(t float64) float64 {
+	t = min(max(t, 0), 1)
+	switch e {
+	case EaseIn:
+		return t * t
+	case EaseOut:
+		return 1 - (1-t)*(1-t)
+	case EaseInOut:
+		if t < 0.5 {
+			return 2 * t * t
+		}
+		return 1 - 2*(1-t)*(1-t)
+	}
+	return t
+}
@@ -0,0 +1,244 @@
+//go:build !nogpu
+
+package graphics
+
+import (
+	"errors"
+	"math"
+	"testing"
+	"time"
+)
+
+// near は2つの値の差が tolerance 以下かを返す
+func near(a, b, tolerance float64) bool {
+	return math.Abs(a-b) <= tolerance
+}
+
+func TestNewPathValidation(t *testing.T) {
+	pts := func(n int) []PathPoint {
+		points := make([]PathPoint, n)
+		for i := range points {
+			points[i] = PathPoint{X: float64(i * 10), Y: 0}
+		}
+		return points
+	}
+	tests := []struct {
+		name    string
+		kind    PathKind
+		points  int
+		wantErr bool
+	}{
+		{"catmull-rom 1 point", PathCatmullRom, 1, true},
+		{"catmull-rom 2 points", PathCatmullRom, 2, false},
+		{"catmull-rom 5 points", PathCatmullRom, 5, false},
+		{"bezier 3 points", PathBezier, 3, true},
+		{"bezier 4 points", PathBezier, 4, false},
+		{"bezier 5 points", PathBezier, 5, true},
+		{"bezier 7 points", PathBezier, 7, false},
+		{"unknown kind", PathKind(9), 4, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewPath(tt.kind, pts(tt.points))
+			if (err != nil) != tt.wantErr {
+				t.Errorf("NewPath error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+// TestPathCatmullRomPassesThroughPoints tests that a Catmull-Rom path passes through
+// every point and ends exactly at the last point.
+func TestPathCatmullRomPassesThroughPoints(t *testing.T) {
+	points := []PathPoint{{0, 0}, {100, 50}, {200, 0}, {300, 80}}
+	p, err := NewPath(PathCatmullRom, points)
+	if err != nil {
+		t.Fatalf("NewPath failed: %v", err)
+	}
+	if got := p.PointAt(0); got != points[0] {
+		t.Errorf("PointAt(0) = %v, want %v", got, points[0])
+	}
+	if got := p.PointAt(1); got != points[3] {
+		t.Errorf("PointAt(1) = %v, want %v", got, points[3])
+	}
+	// 各区間の始点は近似した折れ線の点に含まれる
+	for seg, want := range points[:3] {
+		if got := p.samples[seg*pathSamplesPerSegment]; got != want {
+			t.Errorf("segment %d starts at %v, want %v", seg, got, want)
+		}
+	}
+}
+
+// TestPathBezier tests the points of a single cubic Bezier segment.
+func TestPathBezier(t *testing.T) {
+	p, err := NewPath(PathBezier, []PathPoint{{0, 0}, {0, 100}, {100, 100}, {100, 0}})
+	if err != nil {
+		t.Fatalf("NewPath failed: %v", err)
+	}
+	// 左右対称な曲線の中点は (50, 75)
+	mid := p.PointAt(0.5)
+	if !near(mid.X, 50, 0.5) || !near(mid.Y, 75, 0.5) {
+		t.Errorf("PointAt(0.5) = %v, want about (50, 75)", mid)
+	}
+	if got := p.PointAt(1); got != (PathPoint{100, 0}) {
+		t.Errorf("PointAt(1) = %v, want the end point", got)
+	}
+}
+
+// TestPathConstantSpeed tests that PointAt moves by arc length, not by the curve
+// parameter, so unevenly spaced points do not change the speed.
+func TestPathConstantSpeed(t *testing.T) {
+	// 直線上の制御点が始点に偏っているため、媒介変数では終点の近くで急に速くなる
+	p, err := NewPath(PathBezier, []PathPoint{{0, 0}, {10, 0}, {20, 0}, {300, 0}})
+	if err != nil {
+		t.Fatalf("NewPath failed: %v", err)
+	}
+	if !near(p.Length(), 300, 1) {
+		t.Errorf("Length() = %v, want about 300", p.Length())
+	}
+	for _, f := range []float64{0.1, 0.25, 0.5, 0.9} {
+		if got := p.PointAt(f); !near(got.X, 300*f, 1) || got.Y != 0 {
+			t.Errorf("PointAt(%v) = %v, want about (%v, 0)", f, got, 300*f)
+		}
+	}
+}
+
+// TestPathZeroLength tests that a path whose points are all the same stays at the point.
+func TestPathZeroLength(t *testing.T) {
+	p, err := NewPath(PathCatmullRom, []PathPoint{{5, 5}, {5, 5}})
+	if err != nil {
+		t.Fatalf("NewPath failed: %v", err)
+	}
+	if got := p.PointAt(0.5); got != (PathPoint{5, 5}) {
+		t.Errorf("PointAt(0.5) = %v, want (5, 5)", got)
+	}
+}
+
+func TestEasingApply(t *testing.T) {
+	tests := []struct {
+		easing Easing
+		t      float64
+		want   float64
+	}{
+		{EaseLinear, 0.25, 0.25},
+		{EaseIn, 0.5, 0.25},
+		{EaseOut, 0.5, 0.75},
+		{EaseInOut, 0.25, 0.125},
+		{EaseInOut, 0.5, 0.5},
+		{EaseInOut, 0.75, 0.875},
+		{EaseIn, -1, 0},
+		{EaseOut, 2, 1},
+	}
+	for _, tt := range tests {
+		if got := tt.easing.Apply(tt.t); !near(got, tt.want, 1e-9) {
+			t.Errorf("Easing(%d).Apply(%v) = %v, want %v", tt.easing, tt.t, got, tt.want)
+		}
+	}
+	for _, e := range []Easing{EaseLinear, EaseIn, EaseOut, EaseInOut} {
+		if e.Apply(0) != 0 || e.Apply(1) != 1 {
+			t.Errorf("Easing(%d) does not go from 0 to 1", e)
+		}
+	}
+	if ValidEasing(-1) || ValidEasing(easingCount) {
+		t.Error("ValidEasing accepted an undefined easing")
+	}
+}
+
+// TestGraphicsSystemMoveCastAlongPath tests that the cast moves along the path through
+// Update and stops at the end point.
+func TestGraphicsSystemMoveCastAlongPath(t *testing.T) {
+	gs := NewGraphicsSystem("")
+	picID, err := gs.CreatePic(64, 64)
+	if err != nil {
+		t.Fatalf("CreatePic failed: %v", err)
+	}
+	if _, err := gs.OpenWin(picID, 0, 0, 64, 64, 0, 0, 0); err != nil {
+		t.Fatalf("OpenWin failed: %v", err)
+	}
+	castID, err := gs.PutCast(picID, picID, 0, 0, 0, 0, 16, 16)
+	if err != nil {
+		t.Fatalf("PutCast failed: %v", err)
+	}
+	path, err := NewPath(PathCatmullRom, []PathPoint{{10, 0}, {70, 0}})
+	if err != nil {
+		t.Fatalf("NewPath failed: %v", err)
+	}
+	castPos := func() (int, int) {
+		cast, err := gs.casts.GetCast(castID)
+		if err != nil {
+			t.Fatalf("GetCast failed: %v", err)
+		}
+		return cast.X, cast.Y
+	}
+
+	// 100ms は 60TPS で 6 フレーム
+	if err := gs.MoveCastAlongPath(castID, path, 100*time.Millisecond, EaseLinear); err != nil {
+		t.Fatalf("MoveCastAlongPath failed: %v", err)
+	}
+	if x, y := castPos(); x != 10 || y != 0 {
+		t.Errorf("position after start = (%d, %d), want the start point (10, 0)", x, y)
+	}
+	gs.Update()
+	if x, _ := castPos(); x != 20 {
+		t.Errorf("x after 1 frame = %d, want 20", x)
+	}
+	for range 5 {
+		gs.Update()
+	}
+	if x, y := castPos(); x != 70 || y != 0 {
+		t.Errorf("position after 6 frames = (%d, %d), want the end point (70, 0)", x, y)
+	}
+	if len(gs.motions) != 0 {
+		t.Error("expected the motion to finish after 6 frames")
+	}
+	if x, _ := gs.castSprite(castID).Position(); x != 70 {
+		t.Errorf("sprite x = %v, want 70", x)
+	}
+
+	// nil のパスを指定すると止まり、キャストはその位置に残る
+	gs.MoveCastAlongPath(castID, path, time.Second, EaseIn)
+	gs.Update()
+	gs.MoveCastAlongPath(castID, nil, 0, EaseLinear)
+	x, _ := castPos()
+	gs.Update()
+	if after, _ := castPos(); after != x || len(gs.motions) != 0 {
+		t.Errorf("expected the motion to stop at x=%d, got x=%d", x, after)
+	}
+
+	// 削除されたキャストの移動は取り除かれる
+	gs.MoveCastAlongPath(castID, path, time.Second, EaseLinear)
+	gs.DelCast(castID)
+	gs.Update()
+	if len(gs.motions) != 0 {
+		t.Error("expected the motion of a deleted cast to be removed")
+	}
+
+	if err := gs.MoveCastAlongPath(999, path, time.Second, EaseLinear); !errors.Is(err, ErrCastNotFound) {
+		t.Errorf("expected ErrCastNotFound, got %v", err)
+	}
+	if err := gs.MoveCastAlongPath(castID, path, time.Second, Easing(9)); err == nil {
+		t.Error("expected an error for an unknown easing")
+	}
+}
+
+func TestHeadlessGraphicsSystem_MoveCastAlongPath(t *testing.T) {
+	hgs := NewHeadlessGraphicsSystem(WithLogOperations(false))
+	picID, _ := hgs.CreatePic(64, 64)
+	winID, _ := hgs.OpenWin(picID)
+	castID, _ := hgs.PutCast(winID, picID, 0, 0, 0, 0, 32, 32)
+	path, _ := NewPath(PathBezier, []PathPoint{{0, 0}, {0, 40}, {40, 40}, {40.4, 19.6}})
+
+	// ヘッドレスモードではすぐに終点に移動する
+	if err := hgs.MoveCastAlongPath(castID, path, time.Second, EaseOut); err != nil {
+		t.Fatalf("MoveCastAlongPath failed: %v", err)
+	}
+	if cast := hgs.casts[castID]; cast.X != 40 || cast.Y != 20 {
+		t.Errorf("cast position = (%d, %d), want the end point (40, 20)", cast.X, cast.Y)
+	}
+	if err := hgs.MoveCastAlongPath(castID, nil, 0, EaseLinear); err != nil {
+		t.Errorf("stopping the motion failed: %v", err)
+	}
+	if err := hgs.MoveCastAlongPath(999, path, time.Second, EaseLinear); !errors.Is(err, ErrCastNotFound) {
+		t.Errorf("expected ErrCastNotFound, got %v", err)
+	}
+}
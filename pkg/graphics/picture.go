@@ -1,29 +1,21 @@
+//go:build !nogpu
+
 package graphics
 
 import (
-	"bytes"
 	"fmt"
 	"image"
-	_ "image/png" // PNG デコーダを登録
-	"io"
 	"io/fs"
 	"log/slog"
-	"path/filepath"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/hajimehoshi/ebiten/v2"
-	_ "golang.org/x/image/bmp" // BMP デコーダを登録（非圧縮BMP用）
 
 	"github.com/zurustar/son-et/pkg/fileutil"
 )
 
-// isBMPFile はファイルパスがBMPファイルかどうかを判定する
-func isBMPFile(path string) bool {
-	ext := strings.ToLower(filepath.Ext(path))
-	return ext == ".bmp"
-}
-
 // Picture はメモリ上の画像データを表す
 type Picture struct {
 	ID            int
@@ -32,16 +24,32 @@ type Picture struct {
 	BackBuffer    *ebiten.Image // ダブルバッファリング用（キャスト再描画時に使用）
 	Width         int
 	Height        int
+	Hires         *ebiten.Image // @2x の画像（縦横が2倍、nil の場合はなし、hires.go を参照）
+
+	pending *prefetchedPicture // デコードの完了待ち（ストリーミング読み込み、nil の場合はデコード済み）
 }
 
 // PictureManager はピクチャーを管理する
 type PictureManager struct {
-	pictures map[int]*Picture
-	nextID   int
-	maxID    int // 最大256（要件 9.5）
-	fs       fileutil.FileSystem
-	log      *slog.Logger
-	mu       sync.RWMutex
+	pictures  map[int]*Picture
+	nextID    int
+	maxID     int // 最大256（要件 9.5）
+	maxPixels int // 全ピクチャーの合計ピクセル数の上限（0は無制限、サンドボックスモードで使用）
+	fs        fileutil.FileSystem
+	assetDirs []string          // 画像ファイルを追加で探すディレクトリ（プロジェクトマニフェストの assets）
+	assetVars map[string]string // ファイル名の ${名前} に展開する変数（マニフェストの vars と -A）
+	log       *slog.Logger
+	mu        sync.RWMutex
+
+	prefetched map[string]*prefetchedPicture // 先読み中・先読み済みの画像（キーは小文字のファイル名）
+	stream     *assetStream                  // ストリーミング読み込み（nil の場合は LoadPic でデコードを待つ）
+	async      *assetStream                  // LoadPicAsync のデコード（ストリーミング読み込みが無効の場合に使用、キャッシュしない）
+
+	decodeWorkers int // バックグラウンドで同時にデコードする画像の数の上限（0は既定値、SetDecodeWorkers）
+
+	preferHires bool // LoadPic で @2x の画像も読み込むか（実効の表示スケールによる、SetPreferHires）
+
+	tracker *imageTracker // 作成した文と表示の状況（リークの報告用）
 }
 
 // NewPictureManager は新しい PictureManager を作成する
@@ -52,6 +60,7 @@ func NewPictureManager(basePath string) *PictureManager {
 		maxID:    256,
 		fs:       fileutil.NewRealFS(basePath),
 		log:      slog.Default(),
+		tracker:  newImageTracker(),
 	}
 }
 
@@ -71,9 +80,90 @@ func (pm *PictureManager) SetFileSystem(fsys fileutil.FileSystem) {
 	pm.fs = fsys
 }
 
+// SetAssetDirs は画像ファイルが見つからない場合に探すディレクトリ（ベースパスからの相対パス）を設定する
+func (pm *PictureManager) SetAssetDirs(dirs []string) {
+	pm.mu.Lock()
+	defer pm.mu.Unlock()
+	pm.assetDirs = append([]string(nil), dirs...)
+}
+
+// SetAssetVars はファイル名の ${名前} に展開する変数を設定する
+func (pm *PictureManager) SetAssetVars(vars map[string]string) {
+	pm.mu.Lock()
+	defer pm.mu.Unlock()
+	pm.assetVars = vars
+}
+
+// SetPreferHires は LoadPic で @2x の画像（pic01@2x.bmp など）も読み込むかを設定する
+// 読み込み済みのピクチャーには影響しない
+func (pm *PictureManager) SetPreferHires(prefer bool) {
+	pm.mu.Lock()
+	defer pm.mu.Unlock()
+	pm.preferHires = prefer
+}
+
+// files は画像ファイルを読み込む FileSystem を返す（変数を展開し、assetDirs も探す）
+// 呼び出し元は pm.mu のロックを保持していること
+func (pm *PictureManager) files() fileutil.FileSystem {
+	return fileutil.WithVars(fileutil.WithSearchDirs(pm.fs, pm.assetDirs), pm.assetVars)
+}
+
+// EnableSandbox はサンドボックスモードの制限を有効にする
+// 実ファイルシステムをベースパスの外を指すシンボリックリンクを辿らないものに置き換え、
+// 全ピクチャーの合計ピクセル数を maxPixels までに制限する
+func (pm *PictureManager) EnableSandbox(maxPixels int) {
+	pm.mu.Lock()
+	defer pm.mu.Unlock()
+	if !pm.fs.IsEmbedded() {
+		pm.fs = fileutil.NewConfinedRealFS(pm.fs.BasePath())
+	}
+	pm.maxPixels = maxPixels
+}
+
+// checkPixelBudget は width x height のピクチャーを追加しても合計ピクセル数の上限を超えないかを確認する
+// 呼び出し元は pm.mu のロックを保持していること
+func (pm *PictureManager) checkPixelBudget(width, height int) error {
+	if pm.maxPixels <= 0 {
+		return nil
+	}
+	total := width * height
+	for _, pic := range pm.pictures {
+		total += pic.Width * pic.Height
+	}
+	if total > pm.maxPixels {
+		return fmt.Errorf("picture memory limit reached: %d pixels (max %d)", total, pm.maxPixels)
+	}
+	return nil
+}
+
 // LoadPic は指定されたファイルから画像を読み込み、ピクチャーIDを返す
 // 要件 1.1, 1.2, 1.3, 1.10, 1.10.1, 1.10.2, 1.11, 1.12
 func (pm *PictureManager) LoadPic(filename string) (int, error) {
+	picID, _, err := pm.loadPic(filename, false)
+	return picID, err
+}
+
+// LoadPicAsync は LoadPic と同じくピクチャーIDを返すが、ストリーミング読み込みが無効でも
+// デコードをワーカーで行い、完了を待たずに戻る
+// デコード中の場合は完了待ちの entry を返す（デコード済みの画像を使った場合は nil）
+func (pm *PictureManager) LoadPicAsync(filename string) (int, *prefetchedPicture, error) {
+	return pm.loadPic(filename, true)
+}
+
+// decoder はデコードをワーカーで行う場合の assetStream を返す（nil の場合は LoadPic でデコードを待つ）
+// 呼び出し元は pm.mu のロックを保持していること
+func (pm *PictureManager) decoder(async bool) *assetStream {
+	if pm.stream != nil || !async {
+		return pm.stream
+	}
+	if pm.async == nil {
+		pm.async = newAssetStream(0, pm.streamWorkers(), pm.log)
+	}
+	return pm.async
+}
+
+// loadPic は画像を読み込んでピクチャーを作成する（LoadPic と LoadPicAsync の共通処理）
+func (pm *PictureManager) loadPic(filename string, async bool) (int, *prefetchedPicture, error) {
 	pm.mu.Lock()
 	defer pm.mu.Unlock()
 
@@ -81,7 +171,7 @@ func (pm *PictureManager) LoadPic(filename string) (int, error) {
 	if len(pm.pictures) >= pm.maxID {
 		err := fmt.Errorf("picture limit reached: %d", pm.maxID)
 		pm.log.Error("LoadPic: resource limit exceeded", "filename", filename, "limit", pm.maxID)
-		return -1, err
+		return -1, nil, err
 	}
 
 	// FILLYでは "/" で始まるパスはタイトルディレクトリからの相対パスとして扱う
@@ -91,66 +181,56 @@ func (pm *PictureManager) LoadPic(filename string) (int, error) {
 		searchFilename = filename[1:] // 先頭の "/" または "\" を除去
 	}
 
-	// FileSystemインターフェースを使用してファイルを開く
-	file, err := pm.fs.Open(searchFilename)
-	if err != nil {
-		pm.log.Error("LoadPic: file not found", "filename", filename, "searchFilename", searchFilename, "basePath", pm.fs.BasePath())
-		return -1, fmt.Errorf("file not found: %s", filename)
-	}
-	defer file.Close()
-
-	// 画像をデコード（BMP/PNG対応、要件 1.10, 1.10.1, 1.10.2, 1.11）
-	var img image.Image
-
-	// BMPファイルの場合、RLE圧縮かどうかを確認
-	if isBMPFile(searchFilename) {
-		// ファイル内容を一度読み込む（Seekが使えない場合があるため）
-		data, err := io.ReadAll(file)
-		if err != nil {
-			pm.log.Error("LoadPic: failed to read file", "filename", filename, "error", err)
-			return -1, fmt.Errorf("failed to read file: %w", err)
-		}
-
-		isRLE, err := IsBMPRLECompressedFromBytes(data)
+	// 先読み済みの画像があれば使用し、なければファイルを読み込んでデコードする
+	// ストリーミング読み込みの場合はサイズだけを読み取り、デコードはワーカーで行う
+	var originalRGBA, hiresRGBA *image.RGBA
+	var pending *prefetchedPicture
+	var width, height int
+	prefetched := false
+	if stream := pm.decoder(async); stream != nil {
+		var err error
+		originalRGBA, pending, width, height, err = pm.openStreamed(stream, searchFilename)
 		if err != nil {
-			pm.log.Warn("LoadPic: failed to check RLE compression, falling back to standard decoder", "filename", filename, "error", err)
+			pm.log.Error("LoadPic: failed to load image", "filename", filename, "searchFilename", searchFilename, "basePath", pm.fs.BasePath(), "error", err)
+			return -1, nil, err
 		}
-
-		if isRLE {
-			// RLE圧縮BMPの場合、カスタムデコーダーを使用（要件 1.10.1）
-			pm.log.Info("LoadPic: using custom RLE BMP decoder", "filename", filename)
-			img, err = DecodeBMPFromBytes(data)
-			if err != nil {
-				pm.log.Error("LoadPic: failed to decode RLE BMP", "filename", filename, "error", err)
-				return -1, fmt.Errorf("failed to decode RLE BMP: %w", err)
-			}
-		} else {
-			// 非圧縮BMPの場合、標準デコーダーを使用（要件 1.10.2）
-			img, _, err = image.Decode(bytes.NewReader(data))
+	} else {
+		originalRGBA, prefetched = pm.takePrefetched(searchFilename)
+		if originalRGBA == nil {
+			var err error
+			originalRGBA, err = decodePictureFile(pm.files(), searchFilename, pm.log)
 			if err != nil {
-				pm.log.Error("LoadPic: failed to decode image", "filename", filename, "error", err)
-				return -1, fmt.Errorf("failed to decode image: %w", err)
+				// 等倍の画像がなく @2x の画像だけがある場合は、縮小した画像をピクチャーとする
+				hiresRGBA, _ = decodePictureFile(pm.files(), hiresVariantName(searchFilename), pm.log)
+				if hiresRGBA == nil {
+					pm.log.Error("LoadPic: failed to load image", "filename", filename, "searchFilename", searchFilename, "basePath", pm.fs.BasePath(), "error", err)
+					return -1, nil, err
+				}
+				originalRGBA = downscaleHires(hiresRGBA)
+				pm.log.Info("LoadPic: using the downscaled @2x variant", "filename", filename)
 			}
 		}
-	} else {
-		// BMP以外の場合、標準デコーダーを使用
-		img, _, err = image.Decode(file)
-		if err != nil {
-			pm.log.Error("LoadPic: failed to decode image", "filename", filename, "error", err)
-			return -1, fmt.Errorf("failed to decode image: %w", err)
+		if !pm.preferHires {
+			hiresRGBA = nil
+		} else if hiresRGBA == nil {
+			hiresRGBA = pm.loadHiresVariant(searchFilename, originalRGBA.Bounds())
 		}
+		width, height = originalRGBA.Bounds().Dx(), originalRGBA.Bounds().Dy()
 	}
 
-	// Ebiten画像に変換
-	ebitenImg := ebiten.NewImageFromImage(img)
+	// メモリ制限チェック（サンドボックスモード）
+	if err := pm.checkPixelBudget(width, height); err != nil {
+		pm.log.Error("LoadPic: resource limit exceeded", "filename", filename, "error", err)
+		return -1, nil, err
+	}
 
-	// 元の背景画像をRGBAとして保存（テキスト描画用）
-	bounds := img.Bounds()
-	originalRGBA := image.NewRGBA(bounds)
-	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
-		for x := bounds.Min.X; x < bounds.Max.X; x++ {
-			originalRGBA.Set(x, y, img.At(x, y))
-		}
+	// Ebiten画像に変換（元の背景画像はテキスト描画用に保持する）
+	// デコード中の場合はプレースホルダーで塗りつぶしておき、完了後に画素を書き込む
+	var ebitenImg *ebiten.Image
+	if pending != nil {
+		ebitenImg = newPlaceholderImage(width, height)
+	} else {
+		ebitenImg = ebiten.NewImageFromImage(originalRGBA)
 	}
 
 	// ピクチャーIDを割り当て（要件 1.2）
@@ -162,19 +242,46 @@ func (pm *PictureManager) LoadPic(filename string) (int, error) {
 		ID:            picID,
 		Image:         ebitenImg,
 		OriginalImage: originalRGBA,
-		Width:         ebitenImg.Bounds().Dx(),
-		Height:        ebitenImg.Bounds().Dy(),
+		Width:         width,
+		Height:        height,
+		pending:       pending,
+	}
+	if hiresRGBA != nil {
+		pic.Hires = ebiten.NewImageFromImage(hiresRGBA)
 	}
 
 	pm.pictures[picID] = pic
+	pm.tracker.add(picID, pic.Width, pic.Height, time.Now())
 
 	pm.log.Info("LoadPic: loaded picture",
 		"filename", filename,
 		"pictureID", picID,
 		"width", pic.Width,
-		"height", pic.Height)
+		"height", pic.Height,
+		"prefetched", prefetched,
+		"streaming", pending != nil,
+		"hires", pic.Hires != nil)
 
-	return picID, nil
+	return picID, pending, nil
+}
+
+// loadHiresVariant は searchFilename の @2x の画像を読み込む
+// ファイルがない場合や、大きさが等倍の画像（base）のちょうど2倍でない場合は nil を返す
+// 呼び出し元は pm.mu のロックを保持していること
+func (pm *PictureManager) loadHiresVariant(searchFilename string, base image.Rectangle) *image.RGBA {
+	name := hiresVariantName(searchFilename)
+	hires, err := decodePictureFile(pm.files(), name, pm.log)
+	if err != nil {
+		return nil
+	}
+	if !isHiresVariantOf(hires.Bounds(), base) {
+		pm.log.Warn("LoadPic: ignoring @2x variant with a size that is not twice the picture",
+			"filename", name,
+			"size", fmt.Sprintf("%dx%d", hires.Bounds().Dx(), hires.Bounds().Dy()),
+			"pictureSize", fmt.Sprintf("%dx%d", base.Dx(), base.Dy()))
+		return nil
+	}
+	return hires
 }
 
 // CreatePic は指定されたサイズの空のピクチャーを生成する
@@ -190,6 +297,12 @@ func (pm *PictureManager) CreatePic(width, height int) (int, error) {
 		return -1, err
 	}
 
+	// メモリ制限チェック（サンドボックスモード）
+	if err := pm.checkPixelBudget(width, height); err != nil {
+		pm.log.Error("CreatePic: resource limit exceeded", "error", err)
+		return -1, err
+	}
+
 	// 空の画像を作成
 	ebitenImg := ebiten.NewImage(width, height)
 
@@ -210,6 +323,7 @@ func (pm *PictureManager) CreatePic(width, height int) (int, error) {
 	}
 
 	pm.pictures[picID] = pic
+	pm.tracker.add(picID, pic.Width, pic.Height, time.Now())
 
 	pm.log.Info("CreatePic: created picture",
 		"pictureID", picID,
@@ -241,6 +355,12 @@ func (pm *PictureManager) CreatePicFrom(srcID int) (int, error) {
 		return -1, err
 	}
 
+	// メモリ制限チェック（サンドボックスモード）
+	if err := pm.checkPixelBudget(srcPic.Width, srcPic.Height); err != nil {
+		pm.log.Error("CreatePicFrom: resource limit exceeded", "error", err)
+		return -1, err
+	}
+
 	// 同じサイズの空の画像を作成（内容はコピーしない）
 	ebitenImg := ebiten.NewImage(srcPic.Width, srcPic.Height)
 
@@ -261,6 +381,7 @@ func (pm *PictureManager) CreatePicFrom(srcID int) (int, error) {
 	}
 
 	pm.pictures[picID] = pic
+	pm.tracker.add(picID, pic.Width, pic.Height, time.Now())
 
 	pm.log.Info("CreatePicFrom: created empty picture with same size as source",
 		"pictureID", picID,
@@ -288,9 +409,13 @@ func (pm *PictureManager) DelPic(id int) error {
 	if pic.Image != nil {
 		pic.Image.Deallocate()
 	}
+	if pic.Hires != nil {
+		pic.Hires.Deallocate()
+	}
 
 	// マップから削除（要件 9.4: ID再利用を許可）
 	delete(pm.pictures, id)
+	pm.tracker.remove(id)
 
 	pm.log.Info("DelPic: deleted picture", "pictureID", id)
 
@@ -314,14 +439,26 @@ func (pm *PictureManager) GetPic(id int) (*Picture, error) {
 
 // GetPicWithoutLock はロックなしでピクチャーを取得する（内部用）
 // 呼び出し元でロックを取得している場合に使用
+// ストリーミング読み込みでデコード中の場合は、完了を待って画素を書き込んでから返す
 func (pm *PictureManager) GetPicWithoutLock(id int) (*Picture, error) {
-	pic, exists := pm.pictures[id]
-	if !exists {
-		err := fmt.Errorf("picture not found: %d", id)
+	pic, err := pm.lookupPicWithoutLock(id)
+	if err != nil {
 		pm.log.Warn("GetPicWithoutLock: picture not found", "pictureID", id)
 		return nil, err
 	}
+	if pic.pending != nil {
+		pm.finishStreamed(pic)
+	}
+	return pic, nil
+}
 
+// lookupPicWithoutLock はデコードの完了を待たずにピクチャーを取得する（内部用）
+// 画素を参照しない処理（スプライトへの画像の関連付けなど）で使用する
+func (pm *PictureManager) lookupPicWithoutLock(id int) (*Picture, error) {
+	pic, exists := pm.pictures[id]
+	if !exists {
+		return nil, fmt.Errorf("picture not found: %d", id)
+	}
 	return pic, nil
 }
 
@@ -399,6 +536,12 @@ func (pm *PictureManager) CreatePicWithSize(srcID, width, height int) (int, erro
 		return -1, err
 	}
 
+	// メモリ制限チェック（サンドボックスモード）
+	if err := pm.checkPixelBudget(width, height); err != nil {
+		pm.log.Error("CreatePicWithSize: resource limit exceeded", "error", err)
+		return -1, err
+	}
+
 	// 指定サイズの空のピクチャーを作成（要件 2.1, 2.2）
 	// ソースピクチャーの内容はコピーしない
 	ebitenImg := ebiten.NewImage(width, height)
@@ -420,6 +563,7 @@ func (pm *PictureManager) CreatePicWithSize(srcID, width, height int) (int, erro
 	}
 
 	pm.pictures[picID] = pic
+	pm.tracker.add(picID, pic.Width, pic.Height, time.Now())
 
 	pm.log.Info("CreatePicWithSize: created picture with specified size",
 		"pictureID", picID,
@@ -0,0 +1,88 @@
+// picture_decode.go は画像ファイルのデコードを提供する
+// Ebitengine を使わないため、ヘッドレスモードのソフトウェア描画（WithHeadlessRendering）からも使う
+package graphics
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	_ "image/gif" // GIF デコーダを登録（LoadPic は最初のフレームを読み込む）
+	_ "image/png" // PNG デコーダを登録
+	"io"
+	"log/slog"
+	"path/filepath"
+	"strings"
+
+	_ "golang.org/x/image/bmp" // BMP デコーダを登録（非圧縮BMP用）
+
+	"github.com/zurustar/son-et/pkg/fileutil"
+)
+
+// isBMPFile はファイルパスがBMPファイルかどうかを判定する
+func isBMPFile(path string) bool {
+	ext := strings.ToLower(filepath.Ext(path))
+	return ext == ".bmp"
+}
+
+// decodePictureFile はファイルから画像を読み込み、RGBA画像にデコードする（BMP/PNG対応、要件 1.10, 1.10.1, 1.10.2, 1.11）
+// ロックを取らないため、先読みのワーカーからも呼び出せる
+func decodePictureFile(fsys fileutil.FileSystem, searchFilename string, log *slog.Logger) (*image.RGBA, error) {
+	file, err := fsys.Open(searchFilename)
+	if err != nil {
+		return nil, fmt.Errorf("file not found: %s", searchFilename)
+	}
+	defer file.Close()
+
+	// ファイル内容を一度読み込む（Seekが使えない場合があるため）
+	data, err := io.ReadAll(file)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read file: %w", err)
+	}
+	return decodePictureData(data, searchFilename, log)
+}
+
+// decodePictureData はファイルの内容をRGBA画像にデコードする
+// data は読み取り専用として扱う（メモリマップしたファイルをそのまま渡せる）
+func decodePictureData(data []byte, searchFilename string, log *slog.Logger) (*image.RGBA, error) {
+	var img image.Image
+	var err error
+
+	// BMPファイルの場合、RLE圧縮かどうかを確認
+	isRLE := false
+	if isBMPFile(searchFilename) {
+		isRLE, err = IsBMPRLECompressedFromBytes(data)
+		if err != nil {
+			log.Warn("LoadPic: failed to check RLE compression, falling back to standard decoder", "filename", searchFilename, "error", err)
+		}
+	}
+
+	if isRLE {
+		// RLE圧縮BMPの場合、カスタムデコーダーを使用（要件 1.10.1）
+		log.Info("LoadPic: using custom RLE BMP decoder", "filename", searchFilename)
+		img, err = DecodeBMPFromBytes(data)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode RLE BMP: %w", err)
+		}
+	} else {
+		// 非圧縮BMP・PNGの場合、標準デコーダーを使用（要件 1.10.2）
+		img, _, err = image.Decode(bytes.NewReader(data))
+		if err != nil && isBMPFile(searchFilename) {
+			// 標準デコーダーが対応していないBMP（OS/2形式のヘッダー、16ビットなど）はカスタムデコーダーで読む
+			log.Info("LoadPic: standard decoder failed, using custom BMP decoder", "filename", searchFilename, "error", err)
+			img, err = DecodeBMPFromBytes(data)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode image: %w", err)
+		}
+	}
+
+	// RGBAに変換
+	bounds := img.Bounds()
+	rgba := image.NewRGBA(bounds)
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			rgba.Set(x, y, img.At(x, y))
+		}
+	}
+	return rgba, nil
+}
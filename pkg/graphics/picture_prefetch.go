@@ -0,0 +1,109 @@
+//go:build !nogpu
+
+package graphics
+
+import (
+	"image"
+	"strings"
+)
+
+// 画像の先読みの設定
+const (
+	prefetchWorkers     = 2  // 同時にデコードする画像の数
+	maxPrefetchPictures = 64 // 先読みする画像の上限（メモリ使用量を抑えるため）
+)
+
+// prefetchedPicture は先読み中・先読み済みの画像
+// done が閉じられた後に img と err を参照する
+type prefetchedPicture struct {
+	done chan struct{}
+	img  *image.RGBA
+	err  error
+}
+
+// prefetchKey は先読みの一覧でファイル名を照合するためのキーを返す
+// スクリプトの中で大文字・小文字が揃っていない場合も同じファイルとして扱う
+func prefetchKey(searchFilename string) string {
+	return strings.ToLower(searchFilename)
+}
+
+// Prefetch は指定された画像をバックグラウンドで読み込み、デコードしておく
+//
+// filenames はスクリプトの静的解析（compiler.CollectAssets）で見つかった LoadPic の引数で、
+// 出現順に先読みする。LoadPic で同じファイルを読み込むときに結果を使い、
+// 低速なメディアでもディスクの読み込みとデコードの待ち時間が表に出ないようにする。
+// 先読みした画像は一度使うと破棄する。先読みに失敗した場合は LoadPic が通常どおり読み込む。
+func (pm *PictureManager) Prefetch(filenames []string) {
+	pm.mu.Lock()
+	if pm.prefetched == nil {
+		pm.prefetched = make(map[string]*prefetchedPicture)
+	}
+	fsys := pm.files()
+	log := pm.log
+	workers := pm.limitDecodeWorkers(prefetchWorkers)
+
+	type job struct {
+		name  string
+		entry *prefetchedPicture
+	}
+	var jobs []job
+	for _, filename := range filenames {
+		if len(pm.prefetched) >= maxPrefetchPictures {
+			break
+		}
+		name := strings.TrimLeft(filename, "/\\")
+		key := prefetchKey(name)
+		if _, exists := pm.prefetched[key]; exists || name == "" {
+			continue
+		}
+		entry := &prefetchedPicture{done: make(chan struct{})}
+		pm.prefetched[key] = entry
+		jobs = append(jobs, job{name: name, entry: entry})
+	}
+	pm.mu.Unlock()
+
+	if len(jobs) == 0 {
+		return
+	}
+	log.Info("Prefetching pictures", "count", len(jobs))
+
+	queue := make(chan job, len(jobs))
+	for _, j := range jobs {
+		queue <- j
+	}
+	close(queue)
+	for range min(workers, len(jobs)) {
+		go func() {
+			for j := range queue {
+				j.entry.img, j.entry.err = decodePictureFile(fsys, j.name, log)
+				if j.entry.err != nil {
+					log.Debug("Picture prefetch failed", "filename", j.name, "error", j.entry.err)
+				}
+				close(j.entry.done)
+			}
+		}()
+	}
+}
+
+// takePrefetched は先読みした画像を取り出す（先読み中の場合は完了を待つ）
+// 先読みしていない、または先読みに失敗した場合は nil を返す
+// 呼び出し元は pm.mu のロックを保持していること
+func (pm *PictureManager) takePrefetched(searchFilename string) (*image.RGBA, bool) {
+	key := prefetchKey(searchFilename)
+	entry, ok := pm.prefetched[key]
+	if !ok {
+		return nil, false
+	}
+	delete(pm.prefetched, key)
+
+	<-entry.done
+	if entry.err != nil {
+		return nil, false
+	}
+	return entry.img, true
+}
+
+// PrefetchPictures は画像をバックグラウンドで先読みする（PictureManager.Prefetch を参照）
+func (gs *GraphicsSystem) PrefetchPictures(filenames []string) {
+	gs.pictures.Prefetch(filenames)
+}
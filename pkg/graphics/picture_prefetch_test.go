@@ -0,0 +1,62 @@
+//go:build !nogpu
+
+package graphics
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// TestPrefetchThenLoadPic tests that LoadPic uses a prefetched picture once and then decodes normally.
+func TestPrefetchThenLoadPic(t *testing.T) {
+	tmpDir := t.TempDir()
+	createTestBMP(t, filepath.Join(tmpDir, "title.bmp"), 40, 30)
+
+	pm := NewPictureManager(tmpDir)
+	pm.Prefetch([]string{"/title.bmp", "TITLE.BMP", "missing.bmp"})
+
+	pm.mu.RLock()
+	queued := len(pm.prefetched)
+	entry := pm.prefetched["title.bmp"]
+	pm.mu.RUnlock()
+	if queued != 2 || entry == nil {
+		t.Fatalf("expected 2 queued pictures (duplicates removed), got %d", queued)
+	}
+	<-entry.done
+
+	// 先読み後にファイルを削除しても、先読みした画像から読み込める
+	if err := os.Remove(filepath.Join(tmpDir, "title.bmp")); err != nil {
+		t.Fatal(err)
+	}
+	id, err := pm.LoadPic("Title.bmp")
+	if err != nil {
+		t.Fatalf("LoadPic should use the prefetched picture: %v", err)
+	}
+	if w, h := pm.PicWidth(id), pm.PicHeight(id); w != 40 || h != 30 {
+		t.Errorf("size = %dx%d, want 40x30", w, h)
+	}
+
+	// 先読みした画像は一度だけ使う
+	if _, err := pm.LoadPic("title.bmp"); err == nil {
+		t.Error("second LoadPic should read the (removed) file again")
+	}
+}
+
+// TestPrefetchFailureFallsBack tests that a failed prefetch does not prevent LoadPic.
+func TestPrefetchFailureFallsBack(t *testing.T) {
+	tmpDir := t.TempDir()
+	pm := NewPictureManager(tmpDir)
+	pm.Prefetch([]string{"late.bmp"})
+
+	// 先読みの完了を待ってからファイルを作成する
+	pm.mu.RLock()
+	entry := pm.prefetched["late.bmp"]
+	pm.mu.RUnlock()
+	<-entry.done
+	createTestBMP(t, filepath.Join(tmpDir, "late.bmp"), 10, 10)
+
+	if _, err := pm.LoadPic("late.bmp"); err != nil {
+		t.Errorf("LoadPic should decode the file after a failed prefetch: %v", err)
+	}
+}
@@ -1,3 +1,5 @@
+//go:build !nogpu
+
 package graphics
 
 import (
@@ -8,6 +10,8 @@ import (
 	"testing"
 
 	"golang.org/x/image/bmp"
+
+	"github.com/zurustar/son-et/pkg/fileutil"
 )
 
 // createTestBMP creates a test BMP file
@@ -90,6 +94,43 @@ func TestCreatePic(t *testing.T) {
 	}
 }
 
+func TestPictureManagerSandbox(t *testing.T) {
+	pm := NewPictureManager("/test/path")
+	pm.EnableSandbox(100 * 100)
+
+	if realFS, ok := pm.fs.(*fileutil.RealFS); !ok || !realFS.IsConfined() {
+		t.Error("sandbox should switch to a confined RealFS")
+	}
+	if pm.fs.BasePath() != "/test/path" {
+		t.Errorf("Expected basePath '/test/path', got '%s'", pm.fs.BasePath())
+	}
+
+	// 合計ピクセル数の上限まで作成できる
+	if _, err := pm.CreatePic(100, 50); err != nil {
+		t.Fatalf("CreatePic within budget failed: %v", err)
+	}
+	id, err := pm.CreatePic(50, 100)
+	if err != nil {
+		t.Fatalf("CreatePic up to the budget failed: %v", err)
+	}
+
+	// 上限を超える作成は失敗する
+	if _, err := pm.CreatePic(1, 1); err == nil {
+		t.Error("CreatePic beyond the budget should fail")
+	}
+	if _, err := pm.CreatePicFrom(id); err == nil {
+		t.Error("CreatePicFrom beyond the budget should fail")
+	}
+
+	// 削除すると再び作成できる
+	if err := pm.DelPic(id); err != nil {
+		t.Fatalf("DelPic failed: %v", err)
+	}
+	if _, err := pm.CreatePicWithSize(0, 50, 100); err != nil {
+		t.Errorf("CreatePicWithSize after DelPic failed: %v", err)
+	}
+}
+
 func TestCreatePicMultiple(t *testing.T) {
 	pm := NewPictureManager("")
 
@@ -305,6 +346,62 @@ func TestLoadPicCaseInsensitive(t *testing.T) {
 	}
 }
 
+func TestLoadPicAssetDirs(t *testing.T) {
+	tmpDir := t.TempDir()
+	if err := os.Mkdir(filepath.Join(tmpDir, "pics"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	createTestBMP(t, filepath.Join(tmpDir, "pics", "back.bmp"), 40, 30)
+
+	pm := NewPictureManager(tmpDir)
+	if _, err := pm.LoadPic("back.bmp"); err == nil {
+		t.Fatal("LoadPic should not search asset directories before they are set")
+	}
+
+	// プロジェクトマニフェストの assets に指定したディレクトリからも探す
+	pm.SetAssetDirs([]string{"pics"})
+	id, err := pm.LoadPic("BACK.BMP")
+	if err != nil {
+		t.Fatalf("LoadPic from asset directory failed: %v", err)
+	}
+	pic, err := pm.GetPic(id)
+	if err != nil {
+		t.Fatalf("GetPic failed: %v", err)
+	}
+	if pic.Width != 40 || pic.Height != 30 {
+		t.Errorf("size = %dx%d, want 40x30", pic.Width, pic.Height)
+	}
+}
+
+func TestLoadPicAssetVars(t *testing.T) {
+	tmpDir := t.TempDir()
+	for _, dir := range []string{"original", "hires"} {
+		if err := os.Mkdir(filepath.Join(tmpDir, dir), 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	createTestBMP(t, filepath.Join(tmpDir, "original", "pic01.bmp"), 40, 30)
+	createTestBMP(t, filepath.Join(tmpDir, "hires", "pic01.bmp"), 80, 60)
+
+	// 同じファイル名でも変数の値によって別のアセットを読み込む
+	for dir, wantWidth := range map[string]int{"original": 40, "hires": 80} {
+		pm := NewPictureManager(tmpDir)
+		pm.SetAssetVars(map[string]string{"ASSETS": dir})
+		id, err := pm.LoadPic("${ASSETS}/PIC01.BMP")
+		if err != nil {
+			t.Fatalf("ASSETS=%s: LoadPic failed: %v", dir, err)
+		}
+		if got := pm.PicWidth(id); got != wantWidth {
+			t.Errorf("ASSETS=%s: width = %d, want %d", dir, got, wantWidth)
+		}
+	}
+
+	pm := NewPictureManager(tmpDir)
+	if _, err := pm.LoadPic("${ASSETS}/pic01.bmp"); err == nil {
+		t.Error("LoadPic with an undefined variable should fail")
+	}
+}
+
 func TestLoadPicNonExistent(t *testing.T) {
 	tmpDir := t.TempDir()
 	pm := NewPictureManager(tmpDir)
@@ -631,3 +728,49 @@ func TestLoadPicRLE8AllRobotFiles(t *testing.T) {
 		})
 	}
 }
+
+func TestLoadPicHiresVariant(t *testing.T) {
+	tmpDir := t.TempDir()
+	createTestBMP(t, filepath.Join(tmpDir, "bg.bmp"), 40, 30)
+	createTestBMP(t, filepath.Join(tmpDir, "bg@2x.bmp"), 80, 60)
+	createTestBMP(t, filepath.Join(tmpDir, "odd.bmp"), 40, 30)
+	createTestBMP(t, filepath.Join(tmpDir, "odd@2x.bmp"), 81, 60)
+	createTestBMP(t, filepath.Join(tmpDir, "only@2x.bmp"), 80, 60)
+
+	pm := NewPictureManager(tmpDir)
+	id, err := pm.LoadPic("bg.bmp")
+	if err != nil {
+		t.Fatalf("LoadPic failed: %v", err)
+	}
+	if pic, _ := pm.GetPic(id); pic.Hires != nil {
+		t.Error("expected no @2x image unless preferred")
+	}
+
+	pm.SetPreferHires(true)
+	id, err = pm.LoadPic("bg.bmp")
+	if err != nil {
+		t.Fatalf("LoadPic failed: %v", err)
+	}
+	pic, _ := pm.GetPic(id)
+	if pic.Width != 40 || pic.Height != 30 {
+		t.Errorf("picture size = %dx%d, want the 1x size 40x30", pic.Width, pic.Height)
+	}
+	if pic.Hires == nil || pic.Hires.Bounds() != image.Rect(0, 0, 80, 60) {
+		t.Errorf("expected an 80x60 @2x image, got %v", pic.Hires)
+	}
+
+	// 大きさがちょうど2倍でない @2x の画像は使わない
+	id, _ = pm.LoadPic("odd.bmp")
+	if pic, _ := pm.GetPic(id); pic.Hires != nil {
+		t.Error("expected the @2x image with a mismatched size to be ignored")
+	}
+
+	// @2x の画像だけがある場合は縮小した画像がピクチャーになる
+	id, err = pm.LoadPic("only.bmp")
+	if err != nil {
+		t.Fatalf("LoadPic with only the @2x image failed: %v", err)
+	}
+	if pic, _ := pm.GetPic(id); pic.Width != 40 || pic.Height != 30 || pic.Hires == nil {
+		t.Errorf("picture = %dx%d (hires %v), want 40x30 with the @2x image", pic.Width, pic.Height, pic.Hires != nil)
+	}
+}
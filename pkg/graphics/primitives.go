@@ -1,3 +1,5 @@
+//go:build !nogpu
+
 package graphics
 
 import (
@@ -27,6 +29,9 @@ func (gs *GraphicsSystem) drawLineInternal(picID, x1, y1, x2, y2 int) error {
 		return fmt.Errorf("picture not found: %d", picID)
 	}
 
+	// 図形は等倍の画像だけに描くため、@2x の画像は使わない
+	gs.dropHires(picID, pic)
+
 	// 線を描画
 	vector.StrokeLine(
 		pic.Image,
@@ -89,6 +94,7 @@ func (gs *GraphicsSystem) drawLineInternal(picID, x1, y1, x2, y2 int) error {
 func (gs *GraphicsSystem) DrawLineOnPic(picID, x1, y1, x2, y2 int) error {
 	gs.mu.Lock()
 	defer gs.mu.Unlock()
+	gs.invalidate()
 	return gs.drawLineInternal(picID, x1, y1, x2, y2)
 }
 
@@ -111,6 +117,8 @@ func (gs *GraphicsSystem) drawRectInternal(picID, x1, y1, x2, y2, fillMode int)
 		return fmt.Errorf("picture not found: %d", picID)
 	}
 
+	gs.dropHires(picID, pic)
+
 	// 座標を正規化（x1 < x2, y1 < y2 を保証）
 	if x1 > x2 {
 		x1, x2 = x2, x1
@@ -220,6 +228,7 @@ func (gs *GraphicsSystem) drawRectInternal(picID, x1, y1, x2, y2, fillMode int)
 func (gs *GraphicsSystem) DrawRectOnPic(picID, x1, y1, x2, y2, fillMode int) error {
 	gs.mu.Lock()
 	defer gs.mu.Unlock()
+	gs.invalidate()
 	return gs.drawRectInternal(picID, x1, y1, x2, y2, fillMode)
 }
 
@@ -242,6 +251,8 @@ func (gs *GraphicsSystem) fillRectInternal(picID, x1, y1, x2, y2 int, c color.Co
 		return fmt.Errorf("picture not found: %d", picID)
 	}
 
+	gs.dropHires(picID, pic)
+
 	// 座標を正規化（x1 < x2, y1 < y2 を保証）
 	if x1 > x2 {
 		x1, x2 = x2, x1
@@ -311,6 +322,7 @@ func (gs *GraphicsSystem) fillRectInternal(picID, x1, y1, x2, y2 int, c color.Co
 func (gs *GraphicsSystem) FillRectOnPic(picID, x1, y1, x2, y2 int, c color.Color) error {
 	gs.mu.Lock()
 	defer gs.mu.Unlock()
+	gs.invalidate()
 	return gs.fillRectInternal(picID, x1, y1, x2, y2, c)
 }
 
@@ -333,6 +345,8 @@ func (gs *GraphicsSystem) drawCircleInternal(picID, x, y, radius, fillMode int)
 		return fmt.Errorf("picture not found: %d", picID)
 	}
 
+	gs.dropHires(picID, pic)
+
 	if radius <= 0 {
 		gs.log.Debug("DrawCircle: invalid radius", "radius", radius)
 		return nil
@@ -436,6 +450,7 @@ func (gs *GraphicsSystem) drawCircleInternal(picID, x, y, radius, fillMode int)
 func (gs *GraphicsSystem) DrawCircleOnPic(picID, x, y, radius, fillMode int) error {
 	gs.mu.Lock()
 	defer gs.mu.Unlock()
+	gs.invalidate()
 	return gs.drawCircleInternal(picID, x, y, radius, fillMode)
 }
 
@@ -0,0 +1,9 @@
+package graphics
+
+// サンドボックスモード（--sandbox）のリソース制限
+const (
+	// SandboxMaxPicturePixels は全ピクチャーの合計ピクセル数の上限（RGBAで約256MB）
+	SandboxMaxPicturePixels = 64 * 1024 * 1024
+	// SandboxMaxCasts はキャストの最大数（通常は1024）
+	SandboxMaxCasts = 512
+)
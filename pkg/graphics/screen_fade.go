@@ -0,0 +1,131 @@
+//go:build !nogpu
+
+package graphics
+
+import (
+	"fmt"
+	"image/color"
+	"math"
+	"time"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/vector"
+)
+
+// screenFade は画面全体のフェード（FadeOut/FadeIn）の状態を管理する
+// すべてのスプライトを描画した後に、指定色の矩形を不透明度を変えながら重ねる
+//
+// FadeOut は不透明度を 0 → 1 に変化させ、完了後も画面を覆ったままにする
+// FadeIn は不透明度を 1 → 0 に変化させ、完了後はオーバーレイを取り除く
+type screenFade struct {
+	color  color.RGBA
+	from   float64 // 開始時の不透明度（0.0 - 1.0）
+	to     float64 // 終了時の不透明度（0.0 - 1.0）
+	frames int     // フェードにかけるフレーム数
+	frame  int     // 経過フレーム数
+	onDone func()  // 完了時に一度だけ呼び出すコールバック
+}
+
+// newScreenFade は新しいフェードを作成する
+// duration は durationFrames でフレーム数に変換する
+func newScreenFade(fadeOut bool, c color.RGBA, duration time.Duration, onDone func()) *screenFade {
+	f := &screenFade{color: c, frames: durationFrames(duration), onDone: onDone}
+	if fadeOut {
+		f.to = 1
+	} else {
+		f.from = 1
+	}
+	return f
+}
+
+// durationFrames は duration を現在のTPS（--tps）に基づいてフレーム数に変換する（最低1フレーム）
+func durationFrames(duration time.Duration) int {
+	tps := ebiten.TPS()
+	if tps <= 0 {
+		tps = ebiten.DefaultTPS
+	}
+	return max(int(math.Ceil(duration.Seconds()*float64(tps))), 1)
+}
+
+// step はフェードを1フレーム進める
+// このフレームでフェードが完了した場合は完了コールバックを返す（それ以外は nil）
+func (f *screenFade) step() func() {
+	if f.frame >= f.frames {
+		return nil
+	}
+	f.frame++
+	if f.frame < f.frames {
+		return nil
+	}
+	onDone := f.onDone
+	f.onDone = nil
+	return onDone
+}
+
+// alpha は現在の不透明度を返す
+func (f *screenFade) alpha() float64 {
+	progress := float64(f.frame) / float64(f.frames)
+	return f.from + (f.to-f.from)*progress
+}
+
+// finished はフェードが完了し、オーバーレイが不要になったかを返す
+func (f *screenFade) finished() bool {
+	return f.frame >= f.frames && f.to == 0
+}
+
+// overlayColor は現在の不透明度を反映したオーバーレイの色を返す
+func (f *screenFade) overlayColor() color.NRGBA {
+	return color.NRGBA{
+		R: f.color.R,
+		G: f.color.G,
+		B: f.color.B,
+		A: uint8(math.Round(f.alpha() * 0xFF)),
+	}
+}
+
+// Fade は画面全体のフェードを開始する
+// fadeOut が true の場合は画面を指定色で覆い、false の場合は指定色から画面を表示する
+// c は 0xRRGGBB 形式の int または color.Color
+// フェード完了時に onDone を一度だけ呼び出す（onDone は nil でもよい）
+// 実行中のフェードは置き換えられ、その完了コールバックは呼び出されない
+func (gs *GraphicsSystem) Fade(fadeOut bool, c any, duration time.Duration, onDone func()) error {
+	fadeColor, err := fadeColorFrom(c)
+	if err != nil {
+		return err
+	}
+
+	gs.mu.Lock()
+	defer gs.mu.Unlock()
+
+	gs.fade = newScreenFade(fadeOut, fadeColor, duration, onDone)
+	gs.invalidate()
+	gs.log.Debug("Screen fade started", "fadeOut", fadeOut, "color", fmt.Sprintf("0x%06X", ColorToInt(fadeColor)), "frames", gs.fade.frames)
+	return nil
+}
+
+// updateFade はフェードを1フレーム進め、完了した場合はコールバックを返す
+// 呼び出し元は gs.mu のロックを保持していること
+func (gs *GraphicsSystem) updateFade() func() {
+	if gs.fade == nil {
+		return nil
+	}
+	onDone := gs.fade.step()
+	if gs.fade.finished() {
+		gs.fade = nil
+	}
+	return onDone
+}
+
+// drawFade はフェードのオーバーレイを画面全体に描画する
+// 呼び出し元は gs.mu の読み取りロックを保持していること
+func (gs *GraphicsSystem) drawFade(screen *ebiten.Image) {
+	if gs.fade == nil {
+		return
+	}
+	overlay := gs.fade.overlayColor()
+	if overlay.A == 0 {
+		return
+	}
+	bounds := screen.Bounds()
+	vector.FillRect(screen, 0, 0, float32(bounds.Dx()), float32(bounds.Dy()), overlay, false)
+}
@@ -0,0 +1,109 @@
+//go:build !nogpu
+
+package graphics
+
+import (
+	"image/color"
+	"testing"
+	"time"
+)
+
+// TestScreenFadeOut tests that FadeOut reaches full opacity and keeps the overlay.
+func TestScreenFadeOut(t *testing.T) {
+	done := 0
+	f := newScreenFade(true, color.RGBA{A: 0xFF}, 100*time.Millisecond, func() { done++ })
+
+	// 100ms は 60TPS で 6 フレーム
+	if f.frames != 6 {
+		t.Fatalf("frames = %d, want 6", f.frames)
+	}
+	if f.alpha() != 0 {
+		t.Errorf("initial alpha = %v, want 0", f.alpha())
+	}
+
+	for i := 0; i < 3; i++ {
+		if cb := f.step(); cb != nil {
+			t.Fatalf("callback returned at frame %d", i+1)
+		}
+	}
+	if a := f.alpha(); a != 0.5 {
+		t.Errorf("alpha at half way = %v, want 0.5", a)
+	}
+
+	for i := 0; i < 3; i++ {
+		if cb := f.step(); cb != nil {
+			cb()
+		}
+	}
+	if done != 1 {
+		t.Errorf("callback called %d times, want 1", done)
+	}
+	if f.overlayColor().A != 0xFF {
+		t.Errorf("overlay alpha = %d, want 255", f.overlayColor().A)
+	}
+	if f.finished() {
+		t.Error("FadeOut overlay should remain after completion")
+	}
+
+	// 完了後はコールバックを再度返さない
+	if cb := f.step(); cb != nil {
+		t.Error("callback should be returned only once")
+	}
+}
+
+// TestScreenFadeIn tests that FadeIn starts opaque and finishes without overlay.
+func TestScreenFadeIn(t *testing.T) {
+	f := newScreenFade(false, color.RGBA{R: 0xFF, G: 0xFF, B: 0xFF, A: 0xFF}, 0, nil)
+
+	// 0 は最低1フレーム
+	if f.frames != 1 {
+		t.Fatalf("frames = %d, want 1", f.frames)
+	}
+	if f.overlayColor() != (color.NRGBA{R: 0xFF, G: 0xFF, B: 0xFF, A: 0xFF}) {
+		t.Errorf("initial overlay = %v, want opaque white", f.overlayColor())
+	}
+
+	f.step()
+	if !f.finished() {
+		t.Error("FadeIn should be finished")
+	}
+	if f.alpha() != 0 {
+		t.Errorf("final alpha = %v, want 0", f.alpha())
+	}
+}
+
+// TestGraphicsSystemFade tests the fade lifecycle through Update.
+func TestGraphicsSystemFade(t *testing.T) {
+	gs := NewGraphicsSystem("")
+
+	done := 0
+	if err := gs.Fade(true, 0x000000, 50*time.Millisecond, func() { done++ }); err != nil {
+		t.Fatalf("Fade failed: %v", err)
+	}
+	for i := 0; i < 10; i++ {
+		gs.Update()
+	}
+	if done != 1 {
+		t.Errorf("FadeOut callback called %d times, want 1", done)
+	}
+	if gs.fade == nil {
+		t.Error("FadeOut overlay should remain after completion")
+	}
+
+	if err := gs.Fade(false, 0x000000, 50*time.Millisecond, func() { done++ }); err != nil {
+		t.Fatalf("Fade failed: %v", err)
+	}
+	for i := 0; i < 10; i++ {
+		gs.Update()
+	}
+	if done != 2 {
+		t.Errorf("FadeIn callback called %d times, want 2 in total", done)
+	}
+	if gs.fade != nil {
+		t.Error("FadeIn overlay should be removed after completion")
+	}
+
+	if err := gs.Fade(true, "red", time.Second, nil); err == nil {
+		t.Error("Fade with invalid color should return error")
+	}
+}
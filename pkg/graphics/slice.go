@@ -0,0 +1,55 @@
+package graphics
+
+import (
+	"fmt"
+	"image"
+)
+
+// NineSlice はスプライトの画像を9分割して伸縮する設定（SetNineSlice）
+// 画像の端から Left/Top/Right/Bottom ピクセルの枠は伸縮せず、上下の辺は横に、左右の辺は縦に、
+// 中央は両方向に伸縮して Width×Height の大きさで描画する。
+// Width が Left+Right より小さい場合は左右の枠を縮める（Height も同様）。
+type NineSlice struct {
+	Left, Top, Right, Bottom int // 伸縮しない枠の幅（画像の端からのピクセル数）
+	Width, Height            int // 描画する大きさ
+}
+
+// checkSourceRect は描画する範囲を検証する（nil は解除として常に有効）
+func checkSourceRect(rect *image.Rectangle) error {
+	if rect != nil && (rect.Min.X < 0 || rect.Min.Y < 0 || rect.Empty()) {
+		return fmt.Errorf("invalid source rect: %v", *rect)
+	}
+	return nil
+}
+
+// checkNineSlice は9分割の設定を検証する（nil は解除として常に有効）
+func checkNineSlice(ns *NineSlice) error {
+	if ns == nil {
+		return nil
+	}
+	if ns.Left < 0 || ns.Top < 0 || ns.Right < 0 || ns.Bottom < 0 {
+		return fmt.Errorf("invalid nine-slice borders: %d, %d, %d, %d", ns.Left, ns.Top, ns.Right, ns.Bottom)
+	}
+	if ns.Width <= 0 || ns.Height <= 0 {
+		return fmt.Errorf("invalid nine-slice size: %dx%d", ns.Width, ns.Height)
+	}
+	return nil
+}
+
+// copySourceRect は呼び出し元の変更が反映されないように描画する範囲を複製する
+func copySourceRect(rect *image.Rectangle) *image.Rectangle {
+	if rect == nil {
+		return nil
+	}
+	c := *rect
+	return &c
+}
+
+// copyNineSlice は呼び出し元の変更が反映されないように9分割の設定を複製する
+func copyNineSlice(ns *NineSlice) *NineSlice {
+	if ns == nil {
+		return nil
+	}
+	c := *ns
+	return &c
+}
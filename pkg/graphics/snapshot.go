@@ -0,0 +1,38 @@
+package graphics
+
+import (
+	"image"
+	"image/png"
+	"os"
+	"path/filepath"
+)
+
+// SnapshotScreen は SaveSnapshot で画面全体を書き出すことを表すキャストID
+const SnapshotScreen = -1
+
+// maxPendingSnapshots は次の描画を待つ書き出しの上限（ループから呼び出してもメモリを使い切らないようにする）
+const maxPendingSnapshots = 16
+
+// snapshotRequest は次の描画で書き出す画像
+type snapshotRequest struct {
+	castID int
+	path   string
+}
+
+// writePNG は img を PNG ファイルとして書き出す
+// 描画中に呼び出すため、圧縮率より速さを優先する
+func writePNG(path string, img image.Image) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	encoder := png.Encoder{CompressionLevel: png.BestSpeed}
+	if err := encoder.Encode(f, img); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
@@ -1,3 +1,5 @@
+//go:build !nogpu
+
 // Package graphics provides sprite-based rendering system.
 package graphics
 
@@ -44,6 +46,30 @@ type Sprite struct {
 	// 透明色処理など、特殊な描画が必要な場合に使用
 	// nilの場合は通常の描画を行う
 	customDraw func(screen *ebiten.Image, x, y float64, alpha float32)
+
+	// マスク（nilの場合は制限しない）
+	// 設定されている場合、マスクの外側は自身も子スプライトも描画しない
+	mask *SpriteMask
+
+	// 影と縁取り（nilの場合はなし）
+	shadow  *Shadow
+	outline *Outline
+
+	// ヒット演出（Shake/Flash）
+	// 揺れは描画する位置だけをずらし、当たり判定や子スプライトの位置には影響しない
+	shakeX, shakeY float64
+	flash          *spriteFlash // nilの場合はなし
+
+	// 描画する範囲と9分割の伸縮（nilの場合は画像全体をそのまま描画する）
+	source    *image.Rectangle
+	nineSlice *NineSlice
+
+	// 絵を差し替えるクロスフェード（nilの場合はなし、ChangePicture）
+	crossfade *spriteCrossfade
+
+	// @2x の画像（縦横が image の2倍、nilの場合はなし）
+	// 透明色の処理を済ませた画像で、@2x の画面（HiresFrame）では customDraw の代わりに描画する
+	hires *ebiten.Image
 }
 
 // NewSprite は新しいスプライトを作成する
@@ -75,10 +101,28 @@ func (s *Sprite) Image() *ebiten.Image {
 
 // SetImage はスプライトの画像を設定する
 func (s *Sprite) SetImage(img *ebiten.Image) {
+	if img != s.image {
+		// 別の画像に替えた場合、@2x の画像は内容が合わなくなる
+		s.hires = nil
+	}
 	s.image = img
 	s.dirty = true
 }
 
+// HiresImage はスプライトの @2x の画像を返す（nilの場合はなし）
+func (s *Sprite) HiresImage() *ebiten.Image {
+	return s.hires
+}
+
+// SetHiresImage はスプライトの @2x の画像を設定する（nilの場合は等倍の画像だけを使う）
+func (s *Sprite) SetHiresImage(img *ebiten.Image) {
+	if img == s.hires {
+		return
+	}
+	s.hires = img
+	s.dirty = true
+}
+
 // Position はスプライトの位置を返す
 func (s *Sprite) Position() (float64, float64) {
 	return s.x, s.y
@@ -300,6 +344,23 @@ type SpriteManager struct {
 	// 各スプライト描画後に呼び出される（デバッグオーバーレイ用）
 	// 引数: screen, sprite, absX, absY
 	debugDrawCallback func(screen *ebiten.Image, s *Sprite, absX, absY float64)
+
+	// アルファマスクを適用するための作業用画像（描画先と同じ大きさ、必要になった時点で作成）
+	maskBuffer *ebiten.Image
+
+	// 影・縁取りを描画するための作業用画像（必要になった時点で作成し、スプライト間で使い回す）
+	effectSource *ebiten.Image
+	effectBuffer *ebiten.Image
+
+	// 描画する範囲・9分割の伸縮を適用するための作業用画像
+	sliceBuffer *ebiten.Image
+
+	// クロスフェード中の2つの絵を合成するための作業用画像
+	crossfadeSource *ebiten.Image
+	crossfadeBuffer *ebiten.Image
+
+	// 描画品質の段階（--adaptive-quality で下がると影・縁取りを描画しない）
+	quality QualityLevel
 }
 
 // NewSpriteManager は新しいSpriteManagerを作成する
@@ -321,6 +382,13 @@ func (sm *SpriteManager) SetDebugDrawCallback(callback func(screen *ebiten.Image
 	sm.debugDrawCallback = callback
 }
 
+// SetQualityLevel は描画品質の段階を設定する
+func (sm *SpriteManager) SetQualityLevel(level QualityLevel) {
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+	sm.quality = level
+}
+
 // CreateSprite は新しいスプライトを作成して登録する
 func (sm *SpriteManager) CreateSprite(img *ebiten.Image) *Sprite {
 	sm.mu.Lock()
@@ -476,22 +544,62 @@ func (sm *SpriteManager) sortSprites() {
 // 要件 3.2: 同じ親を持つ子スプライトをLocal_Z_Order順で描画する
 // 要件 15.1-15.8: デバッグオーバーレイの描画（各スプライト描画直後）
 func (sm *SpriteManager) Draw(screen *ebiten.Image) {
+	sm.drawSprites(screen, nil)
+}
+
+// spriteDrawItem は描画するスプライトの状態のスナップショット
+type spriteDrawItem struct {
+	sprite     *Sprite
+	image      *ebiten.Image
+	hires      *ebiten.Image
+	x, y       float64
+	alpha      float64
+	customDraw func(screen *ebiten.Image, x, y float64, alpha float32)
+	clip       image.Rectangle // マスクによるクリップ矩形（clipped の場合）
+	masks      []placedMask    // アルファマスク
+	clipped    bool
+	shadow     *Shadow
+	outline    *Outline
+	flash      *spriteFlash
+	source     *image.Rectangle
+	nineSlice  *NineSlice
+	crossfade  *spriteCrossfade
+}
+
+// drawSprites は keep が true を返す可視スプライトだけをZ_Path順で描画する
+// keep が nil の場合はすべての可視スプライトを描画し、すべてのスプライトを描画済みとする
+func (sm *SpriteManager) drawSprites(screen *ebiten.Image, keep func(s *Sprite) bool) {
+	items, debugCallback, drawsEffects := sm.snapshotDrawItems(keep)
+
+	for _, item := range items {
+		sm.drawItem(screen, item, drawsEffects)
+
+		// デバッグ描画コールバックを呼び出す（各スプライト描画直後）
+		// これにより、後から描画されるスプライトによってデバッグ情報が隠れる
+		if debugCallback != nil {
+			debugCallback(screen, item.sprite, item.x, item.y)
+		}
+	}
+}
+
+// snapshotDrawItems は keep が true を返す可視スプライトの状態をZ_Path順に取り出す
+// keep が nil の場合はすべての可視スプライトを取り出し、すべてのスプライトを描画済みとする
+func (sm *SpriteManager) snapshotDrawItems(keep func(s *Sprite) bool) ([]spriteDrawItem, func(screen *ebiten.Image, s *Sprite, absX, absY float64), bool) {
 	sm.mu.Lock()
+	defer sm.mu.Unlock()
 	if sm.needSort {
 		sm.sortSprites()
 	}
 	// ソート済みスライスのコピーを作成し、描画中のレースコンディションを防ぐ
 	// 各スプライトの状態（visible, image, position等）も描画前にスナップショットを取る
-	type drawItem struct {
-		sprite     *Sprite
-		visible    bool
-		image      *ebiten.Image
-		x, y       float64
-		alpha      float64
-		customDraw func(screen *ebiten.Image, x, y float64, alpha float32)
-	}
-	items := make([]drawItem, 0, len(sm.sorted))
+	items := make([]spriteDrawItem, 0, len(sm.sorted))
 	for _, s := range sm.sorted {
+		// 描画するかどうかにかかわらず、この時点の状態を描画済みとする
+		// 一部のスプライトだけを描画する場合（CaptureLayers）は描画済みとしない
+		if keep == nil {
+			s.ClearDirty()
+		}
+
 		// レースコンディション対策: zPathがnilのスプライトはスキップ
 		// スプライトが完全に初期化される前（zPathが設定される前）に描画されることを防ぐ
 		// これにより、新しく作成されたスプライトがzPathを設定する前に
@@ -502,43 +610,150 @@ func (sm *SpriteManager) Draw(screen *ebiten.Image) {
 		if !s.IsEffectivelyVisible() || s.image == nil {
 			continue
 		}
+		if keep != nil && !keep(s) {
+			continue
+		}
 		x, y := s.AbsolutePosition()
-		items = append(items, drawItem{
+		clip, masks, clipped := s.effectiveMask()
+		items = append(items, spriteDrawItem{
 			sprite:     s,
-			visible:    true,
 			image:      s.image,
-			x:          x,
-			y:          y,
+			hires:      s.hires,
+			x:          x + s.shakeX,
+			y:          y + s.shakeY,
 			alpha:      s.EffectiveAlpha(),
 			customDraw: s.customDraw,
+			clip:       clip,
+			masks:      masks,
+			clipped:    clipped,
+			shadow:     s.shadow,
+			outline:    s.outline,
+			flash:      s.flash,
+			source:     s.source,
+			nineSlice:  s.nineSlice,
+			crossfade:  s.crossfade,
 		})
 	}
-	debugCallback := sm.debugDrawCallback
-	sm.mu.Unlock()
+	return items, sm.debugDrawCallback, sm.quality.drawsEffects()
+}
 
-	for _, item := range items {
+// drawItem はスプライト1つを描画する（マスク、影などの演出を含む）
+func (sm *SpriteManager) drawItem(screen *ebiten.Image, item spriteDrawItem, drawsEffects bool) {
+	drawAt, size := sm.spriteDrawer(item.image, item.customDraw, item.source, item.nineSlice, item.crossfade)
+	draw := func(target *ebiten.Image) {
+		shadow, outline := item.shadow, item.outline
+		if !drawsEffects {
+			shadow, outline = nil, nil
+		}
+		if shadow != nil || outline != nil || item.flash != nil {
+			sm.drawWithEffects(target, size, item.x, item.y, item.alpha, shadow, outline, item.flash, drawAt)
+			return
+		}
+		drawAt(target, item.x, item.y, float32(item.alpha))
+	}
+
+	switch {
+	case !item.clipped:
+		draw(screen)
+	case len(item.masks) == 0:
+		// 矩形マスク: サブイメージに描画してクリップする
+		if clip := item.clip.Intersect(screen.Bounds()); !clip.Empty() {
+			draw(screen.SubImage(clip).(*ebiten.Image))
+		}
+	default:
+		sm.drawMasked(screen, item.clip, item.masks, draw)
+	}
+}
+
+// spriteDrawer はスプライト単体の画像を描画する関数と、描画する大きさを返す
+// 透明色の処理（customDraw）、描画する範囲と9分割の伸縮、クロスフェードを適用する（影などの演出は含まない）
+func (sm *SpriteManager) spriteDrawer(img *ebiten.Image, customDraw func(screen *ebiten.Image, x, y float64, alpha float32), source *image.Rectangle, ns *NineSlice, cf *spriteCrossfade) (func(target *ebiten.Image, x, y float64, alpha float32), image.Point) {
+	drawAt := func(target *ebiten.Image, x, y float64, alpha float32) {
 		// カスタム描画関数が設定されている場合はそれを使用
 		// 透明色処理など、特殊な描画が必要なスプライトで使用
-		if item.customDraw != nil {
-			item.customDraw(screen, item.x, item.y, float32(item.alpha))
-		} else {
-			// 通常描画
-			op := &ebiten.DrawImageOptions{}
-			op.GeoM.Translate(item.x, item.y)
-
-			if item.alpha < 1.0 {
-				op.ColorScale.ScaleAlpha(float32(item.alpha))
-			}
+		if customDraw != nil {
+			customDraw(target, x, y, alpha)
+			return
+		}
+		// 通常描画
+		op := &ebiten.DrawImageOptions{}
+		op.GeoM.Translate(x, y)
 
-			screen.DrawImage(item.image, op)
+		if alpha < 1.0 {
+			op.ColorScale.ScaleAlpha(alpha)
 		}
 
-		// デバッグ描画コールバックを呼び出す（各スプライト描画直後）
-		// これにより、後から描画されるスプライトによってデバッグ情報が隠れる
-		if debugCallback != nil {
-			debugCallback(screen, item.sprite, item.x, item.y)
+		target.DrawImage(img, op)
+	}
+	size := img.Bounds().Size()
+	if source != nil || ns != nil {
+		// 画像の一部・9分割して伸縮した画像を、スプライトの画像として描画する
+		drawWhole, imageSize := drawAt, size
+		drawAt = func(target *ebiten.Image, x, y float64, alpha float32) {
+			sm.drawSliced(target, imageSize, x, y, alpha, source, ns, drawWhole)
 		}
+		size = sourceRegion(size, source).Size()
+		if ns != nil {
+			size = image.Pt(ns.Width, ns.Height)
+		}
+	}
+	if cf != nil {
+		// 差し替える前の絵と合成した画像を、スプライトの画像として描画する
+		drawImage, imageSize := drawAt, size
+		drawAt = func(target *ebiten.Image, x, y float64, alpha float32) {
+			sm.drawCrossfade(target, imageSize, x, y, alpha, cf, drawImage)
+		}
+		size = cf.size(size)
 	}
+	return drawAt, size
+}
+
+// drawMasked はアルファマスクを適用してスプライトを描画する
+// 作業用画像にスプライトを描画し、各マスクのアルファ値を掛け合わせてから、
+// クリップ矩形（すべてのマスクの範囲の共通部分）だけを描画先に重ねる
+func (sm *SpriteManager) drawMasked(screen *ebiten.Image, clip image.Rectangle, masks []placedMask, draw func(target *ebiten.Image)) {
+	clip = clip.Intersect(screen.Bounds())
+	if clip.Empty() {
+		return
+	}
+
+	bounds := screen.Bounds()
+	if sm.maskBuffer == nil || sm.maskBuffer.Bounds().Dx() < bounds.Max.X || sm.maskBuffer.Bounds().Dy() < bounds.Max.Y {
+		if sm.maskBuffer != nil {
+			sm.maskBuffer.Deallocate()
+		}
+		sm.maskBuffer = ebiten.NewImage(bounds.Max.X, bounds.Max.Y)
+	}
+	buffer := sm.maskBuffer.SubImage(clip).(*ebiten.Image)
+	buffer.Clear()
+	draw(buffer)
+
+	for _, m := range masks {
+		op := &ebiten.DrawImageOptions{}
+		op.GeoM.Translate(float64(m.at.X), float64(m.at.Y))
+		op.Blend = ebiten.BlendDestinationIn
+		buffer.DrawImage(m.image, op)
+	}
+
+	op := &ebiten.DrawImageOptions{}
+	op.GeoM.Translate(float64(clip.Min.X), float64(clip.Min.Y))
+	screen.DrawImage(buffer, op)
+}
+
+// IsDirty は前回の Draw 以降にスプライトの追加・削除・並べ替え、
+// またはいずれかのスプライトの状態の変更があったかを返す
+func (sm *SpriteManager) IsDirty() bool {
+	sm.mu.RLock()
+	defer sm.mu.RUnlock()
+	if sm.needSort {
+		return true
+	}
+	for _, s := range sm.sprites {
+		if s.dirty {
+			return true
+		}
+	}
+	return false
 }
 
 // MarkNeedSort はソートが必要であることをマークする
@@ -762,6 +977,44 @@ func (sm *SpriteManager) SendToBack(spriteID int) error {
 	return nil
 }
 
+// RestackSiblings は兄弟スプライトの前後関係を並べ替える
+//
+// spriteIDs は同じ親を持つスプライトのIDを奥から手前の順に並べたものです。
+// それらが現在使用しているLocal_Z_Orderの値を小さい順に割り当て直すため、
+// 対象に含まれない兄弟スプライトが占める位置は変わりません。
+// 子スプライトがある場合、それらのZ_Pathも再帰的に更新されます。
+func (sm *SpriteManager) RestackSiblings(spriteIDs []int) error {
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+
+	sprites := make([]*Sprite, len(spriteIDs))
+	slots := make([]int, len(spriteIDs))
+	for i, id := range spriteIDs {
+		s := sm.sprites[id]
+		if s == nil {
+			return fmt.Errorf("sprite not found: %d", id)
+		}
+		if i > 0 && s.parent != sprites[0].parent {
+			return fmt.Errorf("sprite %d does not share the parent of sprite %d", id, spriteIDs[0])
+		}
+		sprites[i] = s
+		slots[i] = s.zPath.LocalZOrder()
+	}
+	sort.Ints(slots)
+
+	for i, s := range sprites {
+		var parentZPath *ZPath
+		if s.parent != nil {
+			parentZPath = s.parent.zPath
+		}
+		s.SetZPath(NewZPathFromParent(parentZPath, slots[i]))
+		sm.updateChildrenZPaths(s)
+	}
+
+	sm.needSort = true
+	return nil
+}
+
 // ============================================================================
 // Z_Pathの可視化 (Z_Path Visualization)
 // ============================================================================
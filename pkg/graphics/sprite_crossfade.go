@@ -0,0 +1,125 @@
+//go:build !nogpu
+
+package graphics
+
+import (
+	"fmt"
+	"image"
+
+	"github.com/hajimehoshi/ebiten/v2"
+)
+
+// クロスフェード（ChangePicture）
+//
+// キャストの絵を差し替えるときに、古い絵から新しい絵へ徐々に移り変わらせる。
+// スライドショーで2つのキャストを重ねて不透明度を少しずつ変える処理を、スクリプトに書かずに済むようにする。
+// 差し替える前のスプライトの見た目（透明色・描画範囲の処理を含む）を画像として残し、移り変わりの間は
+// 古い絵と新しい絵を進み具合に応じた割合で加算合成する。不透明な部分どうしは明るさが変わらずに移り変わり、
+// 片方だけが不透明な部分は背景との間でフェードする。進み具合はVMがティックごとに設定する。
+
+// spriteCrossfade はスプライトの絵を差し替えるクロスフェードの状態
+type spriteCrossfade struct {
+	from     *ebiten.Image // 差し替える前のスプライトの見た目
+	progress float64       // 進み具合（0.0 は古い絵だけ、1.0 は新しい絵だけ）
+}
+
+// size は新しい絵の大きさが imageSize の場合に、合成した画像の大きさを返す（両方を含む大きさ）
+func (cf *spriteCrossfade) size(imageSize image.Point) image.Point {
+	from := cf.from.Bounds().Size()
+	return image.Pt(max(imageSize.X, from.X), max(imageSize.Y, from.Y))
+}
+
+// setCrossfade はスプライトのクロスフェードを設定する（nil で解除）
+func (s *Sprite) setCrossfade(cf *spriteCrossfade) {
+	s.crossfade = cf
+	s.dirty = true
+}
+
+// drawCrossfade は新しい絵（drawAt で描画する、大きさ size）と差し替える前の絵を
+// 進み具合に応じて合成し、target の (x, y) に描画する
+func (sm *SpriteManager) drawCrossfade(target *ebiten.Image, size image.Point, x, y float64, alpha float32, cf *spriteCrossfade, drawAt func(target *ebiten.Image, x, y float64, alpha float32)) {
+	// 透明色の処理（customDraw）には不透明度を反映しないものがあるため、新しい絵は不透明に描いてから割合を掛ける
+	src := effectImage(&sm.crossfadeSource, size)
+	drawAt(src, 0, 0, 1)
+	buf := effectImage(&sm.crossfadeBuffer, cf.size(size))
+	op := &ebiten.DrawImageOptions{}
+	op.ColorScale.ScaleAlpha(float32(cf.progress))
+	buf.DrawImage(src, op)
+
+	// 乗算済みアルファの色を加算するため、重なった部分の不透明度は (1-t)・a0 + t・a1 となる
+	op = &ebiten.DrawImageOptions{}
+	op.ColorScale.ScaleAlpha(float32(1 - cf.progress))
+	op.Blend = ebiten.BlendLighter
+	buf.DrawImage(cf.from, op)
+
+	op = &ebiten.DrawImageOptions{}
+	op.GeoM.Translate(x, y)
+	if alpha < 1.0 {
+		op.ColorScale.ScaleAlpha(alpha)
+	}
+	target.DrawImage(buf, op)
+}
+
+// snapshotSprite はスプライト単体の今の見た目（透明色・描画範囲・9分割・クロスフェードを適用し、
+// 影などの演出は含まない）を新しい画像に描画して返す。画像がない場合は nil を返す。
+// 描画中のフレームと作業用画像を共有しないよう、作業用画像は一時的な SpriteManager に作成する。
+func snapshotSprite(s *Sprite) *ebiten.Image {
+	if s.image == nil {
+		return nil
+	}
+	scratch := &SpriteManager{}
+	defer scratch.deallocateBuffers()
+
+	drawAt, size := scratch.spriteDrawer(s.image, s.customDraw, s.source, s.nineSlice, s.crossfade)
+	if size.X <= 0 || size.Y <= 0 {
+		return nil
+	}
+	img := ebiten.NewImage(size.X, size.Y)
+	drawAt(img, 0, 0, 1)
+	return img
+}
+
+// deallocateBuffers は作業用画像を解放する
+func (sm *SpriteManager) deallocateBuffers() {
+	for _, buf := range []**ebiten.Image{&sm.maskBuffer, &sm.effectSource, &sm.effectBuffer, &sm.sliceBuffer, &sm.crossfadeSource, &sm.crossfadeBuffer} {
+		if *buf != nil {
+			(*buf).Deallocate()
+			*buf = nil
+		}
+	}
+}
+
+// StartCastCrossfade はキャストの今の見た目を残し、次に絵を差し替えた後もその見た目から
+// クロスフェードで移り変わるようにする。クロスフェードの途中の場合は、合成した今の見た目から始める。
+// 進み具合は SetCastCrossfade で設定する。
+func (gs *GraphicsSystem) StartCastCrossfade(id int) error {
+	return gs.withCastSprite(id, func(s *Sprite) {
+		from := snapshotSprite(s)
+		if from == nil {
+			s.setCrossfade(nil)
+			return
+		}
+		s.setCrossfade(&spriteCrossfade{from: from})
+		gs.log.Debug("Cast crossfade started", "castID", id, "width", from.Bounds().Dx(), "height", from.Bounds().Dy())
+	})
+}
+
+// SetCastCrossfade はキャストのクロスフェードの進み具合（0.0〜1.0）を設定する
+// 1.0 以上の場合はクロスフェードを終え、新しい絵だけを描画する。クロスフェード中でない場合は何もしない。
+func (gs *GraphicsSystem) SetCastCrossfade(id int, progress float64) error {
+	if progress < 0 {
+		return fmt.Errorf("invalid crossfade progress: %g", progress)
+	}
+	return gs.withCastSprite(id, func(s *Sprite) {
+		if s.crossfade == nil {
+			return
+		}
+		if progress >= 1 {
+			// 描画中のフレームが古い絵を参照している場合があるため、画像は解放せずに手放す
+			s.setCrossfade(nil)
+			gs.log.Debug("Cast crossfade finished", "castID", id)
+			return
+		}
+		s.setCrossfade(&spriteCrossfade{from: s.crossfade.from, progress: progress})
+	})
+}
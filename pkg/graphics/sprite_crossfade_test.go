@@ -0,0 +1,100 @@
+//go:build !nogpu
+
+package graphics
+
+import (
+	"errors"
+	"image"
+	"image/color"
+	"testing"
+
+	"github.com/hajimehoshi/ebiten/v2"
+)
+
+func TestSpriteCrossfadeSize(t *testing.T) {
+	cf := &spriteCrossfade{from: ebiten.NewImage(40, 10)}
+	if got := cf.size(image.Pt(20, 30)); got != image.Pt(40, 30) {
+		t.Errorf("size = %v, want (40,30)", got)
+	}
+}
+
+// TestDrawCrossfadeBlendsPictures tests that the crossfade mixes the old and new
+// pictures by the progress without darkening opaque pixels.
+func TestDrawCrossfadeBlendsPictures(t *testing.T) {
+	from := ebiten.NewImage(4, 4)
+	from.Fill(color.RGBA{0xFF, 0, 0, 0xFF})
+	to := ebiten.NewImage(4, 4)
+	to.Fill(color.RGBA{0, 0, 0xFF, 0xFF})
+
+	sm := NewSpriteManager()
+	drawAt, size := sm.spriteDrawer(to, nil, nil, nil, &spriteCrossfade{from: from, progress: 0.25})
+	if size != image.Pt(4, 4) {
+		t.Fatalf("size = %v, want (4,4)", size)
+	}
+	target := ebiten.NewImage(4, 4)
+	drawAt(target, 0, 0, 1)
+
+	got := target.At(1, 1).(color.RGBA)
+	if got.A != 0xFF {
+		t.Errorf("alpha = %d, want opaque", got.A)
+	}
+	if got.R < 0xB8 || got.R > 0xC4 || got.B < 0x3B || got.B > 0x45 {
+		t.Errorf("color = %v, want 3/4 red and 1/4 blue", got)
+	}
+}
+
+// TestGraphicsSystemCastCrossfade tests that ChangePicture's crossfade keeps the old
+// look until it finishes.
+func TestGraphicsSystemCastCrossfade(t *testing.T) {
+	gs := NewGraphicsSystem("")
+	picID, err := gs.CreatePic(64, 64)
+	if err != nil {
+		t.Fatalf("CreatePic failed: %v", err)
+	}
+	nextID, err := gs.CreatePic(32, 32)
+	if err != nil {
+		t.Fatalf("CreatePic failed: %v", err)
+	}
+	if _, err := gs.OpenWin(picID, 0, 0, 64, 64, 0, 0, 0); err != nil {
+		t.Fatalf("OpenWin failed: %v", err)
+	}
+	castID, err := gs.PutCast(picID, picID, 0, 0, 0, 0, 16, 16)
+	if err != nil {
+		t.Fatalf("PutCast failed: %v", err)
+	}
+	sprite := gs.castSprite(castID)
+
+	// クロスフェード中でなければ進み具合の設定は何もしない
+	if err := gs.SetCastCrossfade(castID, 0.5); err != nil || sprite.crossfade != nil {
+		t.Fatalf("SetCastCrossfade without a crossfade = %v, crossfade %v", err, sprite.crossfade)
+	}
+
+	if err := gs.StartCastCrossfade(castID); err != nil {
+		t.Fatalf("StartCastCrossfade failed: %v", err)
+	}
+	if sprite.crossfade == nil || sprite.crossfade.from.Bounds().Size() != image.Pt(16, 16) {
+		t.Fatalf("crossfade = %+v, want the 16x16 look of the cast", sprite.crossfade)
+	}
+	if err := gs.MoveCastWithOptions(castID, WithCastPicID(nextID), WithCastSource(0, 0, 32, 32)); err != nil {
+		t.Fatalf("MoveCastWithOptions failed: %v", err)
+	}
+	if err := gs.SetCastCrossfade(castID, 0.5); err != nil {
+		t.Fatalf("SetCastCrossfade failed: %v", err)
+	}
+	if sprite.crossfade == nil || sprite.crossfade.progress != 0.5 {
+		t.Fatalf("crossfade = %+v, want progress 0.5", sprite.crossfade)
+	}
+	if _, size := gs.spriteManager.spriteDrawer(sprite.image, sprite.customDraw, sprite.source, sprite.nineSlice, sprite.crossfade); size != image.Pt(32, 32) {
+		t.Errorf("drawn size = %v, want (32,32)", size)
+	}
+
+	if err := gs.SetCastCrossfade(castID, 1); err != nil || sprite.crossfade != nil {
+		t.Errorf("finishing the crossfade = %v, crossfade %v", err, sprite.crossfade)
+	}
+	if err := gs.SetCastCrossfade(castID, -1); err == nil {
+		t.Error("a negative progress should be rejected")
+	}
+	if err := gs.StartCastCrossfade(999); !errors.Is(err, ErrCastNotFound) {
+		t.Errorf("StartCastCrossfade of a missing cast = %v, want ErrCastNotFound", err)
+	}
+}
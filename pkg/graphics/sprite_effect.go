@@ -0,0 +1,158 @@
+//go:build !nogpu
+
+package graphics
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/colorm"
+)
+
+// SetShadow はスプライトの影を設定する（nil で解除）
+func (s *Sprite) SetShadow(shadow *Shadow) {
+	s.shadow = shadow
+	s.dirty = true
+}
+
+// Shadow はスプライトの影を返す（設定されていない場合は nil）
+func (s *Sprite) Shadow() *Shadow {
+	return s.shadow
+}
+
+// SetOutline はスプライトの縁取りを設定する（nil で解除）
+func (s *Sprite) SetOutline(outline *Outline) {
+	s.outline = outline
+	s.dirty = true
+}
+
+// Outline はスプライトの縁取りを返す（設定されていない場合は nil）
+func (s *Sprite) Outline() *Outline {
+	return s.outline
+}
+
+// outlineOffsets は太さ width の縁取りを作るためにシルエットをずらして描く位置を返す
+// 半径 width の円の内側の点（原点を除く）で、角が丸い縁取りになる。
+func outlineOffsets(width int) []image.Point {
+	var offsets []image.Point
+	for dy := -width; dy <= width; dy++ {
+		for dx := -width; dx <= width; dx++ {
+			if (dx == 0 && dy == 0) || dx*dx+dy*dy > width*width+width {
+				continue
+			}
+			offsets = append(offsets, image.Pt(dx, dy))
+		}
+	}
+	return offsets
+}
+
+// silhouetteColorM は画像の不透明度を保ったまま色を c に置き換え、不透明度に alpha を掛ける色行列を返す
+func silhouetteColorM(c color.Color, alpha float64) colorm.ColorM {
+	r, g, b, _ := color.NRGBAModel.Convert(c).(color.NRGBA).RGBA()
+	var cm colorm.ColorM
+	cm.Scale(0, 0, 0, alpha)
+	cm.Translate(float64(r)/0xFFFF, float64(g)/0xFFFF, float64(b)/0xFFFF, 0)
+	return cm
+}
+
+// effectImage は作業用画像 buf から左上 (0, 0)、大きさ size の領域を返す（足りない場合は作り直す）
+func effectImage(buf **ebiten.Image, size image.Point) *ebiten.Image {
+	if *buf == nil || (*buf).Bounds().Dx() < size.X || (*buf).Bounds().Dy() < size.Y {
+		if *buf != nil {
+			(*buf).Deallocate()
+		}
+		*buf = ebiten.NewImage(max(size.X, 1), max(size.Y, 1))
+	}
+	img := (*buf).SubImage(image.Rectangle{Max: size}).(*ebiten.Image)
+	img.Clear()
+	return img
+}
+
+// drawWithEffects は影と縁取り、フラッシュを付けてスプライトを描画する
+// drawAt でスプライト単体を作業用画像に描き、縁取りとスプライト（フラッシュの色を重ねたもの）を合成した画像をその都度作成してから、
+// 影（合成した画像のシルエット）、合成した画像の順に target へ描画する。
+// 縁取りは不透明に合成してからスプライトの不透明度を掛けるため、半透明のスプライトでも縁取りの重なりが濃くならない。
+func (sm *SpriteManager) drawWithEffects(target *ebiten.Image, size image.Point, x, y, alpha float64, shadow *Shadow, outline *Outline, flash *spriteFlash, drawAt func(target *ebiten.Image, x, y float64, alpha float32)) {
+	src := effectImage(&sm.effectSource, size)
+	drawAt(src, 0, 0, 1)
+
+	pad := 0
+	if outline != nil {
+		pad = outline.Width
+	}
+	baked := effectImage(&sm.effectBuffer, size.Add(image.Pt(pad*2, pad*2)))
+	if outline != nil {
+		cm := silhouetteColorM(outline.Color, 1)
+		for _, off := range outlineOffsets(outline.Width) {
+			op := &colorm.DrawImageOptions{}
+			op.GeoM.Translate(float64(pad+off.X), float64(pad+off.Y))
+			colorm.DrawImage(baked, src, cm, op)
+		}
+	}
+	op := &ebiten.DrawImageOptions{}
+	op.GeoM.Translate(float64(pad), float64(pad))
+	baked.DrawImage(src, op)
+	if flash != nil {
+		fop := &colorm.DrawImageOptions{}
+		fop.GeoM.Translate(float64(pad), float64(pad))
+		colorm.DrawImage(baked, src, silhouetteColorM(flash.color, flash.amount), fop)
+	}
+
+	x, y = x-float64(pad), y-float64(pad)
+	if shadow != nil {
+		op := &colorm.DrawImageOptions{}
+		op.GeoM.Translate(x+float64(shadow.DX), y+float64(shadow.DY))
+		colorm.DrawImage(target, baked, silhouetteColorM(shadow.Color, shadow.Alpha*alpha), op)
+	}
+	bop := &ebiten.DrawImageOptions{}
+	bop.GeoM.Translate(x, y)
+	if alpha < 1.0 {
+		bop.ColorScale.ScaleAlpha(float32(alpha))
+	}
+	target.DrawImage(baked, bop)
+}
+
+// SetCastShadow はキャストに影を設定する（nil で解除）
+// 写真の上の白い文字など、背景によって見えにくいキャストを読みやすくするために使う。
+func (gs *GraphicsSystem) SetCastShadow(id int, shadow *Shadow) error {
+	if err := checkShadow(shadow); err != nil {
+		return err
+	}
+	return gs.withCastSprite(id, func(s *Sprite) {
+		s.SetShadow(copyShadow(shadow))
+		gs.log.Debug("Cast shadow set", "castID", id, "shadow", shadow)
+	})
+}
+
+// SetCastOutline はキャストに縁取りを設定する（nil で解除）
+func (gs *GraphicsSystem) SetCastOutline(id int, outline *Outline) error {
+	if err := checkOutline(outline); err != nil {
+		return err
+	}
+	return gs.withCastSprite(id, func(s *Sprite) {
+		s.SetOutline(copyOutline(outline))
+		gs.log.Debug("Cast outline set", "castID", id, "outline", outline)
+	})
+}
+
+// withCastSprite はキャストのスプライトに fn を適用する
+func (gs *GraphicsSystem) withCastSprite(id int, fn func(s *Sprite)) error {
+	gs.mu.Lock()
+	defer gs.mu.Unlock()
+	gs.invalidate()
+
+	if _, err := gs.casts.GetCast(id); err != nil {
+		return err
+	}
+	if gs.castSpriteManager == nil {
+		return nil
+	}
+	cs := gs.castSpriteManager.GetCastSprite(id)
+	if cs == nil {
+		return fmt.Errorf("%w: %d", ErrCastNotFound, id)
+	}
+	fn(cs.GetSprite())
+	return nil
+}
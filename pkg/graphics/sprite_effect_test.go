@@ -0,0 +1,133 @@
+//go:build !nogpu
+
+package graphics
+
+import (
+	"errors"
+	"image"
+	"image/color"
+	"testing"
+)
+
+func TestOutlineOffsets(t *testing.T) {
+	offsets := outlineOffsets(1)
+	if len(offsets) != 8 {
+		t.Fatalf("width 1: got %d offsets, want 8 (%v)", len(offsets), offsets)
+	}
+
+	offsets = outlineOffsets(3)
+	seen := make(map[image.Point]bool)
+	for _, p := range offsets {
+		if p == (image.Point{}) {
+			t.Error("expected the origin to be skipped")
+		}
+		if p.X*p.X+p.Y*p.Y > 3*3+3 {
+			t.Errorf("offset %v is outside the outline", p)
+		}
+		seen[p] = true
+	}
+	// 上下左右の端は含み、角は丸める
+	for _, p := range []image.Point{{3, 0}, {-3, 0}, {0, 3}, {0, -3}, {2, 2}} {
+		if !seen[p] {
+			t.Errorf("expected offset %v to be included", p)
+		}
+	}
+	if seen[image.Pt(3, 3)] {
+		t.Error("expected the corner (3, 3) to be excluded")
+	}
+}
+
+func TestSilhouetteColorM(t *testing.T) {
+	cm := silhouetteColorM(color.RGBA{R: 0xFF, G: 0x80, B: 0x00, A: 0xFF}, 0.5)
+	got := color.NRGBAModel.Convert(cm.Apply(color.NRGBA{R: 0x12, G: 0x34, B: 0x56, A: 0xFF})).(color.NRGBA)
+	if got.R != 0xFF || got.G != 0x80 || got.B != 0x00 {
+		t.Errorf("color = %+v, want the silhouette color", got)
+	}
+	if got.A < 0x7F || got.A > 0x80 {
+		t.Errorf("alpha = %d, want half of the source alpha", got.A)
+	}
+
+	// 透明な部分は透明のまま
+	if _, _, _, a := cm.Apply(color.NRGBA{}).RGBA(); a != 0 {
+		t.Errorf("alpha of a transparent pixel = %d, want 0", a)
+	}
+}
+
+func TestSpriteEffects(t *testing.T) {
+	s := NewSprite(1, nil)
+	s.dirty = false
+	s.SetShadow(&Shadow{DX: 2, DY: 2, Color: color.Black, Alpha: 0.5})
+	if !s.IsDirty() || s.Shadow() == nil {
+		t.Error("expected SetShadow to set the shadow and mark the sprite dirty")
+	}
+
+	s.dirty = false
+	s.SetOutline(&Outline{Color: color.White, Width: 1})
+	if !s.IsDirty() || s.Outline() == nil {
+		t.Error("expected SetOutline to set the outline and mark the sprite dirty")
+	}
+
+	s.SetShadow(nil)
+	s.SetOutline(nil)
+	if s.Shadow() != nil || s.Outline() != nil {
+		t.Error("expected nil to remove the effects")
+	}
+}
+
+func TestCheckEffects(t *testing.T) {
+	for _, shadow := range []*Shadow{nil, {Alpha: 0}, {Alpha: 1}} {
+		if err := checkShadow(shadow); err != nil {
+			t.Errorf("checkShadow(%+v) = %v, want nil", shadow, err)
+		}
+	}
+	for _, shadow := range []*Shadow{{Alpha: -0.1}, {Alpha: 1.5}} {
+		if err := checkShadow(shadow); err == nil {
+			t.Errorf("checkShadow(%+v) = nil, want an error", shadow)
+		}
+	}
+	for _, outline := range []*Outline{nil, {Width: 1}, {Width: MaxOutlineWidth}} {
+		if err := checkOutline(outline); err != nil {
+			t.Errorf("checkOutline(%+v) = %v, want nil", outline, err)
+		}
+	}
+	for _, outline := range []*Outline{{Width: 0}, {Width: MaxOutlineWidth + 1}} {
+		if err := checkOutline(outline); err == nil {
+			t.Errorf("checkOutline(%+v) = nil, want an error", outline)
+		}
+	}
+}
+
+func TestHeadlessGraphicsSystem_Effects(t *testing.T) {
+	hgs := NewHeadlessGraphicsSystem(WithLogOperations(false))
+	picID, _ := hgs.CreatePic(64, 64)
+	winID, _ := hgs.OpenWin(picID)
+	castID, _ := hgs.PutCast(winID, picID, 0, 0, 0, 0, 32, 32)
+
+	shadow := &Shadow{DX: 3, DY: 3, Color: color.Black, Alpha: 0.5}
+	if err := hgs.SetCastShadow(castID, shadow); err != nil {
+		t.Fatalf("SetCastShadow failed: %v", err)
+	}
+	shadow.DX = 100 // 呼び出し元の変更は反映されない
+	if cast, _ := hgs.GetCast(castID); cast.Shadow == nil || cast.Shadow.DX != 3 {
+		t.Errorf("cast shadow = %+v, want DX=3", cast.Shadow)
+	}
+	if err := hgs.SetCastOutline(castID, &Outline{Color: color.White, Width: 2}); err != nil {
+		t.Fatalf("SetCastOutline failed: %v", err)
+	}
+	if cast, _ := hgs.GetCast(castID); cast.Outline == nil || cast.Outline.Width != 2 {
+		t.Errorf("cast outline = %+v, want Width=2", cast.Outline)
+	}
+
+	hgs.SetCastShadow(castID, nil)
+	hgs.SetCastOutline(castID, nil)
+	if cast, _ := hgs.GetCast(castID); cast.Shadow != nil || cast.Outline != nil {
+		t.Errorf("expected the effects to be removed, got %+v %+v", cast.Shadow, cast.Outline)
+	}
+
+	if err := hgs.SetCastShadow(999, shadow); !errors.Is(err, ErrCastNotFound) {
+		t.Errorf("expected ErrCastNotFound, got %v", err)
+	}
+	if err := hgs.SetCastOutline(castID, &Outline{Width: 0}); err == nil {
+		t.Error("expected an error for an outline width of 0")
+	}
+}
@@ -1,3 +1,5 @@
+//go:build !nogpu
+
 // Package graphics provides integration tests for the sprite system full integration.
 // タスク 5.4: スプライトシステム完全統合の統合テスト
 //
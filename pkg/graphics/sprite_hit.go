@@ -0,0 +1,167 @@
+//go:build !nogpu
+
+package graphics
+
+import (
+	"fmt"
+	"image/color"
+	"math"
+	"time"
+)
+
+// ヒット演出（Shake/Flash）
+//
+// 攻撃が当たったキャストを揺らしたり、一瞬白く光らせたりする短い演出。
+// スクリプトで MoveCast を繰り返すと多くの OpCode を消費するため、グラフィックスシステムが
+// 画面フェードと同じように毎フレーム進める。
+
+// shakeStepX, shakeStepY は1フレームごとに進める揺れの位相（ラジアン）
+// 横と縦で周期をずらし、同じ方向に往復するだけにならないようにする
+const (
+	shakeStepX = 2.0
+	shakeStepY = 2.9
+)
+
+// spriteFlash はスプライトに重ねるフラッシュの色
+type spriteFlash struct {
+	color  color.Color
+	amount float64 // 色の濃さ（0.0〜1.0）
+}
+
+// castShake はキャストを揺らす演出（Shake）の状態
+// 揺れ幅は時間とともに小さくなり、最後は元の位置に戻る
+type castShake struct {
+	amplitude float64 // 最初の揺れ幅（ピクセル）
+	frames    int     // 演出にかけるフレーム数
+	frame     int     // 経過フレーム数
+}
+
+// offset は現在のフレームでの描画位置のずれを返す
+func (s *castShake) offset() (float64, float64) {
+	decay := s.amplitude * (1 - float64(s.frame)/float64(s.frames))
+	phase := float64(s.frame)
+	return math.Round(decay * math.Sin(phase*shakeStepX)), math.Round(decay * math.Sin(phase*shakeStepY))
+}
+
+// castFlash はキャストを光らせる演出（Flash）の状態
+// 最初は色で塗りつぶし、時間とともに元の絵に戻る
+type castFlash struct {
+	color  color.Color
+	frames int // 演出にかけるフレーム数
+	frame  int // 経過フレーム数
+}
+
+// current は現在のフレームで重ねる色を返す
+func (f *castFlash) current() *spriteFlash {
+	return &spriteFlash{color: f.color, amount: 1 - float64(f.frame)/float64(f.frames)}
+}
+
+// SetShakeOffset はスプライトを描画する位置のずれを設定する（Shake）
+func (s *Sprite) SetShakeOffset(dx, dy float64) {
+	s.shakeX, s.shakeY = dx, dy
+	s.dirty = true
+}
+
+// ShakeOffset はスプライトを描画する位置のずれを返す
+func (s *Sprite) ShakeOffset() (float64, float64) {
+	return s.shakeX, s.shakeY
+}
+
+// setFlash はスプライトに重ねるフラッシュの色を設定する（nil で解除）
+func (s *Sprite) setFlash(flash *spriteFlash) {
+	s.flash = flash
+	s.dirty = true
+}
+
+// ShakeCast はキャストを amplitude ピクセルの幅で duration の間揺らす
+// 実行中の揺れは置き換えられる。amplitude か duration が 0 の場合は揺れを止めて元の位置に戻す。
+func (gs *GraphicsSystem) ShakeCast(id, amplitude int, duration time.Duration) error {
+	if amplitude < 0 {
+		return fmt.Errorf("invalid shake amplitude: %d", amplitude)
+	}
+	return gs.withCastSprite(id, func(s *Sprite) {
+		if amplitude == 0 || duration <= 0 {
+			delete(gs.shakes, id)
+			s.SetShakeOffset(0, 0)
+			return
+		}
+		if gs.shakes == nil {
+			gs.shakes = make(map[int]*castShake)
+		}
+		gs.shakes[id] = &castShake{amplitude: float64(amplitude), frames: durationFrames(duration)}
+		gs.log.Debug("Cast shake started", "castID", id, "amplitude", amplitude, "frames", gs.shakes[id].frames)
+	})
+}
+
+// FlashCast はキャストを色 c で光らせ、duration をかけて元の絵に戻す
+// 実行中のフラッシュは置き換えられる。duration が 0 の場合はフラッシュを止める。
+func (gs *GraphicsSystem) FlashCast(id int, c color.Color, duration time.Duration) error {
+	return gs.withCastSprite(id, func(s *Sprite) {
+		if duration <= 0 {
+			delete(gs.flashes, id)
+			s.setFlash(nil)
+			return
+		}
+		if gs.flashes == nil {
+			gs.flashes = make(map[int]*castFlash)
+		}
+		f := &castFlash{color: c, frames: durationFrames(duration)}
+		gs.flashes[id] = f
+		s.setFlash(f.current())
+		gs.log.Debug("Cast flash started", "castID", id, "color", fmt.Sprintf("0x%06X", ColorToInt(c)), "frames", f.frames)
+	})
+}
+
+// hasHitEffects は実行中のヒット演出があるかを返す
+// 呼び出し元は gs.mu のロックを保持していること
+func (gs *GraphicsSystem) hasHitEffects() bool {
+	return len(gs.shakes) > 0 || len(gs.flashes) > 0
+}
+
+// updateHitEffects はヒット演出を1フレーム進める
+// 終わった演出と、削除されたキャストの演出は取り除く
+// 呼び出し元は gs.mu のロックを保持していること
+func (gs *GraphicsSystem) updateHitEffects() {
+	for id, shake := range gs.shakes {
+		s := gs.castSprite(id)
+		if s == nil {
+			delete(gs.shakes, id)
+			continue
+		}
+		shake.frame++
+		if shake.frame >= shake.frames {
+			delete(gs.shakes, id)
+			s.SetShakeOffset(0, 0)
+			continue
+		}
+		s.SetShakeOffset(shake.offset())
+	}
+
+	for id, flash := range gs.flashes {
+		s := gs.castSprite(id)
+		if s == nil {
+			delete(gs.flashes, id)
+			continue
+		}
+		flash.frame++
+		if flash.frame >= flash.frames {
+			delete(gs.flashes, id)
+			s.setFlash(nil)
+			continue
+		}
+		s.setFlash(flash.current())
+	}
+}
+
+// castSprite はキャストのスプライトを返す（キャストがない場合は nil）
+// 呼び出し元は gs.mu のロックを保持していること
+func (gs *GraphicsSystem) castSprite(id int) *Sprite {
+	if gs.castSpriteManager == nil {
+		return nil
+	}
+	cs := gs.castSpriteManager.GetCastSprite(id)
+	if cs == nil {
+		return nil
+	}
+	return cs.GetSprite()
+}
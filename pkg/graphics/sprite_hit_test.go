@@ -0,0 +1,151 @@
+//go:build !nogpu
+
+package graphics
+
+import (
+	"errors"
+	"image/color"
+	"testing"
+	"time"
+)
+
+func TestCastShakeOffset(t *testing.T) {
+	s := &castShake{amplitude: 8, frames: 6}
+	if dx, dy := s.offset(); dx != 0 || dy != 0 {
+		t.Errorf("offset at frame 0 = (%v, %v), want (0, 0)", dx, dy)
+	}
+
+	moved := false
+	for s.frame = 1; s.frame < s.frames; s.frame++ {
+		dx, dy := s.offset()
+		limit := s.amplitude * (1 - float64(s.frame)/float64(s.frames))
+		if dx < -limit-0.5 || dx > limit+0.5 || dy < -limit-0.5 || dy > limit+0.5 {
+			t.Errorf("offset at frame %d = (%v, %v), want within %v", s.frame, dx, dy, limit)
+		}
+		if dx != 0 || dy != 0 {
+			moved = true
+		}
+	}
+	if !moved {
+		t.Error("expected the shake to move the cast")
+	}
+
+	// 同じ状態からは同じ揺れになる（リプレイやネットワーク同期で結果が変わらない）
+	a := &castShake{amplitude: 5, frames: 10, frame: 3}
+	b := &castShake{amplitude: 5, frames: 10, frame: 3}
+	ax, ay := a.offset()
+	bx, by := b.offset()
+	if ax != bx || ay != by {
+		t.Errorf("offsets differ: (%v, %v) and (%v, %v)", ax, ay, bx, by)
+	}
+}
+
+func TestCastFlashCurrent(t *testing.T) {
+	f := &castFlash{color: color.White, frames: 4}
+	for frame, want := range []float64{1, 0.75, 0.5, 0.25} {
+		f.frame = frame
+		if got := f.current(); got.amount != want || got.color != color.White {
+			t.Errorf("flash at frame %d = %+v, want amount %v", frame, got, want)
+		}
+	}
+}
+
+// TestGraphicsSystemHitEffects tests that Shake and Flash run through Update and
+// restore the cast when they finish.
+func TestGraphicsSystemHitEffects(t *testing.T) {
+	gs := NewGraphicsSystem("")
+	picID, err := gs.CreatePic(64, 64)
+	if err != nil {
+		t.Fatalf("CreatePic failed: %v", err)
+	}
+	if _, err := gs.OpenWin(picID, 0, 0, 64, 64, 0, 0, 0); err != nil {
+		t.Fatalf("OpenWin failed: %v", err)
+	}
+	castID, err := gs.PutCast(picID, picID, 10, 20, 0, 0, 16, 16)
+	if err != nil {
+		t.Fatalf("PutCast failed: %v", err)
+	}
+	sprite := gs.castSprite(castID)
+	if sprite == nil {
+		t.Fatal("cast sprite not found")
+	}
+
+	// 100ms は 60TPS で 6 フレーム
+	if err := gs.ShakeCast(castID, 4, 100*time.Millisecond); err != nil {
+		t.Fatalf("ShakeCast failed: %v", err)
+	}
+	if err := gs.FlashCast(castID, color.White, 100*time.Millisecond); err != nil {
+		t.Fatalf("FlashCast failed: %v", err)
+	}
+	if sprite.flash == nil || sprite.flash.amount != 1 {
+		t.Errorf("flash = %+v, want full strength right after FlashCast", sprite.flash)
+	}
+
+	moved := false
+	for range 5 {
+		gs.Update()
+		if dx, dy := sprite.ShakeOffset(); dx != 0 || dy != 0 {
+			moved = true
+		}
+	}
+	if !moved {
+		t.Error("expected the cast to shake")
+	}
+	if !gs.hasHitEffects() {
+		t.Error("expected the effects to run for 6 frames")
+	}
+
+	gs.Update()
+	if gs.hasHitEffects() {
+		t.Error("expected the effects to finish after 6 frames")
+	}
+	if dx, dy := sprite.ShakeOffset(); dx != 0 || dy != 0 || sprite.flash != nil {
+		t.Errorf("cast was not restored: offset (%v, %v), flash %+v", dx, dy, sprite.flash)
+	}
+	if x, y := sprite.Position(); x != 10 || y != 20 {
+		t.Errorf("position = (%v, %v), want the cast position (10, 20)", x, y)
+	}
+
+	// 0 を指定すると止まる
+	gs.ShakeCast(castID, 4, time.Second)
+	gs.Update()
+	gs.ShakeCast(castID, 0, 0)
+	if dx, dy := sprite.ShakeOffset(); dx != 0 || dy != 0 || gs.hasHitEffects() {
+		t.Errorf("expected ShakeCast(0, 0) to stop the shake, got offset (%v, %v)", dx, dy)
+	}
+
+	// 削除されたキャストの演出は取り除かれる
+	gs.FlashCast(castID, color.White, time.Second)
+	gs.DelCast(castID)
+	gs.Update()
+	if gs.hasHitEffects() {
+		t.Error("expected the effects of a deleted cast to be removed")
+	}
+
+	if err := gs.ShakeCast(999, 4, time.Second); !errors.Is(err, ErrCastNotFound) {
+		t.Errorf("expected ErrCastNotFound, got %v", err)
+	}
+	if err := gs.ShakeCast(castID, -1, time.Second); err == nil {
+		t.Error("expected an error for a negative amplitude")
+	}
+}
+
+func TestHeadlessGraphicsSystem_HitEffects(t *testing.T) {
+	hgs := NewHeadlessGraphicsSystem(WithLogOperations(false))
+	picID, _ := hgs.CreatePic(64, 64)
+	winID, _ := hgs.OpenWin(picID)
+	castID, _ := hgs.PutCast(winID, picID, 0, 0, 0, 0, 32, 32)
+
+	if err := hgs.ShakeCast(castID, 4, time.Second); err != nil {
+		t.Errorf("ShakeCast failed: %v", err)
+	}
+	if err := hgs.FlashCast(castID, color.White, time.Second); err != nil {
+		t.Errorf("FlashCast failed: %v", err)
+	}
+	if err := hgs.FlashCast(999, color.White, time.Second); !errors.Is(err, ErrCastNotFound) {
+		t.Errorf("expected ErrCastNotFound, got %v", err)
+	}
+	if err := hgs.ShakeCast(castID, -1, time.Second); err == nil {
+		t.Error("expected an error for a negative amplitude")
+	}
+}
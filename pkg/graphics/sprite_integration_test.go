@@ -1,3 +1,5 @@
+//go:build !nogpu
+
 // Package graphics provides integration tests for the sprite system.
 // These tests verify that the sprite system components work together correctly.
 // タスク 14.4: 描画システムの統合テスト
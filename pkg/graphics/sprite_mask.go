@@ -0,0 +1,160 @@
+//go:build !nogpu
+
+package graphics
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+
+	"github.com/hajimehoshi/ebiten/v2"
+)
+
+// SpriteMask はスプライトに設定されたマスク
+// Rect はスプライトの原点からの相対座標で、この外側は描画されない。
+// Image が nil でない場合、Rect の位置に配置したアルファ値でさらに描画を絞る（Rect は Image と同じ大きさ）。
+type SpriteMask struct {
+	Rect  image.Rectangle
+	Image *ebiten.Image
+}
+
+// placedMask は描画先の座標に配置したアルファマスク
+type placedMask struct {
+	image *ebiten.Image
+	at    image.Point
+}
+
+// SetMask はスプライトのマスクを設定する（nil で解除）
+// マスクは子スプライトにも適用される
+func (s *Sprite) SetMask(mask *SpriteMask) {
+	s.mask = mask
+	s.dirty = true
+}
+
+// Mask はスプライトのマスクを返す（設定されていない場合は nil）
+func (s *Sprite) Mask() *SpriteMask {
+	return s.mask
+}
+
+// effectiveMask はスプライト自身と親のマスクを合成した描画先の座標でのクリップ矩形と
+// アルファマスクを返す。マスクがない場合 clipped は false となる。
+func (s *Sprite) effectiveMask() (clip image.Rectangle, masks []placedMask, clipped bool) {
+	for p := s; p != nil; p = p.parent {
+		if p.mask == nil {
+			continue
+		}
+		px, py := p.AbsolutePosition()
+		r := p.mask.Rect.Add(image.Pt(int(px), int(py)))
+		if clipped {
+			clip = clip.Intersect(r)
+		} else {
+			clip = r
+			clipped = true
+		}
+		if p.mask.Image != nil {
+			masks = append(masks, placedMask{image: p.mask.Image, at: r.Min})
+		}
+	}
+	return clip, masks, clipped
+}
+
+// maskImageFromPicture はピクチャーの明るさを不透明度とするアルファマスク画像を作成する
+// 白は不透明（表示）、黒は透明（非表示）となる。ピクチャー自体の透明度も掛け合わせる。
+func maskImageFromPicture(src *ebiten.Image) *ebiten.Image {
+	bounds := src.Bounds()
+	mask := image.NewRGBA(image.Rect(0, 0, bounds.Dx(), bounds.Dy()))
+	for y := 0; y < bounds.Dy(); y++ {
+		for x := 0; x < bounds.Dx(); x++ {
+			r, g, b, a := src.At(bounds.Min.X+x, bounds.Min.Y+y).RGBA()
+			// ITU-R BT.601 の輝度（16bit）
+			luma := (299*r + 587*g + 114*b) / 1000
+			alpha := uint8((luma * a / 0xFFFF) >> 8)
+			mask.SetRGBA(x, y, color.RGBA{alpha, alpha, alpha, alpha})
+		}
+	}
+	return ebiten.NewImageFromImage(mask)
+}
+
+// spriteMask は Mask からスプライトのマスクを作成する
+// offset はマスクの座標の原点（スプライトの原点からの相対座標）
+// gs.mu を保持して呼び出すこと
+func (gs *GraphicsSystem) spriteMask(mask *Mask, offset image.Point) (*SpriteMask, error) {
+	if !mask.UsePic {
+		if mask.Width < 0 || mask.Height < 0 {
+			return nil, fmt.Errorf("invalid mask size: %dx%d", mask.Width, mask.Height)
+		}
+		rect := image.Rect(mask.X, mask.Y, mask.X+mask.Width, mask.Y+mask.Height).Add(offset)
+		return &SpriteMask{Rect: rect}, nil
+	}
+
+	pic, err := gs.pictures.GetPicWithoutLock(mask.PicID)
+	if err != nil {
+		return nil, err
+	}
+	rect := image.Rect(mask.X, mask.Y, mask.X+pic.Width, mask.Y+pic.Height).Add(offset)
+	return &SpriteMask{Rect: rect, Image: maskImageFromPicture(pic.Image)}, nil
+}
+
+// SetCastMask はキャストにマスクを設定する（nil で解除）
+// 座標はキャストの左上からの相対座標。スポットライトや、徐々に現れる演出に使う。
+func (gs *GraphicsSystem) SetCastMask(id int, mask *Mask) error {
+	gs.mu.Lock()
+	defer gs.mu.Unlock()
+	gs.invalidate()
+
+	if _, err := gs.casts.GetCast(id); err != nil {
+		return err
+	}
+	if gs.castSpriteManager == nil {
+		return nil
+	}
+	cs := gs.castSpriteManager.GetCastSprite(id)
+	if cs == nil {
+		return fmt.Errorf("%w: %d", ErrCastNotFound, id)
+	}
+	if mask == nil {
+		cs.GetSprite().SetMask(nil)
+		gs.log.Debug("Cast mask removed", "castID", id)
+		return nil
+	}
+
+	sm, err := gs.spriteMask(mask, image.Point{})
+	if err != nil {
+		return err
+	}
+	cs.GetSprite().SetMask(sm)
+	gs.log.Debug("Cast mask set", "castID", id, "rect", sm.Rect, "usePic", mask.UsePic)
+	return nil
+}
+
+// SetWinMask はウィンドウにマスクを設定する（nil で解除）
+// 座標はウィンドウのコンテンツ領域の左上からの相対座標。マスクはウィンドウ内のキャストにも適用される。
+func (gs *GraphicsSystem) SetWinMask(id int, mask *Mask) error {
+	gs.mu.Lock()
+	defer gs.mu.Unlock()
+	gs.invalidate()
+
+	if _, err := gs.windows.GetWin(id); err != nil {
+		return err
+	}
+	if gs.windowSpriteManager == nil {
+		return nil
+	}
+	ws := gs.windowSpriteManager.GetWindowSprite(id)
+	if ws == nil {
+		return fmt.Errorf("%w: %d", ErrWindowNotFound, id)
+	}
+	if mask == nil {
+		ws.GetSprite().SetMask(nil)
+		gs.log.Debug("Window mask removed", "winID", id)
+		return nil
+	}
+
+	sm, err := gs.spriteMask(mask, image.Pt(ws.GetContentOffset()))
+	if err != nil {
+		return err
+	}
+	ws.GetSprite().SetMask(sm)
+	gs.log.Debug("Window mask set", "winID", id, "rect", sm.Rect, "usePic", mask.UsePic)
+	return nil
+}
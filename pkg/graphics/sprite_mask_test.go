@@ -0,0 +1,108 @@
+//go:build !nogpu
+
+package graphics
+
+import (
+	"errors"
+	"image"
+	"testing"
+)
+
+func TestSpriteEffectiveMask(t *testing.T) {
+	t.Run("no mask", func(t *testing.T) {
+		s := NewSprite(1, nil)
+		if _, _, clipped := s.effectiveMask(); clipped {
+			t.Error("expected a sprite without masks not to be clipped")
+		}
+	})
+
+	t.Run("own mask is placed at the absolute position", func(t *testing.T) {
+		s := NewSprite(1, nil)
+		s.SetPosition(100, 50)
+		s.SetMask(&SpriteMask{Rect: image.Rect(10, 0, 30, 20)})
+
+		clip, masks, clipped := s.effectiveMask()
+		if !clipped || len(masks) != 0 {
+			t.Fatalf("expected a rectangle mask, got clipped=%v masks=%d", clipped, len(masks))
+		}
+		if want := image.Rect(110, 50, 130, 70); clip != want {
+			t.Errorf("clip = %v, want %v", clip, want)
+		}
+	})
+
+	t.Run("parent mask intersects child mask", func(t *testing.T) {
+		parent := NewSprite(1, nil)
+		parent.SetPosition(100, 100)
+		parent.SetMask(&SpriteMask{Rect: image.Rect(0, 0, 50, 50)})
+		child := NewSprite(2, nil)
+		child.SetParent(parent)
+		child.SetPosition(30, 30)
+		child.SetMask(&SpriteMask{Rect: image.Rect(0, 0, 100, 10)})
+
+		clip, _, clipped := child.effectiveMask()
+		if want := image.Rect(130, 130, 150, 140); !clipped || clip != want {
+			t.Errorf("clip = %v (clipped=%v), want %v", clip, clipped, want)
+		}
+
+		// 親のマスクだけでも子は切り抜かれる
+		child.SetMask(nil)
+		clip, _, clipped = child.effectiveMask()
+		if want := image.Rect(100, 100, 150, 150); !clipped || clip != want {
+			t.Errorf("clip = %v (clipped=%v), want %v", clip, clipped, want)
+		}
+	})
+
+	t.Run("SetMask marks the sprite dirty", func(t *testing.T) {
+		s := NewSprite(1, nil)
+		s.dirty = false
+		s.SetMask(&SpriteMask{})
+		if !s.IsDirty() {
+			t.Error("expected SetMask to mark the sprite dirty")
+		}
+		if s.Mask() == nil {
+			t.Error("expected Mask to return the mask")
+		}
+	})
+}
+
+func TestHeadlessGraphicsSystem_Masks(t *testing.T) {
+	hgs := NewHeadlessGraphicsSystem(WithLogOperations(false))
+	picID, _ := hgs.CreatePic(64, 64)
+	winID, _ := hgs.OpenWin(picID)
+	castID, _ := hgs.PutCast(winID, picID, 0, 0, 0, 0, 32, 32)
+
+	mask := &Mask{X: 4, Y: 4, Width: 8, Height: 8}
+	if err := hgs.SetCastMask(castID, mask); err != nil {
+		t.Fatalf("SetCastMask failed: %v", err)
+	}
+	mask.X = 100 // 呼び出し元の変更は反映されない
+	if cast, _ := hgs.GetCast(castID); cast.Mask == nil || cast.Mask.X != 4 {
+		t.Errorf("cast mask = %+v, want X=4", cast.Mask)
+	}
+	if err := hgs.SetCastMask(castID, nil); err != nil {
+		t.Fatalf("SetCastMask(nil) failed: %v", err)
+	}
+	if cast, _ := hgs.GetCast(castID); cast.Mask != nil {
+		t.Errorf("expected the cast mask to be removed, got %+v", cast.Mask)
+	}
+
+	if err := hgs.SetWinMask(winID, &Mask{PicID: picID, UsePic: true}); err != nil {
+		t.Fatalf("SetWinMask failed: %v", err)
+	}
+	if wins := hgs.GetWindows(); len(wins) != 1 || wins[0].Mask == nil || !wins[0].Mask.UsePic {
+		t.Errorf("expected the window to have a picture mask, got %+v", wins)
+	}
+
+	if err := hgs.SetCastMask(999, mask); !errors.Is(err, ErrCastNotFound) {
+		t.Errorf("expected ErrCastNotFound, got %v", err)
+	}
+	if err := hgs.SetWinMask(999, mask); !errors.Is(err, ErrWindowNotFound) {
+		t.Errorf("expected ErrWindowNotFound, got %v", err)
+	}
+	if err := hgs.SetWinMask(winID, &Mask{PicID: 999, UsePic: true}); !errors.Is(err, ErrPictureNotFound) {
+		t.Errorf("expected ErrPictureNotFound, got %v", err)
+	}
+	if err := hgs.SetCastMask(castID, &Mask{Width: -1, Height: 8}); err == nil {
+		t.Error("expected an error for a negative mask size")
+	}
+}
@@ -0,0 +1,331 @@
+//go:build !nogpu
+
+// sprite_pool.go はパーティクル演出（雪・花びら・紙吹雪など）用のスプライトプールを提供する
+package graphics
+
+import (
+	"fmt"
+	"image/color"
+	"math"
+	"sync"
+
+	"github.com/hajimehoshi/ebiten/v2"
+)
+
+// verticesPerParticle は粒子1つあたりの頂点数とインデックス数（矩形 = 三角形2つ）
+const (
+	verticesPerParticle = 4
+	indicesPerParticle  = 6
+)
+
+// SpritePool は同じ画像を使う多数の小さなスプライト（粒子）をまとめて管理する
+// 粒子は個別のスプライトとして登録せず、プール全体を1つのスプライトとして
+// DrawTriangles の1回の呼び出しで描画する。配置先ピクチャーの子スプライトとなるため、
+// ウィンドウの位置や重なり順はキャストと同じように扱われる。
+type SpritePool struct {
+	id       int
+	picID    int // 配置先ピクチャーID
+	image    *ebiten.Image
+	sprite   *Sprite
+	set      *particleSet
+	vertices []ebiten.Vertex
+	indices  []uint16
+	stride   int // 何個に1個の粒子を描画するか（--adaptive-quality で負荷が高い場合に間引く）
+	mu       sync.Mutex
+}
+
+// ID はスプライトプールのIDを返す
+func (sp *SpritePool) ID() int {
+	return sp.id
+}
+
+// Size は粒子の数を返す
+func (sp *SpritePool) Size() int {
+	return len(sp.set.particles)
+}
+
+// GetSprite は基盤となるスプライトを返す
+func (sp *SpritePool) GetSprite() *Sprite {
+	return sp.sprite
+}
+
+// SetParticle は粒子の位置と可視性を設定する
+func (sp *SpritePool) SetParticle(index int, x, y float64, visible bool) error {
+	sp.mu.Lock()
+	defer sp.mu.Unlock()
+	return sp.set.set(index, x, y, visible)
+}
+
+// SetVelocity は粒子の速度を設定する（index が負の場合はすべての粒子）
+func (sp *SpritePool) SetVelocity(index int, vx, vy float64) error {
+	sp.mu.Lock()
+	defer sp.mu.Unlock()
+	return sp.set.setVelocity(index, vx, vy)
+}
+
+// Step はすべての粒子を速度の分だけ進める
+func (sp *SpritePool) Step() {
+	sp.mu.Lock()
+	defer sp.mu.Unlock()
+	sp.set.step()
+}
+
+// draw は可視の粒子をまとめて描画する（Sprite のカスタム描画関数）
+// x, y はプールのスプライトの絶対位置（配置先ピクチャーの左上）
+func (sp *SpritePool) draw(screen *ebiten.Image, x, y float64, alpha float32) {
+	sp.mu.Lock()
+	defer sp.mu.Unlock()
+
+	w, h := float32(sp.set.itemW), float32(sp.set.itemH)
+	sp.vertices = sp.vertices[:0]
+	sp.indices = sp.indices[:0]
+	stride := max(sp.stride, 1)
+	for i, p := range sp.set.particles {
+		if !p.visible || i%stride != 0 {
+			continue
+		}
+		// 整数座標に揃えてドット絵がにじまないようにする
+		dx := float32(math.Floor(x + p.x))
+		dy := float32(math.Floor(y + p.y))
+		base := uint16(len(sp.vertices))
+		sp.vertices = append(sp.vertices,
+			ebiten.Vertex{DstX: dx, DstY: dy, SrcX: 0, SrcY: 0, ColorR: 1, ColorG: 1, ColorB: 1, ColorA: alpha},
+			ebiten.Vertex{DstX: dx + w, DstY: dy, SrcX: w, SrcY: 0, ColorR: 1, ColorG: 1, ColorB: 1, ColorA: alpha},
+			ebiten.Vertex{DstX: dx, DstY: dy + h, SrcX: 0, SrcY: h, ColorR: 1, ColorG: 1, ColorB: 1, ColorA: alpha},
+			ebiten.Vertex{DstX: dx + w, DstY: dy + h, SrcX: w, SrcY: h, ColorR: 1, ColorG: 1, ColorB: 1, ColorA: alpha},
+		)
+		sp.indices = append(sp.indices, base, base+1, base+2, base+1, base+3, base+2)
+	}
+	if len(sp.indices) == 0 {
+		return
+	}
+	screen.DrawTriangles(sp.vertices, sp.indices, sp.image, nil)
+}
+
+// SpritePoolManager はスプライトプールを管理する
+type SpritePoolManager struct {
+	pools         map[int]*SpritePool
+	spriteManager *SpriteManager
+	nextID        int
+	stride        int // 新しいプールの粒子を何個に1個描画するか
+	mu            sync.RWMutex
+}
+
+// NewSpritePoolManager は新しいSpritePoolManagerを作成する
+func NewSpritePoolManager(sm *SpriteManager) *SpritePoolManager {
+	return &SpritePoolManager{
+		pools:         make(map[int]*SpritePool),
+		spriteManager: sm,
+		nextID:        1,
+	}
+}
+
+// CreatePool は img を使う n 個の粒子からなるスプライトプールを作成する
+// 粒子は (areaW, areaH) の範囲を移動する。parent が nil の場合は描画されない。
+func (spm *SpritePoolManager) CreatePool(picID int, img *ebiten.Image, n, areaW, areaH int, parent *Sprite) (*SpritePool, error) {
+	if img == nil {
+		return nil, fmt.Errorf("sprite pool image is nil")
+	}
+	bounds := img.Bounds()
+	set, err := newParticleSet(n, bounds.Dx(), bounds.Dy(), areaW, areaH)
+	if err != nil {
+		return nil, err
+	}
+
+	spm.mu.Lock()
+	defer spm.mu.Unlock()
+
+	sp := &SpritePool{
+		id:       spm.nextID,
+		picID:    picID,
+		image:    img,
+		set:      set,
+		vertices: make([]ebiten.Vertex, 0, n*verticesPerParticle),
+		indices:  make([]uint16, 0, n*indicesPerParticle),
+		stride:   spm.stride,
+	}
+	spm.nextID++
+
+	// キャストと同様に非表示で作成し、Z_Pathを設定してから表示する
+	sp.sprite = spm.spriteManager.CreateSpriteHidden(img)
+	sp.sprite.SetCustomDraw(sp.draw)
+	if parent != nil {
+		parent.AddChild(sp.sprite)
+		if parent.GetZPath() != nil {
+			localZOrder := spm.spriteManager.GetZOrderCounter().GetNext(parent.ID())
+			sp.sprite.SetZPath(NewZPathFromParent(parent.GetZPath(), localZOrder))
+			sp.sprite.SetVisible(true)
+			spm.spriteManager.MarkNeedSort()
+		}
+	}
+
+	spm.pools[sp.id] = sp
+	return sp, nil
+}
+
+// GetPool はIDからスプライトプールを取得する
+func (spm *SpritePoolManager) GetPool(id int) (*SpritePool, error) {
+	spm.mu.RLock()
+	defer spm.mu.RUnlock()
+	sp, ok := spm.pools[id]
+	if !ok {
+		return nil, fmt.Errorf("%w: %d", ErrSpritePoolNotFound, id)
+	}
+	return sp, nil
+}
+
+// RemovePool はスプライトプールを削除する
+func (spm *SpritePoolManager) RemovePool(id int) error {
+	spm.mu.Lock()
+	defer spm.mu.Unlock()
+	sp, ok := spm.pools[id]
+	if !ok {
+		return fmt.Errorf("%w: %d", ErrSpritePoolNotFound, id)
+	}
+	spm.spriteManager.RemoveSprite(sp.sprite.ID())
+	delete(spm.pools, id)
+	return nil
+}
+
+// RemovePoolsByPicID は配置先ピクチャーに属するスプライトプールをすべて削除する
+func (spm *SpritePoolManager) RemovePoolsByPicID(picID int) {
+	spm.mu.Lock()
+	defer spm.mu.Unlock()
+	for id, sp := range spm.pools {
+		if sp.picID == picID {
+			spm.spriteManager.RemoveSprite(sp.sprite.ID())
+			delete(spm.pools, id)
+		}
+	}
+}
+
+// Clear はすべてのスプライトプールを削除する
+func (spm *SpritePoolManager) Clear() {
+	spm.mu.Lock()
+	defer spm.mu.Unlock()
+	for _, sp := range spm.pools {
+		spm.spriteManager.RemoveSprite(sp.sprite.ID())
+	}
+	spm.pools = make(map[int]*SpritePool)
+}
+
+// Count は登録されているスプライトプールの数を返す
+func (spm *SpritePoolManager) Count() int {
+	spm.mu.RLock()
+	defer spm.mu.RUnlock()
+	return len(spm.pools)
+}
+
+// CreateSpritePool は srcPicID の画像を使う n 個の粒子からなるスプライトプールを dstPicID 上に作成する
+// 粒子は最初はすべて非表示。transColor が nil でない場合、その色を透明として扱う。
+func (gs *GraphicsSystem) CreateSpritePool(srcPicID, dstPicID, n int, transColor color.Color) (int, error) {
+	gs.mu.Lock()
+	defer gs.mu.Unlock()
+	gs.invalidate()
+
+	srcPic, err := gs.pictures.GetPicWithoutLock(srcPicID)
+	if err != nil {
+		return -1, err
+	}
+	dstPic, err := gs.pictures.GetPicWithoutLock(dstPicID)
+	if err != nil {
+		return -1, err
+	}
+
+	img := ebiten.NewImage(srcPic.Width, srcPic.Height)
+	if transColor != nil {
+		applyColorKeyToImage(img, srcPic.Image, transColor)
+	} else {
+		img.DrawImage(srcPic.Image, nil)
+	}
+
+	var parent *Sprite
+	if gs.pictureSpriteManager != nil {
+		if ps := gs.pictureSpriteManager.GetPictureSpriteByPictureID(dstPicID); ps != nil {
+			parent = ps.GetSprite()
+		} else {
+			parent = gs.pictureSpriteManager.GetBackgroundPictureSpriteSprite(dstPicID)
+		}
+	}
+
+	sp, err := gs.spritePools.CreatePool(dstPicID, img, n, dstPic.Width, dstPic.Height, parent)
+	if err != nil {
+		return -1, err
+	}
+	gs.log.Debug("CreateSpritePool", "poolID", sp.ID(), "srcPicID", srcPicID, "dstPicID", dstPicID, "size", n, "hasParent", parent != nil)
+	return sp.ID(), nil
+}
+
+// SetPoolParticle はスプライトプールの粒子の位置（配置先ピクチャー内の座標）と可視性を設定する
+func (gs *GraphicsSystem) SetPoolParticle(poolID, index int, x, y float64, visible bool) error {
+	gs.mu.Lock()
+	defer gs.mu.Unlock()
+	gs.invalidate()
+
+	sp, err := gs.spritePools.GetPool(poolID)
+	if err != nil {
+		return err
+	}
+	return sp.SetParticle(index, x, y, visible)
+}
+
+// SetPoolVelocity はスプライトプールの粒子の速度を設定する（index が負の場合はすべての粒子）
+func (gs *GraphicsSystem) SetPoolVelocity(poolID, index int, vx, vy float64) error {
+	gs.mu.Lock()
+	defer gs.mu.Unlock()
+
+	sp, err := gs.spritePools.GetPool(poolID)
+	if err != nil {
+		return err
+	}
+	return sp.SetVelocity(index, vx, vy)
+}
+
+// StepSpritePool はスプライトプールのすべての粒子を速度の分だけ進める
+// 配置先ピクチャーの外に完全に出た粒子は反対側から入ってくる
+func (gs *GraphicsSystem) StepSpritePool(poolID int) error {
+	gs.mu.Lock()
+	defer gs.mu.Unlock()
+	gs.invalidate()
+
+	sp, err := gs.spritePools.GetPool(poolID)
+	if err != nil {
+		return err
+	}
+	sp.Step()
+	return nil
+}
+
+// SpritePoolSize はスプライトプールの粒子の数を返す（存在しない場合は0）
+func (gs *GraphicsSystem) SpritePoolSize(poolID int) int {
+	gs.mu.RLock()
+	defer gs.mu.RUnlock()
+
+	sp, err := gs.spritePools.GetPool(poolID)
+	if err != nil {
+		return 0
+	}
+	return sp.Size()
+}
+
+// DelSpritePool はスプライトプールを削除する
+func (gs *GraphicsSystem) DelSpritePool(poolID int) error {
+	gs.mu.Lock()
+	defer gs.mu.Unlock()
+	gs.invalidate()
+
+	return gs.spritePools.RemovePool(poolID)
+}
+
+// setParticleStride はすべてのプールで粒子を何個に1個描画するかを設定する
+// 後から作成したプールにも適用する
+func (spm *SpritePoolManager) setParticleStride(stride int) {
+	spm.mu.Lock()
+	defer spm.mu.Unlock()
+	spm.stride = stride
+	for _, sp := range spm.pools {
+		sp.mu.Lock()
+		sp.stride = stride
+		sp.mu.Unlock()
+	}
+}
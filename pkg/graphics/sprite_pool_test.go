@@ -0,0 +1,136 @@
+package graphics
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestWrapCoord(t *testing.T) {
+	tests := []struct {
+		name string
+		v    float64
+		want float64
+	}{
+		{"inside", 50, 50},
+		{"left edge", -10, -10},
+		{"right edge wraps to left", 100, -10},
+		{"past right edge", 105, -5},
+		{"past left edge", -11, 99},
+		{"several periods", 50 + 3*110, 50},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// size=10, area=100 → 範囲は [-10, 100)
+			if got := wrapCoord(tt.v, 10, 100); got != tt.want {
+				t.Errorf("wrapCoord(%v) = %v, want %v", tt.v, got, tt.want)
+			}
+		})
+	}
+
+	if got := wrapCoord(42, 0, 0); got != 42 {
+		t.Errorf("wrapCoord with an empty area should not change the value, got %v", got)
+	}
+}
+
+func TestNewParticleSet(t *testing.T) {
+	for _, n := range []int{0, -1, MaxSpritePoolSize + 1} {
+		if _, err := newParticleSet(n, 8, 8, 100, 100); err == nil {
+			t.Errorf("newParticleSet(%d) should fail", n)
+		}
+	}
+
+	ps, err := newParticleSet(MaxSpritePoolSize, 8, 8, 100, 100)
+	if err != nil {
+		t.Fatalf("newParticleSet failed: %v", err)
+	}
+	if len(ps.particles) != MaxSpritePoolSize {
+		t.Errorf("expected %d particles, got %d", MaxSpritePoolSize, len(ps.particles))
+	}
+	for i, p := range ps.particles {
+		if p.visible {
+			t.Fatalf("particle %d should start hidden", i)
+		}
+	}
+}
+
+func TestParticleSetStep(t *testing.T) {
+	ps, err := newParticleSet(3, 10, 10, 100, 80)
+	if err != nil {
+		t.Fatalf("newParticleSet failed: %v", err)
+	}
+
+	if err := ps.set(0, 20, 75, true); err != nil {
+		t.Fatalf("set failed: %v", err)
+	}
+	if err := ps.set(3, 0, 0, true); err == nil {
+		t.Error("set with an out of range index should fail")
+	}
+
+	// すべての粒子に同じ速度を設定してから、1つだけ上書きする
+	if err := ps.setVelocity(-1, 1, 2); err != nil {
+		t.Fatalf("setVelocity(all) failed: %v", err)
+	}
+	if err := ps.setVelocity(1, -3, 0); err != nil {
+		t.Fatalf("setVelocity failed: %v", err)
+	}
+	if err := ps.setVelocity(5, 0, 0); err == nil {
+		t.Error("setVelocity with an out of range index should fail")
+	}
+
+	for range 3 {
+		ps.step()
+	}
+
+	// 粒子0: y=75+6=81 は下端を越えるので上から入ってくる（-10 + 1）
+	if p := ps.particles[0]; p.x != 23 || p.y != -9 || !p.visible {
+		t.Errorf("particle 0 = %+v, want (23, -9) visible", p)
+	}
+	// 粒子1: x=0-9 は左端 -10 の内側
+	if p := ps.particles[1]; p.x != -9 || p.y != 0 {
+		t.Errorf("particle 1 = %+v, want (-9, 0)", p)
+	}
+}
+
+func TestHeadlessSpritePool(t *testing.T) {
+	hgs := NewHeadlessGraphicsSystem()
+	srcID, _ := hgs.CreatePic(8, 8)
+	dstID, _ := hgs.CreatePic(320, 240)
+
+	if _, err := hgs.CreateSpritePool(999, dstID, 10, nil); !errors.Is(err, ErrPictureNotFound) {
+		t.Errorf("expected ErrPictureNotFound, got %v", err)
+	}
+	if _, err := hgs.CreateSpritePool(srcID, dstID, 0, nil); err == nil {
+		t.Error("CreateSpritePool with size 0 should fail")
+	}
+
+	poolID, err := hgs.CreateSpritePool(srcID, dstID, 10, nil)
+	if err != nil {
+		t.Fatalf("CreateSpritePool failed: %v", err)
+	}
+	if size := hgs.SpritePoolSize(poolID); size != 10 {
+		t.Errorf("SpritePoolSize = %d, want 10", size)
+	}
+
+	if err := hgs.SetPoolParticle(poolID, 4, 100, 50, true); err != nil {
+		t.Fatalf("SetPoolParticle failed: %v", err)
+	}
+	if err := hgs.SetPoolVelocity(poolID, -1, 0, 5); err != nil {
+		t.Fatalf("SetPoolVelocity failed: %v", err)
+	}
+	if err := hgs.StepSpritePool(poolID); err != nil {
+		t.Fatalf("StepSpritePool failed: %v", err)
+	}
+	if x, y, visible, ok := hgs.GetPoolParticle(poolID, 4); !ok || x != 100 || y != 55 || !visible {
+		t.Errorf("particle 4 = (%v, %v, %v, %v), want (100, 55, true, true)", x, y, visible, ok)
+	}
+
+	if err := hgs.DelSpritePool(poolID); err != nil {
+		t.Fatalf("DelSpritePool failed: %v", err)
+	}
+	if err := hgs.StepSpritePool(poolID); !errors.Is(err, ErrSpritePoolNotFound) {
+		t.Errorf("expected ErrSpritePoolNotFound after delete, got %v", err)
+	}
+	if size := hgs.SpritePoolSize(poolID); size != 0 {
+		t.Errorf("SpritePoolSize of a deleted pool = %d, want 0", size)
+	}
+}
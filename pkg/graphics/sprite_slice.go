@@ -0,0 +1,165 @@
+//go:build !nogpu
+
+package graphics
+
+import (
+	"image"
+
+	"github.com/hajimehoshi/ebiten/v2"
+)
+
+// 描画範囲と9分割の伸縮
+// SetSourceRect はスプライトの画像の一部だけを描画する（1枚の素材画像に並べた部品を切り替えるなど）。
+// SetNineSlice は画像を9分割し、四隅はそのまま、辺と中央だけを伸縮して指定の大きさにする。
+// ダイアログの枠などを拡大しても、角や縁の太さが変わらずにぼやけない。
+
+// slicePatch は9分割した1つの部分の、画像内の範囲と描画する位置・大きさ
+type slicePatch struct {
+	src        image.Rectangle
+	x, y, w, h float64
+}
+
+// SetSourceRect はスプライトの画像のうち描画する範囲を設定する（nil で画像全体）
+// 範囲はスプライトの画像の左上を (0, 0) とする座標で指定する。
+func (s *Sprite) SetSourceRect(rect *image.Rectangle) {
+	s.source = rect
+	s.dirty = true
+}
+
+// SourceRect はスプライトの描画する範囲を返す（設定されていない場合は nil）
+func (s *Sprite) SourceRect() *image.Rectangle {
+	return s.source
+}
+
+// SetNineSlice はスプライトの9分割の伸縮を設定する（nil で解除）
+func (s *Sprite) SetNineSlice(ns *NineSlice) {
+	s.nineSlice = ns
+	s.dirty = true
+}
+
+// NineSlice はスプライトの9分割の伸縮を返す（設定されていない場合は nil）
+func (s *Sprite) NineSlice() *NineSlice {
+	return s.nineSlice
+}
+
+// DrawnSize はスプライトを描画する大きさを返す
+// 9分割の伸縮が設定されている場合はその大きさ、描画する範囲が設定されている場合はその範囲の大きさ
+func (s *Sprite) DrawnSize() (int, int) {
+	if s.image == nil {
+		return 0, 0
+	}
+	if s.nineSlice != nil {
+		return s.nineSlice.Width, s.nineSlice.Height
+	}
+	r := sourceRegion(s.image.Bounds().Size(), s.source)
+	return r.Dx(), r.Dy()
+}
+
+// sourceRegion は大きさ size の画像のうち描画する範囲を返す（画像の外にはみ出した部分は除く）
+func sourceRegion(size image.Point, source *image.Rectangle) image.Rectangle {
+	full := image.Rectangle{Max: size}
+	if source == nil {
+		return full
+	}
+	return source.Intersect(full)
+}
+
+// sliceSpans は長さ length の区間を、端の near/far ピクセルを伸縮せずに長さ target にするときの、
+// 元の3つの区間の長さと描画する3つの区間の長さを返す
+func sliceSpans(length, near, far, target int) (src [3]int, dst [3]float64) {
+	near = min(max(near, 0), length)
+	far = min(max(far, 0), length-near)
+	src = [3]int{near, length - near - far, far}
+	if edges := near + far; target < edges {
+		// 枠が収まらない場合は枠を縮め、中央は描画しない
+		k := float64(target) / float64(edges)
+		return src, [3]float64{float64(near) * k, 0, float64(far) * k}
+	}
+	return src, [3]float64{float64(near), float64(target - near - far), float64(far)}
+}
+
+// nineSlicePatches は画像の範囲 region を ns で9分割した部分を返す（大きさが0の部分は除く）
+func nineSlicePatches(region image.Rectangle, ns NineSlice) []slicePatch {
+	srcW, dstW := sliceSpans(region.Dx(), ns.Left, ns.Right, max(ns.Width, 0))
+	srcH, dstH := sliceSpans(region.Dy(), ns.Top, ns.Bottom, max(ns.Height, 0))
+
+	var patches []slicePatch
+	sy, dy := region.Min.Y, 0.0
+	for row := range 3 {
+		sx, dx := region.Min.X, 0.0
+		for col := range 3 {
+			if srcW[col] > 0 && srcH[row] > 0 && dstW[col] > 0 && dstH[row] > 0 {
+				patches = append(patches, slicePatch{
+					src: image.Rect(sx, sy, sx+srcW[col], sy+srcH[row]),
+					x:   dx, y: dy, w: dstW[col], h: dstH[row],
+				})
+			}
+			sx += srcW[col]
+			dx += dstW[col]
+		}
+		sy += srcH[row]
+		dy += dstH[row]
+	}
+	return patches
+}
+
+// drawSliced は描画する範囲と9分割の伸縮を適用してスプライトを描画する
+// drawAt でスプライト単体を作業用画像に描き（透明色の処理などを含む）、その一部を切り出して target へ描画する。
+func (sm *SpriteManager) drawSliced(target *ebiten.Image, size image.Point, x, y float64, alpha float32, source *image.Rectangle, ns *NineSlice, drawAt func(target *ebiten.Image, x, y float64, alpha float32)) {
+	src := effectImage(&sm.sliceBuffer, size)
+	drawAt(src, 0, 0, 1)
+
+	region := sourceRegion(size, source)
+	if region.Empty() {
+		return
+	}
+	patches := []slicePatch{{src: region, w: float64(region.Dx()), h: float64(region.Dy())}}
+	if ns != nil {
+		patches = nineSlicePatches(region, *ns)
+	}
+	for _, p := range patches {
+		op := &ebiten.DrawImageOptions{}
+		op.GeoM.Scale(p.w/float64(p.src.Dx()), p.h/float64(p.src.Dy()))
+		op.GeoM.Translate(x+p.x, y+p.y)
+		if alpha < 1.0 {
+			op.ColorScale.ScaleAlpha(alpha)
+		}
+		target.DrawImage(src.SubImage(p.src).(*ebiten.Image), op)
+	}
+}
+
+// SetCastSourceRect はキャストの画像のうち描画する範囲を設定する（nil で画像全体）
+// 範囲はキャストの画像（PutCast・MoveCast で切り出した領域）の左上を (0, 0) とする座標で指定する。
+func (gs *GraphicsSystem) SetCastSourceRect(id int, rect *image.Rectangle) error {
+	if err := checkSourceRect(rect); err != nil {
+		return err
+	}
+	return gs.withCastSprite(id, func(s *Sprite) {
+		s.SetSourceRect(copySourceRect(rect))
+		gs.log.Debug("Cast source rect set", "castID", id, "rect", rect)
+	})
+}
+
+// SetCastNineSlice はキャストの9分割の伸縮を設定する（nil で解除）
+func (gs *GraphicsSystem) SetCastNineSlice(id int, ns *NineSlice) error {
+	if err := checkNineSlice(ns); err != nil {
+		return err
+	}
+	return gs.withCastSprite(id, func(s *Sprite) {
+		s.SetNineSlice(copyNineSlice(ns))
+		gs.log.Debug("Cast nine-slice set", "castID", id, "nineSlice", ns)
+	})
+}
+
+// castDrawnSize はキャストを描画する大きさを返す（ヒット判定に使用）
+// 呼び出し元は gs.mu のロックを保持していること
+func (gs *GraphicsSystem) castDrawnSize(cast *Cast) (int, int) {
+	if gs.castSpriteManager != nil {
+		if cs := gs.castSpriteManager.GetCastSprite(cast.ID); cs != nil {
+			if s := cs.GetSprite(); s != nil && s.Image() != nil {
+				return s.DrawnSize()
+			}
+		}
+	}
+	return cast.Width, cast.Height
+}
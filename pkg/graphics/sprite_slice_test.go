@@ -0,0 +1,152 @@
+//go:build !nogpu
+
+package graphics
+
+import (
+	"errors"
+	"image"
+	"testing"
+
+	"github.com/hajimehoshi/ebiten/v2"
+)
+
+func TestSliceSpans(t *testing.T) {
+	for _, tt := range []struct {
+		length, near, far, target int
+		src                       [3]int
+		dst                       [3]float64
+	}{
+		{30, 8, 8, 100, [3]int{8, 14, 8}, [3]float64{8, 84, 8}},     // 中央だけが伸びる
+		{30, 8, 8, 16, [3]int{8, 14, 8}, [3]float64{8, 0, 8}},       // 枠だけになる
+		{30, 8, 4, 6, [3]int{8, 18, 4}, [3]float64{4, 0, 2}},        // 枠が収まらない場合は枠を縮める
+		{30, 20, 20, 60, [3]int{20, 0, 10}, [3]float64{20, 30, 10}}, // 枠は画像の大きさまで
+		{30, -1, 0, 60, [3]int{0, 30, 0}, [3]float64{0, 60, 0}},
+	} {
+		src, dst := sliceSpans(tt.length, tt.near, tt.far, tt.target)
+		if src != tt.src || dst != tt.dst {
+			t.Errorf("sliceSpans(%d, %d, %d, %d) = %v, %v; want %v, %v",
+				tt.length, tt.near, tt.far, tt.target, src, dst, tt.src, tt.dst)
+		}
+	}
+}
+
+func TestNineSlicePatches(t *testing.T) {
+	region := image.Rect(10, 20, 40, 50) // 30x30
+	patches := nineSlicePatches(region, NineSlice{Left: 8, Top: 6, Right: 8, Bottom: 10, Width: 100, Height: 60})
+	if len(patches) != 9 {
+		t.Fatalf("got %d patches, want 9", len(patches))
+	}
+
+	// 四隅はそのままの大きさ
+	corners := map[int]slicePatch{
+		0: {src: image.Rect(10, 20, 18, 26), x: 0, y: 0, w: 8, h: 6},
+		2: {src: image.Rect(32, 20, 40, 26), x: 92, y: 0, w: 8, h: 6},
+		6: {src: image.Rect(10, 40, 18, 50), x: 0, y: 50, w: 8, h: 10},
+		8: {src: image.Rect(32, 40, 40, 50), x: 92, y: 50, w: 8, h: 10},
+	}
+	for i, want := range corners {
+		if patches[i] != want {
+			t.Errorf("corner %d = %+v, want %+v", i, patches[i], want)
+		}
+	}
+	// 中央は両方向に伸びる
+	if want := (slicePatch{src: image.Rect(18, 26, 32, 40), x: 8, y: 6, w: 84, h: 44}); patches[4] != want {
+		t.Errorf("center = %+v, want %+v", patches[4], want)
+	}
+
+	// 枠しか収まらない場合は中央を描画しない
+	if patches := nineSlicePatches(region, NineSlice{Left: 8, Top: 6, Right: 8, Bottom: 10, Width: 16, Height: 16}); len(patches) != 4 {
+		t.Errorf("got %d patches for a frame-only size, want the 4 corners", len(patches))
+	}
+}
+
+func TestSpriteDrawnSize(t *testing.T) {
+	s := NewSprite(1, nil)
+	if w, h := s.DrawnSize(); w != 0 || h != 0 {
+		t.Errorf("sprite without an image: %dx%d, want 0x0", w, h)
+	}
+
+	s = NewSprite(1, ebiten.NewImage(64, 32))
+	if w, h := s.DrawnSize(); w != 64 || h != 32 {
+		t.Errorf("DrawnSize = %dx%d, want the image size 64x32", w, h)
+	}
+
+	s.dirty = false
+	rect := image.Rect(48, 16, 96, 64) // 画像の外にはみ出した部分は描画しない
+	s.SetSourceRect(&rect)
+	if !s.IsDirty() || s.SourceRect() == nil {
+		t.Error("expected SetSourceRect to set the rect and mark the sprite dirty")
+	}
+	if w, h := s.DrawnSize(); w != 16 || h != 16 {
+		t.Errorf("DrawnSize with a source rect = %dx%d, want 16x16", w, h)
+	}
+
+	s.SetNineSlice(&NineSlice{Left: 4, Top: 4, Right: 4, Bottom: 4, Width: 200, Height: 80})
+	if w, h := s.DrawnSize(); w != 200 || h != 80 {
+		t.Errorf("DrawnSize with a nine-slice = %dx%d, want 200x80", w, h)
+	}
+
+	s.SetSourceRect(nil)
+	s.SetNineSlice(nil)
+	if s.SourceRect() != nil || s.NineSlice() != nil {
+		t.Error("expected nil to draw the whole image again")
+	}
+}
+
+func TestCheckSlice(t *testing.T) {
+	valid := image.Rect(0, 0, 10, 10)
+	if err := checkSourceRect(&valid); err != nil {
+		t.Errorf("checkSourceRect(%v) = %v, want nil", valid, err)
+	}
+	for _, rect := range []image.Rectangle{image.Rect(-1, 0, 10, 10), image.Rect(0, 0, 0, 10)} {
+		if err := checkSourceRect(&rect); err == nil {
+			t.Errorf("checkSourceRect(%v) = nil, want an error", rect)
+		}
+	}
+	for _, ns := range []*NineSlice{nil, {Left: 4, Width: 1, Height: 1}} {
+		if err := checkNineSlice(ns); err != nil {
+			t.Errorf("checkNineSlice(%+v) = %v, want nil", ns, err)
+		}
+	}
+	for _, ns := range []*NineSlice{{Left: -1, Width: 10, Height: 10}, {Width: 0, Height: 10}, {Width: 10, Height: -1}} {
+		if err := checkNineSlice(ns); err == nil {
+			t.Errorf("checkNineSlice(%+v) = nil, want an error", ns)
+		}
+	}
+}
+
+func TestHeadlessGraphicsSystem_Slice(t *testing.T) {
+	hgs := NewHeadlessGraphicsSystem(WithLogOperations(false))
+	picID, _ := hgs.CreatePic(64, 64)
+	winID, _ := hgs.OpenWin(picID)
+	castID, _ := hgs.PutCast(winID, picID, 0, 0, 0, 0, 32, 32)
+
+	rect := image.Rect(0, 0, 16, 16)
+	if err := hgs.SetCastSourceRect(castID, &rect); err != nil {
+		t.Fatalf("SetCastSourceRect failed: %v", err)
+	}
+	rect.Max.X = 100 // 呼び出し元の変更は反映されない
+	if cast, _ := hgs.GetCast(castID); cast.Source == nil || cast.Source.Dx() != 16 {
+		t.Errorf("cast source = %v, want 16 wide", cast.Source)
+	}
+	ns := &NineSlice{Left: 4, Top: 4, Right: 4, Bottom: 4, Width: 120, Height: 60}
+	if err := hgs.SetCastNineSlice(castID, ns); err != nil {
+		t.Fatalf("SetCastNineSlice failed: %v", err)
+	}
+	if cast, _ := hgs.GetCast(castID); cast.Slice == nil || *cast.Slice != *ns {
+		t.Errorf("cast nine-slice = %+v, want %+v", cast.Slice, ns)
+	}
+
+	hgs.SetCastSourceRect(castID, nil)
+	hgs.SetCastNineSlice(castID, nil)
+	if cast, _ := hgs.GetCast(castID); cast.Source != nil || cast.Slice != nil {
+		t.Errorf("expected the source rect and nine-slice to be removed, got %v %+v", cast.Source, cast.Slice)
+	}
+
+	if err := hgs.SetCastNineSlice(999, ns); !errors.Is(err, ErrCastNotFound) {
+		t.Errorf("expected ErrCastNotFound, got %v", err)
+	}
+	if err := hgs.SetCastNineSlice(castID, &NineSlice{Width: 0, Height: 10}); err == nil {
+		t.Error("expected an error for a width of 0")
+	}
+}
@@ -1,3 +1,5 @@
+//go:build !nogpu
+
 // sprite_sort.go はスプライトのソート処理を提供する
 // Z順序に基づくスプライトのソートアルゴリズムを含む
 package graphics
@@ -1,3 +1,5 @@
+//go:build !nogpu
+
 package graphics
 
 import (
@@ -66,6 +68,26 @@ func TestSpriteSetters(t *testing.T) {
 	}
 }
 
+func TestSpriteHiresImage(t *testing.T) {
+	img := ebiten.NewImage(10, 10)
+	s := NewSprite(1, img)
+	hires := ebiten.NewImage(20, 20)
+	s.SetHiresImage(hires)
+	if s.HiresImage() != hires {
+		t.Fatal("expected the @2x image to be set")
+	}
+
+	// 同じ画像を設定し直しても @2x の画像は残る
+	s.SetImage(img)
+	if s.HiresImage() != hires {
+		t.Error("expected the @2x image to survive setting the same image")
+	}
+	s.SetImage(ebiten.NewImage(10, 10))
+	if s.HiresImage() != nil {
+		t.Error("expected a different image to clear the @2x image")
+	}
+}
+
 func TestSpriteParentChild(t *testing.T) {
 	parent := NewSprite(1, nil)
 	parent.SetPosition(100, 50)
@@ -231,6 +253,33 @@ func TestSpriteDirtyFlag(t *testing.T) {
 	}
 }
 
+func TestSpriteManagerIsDirty(t *testing.T) {
+	sm := NewSpriteManager()
+	s := sm.CreateSprite(ebiten.NewImage(10, 10))
+	s.SetZPath(NewZPath(1))
+
+	if !sm.IsDirty() {
+		t.Error("manager should be dirty after a sprite is created")
+	}
+
+	screen := ebiten.NewImage(100, 100)
+	sm.Draw(screen)
+	if sm.IsDirty() {
+		t.Error("manager should not be dirty after Draw")
+	}
+
+	s.SetPosition(5, 5)
+	if !sm.IsDirty() {
+		t.Error("manager should be dirty after a sprite moves")
+	}
+	sm.Draw(screen)
+
+	sm.RemoveSprite(s.ID())
+	if !sm.IsDirty() {
+		t.Error("manager should be dirty after a sprite is removed")
+	}
+}
+
 // TestSpriteChildManagement は子スプライト管理メソッドをテストする
 // 要件 1.4: 子スプライトが作成されたとき、親のZ_Pathを継承し、自身のLocal_Z_Orderを追加する
 // 要件 9.1: PictureSpriteは子スプライトを持てる
@@ -1027,6 +1076,40 @@ func TestSpriteManager_BringToFront(t *testing.T) {
 	}
 }
 
+// TestSpriteManager_RestackSiblings はRestackSiblingsメソッドをテストする
+// 対象のスプライトが使用しているLocal_Z_Orderの値だけを並べ替え、背景などの他の兄弟との関係は変えない
+func TestSpriteManager_RestackSiblings(t *testing.T) {
+	sm := NewSpriteManager()
+
+	window := sm.CreateRootSprite(nil, 0)               // Z_Path: [0]
+	background := sm.CreateSpriteWithZPath(nil, window) // Z_Path: [0, 0]
+	cast1 := sm.CreateSpriteWithZPath(nil, window)      // Z_Path: [0, 1]
+	cast2 := sm.CreateSpriteWithZPath(nil, window)      // Z_Path: [0, 2]
+	grandChild := sm.CreateSpriteWithZPath(nil, cast2)  // Z_Path: [0, 2, 0]
+	other := sm.CreateSpriteWithZPath(nil, background)  // Z_Path: [0, 0, 0]
+
+	// cast2 を cast1 の奥に移動する
+	if err := sm.RestackSiblings([]int{cast2.ID(), cast1.ID()}); err != nil {
+		t.Fatalf("RestackSiblingsがエラーを返した: %v", err)
+	}
+	if cast2.GetZPath().LocalZOrder() != 1 || cast1.GetZPath().LocalZOrder() != 2 {
+		t.Errorf("cast2=%s, cast1=%s; want [0 1], [0 2]", cast2.ZPathString(), cast1.ZPathString())
+	}
+	if !background.GetZPath().Less(cast2.GetZPath()) {
+		t.Error("キャストは背景より奥に移動しないはず")
+	}
+	if path := grandChild.GetZPath().Path(); len(path) != 3 || path[1] != 1 {
+		t.Errorf("子スプライトのZ_Pathも更新されるはず: got %s", grandChild.ZPathString())
+	}
+
+	if err := sm.RestackSiblings([]int{cast1.ID(), other.ID()}); err == nil {
+		t.Error("親が異なるスプライトを指定した場合はエラーを返すはず")
+	}
+	if err := sm.RestackSiblings([]int{999}); err == nil {
+		t.Error("存在しないスプライトIDを指定した場合はエラーを返すはず")
+	}
+}
+
 // TestSpriteManager_BringToFront_NotFound は存在しないスプライトIDでBringToFrontを呼び出した場合のテスト
 func TestSpriteManager_BringToFront_NotFound(t *testing.T) {
 	sm := NewSpriteManager()
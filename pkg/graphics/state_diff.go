@@ -0,0 +1,233 @@
+//go:build !nogpu
+
+package graphics
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// フレーム間のスプライトの状態の差分ログ（--debug-state-diff）
+// 映像と音声のずれの報告を調べるため、描画したフレームごとに前のフレームから変わったスプライト
+// （移動・作成・削除・アルファ・表示の切り替え）を1行にまとめて記録する。
+// 各変更には、VMがその変更を行ったときのMIDIティック（ObserveState で受け取る）を付ける。
+// フレームのティックと変更のティックの差が大きければ描画が、変更のティック自体が音楽より遅れていればVMが遅れている。
+
+// noTick はMIDIティックがまだない（MIDIを再生していない）ことを表す
+const noTick = -1
+
+// spriteState は差分を比べるスプライトの状態
+type spriteState struct {
+	x, y    float64
+	alpha   float64
+	visible bool
+}
+
+// observedState はVMの処理の後に観測したスプライトの状態と、その状態になったときのMIDIティック
+type observedState struct {
+	state spriteState
+	tick  int
+	gone  bool // 削除された
+}
+
+// stateDiff はスプライトの状態の変更を観測し、描画したフレームごとの差分を作る
+type stateDiff struct {
+	observed map[int]*observedState // スプライトID → 最後に観測した状態
+	drawn    map[int]spriteState    // 前のフレームで描画した状態
+	tick     int                    // 最後に観測したVMのMIDIティック
+	frame    int                    // 描画したフレームの数
+	mu       sync.Mutex
+}
+
+func newStateDiff() *stateDiff {
+	return &stateDiff{
+		observed: make(map[int]*observedState),
+		drawn:    make(map[int]spriteState),
+		tick:     noTick,
+	}
+}
+
+// observe は現在のスプライトの状態を記録し、変わったスプライトに tick を付ける
+func (sd *stateDiff) observe(states map[int]spriteState, tick int) {
+	sd.mu.Lock()
+	defer sd.mu.Unlock()
+
+	sd.tick = tick
+	for id, state := range states {
+		if o, ok := sd.observed[id]; !ok || o.gone || o.state != state {
+			sd.observed[id] = &observedState{state: state, tick: tick}
+		}
+	}
+	for id, o := range sd.observed {
+		if _, ok := states[id]; !ok && !o.gone {
+			o.gone, o.tick = true, tick
+		}
+	}
+}
+
+// stateChange はフレーム間のスプライトの1つの変更
+type stateChange struct {
+	kind   string // "move", "new", "del", "alpha", "show", "hide"
+	sprite int
+	detail string
+	tick   int // 変更したときのMIDIティック（noTick は不明）
+}
+
+// frameDiff は描画するスプライトの状態を前のフレームと比べた変更を返し、描画した状態として記録する
+// 戻り値はフレームの番号と、フレームの時点のMIDIティック
+func (sd *stateDiff) frameDiff(states map[int]spriteState) (frame, tick int, changes []stateChange) {
+	sd.mu.Lock()
+	defer sd.mu.Unlock()
+
+	sd.frame++
+	requested := func(id int) int {
+		if o, ok := sd.observed[id]; ok {
+			return o.tick
+		}
+		return noTick
+	}
+	for _, id := range slices.Sorted(maps.Keys(states)) {
+		cur := states[id]
+		prev, ok := sd.drawn[id]
+		if !ok {
+			changes = append(changes, stateChange{"new", id, fmt.Sprintf("(%g,%g)", cur.x, cur.y), requested(id)})
+			continue
+		}
+		if cur.x != prev.x || cur.y != prev.y {
+			changes = append(changes, stateChange{"move", id, fmt.Sprintf("(%g,%g)->(%g,%g)", prev.x, prev.y, cur.x, cur.y), requested(id)})
+		}
+		if cur.alpha != prev.alpha {
+			changes = append(changes, stateChange{"alpha", id, fmt.Sprintf("%.2f->%.2f", prev.alpha, cur.alpha), requested(id)})
+		}
+		if cur.visible != prev.visible {
+			kind := "hide"
+			if cur.visible {
+				kind = "show"
+			}
+			changes = append(changes, stateChange{kind, id, "", requested(id)})
+		}
+	}
+	for _, id := range slices.Sorted(maps.Keys(sd.drawn)) {
+		if _, ok := states[id]; !ok {
+			changes = append(changes, stateChange{"del", id, "", requested(id)})
+		}
+	}
+
+	sd.drawn = states
+	// 削除を報告したスプライト（描画される前に削除されたものを含む）の観測を捨てる
+	maps.DeleteFunc(sd.observed, func(_ int, o *observedState) bool { return o.gone })
+	return sd.frame, sd.tick, changes
+}
+
+// formatStateChanges は変更を1行にまとめる（例: "move cast3 (10,20)->(15,20) @480; del win2 @-"）
+// label はスプライトIDからキャスト・ウィンドウの名前を返す
+func formatStateChanges(changes []stateChange, label func(id int) string) string {
+	parts := make([]string, len(changes))
+	for i, c := range changes {
+		var sb strings.Builder
+		sb.WriteString(c.kind)
+		sb.WriteString(" ")
+		sb.WriteString(label(c.sprite))
+		if c.detail != "" {
+			sb.WriteString(" ")
+			sb.WriteString(c.detail)
+		}
+		sb.WriteString(" @")
+		sb.WriteString(formatTick(c.tick))
+		parts[i] = sb.String()
+	}
+	return strings.Join(parts, "; ")
+}
+
+// formatTick はMIDIティックを表示する（不明な場合は "-"）
+func formatTick(tick int) string {
+	if tick == noTick {
+		return "-"
+	}
+	return fmt.Sprint(tick)
+}
+
+// WithStateDiff はフレーム間のスプライトの状態の差分ログの有効/無効を設定する
+// 有効な場合、描画したフレームごとに変わったスプライトをログに記録する。
+// 変更したときのMIDIティックは ObserveState で受け取る（VMがイベントを処理するたびに呼び出す）。
+func WithStateDiff(enabled bool) Option {
+	return func(gs *GraphicsSystem) {
+		if enabled {
+			gs.stateDiff = newStateDiff()
+		} else {
+			gs.stateDiff = nil
+		}
+	}
+}
+
+// ObserveState はVMがイベントを処理した後のスプライトの状態を記録する
+// tick はVMが処理した最後のMIDIティック（MIDIを再生していない場合は負の値）。
+// 差分ログが無効な場合は何もしない。
+func (gs *GraphicsSystem) ObserveState(tick int) {
+	if gs.stateDiff == nil {
+		return
+	}
+	gs.mu.RLock()
+	defer gs.mu.RUnlock()
+	gs.stateDiff.observe(gs.spriteManager.spriteStates(), max(tick, noTick))
+}
+
+// logStateDiff は描画するフレームの差分をログに記録する（変更がないフレームは記録しない）
+// 呼び出し元は gs.mu のロックを保持していること
+func (gs *GraphicsSystem) logStateDiff() {
+	if gs.stateDiff == nil || gs.spriteManager == nil {
+		return
+	}
+	frame, tick, changes := gs.stateDiff.frameDiff(gs.spriteManager.spriteStates())
+	if len(changes) == 0 {
+		return
+	}
+	gs.log.Info("Frame state diff", "frame", frame, "tick", formatTick(tick),
+		"changes", formatStateChanges(changes, gs.spriteLabeler()))
+}
+
+// spriteLabeler はスプライトIDをキャスト・ウィンドウの名前（"cast3"・"win1"）にする関数を返す
+// それ以外のスプライトは "s" とスプライトIDにする。
+// 呼び出し元は gs.mu のロックを保持していること
+func (gs *GraphicsSystem) spriteLabeler() func(id int) string {
+	labels := make(map[int]string)
+	if csm := gs.castSpriteManager; csm != nil {
+		csm.mu.RLock()
+		for castID, cs := range csm.castSprites {
+			if s := cs.GetSprite(); s != nil {
+				labels[s.ID()] = fmt.Sprintf("cast%d", castID)
+			}
+		}
+		csm.mu.RUnlock()
+	}
+	if wsm := gs.windowSpriteManager; wsm != nil {
+		wsm.mu.RLock()
+		for winID, ws := range wsm.windowSprites {
+			if s := ws.GetSprite(); s != nil {
+				labels[s.ID()] = fmt.Sprintf("win%d", winID)
+			}
+		}
+		wsm.mu.RUnlock()
+	}
+	return func(id int) string {
+		if label, ok := labels[id]; ok {
+			return label
+		}
+		return fmt.Sprintf("s%d", id)
+	}
+}
+
+// spriteStates は登録されているすべてのスプライトの状態を返す
+func (sm *SpriteManager) spriteStates() map[int]spriteState {
+	sm.mu.RLock()
+	defer sm.mu.RUnlock()
+
+	states := make(map[int]spriteState, len(sm.sprites))
+	for id, s := range sm.sprites {
+		states[id] = spriteState{x: s.x, y: s.y, alpha: s.alpha, visible: s.visible}
+	}
+	return states
+}
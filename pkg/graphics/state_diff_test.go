@@ -0,0 +1,100 @@
+//go:build !nogpu
+
+package graphics
+
+import (
+	"bytes"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+// TestStateDiffFrameDiff tests that each kind of change is reported with the tick at which it was observed.
+func TestStateDiffFrameDiff(t *testing.T) {
+	sd := newStateDiff()
+	first := map[int]spriteState{
+		1: {x: 10, y: 20, alpha: 1, visible: true},
+		2: {x: 0, y: 0, alpha: 1, visible: true},
+		3: {x: 5, y: 5, alpha: 1, visible: true},
+	}
+	sd.observe(first, 480)
+	frame, tick, changes := sd.frameDiff(first)
+	if frame != 1 || tick != 480 || len(changes) != 3 {
+		t.Fatalf("first frame = (%d, %d, %v), want 3 new sprites at frame 1, tick 480", frame, tick, changes)
+	}
+
+	// 変更のない状態を再び観測しても、ティックは最初に変わったときのまま
+	second := map[int]spriteState{
+		1: {x: 15, y: 20, alpha: 1, visible: true},
+		2: {x: 0, y: 0, alpha: 0.5, visible: false},
+		4: {x: 1, y: 2, alpha: 1, visible: true},
+	}
+	sd.observe(second, 481)
+	sd.observe(second, 482)
+	_, tick, changes = sd.frameDiff(second)
+	if tick != 482 {
+		t.Errorf("tick = %d, want 482", tick)
+	}
+	got := formatStateChanges(changes, func(id int) string { return []string{"", "cast1", "win1", "s3", "s4"}[id] })
+	want := "move cast1 (10,20)->(15,20) @481; alpha win1 1.00->0.50 @481; hide win1 @481; new s4 (1,2) @481; del s3 @481"
+	if got != want {
+		t.Errorf("changes = %q, want %q", got, want)
+	}
+
+	// 変更のないフレームは空
+	if _, _, changes := sd.frameDiff(second); len(changes) != 0 {
+		t.Errorf("unchanged frame = %v, want no changes", changes)
+	}
+	if _, ok := sd.observed[3]; ok {
+		t.Error("the observation of the deleted sprite should be discarded")
+	}
+}
+
+// TestStateDiffUnobserved tests that changes never observed from the VM have no tick.
+func TestStateDiffUnobserved(t *testing.T) {
+	sd := newStateDiff()
+	frame, tick, changes := sd.frameDiff(map[int]spriteState{7: {alpha: 1, visible: true}})
+	if frame != 1 || tick != noTick {
+		t.Errorf("frame, tick = %d, %d, want 1, %d", frame, tick, noTick)
+	}
+	if got := formatStateChanges(changes, func(int) string { return "s7" }); got != "new s7 (0,0) @-" {
+		t.Errorf("changes = %q", got)
+	}
+}
+
+// TestGraphicsSystemStateDiffLog tests that Draw logs the diff with cast labels and the observed tick.
+func TestGraphicsSystemStateDiffLog(t *testing.T) {
+	var buf bytes.Buffer
+	gs := NewGraphicsSystem("", WithLogger(slog.New(slog.NewTextHandler(&buf, nil))), WithStateDiff(true))
+
+	s := gs.spriteManager.CreateSpriteWithSize(4, 4)
+	gs.ObserveState(120)
+	gs.logStateDiff()
+	s.SetPosition(8, 9)
+	gs.ObserveState(240)
+	gs.logStateDiff()
+	gs.logStateDiff() // 変更がないフレームは記録しない
+
+	out := buf.String()
+	if n := strings.Count(out, "Frame state diff"); n != 2 {
+		t.Fatalf("expected 2 log lines, got %d:\n%s", n, out)
+	}
+	if !strings.Contains(out, "new s") || !strings.Contains(out, "@120") {
+		t.Errorf("missing the creation at tick 120:\n%s", out)
+	}
+	if !strings.Contains(out, "->(8,9) @240") || !strings.Contains(out, "frame=2 tick=240") {
+		t.Errorf("missing the move at tick 240:\n%s", out)
+	}
+}
+
+// TestGraphicsSystemStateDiffDisabled tests that nothing is recorded without WithStateDiff.
+func TestGraphicsSystemStateDiffDisabled(t *testing.T) {
+	var buf bytes.Buffer
+	gs := NewGraphicsSystem("", WithLogger(slog.New(slog.NewTextHandler(&buf, nil))))
+	gs.spriteManager.CreateSpriteWithSize(4, 4)
+	gs.ObserveState(0)
+	gs.logStateDiff()
+	if gs.stateDiff != nil || buf.Len() != 0 {
+		t.Errorf("expected no state diff, got log %q", buf.String())
+	}
+}
@@ -1,3 +1,5 @@
+//go:build !nogpu
+
 // Package graphics provides sprite-based rendering system.
 package graphics
 
@@ -25,6 +27,8 @@ type SpriteStats struct {
 	Pictures int `json:"pictures"` // ロードされているピクチャー数
 	Windows  int `json:"windows"`  // 開いているウィンドウ数
 	Casts    int `json:"casts"`    // アクティブなキャスト数（CastManager）
+
+	AtlasPages int `json:"atlas_pages"` // キャストの画像を配置しているアトラスのページ数
 }
 
 // String はSpriteStatsの文字列表現を返す
@@ -40,6 +44,7 @@ func (ss *SpriteStats) String() string {
 	sb.WriteString(fmt.Sprintf("Pictures: %d\n", ss.Pictures))
 	sb.WriteString(fmt.Sprintf("Windows:  %d\n", ss.Windows))
 	sb.WriteString(fmt.Sprintf("Casts:    %d\n", ss.Casts))
+	sb.WriteString(fmt.Sprintf("Atlas Pages: %d\n", ss.AtlasPages))
 	sb.WriteString("=========================\n")
 	return sb.String()
 }
@@ -80,6 +85,7 @@ func (gs *GraphicsSystem) GetSpriteStats() *SpriteStats {
 
 	if gs.castSpriteManager != nil {
 		stats.CastSprites = gs.castSpriteManager.Count()
+		stats.AtlasPages = gs.castSpriteManager.atlas.pageCount()
 	}
 
 	if gs.textSpriteManager != nil {
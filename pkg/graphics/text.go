@@ -1,3 +1,5 @@
+//go:build !nogpu
+
 package graphics
 
 import (
@@ -15,7 +17,6 @@ import (
 	"golang.org/x/image/font"
 	"golang.org/x/image/font/basicfont"
 	"golang.org/x/image/font/opentype"
-	"golang.org/x/image/math/fixed"
 )
 
 // FontSettings はフォント設定を保持する
@@ -31,9 +32,10 @@ type FontSettings struct {
 
 // TextSettings はテキスト描画設定を保持する
 type TextSettings struct {
-	TextColor color.Color // 文字色
-	BgColor   color.Color // 背景色
-	BackMode  int         // 背景モード（0=背景あり/不透明, 1=透明）
+	TextColor color.Color   // 文字色
+	BgColor   color.Color   // 背景色
+	BackMode  int           // 背景モード（0=背景あり/不透明, 1=透明）
+	Direction TextDirection // 文字列の向き（横書き・縦書き）
 }
 
 // TextRenderer はテキスト描画を管理する
@@ -42,8 +44,16 @@ type TextRenderer struct {
 	font     *FontSettings // 現在のフォント設定
 	settings *TextSettings // 現在のテキスト設定
 	face     font.Face     // 現在のフォントフェイス
+	loaded   bool          // face が指定したフォントから読み込めたかどうか
 	log      *slog.Logger  // ロガー
 	mu       sync.RWMutex  // 排他制御
+
+	// 現在のフォントを固定ピッチで描画するフォントフェイス（必要になったときに作成する）
+	fixedFace font.Face
+
+	// 計測専用のフォントフェイス（TextWidth/TextHeightでフォントを指定した場合）
+	// キーは "フォント名/描画サイズ/ピッチ"
+	measureFaces map[string]font.Face
 }
 
 // フォントマッピング（Windows → クロスプラットフォーム）
@@ -66,7 +76,7 @@ func NewTextRenderer() *TextRenderer {
 	tr := &TextRenderer{
 		font: &FontSettings{
 			Name:   "default",
-			Size:   12,
+			Size:   defaultFontSize,
 			Weight: 400, // 通常の太さ
 		},
 		settings: &TextSettings{
@@ -98,17 +108,15 @@ func (tr *TextRenderer) SetFont(name string, size int, opts ...FontOption) error
 
 	// サイズの妥当性チェック
 	if size <= 0 {
-		size = 12 // デフォルトサイズ
+		size = defaultFontSize
 	}
 	// 大きなフォントサイズは許可する（FILLYスクリプトでは640などの大きなサイズが使われる）
 	// これは「ピクチャー全体を背景色で塗りつぶす」テクニックとして使用される
 	// ただし、実際のフォント描画では最大サイズを制限する
-	actualSize := size
-	if size > 200 {
+	actualSize := fontRenderSize(size)
+	if actualSize != size {
 		tr.log.Debug("SetFont: Large size detected, will use for background fill calculation",
 			"requestedSize", size)
-		// 実際のフォント描画には制限されたサイズを使用
-		actualSize = 72 // 大きめのサイズを使用
 	}
 
 	// フォント設定を更新
@@ -128,10 +136,14 @@ func (tr *TextRenderer) SetFont(name string, size int, opts ...FontOption) error
 			"error", err)
 		// フォールバックフォントを使用
 		tr.face = basicfont.Face7x13
+		tr.loaded = false
+		tr.fixedFace = nil
 		return nil // エラーは返さない（要件 5.8）
 	}
 
 	tr.face = face
+	tr.loaded = true
+	tr.fixedFace = nil
 	tr.log.Debug("Font set successfully",
 		"name", name,
 		"size", size,
@@ -203,12 +215,37 @@ func (tr *TextRenderer) SetBackMode(mode int) {
 	tr.settings.BackMode = mode
 }
 
+// SetTextDirection は文字列の向き（横書き・縦書き）を設定する
+func (tr *TextRenderer) SetTextDirection(direction TextDirection) {
+	tr.mu.Lock()
+	defer tr.mu.Unlock()
+	tr.settings.Direction = direction
+}
+
 // TextWrite はピクチャーに文字列を描画する
 // 要件 5.2: TextWrite(pic_no, x, y, text)が呼ばれたとき、指定されたピクチャーに文字列を描画する
 // スプライトシステム: TextSpriteはGraphicsSystem.TextWrite()で作成される
 // レイヤー方式: 背景に文字を描画し、差分を取って文字部分だけを抽出
 // これにより、同じ位置に別の色で描画しても前の文字の影が残らない
 func (tr *TextRenderer) TextWrite(pic *Picture, x, y int, text string) error {
+	return tr.TextWriteWithPitch(pic, x, y, text, PitchDefault)
+}
+
+// TextWriteWithPitch はピッチを指定してピクチャーに文字列を描画する（TextWrite を参照）
+func (tr *TextRenderer) TextWriteWithPitch(pic *Picture, x, y int, text string, pitch FontPitch) error {
+	return tr.TextWriteHighlighted(pic, x, y, text, pitch, TextHighlight{})
+}
+
+// TextWriteHighlighted はテキストを描画し、先頭の highlight.Count 文字を highlight.Color で描画する
+func (tr *TextRenderer) TextWriteHighlighted(pic *Picture, x, y int, text string, pitch FontPitch, highlight TextHighlight) error {
+	return tr.TextWriteWithLayout(pic, x, y, text, pitch, TextLayout{}, highlight)
+}
+
+// TextWriteWithLayout は字間と行間を指定してテキストを描画する（TextWriteHighlighted を参照）
+// 改行を含む文字列は複数行（縦書きでは複数列）で描画する
+func (tr *TextRenderer) TextWriteWithLayout(pic *Picture, x, y int, text string, pitch FontPitch, layout TextLayout, highlight TextHighlight) error {
+	face := tr.FaceForLayout(pitch, layout)
+
 	tr.mu.RLock()
 	defer tr.mu.RUnlock()
 
@@ -240,9 +277,11 @@ func (tr *TextRenderer) TextWrite(pic *Picture, x, y int, text string) error {
 	if tr.settings.BackMode == 0 {
 		// BackMode=0: 背景あり/不透明
 		// テキストの境界を計算
-		textBounds, _ := font.BoundString(tr.face, text)
-		width := (textBounds.Max.X - textBounds.Min.X).Ceil()
-		height := (textBounds.Max.Y - textBounds.Min.Y).Ceil()
+		textBounds := measureText(face, text)
+		width, height := textBounds.Dx(), textBounds.Dy()
+		if tr.settings.Direction == TextVertical {
+			width, height = verticalTextExtent(face, text)
+		}
 
 		// 大きなフォントサイズの場合、ピクチャー全体を背景色で塗りつぶす
 		// これはFILLYスクリプトで「ピクチャーを白で塗りつぶす」テクニックとして使用される
@@ -253,6 +292,10 @@ func (tr *TextRenderer) TextWrite(pic *Picture, x, y int, text string) error {
 			tr.log.Debug("TextWrite: Large font size, filling entire picture with background color",
 				"fontSize", tr.font.Size,
 				"bgColor", tr.settings.BgColor)
+		} else if tr.settings.Direction == TextVertical {
+			// 縦書きでは列の範囲を塗りつぶす
+			bgRect := image.Rect(x, y, x+width, y+height)
+			draw.Draw(rgba, bgRect, &image.Uniform{tr.settings.BgColor}, image.Point{}, draw.Src)
 		} else {
 			// 通常のフォントサイズの場合、テキストの境界だけを塗りつぶす
 			bgRect := image.Rect(x, y, x+width, y+height+tr.font.Size)
@@ -260,14 +303,12 @@ func (tr *TextRenderer) TextWrite(pic *Picture, x, y int, text string) error {
 		}
 	}
 
-	// テキストを直接描画
-	drawer := &font.Drawer{
-		Dst:  rgba,
-		Src:  image.NewUniform(tr.settings.TextColor),
-		Face: tr.face,
-		Dot:  fixed.Point26_6{X: fixed.I(x), Y: fixed.I(y + tr.font.Size)},
+	// テキストを直接描画（縦書きでは (x, y) が列の左上）
+	textY := y + tr.font.Size
+	if tr.settings.Direction == TextVertical {
+		textY = y
 	}
-	drawer.DrawString(text)
+	drawHighlightedText(rgba, tr.settings.TextColor, face, x, textY, text, tr.settings.Direction, highlight)
 
 	// Ebitengine画像に変換して戻す
 	pic.Image = ebiten.NewImageFromImage(rgba)
@@ -293,6 +334,101 @@ func (tr *TextRenderer) MeasureText(text string) (int, int) {
 	return width, height
 }
 
+// TextExtent は描画時と同じフォントメトリクスでテキストの幅と高さを返す
+// 幅は描画位置の進み幅（advance）、高さはフォントの行の高さ（アセント+ディセント）。
+// name が空の場合は現在のフォントで計測する。フォントを指定した場合も現在のフォント設定は変更しない。
+// フォントが見つからない場合は、SetFontと同様にデフォルトフォントで計測する。
+func (tr *TextRenderer) TextExtent(text, name string, size int) (int, int) {
+	return tr.TextExtentWithPitch(text, name, size, PitchDefault)
+}
+
+// TextExtentWithPitch はピッチを指定してテキストの幅と高さを返す（TextExtent を参照）
+func (tr *TextRenderer) TextExtentWithPitch(text, name string, size int, pitch FontPitch) (int, int) {
+	return tr.TextExtentWithLayout(text, name, size, pitch, TextLayout{})
+}
+
+// TextExtentWithLayout は字間と行間を指定してテキストの幅と高さを返す（TextExtent を参照）
+// 改行を含む文字列では、最も長い行の幅とすべての行の高さを返す
+func (tr *TextRenderer) TextExtentWithLayout(text, name string, size int, pitch FontPitch, layout TextLayout) (int, int) {
+	face, renderSize := tr.measureFace(name, size, pitch)
+	lf := newLayoutFace(face, renderSize, layout)
+	if tr.GetTextSettings().Direction == TextVertical {
+		return verticalTextExtent(lf, text)
+	}
+	return horizontalTextExtent(lf, text)
+}
+
+// measureFace は計測に使うフォントフェイスと、その描画サイズを返す
+func (tr *TextRenderer) measureFace(name string, size int, pitch FontPitch) (font.Face, int) {
+	tr.mu.Lock()
+	defer tr.mu.Unlock()
+
+	if name == "" || (name == tr.font.Name && size == tr.font.Size) {
+		return tr.faceForPitchLocked(pitch), fontRenderSize(tr.font.Size)
+	}
+
+	renderSize := fontRenderSize(size)
+	pitch = resolvePitch(name, pitch)
+	key := fmt.Sprintf("%s/%d/%s", name, renderSize, pitch)
+	if face, ok := tr.measureFaces[key]; ok {
+		return face, renderSize
+	}
+
+	face, err := tr.loadFont(name, renderSize)
+	if err != nil {
+		tr.log.Debug("TextExtent: font not found, measuring with fallback", "fontName", name, "error", err)
+		face = basicfont.Face7x13
+	}
+	if pitch == PitchFixed {
+		face = tr.newFixedFace(face, err == nil, renderSize)
+	}
+	if tr.measureFaces == nil {
+		tr.measureFaces = make(map[string]font.Face)
+	}
+	tr.measureFaces[key] = face
+	return face, renderSize
+}
+
+// FaceForPitch は現在のフォントを pitch で描画するフォントフェイスを返す
+// PitchDefault の場合は、フォント名が等幅フォント（ＭＳ ゴシックなど）なら固定ピッチで描画する。
+func (tr *TextRenderer) FaceForPitch(pitch FontPitch) font.Face {
+	tr.mu.Lock()
+	defer tr.mu.Unlock()
+	return tr.faceForPitchLocked(pitch)
+}
+
+// FaceForLayout は現在のフォントを pitch で、layout の字間と行間で描画するフォントフェイスを返す
+func (tr *TextRenderer) FaceForLayout(pitch FontPitch, layout TextLayout) font.Face {
+	tr.mu.Lock()
+	defer tr.mu.Unlock()
+	return newLayoutFace(tr.faceForPitchLocked(pitch), fontRenderSize(tr.font.Size), layout)
+}
+
+// faceForPitchLocked は FaceForPitch の本体（呼び出し元は tr.mu のロックを保持していること）
+func (tr *TextRenderer) faceForPitchLocked(pitch FontPitch) font.Face {
+	if resolvePitch(tr.font.Name, pitch) != PitchFixed {
+		return tr.face
+	}
+	if tr.fixedFace == nil {
+		tr.fixedFace = tr.newFixedFace(tr.face, tr.loaded, fontRenderSize(tr.font.Size))
+	}
+	return tr.fixedFace
+}
+
+// newFixedFace は face を固定ピッチで描画するフォントフェイスを作成する
+// フォントを読み込めなかった場合（loaded が false）は、固定ピッチのフォールバックフォントを使う
+func (tr *TextRenderer) newFixedFace(face font.Face, loaded bool, size int) font.Face {
+	if !loaded {
+		fallback, err := fixedPitchFallbackFace(size)
+		if err != nil {
+			tr.log.Warn("Failed to load fixed-pitch fallback font", "error", err)
+		} else {
+			face = fallback
+		}
+	}
+	return newFixedPitchFace(face, size)
+}
+
 // GetFontSettings は現在のフォント設定を返す
 func (tr *TextRenderer) GetFontSettings() FontSettings {
 	tr.mu.RLock()
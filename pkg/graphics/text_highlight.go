@@ -0,0 +1,102 @@
+package graphics
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+	"unicode/utf8"
+
+	"golang.org/x/image/font"
+)
+
+// TextHighlight は文字列の先頭の Count 文字を Color で描画する指定（カラオケ表示の歌った部分）
+// Count が0以下または Color が nil の場合は強調しない
+type TextHighlight struct {
+	Count int
+	Color color.Color
+}
+
+// active は強調する文字があるかどうかを返す
+func (h TextHighlight) active() bool {
+	return h.Count > 0 && h.Color != nil
+}
+
+// highlightExtent は十分に大きな座標（強調範囲の片側を開いた矩形にするため）
+const highlightExtent = 1 << 20
+
+// highlightRegions は drawHighlightedText で (x, y) に描画した文字列の先頭 count 文字が占める範囲を返す
+// 行ごとに1つの範囲を返す。横書きでは行の先頭の文字列の幅まで、縦書きでは列の高さまでの範囲になる
+func highlightRegions(face font.Face, x, y int, text string, count int, direction TextDirection) []image.Rectangle {
+	lines := textLines(text)
+	regions := make([]image.Rectangle, 0, len(lines))
+	for i, line := range lines {
+		if count <= 0 {
+			break
+		}
+		prefix, _ := splitRunes(line, count)
+		count -= utf8.RuneCountInString(prefix)
+
+		lx, ly := lineOrigin(face, x, y, i, len(lines), direction)
+		if direction == TextVertical {
+			// 1列目は右端、最後の列は左端まで広げる
+			left, right := lx, lx+columnAdvance(face)
+			if i == 0 {
+				right = highlightExtent
+			}
+			if i == len(lines)-1 {
+				left = -highlightExtent
+			}
+			regions = append(regions, image.Rect(left, -highlightExtent, right, ly+verticalColumnHeight(face, prefix)))
+			continue
+		}
+		// 行の範囲はアセントの位置で区切り、1行目は上端、最後の行は下端まで広げる
+		ascent := face.Metrics().Ascent.Ceil()
+		top, bottom := ly-ascent, ly-ascent+lineAdvance(face)
+		if i == 0 {
+			top = -highlightExtent
+		}
+		if i == len(lines)-1 {
+			bottom = highlightExtent
+		}
+		width := font.MeasureString(face, prefix).Round()
+		regions = append(regions, image.Rect(-highlightExtent, top, lx+width, bottom))
+	}
+	return regions
+}
+
+// drawHighlightedText は drawText と同じ位置に文字列を描画し、先頭の文字だけを強調色で描画する
+// 強調する文字数に改行は含めない
+func drawHighlightedText(dst draw.Image, textColor color.Color, face font.Face, x, y int, text string, direction TextDirection, highlight TextHighlight) {
+	if !highlight.active() {
+		drawText(dst, image.NewUniform(textColor), face, x, y, text, direction)
+		return
+	}
+	count := highlight.Count
+	lines := textLines(text)
+	for i, line := range lines {
+		lx, ly := lineOrigin(face, x, y, i, len(lines), direction)
+		prefix, rest := splitRunes(line, count)
+		count -= utf8.RuneCountInString(prefix)
+
+		drawLine(dst, image.NewUniform(highlight.Color), face, lx, ly, prefix, direction)
+		if rest == "" {
+			continue
+		}
+		if direction == TextVertical {
+			drawLine(dst, image.NewUniform(textColor), face, lx, ly+verticalColumnHeight(face, prefix), rest, direction)
+			continue
+		}
+		drawLine(dst, image.NewUniform(textColor), face, lx+font.MeasureString(face, prefix).Round(), ly, rest, direction)
+	}
+}
+
+// splitRunes は文字列を先頭の n 文字とそれ以降に分ける
+func splitRunes(text string, n int) (string, string) {
+	for i := range text {
+		if n == 0 {
+			return text[:i], text[i:]
+		}
+		n--
+	}
+	return text, ""
+}
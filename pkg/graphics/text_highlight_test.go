@@ -0,0 +1,118 @@
+//go:build !nogpu
+
+package graphics
+
+import (
+	"image"
+	"image/color"
+	"testing"
+)
+
+func TestSplitRunes(t *testing.T) {
+	tests := []struct {
+		text         string
+		n            int
+		prefix, rest string
+	}{
+		{"さくら", 0, "", "さくら"},
+		{"さくら", 2, "さく", "ら"},
+		{"さくら", 3, "さくら", ""},
+		{"さくら", 5, "さくら", ""},
+		{"abc", 1, "a", "bc"},
+		{"", 1, "", ""},
+	}
+	for _, tt := range tests {
+		prefix, rest := splitRunes(tt.text, tt.n)
+		if prefix != tt.prefix || rest != tt.rest {
+			t.Errorf("splitRunes(%q, %d) = (%q, %q), want (%q, %q)", tt.text, tt.n, prefix, rest, tt.prefix, tt.rest)
+		}
+	}
+}
+
+// colorBounds は dst の中で c に近い画素の範囲を返す
+func colorBounds(img *image.RGBA, match func(c color.RGBA) bool) image.Rectangle {
+	var r image.Rectangle
+	b := img.Bounds()
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		for x := b.Min.X; x < b.Max.X; x++ {
+			if match(img.RGBAAt(x, y)) {
+				r = r.Union(image.Rect(x, y, x+1, y+1))
+			}
+		}
+	}
+	return r
+}
+
+func isRed(c color.RGBA) bool   { return c.R > 0x80 && c.G < 0x40 && c.B < 0x40 }
+func isBlack(c color.RGBA) bool { return c.A > 0x80 && c.R < 0x40 && c.G < 0x40 && c.B < 0x40 }
+
+func TestDrawHighlightedText(t *testing.T) {
+	face, err := fixedPitchFallbackFace(16)
+	if err != nil {
+		t.Fatalf("fixedPitchFallbackFace failed: %v", err)
+	}
+	red := color.RGBA{R: 0xFF, A: 0xFF}
+
+	// 横書き: 先頭の2文字が赤、残りが黒で、赤の文字は黒の文字より左にある
+	img := image.NewRGBA(image.Rect(0, 0, 128, 32))
+	drawHighlightedText(img, color.Black, face, 4, 20, "HHHH", TextHorizontal, TextHighlight{Count: 2, Color: red})
+	redInk, blackInk := colorBounds(img, isRed), colorBounds(img, isBlack)
+	if redInk.Empty() || blackInk.Empty() {
+		t.Fatalf("red ink = %v, black ink = %v, want both", redInk, blackInk)
+	}
+	if redInk.Max.X > blackInk.Min.X {
+		t.Errorf("red ink %v should be left of black ink %v", redInk, blackInk)
+	}
+	if regions := highlightRegions(face, 4, 20, "HHHH", 2, TextHorizontal); len(regions) != 1 || !redInk.In(regions[0]) || blackInk.Overlaps(regions[0]) {
+		t.Errorf("highlight regions %v should cover red ink %v only", regions, redInk)
+	}
+
+	// 縦書き: 先頭の1文字が赤で、黒の文字より上にある
+	img = image.NewRGBA(image.Rect(0, 0, 32, 128))
+	drawHighlightedText(img, color.Black, face, 0, 0, "ああ", TextVertical, TextHighlight{Count: 1, Color: red})
+	redInk, blackInk = colorBounds(img, isRed), colorBounds(img, isBlack)
+	if redInk.Empty() || blackInk.Empty() || redInk.Max.Y > blackInk.Min.Y {
+		t.Errorf("red ink %v should be above black ink %v", redInk, blackInk)
+	}
+
+	// 強調しない場合は全体が文字色になる
+	img = image.NewRGBA(image.Rect(0, 0, 128, 32))
+	drawHighlightedText(img, color.Black, face, 4, 20, "HHHH", TextHorizontal, TextHighlight{Count: 2})
+	if !colorBounds(img, isRed).Empty() {
+		t.Error("highlight without color should not draw red")
+	}
+}
+
+func TestCreateTextSpriteImage_Highlight(t *testing.T) {
+	face, err := fixedPitchFallbackFace(16)
+	if err != nil {
+		t.Fatalf("fixedPitchFallbackFace failed: %v", err)
+	}
+	red := color.RGBA{R: 0xFF, A: 0xFF}
+	white := image.NewRGBA(image.Rect(0, 0, 80, 30))
+	for i := range white.Pix {
+		white.Pix[i] = 0xFF
+	}
+
+	for name, opts := range map[string]TextSpriteOptionsWithBackground{
+		"opaque":     {TextSpriteOptions: TextSpriteOptions{BackMode: 0}},
+		"difference": {TextSpriteOptions: TextSpriteOptions{BackMode: 1}},
+		"mask":       {TextSpriteOptions: TextSpriteOptions{BackMode: 1}, BackgroundImage: white},
+	} {
+		t.Run(name, func(t *testing.T) {
+			opts.Text = "HHHH"
+			opts.Face = face
+			opts.TextColor = color.Black
+			opts.BgColor = color.White
+			opts.Y = 16
+			opts.Width, opts.Height = 80, 30
+			opts.Highlight = TextHighlight{Count: 2, Color: red}
+
+			img := CreateTextSpriteImageWithBackground(opts)
+			redInk, blackInk := colorBounds(img, isRed), colorBounds(img, isBlack)
+			if redInk.Empty() || blackInk.Empty() || redInk.Max.X > blackInk.Min.X {
+				t.Errorf("red ink %v should be left of black ink %v", redInk, blackInk)
+			}
+		})
+	}
+}
@@ -0,0 +1,144 @@
+package graphics
+
+import (
+	"image"
+	"strings"
+
+	"golang.org/x/image/font"
+	"golang.org/x/image/math/fixed"
+)
+
+// TextLayout は1回の文字列描画の字間と行間（ピクセル単位、負の値で詰める）
+// 現在のフォントの字形がＭＳ ゴシックと異なっていても、元のタイトルの詰まった配置を再現するために使う
+type TextLayout struct {
+	// Tracking は各文字の後ろに加える間隔（縦書きでは文字の下）
+	Tracking int
+	// LineSpacing は改行（\n）を含む文字列で行と行の間に加える間隔（縦書きでは列の間）
+	LineSpacing int
+}
+
+// maxTextSpacing は字間・行間として受け付ける絶対値の上限
+const maxTextSpacing = 256
+
+// clamped は字間と行間を ±maxTextSpacing の範囲に収めた TextLayout を返す
+func (l TextLayout) clamped() TextLayout {
+	return TextLayout{
+		Tracking:    min(max(l.Tracking, -maxTextSpacing), maxTextSpacing),
+		LineSpacing: min(max(l.LineSpacing, -maxTextSpacing), maxTextSpacing),
+	}
+}
+
+// layoutFace は字間と行間を指定したフォントフェイス
+// 横書きの字間は文字の送り幅に加えるため、font.Drawer や font.MeasureString にそのまま反映される
+type layoutFace struct {
+	font.Face
+	tracking    int
+	lineHeight  int // 行の送りの基準（フォントサイズ。ＭＳ ゴシックの行の高さと同じ）
+	lineSpacing int
+}
+
+// newLayoutFace は face を size のフォントとして layout の字間と行間で描画するフォントフェイスを作成する
+func newLayoutFace(face font.Face, size int, layout TextLayout) *layoutFace {
+	layout = layout.clamped()
+	return &layoutFace{Face: face, tracking: layout.Tracking, lineHeight: max(size, 1), lineSpacing: layout.LineSpacing}
+}
+
+// Glyph は字間を加えた送り幅で文字を描画する
+func (f *layoutFace) Glyph(dot fixed.Point26_6, r rune) (image.Rectangle, image.Image, image.Point, fixed.Int26_6, bool) {
+	dr, mask, maskp, advance, ok := f.Face.Glyph(dot, r)
+	return dr, mask, maskp, advance + fixed.I(f.tracking), ok
+}
+
+// GlyphBounds は字間を加えた送り幅と文字の境界を返す
+func (f *layoutFace) GlyphBounds(r rune) (fixed.Rectangle26_6, fixed.Int26_6, bool) {
+	bounds, advance, ok := f.Face.GlyphBounds(r)
+	return bounds, advance + fixed.I(f.tracking), ok
+}
+
+// GlyphAdvance は字間を加えた送り幅を返す
+func (f *layoutFace) GlyphAdvance(r rune) (fixed.Int26_6, bool) {
+	advance, ok := f.Face.GlyphAdvance(r)
+	return advance + fixed.I(f.tracking), ok
+}
+
+// baseFace は字間を加える前のフォントフェイスと字間を返す（縦書きは文字ごとに字間を加える）
+func baseFace(face font.Face) (font.Face, int) {
+	if lf, ok := face.(*layoutFace); ok {
+		return lf.Face, lf.tracking
+	}
+	return face, 0
+}
+
+// lineAdvance は横書きで次の行のベースラインまでの距離を返す
+// 行間を指定していないフォントフェイスではフォントの行の高さになる
+func lineAdvance(face font.Face) int {
+	if lf, ok := face.(*layoutFace); ok {
+		return max(lf.lineHeight+lf.lineSpacing, 1)
+	}
+	return getFontHeight(face)
+}
+
+// columnAdvance は縦書きで隣の列までの距離を返す
+func columnAdvance(face font.Face) int {
+	base, _ := baseFace(face)
+	advance := verticalCellSize(base)
+	if lf, ok := face.(*layoutFace); ok {
+		advance += lf.lineSpacing
+	}
+	return max(advance, 1)
+}
+
+// textLines は文字列を改行で行に分ける（\r\n の \r は取り除く）
+func textLines(text string) []string {
+	lines := strings.Split(text, "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimSuffix(line, "\r")
+	}
+	return lines
+}
+
+// lineOrigin は n 行の文字列を (x, y) に描画したときの i 行目の描画位置を返す
+// 横書きでは行が下に進む。縦書きでは (x, y) が全体の左上で、1行目を右端の列として左に進む
+func lineOrigin(face font.Face, x, y, i, n int, direction TextDirection) (int, int) {
+	if direction == TextVertical {
+		return x + (n-1-i)*columnAdvance(face), y
+	}
+	return x, y + i*lineAdvance(face)
+}
+
+// horizontalTextExtent は横書きで描画したときの文字列の幅（最も長い行の送り幅）と高さを返す
+func horizontalTextExtent(face font.Face, text string) (int, int) {
+	lines := textLines(text)
+	width := 0
+	for _, line := range lines {
+		width = max(width, font.MeasureString(face, line).Ceil())
+	}
+	return width, (len(lines)-1)*lineAdvance(face) + getFontHeight(face)
+}
+
+// フォントサイズ
+const (
+	defaultFontSize     = 12  // サイズ未指定時のフォントサイズ
+	largeFontThreshold  = 200 // これを超えるサイズは背景塗りつぶし用とみなす
+	largeFontRenderSize = 72  // 大きなサイズが指定されたときに実際の描画に使うサイズ
+)
+
+// fontRenderSize は指定されたフォントサイズから実際の描画に使うサイズを返す
+func fontRenderSize(size int) int {
+	if size <= 0 {
+		return defaultFontSize
+	}
+	if size > largeFontThreshold {
+		return largeFontRenderSize
+	}
+	return size
+}
+
+// getFontHeight はフォントの高さを取得する
+func getFontHeight(face font.Face) int {
+	if face == nil {
+		return 13 // デフォルト値
+	}
+	metrics := face.Metrics()
+	return (metrics.Ascent + metrics.Descent).Ceil()
+}
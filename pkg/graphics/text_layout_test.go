@@ -0,0 +1,146 @@
+//go:build !nogpu
+
+package graphics
+
+import (
+	"image"
+	"image/color"
+	"slices"
+	"testing"
+
+	"golang.org/x/image/font"
+)
+
+func TestTextLayoutClamped(t *testing.T) {
+	got := TextLayout{Tracking: -1000, LineSpacing: 1000}.clamped()
+	if got != (TextLayout{Tracking: -maxTextSpacing, LineSpacing: maxTextSpacing}) {
+		t.Errorf("clamped = %+v", got)
+	}
+	if got := (TextLayout{Tracking: -2, LineSpacing: 3}).clamped(); got != (TextLayout{Tracking: -2, LineSpacing: 3}) {
+		t.Errorf("clamped = %+v, want unchanged", got)
+	}
+}
+
+func TestTextLines(t *testing.T) {
+	if got := textLines("a\r\nb\nc"); !slices.Equal(got, []string{"a", "b", "c"}) {
+		t.Errorf("textLines = %q", got)
+	}
+	if got := textLines(""); !slices.Equal(got, []string{""}) {
+		t.Errorf("textLines(\"\") = %q", got)
+	}
+}
+
+func TestLayoutFace(t *testing.T) {
+	face, err := fixedPitchFallbackFace(16)
+	if err != nil {
+		t.Fatalf("fixedPitchFallbackFace failed: %v", err)
+	}
+	base := font.MeasureString(face, "HHHH").Ceil()
+
+	// 字間は1文字ごとに送り幅に加わる
+	if got := font.MeasureString(newLayoutFace(face, 16, TextLayout{Tracking: 3}), "HHHH").Ceil(); got != base+12 {
+		t.Errorf("width with tracking = %d, want %d", got, base+12)
+	}
+	if got := font.MeasureString(newLayoutFace(face, 16, TextLayout{Tracking: -1}), "HHHH").Ceil(); got != base-4 {
+		t.Errorf("width with negative tracking = %d, want %d", got, base-4)
+	}
+
+	// 行の送りはフォントサイズに行間を加えた値（1ピクセル未満にはならない）
+	if got := lineAdvance(newLayoutFace(face, 16, TextLayout{LineSpacing: -2})); got != 14 {
+		t.Errorf("lineAdvance = %d, want 14", got)
+	}
+	if got := lineAdvance(newLayoutFace(face, 16, TextLayout{LineSpacing: -100})); got != 1 {
+		t.Errorf("lineAdvance = %d, want 1", got)
+	}
+	if got := lineAdvance(face); got != getFontHeight(face) {
+		t.Errorf("lineAdvance without layout = %d, want the font height %d", got, getFontHeight(face))
+	}
+}
+
+func TestMultiLineTextExtent(t *testing.T) {
+	face, err := fixedPitchFallbackFace(16)
+	if err != nil {
+		t.Fatalf("fixedPitchFallbackFace failed: %v", err)
+	}
+	lf := newLayoutFace(face, 16, TextLayout{Tracking: 1, LineSpacing: 4})
+
+	// 横書き: 幅は最も長い行、高さは行の送り（16+4）と1行の高さ
+	width, height := horizontalTextExtent(lf, "ab\r\nabcd")
+	if want := font.MeasureString(lf, "abcd").Ceil(); width != want {
+		t.Errorf("width = %d, want %d", width, want)
+	}
+	if want := 20 + getFontHeight(face); height != want {
+		t.Errorf("height = %d, want %d", height, want)
+	}
+
+	// 縦書き: 列の送り（セル+4）ずつ幅が増え、字間は文字の下に加わる
+	cell := verticalCellSize(face)
+	singleWidth, singleHeight := verticalTextExtent(face, "あ")
+	width, height = verticalTextExtent(lf, "あ\nああ")
+	if width != cell+cell+4 || singleWidth != cell {
+		t.Errorf("vertical width = %d (single %d), want %d", width, singleWidth, 2*cell+4)
+	}
+	if height != 2*(singleHeight+1) {
+		t.Errorf("vertical height = %d, want %d", height, 2*(singleHeight+1))
+	}
+}
+
+func TestDrawMultiLineText(t *testing.T) {
+	face, err := fixedPitchFallbackFace(16)
+	if err != nil {
+		t.Fatalf("fixedPitchFallbackFace failed: %v", err)
+	}
+	lf := newLayoutFace(face, 16, TextLayout{LineSpacing: 4})
+
+	// 横書き: 2行目は1行目の20ピクセル下に描画される
+	img := image.NewRGBA(image.Rect(0, 0, 64, 64))
+	drawText(img, image.Black, lf, 0, 16, "H\nH", TextHorizontal)
+	ink := colorBounds(img, isBlack)
+	single := image.NewRGBA(image.Rect(0, 0, 64, 64))
+	drawText(single, image.Black, lf, 0, 16, "H", TextHorizontal)
+	first := colorBounds(single, isBlack)
+	if ink.Min != first.Min || ink.Max.Y != first.Max.Y+20 {
+		t.Errorf("two lines ink = %v, want %v extended by 20 pixels", ink, first)
+	}
+
+	// 縦書き: 1行目が右の列、2行目が左の列になる
+	img = image.NewRGBA(image.Rect(0, 0, 64, 64))
+	drawHighlightedText(img, color.Black, lf, 0, 0, "あ\nあ", TextVertical, TextHighlight{Count: 1, Color: color.RGBA{R: 0xFF, A: 0xFF}})
+	redInk, blackInk := colorBounds(img, isRed), colorBounds(img, isBlack)
+	if redInk.Empty() || blackInk.Empty() || redInk.Min.X < blackInk.Max.X {
+		t.Errorf("first column %v should be right of second column %v", redInk, blackInk)
+	}
+}
+
+func TestMultiLineHighlight(t *testing.T) {
+	face, err := fixedPitchFallbackFace(16)
+	if err != nil {
+		t.Fatalf("fixedPitchFallbackFace failed: %v", err)
+	}
+	lf := newLayoutFace(face, 16, TextLayout{})
+	red := color.RGBA{R: 0xFF, A: 0xFF}
+
+	// 強調する文字数は改行を含まず、1行目の2文字と2行目の1文字が赤になる
+	img := image.NewRGBA(image.Rect(0, 0, 64, 48))
+	drawHighlightedText(img, color.Black, lf, 0, 16, "HH\nHH", TextHorizontal, TextHighlight{Count: 3, Color: red})
+	redInk, blackInk := colorBounds(img, isRed), colorBounds(img, isBlack)
+	if blackInk.Empty() || blackInk.Min.Y < 16 || blackInk.Min.X <= redInk.Min.X {
+		t.Errorf("black ink %v should be the second character of the second line (red ink %v)", blackInk, redInk)
+	}
+
+	regions := highlightRegions(lf, 0, 16, "HH\nHH", 3, TextHorizontal)
+	if len(regions) != 2 {
+		t.Fatalf("regions = %v, want one per line", regions)
+	}
+	if regions[0].Overlaps(regions[1]) || blackInk.Overlaps(regions[1]) || !redInk.In(regions[0].Union(regions[1])) {
+		t.Errorf("regions %v should cover red ink %v but not black ink %v", regions, redInk, blackInk)
+	}
+}
+
+func TestHeadlessTextExtentWithLayout(t *testing.T) {
+	hgs := NewHeadlessGraphicsSystem()
+	width, height := hgs.TextExtentWithLayout("ab\nabcd", "MS Gothic", 16, PitchDefault, TextLayout{Tracking: 1, LineSpacing: 2})
+	if width != 4*8+4 || height != 16+18 {
+		t.Errorf("extent = %dx%d, want 36x34", width, height)
+	}
+}
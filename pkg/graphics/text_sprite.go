@@ -1,3 +1,5 @@
+//go:build !nogpu
+
 // Package graphics provides text sprite creation with anti-aliasing removal.
 package graphics
 
@@ -9,7 +11,6 @@ import (
 
 	"github.com/hajimehoshi/ebiten/v2"
 	"golang.org/x/image/font"
-	"golang.org/x/image/math/fixed"
 )
 
 // TextSpriteOptions はテキストスプライト作成のオプション
@@ -32,6 +33,10 @@ type TextSpriteOptions struct {
 	X int
 	// Y はテキストのベースラインY座標
 	Y int
+	// Direction は文字列の向き（縦書きでは X, Y が列の左上の座標になる）
+	Direction TextDirection
+	// Highlight は先頭の文字を別の色で描画する指定（カラオケ表示用）
+	Highlight TextHighlight
 }
 
 // TextSpriteOptionsWithBackground はテキストスプライト作成のオプション（背景画像付き）
@@ -73,6 +78,15 @@ func CreateTextSpriteImageWithBackground(opts TextSpriteOptionsWithBackground) *
 	// サイズの自動計算
 	width := opts.Width
 	height := opts.Height
+	if (width == 0 || height == 0) && opts.Direction == TextVertical {
+		columnWidth, columnHeight := verticalTextExtent(opts.Face, opts.Text)
+		if width == 0 {
+			width = columnWidth + opts.X + 10 // 余白を追加
+		}
+		if height == 0 {
+			height = columnHeight + opts.Y + 10 // 余白を追加
+		}
+	}
 	if width == 0 || height == 0 {
 		bounds := measureText(opts.Face, opts.Text)
 		if width == 0 {
@@ -99,14 +113,14 @@ func CreateTextSpriteImageWithBackground(opts TextSpriteOptionsWithBackground) *
 
 	// BackMode=0（背景あり/不透明）の場合、背景色で塗りつぶした不透明なスプライトを作成
 	if opts.BackMode == 0 {
-		return createOpaqueTextSprite(opts.Face, opts.Text, opts.X, opts.Y, width, height, textColor, bgColor)
+		return createOpaqueTextSprite(opts.Face, opts.Text, opts.X, opts.Y, width, height, textColor, bgColor, opts.Direction, opts.Highlight)
 	}
 
 	// BackMode=1（透明）の場合
 
 	// 背景画像が提供された場合、マスク方式を使用
 	if opts.BackgroundImage != nil {
-		return createTextSpriteWithMask(opts.Face, opts.Text, opts.X, opts.Y, width, height, textColor, opts.BackgroundImage)
+		return createTextSpriteWithMask(opts.Face, opts.Text, opts.X, opts.Y, width, height, textColor, opts.BackgroundImage, opts.Direction, opts.Highlight)
 	}
 
 	// 背景画像がない場合は従来の差分抽出方式を使用
@@ -131,13 +145,7 @@ func CreateTextSpriteImageWithBackground(opts TextSpriteOptionsWithBackground) *
 	draw.Draw(bgCopy, bgCopy.Bounds(), bgImg, image.Point{}, draw.Src)
 
 	// 3. テキストを描画
-	drawer := &font.Drawer{
-		Dst:  bgImg,
-		Src:  image.NewUniform(textColor),
-		Face: opts.Face,
-		Dot:  fixed.Point26_6{X: fixed.I(opts.X), Y: fixed.I(opts.Y)},
-	}
-	drawer.DrawString(opts.Text)
+	drawHighlightedText(bgImg, textColor, opts.Face, opts.X, opts.Y, opts.Text, opts.Direction, opts.Highlight)
 
 	// 4. 差分を抽出（背景と異なるピクセルのみを残す）
 	result := image.NewRGBA(image.Rect(0, 0, width, height))
@@ -148,19 +156,13 @@ func CreateTextSpriteImageWithBackground(opts TextSpriteOptionsWithBackground) *
 
 // createOpaqueTextSprite はBackMode=0（背景あり/不透明）用の不透明なテキストスプライトを作成する
 // 背景色で塗りつぶした上にテキストを描画し、完全に不透明なスプライトを返す
-func createOpaqueTextSprite(face font.Face, text string, x, y, width, height int, textColor, bgColor color.Color) *image.RGBA {
+func createOpaqueTextSprite(face font.Face, text string, x, y, width, height int, textColor, bgColor color.Color, direction TextDirection, highlight TextHighlight) *image.RGBA {
 	// 背景色で塗りつぶした画像を作成
 	result := image.NewRGBA(image.Rect(0, 0, width, height))
 	draw.Draw(result, result.Bounds(), image.NewUniform(bgColor), image.Point{}, draw.Src)
 
 	// テキストを描画
-	drawer := &font.Drawer{
-		Dst:  result,
-		Src:  image.NewUniform(textColor),
-		Face: face,
-		Dot:  fixed.Point26_6{X: fixed.I(x), Y: fixed.I(y)},
-	}
-	drawer.DrawString(text)
+	drawHighlightedText(result, textColor, face, x, y, text, direction, highlight)
 
 	return result
 }
@@ -168,24 +170,26 @@ func createOpaqueTextSprite(face font.Face, text string, x, y, width, height int
 // createTextSpriteWithMask はマスク方式でテキストスプライト画像を作成する
 // この方式では、テキストを背景と合成して不透明なスプライトを作成する
 // これにより、同じ位置に異なる色のテキストを重ねても、下のテキストが透けない
-func createTextSpriteWithMask(face font.Face, text string, x, y, width, height int, textColor color.Color, background *image.RGBA) *image.RGBA {
+func createTextSpriteWithMask(face font.Face, text string, x, y, width, height int, textColor color.Color, background *image.RGBA, direction TextDirection, highlight TextHighlight) *image.RGBA {
 	// Step 1: マスク作成（黒字で白背景に描画）
 	maskImg := image.NewRGBA(image.Rect(0, 0, width, height))
 	draw.Draw(maskImg, maskImg.Bounds(), image.NewUniform(color.White), image.Point{}, draw.Src)
 
-	drawer := &font.Drawer{
-		Dst:  maskImg,
-		Src:  image.NewUniform(color.Black),
-		Face: face,
-		Dot:  fixed.Point26_6{X: fixed.I(x), Y: fixed.I(y)},
-	}
-	drawer.DrawString(text)
+	drawText(maskImg, image.NewUniform(color.Black), face, x, y, text, direction)
 
 	// Step 2: 透明度付き色画像を作成
 	// マスクの黒い部分 → 指定色（不透明）
 	// マスクのグレー部分 → 指定色（半透明）
 	// マスクの白い部分 → 透明
 	alphaImg := createAlphaColorImage(maskImg, textColor)
+	if highlight.active() {
+		// 先頭の文字の範囲は強調色にする
+		highlighted := createAlphaColorImage(maskImg, highlight.Color)
+		for _, region := range highlightRegions(face, x, y, text, highlight.Count, direction) {
+			region = region.Intersect(alphaImg.Bounds())
+			draw.Draw(alphaImg, region, highlighted, region.Min, draw.Src)
+		}
+	}
 
 	// Step 3: 背景と合成して不透明な画像にする
 	result := blendWithBackground(alphaImg, background, width, height)
@@ -294,15 +298,20 @@ func extractDifference(bg, text, result *image.RGBA) {
 	}
 }
 
-// measureText はテキストの境界ボックスを計算する
+// measureText はテキストの境界ボックスを計算する（改行を含む場合はすべての行を囲む）
 func measureText(face font.Face, text string) image.Rectangle {
-	bounds, _ := font.BoundString(face, text)
-	return image.Rect(
-		bounds.Min.X.Floor(),
-		bounds.Min.Y.Floor(),
-		bounds.Max.X.Ceil(),
-		bounds.Max.Y.Ceil(),
-	)
+	var rect image.Rectangle
+	for i, line := range textLines(text) {
+		bounds, _ := font.BoundString(face, line)
+		lineRect := image.Rect(
+			bounds.Min.X.Floor(),
+			bounds.Min.Y.Floor(),
+			bounds.Max.X.Ceil(),
+			bounds.Max.Y.Ceil(),
+		).Add(image.Pt(0, i*lineAdvance(face)))
+		rect = rect.Union(lineRect)
+	}
+	return rect
 }
 
 // CreateTextSprite はテキストスプライトを作成してSpriteManagerに登録する
@@ -325,9 +334,10 @@ type TextSprite struct {
 	x, y  int    // 描画位置
 
 	// テキスト設定
-	textColor color.Color // テキスト色
-	bgColor   color.Color // 背景色（差分抽出用）
-	face      font.Face   // フォントフェイス
+	textColor color.Color   // テキスト色
+	bgColor   color.Color   // 背景色（差分抽出用）
+	face      font.Face     // フォントフェイス
+	direction TextDirection // 文字列の向き
 
 	mu sync.RWMutex
 }
@@ -445,6 +455,8 @@ func (tsm *TextSpriteManager) CreateTextSpriteWithParent(
 	zOrder int,
 	parent *Sprite,
 	backMode int,
+	direction TextDirection,
+	highlight TextHighlight,
 ) *TextSprite {
 	tsm.mu.Lock()
 	defer tsm.mu.Unlock()
@@ -487,7 +499,14 @@ func (tsm *TextSpriteManager) CreateTextSpriteWithParent(
 	bounds := measureText(face, text)
 	width := bounds.Dx() + 10  // 余白を追加
 	height := bounds.Dy() + 10 // 余白を追加
-	fontHeight := getFontHeight(face)
+	textY := getFontHeight(face)
+	if direction == TextVertical {
+		// 縦書きでは描画位置が列の左上になる
+		columnWidth, columnHeight := verticalTextExtent(face, text)
+		width = columnWidth + 10
+		height = columnHeight + 10
+		textY = 0
+	}
 
 	// 親スプライトから背景画像を抽出
 	var backgroundImg *image.RGBA
@@ -504,9 +523,11 @@ func (tsm *TextSpriteManager) CreateTextSpriteWithParent(
 			BgColor:   bgColor,
 			BackMode:  backMode,
 			X:         0,
-			Y:         fontHeight,
+			Y:         textY,
 			Width:     width,
 			Height:    height,
+			Direction: direction,
+			Highlight: highlight,
 		},
 		BackgroundImage: backgroundImg,
 	}
@@ -530,6 +551,7 @@ func (tsm *TextSpriteManager) CreateTextSpriteWithParent(
 		textColor: textColor,
 		bgColor:   bgColor,
 		face:      face,
+		direction: direction,
 	}
 
 	// ピクチャIDごとにスプライトを管理
@@ -806,6 +828,10 @@ func (ts *TextSprite) UpdateText(text string) {
 			BgColor:   ts.bgColor,
 			X:         0,
 			Y:         getFontHeight(ts.face),
+			Direction: ts.direction,
+		}
+		if ts.direction == TextVertical {
+			opts.Y = 0
 		}
 
 		img := CreateTextSpriteImage(opts)
@@ -815,15 +841,6 @@ func (ts *TextSprite) UpdateText(text string) {
 	}
 }
 
-// getFontHeight はフォントの高さを取得する
-func getFontHeight(face font.Face) int {
-	if face == nil {
-		return 13 // デフォルト値
-	}
-	metrics := face.Metrics()
-	return (metrics.Ascent + metrics.Descent).Ceil()
-}
-
 // abs は整数の絶対値を返す
 func abs(x int) int {
 	if x < 0 {
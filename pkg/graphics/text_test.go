@@ -1,3 +1,5 @@
+//go:build !nogpu
+
 package graphics
 
 import (
@@ -221,6 +223,52 @@ func TestMeasureText(t *testing.T) {
 	}
 }
 
+func TestTextExtent(t *testing.T) {
+	tr := NewTextRenderer()
+
+	// 現在のフォント（デフォルトはbasicfont 7x13）
+	width, height := tr.TextExtent("Hello", "", 0)
+	if width != 5*7 {
+		t.Errorf("expected width 35 with default font, got %d", width)
+	}
+	if height != 13 {
+		t.Errorf("expected height 13 with default font, got %d", height)
+	}
+
+	// 幅は文字列の長さに応じて増える
+	longer, _ := tr.TextExtent("Hello, World", "", 0)
+	if longer <= width {
+		t.Errorf("expected longer text to be wider: %d <= %d", longer, width)
+	}
+
+	// 存在しないフォントを指定してもフォールバックで計測でき、現在のフォント設定は変わらない
+	fbWidth, fbHeight := tr.TextExtent("Hello", "NonExistentFont", 24)
+	if fbWidth <= 0 || fbHeight <= 0 {
+		t.Errorf("expected positive extent with fallback font, got %dx%d", fbWidth, fbHeight)
+	}
+	if tr.GetFontSettings().Name != "default" {
+		t.Errorf("TextExtent should not change the current font, got %s", tr.GetFontSettings().Name)
+	}
+	if len(tr.measureFaces) != 1 {
+		t.Errorf("expected 1 cached measurement face, got %d", len(tr.measureFaces))
+	}
+}
+
+func TestFontRenderSize(t *testing.T) {
+	tests := []struct{ size, want int }{
+		{0, defaultFontSize},
+		{-1, defaultFontSize},
+		{16, 16},
+		{largeFontThreshold, largeFontThreshold},
+		{640, largeFontRenderSize},
+	}
+	for _, tt := range tests {
+		if got := fontRenderSize(tt.size); got != tt.want {
+			t.Errorf("fontRenderSize(%d) = %d, want %d", tt.size, got, tt.want)
+		}
+	}
+}
+
 func TestFontFallback(t *testing.T) {
 	tr := NewTextRenderer()
 
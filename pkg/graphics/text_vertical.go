@@ -0,0 +1,190 @@
+package graphics
+
+import (
+	"fmt"
+	"image"
+	"image/draw"
+	"strings"
+
+	"golang.org/x/image/font"
+	"golang.org/x/image/math/fixed"
+)
+
+// TextDirection は文字列を描画する向き（横書き・縦書き）
+type TextDirection int
+
+const (
+	// TextHorizontal は横書き（左から右）
+	TextHorizontal TextDirection = 0
+	// TextVertical は縦書き（上から下）。描画位置は列の左上になる
+	TextVertical TextDirection = 1
+)
+
+// String は向きの名前を返す
+func (d TextDirection) String() string {
+	if d == TextVertical {
+		return "vertical"
+	}
+	return "horizontal"
+}
+
+// ParseTextDirection はスクリプトで指定した向き（0, 1 または "horizontal", "vertical"）を解析する
+func ParseTextDirection(v any) (TextDirection, error) {
+	if s, ok := v.(string); ok {
+		switch strings.ToLower(strings.TrimSpace(s)) {
+		case "horizontal":
+			return TextHorizontal, nil
+		case "vertical", "tategaki":
+			return TextVertical, nil
+		}
+		return TextHorizontal, fmt.Errorf("unknown text direction %q (want horizontal or vertical)", s)
+	}
+	n, ok := toIntFromAny(v)
+	if !ok || (n != int(TextHorizontal) && n != int(TextVertical)) {
+		return TextHorizontal, fmt.Errorf("text direction must be 0 (horizontal) or 1 (vertical), got %v", v)
+	}
+	return TextDirection(n), nil
+}
+
+// verticalRotatedRunes は縦書きで90度回転して描画する文字（長音・ダッシュ・波ダッシュ・リーダー・括弧など）
+var verticalRotatedRunes = map[rune]bool{
+	'ー': true, 'ｰ': true, '―': true, '‐': true, '－': true, '—': true,
+	'～': true, '〜': true, '…': true, '‥': true,
+	'（': true, '）': true, '「': true, '」': true, '『': true, '』': true,
+	'【': true, '】': true, '〈': true, '〉': true, '《': true, '》': true,
+	'［': true, '］': true, '｛': true, '｝': true, '〔': true, '〕': true,
+	'＝': true, '：': true, '；': true, '｢': true, '｣': true,
+}
+
+// verticalShift は縦書きで位置をずらす文字と、ずらす量（セルの大きさに対する割合の分母）
+// 句読点はセルの右上に、小書きの仮名は少し右上に寄せる
+var verticalShift = map[rune]int{
+	'、': 2, '。': 2, '，': 2, '．': 2, '､': 2, '｡': 2,
+	'ぁ': 8, 'ぃ': 8, 'ぅ': 8, 'ぇ': 8, 'ぉ': 8, 'っ': 8, 'ゃ': 8, 'ゅ': 8, 'ょ': 8, 'ゎ': 8,
+	'ァ': 8, 'ィ': 8, 'ゥ': 8, 'ェ': 8, 'ォ': 8, 'ッ': 8, 'ャ': 8, 'ュ': 8, 'ョ': 8, 'ヮ': 8,
+	'ヵ': 8, 'ヶ': 8,
+}
+
+// verticalCellSize は縦書きの1文字分のセル（列の幅と全角文字の高さ）の大きさを返す
+func verticalCellSize(face font.Face) int {
+	cell := face.Metrics().Ascent.Ceil()
+	if advance, ok := face.GlyphAdvance('国'); ok {
+		cell = max(cell, advance.Ceil())
+	}
+	return max(cell, 1)
+}
+
+// verticalGlyphHeight は縦書きで r が占める高さを返す
+// 半角文字は横に倒して描画するため、横書きの文字幅が高さになる
+func verticalGlyphHeight(face font.Face, r rune, cell int) int {
+	if !isHalfWidthRune(r) {
+		return cell
+	}
+	advance, ok := face.GlyphAdvance(r)
+	if !ok {
+		return cell / 2
+	}
+	return max(advance.Ceil(), 1)
+}
+
+// verticalTextExtent は縦書きで描画したときの文字列の幅（列の幅の合計）と高さ（最も長い列の高さ）を返す
+func verticalTextExtent(face font.Face, text string) (int, int) {
+	lines := textLines(text)
+	height := 0
+	for _, line := range lines {
+		height = max(height, verticalColumnHeight(face, line))
+	}
+	base, _ := baseFace(face)
+	return verticalCellSize(base) + (len(lines)-1)*columnAdvance(face), height
+}
+
+// verticalColumnHeight は1列の縦書きの文字列の高さ（字間を含む）を返す
+func verticalColumnHeight(face font.Face, text string) int {
+	base, tracking := baseFace(face)
+	cell := verticalCellSize(base)
+	height := 0
+	for _, r := range text {
+		height += verticalGlyphHeight(base, r, cell) + tracking
+	}
+	return height
+}
+
+// drawVerticalText は文字列を (x, y) を左上とする1列の縦書きで描画する
+// 全角文字はセルの中央に正立させ、長音・括弧などは90度回転し、句読点は右上に寄せる。
+// 半角文字（英数字）は縦書きのWindowsのフォントと同じく、右に90度倒して描画する。
+func drawVerticalText(dst draw.Image, src image.Image, face font.Face, x, y int, text string) {
+	face, tracking := baseFace(face)
+	cell := verticalCellSize(face)
+	metrics := face.Metrics()
+	lineHeight := (metrics.Ascent + metrics.Descent).Ceil()
+	baseline := (cell-lineHeight)/2 + metrics.Ascent.Ceil()
+
+	top := y
+	for _, r := range text {
+		// 横書きのまま、幅 width・高さ cell の箱にグリフを描く
+		width := verticalGlyphHeight(face, r, cell)
+		rotate := isHalfWidthRune(r) || verticalRotatedRunes[r]
+		if !rotate {
+			width = cell
+		}
+		glyph := image.NewAlpha(image.Rect(0, 0, width, cell))
+		dot := fixed.P(0, baseline)
+		if advance, ok := face.GlyphAdvance(r); ok {
+			dot.X = (fixed.I(width) - advance) / 2
+		}
+		if div, ok := verticalShift[r]; ok {
+			dot.X += fixed.I(cell / div)
+			dot.Y -= fixed.I(cell / div)
+		}
+		drawer := &font.Drawer{Dst: glyph, Src: image.Opaque, Face: face, Dot: dot}
+		drawer.DrawString(string(r))
+
+		var mask *image.Alpha
+		if rotate {
+			mask = rotateAlphaClockwise(glyph)
+		} else {
+			mask = glyph
+		}
+		rect := mask.Bounds().Add(image.Pt(x, top))
+		draw.DrawMask(dst, rect, src, image.Point{}, mask, image.Point{}, draw.Over)
+		top += mask.Bounds().Dy() + tracking
+	}
+}
+
+// rotateAlphaClockwise は src を時計回りに90度回転した画像を返す
+func rotateAlphaClockwise(src *image.Alpha) *image.Alpha {
+	b := src.Bounds()
+	dst := image.NewAlpha(image.Rect(0, 0, b.Dy(), b.Dx()))
+	for sy := 0; sy < b.Dy(); sy++ {
+		for sx := 0; sx < b.Dx(); sx++ {
+			dst.SetAlpha(b.Dy()-1-sy, sx, src.AlphaAt(b.Min.X+sx, b.Min.Y+sy))
+		}
+	}
+	return dst
+}
+
+// drawText は文字列を dst に描画する
+// 横書きでは (x, y) が1行目のベースラインの開始位置、縦書きでは全体の左上になる
+// 改行を含む文字列は lineOrigin の位置に1行ずつ描画する
+func drawText(dst draw.Image, src image.Image, face font.Face, x, y int, text string, direction TextDirection) {
+	lines := textLines(text)
+	for i, line := range lines {
+		lx, ly := lineOrigin(face, x, y, i, len(lines), direction)
+		drawLine(dst, src, face, lx, ly, line, direction)
+	}
+}
+
+// drawLine は改行を含まない文字列を描画する（位置は drawText の1行目と同じ）
+func drawLine(dst draw.Image, src image.Image, face font.Face, x, y int, text string, direction TextDirection) {
+	if direction == TextVertical {
+		drawVerticalText(dst, src, face, x, y, text)
+		return
+	}
+	drawer := &font.Drawer{
+		Dst:  dst,
+		Src:  src,
+		Face: face,
+		Dot:  fixed.Point26_6{X: fixed.I(x), Y: fixed.I(y)},
+	}
+	drawer.DrawString(text)
+}
@@ -0,0 +1,142 @@
+//go:build !nogpu
+
+package graphics
+
+import (
+	"image"
+	"image/color"
+	"testing"
+
+	"golang.org/x/image/font/basicfont"
+)
+
+func TestParseTextDirection(t *testing.T) {
+	tests := []struct {
+		in      any
+		want    TextDirection
+		wantErr bool
+	}{
+		{int64(0), TextHorizontal, false},
+		{int64(1), TextVertical, false},
+		{"Vertical", TextVertical, false},
+		{"tategaki", TextVertical, false},
+		{"horizontal", TextHorizontal, false},
+		{int64(2), TextHorizontal, true},
+		{"diagonal", TextHorizontal, true},
+		{nil, TextHorizontal, true},
+	}
+	for _, tt := range tests {
+		got, err := ParseTextDirection(tt.in)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ParseTextDirection(%v) error = %v, wantErr %v", tt.in, err, tt.wantErr)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("ParseTextDirection(%v) = %v, want %v", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestRotateAlphaClockwise(t *testing.T) {
+	// 幅3・高さ2の画像の左上の画素は、回転後に右上に来る
+	src := image.NewAlpha(image.Rect(0, 0, 3, 2))
+	src.SetAlpha(0, 0, color.Alpha{A: 0xFF})
+	src.SetAlpha(2, 1, color.Alpha{A: 0x80})
+
+	dst := rotateAlphaClockwise(src)
+	if dst.Bounds().Dx() != 2 || dst.Bounds().Dy() != 3 {
+		t.Fatalf("rotated size = %v, want 2x3", dst.Bounds().Size())
+	}
+	if a := dst.AlphaAt(1, 0).A; a != 0xFF {
+		t.Errorf("top-right alpha = %#x, want 0xFF", a)
+	}
+	if a := dst.AlphaAt(0, 2).A; a != 0x80 {
+		t.Errorf("bottom-left alpha = %#x, want 0x80", a)
+	}
+}
+
+func TestVerticalTextExtent(t *testing.T) {
+	face, err := fixedPitchFallbackFace(16)
+	if err != nil {
+		t.Fatalf("fixedPitchFallbackFace failed: %v", err)
+	}
+	fixedFace := newFixedPitchFace(face, 16)
+
+	// 全角文字は1セル（16）、半角文字は横に倒すため文字幅（8）の高さになる
+	width, height := verticalTextExtent(fixedFace, "あab")
+	if width != 16 || height != 32 {
+		t.Errorf("verticalTextExtent = (%d, %d), want (16, 32)", width, height)
+	}
+	if _, height := verticalTextExtent(fixedFace, ""); height != 0 {
+		t.Errorf("height of empty text = %d, want 0", height)
+	}
+}
+
+// inkBounds は dst の黒い画素の範囲を返す
+func inkBounds(img *image.RGBA) image.Rectangle {
+	var r image.Rectangle
+	b := img.Bounds()
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		for x := b.Min.X; x < b.Max.X; x++ {
+			if img.RGBAAt(x, y).R < 0x80 {
+				r = r.Union(image.Rect(x, y, x+1, y+1))
+			}
+		}
+	}
+	return r
+}
+
+func TestDrawText_Vertical(t *testing.T) {
+	face, err := fixedPitchFallbackFace(32)
+	if err != nil {
+		t.Fatalf("fixedPitchFallbackFace failed: %v", err)
+	}
+	newCanvas := func() *image.RGBA {
+		img := image.NewRGBA(image.Rect(0, 0, 128, 128))
+		for i := range img.Pix {
+			img.Pix[i] = 0xFF
+		}
+		return img
+	}
+
+	// 横書きの "-" は横長、縦書きでは90度倒して縦長になる
+	horizontal := newCanvas()
+	drawText(horizontal, image.Black, face, 0, 32, "-", TextHorizontal)
+	if ink := inkBounds(horizontal); ink.Dx() <= ink.Dy() {
+		t.Errorf("horizontal dash ink = %v, want wider than tall", ink)
+	}
+	vertical := newCanvas()
+	drawText(vertical, image.Black, face, 10, 20, "-", TextVertical)
+	ink := inkBounds(vertical)
+	if ink.Dy() <= ink.Dx() {
+		t.Errorf("vertical dash ink = %v, want taller than wide", ink)
+	}
+	// 列の左上が (10, 20) になる
+	cell := verticalCellSize(face)
+	if !ink.In(image.Rect(10, 20, 10+cell, 20+cell)) {
+		t.Errorf("vertical dash ink = %v, want inside the first cell at (10, 20)", ink)
+	}
+
+	// 文字は上から下に並ぶ
+	column := newCanvas()
+	drawText(column, image.Black, face, 0, 0, "ab", TextVertical)
+	if ink := inkBounds(column); ink.Dy() <= ink.Dx() {
+		t.Errorf("vertical text ink = %v, want a column taller than wide", ink)
+	}
+}
+
+func TestTextRenderer_VerticalExtent(t *testing.T) {
+	tr := NewTextRenderer()
+	tr.SetFont("NonExistentFont", 16)
+	horizontalWidth, _ := tr.TextExtent("abc", "", 0)
+
+	tr.SetTextDirection(TextVertical)
+	if tr.GetTextSettings().Direction != TextVertical {
+		t.Fatal("SetTextDirection did not change the text settings")
+	}
+	width, height := tr.TextExtent("abc", "", 0)
+	cell := verticalCellSize(basicfont.Face7x13)
+	if width != cell || height != horizontalWidth {
+		t.Errorf("vertical TextExtent = (%d, %d), want (%d, %d)", width, height, cell, horizontalWidth)
+	}
+}
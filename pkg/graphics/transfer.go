@@ -1,3 +1,5 @@
+//go:build !nogpu
+
 package graphics
 
 import (
@@ -38,8 +40,10 @@ func (gs *GraphicsSystem) MovePic(
 	dstID, dstX, dstY int,
 	mode int,
 ) error {
+	gs.awaitPictures(srcID, dstID)
 	gs.mu.Lock()
 	defer gs.mu.Unlock()
+	gs.invalidate()
 
 	return gs.movePicInternal(srcID, srcX, srcY, width, height, dstID, dstX, dstY, mode, DefaultSceneChangeSpeed)
 }
@@ -61,8 +65,10 @@ func (gs *GraphicsSystem) MovePicWithSpeed(
 	mode int,
 	speed int,
 ) error {
+	gs.awaitPictures(srcID, dstID)
 	gs.mu.Lock()
 	defer gs.mu.Unlock()
+	gs.invalidate()
 
 	return gs.movePicInternal(srcID, srcX, srcY, width, height, dstID, dstX, dstY, mode, speed)
 }
@@ -120,6 +126,14 @@ func (gs *GraphicsSystem) movePicInternal(
 	srcRect := image.Rect(srcX, srcY, srcX+width, srcY+height)
 	subImg := srcPic.Image.SubImage(srcRect).(*ebiten.Image)
 
+	// @2x の画像にも同じ転送を行う（シーンチェンジは等倍の画像で進めるため、@2x の画像を捨てる）
+	switch TransferMode(mode) {
+	case TransferModeNormal, TransferModeTransparent:
+		gs.transferHires(srcPic, dstID, dstPic, srcRect, dstX, dstY)
+	default:
+		gs.dropHires(dstID, dstPic)
+	}
+
 	// 転送モードに応じて処理
 	switch TransferMode(mode) {
 	case TransferModeNormal:
@@ -417,8 +431,10 @@ func (gs *GraphicsSystem) TransPicInternal(
 	dstID, dstX, dstY int,
 	transColor color.Color,
 ) error {
+	gs.awaitPictures(srcID, dstID)
 	gs.mu.Lock()
 	defer gs.mu.Unlock()
+	gs.invalidate()
 
 	// ソースピクチャーを取得
 	srcPic, err := gs.pictures.GetPicWithoutLock(srcID)
@@ -464,6 +480,7 @@ func (gs *GraphicsSystem) TransPicInternal(
 	subImg := srcPic.Image.SubImage(srcRect).(*ebiten.Image)
 
 	// 透明色を除いて転送
+	gs.transferHires(srcPic, dstID, dstPic, srcRect, dstX, dstY)
 	gs.drawWithTransparency(subImg, dstPic.Image, dstX, dstY, transColor)
 
 	gs.log.Debug("TransPic: transferred with transparency",
@@ -480,8 +497,10 @@ func (gs *GraphicsSystem) ReversePic(
 	srcID, srcX, srcY, width, height int,
 	dstID, dstX, dstY int,
 ) error {
+	gs.awaitPictures(srcID, dstID)
 	gs.mu.Lock()
 	defer gs.mu.Unlock()
+	gs.invalidate()
 
 	// ソースピクチャーを取得
 	srcPic, err := gs.pictures.GetPicWithoutLock(srcID)
@@ -526,6 +545,7 @@ func (gs *GraphicsSystem) ReversePic(
 	opts.GeoM.Translate(float64(width), 0)            // 反転後の位置を補正
 	opts.GeoM.Translate(float64(dstX), float64(dstY)) // 転送先に移動
 
+	gs.dropHires(dstID, dstPic)
 	dstPic.Image.DrawImage(subImg, opts)
 
 	gs.log.Debug("ReversePic: transferred with horizontal flip",
@@ -542,8 +562,10 @@ func (gs *GraphicsSystem) MoveSPic(
 	srcID, srcX, srcY, srcW, srcH int,
 	dstID, dstX, dstY, dstW, dstH int,
 ) error {
+	gs.awaitPictures(srcID, dstID)
 	gs.mu.Lock()
 	defer gs.mu.Unlock()
+	gs.invalidate()
 
 	// ソースピクチャーを取得
 	srcPic, err := gs.pictures.GetPicWithoutLock(srcID)
@@ -600,6 +622,7 @@ func (gs *GraphicsSystem) MoveSPic(
 	opts.GeoM.Translate(float64(dstX), float64(dstY))
 	opts.Filter = ebiten.FilterLinear // 線形補間でスムーズにスケーリング
 
+	gs.dropHires(dstID, dstPic)
 	dstPic.Image.DrawImage(subImg, opts)
 
 	gs.log.Debug("MoveSPic: scaled transfer",
@@ -214,6 +214,42 @@ func (wm *WindowManager) GetWindowsOrdered() []*Window {
 	return windows
 }
 
+// BringToFront はウィンドウを最前面に移動し、新しいZ順序を返す
+func (wm *WindowManager) BringToFront(id int) (int, error) {
+	wm.mu.Lock()
+	defer wm.mu.Unlock()
+
+	win, exists := wm.windows[id]
+	if !exists {
+		return 0, fmt.Errorf("window not found: %d", id)
+	}
+
+	win.ZOrder = wm.nextZOrder
+	wm.nextZOrder++
+	return win.ZOrder, nil
+}
+
+// SendToBack はウィンドウを最背面に移動し、新しいZ順序を返す
+// 他のウィンドウの最小のZ順序より1小さい値を設定する
+func (wm *WindowManager) SendToBack(id int) (int, error) {
+	wm.mu.Lock()
+	defer wm.mu.Unlock()
+
+	win, exists := wm.windows[id]
+	if !exists {
+		return 0, fmt.Errorf("window not found: %d", id)
+	}
+
+	minZOrder := win.ZOrder
+	for _, other := range wm.windows {
+		if other.ID != id && other.ZOrder <= minZOrder {
+			minZOrder = other.ZOrder - 1
+		}
+	}
+	win.ZOrder = minZOrder
+	return win.ZOrder, nil
+}
+
 // CapTitle はウィンドウのキャプションを設定する
 // 受け入れ基準 3.8
 func (wm *WindowManager) CapTitle(id int, title string) error {
@@ -1,3 +1,5 @@
+//go:build !nogpu
+
 // Package graphics provides sprite-based rendering system.
 package graphics
 
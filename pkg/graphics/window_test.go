@@ -587,3 +587,42 @@ func TestGetWinByPicIDMultipleWindowsAfterClose(t *testing.T) {
 		t.Errorf("Expected window ID %d (remaining window), got %d", winID1, foundWinID)
 	}
 }
+
+func TestWindowManagerRestack(t *testing.T) {
+	wm := NewWindowManager()
+	win0, _ := wm.OpenWin(0)
+	win1, _ := wm.OpenWin(1)
+	win2, _ := wm.OpenWin(2)
+
+	order := func() []int {
+		ids := []int{}
+		for _, win := range wm.GetWindowsOrdered() {
+			ids = append(ids, win.ID)
+		}
+		return ids
+	}
+
+	if _, err := wm.BringToFront(win0); err != nil {
+		t.Fatalf("BringToFront failed: %v", err)
+	}
+	if got := order(); got[0] != win1 || got[1] != win2 || got[2] != win0 {
+		t.Errorf("order after BringToFront = %v, want [%d %d %d]", got, win1, win2, win0)
+	}
+
+	if _, err := wm.SendToBack(win2); err != nil {
+		t.Fatalf("SendToBack failed: %v", err)
+	}
+	if got := order(); got[0] != win2 || got[1] != win1 || got[2] != win0 {
+		t.Errorf("order after SendToBack = %v, want [%d %d %d]", got, win2, win1, win0)
+	}
+
+	// 新しいウィンドウは最前面に開く
+	win3, _ := wm.OpenWin(3)
+	if got := order(); got[3] != win3 {
+		t.Errorf("new window should open in front, order = %v", got)
+	}
+
+	if _, err := wm.BringToFront(99); err == nil {
+		t.Error("expected error for unknown window")
+	}
+}
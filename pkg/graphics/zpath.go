@@ -312,3 +312,24 @@ func (c *ZOrderCounter) GetCurrentCount(parentID int) int {
 	defer c.mu.RUnlock()
 	return c.counters[parentID]
 }
+
+// restack は奥から手前の順に並べたIDの列 ids の中で、target を最前面（toFront）または最背面に移動した列を返す
+// target が ids に含まれない場合は nil を返す
+func restack(ids []int, target int, toFront bool) []int {
+	result := make([]int, 0, len(ids))
+	found := false
+	for _, id := range ids {
+		if id == target {
+			found = true
+			continue
+		}
+		result = append(result, id)
+	}
+	if !found {
+		return nil
+	}
+	if toFront {
+		return append(result, target)
+	}
+	return append([]int{target}, result...)
+}
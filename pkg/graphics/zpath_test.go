@@ -936,3 +936,34 @@ func TestZOrderCounter_Reset_NonExistentParent(t *testing.T) {
 		t.Errorf("GetNext(999) after Reset = %d, want 0", result)
 	}
 }
+
+// TestRestack tests moving an ID to the front or back of a back-to-front list.
+func TestRestack(t *testing.T) {
+	tests := []struct {
+		name    string
+		ids     []int
+		target  int
+		toFront bool
+		want    []int
+	}{
+		{"to front", []int{1, 2, 3}, 1, true, []int{2, 3, 1}},
+		{"to back", []int{1, 2, 3}, 3, false, []int{3, 1, 2}},
+		{"already in front", []int{1, 2, 3}, 3, true, []int{1, 2, 3}},
+		{"single", []int{5}, 5, false, []int{5}},
+		{"not found", []int{1, 2}, 9, true, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := restack(tt.ids, tt.target, tt.toFront)
+			if len(got) != len(tt.want) {
+				t.Fatalf("restack = %v, want %v", got, tt.want)
+			}
+			for i := range tt.want {
+				if got[i] != tt.want[i] {
+					t.Errorf("restack = %v, want %v", got, tt.want)
+					break
+				}
+			}
+		})
+	}
+}
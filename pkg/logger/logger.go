@@ -2,6 +2,7 @@ package logger
 
 import (
 	"fmt"
+	"io"
 	"log/slog"
 	"os"
 )
@@ -10,6 +11,12 @@ var globalLogger *slog.Logger
 
 // InitLogger ログレベルに応じてslogを初期化
 func InitLogger(level string) error {
+	return InitLoggerWithWriter(level, os.Stdout)
+}
+
+// InitLoggerWithWriter ログレベルに応じてslogを初期化し、ログを w に書き込む
+// 標準出力をプロトコルに使用するモード（son-et lsp）では標準エラー出力を指定する
+func InitLoggerWithWriter(level string, w io.Writer) error {
 	var slogLevel slog.Level
 
 	switch level {
@@ -25,7 +32,7 @@ func InitLogger(level string) error {
 		return fmt.Errorf("invalid log level: %s", level)
 	}
 
-	handler := slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{
+	handler := slog.NewTextHandler(w, &slog.HandlerOptions{
 		Level: slogLevel,
 	})
 
@@ -1,7 +1,9 @@
 package logger
 
 import (
+	"bytes"
 	"log/slog"
+	"strings"
 	"testing"
 )
 
@@ -68,3 +70,20 @@ func TestGetLogger_AfterInit(t *testing.T) {
 		t.Error("GetLogger() should return the initialized logger")
 	}
 }
+
+func TestInitLoggerWithWriter(t *testing.T) {
+	var buf bytes.Buffer
+	if err := InitLoggerWithWriter("warn", &buf); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	GetLogger().Info("hidden")
+	GetLogger().Warn("shown")
+
+	if strings.Contains(buf.String(), "hidden") {
+		t.Error("info message should be filtered at warn level")
+	}
+	if !strings.Contains(buf.String(), "shown") {
+		t.Errorf("warn message should be written to the writer, got %q", buf.String())
+	}
+}
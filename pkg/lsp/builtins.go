@@ -0,0 +1,238 @@
+package lsp
+
+import (
+	"fmt"
+	"strings"
+)
+
+// builtinDoc は組み込み関数のホバー表示用ドキュメント
+type builtinDoc struct {
+	signatures []string // 呼び出し形式（複数の引数形式がある場合は複数）
+	summary    string   // 説明（docs/language-spec.md に合わせる）
+}
+
+// builtinDocs は組み込み関数のドキュメント（キーは小文字の関数名）
+// VMに登録されている組み込み関数に合わせて保守する
+var builtinDocs = map[string]builtinDoc{
+	// ウィンドウ
+	"openwin":     {[]string{"win_id = OpenWin(pic)", "win_id = OpenWin(pic, x, y, width, height, pic_x, pic_y, color)"}, "仮想デスクトップ上に仮想ウインドウを開く。戻り値はウィンドウID"},
+	"movewin":     {[]string{"MoveWin(win, pic, x, y, width, height, pic_x, pic_y)", "MoveWin(win, pic)"}, "仮想ウインドウの設定を変更"},
+	"closewin":    {[]string{"CloseWin(win_no)"}, "仮想ウインドウを閉じる"},
+	"closewinall": {[]string{"CloseWinAll()"}, "すべての仮想ウインドウを閉じる"},
+	"captitle":    {[]string{"CapTitle(win_no, title)"}, "ウィンドウのキャプションの文字を指定"},
+	"getpicno":    {[]string{"pic_no = GetPicNo(win_no)"}, "ウィンドウに関連付けされたピクチャー番号を得る"},
+	"wininfo":     {[]string{"width = WinInfo(0)", "height = WinInfo(1)"}, "ウィンドウ情報（デスクトップの幅・高さ）の取得"},
+
+	// ピクチャー
+	"loadpic":      {[]string{"pic_id = LoadPic(filename)"}, "画像ファイルの読み込み。戻り値はピクチャーID"},
+	"loadpicasync": {[]string{"pic_id = LoadPicAsync(filename)"}, "画像ファイルをデコードの完了を待たずに読み込む。完了時に PIC_READY を送信（son-et拡張）"},
+	"createpic":    {[]string{"pic_id = CreatePic(pic_no, width, height)"}, "ピクチャーの生成"},
+	"movepic":      {[]string{"MovePic(src_pic, dst_pic)", "MovePic(src_pic, src_x, src_y, width, height, dst_pic, dst_x, dst_y, mode, speed)"}, "画像データの転送"},
+	"movespic":     {[]string{"MoveSPic(src_pic, src_x, src_y, src_w, src_h, dst_pic, dst_x, dst_y, dst_w, dst_h)"}, "画像データを拡大縮小して転送"},
+	"transpic":     {[]string{"TransPic(src_pic, src_x, src_y, width, height, dst_pic, dst_x, dst_y, trans_color)"}, "透明色を指定して画像データを転送"},
+	"reversepic":   {[]string{"ReversePic(src_pic, src_x, src_y, width, height, dst_pic, dst_x, dst_y)"}, "左右反転イメージの転写"},
+	"delpic":       {[]string{"DelPic(pic_no)"}, "画像データの破棄"},
+	"picwidth":     {[]string{"width = PicWidth(pic_no)"}, "ピクチャーの幅の取得"},
+	"picheight":    {[]string{"height = PicHeight(pic_no)"}, "ピクチャーの高さの取得"},
+
+	// キャスト
+	"putcast":       {[]string{"cast_id = PutCast(pic_no, base_pic, x, y)", "cast_id = PutCast(pic_no, base_pic, x, y, trans_color, ?, ?, ?, width, height, src_x, src_y)"}, "キャストの配置。戻り値はキャストID"},
+	"movecast":      {[]string{"MoveCast(cast_no, x, y)", "MoveCast(cast_no, pic_no, x, y)", "MoveCast(cast_no, x, y, src_x, src_y, width, height)"}, "キャストの移動"},
+	"delcast":       {[]string{"DelCast(cast_no)"}, "キャストの削除"},
+	"putflipbook":   {[]string{`cast_id = PutFlipbook("anim.gif", base_pic, x, y)`, `cast_id = PutFlipbook("anim.gif", base_pic, x, y, clock, ticks)`}, "アニメーションGIFをキャストとして配置し、フレームをティック（TIME または MIDI_TIME）で進める。DelCast でフレームも削除する（son-et拡張）"},
+	"changepicture": {[]string{"ChangePicture(cast_no, pic_no)", "ChangePicture(cast_no, pic_no, ticks)"}, "キャストの絵を別のピクチャー全体に差し替える。ticks を指定するとTIMEのティック数をかけてクロスフェードする（son-et拡張）"},
+
+	// スプライトプール
+	"createspritepool": {[]string{"pool_no = CreateSpritePool(pic_no, base_pic, count)", "pool_no = CreateSpritePool(pic_no, base_pic, count, trans_color)"}, "同じ画像の粒子をまとめて描画するスプライトプールを作成（粒子は非表示で作成される）"},
+	"setpoolsprite":    {[]string{"SetPoolSprite(pool_no, index, x, y)", "SetPoolSprite(pool_no, index, x, y, visible)"}, "スプライトプールの粒子（0〜count-1）の位置と表示を設定"},
+	"scatterpool":      {[]string{"ScatterPool(pool_no, x, y, width, height)"}, "スプライトプールのすべての粒子を矩形内のランダムな位置に表示"},
+	"setpoolvelocity":  {[]string{"SetPoolVelocity(pool_no, vx, vy)", "SetPoolVelocity(pool_no, vx, vy, jitter)"}, "StepPoolで粒子が進む量を設定。jitter を指定すると粒子ごとにばらつかせる"},
+	"steppool":         {[]string{"StepPool(pool_no)"}, "すべての粒子を速度の分だけ進める。画面外に出た粒子は反対側から入ってくる"},
+	"delspritepool":    {[]string{"DelSpritePool(pool_no)"}, "スプライトプールの削除"},
+
+	// マスク
+	"setcastmask":    {[]string{"SetCastMask(cast_no, x, y, width, height)"}, "キャストの矩形の範囲だけを表示する（座標はキャストの左上から）"},
+	"setcastmaskpic": {[]string{"SetCastMaskPic(cast_no, pic_no)", "SetCastMaskPic(cast_no, pic_no, x, y)"}, "ピクチャーの明るさをキャストの不透明度として使う（白は表示、黒は非表示）"},
+	"delcastmask":    {[]string{"DelCastMask(cast_no)"}, "キャストのマスクを解除"},
+	"setwinmask":     {[]string{"SetWinMask(win_no, x, y, width, height)"}, "ウィンドウと中のキャストの矩形の範囲だけを表示する"},
+	"setwinmaskpic":  {[]string{"SetWinMaskPic(win_no, pic_no)", "SetWinMaskPic(win_no, pic_no, x, y)"}, "ピクチャーの明るさをウィンドウの不透明度として使う（白は表示、黒は非表示）"},
+	"delwinmask":     {[]string{"DelWinMask(win_no)"}, "ウィンドウのマスクを解除"},
+	"setshadow":      {[]string{"SetShadow(cast_no, dx, dy, color, alpha)"}, "キャストの下に(dx, dy)ずらした影を描画する（alphaは0〜255、son-et拡張）"},
+	"delshadow":      {[]string{"DelShadow(cast_no)"}, "キャストの影を解除"},
+	"setoutline":     {[]string{"SetOutline(cast_no, color, width)"}, "キャストの不透明な部分の周囲を縁取る（widthは1〜8、son-et拡張）"},
+	"deloutline":     {[]string{"DelOutline(cast_no)"}, "キャストの縁取りを解除"},
+	"shake":          {[]string{"Shake(cast_no, amplitude, ticks)"}, "キャストを揺らし、ticksをかけて元の位置に戻す（son-et拡張）"},
+	"flash":          {[]string{"Flash(cast_no, color, ticks)"}, "キャストを色で塗りつぶし、ticksをかけて元の絵に戻す（son-et拡張）"},
+	"definepath":     {[]string{"path_id = DefinePath(x1, y1, x2, y2, ...)", "path_id = DefinePath(kind, x1, y1, x2, y2, ...)"}, "点を通る曲線（\"catmullrom\"）またはベジェ曲線（\"bezier\"）のパスを定義（son-et拡張）"},
+	"delpath":        {[]string{"DelPath(path_id)"}, "パスを削除（son-et拡張）"},
+	"movealongpath":  {[]string{"MoveAlongPath(cast_no, path_id, ticks)", "MoveAlongPath(cast_no, path_id, ticks, easing)"}, "キャストをticksをかけてパスに沿って動かす（son-et拡張）"},
+
+	// カメラ
+	"setcamera":  {[]string{"SetCamera(x, y, zoom)", "SetCamera()"}, "仮想デスクトップの(x, y)を画面の中央にzoom倍で表示する。引数なしで元に戻す（son-et拡張）"},
+	"cameramove": {[]string{"CameraMove(x, y, zoom, ticks)", "CameraMove(x, y, zoom, ticks, easing)"}, "ticksをかけてカメラを動かす（son-et拡張）"},
+
+	// 描画範囲・9分割
+	"setsourcerect": {[]string{"SetSourceRect(cast_no, x, y, width, height)"}, "キャストの画像のうち(x, y)からwidth×heightの範囲だけを描画する（son-et拡張）"},
+	"delsourcerect": {[]string{"DelSourceRect(cast_no)"}, "キャストの描画範囲を解除し、画像全体を描画する"},
+	"setnineslice":  {[]string{"SetNineSlice(cast_no, left, top, right, bottom, width, height)"}, "キャストの画像を9分割し、四隅を伸縮せずにwidth×heightの大きさで描画する（son-et拡張）"},
+	"delnineslice":  {[]string{"DelNineSlice(cast_no)"}, "キャストの9分割の伸縮を解除"},
+
+	// マウスカーソル
+	"setcursor":      {[]string{"SetCursor(pic_no)", "SetCursor(pic_no, hot_x, hot_y)", "SetCursor(pic_no, hot_x, hot_y, trans_color)"}, "ピクチャーをマウスカーソルとして表示する（(hot_x, hot_y) がマウスの位置に来る）。システムのカーソルは非表示になる"},
+	"setcursorclick": {[]string{"SetCursorClick(pic_no, frames)", "SetCursorClick(pic_no, frames, ticks)"}, "左ボタンを押したときのカーソルのアニメーション。ピクチャーを横に frames 等分したコマを ticks フレームずつ再生（frames が0で解除）"},
+	"delcursor":      {[]string{"DelCursor()"}, "カスタムカーソルを解除し、システムのカーソルに戻す"},
+	"showsyscursor":  {[]string{"ShowSysCursor(flag)"}, "システムのマウスカーソルを表示（1）・非表示（0）にする"},
+
+	// アプリケーションウィンドウ
+	"setwindowtitle":  {[]string{"SetWindowTitle(title)"}, "アプリケーションのウィンドウのタイトルを設定する"},
+	"setwindowicon":   {[]string{"SetWindowIcon(filename)"}, "画像ファイル（BMP/PNG）をアプリケーションのウィンドウのアイコンにする"},
+	"setwindowsize":   {[]string{"SetWindowSize(width, height)"}, "アプリケーションのウィンドウの大きさを設定する（仮想デスクトップは拡大・縮小して表示）"},
+	"getdisplayscale": {[]string{"GetDisplayScale()"}, "実効の表示スケールをパーセントで返す（100で等倍、高DPIの画面や大きいウィンドウでは大きくなる）"},
+
+	// 実行環境の問い合わせ
+	"getscreenwidth":  {[]string{"GetScreenWidth()"}, "仮想デスクトップの幅を返す（WinInfo(0)と同じ、son-et拡張）"},
+	"getscreenheight": {[]string{"GetScreenHeight()"}, "仮想デスクトップの高さを返す（WinInfo(1)と同じ、son-et拡張）"},
+	"getplatform":     {[]string{"GetPlatform()"}, "実行しているOSを \"windows\"・\"darwin\"・\"linux\"・\"js\" などの文字列で返す（son-et拡張）"},
+	"isheadless":      {[]string{"IsHeadless()"}, "ヘッドレスモード（--headless）で実行している場合は1、GUIモードでは0を返す（son-et拡張）"},
+	"getlang":         {[]string{"GetLang()"}, "ユーザーの言語を \"ja\"・\"en\" などの言語コードで返す（son-et拡張）"},
+	"hassoundfont":    {[]string{"HasSoundFont()"}, "SoundFontが読み込まれていてMIDIを再生できる場合は1、できない場合は0を返す（son-et拡張）"},
+
+	// 画像の書き出し
+	"savesprite": {[]string{"SaveSprite(path)", "SaveSprite(path, cast_id)"}, "画面全体またはキャストの画像を出力ディレクトリ（--output-dir）にPNGで書き出す。予約できた場合は1を返す（son-et拡張）"},
+
+	// 文字表示
+	"setfont":       {[]string{"SetFont(size, font_name, charset, avg_width, escapement, orientation, weight, italic, underline, strikeout)"}, "フォントの設定"},
+	"textwrite":     {[]string{"TextWrite(text, pic_no, x, y)", "TextWrite(text, pic_no, x, y, pitch)", "TextWrite(text, pic_no, x, y, pitch, tracking, line_spacing)"}, "文字列の描画（pitch: \"fixed\" で固定ピッチ、\"proportional\" でプロポーショナル。tracking, line_spacing: 字間と行間のピクセル数）"},
+	"textwidth":     {[]string{"w = TextWidth(text)", "w = TextWidth(text, font_name, size)", "w = TextWidth(text, font_name, size, pitch)", "w = TextWidth(text, font_name, size, pitch, tracking, line_spacing)"}, "文字列の幅（ピクセル）を取得"},
+	"textheight":    {[]string{"h = TextHeight(text)", "h = TextHeight(text, font_name, size)", "h = TextHeight(text, font_name, size, pitch)", "h = TextHeight(text, font_name, size, pitch, tracking, line_spacing)"}, "文字列の高さ（ピクセル）を取得"},
+	"textcolor":     {[]string{"TextColor(color)"}, "文字色の設定"},
+	"bgcolor":       {[]string{"BgColor(color)"}, "背景色の設定"},
+	"backmode":      {[]string{"BackMode(mode)"}, "背景モードの設定"},
+	"textdirection": {[]string{"TextDirection(dir)"}, "文字列の向きの設定（0=横書き, 1=縦書き、son-et拡張）"},
+
+	// 描画
+	"drawline":      {[]string{"DrawLine(pic_no, x1, y1, x2, y2)"}, "直線の描画"},
+	"drawcircle":    {[]string{"DrawCircle(pic_no, x, y, radius, fill_mode)"}, "円の描画"},
+	"drawrect":      {[]string{"DrawRect(pic_no, x1, y1, x2, y2, fill_mode)"}, "矩形の描画"},
+	"fillrect":      {[]string{"FillRect(pic_no, x1, y1, x2, y2, color)"}, "矩形を指定色で塗りつぶす"},
+	"setlinesize":   {[]string{"SetLineSize(size)"}, "線の太さの設定"},
+	"setpaintcolor": {[]string{"SetPaintColor(color)"}, "描画色の設定"},
+	"setcolor":      {[]string{"SetColor(color)"}, "描画色の設定（SetPaintColor の別名）"},
+	"getcolor":      {[]string{"color = GetColor(pic_no, x, y)"}, "ピクセルの色の取得"},
+	"fadeout":       {[]string{"FadeOut(ticks)", "FadeOut(ticks, color)"}, "画面全体を指定色（省略時は黒）にフェードアウト。完了時に FADE_END を送信"},
+	"fadein":        {[]string{"FadeIn(ticks)", "FadeIn(ticks, color)"}, "画面全体を指定色（省略時は黒）からフェードイン。完了時に FADE_END を送信"},
+
+	// 文字列
+	"strlen":   {[]string{"length = StrLen(str)"}, "文字列の長さを取得"},
+	"substr":   {[]string{"substr = SubStr(str, start, length)"}, "部分文字列の取得"},
+	"strfind":  {[]string{"pos = StrFind(str, search_str)"}, "文字列の検索。見つからない場合は-1"},
+	"strprint": {[]string{"result = StrPrint(format, arg1, arg2, ...)"}, "書式付き文字列の生成"},
+	"charcode": {[]string{"code = CharCode(str, index)"}, "文字コードの取得"},
+	"strcode":  {[]string{"str = StrCode(code)"}, "文字コードから文字列を生成"},
+	"strup":    {[]string{"upper = StrUp(str)"}, "大文字に変換"},
+	"strlow":   {[]string{"lower = StrLow(str)"}, "小文字に変換"},
+
+	// ファイル
+	"writeiniint": {[]string{"WriteIniInt(filename, section, key, value)"}, "INIファイルに整数を書き込み"},
+	"getiniint":   {[]string{"value = GetIniInt(filename, section, key, default_value)"}, "INIファイルから整数を読み込み"},
+	"writeinistr": {[]string{"WriteIniStr(filename, section, key, value)"}, "INIファイルに文字列を書き込み"},
+	"getinistr":   {[]string{"value = GetIniStr(filename, section, key, default_value)"}, "INIファイルから文字列を読み込み"},
+	"openf":       {[]string{"handle = OpenF(filename)", "handle = OpenF(filename, mode)"}, "ファイルを開く"},
+	"closef":      {[]string{"CloseF(handle)"}, "ファイルを閉じてハンドルを解放する"},
+	"readf":       {[]string{"value = ReadF(handle, size)"}, "ファイルからバイナリデータを読み込む"},
+	"writef":      {[]string{"WriteF(handle, value)", "WriteF(handle, value, length)"}, "ファイルにバイナリデータを書き込む"},
+	"seekf":       {[]string{"pos = SeekF(handle, offset, origin)"}, "ファイルポインタの移動"},
+	"strreadf":    {[]string{"str = StrReadF(handle)"}, "ファイルから文字列を1行読み込む"},
+	"strwritef":   {[]string{"StrWriteF(handle, str)"}, "ファイルに文字列を書き込む"},
+
+	// 配列
+	"arraysize":   {[]string{"size = ArraySize(array)"}, "配列のサイズを取得"},
+	"delarrayall": {[]string{"DelArrayAll(array)"}, "配列の全要素を削除"},
+	"delarrayat":  {[]string{"DelArrayAt(array, index)"}, "配列の指定位置の要素を削除"},
+	"insarrayat":  {[]string{"InsArrayAt(array, index, value)"}, "配列の指定位置に要素を挿入"},
+
+	// 整数
+	"random":     {[]string{"value = Random(max)"}, "乱数の生成"},
+	"makelong":   {[]string{"long_value = MakeLong(low_word, high_word)"}, "2つの16ビット値を32ビット値に結合"},
+	"gethiword":  {[]string{"high_word = GetHiWord(long_value)"}, "32ビット値の上位16ビットを取得"},
+	"getlowword": {[]string{"low_word = GetLowWord(long_value)"}, "32ビット値の下位16ビットを取得"},
+
+	// オーディオ
+	"playmidi":        {[]string{"PlayMIDI(filename)"}, "MIDIファイルの再生"},
+	"playwave":        {[]string{"PlayWAVE(filename)"}, "WAVファイルの再生"},
+	"setvolume":       {[]string{"SetVolume(volume)", `SetVolume("bus", volume)`}, "ミキサーのバスの音量を0〜100で設定する。bus は master（既定）、music（MIDI）、sfx（WAV）、voice（PlayVoice）"},
+	"getvolume":       {[]string{"volume = GetVolume()", `volume = GetVolume("bus")`}, "ミキサーのバスの音量（0〜100）を取得"},
+	"setmute":         {[]string{"SetMute(flag)", `SetMute("bus", flag)`}, "ミキサーのバスをミュート（1）または解除（0）する。音量は保持される"},
+	"playvoice":       {[]string{"PlayVoice(filename)"}, "WAVファイルを音声（voice）のバスで再生する。SetDucking を設定すると再生中は音楽の音量が下がる（son-et拡張）"},
+	"setducking":      {[]string{"SetDucking(depth_db)", "SetDucking(depth_db, attack_ms, release_ms)"}, "音声（PlayVoice）の再生中に音楽（MIDI）の音量をdepth_dbデシベル下げる。0で無効（son-et拡張）"},
+	"setsynthquality": {[]string{"polyphony = SetSynthQuality(effects)", "polyphony = SetSynthQuality(effects, polyphony)"}, "MIDIのリバーブ・コーラス（effects）と同時発音数を設定する。次のPlayMIDIから適用（son-et拡張）"},
+
+	// MIDIポート
+	"playmidiport": {[]string{`PlayMIDIPort("port", filename)`}, "名前を付けたポートでMIDIファイルを再生する。他のポートの再生は止まらない（\"main\" は PlayMIDI と同じ）"},
+	"stopmidiport": {[]string{`StopMIDIPort("port")`}, "ポートのMIDI再生を停止"},
+	"midiclock":    {[]string{`tick = MIDIClock("port")`}, "ポートの現在のティック（16分音符単位）を取得。再生中でない場合は -1"},
+	"setmidiclock": {[]string{`SetMIDIClock("port")`}, "MIDI_TIME を送るポートを選ぶ。そのポートが再生中でない間は \"main\" が送る"},
+	"oscsend":      {[]string{`OSCSend("host:port", "/address", args...)`}, "OSCメッセージをUDPで送る。送信先は --osc-allow で許可したものだけ。送れた場合は1を返す（son-et拡張）"},
+
+	// メッセージ
+	"getmesno":        {[]string{"mes_no = GetMesNo()"}, "現在のメッセージ番号を取得"},
+	"delmes":          {[]string{"DelMes(mes_no)"}, "指定したメッセージブロックを削除"},
+	"freezemes":       {[]string{"FreezeMes(mes_no)"}, "メッセージブロックを一時停止"},
+	"activatemes":     {[]string{"ActivateMes(mes_no)"}, "メッセージブロックを再開"},
+	"settickpolicy":   {[]string{"SetTickPolicy(mes_no, policy)"}, "処理が追いつかないときのティック（TIME/MIDI_TIME）の扱いを設定（0=すべて処理, 1=最新にまとめる, 2=捨てる、son-et拡張）"},
+	"getdroppedticks": {[]string{"n = GetDroppedTicks(mes_no)"}, "ティックの扱いの設定によって実行しなかったティックの数を取得（son-et拡張）"},
+	"postmes":         {[]string{"PostMes(mes_type, p1, p2, p3, p4)"}, "カスタムメッセージの送信"},
+	"settimer":        {[]string{`timer_id = SetTimer(ms, "FuncName")`, `timer_id = SetTimer(ms, "FuncName", repeat)`}, "ms ミリ秒ごとに FuncName(timer_id) を呼び出す。repeat に0を指定すると1回だけ呼び出す（son-et拡張）"},
+	"killtimer":       {[]string{"KillTimer(timer_id)"}, "SetTimer で設定したタイマーを止める。止めた場合は1を返す（son-et拡張）"},
+	"spawn":           {[]string{`id = Spawn("FuncName", args...)`}, "FuncName(args...) の本体を新しい mes(TIME) のシーケンスとして起動し、シーケンス番号を返す（son-et拡張）"},
+	"kill":            {[]string{"Kill(id)"}, "Spawn で起動したシーケンスを止める。止めた場合は1を返す（son-et拡張）"},
+	"sendmes":         {[]string{"SendMes(id, p1, p2, p3, p4)"}, "指定したシーケンスだけに USER メッセージを送る。WaitAny(..., \"USER\") で受け取る（son-et拡張）"},
+	"wait":            {[]string{"Wait(n)"}, "n 回分のイベントを待つ（mes(MIDI_TIME) 内では n 回の MIDI_TIME イベント）"},
+	"waitany":         {[]string{`r = WaitAny(ticks, "KEY", "USER", "MIDI_END")`}, "ticks 回分のイベントか、指定したイベントのどれかを待ち、起きた条件を返す（0=ティック, 1以降=イベントの順番、son-et拡張）"},
+	"getwaitresult":   {[]string{"r = GetWaitResult()"}, "最後の WaitAny で起きた条件を取得（son-et拡張）"},
+
+	// 入力イベント
+	"onkey":         {[]string{`OnKey("SPACE", "FuncName")`, `OnKey(key, "FuncName", repeat)`}, "キーが押されたときに FuncName(key, mods) を呼び出す。戻り値はハンドラ番号"},
+	"onclick":       {[]string{`OnClick("FuncName")`}, "マウスの左ボタンがクリックされたときに FuncName(x, y) を呼び出す"},
+	"onspriteclick": {[]string{`OnSpriteClick(cast_no, "FuncName")`}, "指定したキャストがクリックされたときに FuncName(cast, x, y) を呼び出す"},
+	"onspritetick":  {[]string{`OnSpriteTick(cast_no, "FuncName")`, `OnSpriteTick(cast_no, "FuncName", n)`}, "n ティック（TIME）ごとに FuncName(cast) を呼び出す。DelCast でキャストを削除すると止まる。戻り値はハンドラ番号（son-et拡張）"},
+	"onnote":        {[]string{`OnNote(channel, note, "FuncName")`, `OnNote(channel, lowNote, highNote, "FuncName")`}, "MIDI再生中、範囲内のノートオンごとに FuncName(note, velocity) を呼び出す。channel は1〜16（0で全チャンネル）"},
+	"highlighttext": {[]string{"HighlightText(text, pic_no, x, y, count)", "HighlightText(text, pic_no, x, y, count, color)"}, "TextWrite と同じように描画し、先頭の count 文字を color（省略時は赤）で描画する（son-et拡張）"},
+	"karaoke":       {[]string{"handler = Karaoke(pic_no, x, y)", "handler = Karaoke(pic_no, x, y, color)"}, "再生中のMIDIの歌詞（MIDI_LYRIC）を (x, y) に表示し、歌った文字を color で描画する。DelMes(handler) で止める（son-et拡張）"},
+	"bindnote":      {[]string{`BindNote(channel, note, "VarName")`, `BindNote(channel, lowNote, highNote, "VarName")`}, "MIDI再生中、範囲内のノートオンのベロシティを変数 VarName に代入する"},
+
+	// システム
+	"getsystime":   {[]string{"time = GetSysTime()"}, "システム時刻の取得"},
+	"formatnumber": {[]string{"str = FormatNumber(value)", "str = FormatNumber(value, decimals)"}, "数値を --locale のロケールの桁区切りと小数点で文字列にする（son-et拡張）"},
+	"formatdate":   {[]string{`str = FormatDate("date")`, `str = FormatDate(style, time)`}, "日付・時刻を --locale のロケールの書式で文字列にする。style は date・time・shorttime・datetime・long・year・month・weekday（son-et拡張）"},
+	"shell":        {[]string{"Shell(command, working_dir)"}, "外部プログラムの実行"},
+	"mci":          {[]string{"MCI(command)"}, "Windows MCI コマンド（未対応）"},
+	"strmci":       {[]string{"result = StrMCI(command)"}, "Windows MCI コマンドの文字列版（未対応）"},
+	"msgbox":       {[]string{"result = MsgBox(message, flags)"}, "メッセージボックスを表示し、押されたボタンを返す"},
+	"savevalue":    {[]string{`SaveValue("key", value)`}, "値の永続保存（son-et拡張）"},
+	"loadvalue":    {[]string{`value = LoadValue("key")`, `value = LoadValue("key", default_value)`}, "永続保存した値の読み込み（son-et拡張）"},
+	"exittitle":    {[]string{"ExitTitle()"}, "タイトルを終了する"},
+	"debug":        {[]string{"Debug(level)"}, "デバッグレベルの設定（何もしない）"},
+	"debugbreak":   {[]string{`DebugBreak("label")`}, "デバッガ接続時（--debug-break）にシーケンスを一時停止し、ローカル変数を表示する（son-et拡張）"},
+	"onexit":       {[]string{`OnExit("FuncName")`, `OnExit("FuncName", budget_ms)`}, "ウィンドウを閉じたとき・タイムアウト時に FuncName() を呼び出し、終了処理を budget_ms（既定3000、最大10000）まで実行する（son-et拡張）"},
+	"chapter":      {[]string{`Chapter("name")`}, "チャプターの目印。--chapter やコンソールの chapter コマンドでこの位置まで早送りできる（son-et拡張）"},
+}
+
+// lookupBuiltinDoc は組み込み関数のドキュメントを返す（大文字・小文字は区別しない）
+func lookupBuiltinDoc(name string) (builtinDoc, bool) {
+	doc, ok := builtinDocs[strings.ToLower(name)]
+	return doc, ok
+}
+
+// markdown はホバーに表示するMarkdownを返す
+func (d builtinDoc) markdown() string {
+	var sb strings.Builder
+	sb.WriteString("```filly\n")
+	for _, sig := range d.signatures {
+		sb.WriteString(sig)
+		sb.WriteString("\n")
+	}
+	sb.WriteString("```\n")
+	fmt.Fprintf(&sb, "\n%s", d.summary)
+	return sb.String()
+}
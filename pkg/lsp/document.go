@@ -0,0 +1,276 @@
+package lsp
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf16"
+	"unicode/utf8"
+
+	lint "github.com/zurustar/son-et/pkg/compiler"
+	"github.com/zurustar/son-et/pkg/compiler/compiler"
+	"github.com/zurustar/son-et/pkg/compiler/lexer"
+	"github.com/zurustar/son-et/pkg/compiler/parser"
+)
+
+// document はエディタで開かれているドキュメント
+type document struct {
+	uri      string
+	version  int
+	text     string
+	analysis *analysis // 最後の解析結果（テキストが変更されると nil に戻る）
+}
+
+// analysis はドキュメントの解析結果
+type analysis struct {
+	tokens      []lexer.Token
+	diagnostics []Diagnostic
+	functions   map[string]*parser.FunctionStatement // 小文字の関数名 → 定義
+	includes    []*parser.IncludeDirective
+}
+
+// newDocument は新しいドキュメントを作成する
+func newDocument(uri string, version int, text string) *document {
+	return &document{uri: uri, version: version, text: text}
+}
+
+// applyChanges は textDocument/didChange の変更を順に適用する
+func (d *document) applyChanges(version int, changes []TextDocumentContentChangeEvent) error {
+	for _, change := range changes {
+		if change.Range == nil {
+			d.text = change.Text
+			continue
+		}
+		start, ok := byteOffset(d.text, change.Range.Start)
+		if !ok {
+			return fmt.Errorf("change start %d:%d is out of range", change.Range.Start.Line, change.Range.Start.Character)
+		}
+		end, ok := byteOffset(d.text, change.Range.End)
+		if !ok || end < start {
+			return fmt.Errorf("change end %d:%d is out of range", change.Range.End.Line, change.Range.End.Character)
+		}
+		d.text = d.text[:start] + change.Text + d.text[end:]
+	}
+	d.version = version
+	d.analysis = nil
+	return nil
+}
+
+// analyze はドキュメントを解析する（前回の解析以降に変更が無ければ結果を再利用する）
+func (d *document) analyze() *analysis {
+	if d.analysis == nil {
+		d.analysis = analyzeSource(d.text)
+	}
+	return d.analysis
+}
+
+// analyzeSource はソースを字句解析・構文解析・コード生成し、診断と定義を集める
+// コード生成のエラーは構文エラーが無い場合のみ報告する（コンパイルパイプラインと同じ順序）
+func analyzeSource(source string) *analysis {
+	a := &analysis{functions: make(map[string]*parser.FunctionStatement)}
+	lines := strings.Split(source, "\n")
+
+	tokens, lexErrs := lexer.New(source).TokenizeWithErrors()
+	a.tokens = tokens
+	for _, e := range lexErrs {
+		a.diagnostics = append(a.diagnostics, newDiagnostic(lines, e.Line, e.Column, e.Message))
+	}
+
+	program, parseErrs := parser.New(lexer.New(source)).ParseProgram()
+	for _, err := range parseErrs {
+		if pe, ok := err.(*parser.ParserError); ok {
+			a.diagnostics = append(a.diagnostics, newDiagnostic(lines, pe.Line, pe.Column, pe.Message))
+		} else {
+			a.diagnostics = append(a.diagnostics, newDiagnostic(lines, 0, 0, err.Error()))
+		}
+	}
+
+	for _, stmt := range program.Statements {
+		switch s := stmt.(type) {
+		case *parser.FunctionStatement:
+			a.functions[strings.ToLower(s.Name)] = s
+		case *parser.IncludeDirective:
+			a.includes = append(a.includes, s)
+		}
+	}
+
+	if len(parseErrs) == 0 {
+		_, compileErrs := compiler.New().Compile(program)
+		for _, err := range compileErrs {
+			if ce, ok := err.(*compiler.CompilerError); ok {
+				a.diagnostics = append(a.diagnostics, newDiagnostic(lines, ce.Line, ce.Column, ce.Message))
+			} else {
+				a.diagnostics = append(a.diagnostics, newDiagnostic(lines, 0, 0, err.Error()))
+			}
+		}
+		a.diagnostics = append(a.diagnostics, tickBudgetDiagnostics(lines, program)...)
+	}
+	return a
+}
+
+// tickBudgetDiagnostics は1ティックのオペコードの数が予算を超えそうなシーケンスについて、
+// 待たずに回るループの行に警告を付ける（ループの無いシーケンスはシーケンスの行に付ける）
+func tickBudgetDiagnostics(lines []string, program *parser.Program) []Diagnostic {
+	var diags []Diagnostic
+	for _, w := range lint.CheckTickBudget(program, lint.DefaultTickBudget) {
+		message := fmt.Sprintf("%s may run about %d opcodes in one tick (budget %d); add a Wait or a step comma inside the loop",
+			w.Sequence, w.Ops, lint.DefaultTickBudget)
+		targets := w.Loops
+		if len(targets) == 0 {
+			targets = []int{w.Line}
+		}
+		for _, line := range targets {
+			column := 1
+			if line >= 1 && line <= len(lines) {
+				column += len(lines[line-1]) - len(strings.TrimLeft(lines[line-1], " \t"))
+			}
+			d := newDiagnostic(lines, line, column, message)
+			d.Severity = SeverityWarning
+			diags = append(diags, d)
+		}
+	}
+	return diags
+}
+
+// newDiagnostic はコンパイラの位置（1始まりの行とバイト単位の桁）のエラーを診断に変換する
+// 範囲はエラー位置の単語（識別子・数値）、単語でなければ1文字とする
+func newDiagnostic(lines []string, line, column int, message string) Diagnostic {
+	start := toPosition(lines, line, column)
+	end := start
+	if line >= 1 && line <= len(lines) {
+		end = toPosition(lines, line, column+wordLength(lines[line-1], column))
+	}
+	return Diagnostic{
+		Range:    Range{Start: start, End: end},
+		Severity: SeverityError,
+		Source:   diagnosticSource,
+		Message:  message,
+	}
+}
+
+// wordLength は1始まりのバイト単位の桁から始まる単語のバイト数を返す（最低1文字）
+func wordLength(line string, column int) int {
+	if column < 1 || column > len(line) {
+		return 0
+	}
+	rest := line[column-1:]
+	n := 0
+	for n < len(rest) && isWordByte(rest[n]) {
+		n++
+	}
+	if n == 0 {
+		_, size := utf8.DecodeRuneInString(rest)
+		return size
+	}
+	return n
+}
+
+// isWordByte は識別子・数値を構成するバイトかを返す
+func isWordByte(c byte) bool {
+	return c == '_' || c >= '0' && c <= '9' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z'
+}
+
+// tokenAt は位置にあるトークンを返す
+// #include は行全体を対象とし、ファイル名のどこを指していても一致する
+func (a *analysis) tokenAt(text string, pos Position) (lexer.Token, bool) {
+	line, column, ok := toLexerPosition(text, pos)
+	if !ok {
+		return lexer.Token{}, false
+	}
+	for _, tok := range a.tokens {
+		if tok.Line != line {
+			continue
+		}
+		if tok.Type == lexer.TOKEN_INCLUDE && column >= tok.Column {
+			return tok, true
+		}
+		if column >= tok.Column && column < tok.Column+len(tok.Literal) {
+			return tok, true
+		}
+	}
+	return lexer.Token{}, false
+}
+
+// tokenRange はトークンの範囲を返す
+func tokenRange(text string, tok lexer.Token) Range {
+	lines := strings.Split(text, "\n")
+	return Range{
+		Start: toPosition(lines, tok.Line, tok.Column),
+		End:   toPosition(lines, tok.Line, tok.Column+len(tok.Literal)),
+	}
+}
+
+// toPosition はコンパイラの位置（1始まりの行とバイト単位の桁）をLSPの位置に変換する
+// 行が無い場合（位置情報の無いエラー）は先頭を返す
+func toPosition(lines []string, line, column int) Position {
+	if line < 1 || line > len(lines) {
+		return Position{}
+	}
+	text := lines[line-1]
+	offset := column - 1
+	if offset < 0 {
+		offset = 0
+	}
+	if offset > len(text) {
+		offset = len(text)
+	}
+	return Position{Line: line - 1, Character: utf16Length(text[:offset])}
+}
+
+// toLexerPosition はLSPの位置をコンパイラの位置（1始まりの行とバイト単位の桁）に変換する
+func toLexerPosition(text string, pos Position) (line, column int, ok bool) {
+	lines := strings.Split(text, "\n")
+	if pos.Line < 0 || pos.Line >= len(lines) {
+		return 0, 0, false
+	}
+	offset, ok := utf16ToByteOffset(lines[pos.Line], pos.Character)
+	if !ok {
+		return 0, 0, false
+	}
+	return pos.Line + 1, offset + 1, true
+}
+
+// byteOffset はLSPの位置をテキスト全体のバイトオフセットに変換する
+func byteOffset(text string, pos Position) (int, bool) {
+	lineStart := 0
+	for i := 0; i < pos.Line; i++ {
+		next := strings.IndexByte(text[lineStart:], '\n')
+		if next < 0 {
+			return 0, false
+		}
+		lineStart += next + 1
+	}
+	lineEnd := len(text)
+	if next := strings.IndexByte(text[lineStart:], '\n'); next >= 0 {
+		lineEnd = lineStart + next
+	}
+	offset, ok := utf16ToByteOffset(text[lineStart:lineEnd], pos.Character)
+	if !ok {
+		return 0, false
+	}
+	return lineStart + offset, true
+}
+
+// utf16ToByteOffset は行内のUTF-16コード単位の桁をバイトオフセットに変換する
+// 行末を超える桁は行末に丸める（LSP仕様）
+func utf16ToByteOffset(line string, character int) (int, bool) {
+	if character < 0 {
+		return 0, false
+	}
+	units := 0
+	for i, r := range line {
+		if units >= character {
+			return i, true
+		}
+		units += utf16.RuneLen(r)
+	}
+	return len(line), true
+}
+
+// utf16Length は文字列のUTF-16コード単位の長さを返す
+func utf16Length(s string) int {
+	n := 0
+	for _, r := range s {
+		n += utf16.RuneLen(r)
+	}
+	return n
+}
@@ -0,0 +1,178 @@
+package lsp
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/zurustar/son-et/pkg/compiler/lexer"
+)
+
+// TestApplyChanges tests incremental and full document updates.
+func TestApplyChanges(t *testing.T) {
+	doc := newDocument("file:///a.tfy", 1, "main() {\n  x = 1\n}\n")
+	doc.analyze()
+
+	// "1" → "42"
+	err := doc.applyChanges(2, []TextDocumentContentChangeEvent{{
+		Range: &Range{Start: Position{Line: 1, Character: 6}, End: Position{Line: 1, Character: 7}},
+		Text:  "42",
+	}})
+	if err != nil {
+		t.Fatalf("applyChanges returned error: %v", err)
+	}
+	if doc.text != "main() {\n  x = 42\n}\n" {
+		t.Errorf("text = %q", doc.text)
+	}
+	if doc.version != 2 {
+		t.Errorf("version = %d, want 2", doc.version)
+	}
+	if doc.analysis != nil {
+		t.Error("analysis should be invalidated after a change")
+	}
+
+	if err := doc.applyChanges(3, []TextDocumentContentChangeEvent{{Text: "main() {}"}}); err != nil {
+		t.Fatalf("applyChanges returned error: %v", err)
+	}
+	if doc.text != "main() {}" {
+		t.Errorf("full replacement: text = %q", doc.text)
+	}
+
+	err = doc.applyChanges(4, []TextDocumentContentChangeEvent{{
+		Range: &Range{Start: Position{Line: 5}, End: Position{Line: 5}},
+	}})
+	if err == nil {
+		t.Error("expected error for out-of-range change")
+	}
+}
+
+// TestApplyChangesUTF16 tests that characters are counted in UTF-16 code units.
+func TestApplyChangesUTF16(t *testing.T) {
+	// "あ" は UTF-16 で1単位、"𠮷" はサロゲートペアで2単位
+	doc := newDocument("file:///a.tfy", 1, `s = "あ𠮷x"`)
+	err := doc.applyChanges(2, []TextDocumentContentChangeEvent{{
+		Range: &Range{Start: Position{Character: 8}, End: Position{Character: 9}},
+		Text:  "y",
+	}})
+	if err != nil {
+		t.Fatalf("applyChanges returned error: %v", err)
+	}
+	if doc.text != `s = "あ𠮷y"` {
+		t.Errorf("text = %q", doc.text)
+	}
+}
+
+// TestAnalyzeDiagnostics tests that lexer and parser errors become diagnostics.
+func TestAnalyzeDiagnostics(t *testing.T) {
+	t.Run("valid source", func(t *testing.T) {
+		a := analyzeSource("main() {\n  x = 1\n}\n")
+		if len(a.diagnostics) != 0 {
+			t.Errorf("expected no diagnostics, got %v", a.diagnostics)
+		}
+	})
+
+	t.Run("syntax error", func(t *testing.T) {
+		a := analyzeSource("main() {\n  x = (1 + \n}\n")
+		if len(a.diagnostics) == 0 {
+			t.Fatal("expected diagnostics for a syntax error")
+		}
+		d := a.diagnostics[0]
+		if d.Severity != SeverityError || d.Source != diagnosticSource {
+			t.Errorf("unexpected diagnostic: %+v", d)
+		}
+	})
+
+	t.Run("illegal character", func(t *testing.T) {
+		a := analyzeSource("main() {\n  x = 1 @\n}\n")
+		found := false
+		for _, d := range a.diagnostics {
+			if strings.Contains(d.Message, "illegal character") {
+				found = true
+				if d.Range.Start != (Position{Line: 1, Character: 8}) {
+					t.Errorf("range start = %+v, want 1:8", d.Range.Start)
+				}
+			}
+		}
+		if !found {
+			t.Errorf("expected an illegal character diagnostic, got %v", a.diagnostics)
+		}
+	})
+
+	t.Run("tick budget", func(t *testing.T) {
+		a := analyzeSource("main() {\n  for (i = 0; i < 1000; i = i + 1) {\n    for (j = 0; j < 1000; j = j + 1) {\n      x = j;\n    }\n  }\n}\n")
+		if len(a.diagnostics) != 2 {
+			t.Fatalf("expected warnings at both loops, got %v", a.diagnostics)
+		}
+		for i, want := range []Position{{Line: 1, Character: 2}, {Line: 2, Character: 4}} {
+			d := a.diagnostics[i]
+			if d.Severity != SeverityWarning || d.Range.Start != want || !strings.Contains(d.Message, "main may run about") {
+				t.Errorf("diagnostic %d = %+v, want a warning at %+v", i, d, want)
+			}
+		}
+	})
+}
+
+// TestAnalyzeDefinitions tests collection of functions and #include directives.
+func TestAnalyzeDefinitions(t *testing.T) {
+	a := analyzeSource("#include \"common.tfy\"\nmain() {\n  Helper()\n}\nHelper() {\n}\n")
+	if _, ok := a.functions["helper"]; !ok {
+		t.Error("Helper should be collected (case-insensitive key)")
+	}
+	if _, ok := a.functions["main"]; !ok {
+		t.Error("main should be collected")
+	}
+	if len(a.includes) != 1 || a.includes[0].FileName != "common.tfy" {
+		t.Errorf("includes = %v", a.includes)
+	}
+}
+
+// TestTokenAt tests finding the token under the cursor.
+func TestTokenAt(t *testing.T) {
+	text := "#include \"common.tfy\"\nmain() {\n  LoadPic(\"a.bmp\")\n}\n"
+	a := analyzeSource(text)
+
+	tests := []struct {
+		pos     Position
+		typ     lexer.TokenType
+		literal string
+	}{
+		{Position{Line: 2, Character: 2}, lexer.TOKEN_IDENT, "LoadPic"},
+		{Position{Line: 2, Character: 8}, lexer.TOKEN_IDENT, "LoadPic"},
+		{Position{Line: 0, Character: 12}, lexer.TOKEN_INCLUDE, "common.tfy"},
+	}
+	for _, tt := range tests {
+		tok, ok := a.tokenAt(text, tt.pos)
+		if !ok {
+			t.Errorf("no token at %+v", tt.pos)
+			continue
+		}
+		if tok.Type != tt.typ || tok.Literal != tt.literal {
+			t.Errorf("token at %+v = %s %q, want %s %q", tt.pos, tok.Type, tok.Literal, tt.typ, tt.literal)
+		}
+	}
+
+	if _, ok := a.tokenAt(text, Position{Line: 2, Character: 0}); ok {
+		t.Error("whitespace should not match a token")
+	}
+}
+
+// TestToPosition tests conversion from compiler positions to LSP positions.
+func TestToPosition(t *testing.T) {
+	lines := []string{"abc", `s = "日本"; x`}
+
+	tests := []struct {
+		line, column int
+		want         Position
+	}{
+		{1, 1, Position{Line: 0, Character: 0}},
+		{1, 3, Position{Line: 0, Character: 2}},
+		// "日本" は各3バイト・UTF-16で各1単位
+		{2, 12, Position{Line: 1, Character: 7}},
+		{0, 0, Position{}},
+		{3, 1, Position{}},
+	}
+	for _, tt := range tests {
+		if got := toPosition(lines, tt.line, tt.column); got != tt.want {
+			t.Errorf("toPosition(%d, %d) = %+v, want %+v", tt.line, tt.column, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,183 @@
+// Package lsp implements a Language Server Protocol server for FILLY scripts (.TFY).
+//
+// The server speaks JSON-RPC 2.0 over stdio (son-et lsp) and reuses the compiler's
+// lexer and parser to provide:
+//   - diagnostics (lexer, parser and code generation errors) on open and change
+//   - go-to-definition for user functions and #include targets
+//   - hover documentation for built-in functions
+//
+// Documents are synchronized incrementally; each document is re-analyzed lazily
+// only when its text has changed since the last analysis.
+package lsp
+
+import "encoding/json"
+
+// JSON-RPC 2.0 のエラーコード
+const (
+	codeParseError     = -32700
+	codeMethodNotFound = -32601
+	codeInvalidParams  = -32602
+)
+
+// syncKindIncremental はドキュメントの変更を差分で受け取る TextDocumentSyncKind（LSP仕様）
+const syncKindIncremental = 2
+
+// SeverityError はエラーを表す DiagnosticSeverity（LSP仕様）
+const SeverityError = 1
+
+// SeverityWarning は警告を表す DiagnosticSeverity（LSP仕様）
+const SeverityWarning = 2
+
+// markupKindMarkdown はMarkdown形式のMarkupContentの種類
+const markupKindMarkdown = "markdown"
+
+// diagnosticSource は診断メッセージの発生元として表示される名前
+const diagnosticSource = "son-et"
+
+// request はクライアントから受信するJSON-RPCメッセージ（リクエストまたは通知）
+// ID が無いものは通知で、応答を返さない
+type request struct {
+	JSONRPC string           `json:"jsonrpc"`
+	ID      *json.RawMessage `json:"id,omitempty"`
+	Method  string           `json:"method"`
+	Params  json.RawMessage  `json:"params,omitempty"`
+}
+
+// response はリクエストに対する成功応答
+// Result が nil の場合も "result": null を出力する必要があるため omitempty を付けない
+type response struct {
+	JSONRPC string           `json:"jsonrpc"`
+	ID      *json.RawMessage `json:"id"`
+	Result  any              `json:"result"`
+}
+
+// errorResponse はリクエストに対するエラー応答
+type errorResponse struct {
+	JSONRPC string           `json:"jsonrpc"`
+	ID      *json.RawMessage `json:"id"`
+	Error   *responseError   `json:"error"`
+}
+
+// responseError はJSON-RPCのエラーオブジェクト
+type responseError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+// notification はサーバーからクライアントへ送信する通知
+type notification struct {
+	JSONRPC string `json:"jsonrpc"`
+	Method  string `json:"method"`
+	Params  any    `json:"params"`
+}
+
+// Position はドキュメント内の位置（0始まりの行と、UTF-16コード単位の0始まりの桁）
+type Position struct {
+	Line      int `json:"line"`
+	Character int `json:"character"`
+}
+
+// Range はドキュメント内の範囲（End は含まない）
+type Range struct {
+	Start Position `json:"start"`
+	End   Position `json:"end"`
+}
+
+// Location はドキュメント内の範囲とそのURI
+type Location struct {
+	URI   string `json:"uri"`
+	Range Range  `json:"range"`
+}
+
+// Diagnostic はエディタに表示するエラー・警告
+type Diagnostic struct {
+	Range    Range  `json:"range"`
+	Severity int    `json:"severity"`
+	Source   string `json:"source"`
+	Message  string `json:"message"`
+}
+
+// MarkupContent はホバーなどで表示する書式付きテキスト
+type MarkupContent struct {
+	Kind  string `json:"kind"`
+	Value string `json:"value"`
+}
+
+// Hover は textDocument/hover の結果
+type Hover struct {
+	Contents MarkupContent `json:"contents"`
+	Range    *Range        `json:"range,omitempty"`
+}
+
+// TextDocumentItem は textDocument/didOpen で送られるドキュメント
+type TextDocumentItem struct {
+	URI        string `json:"uri"`
+	LanguageID string `json:"languageId"`
+	Version    int    `json:"version"`
+	Text       string `json:"text"`
+}
+
+// TextDocumentIdentifier はドキュメントを識別するURI
+type TextDocumentIdentifier struct {
+	URI string `json:"uri"`
+}
+
+// VersionedTextDocumentIdentifier はバージョン付きのドキュメント識別子
+type VersionedTextDocumentIdentifier struct {
+	URI     string `json:"uri"`
+	Version int    `json:"version"`
+}
+
+// TextDocumentContentChangeEvent はドキュメントの変更内容
+// Range が nil の場合は Text がドキュメント全体を表す
+type TextDocumentContentChangeEvent struct {
+	Range *Range `json:"range,omitempty"`
+	Text  string `json:"text"`
+}
+
+// TextDocumentPositionParams はドキュメント内の位置を指定するリクエストのパラメータ
+type TextDocumentPositionParams struct {
+	TextDocument TextDocumentIdentifier `json:"textDocument"`
+	Position     Position               `json:"position"`
+}
+
+// DidOpenTextDocumentParams は textDocument/didOpen のパラメータ
+type DidOpenTextDocumentParams struct {
+	TextDocument TextDocumentItem `json:"textDocument"`
+}
+
+// DidChangeTextDocumentParams は textDocument/didChange のパラメータ
+type DidChangeTextDocumentParams struct {
+	TextDocument   VersionedTextDocumentIdentifier  `json:"textDocument"`
+	ContentChanges []TextDocumentContentChangeEvent `json:"contentChanges"`
+}
+
+// DidCloseTextDocumentParams は textDocument/didClose のパラメータ
+type DidCloseTextDocumentParams struct {
+	TextDocument TextDocumentIdentifier `json:"textDocument"`
+}
+
+// PublishDiagnosticsParams は textDocument/publishDiagnostics のパラメータ
+type PublishDiagnosticsParams struct {
+	URI         string       `json:"uri"`
+	Version     *int         `json:"version,omitempty"`
+	Diagnostics []Diagnostic `json:"diagnostics"`
+}
+
+// ServerCapabilities はサーバーが提供する機能
+type ServerCapabilities struct {
+	TextDocumentSync   int  `json:"textDocumentSync"`
+	HoverProvider      bool `json:"hoverProvider"`
+	DefinitionProvider bool `json:"definitionProvider"`
+}
+
+// ServerInfo はサーバーの名前
+type ServerInfo struct {
+	Name string `json:"name"`
+}
+
+// InitializeResult は initialize の結果
+type InitializeResult struct {
+	Capabilities ServerCapabilities `json:"capabilities"`
+	ServerInfo   ServerInfo         `json:"serverInfo"`
+}
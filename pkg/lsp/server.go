@@ -0,0 +1,410 @@
+package lsp
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/zurustar/son-et/pkg/compiler/lexer"
+	"github.com/zurustar/son-et/pkg/fileutil"
+	"golang.org/x/text/encoding/japanese"
+)
+
+// ServerName は initialize の応答で返すサーバー名
+const ServerName = "son-et"
+
+// ErrExitWithoutShutdown は shutdown を受け取る前に exit 通知を受け取った場合のエラー
+// LSP仕様ではこの場合プロセスは終了コード1で終了する
+var ErrExitWithoutShutdown = errors.New("exit notification received before shutdown")
+
+// tfyExt はFILLYスクリプトの拡張子
+const tfyExt = ".tfy"
+
+// Server はstdio上でLSPを話すFILLYスクリプトの言語サーバー
+type Server struct {
+	in   *bufio.Reader
+	out  io.Writer
+	log  *slog.Logger
+	docs map[string]*document // URI → 開かれているドキュメント
+
+	shutdown bool
+}
+
+// Option はServerの設定オプション
+type Option func(*Server)
+
+// WithLogger はロガーを設定する
+// 標準出力はプロトコルに使用するため、ロガーは標準エラー出力などに書き込むこと
+func WithLogger(log *slog.Logger) Option {
+	return func(s *Server) {
+		s.log = log
+	}
+}
+
+// NewServer は in からリクエストを読み、out に応答を書き込むサーバーを作成する
+func NewServer(in io.Reader, out io.Writer, opts ...Option) *Server {
+	s := &Server{
+		in:   bufio.NewReader(in),
+		out:  out,
+		log:  slog.Default(),
+		docs: make(map[string]*document),
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+// Run は入力が終わるか exit 通知を受け取るまでメッセージを処理する
+// shutdown を受け取らずに exit した場合は ErrExitWithoutShutdown を返す
+func (s *Server) Run() error {
+	for {
+		body, err := readMessage(s.in)
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		var req request
+		if err := json.Unmarshal(body, &req); err != nil {
+			s.log.Warn("LSP: invalid message", "error", err)
+			if err := s.replyError(nil, codeParseError, err.Error()); err != nil {
+				return err
+			}
+			continue
+		}
+
+		if req.Method == "exit" {
+			if !s.shutdown {
+				return ErrExitWithoutShutdown
+			}
+			return nil
+		}
+
+		if err := s.handle(&req); err != nil {
+			return err
+		}
+	}
+}
+
+// handle はメッセージを1つ処理する
+// 返すエラーは出力の書き込み失敗のみで、リクエストの失敗はエラー応答としてクライアントに返す
+func (s *Server) handle(req *request) error {
+	s.log.Debug("LSP message", "method", req.Method)
+
+	var result any
+	var err error
+	switch req.Method {
+	case "initialize":
+		result = InitializeResult{
+			Capabilities: ServerCapabilities{
+				TextDocumentSync:   syncKindIncremental,
+				HoverProvider:      true,
+				DefinitionProvider: true,
+			},
+			ServerInfo: ServerInfo{Name: ServerName},
+		}
+	case "initialized":
+	case "shutdown":
+		s.shutdown = true
+	case "textDocument/didOpen":
+		err = s.didOpen(req.Params)
+	case "textDocument/didChange":
+		err = s.didChange(req.Params)
+	case "textDocument/didClose":
+		err = s.didClose(req.Params)
+	case "textDocument/hover":
+		result, err = s.hover(req.Params)
+	case "textDocument/definition":
+		result, err = s.definition(req.Params)
+	default:
+		if req.ID == nil {
+			// 未対応の通知（$/cancelRequest など）は無視する
+			return nil
+		}
+		return s.replyError(req.ID, codeMethodNotFound, "method not found: "+req.Method)
+	}
+
+	if err != nil {
+		s.log.Warn("LSP request failed", "method", req.Method, "error", err)
+		if req.ID == nil {
+			return nil
+		}
+		return s.replyError(req.ID, codeInvalidParams, err.Error())
+	}
+	if req.ID == nil {
+		return nil
+	}
+	return writeMessage(s.out, response{JSONRPC: "2.0", ID: req.ID, Result: result})
+}
+
+// replyError はエラー応答を書き込む
+func (s *Server) replyError(id *json.RawMessage, code int, message string) error {
+	return writeMessage(s.out, errorResponse{
+		JSONRPC: "2.0",
+		ID:      id,
+		Error:   &responseError{Code: code, Message: message},
+	})
+}
+
+// publishDiagnostics はドキュメントを解析し、診断をクライアントに通知する
+func (s *Server) publishDiagnostics(doc *document) error {
+	diagnostics := doc.analyze().diagnostics
+	if diagnostics == nil {
+		diagnostics = []Diagnostic{}
+	}
+	version := doc.version
+	return writeMessage(s.out, notification{
+		JSONRPC: "2.0",
+		Method:  "textDocument/publishDiagnostics",
+		Params:  PublishDiagnosticsParams{URI: doc.uri, Version: &version, Diagnostics: diagnostics},
+	})
+}
+
+func (s *Server) didOpen(raw json.RawMessage) error {
+	var params DidOpenTextDocumentParams
+	if err := json.Unmarshal(raw, &params); err != nil {
+		return err
+	}
+	item := params.TextDocument
+	doc := newDocument(item.URI, item.Version, item.Text)
+	s.docs[item.URI] = doc
+	return s.publishDiagnostics(doc)
+}
+
+func (s *Server) didChange(raw json.RawMessage) error {
+	var params DidChangeTextDocumentParams
+	if err := json.Unmarshal(raw, &params); err != nil {
+		return err
+	}
+	doc, ok := s.docs[params.TextDocument.URI]
+	if !ok {
+		return fmt.Errorf("document is not open: %s", params.TextDocument.URI)
+	}
+	if err := doc.applyChanges(params.TextDocument.Version, params.ContentChanges); err != nil {
+		return err
+	}
+	return s.publishDiagnostics(doc)
+}
+
+func (s *Server) didClose(raw json.RawMessage) error {
+	var params DidCloseTextDocumentParams
+	if err := json.Unmarshal(raw, &params); err != nil {
+		return err
+	}
+	delete(s.docs, params.TextDocument.URI)
+	// 閉じたドキュメントの診断を消去する
+	return writeMessage(s.out, notification{
+		JSONRPC: "2.0",
+		Method:  "textDocument/publishDiagnostics",
+		Params:  PublishDiagnosticsParams{URI: params.TextDocument.URI, Diagnostics: []Diagnostic{}},
+	})
+}
+
+// hover は組み込み関数の呼び出し形式と説明を返す（該当しない位置では null）
+func (s *Server) hover(raw json.RawMessage) (any, error) {
+	doc, pos, err := s.positionParams(raw)
+	if err != nil {
+		return nil, err
+	}
+	tok, ok := doc.analyze().tokenAt(doc.text, pos)
+	if !ok || tok.Type != lexer.TOKEN_IDENT {
+		return nil, nil
+	}
+	builtin, ok := lookupBuiltinDoc(tok.Literal)
+	if !ok {
+		return nil, nil
+	}
+	r := tokenRange(doc.text, tok)
+	return &Hover{
+		Contents: MarkupContent{Kind: markupKindMarkdown, Value: builtin.markdown()},
+		Range:    &r,
+	}, nil
+}
+
+// definition は関数名の定義位置、または #include のファイルを返す（見つからない場合は null）
+//
+// 関数は次の順に探す（大文字・小文字は区別しない）:
+//  1. 同じドキュメント
+//  2. #include で取り込まれるファイル（再帰的に）
+//  3. 同じディレクトリの他のTFYファイル（このファイルを #include している側の定義など）
+func (s *Server) definition(raw json.RawMessage) (any, error) {
+	doc, pos, err := s.positionParams(raw)
+	if err != nil {
+		return nil, err
+	}
+	a := doc.analyze()
+	tok, ok := a.tokenAt(doc.text, pos)
+	if !ok {
+		return nil, nil
+	}
+
+	path, hasPath := uriToPath(doc.uri)
+	switch tok.Type {
+	case lexer.TOKEN_INCLUDE:
+		if !hasPath {
+			return nil, nil
+		}
+		target, ok := resolveInclude(filepath.Dir(path), tok.Literal)
+		if !ok {
+			return nil, nil
+		}
+		return &Location{URI: pathToURI(target)}, nil
+	case lexer.TOKEN_IDENT:
+		if fn, ok := a.functions[strings.ToLower(tok.Literal)]; ok {
+			return &Location{URI: doc.uri, Range: tokenRange(doc.text, fn.Token)}, nil
+		}
+		if !hasPath {
+			return nil, nil
+		}
+		return s.findFunction(path, a, tok.Literal), nil
+	}
+	return nil, nil
+}
+
+// findFunction は path のドキュメントから辿れる他のファイルで関数定義を探す
+func (s *Server) findFunction(path string, a *analysis, name string) *Location {
+	key := strings.ToLower(name)
+	dir := filepath.Dir(path)
+	visited := map[string]bool{strings.ToLower(filepath.Clean(path)): true}
+
+	// #include を幅優先で辿る
+	queue := includeTargets(dir, a)
+	for len(queue) > 0 {
+		file := queue[0]
+		queue = queue[1:]
+		if visited[strings.ToLower(file)] {
+			continue
+		}
+		visited[strings.ToLower(file)] = true
+
+		text, fa, ok := s.load(file)
+		if !ok {
+			continue
+		}
+		if fn, ok := fa.functions[key]; ok {
+			return &Location{URI: pathToURI(file), Range: tokenRange(text, fn.Token)}
+		}
+		queue = append(queue, includeTargets(filepath.Dir(file), fa)...)
+	}
+
+	// 同じディレクトリの他のTFYファイル
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil
+	}
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.EqualFold(filepath.Ext(entry.Name()), tfyExt) {
+			continue
+		}
+		file := filepath.Join(dir, entry.Name())
+		if visited[strings.ToLower(file)] {
+			continue
+		}
+		text, fa, ok := s.load(file)
+		if !ok {
+			continue
+		}
+		if fn, ok := fa.functions[key]; ok {
+			return &Location{URI: pathToURI(file), Range: tokenRange(text, fn.Token)}
+		}
+	}
+	return nil
+}
+
+// includeTargets は解析結果の #include を解決したファイルパスを返す（見つからないものは除く）
+func includeTargets(dir string, a *analysis) []string {
+	var targets []string
+	for _, inc := range a.includes {
+		if target, ok := resolveInclude(dir, inc.FileName); ok {
+			targets = append(targets, target)
+		}
+	}
+	return targets
+}
+
+// load はファイルのテキストと解析結果を返す
+// エディタで開かれている場合は未保存の内容を使用し、そうでなければディスクから読み込む
+func (s *Server) load(path string) (string, *analysis, bool) {
+	if doc, ok := s.docs[pathToURI(path)]; ok {
+		return doc.text, doc.analyze(), true
+	}
+	text, err := readScript(path)
+	if err != nil {
+		s.log.Debug("LSP: failed to read script", "path", path, "error", err)
+		return "", nil, false
+	}
+	return text, analyzeSource(text), true
+}
+
+// positionParams は位置を指定するリクエストのパラメータを解析し、対象のドキュメントを返す
+func (s *Server) positionParams(raw json.RawMessage) (*document, Position, error) {
+	var params TextDocumentPositionParams
+	if err := json.Unmarshal(raw, &params); err != nil {
+		return nil, Position{}, err
+	}
+	doc, ok := s.docs[params.TextDocument.URI]
+	if !ok {
+		return nil, Position{}, fmt.Errorf("document is not open: %s", params.TextDocument.URI)
+	}
+	return doc, params.Position, nil
+}
+
+// resolveInclude は #include のファイル名をディレクトリからの相対パスとして解決する
+// FILLYタイトルはWindows向けのため、区切り文字 '\' と大文字・小文字の違いを許容する
+func resolveInclude(dir, name string) (string, bool) {
+	path := filepath.Join(dir, filepath.FromSlash(strings.ReplaceAll(name, `\`, "/")))
+	if info, err := os.Stat(path); err == nil && !info.IsDir() {
+		return path, true
+	}
+	found, err := fileutil.FindFileCaseInsensitive(filepath.Dir(path), filepath.Base(path))
+	if err != nil {
+		return "", false
+	}
+	return found, true
+}
+
+// readScript はスクリプトファイルを読み込む（Shift-JISの場合はUTF-8に変換する）
+func readScript(path string) (string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	decoded, err := japanese.ShiftJIS.NewDecoder().Bytes(data)
+	if err != nil {
+		return string(data), nil
+	}
+	return string(decoded), nil
+}
+
+// uriToPath は file: URIをファイルパスに変換する
+func uriToPath(uri string) (string, bool) {
+	u, err := url.Parse(uri)
+	if err != nil || u.Scheme != "file" {
+		return "", false
+	}
+	path := u.Path
+	// Windowsのドライブレター（/C:/...）
+	if len(path) >= 3 && path[0] == '/' && path[2] == ':' {
+		path = path[1:]
+	}
+	return filepath.FromSlash(path), true
+}
+
+// pathToURI はファイルパスを file: URIに変換する
+func pathToURI(path string) string {
+	slashed := filepath.ToSlash(path)
+	if !strings.HasPrefix(slashed, "/") {
+		slashed = "/" + slashed
+	}
+	return (&url.URL{Scheme: "file", Path: slashed}).String()
+}
@@ -0,0 +1,299 @@
+package lsp
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// rpcMessage is a decoded message written by the server.
+type rpcMessage struct {
+	ID     *int            `json:"id"`
+	Method string          `json:"method"`
+	Params json.RawMessage `json:"params"`
+	Result json.RawMessage `json:"result"`
+	Error  *responseError  `json:"error"`
+}
+
+// lspSession builds the client side of a conversation and runs the server over it.
+type lspSession struct {
+	t     *testing.T
+	input bytes.Buffer
+	id    int
+}
+
+func newLSPSession(t *testing.T) *lspSession {
+	t.Helper()
+	s := &lspSession{t: t}
+	s.request("initialize", map[string]any{})
+	s.notify("initialized", map[string]any{})
+	return s
+}
+
+// request queues a request and returns its ID.
+func (s *lspSession) request(method string, params any) int {
+	s.id++
+	s.write(map[string]any{"jsonrpc": "2.0", "id": s.id, "method": method, "params": params})
+	return s.id
+}
+
+func (s *lspSession) notify(method string, params any) {
+	s.write(map[string]any{"jsonrpc": "2.0", "method": method, "params": params})
+}
+
+func (s *lspSession) write(v any) {
+	s.t.Helper()
+	if err := writeMessage(&s.input, v); err != nil {
+		s.t.Fatalf("writeMessage failed: %v", err)
+	}
+}
+
+func (s *lspSession) open(uri, text string) {
+	s.notify("textDocument/didOpen", map[string]any{
+		"textDocument": map[string]any{"uri": uri, "languageId": "filly", "version": 1, "text": text},
+	})
+}
+
+func (s *lspSession) position(method, uri string, line, character int) int {
+	return s.request(method, map[string]any{
+		"textDocument": map[string]any{"uri": uri},
+		"position":     map[string]any{"line": line, "character": character},
+	})
+}
+
+// run runs the server over the queued messages and returns everything it wrote.
+func (s *lspSession) run() ([]rpcMessage, error) {
+	s.t.Helper()
+	var out bytes.Buffer
+	err := NewServer(&s.input, &out).Run()
+
+	var messages []rpcMessage
+	r := bufio.NewReader(&out)
+	for {
+		body, readErr := readMessage(r)
+		if errors.Is(readErr, io.EOF) {
+			break
+		}
+		if readErr != nil {
+			s.t.Fatalf("failed to read server output: %v", readErr)
+		}
+		var msg rpcMessage
+		if jsonErr := json.Unmarshal(body, &msg); jsonErr != nil {
+			s.t.Fatalf("invalid server output %q: %v", body, jsonErr)
+		}
+		messages = append(messages, msg)
+	}
+	return messages, err
+}
+
+// responseFor returns the response to the request with the given ID.
+func responseFor(t *testing.T, messages []rpcMessage, id int) rpcMessage {
+	t.Helper()
+	for _, msg := range messages {
+		if msg.ID != nil && *msg.ID == id {
+			return msg
+		}
+	}
+	t.Fatalf("no response for request %d", id)
+	return rpcMessage{}
+}
+
+// TestServerInitialize tests the initialize handshake and capabilities.
+func TestServerInitialize(t *testing.T) {
+	s := newLSPSession(t)
+	s.request("shutdown", nil)
+	s.notify("exit", nil)
+
+	messages, err := s.run()
+	if err != nil {
+		t.Fatalf("Run returned error: %v", err)
+	}
+
+	var result InitializeResult
+	if err := json.Unmarshal(responseFor(t, messages, 1).Result, &result); err != nil {
+		t.Fatalf("invalid initialize result: %v", err)
+	}
+	caps := result.Capabilities
+	if caps.TextDocumentSync != syncKindIncremental || !caps.HoverProvider || !caps.DefinitionProvider {
+		t.Errorf("unexpected capabilities: %+v", caps)
+	}
+	if result.ServerInfo.Name != ServerName {
+		t.Errorf("server name = %q", result.ServerInfo.Name)
+	}
+}
+
+// TestServerExitWithoutShutdown tests the exit notification without a prior shutdown.
+func TestServerExitWithoutShutdown(t *testing.T) {
+	s := newLSPSession(t)
+	s.notify("exit", nil)
+
+	if _, err := s.run(); !errors.Is(err, ErrExitWithoutShutdown) {
+		t.Errorf("expected ErrExitWithoutShutdown, got %v", err)
+	}
+}
+
+// TestServerUnknownMethod tests that unknown requests get an error and unknown notifications are ignored.
+func TestServerUnknownMethod(t *testing.T) {
+	s := newLSPSession(t)
+	s.notify("$/cancelRequest", map[string]any{"id": 1})
+	id := s.request("workspace/symbol", map[string]any{})
+
+	messages, err := s.run()
+	if err != nil {
+		t.Fatalf("Run returned error: %v", err)
+	}
+	resp := responseFor(t, messages, id)
+	if resp.Error == nil || resp.Error.Code != codeMethodNotFound {
+		t.Errorf("expected MethodNotFound error, got %+v", resp.Error)
+	}
+}
+
+// TestServerDiagnostics tests that diagnostics are published on open and change.
+func TestServerDiagnostics(t *testing.T) {
+	const uri = "file:///title/MAIN.TFY"
+	s := newLSPSession(t)
+	s.open(uri, "main() {\n  x = (1 + \n}\n")
+	s.notify("textDocument/didChange", map[string]any{
+		"textDocument": map[string]any{"uri": uri, "version": 2},
+		"contentChanges": []map[string]any{{
+			"range": map[string]any{
+				"start": map[string]any{"line": 1, "character": 6},
+				"end":   map[string]any{"line": 1, "character": 11},
+			},
+			"text": "1",
+		}},
+	})
+
+	messages, err := s.run()
+	if err != nil {
+		t.Fatalf("Run returned error: %v", err)
+	}
+
+	var published []PublishDiagnosticsParams
+	for _, msg := range messages {
+		if msg.Method == "textDocument/publishDiagnostics" {
+			var params PublishDiagnosticsParams
+			json.Unmarshal(msg.Params, &params)
+			published = append(published, params)
+		}
+	}
+	if len(published) != 2 {
+		t.Fatalf("expected 2 publishDiagnostics notifications, got %d", len(published))
+	}
+	if len(published[0].Diagnostics) == 0 {
+		t.Error("syntax error should produce diagnostics on open")
+	}
+	if len(published[1].Diagnostics) != 0 {
+		t.Errorf("fixed source should clear diagnostics, got %v", published[1].Diagnostics)
+	}
+	if published[1].Version == nil || *published[1].Version != 2 {
+		t.Errorf("diagnostics should carry the document version 2")
+	}
+}
+
+// TestServerHover tests hover documentation for built-in functions.
+func TestServerHover(t *testing.T) {
+	const uri = "file:///title/MAIN.TFY"
+	s := newLSPSession(t)
+	s.open(uri, "main() {\n  pic = loadpic(\"a.bmp\")\n  MyFunc()\n}\nMyFunc() {\n}\n")
+	builtinID := s.position("textDocument/hover", uri, 1, 10)
+	userID := s.position("textDocument/hover", uri, 2, 3)
+
+	messages, err := s.run()
+	if err != nil {
+		t.Fatalf("Run returned error: %v", err)
+	}
+
+	var hover Hover
+	if err := json.Unmarshal(responseFor(t, messages, builtinID).Result, &hover); err != nil {
+		t.Fatalf("invalid hover result: %v", err)
+	}
+	if hover.Contents.Kind != markupKindMarkdown || !strings.Contains(hover.Contents.Value, "LoadPic(filename)") {
+		t.Errorf("unexpected hover contents: %+v", hover.Contents)
+	}
+	if hover.Range == nil || hover.Range.Start != (Position{Line: 1, Character: 8}) {
+		t.Errorf("hover range = %+v, want start 1:8", hover.Range)
+	}
+
+	if result := responseFor(t, messages, userID).Result; string(result) != "null" {
+		t.Errorf("hover on a user function should be null, got %s", result)
+	}
+}
+
+// TestServerDefinition tests go-to-definition for functions and #include targets.
+func TestServerDefinition(t *testing.T) {
+	dir := t.TempDir()
+	mainPath := filepath.Join(dir, "MAIN.TFY")
+	commonPath := filepath.Join(dir, "COMMON.TFY")
+	scenePath := filepath.Join(dir, "SCENE.TFY")
+	if err := os.WriteFile(commonPath, []byte("Common() {\n}\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(scenePath, []byte("  Scene() {\n  Common()\n}\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	mainURI := pathToURI(mainPath)
+	s := newLSPSession(t)
+	// #include はWindowsのタイトルと同様に大文字・小文字を区別しない
+	s.open(mainURI, "#include \"common.tfy\"\nmain() {\n  Local()\n  common()\n  Scene()\n  Missing()\n}\nLocal() {\n}\n")
+	localID := s.position("textDocument/definition", mainURI, 2, 3)
+	includedID := s.position("textDocument/definition", mainURI, 3, 3)
+	siblingID := s.position("textDocument/definition", mainURI, 4, 3)
+	includeID := s.position("textDocument/definition", mainURI, 0, 12)
+	unknownID := s.position("textDocument/definition", mainURI, 5, 3)
+
+	messages, err := s.run()
+	if err != nil {
+		t.Fatalf("Run returned error: %v", err)
+	}
+
+	tests := []struct {
+		name string
+		id   int
+		want Location
+	}{
+		{"same document", localID, Location{URI: mainURI, Range: Range{Start: Position{Line: 7}, End: Position{Line: 7, Character: 5}}}},
+		{"included file", includedID, Location{URI: pathToURI(commonPath), Range: Range{End: Position{Character: 6}}}},
+		{"sibling file", siblingID, Location{URI: pathToURI(scenePath), Range: Range{Start: Position{Character: 2}, End: Position{Character: 7}}}},
+		{"include target", includeID, Location{URI: pathToURI(commonPath)}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var loc Location
+			if err := json.Unmarshal(responseFor(t, messages, tt.id).Result, &loc); err != nil {
+				t.Fatalf("invalid definition result: %v", err)
+			}
+			if loc != tt.want {
+				t.Errorf("definition = %+v, want %+v", loc, tt.want)
+			}
+		})
+	}
+
+	if result := responseFor(t, messages, unknownID).Result; string(result) != "null" {
+		t.Errorf("definition of an unknown name should be null, got %s", result)
+	}
+}
+
+// TestURIConversion tests file URI and path conversion.
+func TestURIConversion(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "my title", "MAIN.TFY")
+	uri := pathToURI(path)
+	if !strings.HasPrefix(uri, "file:///") || strings.Contains(uri, " ") {
+		t.Errorf("pathToURI(%q) = %q", path, uri)
+	}
+	got, ok := uriToPath(uri)
+	if !ok || got != path {
+		t.Errorf("uriToPath(%q) = %q, %v; want %q", uri, got, ok, path)
+	}
+
+	if _, ok := uriToPath("untitled:Untitled-1"); ok {
+		t.Error("non-file URIs should not convert to a path")
+	}
+}
@@ -0,0 +1,61 @@
+package lsp
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/textproto"
+	"strconv"
+)
+
+// headerContentLength はメッセージ本体のバイト数を示すヘッダー
+const headerContentLength = "Content-Length"
+
+// maxMessageSize は受け付けるメッセージ本体の最大バイト数
+// 壊れたヘッダーによる巨大なメモリ確保を防ぐ
+const maxMessageSize = 64 * 1024 * 1024
+
+// ErrMissingContentLength はメッセージにContent-Lengthヘッダーが無い場合のエラー
+var ErrMissingContentLength = errors.New("missing Content-Length header")
+
+// readMessage はベースプロトコルのヘッダー（Content-Length）で区切られたメッセージ本体を1つ読み込む
+// 入力の終端では io.EOF を返す
+func readMessage(r *bufio.Reader) ([]byte, error) {
+	header, err := textproto.NewReader(r).ReadMIMEHeader()
+	if err != nil {
+		if errors.Is(err, io.EOF) && len(header) == 0 {
+			return nil, io.EOF
+		}
+		return nil, fmt.Errorf("failed to read message header: %w", err)
+	}
+
+	value := header.Get(headerContentLength)
+	if value == "" {
+		return nil, ErrMissingContentLength
+	}
+	length, err := strconv.Atoi(value)
+	if err != nil || length < 0 || length > maxMessageSize {
+		return nil, fmt.Errorf("invalid %s: %q", headerContentLength, value)
+	}
+
+	body := make([]byte, length)
+	if _, err := io.ReadFull(r, body); err != nil {
+		return nil, fmt.Errorf("failed to read message body: %w", err)
+	}
+	return body, nil
+}
+
+// writeMessage は v をJSONにエンコードし、Content-Lengthヘッダーを付けて書き込む
+func writeMessage(w io.Writer, v any) error {
+	body, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Errorf("failed to encode message: %w", err)
+	}
+	if _, err := fmt.Fprintf(w, "%s: %d\r\n\r\n", headerContentLength, len(body)); err != nil {
+		return err
+	}
+	_, err = w.Write(body)
+	return err
+}
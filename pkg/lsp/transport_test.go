@@ -0,0 +1,70 @@
+package lsp
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+)
+
+// TestReadMessage tests reading Content-Length framed messages.
+func TestReadMessage(t *testing.T) {
+	input := "Content-Length: 2\r\n\r\n{}" +
+		"Content-Type: application/vscode-jsonrpc; charset=utf-8\r\nContent-Length: 7\r\n\r\n[1,2,3]"
+	r := bufio.NewReader(strings.NewReader(input))
+
+	for _, want := range []string{"{}", "[1,2,3]"} {
+		body, err := readMessage(r)
+		if err != nil {
+			t.Fatalf("readMessage returned error: %v", err)
+		}
+		if string(body) != want {
+			t.Errorf("body = %q, want %q", body, want)
+		}
+	}
+
+	if _, err := readMessage(r); !errors.Is(err, io.EOF) {
+		t.Errorf("expected io.EOF at end of input, got %v", err)
+	}
+}
+
+// TestReadMessageInvalid tests that malformed headers are rejected.
+func TestReadMessageInvalid(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+	}{
+		{"missing length", "Content-Type: x\r\n\r\n{}"},
+		{"non-numeric length", "Content-Length: abc\r\n\r\n{}"},
+		{"negative length", "Content-Length: -1\r\n\r\n{}"},
+		{"truncated body", "Content-Length: 10\r\n\r\n{}"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := readMessage(bufio.NewReader(strings.NewReader(tt.input))); err == nil {
+				t.Error("expected error")
+			}
+		})
+	}
+}
+
+// TestWriteMessage tests that written messages can be read back.
+func TestWriteMessage(t *testing.T) {
+	var buf bytes.Buffer
+	if err := writeMessage(&buf, map[string]int{"a": 1}); err != nil {
+		t.Fatalf("writeMessage returned error: %v", err)
+	}
+	if !strings.HasPrefix(buf.String(), "Content-Length: 7\r\n\r\n") {
+		t.Errorf("unexpected header: %q", buf.String())
+	}
+
+	body, err := readMessage(bufio.NewReader(&buf))
+	if err != nil {
+		t.Fatalf("readMessage returned error: %v", err)
+	}
+	if string(body) != `{"a":1}` {
+		t.Errorf("body = %q", body)
+	}
+}
@@ -0,0 +1,115 @@
+package netsync
+
+import (
+	"cmp"
+	"slices"
+	"time"
+)
+
+// 推定に使うサンプルの数
+const (
+	maxSamples      = 32 // 保持するサンプルの数（古いものから捨てる）
+	minDriftSamples = 4  // ドリフトを推定するのに必要なサンプルの数
+)
+
+// maxDrift はドリフトとして受け入れる上限（水晶発振器の誤差は通常 ±100ppm 以下）
+const maxDrift = 500e-6
+
+// maxRTT は受け入れる往復時間の上限（これより遅い応答は LAN の外か古い応答とみなして捨てる）
+const maxRTT = time.Second
+
+// sample は1回の問い合わせの結果
+type sample struct {
+	local  time.Duration // 要求と応答の中間のフォロワーの時刻
+	offset time.Duration // マスターの時刻 - フォロワーの時刻
+	rtt    time.Duration // ネットワークの往復時間（マスターでの処理時間を除く）
+}
+
+// newSample は NTP と同じ方法で4つの時刻からサンプルを作る
+// t1: 要求を送った時刻、t2: マスターが受け取った時刻、t3: マスターが送った時刻、t4: 応答を受け取った時刻
+func newSample(t1, t2, t3, t4 time.Duration) (sample, bool) {
+	rtt := (t4 - t1) - (t3 - t2)
+	if t4 < t1 || t3 < t2 || rtt < 0 || rtt > maxRTT {
+		return sample{}, false
+	}
+	return sample{
+		local:  t1 + (t4-t1)/2,
+		offset: ((t2 - t1) + (t3 - t4)) / 2,
+		rtt:    rtt,
+	}, true
+}
+
+// estimator は最近のサンプルからマスターの時刻を推定する
+//
+// 往復時間が長いサンプルは経路の非対称で誤差が大きいため、往復時間の短い半分だけを使う。
+// サンプルが minDriftSamples 以上あれば、オフセットをフォロワーの時刻の一次式で近似し、
+// 傾きをドリフト（時計の進み方の違い）とする。
+type estimator struct {
+	samples []sample
+
+	ready  bool
+	base   time.Duration // 近似の基準となるフォロワーの時刻
+	offset time.Duration // base でのオフセット
+	drift  float64       // フォロワーの1秒あたりのオフセットの変化（秒/秒）
+	rtt    time.Duration // 使ったサンプルの最小の往復時間
+}
+
+// add はサンプルを追加して推定し直す
+func (e *estimator) add(s sample) {
+	e.samples = append(e.samples, s)
+	if len(e.samples) > maxSamples {
+		e.samples = e.samples[len(e.samples)-maxSamples:]
+	}
+	e.update()
+}
+
+// update は保持しているサンプルからオフセットとドリフトを推定する
+func (e *estimator) update() {
+	if len(e.samples) == 0 {
+		return
+	}
+
+	// 往復時間の短い半分を使う
+	best := slices.Clone(e.samples)
+	slices.SortStableFunc(best, func(a, b sample) int { return cmp.Compare(a.rtt, b.rtt) })
+	best = best[:(len(best)+1)/2]
+	e.rtt = best[0].rtt
+	e.ready = true
+
+	if len(e.samples) < minDriftSamples {
+		e.base, e.offset, e.drift = best[0].local, best[0].offset, 0
+		return
+	}
+
+	// 最小二乗法で offset = offset0 + drift * (local - mean) を求める
+	var meanLocal, meanOffset float64
+	for _, s := range best {
+		meanLocal += float64(s.local)
+		meanOffset += float64(s.offset)
+	}
+	meanLocal /= float64(len(best))
+	meanOffset /= float64(len(best))
+
+	var sxx, sxy float64
+	for _, s := range best {
+		dx := float64(s.local) - meanLocal
+		sxx += dx * dx
+		sxy += dx * (float64(s.offset) - meanOffset)
+	}
+	drift := 0.0
+	if sxx > 0 {
+		drift = max(-maxDrift, min(maxDrift, sxy/sxx))
+	}
+	e.base = time.Duration(meanLocal)
+	e.offset = time.Duration(meanOffset)
+	e.drift = drift
+}
+
+// masterTime はフォロワーの時刻 local に対応するマスターの時刻を返す
+func (e *estimator) masterTime(local time.Duration) (time.Duration, bool) {
+	if !e.ready {
+		return 0, false
+	}
+	correction := time.Duration(e.drift * float64(local-e.base))
+	return local + e.offset + correction, true
+}
@@ -0,0 +1,90 @@
+package netsync
+
+import (
+	"testing"
+	"time"
+)
+
+func TestNewSample(t *testing.T) {
+	// マスターの時計が1秒進んでいて、片道10ms、マスターでの処理に1msかかった場合
+	ms := time.Millisecond
+	s, ok := newSample(100*ms, 1110*ms, 1111*ms, 121*ms)
+	if !ok {
+		t.Fatal("newSample rejected a valid sample")
+	}
+	if s.offset != time.Second || s.rtt != 20*ms {
+		t.Errorf("sample = %+v, want offset 1s and rtt 20ms", s)
+	}
+
+	invalid := [][4]time.Duration{
+		{100 * ms, 0, 0, 50 * ms},            // 応答が要求より前
+		{0, 10 * ms, 5 * ms, 20 * ms},        // マスターの時刻が逆転
+		{0, 0, 0, maxRTT + time.Millisecond}, // 往復時間が長すぎる
+	}
+	for _, ts := range invalid {
+		if _, ok := newSample(ts[0], ts[1], ts[2], ts[3]); ok {
+			t.Errorf("newSample%v should be rejected", ts)
+		}
+	}
+}
+
+func TestEstimatorOffset(t *testing.T) {
+	var e estimator
+	if _, ok := e.masterTime(0); ok {
+		t.Fatal("masterTime should not be ready without samples")
+	}
+
+	// 往復時間の長いサンプルは経路の非対称でオフセットがずれているが、使われない
+	e.add(sample{local: time.Second, offset: 500 * time.Millisecond, rtt: 2 * time.Millisecond})
+	e.add(sample{local: 2 * time.Second, offset: 560 * time.Millisecond, rtt: 100 * time.Millisecond})
+	got, ok := e.masterTime(3 * time.Second)
+	if !ok || got != 3500*time.Millisecond {
+		t.Errorf("masterTime = %v (%v), want 3.5s", got, ok)
+	}
+}
+
+func TestEstimatorDrift(t *testing.T) {
+	// マスターの時計が 100ppm 速い
+	const drift = 100e-6
+	var e estimator
+	for i := range 10 {
+		local := time.Duration(i) * time.Second
+		offset := 2*time.Second + time.Duration(drift*float64(local))
+		e.add(sample{local: local, offset: offset, rtt: time.Millisecond})
+	}
+	if diff := e.drift - drift; diff > 1e-9 || diff < -1e-9 {
+		t.Errorf("drift = %g, want %g", e.drift, drift)
+	}
+
+	// 1時間後も推定がずれない
+	local := time.Hour
+	want := local + 2*time.Second + time.Duration(drift*float64(local))
+	got, _ := e.masterTime(local)
+	if diff := (got - want).Abs(); diff > time.Microsecond {
+		t.Errorf("masterTime(1h) = %v, want %v", got, want)
+	}
+}
+
+func TestEstimatorDriftClamped(t *testing.T) {
+	var e estimator
+	for i := range 8 {
+		local := time.Duration(i) * time.Second
+		e.add(sample{local: local, offset: local / 10, rtt: time.Millisecond}) // 10%（あり得ない値）
+	}
+	if e.drift != maxDrift {
+		t.Errorf("drift = %g, want clamped to %g", e.drift, maxDrift)
+	}
+}
+
+func TestEstimatorKeepsRecentSamples(t *testing.T) {
+	var e estimator
+	for i := range maxSamples + 10 {
+		e.add(sample{local: time.Duration(i) * time.Second, rtt: time.Millisecond})
+	}
+	if len(e.samples) != maxSamples {
+		t.Errorf("samples = %d, want %d", len(e.samples), maxSamples)
+	}
+	if e.samples[0].local != 10*time.Second {
+		t.Errorf("oldest sample = %v, want 10s", e.samples[0].local)
+	}
+}
@@ -0,0 +1,166 @@
+package netsync
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"sync"
+	"time"
+)
+
+// Follower polls a master and follows its clock.
+type Follower struct {
+	conn         net.Conn
+	start        time.Time // フォロワーの時刻の基準
+	pollInterval time.Duration
+	log          *slog.Logger
+
+	mu   sync.Mutex
+	est  estimator
+	seq  uint32
+	last time.Duration // Elapsed が最後に返した時刻（時刻を戻さないため）
+
+	stop chan struct{}
+	wg   sync.WaitGroup
+}
+
+// Status is the synchronization state of a follower.
+type Status struct {
+	Synced bool          // at least one answer has been received from the master
+	Offset time.Duration // master clock - follower clock (at the follower's current time)
+	Drift  float64       // how much faster the master's clock runs (seconds per second)
+	RTT    time.Duration // shortest network round trip among the samples in use
+}
+
+// Follow starts following the master at the UDP address masterAddr (such as "192.168.0.10:7400").
+// Elapsed reports false until the first answer arrives.
+func Follow(masterAddr string, opts ...Option) (*Follower, error) {
+	o := newOptions(opts)
+	conn, err := net.Dial("udp", masterAddr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to sync master %s: %w", masterAddr, err)
+	}
+	f := &Follower{
+		conn:         conn,
+		start:        time.Now(),
+		pollInterval: o.pollInterval,
+		log:          o.log,
+		stop:         make(chan struct{}),
+	}
+	f.wg.Add(2)
+	go f.poll()
+	go f.receive()
+	f.log.Info("Following sync master", "master", conn.RemoteAddr(), "pollInterval", f.pollInterval)
+	return f, nil
+}
+
+// Elapsed returns the master's clock estimated from the follower's clock.
+// The returned time never goes backwards, even when a new estimate is earlier.
+func (f *Follower) Elapsed() (time.Duration, bool) {
+	local := time.Since(f.start)
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	elapsed, ok := f.est.masterTime(local)
+	if !ok {
+		return 0, false
+	}
+	f.last = max(f.last, elapsed)
+	return f.last, true
+}
+
+// Status returns the current synchronization state.
+func (f *Follower) Status() Status {
+	local := time.Since(f.start)
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	master, ok := f.est.masterTime(local)
+	return Status{Synced: ok, Offset: master - local, Drift: f.est.drift, RTT: f.est.rtt}
+}
+
+// Close stops polling the master.
+func (f *Follower) Close() error {
+	close(f.stop)
+	err := f.conn.Close()
+	f.wg.Wait()
+	return err
+}
+
+// poll は pollInterval ごとにマスターに時刻を問い合わせる
+func (f *Follower) poll() {
+	defer f.wg.Done()
+	ticker := time.NewTicker(f.pollInterval)
+	defer ticker.Stop()
+	for {
+		f.request()
+		select {
+		case <-f.stop:
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// request は時刻の要求を1つ送る
+func (f *Follower) request() {
+	f.mu.Lock()
+	f.seq++
+	req := packet{kind: kindRequest, seq: f.seq}
+	f.mu.Unlock()
+
+	req.t1 = int64(time.Since(f.start))
+	if _, err := f.conn.Write(req.marshal()); err != nil && !errors.Is(err, net.ErrClosed) {
+		// マスターがまだ起動していない場合などは、次の問い合わせで再び試す
+		f.log.Debug("Failed to send sync request", "error", err)
+	}
+}
+
+// receive はマスターの応答を受け取ってサンプルに加える
+func (f *Follower) receive() {
+	defer f.wg.Done()
+	buf := make([]byte, packetSize+1)
+	for {
+		n, err := f.conn.Read(buf)
+		received := time.Since(f.start)
+		if err != nil {
+			if errors.Is(err, net.ErrClosed) {
+				return
+			}
+			// マスターが起動していないと ICMP port unreachable で読み込みが失敗することがある
+			select {
+			case <-f.stop:
+				return
+			default:
+			}
+			f.log.Debug("Failed to receive sync response", "error", err)
+			continue
+		}
+		resp, err := unmarshalPacket(buf[:n])
+		if err != nil || resp.kind != kindResponse {
+			f.log.Debug("Ignoring invalid sync packet", "error", err)
+			continue
+		}
+		f.addResponse(resp, received)
+	}
+}
+
+// addResponse は received に受け取った応答をサンプルとして加える
+func (f *Follower) addResponse(resp packet, received time.Duration) {
+	s, ok := newSample(time.Duration(resp.t1), time.Duration(resp.t2), time.Duration(resp.t3), received)
+	if !ok {
+		f.log.Debug("Ignoring sync response", "seq", resp.seq, "t1", resp.t1, "received", received)
+		return
+	}
+
+	f.mu.Lock()
+	first := !f.est.ready
+	f.est.add(s)
+	drift := f.est.drift
+	f.mu.Unlock()
+
+	if first {
+		f.log.Info("Synchronized with sync master", "offset", s.offset, "rtt", s.rtt)
+	} else {
+		f.log.Debug("Sync sample", "offset", s.offset, "rtt", s.rtt, "driftPPM", drift*1e6)
+	}
+}
@@ -0,0 +1,79 @@
+package netsync
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"time"
+)
+
+// Master answers the time requests of followers with the time elapsed since it started.
+type Master struct {
+	conn  net.PacketConn
+	start time.Time
+	log   *slog.Logger
+	done  chan struct{}
+}
+
+// Listen starts a master listening for followers on the UDP address addr (such as ":7400").
+// The shared clock starts now.
+func Listen(addr string, opts ...Option) (*Master, error) {
+	o := newOptions(opts)
+	conn, err := net.ListenPacket("udp", addr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to listen for sync followers on %s: %w", addr, err)
+	}
+	m := &Master{
+		conn:  conn,
+		start: time.Now(),
+		log:   o.log,
+		done:  make(chan struct{}),
+	}
+	go m.serve()
+	m.log.Info("Sync master started", "addr", conn.LocalAddr())
+	return m, nil
+}
+
+// Elapsed returns the time elapsed since the master started. It is always synchronized.
+func (m *Master) Elapsed() (time.Duration, bool) {
+	return time.Since(m.start), true
+}
+
+// Addr returns the UDP address the master listens on.
+func (m *Master) Addr() net.Addr {
+	return m.conn.LocalAddr()
+}
+
+// Close stops answering followers.
+func (m *Master) Close() error {
+	err := m.conn.Close()
+	<-m.done
+	return err
+}
+
+// serve は要求に応答する（Close まで続ける）
+func (m *Master) serve() {
+	defer close(m.done)
+	buf := make([]byte, packetSize+1) // 大きすぎるパケットを検出するため1バイト余分に読む
+	for {
+		n, from, err := m.conn.ReadFrom(buf)
+		received := time.Since(m.start)
+		if err != nil {
+			if !errors.Is(err, net.ErrClosed) {
+				m.log.Warn("Sync master stopped", "error", err)
+			}
+			return
+		}
+		req, err := unmarshalPacket(buf[:n])
+		if err != nil || req.kind != kindRequest {
+			m.log.Debug("Ignoring invalid sync packet", "from", from, "error", err)
+			continue
+		}
+		resp := packet{kind: kindResponse, seq: req.seq, t1: req.t1, t2: int64(received)}
+		resp.t3 = int64(time.Since(m.start))
+		if _, err := m.conn.WriteTo(resp.marshal(), from); err != nil {
+			m.log.Debug("Failed to answer sync request", "to", from, "error", err)
+		}
+	}
+}
@@ -0,0 +1,130 @@
+// Package netsync synchronizes the tick clocks of several son-et instances on a LAN.
+//
+// One instance is the master (--sync-master): it answers time requests over UDP
+// with the time elapsed since it started. The other instances are followers
+// (--sync-follow): they poll the master, estimate the offset between the master's
+// clock and their own from the round trip (as NTP does), and the drift of their
+// clock from a linear fit of recent offsets. Both expose the shared clock as a
+// Clock, which the TIME timer uses instead of its own ticker so that TIME event
+// n fires on every screen at the same moment.
+//
+// The protocol is one fixed-size datagram each way, with no authentication; it
+// is meant for a closed installation network.
+package netsync
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+)
+
+// Clock is the clock shared by the synchronized instances.
+type Clock interface {
+	// Elapsed returns the time elapsed on the master since it started.
+	// ok is false until the clock has been synchronized.
+	Elapsed() (elapsed time.Duration, ok bool)
+}
+
+// パケットの形式
+// magic(4) version(1) kind(1) reserved(2) seq(4) t1(8) t2(8) t3(8)、数値はビッグエンディアン
+// 要求と応答を同じ大きさにして、応答で通信量が増幅されないようにする
+const (
+	packetMagic   = "SNSY"
+	packetVersion = 1
+	packetSize    = 36
+)
+
+// パケットの種類
+const (
+	kindRequest  byte = 1 // フォロワーからマスターへの時刻の要求
+	kindResponse byte = 2 // マスターからの応答
+)
+
+// packet は時刻の要求または応答
+// 時刻はナノ秒で、t1・t4 はフォロワー、t2・t3 はマスターの起動からの経過時間
+type packet struct {
+	kind byte
+	seq  uint32
+	t1   int64 // フォロワーが要求を送った時刻（応答ではそのまま返す）
+	t2   int64 // マスターが要求を受け取った時刻
+	t3   int64 // マスターが応答を送った時刻
+}
+
+// errInvalidPacket は son-et の同期パケットでないデータを受け取ったことを表す
+var errInvalidPacket = errors.New("invalid sync packet")
+
+// marshal はパケットをバイト列にする
+func (p packet) marshal() []byte {
+	b := make([]byte, packetSize)
+	copy(b, packetMagic)
+	b[4] = packetVersion
+	b[5] = p.kind
+	binary.BigEndian.PutUint32(b[8:], p.seq)
+	binary.BigEndian.PutUint64(b[12:], uint64(p.t1))
+	binary.BigEndian.PutUint64(b[20:], uint64(p.t2))
+	binary.BigEndian.PutUint64(b[28:], uint64(p.t3))
+	return b
+}
+
+// unmarshalPacket はバイト列をパケットにする
+func unmarshalPacket(b []byte) (packet, error) {
+	if len(b) != packetSize || string(b[:4]) != packetMagic {
+		return packet{}, errInvalidPacket
+	}
+	if b[4] != packetVersion {
+		return packet{}, fmt.Errorf("%w: version %d (want %d)", errInvalidPacket, b[4], packetVersion)
+	}
+	p := packet{
+		kind: b[5],
+		seq:  binary.BigEndian.Uint32(b[8:]),
+		t1:   int64(binary.BigEndian.Uint64(b[12:])),
+		t2:   int64(binary.BigEndian.Uint64(b[20:])),
+		t3:   int64(binary.BigEndian.Uint64(b[28:])),
+	}
+	if p.kind != kindRequest && p.kind != kindResponse {
+		return packet{}, fmt.Errorf("%w: kind %d", errInvalidPacket, p.kind)
+	}
+	return p, nil
+}
+
+// DefaultPollInterval はフォロワーがマスターに時刻を問い合わせる既定の間隔
+const DefaultPollInterval = 500 * time.Millisecond
+
+// options はマスターとフォロワーの設定
+type options struct {
+	log          *slog.Logger
+	pollInterval time.Duration
+}
+
+// Option はマスターとフォロワーの設定を変更する
+type Option func(*options)
+
+// WithLogger sets the logger (slog.Default() by default).
+func WithLogger(log *slog.Logger) Option {
+	return func(o *options) {
+		if log != nil {
+			o.log = log
+		}
+	}
+}
+
+// WithPollInterval sets how often a follower polls the master (DefaultPollInterval by default).
+// It has no effect on a master.
+func WithPollInterval(d time.Duration) Option {
+	return func(o *options) {
+		if d > 0 {
+			o.pollInterval = d
+		}
+	}
+}
+
+// newOptions は既定値に opts を適用した設定を返す
+func newOptions(opts []Option) options {
+	o := options{log: slog.Default(), pollInterval: DefaultPollInterval}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return o
+}
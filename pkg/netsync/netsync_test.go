@@ -0,0 +1,160 @@
+package netsync
+
+import (
+	"errors"
+	"net"
+	"testing"
+	"time"
+)
+
+func TestPacketRoundTrip(t *testing.T) {
+	p := packet{kind: kindResponse, seq: 42, t1: 1, t2: -2, t3: int64(time.Hour)}
+	b := p.marshal()
+	if len(b) != packetSize {
+		t.Fatalf("packet size = %d, want %d", len(b), packetSize)
+	}
+	got, err := unmarshalPacket(b)
+	if err != nil {
+		t.Fatalf("unmarshalPacket failed: %v", err)
+	}
+	if got != p {
+		t.Errorf("round trip = %+v, want %+v", got, p)
+	}
+}
+
+func TestUnmarshalPacketInvalid(t *testing.T) {
+	valid := packet{kind: kindRequest}.marshal()
+	wrongVersion := packet{kind: kindRequest}.marshal()
+	wrongVersion[4] = packetVersion + 1
+	wrongKind := packet{kind: 9}.marshal()
+	wrongMagic := packet{kind: kindRequest}.marshal()
+	copy(wrongMagic, "HTTP")
+
+	for name, b := range map[string][]byte{
+		"short":   valid[:packetSize-1],
+		"long":    append(valid, 0),
+		"version": wrongVersion,
+		"kind":    wrongKind,
+		"magic":   wrongMagic,
+	} {
+		if _, err := unmarshalPacket(b); !errors.Is(err, errInvalidPacket) {
+			t.Errorf("%s: error = %v, want errInvalidPacket", name, err)
+		}
+	}
+}
+
+// startPair はループバックでマスターを起動し、delay の後にフォロワーを起動する
+func startPair(t *testing.T, delay time.Duration) (*Master, *Follower) {
+	t.Helper()
+	m, err := Listen("127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Listen failed: %v", err)
+	}
+	t.Cleanup(func() { m.Close() })
+	time.Sleep(delay)
+	f, err := Follow(m.Addr().String(), WithPollInterval(5*time.Millisecond))
+	if err != nil {
+		t.Fatalf("Follow failed: %v", err)
+	}
+	t.Cleanup(func() { f.Close() })
+	return m, f
+}
+
+// waitSynced は f が同期するまで待つ
+func waitSynced(t *testing.T, f *Follower) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for !f.Status().Synced {
+		if time.Now().After(deadline) {
+			t.Fatal("follower did not synchronize")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestFollowerFollowsMaster(t *testing.T) {
+	// フォロワーを後から起動し、マスターの時計が進んでいる状態で同期させる
+	const delay = 100 * time.Millisecond
+	m, f := startPair(t, delay)
+	waitSynced(t, f)
+	time.Sleep(50 * time.Millisecond)
+
+	followerTime, ok := f.Elapsed()
+	masterTime, _ := m.Elapsed()
+	if !ok {
+		t.Fatal("Elapsed should be synchronized")
+	}
+	if diff := (masterTime - followerTime).Abs(); diff > 20*time.Millisecond {
+		t.Errorf("follower clock %v differs from master %v by %v", followerTime, masterTime, diff)
+	}
+	if status := f.Status(); status.Offset < delay || status.RTT < 0 {
+		t.Errorf("status = %+v, want offset of at least %v", status, delay)
+	}
+}
+
+func TestFollowerWaitsForMaster(t *testing.T) {
+	// 応答しないアドレス（閉じたソケット）をマスターとして指定する
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("ListenPacket failed: %v", err)
+	}
+	addr := conn.LocalAddr().String()
+	conn.Close()
+
+	f, err := Follow(addr, WithPollInterval(5*time.Millisecond))
+	if err != nil {
+		t.Fatalf("Follow failed: %v", err)
+	}
+	defer f.Close()
+	time.Sleep(30 * time.Millisecond)
+	if _, ok := f.Elapsed(); ok {
+		t.Error("Elapsed should not be synchronized without a master")
+	}
+}
+
+func TestFollowerElapsedMonotonic(t *testing.T) {
+	_, f := startPair(t, 0)
+	waitSynced(t, f)
+
+	// 推定が過去に戻っても、Elapsed は戻らない
+	before, _ := f.Elapsed()
+	f.mu.Lock()
+	f.est.offset -= time.Second
+	f.mu.Unlock()
+	after, _ := f.Elapsed()
+	if after < before {
+		t.Errorf("Elapsed went backwards: %v -> %v", before, after)
+	}
+}
+
+func TestMasterIgnoresInvalidPackets(t *testing.T) {
+	m, err := Listen("127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Listen failed: %v", err)
+	}
+	defer m.Close()
+
+	conn, err := net.Dial("udp", m.Addr().String())
+	if err != nil {
+		t.Fatalf("Dial failed: %v", err)
+	}
+	defer conn.Close()
+
+	conn.Write([]byte("hello"))
+	conn.Write(packet{kind: kindResponse}.marshal())
+	conn.Write(packet{kind: kindRequest, seq: 7, t1: 123}.marshal())
+
+	conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	buf := make([]byte, packetSize)
+	n, err := conn.Read(buf)
+	if err != nil {
+		t.Fatalf("no response: %v", err)
+	}
+	resp, err := unmarshalPacket(buf[:n])
+	if err != nil {
+		t.Fatalf("invalid response: %v", err)
+	}
+	if resp.kind != kindResponse || resp.seq != 7 || resp.t1 != 123 || resp.t3 < resp.t2 {
+		t.Errorf("response = %+v, want the answer to request 7", resp)
+	}
+}
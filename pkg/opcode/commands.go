@@ -0,0 +1,48 @@
+package opcode
+
+import (
+	"cmp"
+	"slices"
+)
+
+// CommandInfo describes an OpCode command for documentation and external tools
+// (see son-et opcodes).
+type CommandInfo struct {
+	Cmd  Cmd      // Command name
+	Kind Kind     // Integer form of the command
+	Args []string // Layout of OpCode.Args
+}
+
+// commandArgs is the layout of OpCode.Args for every command.
+// Keep it in sync with the comments on the Cmd constants.
+var commandArgs = map[Cmd][]string{
+	Assign:               {"Variable(name)", "value"},
+	ArrayAssign:          {"Variable(arrayName)", "index", "value"},
+	Call:                 {"functionName", "arg..."},
+	BinaryOp:             {"operator", "leftOperand", "rightOperand"},
+	UnaryOp:              {"operator", "operand"},
+	ArrayAccess:          {"Variable(arrayName)", "index"},
+	If:                   {"condition", "thenBlock []OpCode", "elseBlock []OpCode"},
+	For:                  {"initBlock []OpCode", "condition", "postBlock []OpCode", "bodyBlock []OpCode"},
+	While:                {"condition", "bodyBlock []OpCode"},
+	Switch:               {"value", "cases []CaseClause", "defaultBlock []OpCode"},
+	Break:                {},
+	Continue:             {},
+	RegisterEventHandler: {"eventType string", "bodyBlock []OpCode"},
+	Wait:                 {"stepCount int"},
+	SetStep:              {"stepDuration int"},
+	DefineFunction:       {"functionName string", "parameters []map[string]any", "bodyBlock []OpCode"},
+	DebugBreak:           {"label", "line int"},
+	WaitAny:              {"ticks", "eventName..."},
+	Try:                  {"tryBlock []OpCode", "catchBlock []OpCode", "errorVar Variable"},
+}
+
+// Commands returns every OpCode command in Kind order.
+func Commands() []CommandInfo {
+	cmds := make([]CommandInfo, 0, len(cmdKinds))
+	for cmd, kind := range cmdKinds {
+		cmds = append(cmds, CommandInfo{Cmd: cmd, Kind: kind, Args: commandArgs[cmd]})
+	}
+	slices.SortFunc(cmds, func(a, b CommandInfo) int { return cmp.Compare(a.Kind, b.Kind) })
+	return cmds
+}
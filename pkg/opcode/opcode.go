@@ -74,8 +74,85 @@ const (
 	// Args: [functionName string, parameters []map[string]any, bodyBlock []OpCode]
 	// Each parameter map contains: name, type, isArray, and optionally default
 	DefineFunction Cmd = "DefineFunction"
+
+	// DebugBreak pauses the sequence while a debugger is attached (DebugBreak("label")).
+	// Args: [label, line int]
+	// The compiler records the source line so the debugger can show where the sequence stopped.
+	DebugBreak Cmd = "DebugBreak"
+
+	// WaitAny pauses the sequence until the first of several conditions occurs:
+	// a number of ticks, or one of the listed events (WaitAny(ticks, "KEY", "USER", ...)).
+	// Args: [ticks, eventName...]
+	// The condition that occurred is returned by GetWaitResult() after the sequence resumes.
+	WaitAny Cmd = "WaitAny"
+
+	// Try runs a block and, if a statement in it fails (a built-in function returns an
+	// error, an undefined function is called, ...), skips the rest of the block and runs
+	// the catch block instead of logging the error and continuing.
+	// Args: [tryBlock []OpCode, catchBlock []OpCode, errorVar Variable]
+	// errorVar receives the error message; it is "" when catch has no variable.
+	Try Cmd = "Try"
+)
+
+// Kind is the integer form of Cmd.
+// The VM dispatches on Kind through a table instead of comparing Cmd strings.
+type Kind uint8
+
+// Kinds of the OpCode commands. KindUnknown is the zero value of OpCode.Kind before
+// the VM decodes the program, and the kind of unknown commands.
+const (
+	KindUnknown Kind = iota
+	KindAssign
+	KindArrayAssign
+	KindCall
+	KindBinaryOp
+	KindUnaryOp
+	KindArrayAccess
+	KindIf
+	KindFor
+	KindWhile
+	KindSwitch
+	KindBreak
+	KindContinue
+	KindRegisterEventHandler
+	KindWait
+	KindSetStep
+	KindDefineFunction
+	KindDebugBreak
+	KindWaitAny
+	KindTry
+
+	// NumKinds is the number of kinds (the size of a dispatch table indexed by Kind).
+	NumKinds
 )
 
+var cmdKinds = map[Cmd]Kind{
+	Assign:               KindAssign,
+	ArrayAssign:          KindArrayAssign,
+	Call:                 KindCall,
+	BinaryOp:             KindBinaryOp,
+	UnaryOp:              KindUnaryOp,
+	ArrayAccess:          KindArrayAccess,
+	If:                   KindIf,
+	For:                  KindFor,
+	While:                KindWhile,
+	Switch:               KindSwitch,
+	Break:                KindBreak,
+	Continue:             KindContinue,
+	RegisterEventHandler: KindRegisterEventHandler,
+	Wait:                 KindWait,
+	SetStep:              KindSetStep,
+	DefineFunction:       KindDefineFunction,
+	DebugBreak:           KindDebugBreak,
+	WaitAny:              KindWaitAny,
+	Try:                  KindTry,
+}
+
+// Kind returns the integer form of the command (KindUnknown for unknown commands).
+func (c Cmd) Kind() Kind {
+	return cmdKinds[c]
+}
+
 // OpCode represents a single instruction for the VM.
 // It consists of a command type (Cmd) and a slice of arguments (Args).
 // The Args can contain various types including:
@@ -86,6 +163,14 @@ const (
 type OpCode struct {
 	Cmd  Cmd
 	Args []any
+
+	// Kind is Cmd as an integer. The compiler leaves it zero (KindUnknown); the VM fills it
+	// in when it loads the program, and falls back to Cmd.Kind() for OpCodes it did not load.
+	Kind Kind
+	// Decoded holds the arguments pre-decoded by the VM at load time into a typed struct
+	// private to the VM (for example the lower-cased name of a Call), so that they are
+	// not type-asserted again on every execution. Nil if the OpCode was not decoded.
+	Decoded any
 }
 
 // Variable represents a variable reference in OpCode arguments.
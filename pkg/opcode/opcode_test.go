@@ -0,0 +1,47 @@
+package opcode
+
+import "testing"
+
+// TestCmdKind tests that every command has its own kind within the dispatch table size.
+func TestCmdKind(t *testing.T) {
+	cmds := []Cmd{
+		Assign, ArrayAssign, Call, BinaryOp, UnaryOp, ArrayAccess, If, For, While, Switch,
+		Break, Continue, RegisterEventHandler, Wait, SetStep, DefineFunction, DebugBreak, WaitAny, Try,
+	}
+	seen := make(map[Kind]Cmd)
+	for _, cmd := range cmds {
+		kind := cmd.Kind()
+		if kind == KindUnknown || kind >= NumKinds {
+			t.Errorf("%s.Kind() = %d, want 1..%d", cmd, kind, NumKinds-1)
+		}
+		if other, dup := seen[kind]; dup {
+			t.Errorf("%s and %s have the same kind %d", cmd, other, kind)
+		}
+		seen[kind] = cmd
+	}
+	if len(seen) != int(NumKinds)-1 {
+		t.Errorf("%d commands have kinds, want %d", len(seen), NumKinds-1)
+	}
+	if got := Cmd("NoSuchCmd").Kind(); got != KindUnknown {
+		t.Errorf("unknown command kind = %d, want KindUnknown", got)
+	}
+}
+
+// TestCommands tests that every command is listed once in Kind order with its argument layout.
+func TestCommands(t *testing.T) {
+	cmds := Commands()
+	if len(cmds) != int(NumKinds)-1 {
+		t.Fatalf("%d commands, want %d", len(cmds), NumKinds-1)
+	}
+	for i, c := range cmds {
+		if c.Kind != Kind(i+1) || c.Cmd.Kind() != c.Kind {
+			t.Errorf("command %d = %s (kind %d), want kind %d", i, c.Cmd, c.Kind, i+1)
+		}
+		if _, ok := commandArgs[c.Cmd]; !ok {
+			t.Errorf("%s has no argument layout", c.Cmd)
+		}
+	}
+	if got := cmds[0]; got.Cmd != Assign || len(got.Args) != 2 {
+		t.Errorf("first command = %+v, want Assign with 2 arguments", got)
+	}
+}
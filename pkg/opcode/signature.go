@@ -0,0 +1,541 @@
+package opcode
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// ArgType is the type of a built-in function argument.
+type ArgType int
+
+// Argument types used by the signature table.
+const (
+	// ArgAny accepts any value (the built-in converts it itself).
+	ArgAny ArgType = iota
+	// ArgInt accepts numbers; numeric strings are converted to int64 (an empty string is 0).
+	ArgInt
+	// ArgNumber accepts numbers including fractions; numeric strings are converted to float64.
+	ArgNumber
+	// ArgString accepts strings only.
+	ArgString
+)
+
+// String returns the name of the type used in diagnostics.
+func (t ArgType) String() string {
+	switch t {
+	case ArgInt:
+		return "int"
+	case ArgNumber:
+		return "number"
+	case ArgString:
+		return "string"
+	default:
+		return "any"
+	}
+}
+
+// Signature describes the arguments of a built-in function.
+// The same table is consulted by the compiler (literal arguments and argument counts are
+// checked before playback) and by the VM (evaluated arguments are converted and checked
+// when the built-in is dispatched).
+type Signature struct {
+	Name     string    // Canonical function name
+	Args     []ArgType // Argument types, including optional arguments
+	Required int       // Number of required arguments
+	Variadic bool      // The last argument type repeats (no upper limit on the count)
+	Invalid  any       // Value returned by the VM when the arguments are invalid (nil = 0)
+}
+
+// MaxArgs returns the largest accepted argument count, or -1 if there is no limit.
+func (s *Signature) MaxArgs() int {
+	if s.Variadic {
+		return -1
+	}
+	return len(s.Args)
+}
+
+// ArgType returns the type of the i-th (0-based) argument.
+func (s *Signature) ArgType(i int) ArgType {
+	switch {
+	case i < len(s.Args):
+		return s.Args[i]
+	case s.Variadic && len(s.Args) > 0:
+		return s.Args[len(s.Args)-1]
+	default:
+		return ArgAny
+	}
+}
+
+// InvalidResult returns the value the VM returns instead of calling the built-in
+// when the arguments are invalid.
+func (s *Signature) InvalidResult() any {
+	if s.Invalid == nil {
+		return int64(0)
+	}
+	return s.Invalid
+}
+
+// CheckCount checks the number of arguments.
+func (s *Signature) CheckCount(n int) error {
+	if n < s.Required || (!s.Variadic && n > len(s.Args)) {
+		return &ArgError{Func: s.Name, Index: 0, Count: n, sig: s}
+	}
+	return nil
+}
+
+// ConvertArgs checks the types of the arguments and returns them with numeric strings
+// converted for int and number arguments. The argument count is not checked (see CheckCount).
+// Values that are only known at run time (Variable and OpCode in compiled code) are skipped,
+// so the compiler can check the literal arguments of a call with the same method.
+// The returned slice is a copy only when a value was converted.
+func (s *Signature) ConvertArgs(args []any) ([]any, error) {
+	converted := args
+	copied := false
+	for i, arg := range args {
+		want := s.ArgType(i)
+		v, changed, ok := convertArg(want, arg)
+		if !ok {
+			return args, &ArgError{Func: s.Name, Index: i + 1, Want: want, Got: arg, sig: s}
+		}
+		if changed {
+			if !copied {
+				converted = append([]any(nil), args...)
+				copied = true
+			}
+			converted[i] = v
+		}
+	}
+	return converted, nil
+}
+
+// convertArg converts a value to the argument type.
+// changed reports whether the value was converted; ok is false if the value can never be
+// used as the type.
+func convertArg(want ArgType, arg any) (v any, changed, ok bool) {
+	switch arg.(type) {
+	case nil, Variable, OpCode:
+		// Known only at run time (or an uninitialized value the built-in treats as 0)
+		return arg, false, true
+	}
+	switch want {
+	case ArgInt, ArgNumber:
+		switch val := arg.(type) {
+		case int, int64, float64, bool:
+			return arg, false, true
+		case string:
+			str := strings.TrimSpace(val)
+			if str == "" {
+				// An empty (uninitialized) string is 0, as before the check
+				return zeroOf(want), true, true
+			}
+			if i, err := strconv.ParseInt(str, 0, 64); err == nil {
+				if want == ArgNumber {
+					return float64(i), true, true
+				}
+				return i, true, true
+			}
+			if f, err := strconv.ParseFloat(str, 64); err == nil {
+				if want == ArgInt {
+					return int64(f), true, true
+				}
+				return f, true, true
+			}
+		}
+		return nil, false, false
+	case ArgString:
+		_, ok := arg.(string)
+		return arg, false, ok
+	default:
+		return arg, false, true
+	}
+}
+
+// zeroOf returns 0 of the Go type a numeric argument type is converted to.
+func zeroOf(want ArgType) any {
+	if want == ArgNumber {
+		return float64(0)
+	}
+	return int64(0)
+}
+
+// ArgError describes arguments that do not match a built-in function's signature.
+type ArgError struct {
+	Func  string  // Function name
+	Index int     // 1-based argument number, or 0 for a wrong argument count
+	Want  ArgType // Expected type (type errors)
+	Got   any     // Actual value (type errors)
+	Count int     // Number of arguments passed (count errors)
+	sig   *Signature
+}
+
+// Error implements the error interface.
+// Example: picture(): arg 2 expects int, got string "abc"
+func (e *ArgError) Error() string {
+	if e.Index > 0 {
+		return fmt.Sprintf("%s(): arg %d expects %s, got %s", e.Func, e.Index, e.Want, describeValue(e.Got))
+	}
+	sig := e.signature()
+	if sig == nil {
+		return fmt.Sprintf("%s(): unexpected argument count %d", e.Func, e.Count)
+	}
+	return fmt.Sprintf("%s(): expects %s, got %d", e.Func, describeCount(sig), e.Count)
+}
+
+// ExtraArgs reports whether the error is that more arguments were passed than the
+// function takes. The VM ignores the extra arguments, so callers report this as a
+// warning rather than as an invalid call.
+func (e *ArgError) ExtraArgs() bool {
+	sig := e.signature()
+	return e.Index == 0 && sig != nil && !sig.Variadic && e.Count > len(sig.Args)
+}
+
+// signature returns the signature the arguments were checked against. An ArgError
+// that was decoded (e.g. from the codegen cache) looks it up by name.
+func (e *ArgError) signature() *Signature {
+	if e.sig == nil {
+		e.sig, _ = LookupSignature(e.Func)
+	}
+	return e.sig
+}
+
+// describeValue returns the type and value of an argument for diagnostics.
+func describeValue(v any) string {
+	switch val := v.(type) {
+	case string:
+		return fmt.Sprintf("string %q", val)
+	case int, int64:
+		return fmt.Sprintf("int %d", val)
+	case float64:
+		return fmt.Sprintf("number %g", val)
+	default:
+		return fmt.Sprintf("%T", v)
+	}
+}
+
+// describeCount describes the accepted argument count of a signature.
+func describeCount(s *Signature) string {
+	plural := func(n int) string {
+		if n == 1 {
+			return "1 argument"
+		}
+		return fmt.Sprintf("%d arguments", n)
+	}
+	switch {
+	case s.Variadic:
+		return "at least " + plural(s.Required)
+	case s.Required == len(s.Args):
+		return plural(s.Required)
+	default:
+		return fmt.Sprintf("%d to %s", s.Required, plural(len(s.Args)))
+	}
+}
+
+// LookupSignature returns the signature of a built-in function (case-insensitive),
+// including the functions added with RegisterSignature.
+func LookupSignature(name string) (*Signature, bool) {
+	if s, ok := signatureIndex[name]; ok {
+		return s, true
+	}
+	if s, ok := signatureIndex[strings.ToLower(name)]; ok {
+		return s, true
+	}
+	extensionSignatures.RLock()
+	defer extensionSignatures.RUnlock()
+	s, ok := extensionSignatures.index[strings.ToLower(name)]
+	return s, ok
+}
+
+// RegisterSignature adds the signature of a built-in function implemented outside the
+// engine (see package engine). It fails if the signature is malformed or a built-in
+// function with the same name (case-insensitive) already has a signature.
+// Signatures() lists only the engine's own functions.
+func RegisterSignature(sig Signature) error {
+	if err := sig.validate(); err != nil {
+		return err
+	}
+	key := strings.ToLower(sig.Name)
+	if _, ok := signatureIndex[key]; ok {
+		return fmt.Errorf("%s is already a built-in function", sig.Name)
+	}
+	extensionSignatures.Lock()
+	defer extensionSignatures.Unlock()
+	if _, ok := extensionSignatures.index[key]; ok {
+		return fmt.Errorf("%s is already registered", sig.Name)
+	}
+	sig.Args = append([]ArgType(nil), sig.Args...)
+	extensionSignatures.index[key] = &sig
+	return nil
+}
+
+// validate は登録するシグネチャの引数の数が矛盾していないか調べる
+func (s *Signature) validate() error {
+	switch {
+	case s.Name == "":
+		return fmt.Errorf("signature has no function name")
+	case s.Required < 0 || s.Required > len(s.Args):
+		return fmt.Errorf("%s: %d required arguments with %d argument types", s.Name, s.Required, len(s.Args))
+	case s.Variadic && len(s.Args) == 0:
+		return fmt.Errorf("%s: variadic signature has no argument type", s.Name)
+	}
+	return nil
+}
+
+// extensionSignatures は RegisterSignature で追加したシグネチャ（小文字の名前で引く）
+// 拡張はプログラムの初期化中に登録されるが、VM の実行中にも引くためロックで保護する
+var extensionSignatures = struct {
+	sync.RWMutex
+	index map[string]*Signature
+}{index: make(map[string]*Signature)}
+
+// Signatures returns the signatures of all built-in functions, in table order.
+func Signatures() []Signature {
+	return signatures
+}
+
+// signatureIndex indexes signatures by canonical and lowercase name.
+var signatureIndex = func() map[string]*Signature {
+	index := make(map[string]*Signature, len(signatures)*2)
+	for i := range signatures {
+		s := &signatures[i]
+		index[s.Name] = s
+		index[strings.ToLower(s.Name)] = s
+	}
+	return index
+}()
+
+// repeat returns n arguments of the same type.
+func repeat(t ArgType, n int) []ArgType {
+	args := make([]ArgType, n)
+	for i := range args {
+		args[i] = t
+	}
+	return args
+}
+
+// signatures is the signature table of every built-in function registered by the VM.
+// Keep it in sync with the VM's built-ins (pkg/vm) and the LSP documentation (pkg/lsp).
+var signatures = []Signature{
+	// Windows
+	{Name: "OpenWin", Args: repeat(ArgInt, 8), Required: 1, Invalid: int64(-1)},
+	{Name: "MoveWin", Args: repeat(ArgInt, 8), Required: 1},
+	{Name: "CloseWin", Args: []ArgType{ArgInt}, Required: 1},
+	{Name: "CloseWinAll"},
+	{Name: "CapTitle", Args: []ArgType{ArgAny, ArgAny}, Required: 1},
+	{Name: "GetPicNo", Args: []ArgType{ArgInt}, Required: 1, Invalid: int64(-1)},
+	{Name: "WinInfo", Args: []ArgType{ArgInt}, Required: 1},
+	{Name: "BringWinToFront", Args: []ArgType{ArgInt}, Required: 1},
+	{Name: "SendWinToBack", Args: []ArgType{ArgInt}, Required: 1},
+
+	// Pictures
+	{Name: "LoadPic", Args: []ArgType{ArgString}, Required: 1, Invalid: int64(-1)},
+	{Name: "LoadPicAsync", Args: []ArgType{ArgString}, Required: 1, Invalid: int64(-1)},
+	{Name: "CreatePic", Args: repeat(ArgInt, 3), Required: 1, Invalid: int64(-1)},
+	{Name: "MovePic", Args: repeat(ArgInt, 10), Required: 2},
+	{Name: "MoveSPic", Args: repeat(ArgInt, 10), Required: 10},
+	{Name: "TransPic", Args: repeat(ArgInt, 9), Required: 9},
+	{Name: "ReversePic", Args: repeat(ArgInt, 8), Required: 8},
+	{Name: "DelPic", Args: []ArgType{ArgInt}, Required: 1},
+	{Name: "PicWidth", Args: []ArgType{ArgInt}, Required: 1},
+	{Name: "PicHeight", Args: []ArgType{ArgInt}, Required: 1},
+
+	// Casts
+	{Name: "PutCast", Args: repeat(ArgInt, 12), Required: 4, Invalid: int64(-1)},
+	{Name: "MoveCast", Args: repeat(ArgInt, 9), Required: 3},
+	{Name: "DelCast", Args: []ArgType{ArgInt}, Required: 1},
+	{Name: "PutFlipbook", Args: []ArgType{ArgString, ArgInt, ArgInt, ArgInt, ArgInt, ArgInt}, Required: 4, Invalid: int64(-1)},
+	{Name: "ChangePicture", Args: repeat(ArgInt, 3), Required: 2},
+	{Name: "BringCastToFront", Args: []ArgType{ArgInt}, Required: 1},
+	{Name: "SendCastToBack", Args: []ArgType{ArgInt}, Required: 1},
+
+	// Sprite pools
+	{Name: "CreateSpritePool", Args: repeat(ArgInt, 4), Required: 3, Invalid: int64(-1)},
+	{Name: "SetPoolSprite", Args: repeat(ArgInt, 5), Required: 4},
+	{Name: "ScatterPool", Args: repeat(ArgInt, 5), Required: 5},
+	{Name: "SetPoolVelocity", Args: repeat(ArgNumber, 4), Required: 3},
+	{Name: "StepPool", Args: []ArgType{ArgInt}, Required: 1},
+	{Name: "DelSpritePool", Args: []ArgType{ArgInt}, Required: 1},
+
+	// Masks
+	{Name: "SetCastMask", Args: repeat(ArgInt, 5), Required: 5},
+	{Name: "SetCastMaskPic", Args: repeat(ArgInt, 4), Required: 2},
+	{Name: "DelCastMask", Args: []ArgType{ArgInt}, Required: 1},
+	{Name: "SetWinMask", Args: repeat(ArgInt, 5), Required: 5},
+	{Name: "SetWinMaskPic", Args: repeat(ArgInt, 4), Required: 2},
+	{Name: "DelWinMask", Args: []ArgType{ArgInt}, Required: 1},
+
+	// Shadows and outlines
+	{Name: "SetShadow", Args: repeat(ArgInt, 5), Required: 5},
+	{Name: "DelShadow", Args: []ArgType{ArgInt}, Required: 1},
+	{Name: "SetOutline", Args: repeat(ArgInt, 3), Required: 3},
+	{Name: "DelOutline", Args: []ArgType{ArgInt}, Required: 1},
+	{Name: "Shake", Args: repeat(ArgInt, 3), Required: 3},
+	{Name: "Flash", Args: repeat(ArgInt, 3), Required: 3},
+
+	// Motion along paths
+	{Name: "DefinePath", Args: repeat(ArgAny, 4), Required: 4, Variadic: true, Invalid: int64(-1)},
+	{Name: "DelPath", Args: []ArgType{ArgInt}, Required: 1},
+	{Name: "MoveAlongPath", Args: repeat(ArgInt, 4), Required: 3},
+
+	// Camera
+	{Name: "SetCamera", Args: repeat(ArgNumber, 3), Required: 0},
+	{Name: "CameraMove", Args: []ArgType{ArgNumber, ArgNumber, ArgNumber, ArgInt, ArgInt}, Required: 4},
+
+	// Source rects and nine-slice
+	{Name: "SetSourceRect", Args: repeat(ArgInt, 5), Required: 5},
+	{Name: "DelSourceRect", Args: []ArgType{ArgInt}, Required: 1},
+	{Name: "SetNineSlice", Args: repeat(ArgInt, 7), Required: 7},
+	{Name: "DelNineSlice", Args: []ArgType{ArgInt}, Required: 1},
+
+	// Mouse cursor
+	{Name: "SetCursor", Args: repeat(ArgInt, 4), Required: 1},
+	{Name: "SetCursorClick", Args: repeat(ArgInt, 3), Required: 2},
+	{Name: "DelCursor"},
+	{Name: "ShowSysCursor", Args: []ArgType{ArgInt}, Required: 1},
+
+	// Application window
+	{Name: "SetWindowTitle", Args: []ArgType{ArgString}, Required: 1},
+	{Name: "SetWindowIcon", Args: []ArgType{ArgString}, Required: 1},
+	{Name: "SetWindowSize", Args: []ArgType{ArgInt, ArgInt}, Required: 2},
+	{Name: "GetDisplayScale"},
+
+	// System queries
+	{Name: "GetScreenWidth"},
+	{Name: "GetScreenHeight"},
+	{Name: "GetPlatform"},
+	{Name: "IsHeadless"},
+	{Name: "GetLang"},
+	{Name: "HasSoundFont"},
+
+	// Image export
+	{Name: "SaveSprite", Args: []ArgType{ArgString, ArgInt}, Required: 1},
+
+	// Text
+	{Name: "SetFont", Args: []ArgType{ArgInt, ArgAny, ArgAny}, Required: 2, Variadic: true},
+	{Name: "TextWrite", Args: []ArgType{ArgString, ArgInt, ArgInt, ArgInt, ArgAny, ArgInt, ArgInt}, Required: 4},
+	{Name: "TextWidth", Args: []ArgType{ArgAny, ArgAny, ArgInt, ArgAny, ArgInt, ArgInt}, Required: 1},
+	{Name: "TextHeight", Args: []ArgType{ArgAny, ArgAny, ArgInt, ArgAny, ArgInt, ArgInt}, Required: 1},
+	{Name: "TextColor", Args: repeat(ArgInt, 3), Required: 1},
+	{Name: "BgColor", Args: repeat(ArgInt, 3), Required: 1},
+	{Name: "BackMode", Args: []ArgType{ArgInt}, Required: 1},
+	{Name: "TextDirection", Args: []ArgType{ArgAny}, Required: 1},
+
+	// Drawing
+	{Name: "DrawLine", Args: repeat(ArgInt, 5), Required: 5},
+	{Name: "DrawCircle", Args: repeat(ArgInt, 5), Required: 5},
+	{Name: "DrawRect", Args: repeat(ArgInt, 8), Required: 6},
+	{Name: "FillRect", Args: repeat(ArgInt, 6), Required: 6},
+	{Name: "SetLineSize", Args: []ArgType{ArgInt}, Required: 1},
+	{Name: "SetPaintColor", Args: repeat(ArgInt, 3), Required: 1},
+	{Name: "SetColor", Args: repeat(ArgInt, 3), Required: 1},
+	{Name: "GetColor", Args: repeat(ArgInt, 3), Required: 3},
+
+	// Screen effects and palette
+	{Name: "FadeOut", Args: []ArgType{ArgInt, ArgInt}, Required: 1},
+	{Name: "FadeIn", Args: []ArgType{ArgInt, ArgInt}, Required: 1},
+	{Name: "SetPalette", Args: []ArgType{ArgInt, ArgInt}, Required: 2},
+	{Name: "GetPalette", Args: []ArgType{ArgInt}, Required: 1, Invalid: int64(-1)},
+	{Name: "CyclePalette", Args: repeat(ArgInt, 3), Required: 2},
+	{Name: "ResetPalette"},
+	{Name: "SetGamma", Args: []ArgType{ArgNumber}, Required: 1},
+	{Name: "SetBrightness", Args: []ArgType{ArgNumber}, Required: 1},
+	{Name: "SetContrast", Args: []ArgType{ArgNumber}, Required: 1},
+
+	// Audio
+	{Name: "PlayMIDI", Args: []ArgType{ArgString}, Required: 1},
+	{Name: "PlayWAVE", Args: []ArgType{ArgString}, Required: 1},
+	{Name: "SetVolume", Args: []ArgType{ArgAny, ArgInt}, Required: 1},
+	{Name: "GetVolume", Args: []ArgType{ArgAny}},
+	{Name: "SetMute", Args: []ArgType{ArgAny, ArgInt}, Required: 1},
+	{Name: "PlayVoice", Args: []ArgType{ArgString}, Required: 1},
+	{Name: "SetDucking", Args: repeat(ArgInt, 3), Required: 1},
+	{Name: "SetSynthQuality", Args: repeat(ArgInt, 2), Required: 1},
+	{Name: "PlayMIDIPort", Args: []ArgType{ArgString, ArgString}, Required: 2},
+	{Name: "StopMIDIPort", Args: []ArgType{ArgString}, Required: 1},
+	{Name: "MIDIClock", Args: []ArgType{ArgString}, Required: 1, Invalid: int64(-1)},
+	{Name: "SetMIDIClock", Args: []ArgType{ArgString}, Required: 1},
+	{Name: "OSCSend", Args: []ArgType{ArgString, ArgString, ArgAny}, Required: 2, Variadic: true},
+
+	// Input events
+	{Name: "OnKey", Args: []ArgType{ArgAny, ArgAny, ArgInt}, Required: 2},
+	{Name: "OnClick", Args: []ArgType{ArgAny}, Required: 1},
+	{Name: "OnSpriteClick", Args: []ArgType{ArgInt, ArgAny}, Required: 2},
+	{Name: "OnSpriteTick", Args: []ArgType{ArgInt, ArgAny, ArgInt}, Required: 2},
+	{Name: "OnNote", Args: []ArgType{ArgInt, ArgInt, ArgAny, ArgAny}, Required: 3},
+	{Name: "BindNote", Args: []ArgType{ArgInt, ArgInt, ArgAny, ArgAny}, Required: 3},
+	{Name: "HighlightText", Args: []ArgType{ArgString, ArgInt, ArgInt, ArgInt, ArgInt, ArgInt}, Required: 5},
+	{Name: "Karaoke", Args: repeat(ArgInt, 4), Required: 3},
+	{Name: "OnExit", Args: []ArgType{ArgAny, ArgInt}, Required: 1},
+	{Name: "Chapter", Args: []ArgType{ArgString}, Required: 1},
+
+	// Integers
+	{Name: "Random", Args: []ArgType{ArgInt, ArgInt}, Required: 1},
+	{Name: "MakeLong", Args: []ArgType{ArgInt, ArgInt}, Required: 2},
+	{Name: "GetHiWord", Args: []ArgType{ArgInt}, Required: 1},
+	{Name: "GetLowWord", Args: []ArgType{ArgInt}, Required: 1},
+
+	// Strings
+	{Name: "StrPrint", Args: []ArgType{ArgString, ArgAny}, Required: 1, Variadic: true, Invalid: ""},
+	{Name: "StrCode", Args: []ArgType{ArgInt}, Required: 1, Invalid: ""},
+	{Name: "StrLen", Args: []ArgType{ArgAny}, Required: 1},
+	{Name: "SubStr", Args: []ArgType{ArgAny, ArgInt, ArgInt}, Required: 3, Invalid: ""},
+	{Name: "StrFind", Args: []ArgType{ArgAny, ArgAny}, Required: 2},
+	{Name: "StrUp", Args: []ArgType{ArgAny}, Required: 1},
+	{Name: "StrLow", Args: []ArgType{ArgAny}, Required: 1},
+	{Name: "CharCode", Args: []ArgType{ArgAny, ArgInt}, Required: 2},
+	{Name: "FormatNumber", Args: []ArgType{ArgNumber, ArgInt}, Required: 1, Invalid: ""},
+	{Name: "FormatDate", Args: []ArgType{ArgString, ArgInt}, Required: 1, Invalid: ""},
+
+	// Arrays
+	{Name: "ArraySize", Args: []ArgType{ArgAny}, Required: 1},
+	{Name: "DelArrayAll", Args: []ArgType{ArgAny}, Required: 1},
+	{Name: "DelArrayAt", Args: []ArgType{ArgAny, ArgInt}, Required: 2},
+	{Name: "InsArrayAt", Args: []ArgType{ArgAny, ArgInt, ArgAny}, Required: 3},
+
+	// Files
+	{Name: "OpenF", Args: []ArgType{ArgAny, ArgInt}, Required: 1},
+	{Name: "CloseF", Args: []ArgType{ArgInt}, Required: 1},
+	{Name: "SeekF", Args: repeat(ArgInt, 3), Required: 3},
+	{Name: "ReadF", Args: []ArgType{ArgInt, ArgInt}, Required: 2},
+	{Name: "WriteF", Args: []ArgType{ArgInt, ArgAny, ArgInt}, Required: 2},
+	{Name: "StrReadF", Args: []ArgType{ArgInt}, Required: 1, Invalid: ""},
+	{Name: "StrWriteF", Args: []ArgType{ArgInt, ArgAny}, Required: 2},
+	{Name: "GetIniStr", Args: repeat(ArgAny, 4), Required: 3},
+	{Name: "GetIniInt", Args: repeat(ArgAny, 4), Required: 3},
+	{Name: "WriteIniInt", Args: []ArgType{ArgAny, ArgAny, ArgInt, ArgAny}, Required: 4},
+	{Name: "WriteIniStr", Args: repeat(ArgAny, 4), Required: 4},
+
+	// Message blocks
+	{Name: "del_me"},
+	{Name: "del_us"},
+	{Name: "del_all"},
+	{Name: "end_step"},
+	{Name: "Wait", Args: []ArgType{ArgNumber}},
+	{Name: "WaitAny", Args: []ArgType{ArgInt, ArgString}, Required: 1, Variadic: true},
+	{Name: "GetWaitResult"},
+	{Name: "ExitTitle"},
+	{Name: "GetMesNo", Args: []ArgType{ArgInt}},
+	{Name: "DelMes", Args: []ArgType{ArgInt}, Required: 1},
+	{Name: "FreezeMes", Args: []ArgType{ArgInt}, Required: 1},
+	{Name: "ActivateMes", Args: []ArgType{ArgInt}, Required: 1},
+	{Name: "SetTickPolicy", Args: []ArgType{ArgInt, ArgInt}, Required: 2},
+	{Name: "GetDroppedTicks", Args: []ArgType{ArgInt}, Required: 1},
+	{Name: "PostMes", Args: repeat(ArgInt, 5), Required: 5},
+	{Name: "SetTimer", Args: []ArgType{ArgInt, ArgAny, ArgInt}, Required: 2},
+	{Name: "KillTimer", Args: []ArgType{ArgInt}, Required: 1},
+	{Name: "Spawn", Args: []ArgType{ArgAny, ArgAny}, Required: 1, Variadic: true},
+	{Name: "Kill", Args: []ArgType{ArgInt}, Required: 1},
+	{Name: "SendMes", Args: repeat(ArgInt, 5), Required: 1},
+
+	// System
+	{Name: "GetSysTime"},
+	{Name: "Shell", Args: []ArgType{ArgAny}, Variadic: true},
+	{Name: "MCI", Args: []ArgType{ArgAny}, Variadic: true},
+	{Name: "StrMCI", Args: []ArgType{ArgAny}, Variadic: true},
+	{Name: "MsgBox", Args: []ArgType{ArgAny, ArgInt}, Required: 1},
+	{Name: "SaveValue", Args: []ArgType{ArgAny, ArgAny}, Required: 2},
+	{Name: "LoadValue", Args: []ArgType{ArgAny, ArgAny}, Required: 1},
+	{Name: "Debug", Args: []ArgType{ArgAny}},
+	{Name: "DebugBreak", Args: []ArgType{ArgAny}},
+}
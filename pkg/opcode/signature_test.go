@@ -0,0 +1,208 @@
+package opcode
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// TestSignatureTable tests that the signature table is consistent.
+func TestSignatureTable(t *testing.T) {
+	seen := make(map[string]bool)
+	for _, sig := range Signatures() {
+		key := strings.ToLower(sig.Name)
+		if seen[key] {
+			t.Errorf("duplicate signature %s", sig.Name)
+		}
+		seen[key] = true
+		if sig.Required < 0 || sig.Required > len(sig.Args) {
+			t.Errorf("%s: Required %d out of range (%d args)", sig.Name, sig.Required, len(sig.Args))
+		}
+		if sig.Variadic && len(sig.Args) == 0 {
+			t.Errorf("%s: variadic signature without argument types", sig.Name)
+		}
+	}
+}
+
+// TestLookupSignature tests that signatures are found case-insensitively.
+func TestLookupSignature(t *testing.T) {
+	for _, name := range []string{"LoadPic", "loadpic", "LOADPIC"} {
+		sig, ok := LookupSignature(name)
+		if !ok || sig.Name != "LoadPic" {
+			t.Errorf("LookupSignature(%q) = %v, %v", name, sig, ok)
+		}
+	}
+	if _, ok := LookupSignature("NoSuchFunction"); ok {
+		t.Error("unknown functions should have no signature")
+	}
+}
+
+// TestRegisterSignature tests adding the signature of a function implemented outside the engine.
+func TestRegisterSignature(t *testing.T) {
+	args := []ArgType{ArgString, ArgInt}
+	if err := RegisterSignature(Signature{Name: "TestDMXSend", Args: args, Required: 1}); err != nil {
+		t.Fatalf("RegisterSignature failed: %v", err)
+	}
+	args[0] = ArgAny // 登録後に呼び出し側のスライスを変更しても影響しない
+
+	sig, ok := LookupSignature("testdmxsend")
+	if !ok || sig.Name != "TestDMXSend" || sig.ArgType(0) != ArgString {
+		t.Fatalf("LookupSignature = %+v, %v", sig, ok)
+	}
+	if _, err := sig.ConvertArgs([]any{"ch", "12"}); err != nil {
+		t.Errorf("ConvertArgs failed: %v", err)
+	}
+	for _, name := range Signatures() {
+		if name.Name == "TestDMXSend" {
+			t.Error("Signatures should list only the engine's own functions")
+		}
+	}
+
+	invalid := []Signature{
+		{Name: "LOADPIC"},
+		{Name: "testDMXsend"},
+		{Name: ""},
+		{Name: "TestTooMany", Args: []ArgType{ArgInt}, Required: 2},
+		{Name: "TestVariadic", Variadic: true},
+	}
+	for _, s := range invalid {
+		if err := RegisterSignature(s); err == nil {
+			t.Errorf("RegisterSignature(%+v): expected error", s)
+		}
+	}
+}
+
+// TestConvertArgs tests the conversion of numeric strings and the rejection of invalid types.
+func TestConvertArgs(t *testing.T) {
+	sig := &Signature{Name: "f", Args: []ArgType{ArgInt, ArgNumber, ArgString, ArgAny}, Required: 1}
+
+	tests := []struct {
+		name string
+		args []any
+		want []any
+	}{
+		{"unchanged", []any{int64(1), 1.5, "a", "b"}, []any{int64(1), 1.5, "a", "b"}},
+		{"numeric strings", []any{"42", "2.5"}, []any{int64(42), 2.5}},
+		{"hex and fraction", []any{" 0x10 ", "3"}, []any{int64(16), float64(3)}},
+		{"fraction to int", []any{"7.9"}, []any{int64(7)}},
+		{"empty string", []any{"", ""}, []any{int64(0), float64(0)}},
+		{"run-time values", []any{Variable("x"), OpCode{Cmd: Call}, Variable("s")}, []any{Variable("x"), OpCode{Cmd: Call}, Variable("s")}},
+		{"nil", []any{nil}, []any{nil}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := sig.ConvertArgs(tt.args)
+			if err != nil {
+				t.Fatalf("ConvertArgs(%v) failed: %v", tt.args, err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ConvertArgs(%v) = %#v, want %#v", tt.args, got, tt.want)
+			}
+		})
+	}
+}
+
+// TestConvertArgsDoesNotModifyInput tests that converted arguments are returned in a copy.
+func TestConvertArgsDoesNotModifyInput(t *testing.T) {
+	sig := &Signature{Name: "f", Args: []ArgType{ArgInt}, Required: 1}
+	args := []any{"5"}
+	got, err := sig.ConvertArgs(args)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if args[0] != "5" || got[0] != int64(5) {
+		t.Errorf("args = %v, converted = %v", args, got)
+	}
+}
+
+// TestConvertArgsErrors tests the diagnostics for arguments of the wrong type.
+func TestConvertArgsErrors(t *testing.T) {
+	sig := &Signature{Name: "picture", Args: []ArgType{ArgInt, ArgInt, ArgString}, Required: 2}
+
+	tests := []struct {
+		args []any
+		want string
+	}{
+		{[]any{int64(1), "abc"}, `picture(): arg 2 expects int, got string "abc"`},
+		{[]any{int64(1), int64(2), int64(3)}, `picture(): arg 3 expects string, got int 3`},
+		{[]any{int64(1), int64(2), 1.5}, `picture(): arg 3 expects string, got number 1.5`},
+		{[]any{[]int{1}}, `picture(): arg 1 expects int, got []int`},
+	}
+	for _, tt := range tests {
+		_, err := sig.ConvertArgs(tt.args)
+		if err == nil {
+			t.Errorf("ConvertArgs(%v) should fail", tt.args)
+			continue
+		}
+		if err.Error() != tt.want {
+			t.Errorf("ConvertArgs(%v) error = %q, want %q", tt.args, err.Error(), tt.want)
+		}
+	}
+}
+
+// TestVariadicArgType tests that the last argument type repeats for variadic signatures.
+func TestVariadicArgType(t *testing.T) {
+	sig := &Signature{Name: "f", Args: []ArgType{ArgString, ArgInt}, Required: 1, Variadic: true}
+	if sig.ArgType(5) != ArgInt || sig.MaxArgs() != -1 {
+		t.Errorf("ArgType(5) = %v, MaxArgs() = %d", sig.ArgType(5), sig.MaxArgs())
+	}
+	if _, err := sig.ConvertArgs([]any{"fmt", int64(1), "x"}); err == nil {
+		t.Error("extra variadic arguments should be checked")
+	}
+
+	fixed := &Signature{Name: "g", Args: []ArgType{ArgInt}, Required: 1}
+	if fixed.ArgType(3) != ArgAny {
+		t.Error("arguments past a fixed signature should be ArgAny")
+	}
+}
+
+// TestCheckCount tests the argument count check and its diagnostics.
+func TestCheckCount(t *testing.T) {
+	tests := []struct {
+		sig  Signature
+		n    int
+		want string
+	}{
+		{Signature{Name: "a", Args: []ArgType{ArgInt}, Required: 1}, 0, "a(): expects 1 argument, got 0"},
+		{Signature{Name: "b", Args: []ArgType{ArgInt, ArgInt}, Required: 2}, 3, "b(): expects 2 arguments, got 3"},
+		{Signature{Name: "c", Args: []ArgType{ArgInt, ArgInt, ArgInt}, Required: 1}, 4, "c(): expects 1 to 3 arguments, got 4"},
+		{Signature{Name: "d", Args: []ArgType{ArgString}, Required: 1, Variadic: true}, 0, "d(): expects at least 1 argument, got 0"},
+		{Signature{Name: "e", Args: []ArgType{ArgInt, ArgInt}, Required: 1}, 2, ""},
+		{Signature{Name: "f", Args: []ArgType{ArgAny}, Required: 0, Variadic: true}, 10, ""},
+	}
+	for _, tt := range tests {
+		err := tt.sig.CheckCount(tt.n)
+		got := ""
+		if err != nil {
+			got = err.Error()
+		}
+		if got != tt.want {
+			t.Errorf("%s.CheckCount(%d) = %q, want %q", tt.sig.Name, tt.n, got, tt.want)
+		}
+	}
+}
+
+// TestArgErrorExtraArgs tests that only too many arguments count as extra arguments.
+func TestArgErrorExtraArgs(t *testing.T) {
+	sig := Signature{Name: "a", Args: []ArgType{ArgInt, ArgInt}, Required: 1}
+	if err := sig.CheckCount(3).(*ArgError); !err.ExtraArgs() {
+		t.Errorf("expected %v to be extra arguments", err)
+	}
+	if err := sig.CheckCount(0).(*ArgError); err.ExtraArgs() {
+		t.Errorf("expected %v not to be extra arguments", err)
+	}
+	if _, err := sig.ConvertArgs([]any{"x"}); err.(*ArgError).ExtraArgs() {
+		t.Errorf("expected %v not to be extra arguments", err)
+	}
+}
+
+// TestInvalidResult tests the value returned for invalid arguments.
+func TestInvalidResult(t *testing.T) {
+	if got := (&Signature{}).InvalidResult(); got != int64(0) {
+		t.Errorf("default InvalidResult = %v, want 0", got)
+	}
+	sig, _ := LookupSignature("LoadPic")
+	if got := sig.InvalidResult(); got != int64(-1) {
+		t.Errorf("LoadPic InvalidResult = %v, want -1", got)
+	}
+}
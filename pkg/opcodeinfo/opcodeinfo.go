@@ -0,0 +1,136 @@
+// Package opcodeinfo はVMの命令セット（OpCodeのコマンドと組み込み関数の引数）の一覧をまとめる（son-et opcodes）。
+//
+// 一覧はコンパイラとVMが引数の検査に使う表（opcode.Signatures と opcode.Commands）から
+// そのまま作るため、LSP・リンター・変換ツールなどの外部のツールは実装と食い違わない。
+package opcodeinfo
+
+import (
+	"cmp"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+
+	"github.com/zurustar/son-et/pkg/compat"
+	"github.com/zurustar/son-et/pkg/opcode"
+)
+
+// Info は命令セットの一覧
+type Info struct {
+	Commands []Command `json:"commands"` // OpCodeのコマンド（Kind の順）
+	Builtins []Builtin `json:"builtins"` // 組み込み関数（名前の順）
+}
+
+// Command はOpCodeのコマンド
+type Command struct {
+	Name string   `json:"name"` // Cmd の名前
+	Kind int      `json:"kind"` // Cmd の整数形式
+	Args []string `json:"args"` // OpCode.Args の並び
+}
+
+// Builtin は組み込み関数の引数の形式
+type Builtin struct {
+	Name      string   `json:"name"`                // 関数名（大文字小文字を区別しない）
+	Args      []string `json:"args"`                // 引数の型（"int"・"number"・"string"・"any"、省略できる引数を含む）
+	Required  int      `json:"required"`            // 必須の引数の数
+	Variadic  bool     `json:"variadic,omitempty"`  // 最後の引数の型を繰り返せる（引数の数に上限がない）
+	Invalid   any      `json:"invalid"`             // 引数が不正な場合にVMが返す値
+	Extension bool     `json:"extension,omitempty"` // son-et の拡張（--compat filly97 では未定義）
+}
+
+// Collect は命令セットの一覧を作る
+func Collect() *Info {
+	info := &Info{}
+	for _, c := range opcode.Commands() {
+		info.Commands = append(info.Commands, Command{
+			Name: string(c.Cmd),
+			Kind: int(c.Kind),
+			Args: append([]string{}, c.Args...),
+		})
+	}
+	for _, sig := range opcode.Signatures() {
+		args := make([]string, len(sig.Args))
+		for i, a := range sig.Args {
+			args[i] = a.String()
+		}
+		info.Builtins = append(info.Builtins, Builtin{
+			Name:      sig.Name,
+			Args:      args,
+			Required:  sig.Required,
+			Variadic:  sig.Variadic,
+			Invalid:   sig.InvalidResult(),
+			Extension: compat.IsExtensionBuiltin(sig.Name),
+		})
+	}
+	slices.SortFunc(info.Builtins, func(a, b Builtin) int {
+		return cmp.Compare(strings.ToLower(a.Name), strings.ToLower(b.Name))
+	})
+	return info
+}
+
+// WriteMarkdown は一覧をMarkdownの表として書き出す
+func (info *Info) WriteMarkdown(out io.Writer) error {
+	w := &strings.Builder{}
+	w.WriteString("# son-et instruction set\n\n")
+	w.WriteString("Generated by `son-et opcodes --markdown` from the argument tables of the compiler and VM.\n")
+
+	fmt.Fprintf(w, "\n## OpCode commands (%d)\n\n", len(info.Commands))
+	w.WriteString("| Kind | Command | Args |\n|---:|---|---|\n")
+	for _, c := range info.Commands {
+		fmt.Fprintf(w, "| %d | `%s` | %s |\n", c.Kind, c.Name, markdownCode(c.Args))
+	}
+
+	fmt.Fprintf(w, "\n## Built-in functions (%d)\n\n", len(info.Builtins))
+	w.WriteString("| Function | Arguments | Invalid result | Extension |\n|---|---|---|---|\n")
+	for _, b := range info.Builtins {
+		ext := ""
+		if b.Extension {
+			ext = "yes"
+		}
+		fmt.Fprintf(w, "| `%s` | %s | `%s` | %s |\n", b.Name, b.argsText(), invalidText(b.Invalid), ext)
+	}
+	_, err := io.WriteString(out, w.String())
+	return err
+}
+
+// argsText は引数の型を呼び出しの形で表す（省略できる引数は [ ]、繰り返せる引数は ...）
+// 例: (int, int[, string])
+func (b Builtin) argsText() string {
+	var sb strings.Builder
+	sb.WriteString("(")
+	for i, a := range b.Args {
+		switch {
+		case i == b.Required && i > 0:
+			sb.WriteString("[, ")
+		case i == b.Required:
+			sb.WriteString("[")
+		case i > 0:
+			sb.WriteString(", ")
+		}
+		sb.WriteString(a)
+		if b.Variadic && i == len(b.Args)-1 {
+			sb.WriteString("...")
+		}
+	}
+	if b.Required < len(b.Args) {
+		sb.WriteString("]")
+	}
+	sb.WriteString(")")
+	return sb.String()
+}
+
+// invalidText は不正な引数の場合の戻り値を表す（文字列は引用符で囲む）
+func invalidText(v any) string {
+	if s, ok := v.(string); ok {
+		return fmt.Sprintf("%q", s)
+	}
+	return fmt.Sprint(v)
+}
+
+// markdownCode は OpCode.Args の並びを表す（引数がない場合は -）
+func markdownCode(args []string) string {
+	if len(args) == 0 {
+		return "-"
+	}
+	return "`[" + strings.Join(args, ", ") + "]`"
+}
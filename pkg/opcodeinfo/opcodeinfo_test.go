@@ -0,0 +1,107 @@
+package opcodeinfo
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/zurustar/son-et/pkg/opcode"
+)
+
+// TestCollect tests that the list covers the signature table and the commands.
+func TestCollect(t *testing.T) {
+	info := Collect()
+	if len(info.Commands) != int(opcode.NumKinds)-1 {
+		t.Errorf("%d commands, want %d", len(info.Commands), opcode.NumKinds-1)
+	}
+	if len(info.Builtins) != len(opcode.Signatures()) {
+		t.Errorf("%d built-ins, want %d", len(info.Builtins), len(opcode.Signatures()))
+	}
+	for i := 1; i < len(info.Builtins); i++ {
+		if strings.ToLower(info.Builtins[i-1].Name) >= strings.ToLower(info.Builtins[i].Name) {
+			t.Errorf("built-ins not sorted: %s before %s", info.Builtins[i-1].Name, info.Builtins[i].Name)
+		}
+	}
+
+	byName := make(map[string]Builtin)
+	for _, b := range info.Builtins {
+		byName[b.Name] = b
+	}
+	putCast := byName["PutCast"]
+	if putCast.Required != 4 || len(putCast.Args) != 12 || putCast.Args[0] != "int" || putCast.Invalid != int64(-1) || putCast.Extension {
+		t.Errorf("PutCast = %+v", putCast)
+	}
+	if strPrint := byName["StrPrint"]; !strPrint.Variadic || strPrint.Invalid != "" {
+		t.Errorf("StrPrint = %+v, want variadic with \"\" for invalid arguments", strPrint)
+	}
+	if !byName["SaveValue"].Extension {
+		t.Error("SaveValue should be an extension")
+	}
+}
+
+// TestCollectJSON tests the JSON field names external tools read.
+func TestCollectJSON(t *testing.T) {
+	data, err := json.Marshal(Collect())
+	if err != nil {
+		t.Fatalf("Marshal failed: %v", err)
+	}
+	var decoded struct {
+		Commands []struct {
+			Name string   `json:"name"`
+			Args []string `json:"args"`
+		} `json:"commands"`
+		Builtins []struct {
+			Name     string   `json:"name"`
+			Args     []string `json:"args"`
+			Required int      `json:"required"`
+			Invalid  any      `json:"invalid"`
+		} `json:"builtins"`
+	}
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatalf("Unmarshal failed: %v", err)
+	}
+	if decoded.Commands[0].Name != "Assign" || len(decoded.Commands[0].Args) != 2 {
+		t.Errorf("first command = %+v, want Assign with 2 arguments", decoded.Commands[0])
+	}
+	if len(decoded.Builtins) == 0 || decoded.Builtins[0].Args == nil {
+		t.Errorf("built-ins = %+v, want args listed even when empty", decoded.Builtins)
+	}
+}
+
+func TestBuiltinArgsText(t *testing.T) {
+	tests := []struct {
+		b    Builtin
+		want string
+	}{
+		{Builtin{}, "()"},
+		{Builtin{Args: []string{"int"}, Required: 1}, "(int)"},
+		{Builtin{Args: []string{"int"}}, "([int])"},
+		{Builtin{Args: []string{"string", "int"}, Required: 1}, "(string[, int])"},
+		{Builtin{Args: []string{"string", "any"}, Required: 1, Variadic: true}, "(string[, any...])"},
+		{Builtin{Args: []string{"int", "any"}, Required: 2, Variadic: true}, "(int, any...)"},
+	}
+	for _, tt := range tests {
+		if got := tt.b.argsText(); got != tt.want {
+			t.Errorf("argsText(%+v) = %q, want %q", tt.b, got, tt.want)
+		}
+	}
+}
+
+func TestWriteMarkdown(t *testing.T) {
+	var sb strings.Builder
+	if err := Collect().WriteMarkdown(&sb); err != nil {
+		t.Fatalf("WriteMarkdown failed: %v", err)
+	}
+	out := sb.String()
+	for _, want := range []string{
+		"| 1 | `Assign` | `[Variable(name), value]` |",
+		"| 11 | `Break` | - |",
+		"| `PutCast` | (int, int, int, int[, int, int, int, int, int, int, int, int]) | `-1` |  |",
+		"| `StrPrint` | (string[, any...]) | `\"\"` |  |",
+		"| `SaveValue` |",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("Markdown does not contain %q", want)
+		}
+	}
+}
@@ -0,0 +1,89 @@
+// Package osc sends Open Sound Control 1.0 messages over UDP, so that titles can drive
+// external lighting and visual software (in time with MIDI_TIME, for example).
+//
+// Only destinations on an allowlist (--osc-allow) can be reached, and each destination
+// is rate-limited so that a script sending from a fast handler cannot flood the network.
+// Messages over the limit are dropped rather than delayed, since a late cue is worse than
+// a missing one.
+package osc
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+	"strings"
+)
+
+// OSC の型タグ
+const (
+	tagInt32   = 'i'
+	tagInt64   = 'h'
+	tagFloat32 = 'f'
+	tagString  = 's'
+)
+
+// invalidAddressChars はアドレスに使えない文字（空白と、バンドルを表す #）
+const invalidAddressChars = " #"
+
+// Marshal encodes an OSC message. Arguments are sent as int32 (int64 when the value
+// does not fit), float32 or string; other types are an error.
+func Marshal(address string, args ...any) ([]byte, error) {
+	if err := validateAddress(address); err != nil {
+		return nil, err
+	}
+	tags := []byte{','}
+	var data []byte
+	for i, arg := range args {
+		if n, ok := arg.(int); ok {
+			arg = int64(n)
+		}
+		switch v := arg.(type) {
+		case int64:
+			if v >= math.MinInt32 && v <= math.MaxInt32 {
+				tags = append(tags, tagInt32)
+				data = binary.BigEndian.AppendUint32(data, uint32(int32(v)))
+			} else {
+				tags = append(tags, tagInt64)
+				data = binary.BigEndian.AppendUint64(data, uint64(v))
+			}
+		case float64:
+			tags = append(tags, tagFloat32)
+			data = binary.BigEndian.AppendUint32(data, math.Float32bits(float32(v)))
+		case string:
+			if strings.IndexByte(v, 0) >= 0 {
+				return nil, fmt.Errorf("OSC argument %d contains a NUL character", i+1)
+			}
+			tags = append(tags, tagString)
+			data = appendString(data, v)
+		default:
+			return nil, fmt.Errorf("OSC argument %d has unsupported type %T", i+1, arg)
+		}
+	}
+
+	msg := appendString(nil, address)
+	msg = appendString(msg, string(tags))
+	return append(msg, data...), nil
+}
+
+// validateAddress は OSC のアドレス（パターン）として送れるか調べる
+func validateAddress(address string) error {
+	if !strings.HasPrefix(address, "/") {
+		return fmt.Errorf("OSC address must start with /, got %q", address)
+	}
+	for _, r := range address {
+		if r < 0x20 || r > 0x7e || strings.ContainsRune(invalidAddressChars, r) {
+			return fmt.Errorf("OSC address %q contains invalid character %q", address, r)
+		}
+	}
+	return nil
+}
+
+// appendString は NUL で終端し、4バイト境界まで NUL で埋めた OSC 文字列を追加する
+func appendString(b []byte, s string) []byte {
+	b = append(b, s...)
+	pad := 4 - len(s)%4
+	for range pad {
+		b = append(b, 0)
+	}
+	return b
+}
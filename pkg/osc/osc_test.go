@@ -0,0 +1,68 @@
+package osc
+
+import (
+	"bytes"
+	"math"
+	"testing"
+)
+
+func TestMarshal(t *testing.T) {
+	got, err := Marshal("/light/1", int64(255), 0.5, "on")
+	if err != nil {
+		t.Fatalf("Marshal failed: %v", err)
+	}
+	want := []byte("/light/1\x00\x00\x00\x00" + ",ifs\x00\x00\x00\x00" +
+		"\x00\x00\x00\xff" + "\x3f\x00\x00\x00" + "on\x00\x00")
+	if !bytes.Equal(got, want) {
+		t.Errorf("Marshal = %q, want %q", got, want)
+	}
+}
+
+func TestMarshalNoArgs(t *testing.T) {
+	got, err := Marshal("/go")
+	if err != nil {
+		t.Fatalf("Marshal failed: %v", err)
+	}
+	if want := []byte("/go\x00,\x00\x00\x00"); !bytes.Equal(got, want) {
+		t.Errorf("Marshal = %q, want %q", got, want)
+	}
+}
+
+func TestMarshalIntegers(t *testing.T) {
+	got, err := Marshal("/n", -1, int64(math.MaxInt32)+1)
+	if err != nil {
+		t.Fatalf("Marshal failed: %v", err)
+	}
+	want := []byte("/n\x00\x00" + ",ih\x00" + "\xff\xff\xff\xff" + "\x00\x00\x00\x00\x80\x00\x00\x00")
+	if !bytes.Equal(got, want) {
+		t.Errorf("Marshal = %q, want %q", got, want)
+	}
+}
+
+func TestMarshalErrors(t *testing.T) {
+	tests := []struct {
+		address string
+		args    []any
+	}{
+		{"light", nil},
+		{"", nil},
+		{"/a b", nil},
+		{"/a#b", nil},
+		{"/a\n", nil},
+		{"/ok", []any{[]int{1}}},
+		{"/ok", []any{"a\x00b"}},
+	}
+	for _, tt := range tests {
+		if _, err := Marshal(tt.address, tt.args...); err == nil {
+			t.Errorf("Marshal(%q, %v): expected error", tt.address, tt.args)
+		}
+	}
+}
+
+func TestAppendStringPadding(t *testing.T) {
+	for s, size := range map[string]int{"": 4, "abc": 4, "abcd": 8, "abcde": 8} {
+		if got := len(appendString(nil, s)); got != size {
+			t.Errorf("len(appendString(%q)) = %d, want %d", s, got, size)
+		}
+	}
+}
@@ -0,0 +1,177 @@
+package osc
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// 送信の制限の既定値（送信先ごと）
+const (
+	DefaultRate  = 100 // 1秒あたりに送れるメッセージ数
+	DefaultBurst = 32  // 一度にまとめて送れるメッセージ数
+)
+
+// Errors returned by Sender.Send.
+var (
+	// ErrNotAllowed is returned for a destination that is not on the allowlist.
+	ErrNotAllowed = errors.New("OSC destination is not allowed")
+	// ErrRateLimited is returned when a message is dropped by the rate limit.
+	ErrRateLimited = errors.New("OSC message dropped by rate limit")
+)
+
+// Sender sends OSC messages to the destinations on its allowlist.
+// It is safe for concurrent use.
+type Sender struct {
+	rate  float64
+	burst float64
+	now   func() time.Time
+
+	mu      sync.Mutex
+	targets map[string]*target // 正規化した "host:port" で引く
+	closed  bool
+}
+
+// target は1つの送信先（接続は最初の送信時に作る）
+type target struct {
+	addr   string
+	conn   net.Conn
+	tokens float64   // 送れるメッセージの残り（トークンバケット）
+	last   time.Time // tokens を最後に補充した時刻
+}
+
+// Option configures a Sender.
+type Option func(*Sender)
+
+// WithRateLimit sets how many messages per second can be sent to each destination,
+// and how many can be sent at once after a pause (DefaultRate and DefaultBurst by default).
+func WithRateLimit(perSecond, burst int) Option {
+	return func(s *Sender) {
+		if perSecond > 0 {
+			s.rate = float64(perSecond)
+		}
+		if burst > 0 {
+			s.burst = float64(burst)
+		}
+	}
+}
+
+// withClock は時刻の取得方法を差し替える（テスト用）
+func withClock(now func() time.Time) Option {
+	return func(s *Sender) {
+		s.now = now
+	}
+}
+
+// NewSender creates a sender that can send to the given "host:port" destinations.
+func NewSender(allow []string, opts ...Option) (*Sender, error) {
+	s := &Sender{
+		rate:    DefaultRate,
+		burst:   DefaultBurst,
+		now:     time.Now,
+		targets: make(map[string]*target, len(allow)),
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	for _, addr := range allow {
+		key, err := normalizeTarget(addr)
+		if err != nil {
+			return nil, err
+		}
+		s.targets[key] = &target{addr: addr, tokens: s.burst}
+	}
+	return s, nil
+}
+
+// Targets returns the allowed destinations, sorted.
+func (s *Sender) Targets() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	addrs := make([]string, 0, len(s.targets))
+	for _, t := range s.targets {
+		addrs = append(addrs, t.addr)
+	}
+	slices.Sort(addrs)
+	return addrs
+}
+
+// Send sends an OSC message to dest, which must be on the allowlist.
+// The host is compared case-insensitively but is not resolved, so "localhost:9000"
+// and "127.0.0.1:9000" are different destinations.
+func (s *Sender) Send(dest, address string, args ...any) error {
+	msg, err := Marshal(address, args...)
+	if err != nil {
+		return err
+	}
+	key, err := normalizeTarget(dest)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return net.ErrClosed
+	}
+	t, ok := s.targets[key]
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrNotAllowed, dest)
+	}
+	if !s.take(t) {
+		return fmt.Errorf("%w: %s", ErrRateLimited, dest)
+	}
+	if t.conn == nil {
+		conn, err := net.Dial("udp", t.addr)
+		if err != nil {
+			return fmt.Errorf("failed to connect to OSC destination %s: %w", t.addr, err)
+		}
+		t.conn = conn
+	}
+	if _, err := t.conn.Write(msg); err != nil {
+		return fmt.Errorf("failed to send OSC message to %s: %w", t.addr, err)
+	}
+	return nil
+}
+
+// take はトークンを補充してから1つ使う（残りがなければ false）
+func (s *Sender) take(t *target) bool {
+	now := s.now()
+	if !t.last.IsZero() {
+		t.tokens = min(s.burst, t.tokens+now.Sub(t.last).Seconds()*s.rate)
+	}
+	t.last = now
+	if t.tokens < 1 {
+		return false
+	}
+	t.tokens--
+	return true
+}
+
+// Close closes the connections to all destinations. Send fails afterwards.
+func (s *Sender) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.closed = true
+	var errs []error
+	for _, t := range s.targets {
+		if t.conn != nil {
+			errs = append(errs, t.conn.Close())
+			t.conn = nil
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// normalizeTarget は "host:port" を比較用に正規化する
+func normalizeTarget(addr string) (string, error) {
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil || host == "" || port == "" {
+		return "", fmt.Errorf("OSC destination must be host:port such as 127.0.0.1:9000, got %q", addr)
+	}
+	return net.JoinHostPort(strings.ToLower(host), port), nil
+}
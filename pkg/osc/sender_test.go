@@ -0,0 +1,130 @@
+package osc
+
+import (
+	"bytes"
+	"errors"
+	"net"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// listenUDP はループバックで受信用のソケットを開く
+func listenUDP(t *testing.T) net.PacketConn {
+	t.Helper()
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("ListenPacket failed: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return conn
+}
+
+func TestSenderSend(t *testing.T) {
+	recv := listenUDP(t)
+	addr := recv.LocalAddr().String()
+	s, err := NewSender([]string{addr})
+	if err != nil {
+		t.Fatalf("NewSender failed: %v", err)
+	}
+	defer s.Close()
+
+	if err := s.Send(addr, "/cue", int64(3)); err != nil {
+		t.Fatalf("Send failed: %v", err)
+	}
+	recv.SetReadDeadline(time.Now().Add(2 * time.Second))
+	buf := make([]byte, 64)
+	n, _, err := recv.ReadFrom(buf)
+	if err != nil {
+		t.Fatalf("no message received: %v", err)
+	}
+	want, _ := Marshal("/cue", int64(3))
+	if !bytes.Equal(buf[:n], want) {
+		t.Errorf("received %q, want %q", buf[:n], want)
+	}
+}
+
+func TestSenderAllowlist(t *testing.T) {
+	s, err := NewSender([]string{"Localhost:9000"})
+	if err != nil {
+		t.Fatalf("NewSender failed: %v", err)
+	}
+	defer s.Close()
+
+	for _, dest := range []string{"127.0.0.1:9000", "localhost:9001", "192.168.0.1:9000"} {
+		if err := s.Send(dest, "/x"); !errors.Is(err, ErrNotAllowed) {
+			t.Errorf("Send(%s) error = %v, want ErrNotAllowed", dest, err)
+		}
+	}
+	if err := s.Send("9000", "/x"); err == nil || errors.Is(err, ErrNotAllowed) {
+		t.Errorf("Send with a malformed destination: error = %v", err)
+	}
+	if got := s.Targets(); !reflect.DeepEqual(got, []string{"Localhost:9000"}) {
+		t.Errorf("Targets() = %v", got)
+	}
+}
+
+func TestNewSenderInvalidTarget(t *testing.T) {
+	for _, addr := range []string{"", "9000", ":9000", "host:"} {
+		if _, err := NewSender([]string{addr}); err == nil {
+			t.Errorf("NewSender(%q): expected error", addr)
+		}
+	}
+}
+
+func TestSenderRateLimit(t *testing.T) {
+	recv := listenUDP(t)
+	addr := recv.LocalAddr().String()
+	now := time.Unix(0, 0)
+	s, err := NewSender([]string{addr}, WithRateLimit(10, 3), withClock(func() time.Time { return now }))
+	if err != nil {
+		t.Fatalf("NewSender failed: %v", err)
+	}
+	defer s.Close()
+
+	send := func() error { return s.Send(addr, "/tick") }
+	for i := range 3 {
+		if err := send(); err != nil {
+			t.Fatalf("message %d within the burst failed: %v", i, err)
+		}
+	}
+	if err := send(); !errors.Is(err, ErrRateLimited) {
+		t.Fatalf("message over the burst: error = %v, want ErrRateLimited", err)
+	}
+
+	// 10メッセージ/秒なので100msで1つ送れるようになる
+	now = now.Add(100 * time.Millisecond)
+	if err := send(); err != nil {
+		t.Errorf("message after refill failed: %v", err)
+	}
+	if err := send(); !errors.Is(err, ErrRateLimited) {
+		t.Errorf("second message after refill: error = %v, want ErrRateLimited", err)
+	}
+
+	// 長く待ってもバースト以上はたまらない
+	now = now.Add(time.Hour)
+	for range 3 {
+		send()
+	}
+	if err := send(); !errors.Is(err, ErrRateLimited) {
+		t.Errorf("burst should be capped: error = %v", err)
+	}
+}
+
+func TestSenderClose(t *testing.T) {
+	recv := listenUDP(t)
+	addr := recv.LocalAddr().String()
+	s, err := NewSender([]string{addr})
+	if err != nil {
+		t.Fatalf("NewSender failed: %v", err)
+	}
+	if err := s.Send(addr, "/a"); err != nil {
+		t.Fatalf("Send failed: %v", err)
+	}
+	if err := s.Close(); err != nil {
+		t.Errorf("Close failed: %v", err)
+	}
+	if err := s.Send(addr, "/a"); !errors.Is(err, net.ErrClosed) {
+		t.Errorf("Send after Close: error = %v, want net.ErrClosed", err)
+	}
+}
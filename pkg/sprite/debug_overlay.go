@@ -1,3 +1,5 @@
+//go:build !nogpu
+
 // Package sprite provides sprite-based rendering system with slice-based draw ordering.
 package sprite
 
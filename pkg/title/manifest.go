@@ -0,0 +1,424 @@
+package title
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"image/color"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/zurustar/son-et/pkg/compat"
+	"github.com/zurustar/son-et/pkg/fileutil"
+)
+
+// ManifestNames はプロジェクトマニフェストのファイル名（この順に探し、最初に見つかったものを使う）
+var ManifestNames = []string{"project.yaml", "project.yml", "project.json"}
+
+// maxManifestResolution は resolution の幅・高さの上限（SetWindowSize と同じ）
+const maxManifestResolution = 8192
+
+// Manifest はプロジェクトマニフェスト（project.yaml / project.json）
+//
+// タイトルのディレクトリに置くと、エントリーポイントの自動検出や SoundFont の検索より優先される。
+// YAMLは「キー: 値」とリスト（「- 値」または [a, b]）だけの単純な形式に対応する。
+//
+//	entry: MAIN.TFY
+//	title: My Title
+//	soundfont: sound/GM.sf2
+//	resolution: 800x600
+//	compat: filly97
+//	assets:
+//	  - pics
+//	  - midi
+//	vars:
+//	  - ASSETS=hires
+//	midi_remap:
+//	  - program 81 -> 80
+//	pause_menu: on
+//	pause_menu_background: "#000040C0"
+//	pause_menu_color: "#FFC000"
+type Manifest struct {
+	Entry      string   `json:"entry"`      // エントリーポイントのTFYファイル
+	Title      string   `json:"title"`      // タイトル名（ウィンドウのタイトル、#info INAM より優先）
+	SoundFont  string   `json:"soundfont"`  // SoundFontファイル（タイトルのディレクトリからの相対パス、または絶対パス）
+	Resolution string   `json:"resolution"` // ウィンドウの大きさ（"幅x高さ"）
+	Compat     string   `json:"compat"`     // 互換モード（--compat の値）
+	Assets     []string `json:"assets"`     // 画像・音声を探すディレクトリ（タイトルのディレクトリからの相対パス）
+	Vars       []string `json:"vars"`       // アセットパスの変数（"名前=値"、ファイル名の ${名前} を置き換える）
+	MIDIRemap  []string `json:"midi_remap"` // MIDIの音色・バンク・ドラムの音の置き換え（"program 81 -> 80" など）
+
+	PauseMenu           string `json:"pause_menu"`            // Escキーのポーズメニュー（"on" または "off"、既定は on）
+	PauseMenuBackground string `json:"pause_menu_background"` // ポーズメニューの背景色（"#RRGGBB" または "#RRGGBBAA"）
+	PauseMenuColor      string `json:"pause_menu_color"`      // ポーズメニューの選択中の項目の色（"#RRGGBB" または "#RRGGBBAA"）
+
+	Path       string            `json:"-"` // 読み込んだマニフェストのパス
+	Width      int               `json:"-"` // Resolution の幅（0は未指定）
+	Height     int               `json:"-"` // Resolution の高さ（0は未指定）
+	CompatMode compat.Mode       `json:"-"` // Compat を解析した互換モード
+	AssetVars  map[string]string `json:"-"` // Vars を解析した変数（nil は未指定）
+	MIDIRemaps *MIDIRemapTable   `json:"-"` // MIDIRemap を解析した置き換えの表（nil は未指定）
+
+	NoPauseMenu              bool         `json:"-"` // PauseMenu が "off"
+	PauseMenuBackgroundColor *color.NRGBA `json:"-"` // PauseMenuBackground を解析した色（nil は未指定）
+	PauseMenuSelectedColor   *color.NRGBA `json:"-"` // PauseMenuColor を解析した色（nil は未指定）
+}
+
+// ManifestError はマニフェストの誤り
+type ManifestError struct {
+	Path string // マニフェストのパス
+	Line int    // 行番号（0は行を特定できない）
+	Msg  string
+}
+
+func (e *ManifestError) Error() string {
+	if e.Line > 0 {
+		return fmt.Sprintf("%s:%d: %s", e.Path, e.Line, e.Msg)
+	}
+	return fmt.Sprintf("%s: %s", e.Path, e.Msg)
+}
+
+// LoadManifest はタイトルのディレクトリからプロジェクトマニフェストを読み込んで検証する
+// マニフェストがない場合は nil, nil を返す。誤りがある場合は *ManifestError を返す。
+func LoadManifest(dir string) (*Manifest, error) {
+	for _, name := range ManifestNames {
+		path, err := fileutil.FindFileCaseInsensitive(dir, name)
+		if err != nil {
+			continue
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read project manifest: %w", err)
+		}
+		return ParseManifest(path, data, dir)
+	}
+	return nil, nil
+}
+
+// ParseManifest はマニフェストの内容を解析し、タイトルのディレクトリ dir に対して検証する
+// path の拡張子が .json の場合はJSON、それ以外はYAMLとして解析する。
+func ParseManifest(path string, data []byte, dir string) (*Manifest, error) {
+	m := &Manifest{Path: path}
+	var err error
+	if strings.EqualFold(filepath.Ext(path), ".json") {
+		err = m.parseJSON(data)
+	} else {
+		err = m.parseYAML(data)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := m.validate(dir); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// errorf は行番号付きのマニフェストの誤りを作成する
+func (m *Manifest) errorf(line int, format string, args ...any) error {
+	return &ManifestError{Path: m.Path, Line: line, Msg: fmt.Sprintf(format, args...)}
+}
+
+// parseJSON はJSON形式のマニフェストを解析する（未知のキーは誤り）
+func (m *Manifest) parseJSON(data []byte) error {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(m); err != nil {
+		line := 0
+		var syntaxErr *json.SyntaxError
+		if errors.As(err, &syntaxErr) {
+			line = bytes.Count(data[:syntaxErr.Offset], []byte("\n")) + 1
+		}
+		return m.errorf(line, "invalid JSON: %v", err)
+	}
+	return nil
+}
+
+// parseYAML は単純なYAML形式のマニフェストを解析する
+func (m *Manifest) parseYAML(data []byte) error {
+	seen := make(map[string]bool)
+	listKey := "" // 値を省略したキー（続く行はリストの要素）
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimRight(stripYAMLComment(scanner.Text()), " \t\r")
+		trimmed := strings.TrimSpace(text)
+		if trimmed == "" || trimmed == "---" {
+			continue
+		}
+
+		if strings.HasPrefix(trimmed, "- ") || trimmed == "-" {
+			if listKey == "" {
+				return m.errorf(line, "list item without a key")
+			}
+			if err := m.appendYAMLItem(listKey, unquoteYAML(strings.TrimSpace(trimmed[1:])), line); err != nil {
+				return err
+			}
+			continue
+		}
+		if text[0] == ' ' || text[0] == '\t' {
+			return m.errorf(line, "nested values are not supported")
+		}
+
+		key, value, ok := strings.Cut(trimmed, ":")
+		if !ok {
+			return m.errorf(line, "expected \"key: value\", got %q", trimmed)
+		}
+		key, value = strings.TrimSpace(key), strings.TrimSpace(value)
+		if seen[key] {
+			return m.errorf(line, "duplicate key %q", key)
+		}
+		seen[key] = true
+		listKey = ""
+
+		switch {
+		case value == "":
+			if _, known := manifestScalars(m)[key]; !known && manifestLists(m)[key] == nil {
+				return m.errorf(line, "unknown key %q (known keys: %s)", key, manifestKeys)
+			}
+			listKey = key
+		case strings.HasPrefix(value, "["):
+			if !strings.HasSuffix(value, "]") {
+				return m.errorf(line, "unterminated list for %q", key)
+			}
+			for _, item := range strings.Split(value[1:len(value)-1], ",") {
+				if item = strings.TrimSpace(item); item != "" {
+					if err := m.appendYAMLItem(key, unquoteYAML(item), line); err != nil {
+						return err
+					}
+				}
+			}
+		default:
+			if err := m.setYAMLScalar(key, unquoteYAML(value), line); err != nil {
+				return err
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return m.errorf(0, "failed to read: %v", err)
+	}
+	return nil
+}
+
+// manifestKeys はエラーメッセージに示すマニフェストのキーの一覧
+const manifestKeys = "entry, title, soundfont, resolution, compat, assets, vars, midi_remap, pause_menu, pause_menu_background, pause_menu_color"
+
+// manifestScalars はマニフェストの文字列のキーと格納先を返す
+func manifestScalars(m *Manifest) map[string]*string {
+	return map[string]*string{
+		"entry":      &m.Entry,
+		"title":      &m.Title,
+		"soundfont":  &m.SoundFont,
+		"resolution": &m.Resolution,
+		"compat":     &m.Compat,
+
+		"pause_menu":            &m.PauseMenu,
+		"pause_menu_background": &m.PauseMenuBackground,
+		"pause_menu_color":      &m.PauseMenuColor,
+	}
+}
+
+// manifestLists はマニフェストのリストのキーと格納先を返す
+func manifestLists(m *Manifest) map[string]*[]string {
+	return map[string]*[]string{
+		"assets":     &m.Assets,
+		"vars":       &m.Vars,
+		"midi_remap": &m.MIDIRemap,
+	}
+}
+
+// setYAMLScalar は文字列のキーに値を設定する
+func (m *Manifest) setYAMLScalar(key, value string, line int) error {
+	if _, ok := manifestLists(m)[key]; ok {
+		return m.errorf(line, "%q must be a list", key)
+	}
+	dst, ok := manifestScalars(m)[key]
+	if !ok {
+		return m.errorf(line, "unknown key %q (known keys: %s)", key, manifestKeys)
+	}
+	*dst = value
+	return nil
+}
+
+// appendYAMLItem はリストのキーに値を追加する
+func (m *Manifest) appendYAMLItem(key, value string, line int) error {
+	dst, ok := manifestLists(m)[key]
+	if !ok {
+		if _, ok := manifestScalars(m)[key]; ok {
+			return m.errorf(line, "%q must be a single value, not a list", key)
+		}
+		return m.errorf(line, "unknown key %q (known keys: %s)", key, manifestKeys)
+	}
+	*dst = append(*dst, value)
+	return nil
+}
+
+// stripYAMLComment は行の "#" 以降のコメントを取り除く（引用符の中の "#" は残す）
+func stripYAMLComment(s string) string {
+	var quote rune
+	for i, r := range s {
+		switch {
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			}
+		case r == '"' || r == '\'':
+			quote = r
+		case r == '#' && (i == 0 || s[i-1] == ' ' || s[i-1] == '\t'):
+			return s[:i]
+		}
+	}
+	return s
+}
+
+// unquoteYAML は引用符で囲まれた値の引用符を取り除く
+func unquoteYAML(s string) string {
+	if len(s) >= 2 {
+		switch {
+		case s[0] == '"' && s[len(s)-1] == '"':
+			if u, err := strconv.Unquote(s); err == nil {
+				return u
+			}
+			return s[1 : len(s)-1]
+		case s[0] == '\'' && s[len(s)-1] == '\'':
+			return strings.ReplaceAll(s[1:len(s)-1], "''", "'")
+		}
+	}
+	return s
+}
+
+// validate はマニフェストの値をタイトルのディレクトリ dir に対して検証し、解析した値を設定する
+func (m *Manifest) validate(dir string) error {
+	if m.Entry != "" {
+		if !strings.EqualFold(filepath.Ext(m.Entry), ".tfy") {
+			return m.errorf(0, "entry must be a .TFY file, got %q", m.Entry)
+		}
+		found, err := fileutil.FindFileCaseInsensitive(filepath.Join(dir, filepath.Dir(m.Entry)), filepath.Base(m.Entry))
+		if err != nil {
+			return m.errorf(0, "entry file %q not found in %s", m.Entry, dir)
+		}
+		// 実際のファイル名（大文字・小文字）を使う
+		m.Entry, _ = filepath.Rel(dir, found)
+	}
+
+	if m.SoundFont != "" {
+		path := m.SoundFont
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(dir, path)
+		}
+		found, err := fileutil.FindFileCaseInsensitive(filepath.Dir(path), filepath.Base(path))
+		if err != nil {
+			return m.errorf(0, "soundfont %q not found", m.SoundFont)
+		}
+		m.SoundFont = found
+	}
+
+	if m.Resolution != "" {
+		w, h, ok := parseResolution(m.Resolution)
+		if !ok {
+			return m.errorf(0, "resolution must be WIDTHxHEIGHT (1-%d), got %q", maxManifestResolution, m.Resolution)
+		}
+		m.Width, m.Height = w, h
+	}
+
+	mode, err := compat.Parse(m.Compat)
+	if err != nil {
+		return m.errorf(0, "compat: %v", err)
+	}
+	m.CompatMode = mode
+
+	for _, asset := range m.Assets {
+		if asset == "" || filepath.IsAbs(asset) {
+			return m.errorf(0, "asset directory must be a path relative to the title, got %q", asset)
+		}
+		path := filepath.Join(dir, asset)
+		if rel, err := filepath.Rel(dir, path); err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			return m.errorf(0, "asset directory %q is outside the title directory", asset)
+		}
+		info, err := os.Stat(path)
+		if err != nil || !info.IsDir() {
+			return m.errorf(0, "asset directory %q not found in %s", asset, dir)
+		}
+	}
+
+	for _, v := range m.Vars {
+		name, value, err := fileutil.ParseVar(v)
+		if err != nil {
+			return m.errorf(0, "vars: %v", err)
+		}
+		if _, dup := m.AssetVars[name]; dup {
+			return m.errorf(0, "vars: duplicate variable %q", name)
+		}
+		if m.AssetVars == nil {
+			m.AssetVars = make(map[string]string)
+		}
+		m.AssetVars[name] = value
+	}
+
+	if len(m.MIDIRemap) > 0 {
+		table, err := ParseMIDIRemap(m.MIDIRemap)
+		if err != nil {
+			return m.errorf(0, "midi_remap: %v", err)
+		}
+		m.MIDIRemaps = table
+	}
+
+	switch strings.ToLower(m.PauseMenu) {
+	case "", "on":
+	case "off":
+		m.NoPauseMenu = true
+	default:
+		return m.errorf(0, "pause_menu must be on or off, got %q", m.PauseMenu)
+	}
+	for _, c := range []struct {
+		key   string
+		value string
+		dst   **color.NRGBA
+	}{
+		{"pause_menu_background", m.PauseMenuBackground, &m.PauseMenuBackgroundColor},
+		{"pause_menu_color", m.PauseMenuColor, &m.PauseMenuSelectedColor},
+	} {
+		if c.value == "" {
+			continue
+		}
+		rgba, ok := parseHexColor(c.value)
+		if !ok {
+			return m.errorf(0, "%s must be #RRGGBB or #RRGGBBAA, got %q", c.key, c.value)
+		}
+		*c.dst = &rgba
+	}
+	return nil
+}
+
+// parseHexColor は "#RRGGBB" または "#RRGGBBAA" を解析する（アルファを省略した場合は不透明）
+func parseHexColor(s string) (color.NRGBA, bool) {
+	hex, ok := strings.CutPrefix(s, "#")
+	if !ok || (len(hex) != 6 && len(hex) != 8) {
+		return color.NRGBA{}, false
+	}
+	if len(hex) == 6 {
+		hex += "ff"
+	}
+	v, err := strconv.ParseUint(hex, 16, 32)
+	if err != nil {
+		return color.NRGBA{}, false
+	}
+	return color.NRGBA{R: uint8(v >> 24), G: uint8(v >> 16), B: uint8(v >> 8), A: uint8(v)}, true
+}
+
+// parseResolution は "幅x高さ" を解析する
+func parseResolution(s string) (w, h int, ok bool) {
+	ws, hs, found := strings.Cut(strings.ToLower(s), "x")
+	if !found {
+		return 0, 0, false
+	}
+	w, errW := strconv.Atoi(strings.TrimSpace(ws))
+	h, errH := strconv.Atoi(strings.TrimSpace(hs))
+	if errW != nil || errH != nil || w < 1 || h < 1 || w > maxManifestResolution || h > maxManifestResolution {
+		return 0, 0, false
+	}
+	return w, h, true
+}
@@ -0,0 +1,316 @@
+package title
+
+import (
+	"errors"
+	"image/color"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/zurustar/son-et/pkg/compat"
+)
+
+// writeManifestTitle はマニフェストのテスト用のタイトルディレクトリを作成する
+func writeManifestTitle(t *testing.T, manifestName, manifest string) string {
+	t.Helper()
+	dir := t.TempDir()
+	files := map[string]string{
+		"MAIN.TFY":                  "main() {}\n",
+		"SUB.TFY":                   "main() {}\n",
+		"sound/GM.sf2":              "RIFF....sfbk",
+		"pics/.keep":                "",
+		"midi/.keep":                "",
+		filepath.Base(manifestName): manifest,
+	}
+	for name, content := range files {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+func TestLoadManifest_YAML(t *testing.T) {
+	dir := writeManifestTitle(t, "project.yaml", `# テスト用のマニフェスト
+entry: sub.tfy
+title: "My Title # 1"
+soundfont: sound/gm.sf2
+resolution: 800x600
+compat: filly97
+assets:
+  - pics   # 画像
+  - 'midi'
+vars:
+  - ASSETS=hires
+  - "EXT=BMP"
+`)
+	m, err := LoadManifest(dir)
+	if err != nil {
+		t.Fatalf("LoadManifest failed: %v", err)
+	}
+	if m == nil {
+		t.Fatal("LoadManifest returned nil")
+	}
+	if m.Entry != "SUB.TFY" {
+		t.Errorf("Entry = %q, want SUB.TFY (actual file name)", m.Entry)
+	}
+	if m.Title != "My Title # 1" {
+		t.Errorf("Title = %q, want %q", m.Title, "My Title # 1")
+	}
+	if want := filepath.Join(dir, "sound", "GM.sf2"); m.SoundFont != want {
+		t.Errorf("SoundFont = %q, want %q", m.SoundFont, want)
+	}
+	if m.Width != 800 || m.Height != 600 {
+		t.Errorf("resolution = %dx%d, want 800x600", m.Width, m.Height)
+	}
+	if m.CompatMode != compat.FILLY97 {
+		t.Errorf("CompatMode = %v, want filly97", m.CompatMode)
+	}
+	if !reflect.DeepEqual(m.Assets, []string{"pics", "midi"}) {
+		t.Errorf("Assets = %v, want [pics midi]", m.Assets)
+	}
+	if want := map[string]string{"ASSETS": "hires", "EXT": "BMP"}; !reflect.DeepEqual(m.AssetVars, want) {
+		t.Errorf("AssetVars = %v, want %v", m.AssetVars, want)
+	}
+	if m.Path != filepath.Join(dir, "project.yaml") {
+		t.Errorf("Path = %q", m.Path)
+	}
+}
+
+func TestLoadManifest_JSON(t *testing.T) {
+	dir := writeManifestTitle(t, "project.json", `{
+  "entry": "MAIN.TFY",
+  "assets": ["pics"],
+  "vars": ["ASSETS=original"]
+}`)
+	m, err := LoadManifest(dir)
+	if err != nil {
+		t.Fatalf("LoadManifest failed: %v", err)
+	}
+	if m.Entry != "MAIN.TFY" || !reflect.DeepEqual(m.Assets, []string{"pics"}) || m.AssetVars["ASSETS"] != "original" {
+		t.Errorf("manifest = %+v", m)
+	}
+	if m.CompatMode != compat.Extended {
+		t.Errorf("CompatMode = %v, want extended by default", m.CompatMode)
+	}
+	if m.Width != 0 || m.Height != 0 {
+		t.Errorf("resolution = %dx%d, want unset", m.Width, m.Height)
+	}
+}
+
+func TestLoadManifest_InlineList(t *testing.T) {
+	dir := writeManifestTitle(t, "project.yml", "assets: [pics, \"midi\"]\n")
+	m, err := LoadManifest(dir)
+	if err != nil {
+		t.Fatalf("LoadManifest failed: %v", err)
+	}
+	if !reflect.DeepEqual(m.Assets, []string{"pics", "midi"}) {
+		t.Errorf("Assets = %v, want [pics midi]", m.Assets)
+	}
+}
+
+func TestLoadManifest_MIDIRemap(t *testing.T) {
+	dir := writeManifestTitle(t, "project.yaml", `midi_remap:
+  - program 81 -> 80
+  - bank 8->0
+  - note 35 -> 36  # アコースティックバスドラム
+`)
+	m, err := LoadManifest(dir)
+	if err != nil {
+		t.Fatalf("LoadManifest failed: %v", err)
+	}
+	want := &MIDIRemapTable{
+		Programs:  map[int]int{81: 80},
+		Banks:     map[int]int{8: 0},
+		DrumNotes: map[int]int{35: 36},
+	}
+	if !reflect.DeepEqual(m.MIDIRemaps, want) {
+		t.Errorf("MIDIRemaps = %+v, want %+v", m.MIDIRemaps, want)
+	}
+
+	dir = writeManifestTitle(t, "project.json", `{"entry": "MAIN.TFY"}`)
+	if m, err = LoadManifest(dir); err != nil || m.MIDIRemaps != nil {
+		t.Errorf("MIDIRemaps without midi_remap = %v (%v), want nil", m.MIDIRemaps, err)
+	}
+}
+
+func TestLoadManifest_PauseMenu(t *testing.T) {
+	dir := writeManifestTitle(t, "project.yaml", `pause_menu: OFF
+pause_menu_background: "#000040C0"
+pause_menu_color: '#FFC000'
+`)
+	m, err := LoadManifest(dir)
+	if err != nil {
+		t.Fatalf("LoadManifest failed: %v", err)
+	}
+	if !m.NoPauseMenu {
+		t.Error("NoPauseMenu = false, want true for pause_menu: OFF")
+	}
+	if want := (color.NRGBA{0x00, 0x00, 0x40, 0xC0}); m.PauseMenuBackgroundColor == nil || *m.PauseMenuBackgroundColor != want {
+		t.Errorf("PauseMenuBackgroundColor = %v, want %v", m.PauseMenuBackgroundColor, want)
+	}
+	if want := (color.NRGBA{0xFF, 0xC0, 0x00, 0xFF}); m.PauseMenuSelectedColor == nil || *m.PauseMenuSelectedColor != want {
+		t.Errorf("PauseMenuSelectedColor = %v, want %v", m.PauseMenuSelectedColor, want)
+	}
+
+	// 指定しない場合はメニューを使い、色は既定のまま
+	dir = writeManifestTitle(t, "project.json", `{"pause_menu": "on"}`)
+	if m, err = LoadManifest(dir); err != nil || m.NoPauseMenu || m.PauseMenuBackgroundColor != nil || m.PauseMenuSelectedColor != nil {
+		t.Errorf("LoadManifest with pause_menu: on = %+v (%v), want the default menu", m, err)
+	}
+}
+
+func TestParseMIDIRemap(t *testing.T) {
+	tests := []struct {
+		rule string
+		want string // 誤りの場合にメッセージに含まれる文字列（空は正しい規則）
+	}{
+		{"program 0 -> 127", ""},
+		{"PROGRAM 1 -> 2", ""},
+		{"note 35 -> 36", ""},
+		{"program 81", `expected "program FROM -> TO"`},
+		{"program -1 -> 2", "values must be 0-127"},
+		{"bank x -> 0", "values must be 0-127"},
+		{"drum 35 -> 36", "kind must be program, bank or note"},
+	}
+	for _, tt := range tests {
+		_, err := ParseMIDIRemap([]string{tt.rule})
+		switch {
+		case tt.want == "" && err != nil:
+			t.Errorf("ParseMIDIRemap(%q) failed: %v", tt.rule, err)
+		case tt.want != "" && (err == nil || !strings.Contains(err.Error(), tt.want)):
+			t.Errorf("ParseMIDIRemap(%q) error = %v, want %q", tt.rule, err, tt.want)
+		}
+	}
+	if _, err := ParseMIDIRemap([]string{"note 35 -> 36", "note 35 -> 37"}); err == nil {
+		t.Error("expected an error for a note remapped twice")
+	}
+}
+
+func TestLoadManifest_None(t *testing.T) {
+	m, err := LoadManifest(t.TempDir())
+	if err != nil || m != nil {
+		t.Errorf("LoadManifest without manifest = %v, %v; want nil, nil", m, err)
+	}
+}
+
+func TestLoadManifest_Errors(t *testing.T) {
+	tests := []struct {
+		name     string
+		file     string
+		manifest string
+		line     int
+		want     string
+	}{
+		{"unknown key", "project.yaml", "entry: MAIN.TFY\nsoundfnot: GM.sf2\n", 2, `unknown key "soundfnot" (known keys: entry, title`},
+		{"duplicate key", "project.yaml", "title: a\ntitle: b\n", 2, `duplicate key "title"`},
+		{"nested value", "project.yaml", "title:\n  name: a\n", 2, "nested values are not supported"},
+		{"list item without key", "project.yaml", "- pics\n", 1, "list item without a key"},
+		{"scalar as list", "project.yaml", "entry:\n  - MAIN.TFY\n", 2, `"entry" must be a single value`},
+		{"list as scalar", "project.yaml", "assets: pics\n", 1, `"assets" must be a list`},
+		{"not key value", "project.yaml", "entry MAIN.TFY\n", 1, `expected "key: value"`},
+		{"unterminated list", "project.yaml", "assets: [pics\n", 1, "unterminated list"},
+		{"missing entry", "project.yaml", "entry: NONE.TFY\n", 0, `entry file "NONE.TFY" not found`},
+		{"entry not tfy", "project.yaml", "entry: sound/GM.sf2\n", 0, "entry must be a .TFY file"},
+		{"missing soundfont", "project.yaml", "soundfont: none.sf2\n", 0, `soundfont "none.sf2" not found`},
+		{"bad resolution", "project.yaml", "resolution: 800*600\n", 0, "resolution must be WIDTHxHEIGHT"},
+		{"huge resolution", "project.yaml", "resolution: 100000x600\n", 0, "resolution must be WIDTHxHEIGHT"},
+		{"bad compat", "project.yaml", "compat: filly95\n", 0, "compat:"},
+		{"missing asset dir", "project.yaml", "assets: [images]\n", 0, `asset directory "images" not found`},
+		{"asset outside title", "project.yaml", "assets: [../pics]\n", 0, "outside the title directory"},
+		{"asset is a file", "project.yaml", "assets: [MAIN.TFY]\n", 0, `asset directory "MAIN.TFY" not found`},
+		{"var without value", "project.yaml", "vars: [ASSETS]\n", 0, "vars: asset variable must be NAME=value"},
+		{"bad var name", "project.yaml", "vars: [MY-ASSETS=hires]\n", 0, "vars: invalid asset variable name"},
+		{"duplicate var", "project.yaml", "vars: [A=1, A=2]\n", 0, `vars: duplicate variable "A"`},
+		{"vars as scalar", "project.yaml", "vars: A=1\n", 1, `"vars" must be a list`},
+		{"bad remap kind", "project.yaml", "midi_remap: [voice 1 -> 2]\n", 0, "midi_remap:"},
+		{"remap out of range", "project.yaml", "midi_remap: [program 128 -> 0]\n", 0, "values must be 0-127"},
+		{"bad pause menu", "project.yaml", "pause_menu: hidden\n", 0, "pause_menu must be on or off"},
+		{"bad pause menu color", "project.yaml", "pause_menu_color: \"#FFF\"\n", 0, "pause_menu_color must be #RRGGBB"},
+		{"json syntax", "project.json", "{\n  \"entry\": \"MAIN.TFY\",\n}\n", 3, "invalid JSON"},
+		{"json unknown key", "project.json", `{"entyr": "MAIN.TFY"}`, 0, "invalid JSON"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := writeManifestTitle(t, tt.file, tt.manifest)
+			m, err := LoadManifest(dir)
+			if err == nil {
+				t.Fatalf("LoadManifest = %+v, want error", m)
+			}
+			var merr *ManifestError
+			if !errors.As(err, &merr) {
+				t.Fatalf("error %v is not a *ManifestError", err)
+			}
+			if merr.Line != tt.line {
+				t.Errorf("Line = %d, want %d (%v)", merr.Line, tt.line, err)
+			}
+			if !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("error = %q, want it to contain %q", err, tt.want)
+			}
+			if !strings.HasPrefix(err.Error(), filepath.Join(dir, tt.file)) {
+				t.Errorf("error = %q, want it to start with the manifest path", err)
+			}
+		})
+	}
+}
+
+func TestLoadManifest_Priority(t *testing.T) {
+	dir := writeManifestTitle(t, "project.yaml", "entry: MAIN.TFY\n")
+	if err := os.WriteFile(filepath.Join(dir, "project.json"), []byte(`{"entry": "SUB.TFY"}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	m, err := LoadManifest(dir)
+	if err != nil {
+		t.Fatalf("LoadManifest failed: %v", err)
+	}
+	if m.Entry != "MAIN.TFY" {
+		t.Errorf("Entry = %q, want project.yaml to take precedence", m.Entry)
+	}
+}
+
+func TestLoadExternalTitle_Manifest(t *testing.T) {
+	dir := writeManifestTitle(t, "project.yaml", "entry: SUB.TFY\ntitle: Manifest Title\n")
+	// title.json よりマニフェストが優先される
+	if err := os.WriteFile(filepath.Join(dir, "title.json"), []byte(`{"entryFile": "MAIN.TFY"}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	registry := NewFillyTitleRegistry(testEmbedFS)
+	if err := registry.LoadExternalTitle(dir); err != nil {
+		t.Fatalf("LoadExternalTitle failed: %v", err)
+	}
+	title := registry.externalTitle
+	if title.Manifest == nil {
+		t.Fatal("Manifest should be loaded")
+	}
+	if title.EntryFile != "SUB.TFY" {
+		t.Errorf("EntryFile = %q, want SUB.TFY from the manifest", title.EntryFile)
+	}
+	if title.DisplayName() != "Manifest Title" {
+		t.Errorf("DisplayName = %q, want the manifest title", title.DisplayName())
+	}
+
+	// コマンドラインで指定したエントリーファイルはマニフェストより優先される
+	if err := registry.LoadExternalTitleWithEntry(dir, "MAIN.TFY"); err != nil {
+		t.Fatalf("LoadExternalTitleWithEntry failed: %v", err)
+	}
+	if registry.externalTitle.EntryFile != "MAIN.TFY" {
+		t.Errorf("EntryFile = %q, want MAIN.TFY from the argument", registry.externalTitle.EntryFile)
+	}
+}
+
+func TestLoadExternalTitle_InvalidManifest(t *testing.T) {
+	dir := writeManifestTitle(t, "project.yaml", "entry: NONE.TFY\n")
+	registry := NewFillyTitleRegistry(testEmbedFS)
+	err := registry.LoadExternalTitle(dir)
+	var merr *ManifestError
+	if !errors.As(err, &merr) {
+		t.Errorf("LoadExternalTitle = %v, want *ManifestError", err)
+	}
+}
@@ -0,0 +1,65 @@
+package title
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// MIDIの音色・バンク・ドラムの音の置き換え（マニフェストの midi_remap）
+//
+// 古いMIDIファイルの多くは特定の音源の音色の並びを前提にしているため、
+// SoundFont で鳴らすと違う楽器になることがある。規則は1行に1つ書く。
+//
+//	midi_remap:
+//	  - program 81 -> 80   # プログラムチェンジの番号（0〜127、MIDIファイル内の値）
+//	  - bank 8 -> 0        # バンクセレクト（CC#0）の値
+//	  - note 35 -> 36      # ドラム（チャンネル10）の音の番号
+
+// maxMIDIValue はMIDIのデータバイトの最大値
+const maxMIDIValue = 127
+
+// MIDIRemapTable は midi_remap を解析した置き換えの表（元の値 → 置き換える値）
+type MIDIRemapTable struct {
+	Programs  map[int]int // プログラムチェンジの番号
+	Banks     map[int]int // バンクセレクト（CC#0）の値
+	DrumNotes map[int]int // ドラムのチャンネルの音の番号
+}
+
+// ParseMIDIRemap は "種類 元の値 -> 置き換える値" の規則の並びを解析する
+// 種類は program・bank・note のいずれか。同じ値の規則が2つある場合は誤り。
+func ParseMIDIRemap(rules []string) (*MIDIRemapTable, error) {
+	table := &MIDIRemapTable{
+		Programs:  make(map[int]int),
+		Banks:     make(map[int]int),
+		DrumNotes: make(map[int]int),
+	}
+	for _, rule := range rules {
+		kind, rest, _ := strings.Cut(strings.TrimSpace(rule), " ")
+		var dst map[int]int
+		switch strings.ToLower(kind) {
+		case "program":
+			dst = table.Programs
+		case "bank":
+			dst = table.Banks
+		case "note":
+			dst = table.DrumNotes
+		default:
+			return nil, fmt.Errorf("%q: kind must be program, bank or note", rule)
+		}
+		fromStr, toStr, ok := strings.Cut(rest, "->")
+		if !ok {
+			return nil, fmt.Errorf("%q: expected \"%s FROM -> TO\"", rule, kind)
+		}
+		from, errFrom := strconv.Atoi(strings.TrimSpace(fromStr))
+		to, errTo := strconv.Atoi(strings.TrimSpace(toStr))
+		if errFrom != nil || errTo != nil || from < 0 || to < 0 || from > maxMIDIValue || to > maxMIDIValue {
+			return nil, fmt.Errorf("%q: values must be 0-%d", rule, maxMIDIValue)
+		}
+		if _, dup := dst[from]; dup {
+			return nil, fmt.Errorf("%q: %s %d is remapped twice", rule, kind, from)
+		}
+		dst[from] = to
+	}
+	return table, nil
+}
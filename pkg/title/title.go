@@ -8,6 +8,7 @@ import (
 	"io/fs"
 	"os"
 	"path/filepath"
+	"strconv"
 	"strings"
 
 	"github.com/zurustar/son-et/pkg/compiler/lexer"
@@ -28,6 +29,7 @@ type FillyTitle struct {
 	IsEmbedded bool           // embedされたタイトルかどうか
 	Metadata   *TitleMetadata // #infoから抽出したメタデータ
 	EntryFile  string         // エントリーポイントファイル名（空の場合は自動検出）
+	Manifest   *Manifest      // プロジェクトマニフェスト（project.yaml / project.json、ない場合は nil）
 }
 
 // TitleMetadata は#infoディレクティブから抽出したメタデータ
@@ -37,6 +39,7 @@ type TitleMetadata struct {
 	ISBJ string   // サブジェクト（説明）
 	IART string   // アーティスト
 	ICMT []string // コメント（複数行可）
+	FPS  int      // 描画フレームレート（#info FPS 30 など、0は未指定）
 }
 
 // FillyTitleRegistry はFILLYタイトルの管理を行う
@@ -145,6 +148,9 @@ func (r *FillyTitleRegistry) extractEmbeddedMetadata(titlePath string) *TitleMet
 		if metadata.IART == "" && meta.IART != "" {
 			metadata.IART = meta.IART
 		}
+		if metadata.FPS == 0 && meta.FPS != 0 {
+			metadata.FPS = meta.FPS
+		}
 		metadata.ICMT = append(metadata.ICMT, meta.ICMT...)
 	}
 
@@ -174,7 +180,8 @@ func (r *FillyTitleRegistry) LoadExternalTitleWithEntry(path string, entryFile s
 	info, err := os.Stat(path)
 	if err != nil {
 		if os.IsNotExist(err) {
-			return fmt.Errorf("title directory does not exist: %s", path)
+			// 終了コードで見つからないファイルとして扱えるよう fs.ErrNotExist を含める
+			return fmt.Errorf("title directory does not exist: %s: %w", path, fs.ErrNotExist)
 		}
 		return fmt.Errorf("failed to access title directory: %w", err)
 	}
@@ -189,14 +196,24 @@ func (r *FillyTitleRegistry) LoadExternalTitleWithEntry(path string, entryFile s
 		return fmt.Errorf("failed to get absolute path: %w", err)
 	}
 
+	// プロジェクトマニフェストに誤りがある場合は、推測で実行せずにエラーにする
+	manifest, err := LoadManifest(absPath)
+	if err != nil {
+		return err
+	}
+
 	// メタデータを抽出
 	metadata, _ := ExtractMetadataFromDirectory(absPath)
 
 	// エントリーファイルの決定
 	// 1. 引数で指定されていればそれを使用
-	// 2. title.jsonがあればそれを使用
-	// 3. どちらもなければ空（自動検出）
+	// 2. プロジェクトマニフェストの entry があればそれを使用
+	// 3. title.jsonがあればそれを使用
+	// 4. いずれもなければ空（自動検出）
 	finalEntryFile := entryFile
+	if finalEntryFile == "" && manifest != nil {
+		finalEntryFile = manifest.Entry
+	}
 	if finalEntryFile == "" {
 		finalEntryFile = loadTitleConfig(absPath)
 	}
@@ -207,6 +224,7 @@ func (r *FillyTitleRegistry) LoadExternalTitleWithEntry(path string, entryFile s
 		IsEmbedded: false,
 		Metadata:   metadata,
 		EntryFile:  finalEntryFile,
+		Manifest:   manifest,
 	}
 
 	return nil
@@ -319,6 +337,11 @@ func parseInfoDirective(literal string, metadata *TitleMetadata) {
 		metadata.IART = value
 	case "ICMT":
 		metadata.ICMT = append(metadata.ICMT, value)
+	case "FPS":
+		// 不正な値は無視する（既定のフレームレートで実行する）
+		if fps, err := strconv.Atoi(value); err == nil && fps > 0 {
+			metadata.FPS = fps
+		}
 	}
 }
 
@@ -349,6 +372,9 @@ func ExtractMetadataFromDirectory(dirPath string) (*TitleMetadata, error) {
 		if combined.IART == "" && meta.IART != "" {
 			combined.IART = meta.IART
 		}
+		if combined.FPS == 0 && meta.FPS != 0 {
+			combined.FPS = meta.FPS
+		}
 		combined.ICMT = append(combined.ICMT, meta.ICMT...)
 	}
 
@@ -356,8 +382,11 @@ func ExtractMetadataFromDirectory(dirPath string) (*TitleMetadata, error) {
 }
 
 // DisplayName はタイトルの表示名を返す
-// INAMがあればそれを、なければディレクトリ名を返す
+// プロジェクトマニフェストの title、INAM、ディレクトリ名の順に、最初に空でないものを返す
 func (t *FillyTitle) DisplayName() string {
+	if t.Manifest != nil && t.Manifest.Title != "" {
+		return t.Manifest.Title
+	}
 	if t.Metadata != nil && t.Metadata.INAM != "" {
 		return t.Metadata.INAM
 	}
@@ -210,6 +210,24 @@ main() {
 	}
 }
 
+func TestExtractMetadata_FPS(t *testing.T) {
+	tests := []struct {
+		content string
+		want    int
+	}{
+		{"#info FPS 30\nmain() {}\n", 30},
+		{"#info fps \"24\"\nmain() {}\n", 24},
+		{"#info FPS fast\nmain() {}\n", 0},
+		{"#info FPS -5\nmain() {}\n", 0},
+		{"main() {}\n", 0},
+	}
+	for _, tt := range tests {
+		if got := ExtractMetadata(tt.content).FPS; got != tt.want {
+			t.Errorf("ExtractMetadata(%q).FPS = %d, want %d", tt.content, got, tt.want)
+		}
+	}
+}
+
 func TestExtractMetadata_NoInfo(t *testing.T) {
 	content := `main() {
 	LoadPic("test.bmp");
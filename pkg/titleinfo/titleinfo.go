@@ -0,0 +1,241 @@
+// Package titleinfo はタイトルを実行せずに読み込み、目録作成向けの情報をまとめる（son-et info）。
+//
+// #info のメタデータ、TFYファイルの一覧、スクリプトが文字列リテラルで参照するアセット
+// （大きさと欠落の有無、MIDIの長さとPPQ）、コンパイル後のOpCodeの概数を集める。
+// アセットの検索は実行時と同じく大文字小文字を区別しない。
+package titleinfo
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+
+	"github.com/sinshu/go-meltysynth/meltysynth"
+
+	"github.com/zurustar/son-et/pkg/compat"
+	"github.com/zurustar/son-et/pkg/compiler"
+	"github.com/zurustar/son-et/pkg/fileutil"
+	"github.com/zurustar/son-et/pkg/opcode"
+	"github.com/zurustar/son-et/pkg/script"
+	"github.com/zurustar/son-et/pkg/title"
+)
+
+// Info はタイトルの情報
+type Info struct {
+	Path      string   `json:"path"`                // タイトルのディレクトリ
+	EntryFile string   `json:"entryFile,omitempty"` // エントリーポイントのファイル（見つからない場合は空）
+	Title     string   `json:"title,omitempty"`     // #info INAM
+	Author    string   `json:"author,omitempty"`    // #info IART
+	Copyright string   `json:"copyright,omitempty"` // #info ICOP
+	Subject   string   `json:"subject,omitempty"`   // #info ISBJ
+	Comments  []string `json:"comments,omitempty"`  // #info ICMT
+
+	Scripts       []ScriptFile `json:"scripts"`       // タイトル内のTFYファイル
+	IncludedFiles []string     `json:"includedFiles"` // エントリーポイントから #include で読み込まれるファイル
+	Assets        []Asset      `json:"assets"`        // スクリプトが参照するアセット
+	MissingAssets int          `json:"missingAssets"` // 見つからないアセットの数
+	OpCodes       int          `json:"opCodes"`       // コンパイル後のOpCodeの数（入れ子を含む概数）
+
+	// CompileError はエントリーポイントの検出またはコンパイルに失敗した場合のエラー
+	// （アセットとOpCodeの数は集められない）
+	CompileError string `json:"compileError,omitempty"`
+}
+
+// ScriptFile はタイトル内のTFYファイル
+type ScriptFile struct {
+	Name string `json:"name"`
+	Size int64  `json:"size"`
+}
+
+// Asset はスクリプトが参照するアセット
+type Asset struct {
+	Kind    string    `json:"kind"`              // "picture", "midi", "wave"
+	Path    string    `json:"path"`              // スクリプトに書かれたファイル名
+	Size    int64     `json:"size"`              // ファイルの大きさ（見つからない場合は0）
+	Missing bool      `json:"missing,omitempty"` // ファイルが見つからない
+	MIDI    *MIDIInfo `json:"midi,omitempty"`    // MIDIファイルの情報（読み取れた場合）
+}
+
+// MIDIInfo はMIDIファイルの情報
+type MIDIInfo struct {
+	Seconds float64 `json:"seconds"` // 演奏時間（秒）
+	PPQ     int     `json:"ppq"`     // 4分音符あたりのティック数
+}
+
+// Inspect はタイトルを実行せずに読み込み、情報を集める。
+// entryFile が空の場合は main 関数を含むファイルを探す。
+// TFYファイルが読めない場合はエラーを返すが、コンパイルの失敗は Info.CompileError に記録する。
+func Inspect(dir, entryFile string, mode compat.Mode) (*Info, error) {
+	scripts, err := script.NewLoader(dir).LoadAllScripts()
+	if err != nil {
+		return nil, err
+	}
+
+	info := &Info{Path: dir, EntryFile: entryFile}
+	for _, s := range scripts {
+		info.Scripts = append(info.Scripts, ScriptFile{Name: s.FileName, Size: s.Size})
+	}
+	meta, err := title.ExtractMetadataFromDirectory(dir)
+	if err == nil {
+		info.Title, info.Author, info.Copyright, info.Subject = meta.INAM, meta.IART, meta.ICOP, meta.ISBJ
+		info.Comments = meta.ICMT
+	}
+
+	if info.EntryFile == "" {
+		mainInfo, err := compiler.FindMainScript(scripts)
+		if err != nil {
+			info.CompileError = err.Error()
+			return info, nil
+		}
+		info.EntryFile = mainInfo.FileName
+	}
+
+	ops, result, err := compiler.CompileWithPreprocessorOptions(dir, info.EntryFile, nil, compiler.CompileOptions{Compat: mode})
+	if result != nil {
+		info.IncludedFiles = result.IncludedFiles
+	}
+	if err != nil {
+		info.CompileError = err.Error()
+		return info, nil
+	}
+	info.OpCodes = CountOpCodes(ops)
+
+	fsys := fileutil.NewRealFS(dir)
+	for _, a := range compiler.CollectAssets(ops) {
+		asset := inspectAsset(fsys, a)
+		if asset.Missing {
+			info.MissingAssets++
+		}
+		info.Assets = append(info.Assets, asset)
+	}
+	return info, nil
+}
+
+// inspectAsset はアセットの大きさを調べ、MIDIの場合は演奏時間とPPQを読み取る
+func inspectAsset(fsys *fileutil.RealFS, a compiler.Asset) Asset {
+	asset := Asset{Kind: a.Kind.String(), Path: a.Path}
+	f, err := fsys.Open(a.Path)
+	if err != nil {
+		asset.Missing = true
+		return asset
+	}
+	stat, err := f.Stat()
+	f.Close()
+	if err != nil {
+		asset.Missing = true
+		return asset
+	}
+	asset.Size = stat.Size()
+
+	if a.Kind == compiler.AssetMIDI {
+		if data, err := fsys.ReadFile(a.Path); err == nil {
+			asset.MIDI = readMIDIInfo(data)
+		}
+	}
+	return asset
+}
+
+// midiHeaderSize はSMFのヘッダーチャンク（"MThd"、長さ、形式、トラック数、分解能）の大きさ
+const midiHeaderSize = 14
+
+// readMIDIInfo はMIDIファイルの演奏時間とPPQを読み取る（読み取れない場合は nil）
+func readMIDIInfo(data []byte) *MIDIInfo {
+	if len(data) < midiHeaderSize || string(data[0:4]) != "MThd" {
+		return nil
+	}
+	midi, err := meltysynth.NewMidiFile(bytes.NewReader(data))
+	if err != nil {
+		return nil
+	}
+	return &MIDIInfo{
+		Seconds: midi.GetLength().Seconds(),
+		PPQ:     int(binary.BigEndian.Uint16(data[12:14])),
+	}
+}
+
+// CountOpCodes は関数本体・mes() ブロック・制御構文の中を含めたOpCodeの数を返す
+func CountOpCodes(ops []opcode.OpCode) int {
+	n := 0
+	for _, op := range ops {
+		n++
+		for _, arg := range op.Args {
+			n += countArg(arg)
+		}
+	}
+	return n
+}
+
+// countArg は引数に含まれるOpCodeの数を返す
+func countArg(arg any) int {
+	switch a := arg.(type) {
+	case opcode.OpCode:
+		return CountOpCodes([]opcode.OpCode{a})
+	case []opcode.OpCode:
+		return CountOpCodes(a)
+	case []any:
+		n := 0
+		for _, v := range a {
+			n += countArg(v)
+		}
+		return n
+	}
+	return 0
+}
+
+// WriteText は情報を人が読む形式で書き出す
+func (info *Info) WriteText(out io.Writer) error {
+	w := &strings.Builder{}
+	name := info.Title
+	if name == "" {
+		name = filepath.Base(info.Path)
+	}
+	writeField(w, "Title:", name)
+	writeField(w, "Author:", info.Author)
+	writeField(w, "Copyright:", info.Copyright)
+	writeField(w, "Subject:", info.Subject)
+	for _, c := range info.Comments {
+		writeField(w, "Comment:", c)
+	}
+	writeField(w, "Path:", info.Path)
+	writeField(w, "Entry:", info.EntryFile)
+
+	fmt.Fprintf(w, "\nScripts (%d):\n", len(info.Scripts))
+	for _, s := range info.Scripts {
+		fmt.Fprintf(w, "  %-24s %10d bytes\n", s.Name, s.Size)
+	}
+
+	if info.CompileError != "" {
+		fmt.Fprintf(w, "\nCompile error: %s\n", info.CompileError)
+	} else {
+		info.writeAssets(w)
+	}
+	_, err := io.WriteString(out, w.String())
+	return err
+}
+
+// writeAssets はOpCodeの数とアセットの一覧を書き出す
+func (info *Info) writeAssets(w *strings.Builder) {
+	fmt.Fprintf(w, "\nOpCodes:    %d\n", info.OpCodes)
+
+	fmt.Fprintf(w, "\nAssets (%d, %d missing):\n", len(info.Assets), info.MissingAssets)
+	for _, a := range info.Assets {
+		switch {
+		case a.Missing:
+			fmt.Fprintf(w, "  %-8s %-24s MISSING\n", a.Kind, a.Path)
+		case a.MIDI != nil:
+			fmt.Fprintf(w, "  %-8s %-24s %10d bytes  %.1fs, PPQ %d\n", a.Kind, a.Path, a.Size, a.MIDI.Seconds, a.MIDI.PPQ)
+		default:
+			fmt.Fprintf(w, "  %-8s %-24s %10d bytes\n", a.Kind, a.Path, a.Size)
+		}
+	}
+}
+
+// writeField は値が空でない場合に項目を1行書き出す
+func writeField(w *strings.Builder, label, value string) {
+	if value != "" {
+		fmt.Fprintf(w, "%-11s %s\n", label, value)
+	}
+}
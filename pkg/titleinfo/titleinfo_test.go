@@ -0,0 +1,179 @@
+package titleinfo
+
+import (
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/zurustar/son-et/pkg/compat"
+	"github.com/zurustar/son-et/pkg/compiler"
+)
+
+// testMIDI は PPQ 96、テンポ120（4分音符0.5秒）で4分音符を2つ鳴らす1秒のSMF（形式0）
+var testMIDI = []byte{
+	'M', 'T', 'h', 'd', 0, 0, 0, 6, 0, 0, 0, 1, 0, 96,
+	'M', 'T', 'r', 'k', 0, 0, 0, 27,
+	0x00, 0xFF, 0x51, 0x03, 0x07, 0xA1, 0x20, // テンポ 500000us
+	0x00, 0x90, 0x3C, 0x64, // NoteOn
+	0x60, 0x80, 0x3C, 0x00, // 96ティック後に NoteOff
+	0x00, 0x90, 0x3E, 0x64,
+	0x60, 0x80, 0x3E, 0x00,
+	0x00, 0xFF, 0x2F, 0x00, // End of Track
+}
+
+const testScript = `#info INAM "Info Test"
+#info IART "Someone"
+#info ICMT "first"
+#include "sub.tfy"
+
+main() {
+	LoadPic("Back.BMP");
+	PlayMIDI("song.mid");
+	PlayWAVE("missing.wav");
+	sub();
+}
+`
+
+const testSubScript = `sub() {
+	LoadPic("back.bmp");
+}
+`
+
+// writeTestTitle はテスト用のタイトルを一時ディレクトリに作成する
+func writeTestTitle(t *testing.T) string {
+	t.Helper()
+	dir := t.TempDir()
+	files := map[string][]byte{
+		"MAIN.TFY": []byte(testScript),
+		"sub.tfy":  []byte(testSubScript),
+		"BACK.BMP": make([]byte, 1234),
+		"SONG.MID": testMIDI,
+	}
+	for name, data := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), data, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+func TestInspect(t *testing.T) {
+	dir := writeTestTitle(t)
+	info, err := Inspect(dir, "", compat.Extended)
+	if err != nil {
+		t.Fatalf("Inspect failed: %v", err)
+	}
+
+	if info.CompileError != "" {
+		t.Fatalf("unexpected compile error: %s", info.CompileError)
+	}
+	if info.Title != "Info Test" || info.Author != "Someone" || len(info.Comments) != 1 {
+		t.Errorf("unexpected metadata: %+v", info)
+	}
+	if info.EntryFile != "MAIN.TFY" {
+		t.Errorf("EntryFile = %q, want MAIN.TFY", info.EntryFile)
+	}
+	if len(info.Scripts) != 2 {
+		t.Errorf("expected 2 scripts, got %+v", info.Scripts)
+	}
+	if info.OpCodes == 0 {
+		t.Error("expected the opcode count to be estimated")
+	}
+
+	// "back.bmp" は大文字小文字の違う同じファイルだが、スクリプトの表記ごとに1件となる
+	if len(info.Assets) != 4 || info.MissingAssets != 1 {
+		t.Fatalf("unexpected assets: %+v (missing %d)", info.Assets, info.MissingAssets)
+	}
+	assets := make(map[string]Asset)
+	for _, a := range info.Assets {
+		assets[a.Path] = a
+	}
+	if pic := assets["Back.BMP"]; pic.Kind != "picture" || pic.Size != 1234 || pic.Missing {
+		t.Errorf("unexpected picture asset: %+v", pic)
+	}
+	if midi := assets["song.mid"]; midi.MIDI == nil || midi.MIDI.PPQ != 96 || math.Abs(midi.MIDI.Seconds-1) > 0.01 {
+		t.Errorf("unexpected MIDI asset: %+v (%+v)", midi, midi.MIDI)
+	}
+	if wave := assets["missing.wav"]; !wave.Missing || wave.Size != 0 {
+		t.Errorf("expected missing.wav to be reported missing: %+v", wave)
+	}
+}
+
+func TestInspectExplicitEntry(t *testing.T) {
+	dir := writeTestTitle(t)
+	info, err := Inspect(dir, "sub.tfy", compat.Extended)
+	if err != nil {
+		t.Fatalf("Inspect failed: %v", err)
+	}
+	if info.EntryFile != "sub.tfy" || len(info.Assets) != 1 {
+		t.Errorf("expected only the assets of sub.tfy, got %+v", info.Assets)
+	}
+}
+
+func TestInspectCompileError(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "MAIN.TFY"), []byte("main() {\n\tx = = 5;\n}\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	info, err := Inspect(dir, "", compat.Extended)
+	if err != nil {
+		t.Fatalf("Inspect failed: %v", err)
+	}
+	if info.CompileError == "" || info.OpCodes != 0 {
+		t.Errorf("expected a compile error, got %+v", info)
+	}
+
+	var out strings.Builder
+	if err := info.WriteText(&out); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(out.String(), "Compile error:") {
+		t.Errorf("expected the compile error in the report:\n%s", out.String())
+	}
+}
+
+func TestInspectNoScripts(t *testing.T) {
+	if _, err := Inspect(t.TempDir(), "", compat.Extended); err == nil {
+		t.Error("expected an error for a directory without scripts")
+	}
+}
+
+func TestCountOpCodes(t *testing.T) {
+	ops, errs := compiler.Compile("main() {\n\tif (1) {\n\t\tx = 1;\n\t}\n}\n")
+	if len(errs) > 0 {
+		t.Fatalf("compile failed: %v", errs)
+	}
+	if n := CountOpCodes(ops); n <= len(ops) {
+		t.Errorf("CountOpCodes = %d, want more than the %d top-level opcodes", n, len(ops))
+	}
+	if n := CountOpCodes(nil); n != 0 {
+		t.Errorf("CountOpCodes(nil) = %d, want 0", n)
+	}
+}
+
+func TestWriteText(t *testing.T) {
+	info := &Info{
+		Path:      "/titles/demo",
+		EntryFile: "MAIN.TFY",
+		Author:    "Someone",
+		Scripts:   []ScriptFile{{Name: "MAIN.TFY", Size: 100}},
+		OpCodes:   42,
+		Assets: []Asset{
+			{Kind: "midi", Path: "song.mid", Size: 10, MIDI: &MIDIInfo{Seconds: 61.25, PPQ: 480}},
+			{Kind: "wave", Path: "se.wav", Missing: true},
+		},
+		MissingAssets: 1,
+	}
+	var out strings.Builder
+	if err := info.WriteText(&out); err != nil {
+		t.Fatal(err)
+	}
+	text := out.String()
+	for _, want := range []string{"Title:      demo", "Author:     Someone", "OpCodes:    42", "Assets (2, 1 missing)", "61.2s, PPQ 480", "se.wav", "MISSING"} {
+		if !strings.Contains(text, want) {
+			t.Errorf("expected %q in the report:\n%s", want, text)
+		}
+	}
+}
@@ -4,34 +4,72 @@
 package audio
 
 import (
+	"errors"
+	"io"
 	"path/filepath"
+	"strings"
 	"sync"
 	"time"
 
-	"github.com/hajimehoshi/ebiten/v2/audio"
+	"github.com/zurustar/son-et/pkg/eventbus"
 	"github.com/zurustar/son-et/pkg/fileutil"
 	"github.com/zurustar/son-et/pkg/vm"
 )
 
+// Event bus topics published by the AudioSystem.
+const (
+	TopicMIDIPlay  = eventbus.CategoryAudio + ".midi.play"
+	TopicMIDIStop  = eventbus.CategoryAudio + ".midi.stop"
+	TopicWAVPlay   = eventbus.CategoryAudio + ".wav.play"
+	TopicMute      = eventbus.CategoryAudio + ".mute"
+	TopicPause     = eventbus.CategoryAudio + ".pause"
+	TopicResume    = eventbus.CategoryAudio + ".resume"
+	TopicMixer     = eventbus.CategoryAudio + ".mixer"
+	TopicTimeScale = eventbus.CategoryAudio + ".timescale"
+	TopicAVOffset  = eventbus.CategoryAudio + ".avoffset"
+	TopicDucking   = eventbus.CategoryAudio + ".ducking"
+)
+
+// Range of the time scale (slow motion / fast forward) set by SetTimeScale.
+const (
+	MinTimeScale = 0.25
+	MaxTimeScale = 4.0
+)
+
 // AudioSystem is the main interface for audio operations in the FILLY VM.
 // It manages the lifecycle of all audio components and provides a unified API
 // for audio playback and control.
 //
-// Design: AudioSystem integrates midiPlayer, wavPlayer, timer, and audioCtx.
+// Design: AudioSystem integrates midiPlayer, wavPlayer, timer, and the audio output.
 // The AudioSystem should be the main interface for audio operations.
 // It manages the lifecycle of all audio components.
 type AudioSystem struct {
-	// midiPlayer handles MIDI file playback
+	// midiPlayer handles MIDI file playback (MainMIDIPort)
 	midiPlayer *MIDIPlayer
 
+	// ports holds the players of the other MIDI ports by name (see midi_ports.go)
+	ports map[string]*MIDIPlayer
+
+	// clockPort is the port selected by SetMIDIClock to drive MIDI_TIME events
+	clockPort string
+
 	// wavPlayer handles WAV file playback
 	wavPlayer *WAVPlayer
 
+	// voicePlayer plays WAV files on the voice bus (PlayVoice), which ducks the music bus
+	voicePlayer *WAVPlayer
+
+	// ducker lowers the music bus while the voice bus is playing (see ducking.go)
+	ducker ducker
+
 	// timer generates periodic TIME events
 	timer *Timer
 
-	// audioCtx is the shared Ebitengine audio context
-	audioCtx *audio.Context
+	// mixer holds the master/music/sfx bus volumes applied to the players
+	mixer *Mixer
+
+	// output is the shared audio output (an Ebitengine audio context, or NullOutput in tests)
+	output Output
 
 	// eventQueue is the event queue for audio events
 	eventQueue *vm.EventQueue
@@ -50,80 +88,68 @@ type AudioSystem struct {
 	// (and should not create a new one)
 	ownsAudioCtx bool
 
-	// fadeout state
-	fadingOut       bool
-	fadeStartTime   time.Time
-	fadeDuration    time.Duration
-	fadeStartVolume float64
+	// fadeout state (MIDI only)
+	fadingOut     bool
+	fadeStartTime time.Time
+	fadeDuration  time.Duration
 
-	// mu protects the audio system state
-	mu sync.RWMutex
-}
+	// master fadeout state (all buses, e.g. on exit)
+	masterFadingOut     bool
+	masterFadeStartTime time.Time
+	masterFadeDuration  time.Duration
 
-// NewAudioSystem creates a new AudioSystem with the specified SoundFont and event queue.
-// The AudioSystem creates and shares a single Ebitengine audio context among all components.
-//
-// Design: NewAudioSystem(soundFontPath string, eventSystem *EventSystem) (*AudioSystem, error)
-//
-// Parameters:
-//   - soundFontPath: Path to the SoundFont (.sf2) file for MIDI playback
-//   - eventQueue: Event queue for TIME and MIDI_TIME events
-//
-// Returns:
-//   - *AudioSystem: The initialized AudioSystem
-//   - error: Error if initialization fails (e.g., SoundFont not found)
-func NewAudioSystem(soundFontPath string, eventQueue *vm.EventQueue) (*AudioSystem, error) {
-	// Create shared audio context
-	// All audio components share this context for automatic mixing
-	audioCtx := audio.NewContext(SampleRate)
-	return NewAudioSystemWithContext(soundFontPath, eventQueue, audioCtx)
-}
+	// timeScale is the speed of the TIME timer and MIDI playback (1 = normal speed)
+	timeScale float64
 
-// NewAudioSystemWithContext creates a new AudioSystem with an existing audio context.
-// This is useful for testing or when the audio context is managed externally.
-//
-// Parameters:
-//   - soundFontPath: Path to the SoundFont (.sf2) file for MIDI playback
-//   - eventQueue: Event queue for TIME and MIDI_TIME events
-//   - audioCtx: Existing Ebitengine audio context to use
-//
-// Returns:
-//   - *AudioSystem: The initialized AudioSystem
-//   - error: Error if initialization fails (e.g., SoundFont not found)
-func NewAudioSystemWithContext(soundFontPath string, eventQueue *vm.EventQueue, audioCtx *audio.Context) (*AudioSystem, error) {
-	return NewAudioSystemWithFS(soundFontPath, eventQueue, audioCtx, nil)
+	// avOffset delays MIDI_TIME/MIDI_NOTE events behind the audio clock (see avoffset.go)
+	avOffset time.Duration
+
+	// calibration is the running A/V calibration click track (nil when not calibrating)
+	calibration *avCalibration
+
+	// paused indicates whether audio and TIME events are suspended (e.g. window focus lost)
+	paused   bool
+	pausedAt time.Time
+
+	// onPlayMIDI is called with the requested filename whenever PlayMIDI is called
+	// (used by --render-audio to find the piece to render)
+	onPlayMIDI func(filename string)
+
+	// bus receives playback state changes for external tools (nil = not published)
+	bus *eventbus.Bus
+
+	// mu protects the audio system state
+	mu sync.RWMutex
 }
 
-// NewAudioSystemWithFS creates a new AudioSystem with FileSystem support.
-// This allows loading SoundFont from embedded file system.
+// NewAudioSystemWithOutput creates a new AudioSystem playing on an audio output.
+// Tests pass a NullOutput to run without a sound device or an Ebitengine audio
+// context, advancing the audio with NullOutput.Advance.
 //
 // Parameters:
 //   - soundFontPath: Path to the SoundFont (.sf2) file for MIDI playback
 //   - eventQueue: Event queue for TIME and MIDI_TIME events
-//   - audioCtx: Existing Ebitengine audio context to use (can be nil)
+//   - output: Audio output shared by all players (must not be nil)
 //   - soundFontFS: FileSystem for loading SoundFont (can be nil for regular file system)
 //
 // Returns:
 //   - *AudioSystem: The initialized AudioSystem
 //   - error: Error if initialization fails (e.g., SoundFont not found)
-func NewAudioSystemWithFS(soundFontPath string, eventQueue *vm.EventQueue, audioCtx *audio.Context, soundFontFS fileutil.FileSystem) (*AudioSystem, error) {
-	// Create audio context if not provided
-	ownsAudioCtx := false
-	if audioCtx == nil {
-		audioCtx = audio.NewContext(SampleRate)
-		ownsAudioCtx = true
+func NewAudioSystemWithOutput(soundFontPath string, eventQueue *vm.EventQueue, output Output, soundFontFS fileutil.FileSystem) (*AudioSystem, error) {
+	if output == nil {
+		return nil, ErrNoAudioContext
 	}
 
-	// Create MIDI player with shared audio context and FileSystem support
+	// Create MIDI player with shared audio output and FileSystem support
 	// Requirement 4.9: When SoundFont file is provided, system uses it for MIDI synthesis.
-	midiPlayer, err := NewMIDIPlayerWithFS(soundFontPath, audioCtx, eventQueue, soundFontFS)
+	midiPlayer, err := newMIDIPlayer(soundFontPath, output, eventQueue, soundFontFS)
 	if err != nil {
 		return nil, err
 	}
 
-	// Create WAV player with shared audio context
+	// Create WAV player with shared audio output
 	// Requirement 5.6: System mixes multiple WAV streams into a single audio output.
-	wavPlayer := NewWAVPlayer(audioCtx)
+	wavPlayer := newWAVPlayer(output)
 
 	// Create timer for TIME event generation
 	// Requirement 3.1: System generates TIME events periodically.
@@ -132,12 +158,15 @@ func NewAudioSystemWithFS(soundFontPath string, eventQueue *vm.EventQueue, audio
 	return &AudioSystem{
 		midiPlayer:    midiPlayer,
 		wavPlayer:     wavPlayer,
+		voicePlayer:   newWAVPlayer(output),
 		timer:         timer,
-		audioCtx:      audioCtx,
+		mixer:         NewMixer(),
+		output:        output,
 		eventQueue:    eventQueue,
 		muted:         false,
 		soundFontPath: soundFontPath,
-		ownsAudioCtx:  ownsAudioCtx,
+		timeScale:     1,
+		clockPort:     MainMIDIPort,
 	}, nil
 }
 
@@ -164,6 +193,10 @@ func (as *AudioSystem) PlayMIDI(filename string) error {
 		return ErrNoSoundFont
 	}
 
+	if as.onPlayMIDI != nil {
+		as.onPlayMIDI(filename)
+	}
+
 	// If FileSystem is set, extract just the filename (base name)
 	// because the FileSystem already has the base path configured
 	playPath := filename
@@ -171,7 +204,16 @@ func (as *AudioSystem) PlayMIDI(filename string) error {
 		playPath = extractFilename(filename)
 	}
 
-	return as.midiPlayer.Play(playPath)
+	if err := as.midiPlayer.Play(playPath); err != nil {
+		return err
+	}
+
+	// A MIDI started while paused must not be heard until Resume
+	if as.paused {
+		as.midiPlayer.Pause()
+	}
+	as.bus.Publish(TopicMIDIPlay, map[string]any{"file": filename})
+	return nil
 }
 
 // PlayWAVE starts playback of the specified WAV file.
@@ -202,7 +244,11 @@ func (as *AudioSystem) PlayWAVE(filename string) error {
 		playPath = extractFilename(filename)
 	}
 
-	return as.wavPlayer.Play(playPath)
+	if err := as.wavPlayer.Play(playPath); err != nil {
+		return err
+	}
+	as.bus.Publish(TopicWAVPlay, map[string]any{"file": filename})
+	return nil
 }
 
 // extractFilename extracts the base filename from a path.
@@ -228,15 +274,17 @@ func (as *AudioSystem) SetMuted(muted bool) {
 
 	as.muted = muted
 
-	// Mute MIDI player
+	// Mute MIDI players
 	if as.midiPlayer != nil {
 		as.midiPlayer.SetMuted(muted)
 	}
+	as.eachPort(func(mp *MIDIPlayer) { mp.SetMuted(muted) })
 
-	// Mute WAV player
-	if as.wavPlayer != nil {
-		as.wavPlayer.SetMuted(muted)
+	// Mute WAV players (stay muted while paused; restored on Resume)
+	if !as.paused {
+		as.eachWAV(func(wp *WAVPlayer) { wp.SetMuted(muted) })
 	}
+	as.bus.Publish(TopicMute, map[string]any{"muted": muted})
 }
 
 // IsMuted returns whether the audio system is muted.
@@ -261,36 +309,56 @@ func (as *AudioSystem) Update() {
 	as.mu.Lock()
 	defer as.mu.Unlock()
 
+	// Nothing advances while paused
+	if as.paused {
+		return
+	}
+
+	// Process master fadeout if active
+	if as.masterFadingOut {
+		elapsed := time.Since(as.masterFadeStartTime)
+		if elapsed >= as.masterFadeDuration {
+			// Master fadeout complete - stop everything and restore the bus volumes
+			if as.midiPlayer != nil {
+				as.midiPlayer.Stop()
+			}
+			as.eachPort(func(mp *MIDIPlayer) { mp.Stop() })
+			as.eachWAV(func(wp *WAVPlayer) { wp.StopAll() })
+			as.masterFadingOut = false
+			as.mixer.setFade(1)
+		} else {
+			as.mixer.setFade(1.0 - float64(elapsed)/float64(as.masterFadeDuration))
+		}
+		as.applyMixer()
+	}
+
 	// Process fadeout if active
 	if as.fadingOut {
 		elapsed := time.Since(as.fadeStartTime)
 		if elapsed >= as.fadeDuration {
-			// Fadeout complete - stop MIDI
+			// Fadeout complete - stop MIDI and restore the music bus volume
 			if as.midiPlayer != nil {
 				as.midiPlayer.Stop()
+				as.midiPlayer.SetVolume(as.mixer.Volume(BusMusic))
 			}
 			as.fadingOut = false
-		} else {
-			// Calculate current volume (linear fadeout)
+		} else if as.midiPlayer != nil {
+			// Linear fadeout from the music bus volume (the player stays silent while muted)
 			progress := float64(elapsed) / float64(as.fadeDuration)
-			currentVolume := as.fadeStartVolume * (1.0 - progress)
-
-			// Apply volume to MIDI player
-			if as.midiPlayer != nil && as.midiPlayer.player != nil {
-				as.midiPlayer.player.SetVolume(currentVolume)
-			}
+			as.midiPlayer.SetVolume(as.mixer.Volume(BusMusic) * (1.0 - progress))
 		}
 	}
 
-	// Update MIDI player (generates MIDI_TIME and MIDI_END events)
+	// Update MIDI players (generates MIDI_TIME and MIDI_END events from the clock port)
+	as.arbitrateClock()
 	if as.midiPlayer != nil {
 		as.midiPlayer.Update()
 	}
+	as.eachPort(func(mp *MIDIPlayer) { mp.Update() })
 
-	// Update WAV player (cleanup finished players)
-	if as.wavPlayer != nil {
-		as.wavPlayer.Update()
-	}
+	// Update WAV players (cleanup finished players), then duck the music while a voice plays
+	as.eachWAV(func(wp *WAVPlayer) { wp.Update() })
+	as.updateDucking(time.Now())
 }
 
 // Shutdown stops all audio playback and releases resources.
@@ -313,10 +381,18 @@ func (as *AudioSystem) Shutdown() {
 	if as.midiPlayer != nil {
 		as.midiPlayer.Stop()
 	}
+	as.eachPort(func(mp *MIDIPlayer) { mp.Stop() })
 
 	// Stop all WAV playback
-	if as.wavPlayer != nil {
-		as.wavPlayer.StopAll()
+	as.eachWAV(func(wp *WAVPlayer) { wp.StopAll() })
+	// Stop the A/V calibration click track
+	if as.calibration != nil {
+		as.calibration.player.Close()
+		as.calibration = nil
+	}
+	// Stop the clock of an output without a sound device (ClockOutput)
+	if closer, ok := as.output.(io.Closer); ok {
+		closer.Close()
 	}
 }
 
@@ -329,6 +405,10 @@ func (as *AudioSystem) StartTimer() {
 
 	if as.timer != nil {
 		as.timer.Start()
+		// A timer started while paused must not send TIME events until Resume
+		if as.paused {
+			as.timer.Pause()
+		}
 	}
 }
 
@@ -342,6 +422,111 @@ func (as *AudioSystem) StopTimer() {
 	}
 }
 
+// Pause suspends the audio system, e.g. when the window loses focus.
+// TIME event generation and MIDI playback are suspended at their current
+// position and WAV output is muted. Elapsed time is not accumulated while
+// paused, so Resume continues seamlessly without a burst of TIME/MIDI_TIME events.
+func (as *AudioSystem) Pause() {
+	as.mu.Lock()
+	defer as.mu.Unlock()
+
+	if as.paused {
+		return
+	}
+	as.paused = true
+	as.pausedAt = time.Now()
+
+	if as.timer != nil {
+		as.timer.Pause()
+	}
+	if as.midiPlayer != nil {
+		as.midiPlayer.Pause()
+	}
+	as.eachPort(func(mp *MIDIPlayer) { mp.Pause() })
+	as.eachWAV(func(wp *WAVPlayer) { wp.SetMuted(true) })
+	as.bus.Publish(TopicPause, nil)
+}
+
+// Resume continues the audio system after Pause.
+func (as *AudioSystem) Resume() {
+	as.mu.Lock()
+	defer as.mu.Unlock()
+
+	if !as.paused {
+		return
+	}
+	as.paused = false
+
+	// Time spent paused does not count towards an in-progress fadeout
+	if as.fadingOut {
+		as.fadeStartTime = as.fadeStartTime.Add(time.Since(as.pausedAt))
+	}
+	if as.masterFadingOut {
+		as.masterFadeStartTime = as.masterFadeStartTime.Add(time.Since(as.pausedAt))
+	}
+
+	if as.timer != nil {
+		as.timer.Resume()
+	}
+	if as.midiPlayer != nil {
+		as.midiPlayer.Resume()
+	}
+	as.eachPort(func(mp *MIDIPlayer) { mp.Resume() })
+	as.eachWAV(func(wp *WAVPlayer) { wp.SetMuted(as.muted) })
+	// The ducking ramp does not advance while paused
+	as.ducker.last = time.Time{}
+	as.bus.Publish(TopicResume, nil)
+}
+
+// SetTimeScale sets the playback speed for reviewing long titles (slow motion / fast forward).
+// The TIME timer and MIDI playback (as a tempo scale, without changing the pitch) are scaled
+// together, so TIME and MIDI_TIME handlers stay in step with each other.
+// WAV playback is not affected. The scale is clamped to MinTimeScale-MaxTimeScale
+// and the applied scale is returned.
+func (as *AudioSystem) SetTimeScale(scale float64) float64 {
+	scale = max(MinTimeScale, min(MaxTimeScale, scale))
+
+	as.mu.Lock()
+	defer as.mu.Unlock()
+
+	as.timeScale = scale
+	if as.timer != nil {
+		as.timer.SetScale(scale)
+	}
+	if as.midiPlayer != nil {
+		as.midiPlayer.SetTempoScale(scale)
+	}
+	as.eachPort(func(mp *MIDIPlayer) { mp.SetTempoScale(scale) })
+	as.bus.Publish(TopicTimeScale, map[string]any{"scale": scale})
+	return scale
+}
+
+// SetSyncClock makes the TIME timer follow a clock shared with other son-et
+// instances (--sync-master / --sync-follow), so that their TIME events fire in lockstep.
+// nil returns to the local timer. MIDI playback keeps its own clock.
+func (as *AudioSystem) SetSyncClock(clock SyncClock) {
+	as.mu.Lock()
+	defer as.mu.Unlock()
+
+	if as.timer != nil {
+		as.timer.SetClock(clock)
+	}
+}
+
+// TimeScale returns the playback speed set by SetTimeScale (1 = normal speed).
+func (as *AudioSystem) TimeScale() float64 {
+	as.mu.RLock()
+	defer as.mu.RUnlock()
+	return as.timeScale
+}
+
+// IsPaused returns whether the audio system is paused.
+func (as *AudioSystem) IsPaused() bool {
+	as.mu.RLock()
+	defer as.mu.RUnlock()
+	return as.paused
+}
+
 // IsTimerRunning returns whether the timer is currently running.
 func (as *AudioSystem) IsTimerRunning() bool {
 	as.mu.RLock()
@@ -372,16 +557,51 @@ func (as *AudioSystem) StopMIDI() {
 	if as.midiPlayer != nil {
 		as.midiPlayer.Stop()
 	}
+	as.bus.Publish(TopicMIDIStop, nil)
+}
+
+// SeekMIDI jumps forward to a FILLY tick of the MIDI file played by PlayMIDI (see MIDIPlayer.Seek).
+func (as *AudioSystem) SeekMIDI(tick int) error {
+	as.mu.RLock()
+	defer as.mu.RUnlock()
+
+	if as.midiPlayer == nil {
+		return errors.New("no MIDI is playing")
+	}
+	return as.midiPlayer.Seek(tick)
+}
+
+// SkipMIDI jumps forward to a FILLY tick of the MIDI file played by PlayMIDI
+// without delivering the skipped MIDI_TIME events (see MIDIPlayer.Skip).
+func (as *AudioSystem) SkipMIDI(tick int) error {
+	as.mu.RLock()
+	defer as.mu.RUnlock()
+
+	if as.midiPlayer == nil {
+		return errors.New("no MIDI is playing")
+	}
+	return as.midiPlayer.Skip(tick)
+}
+
+// MIDITickAfter returns the FILLY tick the MIDI file played by PlayMIDI reaches
+// d after its last delivered MIDI_TIME tick at normal speed, and whether it has
+// ended by then (see MIDIPlayer.TickAfter). Returns -1 if nothing is playing.
+func (as *AudioSystem) MIDITickAfter(d time.Duration) (int, bool) {
+	as.mu.RLock()
+	defer as.mu.RUnlock()
+
+	if as.midiPlayer == nil {
+		return -1, false
+	}
+	return as.midiPlayer.TickAfter(d)
 }
 
-// StopAllWAV stops all WAV playback.
+// StopAllWAV stops all WAV playback, including the voice bus.
 func (as *AudioSystem) StopAllWAV() {
 	as.mu.Lock()
 	defer as.mu.Unlock()
 
-	if as.wavPlayer != nil {
-		as.wavPlayer.StopAll()
-	}
+	as.eachWAV(func(wp *WAVPlayer) { wp.StopAll() })
 }
 
 // GetMIDIPlayer returns the MIDI player for advanced operations.
@@ -408,14 +628,6 @@ func (as *AudioSystem) GetTimer() *Timer {
 	return as.timer
 }
 
-// GetAudioContext returns the shared audio context.
-// This is useful for testing and debugging.
-func (as *AudioSystem) GetAudioContext() *audio.Context {
-	as.mu.RLock()
-	defer as.mu.RUnlock()
-	return as.audioCtx
-}
-
 // GetEventQueue returns the event queue.
 func (as *AudioSystem) GetEventQueue() *vm.EventQueue {
 	as.mu.RLock()
@@ -437,12 +649,100 @@ func (as *AudioSystem) StartFadeout(duration time.Duration) {
 	as.fadingOut = true
 	as.fadeStartTime = time.Now()
 	as.fadeDuration = duration
-	as.fadeStartVolume = 1.0 // Start from full volume
+}
+
+// StartMasterFadeout fades all buses out together over the specified duration
+// through the master bus, then stops MIDI and WAV playback (e.g. before exiting).
+// Bus gains are not changed; the volumes are restored once the fadeout completes.
+func (as *AudioSystem) StartMasterFadeout(duration time.Duration) {
+	as.mu.Lock()
+	defer as.mu.Unlock()
+
+	as.masterFadingOut = true
+	as.masterFadeStartTime = time.Now()
+	as.masterFadeDuration = duration
+}
+
+// IsMasterFadingOut returns whether a master fadeout is in progress.
+func (as *AudioSystem) IsMasterFadingOut() bool {
+	as.mu.RLock()
+	defer as.mu.RUnlock()
+	return as.masterFadingOut
+}
+
+// SetBusGain sets the gain (0-1) of a mixer bus (BusMaster, BusMusic or BusSFX)
+// and applies the resulting volumes to the players.
+func (as *AudioSystem) SetBusGain(bus string, gain float64) error {
+	as.mu.Lock()
+	defer as.mu.Unlock()
+
+	if err := as.mixer.SetGain(bus, gain); err != nil {
+		return err
+	}
+	as.applyMixer()
+	as.publishBus(bus)
+	return nil
+}
+
+// BusGain returns the gain of a mixer bus.
+func (as *AudioSystem) BusGain(bus string) (float64, error) {
+	return as.mixer.Gain(bus)
+}
 
-	// If muted, start from 0 volume
-	if as.muted {
-		as.fadeStartVolume = 0.0
+// SetBusMuted mutes or unmutes a mixer bus and applies the resulting volumes to the players.
+// Unlike SetMuted, this is controlled by the script and does not affect headless muting.
+func (as *AudioSystem) SetBusMuted(bus string, muted bool) error {
+	as.mu.Lock()
+	defer as.mu.Unlock()
+
+	if err := as.mixer.SetMuted(bus, muted); err != nil {
+		return err
 	}
+	as.applyMixer()
+	as.publishBus(bus)
+	return nil
+}
+
+// GetMixer returns the mixer.
+func (as *AudioSystem) GetMixer() *Mixer {
+	return as.mixer
+}
+
+// applyMixer applies the mixer's bus volumes to the players.
+// During a MIDI fadeout the music volume of the main port is left to Update.
+// Must be called with as.mu held.
+func (as *AudioSystem) applyMixer() {
+	if as.midiPlayer != nil && !as.fadingOut {
+		as.midiPlayer.SetVolume(as.mixer.Volume(BusMusic))
+	}
+	as.eachPort(func(mp *MIDIPlayer) { mp.SetVolume(as.mixer.Volume(BusMusic)) })
+	if as.wavPlayer != nil {
+		as.wavPlayer.SetVolume(as.mixer.Volume(BusSFX))
+	}
+	if as.voicePlayer != nil {
+		as.voicePlayer.SetVolume(as.mixer.Volume(BusVoice))
+	}
+}
+
+// eachWAV calls fn for the WAV player of each bus (sfx and voice).
+// Must be called with as.mu held.
+func (as *AudioSystem) eachWAV(fn func(wp *WAVPlayer)) {
+	for _, wp := range []*WAVPlayer{as.wavPlayer, as.voicePlayer} {
+		if wp != nil {
+			fn(wp)
+		}
+	}
+}
+
+// publishBus publishes the state of a mixer bus to the event bus.
+// Must be called with as.mu held.
+func (as *AudioSystem) publishBus(bus string) {
+	if !as.bus.HasSubscribers() {
+		return
+	}
+	gain, _ := as.mixer.Gain(bus)
+	muted, _ := as.mixer.IsMuted(bus)
+	as.bus.Publish(TopicMixer, map[string]any{"bus": strings.ToLower(bus), "gain": gain, "muted": muted})
 }
 
 // IsFadingOut returns whether a fadeout is currently in progress.
@@ -452,6 +752,24 @@ func (as *AudioSystem) IsFadingOut() bool {
 	return as.fadingOut
 }
 
+// SetOnPlayMIDI sets a callback invoked with the filename each time PlayMIDI is called.
+// The callback is called with the AudioSystem lock held and must not call back into it.
+// Passing nil removes the callback.
+func (as *AudioSystem) SetOnPlayMIDI(fn func(filename string)) {
+	as.mu.Lock()
+	defer as.mu.Unlock()
+	as.onPlayMIDI = fn
+}
+
+// SetEventBus sets the event bus that receives playback state changes
+// (audio.midi.play, audio.wav.play, audio.mute, ...). MIDI_TIME and MIDI_END are
+// published by the event queue as vm.event.* topics. Passing nil disables publishing.
+func (as *AudioSystem) SetEventBus(bus *eventbus.Bus) {
+	as.mu.Lock()
+	defer as.mu.Unlock()
+	as.bus = bus
+}
+
 // SetFileSystem sets the file system interface for reading audio files.
 // This allows the AudioSystem to read files from embedded file systems.
 //
@@ -463,15 +781,14 @@ func (as *AudioSystem) SetFileSystem(fs fileutil.FileSystem) {
 
 	as.fs = fs
 
-	// Update MIDI player's file system
+	// Update MIDI players' file system
 	if as.midiPlayer != nil {
 		as.midiPlayer.SetFileSystem(fs)
 	}
+	as.eachPort(func(mp *MIDIPlayer) { mp.SetFileSystem(fs) })
 
-	// Update WAV player's file system
-	if as.wavPlayer != nil {
-		as.wavPlayer.SetFileSystem(fs)
-	}
+	// Update WAV players' file system
+	as.eachWAV(func(wp *WAVPlayer) { wp.SetFileSystem(fs) })
 }
 
 // GetFileSystem returns the current file system interface.
@@ -1,3 +1,5 @@
+//go:build !nogpu
+
 // Package audio provides audio-related components for the FILLY virtual machine.
 // This file contains tests for the AudioSystem integration.
 package audio
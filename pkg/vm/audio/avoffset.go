@@ -0,0 +1,184 @@
+package audio
+
+import (
+	"encoding/binary"
+	"errors"
+	"math"
+	"sync"
+	"time"
+)
+
+// MaxAVOffset is the largest A/V offset (in either direction) accepted by SetAVOffset.
+const MaxAVOffset = time.Second
+
+// Calibration click track: one short click per beat.
+const (
+	calibrationBeat        = 500 * time.Millisecond // 120 BPM
+	calibrationClickLength = 30 * time.Millisecond
+	calibrationClickFreq   = 1000.0 // Hz
+	calibrationClickVolume = 0.5
+	calibrationFlashLength = 100 * time.Millisecond // how long the visual beat is shown
+)
+
+// ErrNoAudioContext is returned by StartAVCalibration when there is no audio output.
+var ErrNoAudioContext = errors.New("no audio context available")
+
+// SetAVOffset sets how far MIDI_TIME and MIDI_NOTE events lag behind the audio clock.
+// A positive offset compensates for output latency (Bluetooth speakers): the visuals wait
+// until the sound is actually heard. A negative offset moves the visuals earlier, for
+// displays that are slower than the audio.
+func (mp *MIDIPlayer) SetAVOffset(offset time.Duration) {
+	mp.mu.Lock()
+	defer mp.mu.Unlock()
+	mp.avOffset = offset
+}
+
+// visualSamples returns the song position that the visuals follow: the audio
+// position shifted by the A/V offset, kept within the song.
+// Must be called with mp.mu held.
+func (mp *MIDIPlayer) visualSamples() int64 {
+	if mp.player == nil {
+		return 0
+	}
+	output := int64(mp.player.Position().Seconds()*float64(SampleRate)) - int64(mp.avOffset.Seconds()*float64(SampleRate))
+	samples := max(mp.songSamplesAt(max(output, 0)), 0)
+	if mp.duration > 0 {
+		samples = min(samples, int64(mp.duration.Seconds()*float64(SampleRate)))
+	}
+	return samples
+}
+
+// SetAVOffset sets the audio-visual offset of all MIDI ports (see MIDIPlayer.SetAVOffset).
+// The offset is clamped to ±MaxAVOffset and the applied offset is returned.
+// It also applies to the beat shown by the calibration screen.
+func (as *AudioSystem) SetAVOffset(offset time.Duration) time.Duration {
+	offset = max(-MaxAVOffset, min(MaxAVOffset, offset))
+
+	as.mu.Lock()
+	defer as.mu.Unlock()
+
+	as.avOffset = offset
+	if as.midiPlayer != nil {
+		as.midiPlayer.SetAVOffset(offset)
+	}
+	as.eachPort(func(mp *MIDIPlayer) { mp.SetAVOffset(offset) })
+	as.bus.Publish(TopicAVOffset, map[string]any{"offset_ms": offset.Milliseconds()})
+	return offset
+}
+
+// AVOffset returns the audio-visual offset set by SetAVOffset.
+func (as *AudioSystem) AVOffset() time.Duration {
+	as.mu.RLock()
+	defer as.mu.RUnlock()
+	return as.avOffset
+}
+
+// StartAVCalibration pauses the title's audio and plays a click track for calibrating
+// the A/V offset. AVCalibrationBeat reports when the visual beat should be shown;
+// the offset is right when the flash and the click are perceived together.
+func (as *AudioSystem) StartAVCalibration() error {
+	as.mu.RLock()
+	output := as.output
+	calibrating := as.calibration != nil
+	as.mu.RUnlock()
+	if output == nil {
+		return ErrNoAudioContext
+	}
+	if calibrating {
+		return nil
+	}
+
+	// Pause before taking the lock again (Pause locks as.mu)
+	wasPaused := as.IsPaused()
+	as.Pause()
+
+	player, err := output.NewPlayer(&clickStream{})
+	if err != nil {
+		if !wasPaused {
+			as.Resume()
+		}
+		return err
+	}
+	player.SetVolume(calibrationClickVolume)
+	player.Play()
+
+	as.mu.Lock()
+	as.calibration = &avCalibration{player: player, wasPaused: wasPaused}
+	as.mu.Unlock()
+	return nil
+}
+
+// StopAVCalibration stops the click track and resumes the title's audio.
+func (as *AudioSystem) StopAVCalibration() {
+	as.mu.Lock()
+	c := as.calibration
+	as.calibration = nil
+	as.mu.Unlock()
+	if c == nil {
+		return
+	}
+	c.player.Close()
+	if !c.wasPaused {
+		as.Resume()
+	}
+}
+
+// AVCalibrationBeat reports whether the calibration beat should be shown now:
+// the click track position delayed by the A/V offset is at the start of a beat.
+// It returns false when calibration is not running.
+func (as *AudioSystem) AVCalibrationBeat() bool {
+	as.mu.RLock()
+	defer as.mu.RUnlock()
+	if as.calibration == nil {
+		return false
+	}
+	return calibrationBeatAt(as.calibration.player.Position() - as.avOffset)
+}
+
+// calibrationBeatAt reports whether a click track position is within the flash of a beat.
+func calibrationBeatAt(position time.Duration) bool {
+	if position < 0 {
+		return false
+	}
+	return position%calibrationBeat < calibrationFlashLength
+}
+
+// avCalibration is the state of a running A/V calibration.
+type avCalibration struct {
+	player    OutputPlayer
+	wasPaused bool // the audio system was already paused (e.g. focus lost) before calibration
+}
+
+// clickStream generates the calibration click track (16-bit stereo PCM at SampleRate).
+// Each beat starts with a short decaying sine click followed by silence.
+type clickStream struct {
+	mu     sync.Mutex
+	sample int64 // next sample to generate
+}
+
+// Read implements io.Reader.
+func (s *clickStream) Read(p []byte) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	beatSamples := int64(calibrationBeat.Seconds() * SampleRate)
+	n := len(p) / 4 * 4
+	for i := 0; i < n; i += 4 {
+		v := int16(clickWave(s.sample%beatSamples) * math.MaxInt16)
+		binary.LittleEndian.PutUint16(p[i:], uint16(v))
+		binary.LittleEndian.PutUint16(p[i+2:], uint16(v))
+		s.sample++
+	}
+	return n, nil
+}
+
+// clickWave returns the click at sample t from its start (-1 to 1): a short decaying
+// sine, silent from calibrationClickLength on. The metronome uses the same click.
+func clickWave(t int64) float64 {
+	clickSamples := int64(calibrationClickLength.Seconds() * SampleRate)
+	if t < 0 || t >= clickSamples {
+		return 0
+	}
+	decay := 1 - float64(t)/float64(clickSamples)
+	return math.Sin(2*math.Pi*calibrationClickFreq*float64(t)/SampleRate) * decay
+}
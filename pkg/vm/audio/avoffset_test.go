@@ -0,0 +1,99 @@
+package audio
+
+import (
+	"encoding/binary"
+	"testing"
+	"time"
+)
+
+// TestAudioSystemSetAVOffset tests that the A/V offset is clamped and applied to every MIDI port.
+func TestAudioSystemSetAVOffset(t *testing.T) {
+	main := &MIDIPlayer{}
+	port := &MIDIPlayer{}
+	as := &AudioSystem{midiPlayer: main, ports: map[string]*MIDIPlayer{"bgm": port}}
+
+	if got := as.SetAVOffset(40 * time.Millisecond); got != 40*time.Millisecond || as.AVOffset() != got {
+		t.Errorf("expected offset 40ms, got %v", got)
+	}
+	if main.avOffset != 40*time.Millisecond || port.avOffset != 40*time.Millisecond {
+		t.Errorf("offset not applied to the players: main %v, port %v", main.avOffset, port.avOffset)
+	}
+	if got := as.SetAVOffset(5 * time.Second); got != MaxAVOffset {
+		t.Errorf("expected offset to be clamped to %v, got %v", MaxAVOffset, got)
+	}
+	if got := as.SetAVOffset(-5 * time.Second); got != -MaxAVOffset {
+		t.Errorf("expected offset to be clamped to %v, got %v", -MaxAVOffset, got)
+	}
+}
+
+// TestStartAVCalibrationWithoutContext tests that calibration needs an audio context.
+func TestStartAVCalibrationWithoutContext(t *testing.T) {
+	as := &AudioSystem{}
+	if err := as.StartAVCalibration(); err != ErrNoAudioContext {
+		t.Errorf("expected ErrNoAudioContext, got %v", err)
+	}
+	if as.AVCalibrationBeat() {
+		t.Error("no beat should be shown without calibration")
+	}
+	as.StopAVCalibration() // no-op
+	if as.IsPaused() {
+		t.Error("a failed calibration should not pause the audio system")
+	}
+}
+
+func TestCalibrationBeatAt(t *testing.T) {
+	tests := []struct {
+		position time.Duration
+		want     bool
+	}{
+		{-10 * time.Millisecond, false}, // the offset delays the first beat
+		{0, true},
+		{calibrationFlashLength - time.Millisecond, true},
+		{calibrationFlashLength, false},
+		{calibrationBeat - time.Millisecond, false},
+		{calibrationBeat, true},
+		{3*calibrationBeat + 50*time.Millisecond, true},
+	}
+	for _, tt := range tests {
+		if got := calibrationBeatAt(tt.position); got != tt.want {
+			t.Errorf("calibrationBeatAt(%v) = %v, want %v", tt.position, got, tt.want)
+		}
+	}
+}
+
+// TestClickStream tests that the click track has a click at the start of every beat and silence between.
+func TestClickStream(t *testing.T) {
+	beatSamples := int(calibrationBeat.Seconds() * SampleRate)
+	clickSamples := int(calibrationClickLength.Seconds() * SampleRate)
+	buf := make([]byte, (2*beatSamples+2)*4)
+	s := &clickStream{}
+	if n, err := s.Read(buf); err != nil || n != len(buf) {
+		t.Fatalf("Read = %d, %v", n, err)
+	}
+	sample := func(i int) int16 { return int16(binary.LittleEndian.Uint16(buf[i*4:])) }
+
+	for _, beat := range []int{0, beatSamples} {
+		loud := false
+		for i := beat; i < beat+clickSamples; i++ {
+			if sample(i) != int16(binary.LittleEndian.Uint16(buf[i*4+2:])) {
+				t.Fatalf("left and right channels differ at sample %d", i)
+			}
+			if sample(i) > 1000 || sample(i) < -1000 {
+				loud = true
+			}
+		}
+		if !loud {
+			t.Errorf("expected a click at sample %d", beat)
+		}
+		for i := beat + clickSamples; i < beat+beatSamples && i < 2*beatSamples; i++ {
+			if sample(i) != 0 {
+				t.Fatalf("expected silence at sample %d, got %d", i, sample(i))
+			}
+		}
+	}
+
+	// 奇数バイトは読み残す
+	if n, _ := s.Read(make([]byte, 6)); n != 4 {
+		t.Errorf("expected whole frames only, got %d bytes", n)
+	}
+}
@@ -0,0 +1,63 @@
+// Package audio provides audio-related components for the FILLY virtual machine.
+// This file implements ClockOutput, the audio output of the nogpu build.
+package audio
+
+import (
+	"sync"
+	"time"
+)
+
+// clockOutputPeriod is how often a ClockOutput reads the playing streams.
+const clockOutputPeriod = 10 * time.Millisecond
+
+// ClockOutput is an audio output without a sound device that plays in real time:
+// a goroutine reads the playing streams at the rate of the wall clock and discards
+// the mix. MIDI_TIME and MIDI_END are generated as with a sound device, so
+// headless runs on servers without audio hardware keep the timing of the title.
+type ClockOutput struct {
+	*NullOutput
+	stop chan struct{}
+	done chan struct{}
+	once sync.Once
+}
+
+// NewClockOutput creates a ClockOutput and starts its clock.
+// Close stops the clock.
+func NewClockOutput() *ClockOutput {
+	o := &ClockOutput{
+		NullOutput: NewNullOutput(),
+		stop:       make(chan struct{}),
+		done:       make(chan struct{}),
+	}
+	go o.run(time.Now())
+	return o
+}
+
+// run reads the streams until Close is called.
+// The frames to read are counted from start, so the played time does not drift
+// from the wall clock when a tick is late.
+func (o *ClockOutput) run(start time.Time) {
+	defer close(o.done)
+	ticker := time.NewTicker(clockOutputPeriod)
+	defer ticker.Stop()
+
+	played := 0
+	for {
+		select {
+		case <-o.stop:
+			return
+		case now := <-ticker.C:
+			total := int(now.Sub(start) * SampleRate / time.Second)
+			o.advanceFrames(total - played)
+			played = total
+		}
+	}
+}
+
+// Close stops the clock; the players are no longer read.
+// It can be called more than once.
+func (o *ClockOutput) Close() error {
+	o.once.Do(func() { close(o.stop) })
+	<-o.done
+	return nil
+}
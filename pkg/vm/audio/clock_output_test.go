@@ -0,0 +1,64 @@
+// Package audio provides audio-related components for the FILLY virtual machine.
+// This file contains tests for ClockOutput.
+package audio
+
+import (
+	"bytes"
+	"testing"
+	"time"
+)
+
+// TestClockOutputPlaysInRealTime tests that a ClockOutput reads the playing streams at the rate of the wall clock.
+func TestClockOutputPlaysInRealTime(t *testing.T) {
+	out := NewClockOutput()
+	defer out.Close()
+
+	p, err := out.NewPlayer(bytes.NewReader(pcmFrames(SampleRate, 100, 100)))
+	if err != nil {
+		t.Fatalf("NewPlayer failed: %v", err)
+	}
+	p.Play()
+
+	deadline := time.Now().Add(2 * time.Second)
+	for p.Position() < 50*time.Millisecond {
+		if time.Now().After(deadline) {
+			t.Fatalf("position = %v after 2s, want at least 50ms", p.Position())
+		}
+		time.Sleep(clockOutputPeriod)
+	}
+	if out.DrainTime() != 0 {
+		t.Errorf("DrainTime() = %v, want 0", out.DrainTime())
+	}
+}
+
+// TestClockOutputClose tests that Close stops the clock and can be called twice.
+func TestClockOutputClose(t *testing.T) {
+	out := NewClockOutput()
+	p, _ := out.NewPlayer(bytes.NewReader(pcmFrames(SampleRate, 100, 100)))
+	p.Play()
+
+	if err := out.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+	stopped := p.Position()
+	time.Sleep(5 * clockOutputPeriod)
+	if got := p.Position(); got != stopped {
+		t.Errorf("position = %v after Close, want %v", got, stopped)
+	}
+	if err := out.Close(); err != nil {
+		t.Errorf("second Close failed: %v", err)
+	}
+}
+
+// TestAudioSystemShutdownClosesOutput tests that Shutdown stops the clock of a ClockOutput.
+func TestAudioSystemShutdownClosesOutput(t *testing.T) {
+	out := NewClockOutput()
+	as := &AudioSystem{output: out}
+	as.Shutdown()
+
+	select {
+	case <-out.done:
+	default:
+		t.Error("the clock is still running after Shutdown")
+	}
+}
@@ -0,0 +1,131 @@
+package audio
+
+import (
+	"math"
+	"time"
+)
+
+// Ducking
+//
+// WAV files played by PlayVoice go to the voice bus. While any of them is playing,
+// the music bus is lowered by the ducking depth, so narration stays intelligible over
+// the MIDI music. The music fades down over the attack time when a voice starts and
+// back up over the release time after the last voice ends. Ducking is off (depth 0)
+// until the script calls SetDucking.
+
+// Limits of the ducking settings accepted by SetDucking.
+const (
+	MaxDuckingDepth = 60.0             // dB
+	MaxDuckingRamp  = 10 * time.Second // attack and release
+)
+
+// ducker lowers the music bus while the voice bus is playing.
+type ducker struct {
+	depth   float64       // how far the music is lowered (dB, 0 = off)
+	attack  time.Duration // time to reach depth after a voice starts
+	release time.Duration // time to return to 0 dB after the last voice ends
+	level   float64       // current attenuation (dB)
+	last    time.Time     // time of the previous step (zero = not stepped yet)
+}
+
+// step moves the attenuation towards depth while active (a voice is playing)
+// or towards 0 dB otherwise, at the rate given by the attack or release time,
+// and returns the resulting gain factor for the music bus.
+func (d *ducker) step(active bool, now time.Time) float64 {
+	var elapsed time.Duration
+	if !d.last.IsZero() {
+		elapsed = now.Sub(d.last)
+	}
+	d.last = now
+
+	target, ramp := 0.0, d.release
+	if active {
+		target, ramp = d.depth, d.attack
+	}
+	if ramp <= 0 || d.depth <= 0 {
+		d.level = target
+	} else {
+		// The ramp covers the whole depth in the ramp time
+		delta := d.depth * elapsed.Seconds() / ramp.Seconds()
+		if d.level < target {
+			d.level = min(d.level+delta, target)
+		} else {
+			d.level = max(d.level-delta, target)
+		}
+	}
+	return dbToGain(-d.level)
+}
+
+// dbToGain converts a level in decibels to a linear gain factor (0 dB = 1).
+func dbToGain(db float64) float64 {
+	return math.Pow(10, db/20)
+}
+
+// PlayVoice plays a WAV file on the voice bus; the music bus is ducked while it plays.
+func (as *AudioSystem) PlayVoice(filename string) error {
+	as.mu.Lock()
+	defer as.mu.Unlock()
+
+	if as.voicePlayer == nil {
+		return nil // No error, just skip if not initialized
+	}
+
+	playPath := filename
+	if as.fs != nil {
+		playPath = extractFilename(filename)
+	}
+	if err := as.voicePlayer.Play(playPath); err != nil {
+		return err
+	}
+	as.bus.Publish(TopicWAVPlay, map[string]any{"file": filename, "bus": BusVoice})
+	return nil
+}
+
+// SetDucking sets how far (in dB) the music bus is lowered while the voice bus is
+// playing, and how long it takes to go down (attack) and back up (release).
+// A depth of 0 turns ducking off. The settings are clamped to MaxDuckingDepth and
+// MaxDuckingRamp, and the applied depth is returned.
+func (as *AudioSystem) SetDucking(depth float64, attack, release time.Duration) float64 {
+	depth = min(max(depth, 0), MaxDuckingDepth)
+	attack = min(max(attack, 0), MaxDuckingRamp)
+	release = min(max(release, 0), MaxDuckingRamp)
+
+	as.mu.Lock()
+	defer as.mu.Unlock()
+
+	as.ducker.depth = depth
+	as.ducker.attack = attack
+	as.ducker.release = release
+	as.bus.Publish(TopicDucking, map[string]any{
+		"depth_db":   depth,
+		"attack_ms":  attack.Milliseconds(),
+		"release_ms": release.Milliseconds(),
+	})
+	return depth
+}
+
+// Ducking returns the ducking settings set by SetDucking.
+func (as *AudioSystem) Ducking() (depth float64, attack, release time.Duration) {
+	as.mu.RLock()
+	defer as.mu.RUnlock()
+	return as.ducker.depth, as.ducker.attack, as.ducker.release
+}
+
+// IsVoicePlaying returns whether a WAV file played by PlayVoice is playing.
+func (as *AudioSystem) IsVoicePlaying() bool {
+	as.mu.RLock()
+	defer as.mu.RUnlock()
+	return as.voicePlayer != nil && as.voicePlayer.GetActivePlayerCount() > 0
+}
+
+// updateDucking steps the ducking of the music bus and applies the volumes if it changed.
+// Must be called with as.mu held.
+func (as *AudioSystem) updateDucking(now time.Time) {
+	active := as.voicePlayer != nil && as.voicePlayer.GetActivePlayerCount() > 0
+	before := as.ducker.level
+	duck := as.ducker.step(active, now)
+	if as.ducker.level != before {
+		as.mixer.setDuck(duck)
+		as.applyMixer()
+	}
+}
@@ -0,0 +1,107 @@
+package audio
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func TestDbToGain(t *testing.T) {
+	for _, tt := range []struct {
+		db, want float64
+	}{
+		{0, 1},
+		{-6, 0.501},
+		{-20, 0.1},
+		{-40, 0.01},
+	} {
+		if got := dbToGain(tt.db); math.Abs(got-tt.want) > 1e-3 {
+			t.Errorf("dbToGain(%v) = %v, want %v", tt.db, got, tt.want)
+		}
+	}
+}
+
+// TestDuckerStep tests the attack and release ramps of the ducking.
+func TestDuckerStep(t *testing.T) {
+	d := &ducker{depth: 20, attack: 200 * time.Millisecond, release: time.Second}
+	start := time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC)
+	at := func(ms int) time.Time { return start.Add(time.Duration(ms) * time.Millisecond) }
+
+	// No time has passed at the first step, so the gain does not drop yet
+	if g := d.step(true, at(0)); g != 1 {
+		t.Errorf("gain at the start = %v, want 1", g)
+	}
+	// Halfway through the attack the gain is at half the depth (-10dB)
+	d.step(true, at(100))
+	if math.Abs(d.level-10) > 1e-9 {
+		t.Errorf("level after half the attack = %v, want 10", d.level)
+	}
+	// After the attack the gain stays at the depth
+	if g := d.step(true, at(500)); math.Abs(g-0.1) > 1e-9 || d.level != 20 {
+		t.Errorf("gain after the attack = %v (level %v), want 0.1 (20dB)", g, d.level)
+	}
+	// The release returns to 0dB over the release time
+	d.step(false, at(1000))
+	if math.Abs(d.level-10) > 1e-9 {
+		t.Errorf("level after half the release = %v, want 10", d.level)
+	}
+	if g := d.step(false, at(2000)); g != 1 || d.level != 0 {
+		t.Errorf("gain after the release = %v (level %v), want 1", g, d.level)
+	}
+}
+
+func TestDuckerImmediate(t *testing.T) {
+	now := time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	// With zero times the gain switches at once
+	d := &ducker{depth: 6}
+	if g := d.step(true, now); math.Abs(g-dbToGain(-6)) > 1e-9 {
+		t.Errorf("gain without an attack = %v, want %v", g, dbToGain(-6))
+	}
+	if g := d.step(false, now); g != 1 {
+		t.Errorf("gain without a release = %v, want 1", g)
+	}
+
+	// With zero depth the gain does not drop
+	d = &ducker{attack: time.Second, release: time.Second}
+	if g := d.step(true, now.Add(time.Second)); g != 1 {
+		t.Errorf("gain with ducking off = %v, want 1", g)
+	}
+}
+
+// TestAudioSystemSetDucking tests that the ducking settings are clamped.
+func TestAudioSystemSetDucking(t *testing.T) {
+	as := &AudioSystem{mixer: NewMixer()}
+	if got := as.SetDucking(12, 100*time.Millisecond, 500*time.Millisecond); got != 12 {
+		t.Errorf("SetDucking returned %v, want 12", got)
+	}
+	if depth, attack, release := as.Ducking(); depth != 12 || attack != 100*time.Millisecond || release != 500*time.Millisecond {
+		t.Errorf("Ducking() = %v, %v, %v", depth, attack, release)
+	}
+
+	if got := as.SetDucking(100, -time.Second, time.Minute); got != MaxDuckingDepth {
+		t.Errorf("SetDucking returned %v, want %v", got, MaxDuckingDepth)
+	}
+	if _, attack, release := as.Ducking(); attack != 0 || release != MaxDuckingRamp {
+		t.Errorf("ramps should be clamped, got %v, %v", attack, release)
+	}
+	if got := as.SetDucking(-3, 0, 0); got != 0 {
+		t.Errorf("SetDucking returned %v, want 0", got)
+	}
+}
+
+// TestAudioSystemUpdateDuckingWithoutVoice tests that the music is not ducked when no voice plays.
+func TestAudioSystemUpdateDuckingWithoutVoice(t *testing.T) {
+	as := &AudioSystem{mixer: NewMixer()}
+	as.SetDucking(12, 0, 0)
+	as.updateDucking(time.Now())
+	if v := as.mixer.Volume(BusMusic); v != MaxBusGain {
+		t.Errorf("Volume(music) = %v, want %v", v, MaxBusGain)
+	}
+	if as.IsVoicePlaying() {
+		t.Error("IsVoicePlaying should be false without a voice player")
+	}
+	if err := as.PlayVoice("voice.wav"); err != nil {
+		t.Errorf("PlayVoice should not return error when the voice player is nil: %v", err)
+	}
+}
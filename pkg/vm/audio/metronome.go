@@ -0,0 +1,121 @@
+package audio
+
+// The metronome (--metronome) mixes a click into the MIDI audio on every quarter
+// note of the song, so authors can hear whether MIDI_TIME cues fall on the beat.
+// Only the port driving MIDI_TIME clicks, and the clicks follow its tempo map and
+// tempo scale. MetronomeBeat tells the window when to flash the beat indicator.
+
+// metronomeVolume is the amplitude of the click mixed into the MIDI audio.
+const metronomeVolume = 0.5
+
+// metronome places clicks at the song positions of the quarter notes of a MIDI file.
+type metronome struct {
+	tickCalc *TickCalculator
+	next     int   // index of the next quarter note
+	nextAt   int64 // song position (samples at normal tempo) of the next quarter note
+	click    int64 // output samples since the current click started
+}
+
+// newMetronome creates a metronome for the tempo map of tickCalc, starting at the
+// song position song. It returns nil if the file has no usable tempo information.
+func newMetronome(tickCalc *TickCalculator, song int64) *metronome {
+	if tickCalc == nil || tickCalc.ppq <= 0 || len(tickCalc.tempoMap) == 0 {
+		return nil
+	}
+	m := &metronome{tickCalc: tickCalc}
+	m.seek(song)
+	return m
+}
+
+// seek moves to a song position: the next click is on the first quarter note at
+// or after it, and a click that is sounding is cut.
+func (m *metronome) seek(song int64) {
+	ppq := m.tickCalc.ppq
+	m.next = m.tickCalc.TickFromSamples(song) / ppq
+	m.nextAt = m.tickCalc.SamplesFromTick(m.next * ppq)
+	for m.nextAt < song {
+		m.advance()
+	}
+	m.click = int64(calibrationClickLength.Seconds() * SampleRate)
+}
+
+// advance moves to the following quarter note.
+func (m *metronome) advance() {
+	m.next++
+	// advance at least one sample so a zero tempo cannot stall
+	m.nextAt = max(m.tickCalc.SamplesFromTick(m.next*m.tickCalc.ppq), m.nextAt+1)
+}
+
+// mix adds the clicks to rendered samples. song is the song position of the first
+// sample and scale the song samples per output sample (the tempo scale).
+// Quarter notes skipped within one sample (very fast forward) sound as one click.
+func (m *metronome) mix(left, right []float32, song, scale float64) {
+	for i := range left {
+		if pos := song + float64(i)*scale; pos >= float64(m.nextAt) {
+			m.click = 0
+			for float64(m.nextAt) <= pos {
+				m.advance()
+			}
+		}
+		v := float32(clickWave(m.click) * metronomeVolume)
+		left[i] += v
+		right[i] += v
+		m.click++
+	}
+}
+
+// SetMetronome sets whether a click is mixed in on every quarter note
+// (applied while the player drives MIDI_TIME events).
+func (mp *MIDIPlayer) SetMetronome(enabled bool) {
+	mp.mu.Lock()
+	defer mp.mu.Unlock()
+	mp.metronome = enabled
+	mp.applyMetronome()
+}
+
+// applyMetronome turns the click of the current sequencer on or off.
+// Must be called with mp.mu held.
+func (mp *MIDIPlayer) applyMetronome() {
+	if mp.sequencer != nil {
+		mp.sequencer.SetMetronome(mp.metronome && mp.timeEvents)
+	}
+}
+
+// metronomeBeat reports whether the visual position (delayed by the A/V offset)
+// is within the flash of a quarter note.
+func (mp *MIDIPlayer) metronomeBeat() bool {
+	mp.mu.RLock()
+	defer mp.mu.RUnlock()
+	if !mp.playing || mp.paused || mp.tickCalc == nil || mp.tickCalc.ppq <= 0 {
+		return false
+	}
+	visual := mp.visualSamples()
+	ppq := mp.tickCalc.ppq
+	beat := mp.tickCalc.SamplesFromTick(mp.tickCalc.TickFromSamples(visual) / ppq * ppq)
+	return visual-beat < int64(calibrationFlashLength.Seconds()*SampleRate)
+}
+
+// SetMetronome sets whether a click is mixed into the MIDI audio on every quarter
+// note of the port driving MIDI_TIME events (--metronome).
+func (as *AudioSystem) SetMetronome(enabled bool) {
+	as.mu.Lock()
+	defer as.mu.Unlock()
+
+	if as.midiPlayer == nil {
+		return
+	}
+	as.midiPlayer.SetMetronome(enabled)
+	as.eachPort(func(mp *MIDIPlayer) {
+		mp.SetMetronome(enabled)
+	})
+}
+
+// MetronomeBeat reports whether the beat indicator (--metronome-flash) should be
+// shown now: a quarter note of the port driving MIDI_TIME events has just been
+// heard (the position is delayed by the A/V offset like MIDI_TIME).
+func (as *AudioSystem) MetronomeBeat() bool {
+	as.mu.RLock()
+	defer as.mu.RUnlock()
+	mp := as.portPlayer(as.activeClockPort())
+	return mp != nil && mp.metronomeBeat()
+}
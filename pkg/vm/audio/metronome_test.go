@@ -0,0 +1,147 @@
+package audio
+
+import (
+	"testing"
+	"time"
+)
+
+// metronomeTestCalc returns a tick calculator at 120 BPM (a quarter note every half second).
+func metronomeTestCalc() *TickCalculator {
+	return NewTickCalculator(480, []TempoEvent{{Tick: 0, MicrosPerBeat: 500000}})
+}
+
+// clickStarts returns the samples at which a click starts (silence before them).
+func clickStarts(left []float32) []int {
+	var starts []int
+	for i := 1; i < len(left); i++ {
+		if left[i] != 0 && left[i-1] == 0 && (len(starts) == 0 || i-starts[len(starts)-1] > 1000) {
+			starts = append(starts, i-1)
+		}
+	}
+	return starts
+}
+
+func TestMetronomeMix(t *testing.T) {
+	beat := SampleRate / 2
+	tests := []struct {
+		name  string
+		song  int64
+		scale float64
+		want  []int
+	}{
+		{"from the start", 0, 1, []int{0, beat, 2 * beat}},
+		{"twice as fast", 0, 2, []int{0, beat / 2, beat, 3 * beat / 2, 2 * beat}},
+		{"from the middle of a beat", int64(beat / 2), 1, []int{beat / 2, 3 * beat / 2}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			left := make([]float32, 2*beat+10)
+			right := make([]float32, len(left))
+			m := newMetronome(metronomeTestCalc(), tt.song)
+			// positions carry over when rendered block by block
+			for start := 0; start < len(left); start += 64 {
+				end := min(start+64, len(left))
+				m.mix(left[start:end], right[start:end], float64(tt.song)+float64(start)*tt.scale, tt.scale)
+			}
+
+			got := clickStarts(left)
+			if len(got) != len(tt.want) {
+				t.Fatalf("clicks at %v, want %v", got, tt.want)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Errorf("click %d at sample %d, want %d", i, got[i], tt.want[i])
+				}
+			}
+			for i := range left {
+				if left[i] != right[i] {
+					t.Fatalf("left and right differ at sample %d", i)
+				}
+			}
+		})
+	}
+}
+
+// TestMetronomeSeek tests that a seek cuts the sounding click and clicks again on the next quarter note.
+func TestMetronomeSeek(t *testing.T) {
+	beat := SampleRate / 2
+	m := newMetronome(metronomeTestCalc(), 0)
+	buf := make([]float32, 10)
+	m.mix(buf, make([]float32, 10), 0, 1)
+	if buf[1] == 0 {
+		t.Fatal("expected a click at the start")
+	}
+
+	m.seek(int64(beat + 100))
+	left := make([]float32, beat)
+	m.mix(left, make([]float32, beat), float64(beat+100), 1)
+	if got := clickStarts(left); len(got) != 1 || got[0] != beat-100 {
+		t.Errorf("clicks at %v after the seek, want [%d]", got, beat-100)
+	}
+	if left[0] != 0 {
+		t.Error("expected the click before the seek to be cut")
+	}
+}
+
+func TestNewMetronomeWithoutTempo(t *testing.T) {
+	if newMetronome(NewTickCalculator(0, nil), 0) != nil {
+		t.Error("expected no metronome without a tempo map")
+	}
+	if newMetronome(nil, 0) != nil {
+		t.Error("expected no metronome without a tick calculator")
+	}
+}
+
+// TestAudioSystemSetMetronome tests that the setting reaches every MIDI port.
+func TestAudioSystemSetMetronome(t *testing.T) {
+	main := &MIDIPlayer{}
+	port := &MIDIPlayer{}
+	as := &AudioSystem{midiPlayer: main, ports: map[string]*MIDIPlayer{"bgm": port}}
+	as.SetMetronome(true)
+	if !main.metronome || !port.metronome {
+		t.Errorf("metronome main %v, port %v; want both on", main.metronome, port.metronome)
+	}
+	as.SetMetronome(false)
+	if main.metronome || port.metronome {
+		t.Error("expected the metronome to be off")
+	}
+}
+
+// TestMetronomeBeat tests that the beat indicator is shown for a moment after each
+// quarter note, delayed by the A/V offset.
+func TestMetronomeBeat(t *testing.T) {
+	out := NewNullOutput()
+	player, err := out.NewPlayer(&clickStream{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	player.Play()
+	mp := &MIDIPlayer{playing: true, player: player, tickCalc: metronomeTestCalc()}
+	as := &AudioSystem{midiPlayer: mp}
+
+	if !as.MetronomeBeat() {
+		t.Error("expected the beat at the start of the song")
+	}
+	out.Advance(calibrationFlashLength + 10*time.Millisecond)
+	if as.MetronomeBeat() {
+		t.Error("expected no beat after the flash")
+	}
+	out.Advance(400 * time.Millisecond) // just after beat 2
+	if !as.MetronomeBeat() {
+		t.Error("expected the second beat")
+	}
+
+	as.SetAVOffset(50 * time.Millisecond)
+	out.Advance(100 * time.Millisecond) // 110ms after the beat (60ms once the offset is subtracted)
+	if !as.MetronomeBeat() {
+		t.Error("expected the offset to delay the end of the flash")
+	}
+
+	mp.paused = true
+	if as.MetronomeBeat() {
+		t.Error("expected no beat while paused")
+	}
+	if (&AudioSystem{}).MetronomeBeat() {
+		t.Error("expected no beat without a MIDI player")
+	}
+}
@@ -12,7 +12,6 @@ import (
 	"sync"
 	"time"
 
-	"github.com/hajimehoshi/ebiten/v2/audio"
 	"github.com/sinshu/go-meltysynth/meltysynth"
 	"github.com/zurustar/son-et/pkg/fileutil"
 	"github.com/zurustar/son-et/pkg/vm"
@@ -39,12 +38,18 @@ var ErrMIDIInvalidFormat = errors.New("invalid MIDI file format")
 //
 // Requirement 4.8: System uses software synthesizer to render MIDI audio.
 type MIDIStream struct {
-	sequencer   *meltysynth.MidiFileSequencer
+	sequencer   midiRenderer
 	sampleCount int64
 	stopped     bool
 	mu          sync.Mutex
 }
 
+// midiRenderer renders the audio of a MIDI sequence
+// (tempoSequencer for playback and offline rendering).
+type midiRenderer interface {
+	Render(left []float32, right []float32)
+}
+
 // Read implements io.Reader interface for MIDIStream.
 // It renders audio samples from the sequencer and converts them to int16 format.
 func (s *MIDIStream) Read(p []byte) (int, error) {
@@ -200,6 +205,27 @@ func (tc *TickCalculator) TickFromSamples(samples int64) int {
 	return tempo.Tick + ticksIntoSegment
 }
 
+// SamplesFromTick converts a MIDI tick (PPQ units) to the sample count at which it is played.
+// This is the inverse of TickFromSamples.
+func (tc *TickCalculator) SamplesFromTick(tick int) int64 {
+	if len(tc.tempoMap) == 0 || tc.ppq == 0 {
+		return 0
+	}
+
+	// Find which tempo segment the tick is in
+	segmentIdx := 0
+	for i := len(tc.tempoMap) - 1; i >= 0; i-- {
+		if tick >= tc.tempoMap[i].Tick {
+			segmentIdx = i
+			break
+		}
+	}
+
+	tempo := tc.tempoMap[segmentIdx]
+	samplesPerTick := float64(SampleRate) * float64(tempo.MicrosPerBeat) / float64(tc.ppq) / 1000000.0
+	return tc.sampleAtTempo[segmentIdx] + int64(float64(tick-tempo.Tick)*samplesPerTick)
+}
+
 // FillyTickFromSamples converts sample count to FILLY tick (16th note units).
 // In FILLY, 1 quarter note = 4 ticks (16th notes).
 // This is confirmed by typical FILLY usage: "mes(MIDI_TIME){step{  // MIDI演奏中、16分音符ごとに..."
@@ -233,12 +259,16 @@ type MIDIPlayer struct {
 	// go-meltysynth components
 	soundFont *meltysynth.SoundFont
 	synth     *meltysynth.Synthesizer
-	sequencer *meltysynth.MidiFileSequencer
+	sequencer *tempoSequencer
+	quality   SynthQuality // settings of synth (see synth_quality.go)
+	remap     *MIDIRemap   // program/bank/drum note remapping (see midi_remap.go), nil for none
+	strict    bool         // broken files fail to play instead of being repaired (see midi_repair.go)
+	metronome bool         // click on every quarter note while driving MIDI_TIME (see metronome.go)
 
-	// Ebitengine/audio components
-	audioCtx *audio.Context
-	player   *audio.Player
-	stream   *MIDIStream
+	// Audio output components (Ebitengine/audio, or NullOutput in tests)
+	output Output
+	player OutputPlayer
+	stream *MIDIStream
 
 	// Tempo management
 	tickCalc *TickCalculator
@@ -246,6 +276,16 @@ type MIDIPlayer struct {
 	// Event generation (will be used in task 5.5)
 	eventQueue *vm.EventQueue
 	lastTick   int
+	timeEvents bool // whether MIDI_TIME/MIDI_NOTE/MIDI_LYRIC events are pushed (false when another port is the clock)
+	endEvent   bool // whether MIDI_END is pushed when playback finishes
+
+	// NoteOn messages of the current file (MIDI_NOTE events)
+	notes    []NoteOnEvent
+	nextNote int // index of the next note to report
+
+	// Lyrics of the current file (MIDI_LYRIC events)
+	lyrics    []LyricEvent
+	nextLyric int // index of the next syllable to report
 
 	// File system interface for reading MIDI files
 	fs fileutil.FileSystem
@@ -255,6 +295,11 @@ type MIDIPlayer struct {
 	draining      bool      // true when MIDI sequence finished but waiting for audio buffer to drain
 	drainEndTime  time.Time // when to consider audio buffer drained
 	muted         bool
+	volume        float64       // output volume set by the mixer's music bus (0-1)
+	tempoScale    float64       // tempo scale (1 = as written in the file)
+	avOffset      time.Duration // delay of MIDI_TIME/MIDI_NOTE events behind the audio clock (see avoffset.go)
+	paused        bool          // true while playback is suspended by Pause
+	pausedAt      time.Time     // when Pause was called (used to extend the drain period)
 	duration      time.Duration
 	soundFontPath string
 	currentFile   string
@@ -266,43 +311,8 @@ type MIDIPlayer struct {
 	mu sync.RWMutex
 }
 
-// NewMIDIPlayer creates a new MIDI player with the specified SoundFont.
-// This is a convenience wrapper around NewMIDIPlayerWithFS with nil FileSystem.
-//
-// Requirement 4.9: When SoundFont file is provided, system uses it for MIDI synthesis.
-// Requirement 4.10: When SoundFont is not provided, system reports error.
-//
-// Parameters:
-//   - soundFontPath: Path to the SoundFont (.sf2) file
-//   - audioCtx: Ebitengine audio context (can be nil, will be created if needed)
-//   - eventQueue: Event queue for MIDI_TIME events (can be nil for basic playback)
-//
-// Returns:
-//   - *MIDIPlayer: The initialized MIDI player
-//   - error: Error if SoundFont cannot be loaded
-func NewMIDIPlayer(soundFontPath string, audioCtx *audio.Context, eventQueue *vm.EventQueue) (*MIDIPlayer, error) {
-	// Requirement 2.2: Backward compatibility - delegate to NewMIDIPlayerWithFS with nil fs
-	return NewMIDIPlayerWithFS(soundFontPath, audioCtx, eventQueue, nil)
-}
-
-// NewMIDIPlayerWithFS creates a new MIDI player with FileSystem support.
-// This allows loading SoundFont from embedded file system.
-//
-// Requirement 2.1: SoundFont_Loader SHALL FileSystemインターフェースを使用してSF2ファイルを読み込む
-// Requirement 2.3: FileSystemが設定されている場合は FileSystem.ReadFile を使用
-// Requirement 4.9: When SoundFont file is provided, system uses it for MIDI synthesis.
-// Requirement 4.10: When SoundFont is not provided, system reports error.
-//
-// Parameters:
-//   - soundFontPath: Path to the SoundFont (.sf2) file
-//   - audioCtx: Ebitengine audio context (can be nil, will be created if needed)
-//   - eventQueue: Event queue for MIDI_TIME events (can be nil for basic playback)
-//   - fs: FileSystem interface for loading SoundFont (can be nil for regular file system)
-//
-// Returns:
-//   - *MIDIPlayer: The initialized MIDI player
-//   - error: Error if SoundFont cannot be loaded
-func NewMIDIPlayerWithFS(soundFontPath string, audioCtx *audio.Context, eventQueue *vm.EventQueue, fs fileutil.FileSystem) (*MIDIPlayer, error) {
+// newMIDIPlayer creates a MIDI player playing on output.
+func newMIDIPlayer(soundFontPath string, output Output, eventQueue *vm.EventQueue, fs fileutil.FileSystem) (*MIDIPlayer, error) {
 	// Requirement 4.10: When SoundFont is not provided, system reports error.
 	if soundFontPath == "" {
 		return nil, ErrNoSoundFont
@@ -315,28 +325,28 @@ func NewMIDIPlayerWithFS(soundFontPath string, audioCtx *audio.Context, eventQue
 		return nil, err
 	}
 
-	// Create audio context if not provided
-	if audioCtx == nil {
-		audioCtx = audio.NewContext(SampleRate)
-	}
-
 	// Create synthesizer
 	// Requirement 4.8: System uses software synthesizer to render MIDI audio.
-	settings := meltysynth.NewSynthesizerSettings(SampleRate)
-	synth, err := meltysynth.NewSynthesizer(soundFont, settings)
+	quality := DefaultSynthQuality()
+	synth, err := newSynthesizer(soundFont, quality)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create synthesizer: %w", err)
+		return nil, err
 	}
 
 	return &MIDIPlayer{
 		soundFont:     soundFont,
 		synth:         synth,
-		audioCtx:      audioCtx,
+		quality:       quality,
+		output:        output,
 		eventQueue:    eventQueue,
 		soundFontPath: soundFontPath,
 		soundFontFS:   fs,
 		playing:       false,
 		muted:         false,
+		volume:        MaxBusGain,
+		tempoScale:    1,
+		timeEvents:    true,
+		endEvent:      true,
 	}, nil
 }
 
@@ -362,6 +372,10 @@ func (mp *MIDIPlayer) Play(filename string) error {
 	if err != nil {
 		return fmt.Errorf("%w: %s", ErrMIDIFileNotFound, filename)
 	}
+	midiData, err = checkMIDI(filename, midiData, mp.strict)
+	if err != nil {
+		return err
+	}
 
 	// Parse MIDI file
 	// Requirement 4.7: System supports Standard MIDI File (SMF) format.
@@ -374,10 +388,15 @@ func (mp *MIDIPlayer) Play(filename string) error {
 	// Requirement 4.2: When MIDI playback starts, system extracts tempo information from MIDI file.
 	tempoMap, ppq := ParseMIDITempoMap(midiData)
 	mp.tickCalc = NewTickCalculator(ppq, tempoMap)
+	mp.notes = ParseMIDINoteOns(midiData)
+	mp.nextNote = 0
+	mp.lyrics = ParseMIDILyrics(midiData)
+	mp.nextLyric = 0
 
-	// Create sequencer and start playback
-	mp.sequencer = meltysynth.NewMidiFileSequencer(mp.synth)
-	mp.sequencer.Play(midi, false) // false = don't loop
+	// Create sequencer and start playback (at the current tempo scale)
+	mp.sequencer = newTempoSequencer(mp.synth, mp.remap.apply(ParseMIDIMessages(midiData)), mp.tickCalc)
+	mp.sequencer.SetScale(mp.tempoScale)
+	mp.applyMetronome()
 
 	// Get duration
 	mp.duration = midi.GetLength()
@@ -389,16 +408,14 @@ func (mp *MIDIPlayer) Play(filename string) error {
 	mp.stream = &MIDIStream{sequencer: mp.sequencer}
 
 	// Create audio player
-	player, err := mp.audioCtx.NewPlayer(mp.stream)
+	player, err := mp.output.NewPlayer(mp.stream)
 	if err != nil {
 		return fmt.Errorf("failed to create audio player: %w", err)
 	}
 	mp.player = player
 
-	// Set volume based on muted state
-	if mp.muted {
-		mp.player.SetVolume(0)
-	}
+	// Set volume based on muted state and the mixer
+	mp.player.SetVolume(mp.outputVolume())
 
 	// Start playback
 	mp.player.Play()
@@ -433,8 +450,57 @@ func (mp *MIDIPlayer) stopInternal() {
 	mp.stream = nil
 	mp.playing = false
 	mp.draining = false
+	mp.paused = false
 	mp.currentFile = ""
 	mp.lastTick = 0
+	mp.notes = nil
+	mp.nextNote = 0
+	mp.lyrics = nil
+	mp.nextLyric = 0
+}
+
+// Pause suspends MIDI playback at the current position.
+// MIDI_TIME events are derived from the player position, which does not
+// advance while paused, so resuming continues without a tick jump.
+func (mp *MIDIPlayer) Pause() {
+	mp.mu.Lock()
+	defer mp.mu.Unlock()
+
+	if mp.paused || (!mp.playing && !mp.draining) {
+		return
+	}
+
+	mp.paused = true
+	mp.pausedAt = time.Now()
+	if mp.player != nil {
+		mp.player.Pause()
+	}
+}
+
+// Resume continues MIDI playback suspended by Pause.
+func (mp *MIDIPlayer) Resume() {
+	mp.mu.Lock()
+	defer mp.mu.Unlock()
+
+	if !mp.paused {
+		return
+	}
+
+	mp.paused = false
+	if mp.draining {
+		// Time spent paused does not count towards draining the audio buffer
+		mp.drainEndTime = mp.drainEndTime.Add(time.Since(mp.pausedAt))
+	}
+	if mp.player != nil {
+		mp.player.Play()
+	}
+}
+
+// IsPaused returns whether MIDI playback is suspended by Pause.
+func (mp *MIDIPlayer) IsPaused() bool {
+	mp.mu.RLock()
+	defer mp.mu.RUnlock()
+	return mp.paused
 }
 
 // IsPlaying returns whether MIDI is currently playing.
@@ -455,14 +521,31 @@ func (mp *MIDIPlayer) SetMuted(muted bool) {
 
 	mp.muted = muted
 	if mp.player != nil {
-		if muted {
-			mp.player.SetVolume(0)
-		} else {
-			mp.player.SetVolume(1)
-		}
+		mp.player.SetVolume(mp.outputVolume())
+	}
+}
+
+// SetVolume sets the output volume (0-1), normally the mixer's music bus volume.
+// The volume is kept while muted and applies again when unmuted.
+func (mp *MIDIPlayer) SetVolume(volume float64) {
+	mp.mu.Lock()
+	defer mp.mu.Unlock()
+
+	mp.volume = volume
+	if mp.player != nil {
+		mp.player.SetVolume(mp.outputVolume())
 	}
 }
 
+// outputVolume returns the volume to apply to the audio player.
+// Must be called with mp.mu held.
+func (mp *MIDIPlayer) outputVolume() float64 {
+	if mp.muted {
+		return 0
+	}
+	return mp.volume
+}
+
 // IsMuted returns whether the MIDI player is muted.
 func (mp *MIDIPlayer) IsMuted() bool {
 	mp.mu.RLock()
@@ -477,7 +560,8 @@ func (mp *MIDIPlayer) GetDuration() time.Duration {
 	return mp.duration
 }
 
-// GetPosition returns the current playback position.
+// GetPosition returns the current playback position in the song.
+// With a tempo scale other than 1 this differs from the elapsed playing time.
 func (mp *MIDIPlayer) GetPosition() time.Duration {
 	mp.mu.RLock()
 	defer mp.mu.RUnlock()
@@ -485,7 +569,115 @@ func (mp *MIDIPlayer) GetPosition() time.Duration {
 	if mp.player == nil {
 		return 0
 	}
-	return mp.player.Position()
+	return time.Duration(mp.songSamples()) * time.Second / SampleRate
+}
+
+// songSamples returns the song position (samples at normal tempo) of the audio currently heard.
+// Must be called with mp.mu held.
+func (mp *MIDIPlayer) songSamples() int64 {
+	if mp.player == nil {
+		return 0
+	}
+	return mp.songSamplesAt(int64(mp.player.Position().Seconds() * float64(SampleRate)))
+}
+
+// songSamplesAt converts an output position (samples played) to the song position.
+// Must be called with mp.mu held.
+func (mp *MIDIPlayer) songSamplesAt(output int64) int64 {
+	if mp.sequencer == nil {
+		return output
+	}
+	return mp.sequencer.SongPosition(output)
+}
+
+// SetTempoScale sets the tempo scale of MIDI playback (2 = twice as fast, 0.5 = half speed).
+// The pitch does not change, and MIDI_TIME/MIDI_NOTE events follow the scaled tempo.
+// The scale also applies to files played later. A scale of 0 or less is treated as 1.
+func (mp *MIDIPlayer) SetTempoScale(scale float64) {
+	if scale <= 0 {
+		scale = 1
+	}
+
+	mp.mu.Lock()
+	defer mp.mu.Unlock()
+
+	mp.tempoScale = scale
+	if mp.sequencer != nil {
+		mp.sequencer.SetScale(scale)
+	}
+}
+
+// TempoScale returns the tempo scale of MIDI playback.
+func (mp *MIDIPlayer) TempoScale() float64 {
+	mp.mu.RLock()
+	defer mp.mu.RUnlock()
+	return mp.tempoScale
+}
+
+// Seek jumps forward to a FILLY tick (16th note units) of the playing file.
+// The skipped MIDI_TIME events are still delivered (as a burst once the jump is
+// heard), so scripts that count ticks stay in step with the music; the skipped
+// MIDI_NOTE and MIDI_LYRIC events are dropped. Seeking backward is an error
+// because a script cannot be rewound.
+func (mp *MIDIPlayer) Seek(tick int) error {
+	return mp.seek(tick, true)
+}
+
+// Skip jumps forward to a FILLY tick like Seek, but the skipped MIDI_TIME events
+// are not delivered: the caller has already sent them (chapter fast-forward).
+func (mp *MIDIPlayer) Skip(tick int) error {
+	return mp.seek(tick, false)
+}
+
+// seek jumps forward to a FILLY tick; catchUp delivers the skipped MIDI_TIME events.
+func (mp *MIDIPlayer) seek(tick int, catchUp bool) error {
+	mp.mu.Lock()
+	defer mp.mu.Unlock()
+
+	if !mp.playing || mp.draining || mp.sequencer == nil || mp.tickCalc == nil {
+		return errors.New("no MIDI is playing")
+	}
+	if tick <= mp.lastTick {
+		return fmt.Errorf("cannot seek backward: tick %d is not after the current tick %d", tick, mp.lastTick)
+	}
+	midiTick := tick * mp.tickCalc.GetPPQ() / 4
+	song := mp.tickCalc.SamplesFromTick(midiTick)
+	if time.Duration(song)*time.Second/SampleRate >= mp.duration {
+		return fmt.Errorf("tick %d is after the end of %s", tick, mp.currentFile)
+	}
+
+	mp.sequencer.SeekTo(song)
+	for mp.nextNote < len(mp.notes) && mp.notes[mp.nextNote].Tick < midiTick {
+		mp.nextNote++
+	}
+	for mp.nextLyric < len(mp.lyrics) && mp.lyrics[mp.nextLyric].Tick < midiTick {
+		mp.nextLyric++
+	}
+	slog.Info("MIDI seek", "file", mp.currentFile, "from", mp.lastTick, "to", tick, "catchUp", catchUp)
+	if !catchUp {
+		mp.lastTick = tick
+	}
+	return nil
+}
+
+// TickAfter returns the FILLY tick the playing file reaches d after its last
+// delivered MIDI_TIME tick at normal speed, and whether the file has ended by then.
+// Used to fast-forward to a chapter while the player is paused.
+// Returns -1 if nothing is playing.
+func (mp *MIDIPlayer) TickAfter(d time.Duration) (int, bool) {
+	mp.mu.RLock()
+	defer mp.mu.RUnlock()
+
+	if !mp.playing || mp.tickCalc == nil {
+		return -1, false
+	}
+	base := mp.tickCalc.SamplesFromTick(mp.lastTick * mp.tickCalc.GetPPQ() / 4)
+	samples := base + int64(d)*SampleRate/int64(time.Second)
+	end := int64(mp.duration) * SampleRate / int64(time.Second)
+	if samples >= end {
+		return max(mp.tickCalc.FillyTickFromSamples(end), mp.lastTick), true
+	}
+	return max(mp.tickCalc.FillyTickFromSamples(samples), mp.lastTick), false
 }
 
 // GetCurrentTick returns the current MIDI tick position.
@@ -497,12 +689,11 @@ func (mp *MIDIPlayer) GetCurrentTick() int {
 		return 0
 	}
 
-	position := mp.player.Position()
-	samples := int64(position.Seconds() * float64(SampleRate))
-	return mp.tickCalc.TickFromSamples(samples)
+	return mp.tickCalc.TickFromSamples(mp.songSamples())
 }
 
 // GetCurrentFillyTick returns the current FILLY tick position (16th note units).
+// Like MIDI_TIME events, the tick is delayed by the A/V offset.
 func (mp *MIDIPlayer) GetCurrentFillyTick() int {
 	mp.mu.RLock()
 	defer mp.mu.RUnlock()
@@ -511,9 +702,7 @@ func (mp *MIDIPlayer) GetCurrentFillyTick() int {
 		return 0
 	}
 
-	position := mp.player.Position()
-	samples := int64(position.Seconds() * float64(SampleRate))
-	return mp.tickCalc.FillyTickFromSamples(samples)
+	return mp.tickCalc.FillyTickFromSamples(mp.visualSamples())
 }
 
 // GetTickCalculator returns the tick calculator for the current MIDI file.
@@ -537,7 +726,7 @@ func (mp *MIDIPlayer) GetCurrentFile() string {
 // Requirement 4.5: When MIDI playback completes, system generates MIDI_END event.
 //
 // The method:
-// 1. Gets the current audio playback position using player.Position()
+// 1. Gets the current song position from player.Position() (adjusted for the tempo scale)
 // 2. Converts the position to FILLY ticks using TickCalculator
 // 3. If the tick has advanced since the last update, generates MIDI_TIME events
 // 4. Pushes the events to the event queue
@@ -546,12 +735,17 @@ func (mp *MIDIPlayer) Update() {
 	mp.mu.Lock()
 	defer mp.mu.Unlock()
 
+	// No events are generated while paused
+	if mp.paused {
+		return
+	}
+
 	// Check if we're in draining state (waiting for audio buffer to flush)
 	if mp.draining {
 		// Wait for audio buffer to drain (fixed time based on typical buffer size)
 		if time.Now().After(mp.drainEndTime) {
 			slog.Info("MIDI audio buffer drained, generating MIDI_END event")
-			if mp.eventQueue != nil {
+			if mp.eventQueue != nil && mp.endEvent {
 				event := vm.NewEvent(vm.EventMIDI_END)
 				mp.eventQueue.Push(event)
 			}
@@ -565,38 +759,38 @@ func (mp *MIDIPlayer) Update() {
 		return
 	}
 
-	// Get current playback position
-	position := mp.player.Position()
+	// Get current song position of the visuals (the audio position shifted by the A/V offset)
+	samples := mp.visualSamples()
 
-	// Check if playback has finished
+	// Check if playback has finished: both the audio and the visuals must have reached the end,
+	// so that a negative offset does not cut off the audio and a positive one the last ticks
 	// Requirement 4.5: When MIDI playback completes, system generates MIDI_END event.
+	position := time.Duration(min(samples, mp.songSamples())) * time.Second / SampleRate
 	if position >= mp.duration {
 		slog.Info("MIDI playback finished, starting drain period", "position", position, "duration", mp.duration)
 
 		// Start drain period to allow audio buffer to flush
 		// Stop the stream so it returns silence
-		// Wait for a fixed time to allow Ebitengine's audio buffer to drain
-		// Typical audio buffer is 200-500ms, so 1 second should be safe
+		// Wait for the output's drain time to allow its audio buffer to drain
 		if mp.stream != nil {
 			mp.stream.Stop()
 		}
 		mp.draining = true
-		mp.drainEndTime = time.Now().Add(1 * time.Second)
+		mp.drainEndTime = time.Now().Add(mp.output.DrainTime())
 		return
 	}
 
 	// Generate MIDI_TIME events if tick has advanced
 	// Requirement 4.3: When MIDI is playing, system generates MIDI_TIME events synchronized to MIDI tempo.
 	if mp.tickCalc != nil && mp.eventQueue != nil {
-		// Convert position to samples
-		samples := int64(position.Seconds() * float64(SampleRate))
-
 		// Get current FILLY tick (16th note units)
 		currentTick := mp.tickCalc.FillyTickFromSamples(samples)
 
 		// Generate MIDI_TIME events for each tick that has passed
 		// Requirement 4.4: System generates MIDI_TIME events at the correct interval
-		for tick := mp.lastTick + 1; tick <= currentTick; tick++ {
+		// The ticks still advance while another port is the clock, so that
+		// becoming the clock does not produce a burst of past ticks.
+		for tick := mp.lastTick + 1; mp.timeEvents && tick <= currentTick; tick++ {
 			event := vm.NewEventWithParams(vm.EventMIDI_TIME, map[string]any{
 				"Tick": tick,
 			})
@@ -605,6 +799,11 @@ func (mp *MIDIPlayer) Update() {
 
 		// Update last tick
 		mp.lastTick = currentTick
+
+		// Generate MIDI_NOTE events for NoteOn messages that have been played
+		mp.pushNoteEvents(mp.tickCalc.TickFromSamples(samples))
+		// Generate MIDI_LYRIC events for lyric syllables that have been sung
+		mp.pushLyricEvents(mp.tickCalc.TickFromSamples(samples))
 	}
 }
 
@@ -0,0 +1,208 @@
+package audio
+
+import (
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/zurustar/son-et/pkg/vm"
+	"golang.org/x/text/encoding/japanese"
+)
+
+// MIDI meta event types
+const (
+	metaText  = 0x01 // text (lyrics in KAR files)
+	metaLyric = 0x05 // lyric
+)
+
+// Text events of KAR files.
+// A KAR file starts with "@KMIDI KARAOKE FILE"; texts starting with @ carry information
+// such as the song title and are not lyrics.
+const (
+	karaokeSignature  = "@KMIDI"
+	karaokeInfoPrefix = "@"
+)
+
+// LyricEvent is a lyric syllable in a MIDI file, with the line it belongs to.
+type LyricEvent struct {
+	Tick   int    // MIDI tick (PPQ units) from the start of the file
+	Text   string // the syllable (line break markers removed)
+	Line   string // the whole line the syllable belongs to
+	Sung   int    // characters (runes) of Line up to and including this syllable
+	LineNo int    // line number from 0
+}
+
+// metaTextEvent is a text meta event.
+type metaTextEvent struct {
+	tick int
+	kind byte
+	data []byte
+}
+
+// ParseMIDILyrics extracts the lyrics of MIDI data, ordered by tick.
+// Lyric meta events (FF 05) are used when the file has any; otherwise the text
+// events (FF 01) of a karaoke (.kar) file, which starts with "@KMIDI KARAOKE FILE".
+// A syllable starting with "/" or "\" (KAR) or following one that ends with CR/LF
+// (RP-017) starts a new line. Shift_JIS text is converted to UTF-8.
+func ParseMIDILyrics(data []byte) []LyricEvent {
+	var lyrics, texts []metaTextEvent
+	karaoke := false
+	for _, e := range parseMIDIMetaTexts(data) {
+		switch e.kind {
+		case metaLyric:
+			lyrics = append(lyrics, e)
+		case metaText:
+			texts = append(texts, e)
+			if strings.HasPrefix(string(e.data), karaokeSignature) {
+				karaoke = true
+			}
+		}
+	}
+	if len(lyrics) == 0 && karaoke {
+		return buildLyricLines(texts, true)
+	}
+	return buildLyricLines(lyrics, false)
+}
+
+// buildLyricLines groups syllables into lines (when kar is true, information texts starting with @ are skipped).
+func buildLyricLines(events []metaTextEvent, kar bool) []LyricEvent {
+	var result []LyricEvent
+	lineNo := -1
+	newLine := true
+	for _, e := range events {
+		text := decodeLyricText(e.data)
+		if kar && strings.HasPrefix(text, karaokeInfoPrefix) {
+			continue
+		}
+		if strings.HasPrefix(text, "/") || strings.HasPrefix(text, `\`) {
+			newLine = true
+			text = text[1:]
+		}
+		trimmed := strings.TrimRight(text, "\r\n")
+		endsLine := len(trimmed) != len(text)
+		if trimmed != "" {
+			if newLine {
+				lineNo++
+			}
+			result = append(result, LyricEvent{Tick: e.tick, Text: trimmed, LineNo: lineNo})
+			newLine = false
+		}
+		if endsLine {
+			newLine = true
+		}
+	}
+
+	// Set the line text and the number of characters sung up to each syllable
+	for start := 0; start < len(result); {
+		end := start
+		var line strings.Builder
+		for end < len(result) && result[end].LineNo == result[start].LineNo {
+			line.WriteString(result[end].Text)
+			end++
+		}
+		sung := 0
+		for i := start; i < end; i++ {
+			sung += utf8.RuneCountInString(result[i].Text)
+			result[i].Line = line.String()
+			result[i].Sung = sung
+		}
+		start = end
+	}
+	return result
+}
+
+// decodeLyricText converts the text of a meta event to UTF-8 (text that is not UTF-8 is read as Shift_JIS).
+func decodeLyricText(data []byte) string {
+	if utf8.Valid(data) {
+		return string(data)
+	}
+	decoded, err := japanese.ShiftJIS.NewDecoder().Bytes(data)
+	if err != nil {
+		return strings.ToValidUTF8(string(data), "?")
+	}
+	return string(decoded)
+}
+
+// parseMIDIMetaTexts extracts the text meta events (FF 01 to FF 0F) in time order.
+// Events at the same time keep the track order. Truncated data is read as far as possible without panicking.
+func parseMIDIMetaTexts(data []byte) []metaTextEvent {
+	if len(data) < 14 || string(data[0:4]) != "MThd" {
+		return nil
+	}
+
+	var events []metaTextEvent
+	offset := 14
+	for offset < len(data) {
+		if offset+8 > len(data) || string(data[offset:offset+4]) != "MTrk" {
+			break
+		}
+
+		trackLen := int(data[offset+4])<<24 | int(data[offset+5])<<16 | int(data[offset+6])<<8 | int(data[offset+7])
+		trackEnd := min(offset+8+trackLen, len(data))
+		pos := offset + 8
+		currentTick := 0
+		lastStatus := byte(0)
+
+		for pos < trackEnd {
+			delta, n := readVarLen(data[pos:trackEnd])
+			pos += n
+			currentTick += delta
+			if pos >= trackEnd {
+				break
+			}
+
+			eventByte := data[pos]
+			if eventByte < 0x80 {
+				// Running status
+				eventByte = lastStatus
+			} else {
+				pos++
+				if eventByte < 0xF0 {
+					lastStatus = eventByte
+				}
+			}
+
+			switch {
+			case eventByte == 0xFF: // Meta event
+				if pos >= trackEnd {
+					continue
+				}
+				kind := data[pos]
+				pos++
+				length, n := readVarLen(data[pos:trackEnd])
+				pos += n
+				if kind >= metaText && kind <= 0x0F && pos+length <= trackEnd {
+					events = append(events, metaTextEvent{tick: currentTick, kind: kind, data: data[pos : pos+length]})
+				}
+				pos += length
+			case eventByte == 0xF0 || eventByte == 0xF7: // SysEx
+				length, n := readVarLen(data[pos:trackEnd])
+				pos += n + length
+			case eventByte >= 0xC0 && eventByte < 0xE0: // Program change, channel pressure
+				pos++
+			case eventByte >= 0x80:
+				pos += 2
+			default:
+				// Data byte without a running status: skip it
+				pos++
+			}
+		}
+		offset = trackEnd
+	}
+
+	sort.SliceStable(events, func(i, j int) bool { return events[i].tick < events[j].tick })
+	return events
+}
+
+// pushLyricEvents pushes a MIDI_LYRIC event for every lyric syllable up to currentTick (MIDI ticks).
+// Like MIDI_NOTE, the syllables are skipped without events while another port is the clock.
+// Must be called with mp.mu held.
+func (mp *MIDIPlayer) pushLyricEvents(currentTick int) {
+	for mp.nextLyric < len(mp.lyrics) && mp.lyrics[mp.nextLyric].Tick <= currentTick {
+		l := mp.lyrics[mp.nextLyric]
+		if mp.timeEvents {
+			mp.eventQueue.Push(vm.NewMIDILyricEvent(l.Text, l.Tick, l.Sung, l.Line))
+		}
+		mp.nextLyric++
+	}
+}
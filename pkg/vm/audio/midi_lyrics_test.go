@@ -0,0 +1,109 @@
+package audio
+
+import (
+	"testing"
+)
+
+// metaEvent builds a meta event (delta and text shorter than 128).
+func metaEvent(delta byte, kind byte, text []byte) []byte {
+	return append([]byte{delta, 0xFF, kind, byte(len(text))}, text...)
+}
+
+// TestParseMIDILyrics tests lyric meta events split into lines by CR.
+func TestParseMIDILyrics(t *testing.T) {
+	var events []byte
+	events = append(events, metaEvent(0, metaText, []byte("title"))...)
+	events = append(events, metaEvent(0, metaLyric, []byte("さ"))...)
+	events = append(events, 0x60, 0x90, 60, 64) // NoteOn after 96 ticks
+	events = append(events, metaEvent(0, metaLyric, []byte("く"))...)
+	events = append(events, metaEvent(0x60, metaLyric, []byte("ら\r"))...)
+	events = append(events, metaEvent(0x60, metaLyric, []byte("や"))...)
+	events = append(events, metaEvent(0x60, metaLyric, []byte("よ"))...)
+	events = append(events, 0x00, 0xFF, 0x2F, 0x00)
+	data := append(buildMIDIHeader(480), buildMIDITrack(events)...)
+
+	got := ParseMIDILyrics(data)
+	want := []LyricEvent{
+		{Tick: 0, Text: "さ", Line: "さくら", Sung: 1, LineNo: 0},
+		{Tick: 96, Text: "く", Line: "さくら", Sung: 2, LineNo: 0},
+		{Tick: 192, Text: "ら", Line: "さくら", Sung: 3, LineNo: 0},
+		{Tick: 288, Text: "や", Line: "やよ", Sung: 1, LineNo: 1},
+		{Tick: 384, Text: "よ", Line: "やよ", Sung: 2, LineNo: 1},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("got %d lyrics, want %d: %+v", len(got), len(want), got)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("lyric %d = %+v, want %+v", i, got[i], want[i])
+		}
+	}
+}
+
+// TestParseMIDILyricsKAR tests the text events of a karaoke (.kar) file:
+// "@" texts are information and "/" starts a new line.
+func TestParseMIDILyricsKAR(t *testing.T) {
+	var events []byte
+	events = append(events, metaEvent(0, metaText, []byte("@KMIDI KARAOKE FILE"))...)
+	events = append(events, metaEvent(0, metaText, []byte("@TSong"))...)
+	events = append(events, metaEvent(0x10, metaText, []byte("Hel"))...)
+	events = append(events, metaEvent(0x10, metaText, []byte("lo "))...)
+	events = append(events, metaEvent(0x10, metaText, []byte("/World"))...)
+	events = append(events, metaEvent(0x10, metaText, []byte(`\Bye`))...)
+	events = append(events, 0x00, 0xFF, 0x2F, 0x00)
+	data := append(buildMIDIHeader(480), buildMIDITrack(events)...)
+
+	got := ParseMIDILyrics(data)
+	want := []LyricEvent{
+		{Tick: 16, Text: "Hel", Line: "Hello ", Sung: 3, LineNo: 0},
+		{Tick: 32, Text: "lo ", Line: "Hello ", Sung: 6, LineNo: 0},
+		{Tick: 48, Text: "World", Line: "World", Sung: 5, LineNo: 1},
+		{Tick: 64, Text: "Bye", Line: "Bye", Sung: 3, LineNo: 2},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("got %d lyrics, want %d: %+v", len(got), len(want), got)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("lyric %d = %+v, want %+v", i, got[i], want[i])
+		}
+	}
+}
+
+// TestParseMIDILyricsTextWithoutKaraoke tests that plain text events of a non-karaoke file are not lyrics.
+func TestParseMIDILyricsTextWithoutKaraoke(t *testing.T) {
+	events := metaEvent(0, metaText, []byte("Copyright notes"))
+	events = append(events, 0x00, 0xFF, 0x2F, 0x00)
+	data := append(buildMIDIHeader(480), buildMIDITrack(events)...)
+
+	if got := ParseMIDILyrics(data); len(got) != 0 {
+		t.Errorf("expected no lyrics, got %+v", got)
+	}
+}
+
+// TestParseMIDILyricsShiftJIS tests that Shift_JIS lyrics are converted to UTF-8.
+func TestParseMIDILyricsShiftJIS(t *testing.T) {
+	events := metaEvent(0, metaLyric, []byte{0x82, 0xA0, 0x82, 0xA2}) // "あい"
+	events = append(events, 0x00, 0xFF, 0x2F, 0x00)
+	data := append(buildMIDIHeader(480), buildMIDITrack(events)...)
+
+	got := ParseMIDILyrics(data)
+	if len(got) != 1 || got[0].Text != "あい" || got[0].Sung != 2 {
+		t.Errorf("got %+v, want one syllable \"あい\"", got)
+	}
+}
+
+// TestParseMIDILyricsTruncated tests that truncated data does not panic.
+func TestParseMIDILyricsTruncated(t *testing.T) {
+	data := buildMIDIHeader(480)
+	data = append(data, []byte("MTrk")...)
+	data = append(data, 0, 0, 0, 127)
+	data = append(data, 0x00, 0xFF, metaLyric, 0x10, 'a')
+
+	if got := ParseMIDILyrics(data); len(got) != 0 {
+		t.Errorf("expected no lyrics from a truncated event, got %+v", got)
+	}
+	if got := ParseMIDILyrics([]byte("not midi")); got != nil {
+		t.Errorf("expected nil for invalid data, got %+v", got)
+	}
+}
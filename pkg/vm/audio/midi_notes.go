@@ -0,0 +1,44 @@
+package audio
+
+import (
+	"github.com/zurustar/son-et/pkg/vm"
+)
+
+// NoteOnEvent is a NoteOn message in a MIDI file.
+type NoteOnEvent struct {
+	Tick     int // MIDI tick (PPQ units) from the start of the file
+	Channel  int // MIDI channel (0-15)
+	Note     int // note number (0-127)
+	Velocity int // velocity (1-127; NoteOn with velocity 0 is a NoteOff and is not included)
+}
+
+// ParseMIDINoteOns extracts all NoteOn messages from MIDI data, ordered by tick.
+// Messages on the same tick keep their track order.
+// Truncated or corrupt tracks are read up to the end of the data without panicking.
+func ParseMIDINoteOns(data []byte) []NoteOnEvent {
+	var notes []NoteOnEvent
+	for _, msg := range ParseMIDIMessages(data) {
+		if msg.Command == 0x90 && msg.Data2 > 0 {
+			notes = append(notes, NoteOnEvent{
+				Tick:     msg.Tick,
+				Channel:  msg.Channel,
+				Note:     msg.Data1,
+				Velocity: msg.Data2,
+			})
+		}
+	}
+	return notes
+}
+
+// pushNoteEvents pushes a MIDI_NOTE event for every NoteOn up to currentTick (MIDI ticks).
+// The notes are skipped without events while another port is the clock.
+// Must be called with mp.mu held.
+func (mp *MIDIPlayer) pushNoteEvents(currentTick int) {
+	for mp.nextNote < len(mp.notes) && mp.notes[mp.nextNote].Tick <= currentTick {
+		n := mp.notes[mp.nextNote]
+		if mp.timeEvents {
+			mp.eventQueue.Push(vm.NewMIDINoteEvent(n.Channel, n.Note, n.Velocity))
+		}
+		mp.nextNote++
+	}
+}
@@ -0,0 +1,57 @@
+package audio
+
+import (
+	"encoding/binary"
+	"testing"
+)
+
+// buildMIDITrack wraps track events in an MTrk chunk.
+func buildMIDITrack(events []byte) []byte {
+	track := append([]byte("MTrk"), 0, 0, 0, 0)
+	binary.BigEndian.PutUint32(track[4:], uint32(len(events)))
+	return append(track, events...)
+}
+
+// TestParseMIDINoteOns tests NoteOn extraction, including running status and NoteOn with velocity 0.
+func TestParseMIDINoteOns(t *testing.T) {
+	data := buildMIDIHeader(480)
+	data = append(data, buildMIDITrack([]byte{
+		0x00, 0xFF, 0x51, 0x03, 0x07, 0xA1, 0x20, // tempo
+		0x00, 0x99, 36, 100, // ch10 NoteOn
+		0x60, 38, 90, // running status: ch10 NoteOn after 96 ticks
+		0x10, 38, 0, // running status: NoteOn velocity 0 (NoteOff)
+		0x00, 0xC0, 0x05, // program change
+		0x20, 0x90, 60, 64, // ch1 NoteOn
+		0x00, 0xFF, 0x2F, 0x00, // end of track
+	})...)
+
+	got := ParseMIDINoteOns(data)
+	want := []NoteOnEvent{
+		{Tick: 0, Channel: 9, Note: 36, Velocity: 100},
+		{Tick: 96, Channel: 9, Note: 38, Velocity: 90},
+		{Tick: 144, Channel: 0, Note: 60, Velocity: 64},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("got %d notes, want %d: %+v", len(got), len(want), got)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("note %d = %+v, want %+v", i, got[i], want[i])
+		}
+	}
+}
+
+// TestParseMIDINoteOnsTruncated tests that truncated data does not panic.
+func TestParseMIDINoteOnsTruncated(t *testing.T) {
+	data := buildMIDIHeader(480)
+	data = append(data, []byte("MTrk")...)
+	data = append(data, 0, 0, 0, 127)
+	data = append(data, 0x00, 0x90, 60)
+
+	if notes := ParseMIDINoteOns(data); len(notes) != 0 {
+		t.Errorf("expected no notes from a truncated event, got %+v", notes)
+	}
+	if notes := ParseMIDINoteOns([]byte("not midi")); notes != nil {
+		t.Errorf("expected nil for invalid data, got %+v", notes)
+	}
+}
@@ -0,0 +1,209 @@
+package audio
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// MIDI ports allow several MIDI files to play at once (e.g. background music plus a
+// short jingle). Each port has its own synthesizer and sequencer, and therefore its
+// own tick stream ("clock"). PlayMIDI plays on MainMIDIPort.
+//
+// Only one port drives MIDI_TIME and MIDI_NOTE events: the port selected by
+// SetMIDIClock while it is playing, otherwise MainMIDIPort. MIDI_END is pushed when
+// the main port finishes, and when the selected clock port finishes.
+
+// MainMIDIPort is the name of the MIDI port used by PlayMIDI.
+const MainMIDIPort = "main"
+
+// MaxMIDIPorts is the maximum number of MIDI ports including MainMIDIPort.
+// Each port renders its own synthesizer, so the number is kept small.
+const MaxMIDIPorts = 4
+
+// ErrTooManyMIDIPorts is returned when PlayMIDIPort would exceed MaxMIDIPorts.
+var ErrTooManyMIDIPorts = errors.New("too many MIDI ports")
+
+// normalizeMIDIPort returns the port name in canonical form (case-insensitive, "" is MainMIDIPort).
+func normalizeMIDIPort(port string) string {
+	port = strings.ToLower(strings.TrimSpace(port))
+	if port == "" {
+		return MainMIDIPort
+	}
+	return port
+}
+
+// newPortPlayer creates a MIDI player for another port.
+// The parsed SoundFont, the output settings (mute, volume, tempo scale, A/V offset,
+// synthesizer quality, remapping, metronome) and the file system are shared with mp; the player starts
+// without pushing events.
+func (mp *MIDIPlayer) newPortPlayer() (*MIDIPlayer, error) {
+	mp.mu.RLock()
+	defer mp.mu.RUnlock()
+
+	synth, err := newSynthesizer(mp.soundFont, mp.quality)
+	if err != nil {
+		return nil, err
+	}
+	return &MIDIPlayer{
+		soundFont:     mp.soundFont,
+		synth:         synth,
+		quality:       mp.quality,
+		remap:         mp.remap,
+		output:        mp.output,
+		eventQueue:    mp.eventQueue,
+		fs:            mp.fs,
+		soundFontPath: mp.soundFontPath,
+		soundFontFS:   mp.soundFontFS,
+		muted:         mp.muted,
+		volume:        mp.volume,
+		tempoScale:    mp.tempoScale,
+		avOffset:      mp.avOffset,
+		metronome:     mp.metronome,
+	}, nil
+}
+
+// setEventOutput selects which events the player pushes to the event queue.
+func (mp *MIDIPlayer) setEventOutput(timeEvents, endEvent bool) {
+	mp.mu.Lock()
+	defer mp.mu.Unlock()
+	mp.timeEvents = timeEvents
+	mp.endEvent = endEvent
+	mp.applyMetronome()
+}
+
+// PlayMIDIPort starts playback of a MIDI file on the named port, stopping the file
+// previously playing on that port. Other ports keep playing.
+// The port is created on first use; MainMIDIPort (or "") is the same as PlayMIDI.
+func (as *AudioSystem) PlayMIDIPort(port, filename string) error {
+	port = normalizeMIDIPort(port)
+	if port == MainMIDIPort {
+		return as.PlayMIDI(filename)
+	}
+
+	as.mu.Lock()
+	defer as.mu.Unlock()
+
+	if as.midiPlayer == nil {
+		return ErrNoSoundFont
+	}
+	mp, ok := as.ports[port]
+	if !ok {
+		if len(as.ports)+1 >= MaxMIDIPorts {
+			return fmt.Errorf("%w: %q (max %d)", ErrTooManyMIDIPorts, port, MaxMIDIPorts)
+		}
+		var err error
+		if mp, err = as.midiPlayer.newPortPlayer(); err != nil {
+			return err
+		}
+		if as.ports == nil {
+			as.ports = make(map[string]*MIDIPlayer)
+		}
+		as.ports[port] = mp
+	}
+
+	playPath := filename
+	if as.fs != nil {
+		playPath = extractFilename(filename)
+	}
+	if err := mp.Play(playPath); err != nil {
+		return err
+	}
+	if as.paused {
+		mp.Pause()
+	}
+	as.arbitrateClock()
+	as.bus.Publish(TopicMIDIPlay, map[string]any{"file": filename, "port": port})
+	return nil
+}
+
+// StopMIDIPort stops playback on the named port (MainMIDIPort is the same as StopMIDI).
+// Stopping a port that does not exist does nothing.
+func (as *AudioSystem) StopMIDIPort(port string) {
+	port = normalizeMIDIPort(port)
+	if port == MainMIDIPort {
+		as.StopMIDI()
+		return
+	}
+
+	as.mu.Lock()
+	defer as.mu.Unlock()
+
+	if mp, ok := as.ports[port]; ok {
+		mp.Stop()
+		as.arbitrateClock()
+		as.bus.Publish(TopicMIDIStop, map[string]any{"port": port})
+	}
+}
+
+// MIDIPortTick returns the current FILLY tick (16th note units) of the named port,
+// or -1 if nothing is playing on it.
+func (as *AudioSystem) MIDIPortTick(port string) int {
+	as.mu.RLock()
+	defer as.mu.RUnlock()
+
+	mp := as.portPlayer(normalizeMIDIPort(port))
+	if mp == nil || !mp.IsPlaying() {
+		return -1
+	}
+	return mp.GetCurrentFillyTick()
+}
+
+// SetMIDIClock selects the port whose ticks drive MIDI_TIME and MIDI_NOTE events.
+// The port does not have to exist yet: while nothing is playing on it, MainMIDIPort
+// drives the events. Ticks that passed on a port while it was not the clock are not
+// replayed when it becomes the clock.
+func (as *AudioSystem) SetMIDIClock(port string) {
+	as.mu.Lock()
+	defer as.mu.Unlock()
+
+	as.clockPort = normalizeMIDIPort(port)
+	as.arbitrateClock()
+}
+
+// MIDIClock returns the port currently driving MIDI_TIME events.
+func (as *AudioSystem) MIDIClock() string {
+	as.mu.RLock()
+	defer as.mu.RUnlock()
+	return as.activeClockPort()
+}
+
+// portPlayer returns the player of a normalized port name, or nil.
+// Must be called with as.mu held.
+func (as *AudioSystem) portPlayer(port string) *MIDIPlayer {
+	if port == MainMIDIPort {
+		return as.midiPlayer
+	}
+	return as.ports[port]
+}
+
+// activeClockPort returns the port driving MIDI_TIME events: the port selected by
+// SetMIDIClock while it is playing, otherwise MainMIDIPort.
+// Must be called with as.mu held.
+func (as *AudioSystem) activeClockPort() string {
+	if mp, ok := as.ports[as.clockPort]; ok && mp.IsPlaying() {
+		return as.clockPort
+	}
+	return MainMIDIPort
+}
+
+// arbitrateClock lets only the active clock port push MIDI_TIME/MIDI_NOTE events.
+// The main port always pushes MIDI_END; other ports only while they are the clock.
+// Must be called with as.mu held.
+func (as *AudioSystem) arbitrateClock() {
+	clock := as.activeClockPort()
+	if as.midiPlayer != nil {
+		as.midiPlayer.setEventOutput(clock == MainMIDIPort, true)
+	}
+	for name, mp := range as.ports {
+		mp.setEventOutput(name == clock, name == clock)
+	}
+}
+
+// eachPort calls fn for the player of every port other than MainMIDIPort.
+// Must be called with as.mu held.
+func (as *AudioSystem) eachPort(fn func(mp *MIDIPlayer)) {
+	for _, mp := range as.ports {
+		fn(mp)
+	}
+}
@@ -0,0 +1,106 @@
+//go:build !nogpu
+
+package audio
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/zurustar/son-et/pkg/vm"
+)
+
+func TestNormalizeMIDIPort(t *testing.T) {
+	tests := []struct {
+		port string
+		want string
+	}{
+		{"", MainMIDIPort},
+		{"  ", MainMIDIPort},
+		{"MAIN", MainMIDIPort},
+		{"Jingle", "jingle"},
+		{" se ", "se"},
+	}
+	for _, tt := range tests {
+		if got := normalizeMIDIPort(tt.port); got != tt.want {
+			t.Errorf("normalizeMIDIPort(%q) = %q, want %q", tt.port, got, tt.want)
+		}
+	}
+}
+
+func TestAudioSystemMIDIPorts(t *testing.T) {
+	soundFontPath := findSoundFont(t)
+	midiPath := findMIDIFile(t)
+
+	as, err := NewAudioSystemWithContext(soundFontPath, vm.NewEventQueue(), getSharedAudioContext())
+	if err != nil {
+		t.Fatalf("NewAudioSystemWithContext failed: %v", err)
+	}
+	defer as.Shutdown()
+	as.SetMuted(true)
+
+	if err := as.PlayMIDI(midiPath); err != nil {
+		t.Fatalf("PlayMIDI failed: %v", err)
+	}
+	if err := as.PlayMIDIPort("Jingle", midiPath); err != nil {
+		t.Fatalf("PlayMIDIPort failed: %v", err)
+	}
+	jingle := as.ports["jingle"]
+	if jingle == nil || !jingle.IsPlaying() || !as.IsMIDIPlaying() {
+		t.Fatal("expected both ports to be playing")
+	}
+	if !jingle.IsMuted() {
+		t.Error("expected a new port to inherit the muted state")
+	}
+
+	t.Run("ticks", func(t *testing.T) {
+		if tick := as.MIDIPortTick("jingle"); tick < 0 {
+			t.Errorf("MIDIPortTick(jingle) = %d, want >= 0", tick)
+		}
+		if tick := as.MIDIPortTick(""); tick < 0 {
+			t.Errorf("MIDIPortTick(main) = %d, want >= 0", tick)
+		}
+		if tick := as.MIDIPortTick("unknown"); tick != -1 {
+			t.Errorf("MIDIPortTick(unknown) = %d, want -1", tick)
+		}
+	})
+
+	t.Run("clock arbitration", func(t *testing.T) {
+		as.Update()
+		if as.MIDIClock() != MainMIDIPort || jingle.timeEvents || jingle.endEvent {
+			t.Error("expected the main port to drive MIDI_TIME by default")
+		}
+
+		as.SetMIDIClock("JINGLE")
+		if as.MIDIClock() != "jingle" || !jingle.timeEvents || as.midiPlayer.timeEvents {
+			t.Error("expected the jingle port to drive MIDI_TIME")
+		}
+		if !as.midiPlayer.endEvent {
+			t.Error("expected the main port to keep pushing MIDI_END")
+		}
+
+		// When the selected port stops, the clock falls back to main
+		as.StopMIDIPort("jingle")
+		if as.MIDIClock() != MainMIDIPort || !as.midiPlayer.timeEvents {
+			t.Error("expected the clock to fall back to the main port")
+		}
+		if !as.IsMIDIPlaying() {
+			t.Error("stopping a port should not stop the main port")
+		}
+	})
+
+	t.Run("port limit", func(t *testing.T) {
+		for i := len(as.ports) + 1; i < MaxMIDIPorts; i++ {
+			if err := as.PlayMIDIPort(fmt.Sprintf("port%d", i), midiPath); err != nil {
+				t.Fatalf("PlayMIDIPort(port%d) failed: %v", i, err)
+			}
+		}
+		if err := as.PlayMIDIPort("one-too-many", midiPath); !errors.Is(err, ErrTooManyMIDIPorts) {
+			t.Errorf("expected ErrTooManyMIDIPorts, got %v", err)
+		}
+		// An existing port can be reused
+		if err := as.PlayMIDIPort("jingle", midiPath); err != nil {
+			t.Errorf("replaying an existing port failed: %v", err)
+		}
+	})
+}
@@ -0,0 +1,85 @@
+package audio
+
+// MIDI program/bank/drum note remapping.
+//
+// Many old MIDI files were written for a specific hardware synthesizer and pick
+// instruments by that synthesizer's numbering. A remapping table from the project
+// manifest rewrites the channel messages before the sequencer sends them to the
+// SoundFont synthesizer; MIDI_NOTE events still report the notes of the file.
+
+// drumChannel is the MIDI channel of the GM percussion part (channel 10).
+const drumChannel = 9
+
+// MIDI commands and controllers rewritten by a remapping table.
+const (
+	midiNoteOff       = 0x80
+	midiNoteOn        = 0x90
+	midiKeyPressure   = 0xA0
+	midiControlChange = 0xB0
+	midiProgramChange = 0xC0
+	midiBankSelectMSB = 0
+)
+
+// MIDIRemap maps the values of a MIDI file to the values sent to the synthesizer.
+// Values without an entry are sent unchanged.
+type MIDIRemap struct {
+	Programs  map[int]int // program change numbers (0-127)
+	Banks     map[int]int // bank select (CC#0) values
+	DrumNotes map[int]int // note numbers on the drum channel
+}
+
+// empty reports whether the table changes nothing.
+func (r *MIDIRemap) empty() bool {
+	return r == nil || len(r.Programs)+len(r.Banks)+len(r.DrumNotes) == 0
+}
+
+// apply returns the messages with the table applied.
+// The input is not modified; it is returned as is when the table is empty.
+func (r *MIDIRemap) apply(messages []MIDIMessage) []MIDIMessage {
+	if r.empty() {
+		return messages
+	}
+	remapped := make([]MIDIMessage, len(messages))
+	for i, msg := range messages {
+		switch {
+		case msg.Command == midiProgramChange:
+			msg.Data1 = remapValue(r.Programs, msg.Data1)
+		case msg.Command == midiControlChange && msg.Data1 == midiBankSelectMSB:
+			msg.Data2 = remapValue(r.Banks, msg.Data2)
+		case msg.Channel == drumChannel && (msg.Command == midiNoteOn || msg.Command == midiNoteOff || msg.Command == midiKeyPressure):
+			msg.Data1 = remapValue(r.DrumNotes, msg.Data1)
+		}
+		remapped[i] = msg
+	}
+	return remapped
+}
+
+// remapValue returns the mapped value of v, or v if the table has no entry for it.
+func remapValue(table map[int]int, v int) int {
+	if to, ok := table[v]; ok {
+		return to
+	}
+	return v
+}
+
+// SetMIDIRemap sets the remapping table used from the next Play (nil disables remapping).
+func (mp *MIDIPlayer) SetMIDIRemap(remap *MIDIRemap) {
+	mp.mu.Lock()
+	defer mp.mu.Unlock()
+	mp.remap = remap
+}
+
+// SetMIDIRemap sets the remapping table of all MIDI ports and of offline rendering.
+// MIDI files that are already playing change from their next PlayMIDI.
+func (as *AudioSystem) SetMIDIRemap(remap *MIDIRemap) {
+	as.mu.Lock()
+	defer as.mu.Unlock()
+
+	if as.midiPlayer == nil {
+		return
+	}
+	as.midiPlayer.SetMIDIRemap(remap)
+	as.eachPort(func(mp *MIDIPlayer) {
+		mp.SetMIDIRemap(remap)
+	})
+}
@@ -0,0 +1,52 @@
+package audio
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestMIDIRemapApply(t *testing.T) {
+	remap := &MIDIRemap{
+		Programs:  map[int]int{81: 80},
+		Banks:     map[int]int{8: 0},
+		DrumNotes: map[int]int{35: 36},
+	}
+	messages := []MIDIMessage{
+		{Channel: 0, Command: 0xC0, Data1: 81},             // program change
+		{Channel: 0, Command: 0xC0, Data1: 5},              // program change (not remapped)
+		{Channel: 0, Command: 0xB0, Data1: 0, Data2: 8},    // bank select
+		{Channel: 0, Command: 0xB0, Data1: 7, Data2: 8},    // volume (not remapped)
+		{Channel: 9, Command: 0x90, Data1: 35, Data2: 100}, // drum NoteOn
+		{Channel: 9, Command: 0x80, Data1: 35},             // drum NoteOff
+		{Channel: 0, Command: 0x90, Data1: 35, Data2: 100}, // melody NoteOn (not remapped)
+	}
+	original := append([]MIDIMessage(nil), messages...)
+
+	got := remap.apply(messages)
+	want := []MIDIMessage{
+		{Channel: 0, Command: 0xC0, Data1: 80},
+		{Channel: 0, Command: 0xC0, Data1: 5},
+		{Channel: 0, Command: 0xB0, Data1: 0, Data2: 0},
+		{Channel: 0, Command: 0xB0, Data1: 7, Data2: 8},
+		{Channel: 9, Command: 0x90, Data1: 36, Data2: 100},
+		{Channel: 9, Command: 0x80, Data1: 36},
+		{Channel: 0, Command: 0x90, Data1: 35, Data2: 100},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("apply = %+v, want %+v", got, want)
+	}
+	if !reflect.DeepEqual(messages, original) {
+		t.Error("apply modified the input messages")
+	}
+}
+
+// TestMIDIRemapEmpty tests that an empty or nil table returns the messages as is.
+func TestMIDIRemapEmpty(t *testing.T) {
+	messages := []MIDIMessage{{Command: 0xC0, Data1: 81}}
+	var nilRemap *MIDIRemap
+	for _, remap := range []*MIDIRemap{nilRemap, {}} {
+		if got := remap.apply(messages); &got[0] != &messages[0] {
+			t.Errorf("apply with %+v copied the messages", remap)
+		}
+	}
+}
@@ -0,0 +1,324 @@
+// Package audio provides audio-related components for the FILLY virtual machine.
+// This file implements the diagnostics and best-effort repair of broken MIDI files.
+//
+// MIDI files from old archives often have broken running status, truncated tracks
+// or no end-of-track event. Such files are rejected by the SMF parser of go-meltysynth,
+// so PlayMIDI would fail entirely. RepairMIDI reports each problem with its position
+// and rewrites the file to a well-formed one that keeps every readable event.
+package audio
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"log/slog"
+)
+
+// SMF chunk layout
+const (
+	smfChunkHeaderSize = 8 // chunk ID and length
+	smfHeaderLength    = 6 // length of the MThd body (format, tracks, division)
+)
+
+// endOfTrack is the end-of-track meta event (without its delta time).
+var endOfTrack = []byte{0xFF, 0x2F, 0x00}
+
+// MIDIIssue is a structural problem found in a MIDI file by RepairMIDI.
+type MIDIIssue struct {
+	Track   int    // track index from 0 (-1 for the file header)
+	Offset  int    // byte offset in the file
+	Message string // what is wrong and how it was repaired
+}
+
+// String formats the issue for logs and errors.
+func (i MIDIIssue) String() string {
+	if i.Track < 0 {
+		return fmt.Sprintf("header at byte %d: %s", i.Offset, i.Message)
+	}
+	return fmt.Sprintf("track %d at byte %d: %s", i.Track, i.Offset, i.Message)
+}
+
+// RepairMIDI checks the structure of a Standard MIDI File and returns a repaired copy
+// with the problems found. If there are no problems, data is returned unchanged.
+//
+// The repair is best effort:
+//   - data bytes without a running status (up to the next status byte) and unexpected
+//     status bytes are dropped (their delta time is kept, so later events stay in time)
+//   - an event cut off by a status byte or by the end of the track is dropped
+//   - a track longer than the file is read up to the end of the file
+//   - a missing end-of-track event is added
+//   - the track count of the header is corrected
+//
+// An error is returned only if the file has no MIDI header or no track at all.
+func RepairMIDI(data []byte) ([]byte, []MIDIIssue, error) {
+	if len(data) < smfChunkHeaderSize+smfHeaderLength || string(data[0:4]) != "MThd" {
+		return nil, nil, errors.New("MThd header not found")
+	}
+	headerLen := int(binary.BigEndian.Uint32(data[4:]))
+	if headerLen < smfHeaderLength {
+		return nil, nil, fmt.Errorf("MThd header too short (%d bytes)", headerLen)
+	}
+	declared := int(binary.BigEndian.Uint16(data[10:]))
+
+	var issues []MIDIIssue
+	var tracks [][]byte
+	pos := smfChunkHeaderSize + min(headerLen, len(data)-smfChunkHeaderSize)
+	for pos < len(data) {
+		if pos+smfChunkHeaderSize > len(data) {
+			issues = append(issues, MIDIIssue{Track: len(tracks), Offset: pos, Message: fmt.Sprintf("%d stray bytes at the end of the file ignored", len(data)-pos)})
+			break
+		}
+		id := string(data[pos : pos+4])
+		length := int(binary.BigEndian.Uint32(data[pos+4:]))
+		body := data[pos+smfChunkHeaderSize:]
+		if length > len(body) {
+			if id == "MTrk" {
+				issues = append(issues, MIDIIssue{Track: len(tracks), Offset: pos, Message: fmt.Sprintf("track declares %d bytes but only %d remain; read to the end of the file", length, len(body))})
+			}
+			length = len(body)
+		}
+		if id == "MTrk" {
+			track, trackIssues := repairTrack(body[:length], len(tracks), pos+smfChunkHeaderSize)
+			tracks = append(tracks, track)
+			issues = append(issues, trackIssues...)
+		} else if !isChunkID(id) {
+			issues = append(issues, MIDIIssue{Track: len(tracks), Offset: pos, Message: fmt.Sprintf("%d bytes of unknown data ignored", len(data)-pos)})
+			break
+		}
+		// MTrk 以外のチャンクは仕様どおり読み飛ばす
+		pos += smfChunkHeaderSize + length
+	}
+
+	if len(tracks) == 0 {
+		return nil, issues, errors.New("no MTrk track found")
+	}
+	if declared != len(tracks) {
+		issues = append(issues, MIDIIssue{Track: -1, Offset: 10, Message: fmt.Sprintf("header declares %d tracks but %d were found", declared, len(tracks))})
+	}
+	if len(issues) == 0 {
+		return data, nil, nil
+	}
+
+	out := make([]byte, 0, len(data)+len(tracks)*(len(endOfTrack)+1))
+	out = append(out, "MThd"...)
+	out = binary.BigEndian.AppendUint32(out, smfHeaderLength)
+	out = append(out, data[8:10]...) // format
+	out = binary.BigEndian.AppendUint16(out, uint16(len(tracks)))
+	out = append(out, data[12:14]...) // division
+	for _, track := range tracks {
+		out = append(out, "MTrk"...)
+		out = binary.BigEndian.AppendUint32(out, uint32(len(track)))
+		out = append(out, track...)
+	}
+	return out, issues, nil
+}
+
+// repairTrack re-encodes the events of one track body that can be read,
+// ending it with an end-of-track event. base is the offset of body in the file.
+func repairTrack(body []byte, index, base int) ([]byte, []MIDIIssue) {
+	var issues []MIDIIssue
+	report := func(pos int, format string, args ...any) {
+		issues = append(issues, MIDIIssue{Track: index, Offset: base + pos, Message: fmt.Sprintf(format, args...)})
+	}
+
+	out := make([]byte, 0, len(body)+len(endOfTrack)+1)
+	var running byte
+	pending := 0      // delta time of dropped events, added to the next event
+	needDelta := true // false when resynchronizing on a status byte found inside an event
+	pos := 0
+	for pos < len(body) {
+		start := pos
+		if needDelta {
+			delta, n, ok := readVarLenChecked(body[pos:])
+			if !ok {
+				report(pos, "delta time cut off by the end of the track")
+				break
+			}
+			pending += delta
+			pos += n
+			if pos >= len(body) {
+				report(start, "event cut off by the end of the track")
+				break
+			}
+		}
+		needDelta = true
+
+		status := body[pos]
+		if status < 0x80 {
+			if running == 0 {
+				// 状態バイトまでのデータバイトをまとめて捨てる（次のイベントのデルタタイムと区別できないため）
+				n := dataEnd(body[pos:])
+				report(pos, "%d data bytes without running status dropped", n)
+				pos += n
+				needDelta = false
+				continue
+			}
+			status = running
+		} else {
+			pos++
+		}
+
+		switch {
+		case status == 0xFF:
+			if pos >= len(body) {
+				report(start, "meta event cut off by the end of the track")
+				pos = len(body)
+				break
+			}
+			metaType := body[pos]
+			length, n, ok := readVarLenChecked(body[pos+1:])
+			end := pos + 1 + n + length
+			if !ok || end > len(body) {
+				report(start, "meta event 0x%02X cut off by the end of the track", metaType)
+				pos = len(body)
+				break
+			}
+			if metaType == 0x2F {
+				out = appendVarLen(out, pending)
+				out = append(out, endOfTrack...)
+				return out, issues
+			}
+			out = appendVarLen(out, pending)
+			out = append(out, 0xFF)
+			out = append(out, body[pos:end]...)
+			pending = 0
+			pos = end
+		case status == 0xF0 || status == 0xF7:
+			length, n, ok := readVarLenChecked(body[pos:])
+			end := pos + n + length
+			if !ok || end > len(body) {
+				report(start, "SysEx cut off by the end of the track")
+				pos = len(body)
+				break
+			}
+			out = appendVarLen(out, pending)
+			out = append(out, status)
+			out = append(out, body[pos:end]...)
+			pending = 0
+			pos = end
+		case status > 0xF0:
+			// システムコモン・リアルタイムのメッセージは SMF には現れない
+			report(start, "unexpected status byte 0x%02X dropped", status)
+			running = 0
+		default:
+			size := channelDataSize(status)
+			end := min(pos+size, len(body))
+			if i := dataEnd(body[pos:end]); i < size {
+				if pos+i < len(body) {
+					// データの途中に状態バイトがある: イベントを捨て、その状態バイトから読み直す
+					report(start, "event 0x%02X missing %d data bytes dropped", status, size-i)
+					needDelta = false
+				} else {
+					report(start, "event 0x%02X cut off by the end of the track", status)
+				}
+				pos += i
+				continue
+			}
+			out = appendVarLen(out, pending)
+			out = append(out, status)
+			out = append(out, body[pos:end]...)
+			pending = 0
+			running = status
+			pos = end
+		}
+	}
+
+	report(len(body), "end of track missing; added")
+	out = appendVarLen(out, pending)
+	out = append(out, endOfTrack...)
+	return out, issues
+}
+
+// channelDataSize returns the number of data bytes of a channel message.
+func channelDataSize(status byte) int {
+	if status >= 0xC0 && status < 0xE0 {
+		return 1 // program change, channel pressure
+	}
+	return 2
+}
+
+// dataEnd returns the number of leading data bytes (below 0x80) in b.
+func dataEnd(b []byte) int {
+	for i, c := range b {
+		if c >= 0x80 {
+			return i
+		}
+	}
+	return len(b)
+}
+
+// isChunkID reports whether id is a plausible chunk ID (four printable ASCII characters).
+func isChunkID(id string) bool {
+	for i := range len(id) {
+		if id[i] < 0x20 || id[i] > 0x7E {
+			return false
+		}
+	}
+	return true
+}
+
+// readVarLenChecked reads a variable-length quantity like readVarLen,
+// reporting false if it is cut off by the end of data.
+func readVarLenChecked(data []byte) (int, int, bool) {
+	value, n := readVarLen(data)
+	if n == 0 || data[n-1]&0x80 != 0 {
+		return value, n, false
+	}
+	return value, n, true
+}
+
+// appendVarLen appends v as a variable-length quantity.
+func appendVarLen(b []byte, v int) []byte {
+	var buf [4]byte
+	i := len(buf) - 1
+	buf[i] = byte(v & 0x7F)
+	for v >>= 7; v > 0 && i > 0; v >>= 7 {
+		i--
+		buf[i] = byte(v&0x7F) | 0x80
+	}
+	return append(b, buf[i:]...)
+}
+
+// checkMIDI logs the problems of a MIDI file and returns the data to play.
+// Broken files are repaired unless strict is set, in which case the first
+// problem is returned as an error.
+func checkMIDI(filename string, data []byte, strict bool) ([]byte, error) {
+	repaired, issues, err := RepairMIDI(data)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrMIDIInvalidFormat, err)
+	}
+	if len(issues) == 0 {
+		return data, nil
+	}
+	for _, issue := range issues {
+		slog.Warn("MIDI file problem", "filename", filename, "track", issue.Track, "offset", issue.Offset, "problem", issue.Message)
+	}
+	if strict {
+		return nil, fmt.Errorf("%w: %s (%d problems, strict mode)", ErrMIDIInvalidFormat, issues[0], len(issues))
+	}
+	slog.Warn("MIDI file repaired", "filename", filename, "problems", len(issues))
+	return repaired, nil
+}
+
+// SetMIDIStrict sets whether broken MIDI files fail to play instead of being repaired
+// (used from the next Play).
+func (mp *MIDIPlayer) SetMIDIStrict(strict bool) {
+	mp.mu.Lock()
+	defer mp.mu.Unlock()
+	mp.strict = strict
+}
+
+// SetMIDIStrict sets whether broken MIDI files fail to play or render instead of
+// being repaired, for all MIDI ports and offline rendering.
+func (as *AudioSystem) SetMIDIStrict(strict bool) {
+	as.mu.Lock()
+	defer as.mu.Unlock()
+
+	if as.midiPlayer == nil {
+		return
+	}
+	as.midiPlayer.SetMIDIStrict(strict)
+	as.eachPort(func(mp *MIDIPlayer) {
+		mp.SetMIDIStrict(strict)
+	})
+}
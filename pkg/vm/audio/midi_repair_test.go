@@ -0,0 +1,208 @@
+package audio
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/sinshu/go-meltysynth/meltysynth"
+)
+
+// buildSMF builds a format 1 MIDI file with the given track bodies.
+// declaredTracks overrides the track count of the header when not negative.
+func buildSMF(declaredTracks int, tracks ...[]byte) []byte {
+	if declaredTracks < 0 {
+		declaredTracks = len(tracks)
+	}
+	data := []byte("MThd")
+	data = binary.BigEndian.AppendUint32(data, 6)
+	data = binary.BigEndian.AppendUint16(data, 1)
+	data = binary.BigEndian.AppendUint16(data, uint16(declaredTracks))
+	data = binary.BigEndian.AppendUint16(data, 480)
+	for _, track := range tracks {
+		data = append(data, "MTrk"...)
+		data = binary.BigEndian.AppendUint32(data, uint32(len(track)))
+		data = append(data, track...)
+	}
+	return data
+}
+
+// noteTrack is a well-formed track: a note on and off with running status.
+var noteTrack = []byte{
+	0x00, 0x90, 0x3C, 0x64, // note on
+	0x83, 0x60, 0x3C, 0x00, // 480 ticks later, note off by running status
+	0x00, 0xFF, 0x2F, 0x00, // end of track
+}
+
+// repairAndCheck repairs data and checks that the result is accepted by go-meltysynth
+// and has no problems left.
+func repairAndCheck(t *testing.T, data []byte) ([]byte, []MIDIIssue) {
+	t.Helper()
+	repaired, issues, err := RepairMIDI(data)
+	if err != nil {
+		t.Fatalf("RepairMIDI failed: %v", err)
+	}
+	if _, err := meltysynth.NewMidiFile(bytes.NewReader(repaired)); err != nil {
+		t.Fatalf("repaired file is not accepted by meltysynth: %v", err)
+	}
+	if _, again, _ := RepairMIDI(repaired); len(again) != 0 {
+		t.Errorf("repaired file still has problems: %v", again)
+	}
+	return repaired, issues
+}
+
+// issueMessages returns the messages of the issues joined for matching.
+func issueMessages(issues []MIDIIssue) string {
+	var messages []string
+	for _, issue := range issues {
+		messages = append(messages, issue.String())
+	}
+	return strings.Join(messages, "\n")
+}
+
+// TestRepairMIDIWellFormed tests that a well-formed file is returned unchanged.
+func TestRepairMIDIWellFormed(t *testing.T) {
+	data := buildSMF(-1, noteTrack)
+	repaired, issues, err := RepairMIDI(data)
+	if err != nil || len(issues) != 0 {
+		t.Fatalf("RepairMIDI = %v, %v; want no problems", issues, err)
+	}
+	if !bytes.Equal(repaired, data) {
+		t.Error("a well-formed file should be returned unchanged")
+	}
+}
+
+// TestRepairMIDIMissingEndOfTrack tests that a missing end-of-track event is added.
+func TestRepairMIDIMissingEndOfTrack(t *testing.T) {
+	data := buildSMF(-1, noteTrack[:8])
+	repaired, issues := repairAndCheck(t, data)
+
+	if len(issues) != 1 || issues[0].Track != 0 || issues[0].Offset != len(data) {
+		t.Fatalf("issues = %v, want 1 at the end of track 0", issues)
+	}
+	// ランニングステータスは省略せずに書き直す
+	want := buildSMF(-1, []byte{0x00, 0x90, 0x3C, 0x64, 0x83, 0x60, 0x90, 0x3C, 0x00, 0x00, 0xFF, 0x2F, 0x00})
+	if !bytes.Equal(repaired, want) {
+		t.Errorf("repaired = % X, want % X", repaired, want)
+	}
+}
+
+// TestRepairMIDITruncatedTrack tests that a track longer than the file is read to the end
+// and that the event cut off is dropped.
+func TestRepairMIDITruncatedTrack(t *testing.T) {
+	data := buildSMF(-1, noteTrack)
+	data = data[:len(data)-6] // cut in the note off event
+
+	repaired, issues := repairAndCheck(t, data)
+	messages := issueMessages(issues)
+	for _, want := range []string{"track declares 12 bytes but only 6 remain", "cut off by the end of the track", "end of track missing"} {
+		if !strings.Contains(messages, want) {
+			t.Errorf("issues do not report %q:\n%s", want, messages)
+		}
+	}
+	if want := buildSMF(-1, []byte{0x00, 0x90, 0x3C, 0x64, 0x83, 0x60, 0xFF, 0x2F, 0x00}); !bytes.Equal(repaired, want) {
+		t.Errorf("repaired = % X, want % X", repaired, want)
+	}
+}
+
+// TestRepairMIDIRunningStatus tests that data bytes without a running status are dropped
+// and their delta time is kept.
+func TestRepairMIDIRunningStatus(t *testing.T) {
+	track := []byte{
+		0x10, 0x3C, 0x64, // data bytes before any status
+		0x90, 0x3C, 0x64,
+		0x00, 0xFF, 0x2F, 0x00,
+	}
+	repaired, issues := repairAndCheck(t, buildSMF(-1, track))
+
+	if len(issues) != 1 || !strings.Contains(issues[0].Message, "2 data bytes without running status") {
+		t.Fatalf("issues = %v, want 2 data bytes without running status", issues)
+	}
+	if issues[0].Offset != 23 {
+		t.Errorf("offset = %d, want 23", issues[0].Offset)
+	}
+	// 捨てたバイトのデルタタイムは次のイベントに加える
+	if want := buildSMF(-1, []byte{0x10, 0x90, 0x3C, 0x64, 0x00, 0xFF, 0x2F, 0x00}); !bytes.Equal(repaired, want) {
+		t.Errorf("repaired = % X, want % X", repaired, want)
+	}
+}
+
+// TestRepairMIDIMissingDataByte tests that an event interrupted by a status byte is dropped
+// and parsing resumes at that status byte.
+func TestRepairMIDIMissingDataByte(t *testing.T) {
+	track := []byte{
+		0x00, 0x90, 0x3C, // note on missing its velocity
+		0x80, 0x3C, 0x00, // note off read from this status byte
+		0x00, 0xFF, 0x2F, 0x00,
+	}
+	repaired, issues := repairAndCheck(t, buildSMF(-1, track))
+
+	if len(issues) != 1 || !strings.Contains(issues[0].Message, "missing 1 data bytes") {
+		t.Fatalf("issues = %v, want 1 missing data byte", issues)
+	}
+	if want := buildSMF(-1, []byte{0x00, 0x80, 0x3C, 0x00, 0x00, 0xFF, 0x2F, 0x00}); !bytes.Equal(repaired, want) {
+		t.Errorf("repaired = % X, want % X", repaired, want)
+	}
+}
+
+// TestRepairMIDITrackCount tests that the track count of the header is corrected.
+func TestRepairMIDITrackCount(t *testing.T) {
+	repaired, issues := repairAndCheck(t, buildSMF(3, noteTrack, noteTrack))
+
+	if len(issues) != 1 || issues[0].Track != -1 {
+		t.Fatalf("issues = %v, want 1 header problem", issues)
+	}
+	if got := binary.BigEndian.Uint16(repaired[10:]); got != 2 {
+		t.Errorf("track count = %d, want 2", got)
+	}
+}
+
+// TestRepairMIDIUnrepairable tests the files that cannot be repaired.
+func TestRepairMIDIUnrepairable(t *testing.T) {
+	tests := []struct {
+		name string
+		data []byte
+	}{
+		{"empty", nil},
+		{"not MIDI", []byte("RIFF0000WAVEfmt ")},
+		{"no track", buildSMF(0)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, _, err := RepairMIDI(tt.data); err == nil {
+				t.Error("expected an error")
+			}
+		})
+	}
+}
+
+// TestCheckMIDIStrict tests that strict mode rejects a broken file and the default repairs it.
+func TestCheckMIDIStrict(t *testing.T) {
+	broken := buildSMF(-1, noteTrack[:8])
+
+	if _, err := checkMIDI("broken.mid", broken, true); !errors.Is(err, ErrMIDIInvalidFormat) {
+		t.Errorf("strict checkMIDI error = %v, want ErrMIDIInvalidFormat", err)
+	}
+	data, err := checkMIDI("broken.mid", broken, false)
+	if err != nil || bytes.Equal(data, broken) {
+		t.Errorf("checkMIDI = %v, %v; want the repaired file", data, err)
+	}
+
+	good := buildSMF(-1, noteTrack)
+	if data, err := checkMIDI("good.mid", good, true); err != nil || !bytes.Equal(data, good) {
+		t.Errorf("strict checkMIDI of a good file = %v, %v", data, err)
+	}
+}
+
+// TestAppendVarLen tests the encoding of variable-length quantities.
+func TestAppendVarLen(t *testing.T) {
+	for _, v := range []int{0, 0x40, 0x7F, 0x80, 0x2000, 0x3FFF, 0x4000, 0x0FFFFFFF} {
+		b := appendVarLen(nil, v)
+		got, n, ok := readVarLenChecked(b)
+		if !ok || got != v || n != len(b) {
+			t.Errorf("appendVarLen(%#x) = % X, read back %#x (%d bytes, %v)", v, b, got, n, ok)
+		}
+	}
+}
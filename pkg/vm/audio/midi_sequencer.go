@@ -0,0 +1,273 @@
+package audio
+
+import (
+	"sort"
+	"sync"
+
+	"github.com/sinshu/go-meltysynth/meltysynth"
+)
+
+// MIDIMessage is a channel message (NoteOn, NoteOff, ControlChange, ProgramChange, ...) in a MIDI file.
+type MIDIMessage struct {
+	Tick    int // MIDI tick (PPQ units) from the start of the file
+	Channel int // MIDI channel (0-15)
+	Command int // status byte without the channel (0x80-0xE0)
+	Data1   int
+	Data2   int // 0 for ProgramChange and ChannelPressure
+}
+
+// ParseMIDIMessages extracts all channel messages from MIDI data, ordered by tick.
+// Messages on the same tick keep their track order. Meta events and SysEx are skipped.
+// Truncated or corrupt tracks are read up to the end of the data without panicking.
+func ParseMIDIMessages(data []byte) []MIDIMessage {
+	if len(data) < 14 || string(data[0:4]) != "MThd" {
+		return nil
+	}
+
+	var messages []MIDIMessage
+	offset := 14
+	for offset < len(data) {
+		if offset+8 > len(data) || string(data[offset:offset+4]) != "MTrk" {
+			break
+		}
+
+		trackLen := int(data[offset+4])<<24 | int(data[offset+5])<<16 | int(data[offset+6])<<8 | int(data[offset+7])
+		trackEnd := min(offset+8+trackLen, len(data))
+		pos := offset + 8
+		currentTick := 0
+		lastStatus := byte(0)
+
+		for pos < trackEnd {
+			delta, n := readVarLen(data[pos:trackEnd])
+			pos += n
+			currentTick += delta
+			if pos >= trackEnd {
+				break
+			}
+
+			eventByte := data[pos]
+			if eventByte < 0x80 {
+				// Running status
+				eventByte = lastStatus
+			} else {
+				pos++
+				if eventByte < 0xF0 {
+					lastStatus = eventByte
+				}
+			}
+
+			switch {
+			case eventByte == 0xFF: // Meta event
+				if pos >= trackEnd {
+					continue
+				}
+				pos++
+				length, n := readVarLen(data[pos:trackEnd])
+				pos += n + length
+			case eventByte == 0xF0 || eventByte == 0xF7: // SysEx
+				length, n := readVarLen(data[pos:trackEnd])
+				pos += n + length
+			case eventByte >= 0xC0 && eventByte < 0xE0: // Program change, channel pressure
+				if pos+1 <= trackEnd {
+					messages = append(messages, MIDIMessage{
+						Tick:    currentTick,
+						Channel: int(eventByte & 0x0F),
+						Command: int(eventByte & 0xF0),
+						Data1:   int(data[pos]),
+					})
+				}
+				pos++
+			case eventByte >= 0x80:
+				if pos+2 <= trackEnd {
+					messages = append(messages, MIDIMessage{
+						Tick:    currentTick,
+						Channel: int(eventByte & 0x0F),
+						Command: int(eventByte & 0xF0),
+						Data1:   int(data[pos]),
+						Data2:   int(data[pos+1]),
+					})
+				}
+				pos += 2
+			default:
+				// Data byte without a running status: skip it
+				pos++
+			}
+		}
+		offset = trackEnd
+	}
+
+	sort.SliceStable(messages, func(i, j int) bool { return messages[i].Tick < messages[j].Tick })
+	return messages
+}
+
+// scaleChange records where the tempo scale of a tempoSequencer changed.
+type scaleChange struct {
+	output int64   // output samples rendered before the change
+	song   float64 // song position (samples at normal tempo) at the change
+	scale  float64 // tempo scale from this point on
+}
+
+// tempoSequencer plays the channel messages of a MIDI file on a synthesizer
+// with an adjustable tempo scale (slow motion / fast forward).
+// Unlike meltysynth.MidiFileSequencer, changing the scale changes how fast the
+// song advances without changing the pitch.
+type tempoSequencer struct {
+	synth    *meltysynth.Synthesizer
+	messages []MIDIMessage
+	times    []int64 // song position (samples at normal tempo) of each message
+	next     int     // index of the next message to send
+	tickCalc *TickCalculator
+
+	position   float64 // song position of the next block (samples at normal tempo)
+	rendered   int64   // output samples rendered so far
+	blockWrote int32   // samples of the current synthesizer block already written
+	scale      float64 // tempo scale of the current block
+	pending    float64 // tempo scale requested by SetScale (applied at the next block)
+	changes    []scaleChange
+	metronome  *metronome // clicks on quarter notes (see metronome.go), nil when off
+
+	mu sync.Mutex
+}
+
+// newTempoSequencer creates a sequencer that plays messages from the start of the song.
+// tickCalc converts the message ticks to song positions.
+func newTempoSequencer(synth *meltysynth.Synthesizer, messages []MIDIMessage, tickCalc *TickCalculator) *tempoSequencer {
+	times := make([]int64, len(messages))
+	for i, msg := range messages {
+		times[i] = tickCalc.SamplesFromTick(msg.Tick)
+	}
+	synth.Reset()
+	return &tempoSequencer{
+		synth:      synth,
+		messages:   messages,
+		times:      times,
+		tickCalc:   tickCalc,
+		blockWrote: synth.BlockSize,
+		scale:      1,
+		pending:    1,
+		changes:    []scaleChange{{scale: 1}},
+	}
+}
+
+// Render renders the next len(left) samples, sending the messages that are due
+// at the start of each synthesizer block.
+func (s *tempoSequencer) Render(left []float32, right []float32) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var wrote int32
+	length := int32(len(left))
+	for wrote < length {
+		if s.blockWrote == s.synth.BlockSize {
+			if s.pending != s.scale {
+				s.scale = s.pending
+				s.changes = append(s.changes, scaleChange{output: s.rendered, song: s.position, scale: s.scale})
+			}
+			s.processMessages()
+			s.blockWrote = 0
+			s.position += float64(s.synth.BlockSize) * s.scale
+		}
+
+		rem := min(s.synth.BlockSize-s.blockWrote, length-wrote)
+		s.synth.Render(left[wrote:wrote+rem], right[wrote:wrote+rem])
+		if s.metronome != nil {
+			s.metronome.mix(left[wrote:wrote+rem], right[wrote:wrote+rem], s.songAt(s.blockWrote), s.scale)
+		}
+
+		s.blockWrote += rem
+		s.rendered += int64(rem)
+		wrote += rem
+	}
+}
+
+// processMessages sends every message up to the current song position to the synthesizer.
+// Must be called with s.mu held.
+func (s *tempoSequencer) processMessages() {
+	for s.next < len(s.messages) && float64(s.times[s.next]) <= s.position {
+		msg := s.messages[s.next]
+		s.synth.ProcessMidiMessage(int32(msg.Channel), int32(msg.Command), int32(msg.Data1), int32(msg.Data2))
+		s.next++
+	}
+}
+
+// SeekTo jumps to a song position (samples at normal tempo) from the next synthesizer block.
+// Sounding notes are cut, and the program and controller changes before the position
+// are replayed, so the instruments sound as if the song had been played up to there.
+func (s *tempoSequencer) SeekTo(song int64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.next = seekIndex(s.times, song)
+	s.synth.Reset()
+	for _, msg := range chaseMessages(s.messages[:s.next]) {
+		s.synth.ProcessMidiMessage(int32(msg.Channel), int32(msg.Command), int32(msg.Data1), int32(msg.Data2))
+	}
+	s.position = float64(song)
+	s.blockWrote = s.synth.BlockSize
+	if s.metronome != nil {
+		s.metronome.seek(song)
+	}
+	s.changes = append(s.changes, scaleChange{output: s.rendered, song: s.position, scale: s.scale})
+}
+
+// seekIndex returns the index of the first message at or after the song position.
+func seekIndex(times []int64, song int64) int {
+	return sort.Search(len(times), func(i int) bool { return times[i] >= song })
+}
+
+// chaseMessages returns the messages that set the state of a channel (program,
+// controllers, pitch bend, channel pressure), i.e. all but NoteOn, NoteOff and
+// polyphonic key pressure, in order.
+func chaseMessages(messages []MIDIMessage) []MIDIMessage {
+	var chased []MIDIMessage
+	for _, msg := range messages {
+		switch msg.Command {
+		case 0x80, 0x90, 0xA0:
+			continue
+		}
+		chased = append(chased, msg)
+	}
+	return chased
+}
+
+// SetScale sets the tempo scale; it takes effect from the next synthesizer block.
+func (s *tempoSequencer) SetScale(scale float64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.pending = scale
+}
+
+// SetMetronome turns the click on every quarter note on or off.
+func (s *tempoSequencer) SetMetronome(enabled bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	switch {
+	case !enabled:
+		s.metronome = nil
+	case s.metronome == nil:
+		s.metronome = newMetronome(s.tickCalc, int64(s.songAt(s.blockWrote)))
+	}
+}
+
+// songAt returns the song position of a sample of the current synthesizer block.
+// Must be called with s.mu held.
+func (s *tempoSequencer) songAt(sample int32) float64 {
+	return s.position - float64(s.synth.BlockSize-sample)*s.scale
+}
+
+// SongPosition returns the song position (samples at normal tempo) of the given
+// output sample, e.g. the one currently heard according to the audio player.
+func (s *tempoSequencer) SongPosition(output int64) int64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	c := s.changes[0]
+	for i := len(s.changes) - 1; i > 0; i-- {
+		if output >= s.changes[i].output {
+			c = s.changes[i]
+			break
+		}
+	}
+	return int64(c.song + float64(output-c.output)*c.scale)
+}
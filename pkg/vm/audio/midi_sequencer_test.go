@@ -0,0 +1,127 @@
+package audio
+
+import (
+	"testing"
+
+	"github.com/zurustar/son-et/pkg/vm"
+)
+
+// TestParseMIDIMessages tests channel message extraction across tracks.
+func TestParseMIDIMessages(t *testing.T) {
+	data := buildMIDIHeader(480)
+	data = append(data, buildMIDITrack([]byte{
+		0x00, 0xFF, 0x51, 0x03, 0x07, 0xA1, 0x20, // tempo (skipped)
+		0x00, 0xC1, 0x05, // ch2 program change
+		0x10, 0x91, 60, 100, // ch2 NoteOn
+		0x10, 60, 0, // running status: NoteOn velocity 0
+		0x00, 0xFF, 0x2F, 0x00,
+	})...)
+	data = append(data, buildMIDITrack([]byte{
+		0x10, 0xB0, 7, 90, // ch1 volume at the same tick as the NoteOn
+		0x00, 0xFF, 0x2F, 0x00,
+	})...)
+
+	got := ParseMIDIMessages(data)
+	want := []MIDIMessage{
+		{Tick: 0, Channel: 1, Command: 0xC0, Data1: 5},
+		{Tick: 16, Channel: 1, Command: 0x90, Data1: 60, Data2: 100},
+		{Tick: 16, Channel: 0, Command: 0xB0, Data1: 7, Data2: 90},
+		{Tick: 32, Channel: 1, Command: 0x90, Data1: 60, Data2: 0},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("got %d messages, want %d: %+v", len(got), len(want), got)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("message %d = %+v, want %+v", i, got[i], want[i])
+		}
+	}
+}
+
+// TestSamplesFromTick tests that SamplesFromTick is the inverse of TickFromSamples across tempo changes.
+func TestSamplesFromTick(t *testing.T) {
+	tc := NewTickCalculator(480, []TempoEvent{
+		{Tick: 0, MicrosPerBeat: 500000},   // 120 BPM
+		{Tick: 960, MicrosPerBeat: 250000}, // 240 BPM
+	})
+
+	if got := tc.SamplesFromTick(480); got != SampleRate/2 {
+		t.Errorf("SamplesFromTick(480) = %d, want %d", got, SampleRate/2)
+	}
+	if got := tc.SamplesFromTick(1440); got != SampleRate+SampleRate/4 {
+		t.Errorf("SamplesFromTick(1440) = %d, want %d", got, SampleRate+SampleRate/4)
+	}
+	for _, tick := range []int{0, 100, 960, 1500} {
+		if got := tc.TickFromSamples(tc.SamplesFromTick(tick) + 1); got != tick {
+			t.Errorf("TickFromSamples(SamplesFromTick(%d)) = %d", tick, got)
+		}
+	}
+}
+
+// TestTempoSequencerSongPosition tests the mapping from output samples to the song position.
+func TestTempoSequencerSongPosition(t *testing.T) {
+	s := &tempoSequencer{changes: []scaleChange{
+		{scale: 1},
+		{output: 1000, song: 1000, scale: 2},
+		{output: 2000, song: 3000, scale: 0.5},
+	}}
+
+	tests := []struct {
+		output int64
+		want   int64
+	}{
+		{0, 0},
+		{500, 500},
+		{1500, 2000},
+		{2000, 3000},
+		{3000, 3500},
+	}
+	for _, tt := range tests {
+		if got := s.SongPosition(tt.output); got != tt.want {
+			t.Errorf("SongPosition(%d) = %d, want %d", tt.output, got, tt.want)
+		}
+	}
+}
+
+// TestAudioSystemSetTimeScale tests that the time scale is clamped and applied to the timer.
+func TestAudioSystemSetTimeScale(t *testing.T) {
+	as := &AudioSystem{timer: NewTimer(DefaultTimerInterval, vm.NewEventQueue()), timeScale: 1}
+
+	if got := as.SetTimeScale(2); got != 2 || as.TimeScale() != 2 || as.timer.GetScale() != 2 {
+		t.Errorf("expected scale 2, got %v (timer %v)", got, as.timer.GetScale())
+	}
+	if got := as.SetTimeScale(10); got != MaxTimeScale {
+		t.Errorf("expected scale to be clamped to %v, got %v", MaxTimeScale, got)
+	}
+	if got := as.SetTimeScale(0.1); got != MinTimeScale {
+		t.Errorf("expected scale to be clamped to %v, got %v", MinTimeScale, got)
+	}
+	if as.timer.GetInterval() != DefaultTimerInterval {
+		t.Errorf("time scale should not change the timer interval, got %v", as.timer.GetInterval())
+	}
+}
+
+func TestSeekIndex(t *testing.T) {
+	times := []int64{0, 100, 100, 250}
+	for song, want := range map[int64]int{0: 0, 50: 1, 100: 1, 101: 3, 250: 3, 300: 4} {
+		if got := seekIndex(times, song); got != want {
+			t.Errorf("seekIndex(%d) = %d, want %d", song, got, want)
+		}
+	}
+}
+
+// TestChaseMessages tests that only the channel state messages are replayed on seek.
+func TestChaseMessages(t *testing.T) {
+	messages := []MIDIMessage{
+		{Command: 0xC0, Data1: 5},            // program change
+		{Command: 0x90, Data1: 60, Data2: 1}, // NoteOn
+		{Command: 0xB0, Data1: 7, Data2: 90}, // volume
+		{Command: 0x80, Data1: 60},           // NoteOff
+		{Command: 0xA0, Data1: 60, Data2: 3}, // key pressure
+		{Command: 0xE0, Data1: 0, Data2: 64}, // pitch bend
+	}
+	got := chaseMessages(messages)
+	if len(got) != 3 || got[0].Command != 0xC0 || got[1].Command != 0xB0 || got[2].Command != 0xE0 {
+		t.Errorf("chaseMessages = %+v", got)
+	}
+}
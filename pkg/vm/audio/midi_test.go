@@ -1,8 +1,9 @@
+//go:build !nogpu
+
 package audio
 
 import (
 	"os"
-	"path/filepath"
 	"sync"
 	"testing"
 	"time"
@@ -154,6 +155,35 @@ func TestMIDIPlayerStop(t *testing.T) {
 	})
 }
 
+// TestMIDIPlayerSeek tests that Seek only jumps forward within the playing file.
+func TestMIDIPlayerSeek(t *testing.T) {
+	soundFontPath := findSoundFont(t)
+	midiPath := findMIDIFile(t)
+	audioCtx := getSharedAudioContext()
+
+	player, err := NewMIDIPlayer(soundFontPath, audioCtx, nil)
+	if err != nil {
+		t.Fatalf("NewMIDIPlayer failed: %v", err)
+	}
+	if err := player.Seek(16); err == nil {
+		t.Error("Seek should fail when nothing is playing")
+	}
+
+	if err := player.Play(midiPath); err != nil {
+		t.Fatalf("Play failed: %v", err)
+	}
+	defer player.Stop()
+	if err := player.Seek(0); err == nil {
+		t.Error("Seek to the current tick should fail")
+	}
+	if err := player.Seek(1 << 30); err == nil {
+		t.Error("Seek past the end should fail")
+	}
+	if err := player.Seek(4); err != nil {
+		t.Errorf("Seek(4) failed: %v", err)
+	}
+}
+
 // TestMIDIPlayerMute tests muting functionality.
 func TestMIDIPlayerMute(t *testing.T) {
 	soundFontPath := findSoundFont(t)
@@ -371,79 +401,6 @@ func TestMIDIPlayerGetCurrentTick(t *testing.T) {
 	})
 }
 
-// Helper functions
-
-// findSoundFont finds the SoundFont file in the project.
-func findSoundFont(t *testing.T) string {
-	t.Helper()
-
-	// Try common locations
-	paths := []string{
-		"../../../GeneralUser-GS.sf2",
-		"../../GeneralUser-GS.sf2",
-		"GeneralUser-GS.sf2",
-	}
-
-	for _, p := range paths {
-		absPath, err := filepath.Abs(p)
-		if err != nil {
-			continue
-		}
-		if _, err := os.Stat(absPath); err == nil {
-			return absPath
-		}
-	}
-
-	t.Skip("SoundFont file not found")
-	return ""
-}
-
-// findMIDIFile finds a MIDI file in the samples directory.
-func findMIDIFile(t *testing.T) string {
-	t.Helper()
-
-	// Try to find a MIDI file in samples
-	sampleDirs := []string{
-		"../../../samples",
-		"../../samples",
-		"samples",
-	}
-
-	for _, dir := range sampleDirs {
-		absDir, err := filepath.Abs(dir)
-		if err != nil {
-			continue
-		}
-
-		// Walk the directory to find .mid files
-		err = filepath.Walk(absDir, func(path string, info os.FileInfo, err error) error {
-			if err != nil {
-				return nil
-			}
-			if !info.IsDir() {
-				ext := filepath.Ext(path)
-				if ext == ".mid" || ext == ".MID" {
-					return filepath.SkipAll
-				}
-			}
-			return nil
-		})
-
-		// Try to find any .mid file
-		matches, _ := filepath.Glob(filepath.Join(absDir, "*", "*.mid"))
-		if len(matches) > 0 {
-			return matches[0]
-		}
-		matches, _ = filepath.Glob(filepath.Join(absDir, "*", "*.MID"))
-		if len(matches) > 0 {
-			return matches[0]
-		}
-	}
-
-	t.Skip("MIDI file not found in samples")
-	return ""
-}
-
 // TestMIDIPlayerUpdate tests the Update method for MIDI_TIME event generation.
 // Requirement 4.3: When MIDI is playing, system generates MIDI_TIME events synchronized to MIDI tempo.
 // Requirement 4.4: When MIDI tempo is 120 BPM with resolution 480 ticks per beat, system generates MIDI_TIME event every 1.04ms.
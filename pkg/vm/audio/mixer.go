@@ -0,0 +1,173 @@
+// Package audio provides audio-related components for the FILLY virtual machine.
+// This file implements the Mixer, which balances music and sound effects through named buses.
+package audio
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Bus names of the Mixer.
+// The music bus feeds the MIDI player, the sfx bus feeds the WAV player and the
+// voice bus feeds the WAV files played by PlayVoice (narration, which ducks the
+// music bus, see ducking.go); all are scaled by the master bus.
+const (
+	BusMaster = "master"
+	BusMusic  = "music"
+	BusSFX    = "sfx"
+	BusVoice  = "voice"
+)
+
+// Gain range of a bus (1 = unchanged).
+const (
+	MinBusGain = 0.0
+	MaxBusGain = 1.0
+)
+
+// ErrUnknownBus is returned when a bus name is not one of the Mixer's buses.
+var ErrUnknownBus = errors.New("unknown audio bus")
+
+// mixerBus is the state of a single bus.
+type mixerBus struct {
+	gain  float64
+	muted bool
+}
+
+// Mixer holds the gain and mute state of the master, music, sfx and voice buses
+// and computes the output volume of each source bus.
+//
+// The Mixer only computes volumes; AudioSystem applies them to the players
+// whenever the state changes.
+type Mixer struct {
+	buses map[string]*mixerBus
+	// fade is an extra factor on the master bus (1 = no fade),
+	// used by AudioSystem.StartMasterFadeout.
+	fade float64
+	// duck is an extra factor on the music bus (1 = not ducked),
+	// lowered by AudioSystem while the voice bus is playing.
+	duck float64
+	mu   sync.Mutex
+}
+
+// NewMixer creates a Mixer with every bus at full gain and unmuted.
+func NewMixer() *Mixer {
+	return &Mixer{
+		buses: map[string]*mixerBus{
+			BusMaster: {gain: MaxBusGain},
+			BusMusic:  {gain: MaxBusGain},
+			BusSFX:    {gain: MaxBusGain},
+			BusVoice:  {gain: MaxBusGain},
+		},
+		fade: 1,
+		duck: 1,
+	}
+}
+
+// BusNames returns the bus names in alphabetical order.
+func (m *Mixer) BusNames() []string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	names := make([]string, 0, len(m.buses))
+	for name := range m.buses {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// bus returns the bus with the given name (case-insensitive).
+// Must be called with m.mu held.
+func (m *Mixer) bus(name string) (*mixerBus, error) {
+	b, ok := m.buses[strings.ToLower(name)]
+	if !ok {
+		return nil, fmt.Errorf("%w: %q", ErrUnknownBus, name)
+	}
+	return b, nil
+}
+
+// SetGain sets the gain of a bus, clamped to MinBusGain-MaxBusGain.
+func (m *Mixer) SetGain(name string, gain float64) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	b, err := m.bus(name)
+	if err != nil {
+		return err
+	}
+	b.gain = min(max(gain, MinBusGain), MaxBusGain)
+	return nil
+}
+
+// Gain returns the gain of a bus.
+func (m *Mixer) Gain(name string) (float64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	b, err := m.bus(name)
+	if err != nil {
+		return 0, err
+	}
+	return b.gain, nil
+}
+
+// SetMuted mutes or unmutes a bus. Muting keeps the gain so that unmuting restores it.
+func (m *Mixer) SetMuted(name string, muted bool) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	b, err := m.bus(name)
+	if err != nil {
+		return err
+	}
+	b.muted = muted
+	return nil
+}
+
+// IsMuted returns whether a bus is muted.
+func (m *Mixer) IsMuted(name string) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	b, err := m.bus(name)
+	if err != nil {
+		return false, err
+	}
+	return b.muted, nil
+}
+
+// setFade sets the master fade factor (0-1).
+func (m *Mixer) setFade(fade float64) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.fade = min(max(fade, 0), 1)
+}
+
+// setDuck sets the music bus ducking factor (0-1).
+func (m *Mixer) setDuck(duck float64) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.duck = min(max(duck, 0), 1)
+}
+
+// Volume returns the output volume of a bus: its gain scaled by the master bus
+// and the master fade (and the ducking factor for the music bus), or 0 if either
+// bus is muted. Unknown buses return 0.
+func (m *Mixer) Volume(name string) float64 {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	master := m.buses[BusMaster]
+	if master.muted {
+		return 0
+	}
+	volume := master.gain * m.fade
+	if strings.EqualFold(name, BusMaster) {
+		return volume
+	}
+	b, err := m.bus(name)
+	if err != nil || b.muted {
+		return 0
+	}
+	if strings.EqualFold(name, BusMusic) {
+		volume *= m.duck
+	}
+	return volume * b.gain
+}
@@ -0,0 +1,102 @@
+package audio
+
+import (
+	"errors"
+	"math"
+	"testing"
+)
+
+func TestMixerDefaults(t *testing.T) {
+	m := NewMixer()
+	for _, bus := range []string{BusMaster, BusMusic, BusSFX, BusVoice} {
+		if v := m.Volume(bus); v != MaxBusGain {
+			t.Errorf("Volume(%s) = %v, want %v", bus, v, MaxBusGain)
+		}
+	}
+	if names := m.BusNames(); len(names) != 4 || names[0] != BusMaster || names[1] != BusMusic || names[2] != BusSFX || names[3] != BusVoice {
+		t.Errorf("BusNames() = %v", names)
+	}
+}
+
+func TestMixerVolume(t *testing.T) {
+	m := NewMixer()
+	if err := m.SetGain(BusMaster, 0.5); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.SetGain("Music", 0.8); err != nil {
+		t.Fatalf("bus names should be case-insensitive: %v", err)
+	}
+
+	if v := m.Volume(BusMusic); math.Abs(v-0.4) > 1e-9 {
+		t.Errorf("Volume(music) = %v, want 0.4 (master 0.5 * music 0.8)", v)
+	}
+	if v := m.Volume(BusSFX); v != 0.5 {
+		t.Errorf("Volume(sfx) = %v, want 0.5", v)
+	}
+
+	// Muting keeps the gain
+	_ = m.SetMuted(BusSFX, true)
+	if v := m.Volume(BusSFX); v != 0 {
+		t.Errorf("muted sfx volume = %v, want 0", v)
+	}
+	if g, _ := m.Gain(BusSFX); g != MaxBusGain {
+		t.Errorf("muting should keep the gain, got %v", g)
+	}
+	_ = m.SetMuted(BusSFX, false)
+
+	// Muting the master silences every bus
+	_ = m.SetMuted(BusMaster, true)
+	if m.Volume(BusMusic) != 0 || m.Volume(BusSFX) != 0 {
+		t.Error("muting master should silence every bus")
+	}
+	_ = m.SetMuted(BusMaster, false)
+
+	// A master fade applies to every bus
+	m.setFade(0.5)
+	if v := m.Volume(BusSFX); v != 0.25 {
+		t.Errorf("Volume(sfx) during fade = %v, want 0.25", v)
+	}
+}
+
+func TestMixerClampAndUnknownBus(t *testing.T) {
+	m := NewMixer()
+	_ = m.SetGain(BusMusic, 2)
+	if g, _ := m.Gain(BusMusic); g != MaxBusGain {
+		t.Errorf("gain should be clamped to %v, got %v", MaxBusGain, g)
+	}
+	_ = m.SetGain(BusMusic, -1)
+	if g, _ := m.Gain(BusMusic); g != MinBusGain {
+		t.Errorf("gain should be clamped to %v, got %v", MinBusGain, g)
+	}
+
+	if err := m.SetGain("ambience", 1); !errors.Is(err, ErrUnknownBus) {
+		t.Errorf("SetGain(ambience) = %v, want ErrUnknownBus", err)
+	}
+	if _, err := m.IsMuted("ambience"); !errors.Is(err, ErrUnknownBus) {
+		t.Errorf("IsMuted(ambience) = %v, want ErrUnknownBus", err)
+	}
+	if v := m.Volume("ambience"); v != 0 {
+		t.Errorf("Volume(ambience) = %v, want 0", v)
+	}
+}
+
+func TestMixerDuck(t *testing.T) {
+	m := NewMixer()
+	_ = m.SetGain(BusMusic, 0.8)
+	m.setDuck(0.5)
+
+	// Ducking applies only to the music bus
+	if v := m.Volume(BusMusic); math.Abs(v-0.4) > 1e-9 {
+		t.Errorf("ducked Volume(music) = %v, want 0.4", v)
+	}
+	if m.Volume(BusSFX) != MaxBusGain || m.Volume(BusVoice) != MaxBusGain || m.Volume(BusMaster) != MaxBusGain {
+		t.Error("ducking should not change the other buses")
+	}
+	if g, _ := m.Gain(BusMusic); g != 0.8 {
+		t.Errorf("ducking should keep the gain, got %v", g)
+	}
+	m.setDuck(1)
+	if v := m.Volume(BusMusic); v != 0.8 {
+		t.Errorf("Volume(music) after ducking = %v, want 0.8", v)
+	}
+}
@@ -0,0 +1,191 @@
+// Package audio provides audio-related components for the FILLY virtual machine.
+// This file implements NullOutput, an offline audio output for tests.
+package audio
+
+import (
+	"encoding/binary"
+	"io"
+	"math"
+	"sync"
+	"time"
+)
+
+// NullOutput is an audio output without a sound device.
+// Time does not pass by itself: Advance reads the given amount of audio from
+// every playing player and returns the mix, so tests of the AudioSystem and
+// of MIDI_TIME generation run deterministically in CI without a sound card or
+// an Ebitengine audio context.
+type NullOutput struct {
+	players []*NullPlayer
+	mu      sync.Mutex
+}
+
+// NewNullOutput creates a NullOutput.
+func NewNullOutput() *NullOutput {
+	return &NullOutput{}
+}
+
+// NewPlayer creates a paused player reading from src.
+func (o *NullOutput) NewPlayer(src io.Reader) (OutputPlayer, error) {
+	p := &NullPlayer{src: src, volume: 1}
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.players = append(o.players, p)
+	return p, nil
+}
+
+// DrainTime returns 0: nothing is buffered, so a stream is heard completely
+// by the time it has been read.
+func (o *NullOutput) DrainTime() time.Duration {
+	return 0
+}
+
+// Players returns the players that have not been closed, in creation order.
+func (o *NullOutput) Players() []*NullPlayer {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.removeClosed()
+	return append([]*NullPlayer(nil), o.players...)
+}
+
+// Advance plays d of audio: every playing player reads d worth of frames and the
+// frames are mixed with the players' volumes. It returns the mix as interleaved
+// stereo samples (left, right, left, ...), clipped to the int16 range.
+// A player whose stream ends stops playing, like an Ebitengine player.
+func (o *NullOutput) Advance(d time.Duration) []int16 {
+	return o.advanceFrames(int(d * SampleRate / time.Second))
+}
+
+// advanceFrames plays the given number of frames (see Advance).
+func (o *NullOutput) advanceFrames(frames int) []int16 {
+	if frames <= 0 {
+		return nil
+	}
+
+	o.mu.Lock()
+	o.removeClosed()
+	players := append([]*NullPlayer(nil), o.players...)
+	o.mu.Unlock()
+
+	mix := make([]float64, frames*2)
+	buf := make([]byte, frames*renderBytesPerFrame)
+	for _, p := range players {
+		p.read(buf, mix)
+	}
+
+	out := make([]int16, len(mix))
+	for i, v := range mix {
+		out[i] = int16(max(math.MinInt16, min(math.MaxInt16, math.Round(v))))
+	}
+	return out
+}
+
+// removeClosed drops the closed players.
+// Must be called with o.mu held.
+func (o *NullOutput) removeClosed() {
+	open := o.players[:0]
+	for _, p := range o.players {
+		if !p.isClosed() {
+			open = append(open, p)
+		}
+	}
+	clear(o.players[len(open):])
+	o.players = open
+}
+
+// NullPlayer is a player of a NullOutput.
+type NullPlayer struct {
+	src     io.Reader
+	playing bool
+	closed  bool
+	volume  float64
+	frames  int64 // frames read from src
+	mu      sync.Mutex
+}
+
+// read reads len(buf) bytes of frames from the stream if the player is playing
+// and adds them to mix with the player's volume.
+func (p *NullPlayer) read(buf []byte, mix []float64) {
+	p.mu.Lock()
+	playing, volume := p.playing, p.volume
+	p.mu.Unlock()
+	if !playing {
+		return
+	}
+
+	// The stream is read without the lock, as an Ebitengine player reads it
+	// from its own goroutine
+	n, err := io.ReadFull(p.src, buf)
+	frames := n / renderBytesPerFrame
+	for i := range frames * 2 {
+		sample := int16(binary.LittleEndian.Uint16(buf[i*2:]))
+		mix[i] += float64(sample) * volume
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.frames += int64(frames)
+	if err != nil {
+		p.playing = false
+	}
+}
+
+// Play starts or resumes reading the stream on Advance.
+func (p *NullPlayer) Play() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if !p.closed {
+		p.playing = true
+	}
+}
+
+// Pause stops reading the stream until Play is called.
+func (p *NullPlayer) Pause() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.playing = false
+}
+
+// IsPlaying returns whether the player reads the stream on Advance.
+func (p *NullPlayer) IsPlaying() bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.playing
+}
+
+// SetVolume sets the volume (0-1) applied when the stream is mixed.
+func (p *NullPlayer) SetVolume(volume float64) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.volume = volume
+}
+
+// Volume returns the volume set by SetVolume.
+func (p *NullPlayer) Volume() float64 {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.volume
+}
+
+// Position returns how much of the stream has been played.
+func (p *NullPlayer) Position() time.Duration {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return time.Duration(p.frames) * time.Second / SampleRate
+}
+
+// Close stops the player; it is removed from the output on the next Advance.
+func (p *NullPlayer) Close() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.playing = false
+	p.closed = true
+	return nil
+}
+
+// isClosed returns whether Close has been called.
+func (p *NullPlayer) isClosed() bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.closed
+}
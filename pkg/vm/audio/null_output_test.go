@@ -0,0 +1,221 @@
+// Package audio provides audio-related components for the FILLY virtual machine.
+// This file contains tests for NullOutput and for the players running on it.
+package audio
+
+import (
+	"bytes"
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/zurustar/son-et/pkg/vm"
+)
+
+// pcmFrames returns n stereo frames of constant left and right samples.
+func pcmFrames(n int, left, right int16) []byte {
+	buf := make([]byte, n*renderBytesPerFrame)
+	for i := range n {
+		binary.LittleEndian.PutUint16(buf[i*4:], uint16(left))
+		binary.LittleEndian.PutUint16(buf[i*4+2:], uint16(right))
+	}
+	return buf
+}
+
+// TestNullOutputMixesPlayingPlayers tests that Advance mixes the playing players with their volumes.
+func TestNullOutputMixesPlayingPlayers(t *testing.T) {
+	out := NewNullOutput()
+	a, _ := out.NewPlayer(bytes.NewReader(pcmFrames(SampleRate, 1000, -1000)))
+	b, _ := out.NewPlayer(bytes.NewReader(pcmFrames(SampleRate, 400, 400)))
+	paused, _ := out.NewPlayer(bytes.NewReader(pcmFrames(SampleRate, 9999, 9999)))
+	a.Play()
+	b.Play()
+	b.SetVolume(0.5)
+
+	mix := out.Advance(10 * time.Millisecond)
+	if len(mix) != SampleRate/100*2 {
+		t.Fatalf("len(mix) = %d, want %d", len(mix), SampleRate/100*2)
+	}
+	if mix[0] != 1200 || mix[1] != -800 {
+		t.Errorf("first frame = (%d, %d), want (1200, -800)", mix[0], mix[1])
+	}
+	if got := a.Position(); got != 10*time.Millisecond {
+		t.Errorf("playing position = %v, want 10ms", got)
+	}
+	if got := paused.Position(); got != 0 {
+		t.Errorf("paused position = %v, want 0", got)
+	}
+}
+
+// TestNullOutputClipsMix tests that the mix is clipped to the int16 range.
+func TestNullOutputClipsMix(t *testing.T) {
+	out := NewNullOutput()
+	for range 2 {
+		p, _ := out.NewPlayer(bytes.NewReader(pcmFrames(100, 30000, -30000)))
+		p.Play()
+	}
+	mix := out.Advance(time.Millisecond)
+	if mix[0] != 32767 || mix[1] != -32768 {
+		t.Errorf("first frame = (%d, %d), want (32767, -32768)", mix[0], mix[1])
+	}
+}
+
+// TestNullOutputStreamEnd tests that a player stops at the end of its stream
+// and that closed players are removed.
+func TestNullOutputStreamEnd(t *testing.T) {
+	out := NewNullOutput()
+	short, _ := out.NewPlayer(bytes.NewReader(pcmFrames(SampleRate/100, 100, 100)))
+	long, _ := out.NewPlayer(bytes.NewReader(pcmFrames(SampleRate, 100, 100)))
+	short.Play()
+	long.Play()
+
+	mix := out.Advance(20 * time.Millisecond)
+	if short.IsPlaying() {
+		t.Error("player should stop at the end of its stream")
+	}
+	if got := short.Position(); got != 10*time.Millisecond {
+		t.Errorf("position at the end = %v, want 10ms", got)
+	}
+	if mix[0] != 200 || mix[len(mix)-1] != 100 {
+		t.Errorf("mix = %d ... %d, want 200 ... 100", mix[0], mix[len(mix)-1])
+	}
+
+	long.Close()
+	if long.IsPlaying() {
+		t.Error("closed player should not be playing")
+	}
+	long.Play()
+	if long.IsPlaying() {
+		t.Error("closed player should not start again")
+	}
+	if got := len(out.Players()); got != 1 {
+		t.Errorf("players = %d, want 1 after Close", got)
+	}
+}
+
+// TestWAVPlayerNullOutput tests WAV playback on a NullOutput.
+func TestWAVPlayerNullOutput(t *testing.T) {
+	var wavData bytes.Buffer
+	pcm := pcmFrames(SampleRate/10, 1234, -1234)
+	if err := writeWAVHeader(&wavData, int64(len(pcm))); err != nil {
+		t.Fatal(err)
+	}
+	wavData.Write(pcm)
+	path := filepath.Join(t.TempDir(), "beep.wav")
+	if err := os.WriteFile(path, wavData.Bytes(), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	out := NewNullOutput()
+	wp := newWAVPlayer(out)
+	if err := wp.Play(path); err != nil {
+		t.Fatalf("Play failed: %v", err)
+	}
+	wp.SetVolume(0.5)
+
+	mix := out.Advance(50 * time.Millisecond)
+	if mix[0] != 617 || mix[1] != -617 {
+		t.Errorf("first frame = (%d, %d), want (617, -617)", mix[0], mix[1])
+	}
+	if got := wp.GetActivePlayerCount(); got != 1 {
+		t.Errorf("active players = %d, want 1", got)
+	}
+
+	out.Advance(100 * time.Millisecond)
+	if got := wp.GetActivePlayerCount(); got != 0 {
+		t.Errorf("active players after the end = %d, want 0", got)
+	}
+}
+
+// TestAudioSystemNullOutputMIDIEvents tests MIDI_TIME and MIDI_END generation driven by a NullOutput.
+func TestAudioSystemNullOutputMIDIEvents(t *testing.T) {
+	soundFontPath := findSoundFont(t)
+
+	// 480 PPQ at 120 BPM: a FILLY tick (16th note) is 125ms; the file is one beat long
+	data := buildMIDIHeader(480)
+	data = append(data, buildMIDITrack([]byte{
+		0x00, 0xFF, 0x51, 0x03, 0x07, 0xA1, 0x20, // tempo 500000us/beat
+		0x00, 0x90, 60, 100, // NoteOn
+		0x83, 0x60, 0x80, 60, 0, // NoteOff after 480 ticks
+		0x00, 0xFF, 0x2F, 0x00, // end of track
+	})...)
+	midiPath := filepath.Join(t.TempDir(), "beat.mid")
+	if err := os.WriteFile(midiPath, data, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	eventQueue := vm.NewEventQueue()
+	out := NewNullOutput()
+	as, err := NewAudioSystemWithOutput(soundFontPath, eventQueue, out, nil)
+	if err != nil {
+		t.Fatalf("NewAudioSystemWithOutput failed: %v", err)
+	}
+	defer as.Shutdown()
+	if err := as.PlayMIDI(midiPath); err != nil {
+		t.Fatalf("PlayMIDI failed: %v", err)
+	}
+
+	// No audio has been played yet, so no tick has passed
+	as.Update()
+	if eventQueue.HasType(vm.EventMIDI_TIME) {
+		t.Error("MIDI_TIME pushed before any audio was played")
+	}
+
+	// Half a beat: ticks 1 and 2
+	out.Advance(250 * time.Millisecond)
+	as.Update()
+	if got := countEvents(eventQueue, vm.EventMIDI_TIME); got != 2 {
+		t.Errorf("MIDI_TIME events after 250ms = %d, want 2", got)
+	}
+
+	// Past the end: MIDI_END is pushed once the drain (none for NullOutput) is over
+	out.Advance(time.Second)
+	as.Update()
+	time.Sleep(time.Millisecond)
+	as.Update()
+	if !eventQueue.HasType(vm.EventMIDI_END) {
+		t.Error("MIDI_END not pushed after the end of the file")
+	}
+	if as.IsMIDIPlaying() {
+		t.Error("MIDI should not be playing after MIDI_END")
+	}
+}
+
+// countEvents pops all events and counts those of the given type.
+func countEvents(eventQueue *vm.EventQueue, eventType vm.EventType) int {
+	count := 0
+	for {
+		event, ok := eventQueue.Pop()
+		if !ok {
+			return count
+		}
+		if event.Type == eventType {
+			count++
+		}
+	}
+}
+
+// bufferSizeOutput is a NullOutput that records the buffer size set on it.
+type bufferSizeOutput struct {
+	*NullOutput
+	bufferSize time.Duration
+}
+
+func (o *bufferSizeOutput) SetBufferSize(d time.Duration) {
+	o.bufferSize = d
+}
+
+// TestAudioSystemSetBufferSize tests that the buffer size is passed to outputs
+// that support it and ignored by the others.
+func TestAudioSystemSetBufferSize(t *testing.T) {
+	out := &bufferSizeOutput{NullOutput: NewNullOutput()}
+	as := &AudioSystem{output: out}
+	as.SetBufferSize(250 * time.Millisecond)
+	if out.bufferSize != 250*time.Millisecond {
+		t.Errorf("buffer size = %v, want 250ms", out.bufferSize)
+	}
+
+	// NullOutput has no buffer
+	(&AudioSystem{output: NewNullOutput()}).SetBufferSize(time.Second)
+}
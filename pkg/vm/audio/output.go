@@ -0,0 +1,51 @@
+// Package audio provides audio-related components for the FILLY virtual machine.
+// This file defines the audio output used by the players, so that the
+// Ebitengine audio context can be replaced by NullOutput in tests.
+package audio
+
+import (
+	"io"
+	"time"
+)
+
+// Output creates the players that send PCM audio (16-bit little-endian stereo
+// at SampleRate) to a device. All the players of an AudioSystem share one Output,
+// which mixes them.
+type Output interface {
+	// NewPlayer creates a paused player reading from src.
+	NewPlayer(src io.Reader) (OutputPlayer, error)
+	// DrainTime returns how long audio read from a stream may take to be heard
+	// (MIDI_END is pushed this long after the MIDI sequence ends).
+	DrainTime() time.Duration
+}
+
+// bufferedOutput is implemented by outputs whose players can buffer more audio
+// ahead of the device. A larger buffer wakes the synthesizer and the decoders less
+// often, at the cost of a longer delay for volume changes and pauses.
+type bufferedOutput interface {
+	// SetBufferSize sets the buffer of the players created from now on
+	// (0 = the default of the output).
+	SetBufferSize(d time.Duration)
+}
+
+// SetBufferSize sets how much audio the players started from now on buffer ahead
+// of the device (--power-save), if the output supports it (0 = the default).
+// Call it before playing anything: players already created keep their buffer.
+func (as *AudioSystem) SetBufferSize(d time.Duration) {
+	as.mu.RLock()
+	defer as.mu.RUnlock()
+	if o, ok := as.output.(bufferedOutput); ok {
+		o.SetBufferSize(d)
+	}
+}
+
+// OutputPlayer plays one stream on an Output (implemented by *audio.Player).
+type OutputPlayer interface {
+	Play()
+	Pause()
+	IsPlaying() bool
+	SetVolume(volume float64)
+	// Position returns how much of the stream has been played.
+	Position() time.Duration
+	Close() error
+}
@@ -0,0 +1,206 @@
+//go:build !nogpu
+
+// Package audio provides audio-related components for the FILLY virtual machine.
+// This file plays the audio on an Ebitengine audio context. It is left out of the
+// nogpu build, which runs without Ebitengine (see output_nogpu.go).
+package audio
+
+import (
+	"bytes"
+	"io"
+	"sync/atomic"
+	"time"
+
+	"github.com/hajimehoshi/ebiten/v2/audio"
+	"github.com/hajimehoshi/ebiten/v2/audio/wav"
+	"github.com/zurustar/son-et/pkg/fileutil"
+	"github.com/zurustar/son-et/pkg/vm"
+)
+
+// NewDefaultOutput returns the output of an AudioSystem that is not given an
+// audio context: a new Ebitengine audio context, which plays on the sound device.
+// Only one Ebitengine audio context can be created in a process.
+func NewDefaultOutput() Output {
+	return newEbitenOutput(nil)
+}
+
+// decodeWAV decodes a WAV file to 16-bit little-endian stereo PCM at SampleRate.
+func decodeWAV(data []byte) (io.Reader, error) {
+	return wav.DecodeWithSampleRate(SampleRate, bytes.NewReader(data))
+}
+
+// ebitenDrainTime is how long audio already handed to Ebitengine may still be
+// heard after a stream ends (typical audio buffers are 200-500ms).
+const ebitenDrainTime = 1 * time.Second
+
+// ebitenOutput plays audio with an Ebitengine audio context.
+type ebitenOutput struct {
+	ctx        *audio.Context
+	bufferSize atomic.Int64 // buffer of new players (time.Duration, 0 = Ebitengine's default)
+}
+
+// newEbitenOutput returns an Output for ctx, creating the audio context if ctx is nil.
+func newEbitenOutput(ctx *audio.Context) *ebitenOutput {
+	if ctx == nil {
+		ctx = audio.NewContext(SampleRate)
+	}
+	return &ebitenOutput{ctx: ctx}
+}
+
+// NewPlayer creates an Ebitengine audio player reading from src.
+func (o *ebitenOutput) NewPlayer(src io.Reader) (OutputPlayer, error) {
+	player, err := o.ctx.NewPlayer(src)
+	if err != nil {
+		return nil, err
+	}
+	if d := time.Duration(o.bufferSize.Load()); d > 0 {
+		player.SetBufferSize(d)
+	}
+	return player, nil
+}
+
+// SetBufferSize sets the buffer of the players created from now on.
+func (o *ebitenOutput) SetBufferSize(d time.Duration) {
+	o.bufferSize.Store(int64(max(d, 0)))
+}
+
+// DrainTime returns the time Ebitengine's audio buffer takes to be heard.
+func (o *ebitenOutput) DrainTime() time.Duration {
+	return ebitenDrainTime
+}
+
+// audioContextOf returns the Ebitengine audio context of an output, or nil if
+// the output does not use one (e.g. NullOutput).
+func audioContextOf(output Output) *audio.Context {
+	if o, ok := output.(*ebitenOutput); ok {
+		return o.ctx
+	}
+	return nil
+}
+
+// NewAudioSystem creates a new AudioSystem with the specified SoundFont and event queue.
+// The AudioSystem creates and shares a single Ebitengine audio context among all components.
+//
+// Design: NewAudioSystem(soundFontPath string, eventSystem *EventSystem) (*AudioSystem, error)
+//
+// Parameters:
+//   - soundFontPath: Path to the SoundFont (.sf2) file for MIDI playback
+//   - eventQueue: Event queue for TIME and MIDI_TIME events
+//
+// Returns:
+//   - *AudioSystem: The initialized AudioSystem
+//   - error: Error if initialization fails (e.g., SoundFont not found)
+func NewAudioSystem(soundFontPath string, eventQueue *vm.EventQueue) (*AudioSystem, error) {
+	// Create shared audio context
+	// All audio components share this context for automatic mixing
+	audioCtx := audio.NewContext(SampleRate)
+	return NewAudioSystemWithContext(soundFontPath, eventQueue, audioCtx)
+}
+
+// NewAudioSystemWithContext creates a new AudioSystem with an existing audio context.
+// This is useful for testing or when the audio context is managed externally.
+//
+// Parameters:
+//   - soundFontPath: Path to the SoundFont (.sf2) file for MIDI playback
+//   - eventQueue: Event queue for TIME and MIDI_TIME events
+//   - audioCtx: Existing Ebitengine audio context to use
+//
+// Returns:
+//   - *AudioSystem: The initialized AudioSystem
+//   - error: Error if initialization fails (e.g., SoundFont not found)
+func NewAudioSystemWithContext(soundFontPath string, eventQueue *vm.EventQueue, audioCtx *audio.Context) (*AudioSystem, error) {
+	return NewAudioSystemWithFS(soundFontPath, eventQueue, audioCtx, nil)
+}
+
+// NewAudioSystemWithFS creates a new AudioSystem with FileSystem support.
+// This allows loading SoundFont from embedded file system.
+//
+// Parameters:
+//   - soundFontPath: Path to the SoundFont (.sf2) file for MIDI playback
+//   - eventQueue: Event queue for TIME and MIDI_TIME events
+//   - audioCtx: Existing Ebitengine audio context to use (can be nil)
+//   - soundFontFS: FileSystem for loading SoundFont (can be nil for regular file system)
+//
+// Returns:
+//   - *AudioSystem: The initialized AudioSystem
+//   - error: Error if initialization fails (e.g., SoundFont not found)
+func NewAudioSystemWithFS(soundFontPath string, eventQueue *vm.EventQueue, audioCtx *audio.Context, soundFontFS fileutil.FileSystem) (*AudioSystem, error) {
+	// Create audio context if not provided
+	ownsAudioCtx := audioCtx == nil
+	as, err := NewAudioSystemWithOutput(soundFontPath, eventQueue, newEbitenOutput(audioCtx), soundFontFS)
+	if err != nil {
+		return nil, err
+	}
+	as.ownsAudioCtx = ownsAudioCtx
+	return as, nil
+}
+
+// GetAudioContext returns the shared audio context.
+// This is useful for testing and debugging.
+// It returns nil when the AudioSystem plays on an output without one (NullOutput).
+func (as *AudioSystem) GetAudioContext() *audio.Context {
+	as.mu.RLock()
+	defer as.mu.RUnlock()
+	return audioContextOf(as.output)
+}
+
+// NewMIDIPlayer creates a new MIDI player with the specified SoundFont.
+// This is a convenience wrapper around NewMIDIPlayerWithFS with nil FileSystem.
+//
+// Requirement 4.9: When SoundFont file is provided, system uses it for MIDI synthesis.
+// Requirement 4.10: When SoundFont is not provided, system reports error.
+//
+// Parameters:
+//   - soundFontPath: Path to the SoundFont (.sf2) file
+//   - audioCtx: Ebitengine audio context (can be nil, will be created if needed)
+//   - eventQueue: Event queue for MIDI_TIME events (can be nil for basic playback)
+//
+// Returns:
+//   - *MIDIPlayer: The initialized MIDI player
+//   - error: Error if SoundFont cannot be loaded
+func NewMIDIPlayer(soundFontPath string, audioCtx *audio.Context, eventQueue *vm.EventQueue) (*MIDIPlayer, error) {
+	// Requirement 2.2: Backward compatibility - delegate to NewMIDIPlayerWithFS with nil fs
+	return NewMIDIPlayerWithFS(soundFontPath, audioCtx, eventQueue, nil)
+}
+
+// NewMIDIPlayerWithFS creates a new MIDI player with FileSystem support.
+// This allows loading SoundFont from embedded file system.
+//
+// Requirement 2.1: SoundFont_Loader SHALL load the SF2 file through the FileSystem interface.
+// Requirement 2.3: When a FileSystem is set, FileSystem.ReadFile is used.
+// Requirement 4.9: When SoundFont file is provided, system uses it for MIDI synthesis.
+// Requirement 4.10: When SoundFont is not provided, system reports error.
+//
+// Parameters:
+//   - soundFontPath: Path to the SoundFont (.sf2) file
+//   - audioCtx: Ebitengine audio context (can be nil, will be created if needed)
+//   - eventQueue: Event queue for MIDI_TIME events (can be nil for basic playback)
+//   - fs: FileSystem interface for loading SoundFont (can be nil for regular file system)
+//
+// Returns:
+//   - *MIDIPlayer: The initialized MIDI player
+//   - error: Error if SoundFont cannot be loaded
+func NewMIDIPlayerWithFS(soundFontPath string, audioCtx *audio.Context, eventQueue *vm.EventQueue, fs fileutil.FileSystem) (*MIDIPlayer, error) {
+	return newMIDIPlayer(soundFontPath, newEbitenOutput(audioCtx), eventQueue, fs)
+}
+
+// NewWAVPlayer creates a new WAV player with the specified audio context.
+// The audio context should be shared with other audio components (e.g., MIDI player)
+// to enable automatic mixing by Ebitengine/audio.
+//
+// Parameters:
+//   - audioCtx: Ebitengine audio context (can be nil, will be created if needed)
+//
+// Returns:
+//   - *WAVPlayer: The initialized WAV player
+func NewWAVPlayer(audioCtx *audio.Context) *WAVPlayer {
+	// Create audio context if not provided
+	return newWAVPlayer(newEbitenOutput(audioCtx))
+}
+
+// GetAudioContext returns the audio context used by this player.
+// This can be used to share the context with other audio components.
+// It returns nil when the player does not play on Ebitengine/audio (NullOutput).
+func (wp *WAVPlayer) GetAudioContext() *audio.Context {
+	return audioContextOf(wp.output)
+}
@@ -0,0 +1,18 @@
+//go:build !nogpu
+
+// Package audio provides audio-related components for the FILLY virtual machine.
+// This file contains tests for the Ebitengine audio output.
+package audio
+
+import "testing"
+
+// TestAudioContextOfNullOutput tests that players on a NullOutput have no audio context.
+func TestAudioContextOfNullOutput(t *testing.T) {
+	out := NewNullOutput()
+	if newWAVPlayer(out).GetAudioContext() != nil {
+		t.Error("a NullOutput player should not have an audio context")
+	}
+	if audioContextOf(out) != nil {
+		t.Error("a NullOutput should not have an audio context")
+	}
+}
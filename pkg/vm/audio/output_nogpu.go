@@ -0,0 +1,26 @@
+//go:build nogpu
+
+// Package audio provides audio-related components for the FILLY virtual machine.
+// This file provides the audio output of the nogpu build, which runs without
+// Ebitengine: the audio is played on a ClockOutput and not heard.
+package audio
+
+import (
+	"bytes"
+	"io"
+)
+
+// NewDefaultOutput returns the output of an AudioSystem in the nogpu build:
+// a ClockOutput, which plays the audio in real time without a sound device.
+func NewDefaultOutput() Output {
+	return NewClockOutput()
+}
+
+// decodeWAV decodes a WAV file to 16-bit little-endian stereo PCM at SampleRate.
+func decodeWAV(data []byte) (io.Reader, error) {
+	pcm, err := decodePCMWAV(data)
+	if err != nil {
+		return nil, err
+	}
+	return bytes.NewReader(pcm), nil
+}
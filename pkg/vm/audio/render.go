@@ -0,0 +1,177 @@
+// Package audio provides audio-related components for the FILLY virtual machine.
+// This file implements offline MIDI rendering (bounce to WAV file).
+package audio
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/sinshu/go-meltysynth/meltysynth"
+)
+
+// RenderTailDuration is the silence-padded tail appended after the last MIDI event
+// so that note releases and reverb can decay naturally.
+const RenderTailDuration = 2 * time.Second
+
+// WAV output format (16-bit stereo PCM at SampleRate)
+const (
+	renderChannels      = 2
+	renderBitsPerSample = 16
+	renderBytesPerFrame = renderChannels * renderBitsPerSample / 8
+	wavHeaderSize       = 44
+	wavFormatPCM        = 1
+	wavFmtChunkSize     = 16
+)
+
+// RenderProgress is called while rendering with the current FILLY tick (16th note units)
+// and the amount of audio rendered so far.
+type RenderProgress func(fillyTick int, rendered, total time.Duration)
+
+// RenderMIDI synthesizes an entire MIDI file offline (faster than real time) and writes it
+// as a 16-bit stereo PCM WAV to w. No audio device is used.
+//
+// The sequencer output is pulled through the same MIDIStream used for live playback,
+// and the tempo map drives a TickCalculator so that progress is reported in the
+// same FILLY ticks that MIDI_TIME events use.
+//
+// Parameters:
+//   - soundFont: SoundFont used for synthesis
+//   - midiData: Standard MIDI File contents
+//   - w: Destination for the WAV data
+//   - progress: Optional progress callback (can be nil)
+//
+// Returns:
+//   - time.Duration: Length of the rendered audio (including the tail)
+//   - error: Error if the MIDI file is invalid or writing fails
+func RenderMIDI(soundFont *meltysynth.SoundFont, midiData []byte, w io.Writer, progress RenderProgress) (time.Duration, error) {
+	return renderMIDI(soundFont, DefaultSynthQuality(), nil, midiData, w, progress)
+}
+
+// renderMIDI is RenderMIDI with the synthesizer quality settings and remapping table.
+// It uses the same sequencer as live playback, so the rendered audio matches what PlayMIDI plays.
+func renderMIDI(soundFont *meltysynth.SoundFont, quality SynthQuality, remap *MIDIRemap, midiData []byte, w io.Writer, progress RenderProgress) (time.Duration, error) {
+	if soundFont == nil {
+		return 0, ErrNoSoundFont
+	}
+
+	midi, err := meltysynth.NewMidiFile(bytes.NewReader(midiData))
+	if err != nil {
+		return 0, fmt.Errorf("%w: %v", ErrMIDIInvalidFormat, err)
+	}
+
+	synth, err := newSynthesizer(soundFont, quality)
+	if err != nil {
+		return 0, err
+	}
+
+	tempoMap, ppq := ParseMIDITempoMap(midiData)
+	tickCalc := NewTickCalculator(ppq, tempoMap)
+	sequencer := newTempoSequencer(synth, remap.apply(ParseMIDIMessages(midiData)), tickCalc)
+
+	total := midi.GetLength() + RenderTailDuration
+	totalSamples := int64(total.Seconds() * SampleRate)
+	dataSize := totalSamples * renderBytesPerFrame
+
+	if err := writeWAVHeader(w, dataSize); err != nil {
+		return 0, fmt.Errorf("failed to write WAV header: %w", err)
+	}
+
+	reader := &renderProgressReader{
+		stream:   &MIDIStream{sequencer: sequencer},
+		tickCalc: tickCalc,
+		total:    total,
+		progress: progress,
+	}
+	if _, err := io.CopyN(w, reader, dataSize); err != nil {
+		return 0, fmt.Errorf("failed to write WAV data: %w", err)
+	}
+
+	return total, nil
+}
+
+// renderProgressReader wraps a MIDIStream and reports rendering progress.
+type renderProgressReader struct {
+	stream   *MIDIStream
+	tickCalc *TickCalculator
+	total    time.Duration
+	progress RenderProgress
+}
+
+// Read implements io.Reader.
+func (r *renderProgressReader) Read(p []byte) (int, error) {
+	// MIDIStream renders whole stereo frames only
+	p = p[:len(p)/renderBytesPerFrame*renderBytesPerFrame]
+	n, err := r.stream.Read(p)
+
+	if r.progress != nil {
+		samples := r.stream.GetSampleCount()
+		rendered := time.Duration(samples) * time.Second / SampleRate
+		r.progress(r.tickCalc.FillyTickFromSamples(samples), rendered, r.total)
+	}
+	return n, err
+}
+
+// writeWAVHeader writes a canonical 44-byte RIFF/WAVE header for 16-bit stereo PCM.
+func writeWAVHeader(w io.Writer, dataSize int64) error {
+	header := make([]byte, wavHeaderSize)
+	copy(header[0:], "RIFF")
+	binary.LittleEndian.PutUint32(header[4:], uint32(wavHeaderSize-8+dataSize))
+	copy(header[8:], "WAVE")
+	copy(header[12:], "fmt ")
+	binary.LittleEndian.PutUint32(header[16:], wavFmtChunkSize)
+	binary.LittleEndian.PutUint16(header[20:], wavFormatPCM)
+	binary.LittleEndian.PutUint16(header[22:], renderChannels)
+	binary.LittleEndian.PutUint32(header[24:], SampleRate)
+	binary.LittleEndian.PutUint32(header[28:], SampleRate*renderBytesPerFrame)
+	binary.LittleEndian.PutUint16(header[32:], renderBytesPerFrame)
+	binary.LittleEndian.PutUint16(header[34:], renderBitsPerSample)
+	copy(header[36:], "data")
+	binary.LittleEndian.PutUint32(header[40:], uint32(dataSize))
+
+	_, err := w.Write(header)
+	return err
+}
+
+// RenderMIDI renders the specified MIDI file offline using this AudioSystem's SoundFont,
+// synthesizer quality, remapping table and file system. Playback state is not affected.
+//
+// Parameters:
+//   - filename: Path to the MIDI file (resolved like PlayMIDI)
+//   - w: Destination for the WAV data
+//   - progress: Optional progress callback (can be nil)
+//
+// Returns:
+//   - time.Duration: Length of the rendered audio
+//   - error: Error if the file cannot be read or rendered
+func (as *AudioSystem) RenderMIDI(filename string, w io.Writer, progress RenderProgress) (time.Duration, error) {
+	as.mu.RLock()
+	midiPlayer := as.midiPlayer
+	fs := as.fs
+	as.mu.RUnlock()
+
+	if midiPlayer == nil {
+		return 0, ErrNoSoundFont
+	}
+
+	path := filename
+	if fs != nil {
+		path = extractFilename(filename)
+	}
+	midiData, err := ReadFileFS(fs, path)
+	if err != nil {
+		return 0, fmt.Errorf("%w: %s", ErrMIDIFileNotFound, filename)
+	}
+
+	midiPlayer.mu.RLock()
+	remap := midiPlayer.remap
+	strict := midiPlayer.strict
+	midiPlayer.mu.RUnlock()
+	midiData, err = checkMIDI(filename, midiData, strict)
+	if err != nil {
+		return 0, err
+	}
+	return renderMIDI(midiPlayer.soundFont, midiPlayer.SynthQuality(), remap, midiData, w, progress)
+}
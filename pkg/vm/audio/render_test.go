@@ -0,0 +1,168 @@
+//go:build !nogpu
+
+// Package audio provides audio-related components for the FILLY virtual machine.
+// This file contains tests for offline MIDI rendering.
+package audio
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/zurustar/son-et/pkg/vm"
+)
+
+// Note: findSoundFont, findMIDIFile, and getSharedAudioContext are defined in midi_test.go
+
+// TestWriteWAVHeader tests the generated RIFF/WAVE header fields.
+func TestWriteWAVHeader(t *testing.T) {
+	var buf bytes.Buffer
+	const dataSize = 1000 * renderBytesPerFrame
+	if err := writeWAVHeader(&buf, dataSize); err != nil {
+		t.Fatalf("writeWAVHeader failed: %v", err)
+	}
+
+	header := buf.Bytes()
+	if len(header) != wavHeaderSize {
+		t.Fatalf("header size = %d, expected %d", len(header), wavHeaderSize)
+	}
+	if string(header[0:4]) != "RIFF" || string(header[8:12]) != "WAVE" || string(header[36:40]) != "data" {
+		t.Errorf("unexpected chunk IDs: %q", header)
+	}
+	if got := binary.LittleEndian.Uint32(header[4:]); got != wavHeaderSize-8+dataSize {
+		t.Errorf("RIFF size = %d, expected %d", got, wavHeaderSize-8+dataSize)
+	}
+	if got := binary.LittleEndian.Uint16(header[22:]); got != renderChannels {
+		t.Errorf("channels = %d, expected %d", got, renderChannels)
+	}
+	if got := binary.LittleEndian.Uint32(header[24:]); got != SampleRate {
+		t.Errorf("sample rate = %d, expected %d", got, SampleRate)
+	}
+	if got := binary.LittleEndian.Uint32(header[40:]); got != dataSize {
+		t.Errorf("data size = %d, expected %d", got, dataSize)
+	}
+}
+
+// TestRenderMIDINoSoundFont tests that rendering without a SoundFont fails.
+func TestRenderMIDINoSoundFont(t *testing.T) {
+	var buf bytes.Buffer
+	if _, err := RenderMIDI(nil, []byte{}, &buf, nil); !errors.Is(err, ErrNoSoundFont) {
+		t.Errorf("expected ErrNoSoundFont, got %v", err)
+	}
+}
+
+// TestRenderMIDI tests that an entire MIDI file is rendered to WAV with progress reports.
+func TestRenderMIDI(t *testing.T) {
+	soundFontPath := findSoundFont(t)
+	midiPath := findMIDIFile(t)
+
+	soundFont, err := LoadSoundFontFS(nil, soundFontPath)
+	if err != nil {
+		t.Fatalf("LoadSoundFontFS failed: %v", err)
+	}
+	midiData, err := os.ReadFile(midiPath)
+	if err != nil {
+		t.Fatalf("failed to read MIDI file: %v", err)
+	}
+
+	lastTick := -1
+	calls := 0
+	var buf bytes.Buffer
+	start := time.Now()
+	length, err := RenderMIDI(soundFont, midiData, &buf, func(fillyTick int, rendered, total time.Duration) {
+		calls++
+		if fillyTick < lastTick {
+			t.Errorf("tick went backwards: %d -> %d", lastTick, fillyTick)
+		}
+		lastTick = fillyTick
+		if rendered > total {
+			t.Errorf("rendered %v exceeds total %v", rendered, total)
+		}
+	})
+	if err != nil {
+		t.Fatalf("RenderMIDI failed: %v", err)
+	}
+
+	if length <= RenderTailDuration {
+		t.Errorf("rendered length %v should include the piece and the tail", length)
+	}
+	if calls == 0 {
+		t.Error("progress should be reported")
+	}
+
+	expectedData := int64(length.Seconds()*SampleRate) * renderBytesPerFrame
+	if int64(buf.Len()) != wavHeaderSize+expectedData {
+		t.Errorf("output size = %d, expected %d", buf.Len(), wavHeaderSize+expectedData)
+	}
+
+	// オフラインレンダリングは実時間より速いこと
+	if elapsed := time.Since(start); elapsed >= length {
+		t.Errorf("offline render took %v for %v of audio", elapsed, length)
+	}
+}
+
+// TestRenderMIDIInvalidData tests that invalid MIDI data is reported.
+func TestRenderMIDIInvalidData(t *testing.T) {
+	soundFontPath := findSoundFont(t)
+	soundFont, err := LoadSoundFontFS(nil, soundFontPath)
+	if err != nil {
+		t.Fatalf("LoadSoundFontFS failed: %v", err)
+	}
+
+	var buf bytes.Buffer
+	if _, err := RenderMIDI(soundFont, []byte("not a midi file"), &buf, nil); !errors.Is(err, ErrMIDIInvalidFormat) {
+		t.Errorf("expected ErrMIDIInvalidFormat, got %v", err)
+	}
+}
+
+// TestAudioSystemRenderMIDIMissingFile tests rendering a file that does not exist.
+func TestAudioSystemRenderMIDIMissingFile(t *testing.T) {
+	soundFontPath := findSoundFont(t)
+	as, err := NewAudioSystemWithContext(soundFontPath, vm.NewEventQueue(), getSharedAudioContext())
+	if err != nil {
+		t.Fatalf("NewAudioSystemWithContext failed: %v", err)
+	}
+	defer as.Shutdown()
+
+	var buf bytes.Buffer
+	if _, err := as.RenderMIDI("/nonexistent/song.mid", &buf, nil); !errors.Is(err, ErrMIDIFileNotFound) {
+		t.Errorf("expected ErrMIDIFileNotFound, got %v", err)
+	}
+}
+
+// TestAudioSystemSetOnPlayMIDI tests that the PlayMIDI callback receives the filename.
+func TestAudioSystemSetOnPlayMIDI(t *testing.T) {
+	soundFontPath := findSoundFont(t)
+	midiPath := findMIDIFile(t)
+
+	as, err := NewAudioSystemWithContext(soundFontPath, vm.NewEventQueue(), getSharedAudioContext())
+	if err != nil {
+		t.Fatalf("NewAudioSystemWithContext failed: %v", err)
+	}
+	defer as.Shutdown()
+
+	var played []string
+	as.SetOnPlayMIDI(func(filename string) {
+		played = append(played, filename)
+	})
+	as.SetMuted(true)
+
+	if err := as.PlayMIDI(midiPath); err != nil {
+		t.Fatalf("PlayMIDI failed: %v", err)
+	}
+	if len(played) != 1 || played[0] != midiPath {
+		t.Errorf("callback received %v, expected [%s]", played, midiPath)
+	}
+
+	// コールバックを解除すると呼ばれない
+	as.SetOnPlayMIDI(nil)
+	if err := as.PlayMIDI(midiPath); err != nil {
+		t.Fatalf("PlayMIDI failed: %v", err)
+	}
+	if len(played) != 1 {
+		t.Errorf("callback should not be called after removal, got %v", played)
+	}
+}
@@ -130,3 +130,74 @@ func findTestSoundFont(t *testing.T) string {
 
 	return ""
 }
+
+// findSoundFont finds the SoundFont file in the project.
+func findSoundFont(t *testing.T) string {
+	t.Helper()
+
+	// Try common locations
+	paths := []string{
+		"../../../GeneralUser-GS.sf2",
+		"../../GeneralUser-GS.sf2",
+		"GeneralUser-GS.sf2",
+	}
+
+	for _, p := range paths {
+		absPath, err := filepath.Abs(p)
+		if err != nil {
+			continue
+		}
+		if _, err := os.Stat(absPath); err == nil {
+			return absPath
+		}
+	}
+
+	t.Skip("SoundFont file not found")
+	return ""
+}
+
+// findMIDIFile finds a MIDI file in the samples directory.
+func findMIDIFile(t *testing.T) string {
+	t.Helper()
+
+	// Try to find a MIDI file in samples
+	sampleDirs := []string{
+		"../../../samples",
+		"../../samples",
+		"samples",
+	}
+
+	for _, dir := range sampleDirs {
+		absDir, err := filepath.Abs(dir)
+		if err != nil {
+			continue
+		}
+
+		// Walk the directory to find .mid files
+		err = filepath.Walk(absDir, func(path string, info os.FileInfo, err error) error {
+			if err != nil {
+				return nil
+			}
+			if !info.IsDir() {
+				ext := filepath.Ext(path)
+				if ext == ".mid" || ext == ".MID" {
+					return filepath.SkipAll
+				}
+			}
+			return nil
+		})
+
+		// Try to find any .mid file
+		matches, _ := filepath.Glob(filepath.Join(absDir, "*", "*.mid"))
+		if len(matches) > 0 {
+			return matches[0]
+		}
+		matches, _ = filepath.Glob(filepath.Join(absDir, "*", "*.MID"))
+		if len(matches) > 0 {
+			return matches[0]
+		}
+	}
+
+	t.Skip("MIDI file not found in samples")
+	return ""
+}
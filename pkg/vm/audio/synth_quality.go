@@ -0,0 +1,99 @@
+package audio
+
+import (
+	"fmt"
+
+	"github.com/sinshu/go-meltysynth/meltysynth"
+)
+
+// Synthesizer quality lets low-power devices trade sound quality for CPU time:
+// the reverb and chorus effects and the number of voices that can sound at once are
+// the largest costs of rendering MIDI. go-meltysynth switches reverb and chorus
+// together and always interpolates samples linearly, so those are the only knobs.
+
+// Limits of the polyphony accepted by SetSynthQuality (the range go-meltysynth accepts).
+const (
+	MinSynthPolyphony     = 8
+	MaxSynthPolyphony     = 256
+	DefaultSynthPolyphony = 64
+)
+
+// SynthQuality holds the quality settings of the MIDI synthesizer.
+type SynthQuality struct {
+	Effects   bool // reverb and chorus
+	Polyphony int  // maximum number of voices sounding at once (older voices are cut)
+}
+
+// DefaultSynthQuality returns the settings used when nothing is configured (full quality).
+func DefaultSynthQuality() SynthQuality {
+	return SynthQuality{Effects: true, Polyphony: DefaultSynthPolyphony}
+}
+
+// clamped returns q with the polyphony kept within the accepted range.
+func (q SynthQuality) clamped() SynthQuality {
+	q.Polyphony = max(MinSynthPolyphony, min(MaxSynthPolyphony, q.Polyphony))
+	return q
+}
+
+// newSynthesizer creates a synthesizer for the SoundFont with the quality settings.
+func newSynthesizer(soundFont *meltysynth.SoundFont, q SynthQuality) (*meltysynth.Synthesizer, error) {
+	settings := meltysynth.NewSynthesizerSettings(SampleRate)
+	settings.EnableReverbAndChorus = q.Effects
+	settings.MaximumPolyphony = int32(q.Polyphony)
+	synth, err := meltysynth.NewSynthesizer(soundFont, settings)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create synthesizer: %w", err)
+	}
+	return synth, nil
+}
+
+// SetSynthQuality replaces the synthesizer of the player with one using the settings.
+// The MIDI file that is playing keeps its synthesizer; the settings apply from the next Play.
+func (mp *MIDIPlayer) SetSynthQuality(q SynthQuality) error {
+	mp.mu.Lock()
+	defer mp.mu.Unlock()
+
+	synth, err := newSynthesizer(mp.soundFont, q)
+	if err != nil {
+		return err
+	}
+	mp.synth = synth
+	mp.quality = q
+	return nil
+}
+
+// SetSynthQuality sets the synthesizer quality of all MIDI ports and of offline rendering.
+// A polyphony of 0 or less keeps the current polyphony; other values are clamped to
+// MinSynthPolyphony-MaxSynthPolyphony. The applied polyphony is returned.
+// MIDI files that are already playing change from their next PlayMIDI.
+func (as *AudioSystem) SetSynthQuality(effects bool, polyphony int) (int, error) {
+	as.mu.Lock()
+	defer as.mu.Unlock()
+
+	if as.midiPlayer == nil {
+		return 0, ErrNoSoundFont
+	}
+	q := SynthQuality{Effects: effects, Polyphony: polyphony}
+	if polyphony <= 0 {
+		q.Polyphony = as.midiPlayer.SynthQuality().Polyphony
+	}
+	q = q.clamped()
+
+	if err := as.midiPlayer.SetSynthQuality(q); err != nil {
+		return 0, err
+	}
+	var portErr error
+	as.eachPort(func(mp *MIDIPlayer) {
+		if err := mp.SetSynthQuality(q); err != nil && portErr == nil {
+			portErr = err
+		}
+	})
+	return q.Polyphony, portErr
+}
+
+// SynthQuality returns the synthesizer quality settings of the player.
+func (mp *MIDIPlayer) SynthQuality() SynthQuality {
+	mp.mu.RLock()
+	defer mp.mu.RUnlock()
+	return mp.quality
+}
@@ -0,0 +1,79 @@
+//go:build !nogpu
+
+package audio
+
+import (
+	"testing"
+
+	"github.com/zurustar/son-et/pkg/vm"
+)
+
+func TestSynthQualityClamped(t *testing.T) {
+	tests := []struct {
+		polyphony int
+		want      int
+	}{
+		{1, MinSynthPolyphony},
+		{24, 24},
+		{1000, MaxSynthPolyphony},
+	}
+	for _, tt := range tests {
+		if got := (SynthQuality{Polyphony: tt.polyphony}).clamped().Polyphony; got != tt.want {
+			t.Errorf("clamped polyphony of %d = %d, want %d", tt.polyphony, got, tt.want)
+		}
+	}
+}
+
+func TestAudioSystemSetSynthQuality(t *testing.T) {
+	soundFontPath := findSoundFont(t)
+	midiPath := findMIDIFile(t)
+
+	as, err := NewAudioSystemWithContext(soundFontPath, vm.NewEventQueue(), getSharedAudioContext())
+	if err != nil {
+		t.Fatalf("NewAudioSystemWithContext failed: %v", err)
+	}
+	defer as.Shutdown()
+	as.SetMuted(true)
+
+	if got := as.midiPlayer.SynthQuality(); got != DefaultSynthQuality() {
+		t.Errorf("initial quality = %+v, want %+v", got, DefaultSynthQuality())
+	}
+	if err := as.PlayMIDIPort("jingle", midiPath); err != nil {
+		t.Fatalf("PlayMIDIPort failed: %v", err)
+	}
+	playing := as.ports["jingle"].sequencer.synth
+
+	polyphony, err := as.SetSynthQuality(false, 24)
+	if err != nil || polyphony != 24 {
+		t.Fatalf("SetSynthQuality(false, 24) = (%d, %v), want (24, nil)", polyphony, err)
+	}
+	want := SynthQuality{Effects: false, Polyphony: 24}
+	for name, mp := range map[string]*MIDIPlayer{"main": as.midiPlayer, "jingle": as.ports["jingle"]} {
+		if got := mp.SynthQuality(); got != want {
+			t.Errorf("%s quality = %+v, want %+v", name, got, want)
+		}
+		if mp.synth.MaximumPolyphony != 24 || mp.synth.EnableReverbAndChorus {
+			t.Errorf("%s synthesizer was not recreated with the settings", name)
+		}
+	}
+	// A file that is playing keeps its original synthesizer
+	if as.ports["jingle"].sequencer.synth != playing {
+		t.Error("expected the playing file to keep its synthesizer")
+	}
+
+	// 0 keeps the polyphony and out-of-range values are clamped
+	if polyphony, _ := as.SetSynthQuality(true, 0); polyphony != 24 {
+		t.Errorf("SetSynthQuality(true, 0) = %d, want 24", polyphony)
+	}
+	if polyphony, _ := as.SetSynthQuality(true, 1000); polyphony != MaxSynthPolyphony {
+		t.Errorf("SetSynthQuality(true, 1000) = %d, want %d", polyphony, MaxSynthPolyphony)
+	}
+
+	// New ports take over the settings
+	if err := as.PlayMIDIPort("sfx", midiPath); err != nil {
+		t.Fatalf("PlayMIDIPort failed: %v", err)
+	}
+	if got := as.ports["sfx"].SynthQuality(); got != (SynthQuality{Effects: true, Polyphony: MaxSynthPolyphony}) {
+		t.Errorf("new port quality = %+v", got)
+	}
+}
@@ -13,6 +13,21 @@ import (
 // Requirement 3.3: System provides default timer interval of 50 milliseconds.
 const DefaultTimerInterval = 50 * time.Millisecond
 
+// SyncClock is a clock shared by several son-et instances (netsync.Master or netsync.Follower).
+// While a Timer follows a SyncClock, TIME event n fires when the shared clock reaches n*interval,
+// so every instance generates the same TIME event at the same moment.
+type SyncClock interface {
+	// Elapsed returns the shared clock; ok is false until it has been synchronized.
+	Elapsed() (elapsed time.Duration, ok bool)
+}
+
+// syncPollPeriod は共有時計に合わせるときに時計を確認する間隔（TIMEイベントの時刻の誤差の上限）
+const syncPollPeriod = 5 * time.Millisecond
+
+// maxSyncCatchUp は共有時計に遅れたときに一度に発生させるTIMEイベントの上限
+// 一時停止などでこれより遅れた分は発生させずに捨てる
+const maxSyncCatchUp = 20
+
 // Timer generates periodic TIME events for the event system.
 // It runs in a separate goroutine and pushes TIME events to the event queue
 // at regular intervals.
@@ -32,8 +47,11 @@ type Timer struct {
 	// Requirement 3.4: When TIME event is generated, system adds it to event queue.
 	eventQueue *vm.EventQueue
 
-	// ticker is the underlying time.Ticker for periodic events.
-	ticker *time.Ticker
+	// ticker is the underlying ticker for periodic events.
+	ticker vm.Ticker
+
+	// source is the clock of the ticker (vm.RealClock unless WithTimerClock is given).
+	source vm.Clock
 
 	// running indicates whether the timer is currently running.
 	running bool
@@ -44,10 +62,50 @@ type Timer struct {
 	// doneCh is used to signal that the timer goroutine has stopped.
 	doneCh chan struct{}
 
+	// paused indicates whether TIME event generation is suspended.
+	// While paused, elapsed time is not accumulated, so no burst of
+	// catch-up events is generated on resume.
+	paused bool
+
+	// lastTick is the time of the most recent TIME event (or of Start/Resume).
+	lastTick time.Time
+
+	// remaining is the part of the interval that was left when the timer was paused.
+	remaining time.Duration
+
+	// resumed is true until the first tick after Resume (or SetScale), which fires
+	// after the remaining partial interval instead of a full one.
+	resumed bool
+
+	// scale is the time scale (slow motion / fast forward).
+	// TIME events are generated every interval/scale.
+	scale float64
+
+	// clock is the shared clock of synchronized playback (nil to run on the local ticker).
+	// The time scale does not apply while following a shared clock.
+	clock SyncClock
+
+	// syncCount is the number of intervals of the shared clock already turned into TIME events.
+	// syncAligned is false until the first tick after the clock is synchronized, which sets
+	// syncCount to the current count without generating the events missed before joining.
+	syncCount   int64
+	syncAligned bool
+
 	// mu protects the timer state.
 	mu sync.Mutex
 }
 
+// TimerOption is a functional option for configuring a Timer.
+type TimerOption func(*Timer)
+
+// WithTimerClock sets the clock of the timer's ticker.
+// Tests pass a vmtest.Clock to generate TIME events without waiting.
+func WithTimerClock(clock vm.Clock) TimerOption {
+	return func(t *Timer) {
+		t.source = clock
+	}
+}
+
 // NewTimer creates a new Timer with the specified interval and event queue.
 // If interval is 0 or negative, the default interval (50ms) is used.
 //
@@ -56,21 +114,28 @@ type Timer struct {
 // Parameters:
 //   - interval: The duration between TIME events (use 0 for default 50ms)
 //   - eventQueue: The event queue to push TIME events to
+//   - opts: Optional configuration options (clock)
 //
 // Returns:
 //   - *Timer: The initialized Timer instance
-func NewTimer(interval time.Duration, eventQueue *vm.EventQueue) *Timer {
+func NewTimer(interval time.Duration, eventQueue *vm.EventQueue, opts ...TimerOption) *Timer {
 	if interval <= 0 {
 		interval = DefaultTimerInterval
 	}
 
-	return &Timer{
+	t := &Timer{
 		interval:   interval,
+		scale:      1,
 		eventQueue: eventQueue,
 		ticker:     nil,
+		source:     vm.RealClock,
 		running:    false,
 		stopCh:     nil,
 	}
+	for _, opt := range opts {
+		opt(t)
+	}
+	return t
 }
 
 // Start starts the timer, generating TIME events at the configured interval.
@@ -90,9 +155,12 @@ func (t *Timer) Start() {
 	}
 
 	t.running = true
+	t.paused = false
+	t.resumed = false
 	t.stopCh = make(chan struct{})
 	t.doneCh = make(chan struct{})
-	t.ticker = time.NewTicker(t.interval)
+	t.ticker = t.source.NewTicker(t.period())
+	t.lastTick = t.source.Now()
 
 	// Start the timer goroutine
 	// Requirement 3.6: System maintains accurate timing even when handler execution takes time.
@@ -108,18 +176,80 @@ func (t *Timer) run() {
 		select {
 		case <-t.stopCh:
 			return
-		case _, ok := <-t.ticker.C:
+		case now, ok := <-t.ticker.C():
 			if !ok {
 				return
 			}
 			// Generate TIME event
 			// Requirement 3.1: System generates TIME events periodically.
 			// Requirement 3.4: When TIME event is generated, system adds it to event queue.
-			t.generateTimeEvent()
+			for range t.onTick(now) {
+				t.generateTimeEvent()
+			}
 		}
 	}
 }
 
+// onTick updates the tick bookkeeping for a tick delivered at now and returns the
+// number of TIME events to generate. A tick that slips through while paused is dropped.
+func (t *Timer) onTick(now time.Time) int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.paused {
+		return 0
+	}
+	if t.clock != nil {
+		return t.syncedTicks()
+	}
+
+	t.lastTick = now
+	if t.resumed {
+		// The first tick after Resume used the remaining partial interval;
+		// go back to the regular interval from here.
+		t.resumed = false
+		if t.ticker != nil {
+			t.ticker.Reset(t.period())
+		}
+	}
+	return 1
+}
+
+// syncedTicks returns the number of TIME events the shared clock has passed since the last tick.
+// Must be called with t.mu held.
+func (t *Timer) syncedTicks() int {
+	elapsed, ok := t.clock.Elapsed()
+	if !ok {
+		// マスターと同期するまではTIMEイベントを発生させない
+		return 0
+	}
+	count := int64(elapsed / t.interval)
+	if !t.syncAligned {
+		t.syncAligned = true
+		t.syncCount = count
+		return 0
+	}
+	n := count - t.syncCount
+	if n <= 0 {
+		return 0
+	}
+	t.syncCount = count
+	return int(min(n, maxSyncCatchUp))
+}
+
+// period returns the wall-clock time between TIME events (interval/scale),
+// or between checks of the shared clock while following one.
+// Must be called with t.mu held.
+func (t *Timer) period() time.Duration {
+	if t.clock != nil {
+		return syncPollPeriod
+	}
+	if t.scale <= 0 {
+		return t.interval
+	}
+	return time.Duration(float64(t.interval) / t.scale)
+}
+
 // generateTimeEvent creates and pushes a TIME event to the event queue.
 //
 // Requirement 3.4: When TIME event is generated, system adds it to event queue.
@@ -201,11 +331,136 @@ func (t *Timer) SetInterval(interval time.Duration) {
 		t.Stop()
 		t.mu.Lock()
 		t.interval = interval
+		t.syncAligned = false
 		t.mu.Unlock()
 		t.Start()
 	} else {
 		t.mu.Lock()
 		t.interval = interval
+		t.syncAligned = false
 		t.mu.Unlock()
 	}
 }
+
+// SetClock makes the timer follow a shared clock for synchronized playback
+// (nil returns to the local ticker). The first TIME event fires at the next
+// multiple of the interval on the shared clock; no events are generated until
+// the clock is synchronized. If the timer is running, it is restarted.
+func (t *Timer) SetClock(clock SyncClock) {
+	t.mu.Lock()
+	wasRunning := t.running
+	t.mu.Unlock()
+
+	if wasRunning {
+		t.Stop()
+	}
+	t.mu.Lock()
+	t.clock = clock
+	t.syncAligned = false
+	t.mu.Unlock()
+	if wasRunning {
+		t.Start()
+	}
+}
+
+// Pause suspends TIME event generation without stopping the timer.
+// The time elapsed since the last TIME event is remembered, so that after
+// Resume the next event fires after the remaining part of the interval.
+// Time spent paused is not accumulated and causes no extra events.
+// If the timer is not running or already paused, this method does nothing.
+func (t *Timer) Pause() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if !t.running || t.paused {
+		return
+	}
+
+	t.paused = true
+	if t.ticker != nil {
+		t.ticker.Stop()
+	}
+
+	period := t.period()
+	t.remaining = period - t.source.Now().Sub(t.lastTick)
+	if t.remaining <= 0 {
+		t.remaining = time.Nanosecond
+	}
+	if t.remaining > period {
+		t.remaining = period
+	}
+}
+
+// Resume restarts TIME event generation after Pause.
+// If the timer is not paused, this method does nothing.
+func (t *Timer) Resume() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if !t.running || !t.paused {
+		return
+	}
+
+	t.paused = false
+	t.resumed = true
+	// Shift lastTick so that time spent paused is not counted as elapsed.
+	t.lastTick = t.source.Now().Add(t.remaining - t.period())
+	if t.ticker != nil {
+		t.ticker.Reset(t.remaining)
+	}
+}
+
+// IsPaused returns whether TIME event generation is currently paused.
+func (t *Timer) IsPaused() bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.paused
+}
+
+// GetScale returns the current time scale (1 = normal speed).
+func (t *Timer) GetScale() float64 {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.scale
+}
+
+// SetScale sets the time scale: TIME events are generated every interval/scale,
+// so 2 doubles the rate (fast forward) and 0.5 halves it (slow motion).
+// The interval set by SetInterval is kept. If the timer is running, the next
+// TIME event fires after the remaining part of the current interval at the new scale.
+// A scale of 0 or less is treated as 1.
+func (t *Timer) SetScale(scale float64) {
+	if scale <= 0 {
+		scale = 1
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if scale == t.scale {
+		return
+	}
+	// 経過した割合を保ったまま新しい周期に切り替える
+	oldPeriod := t.period()
+	t.scale = scale
+	if !t.running {
+		return
+	}
+	if t.paused {
+		t.remaining = time.Duration(float64(t.remaining) * float64(t.period()) / float64(oldPeriod))
+		return
+	}
+	remaining := oldPeriod - t.source.Now().Sub(t.lastTick)
+	if remaining < 0 {
+		remaining = 0
+	}
+	remaining = time.Duration(float64(remaining) * float64(t.period()) / float64(oldPeriod))
+	if remaining <= 0 {
+		remaining = time.Nanosecond
+	}
+	t.lastTick = t.source.Now().Add(remaining - t.period())
+	t.resumed = true
+	if t.ticker != nil {
+		t.ticker.Reset(remaining)
+	}
+}
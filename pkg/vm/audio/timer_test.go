@@ -1,12 +1,52 @@
 package audio
 
 import (
+	"sync"
 	"testing"
 	"time"
 
 	"github.com/zurustar/son-et/pkg/vm"
+	"github.com/zurustar/son-et/pkg/vm/vmtest"
 )
 
+// newClockTimer creates a Timer driven by a simulated clock.
+func newClockTimer(interval time.Duration) (*Timer, *vm.EventQueue, *vmtest.Clock) {
+	clock := vmtest.NewClock(time.Unix(0, 0))
+	eventQueue := vm.NewEventQueue()
+	return NewTimer(interval, eventQueue, WithTimerClock(clock)), eventQueue, clock
+}
+
+// waitForEvents waits until the timer goroutine has queued the events for the ticks
+// already delivered by Advance, and checks that there are exactly want of them.
+// It cannot tell that no more events are coming, so use expectNoEvents for want == 0.
+func waitForEvents(t *testing.T, eventQueue *vm.EventQueue, want int) {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for eventQueue.Len() < want && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	if got := eventQueue.Len(); got != want {
+		t.Fatalf("expected %d events, got %d", want, got)
+	}
+}
+
+// quietTicks is the number of tick periods expectNoEvents lets pass.
+const quietTicks = 5
+
+// expectNoEvents advances the clock by quietTicks periods of the timer's ticker and
+// checks that no events were queued for them. Advance sends a tick only after the
+// timer goroutine has received the previous one, that is, after it has finished
+// processing the tick before that, so one more period makes sure every tick of the
+// quiet span has been processed. A stopped (paused) ticker receives no ticks at all.
+func expectNoEvents(t *testing.T, eventQueue *vm.EventQueue, clock *vmtest.Clock, period time.Duration) {
+	t.Helper()
+	clock.Advance(quietTicks * period)
+	clock.Advance(period)
+	if got := eventQueue.Len(); got != 0 {
+		t.Fatalf("expected no events, got %d", got)
+	}
+}
+
 // TestNewTimer tests the Timer constructor.
 func TestNewTimer(t *testing.T) {
 	eventQueue := vm.NewEventQueue()
@@ -100,25 +140,16 @@ func TestTimerStartStop(t *testing.T) {
 // Requirement 3.1: System generates TIME events periodically.
 // Requirement 3.4: When TIME event is generated, system adds it to event queue.
 func TestTimerGeneratesEvents(t *testing.T) {
-	eventQueue := vm.NewEventQueue()
-	interval := 20 * time.Millisecond
-	timer := NewTimer(interval, eventQueue)
+	timer, eventQueue, clock := newClockTimer(20 * time.Millisecond)
 
-	// Start the timer
+	// Start the timer and let 75ms pass on the simulated clock
 	timer.Start()
-
-	// Wait for some events to be generated
-	time.Sleep(75 * time.Millisecond)
+	clock.Advance(75 * time.Millisecond)
+	waitForEvents(t, eventQueue, 3)
 
 	// Stop the timer
 	timer.Stop()
 
-	// Check that events were generated
-	eventCount := eventQueue.Len()
-	if eventCount < 2 {
-		t.Errorf("expected at least 2 events, got %d", eventCount)
-	}
-
 	// Verify event types
 	for eventQueue.Len() > 0 {
 		event, ok := eventQueue.Pop()
@@ -208,23 +239,18 @@ func TestDefaultTimerInterval(t *testing.T) {
 // TestTimerAccurateTiming tests that the timer maintains accurate timing.
 // Requirement 3.6: System maintains accurate timing even when handler execution takes time.
 func TestTimerAccurateTiming(t *testing.T) {
-	eventQueue := vm.NewEventQueue()
-	interval := 20 * time.Millisecond
-	timer := NewTimer(interval, eventQueue)
+	timer, eventQueue, clock := newClockTimer(20 * time.Millisecond)
 
 	timer.Start()
+	defer timer.Stop()
 
-	// Wait for multiple events
-	time.Sleep(110 * time.Millisecond)
+	// 110ms / 20ms = 5.5: exactly 5 events, however long the handlers take
+	clock.Advance(110 * time.Millisecond)
+	waitForEvents(t, eventQueue, 5)
 
-	timer.Stop()
-
-	// We should have approximately 5 events (110ms / 20ms = 5.5)
-	// Allow some tolerance for timing variations
-	eventCount := eventQueue.Len()
-	if eventCount < 4 || eventCount > 7 {
-		t.Errorf("expected approximately 5 events, got %d", eventCount)
-	}
+	// The next event fires at 120ms, not 110ms + 20ms
+	clock.Advance(10 * time.Millisecond)
+	waitForEvents(t, eventQueue, 6)
 }
 
 // TestTimerConcurrentAccess tests that the timer is safe for concurrent access.
@@ -275,3 +301,207 @@ func TestTimerConcurrentAccess(t *testing.T) {
 	// Clean up
 	timer.Stop()
 }
+
+// TestTimerPauseResume tests that no TIME events are generated while paused.
+func TestTimerPauseResume(t *testing.T) {
+	timer, eventQueue, clock := newClockTimer(20 * time.Millisecond)
+
+	timer.Start()
+	defer timer.Stop()
+
+	// Events at 20ms and 40ms, then pause at 50ms with 10ms of the interval left
+	clock.Advance(50 * time.Millisecond)
+	waitForEvents(t, eventQueue, 2)
+	timer.Pause()
+	if !timer.IsPaused() {
+		t.Fatal("timer should be paused after Pause()")
+	}
+	if !timer.IsRunning() {
+		t.Error("paused timer should still be running")
+	}
+
+	// Wait for several intervals while paused
+	clock.Advance(100 * time.Millisecond)
+	waitForEvents(t, eventQueue, 2)
+
+	timer.Resume()
+	if timer.IsPaused() {
+		t.Error("timer should not be paused after Resume()")
+	}
+
+	// The remaining 10ms, then the regular interval again
+	clock.Advance(10 * time.Millisecond)
+	waitForEvents(t, eventQueue, 3)
+	clock.Advance(20 * time.Millisecond)
+	waitForEvents(t, eventQueue, 4)
+}
+
+// TestTimerResumeNoTickJump tests that time spent paused does not produce catch-up events.
+// Requirement 3.6: System maintains accurate timing even when handler execution takes time.
+func TestTimerResumeNoTickJump(t *testing.T) {
+	interval := 20 * time.Millisecond
+	timer, eventQueue, clock := newClockTimer(interval)
+
+	timer.Start()
+	defer timer.Stop()
+
+	timer.Pause()
+	expectNoEvents(t, eventQueue, clock, interval)
+	timer.Resume()
+
+	// Right after resume no event fires; the first one comes after the remaining interval.
+	// No tick is due within half an interval, so nothing can be left to process.
+	clock.Advance(interval / 2)
+	if got := eventQueue.Len(); got != 0 {
+		t.Fatalf("expected no events before the remaining interval, got %d", got)
+	}
+	clock.Advance(interval / 2)
+	waitForEvents(t, eventQueue, 1)
+}
+
+// TestTimerPauseWhenStopped tests that Pause/Resume on a stopped timer do nothing.
+func TestTimerPauseWhenStopped(t *testing.T) {
+	timer := NewTimer(10*time.Millisecond, vm.NewEventQueue())
+
+	timer.Pause()
+	if timer.IsPaused() {
+		t.Error("stopped timer should not become paused")
+	}
+	timer.Resume()
+	if timer.IsRunning() {
+		t.Error("Resume should not start a stopped timer")
+	}
+}
+
+// TestTimerSetScale tests that the time scale changes the TIME event rate but not the interval.
+func TestTimerSetScale(t *testing.T) {
+	interval := 20 * time.Millisecond
+
+	for _, tt := range []struct {
+		scale float64
+		want  int
+	}{
+		{1, 10},
+		{4, 40},  // 5ms ごと
+		{0.5, 5}, // 40ms ごと
+	} {
+		timer, eventQueue, clock := newClockTimer(interval)
+		timer.SetScale(tt.scale)
+		timer.Start()
+		clock.Advance(200 * time.Millisecond)
+		waitForEvents(t, eventQueue, tt.want)
+		timer.Stop()
+	}
+
+	t.Run("interval is kept", func(t *testing.T) {
+		timer := NewTimer(interval, vm.NewEventQueue())
+		timer.SetScale(2)
+		if timer.GetInterval() != interval || timer.GetScale() != 2 {
+			t.Errorf("expected interval %v and scale 2, got %v and %v", interval, timer.GetInterval(), timer.GetScale())
+		}
+		timer.SetScale(0)
+		if timer.GetScale() != 1 {
+			t.Errorf("expected scale 0 to be treated as 1, got %v", timer.GetScale())
+		}
+	})
+
+	t.Run("while running and paused", func(t *testing.T) {
+		timer, eventQueue, clock := newClockTimer(interval)
+		timer.Start()
+		defer timer.Stop()
+
+		// 一時停止中に4倍にすると、残りの20msは5msになる
+		timer.Pause()
+		timer.SetScale(4)
+		expectNoEvents(t, eventQueue, clock, interval/4)
+		timer.Resume()
+		clock.Advance(interval / 4)
+		waitForEvents(t, eventQueue, 1)
+		clock.Advance(interval)
+		waitForEvents(t, eventQueue, 5)
+	})
+}
+
+// fakeSyncClock is a SyncClock controlled by the test.
+type fakeSyncClock struct {
+	mu      sync.Mutex
+	elapsed time.Duration
+	synced  bool
+}
+
+func (c *fakeSyncClock) Elapsed() (time.Duration, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.elapsed, c.synced
+}
+
+func (c *fakeSyncClock) set(elapsed time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.elapsed = elapsed
+	c.synced = true
+}
+
+// TestTimerSyncClockTicks tests that TIME events follow the intervals of the shared clock.
+func TestTimerSyncClockTicks(t *testing.T) {
+	ms := time.Millisecond
+	clock := &fakeSyncClock{}
+	timer := NewTimer(50*ms, vm.NewEventQueue())
+	timer.SetClock(clock)
+
+	steps := []struct {
+		elapsed time.Duration
+		want    int
+	}{
+		{120 * ms, 0}, // 同期した時点で揃えるだけで、それまでの分は発生させない
+		{149 * ms, 0},
+		{150 * ms, 1},
+		{260 * ms, 2},
+		{250 * ms, 0}, // 時計が戻った場合は追いつくまで待つ
+		{300 * ms, 1},
+		{time.Hour, maxSyncCatchUp}, // 大きく遅れた分は捨てる
+		{time.Hour + 50*ms, 1},
+	}
+	if n := timer.onTick(time.Now()); n != 0 {
+		t.Fatalf("onTick before synchronization = %d, want 0", n)
+	}
+	for _, step := range steps {
+		clock.set(step.elapsed)
+		if n := timer.onTick(time.Now()); n != step.want {
+			t.Errorf("onTick at %v = %d, want %d", step.elapsed, n, step.want)
+		}
+	}
+
+	// 間隔を変えると、新しい間隔で揃え直す
+	timer.SetInterval(100 * ms)
+	if n := timer.onTick(time.Now()); n != 0 {
+		t.Errorf("onTick right after SetInterval = %d, want 0", n)
+	}
+	clock.set(time.Hour + 200*ms)
+	if n := timer.onTick(time.Now()); n != 2 {
+		t.Errorf("onTick after 2 new intervals = %d, want 2", n)
+	}
+}
+
+// TestTimerSyncClockRunning tests a running timer that follows a shared clock.
+func TestTimerSyncClockRunning(t *testing.T) {
+	timer, eventQueue, local := newClockTimer(20 * time.Millisecond)
+	clock := &fakeSyncClock{}
+	timer.Start()
+	defer timer.Stop()
+	timer.SetClock(clock)
+
+	expectNoEvents(t, eventQueue, local, syncPollPeriod)
+
+	// 同期した最初の確認で揃え、次の確認で5間隔分を発生させる
+	// （2回確認させると、1回目の確認は処理し終えている）
+	clock.set(10 * time.Millisecond)
+	local.Advance(2 * syncPollPeriod)
+	clock.set(110 * time.Millisecond)
+	local.Advance(syncPollPeriod)
+	waitForEvents(t, eventQueue, 5)
+
+	timer.SetClock(nil)
+	local.Advance(70 * time.Millisecond)
+	waitForEvents(t, eventQueue, 8)
+}
@@ -1,15 +1,12 @@
 // Package audio provides audio-related components for the FILLY virtual machine.
-// This file implements the WAV Player for WAV file playback using Ebitengine/audio.
+// This file implements the WAV Player for WAV file playback on the audio Output.
 package audio
 
 import (
-	"bytes"
 	"errors"
 	"fmt"
 	"sync"
 
-	"github.com/hajimehoshi/ebiten/v2/audio"
-	"github.com/hajimehoshi/ebiten/v2/audio/wav"
 	"github.com/zurustar/son-et/pkg/fileutil"
 )
 
@@ -32,42 +29,31 @@ var (
 // Requirement 5.3: System supports standard WAV file formats (PCM, 8-bit, 16-bit).
 // Requirement 5.6: System mixes multiple WAV streams into a single audio output.
 type WAVPlayer struct {
-	// Ebitengine/audio context (shared with MIDI player)
-	audioCtx *audio.Context
+	// Audio output (shared with MIDI player)
+	output Output
 
-	// Active players - Ebitengine/audio handles automatic mixing
+	// Active players - the output handles automatic mixing
 	// Requirement 5.6: System mixes multiple WAV streams into a single audio output.
-	players []*audio.Player
+	players []OutputPlayer
 
 	// File system interface for reading WAV files
 	fs fileutil.FileSystem
 
 	// State
-	muted bool
+	muted  bool
+	volume float64 // output volume set by the mixer's sfx bus (0-1)
 
 	// Mutex for thread-safe access
 	mu sync.Mutex
 }
 
-// NewWAVPlayer creates a new WAV player with the specified audio context.
-// The audio context should be shared with other audio components (e.g., MIDI player)
-// to enable automatic mixing by Ebitengine/audio.
-//
-// Parameters:
-//   - audioCtx: Ebitengine audio context (can be nil, will be created if needed)
-//
-// Returns:
-//   - *WAVPlayer: The initialized WAV player
-func NewWAVPlayer(audioCtx *audio.Context) *WAVPlayer {
-	// Create audio context if not provided
-	if audioCtx == nil {
-		audioCtx = audio.NewContext(SampleRate)
-	}
-
+// newWAVPlayer creates a WAV player playing on output.
+func newWAVPlayer(output Output) *WAVPlayer {
 	return &WAVPlayer{
-		audioCtx: audioCtx,
-		players:  make([]*audio.Player, 0),
-		muted:    false,
+		output:  output,
+		players: make([]OutputPlayer, 0),
+		muted:   false,
+		volume:  MaxBusGain,
 	}
 }
 
@@ -102,7 +88,7 @@ func (wp *WAVPlayer) Play(filename string) error {
 	// Decode WAV file
 	// Requirement 5.3: System supports standard WAV file formats (PCM, 8-bit, 16-bit).
 	// Requirement 5.5: When WAV file is corrupted, system logs error and continues execution.
-	stream, err := wav.DecodeWithSampleRate(SampleRate, bytes.NewReader(data))
+	stream, err := decodeWAV(data)
 	if err != nil {
 		return fmt.Errorf("%w: %v", ErrWAVInvalidFormat, err)
 	}
@@ -111,15 +97,13 @@ func (wp *WAVPlayer) Play(filename string) error {
 	// Requirement 5.2: When multiple PlayWAVE calls are made, system plays all WAV files simultaneously.
 	// Requirement 5.6: System mixes multiple WAV streams into a single audio output.
 	// Ebitengine/audio automatically mixes multiple players
-	player, err := wp.audioCtx.NewPlayer(stream)
+	player, err := wp.output.NewPlayer(stream)
 	if err != nil {
 		return fmt.Errorf("failed to create audio player: %w", err)
 	}
 
-	// Set volume based on muted state
-	if wp.muted {
-		player.SetVolume(0)
-	}
+	// Set volume based on muted state and the mixer
+	player.SetVolume(wp.outputVolume())
 
 	// Start playback
 	player.Play()
@@ -142,15 +126,34 @@ func (wp *WAVPlayer) SetMuted(muted bool) {
 	defer wp.mu.Unlock()
 
 	wp.muted = muted
+	wp.applyVolume()
+}
+
+// SetVolume sets the output volume (0-1) of current and future WAV playback,
+// normally the mixer's sfx bus volume. The volume is kept while muted.
+func (wp *WAVPlayer) SetVolume(volume float64) {
+	wp.mu.Lock()
+	defer wp.mu.Unlock()
+
+	wp.volume = volume
+	wp.applyVolume()
+}
 
-	// Update volume for all active players
+// outputVolume returns the volume to apply to the audio players.
+// Must be called with wp.mu held.
+func (wp *WAVPlayer) outputVolume() float64 {
+	if wp.muted {
+		return 0
+	}
+	return wp.volume
+}
+
+// applyVolume updates the volume of all active players.
+// Must be called with wp.mu held.
+func (wp *WAVPlayer) applyVolume() {
 	for _, player := range wp.players {
 		if player != nil {
-			if muted {
-				player.SetVolume(0)
-			} else {
-				player.SetVolume(1)
-			}
+			player.SetVolume(wp.outputVolume())
 		}
 	}
 }
@@ -172,7 +175,7 @@ func (wp *WAVPlayer) StopAll() {
 			player.Close()
 		}
 	}
-	wp.players = make([]*audio.Player, 0)
+	wp.players = make([]OutputPlayer, 0)
 }
 
 // GetActivePlayerCount returns the number of active WAV players.
@@ -190,7 +193,7 @@ func (wp *WAVPlayer) GetActivePlayerCount() int {
 // cleanupFinishedPlayers removes players that have finished playing.
 // Must be called with wp.mu held.
 func (wp *WAVPlayer) cleanupFinishedPlayers() {
-	activePlayers := make([]*audio.Player, 0, len(wp.players))
+	activePlayers := make([]OutputPlayer, 0, len(wp.players))
 	for _, player := range wp.players {
 		if player != nil && player.IsPlaying() {
 			activePlayers = append(activePlayers, player)
@@ -210,12 +213,6 @@ func (wp *WAVPlayer) Update() {
 	wp.cleanupFinishedPlayers()
 }
 
-// GetAudioContext returns the audio context used by this player.
-// This can be used to share the context with other audio components.
-func (wp *WAVPlayer) GetAudioContext() *audio.Context {
-	return wp.audioCtx
-}
-
 // SetFileSystem sets the file system interface for reading WAV files.
 // This allows the WAVPlayer to read files from embedded file systems.
 //
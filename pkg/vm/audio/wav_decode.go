@@ -0,0 +1,94 @@
+// Package audio provides audio-related components for the FILLY virtual machine.
+// This file implements a WAV decoder without Ebitengine, used by the nogpu build.
+package audio
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+)
+
+// RIFF/WAVE chunk layout
+const (
+	riffHeaderSize  = 12 // "RIFF", size, "WAVE"
+	chunkHeaderSize = 8  // chunk ID and size
+)
+
+// decodePCMWAV decodes an uncompressed 8-bit or 16-bit, mono or stereo PCM WAV file
+// to 16-bit little-endian stereo PCM at SampleRate.
+// Other sample rates are converted with linear interpolation.
+func decodePCMWAV(data []byte) ([]byte, error) {
+	if len(data) < riffHeaderSize || string(data[0:4]) != "RIFF" || string(data[8:12]) != "WAVE" {
+		return nil, errors.New("not a RIFF/WAVE file")
+	}
+
+	var format []byte
+	var samples []byte
+	for pos := riffHeaderSize; pos+chunkHeaderSize <= len(data); {
+		id := string(data[pos : pos+4])
+		size := int(binary.LittleEndian.Uint32(data[pos+4:]))
+		body := data[pos+chunkHeaderSize:]
+		// A truncated chunk is used as far as it was read
+		size = min(size, len(body))
+		switch id {
+		case "fmt ":
+			format = body[:size]
+		case "data":
+			samples = body[:size]
+		}
+		// Chunks are aligned to 2-byte boundaries
+		pos += chunkHeaderSize + size + size%2
+	}
+	if len(format) < wavFmtChunkSize {
+		return nil, errors.New("fmt chunk not found")
+	}
+	if samples == nil {
+		return nil, errors.New("data chunk not found")
+	}
+
+	tag := binary.LittleEndian.Uint16(format[0:])
+	channels := int(binary.LittleEndian.Uint16(format[2:]))
+	rate := int(binary.LittleEndian.Uint32(format[4:]))
+	bits := int(binary.LittleEndian.Uint16(format[14:]))
+	if tag != wavFormatPCM {
+		return nil, fmt.Errorf("unsupported format tag %d", tag)
+	}
+	if channels != 1 && channels != 2 {
+		return nil, fmt.Errorf("unsupported channel count %d", channels)
+	}
+	if bits != 8 && bits != 16 {
+		return nil, fmt.Errorf("unsupported bits per sample %d", bits)
+	}
+	if rate <= 0 {
+		return nil, fmt.Errorf("invalid sample rate %d", rate)
+	}
+
+	frameSize := channels * bits / 8
+	frames := len(samples) / frameSize
+	sample := func(frame, channel int) float64 {
+		offset := frame*frameSize + min(channel, channels-1)*bits/8
+		if bits == 8 {
+			// 8-bit PCM is unsigned (128 is silence)
+			return float64(int(samples[offset])-128) * 256
+		}
+		return float64(int16(binary.LittleEndian.Uint16(samples[offset:])))
+	}
+
+	outFrames := frames
+	if rate != SampleRate {
+		outFrames = int(int64(frames) * SampleRate / int64(rate))
+	}
+	out := make([]byte, outFrames*renderBytesPerFrame)
+	for i := range outFrames {
+		// Interpolate linearly between the source frames
+		pos := float64(i) * float64(rate) / SampleRate
+		frame := int(pos)
+		next := min(frame+1, frames-1)
+		frac := pos - float64(frame)
+		for ch := range renderChannels {
+			v := sample(frame, ch)*(1-frac) + sample(next, ch)*frac
+			binary.LittleEndian.PutUint16(out[i*renderBytesPerFrame+ch*2:], uint16(int16(v)))
+		}
+	}
+	return out, nil
+}
@@ -0,0 +1,108 @@
+// Package audio provides audio-related components for the FILLY virtual machine.
+// This file contains tests for the WAV decoder of the nogpu build.
+package audio
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+)
+
+// buildWAV returns a PCM WAV file with the given format and sample data.
+func buildWAV(channels, rate, bits int, samples []byte) []byte {
+	var buf bytes.Buffer
+	buf.WriteString("RIFF")
+	binary.Write(&buf, binary.LittleEndian, uint32(wavHeaderSize-8+len(samples)))
+	buf.WriteString("WAVEfmt ")
+	binary.Write(&buf, binary.LittleEndian, uint32(wavFmtChunkSize))
+	binary.Write(&buf, binary.LittleEndian, uint16(wavFormatPCM))
+	binary.Write(&buf, binary.LittleEndian, uint16(channels))
+	binary.Write(&buf, binary.LittleEndian, uint32(rate))
+	binary.Write(&buf, binary.LittleEndian, uint32(rate*channels*bits/8))
+	binary.Write(&buf, binary.LittleEndian, uint16(channels*bits/8))
+	binary.Write(&buf, binary.LittleEndian, uint16(bits))
+	buf.WriteString("data")
+	binary.Write(&buf, binary.LittleEndian, uint32(len(samples)))
+	buf.Write(samples)
+	return buf.Bytes()
+}
+
+// frameAt returns the left and right samples of a decoded frame.
+func frameAt(pcm []byte, i int) (int16, int16) {
+	return int16(binary.LittleEndian.Uint16(pcm[i*4:])), int16(binary.LittleEndian.Uint16(pcm[i*4+2:]))
+}
+
+// TestDecodePCMWAVStereo16 tests that 16-bit stereo at SampleRate is decoded unchanged.
+func TestDecodePCMWAVStereo16(t *testing.T) {
+	var wav bytes.Buffer
+	pcm := pcmFrames(100, 1234, -1234)
+	writeWAVHeader(&wav, int64(len(pcm)))
+	wav.Write(pcm)
+
+	got, err := decodePCMWAV(wav.Bytes())
+	if err != nil {
+		t.Fatalf("decodePCMWAV failed: %v", err)
+	}
+	if !bytes.Equal(got, pcm) {
+		t.Error("decoded PCM differs from the source")
+	}
+}
+
+// TestDecodePCMWAVMono8 tests that 8-bit mono is converted to 16-bit stereo.
+func TestDecodePCMWAVMono8(t *testing.T) {
+	got, err := decodePCMWAV(buildWAV(1, SampleRate, 8, []byte{128, 255, 0}))
+	if err != nil {
+		t.Fatalf("decodePCMWAV failed: %v", err)
+	}
+	if len(got) != 3*renderBytesPerFrame {
+		t.Fatalf("len = %d, want %d", len(got), 3*renderBytesPerFrame)
+	}
+	want := []int16{0, 127 * 256, -128 * 256}
+	for i, w := range want {
+		if l, r := frameAt(got, i); l != w || r != w {
+			t.Errorf("frame %d = (%d, %d), want (%d, %d)", i, l, r, w, w)
+		}
+	}
+}
+
+// TestDecodePCMWAVResample tests that other sample rates are converted to SampleRate.
+func TestDecodePCMWAVResample(t *testing.T) {
+	samples := make([]byte, 4)
+	binary.LittleEndian.PutUint16(samples[0:], 0)
+	binary.LittleEndian.PutUint16(samples[2:], 1000)
+	got, err := decodePCMWAV(buildWAV(1, SampleRate/2, 16, samples))
+	if err != nil {
+		t.Fatalf("decodePCMWAV failed: %v", err)
+	}
+	if len(got) != 4*renderBytesPerFrame {
+		t.Fatalf("len = %d, want %d", len(got), 4*renderBytesPerFrame)
+	}
+	want := []int16{0, 500, 1000, 1000}
+	for i, w := range want {
+		if l, _ := frameAt(got, i); l != w {
+			t.Errorf("frame %d = %d, want %d", i, l, w)
+		}
+	}
+}
+
+// TestDecodePCMWAVErrors tests that unsupported and broken files are rejected.
+func TestDecodePCMWAVErrors(t *testing.T) {
+	adpcm := buildWAV(1, SampleRate, 16, nil)
+	binary.LittleEndian.PutUint16(adpcm[20:], 2)
+
+	tests := []struct {
+		name string
+		data []byte
+	}{
+		{"not RIFF", []byte("not a wav file")},
+		{"no data chunk", buildWAV(1, SampleRate, 16, nil)[:36]},
+		{"compressed", adpcm},
+		{"24-bit", buildWAV(1, SampleRate, 24, nil)},
+		{"6 channels", buildWAV(6, SampleRate, 16, nil)},
+	}
+	for _, tt := range tests {
+		if _, err := decodePCMWAV(tt.data); err == nil {
+			t.Errorf("%s: decodePCMWAV succeeded, want an error", tt.name)
+		}
+	}
+}
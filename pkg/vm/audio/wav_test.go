@@ -1,3 +1,5 @@
+//go:build !nogpu
+
 // Package audio provides audio-related components for the FILLY virtual machine.
 // This file contains tests for the WAV Player.
 package audio
@@ -17,8 +19,8 @@ func TestNewWAVPlayer(t *testing.T) {
 	if player == nil {
 		t.Fatal("NewWAVPlayer returned nil")
 	}
-	if player.audioCtx == nil {
-		t.Error("audioCtx should not be nil")
+	if player.GetAudioContext() != audioCtx {
+		t.Error("player should play on the shared audio context")
 	}
 	if player.players == nil {
 		t.Error("players slice should not be nil")
@@ -0,0 +1,78 @@
+package vm
+
+import (
+	"fmt"
+	"math"
+)
+
+// registerAppWindowBuiltins registers built-in functions for the application window
+// (the OS window that shows the virtual desktop), so that a title can show its own name
+// and icon instead of the generic son-et title. They take effect in GUI mode only.
+func (vm *VM) registerAppWindowBuiltins() {
+	// SetWindowTitle: Set the title of the application window
+	// SetWindowTitle(title)
+	vm.RegisterBuiltinFunction("SetWindowTitle", func(v *VM, args []any) (any, error) {
+		title, ok := v.appWindowString("SetWindowTitle", args)
+		if !ok {
+			return nil, nil
+		}
+		v.graphicsSystem.SetWindowTitle(title)
+		return nil, nil
+	})
+
+	// SetWindowIcon: Use an image file (BMP/PNG) as the icon of the application window
+	// SetWindowIcon(filename) - the path is relative to the title directory, as in LoadPic
+	vm.RegisterBuiltinFunction("SetWindowIcon", func(v *VM, args []any) (any, error) {
+		filename, ok := v.appWindowString("SetWindowIcon", args)
+		if !ok {
+			return nil, nil
+		}
+		if err := v.graphicsSystem.SetWindowIcon(filename); err != nil {
+			v.log.Error("SetWindowIcon failed", "filename", filename, "error", err)
+		}
+		return nil, nil
+	})
+
+	// SetWindowSize: Resize the application window (the virtual desktop is scaled to fit)
+	// SetWindowSize(width, height)
+	vm.RegisterBuiltinFunction("SetWindowSize", func(v *VM, args []any) (any, error) {
+		nums, ok := v.graphicsArgs("SetWindowSize", args, 2)
+		if !ok {
+			return nil, nil
+		}
+		if err := v.graphicsSystem.SetWindowSize(int(nums[0]), int(nums[1])); err != nil {
+			v.log.Error("SetWindowSize failed", "error", err)
+		}
+		return nil, nil
+	})
+
+	// GetDisplayScale: Get the effective display scale in percent (100 = one physical pixel per
+	// virtual desktop pixel; 200 on a 2x high-DPI screen or a window twice the desktop size)
+	// GetDisplayScale()
+	vm.RegisterBuiltinFunction("GetDisplayScale", func(v *VM, args []any) (any, error) {
+		if v.graphicsSystem == nil {
+			v.log.Debug("GetDisplayScale called but graphics system not initialized", "args", args)
+			return int64(100), nil
+		}
+		return int64(math.Round(v.graphicsSystem.DisplayScale() * 100)), nil
+	})
+}
+
+// appWindowString は文字列を1つ取るアプリケーションウィンドウの組み込み関数の引数を解釈する
+// グラフィックスシステムがない場合や引数が不正な場合はログを記録して ok=false を返す。
+func (vm *VM) appWindowString(name string, args []any) (string, bool) {
+	if vm.graphicsSystem == nil {
+		vm.log.Debug(name+" called but graphics system not initialized", "args", args)
+		return "", false
+	}
+	if len(args) < 1 {
+		vm.log.Error(name + " requires 1 argument")
+		return "", false
+	}
+	s, ok := args[0].(string)
+	if !ok {
+		vm.log.Error(name+": argument must be a string", "got", fmt.Sprintf("%T", args[0]))
+		return "", false
+	}
+	return s, true
+}
@@ -0,0 +1,88 @@
+package vm
+
+import (
+	"testing"
+
+	"github.com/zurustar/son-et/pkg/graphics"
+	"github.com/zurustar/son-et/pkg/opcode"
+)
+
+func TestVMBuiltinAppWindowRegistered(t *testing.T) {
+	vm := New([]opcode.OpCode{})
+	for _, name := range []string{"SetWindowTitle", "SetWindowIcon", "SetWindowSize", "GetDisplayScale"} {
+		if _, ok := vm.builtins[name]; !ok {
+			t.Errorf("expected %s to be registered as built-in function", name)
+		}
+	}
+}
+
+func TestVMBuiltinAppWindow(t *testing.T) {
+	vm := New([]opcode.OpCode{})
+	mockGS := newMockGraphicsSystem()
+	vm.SetGraphicsSystem(mockGS)
+
+	vm.builtins["SetWindowTitle"](vm, []any{"My Title"})
+	vm.builtins["SetWindowIcon"](vm, []any{"ICON.BMP"})
+	vm.builtins["SetWindowSize"](vm, []any{int64(800), int64(600)})
+
+	want := graphics.AppWindowSettings{Title: "My Title", IconFile: "ICON.BMP", Width: 800, Height: 600}
+	if mockGS.appWindow != want {
+		t.Errorf("settings = %+v, want %+v", mockGS.appWindow, want)
+	}
+}
+
+func TestVMBuiltinAppWindowInvalidArgs(t *testing.T) {
+	vm := New([]opcode.OpCode{})
+	mockGS := newMockGraphicsSystem()
+	vm.SetGraphicsSystem(mockGS)
+
+	// 不正な引数はログに記録して無視し、スクリプトは続行する
+	for _, call := range []struct {
+		name string
+		args []any
+	}{
+		{"SetWindowTitle", []any{}},
+		{"SetWindowTitle", []any{int64(1)}},
+		{"SetWindowIcon", []any{""}},
+		{"SetWindowSize", []any{int64(800)}},
+		{"SetWindowSize", []any{int64(0), int64(600)}},
+		{"SetWindowSize", []any{"wide", int64(600)}},
+	} {
+		if _, err := vm.builtins[call.name](vm, call.args); err != nil {
+			t.Errorf("%s(%v) returned error: %v", call.name, call.args, err)
+		}
+	}
+	if mockGS.appWindow != (graphics.AppWindowSettings{}) {
+		t.Errorf("invalid calls should not change the settings: %+v", mockGS.appWindow)
+	}
+
+	// グラフィックスシステムがない場合は何もしない
+	noGS := New([]opcode.OpCode{})
+	if _, err := noGS.builtins["SetWindowTitle"](noGS, []any{"x"}); err != nil {
+		t.Errorf("unexpected error without graphics system: %v", err)
+	}
+}
+
+func TestVMBuiltinGetDisplayScale(t *testing.T) {
+	vm := New([]opcode.OpCode{})
+	if got, _ := vm.builtins["GetDisplayScale"](vm, nil); got != int64(100) {
+		t.Errorf("GetDisplayScale without graphics system = %v, want 100", got)
+	}
+
+	mockGS := newMockGraphicsSystem()
+	vm.SetGraphicsSystem(mockGS)
+	for _, tt := range []struct {
+		scale float64
+		want  int64
+	}{
+		{0, 100},
+		{2, 200},
+		{1.5, 150},
+		{0.666, 67},
+	} {
+		mockGS.displayScale = tt.scale
+		if got, _ := vm.builtins["GetDisplayScale"](vm, nil); got != tt.want {
+			t.Errorf("GetDisplayScale with scale %v = %v, want %d", tt.scale, got, tt.want)
+		}
+	}
+}
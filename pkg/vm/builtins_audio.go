@@ -1,6 +1,10 @@
 package vm
 
-import "fmt"
+import (
+	"fmt"
+	"math"
+	"time"
+)
 
 // registerAudioBuiltins registers audio-related built-in functions.
 func (vm *VM) registerAudioBuiltins() {
@@ -44,4 +48,172 @@ func (vm *VM) registerAudioBuiltins() {
 		v.log.Debug("PlayWAVE called", "filename", filename)
 		return nil, nil
 	})
+
+	// PlayVoice: Play a WAV file on the voice bus (narration); the music is ducked while it plays
+	// PlayVoice(filename)
+	vm.RegisterBuiltinFunction("PlayVoice", func(v *VM, args []any) (any, error) {
+		if len(args) < 1 {
+			v.log.Error("PlayVoice requires filename argument")
+			return nil, nil
+		}
+		filename, ok := args[0].(string)
+		if !ok {
+			v.log.Error("PlayVoice filename must be string", "got", fmt.Sprintf("%T", args[0]))
+			return nil, nil
+		}
+		if err := v.PlayVoice(filename); err != nil {
+			v.log.Error("PlayVoice failed", "filename", filename, "error", err)
+			return nil, nil
+		}
+		v.log.Debug("PlayVoice called", "filename", filename)
+		return nil, nil
+	})
+
+	// SetVolume: Set the volume of a mixer bus in percent (0-100)
+	// SetVolume(volume) sets the master bus; SetVolume("bus", volume) sets "master", "music" (MIDI), "sfx" (WAV) or "voice" (PlayVoice).
+	vm.RegisterBuiltinFunction("SetVolume", func(v *VM, args []any) (any, error) {
+		bus, rest, ok := v.audioBusArgs("SetVolume", args, 1)
+		if !ok {
+			return nil, nil
+		}
+		percent, ok := toInt64(rest[0])
+		if !ok {
+			v.log.Error("SetVolume: volume must be integer", "got", fmt.Sprintf("%T", rest[0]))
+			return nil, nil
+		}
+		percent = min(max(percent, 0), maxVolumePercent)
+		if err := v.audioSystem.SetBusGain(bus, float64(percent)/maxVolumePercent); err != nil {
+			v.log.Error("SetVolume failed", "bus", bus, "error", err)
+			return nil, nil
+		}
+		v.log.Debug("SetVolume called", "bus", bus, "volume", percent)
+		return nil, nil
+	})
+
+	// GetVolume: Get the volume of a mixer bus in percent (0-100)
+	// GetVolume() returns the master bus; GetVolume("bus") returns the named bus.
+	vm.RegisterBuiltinFunction("GetVolume", func(v *VM, args []any) (any, error) {
+		bus, _, ok := v.audioBusArgs("GetVolume", args, 0)
+		if !ok {
+			return int64(0), nil
+		}
+		gain, err := v.audioSystem.BusGain(bus)
+		if err != nil {
+			v.log.Error("GetVolume failed", "bus", bus, "error", err)
+			return int64(0), nil
+		}
+		return int64(math.Round(gain * maxVolumePercent)), nil
+	})
+
+	// SetMute: Mute (1) or unmute (0) a mixer bus; the volume is kept while muted
+	// SetMute(flag) mutes the master bus; SetMute("bus", flag) mutes the named bus.
+	vm.RegisterBuiltinFunction("SetMute", func(v *VM, args []any) (any, error) {
+		bus, rest, ok := v.audioBusArgs("SetMute", args, 1)
+		if !ok {
+			return nil, nil
+		}
+		flag, ok := toInt64(rest[0])
+		if !ok {
+			v.log.Error("SetMute: flag must be integer", "got", fmt.Sprintf("%T", rest[0]))
+			return nil, nil
+		}
+		if err := v.audioSystem.SetBusMuted(bus, flag != 0); err != nil {
+			v.log.Error("SetMute failed", "bus", bus, "error", err)
+			return nil, nil
+		}
+		v.log.Debug("SetMute called", "bus", bus, "muted", flag != 0)
+		return nil, nil
+	})
+
+	// SetDucking: Lower the music bus by depth_db decibels while the voice bus (PlayVoice) is playing
+	// SetDucking(depth_db) / SetDucking(depth_db, attack_ms, release_ms) - SetDucking(0) turns ducking off
+	vm.RegisterBuiltinFunction("SetDucking", func(v *VM, args []any) (any, error) {
+		if v.audioSystem == nil {
+			v.log.Debug("SetDucking called but audio system not initialized", "args", args)
+			return nil, nil
+		}
+		if len(args) != 1 && len(args) != 3 {
+			v.log.Error("SetDucking: wrong number of arguments", "got", len(args))
+			return nil, nil
+		}
+		nums := []int64{0, defaultDuckingAttack.Milliseconds(), defaultDuckingRelease.Milliseconds()}
+		for i, arg := range args {
+			n, ok := toInt64(arg)
+			if !ok {
+				v.log.Error("SetDucking: arguments must be integers", "index", i, "got", fmt.Sprintf("%T", arg))
+				return nil, nil
+			}
+			nums[i] = n
+		}
+		depth := v.audioSystem.SetDucking(float64(nums[0]), time.Duration(nums[1])*time.Millisecond, time.Duration(nums[2])*time.Millisecond)
+		v.log.Debug("SetDucking called", "depth_db", depth, "attack_ms", nums[1], "release_ms", nums[2])
+		return nil, nil
+	})
+
+	// SetSynthQuality: Trade MIDI sound quality for CPU time (or back) on slow machines
+	// SetSynthQuality(effects) / SetSynthQuality(effects, polyphony) - effects 1 enables reverb
+	// and chorus, 0 disables them; polyphony is the maximum number of voices (8-256, omitted
+	// or 0 keeps the current one). Applies from the next PlayMIDI.
+	// Returns the applied polyphony, or 0 on error.
+	vm.RegisterBuiltinFunction("SetSynthQuality", func(v *VM, args []any) (any, error) {
+		if v.audioSystem == nil {
+			v.log.Debug("SetSynthQuality called but audio system not initialized", "args", args)
+			return int64(0), nil
+		}
+		if len(args) != 1 && len(args) != 2 {
+			v.log.Error("SetSynthQuality: wrong number of arguments", "got", len(args))
+			return int64(0), nil
+		}
+		nums := []int64{0, 0}
+		for i, arg := range args {
+			n, ok := toInt64(arg)
+			if !ok {
+				v.log.Error("SetSynthQuality: arguments must be integers", "index", i, "got", fmt.Sprintf("%T", arg))
+				return int64(0), nil
+			}
+			nums[i] = n
+		}
+		polyphony, err := v.audioSystem.SetSynthQuality(nums[0] != 0, int(nums[1]))
+		if err != nil {
+			v.log.Error("SetSynthQuality failed", "error", err)
+			return int64(0), nil
+		}
+		v.log.Debug("SetSynthQuality called", "effects", nums[0] != 0, "polyphony", polyphony)
+		return int64(polyphony), nil
+	})
+}
+
+// SetDucking の attack_ms・release_ms を省略した場合の時間
+const (
+	defaultDuckingAttack  = 100 * time.Millisecond
+	defaultDuckingRelease = 500 * time.Millisecond
+)
+
+// maxVolumePercent はスクリプトから指定する音量の最大値（SetVolume/GetVolume）
+const maxVolumePercent = 100
+
+// defaultAudioBus はバス名を省略した場合に操作するミキサーのバス
+const defaultAudioBus = "master"
+
+// audioBusArgs はミキサーの組み込み関数の引数 ([bus,] values...) を解釈する。
+// 引数が nValues 個ならバス名を省略したものとして defaultAudioBus を返す。
+// オーディオシステムがない場合や引数が不正な場合はログを記録して ok=false を返す。
+func (vm *VM) audioBusArgs(name string, args []any, nValues int) (bus string, values []any, ok bool) {
+	if vm.audioSystem == nil {
+		vm.log.Debug(name+" called but audio system not initialized", "args", args)
+		return "", nil, false
+	}
+	switch len(args) {
+	case nValues:
+		return defaultAudioBus, args, true
+	case nValues + 1:
+		bus, isString := args[0].(string)
+		if !isString {
+			vm.log.Error(name+": bus name must be string", "got", fmt.Sprintf("%T", args[0]))
+			return "", nil, false
+		}
+		return bus, args[1:], true
+	}
+	vm.log.Error(name+": wrong number of arguments", "got", len(args))
+	return "", nil, false
 }
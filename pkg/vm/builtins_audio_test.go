@@ -0,0 +1,363 @@
+package vm
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/zurustar/son-et/pkg/opcode"
+)
+
+// mockAudioSystem is an AudioSystemInterface that only keeps the state of the mixer buses.
+type mockAudioSystem struct {
+	gains       map[string]float64
+	muted       map[string]bool
+	scale       float64
+	ports       map[string]string // File playing on each MIDI port
+	ticks       map[string]int    // FILLY tick reported by MIDIPortTick
+	clock       string            // Port selected by SetMIDIClock
+	avOffset    time.Duration
+	calibrating bool
+	seeks       []int  // Ticks passed to SeekMIDI
+	skips       []int  // Ticks passed to SkipMIDI
+	midiLength  int    // Length in ticks reported by MIDITickAfter (0 = endless)
+	midiFile    string // File played by PlayMIDI
+	paused      bool
+	timer       bool       // StartTimer was called
+	voices      []string   // Files played by PlayVoice
+	ducking     [3]float64 // Depth (dB), attack and release (ms) set by SetDucking
+	effects     bool       // Reverb and chorus set by SetSynthQuality
+	polyphony   int        // Polyphony set by SetSynthQuality
+}
+
+func newMockAudioSystem() *mockAudioSystem {
+	return &mockAudioSystem{
+		gains:     map[string]float64{"master": 1, "music": 1, "sfx": 1, "voice": 1},
+		muted:     make(map[string]bool),
+		scale:     1,
+		effects:   true,
+		polyphony: 64,
+	}
+}
+
+func (m *mockAudioSystem) PlayMIDI(filename string) error { m.midiFile = filename; return nil }
+func (m *mockAudioSystem) PlayWAVE(filename string) error { return nil }
+func (m *mockAudioSystem) PlayVoice(filename string) error {
+	m.voices = append(m.voices, filename)
+	return nil
+}
+func (m *mockAudioSystem) SetMuted(muted bool)                 {}
+func (m *mockAudioSystem) Update()                             {}
+func (m *mockAudioSystem) Shutdown()                           {}
+func (m *mockAudioSystem) StartTimer()                         { m.timer = true }
+func (m *mockAudioSystem) StopTimer()                          {}
+func (m *mockAudioSystem) IsMIDIPlaying() bool                 { return false }
+func (m *mockAudioSystem) IsTimerRunning() bool                { return m.timer }
+func (m *mockAudioSystem) StartFadeout(duration time.Duration) {}
+func (m *mockAudioSystem) IsFadingOut() bool                   { return false }
+func (m *mockAudioSystem) Pause()                              { m.paused = true }
+func (m *mockAudioSystem) Resume()                             { m.paused = false }
+func (m *mockAudioSystem) TimeScale() float64                  { return m.scale }
+
+func (m *mockAudioSystem) PlayMIDIPort(port, filename string) error {
+	if m.ports == nil {
+		m.ports = make(map[string]string)
+	}
+	m.ports[port] = filename
+	return nil
+}
+
+func (m *mockAudioSystem) SeekMIDI(tick int) error {
+	m.seeks = append(m.seeks, tick)
+	return nil
+}
+
+func (m *mockAudioSystem) SkipMIDI(tick int) error {
+	m.skips = append(m.skips, tick)
+	return nil
+}
+
+// MIDITickAfter plays every file at 1 tick per 100ms, ending at midiLength ticks
+func (m *mockAudioSystem) MIDITickAfter(d time.Duration) (int, bool) {
+	if m.midiFile == "" {
+		return -1, false
+	}
+	tick := int(d / (100 * time.Millisecond))
+	if m.midiLength > 0 && tick >= m.midiLength {
+		return m.midiLength, true
+	}
+	return tick, false
+}
+
+func (m *mockAudioSystem) StopMIDIPort(port string) {
+	delete(m.ports, port)
+	if port == "" {
+		m.midiFile = ""
+	}
+}
+func (m *mockAudioSystem) SetMIDIClock(port string) { m.clock = port }
+
+func (m *mockAudioSystem) MIDIPortTick(port string) int {
+	if _, ok := m.ports[port]; !ok {
+		return -1
+	}
+	return m.ticks[port]
+}
+
+func (m *mockAudioSystem) SetAVOffset(offset time.Duration) time.Duration {
+	m.avOffset = max(-time.Second, min(time.Second, offset))
+	return m.avOffset
+}
+
+func (m *mockAudioSystem) AVOffset() time.Duration   { return m.avOffset }
+func (m *mockAudioSystem) StartAVCalibration() error { m.calibrating = true; return nil }
+func (m *mockAudioSystem) StopAVCalibration()        { m.calibrating = false }
+func (m *mockAudioSystem) AVCalibrationBeat() bool   { return m.calibrating }
+func (m *mockAudioSystem) MetronomeBeat() bool       { return m.midiFile != "" }
+
+func (m *mockAudioSystem) SetSynthQuality(effects bool, polyphony int) (int, error) {
+	m.effects = effects
+	if polyphony > 0 {
+		m.polyphony = max(8, min(256, polyphony))
+	}
+	return m.polyphony, nil
+}
+
+func (m *mockAudioSystem) SetDucking(depth float64, attack, release time.Duration) float64 {
+	depth = max(0, min(60, depth))
+	m.ducking = [3]float64{depth, float64(attack.Milliseconds()), float64(release.Milliseconds())}
+	return depth
+}
+
+func (m *mockAudioSystem) SetTimeScale(scale float64) float64 {
+	m.scale = max(0.25, min(4, scale))
+	return m.scale
+}
+
+func (m *mockAudioSystem) SetBusGain(bus string, gain float64) error {
+	bus = strings.ToLower(bus)
+	if _, ok := m.gains[bus]; !ok {
+		return fmt.Errorf("unknown audio bus: %q", bus)
+	}
+	m.gains[bus] = gain
+	return nil
+}
+
+func (m *mockAudioSystem) BusGain(bus string) (float64, error) {
+	gain, ok := m.gains[strings.ToLower(bus)]
+	if !ok {
+		return 0, fmt.Errorf("unknown audio bus: %q", bus)
+	}
+	return gain, nil
+}
+
+func (m *mockAudioSystem) SetBusMuted(bus string, muted bool) error {
+	bus = strings.ToLower(bus)
+	if _, ok := m.gains[bus]; !ok {
+		return fmt.Errorf("unknown audio bus: %q", bus)
+	}
+	m.muted[bus] = muted
+	return nil
+}
+
+func TestSetVolumeBuiltin(t *testing.T) {
+	audio := newMockAudioSystem()
+	vm := New([]opcode.OpCode{})
+	vm.SetAudioSystem(audio)
+
+	tests := []struct {
+		args []any
+		bus  string
+		want float64
+	}{
+		{[]any{int64(50)}, "master", 0.5},
+		{[]any{"music", int64(80)}, "music", 0.8},
+		{[]any{"SFX", int64(150)}, "sfx", 1},
+		{[]any{"sfx", int64(-10)}, "sfx", 0},
+		{[]any{"voice", int64(70)}, "voice", 0.7},
+	}
+	for _, tt := range tests {
+		if _, err := vm.builtins["SetVolume"](vm, tt.args); err != nil {
+			t.Fatalf("SetVolume%v failed: %v", tt.args, err)
+		}
+		if got := audio.gains[tt.bus]; got != tt.want {
+			t.Errorf("SetVolume%v: %s gain = %v, want %v", tt.args, tt.bus, got, tt.want)
+		}
+	}
+
+	// Invalid arguments are logged and execution continues
+	for _, args := range [][]any{{"ambience", int64(10)}, {int64(1), int64(2)}, {}} {
+		if _, err := vm.builtins["SetVolume"](vm, args); err != nil {
+			t.Errorf("SetVolume%v should not fail the script: %v", args, err)
+		}
+	}
+}
+
+func TestGetVolumeBuiltin(t *testing.T) {
+	audio := newMockAudioSystem()
+	audio.gains["music"] = 0.25
+	vm := New([]opcode.OpCode{})
+	vm.SetAudioSystem(audio)
+
+	if got, _ := vm.builtins["GetVolume"](vm, []any{}); got != int64(100) {
+		t.Errorf("GetVolume() = %v, want 100", got)
+	}
+	if got, _ := vm.builtins["GetVolume"](vm, []any{"music"}); got != int64(25) {
+		t.Errorf("GetVolume(music) = %v, want 25", got)
+	}
+	if got, _ := vm.builtins["GetVolume"](vm, []any{"ambience"}); got != int64(0) {
+		t.Errorf("GetVolume(ambience) = %v, want 0", got)
+	}
+
+	// Without an audio system the result is 0
+	noAudio := New([]opcode.OpCode{})
+	if got, _ := noAudio.builtins["GetVolume"](noAudio, []any{}); got != int64(0) {
+		t.Errorf("GetVolume() without audio = %v, want 0", got)
+	}
+}
+
+func TestSetMuteBuiltin(t *testing.T) {
+	audio := newMockAudioSystem()
+	vm := New([]opcode.OpCode{})
+	vm.SetAudioSystem(audio)
+
+	_, _ = vm.builtins["SetMute"](vm, []any{"sfx", int64(1)})
+	_, _ = vm.builtins["SetMute"](vm, []any{int64(1)})
+	if !audio.muted["sfx"] || !audio.muted["master"] {
+		t.Errorf("muted = %v, want sfx and master muted", audio.muted)
+	}
+	_, _ = vm.builtins["SetMute"](vm, []any{"sfx", int64(0)})
+	if audio.muted["sfx"] {
+		t.Error("SetMute(sfx, 0) should unmute")
+	}
+}
+
+func TestPlayVoiceBuiltin(t *testing.T) {
+	audio := newMockAudioSystem()
+	vm := New([]opcode.OpCode{}, WithTitlePath(t.TempDir()))
+	vm.SetAudioSystem(audio)
+
+	_, _ = vm.builtins["PlayVoice"](vm, []any{"line1.wav"})
+	if len(audio.voices) != 1 || !strings.HasSuffix(audio.voices[0], "line1.wav") {
+		t.Errorf("voices = %v, want line1.wav resolved in the title directory", audio.voices)
+	}
+
+	// Invalid arguments are logged and execution continues
+	for _, args := range [][]any{{}, {int64(1)}} {
+		if _, err := vm.builtins["PlayVoice"](vm, args); err != nil {
+			t.Errorf("PlayVoice%v should not fail the script: %v", args, err)
+		}
+	}
+	if len(audio.voices) != 1 {
+		t.Errorf("invalid PlayVoice calls should not play, voices = %v", audio.voices)
+	}
+}
+
+func TestSetDuckingBuiltin(t *testing.T) {
+	audio := newMockAudioSystem()
+	vm := New([]opcode.OpCode{})
+	vm.SetAudioSystem(audio)
+
+	_, _ = vm.builtins["SetDucking"](vm, []any{int64(12), int64(200), int64(800)})
+	if audio.ducking != [3]float64{12, 200, 800} {
+		t.Errorf("ducking = %v, want [12 200 800]", audio.ducking)
+	}
+
+	// Omitted times use the defaults
+	_, _ = vm.builtins["SetDucking"](vm, []any{int64(6)})
+	want := [3]float64{6, float64(defaultDuckingAttack.Milliseconds()), float64(defaultDuckingRelease.Milliseconds())}
+	if audio.ducking != want {
+		t.Errorf("ducking = %v, want %v", audio.ducking, want)
+	}
+
+	// Invalid arguments are logged and execution continues
+	for _, args := range [][]any{{}, {int64(6), int64(100)}, {"6"}} {
+		if _, err := vm.builtins["SetDucking"](vm, args); err != nil {
+			t.Errorf("SetDucking%v should not fail the script: %v", args, err)
+		}
+	}
+	if audio.ducking != want {
+		t.Errorf("invalid SetDucking calls changed the ducking to %v", audio.ducking)
+	}
+}
+
+func TestSetSynthQualityBuiltin(t *testing.T) {
+	audio := newMockAudioSystem()
+	vm := New([]opcode.OpCode{})
+	vm.SetAudioSystem(audio)
+
+	if got, _ := vm.builtins["SetSynthQuality"](vm, []any{int64(0), int64(24)}); got != int64(24) {
+		t.Errorf("SetSynthQuality(0, 24) = %v, want 24", got)
+	}
+	if audio.effects || audio.polyphony != 24 {
+		t.Errorf("effects = %v, polyphony = %d, want false, 24", audio.effects, audio.polyphony)
+	}
+
+	// Omitting the polyphony keeps the current one
+	if got, _ := vm.builtins["SetSynthQuality"](vm, []any{int64(1)}); got != int64(24) {
+		t.Errorf("SetSynthQuality(1) = %v, want the current polyphony 24", got)
+	}
+	if !audio.effects {
+		t.Error("expected SetSynthQuality(1) to enable the effects")
+	}
+
+	// Invalid arguments are logged and return 0
+	for _, args := range [][]any{{}, {int64(1), int64(2), int64(3)}, {"on"}} {
+		if got, err := vm.builtins["SetSynthQuality"](vm, args); got != int64(0) || err != nil {
+			t.Errorf("SetSynthQuality%v = (%v, %v), want (0, nil)", args, got, err)
+		}
+	}
+
+	// Without an audio system the result is 0
+	noAudio := New([]opcode.OpCode{})
+	if got, _ := noAudio.builtins["SetSynthQuality"](noAudio, []any{int64(1)}); got != int64(0) {
+		t.Errorf("SetSynthQuality without audio = %v, want 0", got)
+	}
+}
+
+func TestVMTimeScale(t *testing.T) {
+	vm := New([]opcode.OpCode{})
+	if got := vm.SetTimeScale(2); got != 1 || vm.TimeScale() != 1 {
+		t.Errorf("expected scale 1 without an audio system, got %v", got)
+	}
+
+	audio := newMockAudioSystem()
+	vm.SetAudioSystem(audio)
+	if got := vm.SetTimeScale(2); got != 2 || vm.TimeScale() != 2 {
+		t.Errorf("expected scale 2, got %v (TimeScale %v)", got, vm.TimeScale())
+	}
+	if got := vm.SetTimeScale(10); got != 4 {
+		t.Errorf("expected the applied scale to be clamped to 4, got %v", got)
+	}
+}
+
+func TestVMAVOffset(t *testing.T) {
+	vm := New([]opcode.OpCode{})
+	if got := vm.SetAVOffset(40 * time.Millisecond); got != 0 || vm.AVOffset() != 0 {
+		t.Errorf("expected no offset without an audio system, got %v", got)
+	}
+	if err := vm.StartAVCalibration(); err == nil {
+		t.Error("expected calibration to fail without an audio system")
+	}
+	if vm.AVCalibrationBeat() || vm.MetronomeBeat() {
+		t.Error("expected no beat without an audio system")
+	}
+
+	audio := newMockAudioSystem()
+	vm.SetAudioSystem(audio)
+	if got := vm.SetAVOffset(40 * time.Millisecond); got != 40*time.Millisecond || vm.AVOffset() != got {
+		t.Errorf("expected offset 40ms, got %v (AVOffset %v)", got, vm.AVOffset())
+	}
+	if err := vm.StartAVCalibration(); err != nil || !vm.AVCalibrationBeat() {
+		t.Errorf("expected calibration to start, got %v", err)
+	}
+	vm.StopAVCalibration()
+	if audio.calibrating {
+		t.Error("expected calibration to stop")
+	}
+	audio.PlayMIDI("song.mid")
+	if !vm.MetronomeBeat() {
+		t.Error("expected the metronome beat of the audio system")
+	}
+}
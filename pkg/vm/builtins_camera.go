@@ -0,0 +1,69 @@
+package vm
+
+import (
+	"time"
+
+	"github.com/zurustar/son-et/pkg/graphics"
+)
+
+// registerCameraBuiltins registers built-in functions for panning and zooming the
+// whole virtual desktop. The camera is applied by the renderer as a final transform,
+// and mouse events are converted back to scene coordinates, so scripts keep using
+// the coordinates of the virtual desktop while the camera moves.
+func (vm *VM) registerCameraBuiltins() {
+	// SetCamera: Show the virtual desktop around a point with a zoom
+	// SetCamera(x, y, zoom) - (x, y) is the point of the virtual desktop shown at the
+	// center of the screen and zoom is 0.25-8 (1 is the normal size).
+	// SetCamera() resets the camera so that the whole virtual desktop is shown as is.
+	vm.RegisterBuiltinFunction("SetCamera", func(v *VM, args []any) (any, error) {
+		if len(args) == 0 {
+			if v.graphicsSystem == nil {
+				v.log.Debug("SetCamera called but graphics system not initialized")
+				return nil, nil
+			}
+			if err := v.graphicsSystem.SetCamera(nil); err != nil {
+				v.log.Error("SetCamera failed", "error", err)
+			}
+			return nil, nil
+		}
+		nums, ok := v.graphicsArgs("SetCamera", args, 3)
+		if !ok {
+			return nil, nil
+		}
+		cam := graphics.Camera{X: nums[0], Y: nums[1], Zoom: nums[2]}
+		if err := v.graphicsSystem.SetCamera(&cam); err != nil {
+			v.log.Error("SetCamera failed", "error", err)
+		}
+		return nil, nil
+	})
+
+	// CameraMove: Move the camera smoothly
+	// CameraMove(x, y, zoom, ticks[, easing]) - ticks have the same length as the
+	// FadeOut ticks. easing is 0 (linear, default), 1 (ease in), 2 (ease out) or 3 (ease in-out).
+	// CameraMove with 0 ticks sets the camera at once like SetCamera.
+	vm.RegisterBuiltinFunction("CameraMove", func(v *VM, args []any) (any, error) {
+		nums, ok := v.graphicsArgs("CameraMove", args, 4)
+		if !ok {
+			return nil, nil
+		}
+		ticks := int64(nums[3])
+		if ticks < 0 {
+			v.log.Error("CameraMove: ticks must be non-negative", "ticks", ticks)
+			return nil, nil
+		}
+		easing := graphics.EaseLinear
+		if len(nums) >= 5 {
+			easing = graphics.Easing(nums[4])
+			if !graphics.ValidEasing(easing) {
+				v.log.Error("CameraMove: unknown easing", "easing", nums[4])
+				return nil, nil
+			}
+		}
+		cam := graphics.Camera{X: nums[0], Y: nums[1], Zoom: nums[2]}
+		duration := time.Duration(ticks) * fadeTickDuration
+		if err := v.graphicsSystem.MoveCamera(cam, duration, easing); err != nil {
+			v.log.Error("CameraMove failed", "error", err)
+		}
+		return nil, nil
+	})
+}
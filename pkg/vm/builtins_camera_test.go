@@ -0,0 +1,70 @@
+package vm
+
+import (
+	"testing"
+
+	"github.com/zurustar/son-et/pkg/graphics"
+	"github.com/zurustar/son-et/pkg/opcode"
+)
+
+func TestVMBuiltinCameraRegistered(t *testing.T) {
+	vm := New([]opcode.OpCode{})
+	for _, name := range []string{"SetCamera", "CameraMove"} {
+		if _, ok := vm.builtins[name]; !ok {
+			t.Errorf("expected %s to be registered as built-in function", name)
+		}
+	}
+}
+
+func TestVMBuiltinSetCamera(t *testing.T) {
+	vm, mockGS := newMaskTestVM()
+
+	vm.builtins["SetCamera"](vm, []any{int64(160), int64(120), 2.5})
+	vm.builtins["SetCamera"](vm, []any{})
+	if len(mockGS.cameraMoves) != 2 {
+		t.Fatalf("cameraMoves = %+v, want 2 entries", mockGS.cameraMoves)
+	}
+	want := graphics.Camera{X: 160, Y: 120, Zoom: 2.5}
+	if got := mockGS.cameraMoves[0].camera; got == nil || *got != want {
+		t.Errorf("SetCamera camera = %+v, want %+v", got, want)
+	}
+	if mockGS.cameraMoves[1].camera != nil {
+		t.Errorf("SetCamera() camera = %+v, want nil (reset)", mockGS.cameraMoves[1].camera)
+	}
+
+	// 引数が足りない・数値でない場合は設定しない
+	vm.builtins["SetCamera"](vm, []any{int64(160), int64(120)})
+	vm.builtins["SetCamera"](vm, []any{int64(160), "x", int64(1)})
+	if len(mockGS.cameraMoves) != 2 {
+		t.Errorf("invalid calls set the camera: %+v", mockGS.cameraMoves[2:])
+	}
+}
+
+func TestVMBuiltinCameraMove(t *testing.T) {
+	vm, mockGS := newMaskTestVM()
+
+	vm.builtins["CameraMove"](vm, []any{int64(320), int64(240), int64(2), int64(20)})
+	vm.builtins["CameraMove"](vm, []any{int64(0), int64(0), 0.5, int64(10), int64(2)})
+	want := []mockCameraMove{
+		{camera: &graphics.Camera{X: 320, Y: 240, Zoom: 2}, duration: 20 * fadeTickDuration, easing: graphics.EaseLinear},
+		{camera: &graphics.Camera{X: 0, Y: 0, Zoom: 0.5}, duration: 10 * fadeTickDuration, easing: graphics.EaseOut},
+	}
+	if len(mockGS.cameraMoves) != len(want) {
+		t.Fatalf("cameraMoves = %+v, want %+v", mockGS.cameraMoves, want)
+	}
+	for i := range want {
+		got := mockGS.cameraMoves[i]
+		if *got.camera != *want[i].camera || got.duration != want[i].duration || got.easing != want[i].easing {
+			t.Errorf("cameraMoves[%d] = {%+v %v %v}, want {%+v %v %v}", i,
+				*got.camera, got.duration, got.easing, *want[i].camera, want[i].duration, want[i].easing)
+		}
+	}
+
+	// 不正な引数では移動しない
+	vm.builtins["CameraMove"](vm, []any{int64(0), int64(0), int64(1), int64(-1)})
+	vm.builtins["CameraMove"](vm, []any{int64(0), int64(0), int64(1), int64(10), int64(7)})
+	vm.builtins["CameraMove"](vm, []any{int64(0), int64(0), int64(1)})
+	if len(mockGS.cameraMoves) != len(want) {
+		t.Errorf("invalid calls moved the camera: %+v", mockGS.cameraMoves[len(want):])
+	}
+}
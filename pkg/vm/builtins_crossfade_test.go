@@ -0,0 +1,105 @@
+package vm
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/zurustar/son-et/pkg/opcode"
+)
+
+// newCrossfadeTestVM creates a VM with a 32x16 picture to change to.
+func newCrossfadeTestVM(t *testing.T) (*VM, *mockGraphicsSystem, int) {
+	t.Helper()
+	vm := New([]opcode.OpCode{})
+	gs := newMockGraphicsSystem()
+	vm.SetGraphicsSystem(gs)
+	picID, err := gs.CreatePic(32, 16)
+	if err != nil {
+		t.Fatalf("CreatePic failed: %v", err)
+	}
+	return vm, gs, picID
+}
+
+func TestVMBuiltinChangePictureRegistered(t *testing.T) {
+	vm := New([]opcode.OpCode{})
+	if _, ok := vm.builtins["ChangePicture"]; !ok {
+		t.Error("expected ChangePicture to be registered as built-in function")
+	}
+}
+
+// TestChangePictureCrossfade tests that the crossfade advances by one step per TIME
+// tick, ignores MIDI_TIME and ends after the given ticks.
+func TestChangePictureCrossfade(t *testing.T) {
+	vm, gs, picID := newCrossfadeTestVM(t)
+
+	vm.builtins["ChangePicture"](vm, []any{int64(3), int64(picID), int64(4)})
+	if gs.castPics[3] != picID {
+		t.Fatalf("cast picture = %d, want %d", gs.castPics[3], picID)
+	}
+	dispatchEvents(t, vm, NewEvent(EventMIDI_TIME))
+	for range 5 {
+		dispatchEvents(t, vm, NewEvent(EventTIME))
+	}
+
+	want := []float64{0, 0.25, 0.5, 0.75, 1}
+	if !slices.Equal(gs.crossfades[3], want) {
+		t.Errorf("crossfade progress = %v, want %v", gs.crossfades[3], want)
+	}
+	if len(vm.crossfades) != 0 {
+		t.Errorf("%d crossfades left, want none", len(vm.crossfades))
+	}
+}
+
+// TestChangePictureInstant tests that omitting ticks changes the picture at once,
+// and that it ends a crossfade already running.
+func TestChangePictureInstant(t *testing.T) {
+	vm, gs, picID := newCrossfadeTestVM(t)
+
+	vm.builtins["ChangePicture"](vm, []any{int64(0), int64(picID)})
+	if gs.castPics[0] != picID || len(gs.crossfades[0]) != 0 {
+		t.Fatalf("picture %d, crossfade %v; want picture %d without a crossfade", gs.castPics[0], gs.crossfades[0], picID)
+	}
+
+	vm.builtins["ChangePicture"](vm, []any{int64(0), int64(picID), int64(10)})
+	vm.builtins["ChangePicture"](vm, []any{int64(0), int64(picID), int64(0)})
+	if want := []float64{0, 1}; !slices.Equal(gs.crossfades[0], want) {
+		t.Errorf("crossfade progress = %v, want %v", gs.crossfades[0], want)
+	}
+	if len(vm.crossfades) != 0 {
+		t.Errorf("%d crossfades left, want none", len(vm.crossfades))
+	}
+}
+
+// TestChangePictureInvalidArgs tests that invalid arguments leave the cast as it is.
+func TestChangePictureInvalidArgs(t *testing.T) {
+	vm, gs, picID := newCrossfadeTestVM(t)
+
+	tests := []struct {
+		name string
+		args []any
+	}{
+		{"negative ticks", []any{int64(0), int64(picID), int64(-1)}},
+		{"missing picture", []any{int64(0), int64(99), int64(5)}},
+		{"too few arguments", []any{int64(0)}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			vm.builtins["ChangePicture"](vm, tt.args)
+			if _, ok := gs.castPics[0]; ok || len(gs.crossfades) != 0 || len(vm.crossfades) != 0 {
+				t.Errorf("cast changed: picture %v, crossfades %v", gs.castPics, gs.crossfades)
+			}
+		})
+	}
+}
+
+// TestChangePictureDelCast tests that DelCast stops the crossfade.
+func TestChangePictureDelCast(t *testing.T) {
+	vm, gs, picID := newCrossfadeTestVM(t)
+
+	vm.builtins["ChangePicture"](vm, []any{int64(0), int64(picID), int64(10)})
+	vm.builtins["DelCast"](vm, []any{int64(0)})
+	dispatchEvents(t, vm, NewEvent(EventTIME))
+	if len(vm.crossfades) != 0 || len(gs.crossfades[0]) != 1 {
+		t.Errorf("after DelCast: %d crossfades, progress %v; want none", len(vm.crossfades), gs.crossfades[0])
+	}
+}
@@ -0,0 +1,78 @@
+package vm
+
+import (
+	"github.com/zurustar/son-et/pkg/graphics"
+)
+
+// defaultCursorClickTicks は SetCursorClick でフレーム数を省略した場合の1コマのフレーム数
+const defaultCursorClickTicks = 3
+
+// registerCursorBuiltins registers built-in functions for the mouse cursor.
+// A custom cursor is a picture drawn on top of the screen at the mouse position
+// (offset by its hotspot), optionally playing a short animation on each click.
+// The system cursor is hidden while a custom cursor is shown.
+func (vm *VM) registerCursorBuiltins() {
+	// SetCursor: Show a picture as the mouse cursor
+	// SetCursor(pic_no[, hot_x, hot_y[, transparentColor]]) - (hot_x, hot_y) is the point placed at the mouse position
+	vm.RegisterBuiltinFunction("SetCursor", func(v *VM, args []any) (any, error) {
+		nums, ok := v.graphicsArgs("SetCursor", args, 1)
+		if !ok {
+			return nil, nil
+		}
+		c := &graphics.Cursor{PicID: int(nums[0])}
+		if len(nums) >= 3 {
+			c.HotX, c.HotY = int(nums[1]), int(nums[2])
+		}
+		if len(nums) >= 4 {
+			c.TransColor = graphics.ColorFromInt(int(nums[3]))
+		}
+		if err := v.graphicsSystem.SetCursor(c); err != nil {
+			v.log.Error("SetCursor failed", "error", err)
+		}
+		return nil, nil
+	})
+
+	// SetCursorClick: Play an animation when the left button is pressed
+	// SetCursorClick(pic_no, frames[, ticks]) - the picture is split horizontally into frames,
+	// each shown for ticks frames (default 3); SetCursorClick(-1, 0) removes the animation
+	vm.RegisterBuiltinFunction("SetCursorClick", func(v *VM, args []any) (any, error) {
+		nums, ok := v.graphicsArgs("SetCursorClick", args, 2)
+		if !ok {
+			return nil, nil
+		}
+		var click *graphics.CursorClick
+		if nums[1] > 0 {
+			click = &graphics.CursorClick{PicID: int(nums[0]), Frames: int(nums[1]), Ticks: defaultCursorClickTicks}
+			if len(nums) >= 3 {
+				click.Ticks = int(nums[2])
+			}
+		}
+		if err := v.graphicsSystem.SetCursorClick(click); err != nil {
+			v.log.Error("SetCursorClick failed", "error", err)
+		}
+		return nil, nil
+	})
+
+	// DelCursor: Remove the custom cursor and show the system cursor again (unless hidden by ShowSysCursor)
+	// DelCursor()
+	vm.RegisterBuiltinFunction("DelCursor", func(v *VM, args []any) (any, error) {
+		if _, ok := v.graphicsArgs("DelCursor", args, 0); !ok {
+			return nil, nil
+		}
+		if err := v.graphicsSystem.SetCursor(nil); err != nil {
+			v.log.Error("DelCursor failed", "error", err)
+		}
+		return nil, nil
+	})
+
+	// ShowSysCursor: Show or hide the system mouse cursor
+	// ShowSysCursor(flag) - 0 hides, non-zero shows
+	vm.RegisterBuiltinFunction("ShowSysCursor", func(v *VM, args []any) (any, error) {
+		nums, ok := v.graphicsArgs("ShowSysCursor", args, 1)
+		if !ok {
+			return nil, nil
+		}
+		v.graphicsSystem.SetSystemCursorVisible(nums[0] != 0)
+		return nil, nil
+	})
+}
@@ -0,0 +1,114 @@
+package vm
+
+import (
+	"testing"
+
+	"github.com/zurustar/son-et/pkg/graphics"
+	"github.com/zurustar/son-et/pkg/opcode"
+)
+
+func TestVMBuiltinCursorsRegistered(t *testing.T) {
+	vm := New([]opcode.OpCode{})
+	for _, name := range []string{"SetCursor", "SetCursorClick", "DelCursor", "ShowSysCursor"} {
+		if _, ok := vm.builtins[name]; !ok {
+			t.Errorf("expected %s to be registered as built-in function", name)
+		}
+	}
+}
+
+func TestVMBuiltinSetCursor(t *testing.T) {
+	vm := New([]opcode.OpCode{})
+	mockGS := newMockGraphicsSystem()
+	vm.SetGraphicsSystem(mockGS)
+	picID, _ := mockGS.CreatePic(16, 16)
+
+	vm.builtins["SetCursor"](vm, []any{int64(picID)})
+	if c := mockGS.cursor; c == nil || c.PicID != picID || c.HotX != 0 || c.TransColor != nil {
+		t.Fatalf("unexpected cursor: %+v", c)
+	}
+
+	vm.builtins["SetCursor"](vm, []any{int64(picID), int64(7), int64(8), int64(0xFF00FF)})
+	c := mockGS.cursor
+	if c == nil || c.HotX != 7 || c.HotY != 8 {
+		t.Fatalf("unexpected cursor: %+v", c)
+	}
+	if r, g, b, _ := c.TransColor.RGBA(); r>>8 != 0xFF || g != 0 || b>>8 != 0xFF {
+		t.Errorf("TransColor = %v, want magenta", c.TransColor)
+	}
+
+	vm.builtins["DelCursor"](vm, []any{})
+	if mockGS.cursor != nil {
+		t.Error("expected DelCursor to remove the cursor")
+	}
+}
+
+func TestVMBuiltinSetCursorClick(t *testing.T) {
+	vm := New([]opcode.OpCode{})
+	mockGS := newMockGraphicsSystem()
+	vm.SetGraphicsSystem(mockGS)
+	picID, _ := mockGS.CreatePic(16, 16)
+	vm.builtins["SetCursor"](vm, []any{int64(picID)})
+
+	vm.builtins["SetCursorClick"](vm, []any{int64(picID), int64(4)})
+	want := graphics.CursorClick{PicID: picID, Frames: 4, Ticks: defaultCursorClickTicks}
+	if got := mockGS.cursorClick; got == nil || *got != want {
+		t.Fatalf("click = %+v, want %+v", got, want)
+	}
+
+	vm.builtins["SetCursorClick"](vm, []any{int64(picID), int64(2), int64(5)})
+	if got := mockGS.cursorClick; got == nil || got.Ticks != 5 {
+		t.Fatalf("click = %+v, want 5 ticks per frame", got)
+	}
+
+	vm.builtins["SetCursorClick"](vm, []any{int64(-1), int64(0)})
+	if mockGS.cursorClick != nil {
+		t.Error("expected frames 0 to remove the click animation")
+	}
+}
+
+func TestVMBuiltinShowSysCursor(t *testing.T) {
+	vm := New([]opcode.OpCode{})
+	mockGS := newMockGraphicsSystem()
+	vm.SetGraphicsSystem(mockGS)
+
+	vm.builtins["ShowSysCursor"](vm, []any{int64(0)})
+	if !mockGS.sysCursorOff {
+		t.Error("expected ShowSysCursor(0) to hide the system cursor")
+	}
+	vm.builtins["ShowSysCursor"](vm, []any{int64(1)})
+	if mockGS.sysCursorOff {
+		t.Error("expected ShowSysCursor(1) to show the system cursor")
+	}
+}
+
+func TestVMBuiltinCursorInvalidArgs(t *testing.T) {
+	vm := New([]opcode.OpCode{})
+	mockGS := newMockGraphicsSystem()
+	vm.SetGraphicsSystem(mockGS)
+
+	// 不正な引数ではエラーを記録するだけでスクリプトは継続する
+	for _, call := range []struct {
+		name string
+		args []any
+	}{
+		{"SetCursor", []any{}},
+		{"SetCursor", []any{"pic"}},
+		{"SetCursor", []any{int64(99)}},
+		{"SetCursorClick", []any{int64(1)}},
+		{"SetCursorClick", []any{int64(1), int64(2)}}, // カスタムカーソルが設定されていない
+		{"ShowSysCursor", []any{}},
+	} {
+		if _, err := vm.builtins[call.name](vm, call.args); err != nil {
+			t.Errorf("%s(%v) returned an error: %v", call.name, call.args, err)
+		}
+	}
+	if mockGS.cursor != nil || mockGS.cursorClick != nil || mockGS.sysCursorOff {
+		t.Error("expected invalid calls not to change the cursor")
+	}
+
+	// グラフィックスシステムがない場合も何もしない
+	noGS := New([]opcode.OpCode{})
+	if _, err := noGS.builtins["SetCursor"](noGS, []any{int64(1)}); err != nil {
+		t.Errorf("expected no error without a graphics system, got %v", err)
+	}
+}
@@ -0,0 +1,121 @@
+package vm
+
+import (
+	"time"
+
+	"github.com/zurustar/son-et/pkg/graphics"
+)
+
+// maxShadowAlpha は SetShadow の不透明度の最大値（完全に不透明）
+const maxShadowAlpha = 255
+
+// registerEffectBuiltins registers built-in functions for cast drop shadows and outlines,
+// and for the Shake/Flash hit effects.
+// Shadows and outlines are drawn under the cast following the shape of its opaque pixels,
+// which makes white text readable over photos without preparing a second picture.
+// Shake and Flash are animated by the graphics system every frame, so a hit reaction
+// costs one call instead of a MoveCast loop in the script.
+func (vm *VM) registerEffectBuiltins() {
+	// SetShadow: Draw a drop shadow under a cast
+	// SetShadow(cast_no, dx, dy, color, alpha) - alpha is 0 (invisible) to 255 (opaque)
+	vm.RegisterBuiltinFunction("SetShadow", func(v *VM, args []any) (any, error) {
+		nums, ok := v.graphicsArgs("SetShadow", args, 5)
+		if !ok {
+			return nil, nil
+		}
+		alpha := min(max(nums[4], 0), maxShadowAlpha)
+		shadow := &graphics.Shadow{
+			DX:    int(nums[1]),
+			DY:    int(nums[2]),
+			Color: graphics.ColorFromInt(v.compatColor(int(nums[3]))),
+			Alpha: alpha / maxShadowAlpha,
+		}
+		if err := v.graphicsSystem.SetCastShadow(int(nums[0]), shadow); err != nil {
+			v.log.Error("SetShadow failed", "error", err)
+		}
+		return nil, nil
+	})
+
+	// DelShadow: Remove the shadow of a cast
+	// DelShadow(cast_no)
+	vm.RegisterBuiltinFunction("DelShadow", func(v *VM, args []any) (any, error) {
+		nums, ok := v.graphicsArgs("DelShadow", args, 1)
+		if !ok {
+			return nil, nil
+		}
+		if err := v.graphicsSystem.SetCastShadow(int(nums[0]), nil); err != nil {
+			v.log.Error("DelShadow failed", "error", err)
+		}
+		return nil, nil
+	})
+
+	// SetOutline: Draw an outline around the opaque pixels of a cast
+	// SetOutline(cast_no, color, width) - width is 1 to graphics.MaxOutlineWidth pixels
+	vm.RegisterBuiltinFunction("SetOutline", func(v *VM, args []any) (any, error) {
+		nums, ok := v.graphicsArgs("SetOutline", args, 3)
+		if !ok {
+			return nil, nil
+		}
+		outline := &graphics.Outline{
+			Color: graphics.ColorFromInt(v.compatColor(int(nums[1]))),
+			Width: int(nums[2]),
+		}
+		if err := v.graphicsSystem.SetCastOutline(int(nums[0]), outline); err != nil {
+			v.log.Error("SetOutline failed", "error", err)
+		}
+		return nil, nil
+	})
+
+	// DelOutline: Remove the outline of a cast
+	// DelOutline(cast_no)
+	vm.RegisterBuiltinFunction("DelOutline", func(v *VM, args []any) (any, error) {
+		nums, ok := v.graphicsArgs("DelOutline", args, 1)
+		if !ok {
+			return nil, nil
+		}
+		if err := v.graphicsSystem.SetCastOutline(int(nums[0]), nil); err != nil {
+			v.log.Error("DelOutline failed", "error", err)
+		}
+		return nil, nil
+	})
+	// Shake: Shake a cast, e.g. when it is hit
+	// Shake(cast_no, amplitude, ticks) - the shake starts amplitude pixels wide and settles
+	// back to the cast's position over ticks (same length as the FadeOut ticks).
+	// Only the drawn position moves; CastAt and MoveCast still use the cast's position.
+	// Shake(cast_no, 0, 0) stops a running shake.
+	vm.RegisterBuiltinFunction("Shake", func(v *VM, args []any) (any, error) {
+		nums, ok := v.graphicsArgs("Shake", args, 3)
+		if !ok {
+			return nil, nil
+		}
+		if nums[1] < 0 || nums[2] < 0 {
+			v.log.Error("Shake: amplitude and ticks must be non-negative", "amplitude", nums[1], "ticks", nums[2])
+			return nil, nil
+		}
+		duration := time.Duration(nums[2]) * fadeTickDuration
+		if err := v.graphicsSystem.ShakeCast(int(nums[0]), int(nums[1]), duration); err != nil {
+			v.log.Error("Shake failed", "error", err)
+		}
+		return nil, nil
+	})
+
+	// Flash: Fill a cast with a color and fade back to its picture
+	// Flash(cast_no, color, ticks) - the transparent pixels stay transparent.
+	// Flash(cast_no, color, 0) stops a running flash.
+	vm.RegisterBuiltinFunction("Flash", func(v *VM, args []any) (any, error) {
+		nums, ok := v.graphicsArgs("Flash", args, 3)
+		if !ok {
+			return nil, nil
+		}
+		if nums[2] < 0 {
+			v.log.Error("Flash: ticks must be non-negative", "ticks", nums[2])
+			return nil, nil
+		}
+		c := graphics.ColorFromInt(v.compatColor(int(nums[1])))
+		duration := time.Duration(nums[2]) * fadeTickDuration
+		if err := v.graphicsSystem.FlashCast(int(nums[0]), c, duration); err != nil {
+			v.log.Error("Flash failed", "error", err)
+		}
+		return nil, nil
+	})
+}
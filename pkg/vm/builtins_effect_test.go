@@ -0,0 +1,139 @@
+package vm
+
+import (
+	"testing"
+	"time"
+
+	"github.com/zurustar/son-et/pkg/graphics"
+	"github.com/zurustar/son-et/pkg/opcode"
+)
+
+func TestVMBuiltinEffectsRegistered(t *testing.T) {
+	vm := New([]opcode.OpCode{})
+	for _, name := range []string{"SetShadow", "DelShadow", "SetOutline", "DelOutline", "Shake", "Flash"} {
+		if _, ok := vm.builtins[name]; !ok {
+			t.Errorf("expected %s to be registered as built-in function", name)
+		}
+	}
+}
+
+func TestVMBuiltinSetShadow(t *testing.T) {
+	vm, mockGS := newMaskTestVM()
+	vm.builtins["SetShadow"](vm, []any{int64(2), int64(3), int64(-4), int64(0x102030), int64(255)})
+	got := mockGS.shadows[2]
+	if got == nil {
+		t.Fatal("expected a shadow to be set on cast 2")
+	}
+	want := graphics.Shadow{DX: 3, DY: -4, Color: graphics.ColorFromInt(0x102030), Alpha: 1}
+	if *got != want {
+		t.Errorf("shadow = %+v, want %+v", *got, want)
+	}
+
+	vm.builtins["DelShadow"](vm, []any{int64(2)})
+	if _, ok := mockGS.shadows[2]; ok {
+		t.Error("expected DelShadow to remove the shadow")
+	}
+}
+
+func TestVMBuiltinSetShadowClampsAlpha(t *testing.T) {
+	for _, tt := range []struct {
+		alpha int64
+		want  float64
+	}{
+		{-10, 0},
+		{0, 0},
+		{51, 0.2},
+		{1000, 1},
+	} {
+		vm, mockGS := newMaskTestVM()
+		vm.builtins["SetShadow"](vm, []any{int64(1), int64(2), int64(2), int64(0), tt.alpha})
+		if got := mockGS.shadows[1]; got == nil || got.Alpha != tt.want {
+			t.Errorf("alpha %d: shadow = %+v, want Alpha=%v", tt.alpha, got, tt.want)
+		}
+	}
+}
+
+func TestVMBuiltinSetOutline(t *testing.T) {
+	vm, mockGS := newMaskTestVM()
+	vm.builtins["SetOutline"](vm, []any{int64(5), int64(0xFFFFFF), int64(2)})
+	want := graphics.Outline{Color: graphics.ColorFromInt(0xFFFFFF), Width: 2}
+	if got := mockGS.outlines[5]; got == nil || *got != want {
+		t.Fatalf("outline = %+v, want %+v", got, want)
+	}
+
+	vm.builtins["DelOutline"](vm, []any{int64(5)})
+	if len(mockGS.outlines) != 0 {
+		t.Errorf("expected DelOutline to remove the outline, got %v", mockGS.outlines)
+	}
+}
+
+func TestVMBuiltinEffectInvalidArgs(t *testing.T) {
+	vm, mockGS := newMaskTestVM()
+	for name, args := range map[string][]any{
+		"SetShadow":  {int64(1), int64(2), int64(2), int64(0)},
+		"SetOutline": {int64(1), "white", int64(2)},
+		"DelShadow":  {},
+		"DelOutline": {},
+	} {
+		if result, err := vm.builtins[name](vm, args); result != nil || err != nil {
+			t.Errorf("%s(%v) = (%v, %v), want (nil, nil)", name, args, result, err)
+		}
+	}
+	if len(mockGS.shadows) != 0 || len(mockGS.outlines) != 0 {
+		t.Errorf("expected no effects to be set, got shadows=%v outlines=%v", mockGS.shadows, mockGS.outlines)
+	}
+
+	// グラフィックスシステムがない場合も失敗しない
+	noGS := New([]opcode.OpCode{})
+	if _, err := noGS.builtins["SetOutline"](noGS, []any{int64(1), int64(0), int64(1)}); err != nil {
+		t.Errorf("expected no error without graphics system, got %v", err)
+	}
+}
+
+func TestVMBuiltinShakeAndFlash(t *testing.T) {
+	vm, mockGS := newMaskTestVM()
+	vm.builtins["Shake"](vm, []any{int64(3), int64(6), int64(4)})
+	vm.builtins["Flash"](vm, []any{int64(3), int64(0xFFFFFF), int64(2)})
+	vm.builtins["Shake"](vm, []any{int64(3), int64(0), int64(0)})
+
+	want := []mockHitEffect{
+		{name: "shake", castID: 3, amplitude: 6, duration: 200 * time.Millisecond},
+		{name: "flash", castID: 3, color: graphics.ColorFromInt(0xFFFFFF), duration: 100 * time.Millisecond},
+		{name: "shake", castID: 3},
+	}
+	if len(mockGS.hitEffects) != len(want) {
+		t.Fatalf("hit effects = %+v, want %+v", mockGS.hitEffects, want)
+	}
+	for i, got := range mockGS.hitEffects {
+		if got != want[i] {
+			t.Errorf("hit effect %d = %+v, want %+v", i, got, want[i])
+		}
+	}
+}
+
+func TestVMBuiltinShakeAndFlashInvalidArgs(t *testing.T) {
+	vm, mockGS := newMaskTestVM()
+	for _, tt := range []struct {
+		name string
+		args []any
+	}{
+		{"Shake", []any{int64(1), int64(4)}},
+		{"Shake", []any{int64(1), int64(-4), int64(2)}},
+		{"Shake", []any{int64(1), int64(4), int64(-2)}},
+		{"Flash", []any{int64(1), "white", int64(2)}},
+		{"Flash", []any{int64(1), int64(0), int64(-1)}},
+	} {
+		if result, err := vm.builtins[tt.name](vm, tt.args); result != nil || err != nil {
+			t.Errorf("%s(%v) = (%v, %v), want (nil, nil)", tt.name, tt.args, result, err)
+		}
+	}
+	if len(mockGS.hitEffects) != 0 {
+		t.Errorf("expected no effects to be started, got %+v", mockGS.hitEffects)
+	}
+
+	// グラフィックスシステムがない場合も失敗しない
+	noGS := New([]opcode.OpCode{})
+	if _, err := noGS.builtins["Shake"](noGS, []any{int64(1), int64(4), int64(2)}); err != nil {
+		t.Errorf("expected no error without graphics system, got %v", err)
+	}
+}
@@ -0,0 +1,166 @@
+package vm
+
+import (
+	"fmt"
+
+	"github.com/zurustar/son-et/pkg/graphics"
+)
+
+// Flipbooks
+//
+// PutFlipbook shows an animated GIF as a cast whose frame advances on the tick
+// clock instead of wall time: TIME ticks follow the playback speed (the speed
+// command and hotkeys), and MIDI_TIME ticks also follow the tempo of the music,
+// so looping decorations stay in step with the title when either changes.
+
+// flipbookTickDelay is the length of a TIME tick (50 ms) in the 1/100 s delays of GIF frames
+const flipbookTickDelay = 5
+
+// Flipbook clocks (the TIME and MIDI_TIME event type constants)
+const (
+	flipbookClockTime     = 0
+	flipbookClockMIDITime = 1
+)
+
+// flipbook is a cast showing the frames of an animated GIF.
+type flipbook struct {
+	clock     EventType
+	frames    []int // picture ID of each frame
+	durations []int // ticks each frame is shown
+	frame     int   // index of the frame shown
+	elapsed   int   // ticks the frame has been shown
+}
+
+// registerFlipbookBuiltins registers PutFlipbook.
+func (vm *VM) registerFlipbookBuiltins() {
+	// PutFlipbook("file.gif", pic, x, y[, clock[, ticks]]) - places the animated GIF
+	// on pic like PutCast and loops its frames.
+	// clock is TIME (default) or MIDI_TIME. ticks is how many ticks each frame is
+	// shown; if omitted or 0, the delay of each GIF frame is converted to ticks of
+	// 50 ms (for MIDI_TIME, the GIF plays at its own speed at tempo 150).
+	// The frames are pictures owned by the cast and are deleted with DelCast.
+	// Returns the cast ID, or -1 on error.
+	vm.RegisterBuiltinFunction("PutFlipbook", func(v *VM, args []any) (any, error) {
+		if v.graphicsSystem == nil {
+			v.log.Debug("PutFlipbook called but graphics system not initialized", "args", args)
+			return -1, nil
+		}
+		if len(args) < 4 {
+			v.log.Warn("PutFlipbook requires at least 4 arguments (filename, pic, x, y)")
+			return -1, nil
+		}
+		filename, ok := args[0].(string)
+		if !ok {
+			v.log.Error("PutFlipbook: filename must be a string", "got", fmt.Sprintf("%T", args[0]))
+			return -1, nil
+		}
+		nums := make([]int64, len(args)-1)
+		for i, arg := range args[1:] {
+			if nums[i], ok = toInt64(arg); !ok {
+				v.log.Error("PutFlipbook: arguments must be integers", "index", i+1, "got", fmt.Sprintf("%T", arg))
+				return -1, nil
+			}
+		}
+		clock := EventTIME
+		if len(nums) >= 4 {
+			switch nums[3] {
+			case flipbookClockTime:
+			case flipbookClockMIDITime:
+				clock = EventMIDI_TIME
+			default:
+				v.log.Error("PutFlipbook: clock must be TIME or MIDI_TIME", "got", nums[3])
+				return -1, nil
+			}
+		}
+		ticks := int64(0)
+		if len(nums) >= 5 {
+			if ticks = nums[4]; ticks < 0 {
+				v.log.Error("PutFlipbook: ticks must not be negative", "got", ticks)
+				return -1, nil
+			}
+		}
+
+		frames, delays, err := v.graphicsSystem.LoadPicFrames(filename)
+		if err != nil {
+			v.log.Error("PutFlipbook: failed to load frames", "filename", filename, "error", err)
+			return -1, nil
+		}
+		w := v.graphicsSystem.PicWidth(frames[0])
+		h := v.graphicsSystem.PicHeight(frames[0])
+		castID, err := v.graphicsSystem.PutCast(frames[0], int(nums[0]), int(nums[1]), int(nums[2]), 0, 0, w, h)
+		if err != nil {
+			v.log.Warn("PutFlipbook failed", "error", err)
+			v.deleteFlipbookFrames(frames)
+			return -1, nil
+		}
+
+		fb := &flipbook{clock: clock, frames: frames, durations: flipbookDurations(delays, int(ticks))}
+		// The cast ID may be reused from a flipbook whose cast was deleted with its window
+		v.stopFlipbook(castID)
+		if v.flipbooks == nil {
+			v.flipbooks = make(map[int]*flipbook)
+		}
+		v.flipbooks[castID] = fb
+		if clock == EventTIME {
+			v.StartTimer()
+		}
+		v.log.Debug("PutFlipbook called", "filename", filename, "castID", castID, "frames", len(frames), "clock", clock)
+		return castID, nil
+	})
+}
+
+// flipbookDurations returns the ticks each frame is shown: ticks for every frame,
+// or the GIF delays in 50 ms ticks (at least 1) if ticks is 0.
+func flipbookDurations(delays []int, ticks int) []int {
+	durations := make([]int, len(delays))
+	for i, delay := range delays {
+		if ticks > 0 {
+			durations[i] = ticks
+		} else {
+			durations[i] = max((delay+flipbookTickDelay/2)/flipbookTickDelay, 1)
+		}
+	}
+	return durations
+}
+
+// advanceFlipbooks advances the flipbooks driven by the clock of a dispatched event.
+func (vm *VM) advanceFlipbooks(event *Event) {
+	if event.Type != EventTIME && event.Type != EventMIDI_TIME {
+		return
+	}
+	for castID, fb := range vm.flipbooks {
+		if fb.clock != event.Type || len(fb.frames) < 2 {
+			continue
+		}
+		fb.elapsed++
+		if fb.elapsed < fb.durations[fb.frame] {
+			continue
+		}
+		fb.elapsed = 0
+		fb.frame = (fb.frame + 1) % len(fb.frames)
+		if err := vm.graphicsSystem.MoveCastWithOptions(castID, graphics.WithCastPicID(fb.frames[fb.frame])); err != nil {
+			// The cast was deleted without DelCast (e.g. with its window)
+			vm.log.Debug("Flipbook stopped", "castID", castID, "error", err)
+			vm.stopFlipbook(castID)
+		}
+	}
+}
+
+// stopFlipbook stops the flipbook of a deleted cast and deletes its frames.
+func (vm *VM) stopFlipbook(castID int) {
+	fb, ok := vm.flipbooks[castID]
+	if !ok {
+		return
+	}
+	delete(vm.flipbooks, castID)
+	vm.deleteFlipbookFrames(fb.frames)
+}
+
+// deleteFlipbookFrames deletes the pictures of flipbook frames.
+func (vm *VM) deleteFlipbookFrames(frames []int) {
+	for _, picID := range frames {
+		if err := vm.graphicsSystem.DelPic(picID); err != nil {
+			vm.log.Debug("Flipbook frame already deleted", "picID", picID, "error", err)
+		}
+	}
+}
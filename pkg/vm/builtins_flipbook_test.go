@@ -0,0 +1,132 @@
+package vm
+
+import (
+	"testing"
+
+	"github.com/zurustar/son-et/pkg/opcode"
+)
+
+// newFlipbookTestVM places the 3 frames of the mock GIF (pictures 0-2) as cast 0.
+func newFlipbookTestVM(t *testing.T, args ...any) (*VM, *mockGraphicsSystem) {
+	t.Helper()
+	vm := New([]opcode.OpCode{})
+	gs := newMockGraphicsSystem()
+	vm.SetGraphicsSystem(gs)
+	result, _ := vm.builtins["PutFlipbook"](vm, append([]any{"anim.gif", int64(5), int64(10), int64(20)}, args...))
+	if result != 0 {
+		t.Fatalf("PutFlipbook = %v, want cast 0", result)
+	}
+	return vm, gs
+}
+
+// tickFlipbook dispatches n events of the given type and returns the picture shown by cast 0.
+func tickFlipbook(t *testing.T, vm *VM, gs *mockGraphicsSystem, eventType EventType, n int) int {
+	t.Helper()
+	for range n {
+		dispatchEvents(t, vm, NewEvent(eventType))
+	}
+	if pic, ok := gs.castPics[0]; ok {
+		return pic
+	}
+	return 0
+}
+
+func TestVMBuiltinPutFlipbookRegistered(t *testing.T) {
+	vm := New([]opcode.OpCode{})
+	if _, ok := vm.builtins["PutFlipbook"]; !ok {
+		t.Error("expected PutFlipbook to be registered as built-in function")
+	}
+}
+
+// TestPutFlipbookGIFDelays tests that frames follow the GIF delays in 50 ms TIME ticks
+// and loop, and that MIDI_TIME does not advance a TIME flipbook.
+func TestPutFlipbookGIFDelays(t *testing.T) {
+	vm, gs := newFlipbookTestVM(t)
+
+	// 10, 20 and 5 (1/100 s) are 2, 4 and 1 ticks
+	steps := []struct {
+		eventType EventType
+		n         int
+		want      int
+	}{
+		{EventTIME, 1, 0},
+		{EventMIDI_TIME, 5, 0},
+		{EventTIME, 1, 1},
+		{EventTIME, 3, 1},
+		{EventTIME, 1, 2},
+		{EventTIME, 1, 0},
+	}
+	for i, step := range steps {
+		if got := tickFlipbook(t, vm, gs, step.eventType, step.n); got != step.want {
+			t.Fatalf("step %d: picture = %d, want %d", i, got, step.want)
+		}
+	}
+}
+
+// TestPutFlipbookMIDIClock tests a flipbook driven by MIDI_TIME with a fixed frame length.
+func TestPutFlipbookMIDIClock(t *testing.T) {
+	vm, gs := newFlipbookTestVM(t, int64(1), int64(3))
+
+	if got := tickFlipbook(t, vm, gs, EventTIME, 10); got != 0 {
+		t.Errorf("TIME advanced a MIDI_TIME flipbook to picture %d", got)
+	}
+	if got := tickFlipbook(t, vm, gs, EventMIDI_TIME, 2); got != 0 {
+		t.Errorf("picture after 2 ticks = %d, want 0", got)
+	}
+	if got := tickFlipbook(t, vm, gs, EventMIDI_TIME, 1); got != 1 {
+		t.Errorf("picture after 3 ticks = %d, want 1", got)
+	}
+	if got := tickFlipbook(t, vm, gs, EventMIDI_TIME, 3); got != 2 {
+		t.Errorf("picture after 6 ticks = %d, want 2", got)
+	}
+}
+
+// TestPutFlipbookDelCast tests that DelCast stops the flipbook and deletes its frames.
+func TestPutFlipbookDelCast(t *testing.T) {
+	vm, gs := newFlipbookTestVM(t)
+
+	vm.builtins["DelCast"](vm, []any{int64(0)})
+	if len(vm.flipbooks) != 0 || len(gs.pictures) != 0 {
+		t.Errorf("after DelCast: %d flipbooks, %d pictures; want none", len(vm.flipbooks), len(gs.pictures))
+	}
+	if got := tickFlipbook(t, vm, gs, EventTIME, 10); got != 0 {
+		t.Errorf("deleted flipbook advanced to picture %d", got)
+	}
+}
+
+// TestPutFlipbookErrors tests the results for invalid arguments.
+func TestPutFlipbookErrors(t *testing.T) {
+	vm := New([]opcode.OpCode{})
+	gs := newMockGraphicsSystem()
+	vm.SetGraphicsSystem(gs)
+
+	tests := [][]any{
+		{"anim.gif", int64(0), int64(0)},
+		{int64(1), int64(0), int64(0), int64(0)},
+		{"missing.gif", int64(0), int64(0), int64(0)},
+		{"anim.gif", int64(0), int64(0), int64(0), int64(2)},
+		{"anim.gif", int64(0), int64(0), int64(0), int64(0), int64(-1)},
+		{"anim.gif", int64(0), "x", int64(0)},
+	}
+	for _, args := range tests {
+		if result, _ := vm.builtins["PutFlipbook"](vm, args); result != -1 {
+			t.Errorf("PutFlipbook(%v) = %v, want -1", args, result)
+		}
+	}
+	if len(vm.flipbooks) != 0 || len(gs.pictures) != 0 {
+		t.Errorf("failed calls left %d flipbooks, %d pictures", len(vm.flipbooks), len(gs.pictures))
+	}
+}
+
+func TestFlipbookDurations(t *testing.T) {
+	got := flipbookDurations([]int{0, 2, 3, 10, 12}, 0)
+	want := []int{1, 1, 1, 2, 2}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("flipbookDurations = %v, want %v", got, want)
+		}
+	}
+	if got := flipbookDurations([]int{0, 50}, 3); got[0] != 3 || got[1] != 3 {
+		t.Errorf("flipbookDurations with ticks = %v, want [3 3]", got)
+	}
+}
@@ -2,6 +2,7 @@ package vm
 
 import (
 	"fmt"
+	"time"
 
 	"github.com/zurustar/son-et/pkg/graphics"
 )
@@ -34,6 +35,31 @@ func (vm *VM) registerGraphicsBuiltins() {
 		return picID, nil
 	})
 
+	// LoadPicAsync: Load a picture without waiting for it to be decoded
+	// LoadPicAsync(filename) - returns the picture number immediately; the picture is decoded on a
+	// worker and a PIC_READY event (MesP1 = picture number, MesP2 = 1 or 0 on failure) is sent when done.
+	// Drawing from the picture before then waits for the decoding.
+	vm.RegisterBuiltinFunction("LoadPicAsync", func(v *VM, args []any) (any, error) {
+		if v.graphicsSystem == nil {
+			v.log.Debug("LoadPicAsync called but graphics system not initialized", "args", args)
+			return -1, nil
+		}
+		if len(args) < 1 {
+			return nil, fmt.Errorf("LoadPicAsync requires filename argument")
+		}
+		filename, ok := args[0].(string)
+		if !ok {
+			v.log.Error("LoadPicAsync filename must be string", "got", fmt.Sprintf("%T", args[0]))
+			return -1, nil
+		}
+		picID, err := v.graphicsSystem.LoadPicAsync(filename, v.pushPicReady)
+		if err != nil {
+			return -1, fmt.Errorf("LoadPicAsync failed")
+		}
+		v.log.Debug("LoadPicAsync called", "filename", filename, "picID", picID)
+		return picID, nil
+	})
+
 	// CreatePic: Create a picture
 	// Supports three patterns:
 	// - CreatePic(srcPicID) - create from existing picture (same size)
@@ -462,9 +488,9 @@ func (vm *VM) registerGraphicsBuiltins() {
 			if len(args) >= 1 {
 				infoType, _ := toInt64(args[0])
 				if infoType == 0 {
-					return 1024, nil // default width
+					return defaultScreenWidth, nil
 				}
-				return 768, nil // default height
+				return defaultScreenHeight, nil
 			}
 			return 0, nil
 		}
@@ -711,14 +737,52 @@ func (vm *VM) registerGraphicsBuiltins() {
 		if err := v.graphicsSystem.DelCast(int(castID)); err != nil {
 			v.log.Error("DelCast failed", "castID", castID, "error", err)
 		}
+		v.stopSpriteTicks(int(castID))
+		v.stopFlipbook(int(castID))
+		delete(v.crossfades, int(castID))
 		v.log.Debug("DelCast called", "castID", castID)
 		return nil, nil
 	})
 
+	// ===== Render Order =====
+	// Windows are ordered among themselves and casts within their window,
+	// so moving a window also moves every cast drawn in it.
+
+	// BringWinToFront: Move a window in front of all other windows
+	// BringWinToFront(win_no)
+	vm.RegisterBuiltinFunction("BringWinToFront", func(v *VM, args []any) (any, error) {
+		v.restackByID("BringWinToFront", args, func(id int) error { return v.graphicsSystem.BringWinToFront(id) })
+		return nil, nil
+	})
+
+	// SendWinToBack: Move a window behind all other windows
+	// SendWinToBack(win_no)
+	vm.RegisterBuiltinFunction("SendWinToBack", func(v *VM, args []any) (any, error) {
+		v.restackByID("SendWinToBack", args, func(id int) error { return v.graphicsSystem.SendWinToBack(id) })
+		return nil, nil
+	})
+
+	// BringCastToFront: Move a cast in front of the other casts in its window
+	// BringCastToFront(cast_no)
+	vm.RegisterBuiltinFunction("BringCastToFront", func(v *VM, args []any) (any, error) {
+		v.restackByID("BringCastToFront", args, func(id int) error { return v.graphicsSystem.BringCastToFront(id) })
+		return nil, nil
+	})
+
+	// SendCastToBack: Move a cast behind the other casts in its window (but not behind the window's picture)
+	// SendCastToBack(cast_no)
+	vm.RegisterBuiltinFunction("SendCastToBack", func(v *VM, args []any) (any, error) {
+		v.restackByID("SendCastToBack", args, func(id int) error { return v.graphicsSystem.SendCastToBack(id) })
+		return nil, nil
+	})
+
 	// ===== Text Drawing =====
 
 	// TextWrite: Write text to a picture
-	// TextWrite(text, pic_no, x, y)
+	// TextWrite(text, pic_no, x, y) or TextWrite(text, pic_no, x, y, pitch[, tracking, line_spacing])
+	// pitch is 0/"default" (fixed for MS Gothic etc.), 1/"fixed" or 2/"proportional".
+	// tracking is added after each character and line_spacing between the lines of text
+	// containing "\n", in pixels (negative values tighten the layout).
 	vm.RegisterBuiltinFunction("TextWrite", func(v *VM, args []any) (any, error) {
 		if v.graphicsSystem == nil {
 			v.log.Debug("TextWrite called but graphics system not initialized", "args", args)
@@ -736,11 +800,13 @@ func (vm *VM) registerGraphicsBuiltins() {
 		picID, _ := toInt64(args[1])
 		x, _ := toInt64(args[2])
 		y, _ := toInt64(args[3])
+		pitch := v.fontPitchArg("TextWrite", args, 4)
+		layout := v.textLayoutArg("TextWrite", args, 5)
 
-		if err := v.graphicsSystem.TextWrite(int(picID), int(x), int(y), text); err != nil {
+		if err := v.graphicsSystem.TextWriteWithLayout(int(picID), int(x), int(y), text, pitch, layout); err != nil {
 			v.log.Error("TextWrite failed", "error", err)
 		}
-		v.log.Debug("TextWrite called", "text", text, "picID", picID, "x", x, "y", y)
+		v.log.Debug("TextWrite called", "text", text, "picID", picID, "x", x, "y", y, "pitch", pitch, "layout", layout)
 		return nil, nil
 	})
 
@@ -766,6 +832,22 @@ func (vm *VM) registerGraphicsBuiltins() {
 		return nil, nil
 	})
 
+	// TextWidth: Measure the width of text in pixels
+	// TextWidth(text) or TextWidth(text, font_name, size[, pitch, tracking, line_spacing])
+	// Uses the same font metrics as TextWrite, so scripts can center or right-align
+	// text before drawing it. Without font_name the current font (SetFont) is used.
+	vm.RegisterBuiltinFunction("TextWidth", func(v *VM, args []any) (any, error) {
+		width, _ := v.measureText("TextWidth", args)
+		return int64(width), nil
+	})
+
+	// TextHeight: Measure the line height of text in pixels
+	// TextHeight(text) or TextHeight(text, font_name, size[, pitch, tracking, line_spacing])
+	vm.RegisterBuiltinFunction("TextHeight", func(v *VM, args []any) (any, error) {
+		_, height := v.measureText("TextHeight", args)
+		return int64(height), nil
+	})
+
 	// TextColor: Set text color
 	// TextColor(r, g, b) or TextColor(color)
 	vm.RegisterBuiltinFunction("TextColor", func(v *VM, args []any) (any, error) {
@@ -790,7 +872,7 @@ func (vm *VM) registerGraphicsBuiltins() {
 			colorInt = int(colorInt64)
 		}
 
-		if err := v.graphicsSystem.SetTextColor(colorInt); err != nil {
+		if err := v.graphicsSystem.SetTextColor(v.compatColor(colorInt)); err != nil {
 			v.log.Error("TextColor failed", "error", err)
 		}
 		v.log.Debug("TextColor called", "color", fmt.Sprintf("0x%06X", colorInt))
@@ -821,7 +903,7 @@ func (vm *VM) registerGraphicsBuiltins() {
 			colorInt = int(colorInt64)
 		}
 
-		if err := v.graphicsSystem.SetBgColor(colorInt); err != nil {
+		if err := v.graphicsSystem.SetBgColor(v.compatColor(colorInt)); err != nil {
 			v.log.Error("BgColor failed", "error", err)
 		}
 		v.log.Debug("BgColor called", "color", fmt.Sprintf("0x%06X", colorInt))
@@ -847,6 +929,31 @@ func (vm *VM) registerGraphicsBuiltins() {
 		return nil, nil
 	})
 
+	// TextDirection: Set the writing direction of TextWrite (son-et extension)
+	// TextDirection(dir) - 0/"horizontal", 1/"vertical"
+	// In vertical mode TextWrite draws one top-to-bottom column whose top-left is (x, y),
+	// and TextWidth/TextHeight return the column's width and height.
+	vm.RegisterBuiltinFunction("TextDirection", func(v *VM, args []any) (any, error) {
+		if v.graphicsSystem == nil {
+			v.log.Debug("TextDirection called but graphics system not initialized", "args", args)
+			return nil, nil
+		}
+		if len(args) < 1 {
+			return nil, fmt.Errorf("TextDirection requires 1 argument (dir)")
+		}
+
+		direction, err := graphics.ParseTextDirection(args[0])
+		if err != nil {
+			v.log.Error("TextDirection: invalid direction", "error", err)
+			return nil, nil
+		}
+		if err := v.graphicsSystem.SetTextDirection(direction); err != nil {
+			v.log.Error("TextDirection failed", "error", err)
+		}
+		v.log.Debug("TextDirection called", "direction", direction)
+		return nil, nil
+	})
+
 	// ===== Shape Drawing =====
 
 	// DrawRect: Draw a rectangle
@@ -874,7 +981,7 @@ func (vm *VM) registerGraphicsBuiltins() {
 			g, _ := toInt64(args[6])
 			b, _ := toInt64(args[7])
 			colorInt := int(r)<<16 | int(g)<<8 | int(b)
-			if err := v.graphicsSystem.SetPaintColor(colorInt); err != nil {
+			if err := v.graphicsSystem.SetPaintColor(v.compatColor(colorInt)); err != nil {
 				v.log.Error("DrawRect SetPaintColor failed", "error", err)
 			}
 			fillMode = 0 // outline only when color is specified
@@ -1000,7 +1107,7 @@ func (vm *VM) registerGraphicsBuiltins() {
 			colorInt = int(colorInt64)
 		}
 
-		if err := v.graphicsSystem.SetPaintColor(colorInt); err != nil {
+		if err := v.graphicsSystem.SetPaintColor(v.compatColor(colorInt)); err != nil {
 			v.log.Error("SetPaintColor failed", "error", err)
 		}
 		v.log.Debug("SetPaintColor called", "color", fmt.Sprintf("0x%06X", colorInt))
@@ -1053,10 +1160,320 @@ func (vm *VM) registerGraphicsBuiltins() {
 			colorInt = int(colorInt64)
 		}
 
-		if err := v.graphicsSystem.SetPaintColor(colorInt); err != nil {
+		if err := v.graphicsSystem.SetPaintColor(v.compatColor(colorInt)); err != nil {
 			v.log.Error("SetColor failed", "error", err)
 		}
 		v.log.Debug("SetColor called", "color", fmt.Sprintf("0x%06X", colorInt))
 		return nil, nil
 	})
+
+	// FadeOut: Fade the whole screen to a color
+	// FadeOut(ticks) / FadeOut(ticks, color)
+	// The screen stays covered with the color until FadeIn is called.
+	// A FADE_END event (MesP1=1) is generated when the fade completes.
+	vm.RegisterBuiltinFunction("FadeOut", func(v *VM, args []any) (any, error) {
+		v.startScreenFade("FadeOut", true, args)
+		return nil, nil
+	})
+
+	// FadeIn: Fade the whole screen in from a color
+	// FadeIn(ticks) / FadeIn(ticks, color)
+	// A FADE_END event (MesP1=0) is generated when the fade completes.
+	vm.RegisterBuiltinFunction("FadeIn", func(v *VM, args []any) (any, error) {
+		v.startScreenFade("FadeIn", false, args)
+		return nil, nil
+	})
+
+	// SetPalette: Change the display color of a palette entry (256-color display emulation)
+	// SetPalette(index, color)
+	// Pixels mapped to the entry change color together, as on a 256-color display.
+	vm.RegisterBuiltinFunction("SetPalette", func(v *VM, args []any) (any, error) {
+		if v.graphicsSystem == nil {
+			v.log.Debug("SetPalette called but graphics system not initialized", "args", args)
+			return nil, nil
+		}
+		if len(args) < 2 {
+			v.log.Warn("SetPalette requires 2 arguments (index, color)")
+			return nil, nil
+		}
+		index, ok1 := toInt64(args[0])
+		c, ok2 := toInt64(args[1])
+		if !ok1 || !ok2 {
+			v.log.Error("SetPalette arguments must be integers", "args", args)
+			return nil, nil
+		}
+		if err := v.graphicsSystem.SetPaletteColor(int(index), int(c)); err != nil {
+			v.log.Error("SetPalette failed", "error", err)
+			return nil, nil
+		}
+		v.log.Debug("SetPalette called", "index", index, "color", fmt.Sprintf("0x%06X", c))
+		return nil, nil
+	})
+
+	// GetPalette: Get the current display color of a palette entry
+	// color = GetPalette(index)
+	// Returns -1 if the index is out of range.
+	vm.RegisterBuiltinFunction("GetPalette", func(v *VM, args []any) (any, error) {
+		if v.graphicsSystem == nil {
+			v.log.Debug("GetPalette called but graphics system not initialized", "args", args)
+			return -1, nil
+		}
+		if len(args) < 1 {
+			v.log.Warn("GetPalette requires 1 argument (index)")
+			return -1, nil
+		}
+		index, ok := toInt64(args[0])
+		if !ok {
+			v.log.Error("GetPalette index must be integer", "got", args[0])
+			return -1, nil
+		}
+		colorVal, err := v.graphicsSystem.GetPaletteColor(int(index))
+		if err != nil {
+			v.log.Error("GetPalette failed", "error", err)
+			return -1, nil
+		}
+		return colorVal, nil
+	})
+
+	// CyclePalette: Rotate the display colors of a range of palette entries (color cycling)
+	// CyclePalette(start, count) / CyclePalette(start, count, step)
+	// step defaults to 1; a negative step rotates toward lower indices.
+	vm.RegisterBuiltinFunction("CyclePalette", func(v *VM, args []any) (any, error) {
+		if v.graphicsSystem == nil {
+			v.log.Debug("CyclePalette called but graphics system not initialized", "args", args)
+			return nil, nil
+		}
+		if len(args) < 2 {
+			v.log.Warn("CyclePalette requires at least 2 arguments (start, count)")
+			return nil, nil
+		}
+		start, ok1 := toInt64(args[0])
+		count, ok2 := toInt64(args[1])
+		step := int64(1)
+		ok3 := true
+		if len(args) >= 3 {
+			step, ok3 = toInt64(args[2])
+		}
+		if !ok1 || !ok2 || !ok3 {
+			v.log.Error("CyclePalette arguments must be integers", "args", args)
+			return nil, nil
+		}
+		if err := v.graphicsSystem.CyclePalette(int(start), int(count), int(step)); err != nil {
+			v.log.Error("CyclePalette failed", "error", err)
+			return nil, nil
+		}
+		v.log.Debug("CyclePalette called", "start", start, "count", count, "step", step)
+		return nil, nil
+	})
+
+	// ResetPalette: Restore the default palette
+	// ResetPalette()
+	vm.RegisterBuiltinFunction("ResetPalette", func(v *VM, args []any) (any, error) {
+		if v.graphicsSystem == nil {
+			v.log.Debug("ResetPalette called but graphics system not initialized")
+			return nil, nil
+		}
+		v.graphicsSystem.ResetPalette()
+		v.log.Debug("ResetPalette called")
+		return nil, nil
+	})
+
+	// SetGamma: Set the gamma of the whole screen (1 = unchanged, >1 brightens midtones)
+	// SetGamma(gamma)
+	vm.RegisterBuiltinFunction("SetGamma", func(v *VM, args []any) (any, error) {
+		v.adjustDisplay("SetGamma", args, func(value float64) error {
+			return v.graphicsSystem.SetGamma(value)
+		})
+		return nil, nil
+	})
+
+	// SetBrightness: Set the brightness of the whole screen (-1 = black, 0 = unchanged, 1 = white)
+	// SetBrightness(brightness)
+	vm.RegisterBuiltinFunction("SetBrightness", func(v *VM, args []any) (any, error) {
+		v.adjustDisplay("SetBrightness", args, func(value float64) error {
+			return v.graphicsSystem.SetBrightness(value)
+		})
+		return nil, nil
+	})
+
+	// SetContrast: Set the contrast of the whole screen (0 = flat gray, 1 = unchanged)
+	// SetContrast(contrast)
+	vm.RegisterBuiltinFunction("SetContrast", func(v *VM, args []any) (any, error) {
+		v.adjustDisplay("SetContrast", args, func(value float64) error {
+			return v.graphicsSystem.SetContrast(value)
+		})
+		return nil, nil
+	})
+}
+
+// adjustDisplay は表示調整の組み込み関数の引数 (value) を解釈し、set を呼び出す。
+// 範囲外の値はエラーを記録するだけでスクリプトの実行は継続する。
+func (vm *VM) adjustDisplay(name string, args []any, set func(value float64) error) {
+	if vm.graphicsSystem == nil {
+		vm.log.Debug(name+" called but graphics system not initialized", "args", args)
+		return
+	}
+	if len(args) < 1 {
+		vm.log.Warn(name + " requires 1 argument")
+		return
+	}
+	value, ok := toFloat64(args[0])
+	if !ok {
+		vm.log.Error(name+" argument must be a number", "got", args[0])
+		return
+	}
+	if err := set(value); err != nil {
+		vm.log.Error(name+" failed", "error", err)
+		return
+	}
+	vm.log.Debug(name+" called", "value", value)
+}
+
+// restackByID は描画順を変更する組み込み関数の引数 (id) を解釈し、restack を呼び出す。
+// 存在しないIDはエラーを記録するだけでスクリプトの実行は継続する。
+func (vm *VM) restackByID(name string, args []any, restack func(id int) error) {
+	if vm.graphicsSystem == nil {
+		vm.log.Debug(name+" called but graphics system not initialized", "args", args)
+		return
+	}
+	if len(args) < 1 {
+		vm.log.Warn(name + " requires 1 argument")
+		return
+	}
+	id, ok := toInt64(args[0])
+	if !ok {
+		vm.log.Error(name+" argument must be integer", "got", args[0])
+		return
+	}
+	if err := restack(int(id)); err != nil {
+		vm.log.Error(name+" failed", "id", id, "error", err)
+		return
+	}
+	vm.log.Debug(name+" called", "id", id)
+}
+
+// fadeTickDuration は FadeOut/FadeIn の ticks 引数の1ティックの長さ（TIMEイベントの間隔と同じ）
+const fadeTickDuration = 50 * time.Millisecond
+
+// pushPicReady は LoadPicAsync で読み込んだピクチャーのデコードの完了を PIC_READY イベントとして送る
+func (vm *VM) pushPicReady(picID int, err error) {
+	ok := 1
+	if err != nil {
+		vm.log.Error("LoadPicAsync: failed to decode picture", "picID", picID, "error", err)
+		ok = 0
+	}
+	vm.eventQueue.Push(NewEventWithParams(EventPIC_READY, map[string]any{
+		"MesP1": picID,
+		"MesP2": ok,
+	}))
+}
+
+// defaultFadeColor は FadeOut/FadeIn で色を省略した場合の色（黒）
+const defaultFadeColor = 0x000000
+
+// startScreenFade は FadeOut/FadeIn の引数 (ticks[, color]) を解釈し、画面フェードを開始する。
+// フェードが完了すると FADE_END イベントをキューに追加する。
+func (vm *VM) startScreenFade(name string, fadeOut bool, args []any) {
+	if vm.graphicsSystem == nil {
+		vm.log.Debug(name+" called but graphics system not initialized", "args", args)
+		return
+	}
+	if len(args) < 1 {
+		vm.log.Warn(name + " requires at least 1 argument (ticks)")
+		return
+	}
+
+	ticks, ok := toInt64(args[0])
+	if !ok || ticks < 0 {
+		vm.log.Error(name+" ticks must be a non-negative integer", "got", args[0])
+		return
+	}
+	fadeColor := defaultFadeColor
+	if len(args) >= 2 {
+		c, ok := toInt64(args[1])
+		if !ok {
+			vm.log.Error(name+" color must be integer", "got", fmt.Sprintf("%T", args[1]))
+			return
+		}
+		fadeColor = int(c)
+	}
+
+	direction := 0
+	if fadeOut {
+		direction = 1
+	}
+	onDone := func() {
+		vm.eventQueue.Push(NewEventWithParams(EventFADE_END, map[string]any{
+			"MesP1": direction,
+		}))
+	}
+
+	duration := time.Duration(ticks) * fadeTickDuration
+	if err := vm.graphicsSystem.Fade(fadeOut, fadeColor, duration, onDone); err != nil {
+		vm.log.Error(name+" failed", "error", err)
+		return
+	}
+	vm.log.Debug(name+" called", "ticks", ticks, "color", fmt.Sprintf("0x%06X", fadeColor))
+}
+
+// measureText は TextWidth/TextHeight の引数 (text[, font_name, size, pitch, tracking, line_spacing]) を解釈し、
+// テキストの幅と高さを返す。グラフィックスシステムが未初期化の場合は 0, 0 を返す。
+func (vm *VM) measureText(name string, args []any) (int, int) {
+	if vm.graphicsSystem == nil {
+		vm.log.Debug(name+" called but graphics system not initialized", "args", args)
+		return 0, 0
+	}
+	if len(args) < 1 {
+		vm.log.Warn(name + " requires at least 1 argument (text)")
+		return 0, 0
+	}
+
+	text := toString(args[0])
+	fontName := ""
+	size := 0
+	if len(args) >= 2 {
+		fontName = toString(args[1])
+	}
+	if len(args) >= 3 {
+		if s, ok := toInt64(args[2]); ok {
+			size = int(s)
+		}
+	}
+
+	pitch := vm.fontPitchArg(name, args, 3)
+	layout := vm.textLayoutArg(name, args, 4)
+
+	return vm.graphicsSystem.TextExtentWithLayout(text, fontName, size, pitch, layout)
+}
+
+// textLayoutArg は args[index] 以降の字間と行間（ピクセル）を返す
+// 省略した場合は 0、数値でない場合は 0 として扱う（ログに記録する）
+func (vm *VM) textLayoutArg(name string, args []any, index int) graphics.TextLayout {
+	var spacing [2]int
+	for i := range spacing {
+		if len(args) <= index+i {
+			break
+		}
+		n, ok := toInt64(args[index+i])
+		if !ok {
+			vm.log.Warn(name+": spacing must be a number, using 0", "got", args[index+i])
+			continue
+		}
+		spacing[i] = int(n)
+	}
+	return graphics.TextLayout{Tracking: spacing[0], LineSpacing: spacing[1]}
+}
+
+// fontPitchArg は args[index] のピッチ（0〜2 または "fixed" などの名前）を返す
+// 省略した場合や不正な値の場合は graphics.PitchDefault（不正な値はログに記録する）
+func (vm *VM) fontPitchArg(name string, args []any, index int) graphics.FontPitch {
+	if len(args) <= index {
+		return graphics.PitchDefault
+	}
+	pitch, err := graphics.ParseFontPitch(args[index])
+	if err != nil {
+		vm.log.Warn(name+": invalid pitch, using the default", "error", err)
+		return graphics.PitchDefault
+	}
+	return pitch
 }
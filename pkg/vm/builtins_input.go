@@ -0,0 +1,225 @@
+package vm
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/zurustar/son-et/pkg/opcode"
+)
+
+// Modifier keys of key input (set as a bit mask in MesP3 of KEY events)
+const (
+	KeyModShift = 1
+	KeyModCtrl  = 2
+	KeyModAlt   = 4
+)
+
+// Windows virtual key codes assigned in ranges
+const (
+	vkDigit0  = 0x30 // '0' to '9' are 0x30 to 0x39
+	vkLetterA = 0x41 // 'A' to 'Z' are 0x41 to 0x5A
+	vkF1      = 0x70 // F1 to F12 are 0x70 to 0x7B
+	maxFKey   = 12
+)
+
+// keyNames maps the named keys OnKey accepts to Windows virtual key codes.
+var keyNames = map[string]int{
+	"BACK":      0x08,
+	"BACKSPACE": 0x08,
+	"TAB":       0x09,
+	"ENTER":     0x0D,
+	"RETURN":    0x0D,
+	"SPACE":     0x20,
+	"PAGEUP":    0x21,
+	"PAGEDOWN":  0x22,
+	"END":       0x23,
+	"HOME":      0x24,
+	"LEFT":      0x25,
+	"UP":        0x26,
+	"RIGHT":     0x27,
+	"DOWN":      0x28,
+	"INSERT":    0x2D,
+	"DELETE":    0x2E,
+}
+
+// modifierNames are the modifier names accepted in key specs.
+var modifierNames = map[string]int{
+	"SHIFT":   KeyModShift,
+	"CTRL":    KeyModCtrl,
+	"CONTROL": KeyModCtrl,
+	"ALT":     KeyModAlt,
+}
+
+// ParseKeySpec parses an OnKey key spec ("SPACE", "A", "F1", "CTRL+S", "SHIFT+ALT+X")
+// and returns the virtual key code and the modifier bit mask. It is case-insensitive.
+func ParseKeySpec(spec string) (keyCode, modifiers int, err error) {
+	parts := strings.Split(strings.ToUpper(strings.TrimSpace(spec)), "+")
+	for _, part := range parts[:len(parts)-1] {
+		mod, ok := modifierNames[strings.TrimSpace(part)]
+		if !ok {
+			return 0, 0, fmt.Errorf("unknown modifier %q in key %q", part, spec)
+		}
+		modifiers |= mod
+	}
+
+	name := strings.TrimSpace(parts[len(parts)-1])
+	if code, ok := keyNames[name]; ok {
+		return code, modifiers, nil
+	}
+	if len(name) == 1 {
+		switch c := name[0]; {
+		case c >= 'A' && c <= 'Z':
+			return vkLetterA + int(c-'A'), modifiers, nil
+		case c >= '0' && c <= '9':
+			return vkDigit0 + int(c-'0'), modifiers, nil
+		}
+	}
+	if strings.HasPrefix(name, "F") {
+		if n, convErr := strconv.Atoi(name[1:]); convErr == nil && n >= 1 && n <= maxFKey {
+			return vkF1 + n - 1, modifiers, nil
+		}
+	}
+	return 0, 0, fmt.Errorf("unknown key %q", spec)
+}
+
+// registerInputBuiltins registers built-in functions that bind script functions to input events.
+// Each binding is an ordinary event handler (a mes() sequence), so it is dispatched by the
+// scheduler like any other handler and can be removed with DelMes or paused with FreezeMes.
+func (vm *VM) registerInputBuiltins() {
+	// OnKey(key, "FuncName") / OnKey(key, "FuncName", repeat) - calls FuncName(keyCode, modifiers)
+	// when the key is pressed. key is a name ("SPACE", "A", "F1", "CTRL+S") or a virtual-key code.
+	// Modifiers must match exactly: OnKey("S", ...) does not fire for Ctrl+S.
+	// Auto-repeat presses are ignored unless repeat is non-zero.
+	// Returns the handler number (usable with DelMes).
+	vm.RegisterBuiltinFunction("OnKey", func(v *VM, args []any) (any, error) {
+		if len(args) < 2 {
+			v.log.Warn("OnKey requires 2 arguments (key, function)")
+			return nil, nil
+		}
+
+		var keyCode, modifiers int
+		if spec, isString := args[0].(string); isString {
+			var err error
+			keyCode, modifiers, err = ParseKeySpec(spec)
+			if err != nil {
+				v.log.Error("OnKey: invalid key", "error", err)
+				return nil, nil
+			}
+		} else if code, ok := toInt64(args[0]); ok {
+			keyCode = int(code)
+		} else {
+			v.log.Error("OnKey key must be a name or key code", "got", fmt.Sprintf("%T", args[0]))
+			return nil, nil
+		}
+
+		allowRepeat := false
+		if len(args) >= 3 {
+			if r, ok := toInt64(args[2]); ok {
+				allowRepeat = r != 0
+			}
+		}
+
+		filter := func(event *Event) bool {
+			if !eventParamEquals(event, "MesP2", keyCode) || !eventParamEquals(event, "MesP3", modifiers) {
+				return false
+			}
+			return allowRepeat || eventParamEquals(event, "MesP4", 0)
+		}
+		return v.registerInputHandler("OnKey", EventKEY, args[1], filter,
+			opcode.Variable("MesP2"), opcode.Variable("MesP3"))
+	})
+
+	// OnClick("FuncName") - calls FuncName(x, y) when the left mouse button is clicked
+	// anywhere. x and y are virtual desktop coordinates.
+	// Returns the handler number (usable with DelMes).
+	vm.RegisterBuiltinFunction("OnClick", func(v *VM, args []any) (any, error) {
+		if len(args) < 1 {
+			v.log.Warn("OnClick requires 1 argument (function)")
+			return nil, nil
+		}
+		return v.registerInputHandler("OnClick", EventCLICK, args[0], nil,
+			opcode.Variable("MesP2"), opcode.Variable("MesP3"))
+	})
+
+	// OnSpriteClick(castID, "FuncName") - calls FuncName(castID, x, y) when the cast is clicked.
+	// Only the topmost visible cast under the cursor receives the click.
+	// Returns the handler number (usable with DelMes).
+	vm.RegisterBuiltinFunction("OnSpriteClick", func(v *VM, args []any) (any, error) {
+		if len(args) < 2 {
+			v.log.Warn("OnSpriteClick requires 2 arguments (castID, function)")
+			return nil, nil
+		}
+
+		castID, ok := toInt64(args[0])
+		if !ok {
+			v.log.Error("OnSpriteClick castID must be integer", "got", fmt.Sprintf("%T", args[0]))
+			return nil, nil
+		}
+
+		filter := func(event *Event) bool {
+			if v.graphicsSystem == nil {
+				return false
+			}
+			x, xok := eventParamInt(event, "MesP2")
+			y, yok := eventParamInt(event, "MesP3")
+			return xok && yok && v.graphicsSystem.CastAt(x, y) == int(castID)
+		}
+		return v.registerInputHandler("OnSpriteClick", EventCLICK, args[1], filter,
+			castID, opcode.Variable("MesP2"), opcode.Variable("MesP3"))
+	})
+}
+
+// registerInputHandler registers an event handler that calls the named user function with
+// callArgs each time an event of eventType passes filter. Returns the handler number.
+func (vm *VM) registerInputHandler(builtin string, eventType EventType, funcArg any, filter func(*Event) bool, callArgs ...any) (any, error) {
+	fn, ok := vm.lookupCallback(builtin, funcArg)
+	if !ok {
+		return nil, nil
+	}
+
+	body := []opcode.OpCode{{
+		Cmd:  opcode.Call,
+		Args: append([]any{fn.Name}, callArgs...),
+	}}
+	handler := NewEventHandler("", eventType, body, vm, vm.GetCurrentScope())
+	handler.Filter = filter
+	vm.handlerRegistry.Register(handler)
+
+	vm.log.Debug(builtin+" registered", "handler", handler.ID, "function", fn.Name)
+	return int64(handler.Number), nil
+}
+
+// lookupCallback resolves the user function named by funcArg (case-insensitive).
+// An undefined function is logged as an error of builtin.
+func (vm *VM) lookupCallback(builtin string, funcArg any) (*FunctionDef, bool) {
+	name := toString(funcArg)
+	fn, ok := vm.functions[name]
+	if !ok {
+		fn, ok = vm.functionsLower[strings.ToLower(name)]
+	}
+	if !ok {
+		vm.log.Error(builtin+": undefined function", "function", name)
+	}
+	return fn, ok
+}
+
+// eventParamInt returns an integer event parameter.
+func eventParamInt(event *Event, name string) (int, bool) {
+	val, ok := event.GetParam(name)
+	if !ok {
+		return 0, false
+	}
+	n, ok := toInt64(val)
+	return int(n), ok
+}
+
+// eventParamEquals reports whether an integer event parameter equals want.
+// A missing parameter is treated as 0.
+func eventParamEquals(event *Event, name string, want int) bool {
+	n, ok := eventParamInt(event, name)
+	if !ok {
+		return want == 0
+	}
+	return n == want
+}
@@ -0,0 +1,279 @@
+package vm
+
+import (
+	"testing"
+
+	"github.com/zurustar/son-et/pkg/opcode"
+)
+
+// newInputTestVM creates a VM with a user function "onInput" whose arguments are
+// recorded on each call. Returns the VM and a pointer to the recorded calls.
+func newInputTestVM(t *testing.T, params ...string) (*VM, *[][]any) {
+	t.Helper()
+	vm := New([]opcode.OpCode{})
+
+	var calls [][]any
+	vm.RegisterBuiltinFunction("record", func(v *VM, args []any) (any, error) {
+		calls = append(calls, args)
+		return nil, nil
+	})
+
+	fn := &FunctionDef{Name: "onInput"}
+	recordArgs := []any{"record"}
+	for _, p := range params {
+		fn.Parameters = append(fn.Parameters, FunctionParam{Name: p})
+		recordArgs = append(recordArgs, opcode.Variable(p))
+	}
+	fn.Body = []opcode.OpCode{{Cmd: opcode.Call, Args: recordArgs}}
+	vm.functions["onInput"] = fn
+	vm.functionsLower["oninput"] = fn
+
+	return vm, &calls
+}
+
+// keyEvent creates a KEY event as pushed by PushKeyInput.
+func keyEvent(keyCode, modifiers, repeat int) *Event {
+	return NewEventWithParams(EventKEY, map[string]any{
+		"MesP1": 0,
+		"MesP2": keyCode,
+		"MesP3": modifiers,
+		"MesP4": repeat,
+	})
+}
+
+// clickEvent creates a CLICK event as pushed by PushMouseEvent.
+func clickEvent(x, y int) *Event {
+	return NewEventWithParams(EventCLICK, map[string]any{
+		"MesP1": 0,
+		"MesP2": x,
+		"MesP3": y,
+	})
+}
+
+// TestParseKeySpec tests key name and modifier parsing.
+func TestParseKeySpec(t *testing.T) {
+	tests := []struct {
+		spec      string
+		keyCode   int
+		modifiers int
+	}{
+		{"SPACE", 0x20, 0},
+		{"space", 0x20, 0},
+		{"ENTER", 0x0D, 0},
+		{"LEFT", 0x25, 0},
+		{"A", 0x41, 0},
+		{"z", 0x5A, 0},
+		{"1", 0x31, 0},
+		{"F1", 0x70, 0},
+		{"F12", 0x7B, 0},
+		{"CTRL+S", 0x53, KeyModCtrl},
+		{"Shift+Alt+X", 0x58, KeyModShift | KeyModAlt},
+		{"CONTROL + SHIFT + UP", 0x26, KeyModCtrl | KeyModShift},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.spec, func(t *testing.T) {
+			keyCode, modifiers, err := ParseKeySpec(tt.spec)
+			if err != nil {
+				t.Fatalf("ParseKeySpec(%q) returned error: %v", tt.spec, err)
+			}
+			if keyCode != tt.keyCode || modifiers != tt.modifiers {
+				t.Errorf("ParseKeySpec(%q) = (0x%X, %d), want (0x%X, %d)", tt.spec, keyCode, modifiers, tt.keyCode, tt.modifiers)
+			}
+		})
+	}
+}
+
+// TestParseKeySpecInvalid tests that unknown keys and modifiers are rejected.
+func TestParseKeySpecInvalid(t *testing.T) {
+	for _, spec := range []string{"", "ESCAPE", "F13", "F0", "HYPER+A", "CTRL+", "AB"} {
+		if _, _, err := ParseKeySpec(spec); err == nil {
+			t.Errorf("ParseKeySpec(%q) should return error", spec)
+		}
+	}
+}
+
+// TestOnKey tests that OnKey calls the function for matching key presses only.
+func TestOnKey(t *testing.T) {
+	t.Run("calls function with key code and modifiers", func(t *testing.T) {
+		vm, calls := newInputTestVM(t, "key", "mods")
+
+		result, err := vm.builtins["OnKey"](vm, []any{"SPACE", "onInput"})
+		if err != nil {
+			t.Fatalf("OnKey returned error: %v", err)
+		}
+		if result != int64(1) {
+			t.Errorf("OnKey should return handler number 1, got %v", result)
+		}
+
+		if err := vm.eventDispatcher.Dispatch(keyEvent(0x20, 0, 0)); err != nil {
+			t.Fatalf("Dispatch failed: %v", err)
+		}
+		if len(*calls) != 1 {
+			t.Fatalf("expected 1 call, got %d", len(*calls))
+		}
+		if key, _ := toInt64((*calls)[0][0]); key != 0x20 {
+			t.Errorf("key argument = %v, want 0x20", (*calls)[0][0])
+		}
+
+		// The handler has no steps, so the next event calls it from the start again
+		vm.eventDispatcher.Dispatch(keyEvent(0x20, 0, 0))
+		if len(*calls) != 2 {
+			t.Errorf("expected 2 calls after second press, got %d", len(*calls))
+		}
+	})
+
+	t.Run("ignores other keys", func(t *testing.T) {
+		vm, calls := newInputTestVM(t)
+		vm.builtins["OnKey"](vm, []any{"SPACE", "onInput"})
+
+		vm.eventDispatcher.Dispatch(keyEvent(0x41, 0, 0))
+		if len(*calls) != 0 {
+			t.Errorf("function should not be called for other keys, got %d calls", len(*calls))
+		}
+	})
+
+	t.Run("modifiers must match exactly", func(t *testing.T) {
+		vm, calls := newInputTestVM(t)
+		vm.builtins["OnKey"](vm, []any{"S", "onInput"})
+		vm.builtins["OnKey"](vm, []any{"CTRL+S", "onInput"})
+
+		vm.eventDispatcher.Dispatch(keyEvent(0x53, KeyModCtrl, 0))
+		if len(*calls) != 1 {
+			t.Errorf("Ctrl+S should call only the CTRL+S binding, got %d calls", len(*calls))
+		}
+
+		vm.eventDispatcher.Dispatch(keyEvent(0x53, KeyModCtrl|KeyModShift, 0))
+		if len(*calls) != 1 {
+			t.Errorf("Ctrl+Shift+S should not match, got %d calls", len(*calls))
+		}
+	})
+
+	t.Run("repeat policy", func(t *testing.T) {
+		vm, calls := newInputTestVM(t)
+		vm.builtins["OnKey"](vm, []any{"LEFT", "onInput"})
+
+		vm.eventDispatcher.Dispatch(keyEvent(0x25, 0, 1))
+		if len(*calls) != 0 {
+			t.Errorf("auto-repeat should be ignored by default, got %d calls", len(*calls))
+		}
+
+		vm.builtins["OnKey"](vm, []any{"LEFT", "onInput", int64(1)})
+		vm.eventDispatcher.Dispatch(keyEvent(0x25, 0, 1))
+		if len(*calls) != 1 {
+			t.Errorf("auto-repeat should fire when repeat is enabled, got %d calls", len(*calls))
+		}
+	})
+
+	t.Run("integer key code", func(t *testing.T) {
+		vm, calls := newInputTestVM(t)
+		vm.builtins["OnKey"](vm, []any{int64(0x0D), "onInput"})
+
+		vm.eventDispatcher.Dispatch(keyEvent(0x0D, 0, 0))
+		if len(*calls) != 1 {
+			t.Errorf("expected 1 call, got %d", len(*calls))
+		}
+	})
+
+	t.Run("function name is case-insensitive", func(t *testing.T) {
+		vm, calls := newInputTestVM(t)
+		vm.builtins["OnKey"](vm, []any{"A", "ONINPUT"})
+
+		vm.eventDispatcher.Dispatch(keyEvent(0x41, 0, 0))
+		if len(*calls) != 1 {
+			t.Errorf("expected 1 call, got %d", len(*calls))
+		}
+	})
+
+	t.Run("invalid key or function is not registered", func(t *testing.T) {
+		vm, _ := newInputTestVM(t)
+
+		for _, args := range [][]any{
+			{"NOSUCHKEY", "onInput"},
+			{"SPACE", "undefinedFunc"},
+			{"SPACE"},
+		} {
+			result, err := vm.builtins["OnKey"](vm, args)
+			if err != nil || result != nil {
+				t.Errorf("OnKey(%v) = (%v, %v), want (nil, nil)", args, result, err)
+			}
+		}
+		if vm.handlerRegistry.Count() != 0 {
+			t.Errorf("no handler should be registered, got %d", vm.handlerRegistry.Count())
+		}
+	})
+
+	t.Run("DelMes removes the binding", func(t *testing.T) {
+		vm, calls := newInputTestVM(t)
+		id, _ := vm.builtins["OnKey"](vm, []any{"SPACE", "onInput"})
+
+		vm.builtins["DelMes"](vm, []any{id})
+		vm.eventDispatcher.Dispatch(keyEvent(0x20, 0, 0))
+		if len(*calls) != 0 {
+			t.Errorf("removed binding should not be called, got %d calls", len(*calls))
+		}
+	})
+}
+
+// TestOnClick tests that OnClick calls the function with the click position.
+func TestOnClick(t *testing.T) {
+	vm, calls := newInputTestVM(t, "x", "y")
+	vm.builtins["OnClick"](vm, []any{"onInput"})
+
+	vm.eventDispatcher.Dispatch(clickEvent(120, 45))
+	if len(*calls) != 1 {
+		t.Fatalf("expected 1 call, got %d", len(*calls))
+	}
+	x, _ := toInt64((*calls)[0][0])
+	y, _ := toInt64((*calls)[0][1])
+	if x != 120 || y != 45 {
+		t.Errorf("arguments = (%d, %d), want (120, 45)", x, y)
+	}
+}
+
+// TestOnSpriteClick tests that OnSpriteClick fires only when the cast is hit.
+func TestOnSpriteClick(t *testing.T) {
+	vm, calls := newInputTestVM(t, "cast", "x", "y")
+	gs := newMockGraphicsSystem()
+	gs.castAt = func(x, y int) int {
+		if x >= 100 && x < 150 && y >= 100 && y < 150 {
+			return 3
+		}
+		return -1
+	}
+	vm.SetGraphicsSystem(gs)
+
+	vm.builtins["OnSpriteClick"](vm, []any{int64(3), "onInput"})
+
+	vm.eventDispatcher.Dispatch(clickEvent(10, 10))
+	if len(*calls) != 0 {
+		t.Errorf("click outside the cast should be ignored, got %d calls", len(*calls))
+	}
+
+	vm.eventDispatcher.Dispatch(clickEvent(110, 120))
+	if len(*calls) != 1 {
+		t.Fatalf("expected 1 call, got %d", len(*calls))
+	}
+	if cast, _ := toInt64((*calls)[0][0]); cast != 3 {
+		t.Errorf("cast argument = %v, want 3", (*calls)[0][0])
+	}
+}
+
+// TestPushKeyInput tests the KEY event parameters pushed by the window.
+func TestPushKeyInput(t *testing.T) {
+	vm := New([]opcode.OpCode{})
+	vm.PushKeyInput(0x53, KeyModCtrl, true)
+
+	event, ok := vm.eventQueue.Pop()
+	if !ok {
+		t.Fatal("expected an event in the queue")
+	}
+	if event.Type != EventKEY {
+		t.Errorf("event type = %s, want KEY", event.Type)
+	}
+	for name, want := range map[string]int{"MesP2": 0x53, "MesP3": KeyModCtrl, "MesP4": 1} {
+		if got, _ := eventParamInt(event, name); got != want {
+			t.Errorf("%s = %d, want %d", name, got, want)
+		}
+	}
+}
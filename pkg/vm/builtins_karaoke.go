@@ -0,0 +1,102 @@
+package vm
+
+import (
+	"fmt"
+
+	"github.com/zurustar/son-et/pkg/opcode"
+)
+
+// defaultKaraokeColor は歌った部分を描画する既定の色（赤）
+const defaultKaraokeColor = 0xFF0000
+
+// registerKaraokeBuiltins registers built-in functions that display MIDI lyrics
+// (MIDI_LYRIC events) karaoke-style.
+func (vm *VM) registerKaraokeBuiltins() {
+	// HighlightText(text, pic_no, x, y, count[, color]) draws text like TextWrite,
+	// with the first count characters in color (red by default) and the rest in the TextColor.
+	vm.RegisterBuiltinFunction("HighlightText", func(v *VM, args []any) (any, error) {
+		if v.graphicsSystem == nil {
+			v.log.Debug("HighlightText called but graphics system not initialized", "args", args)
+			return nil, nil
+		}
+		if len(args) < 5 {
+			return nil, fmt.Errorf("HighlightText requires at least 5 arguments (text, pic_no, x, y, count)")
+		}
+
+		text := toString(args[0])
+		nums, err := karaokeInts(args[1:5])
+		if err != nil {
+			v.log.Error("HighlightText: invalid arguments", "error", err)
+			return nil, nil
+		}
+		highlightColor, err := karaokeColorArg(args, 5)
+		if err != nil {
+			v.log.Error("HighlightText: invalid color", "error", err)
+			return nil, nil
+		}
+
+		picID, x, y, count := nums[0], nums[1], nums[2], nums[3]
+		if err := v.graphicsSystem.TextWriteHighlighted(picID, x, y, text, max(count, 0), v.compatColor(highlightColor)); err != nil {
+			v.log.Error("HighlightText failed", "error", err)
+		}
+		v.log.Debug("HighlightText called", "text", text, "picID", picID, "x", x, "y", y, "count", count)
+		return nil, nil
+	})
+
+	// Karaoke(pic_no, x, y[, color]) shows the lyrics of the playing MIDI file at (x, y):
+	// each MIDI_LYRIC event redraws the current line with the sung characters in color.
+	// It is an ordinary MIDI_LYRIC event handler, so DelMes removes it. Returns the handler number.
+	vm.RegisterBuiltinFunction("Karaoke", func(v *VM, args []any) (any, error) {
+		if len(args) < 3 {
+			v.log.Error("Karaoke requires at least 3 arguments (pic_no, x, y)")
+			return nil, nil
+		}
+		nums, err := karaokeInts(args[:3])
+		if err != nil {
+			v.log.Error("Karaoke: invalid arguments", "error", err)
+			return nil, nil
+		}
+		highlightColor, err := karaokeColorArg(args, 3)
+		if err != nil {
+			v.log.Error("Karaoke: invalid color", "error", err)
+			return nil, nil
+		}
+
+		body := []opcode.OpCode{{
+			Cmd: opcode.Call,
+			Args: []any{"HighlightText",
+				opcode.Variable("MesP4"), int64(nums[0]), int64(nums[1]), int64(nums[2]),
+				opcode.Variable("MesP3"), int64(highlightColor)},
+		}}
+		handler := NewEventHandler("", EventMIDI_LYRIC, body, v, v.GetCurrentScope())
+		v.handlerRegistry.Register(handler)
+
+		v.log.Debug("Karaoke registered", "handler", handler.ID, "picID", nums[0], "x", nums[1], "y", nums[2])
+		return int64(handler.Number), nil
+	})
+}
+
+// karaokeInts は引数を整数に変換する
+func karaokeInts(args []any) ([]int, error) {
+	nums := make([]int, len(args))
+	for i, arg := range args {
+		n, ok := toInt64(arg)
+		if !ok {
+			return nil, fmt.Errorf("argument %d must be integer, got %T", i+1, arg)
+		}
+		nums[i] = int(n)
+	}
+	return nums, nil
+}
+
+// karaokeColorArg は args[index] の色（0xRRGGBB）を返す（省略した場合は defaultKaraokeColor）
+func karaokeColorArg(args []any, index int) (int, error) {
+	if len(args) <= index {
+		return defaultKaraokeColor, nil
+	}
+	c, ok := toInt64(args[index])
+	if !ok {
+		return 0, fmt.Errorf("color must be integer (0xRRGGBB), got %T", args[index])
+	}
+	return int(c), nil
+}
@@ -0,0 +1,118 @@
+package vm
+
+import (
+	"testing"
+
+	"github.com/zurustar/son-et/pkg/opcode"
+)
+
+// TestNewMIDILyricEvent tests the MIDI_LYRIC event parameters pushed by the MIDI player.
+func TestNewMIDILyricEvent(t *testing.T) {
+	event := NewMIDILyricEvent("ら", 960, 3, "さくら")
+	if event.Type != EventMIDI_LYRIC {
+		t.Fatalf("Type = %s, want MIDI_LYRIC", event.Type)
+	}
+	for name, want := range map[string]any{"MesP1": "ら", "MesP2": 960, "MesP3": 3, "MesP4": "さくら"} {
+		if got, _ := event.GetParam(name); got != want {
+			t.Errorf("%s = %v, want %v", name, got, want)
+		}
+	}
+}
+
+// TestHighlightText tests that HighlightText passes the highlight count and color to the graphics system.
+func TestHighlightText(t *testing.T) {
+	vm := New([]opcode.OpCode{})
+	gs := newMockGraphicsSystem()
+	vm.SetGraphicsSystem(gs)
+
+	vm.builtins["HighlightText"](vm, []any{"さくら", int64(1), int64(10), int64(20), int64(2), int64(0x0000FF)})
+	vm.builtins["HighlightText"](vm, []any{"さくら", int64(1), int64(10), int64(20), int64(-1)})
+
+	want := []mockHighlightCall{
+		{picID: 1, x: 10, y: 20, text: "さくら", count: 2, color: 0x0000FF},
+		{picID: 1, x: 10, y: 20, text: "さくら", count: 0, color: defaultKaraokeColor},
+	}
+	if len(gs.highlights) != len(want) {
+		t.Fatalf("got %d calls, want %d: %+v", len(gs.highlights), len(want), gs.highlights)
+	}
+	for i := range want {
+		if gs.highlights[i] != want[i] {
+			t.Errorf("call %d = %+v, want %+v", i, gs.highlights[i], want[i])
+		}
+	}
+}
+
+// TestHighlightTextInvalid tests that invalid arguments are ignored without stopping the script.
+func TestHighlightTextInvalid(t *testing.T) {
+	vm := New([]opcode.OpCode{})
+	gs := newMockGraphicsSystem()
+	vm.SetGraphicsSystem(gs)
+
+	if _, err := vm.builtins["HighlightText"](vm, []any{"abc", int64(1), int64(0), int64(0), "two"}); err != nil {
+		t.Errorf("invalid count should not stop the script: %v", err)
+	}
+	if _, err := vm.builtins["HighlightText"](vm, []any{"abc", int64(1), int64(0), int64(0), int64(1), "red"}); err != nil {
+		t.Errorf("invalid color should not stop the script: %v", err)
+	}
+	if len(gs.highlights) != 0 {
+		t.Errorf("invalid arguments should not draw, got %+v", gs.highlights)
+	}
+	if _, err := vm.builtins["HighlightText"](vm, []any{"abc", int64(1)}); err == nil {
+		t.Error("HighlightText with too few arguments should return an error")
+	}
+}
+
+// TestKaraoke tests that Karaoke redraws the lyric line on every MIDI_LYRIC event until DelMes.
+func TestKaraoke(t *testing.T) {
+	vm := New([]opcode.OpCode{})
+	gs := newMockGraphicsSystem()
+	vm.SetGraphicsSystem(gs)
+
+	result, _ := vm.builtins["Karaoke"](vm, []any{int64(2), int64(40), int64(400), int64(0x00FF00)})
+	number, ok := result.(int64)
+	if !ok {
+		t.Fatalf("Karaoke should return the handler number, got %v", result)
+	}
+
+	vm.eventDispatcher.Dispatch(NewMIDILyricEvent("さ", 0, 1, "さくら"))
+	vm.eventDispatcher.Dispatch(NewMIDILyricEvent("く", 480, 2, "さくら"))
+
+	want := []mockHighlightCall{
+		{picID: 2, x: 40, y: 400, text: "さくら", count: 1, color: 0x00FF00},
+		{picID: 2, x: 40, y: 400, text: "さくら", count: 2, color: 0x00FF00},
+	}
+	if len(gs.highlights) != len(want) {
+		t.Fatalf("got %d calls, want %d: %+v", len(gs.highlights), len(want), gs.highlights)
+	}
+	for i := range want {
+		if gs.highlights[i] != want[i] {
+			t.Errorf("call %d = %+v, want %+v", i, gs.highlights[i], want[i])
+		}
+	}
+
+	vm.builtins["DelMes"](vm, []any{number})
+	vm.eventDispatcher.Dispatch(NewMIDILyricEvent("ら", 960, 3, "さくら"))
+	if len(gs.highlights) != len(want) {
+		t.Errorf("DelMes should remove the karaoke handler, got %d calls", len(gs.highlights))
+	}
+}
+
+// TestKaraokeDefaultColor tests the default highlight color and invalid arguments.
+func TestKaraokeDefaultColor(t *testing.T) {
+	vm := New([]opcode.OpCode{})
+	gs := newMockGraphicsSystem()
+	vm.SetGraphicsSystem(gs)
+
+	if result, _ := vm.builtins["Karaoke"](vm, []any{int64(0), int64(0)}); result != nil {
+		t.Errorf("Karaoke with too few arguments should not register a handler, got %v", result)
+	}
+	if result, _ := vm.builtins["Karaoke"](vm, []any{int64(0), "x", int64(0)}); result != nil {
+		t.Errorf("Karaoke with invalid position should not register a handler, got %v", result)
+	}
+
+	vm.builtins["Karaoke"](vm, []any{int64(0), int64(0), int64(0)})
+	vm.eventDispatcher.Dispatch(NewMIDILyricEvent("a", 0, 1, "a"))
+	if len(gs.highlights) != 1 || gs.highlights[0].color != defaultKaraokeColor {
+		t.Errorf("highlights = %+v, want one call with the default color", gs.highlights)
+	}
+}
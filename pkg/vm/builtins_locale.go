@@ -0,0 +1,280 @@
+package vm
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"golang.org/x/text/language"
+	"golang.org/x/text/message"
+	"golang.org/x/text/number"
+)
+
+// Locale-aware formatting
+//
+// FormatNumber and FormatDate format numbers and dates for the locale given with
+// --locale (a BCP 47 tag), so clock and calendar scripts do not need to hard-code
+// separators and month names. Number formatting uses the CLDR data of x/text;
+// dates are formatted from the small tables below, which cover the languages
+// titles are usually shown in. The "-u-ca-japanese" extension selects Japanese
+// era years (令和8年) for Japanese.
+
+// localeEnvVars are the environment variables FormatNumber and FormatDate take the
+// locale from, in order of precedence (following the POSIX locale rules).
+var localeEnvVars = []string{"LC_ALL", "LC_TIME", "LANG"}
+
+// WithLocale sets the locale used by FormatNumber and FormatDate (a BCP 47 tag such as
+// "ja-JP", "en-US" or "ja-JP-u-ca-japanese"). It also sets the language returned by
+// GetLang unless WithLang is given. By default it is taken from the LC_ALL, LC_TIME
+// and LANG environment variables.
+func WithLocale(tag string) Option {
+	return func(vm *VM) {
+		vm.locale = tag
+	}
+}
+
+// detectLocale returns the locale of the environment (such as "ja_JP.UTF-8") as a
+// BCP 47 tag ("ja-JP"), or DefaultLang when no variable gives one.
+func detectLocale(getenv func(string) string) string {
+	for _, name := range localeEnvVars {
+		locale := getenv(name)
+		if locale == "" || locale == "C" || locale == "POSIX" || strings.HasPrefix(locale, "C.") {
+			continue
+		}
+		locale, _, _ = strings.Cut(locale, ".")
+		locale, _, _ = strings.Cut(locale, "@")
+		if locale != "" {
+			return strings.ReplaceAll(locale, "_", "-")
+		}
+	}
+	return DefaultLang
+}
+
+// localeTag returns the locale used by FormatNumber and FormatDate.
+// A locale that cannot be parsed falls back to DefaultLang.
+func (vm *VM) localeTag() language.Tag {
+	if vm.locale == "" {
+		vm.locale = detectLocale(os.Getenv)
+	}
+	tag, err := language.Parse(vm.locale)
+	if err != nil && tag == language.Und {
+		vm.log.Warn("Unknown locale, using the default", "locale", vm.locale, "default", DefaultLang)
+		vm.locale = DefaultLang
+		return language.Make(DefaultLang)
+	}
+	return tag
+}
+
+// formatNumber formats value with the grouping separator and decimal point of the locale.
+// When decimals is negative, integers are kept as they are and fractions get up to 3 decimals.
+func formatNumber(tag language.Tag, value any, decimals int) string {
+	var opts []number.Option
+	if decimals >= 0 {
+		opts = append(opts, number.Scale(decimals))
+	}
+	p := message.NewPrinter(tag)
+	if i, ok := value.(int64); ok {
+		return p.Sprint(number.Decimal(i, opts...))
+	}
+	f, _ := toFloat64(value)
+	return p.Sprint(number.Decimal(f, opts...))
+}
+
+// Styles of FormatDate
+const (
+	dateStyleDate      = "date"      // year, month and day (2026/10/15)
+	dateStyleTime      = "time"      // hours, minutes and seconds (14:05:09)
+	dateStyleShortTime = "shorttime" // hours and minutes (14:05)
+	dateStyleDateTime  = "datetime"  // date with hours and minutes (2026/10/15 14:05)
+	dateStyleLong      = "long"      // date with the weekday (2026年10月15日木曜日)
+	dateStyleYear      = "year"      // year (2026年, 令和8年)
+	dateStyleMonth     = "month"     // month (10月, October)
+	dateStyleWeekday   = "weekday"   // weekday (木曜日, Thursday)
+)
+
+// dateNames holds the month and weekday names of a language.
+type dateNames struct {
+	months   [12]string
+	weekdays [7]string // starting with Sunday
+}
+
+var (
+	englishDateNames = dateNames{
+		months:   [12]string{"January", "February", "March", "April", "May", "June", "July", "August", "September", "October", "November", "December"},
+		weekdays: [7]string{"Sunday", "Monday", "Tuesday", "Wednesday", "Thursday", "Friday", "Saturday"},
+	}
+	germanDateNames = dateNames{
+		months:   [12]string{"Januar", "Februar", "März", "April", "Mai", "Juni", "Juli", "August", "September", "Oktober", "November", "Dezember"},
+		weekdays: [7]string{"Sonntag", "Montag", "Dienstag", "Mittwoch", "Donnerstag", "Freitag", "Samstag"},
+	}
+	frenchDateNames = dateNames{
+		months:   [12]string{"janvier", "février", "mars", "avril", "mai", "juin", "juillet", "août", "septembre", "octobre", "novembre", "décembre"},
+		weekdays: [7]string{"dimanche", "lundi", "mardi", "mercredi", "jeudi", "vendredi", "samedi"},
+	}
+	spanishDateNames = dateNames{
+		months:   [12]string{"enero", "febrero", "marzo", "abril", "mayo", "junio", "julio", "agosto", "septiembre", "octubre", "noviembre", "diciembre"},
+		weekdays: [7]string{"domingo", "lunes", "martes", "miércoles", "jueves", "viernes", "sábado"},
+	}
+	japaneseWeekdays = [7]string{"日曜日", "月曜日", "火曜日", "水曜日", "木曜日", "金曜日", "土曜日"}
+	chineseWeekdays  = [7]string{"星期日", "星期一", "星期二", "星期三", "星期四", "星期五", "星期六"}
+	koreanWeekdays   = [7]string{"일요일", "월요일", "화요일", "수요일", "목요일", "금요일", "토요일"}
+)
+
+// japaneseEras are the Japanese eras and their first days, newest first, in the
+// Gregorian calendar (Meiji is counted in the Gregorian calendar even before the calendar reform).
+var japaneseEras = []struct {
+	name  string
+	start time.Time
+}{
+	{"令和", time.Date(2019, time.May, 1, 0, 0, 0, 0, time.UTC)},
+	{"平成", time.Date(1989, time.January, 8, 0, 0, 0, 0, time.UTC)},
+	{"昭和", time.Date(1926, time.December, 25, 0, 0, 0, 0, time.UTC)},
+	{"大正", time.Date(1912, time.July, 30, 0, 0, 0, 0, time.UTC)},
+	{"明治", time.Date(1868, time.September, 8, 0, 0, 0, 0, time.UTC)},
+}
+
+// japaneseYear returns the year of t as a Japanese era year ("令和8年", or "令和元年"
+// for the first year). Dates before Meiji use the Gregorian year ("1850年").
+func japaneseYear(t time.Time) string {
+	day := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+	for _, era := range japaneseEras {
+		if day.Before(era.start) {
+			continue
+		}
+		if year := t.Year() - era.start.Year() + 1; year > 1 {
+			return fmt.Sprintf("%s%d年", era.name, year)
+		}
+		return era.name + "元年"
+	}
+	return fmt.Sprintf("%d年", t.Year())
+}
+
+// formatDate formats t in the given style for the locale (false for an unknown style).
+func formatDate(tag language.Tag, t time.Time, style string) (string, bool) {
+	base, _ := tag.Base()
+	region, _ := tag.Region()
+	lang := base.String()
+
+	// Parts of the year, date and time for each language. Without a region, the format
+	// follows the region x/text infers.
+	var year, date, long, month, weekday string
+	clock := t.Format("15:04:05")
+	shortClock := t.Format("15:04")
+	m, d, wd := int(t.Month()), t.Day(), int(t.Weekday())
+
+	switch lang {
+	case "ja":
+		year, month, weekday = fmt.Sprintf("%d年", t.Year()), fmt.Sprintf("%d月", m), japaneseWeekdays[wd]
+		date = fmt.Sprintf("%d/%02d/%02d", t.Year(), m, d)
+		if tag.TypeForKey("ca") == "japanese" {
+			year = japaneseYear(t)
+			date = fmt.Sprintf("%s%d月%d日", year, m, d)
+		}
+		long = fmt.Sprintf("%s%d月%d日%s", year, m, d, weekday)
+	case "zh":
+		year, month, weekday = fmt.Sprintf("%d年", t.Year()), fmt.Sprintf("%d月", m), chineseWeekdays[wd]
+		date = fmt.Sprintf("%d/%d/%d", t.Year(), m, d)
+		long = fmt.Sprintf("%s%d月%d日%s", year, m, d, weekday)
+	case "ko":
+		year, month, weekday = fmt.Sprintf("%d년", t.Year()), fmt.Sprintf("%d월", m), koreanWeekdays[wd]
+		date = fmt.Sprintf("%d. %d. %d.", t.Year(), m, d)
+		long = fmt.Sprintf("%s %d월 %d일 %s", year, m, d, weekday)
+	case "de":
+		year, month, weekday = fmt.Sprint(t.Year()), germanDateNames.months[m-1], germanDateNames.weekdays[wd]
+		date = fmt.Sprintf("%02d.%02d.%d", d, m, t.Year())
+		long = fmt.Sprintf("%s, %d. %s %d", weekday, d, month, t.Year())
+	case "fr":
+		year, month, weekday = fmt.Sprint(t.Year()), frenchDateNames.months[m-1], frenchDateNames.weekdays[wd]
+		date = fmt.Sprintf("%02d/%02d/%d", d, m, t.Year())
+		long = fmt.Sprintf("%s %d %s %d", weekday, d, month, t.Year())
+	case "es":
+		year, month, weekday = fmt.Sprint(t.Year()), spanishDateNames.months[m-1], spanishDateNames.weekdays[wd]
+		date = fmt.Sprintf("%d/%d/%d", d, m, t.Year())
+		long = fmt.Sprintf("%s, %d de %s de %d", weekday, d, month, t.Year())
+	default:
+		// English (month/day/year and the 12-hour clock in the US). Languages without a
+		// table also use the English names and an ISO 8601 date.
+		year, month, weekday = fmt.Sprint(t.Year()), englishDateNames.months[m-1], englishDateNames.weekdays[wd]
+		date = t.Format("2006-01-02")
+		long = fmt.Sprintf("%s %d %s %d", weekday, d, month, t.Year())
+		if lang == "en" && region.String() == "US" {
+			date = fmt.Sprintf("%d/%d/%d", m, d, t.Year())
+			long = fmt.Sprintf("%s, %s %d, %d", weekday, month, d, t.Year())
+			clock, shortClock = t.Format("3:04:05 PM"), t.Format("3:04 PM")
+		} else if lang == "en" {
+			date = fmt.Sprintf("%02d/%02d/%d", d, m, t.Year())
+		}
+	}
+
+	switch style {
+	case dateStyleDate:
+		return date, true
+	case dateStyleTime:
+		return clock, true
+	case dateStyleShortTime:
+		return shortClock, true
+	case dateStyleDateTime:
+		return date + " " + shortClock, true
+	case dateStyleLong:
+		return long, true
+	case dateStyleYear:
+		return year, true
+	case dateStyleMonth:
+		return month, true
+	case dateStyleWeekday:
+		return weekday, true
+	}
+	return "", false
+}
+
+// registerLocaleBuiltins registers FormatNumber and FormatDate.
+func (vm *VM) registerLocaleBuiltins() {
+	// FormatNumber: Format a number with the grouping separator and decimal point of the locale
+	// FormatNumber(value) / FormatNumber(value, decimals)
+	vm.RegisterBuiltinFunction("FormatNumber", func(v *VM, args []any) (any, error) {
+		if len(args) < 1 {
+			v.log.Error("FormatNumber requires a value")
+			return "", nil
+		}
+		if _, ok := toFloat64(args[0]); !ok {
+			v.log.Error("FormatNumber value must be a number", "got", fmt.Sprintf("%T", args[0]))
+			return "", nil
+		}
+		decimals := -1
+		if len(args) >= 2 {
+			n, ok := toInt64(args[1])
+			if !ok || n < 0 {
+				v.log.Error("FormatNumber: decimals must be a non-negative integer", "got", args[1])
+				return "", nil
+			}
+			decimals = int(n)
+		}
+		return formatNumber(v.localeTag(), args[0], decimals), nil
+	})
+
+	// FormatDate: Format a date and time for the locale
+	// FormatDate(style) / FormatDate(style, seconds) - seconds is a Unix time such as GetSysTime() (the VM clock's now when omitted)
+	vm.RegisterBuiltinFunction("FormatDate", func(v *VM, args []any) (any, error) {
+		if len(args) < 1 {
+			v.log.Error("FormatDate requires a style")
+			return "", nil
+		}
+		style := strings.ToLower(toString(args[0]))
+		t := v.clock.Now()
+		if len(args) >= 2 {
+			seconds, ok := toInt64(args[1])
+			if !ok {
+				v.log.Error("FormatDate: time must be seconds since the Unix epoch", "got", args[1])
+				return "", nil
+			}
+			t = time.Unix(seconds, 0)
+		}
+		result, ok := formatDate(v.localeTag(), t, style)
+		if !ok {
+			v.log.Error("FormatDate: unknown style", "style", args[0])
+			return "", nil
+		}
+		return result, nil
+	})
+}
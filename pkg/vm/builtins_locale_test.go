@@ -0,0 +1,157 @@
+package vm
+
+import (
+	"testing"
+	"time"
+
+	"github.com/zurustar/son-et/pkg/opcode"
+	"golang.org/x/text/language"
+)
+
+func TestVMBuiltinFormatNumber(t *testing.T) {
+	tests := []struct {
+		locale string
+		args   []any
+		want   string
+	}{
+		{"ja-JP", []any{int64(1234567)}, "1,234,567"},
+		{"ja-JP", []any{1234.5678, int64(2)}, "1,234.57"},
+		{"ja-JP", []any{int64(-5), int64(1)}, "-5.0"},
+		{"de-DE", []any{1234567.891}, "1.234.567,891"},
+		{"fr-FR", []any{int64(1234567)}, "1\u00a0234\u00a0567"},
+		{"de-CH", []any{int64(1234567)}, "1’234’567"},
+		{"hi-IN", []any{int64(1234567)}, "12,34,567"},
+		{"en-US", []any{"1000"}, "1,000"},
+	}
+	for _, tt := range tests {
+		vm := New([]opcode.OpCode{}, WithLocale(tt.locale))
+		if got, _ := vm.builtins["FormatNumber"](vm, tt.args); got != tt.want {
+			t.Errorf("%s: FormatNumber(%v) = %q, want %q", tt.locale, tt.args, got, tt.want)
+		}
+	}
+}
+
+func TestVMBuiltinFormatNumberInvalid(t *testing.T) {
+	vm := New([]opcode.OpCode{}, WithLocale("en-US"))
+	for _, args := range [][]any{nil, {"abc"}, {int64(1), int64(-1)}} {
+		if got, _ := vm.builtins["FormatNumber"](vm, args); got != "" {
+			t.Errorf("FormatNumber(%v) = %q, want \"\"", args, got)
+		}
+	}
+}
+
+func TestVMBuiltinFormatDate(t *testing.T) {
+	// Thursday, 15 October 2026, 14:05:09
+	seconds := time.Date(2026, time.October, 15, 14, 5, 9, 0, time.Local).Unix()
+	tests := []struct {
+		locale string
+		style  string
+		want   string
+	}{
+		{"ja-JP", "date", "2026/10/15"},
+		{"ja-JP", "time", "14:05:09"},
+		{"ja-JP", "datetime", "2026/10/15 14:05"},
+		{"ja-JP", "long", "2026年10月15日木曜日"},
+		{"ja-JP", "Weekday", "木曜日"},
+		{"ja-JP-u-ca-japanese", "year", "令和8年"},
+		{"ja-JP-u-ca-japanese", "date", "令和8年10月15日"},
+		{"en-US", "date", "10/15/2026"},
+		{"en-US", "shorttime", "2:05 PM"},
+		{"en-US", "long", "Thursday, October 15, 2026"},
+		{"en", "date", "10/15/2026"},
+		{"en-GB", "date", "15/10/2026"},
+		{"en-GB", "shorttime", "14:05"},
+		{"de-DE", "long", "Donnerstag, 15. Oktober 2026"},
+		{"de-DE", "date", "15.10.2026"},
+		{"fr-FR", "long", "jeudi 15 octobre 2026"},
+		{"es-ES", "long", "jueves, 15 de octubre de 2026"},
+		{"zh-CN", "long", "2026年10月15日星期四"},
+		{"ko-KR", "date", "2026. 10. 15."},
+		{"it-IT", "date", "2026-10-15"},
+		{"it-IT", "month", "October"},
+	}
+	for _, tt := range tests {
+		vm := New([]opcode.OpCode{}, WithLocale(tt.locale))
+		if got, _ := vm.builtins["FormatDate"](vm, []any{tt.style, seconds}); got != tt.want {
+			t.Errorf("%s: FormatDate(%q) = %q, want %q", tt.locale, tt.style, got, tt.want)
+		}
+	}
+}
+
+func TestVMBuiltinFormatDateInvalid(t *testing.T) {
+	vm := New([]opcode.OpCode{}, WithLocale("ja-JP"))
+	for _, args := range [][]any{nil, {"era"}, {"date", "now"}} {
+		if got, _ := vm.builtins["FormatDate"](vm, args); got != "" {
+			t.Errorf("FormatDate(%v) = %q, want \"\"", args, got)
+		}
+	}
+}
+
+// TestVMBuiltinFormatDateNow tests that FormatDate without a time formats the VM clock's time.
+func TestVMBuiltinFormatDateNow(t *testing.T) {
+	clock := fixedClock{now: time.Date(2026, time.October, 15, 14, 5, 9, 0, time.Local)}
+	vm := New([]opcode.OpCode{}, WithLocale("ja-JP"), WithClock(clock))
+	if got, _ := vm.builtins["FormatDate"](vm, []any{"datetime"}); got != "2026/10/15 14:05" {
+		t.Errorf("FormatDate(\"datetime\") = %q, want the clock's time \"2026/10/15 14:05\"", got)
+	}
+}
+
+func TestJapaneseYear(t *testing.T) {
+	tests := []struct {
+		date time.Time
+		want string
+	}{
+		{time.Date(2019, time.April, 30, 23, 0, 0, 0, time.Local), "平成31年"},
+		{time.Date(2019, time.May, 1, 0, 0, 0, 0, time.Local), "令和元年"},
+		{time.Date(1989, time.January, 7, 0, 0, 0, 0, time.Local), "昭和64年"},
+		{time.Date(1989, time.January, 8, 0, 0, 0, 0, time.Local), "平成元年"},
+		{time.Date(1926, time.December, 25, 0, 0, 0, 0, time.Local), "昭和元年"},
+		{time.Date(1912, time.July, 29, 0, 0, 0, 0, time.Local), "明治45年"},
+		{time.Date(1868, time.January, 1, 0, 0, 0, 0, time.Local), "1868年"},
+	}
+	for _, tt := range tests {
+		if got := japaneseYear(tt.date); got != tt.want {
+			t.Errorf("japaneseYear(%s) = %q, want %q", tt.date.Format("2006-01-02"), got, tt.want)
+		}
+	}
+}
+
+func TestDetectLocale(t *testing.T) {
+	for _, tt := range []struct {
+		name string
+		env  map[string]string
+		want string
+	}{
+		{"unset", nil, DefaultLang},
+		{"LANG", map[string]string{"LANG": "en_US.UTF-8"}, "en-US"},
+		{"LC_TIME wins", map[string]string{"LC_TIME": "de_DE@euro", "LANG": "en_US.UTF-8"}, "de-DE"},
+		{"C is skipped", map[string]string{"LC_ALL": "C.UTF-8", "LANG": "fr_FR"}, "fr-FR"},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := detectLocale(func(name string) string { return tt.env[name] }); got != tt.want {
+				t.Errorf("detectLocale = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestLocaleTagInvalid tests that an invalid locale falls back to the default.
+func TestLocaleTagInvalid(t *testing.T) {
+	vm := New([]opcode.OpCode{}, WithLocale("not a locale"))
+	if got := vm.localeTag(); got != language.Make(DefaultLang) {
+		t.Errorf("localeTag = %v, want %s", got, DefaultLang)
+	}
+}
+
+// TestGetLangFollowsLocale tests that GetLang returns the language of --locale
+// unless the language is given.
+func TestGetLangFollowsLocale(t *testing.T) {
+	vm := New([]opcode.OpCode{}, WithLocale("de-CH"))
+	if got, _ := vm.builtins["GetLang"](vm, nil); got != "de" {
+		t.Errorf("GetLang = %v, want \"de\"", got)
+	}
+	vm = New([]opcode.OpCode{}, WithLocale("de-CH"), WithLang("en"))
+	if got, _ := vm.builtins["GetLang"](vm, nil); got != "en" {
+		t.Errorf("GetLang with WithLang = %v, want \"en\"", got)
+	}
+}
@@ -0,0 +1,100 @@
+package vm
+
+import (
+	"github.com/zurustar/son-et/pkg/graphics"
+)
+
+// registerMaskBuiltins registers built-in functions for cast and window masks.
+// A mask limits the visible region of a cast or a window (and the casts in it):
+// a rectangle for spotlights and wipes, or a picture whose brightness is used as
+// the opacity (white is visible, black is hidden) for soft-edged reveals.
+func (vm *VM) registerMaskBuiltins() {
+	// SetCastMask: Show only a rectangle of a cast (coordinates relative to the cast)
+	// SetCastMask(cast_no, x, y, width, height)
+	vm.RegisterBuiltinFunction("SetCastMask", func(v *VM, args []any) (any, error) {
+		nums, ok := v.graphicsArgs("SetCastMask", args, 5)
+		if !ok {
+			return nil, nil
+		}
+		mask := &graphics.Mask{X: int(nums[1]), Y: int(nums[2]), Width: int(nums[3]), Height: int(nums[4])}
+		if err := v.graphicsSystem.SetCastMask(int(nums[0]), mask); err != nil {
+			v.log.Error("SetCastMask failed", "error", err)
+		}
+		return nil, nil
+	})
+
+	// SetCastMaskPic: Use the brightness of a picture as the opacity of a cast
+	// SetCastMaskPic(cast_no, pic_no[, x, y]) - the picture is placed at (x, y) relative to the cast
+	vm.RegisterBuiltinFunction("SetCastMaskPic", func(v *VM, args []any) (any, error) {
+		nums, ok := v.graphicsArgs("SetCastMaskPic", args, 2)
+		if !ok {
+			return nil, nil
+		}
+		if err := v.graphicsSystem.SetCastMask(int(nums[0]), picMask(nums[1:])); err != nil {
+			v.log.Error("SetCastMaskPic failed", "error", err)
+		}
+		return nil, nil
+	})
+
+	// DelCastMask: Remove the mask of a cast
+	// DelCastMask(cast_no)
+	vm.RegisterBuiltinFunction("DelCastMask", func(v *VM, args []any) (any, error) {
+		nums, ok := v.graphicsArgs("DelCastMask", args, 1)
+		if !ok {
+			return nil, nil
+		}
+		if err := v.graphicsSystem.SetCastMask(int(nums[0]), nil); err != nil {
+			v.log.Error("DelCastMask failed", "error", err)
+		}
+		return nil, nil
+	})
+
+	// SetWinMask: Show only a rectangle of a window and its casts
+	// SetWinMask(win_no, x, y, width, height) - coordinates relative to the window contents
+	vm.RegisterBuiltinFunction("SetWinMask", func(v *VM, args []any) (any, error) {
+		nums, ok := v.graphicsArgs("SetWinMask", args, 5)
+		if !ok {
+			return nil, nil
+		}
+		mask := &graphics.Mask{X: int(nums[1]), Y: int(nums[2]), Width: int(nums[3]), Height: int(nums[4])}
+		if err := v.graphicsSystem.SetWinMask(int(nums[0]), mask); err != nil {
+			v.log.Error("SetWinMask failed", "error", err)
+		}
+		return nil, nil
+	})
+
+	// SetWinMaskPic: Use the brightness of a picture as the opacity of a window and its casts
+	// SetWinMaskPic(win_no, pic_no[, x, y])
+	vm.RegisterBuiltinFunction("SetWinMaskPic", func(v *VM, args []any) (any, error) {
+		nums, ok := v.graphicsArgs("SetWinMaskPic", args, 2)
+		if !ok {
+			return nil, nil
+		}
+		if err := v.graphicsSystem.SetWinMask(int(nums[0]), picMask(nums[1:])); err != nil {
+			v.log.Error("SetWinMaskPic failed", "error", err)
+		}
+		return nil, nil
+	})
+
+	// DelWinMask: Remove the mask of a window
+	// DelWinMask(win_no)
+	vm.RegisterBuiltinFunction("DelWinMask", func(v *VM, args []any) (any, error) {
+		nums, ok := v.graphicsArgs("DelWinMask", args, 1)
+		if !ok {
+			return nil, nil
+		}
+		if err := v.graphicsSystem.SetWinMask(int(nums[0]), nil); err != nil {
+			v.log.Error("DelWinMask failed", "error", err)
+		}
+		return nil, nil
+	})
+}
+
+// picMask は (pic_no[, x, y]) の引数からピクチャーマスクを作成する（x, y の省略時は 0）
+func picMask(nums []float64) *graphics.Mask {
+	mask := &graphics.Mask{PicID: int(nums[0]), UsePic: true}
+	if len(nums) >= 3 {
+		mask.X, mask.Y = int(nums[1]), int(nums[2])
+	}
+	return mask
+}
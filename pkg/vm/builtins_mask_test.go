@@ -0,0 +1,97 @@
+package vm
+
+import (
+	"testing"
+
+	"github.com/zurustar/son-et/pkg/graphics"
+	"github.com/zurustar/son-et/pkg/opcode"
+)
+
+func newMaskTestVM() (*VM, *mockGraphicsSystem) {
+	vm := New([]opcode.OpCode{})
+	mockGS := newMockGraphicsSystem()
+	vm.SetGraphicsSystem(mockGS)
+	return vm, mockGS
+}
+
+func TestVMBuiltinMasksRegistered(t *testing.T) {
+	vm := New([]opcode.OpCode{})
+	for _, name := range []string{"SetCastMask", "SetCastMaskPic", "DelCastMask", "SetWinMask", "SetWinMaskPic", "DelWinMask"} {
+		if _, ok := vm.builtins[name]; !ok {
+			t.Errorf("expected %s to be registered as built-in function", name)
+		}
+	}
+}
+
+func TestVMBuiltinSetCastMask(t *testing.T) {
+	vm, mockGS := newMaskTestVM()
+	if _, err := vm.builtins["SetCastMask"](vm, []any{int64(3), int64(10), int64(20), int64(30), int64(40)}); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	want := graphics.Mask{X: 10, Y: 20, Width: 30, Height: 40}
+	if got := mockGS.masks["cast 3"]; got == nil || *got != want {
+		t.Fatalf("cast mask = %+v, want %+v", got, want)
+	}
+
+	vm.builtins["DelCastMask"](vm, []any{int64(3)})
+	if _, ok := mockGS.masks["cast 3"]; ok {
+		t.Error("expected DelCastMask to remove the mask")
+	}
+}
+
+func TestVMBuiltinSetMaskPic(t *testing.T) {
+	t.Run("cast with offset", func(t *testing.T) {
+		vm, mockGS := newMaskTestVM()
+		vm.builtins["SetCastMaskPic"](vm, []any{int64(1), int64(5), int64(-8), int64(4)})
+		want := graphics.Mask{X: -8, Y: 4, PicID: 5, UsePic: true}
+		if got := mockGS.masks["cast 1"]; got == nil || *got != want {
+			t.Fatalf("cast mask = %+v, want %+v", got, want)
+		}
+	})
+
+	t.Run("window without offset", func(t *testing.T) {
+		vm, mockGS := newMaskTestVM()
+		vm.builtins["SetWinMaskPic"](vm, []any{int64(2), int64(7)})
+		want := graphics.Mask{PicID: 7, UsePic: true}
+		if got := mockGS.masks["win 2"]; got == nil || *got != want {
+			t.Fatalf("window mask = %+v, want %+v", got, want)
+		}
+	})
+}
+
+func TestVMBuiltinSetWinMask(t *testing.T) {
+	vm, mockGS := newMaskTestVM()
+	vm.builtins["SetWinMask"](vm, []any{int64(0), int64(0), int64(0), int64(100), int64(50)})
+	want := graphics.Mask{Width: 100, Height: 50}
+	if got := mockGS.masks["win 0"]; got == nil || *got != want {
+		t.Fatalf("window mask = %+v, want %+v", got, want)
+	}
+
+	vm.builtins["DelWinMask"](vm, []any{int64(0)})
+	if len(mockGS.masks) != 0 {
+		t.Errorf("expected DelWinMask to remove the mask, got %v", mockGS.masks)
+	}
+}
+
+func TestVMBuiltinMaskInvalidArgs(t *testing.T) {
+	vm, mockGS := newMaskTestVM()
+	for name, args := range map[string][]any{
+		"SetCastMask":    {int64(1), int64(0), int64(0), int64(10)},
+		"SetCastMaskPic": {int64(1)},
+		"SetWinMask":     {int64(1), "x", int64(0), int64(10), int64(10)},
+		"DelWinMask":     {},
+	} {
+		if result, err := vm.builtins[name](vm, args); result != nil || err != nil {
+			t.Errorf("%s(%v) = (%v, %v), want (nil, nil)", name, args, result, err)
+		}
+	}
+	if len(mockGS.masks) != 0 {
+		t.Errorf("expected no masks to be set, got %v", mockGS.masks)
+	}
+
+	// グラフィックスシステムがない場合も失敗しない
+	noGS := New([]opcode.OpCode{})
+	if _, err := noGS.builtins["SetCastMask"](noGS, []any{int64(1), int64(0), int64(0), int64(1), int64(1)}); err != nil {
+		t.Errorf("expected no error without graphics system, got %v", err)
+	}
+}
@@ -2,7 +2,6 @@ package vm
 
 import (
 	"fmt"
-	"math/rand/v2"
 )
 
 // registerMathBuiltins registers math-related built-in functions.
@@ -10,6 +9,7 @@ func (vm *VM) registerMathBuiltins() {
 	// Random: Generate random number
 	// Random(max) - returns random number from 0 to max-1
 	// Random(min, max) - returns random number from min to max-1
+	// Each sequence draws from its own deterministic stream (see rng.go)
 	vm.RegisterBuiltinFunction("Random", func(v *VM, args []any) (any, error) {
 		if len(args) < 1 {
 			return int64(0), fmt.Errorf("Random requires at least 1 argument (max)")
@@ -40,8 +40,8 @@ func (vm *VM) registerMathBuiltins() {
 			return min, nil
 		}
 
-		// Generate random number in range [min, max)
-		result := min + int64(rand.IntN(int(max-min)))
+		// Generate random number in range [min, max) from the sequence's own stream
+		result := min + v.sequenceRand().Int64N(max-min)
 		return result, nil
 	})
 
@@ -268,3 +268,107 @@ func TestActivateMes(t *testing.T) {
 		}
 	})
 }
+
+// TestSetTickPolicy tests the SetTickPolicy builtin function.
+func TestSetTickPolicy(t *testing.T) {
+	vm := New([]opcode.OpCode{})
+	handler := helperRegisterHandler(t, vm, EventMIDI_TIME)
+
+	if result, err := vm.builtins["SetTickPolicy"](vm, []any{int64(1), int64(2)}); result != nil || err != nil {
+		t.Fatalf("SetTickPolicy = (%v, %v), want (nil, nil)", result, err)
+	}
+	if handler.TickPolicy != TickDrop {
+		t.Errorf("TickPolicy = %d, want %d", handler.TickPolicy, TickDrop)
+	}
+
+	// 不明なポリシー・存在しないハンドラ・引数不足では変更しない
+	for _, args := range [][]any{
+		{int64(1), int64(3)},
+		{int64(1), int64(-1)},
+		{int64(999), int64(0)},
+		{int64(1), "coalesce"},
+		{int64(1)},
+	} {
+		if result, err := vm.builtins["SetTickPolicy"](vm, args); result != nil || err != nil {
+			t.Errorf("SetTickPolicy(%v) = (%v, %v), want (nil, nil)", args, result, err)
+		}
+	}
+	if handler.TickPolicy != TickDrop {
+		t.Errorf("TickPolicy = %d after invalid calls, want %d", handler.TickPolicy, TickDrop)
+	}
+}
+
+// TestGetDroppedTicks tests the GetDroppedTicks builtin function.
+func TestGetDroppedTicks(t *testing.T) {
+	vm := New([]opcode.OpCode{})
+	handler := helperRegisterHandler(t, vm, EventTIME)
+	handler.DroppedTicks = 5
+
+	if got, _ := vm.builtins["GetDroppedTicks"](vm, []any{int64(1)}); got != int64(5) {
+		t.Errorf("GetDroppedTicks(1) = %v, want 5", got)
+	}
+	if got, _ := vm.builtins["GetDroppedTicks"](vm, []any{int64(2)}); got != int64(0) {
+		t.Errorf("GetDroppedTicks(2) = %v, want 0 for a missing handler", got)
+	}
+	if got, _ := vm.builtins["GetDroppedTicks"](vm, []any{}); got != int64(0) {
+		t.Errorf("GetDroppedTicks() = %v, want 0", got)
+	}
+}
+
+// TestDispatchTickPolicy checks how each policy handles a burst of queued ticks.
+func TestDispatchTickPolicy(t *testing.T) {
+	const burst = 4
+	for _, tt := range []struct {
+		policy      TickPolicy
+		wantRuns    int
+		wantDropped int
+	}{
+		{TickProcessAll, burst, 0},
+		{TickCoalesce, 1, burst - 1},
+		{TickDrop, 1, burst - 1},
+	} {
+		vm := New([]opcode.OpCode{})
+		runs := 0
+		vm.RegisterBuiltinFunction("record", func(v *VM, args []any) (any, error) {
+			runs++
+			return nil, nil
+		})
+		handler := NewEventHandler("", EventMIDI_TIME, []opcode.OpCode{{Cmd: opcode.Call, Args: []any{"record"}}}, vm, nil)
+		handler.TickPolicy = tt.policy
+		vm.handlerRegistry.Register(handler)
+
+		for i := 0; i < burst; i++ {
+			vm.eventQueue.Push(NewEvent(EventMIDI_TIME))
+		}
+		if err := vm.eventDispatcher.ProcessQueue(); err != nil {
+			t.Fatalf("policy %d: ProcessQueue failed: %v", tt.policy, err)
+		}
+		if runs != tt.wantRuns || handler.DroppedTicks != tt.wantDropped {
+			t.Errorf("policy %d: runs=%d dropped=%d, want runs=%d dropped=%d",
+				tt.policy, runs, handler.DroppedTicks, tt.wantRuns, tt.wantDropped)
+		}
+	}
+}
+
+// TestSkipTickWait checks that coalesced ticks still count toward Wait while dropped ticks do not.
+func TestSkipTickWait(t *testing.T) {
+	handler := &EventHandler{TickPolicy: TickCoalesce, WaitCounter: 3}
+	for i := 0; i < 3; i++ {
+		if !handler.skipTick() {
+			t.Fatal("expected the coalesce policy to skip ticks while behind")
+		}
+	}
+	if handler.WaitCounter != 1 || handler.DroppedTicks != 1 {
+		t.Errorf("coalesce: WaitCounter=%d DroppedTicks=%d, want 1 and 1", handler.WaitCounter, handler.DroppedTicks)
+	}
+
+	handler = &EventHandler{TickPolicy: TickDrop, WaitCounter: 3}
+	handler.skipTick()
+	if handler.WaitCounter != 3 || handler.DroppedTicks != 1 {
+		t.Errorf("drop: WaitCounter=%d DroppedTicks=%d, want 3 and 1", handler.WaitCounter, handler.DroppedTicks)
+	}
+
+	if (&EventHandler{}).skipTick() {
+		t.Error("expected the default policy not to skip ticks")
+	}
+}
@@ -0,0 +1,82 @@
+package vm
+
+import (
+	"fmt"
+)
+
+// registerMIDIPortBuiltins registers built-in functions for MIDI ports.
+// A MIDI port plays one MIDI file; several ports play at once (e.g. background
+// music on "main" plus a short jingle on another port). Each port has its own
+// clock, and the clock selected by SetMIDIClock drives MIDI_TIME events.
+func (vm *VM) registerMIDIPortBuiltins() {
+	// PlayMIDIPort: Play a MIDI file on a named port without stopping the other ports
+	// PlayMIDIPort(port, filename) - PlayMIDIPort("main", filename) is the same as PlayMIDI(filename)
+	vm.RegisterBuiltinFunction("PlayMIDIPort", func(v *VM, args []any) (any, error) {
+		strs, ok := v.midiPortArgs("PlayMIDIPort", args, 2)
+		if !ok {
+			return nil, nil
+		}
+		if err := v.PlayMIDIPort(strs[0], strs[1]); err != nil {
+			v.log.Error("PlayMIDIPort failed", "port", strs[0], "filename", strs[1], "error", err)
+			return nil, nil
+		}
+		v.log.Debug("PlayMIDIPort called", "port", strs[0], "filename", strs[1])
+		return nil, nil
+	})
+
+	// StopMIDIPort: Stop the MIDI file playing on a port
+	// StopMIDIPort(port)
+	vm.RegisterBuiltinFunction("StopMIDIPort", func(v *VM, args []any) (any, error) {
+		strs, ok := v.midiPortArgs("StopMIDIPort", args, 1)
+		if !ok {
+			return nil, nil
+		}
+		v.audioSystem.StopMIDIPort(strs[0])
+		return nil, nil
+	})
+
+	// MIDIClock: Get the current tick (16th note units) of a port's clock
+	// MIDIClock(port) returns -1 if nothing is playing on the port
+	vm.RegisterBuiltinFunction("MIDIClock", func(v *VM, args []any) (any, error) {
+		strs, ok := v.midiPortArgs("MIDIClock", args, 1)
+		if !ok {
+			return int64(-1), nil
+		}
+		return int64(v.audioSystem.MIDIPortTick(strs[0])), nil
+	})
+
+	// SetMIDIClock: Select the port whose clock drives MIDI_TIME events
+	// SetMIDIClock(port) - while nothing is playing on the port, "main" drives MIDI_TIME
+	vm.RegisterBuiltinFunction("SetMIDIClock", func(v *VM, args []any) (any, error) {
+		strs, ok := v.midiPortArgs("SetMIDIClock", args, 1)
+		if !ok {
+			return nil, nil
+		}
+		v.audioSystem.SetMIDIClock(strs[0])
+		v.log.Debug("SetMIDIClock called", "port", strs[0])
+		return nil, nil
+	})
+}
+
+// midiPortArgs は MIDI ポートの組み込み関数の文字列引数（ポート名、ファイル名）を解釈する。
+// オーディオシステムがない場合や引数が不正な場合はログを記録して ok=false を返す。
+func (vm *VM) midiPortArgs(name string, args []any, n int) (strs []string, ok bool) {
+	if vm.audioSystem == nil {
+		vm.log.Debug(name+" called but audio system not initialized", "args", args)
+		return nil, false
+	}
+	if len(args) < n {
+		vm.log.Error(fmt.Sprintf("%s requires %d arguments", name, n), "got", len(args))
+		return nil, false
+	}
+	strs = make([]string, n)
+	for i := range strs {
+		s, isString := args[i].(string)
+		if !isString {
+			vm.log.Error(name+": arguments must be strings", "index", i, "got", fmt.Sprintf("%T", args[i]))
+			return nil, false
+		}
+		strs[i] = s
+	}
+	return strs, true
+}
@@ -0,0 +1,70 @@
+package vm
+
+import (
+	"testing"
+
+	"github.com/zurustar/son-et/pkg/opcode"
+)
+
+func TestMIDIPortBuiltinsRegistered(t *testing.T) {
+	vm := New([]opcode.OpCode{})
+	for _, name := range []string{"PlayMIDIPort", "StopMIDIPort", "MIDIClock", "SetMIDIClock"} {
+		if _, ok := vm.builtins[name]; !ok {
+			t.Errorf("expected %s to be registered as built-in function", name)
+		}
+	}
+}
+
+func TestPlayMIDIPortBuiltin(t *testing.T) {
+	audio := newMockAudioSystem()
+	vm := New([]opcode.OpCode{})
+	vm.SetAudioSystem(audio)
+
+	if _, err := vm.builtins["PlayMIDIPort"](vm, []any{"jingle", "fanfare.mid"}); err != nil {
+		t.Fatalf("PlayMIDIPort failed: %v", err)
+	}
+	if got := audio.ports["jingle"]; got != "fanfare.mid" {
+		t.Errorf("jingle port plays %q, want fanfare.mid", got)
+	}
+
+	vm.builtins["StopMIDIPort"](vm, []any{"jingle"})
+	if _, ok := audio.ports["jingle"]; ok {
+		t.Error("expected StopMIDIPort to stop the port")
+	}
+
+	// 不正な引数はログを記録して継続する
+	for _, args := range [][]any{{"jingle"}, {int64(1), "fanfare.mid"}, {}} {
+		if _, err := vm.builtins["PlayMIDIPort"](vm, args); err != nil {
+			t.Errorf("PlayMIDIPort%v should not fail the script: %v", args, err)
+		}
+	}
+	if len(audio.ports) != 0 {
+		t.Errorf("expected no ports to be played, got %v", audio.ports)
+	}
+}
+
+func TestMIDIClockBuiltin(t *testing.T) {
+	audio := newMockAudioSystem()
+	vm := New([]opcode.OpCode{})
+	vm.SetAudioSystem(audio)
+
+	audio.ports = map[string]string{"jingle": "fanfare.mid"}
+	audio.ticks = map[string]int{"jingle": 12}
+	if got, _ := vm.builtins["MIDIClock"](vm, []any{"jingle"}); got != int64(12) {
+		t.Errorf("MIDIClock(jingle) = %v, want 12", got)
+	}
+	if got, _ := vm.builtins["MIDIClock"](vm, []any{"bgm"}); got != int64(-1) {
+		t.Errorf("MIDIClock(bgm) = %v, want -1", got)
+	}
+
+	vm.builtins["SetMIDIClock"](vm, []any{"jingle"})
+	if audio.clock != "jingle" {
+		t.Errorf("clock = %q, want jingle", audio.clock)
+	}
+
+	// オーディオシステムがない場合は -1
+	noAudio := New([]opcode.OpCode{})
+	if got, _ := noAudio.builtins["MIDIClock"](noAudio, []any{"main"}); got != int64(-1) {
+		t.Errorf("MIDIClock without audio system = %v, want -1", got)
+	}
+}
@@ -0,0 +1,108 @@
+package vm
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/zurustar/son-et/pkg/opcode"
+)
+
+// Ranges of the MIDI note hooks
+const (
+	noteAnyChannel = 0   // react to every channel
+	maxMIDIChannel = 16  // scripts number channels 1 to 16
+	maxMIDINote    = 127 // notes are 0 to 127
+)
+
+// noteBinding is the channel and note range given to OnNote/BindNote.
+type noteBinding struct {
+	channel  int // 1 to 16, or noteAnyChannel for every channel
+	low      int
+	high     int
+	funcName any // function to call, or variable to assign
+}
+
+// matches reports whether a MIDI_NOTE event is a note in the range.
+func (b noteBinding) matches(event *Event) bool {
+	channel, ok := eventParamInt(event, "MesP2")
+	if !ok || (b.channel != noteAnyChannel && channel != b.channel) {
+		return false
+	}
+	note, ok := eventParamInt(event, "MesP3")
+	return ok && note >= b.low && note <= b.high
+}
+
+// parseNoteBinding parses (channel, note, target) or (channel, lowNote, highNote, target).
+func parseNoteBinding(args []any) (noteBinding, error) {
+	if len(args) < 3 {
+		return noteBinding{}, fmt.Errorf("requires 3 or 4 arguments (channel, note[, highNote], target)")
+	}
+
+	nums := make([]int, len(args)-1)
+	for i, arg := range args[:len(args)-1] {
+		n, ok := toInt64(arg)
+		if !ok {
+			return noteBinding{}, fmt.Errorf("argument %d must be integer, got %T", i+1, arg)
+		}
+		nums[i] = int(n)
+	}
+
+	b := noteBinding{channel: nums[0], low: nums[1], high: nums[len(nums)-1], funcName: args[len(args)-1]}
+	if b.channel < noteAnyChannel || b.channel > maxMIDIChannel {
+		return noteBinding{}, fmt.Errorf("channel must be 0-%d, got %d", maxMIDIChannel, b.channel)
+	}
+	if b.low < 0 || b.high > maxMIDINote || b.low > b.high {
+		return noteBinding{}, fmt.Errorf("invalid note range %d-%d (0-%d)", b.low, b.high, maxMIDINote)
+	}
+	return b, nil
+}
+
+// registerNoteBuiltins registers built-in functions that react to MIDI NoteOn messages.
+// Like OnKey, each binding is an ordinary MIDI_NOTE event handler, so it can be removed
+// with DelMes or paused with FreezeMes.
+func (vm *VM) registerNoteBuiltins() {
+	// OnNote(channel, note, "FuncName") / OnNote(channel, lowNote, highNote, "FuncName")
+	// calls FuncName(note, velocity) on each NoteOn in the range while MIDI is playing.
+	// channel is 1-16, or 0 for every channel. Returns the handler number.
+	vm.RegisterBuiltinFunction("OnNote", func(v *VM, args []any) (any, error) {
+		b, err := parseNoteBinding(args)
+		if err != nil {
+			v.log.Error("OnNote: invalid arguments", "error", err)
+			return nil, nil
+		}
+		return v.registerInputHandler("OnNote", EventMIDI_NOTE, b.funcName, b.matches,
+			opcode.Variable("MesP3"), opcode.Variable("MesP4"))
+	})
+
+	// BindNote(channel, note, "VarName") / BindNote(channel, lowNote, highNote, "VarName")
+	// stores the velocity of each NoteOn in the range in the variable VarName.
+	// The variable is created as a global (initialized to 0) if it does not exist yet;
+	// the script resets it after reacting, e.g. to fade out a flash.
+	// Returns the handler number.
+	vm.RegisterBuiltinFunction("BindNote", func(v *VM, args []any) (any, error) {
+		b, err := parseNoteBinding(args)
+		if err != nil {
+			v.log.Error("BindNote: invalid arguments", "error", err)
+			return nil, nil
+		}
+		name := strings.TrimSpace(toString(b.funcName))
+		if name == "" {
+			v.log.Error("BindNote: variable name is empty")
+			return nil, nil
+		}
+
+		if _, exists := v.GetCurrentScope().Get(name); !exists {
+			v.globalScope.Set(name, int64(0))
+		}
+		body := []opcode.OpCode{{
+			Cmd:  opcode.Assign,
+			Args: []any{opcode.Variable(name), opcode.Variable("MesP4")},
+		}}
+		handler := NewEventHandler("", EventMIDI_NOTE, body, v, v.GetCurrentScope())
+		handler.Filter = b.matches
+		v.handlerRegistry.Register(handler)
+
+		v.log.Debug("BindNote registered", "handler", handler.ID, "variable", name)
+		return int64(handler.Number), nil
+	})
+}
@@ -0,0 +1,99 @@
+package vm
+
+import (
+	"testing"
+)
+
+// TestNewMIDINoteEvent tests the MIDI_NOTE event parameters pushed by the MIDI player.
+func TestNewMIDINoteEvent(t *testing.T) {
+	event := NewMIDINoteEvent(9, 36, 100)
+	if event.Type != EventMIDI_NOTE {
+		t.Fatalf("Type = %s, want MIDI_NOTE", event.Type)
+	}
+	for name, want := range map[string]int{"MesP2": 10, "MesP3": 36, "MesP4": 100} {
+		if got, _ := eventParamInt(event, name); got != want {
+			t.Errorf("%s = %d, want %d", name, got, want)
+		}
+	}
+}
+
+// TestOnNote tests that OnNote calls the function with note and velocity for notes in range.
+func TestOnNote(t *testing.T) {
+	vm, calls := newInputTestVM(t, "note", "velocity")
+
+	// Bass drum to snare (35 to 40) on channel 10
+	result, _ := vm.builtins["OnNote"](vm, []any{int64(10), int64(35), int64(40), "onInput"})
+	if result != int64(1) {
+		t.Fatalf("OnNote should return handler number 1, got %v", result)
+	}
+
+	vm.eventDispatcher.Dispatch(NewMIDINoteEvent(9, 36, 110))
+	vm.eventDispatcher.Dispatch(NewMIDINoteEvent(9, 42, 80)) // note out of range
+	vm.eventDispatcher.Dispatch(NewMIDINoteEvent(0, 36, 90)) // other channel
+
+	if len(*calls) != 1 {
+		t.Fatalf("expected 1 call, got %d", len(*calls))
+	}
+	note, _ := toInt64((*calls)[0][0])
+	velocity, _ := toInt64((*calls)[0][1])
+	if note != 36 || velocity != 110 {
+		t.Errorf("arguments = (%d, %d), want (36, 110)", note, velocity)
+	}
+}
+
+// TestOnNoteAnyChannel tests that channel 0 matches every channel and a single note can be given.
+func TestOnNoteAnyChannel(t *testing.T) {
+	vm, calls := newInputTestVM(t, "note", "velocity")
+	vm.builtins["OnNote"](vm, []any{int64(0), int64(60), "onInput"})
+
+	vm.eventDispatcher.Dispatch(NewMIDINoteEvent(0, 60, 64))
+	vm.eventDispatcher.Dispatch(NewMIDINoteEvent(15, 60, 64))
+	vm.eventDispatcher.Dispatch(NewMIDINoteEvent(15, 61, 64))
+
+	if len(*calls) != 2 {
+		t.Errorf("expected 2 calls, got %d", len(*calls))
+	}
+}
+
+// TestOnNoteInvalid tests that invalid ranges are not registered.
+func TestOnNoteInvalid(t *testing.T) {
+	vm, _ := newInputTestVM(t)
+	invalid := [][]any{
+		{int64(1), "onInput"},
+		{int64(17), int64(60), "onInput"},
+		{int64(1), int64(70), int64(60), "onInput"},
+		{int64(1), int64(0), int64(128), "onInput"},
+		{int64(1), "C4", "onInput"},
+		{int64(1), int64(60), "undefinedFunc"},
+	}
+	for _, args := range invalid {
+		if result, _ := vm.builtins["OnNote"](vm, args); result != nil {
+			t.Errorf("OnNote(%v) should not register a handler, got %v", args, result)
+		}
+	}
+}
+
+// TestBindNote tests that BindNote stores the velocity in a global variable.
+func TestBindNote(t *testing.T) {
+	vm, _ := newInputTestVM(t)
+
+	result, _ := vm.builtins["BindNote"](vm, []any{int64(10), int64(49), int64(57), "crash"})
+	if result == nil {
+		t.Fatal("BindNote should return the handler number")
+	}
+	if v, ok := vm.globalScope.Get("crash"); !ok || v != int64(0) {
+		t.Fatalf("crash should be initialized to 0, got %v (exists %v)", v, ok)
+	}
+
+	vm.eventDispatcher.Dispatch(NewMIDINoteEvent(9, 49, 127))
+	if v, _ := vm.globalScope.Get("crash"); v != 127 {
+		t.Errorf("crash = %v, want 127", v)
+	}
+
+	// A note out of range does not change it
+	vm.globalScope.Set("crash", int64(0))
+	vm.eventDispatcher.Dispatch(NewMIDINoteEvent(9, 36, 100))
+	if v, _ := vm.globalScope.Get("crash"); v != int64(0) {
+		t.Errorf("crash = %v, want 0 after a note out of range", v)
+	}
+}
@@ -0,0 +1,54 @@
+package vm
+
+import (
+	"errors"
+
+	"github.com/zurustar/son-et/pkg/osc"
+)
+
+// OSCSender sends OSC messages to allowed destinations (implemented by *osc.Sender).
+type OSCSender interface {
+	Send(dest, address string, args ...any) error
+}
+
+// WithOSCSender enables OSCSend with the destinations allowed by --osc-allow.
+// Without a sender, OSCSend logs a warning and sends nothing.
+func WithOSCSender(s OSCSender) Option {
+	return func(vm *VM) {
+		vm.oscSender = s
+	}
+}
+
+// registerOSCBuiltins registers built-in functions for OSC (Open Sound Control) output.
+func (vm *VM) registerOSCBuiltins() {
+	// OSCSend: Send an OSC message over UDP to a destination allowed by --osc-allow
+	// OSCSend(dest, address, args...) - e.g. OSCSend("192.168.0.20:9000", "/light/1", 255)
+	// Integers are sent as int32, real numbers as float32 and strings as strings.
+	// Returns 1 if the message was sent, 0 otherwise (messages over the rate limit are dropped).
+	vm.RegisterBuiltinFunction("OSCSend", func(v *VM, args []any) (any, error) {
+		if len(args) < 2 {
+			v.log.Error("OSCSend requires at least 2 arguments", "got", len(args))
+			return int64(0), nil
+		}
+		dest, _ := args[0].(string)
+		address, _ := args[1].(string)
+		if v.oscSender == nil {
+			// 毎ティック呼び出されることが多いため、警告は1回だけ記録する
+			if !v.oscDisabledWarned {
+				v.oscDisabledWarned = true
+				v.log.Warn("OSCSend called but OSC output is disabled; allow destinations with --osc-allow", "dest", dest)
+			}
+			return int64(0), nil
+		}
+		if err := v.oscSender.Send(dest, address, args[2:]...); err != nil {
+			if errors.Is(err, osc.ErrRateLimited) {
+				v.log.Debug("OSCSend dropped", "dest", dest, "address", address, "error", err)
+			} else {
+				v.log.Error("OSCSend failed", "dest", dest, "address", address, "error", err)
+			}
+			return int64(0), nil
+		}
+		v.log.Debug("OSCSend called", "dest", dest, "address", address, "args", args[2:])
+		return int64(1), nil
+	})
+}
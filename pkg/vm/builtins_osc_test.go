@@ -0,0 +1,64 @@
+package vm
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/zurustar/son-et/pkg/opcode"
+	"github.com/zurustar/son-et/pkg/osc"
+)
+
+// mockOSCSender records the messages passed to Send.
+type mockOSCSender struct {
+	sent []string
+	err  error
+}
+
+func (m *mockOSCSender) Send(dest, address string, args ...any) error {
+	if m.err != nil {
+		return m.err
+	}
+	m.sent = append(m.sent, fmt.Sprint(dest, " ", address, " ", args))
+	return nil
+}
+
+// TestOSCSend tests that OSCSend passes the destination, address and arguments to the sender.
+func TestOSCSend(t *testing.T) {
+	sender := &mockOSCSender{}
+	vm := New([]opcode.OpCode{}, WithOSCSender(sender))
+
+	result, _ := vm.builtins["OSCSend"](vm, []any{"127.0.0.1:9000", "/light/1", int64(255), 0.5, "on"})
+	if result != int64(1) {
+		t.Errorf("result = %v, want 1", result)
+	}
+	want := []string{"127.0.0.1:9000 /light/1 [255 0.5 on]"}
+	if !reflect.DeepEqual(sender.sent, want) {
+		t.Errorf("sent = %v, want %v", sender.sent, want)
+	}
+}
+
+// TestOSCSendFailures tests that OSCSend returns 0 without stopping the script when nothing is sent.
+func TestOSCSendFailures(t *testing.T) {
+	disabled := New([]opcode.OpCode{})
+	for range 2 {
+		if result, err := disabled.builtins["OSCSend"](disabled, []any{"127.0.0.1:9000", "/a"}); result != int64(0) || err != nil {
+			t.Errorf("without a sender: result = %v, err = %v", result, err)
+		}
+	}
+	if !disabled.oscDisabledWarned {
+		t.Error("OSCSend without a sender should be logged")
+	}
+
+	for _, err := range []error{osc.ErrNotAllowed, osc.ErrRateLimited} {
+		vm := New([]opcode.OpCode{}, WithOSCSender(&mockOSCSender{err: err}))
+		if result, _ := vm.builtins["OSCSend"](vm, []any{"127.0.0.1:9000", "/a"}); result != int64(0) {
+			t.Errorf("%v: result = %v, want 0", err, result)
+		}
+	}
+
+	vm := New([]opcode.OpCode{}, WithOSCSender(&mockOSCSender{}))
+	if result, _ := vm.builtins["OSCSend"](vm, []any{"127.0.0.1:9000"}); result != int64(0) {
+		t.Errorf("missing address: result = %v, want 0", result)
+	}
+}
@@ -0,0 +1,131 @@
+package vm
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/zurustar/son-et/pkg/graphics"
+)
+
+// maxPaths は同時に定義できるパスの数の上限
+const maxPaths = 256
+
+// pathKinds は DefinePath の最初の引数で指定できる曲線の種類（小文字）
+var pathKinds = map[string]graphics.PathKind{
+	"catmullrom": graphics.PathCatmullRom,
+	"bezier":     graphics.PathBezier,
+}
+
+// registerPathBuiltins registers built-in functions for moving casts along curves.
+// A path is defined once from its points and can be shared by any number of casts;
+// the graphics system moves the cast every frame, so a curved motion costs one call
+// instead of a loop of MoveCast calls in the script.
+func (vm *VM) registerPathBuiltins() {
+	// DefinePath: Define a curve from its points
+	// DefinePath(["kind",] x1, y1, x2, y2, ...) - kind is "catmullrom" (default, passes
+	// through every point) or "bezier" (cubic Bezier segments: start, control, control, end, ...).
+	// Returns the path ID (1 or more), or -1 on error.
+	vm.RegisterBuiltinFunction("DefinePath", func(v *VM, args []any) (any, error) {
+		kind := graphics.PathCatmullRom
+		if len(args) > 0 {
+			if name, ok := args[0].(string); ok {
+				k, known := pathKinds[strings.ToLower(name)]
+				if !known {
+					v.log.Error("DefinePath: unknown path kind", "kind", name)
+					return int64(-1), nil
+				}
+				kind = k
+				args = args[1:]
+			}
+		}
+		if len(args)%2 != 0 {
+			v.log.Error("DefinePath: coordinates must be x, y pairs", "got", len(args))
+			return int64(-1), nil
+		}
+		points := make([]graphics.PathPoint, 0, len(args)/2)
+		for i := 0; i < len(args); i += 2 {
+			x, okX := toFloat64(args[i])
+			y, okY := toFloat64(args[i+1])
+			if !okX || !okY {
+				v.log.Error("DefinePath: coordinates must be numbers", "index", i, "got", fmt.Sprintf("%T, %T", args[i], args[i+1]))
+				return int64(-1), nil
+			}
+			points = append(points, graphics.PathPoint{X: x, Y: y})
+		}
+		if len(v.paths) >= maxPaths {
+			v.log.Error("DefinePath: too many paths", "max", maxPaths)
+			return int64(-1), nil
+		}
+		path, err := graphics.NewPath(kind, points)
+		if err != nil {
+			v.log.Error("DefinePath failed", "error", err)
+			return int64(-1), nil
+		}
+		if v.paths == nil {
+			v.paths = make(map[int]*graphics.Path)
+		}
+		v.nextPathID++
+		v.paths[v.nextPathID] = path
+		return int64(v.nextPathID), nil
+	})
+
+	// DelPath: Delete a path
+	// DelPath(path_id) - casts already moving along the path finish their motion.
+	vm.RegisterBuiltinFunction("DelPath", func(v *VM, args []any) (any, error) {
+		if len(args) < 1 {
+			v.log.Warn("DelPath requires 1 argument (path_id)")
+			return nil, nil
+		}
+		id, ok := toInt64(args[0])
+		if !ok {
+			v.log.Error("DelPath: path_id must be an integer", "got", args[0])
+			return nil, nil
+		}
+		if _, exists := v.paths[int(id)]; !exists {
+			v.log.Warn("DelPath: path not found", "pathID", id)
+			return nil, nil
+		}
+		delete(v.paths, int(id))
+		return nil, nil
+	})
+
+	// MoveAlongPath: Move a cast from the start to the end of a path
+	// MoveAlongPath(cast_no, path_id, ticks[, easing]) - ticks have the same length as
+	// the FadeOut ticks. easing is 0 (linear, default), 1 (ease in), 2 (ease out) or 3 (ease in-out).
+	// The cast moves at a constant speed along the curve whatever the spacing of the points.
+	// MoveAlongPath(cast_no, 0, 0) stops a running motion where the cast is.
+	vm.RegisterBuiltinFunction("MoveAlongPath", func(v *VM, args []any) (any, error) {
+		nums, ok := v.graphicsArgs("MoveAlongPath", args, 3)
+		if !ok {
+			return nil, nil
+		}
+		castID, pathID, ticks := int(nums[0]), int(nums[1]), nums[2]
+		if ticks < 0 {
+			v.log.Error("MoveAlongPath: ticks must be non-negative", "ticks", ticks)
+			return nil, nil
+		}
+		easing := graphics.EaseLinear
+		if len(nums) >= 4 {
+			easing = graphics.Easing(nums[3])
+			if !graphics.ValidEasing(easing) {
+				v.log.Error("MoveAlongPath: unknown easing", "easing", nums[3])
+				return nil, nil
+			}
+		}
+
+		var path *graphics.Path
+		if ticks > 0 {
+			path = v.paths[pathID]
+			if path == nil {
+				v.log.Error("MoveAlongPath: path not found", "pathID", pathID)
+				return nil, nil
+			}
+		}
+		duration := time.Duration(ticks) * fadeTickDuration
+		if err := v.graphicsSystem.MoveCastAlongPath(castID, path, duration, easing); err != nil {
+			v.log.Error("MoveAlongPath failed", "error", err)
+		}
+		return nil, nil
+	})
+}
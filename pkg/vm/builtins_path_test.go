@@ -0,0 +1,100 @@
+package vm
+
+import (
+	"testing"
+
+	"github.com/zurustar/son-et/pkg/graphics"
+	"github.com/zurustar/son-et/pkg/opcode"
+)
+
+func TestVMBuiltinPathsRegistered(t *testing.T) {
+	vm := New([]opcode.OpCode{})
+	for _, name := range []string{"DefinePath", "DelPath", "MoveAlongPath"} {
+		if _, ok := vm.builtins[name]; !ok {
+			t.Errorf("expected %s to be registered as built-in function", name)
+		}
+	}
+}
+
+func TestVMBuiltinDefinePath(t *testing.T) {
+	vm := New([]opcode.OpCode{})
+	tests := []struct {
+		name     string
+		args     []any
+		want     int64
+		wantKind graphics.PathKind
+	}{
+		{"catmull-rom by default", []any{int64(0), int64(0), int64(100), int64(50)}, 1, graphics.PathCatmullRom},
+		{"bezier", []any{"Bezier", int64(0), int64(0), int64(0), int64(9), int64(9), int64(9), 9.5, int64(0)}, 2, graphics.PathBezier},
+		{"explicit catmull-rom", []any{"catmullrom", int64(0), int64(0), int64(1), int64(1), int64(2), int64(0)}, 3, graphics.PathCatmullRom},
+		{"odd coordinates", []any{int64(0), int64(0), int64(100)}, -1, 0},
+		{"one point", []any{int64(0), int64(0)}, -1, 0},
+		{"bezier with 3 points", []any{"bezier", int64(0), int64(0), int64(1), int64(1), int64(2), int64(2)}, -1, 0},
+		{"unknown kind", []any{"spiral", int64(0), int64(0), int64(1), int64(1)}, -1, 0},
+		{"non-numeric coordinate", []any{int64(0), "x", int64(1), int64(1)}, -1, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := vm.builtins["DefinePath"](vm, tt.args)
+			if err != nil || got != tt.want {
+				t.Fatalf("DefinePath = (%v, %v), want (%d, nil)", got, err, tt.want)
+			}
+			if tt.want > 0 && vm.paths[int(tt.want)].Kind() != tt.wantKind {
+				t.Errorf("path kind = %v, want %v", vm.paths[int(tt.want)].Kind(), tt.wantKind)
+			}
+		})
+	}
+	if len(vm.paths) != 3 {
+		t.Errorf("paths = %d, want 3", len(vm.paths))
+	}
+
+	vm.builtins["DelPath"](vm, []any{int64(2)})
+	if _, ok := vm.paths[2]; ok {
+		t.Error("expected DelPath to remove the path")
+	}
+	// 削除したパスの番号は再利用しない
+	if got, _ := vm.builtins["DefinePath"](vm, []any{int64(0), int64(0), int64(1), int64(1)}); got != int64(4) {
+		t.Errorf("DefinePath after DelPath = %v, want 4", got)
+	}
+}
+
+func TestVMBuiltinDefinePathLimit(t *testing.T) {
+	vm := New([]opcode.OpCode{})
+	for range maxPaths {
+		vm.builtins["DefinePath"](vm, []any{int64(0), int64(0), int64(1), int64(1)})
+	}
+	if got, _ := vm.builtins["DefinePath"](vm, []any{int64(0), int64(0), int64(1), int64(1)}); got != int64(-1) {
+		t.Errorf("DefinePath over the limit = %v, want -1", got)
+	}
+}
+
+func TestVMBuiltinMoveAlongPath(t *testing.T) {
+	vm, mockGS := newMaskTestVM()
+	id, _ := vm.builtins["DefinePath"](vm, []any{int64(0), int64(0), int64(100), int64(50)})
+
+	vm.builtins["MoveAlongPath"](vm, []any{int64(3), id, int64(20)})
+	vm.builtins["MoveAlongPath"](vm, []any{int64(4), id, int64(10), int64(3)})
+	vm.builtins["MoveAlongPath"](vm, []any{int64(3), int64(0), int64(0)})
+	want := []mockPathMotion{
+		{castID: 3, path: vm.paths[1], duration: 20 * fadeTickDuration, easing: graphics.EaseLinear},
+		{castID: 4, path: vm.paths[1], duration: 10 * fadeTickDuration, easing: graphics.EaseInOut},
+		{castID: 3, path: nil, duration: 0, easing: graphics.EaseLinear},
+	}
+	if len(mockGS.pathMotions) != len(want) {
+		t.Fatalf("pathMotions = %+v, want %+v", mockGS.pathMotions, want)
+	}
+	for i := range want {
+		if mockGS.pathMotions[i] != want[i] {
+			t.Errorf("pathMotions[%d] = %+v, want %+v", i, mockGS.pathMotions[i], want[i])
+		}
+	}
+
+	// 不正な引数では移動しない
+	vm.builtins["MoveAlongPath"](vm, []any{int64(3), int64(99), int64(10)})
+	vm.builtins["MoveAlongPath"](vm, []any{int64(3), id, int64(-1)})
+	vm.builtins["MoveAlongPath"](vm, []any{int64(3), id, int64(10), int64(7)})
+	vm.builtins["MoveAlongPath"](vm, []any{int64(3), id})
+	if len(mockGS.pathMotions) != len(want) {
+		t.Errorf("invalid calls started motions: %+v", mockGS.pathMotions[len(want):])
+	}
+}
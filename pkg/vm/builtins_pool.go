@@ -0,0 +1,157 @@
+package vm
+
+import (
+	"fmt"
+	"image/color"
+
+	"github.com/zurustar/son-et/pkg/graphics"
+)
+
+// poolAllParticles は SetPoolVelocity ですべての粒子を対象にする場合の粒子番号
+const poolAllParticles = -1
+
+// registerPoolBuiltins registers built-in functions for sprite pools.
+// A sprite pool holds many small sprites (snow, petals, confetti) sharing one picture.
+// The particles are moved in batches (ScatterPool, SetPoolVelocity, StepPool) so that
+// animating hundreds of them costs a few OpCodes per frame instead of one MoveCast each.
+func (vm *VM) registerPoolBuiltins() {
+	// CreateSpritePool: Create a pool of hidden particles showing a picture on base_pic
+	// CreateSpritePool(pic_no, base_pic, count[, transparentColor]) returns the pool number (-1 on failure)
+	vm.RegisterBuiltinFunction("CreateSpritePool", func(v *VM, args []any) (any, error) {
+		nums, ok := v.graphicsArgs("CreateSpritePool", args, 3)
+		if !ok {
+			return -1, nil
+		}
+		var transColor color.Color
+		if len(nums) >= 4 {
+			transColor = graphics.ColorFromInt(int(nums[3]))
+		}
+		poolID, err := v.graphicsSystem.CreateSpritePool(int(nums[0]), int(nums[1]), int(nums[2]), transColor)
+		if err != nil {
+			v.log.Error("CreateSpritePool failed", "error", err)
+			return -1, nil
+		}
+		v.log.Debug("CreateSpritePool called", "srcPicID", nums[0], "dstPicID", nums[1], "size", nums[2], "poolID", poolID)
+		return poolID, nil
+	})
+
+	// SetPoolSprite: Place one particle (index 0 to count-1) in base_pic coordinates
+	// SetPoolSprite(pool_no, index, x, y[, visible]) - visible defaults to 1
+	vm.RegisterBuiltinFunction("SetPoolSprite", func(v *VM, args []any) (any, error) {
+		nums, ok := v.graphicsArgs("SetPoolSprite", args, 4)
+		if !ok {
+			return nil, nil
+		}
+		visible := len(nums) < 5 || nums[4] != 0
+		if err := v.graphicsSystem.SetPoolParticle(int(nums[0]), int(nums[1]), nums[2], nums[3], visible); err != nil {
+			v.log.Error("SetPoolSprite failed", "error", err)
+		}
+		return nil, nil
+	})
+
+	// ScatterPool: Show every particle at a random position inside a rectangle
+	// ScatterPool(pool_no, x, y, width, height)
+	vm.RegisterBuiltinFunction("ScatterPool", func(v *VM, args []any) (any, error) {
+		nums, ok := v.graphicsArgs("ScatterPool", args, 5)
+		if !ok {
+			return nil, nil
+		}
+		poolID := int(nums[0])
+		n := v.graphicsSystem.SpritePoolSize(poolID)
+		if n == 0 {
+			v.log.Error("ScatterPool: sprite pool not found", "poolID", poolID)
+			return nil, nil
+		}
+		rng := v.sequenceRand()
+		x, y, w, h := nums[1], nums[2], max(nums[3], 0), max(nums[4], 0)
+		for i := range n {
+			px := x + rng.Float64()*w
+			py := y + rng.Float64()*h
+			if err := v.graphicsSystem.SetPoolParticle(poolID, i, px, py, true); err != nil {
+				v.log.Error("ScatterPool failed", "error", err)
+				return nil, nil
+			}
+		}
+		v.log.Debug("ScatterPool called", "poolID", poolID, "size", n)
+		return nil, nil
+	})
+
+	// SetPoolVelocity: Set how far every particle moves per StepPool
+	// SetPoolVelocity(pool_no, vx, vy[, jitter]) - each particle gets vx/vy plus a random value in -jitter..jitter
+	vm.RegisterBuiltinFunction("SetPoolVelocity", func(v *VM, args []any) (any, error) {
+		nums, ok := v.graphicsArgs("SetPoolVelocity", args, 3)
+		if !ok {
+			return nil, nil
+		}
+		poolID, vx, vy := int(nums[0]), nums[1], nums[2]
+		jitter := 0.0
+		if len(nums) >= 4 {
+			jitter = max(nums[3], 0)
+		}
+		if jitter == 0 {
+			if err := v.graphicsSystem.SetPoolVelocity(poolID, poolAllParticles, vx, vy); err != nil {
+				v.log.Error("SetPoolVelocity failed", "error", err)
+			}
+			return nil, nil
+		}
+		rng := v.sequenceRand()
+		for i := range v.graphicsSystem.SpritePoolSize(poolID) {
+			jx := (rng.Float64()*2 - 1) * jitter
+			jy := (rng.Float64()*2 - 1) * jitter
+			if err := v.graphicsSystem.SetPoolVelocity(poolID, i, vx+jx, vy+jy); err != nil {
+				v.log.Error("SetPoolVelocity failed", "error", err)
+				return nil, nil
+			}
+		}
+		return nil, nil
+	})
+
+	// StepPool: Move every particle by its velocity; particles leaving base_pic re-enter from the opposite side
+	// StepPool(pool_no)
+	vm.RegisterBuiltinFunction("StepPool", func(v *VM, args []any) (any, error) {
+		nums, ok := v.graphicsArgs("StepPool", args, 1)
+		if !ok {
+			return nil, nil
+		}
+		if err := v.graphicsSystem.StepSpritePool(int(nums[0])); err != nil {
+			v.log.Error("StepPool failed", "error", err)
+		}
+		return nil, nil
+	})
+
+	// DelSpritePool: Delete a sprite pool and all its particles
+	// DelSpritePool(pool_no)
+	vm.RegisterBuiltinFunction("DelSpritePool", func(v *VM, args []any) (any, error) {
+		nums, ok := v.graphicsArgs("DelSpritePool", args, 1)
+		if !ok {
+			return nil, nil
+		}
+		if err := v.graphicsSystem.DelSpritePool(int(nums[0])); err != nil {
+			v.log.Error("DelSpritePool failed", "error", err)
+		}
+		return nil, nil
+	})
+}
+
+// graphicsArgs はグラフィックス系の組み込み関数（スプライトプール、マスク）の数値引数を解釈する（少なくとも minArgs 個）。
+// グラフィックスシステムがない場合や引数が不正な場合はログを記録して ok=false を返す。
+func (vm *VM) graphicsArgs(name string, args []any, minArgs int) (nums []float64, ok bool) {
+	if vm.graphicsSystem == nil {
+		vm.log.Debug(name+" called but graphics system not initialized", "args", args)
+		return nil, false
+	}
+	if len(args) < minArgs {
+		vm.log.Error(fmt.Sprintf("%s requires at least %d arguments", name, minArgs), "got", len(args))
+		return nil, false
+	}
+	nums = make([]float64, len(args))
+	for i, arg := range args {
+		n, isNum := toFloat64(arg)
+		if !isNum {
+			vm.log.Error(name+": arguments must be numbers", "index", i, "got", fmt.Sprintf("%T", arg))
+			return nil, false
+		}
+		nums[i] = n
+	}
+	return nums, true
+}
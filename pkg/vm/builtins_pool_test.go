@@ -0,0 +1,170 @@
+package vm
+
+import (
+	"testing"
+
+	"github.com/zurustar/son-et/pkg/opcode"
+)
+
+// newPoolTestVM creates a VM with a mock graphics system and a destination picture.
+func newPoolTestVM(t *testing.T) (*VM, *mockGraphicsSystem, int, int) {
+	t.Helper()
+	vm := New([]opcode.OpCode{})
+	mockGS := newMockGraphicsSystem()
+	vm.SetGraphicsSystem(mockGS)
+	srcID, _ := mockGS.CreatePic(8, 8)
+	dstID, _ := mockGS.CreatePic(320, 240)
+	return vm, mockGS, srcID, dstID
+}
+
+func TestVMBuiltinPoolsRegistered(t *testing.T) {
+	vm := New([]opcode.OpCode{})
+	for _, name := range []string{"CreateSpritePool", "SetPoolSprite", "ScatterPool", "SetPoolVelocity", "StepPool", "DelSpritePool"} {
+		if _, ok := vm.builtins[name]; !ok {
+			t.Errorf("expected %s to be registered as built-in function", name)
+		}
+	}
+}
+
+func TestVMBuiltinCreateSpritePool(t *testing.T) {
+	t.Run("creates a pool on base_pic", func(t *testing.T) {
+		vm, mockGS, srcID, dstID := newPoolTestVM(t)
+		result, err := vm.builtins["CreateSpritePool"](vm, []any{int64(srcID), int64(dstID), int64(100), int64(0xFFFFFF)})
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		poolID, ok := result.(int)
+		if !ok || poolID < 1 {
+			t.Fatalf("expected a pool number, got %v", result)
+		}
+		pool := mockGS.pools[poolID]
+		if pool == nil || len(pool.particles) != 100 || pool.srcPicID != srcID || pool.dstPicID != dstID {
+			t.Fatalf("unexpected pool: %+v", pool)
+		}
+		if pool.transColor == nil {
+			t.Error("expected the transparent color to be passed")
+		}
+	})
+
+	t.Run("returns -1 on failure", func(t *testing.T) {
+		vm, _, srcID, dstID := newPoolTestVM(t)
+		fn := vm.builtins["CreateSpritePool"]
+		for _, args := range [][]any{
+			{int64(srcID), int64(dstID)},
+			{int64(srcID), int64(dstID), int64(0)},
+			{int64(srcID), "base", int64(10)},
+		} {
+			if result, _ := fn(vm, args); result != -1 {
+				t.Errorf("CreateSpritePool(%v) = %v, want -1", args, result)
+			}
+		}
+	})
+
+	t.Run("without graphics system", func(t *testing.T) {
+		vm := New([]opcode.OpCode{})
+		if result, err := vm.builtins["CreateSpritePool"](vm, []any{int64(1), int64(2), int64(10)}); err != nil || result != -1 {
+			t.Errorf("expected -1 and no error, got %v, %v", result, err)
+		}
+	})
+}
+
+func TestVMBuiltinSetPoolSprite(t *testing.T) {
+	vm, mockGS, srcID, dstID := newPoolTestVM(t)
+	poolID, _ := mockGS.CreateSpritePool(srcID, dstID, 4, nil)
+
+	fn := vm.builtins["SetPoolSprite"]
+	if _, err := fn(vm, []any{int64(poolID), int64(2), int64(30), 40.5}); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if p := mockGS.pools[poolID].particles[2]; p.x != 30 || p.y != 40.5 || !p.visible {
+		t.Errorf("particle 2 = %+v, want (30, 40.5) visible", p)
+	}
+
+	// visible=0 hides the particle
+	_, _ = fn(vm, []any{int64(poolID), int64(2), int64(30), int64(40), int64(0)})
+	if mockGS.pools[poolID].particles[2].visible {
+		t.Error("particle 2 should be hidden")
+	}
+
+	// An out-of-range index only logs an error and execution continues
+	if _, err := fn(vm, []any{int64(poolID), int64(9), int64(0), int64(0)}); err != nil {
+		t.Errorf("expected no error for an invalid index, got %v", err)
+	}
+}
+
+func TestVMBuiltinScatterPool(t *testing.T) {
+	vm, mockGS, srcID, dstID := newPoolTestVM(t)
+	poolID, _ := mockGS.CreateSpritePool(srcID, dstID, 50, nil)
+
+	if _, err := vm.builtins["ScatterPool"](vm, []any{int64(poolID), int64(10), int64(20), int64(100), int64(50)}); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	for i, p := range mockGS.pools[poolID].particles {
+		if !p.visible || p.x < 10 || p.x > 110 || p.y < 20 || p.y > 70 {
+			t.Fatalf("particle %d = %+v, want visible inside (10,20)-(110,70)", i, p)
+		}
+	}
+
+	if _, err := vm.builtins["ScatterPool"](vm, []any{int64(99), int64(0), int64(0), int64(10), int64(10)}); err != nil {
+		t.Errorf("expected no error for an unknown pool, got %v", err)
+	}
+}
+
+func TestVMBuiltinSetPoolVelocity(t *testing.T) {
+	t.Run("same velocity for every particle", func(t *testing.T) {
+		vm, mockGS, srcID, dstID := newPoolTestVM(t)
+		poolID, _ := mockGS.CreateSpritePool(srcID, dstID, 5, nil)
+		if _, err := vm.builtins["SetPoolVelocity"](vm, []any{int64(poolID), 0.5, int64(2)}); err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		for i, p := range mockGS.pools[poolID].particles {
+			if p.vx != 0.5 || p.vy != 2 {
+				t.Errorf("particle %d velocity = (%v, %v), want (0.5, 2)", i, p.vx, p.vy)
+			}
+		}
+	})
+
+	t.Run("jitter", func(t *testing.T) {
+		vm, mockGS, srcID, dstID := newPoolTestVM(t)
+		poolID, _ := mockGS.CreateSpritePool(srcID, dstID, 20, nil)
+		_, _ = vm.builtins["SetPoolVelocity"](vm, []any{int64(poolID), int64(0), int64(3), int64(1)})
+		varied := false
+		for i, p := range mockGS.pools[poolID].particles {
+			if p.vx < -1 || p.vx > 1 || p.vy < 2 || p.vy > 4 {
+				t.Fatalf("particle %d velocity = (%v, %v), want within jitter", i, p.vx, p.vy)
+			}
+			if p.vy != 3 {
+				varied = true
+			}
+		}
+		if !varied {
+			t.Error("expected jitter to vary the velocities")
+		}
+	})
+}
+
+func TestVMBuiltinStepAndDelSpritePool(t *testing.T) {
+	vm, mockGS, srcID, dstID := newPoolTestVM(t)
+	poolID, _ := mockGS.CreateSpritePool(srcID, dstID, 3, nil)
+
+	for range 3 {
+		if _, err := vm.builtins["StepPool"](vm, []any{int64(poolID)}); err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+	}
+	if steps := mockGS.pools[poolID].steps; steps != 3 {
+		t.Errorf("expected 3 steps, got %d", steps)
+	}
+
+	if _, err := vm.builtins["DelSpritePool"](vm, []any{int64(poolID)}); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if _, ok := mockGS.pools[poolID]; ok {
+		t.Error("pool should be deleted")
+	}
+
+	// A deleted pool only logs an error and execution continues
+	if _, err := vm.builtins["StepPool"](vm, []any{int64(poolID)}); err != nil {
+		t.Errorf("expected no error for a deleted pool, got %v", err)
+	}
+}
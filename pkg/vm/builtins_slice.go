@@ -0,0 +1,77 @@
+package vm
+
+import (
+	"image"
+
+	"github.com/zurustar/son-et/pkg/graphics"
+)
+
+// registerSliceBuiltins registers built-in functions that draw part of a cast's image
+// and stretch frame images by nine-slice.
+// SetSourceRect picks one part of the cast's image (e.g. a button state from a sheet);
+// SetNineSlice keeps the corners and border widths of a dialog frame when it is resized,
+// instead of stretching the whole picture blurry.
+func (vm *VM) registerSliceBuiltins() {
+	// SetSourceRect: Draw only part of a cast's image
+	// SetSourceRect(cast_no, x, y, width, height) - the rectangle is relative to the cast's image
+	vm.RegisterBuiltinFunction("SetSourceRect", func(v *VM, args []any) (any, error) {
+		nums, ok := v.graphicsArgs("SetSourceRect", args, 5)
+		if !ok {
+			return nil, nil
+		}
+		x, y := int(nums[1]), int(nums[2])
+		rect := image.Rect(x, y, x+int(nums[3]), y+int(nums[4]))
+		if err := v.graphicsSystem.SetCastSourceRect(int(nums[0]), &rect); err != nil {
+			v.log.Error("SetSourceRect failed", "error", err)
+		}
+		return nil, nil
+	})
+
+	// DelSourceRect: Draw the whole image of a cast again
+	// DelSourceRect(cast_no)
+	vm.RegisterBuiltinFunction("DelSourceRect", func(v *VM, args []any) (any, error) {
+		nums, ok := v.graphicsArgs("DelSourceRect", args, 1)
+		if !ok {
+			return nil, nil
+		}
+		if err := v.graphicsSystem.SetCastSourceRect(int(nums[0]), nil); err != nil {
+			v.log.Error("DelSourceRect failed", "error", err)
+		}
+		return nil, nil
+	})
+
+	// SetNineSlice: Stretch a cast's image to width x height keeping its borders
+	// SetNineSlice(cast_no, left, top, right, bottom, width, height) - left/top/right/bottom are
+	// the border widths that are not stretched; the edges and the center are stretched.
+	vm.RegisterBuiltinFunction("SetNineSlice", func(v *VM, args []any) (any, error) {
+		nums, ok := v.graphicsArgs("SetNineSlice", args, 7)
+		if !ok {
+			return nil, nil
+		}
+		ns := &graphics.NineSlice{
+			Left:   int(nums[1]),
+			Top:    int(nums[2]),
+			Right:  int(nums[3]),
+			Bottom: int(nums[4]),
+			Width:  int(nums[5]),
+			Height: int(nums[6]),
+		}
+		if err := v.graphicsSystem.SetCastNineSlice(int(nums[0]), ns); err != nil {
+			v.log.Error("SetNineSlice failed", "error", err)
+		}
+		return nil, nil
+	})
+
+	// DelNineSlice: Draw a cast's image at its own size again
+	// DelNineSlice(cast_no)
+	vm.RegisterBuiltinFunction("DelNineSlice", func(v *VM, args []any) (any, error) {
+		nums, ok := v.graphicsArgs("DelNineSlice", args, 1)
+		if !ok {
+			return nil, nil
+		}
+		if err := v.graphicsSystem.SetCastNineSlice(int(nums[0]), nil); err != nil {
+			v.log.Error("DelNineSlice failed", "error", err)
+		}
+		return nil, nil
+	})
+}
@@ -0,0 +1,67 @@
+package vm
+
+import (
+	"image"
+	"testing"
+
+	"github.com/zurustar/son-et/pkg/graphics"
+	"github.com/zurustar/son-et/pkg/opcode"
+)
+
+func TestVMBuiltinSliceRegistered(t *testing.T) {
+	vm := New([]opcode.OpCode{})
+	for _, name := range []string{"SetSourceRect", "DelSourceRect", "SetNineSlice", "DelNineSlice"} {
+		if _, ok := vm.builtins[name]; !ok {
+			t.Errorf("expected %s to be registered as built-in function", name)
+		}
+	}
+}
+
+func TestVMBuiltinSetSourceRect(t *testing.T) {
+	vm, mockGS := newMaskTestVM()
+	vm.builtins["SetSourceRect"](vm, []any{int64(3), int64(16), int64(8), int64(32), int64(24)})
+	if got, want := mockGS.sourceRects[3], image.Rect(16, 8, 48, 32); got != want {
+		t.Errorf("source rect = %v, want %v", got, want)
+	}
+
+	vm.builtins["DelSourceRect"](vm, []any{int64(3)})
+	if _, ok := mockGS.sourceRects[3]; ok {
+		t.Error("expected DelSourceRect to remove the source rect")
+	}
+}
+
+func TestVMBuiltinSetNineSlice(t *testing.T) {
+	vm, mockGS := newMaskTestVM()
+	vm.builtins["SetNineSlice"](vm, []any{int64(4), int64(8), int64(6), int64(8), int64(10), int64(300), int64(120)})
+	want := graphics.NineSlice{Left: 8, Top: 6, Right: 8, Bottom: 10, Width: 300, Height: 120}
+	if got, ok := mockGS.nineSlices[4]; !ok || got != want {
+		t.Errorf("nine-slice = %+v, want %+v", got, want)
+	}
+
+	vm.builtins["DelNineSlice"](vm, []any{int64(4)})
+	if _, ok := mockGS.nineSlices[4]; ok {
+		t.Error("expected DelNineSlice to remove the nine-slice")
+	}
+}
+
+func TestVMBuiltinSliceInvalidArgs(t *testing.T) {
+	vm, mockGS := newMaskTestVM()
+
+	// 引数が足りない・数値でない場合はログに記録して無視する
+	for _, call := range []struct {
+		name string
+		args []any
+	}{
+		{"SetSourceRect", []any{int64(1), int64(0), int64(0), int64(10)}},
+		{"SetNineSlice", []any{int64(1), int64(4), int64(4), int64(4), int64(4), int64(100)}},
+		{"SetNineSlice", []any{int64(1), "four", int64(4), int64(4), int64(4), int64(100), int64(100)}},
+		{"DelSourceRect", []any{}},
+	} {
+		if _, err := vm.builtins[call.name](vm, call.args); err != nil {
+			t.Errorf("%s(%v) returned error: %v", call.name, call.args, err)
+		}
+	}
+	if len(mockGS.sourceRects) != 0 || len(mockGS.nineSlices) != 0 {
+		t.Errorf("invalid calls changed the casts: %v %v", mockGS.sourceRects, mockGS.nineSlices)
+	}
+}
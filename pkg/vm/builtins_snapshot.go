@@ -0,0 +1,112 @@
+package vm
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/zurustar/son-et/pkg/fileutil"
+	"github.com/zurustar/son-et/pkg/graphics"
+)
+
+// snapshotExt is the extension of images written by SaveSprite (added when omitted).
+const snapshotExt = ".png"
+
+// errNoOutputDir is returned when no output directory is set.
+var errNoOutputDir = errors.New("no output directory; set one with --output-dir")
+
+// WithOutputDir sets the directory that SaveSprite writes images to (--output-dir).
+// Scripts cannot write outside it; without it SaveSprite does nothing.
+func WithOutputDir(dir string) Option {
+	return func(vm *VM) {
+		vm.outputDir = dir
+	}
+}
+
+// registerSnapshotBuiltins registers built-in functions that export images, so that
+// titles generating pictures procedurally can save the results.
+func (vm *VM) registerSnapshotBuiltins() {
+	// SaveSprite: Save the screen or a cast as a PNG image in the output directory
+	// SaveSprite(path) - the whole screen, SaveSprite(path, cast_id) - one cast
+	// path is relative to the output directory (--output-dir); ".png" is added if omitted.
+	// The image is captured when the next frame is drawn. Returns 1 if it is scheduled, 0 otherwise.
+	vm.RegisterBuiltinFunction("SaveSprite", func(v *VM, args []any) (any, error) {
+		if v.graphicsSystem == nil {
+			v.log.Debug("SaveSprite called but graphics system not initialized", "args", args)
+			return int64(0), nil
+		}
+		if len(args) < 1 {
+			v.log.Error("SaveSprite requires at least 1 argument (path)")
+			return int64(0), nil
+		}
+		name, ok := args[0].(string)
+		if !ok {
+			v.log.Error("SaveSprite: path must be a string", "got", fmt.Sprintf("%T", args[0]))
+			return int64(0), nil
+		}
+		castID := graphics.SnapshotScreen
+		if len(args) >= 2 {
+			id, _ := toInt64(args[1])
+			if id < 0 {
+				v.log.Error("SaveSprite: invalid cast ID", "castID", id)
+				return int64(0), nil
+			}
+			castID = int(id)
+		}
+
+		path, err := v.resolveOutputPath(name)
+		if errors.Is(err, errNoOutputDir) {
+			// SaveSprite may be called in a loop, so warn only once
+			if !v.saveSpriteWarned {
+				v.saveSpriteWarned = true
+				v.log.Warn("SaveSprite called but image export is disabled", "path", name, "error", err)
+			}
+			return int64(0), nil
+		}
+		if err != nil {
+			v.log.Error("SaveSprite failed", "path", name, "error", err)
+			return int64(0), nil
+		}
+		if err := v.graphicsSystem.SaveSnapshot(castID, path); err != nil {
+			v.log.Error("SaveSprite failed", "path", name, "castID", castID, "error", err)
+			return int64(0), nil
+		}
+		v.log.Debug("SaveSprite called", "path", path, "castID", castID)
+		return int64(1), nil
+	})
+}
+
+// resolveOutputPath resolves a SaveSprite file name to a path in the output directory.
+// Absolute paths and paths leading outside the output directory are refused. In sandbox
+// mode, paths that escape through a symbolic link inside the output directory are refused too.
+func (vm *VM) resolveOutputPath(filename string) (string, error) {
+	if vm.outputDir == "" {
+		return "", errNoOutputDir
+	}
+	switch ext := filepath.Ext(filename); {
+	case ext == "":
+		filename += snapshotExt
+	case !strings.EqualFold(ext, snapshotExt):
+		return "", fmt.Errorf("output file %q must be a %s file", filename, snapshotExt)
+	}
+	if !filepath.IsLocal(filename) {
+		return "", fmt.Errorf("output file %q must be a relative path inside the output directory", filename)
+	}
+
+	base, err := filepath.Abs(vm.outputDir)
+	if err != nil {
+		return "", fmt.Errorf("cannot resolve output directory %q: %w", vm.outputDir, err)
+	}
+	path := filepath.Join(base, filename)
+	if vm.sandbox {
+		if err := os.MkdirAll(base, 0755); err != nil {
+			return "", fmt.Errorf("cannot create output directory: %w", err)
+		}
+		if err := fileutil.ConfinePath(base, path); err != nil {
+			return "", fmt.Errorf("output file %q: %w: %w", filename, ErrSandboxViolation, err)
+		}
+	}
+	return path, nil
+}
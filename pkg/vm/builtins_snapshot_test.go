@@ -0,0 +1,88 @@
+package vm
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/zurustar/son-et/pkg/graphics"
+	"github.com/zurustar/son-et/pkg/opcode"
+)
+
+func TestVMBuiltinSaveSprite(t *testing.T) {
+	dir := t.TempDir()
+	vm := New([]opcode.OpCode{}, WithOutputDir(dir))
+	gs := newMockGraphicsSystem()
+	vm.SetGraphicsSystem(gs)
+
+	for _, args := range [][]any{
+		{"screen"},
+		{"frames/cast.PNG", int64(3)},
+	} {
+		if result, _ := vm.builtins["SaveSprite"](vm, args); result != int64(1) {
+			t.Errorf("SaveSprite(%v) = %v, want 1", args, result)
+		}
+	}
+	want := []mockSnapshotCall{
+		{castID: graphics.SnapshotScreen, path: filepath.Join(dir, "screen.png")},
+		{castID: 3, path: filepath.Join(dir, "frames", "cast.PNG")},
+	}
+	if !slices.Equal(gs.snapshots, want) {
+		t.Errorf("snapshots = %v, want %v", gs.snapshots, want)
+	}
+}
+
+func TestVMBuiltinSaveSpriteRejected(t *testing.T) {
+	dir := t.TempDir()
+	vm := New([]opcode.OpCode{}, WithOutputDir(dir))
+	gs := newMockGraphicsSystem()
+	vm.SetGraphicsSystem(gs)
+
+	// Nothing is written outside the output directory or to files other than PNG
+	for _, args := range [][]any{
+		{},
+		{int64(1)},
+		{"../escape.png"},
+		{filepath.Join(dir, "absolute.png")},
+		{"picture.bmp"},
+		{"cast.png", int64(-2)},
+	} {
+		result, err := vm.builtins["SaveSprite"](vm, args)
+		if err != nil || result != int64(0) {
+			t.Errorf("SaveSprite(%v) = %v, %v; want 0 without error", args, result, err)
+		}
+	}
+	if len(gs.snapshots) != 0 {
+		t.Errorf("rejected calls should not save: %v", gs.snapshots)
+	}
+
+	// Without an output directory nothing happens
+	noDir := New([]opcode.OpCode{})
+	noDir.SetGraphicsSystem(gs)
+	if result, _ := noDir.builtins["SaveSprite"](noDir, []any{"screen.png"}); result != int64(0) || len(gs.snapshots) != 0 {
+		t.Errorf("SaveSprite without an output directory = %v, snapshots %v", result, gs.snapshots)
+	}
+}
+
+func TestVMBuiltinSaveSpriteSandbox(t *testing.T) {
+	dir := t.TempDir()
+	outside := t.TempDir()
+	if err := os.Symlink(outside, filepath.Join(dir, "link")); err != nil {
+		t.Skipf("symlinks not supported: %v", err)
+	}
+
+	// In sandbox mode, paths escaping the output directory through a symbolic link are refused
+	sandboxed := New([]opcode.OpCode{}, WithOutputDir(dir), WithSandbox(true))
+	gs := newMockGraphicsSystem()
+	sandboxed.SetGraphicsSystem(gs)
+	if result, _ := sandboxed.builtins["SaveSprite"](sandboxed, []any{"link/screen.png"}); result != int64(0) {
+		t.Errorf("SaveSprite through a symlink in sandbox mode = %v, want 0", result)
+	}
+	if result, _ := sandboxed.builtins["SaveSprite"](sandboxed, []any{"screen.png"}); result != int64(1) {
+		t.Errorf("SaveSprite in sandbox mode = %v, want 1", result)
+	}
+	if len(gs.snapshots) != 1 {
+		t.Errorf("snapshots = %v, want one", gs.snapshots)
+	}
+}
@@ -0,0 +1,142 @@
+package vm
+
+import (
+	"fmt"
+	"time"
+)
+
+// maxSpawnedSequences is the maximum number of sequences Spawn can run at once.
+const maxSpawnedSequences = 256
+
+// sendMesTargetParam is the event parameter holding the recipient (sequence number) of a SendMes message.
+// A USER event with this parameter is received only by that sequence.
+const sendMesTargetParam = "Target"
+
+// registerSpawnBuiltins registers built-in functions that start functions as sequences.
+// Spawn is mes(TIME) with arguments: the body of the function runs as its own
+// sequence, so several instances of the same code can run at once with different
+// parameters, each addressed by its sequence number.
+func (vm *VM) registerSpawnBuiltins() {
+	// Spawn("FuncName", args...) - starts FuncName(args...) as a new mes(TIME) sequence.
+	// The body may Wait, use step() and WaitAny like a mes(TIME) block; the sequence
+	// ends when the body completes (or returns).
+	// Returns the sequence number (usable with Kill, SendMes and DelMes), or 0 on error.
+	vm.RegisterBuiltinFunction("Spawn", func(v *VM, args []any) (any, error) {
+		if len(args) < 1 {
+			v.log.Warn("Spawn requires at least 1 argument (function)")
+			return int64(0), nil
+		}
+		v.pruneSpawned()
+		if len(v.spawned) >= maxSpawnedSequences {
+			v.log.Error("Spawn: too many sequences", "max", maxSpawnedSequences)
+			return int64(0), nil
+		}
+		fn, ok := v.lookupCallback("Spawn", args[0])
+		if !ok {
+			return int64(0), nil
+		}
+
+		id := v.spawn(fn, args[1:])
+		v.log.Debug("Spawn started a sequence", "sequence", id, "function", fn.Name, "args", len(args)-1)
+		return int64(id), nil
+	})
+
+	// Kill(id) - stops a sequence started by Spawn.
+	// Returns 1 if the sequence was stopped, 0 if it does not exist (or has already ended).
+	vm.RegisterBuiltinFunction("Kill", func(v *VM, args []any) (any, error) {
+		if len(args) < 1 {
+			v.log.Warn("Kill requires 1 argument (sequence id)")
+			return int64(0), nil
+		}
+		id, ok := toInt64(args[0])
+		if !ok {
+			v.log.Error("Kill: sequence ID must be integer", "got", fmt.Sprintf("%T", args[0]))
+			return int64(0), nil
+		}
+		handler, exists := v.spawned[int(id)]
+		if !exists || !v.handlerActive(handler) {
+			v.log.Debug("Kill: no such sequence", "sequence", id)
+			delete(v.spawned, int(id))
+			return int64(0), nil
+		}
+		delete(v.spawned, int(id))
+		v.handlerRegistry.Unregister(handler.ID)
+		return int64(1), nil
+	})
+
+	// SendMes(id, p1, p2, p3, p4) - sends a USER message to one sequence only.
+	// The sequence receives it while waiting in WaitAny(..., "USER") and reads the
+	// parameters from MesP1-MesP4; mes(USER) blocks and other sequences do not see it.
+	// Missing parameters are 0. Returns 1 if the message was queued, 0 if the sequence
+	// does not exist.
+	vm.RegisterBuiltinFunction("SendMes", func(v *VM, args []any) (any, error) {
+		if len(args) < 1 {
+			v.log.Warn("SendMes requires at least 1 argument (sequence id)")
+			return int64(0), nil
+		}
+		id, ok := toInt64(args[0])
+		if !ok {
+			v.log.Error("SendMes: sequence ID must be integer", "got", fmt.Sprintf("%T", args[0]))
+			return int64(0), nil
+		}
+		if _, exists := v.handlerRegistry.GetHandlerByNumber(int(id)); !exists {
+			v.log.Debug("SendMes: no such sequence", "sequence", id)
+			return int64(0), nil
+		}
+
+		params := map[string]any{sendMesTargetParam: int(id)}
+		for i := range 4 {
+			var p int64
+			if i+1 < len(args) {
+				p, _ = toInt64(args[i+1])
+			}
+			params[fmt.Sprintf("MesP%d", i+1)] = int(p)
+		}
+		v.eventQueue.Push(&Event{Type: EventUSER, Timestamp: time.Now(), Params: params})
+		return int64(1), nil
+	})
+}
+
+// spawn registers the body of fn as a mes(TIME) sequence and returns its sequence number.
+// As in a function call, args are bound to the parameters in a scope of the sequence's own.
+func (vm *VM) spawn(fn *FunctionDef, args []any) int {
+	scope := NewScope(vm.GetCurrentScope())
+	bindParameters(fn, scope, args)
+
+	handler := NewEventHandler("", EventTIME, fn.Body, vm, scope)
+	// The sequence ends once the body has run to the end, with or without step()
+	handler.HasStepBlock = true
+	handler.spawned = true
+	vm.handlerRegistry.Register(handler)
+
+	if vm.spawned == nil {
+		vm.spawned = make(map[int]*EventHandler)
+	}
+	vm.spawned[handler.Number] = handler
+	vm.StartTimer()
+	return handler.Number
+}
+
+// pruneSpawned removes finished sequences (including those removed by DelMes or del_me) from the Spawn list.
+func (vm *VM) pruneSpawned() {
+	for id, handler := range vm.spawned {
+		if !vm.handlerActive(handler) {
+			delete(vm.spawned, id)
+		}
+	}
+}
+
+// handlerActive reports whether handler is still registered.
+func (vm *VM) handlerActive(handler *EventHandler) bool {
+	h, ok := vm.handlerRegistry.GetHandler(handler.ID)
+	return ok && h == handler && !h.MarkedForDeletion
+}
+
+// addressedTo reports whether event is delivered to this handler.
+// SendMes messages reach only their recipient sequence; all other events reach every handler.
+func (eh *EventHandler) addressedTo(event *Event) bool {
+	if _, ok := event.GetParam(sendMesTargetParam); !ok {
+		return true
+	}
+	return eventParamEquals(event, sendMesTargetParam, eh.Number)
+}
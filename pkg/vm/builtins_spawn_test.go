@@ -0,0 +1,198 @@
+package vm
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/zurustar/son-et/pkg/opcode"
+)
+
+// newSpawnTestVM defines walker(a, b) with the given body and a record builtin
+// that collects its arguments as integers.
+func newSpawnTestVM(t *testing.T, body ...opcode.OpCode) (*VM, *[][]int64) {
+	t.Helper()
+	vm := New([]opcode.OpCode{})
+	records := &[][]int64{}
+	vm.RegisterBuiltinFunction("record", func(v *VM, args []any) (any, error) {
+		var values []int64
+		for _, arg := range args {
+			n, _ := toInt64(arg)
+			values = append(values, n)
+		}
+		*records = append(*records, values)
+		return nil, nil
+	})
+	fn := &FunctionDef{
+		Name:       "walker",
+		Parameters: []FunctionParam{{Name: "a"}, {Name: "b"}},
+		Body:       body,
+	}
+	vm.functions["walker"] = fn
+	vm.functionsLower["walker"] = fn
+	return vm, records
+}
+
+// recordOp calls record with the values of the given variables.
+func recordOp(names ...string) opcode.OpCode {
+	args := []any{"record"}
+	for _, name := range names {
+		args = append(args, opcode.Variable(name))
+	}
+	return opcode.OpCode{Cmd: opcode.Call, Args: args}
+}
+
+// spawnWalker calls Spawn("walker", args...) and returns the sequence number.
+func spawnWalker(t *testing.T, vm *VM, args ...any) int {
+	t.Helper()
+	result, _ := vm.builtins["Spawn"](vm, append([]any{"Walker"}, args...))
+	id, ok := result.(int64)
+	if !ok || id == 0 {
+		t.Fatalf("Spawn should return a sequence number, got %v", result)
+	}
+	return int(id)
+}
+
+func TestVMBuiltinSpawnRegistered(t *testing.T) {
+	vm := New([]opcode.OpCode{})
+	for _, name := range []string{"Spawn", "Kill", "SendMes"} {
+		if _, ok := vm.builtins[name]; !ok {
+			t.Errorf("expected %s to be registered as built-in function", name)
+		}
+	}
+}
+
+// TestSpawnRunsInstances tests that each spawned instance runs the body as a mes(TIME)
+// sequence with its own arguments, and ends after the body.
+func TestSpawnRunsInstances(t *testing.T) {
+	vm, records := newSpawnTestVM(t,
+		recordOp("a", "b"),
+		opcode.OpCode{Cmd: opcode.Wait, Args: []any{int64(1)}},
+		recordOp("a"),
+	)
+	first := spawnWalker(t, vm, int64(1), int64(2))
+	second := spawnWalker(t, vm, int64(3)) // b defaults to 0
+
+	dispatchEvents(t, vm, NewEvent(EventTIME))
+	if want := [][]int64{{1, 2}, {3, 0}}; !reflect.DeepEqual(*records, want) {
+		t.Fatalf("records after 1 tick = %v, want %v", *records, want)
+	}
+	dispatchEvents(t, vm, NewEvent(EventTIME), NewEvent(EventTIME))
+	if want := [][]int64{{1, 2}, {3, 0}, {1}, {3}}; !reflect.DeepEqual(*records, want) {
+		t.Errorf("records = %v, want %v", *records, want)
+	}
+	for _, id := range []int{first, second} {
+		if _, exists := vm.handlerRegistry.GetHandlerByNumber(id); exists {
+			t.Errorf("sequence %d should end after its body", id)
+		}
+	}
+}
+
+// TestSpawnReturn tests that return in the body ends the sequence.
+func TestSpawnReturn(t *testing.T) {
+	vm, records := newSpawnTestVM(t,
+		recordOp("a"),
+		opcode.OpCode{Cmd: opcode.Call, Args: []any{"return"}},
+		recordOp("b"),
+	)
+	id := spawnWalker(t, vm, int64(1), int64(2))
+
+	dispatchEvents(t, vm, NewEvent(EventTIME), NewEvent(EventTIME))
+	if want := [][]int64{{1}}; !reflect.DeepEqual(*records, want) {
+		t.Errorf("records = %v, want %v", *records, want)
+	}
+	if _, exists := vm.handlerRegistry.GetHandlerByNumber(id); exists {
+		t.Error("return should end the sequence")
+	}
+}
+
+// TestKill tests that Kill stops a spawned sequence only once.
+func TestKill(t *testing.T) {
+	vm, records := newSpawnTestVM(t,
+		opcode.OpCode{Cmd: opcode.Wait, Args: []any{int64(1)}},
+		recordOp("a"),
+	)
+	id := spawnWalker(t, vm, int64(1))
+
+	if result, _ := vm.builtins["Kill"](vm, []any{int64(id)}); result != int64(1) {
+		t.Errorf("Kill = %v, want 1", result)
+	}
+	dispatchEvents(t, vm, NewEvent(EventTIME), NewEvent(EventTIME))
+	if len(*records) != 0 {
+		t.Errorf("killed sequence ran: %v", *records)
+	}
+	if result, _ := vm.builtins["Kill"](vm, []any{int64(id)}); result != int64(0) {
+		t.Errorf("second Kill = %v, want 0", result)
+	}
+
+	// Kill stops only sequences started by Spawn
+	handler := NewEventHandler("", EventTIME, nil, vm, nil)
+	vm.handlerRegistry.Register(handler)
+	if result, _ := vm.builtins["Kill"](vm, []any{int64(handler.Number)}); result != int64(0) {
+		t.Errorf("Kill of a mes() block = %v, want 0", result)
+	}
+}
+
+// TestSendMes tests that a message reaches only the addressed sequence waiting in WaitAny.
+func TestSendMes(t *testing.T) {
+	vm, records := newSpawnTestVM(t,
+		opcode.OpCode{Cmd: opcode.WaitAny, Args: []any{int64(0), "USER"}},
+		recordOp("a", "MesP1", "MesP2"),
+	)
+	spawnWalker(t, vm, int64(1))
+	second := spawnWalker(t, vm, int64(2))
+	userCalls := 0
+	vm.RegisterBuiltinFunction("onUser", func(v *VM, args []any) (any, error) {
+		userCalls++
+		return nil, nil
+	})
+	vm.handlerRegistry.Register(NewEventHandler("", EventUSER, []opcode.OpCode{{Cmd: opcode.Call, Args: []any{"onUser"}}}, vm, nil))
+
+	dispatchEvents(t, vm, NewEvent(EventTIME)) // both sequences start waiting
+	if result, _ := vm.builtins["SendMes"](vm, []any{int64(second), int64(7), int64(8)}); result != int64(1) {
+		t.Fatalf("SendMes = %v, want 1", result)
+	}
+	vm.ProcessEvents()
+
+	if want := [][]int64{{2, 7, 8}}; !reflect.DeepEqual(*records, want) {
+		t.Errorf("records = %v, want %v", *records, want)
+	}
+	if userCalls != 0 {
+		t.Errorf("mes(USER) received the message addressed to a sequence: %d calls", userCalls)
+	}
+
+	// PostMes messages reach every sequence
+	vm.builtins["PostMes"](vm, []any{int64(100), int64(9), int64(0), int64(0), int64(0)})
+	vm.ProcessEvents()
+	if want := [][]int64{{2, 7, 8}, {1, 9, 0}}; !reflect.DeepEqual(*records, want) {
+		t.Errorf("records after PostMes = %v, want %v", *records, want)
+	}
+	if userCalls != 1 {
+		t.Errorf("mes(USER) calls = %d, want 1", userCalls)
+	}
+}
+
+// TestSpawnErrors tests the results for invalid arguments and missing sequences.
+func TestSpawnErrors(t *testing.T) {
+	vm, _ := newSpawnTestVM(t)
+
+	tests := []struct {
+		name string
+		args []any
+	}{
+		{"Spawn", nil},
+		{"Spawn", []any{"missing"}},
+		{"Kill", nil},
+		{"Kill", []any{"x"}},
+		{"Kill", []any{int64(99)}},
+		{"SendMes", nil},
+		{"SendMes", []any{int64(99), int64(1)}},
+	}
+	for _, tt := range tests {
+		if result, _ := vm.builtins[tt.name](vm, tt.args); result != int64(0) {
+			t.Errorf("%s(%v) = %v, want 0", tt.name, tt.args, result)
+		}
+	}
+	if vm.eventQueue.Len() != 0 {
+		t.Errorf("SendMes to a missing sequence queued %d events", vm.eventQueue.Len())
+	}
+}
@@ -0,0 +1,72 @@
+package vm
+
+import (
+	"fmt"
+	"slices"
+)
+
+// registerSpriteTickBuiltins registers OnSpriteTick, which calls a script function for
+// a cast every N ticks. One handler per animated cast replaces a polling mes(TIME)
+// sequence with its own step counter, and is removed together with the cast.
+func (vm *VM) registerSpriteTickBuiltins() {
+	// OnSpriteTick(castID, "FuncName"[, n]) - calls FuncName(castID) every n TIME ticks (default 1).
+	// The callback is an ordinary event handler (usable with DelMes and FreezeMes) and is
+	// removed when the cast is deleted with DelCast.
+	// Returns the handler number, or nil on error.
+	vm.RegisterBuiltinFunction("OnSpriteTick", func(v *VM, args []any) (any, error) {
+		if len(args) < 2 {
+			v.log.Warn("OnSpriteTick requires at least 2 arguments (castID, function)")
+			return nil, nil
+		}
+		castID, ok := toInt64(args[0])
+		if !ok {
+			v.log.Error("OnSpriteTick castID must be integer", "got", fmt.Sprintf("%T", args[0]))
+			return nil, nil
+		}
+		every := int64(1)
+		if len(args) >= 3 {
+			if every, ok = toInt64(args[2]); !ok || every < 1 {
+				v.log.Error("OnSpriteTick: n must be a positive integer", "got", args[2])
+				return nil, nil
+			}
+		}
+
+		// The filter sees every TIME event, so it counts the ticks of this cast
+		ticks := int64(0)
+		filter := func(event *Event) bool {
+			ticks++
+			return ticks%every == 0
+		}
+		number, err := v.registerInputHandler("OnSpriteTick", EventTIME, args[1], filter, castID)
+		if number == nil || err != nil {
+			return number, err
+		}
+		handler, _ := v.handlerRegistry.GetHandlerByNumber(int(number.(int64)))
+		if v.spriteTicks == nil {
+			v.spriteTicks = make(map[int][]*EventHandler)
+		}
+		v.spriteTicks[int(castID)] = append(v.activeSpriteTicks(int(castID)), handler)
+
+		// TIME events are generated only while a TIME handler exists
+		v.StartTimer()
+		return number, nil
+	})
+}
+
+// activeSpriteTicks returns the OnSpriteTick handlers of a cast that have not been
+// removed by DelMes or del_me.
+func (vm *VM) activeSpriteTicks(castID int) []*EventHandler {
+	return slices.DeleteFunc(vm.spriteTicks[castID], func(h *EventHandler) bool {
+		current, ok := vm.handlerRegistry.GetHandler(h.ID)
+		return !ok || current != h || h.MarkedForDeletion
+	})
+}
+
+// stopSpriteTicks removes the OnSpriteTick handlers of a deleted cast.
+func (vm *VM) stopSpriteTicks(castID int) {
+	for _, h := range vm.activeSpriteTicks(castID) {
+		vm.handlerRegistry.Unregister(h.ID)
+		vm.log.Debug("OnSpriteTick removed with its cast", "handler", h.ID, "castID", castID)
+	}
+	delete(vm.spriteTicks, castID)
+}
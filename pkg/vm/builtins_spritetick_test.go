@@ -0,0 +1,101 @@
+package vm
+
+import (
+	"testing"
+)
+
+// dispatchTicks dispatches n TIME events.
+func dispatchTicks(t *testing.T, vm *VM, n int) {
+	t.Helper()
+	for range n {
+		if err := vm.eventDispatcher.Dispatch(NewEvent(EventTIME)); err != nil {
+			t.Fatalf("Dispatch failed: %v", err)
+		}
+	}
+}
+
+func TestOnSpriteTick(t *testing.T) {
+	t.Run("calls function every n ticks with the cast", func(t *testing.T) {
+		vm, calls := newInputTestVM(t, "cast")
+
+		result, _ := vm.builtins["OnSpriteTick"](vm, []any{int64(5), "onInput", int64(3)})
+		if result != int64(1) {
+			t.Fatalf("OnSpriteTick should return handler number 1, got %v", result)
+		}
+
+		dispatchTicks(t, vm, 7)
+		if len(*calls) != 2 {
+			t.Fatalf("expected 2 calls in 7 ticks, got %d", len(*calls))
+		}
+		if cast, _ := toInt64((*calls)[0][0]); cast != 5 {
+			t.Errorf("cast argument = %v, want 5", (*calls)[0][0])
+		}
+	})
+
+	t.Run("calls function every tick by default", func(t *testing.T) {
+		vm, calls := newInputTestVM(t, "cast")
+		vm.builtins["OnSpriteTick"](vm, []any{int64(5), "onInput"})
+		dispatchTicks(t, vm, 4)
+		if len(*calls) != 4 {
+			t.Errorf("expected 4 calls, got %d", len(*calls))
+		}
+	})
+
+	t.Run("stops when the cast is deleted", func(t *testing.T) {
+		vm, calls := newInputTestVM(t, "cast")
+		vm.SetGraphicsSystem(newMockGraphicsSystem())
+		vm.builtins["OnSpriteTick"](vm, []any{int64(5), "onInput"})
+		vm.builtins["OnSpriteTick"](vm, []any{int64(6), "onInput", int64(2)})
+
+		dispatchTicks(t, vm, 2)
+		vm.builtins["DelCast"](vm, []any{int64(5)})
+		dispatchTicks(t, vm, 2)
+
+		// キャスト5は削除前の2回、キャスト6は2ティックごとに2回
+		var five, six int
+		for _, call := range *calls {
+			switch cast, _ := toInt64(call[0]); cast {
+			case 5:
+				five++
+			case 6:
+				six++
+			}
+		}
+		if five != 2 || six != 2 {
+			t.Errorf("calls for cast 5 = %d, cast 6 = %d; want 2 and 2", five, six)
+		}
+		if _, ok := vm.spriteTicks[5]; ok {
+			t.Error("handlers of the deleted cast should be forgotten")
+		}
+	})
+
+	t.Run("DelMes stops the callback", func(t *testing.T) {
+		vm, calls := newInputTestVM(t, "cast")
+		result, _ := vm.builtins["OnSpriteTick"](vm, []any{int64(5), "onInput"})
+		vm.builtins["DelMes"](vm, []any{result})
+		dispatchTicks(t, vm, 2)
+		if len(*calls) != 0 {
+			t.Errorf("expected no calls after DelMes, got %d", len(*calls))
+		}
+		if got := vm.activeSpriteTicks(5); len(got) != 0 {
+			t.Errorf("activeSpriteTicks = %d handlers, want 0", len(got))
+		}
+	})
+
+	t.Run("rejects invalid arguments", func(t *testing.T) {
+		vm, _ := newInputTestVM(t, "cast")
+		for _, args := range [][]any{
+			{int64(5)},
+			{"star", "onInput"},
+			{int64(5), "onInput", int64(0)},
+			{int64(5), "missing"},
+		} {
+			if result, _ := vm.builtins["OnSpriteTick"](vm, args); result != nil {
+				t.Errorf("OnSpriteTick(%v) = %v, want nil", args, result)
+			}
+		}
+		if len(vm.spriteTicks[5]) != 0 {
+			t.Errorf("invalid calls registered %d handlers", len(vm.spriteTicks[5]))
+		}
+	})
+}
@@ -0,0 +1,108 @@
+package vm
+
+import (
+	"os"
+	"runtime"
+	"strings"
+)
+
+// DefaultLang is the language GetLang returns when the environment does not tell
+// (Japanese, the language of FILLY).
+const DefaultLang = "ja"
+
+// Size of the virtual desktop without a graphics system (skelton requirement: 1024x768)
+const (
+	defaultScreenWidth  = 1024
+	defaultScreenHeight = 768
+)
+
+// langEnvVars are the environment variables the language is taken from, in order of
+// precedence (following the POSIX locale rules).
+var langEnvVars = []string{"LC_ALL", "LC_MESSAGES", "LANG"}
+
+// WithLang sets the language returned by GetLang (an ISO 639-1 code such as "ja" or "en").
+// By default it is the language of WithLocale, or taken from the LC_ALL, LC_MESSAGES and
+// LANG environment variables.
+func WithLang(lang string) Option {
+	return func(vm *VM) {
+		vm.lang = lang
+	}
+}
+
+// detectLang returns the language code of the locale in the environment (such as "ja_JP.UTF-8").
+// Unset, "C" and "POSIX" values fall through to the next variable; when none tells, it returns DefaultLang.
+func detectLang(getenv func(string) string) string {
+	for _, name := range langEnvVars {
+		locale := getenv(name)
+		if locale == "" || locale == "C" || locale == "POSIX" || strings.HasPrefix(locale, "C.") {
+			continue
+		}
+		lang, _, _ := strings.Cut(locale, "_")
+		lang, _, _ = strings.Cut(lang, ".")
+		lang, _, _ = strings.Cut(lang, "@")
+		if lang != "" {
+			return strings.ToLower(lang)
+		}
+	}
+	return DefaultLang
+}
+
+// registerSysInfoBuiltins registers built-in functions that query the environment
+// the title runs in, so scripts can adapt their layout or skip audio-dependent
+// branches when run headless, without a SoundFont, or on another platform.
+func (vm *VM) registerSysInfoBuiltins() {
+	// GetScreenWidth: Get the width of the virtual desktop (same as WinInfo(0))
+	// GetScreenWidth()
+	vm.RegisterBuiltinFunction("GetScreenWidth", func(v *VM, args []any) (any, error) {
+		w, _ := v.screenSize()
+		return int64(w), nil
+	})
+
+	// GetScreenHeight: Get the height of the virtual desktop (same as WinInfo(1))
+	// GetScreenHeight()
+	vm.RegisterBuiltinFunction("GetScreenHeight", func(v *VM, args []any) (any, error) {
+		_, h := v.screenSize()
+		return int64(h), nil
+	})
+
+	// GetPlatform: Get the operating system ("windows", "darwin", "linux", "js", ...)
+	// GetPlatform()
+	vm.RegisterBuiltinFunction("GetPlatform", func(v *VM, args []any) (any, error) {
+		return runtime.GOOS, nil
+	})
+
+	// IsHeadless: 1 when running without a GUI (--headless), 0 otherwise
+	// IsHeadless()
+	vm.RegisterBuiltinFunction("IsHeadless", func(v *VM, args []any) (any, error) {
+		return boolToInt(v.headless), nil
+	})
+
+	// GetLang: Get the user's language as an ISO 639-1 code ("ja", "en", ...)
+	// GetLang()
+	vm.RegisterBuiltinFunction("GetLang", func(v *VM, args []any) (any, error) {
+		if v.lang == "" && v.locale != "" {
+			// The language of the locale given with --locale
+			base, _ := v.localeTag().Base()
+			v.lang = base.String()
+		}
+		if v.lang == "" {
+			v.lang = detectLang(os.Getenv)
+		}
+		return v.lang, nil
+	})
+
+	// HasSoundFont: 1 when a SoundFont is loaded and MIDI files can be played, 0 otherwise
+	// HasSoundFont()
+	vm.RegisterBuiltinFunction("HasSoundFont", func(v *VM, args []any) (any, error) {
+		return boolToInt(v.soundFontPath != "" && v.audioSystem != nil), nil
+	})
+}
+
+// screenSize returns the size of the virtual desktop.
+// Without a graphics system it returns the default 1024x768, as WinInfo does.
+func (vm *VM) screenSize() (int, int) {
+	if vm.graphicsSystem == nil {
+		return defaultScreenWidth, defaultScreenHeight
+	}
+	return vm.graphicsSystem.GetVirtualWidth(), vm.graphicsSystem.GetVirtualHeight()
+}
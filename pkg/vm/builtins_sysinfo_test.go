@@ -0,0 +1,98 @@
+package vm
+
+import (
+	"runtime"
+	"testing"
+
+	"github.com/zurustar/son-et/pkg/opcode"
+)
+
+func TestVMBuiltinSysInfoRegistered(t *testing.T) {
+	vm := New([]opcode.OpCode{})
+	for _, name := range []string{"GetScreenWidth", "GetScreenHeight", "GetPlatform", "IsHeadless", "GetLang", "HasSoundFont"} {
+		if _, ok := vm.builtins[name]; !ok {
+			t.Errorf("expected %s to be registered as built-in function", name)
+		}
+	}
+}
+
+func TestVMBuiltinGetScreenSize(t *testing.T) {
+	// Without a graphics system the size is the default, as in WinInfo
+	vm := New([]opcode.OpCode{})
+	w, _ := vm.builtins["GetScreenWidth"](vm, nil)
+	h, _ := vm.builtins["GetScreenHeight"](vm, nil)
+	if w != int64(defaultScreenWidth) || h != int64(defaultScreenHeight) {
+		t.Errorf("screen size without graphics = %vx%v, want %dx%d", w, h, defaultScreenWidth, defaultScreenHeight)
+	}
+
+	vm, mockGS := newMaskTestVM()
+	w, _ = vm.builtins["GetScreenWidth"](vm, nil)
+	h, _ = vm.builtins["GetScreenHeight"](vm, nil)
+	if w != int64(mockGS.GetVirtualWidth()) || h != int64(mockGS.GetVirtualHeight()) {
+		t.Errorf("screen size = %vx%v, want the virtual desktop size", w, h)
+	}
+}
+
+func TestVMBuiltinGetPlatform(t *testing.T) {
+	vm := New([]opcode.OpCode{})
+	if got, _ := vm.builtins["GetPlatform"](vm, nil); got != runtime.GOOS {
+		t.Errorf("GetPlatform = %v, want %q", got, runtime.GOOS)
+	}
+}
+
+func TestVMBuiltinIsHeadless(t *testing.T) {
+	for _, headless := range []bool{false, true} {
+		vm := New([]opcode.OpCode{}, WithHeadless(headless))
+		if got, _ := vm.builtins["IsHeadless"](vm, nil); got != boolToInt(headless) {
+			t.Errorf("IsHeadless with headless=%v = %v", headless, got)
+		}
+	}
+}
+
+func TestVMBuiltinGetLang(t *testing.T) {
+	vm := New([]opcode.OpCode{}, WithLang("en"))
+	if got, _ := vm.builtins["GetLang"](vm, nil); got != "en" {
+		t.Errorf("GetLang = %v, want \"en\"", got)
+	}
+}
+
+func TestDetectLang(t *testing.T) {
+	for _, tt := range []struct {
+		name string
+		env  map[string]string
+		want string
+	}{
+		{"unset", nil, DefaultLang},
+		{"LANG", map[string]string{"LANG": "en_US.UTF-8"}, "en"},
+		{"LC_ALL wins", map[string]string{"LC_ALL": "fr_FR", "LANG": "en_US.UTF-8"}, "fr"},
+		{"LC_MESSAGES before LANG", map[string]string{"LC_MESSAGES": "de_DE", "LANG": "en_US"}, "de"},
+		{"C locale skipped", map[string]string{"LC_ALL": "C", "LANG": "ja_JP.eucJP"}, "ja"},
+		{"C.UTF-8 only", map[string]string{"LANG": "C.UTF-8"}, DefaultLang},
+		{"no territory", map[string]string{"LANG": "ko"}, "ko"},
+		{"modifier", map[string]string{"LANG": "sr@latin"}, "sr"},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := detectLang(func(name string) string { return tt.env[name] }); got != tt.want {
+				t.Errorf("detectLang = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestVMBuiltinHasSoundFont(t *testing.T) {
+	vm := New([]opcode.OpCode{}, WithSoundFont("GeneralUser.sf2"))
+	if got, _ := vm.builtins["HasSoundFont"](vm, nil); got != int64(0) {
+		t.Errorf("HasSoundFont without an audio system = %v, want 0", got)
+	}
+
+	vm.SetAudioSystem(newMockAudioSystem())
+	if got, _ := vm.builtins["HasSoundFont"](vm, nil); got != int64(1) {
+		t.Errorf("HasSoundFont = %v, want 1", got)
+	}
+
+	vm = New([]opcode.OpCode{})
+	vm.SetAudioSystem(newMockAudioSystem())
+	if got, _ := vm.builtins["HasSoundFont"](vm, nil); got != int64(0) {
+		t.Errorf("HasSoundFont without a SoundFont = %v, want 0", got)
+	}
+}
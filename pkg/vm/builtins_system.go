@@ -242,6 +242,59 @@ func (vm *VM) registerSystemBuiltins() {
 		return nil, nil
 	})
 
+	// SetTickPolicy(mes_no, policy) - selects how a mes() block handles TIME/MIDI_TIME ticks
+	// that arrive while it is behind (newer ticks already queued):
+	// 0 = process all (default), 1 = coalesce to the latest tick, 2 = drop and count.
+	// Ticks skipped by policies 1 and 2 are counted by GetDroppedTicks.
+	vm.RegisterBuiltinFunction("SetTickPolicy", func(v *VM, args []any) (any, error) {
+		if len(args) < 2 {
+			v.log.Warn("SetTickPolicy requires 2 arguments (seqID, policy)")
+			return nil, nil
+		}
+
+		seqID, ok1 := toInt64(args[0])
+		policy, ok2 := toInt64(args[1])
+		if !ok1 || !ok2 {
+			v.log.Error("SetTickPolicy arguments must be integers", "seqID", args[0], "policy", args[1])
+			return nil, nil
+		}
+		if policy < int64(TickProcessAll) || policy > int64(TickDrop) {
+			v.log.Error("SetTickPolicy: unknown policy", "policy", policy)
+			return nil, nil
+		}
+
+		handler, exists := v.handlerRegistry.GetHandlerByNumber(int(seqID))
+		if !exists {
+			v.log.Warn("SetTickPolicy: handler not found", "seqID", seqID)
+			return nil, nil
+		}
+		handler.TickPolicy = TickPolicy(policy)
+		v.log.Debug("SetTickPolicy called", "seqID", seqID, "policy", policy)
+		return nil, nil
+	})
+
+	// GetDroppedTicks(mes_no) - returns how many ticks a mes() block skipped because of
+	// its tick policy (0 if the block does not exist)
+	vm.RegisterBuiltinFunction("GetDroppedTicks", func(v *VM, args []any) (any, error) {
+		if len(args) < 1 {
+			v.log.Warn("GetDroppedTicks requires 1 argument (seqID)")
+			return int64(0), nil
+		}
+
+		seqID, ok := toInt64(args[0])
+		if !ok {
+			v.log.Error("GetDroppedTicks seqID must be integer", "got", fmt.Sprintf("%T", args[0]))
+			return int64(0), nil
+		}
+
+		handler, exists := v.handlerRegistry.GetHandlerByNumber(int(seqID))
+		if !exists {
+			v.log.Warn("GetDroppedTicks: handler not found", "seqID", seqID)
+			return int64(0), nil
+		}
+		return int64(handler.DroppedTicks), nil
+	})
+
 	// GetIniStr(section, key, default, filename) - reads a string value from an INI file
 	// INI file format:
 	//   [Section]
@@ -553,6 +606,64 @@ func (vm *VM) registerSystemBuiltins() {
 		return nil, nil
 	})
 
+	// SaveValue(key, value) - stores a value persistently for this title
+	// Values survive between runs (e.g. high scores, visited flags).
+	// The store is a per-title JSON file under the user's config directory;
+	// scripts only choose the key, never the file location.
+	// Supported value types: integer, float, string.
+	vm.RegisterBuiltinFunction("SaveValue", func(v *VM, args []any) (any, error) {
+		if len(args) < 2 {
+			v.log.Warn("SaveValue requires 2 arguments (key, value)")
+			return nil, nil
+		}
+
+		key := toString(args[0])
+		store, err := v.getValueStore()
+		if err != nil {
+			v.log.Error("SaveValue: value store unavailable", "error", err)
+			return nil, nil
+		}
+		if err := store.Set(key, args[1]); err != nil {
+			v.log.Error("SaveValue failed", "key", key, "error", err)
+			return nil, nil
+		}
+
+		v.log.Debug("SaveValue called", "key", key, "value", args[1], "path", store.Path())
+		return nil, nil
+	})
+
+	// LoadValue(key) / LoadValue(key, default) - reads a value stored by SaveValue
+	// Returns the default (or 0 if omitted) when the key has never been saved.
+	vm.RegisterBuiltinFunction("LoadValue", func(v *VM, args []any) (any, error) {
+		var defaultVal any = int64(0)
+		if len(args) >= 2 {
+			defaultVal = args[1]
+		}
+		if len(args) < 1 {
+			v.log.Warn("LoadValue requires at least 1 argument (key)")
+			return defaultVal, nil
+		}
+
+		key := toString(args[0])
+		store, err := v.getValueStore()
+		if err != nil {
+			v.log.Error("LoadValue: value store unavailable", "error", err)
+			return defaultVal, nil
+		}
+		value, ok, err := store.Get(key)
+		if err != nil {
+			v.log.Error("LoadValue failed, returning default", "key", key, "error", err)
+			return defaultVal, nil
+		}
+		if !ok {
+			v.log.Debug("LoadValue: key not found, returning default", "key", key, "default", defaultVal)
+			return defaultVal, nil
+		}
+
+		v.log.Debug("LoadValue called", "key", key, "value", value)
+		return value, nil
+	})
+
 	// Debug: Set debug level (placeholder - does nothing for now)
 	vm.RegisterBuiltinFunction("Debug", func(v *VM, args []any) (any, error) {
 		if len(args) >= 1 {
@@ -562,23 +673,47 @@ func (vm *VM) registerSystemBuiltins() {
 		return nil, nil
 	})
 
+	// DebugBreak("label") - pause the sequence while a debugger is attached (see debugger.go)
+	// The compiler turns DebugBreak calls into OpDebugBreak to record the source line;
+	// this builtin handles calls without line information.
+	vm.RegisterBuiltinFunction("DebugBreak", func(v *VM, args []any) (any, error) {
+		label := ""
+		if len(args) >= 1 {
+			label = toString(args[0])
+		}
+		v.debugBreak(label, 0)
+		return nil, nil
+	})
+
 	// Shell(command) - executes a shell command
 	// This is a legacy Windows-specific function that is not supported on cross-platform systems.
+	// It is always refused in sandbox mode, even if a passthrough is added later.
 	vm.RegisterBuiltinFunction("Shell", func(v *VM, args []any) (any, error) {
+		if v.refuseInSandbox("Shell") {
+			return int64(0), nil
+		}
 		v.log.Info("Shell() is a legacy Windows-specific function and is not supported")
 		return int64(0), nil
 	})
 
 	// MCI(command) - Windows Media Control Interface commands
 	// This is a legacy Windows-specific function that is not supported on cross-platform systems.
+	// It is always refused in sandbox mode, even if a passthrough is added later.
 	vm.RegisterBuiltinFunction("MCI", func(v *VM, args []any) (any, error) {
+		if v.refuseInSandbox("MCI") {
+			return int64(0), nil
+		}
 		v.log.Info("MCI() is a legacy Windows-specific function and is not supported")
 		return int64(0), nil
 	})
 
 	// StrMCI(command) - String variant of MCI commands
 	// This is a legacy Windows-specific function that is not supported on cross-platform systems.
+	// It is always refused in sandbox mode, even if a passthrough is added later.
 	vm.RegisterBuiltinFunction("StrMCI", func(v *VM, args []any) (any, error) {
+		if v.refuseInSandbox("StrMCI") {
+			return "", nil
+		}
 		v.log.Info("StrMCI() is a legacy Windows-specific function and is not supported")
 		return "", nil
 	})
@@ -727,8 +862,8 @@ func (vm *VM) registerSystemBuiltins() {
 	// GetSysTime() - returns current Unix timestamp in seconds since Unix epoch
 	// This is commonly used for timing and performance measurement.
 	vm.RegisterBuiltinFunction("GetSysTime", func(v *VM, args []any) (any, error) {
-		// Return current time in seconds (not milliseconds)
-		seconds := time.Now().Unix()
+		// Return current time in seconds (not milliseconds), read from the VM's clock
+		seconds := v.clock.Now().Unix()
 		v.log.Debug("GetSysTime called", "seconds", seconds)
 		return seconds, nil
 	})
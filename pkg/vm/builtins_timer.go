@@ -0,0 +1,153 @@
+package vm
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"time"
+
+	"github.com/zurustar/son-et/pkg/opcode"
+)
+
+// minTimerInterval is the shortest interval SetTimer accepts; shorter intervals are rounded up.
+const minTimerInterval = 10 * time.Millisecond
+
+// maxScriptTimers is the maximum number of timers registered at the same time.
+const maxScriptTimers = 256
+
+// scriptTimer is a timer registered by SetTimer.
+// Its number is the number of its event handler, so DelMes can delete it too.
+type scriptTimer struct {
+	handler  *EventHandler
+	interval time.Duration
+	next     time.Time // When the next TIMER event is sent
+	repeat   bool
+}
+
+// registerTimerBuiltins registers built-in functions for event-driven timers.
+// Timers fire from the event loop independently of TIME/MIDI_TIME ticks, so scripts
+// no longer need busy-wait loops for periodic updates.
+func (vm *VM) registerTimerBuiltins() {
+	// SetTimer(ms, "FuncName"[, repeat]) - calls FuncName(timer_id) every ms milliseconds.
+	// repeat = 0 calls it only once (default 1). The call runs as its own event handler,
+	// so it is delivered even while the sequence that set the timer is waiting or busy.
+	// Returns the timer ID (usable with KillTimer and DelMes), or 0 on error.
+	vm.RegisterBuiltinFunction("SetTimer", func(v *VM, args []any) (any, error) {
+		if len(args) < 2 {
+			v.log.Warn("SetTimer requires at least 2 arguments (ms, function)")
+			return int64(0), nil
+		}
+		ms, ok := toInt64(args[0])
+		if !ok || ms < 0 {
+			v.log.Error("SetTimer: interval must be a non-negative integer", "got", args[0])
+			return int64(0), nil
+		}
+		repeat := true
+		if len(args) >= 3 {
+			if r, ok := toInt64(args[2]); ok {
+				repeat = r != 0
+			}
+		}
+		if len(v.timers) >= maxScriptTimers {
+			v.log.Error("SetTimer: too many timers", "max", maxScriptTimers)
+			return int64(0), nil
+		}
+		fn, ok := v.lookupCallback("SetTimer", args[1])
+		if !ok {
+			return int64(0), nil
+		}
+
+		interval := max(time.Duration(ms)*time.Millisecond, minTimerInterval)
+		id := v.startTimer(fn, interval, repeat, v.clock.Now())
+		v.log.Debug("SetTimer registered", "timer", id, "function", fn.Name, "interval", interval, "repeat", repeat)
+		return int64(id), nil
+	})
+
+	// KillTimer(timer_id) - stops a timer set by SetTimer.
+	// Returns 1 if the timer was stopped, 0 if it does not exist (or has already fired once).
+	vm.RegisterBuiltinFunction("KillTimer", func(v *VM, args []any) (any, error) {
+		if len(args) < 1 {
+			v.log.Warn("KillTimer requires 1 argument (timer_id)")
+			return int64(0), nil
+		}
+		id, ok := toInt64(args[0])
+		if !ok {
+			v.log.Error("KillTimer: timer ID must be integer", "got", fmt.Sprintf("%T", args[0]))
+			return int64(0), nil
+		}
+		t, exists := v.timers[int(id)]
+		if !exists {
+			v.log.Debug("KillTimer: no such timer", "timer", id)
+			return int64(0), nil
+		}
+		delete(v.timers, int(id))
+		if !v.timerActive(t) {
+			return int64(0), nil
+		}
+		v.handlerRegistry.Unregister(t.handler.ID)
+		return int64(1), nil
+	})
+}
+
+// startTimer registers a TIMER event handler that calls fn and returns the timer number.
+// The handler of a one-shot timer deletes itself after calling fn.
+func (vm *VM) startTimer(fn *FunctionDef, interval time.Duration, repeat bool, now time.Time) int {
+	return vm.registerTimer([]any{fn.Name, opcode.Variable("MesP1")}, interval, repeat, now)
+}
+
+// registerTimer registers a timer handler whose body calls callArgs (a function name and its
+// arguments) and returns the timer number.
+func (vm *VM) registerTimer(callArgs []any, interval time.Duration, repeat bool, now time.Time) int {
+	body := []opcode.OpCode{{Cmd: opcode.Call, Args: callArgs}}
+	if !repeat {
+		body = append(body, opcode.OpCode{Cmd: opcode.Call, Args: []any{"del_me"}})
+	}
+	handler := NewEventHandler("", EventTIMER, body, vm, vm.GetCurrentScope())
+	handler.Filter = func(event *Event) bool {
+		return eventParamEquals(event, "MesP1", handler.Number)
+	}
+	vm.handlerRegistry.Register(handler)
+
+	if vm.timers == nil {
+		vm.timers = make(map[int]*scriptTimer)
+	}
+	vm.timers[handler.Number] = &scriptTimer{
+		handler:  handler,
+		interval: interval,
+		next:     now.Add(interval),
+		repeat:   repeat,
+	}
+	return handler.Number
+}
+
+// fireTimers queues a TIMER event for each timer that is due by now.
+// A timer that fell several periods behind the event loop fires only once.
+func (vm *VM) fireTimers(now time.Time) {
+	for _, id := range slices.Sorted(maps.Keys(vm.timers)) {
+		t := vm.timers[id]
+		// Stop timers whose handler was deleted by DelMes or del_me
+		if !vm.timerActive(t) {
+			delete(vm.timers, id)
+			continue
+		}
+		if now.Before(t.next) {
+			continue
+		}
+		vm.eventQueue.Push(NewEventWithParams(EventTIMER, map[string]any{
+			"MesP1": id,
+		}))
+		if !t.repeat {
+			delete(vm.timers, id)
+			continue
+		}
+		t.next = t.next.Add(t.interval)
+		if !t.next.After(now) {
+			t.next = now.Add(t.interval)
+		}
+	}
+}
+
+// timerActive reports whether the timer's handler is still registered.
+func (vm *VM) timerActive(t *scriptTimer) bool {
+	return vm.handlerActive(t.handler)
+}
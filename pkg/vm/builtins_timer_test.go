@@ -0,0 +1,188 @@
+package vm
+
+import (
+	"testing"
+	"time"
+
+	"github.com/zurustar/son-et/pkg/opcode"
+)
+
+// TestSetTimerRepeat tests that a repeating timer calls the function with its ID at each interval.
+func TestSetTimerRepeat(t *testing.T) {
+	vm, calls := newInputTestVM(t, "id")
+
+	result, _ := vm.builtins["SetTimer"](vm, []any{int64(100), "onInput"})
+	id, ok := result.(int64)
+	if !ok || id == 0 {
+		t.Fatalf("SetTimer should return a timer ID, got %v", result)
+	}
+	start := vm.timers[int(id)].next.Add(-100 * time.Millisecond)
+
+	vm.fireTimers(start.Add(50 * time.Millisecond))
+	vm.ProcessEvents()
+	if len(*calls) != 0 {
+		t.Fatalf("timer fired before its interval: %d calls", len(*calls))
+	}
+
+	vm.fireTimers(start.Add(100 * time.Millisecond))
+	vm.ProcessEvents()
+	vm.fireTimers(start.Add(200 * time.Millisecond))
+	vm.ProcessEvents()
+	if len(*calls) != 2 {
+		t.Fatalf("expected 2 calls, got %d", len(*calls))
+	}
+	if got, _ := toInt64((*calls)[0][0]); got != id {
+		t.Errorf("argument = %d, want timer ID %d", got, id)
+	}
+}
+
+// TestSetTimerOnce tests that a one-shot timer fires once and then removes its handler.
+func TestSetTimerOnce(t *testing.T) {
+	vm, calls := newInputTestVM(t, "id")
+
+	result, _ := vm.builtins["SetTimer"](vm, []any{int64(10), "onInput", int64(0)})
+	id, _ := result.(int64)
+	later := time.Now().Add(time.Second)
+
+	vm.fireTimers(later)
+	vm.ProcessEvents()
+	vm.fireTimers(later.Add(time.Second))
+	vm.ProcessEvents()
+
+	if len(*calls) != 1 {
+		t.Errorf("expected 1 call, got %d", len(*calls))
+	}
+	if _, exists := vm.handlerRegistry.GetHandlerByNumber(int(id)); exists {
+		t.Error("one-shot timer handler should be removed after firing")
+	}
+	if len(vm.timers) != 0 {
+		t.Errorf("timers = %d, want 0", len(vm.timers))
+	}
+}
+
+// TestSetTimerCoalesce tests that a timer that fell behind fires once and is rescheduled from now.
+func TestSetTimerCoalesce(t *testing.T) {
+	vm, calls := newInputTestVM(t, "id")
+
+	result, _ := vm.builtins["SetTimer"](vm, []any{int64(100), "onInput"})
+	timer := vm.timers[int(result.(int64))]
+	late := timer.next.Add(time.Second)
+
+	vm.fireTimers(late)
+	vm.ProcessEvents()
+	if len(*calls) != 1 {
+		t.Errorf("expected 1 call after falling behind, got %d", len(*calls))
+	}
+	if want := late.Add(100 * time.Millisecond); !timer.next.Equal(want) {
+		t.Errorf("next = %v, want %v", timer.next, want)
+	}
+}
+
+// TestSetTimerIndependent tests that two timers only call their own functions.
+func TestSetTimerIndependent(t *testing.T) {
+	vm, calls := newInputTestVM(t, "id")
+
+	first, _ := vm.builtins["SetTimer"](vm, []any{int64(100), "onInput"})
+	second, _ := vm.builtins["SetTimer"](vm, []any{int64(300), "onInput"})
+	start := vm.timers[int(first.(int64))].next.Add(-100 * time.Millisecond)
+
+	vm.fireTimers(start.Add(150 * time.Millisecond))
+	vm.ProcessEvents()
+	if len(*calls) != 1 {
+		t.Fatalf("expected 1 call, got %d", len(*calls))
+	}
+	if got, _ := toInt64((*calls)[0][0]); got != first {
+		t.Errorf("argument = %d, want %v (second timer is %v)", got, first, second)
+	}
+}
+
+// TestSetTimerMinInterval tests that short intervals are raised to minTimerInterval.
+func TestSetTimerMinInterval(t *testing.T) {
+	vm, _ := newInputTestVM(t)
+	result, _ := vm.builtins["SetTimer"](vm, []any{int64(0), "onInput"})
+	if got := vm.timers[int(result.(int64))].interval; got != minTimerInterval {
+		t.Errorf("interval = %v, want %v", got, minTimerInterval)
+	}
+}
+
+// TestSetTimerInvalid tests that invalid arguments do not register a timer.
+func TestSetTimerInvalid(t *testing.T) {
+	vm, _ := newInputTestVM(t)
+	invalid := [][]any{
+		{int64(100)},
+		{int64(-1), "onInput"},
+		{"fast", "onInput"},
+		{int64(100), "undefinedFunc"},
+	}
+	for _, args := range invalid {
+		if result, _ := vm.builtins["SetTimer"](vm, args); result != int64(0) {
+			t.Errorf("SetTimer(%v) = %v, want 0", args, result)
+		}
+	}
+	if vm.handlerRegistry.Count() != 0 {
+		t.Errorf("handlers = %d, want 0", vm.handlerRegistry.Count())
+	}
+}
+
+// TestKillTimer tests that KillTimer stops the timer and removes its handler.
+func TestKillTimer(t *testing.T) {
+	vm, calls := newInputTestVM(t, "id")
+
+	result, _ := vm.builtins["SetTimer"](vm, []any{int64(100), "onInput"})
+	if got, _ := vm.builtins["KillTimer"](vm, []any{result}); got != int64(1) {
+		t.Errorf("KillTimer = %v, want 1", got)
+	}
+	if got, _ := vm.builtins["KillTimer"](vm, []any{result}); got != int64(0) {
+		t.Errorf("second KillTimer = %v, want 0", got)
+	}
+
+	vm.fireTimers(time.Now().Add(time.Second))
+	vm.ProcessEvents()
+	if len(*calls) != 0 {
+		t.Errorf("killed timer fired %d times", len(*calls))
+	}
+	if vm.handlerRegistry.Count() != 0 {
+		t.Errorf("handlers = %d, want 0", vm.handlerRegistry.Count())
+	}
+}
+
+// TestTimerStoppedByDelMes tests that removing the handler with DelMes also stops the timer.
+func TestTimerStoppedByDelMes(t *testing.T) {
+	vm, calls := newInputTestVM(t, "id")
+
+	result, _ := vm.builtins["SetTimer"](vm, []any{int64(100), "onInput"})
+	vm.builtins["DelMes"](vm, []any{result})
+
+	vm.fireTimers(time.Now().Add(time.Second))
+	vm.ProcessEvents()
+	if len(*calls) != 0 {
+		t.Errorf("timer fired %d times after DelMes", len(*calls))
+	}
+	if len(vm.timers) != 0 {
+		t.Errorf("timers = %d, want 0", len(vm.timers))
+	}
+	if got, _ := vm.builtins["KillTimer"](vm, []any{result}); got != int64(0) {
+		t.Errorf("KillTimer after DelMes = %v, want 0", got)
+	}
+}
+
+// TestMesTimer tests that mes(TIMER) blocks receive the events of every timer.
+func TestMesTimer(t *testing.T) {
+	vm, _ := newInputTestVM(t, "id")
+	vm.builtins["SetTimer"](vm, []any{int64(100), "onInput"})
+
+	var received []int
+	vm.RegisterBuiltinFunction("recordTimer", func(v *VM, args []any) (any, error) {
+		n, _ := toInt64(args[0])
+		received = append(received, int(n))
+		return nil, nil
+	})
+	body := []opcode.OpCode{{Cmd: opcode.Call, Args: []any{"recordTimer", opcode.Variable("MesP1")}}}
+	vm.handlerRegistry.Register(NewEventHandler("", EventTIMER, body, vm, vm.GetCurrentScope()))
+
+	vm.fireTimers(time.Now().Add(time.Second))
+	vm.ProcessEvents()
+	if len(received) != 1 || received[0] != 1 {
+		t.Errorf("mes(TIMER) received %v, want [1]", received)
+	}
+}
@@ -0,0 +1,282 @@
+package vm
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/zurustar/son-et/pkg/opcode"
+)
+
+// Chapters
+//
+// Chapter("name") marks a point of a presentation. A run can start at a chapter
+// (--chapter) or an operator can jump ahead to one (the chapter command). The VM
+// gets there by fast-forwarding: the script runs from where it is with audio paused,
+// and TIME/MIDI_TIME/MIDI_END events come from a virtual clock instead of real time,
+// so every Wait, counter and branch plays out exactly as in a normal run, only
+// instantly. When Chapter(name) executes, audio resumes at the matching MIDI position.
+
+const (
+	// chapterSeekStep is the virtual time advanced per fast-forward step
+	chapterSeekStep = 10 * time.Millisecond
+	// chapterSeekLimit is how much virtual time a fast-forward may take before giving up
+	chapterSeekLimit = 4 * time.Hour
+	// timeEventInterval is the interval of TIME events (the audio timer's default)
+	timeEventInterval = 50 * time.Millisecond
+)
+
+// chapterState holds the chapters reached so far and a jump requested by an operator.
+type chapterState struct {
+	mu        sync.Mutex
+	start     string   // chapter to start at (WithStartChapter)
+	passed    []string // chapters reached, in order
+	requested string   // chapter requested by SeekChapter, started by the event loop
+}
+
+// chapterSeek is a fast-forward to a chapter, or to a tick after a rewind (see keyframe.go), in progress.
+// It is only used on the VM goroutine.
+type chapterSeek struct {
+	target     string        // chapter to reach ("" = stop at untilTicks)
+	untilTicks int64         // tick to reach when target is empty
+	start      time.Time     // real time when the fast-forward began (origin of the virtual clock)
+	elapsed    time.Duration // virtual time since start
+	midiFrom   time.Duration // virtual time of the last delivered MIDI_TIME tick before the seek, or of PlayMIDI
+	midiBase   int           // MIDI_TIME tick already delivered by the MIDI player
+	midiTick   int           // last MIDI_TIME tick sent by the fast-forward
+	midiEnded  bool          // MIDI_END was sent
+}
+
+// now returns the virtual time of the fast-forward.
+func (s *chapterSeek) now() time.Time {
+	return s.start.Add(s.elapsed)
+}
+
+// WithStartChapter starts the run at Chapter(name) by fast-forwarding the script (--chapter).
+func WithStartChapter(name string) Option {
+	return func(vm *VM) {
+		vm.chapters.start = name
+	}
+}
+
+// registerChapterBuiltins registers the Chapter built-in function.
+func (vm *VM) registerChapterBuiltins() {
+	// Chapter: Mark a chapter of the presentation
+	// Chapter("name") - a literal name can be jumped to with --chapter or the chapter command
+	vm.RegisterBuiltinFunction("Chapter", func(v *VM, args []any) (any, error) {
+		if len(args) < 1 {
+			v.log.Error("Chapter requires a name")
+			return nil, nil
+		}
+		v.reachChapter(toString(args[0]))
+		return nil, nil
+	})
+}
+
+// Chapters returns the chapter names declared with Chapter("name") in the script, in script order.
+// Names computed at run time are not listed.
+func (vm *VM) Chapters() []string {
+	var names []string
+	seen := make(map[string]bool)
+	walkOpCodes(vm.opcodes, func(op opcode.OpCode) {
+		if op.Cmd != opcode.Call || len(op.Args) < 2 {
+			return
+		}
+		if fn, ok := op.Args[0].(string); !ok || !strings.EqualFold(fn, "Chapter") {
+			return
+		}
+		name, ok := op.Args[1].(string)
+		if ok && !seen[strings.ToLower(name)] {
+			seen[strings.ToLower(name)] = true
+			names = append(names, name)
+		}
+	})
+	return names
+}
+
+// PassedChapters returns the chapters reached so far, in order.
+func (vm *VM) PassedChapters() []string {
+	vm.chapters.mu.Lock()
+	defer vm.chapters.mu.Unlock()
+	return append([]string(nil), vm.chapters.passed...)
+}
+
+// SeekChapter asks the VM to fast-forward to a chapter ahead of the current position.
+// It may be called from any goroutine; the fast-forward starts in the event loop.
+// A chapter that has already been passed is reached by restoring a keyframe taken
+// before it (see keyframe.go); without one, restart the title with --chapter instead.
+func (vm *VM) SeekChapter(name string) error {
+	name, err := vm.findChapter(name)
+	if err != nil {
+		return err
+	}
+	vm.chapters.mu.Lock()
+	defer vm.chapters.mu.Unlock()
+	if i := lastPass(vm.chapters.passed, name); i >= 0 {
+		if vm.requestChapterRewind(name, i) {
+			return nil
+		}
+		return fmt.Errorf("chapter %s has already been passed (restart with --chapter %s)", name, name)
+	}
+	vm.chapters.requested = name
+	return nil
+}
+
+// findChapter returns the declared spelling of a chapter name.
+func (vm *VM) findChapter(name string) (string, error) {
+	chapters := vm.Chapters()
+	for _, c := range chapters {
+		if strings.EqualFold(c, name) {
+			return c, nil
+		}
+	}
+	if len(chapters) == 0 {
+		return "", fmt.Errorf("chapter not found: %s (the script declares no chapters)", name)
+	}
+	return "", fmt.Errorf("chapter not found: %s (chapters: %s)", name, strings.Join(chapters, ", "))
+}
+
+// reachChapter records that Chapter(name) was executed and ends a fast-forward to it.
+func (vm *VM) reachChapter(name string) {
+	vm.chapters.mu.Lock()
+	vm.chapters.passed = append(vm.chapters.passed, name)
+	vm.chapters.mu.Unlock()
+	vm.log.Info("Chapter reached", "chapter", name)
+
+	if vm.seek != nil && vm.seek.target != "" && strings.EqualFold(vm.seek.target, name) {
+		vm.finishChapterSeek(vm.clock.Now())
+	}
+}
+
+// beginChapterSeek starts fast-forwarding to a chapter: audio is paused and the
+// virtual clock starts at now.
+func (vm *VM) beginChapterSeek(name string, now time.Time) {
+	if vm.seek != nil {
+		vm.seek.target = name
+		vm.log.Info("Seek retargeted", "target", vm.seek.goal())
+		return
+	}
+	s := &chapterSeek{target: name, start: now}
+	if vm.audioSystem != nil {
+		vm.audioSystem.Pause()
+		if tick, _ := vm.audioSystem.MIDITickAfter(0); tick >= 0 {
+			s.midiBase, s.midiTick = tick, tick
+		}
+	}
+	vm.seek = s
+	vm.log.Info("Seek started", "target", s.goal())
+}
+
+// restartChapterSeekMIDI tells a fast-forward in progress that PlayMIDI started a new file.
+func (vm *VM) restartChapterSeekMIDI() {
+	if vm.seek == nil {
+		return
+	}
+	vm.seek.midiFrom = vm.seek.elapsed
+	vm.seek.midiBase, vm.seek.midiTick = 0, 0
+	vm.seek.midiEnded = false
+}
+
+// advanceChapterSeek returns the time the event loop should use: now, or the
+// virtual time while fast-forwarding. It starts a fast-forward requested by
+// SeekChapter or Rewind and, once the handlers have consumed the previous ticks,
+// advances the virtual clock by one step and queues the ticks that fall in it.
+func (vm *VM) advanceChapterSeek(now time.Time) time.Time {
+	vm.chapters.mu.Lock()
+	requested := vm.chapters.requested
+	vm.chapters.requested = ""
+	vm.chapters.mu.Unlock()
+	if requested != "" {
+		vm.beginChapterSeek(requested, now)
+	}
+	vm.startRewind(now)
+
+	s := vm.seek
+	if s == nil {
+		return now
+	}
+	if s.target == "" && vm.ticks.Load() >= s.untilTicks {
+		vm.finishChapterSeek(now)
+		return now
+	}
+	if vm.eventQueue.HasType(EventTIME) || vm.eventQueue.HasType(EventMIDI_TIME) {
+		return s.now()
+	}
+	if s.elapsed >= chapterSeekLimit {
+		vm.log.Error("Seek target not reached, resuming normal playback", "target", s.goal(), "limit", chapterSeekLimit)
+		vm.finishChapterSeek(now)
+		return now
+	}
+
+	s.elapsed += chapterSeekStep
+	if vm.audioSystem == nil {
+		return s.now()
+	}
+	if s.elapsed%timeEventInterval == 0 && vm.audioSystem.IsTimerRunning() {
+		vm.eventQueue.Push(NewEvent(EventTIME))
+	}
+	if !s.midiEnded {
+		tick, ended := vm.audioSystem.MIDITickAfter(s.elapsed - s.midiFrom)
+		for t := s.midiTick + 1; t <= tick; t++ {
+			vm.eventQueue.Push(NewEventWithParams(EventMIDI_TIME, map[string]any{"Tick": t}))
+			s.midiTick = t
+		}
+		if ended {
+			vm.eventQueue.Push(NewEvent(EventMIDI_END))
+			s.midiEnded = true
+		}
+	}
+	return s.now()
+}
+
+// finishChapterSeek ends the fast-forward: the MIDI file jumps to the last tick
+// sent (or stops if it ended), audio resumes, and SetTimer timers are moved from
+// the virtual clock back to real time.
+func (vm *VM) finishChapterSeek(now time.Time) {
+	s := vm.seek
+	vm.seek = nil
+	if vm.audioSystem != nil {
+		switch {
+		case s.midiEnded:
+			vm.audioSystem.StopMIDIPort("")
+		case s.midiTick > s.midiBase:
+			if err := vm.audioSystem.SkipMIDI(s.midiTick); err != nil {
+				vm.log.Warn("Failed to move MIDI to the chapter", "tick", s.midiTick, "error", err)
+			}
+		}
+		vm.audioSystem.Resume()
+	}
+	shift := now.Sub(s.now())
+	for _, t := range vm.timers {
+		t.next = t.next.Add(shift)
+	}
+	vm.log.Info("Seek finished", "target", s.goal(), "virtualTime", s.elapsed, "realTime", now.Sub(s.start))
+}
+
+// walkOpCodes calls fn for every OpCode in ops, including nested blocks, switch cases and expressions.
+func walkOpCodes(ops []opcode.OpCode, fn func(opcode.OpCode)) {
+	for _, op := range ops {
+		fn(op)
+		for _, arg := range op.Args {
+			walkOpCodeValue(arg, fn)
+		}
+	}
+}
+
+func walkOpCodeValue(v any, fn func(opcode.OpCode)) {
+	switch val := v.(type) {
+	case opcode.OpCode:
+		walkOpCodes([]opcode.OpCode{val}, fn)
+	case []opcode.OpCode:
+		walkOpCodes(val, fn)
+	case []any:
+		for _, item := range val {
+			walkOpCodeValue(item, fn)
+		}
+	case map[string]any:
+		// switch の case は {"value": ..., "body": [...]}
+		walkOpCodeValue(val["value"], fn)
+		walkOpCodeValue(val["body"], fn)
+	}
+}
@@ -0,0 +1,209 @@
+package vm
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/zurustar/son-et/pkg/opcode"
+)
+
+// newChapterTestVM returns a VM whose script declares the chapters intro, Verse2
+// (inside a function and a switch case) and a computed name that cannot be listed.
+func newChapterTestVM() (*VM, *mockAudioSystem) {
+	chapter := func(arg any) opcode.OpCode {
+		return opcode.OpCode{Cmd: opcode.Call, Args: []any{"Chapter", arg}}
+	}
+	vm := New([]opcode.OpCode{
+		{Cmd: opcode.DefineFunction, Args: []any{"main", []any{}, []opcode.OpCode{
+			chapter("intro"),
+			{Cmd: opcode.Switch, Args: []any{
+				opcode.Variable("x"),
+				[]any{map[string]any{
+					"value": int64(1),
+					"body":  []opcode.OpCode{chapter("Verse2")},
+				}},
+				[]opcode.OpCode{chapter(opcode.Variable("name"))},
+			}},
+			chapter("INTRO"),
+		}}},
+	})
+	audio := newMockAudioSystem()
+	vm.SetAudioSystem(audio)
+	return vm, audio
+}
+
+func TestChapters(t *testing.T) {
+	vm, _ := newChapterTestVM()
+	if got := strings.Join(vm.Chapters(), ","); got != "intro,Verse2" {
+		t.Errorf("Chapters() = %s, want intro,Verse2", got)
+	}
+	if got := New([]opcode.OpCode{}).Chapters(); len(got) != 0 {
+		t.Errorf("Chapters() without Chapter calls = %v", got)
+	}
+}
+
+func TestSeekChapter(t *testing.T) {
+	vm, _ := newChapterTestVM()
+
+	if err := vm.SeekChapter("missing"); err == nil || !strings.Contains(err.Error(), "intro, Verse2") {
+		t.Errorf("SeekChapter(missing) = %v, want an error listing the chapters", err)
+	}
+	if err := vm.SeekChapter("verse2"); err != nil {
+		t.Fatalf("SeekChapter(verse2) failed: %v", err)
+	}
+	if vm.chapters.requested != "Verse2" {
+		t.Errorf("requested = %q, want the declared spelling Verse2", vm.chapters.requested)
+	}
+
+	vm.reachChapter("intro")
+	if err := vm.SeekChapter("Intro"); err == nil || !strings.Contains(err.Error(), "--chapter") {
+		t.Errorf("SeekChapter of a passed chapter = %v, want a restart hint", err)
+	}
+}
+
+// TestChapterSeekEvents tests that the fast-forward sends TIME every 50ms of
+// virtual time while the timer runs, every MIDI_TIME tick and MIDI_END once,
+// and waits for the handlers to consume the previous ticks.
+func TestChapterSeekEvents(t *testing.T) {
+	vm, audio := newChapterTestVM()
+	audio.StartTimer()
+	audio.PlayMIDI("song.mid")
+	audio.midiLength = 3
+
+	start := time.Now()
+	vm.beginChapterSeek("Verse2", start)
+	if !audio.paused {
+		t.Error("expected audio to be paused while fast-forwarding")
+	}
+
+	var times, ticks, ends int
+	for range 40 {
+		vm.advanceChapterSeek(time.Now())
+		for {
+			event, ok := vm.eventQueue.Pop()
+			if !ok {
+				break
+			}
+			switch event.Type {
+			case EventTIME:
+				times++
+			case EventMIDI_TIME:
+				ticks++
+				if tick, _ := event.Params["Tick"].(int); tick != ticks {
+					t.Errorf("MIDI_TIME tick = %v, want %d", event.Params["Tick"], ticks)
+				}
+			case EventMIDI_END:
+				ends++
+			}
+		}
+	}
+	if times != 8 || ticks != 3 || ends != 1 {
+		t.Errorf("TIME %d, MIDI_TIME %d, MIDI_END %d; want 8, 3, 1", times, ticks, ends)
+	}
+	if got := vm.seek.now().Sub(start); got != 400*time.Millisecond {
+		t.Errorf("virtual time = %v, want 400ms", got)
+	}
+
+	// 前のティックが処理されるまで時計を進めない
+	vm.eventQueue.Push(NewEvent(EventTIME))
+	before := vm.seek.elapsed
+	vm.advanceChapterSeek(time.Now())
+	if vm.seek.elapsed != before {
+		t.Error("virtual clock advanced while a TIME event was queued")
+	}
+}
+
+// TestFinishChapterSeek tests that reaching the chapter moves the MIDI to the
+// last tick sent, resumes audio and moves timers back to real time.
+func TestFinishChapterSeek(t *testing.T) {
+	vm, audio := newChapterTestVM()
+	audio.PlayMIDI("song.mid")
+
+	start := time.Now()
+	vm.beginChapterSeek("verse2", start)
+	id := vm.registerTimer([]any{"f"}, time.Second, true, start)
+	for range 30 {
+		vm.advanceChapterSeek(time.Now())
+		vm.eventQueue.Clear()
+	}
+	virtual := vm.seek.now()
+	vm.timers[id].next = virtual.Add(time.Second)
+
+	vm.reachChapter("intro")
+	if vm.seek == nil {
+		t.Fatal("a chapter other than the target ended the fast-forward")
+	}
+	vm.reachChapter("VERSE2")
+	if vm.seek != nil {
+		t.Fatal("expected the target chapter to end the fast-forward")
+	}
+	if len(audio.skips) != 1 || audio.skips[0] != 3 {
+		t.Errorf("skips = %v, want [3]", audio.skips)
+	}
+	if audio.paused {
+		t.Error("expected audio to resume at the chapter")
+	}
+	if left := time.Until(vm.timers[id].next); left < 900*time.Millisecond || left > time.Second {
+		t.Errorf("timer is due in %v, want about 1s of real time", left)
+	}
+	if got := strings.Join(vm.PassedChapters(), ","); got != "intro,VERSE2" {
+		t.Errorf("PassedChapters() = %s", got)
+	}
+}
+
+// TestChapterSeekRequest tests that SeekChapter starts the fast-forward in the event loop.
+func TestChapterSeekRequest(t *testing.T) {
+	vm, audio := newChapterTestVM()
+	if err := vm.SeekChapter("intro"); err != nil {
+		t.Fatal(err)
+	}
+	now := time.Now()
+	if got := vm.advanceChapterSeek(now); !got.After(now) || vm.seek == nil || vm.seek.target != "intro" {
+		t.Errorf("advanceChapterSeek = %v, seek %+v; want a virtual time after now", got, vm.seek)
+	}
+	if !audio.paused {
+		t.Error("expected audio to be paused")
+	}
+
+	// 早送りをしていない間は実時間を返す
+	idle, _ := newChapterTestVM()
+	if got := idle.advanceChapterSeek(now); !got.Equal(now) {
+		t.Errorf("advanceChapterSeek without a seek = %v, want now", got)
+	}
+}
+
+func TestChapterBuiltin(t *testing.T) {
+	vm, _ := newChapterTestVM()
+	fn := vm.builtins["Chapter"]
+	if fn == nil {
+		t.Fatal("Chapter is not registered")
+	}
+	if _, err := fn(vm, []any{"intro"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := fn(vm, []any{}); err != nil {
+		t.Errorf("Chapter without a name should only log: %v", err)
+	}
+	if got := vm.PassedChapters(); len(got) != 1 || got[0] != "intro" {
+		t.Errorf("PassedChapters() = %v, want [intro]", got)
+	}
+}
+
+func TestRunCommandChapter(t *testing.T) {
+	vm, _ := newChapterTestVM()
+	vm.reachChapter("intro")
+
+	if out, err := vm.RunCommand("chapter"); err != nil || out != "* intro\n  Verse2" {
+		t.Errorf("chapter = %q, %v", out, err)
+	}
+	if out, err := vm.RunCommand("chapter verse2"); err != nil || out != "fast-forwarding to chapter verse2" {
+		t.Errorf("chapter verse2 = %q, %v", out, err)
+	}
+	if _, err := vm.RunCommand("chapter intro"); err == nil {
+		t.Error("expected chapter to refuse a passed chapter")
+	}
+	if out, err := New([]opcode.OpCode{}).RunCommand("chapter"); err != nil || out != "no chapters" {
+		t.Errorf("chapter without chapters = %q, %v", out, err)
+	}
+}
@@ -0,0 +1,81 @@
+package vm
+
+import (
+	"context"
+	"time"
+)
+
+// Clock
+//
+// The VM and the TIME timer read the time through a Clock instead of the time
+// package, so tests can replace it with a simulated clock (vmtest.Clock) and run
+// timeouts, SetTimer and TIME events instantly and deterministically. The engine
+// uses RealClock unless WithClock is given.
+
+// Clock is the source of time of the VM and the TIME timer.
+type Clock interface {
+	// Now returns the current time
+	Now() time.Time
+	// NewTicker returns a ticker that delivers a tick every d
+	NewTicker(d time.Duration) Ticker
+}
+
+// Ticker delivers ticks at an interval, like time.Ticker.
+type Ticker interface {
+	// C returns the channel on which the ticks are delivered
+	C() <-chan time.Time
+	// Reset stops the ticker and resets its interval to d
+	Reset(d time.Duration)
+	// Stop turns off the ticker; no more ticks are delivered
+	Stop()
+}
+
+// RealClock is the Clock backed by the time package.
+var RealClock Clock = realClock{}
+
+type realClock struct{}
+
+func (realClock) Now() time.Time { return time.Now() }
+
+func (realClock) NewTicker(d time.Duration) Ticker {
+	return realTicker{time.NewTicker(d)}
+}
+
+// realTicker wraps time.Ticker (its C field cannot satisfy the C method directly).
+type realTicker struct {
+	t *time.Ticker
+}
+
+func (r realTicker) C() <-chan time.Time   { return r.t.C }
+func (r realTicker) Reset(d time.Duration) { r.t.Reset(d) }
+func (r realTicker) Stop()                 { r.t.Stop() }
+
+// WithClock sets the clock of the VM (the timeout, SetTimer timers, chapter
+// fast-forward and image sampling). Tests pass a vmtest.Clock.
+func WithClock(clock Clock) Option {
+	return func(vm *VM) {
+		vm.clock = clock
+	}
+}
+
+// withClockTimeout is context.WithTimeout measured by clock: when d has passed on
+// the clock, the context is cancelled with the cause context.DeadlineExceeded.
+// Check the cause with timedOut, since ctx.Err() reports context.Canceled.
+func withClockTimeout(parent context.Context, clock Clock, d time.Duration) (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithCancelCause(parent)
+	ticker := clock.NewTicker(d)
+	go func() {
+		defer ticker.Stop()
+		select {
+		case <-ticker.C():
+			cancel(context.DeadlineExceeded)
+		case <-ctx.Done():
+		}
+	}()
+	return ctx, func() { cancel(context.Canceled) }
+}
+
+// timedOut reports whether ctx was cancelled because its clock timeout expired.
+func timedOut(ctx context.Context) bool {
+	return context.Cause(ctx) == context.DeadlineExceeded
+}
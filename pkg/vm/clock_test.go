@@ -0,0 +1,63 @@
+package vm
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+// fixedClock is a Clock whose Now does not move (its tickers run in real time).
+type fixedClock struct {
+	now time.Time
+}
+
+func (c fixedClock) Now() time.Time                   { return c.now }
+func (c fixedClock) NewTicker(d time.Duration) Ticker { return RealClock.NewTicker(d) }
+
+func TestWithClock(t *testing.T) {
+	if vm := New(nil); vm.clock != RealClock {
+		t.Error("VM should use RealClock by default")
+	}
+
+	clock := fixedClock{now: time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC)}
+	vm, _ := newInputTestVM(t, "id")
+	WithClock(clock)(vm)
+
+	// SetTimer は VM の時計の時刻から数える
+	result, _ := vm.builtins["SetTimer"](vm, []any{int64(100), "onInput"})
+	timer := vm.timers[int(result.(int64))]
+	if want := clock.now.Add(100 * time.Millisecond); !timer.next.Equal(want) {
+		t.Errorf("next = %v, want %v", timer.next, want)
+	}
+}
+
+func TestWithClockTimeout(t *testing.T) {
+	t.Run("expires", func(t *testing.T) {
+		ctx, cancel := withClockTimeout(context.Background(), RealClock, time.Millisecond)
+		defer cancel()
+		<-ctx.Done()
+		if !timedOut(ctx) {
+			t.Errorf("timedOut = false after the timeout, cause %v", context.Cause(ctx))
+		}
+	})
+
+	t.Run("cancelled", func(t *testing.T) {
+		ctx, cancel := withClockTimeout(context.Background(), RealClock, time.Hour)
+		cancel()
+		<-ctx.Done()
+		if timedOut(ctx) {
+			t.Error("timedOut = true after cancel")
+		}
+	})
+
+	t.Run("parent cancelled", func(t *testing.T) {
+		parent, cancelParent := context.WithCancel(context.Background())
+		ctx, cancel := withClockTimeout(parent, RealClock, time.Hour)
+		defer cancel()
+		cancelParent()
+		<-ctx.Done()
+		if timedOut(ctx) {
+			t.Error("timedOut = true after the parent was cancelled")
+		}
+	})
+}
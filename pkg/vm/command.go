@@ -0,0 +1,407 @@
+package vm
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Operator commands
+//
+// RunCommand executes one line typed by an operator running a live show, e.g.
+// "seek 1200", "vol 0.5" or "spawn Intro". The GUI console (~ key) and the
+// remote watch API (POST /command) share this backend, so a command behaves the
+// same from both. Commands may be called from other goroutines while the VM runs.
+
+// ErrUnknownCommand is returned by RunCommand for a command that does not exist.
+var ErrUnknownCommand = errors.New("unknown command")
+
+// operatorCommand is a command accepted by RunCommand.
+type operatorCommand struct {
+	usage   string
+	help    string
+	minArgs int
+	maxArgs int     // -1 for any number of arguments
+	cue     cueMode // whether the command is recorded to the cue file (see cue.go)
+	run     func(vm *VM, args []string) (string, error)
+}
+
+// operatorCommands are the commands accepted by RunCommand, keyed by lower-case name.
+// RunCommand checks the number of arguments before calling run.
+// "help" is handled by RunCommand itself.
+var operatorCommands = map[string]operatorCommand{
+	"seek": {
+		usage:   "seek <tick>",
+		help:    "jump forward to a MIDI_TIME tick of the playing MIDI",
+		minArgs: 1,
+		maxArgs: 1,
+		cue:     cueAlways,
+		run:     (*VM).commandSeek,
+	},
+	"vol": {
+		usage:   "vol [0-1]",
+		help:    "show or set the master volume",
+		minArgs: 0,
+		maxArgs: 1,
+		cue:     cueWithArgs,
+		run:     (*VM).commandVolume,
+	},
+	"speed": {
+		usage:   "speed [scale]",
+		help:    "show or set the playback speed (0.25-4)",
+		minArgs: 0,
+		maxArgs: 1,
+		cue:     cueWithArgs,
+		run:     (*VM).commandSpeed,
+	},
+	"pause": {
+		usage:   "pause",
+		help:    "pause the tick clock and audio",
+		minArgs: 0,
+		maxArgs: 0,
+		cue:     cueAlways,
+		run:     (*VM).commandPause,
+	},
+	"resume": {
+		usage:   "resume",
+		help:    "resume after pause",
+		minArgs: 0,
+		maxArgs: 0,
+		cue:     cueAlways,
+		run:     (*VM).commandResume,
+	},
+	"spawn": {
+		usage:   "spawn <function> [args...]",
+		help:    "start a script function as a new sequence",
+		minArgs: 1,
+		maxArgs: -1,
+		cue:     cueAlways,
+		run:     (*VM).commandSpawn,
+	},
+	"chapter": {
+		usage:   "chapter [name]",
+		help:    "list the chapters or seek to one (passed ones need keyframes)",
+		minArgs: 0,
+		maxArgs: 1,
+		cue:     cueWithArgs,
+		run:     (*VM).commandChapter,
+	},
+	"rewind": {
+		usage:   "rewind <seconds>",
+		help:    "go back to the nearest keyframe and replay to that many seconds ago",
+		minArgs: 1,
+		maxArgs: 1,
+		cue:     cueAlways,
+		run:     (*VM).commandRewind,
+	},
+	"leaks": {
+		usage:   "leaks [seconds]",
+		help:    "show picture memory and pictures hidden for a while (default 30s)",
+		minArgs: 0,
+		maxArgs: 1,
+		run:     (*VM).commandLeaks,
+	},
+	"layers": {
+		usage:   "layers [dir]",
+		help:    "save each compositing layer of the next frame as PNGs in the output directory",
+		minArgs: 0,
+		maxArgs: 1,
+		run:     (*VM).commandLayers,
+	},
+	"get": {
+		usage:   "get <variable>",
+		help:    "show a global variable",
+		minArgs: 1,
+		maxArgs: 1,
+		run:     (*VM).commandGet,
+	},
+	"set": {
+		usage:   "set <variable> <number>",
+		help:    "change a numeric global variable",
+		minArgs: 2,
+		maxArgs: 2,
+		cue:     cueAlways,
+		run:     (*VM).commandSet,
+	},
+}
+
+// layersDirTimeFormat names the directory of the layers command when none is given.
+const layersDirTimeFormat = "20060102-150405"
+
+// spawnRequest is a function queued by the spawn command.
+// It is started on the VM goroutine by startSpawns.
+type spawnRequest struct {
+	name string
+	args []any
+}
+
+// spawnQueue holds the functions queued by the spawn command.
+type spawnQueue struct {
+	mu       sync.Mutex
+	requests []spawnRequest
+}
+
+// RunCommand executes an operator command line and returns the text to show.
+// Names are case-insensitive; an empty line does nothing.
+func (vm *VM) RunCommand(line string) (string, error) {
+	fields := strings.Fields(line)
+	if len(fields) == 0 {
+		return "", nil
+	}
+	name := strings.ToLower(fields[0])
+	if name == "help" {
+		return commandHelp(), nil
+	}
+	cmd, ok := operatorCommands[name]
+	if !ok {
+		return "", fmt.Errorf("%w: %s (type help for the list)", ErrUnknownCommand, fields[0])
+	}
+	if args := len(fields) - 1; args < cmd.minArgs || (cmd.maxArgs >= 0 && args > cmd.maxArgs) {
+		return "", fmt.Errorf("usage: %s", cmd.usage)
+	}
+	out, err := cmd.run(vm, fields[1:])
+	if err != nil {
+		return "", err
+	}
+	vm.log.Info("Operator command", "command", line, "result", out)
+	if cmd.cue == cueAlways || (cmd.cue == cueWithArgs && len(fields) > 1) {
+		// The name is recorded in lower case
+		vm.recordCue(append([]string{name}, fields[1:]...))
+	}
+	return out, nil
+}
+
+// CommandNames returns the names of the operator commands, sorted (used for completion).
+func CommandNames() []string {
+	names := []string{"help"}
+	for name := range operatorCommands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// commandHelp returns the usage of every command, one per line.
+func commandHelp() string {
+	var sb strings.Builder
+	for _, name := range CommandNames() {
+		if name == "help" {
+			continue
+		}
+		cmd := operatorCommands[name]
+		fmt.Fprintf(&sb, "%-28s %s\n", cmd.usage, cmd.help)
+	}
+	return strings.TrimSuffix(sb.String(), "\n")
+}
+
+func (vm *VM) commandSeek(args []string) (string, error) {
+	tick, err := strconv.Atoi(args[0])
+	if err != nil || tick < 0 {
+		return "", fmt.Errorf("tick must be a non-negative integer: %s", args[0])
+	}
+	if vm.audioSystem == nil {
+		return "", errors.New("audio is not available")
+	}
+	if err := vm.audioSystem.SeekMIDI(tick); err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("seek to tick %d", tick), nil
+}
+
+func (vm *VM) commandVolume(args []string) (string, error) {
+	if vm.audioSystem == nil {
+		return "", errors.New("audio is not available")
+	}
+	if len(args) == 1 {
+		gain, err := strconv.ParseFloat(args[0], 64)
+		if err != nil || gain < 0 || gain > 1 {
+			return "", fmt.Errorf("volume must be a number from 0 to 1: %s", args[0])
+		}
+		if err := vm.audioSystem.SetBusGain("master", gain); err != nil {
+			return "", err
+		}
+	}
+	gain, err := vm.audioSystem.BusGain("master")
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("volume %g", gain), nil
+}
+
+func (vm *VM) commandSpeed(args []string) (string, error) {
+	if len(args) == 1 {
+		scale, err := strconv.ParseFloat(args[0], 64)
+		if err != nil || scale <= 0 {
+			return "", fmt.Errorf("speed must be a positive number: %s", args[0])
+		}
+		return fmt.Sprintf("speed x%g", vm.SetTimeScale(scale)), nil
+	}
+	return fmt.Sprintf("speed x%g", vm.TimeScale()), nil
+}
+
+func (vm *VM) commandPause(args []string) (string, error) {
+	vm.Pause()
+	return "paused", nil
+}
+
+func (vm *VM) commandResume(args []string) (string, error) {
+	vm.Resume()
+	return "resumed", nil
+}
+
+// commandSpawn queues a function to be started on the VM goroutine.
+// Arguments that look like numbers are passed as numbers, the others as strings.
+func (vm *VM) commandSpawn(args []string) (string, error) {
+	req := spawnRequest{name: args[0]}
+	for _, arg := range args[1:] {
+		req.args = append(req.args, parseCommandValue(arg))
+	}
+	vm.spawns.mu.Lock()
+	vm.spawns.requests = append(vm.spawns.requests, req)
+	vm.spawns.mu.Unlock()
+	return fmt.Sprintf("spawning %s", req.name), nil
+}
+
+// commandChapter lists the chapters (marking those passed) or starts a fast-forward.
+func (vm *VM) commandChapter(args []string) (string, error) {
+	if len(args) == 1 {
+		if err := vm.SeekChapter(args[0]); err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("fast-forwarding to chapter %s", args[0]), nil
+	}
+	chapters := vm.Chapters()
+	if len(chapters) == 0 {
+		return "no chapters", nil
+	}
+	passed := make(map[string]bool)
+	for _, name := range vm.PassedChapters() {
+		passed[strings.ToLower(name)] = true
+	}
+	lines := make([]string, len(chapters))
+	for i, name := range chapters {
+		lines[i] = "  " + name
+		if passed[strings.ToLower(name)] {
+			lines[i] = "* " + name
+		}
+	}
+	return strings.Join(lines, "\n"), nil
+}
+
+// commandRewind goes back the given seconds of ticks (see keyframe.go).
+func (vm *VM) commandRewind(args []string) (string, error) {
+	seconds, err := strconv.ParseFloat(args[0], 64)
+	if err != nil || seconds <= 0 {
+		return "", fmt.Errorf("seconds must be a positive number: %s", args[0])
+	}
+	if err := vm.Rewind(time.Duration(seconds * float64(time.Second))); err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("rewinding %s seconds", args[0]), nil
+}
+
+// commandLeaks reports the picture memory and the pictures not shown for the given seconds.
+func (vm *VM) commandLeaks(args []string) (string, error) {
+	var idle time.Duration
+	if len(args) == 1 {
+		seconds, err := strconv.ParseFloat(args[0], 64)
+		if err != nil || seconds <= 0 {
+			return "", fmt.Errorf("seconds must be a positive number: %s", args[0])
+		}
+		idle = time.Duration(seconds * float64(time.Second))
+	}
+	report, err := vm.ImageReport(idle)
+	if err != nil {
+		return "", err
+	}
+	return report.String(), nil
+}
+
+func (vm *VM) commandLayers(args []string) (string, error) {
+	if vm.graphicsSystem == nil {
+		return "", errors.New("graphics is not available")
+	}
+	if vm.outputDir == "" {
+		return "", errNoOutputDir
+	}
+	name := "layers-" + time.Now().Format(layersDirTimeFormat)
+	if len(args) == 1 {
+		name = args[0]
+	}
+	if !filepath.IsLocal(name) {
+		return "", fmt.Errorf("directory %q must be a relative path inside the output directory", name)
+	}
+	dir := filepath.Join(vm.outputDir, name)
+	if err := vm.graphicsSystem.CaptureLayers(dir); err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("the layers of the next frame will be saved in %s", dir), nil
+}
+
+func (vm *VM) commandGet(args []string) (string, error) {
+	value, ok := vm.globalScope.GetLocal(args[0])
+	if !ok || isEventTypeConstant(args[0]) {
+		return "", fmt.Errorf("global variable not found: %s", args[0])
+	}
+	return fmt.Sprintf("%s = %s", args[0], formatCommandValue(value)), nil
+}
+
+func (vm *VM) commandSet(args []string) (string, error) {
+	value, err := strconv.ParseFloat(args[1], 64)
+	if err != nil {
+		return "", fmt.Errorf("value must be a number: %s", args[1])
+	}
+	stored, err := vm.SetWatchedVariable(args[0], value)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%s = %s", args[0], formatCommandValue(stored)), nil
+}
+
+// parseCommandValue converts a command argument to int64, float64 or string.
+func parseCommandValue(s string) any {
+	if n, err := strconv.ParseInt(s, 10, 64); err == nil {
+		return n
+	}
+	if f, err := strconv.ParseFloat(s, 64); err == nil {
+		return f
+	}
+	return s
+}
+
+// formatCommandValue formats a script value for a command result.
+func formatCommandValue(value any) string {
+	switch v := value.(type) {
+	case string:
+		return strconv.Quote(v)
+	case *Array:
+		return fmt.Sprintf("array[%d]", v.Len())
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+// startSpawns starts the functions queued by the spawn command.
+// Each function runs as a one-shot timer that is due immediately, so it gets
+// its own sequence (GetMesNo) and may wait like any event handler.
+// Must be called on the VM goroutine.
+func (vm *VM) startSpawns(now time.Time) {
+	vm.spawns.mu.Lock()
+	requests := vm.spawns.requests
+	vm.spawns.requests = nil
+	vm.spawns.mu.Unlock()
+
+	for _, req := range requests {
+		fn, ok := vm.lookupCallback("spawn", req.name)
+		if !ok {
+			continue
+		}
+		id := vm.registerTimer(append([]any{fn.Name}, req.args...), 0, false, now)
+		vm.log.Info("Function spawned", "function", fn.Name, "sequence", id)
+	}
+}
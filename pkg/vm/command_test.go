@@ -0,0 +1,175 @@
+package vm
+
+import (
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/zurustar/son-et/pkg/opcode"
+)
+
+// TestRunCommandParsing tests command lookup, case-insensitivity and argument checks.
+func TestRunCommandParsing(t *testing.T) {
+	vm := New([]opcode.OpCode{})
+	vm.SetAudioSystem(newMockAudioSystem())
+
+	if out, err := vm.RunCommand("   "); out != "" || err != nil {
+		t.Errorf("empty line = %q, %v; want nothing", out, err)
+	}
+	if _, err := vm.RunCommand("explode now"); !errors.Is(err, ErrUnknownCommand) {
+		t.Errorf("expected ErrUnknownCommand, got %v", err)
+	}
+	for _, line := range []string{"seek", "seek 1 2", "set count", "pause now"} {
+		if _, err := vm.RunCommand(line); err == nil || !strings.HasPrefix(err.Error(), "usage: ") {
+			t.Errorf("RunCommand(%q) = %v, want a usage error", line, err)
+		}
+	}
+	if out, err := vm.RunCommand("SPEED"); err != nil || out != "speed x1" {
+		t.Errorf("SPEED = %q, %v; want speed x1", out, err)
+	}
+
+	help, err := vm.RunCommand("help")
+	if err != nil {
+		t.Fatalf("help failed: %v", err)
+	}
+	for _, name := range CommandNames() {
+		if name != "help" && !strings.Contains(help, name) {
+			t.Errorf("help does not list %s:\n%s", name, help)
+		}
+	}
+}
+
+// TestRunCommandAudio tests the commands forwarded to the audio system.
+func TestRunCommandAudio(t *testing.T) {
+	audio := newMockAudioSystem()
+	vm := New([]opcode.OpCode{})
+	vm.SetAudioSystem(audio)
+
+	if out, err := vm.RunCommand("seek 1200"); err != nil || out != "seek to tick 1200" {
+		t.Errorf("seek = %q, %v", out, err)
+	}
+	if len(audio.seeks) != 1 || audio.seeks[0] != 1200 {
+		t.Errorf("seeks = %v, want [1200]", audio.seeks)
+	}
+	if out, err := vm.RunCommand("vol 0.5"); err != nil || out != "volume 0.5" || audio.gains["master"] != 0.5 {
+		t.Errorf("vol 0.5 = %q, %v; master gain %v", out, err, audio.gains["master"])
+	}
+	if out, err := vm.RunCommand("vol"); err != nil || out != "volume 0.5" {
+		t.Errorf("vol = %q, %v", out, err)
+	}
+	if out, err := vm.RunCommand("speed 8"); err != nil || out != "speed x4" {
+		t.Errorf("speed 8 = %q, %v; want the clamped scale", out, err)
+	}
+
+	for _, line := range []string{"seek -1", "seek abc", "vol 2", "vol loud", "speed 0"} {
+		if _, err := vm.RunCommand(line); err == nil {
+			t.Errorf("RunCommand(%q) should fail", line)
+		}
+	}
+	if len(audio.seeks) != 1 || audio.gains["master"] != 0.5 {
+		t.Errorf("invalid commands changed the audio: seeks %v, gain %v", audio.seeks, audio.gains["master"])
+	}
+
+	noAudio := New([]opcode.OpCode{})
+	if _, err := noAudio.RunCommand("seek 10"); err == nil {
+		t.Error("expected seek to fail without an audio system")
+	}
+}
+
+// TestRunCommandVariables tests get and set.
+func TestRunCommandVariables(t *testing.T) {
+	vm := New([]opcode.OpCode{})
+	vm.globalScope.Set("count", int64(3))
+	vm.globalScope.Set("title", "demo")
+
+	if out, err := vm.RunCommand("get title"); err != nil || out != `title = "demo"` {
+		t.Errorf("get title = %q, %v", out, err)
+	}
+	if out, err := vm.RunCommand("set count 7.6"); err != nil || out != "count = 8" {
+		t.Errorf("set count = %q, %v; want the rounded integer", out, err)
+	}
+	if _, err := vm.RunCommand("set title 1"); err == nil {
+		t.Error("expected set to reject a string variable")
+	}
+	if _, err := vm.RunCommand("get missing"); err == nil {
+		t.Error("expected get to fail for an undefined variable")
+	}
+}
+
+// TestRunCommandSpawn tests that spawn starts the function on the VM goroutine
+// as its own sequence, with numeric and string arguments.
+func TestRunCommandSpawn(t *testing.T) {
+	vm, calls := newInputTestVM(t, "a", "b")
+
+	if out, err := vm.RunCommand("spawn ONINPUT 5 boss"); err != nil || out != "spawning ONINPUT" {
+		t.Fatalf("spawn = %q, %v", out, err)
+	}
+	if _, err := vm.RunCommand("spawn missing"); err != nil {
+		t.Fatalf("spawn of an undefined function is only reported when it starts: %v", err)
+	}
+	if len(*calls) != 0 {
+		t.Fatal("spawn should not run the function on the calling goroutine")
+	}
+
+	now := time.Now()
+	vm.startSpawns(now)
+	vm.fireTimers(now)
+	vm.ProcessEvents()
+	if len(*calls) != 1 {
+		t.Fatalf("expected 1 call, got %d", len(*calls))
+	}
+	if a, _ := toInt64((*calls)[0][0]); a != 5 || (*calls)[0][1] != "boss" {
+		t.Errorf("arguments = %v, want [5 boss]", (*calls)[0])
+	}
+
+	// 一度だけ実行され、シーケンスは終了後に削除される
+	vm.startSpawns(now.Add(time.Second))
+	vm.fireTimers(now.Add(time.Second))
+	vm.ProcessEvents()
+	if len(*calls) != 1 || len(vm.timers) != 0 {
+		t.Errorf("spawned function ran again: %d calls, %d timers", len(*calls), len(vm.timers))
+	}
+}
+
+// TestRunCommandLayers tests that layers asks the graphics system to capture the next
+// frame in a directory inside the output directory.
+func TestRunCommandLayers(t *testing.T) {
+	dir := t.TempDir()
+	vm := New([]opcode.OpCode{}, WithOutputDir(dir))
+	gs := newMockGraphicsSystem()
+	vm.SetGraphicsSystem(gs)
+
+	if _, err := vm.RunCommand("layers broken-z"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := vm.RunCommand("layers"); err != nil {
+		t.Fatal(err)
+	}
+	if len(gs.layerDirs) != 2 || gs.layerDirs[0] != filepath.Join(dir, "broken-z") {
+		t.Fatalf("layer dirs = %v, want broken-z and a default directory", gs.layerDirs)
+	}
+	if name := filepath.Base(gs.layerDirs[1]); !strings.HasPrefix(name, "layers-") || filepath.Dir(gs.layerDirs[1]) != dir {
+		t.Errorf("default directory = %s, want layers-<time> in the output directory", gs.layerDirs[1])
+	}
+
+	for _, line := range []string{"layers ../outside", "layers /tmp/x", "layers a b"} {
+		if _, err := vm.RunCommand(line); err == nil {
+			t.Errorf("RunCommand(%q) should fail", line)
+		}
+	}
+	noOutput := New([]opcode.OpCode{})
+	noOutput.SetGraphicsSystem(newMockGraphicsSystem())
+	if _, err := noOutput.RunCommand("layers"); !errors.Is(err, errNoOutputDir) {
+		t.Errorf("layers without an output directory = %v, want errNoOutputDir", err)
+	}
+}
+
+func TestParseCommandValue(t *testing.T) {
+	for arg, want := range map[string]any{"12": int64(12), "-3": int64(-3), "0.5": 0.5, "boss": "boss"} {
+		if got := parseCommandValue(arg); got != want {
+			t.Errorf("parseCommandValue(%q) = %#v, want %#v", arg, got, want)
+		}
+	}
+}
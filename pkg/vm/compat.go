@@ -0,0 +1,53 @@
+package vm
+
+import (
+	"strings"
+
+	"github.com/zurustar/son-et/pkg/compat"
+)
+
+// WithCompatMode sets the compatibility mode (--compat).
+//
+// In compat.FILLY97 mode:
+//   - Builtins added by son-et (compat.IsExtensionBuiltin) are not registered,
+//     so calling them is an undefined function error as in the original FILLY.
+//   - Arithmetic is always integer arithmetic (1/2 == 0, float operands are truncated).
+//   - Colors passed to TextColor, BgColor, SetPaintColor, SetColor and DrawRect are
+//     rounded to High Color precision (compat.RoundColor).
+//
+// Syntax extensions (floating point literals, extension event types) are rejected
+// by the compiler (compiler.CompileOptions.Compat).
+func WithCompatMode(mode compat.Mode) Option {
+	return func(vm *VM) {
+		vm.compat = mode
+	}
+}
+
+// CompatMode returns the compatibility mode of the VM.
+func (vm *VM) CompatMode() compat.Mode {
+	return vm.compat
+}
+
+// removeExtensionBuiltins unregisters son-et's extension builtins in strict mode.
+// Called after the default builtins are registered.
+func (vm *VM) removeExtensionBuiltins() {
+	if !vm.compat.Strict() {
+		return
+	}
+	vm.mu.Lock()
+	defer vm.mu.Unlock()
+	for name := range vm.builtins {
+		if compat.IsExtensionBuiltin(name) {
+			delete(vm.builtins, name)
+			delete(vm.builtinsLower, strings.ToLower(name))
+		}
+	}
+}
+
+// compatColor rounds a script color to High Color precision in strict mode.
+func (vm *VM) compatColor(c int) int {
+	if vm.compat.Strict() {
+		return compat.RoundColor(c)
+	}
+	return c
+}
@@ -0,0 +1,82 @@
+package vm
+
+import (
+	"testing"
+
+	"github.com/zurustar/son-et/pkg/compat"
+	"github.com/zurustar/son-et/pkg/opcode"
+)
+
+// TestCompatModeBuiltins tests that filly97 mode does not register son-et's extension builtins.
+func TestCompatModeBuiltins(t *testing.T) {
+	extended := New([]opcode.OpCode{})
+	strict := New([]opcode.OpCode{}, WithCompatMode(compat.FILLY97))
+
+	if strict.CompatMode() != compat.FILLY97 || extended.CompatMode() != compat.Extended {
+		t.Fatalf("CompatMode() = %v / %v", strict.CompatMode(), extended.CompatMode())
+	}
+
+	for _, name := range []string{"OnKey", "SetGamma", "SaveValue", "BindNote"} {
+		if _, ok := extended.builtins[name]; !ok {
+			t.Errorf("extended mode should register %s", name)
+		}
+		if _, ok := strict.builtins[name]; ok {
+			t.Errorf("filly97 mode should not register %s", name)
+		}
+		if _, ok := strict.builtinsLower[name]; ok {
+			t.Errorf("filly97 mode should not register %s in the lowercase index", name)
+		}
+	}
+	for _, name := range []string{"LoadPic", "ArraySize", "TextColor"} {
+		if _, ok := strict.builtins[name]; !ok {
+			t.Errorf("filly97 mode should keep %s", name)
+		}
+	}
+}
+
+// TestCompatModeArithmetic tests that filly97 mode always uses integer arithmetic.
+func TestCompatModeArithmetic(t *testing.T) {
+	tests := []struct {
+		op          string
+		left, right any
+		extended    any
+		strict      any
+	}{
+		{"/", int64(7), int64(2), int64(3), int64(3)},
+		{"/", float64(7), int64(2), float64(3.5), int64(3)},
+		{"*", float64(1.5), int64(3), float64(4.5), int64(3)},
+		{"%", float64(7.5), int64(2), float64(1.5), int64(1)},
+	}
+	extended := New([]opcode.OpCode{})
+	strict := New([]opcode.OpCode{}, WithCompatMode(compat.FILLY97))
+	for _, tt := range tests {
+		got, err := extended.executeArithmeticOp(tt.op, tt.left, tt.right)
+		if err != nil || got != tt.extended {
+			t.Errorf("extended: %v %s %v = %v (%v), want %v", tt.left, tt.op, tt.right, got, err, tt.extended)
+		}
+		got, err = strict.executeArithmeticOp(tt.op, tt.left, tt.right)
+		if err != nil || got != tt.strict {
+			t.Errorf("filly97: %v %s %v = %v (%v), want %v", tt.left, tt.op, tt.right, got, err, tt.strict)
+		}
+	}
+}
+
+// TestCompatModeColorRounding tests that filly97 mode rounds colors to High Color precision.
+func TestCompatModeColorRounding(t *testing.T) {
+	for _, mode := range []compat.Mode{compat.Extended, compat.FILLY97} {
+		gs := newMockGraphicsSystem()
+		vm := New([]opcode.OpCode{}, WithCompatMode(mode))
+		vm.SetGraphicsSystem(gs)
+
+		if _, err := vm.builtins["TextColor"](vm, []any{int64(0x123456)}); err != nil {
+			t.Fatalf("%v: TextColor failed: %v", mode, err)
+		}
+		want := 0x123456
+		if mode.Strict() {
+			want = compat.RoundColor(0x123456)
+		}
+		if gs.textColor != want {
+			t.Errorf("%v: text color = %v, want 0x%06X", mode, gs.textColor, want)
+		}
+	}
+}
@@ -0,0 +1,138 @@
+package vm
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Operator cues
+//
+// During a live run, the operator commands that change the show (seek, vol 0.5,
+// speed 2, pause, resume, spawn, chapter <name>, set) can be recorded with the
+// time since the run started into a cue file (--record-cues). The next run replays
+// the cue file (--play-cues), running each command at the same time through
+// RunCommand, so rehearsed manual interventions become automated.
+//
+// A cue file has one cue per line: the seconds since the start and the command,
+// e.g. "12.500 vol 0.5". Empty lines and lines starting with # are ignored, so
+// cue files can be edited by hand.
+
+// cueMode tells whether a command is recorded as a cue.
+type cueMode int
+
+const (
+	cueNever    cueMode = iota // queries (help, leaks, get) are not recorded
+	cueAlways                  // the command always changes the show
+	cueWithArgs                // the command changes the show only with arguments (vol 0.5, not vol)
+)
+
+// cueComment starts a comment line in a cue file.
+const cueComment = "#"
+
+// Cue is an operator command run at a time since the start of the run.
+type Cue struct {
+	At      time.Duration
+	Command string
+}
+
+// String formats the cue as a line of a cue file.
+func (c Cue) String() string {
+	return fmt.Sprintf("%.3f %s", c.At.Seconds(), c.Command)
+}
+
+// ParseCues reads a cue file. The cues are returned in order of time
+// (cues at the same time keep the order of the file).
+func ParseCues(r io.Reader) ([]Cue, error) {
+	var cues []Cue
+	scanner := bufio.NewScanner(r)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, cueComment) {
+			continue
+		}
+		at, command, _ := strings.Cut(text, " ")
+		seconds, err := strconv.ParseFloat(at, 64)
+		if err != nil || seconds < 0 {
+			return nil, fmt.Errorf("line %d: time must be non-negative seconds: %q", line, at)
+		}
+		command = strings.TrimSpace(command)
+		if command == "" {
+			return nil, fmt.Errorf("line %d: missing command", line)
+		}
+		cues = append(cues, Cue{At: time.Duration(seconds * float64(time.Second)), Command: command})
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	sort.SliceStable(cues, func(i, j int) bool { return cues[i].At < cues[j].At })
+	return cues, nil
+}
+
+// cueState holds the cues to replay and the destination of recorded cues.
+type cueState struct {
+	mu       sync.Mutex
+	start    time.Time // start of the run (origin of the cue times)
+	pending  []Cue     // cues not replayed yet, in order of time
+	recorder io.Writer // destination of recorded cues (nil if not recording)
+}
+
+// WithCues replays cues during the run (--play-cues) and records the operator
+// commands that change the show to record (--record-cues). Either may be nil.
+// Replayed cues are recorded too, so a rehearsal can add cues to a replayed file.
+func WithCues(cues []Cue, record io.Writer) Option {
+	return func(vm *VM) {
+		vm.cues.pending = append([]Cue(nil), cues...)
+		vm.cues.recorder = record
+	}
+}
+
+// startCues sets the origin of the cue times to the start of the run.
+func (vm *VM) startCues(now time.Time) {
+	vm.cues.mu.Lock()
+	defer vm.cues.mu.Unlock()
+	vm.cues.start = now
+}
+
+// recordCue writes an operator command that changed the show to the cue file.
+// A failed write is logged and does not fail the command.
+func (vm *VM) recordCue(fields []string) {
+	vm.cues.mu.Lock()
+	defer vm.cues.mu.Unlock()
+	if vm.cues.recorder == nil || vm.cues.start.IsZero() {
+		return
+	}
+	cue := Cue{At: vm.clock.Now().Sub(vm.cues.start), Command: strings.Join(fields, " ")}
+	if _, err := fmt.Fprintln(vm.cues.recorder, cue.String()); err != nil {
+		vm.log.Warn("Failed to record operator cue", "cue", cue.String(), "error", err)
+	}
+}
+
+// playCues runs the cues that are due at now.
+// Must be called on the VM goroutine.
+func (vm *VM) playCues(now time.Time) {
+	vm.cues.mu.Lock()
+	elapsed := now.Sub(vm.cues.start)
+	n := 0
+	for n < len(vm.cues.pending) && vm.cues.pending[n].At <= elapsed {
+		n++
+	}
+	due := vm.cues.pending[:n]
+	vm.cues.pending = vm.cues.pending[n:]
+	vm.cues.mu.Unlock()
+
+	// RunCommand records the cue again, so the lock is not held
+	for _, cue := range due {
+		out, err := vm.RunCommand(cue.Command)
+		if err != nil {
+			vm.log.Error("Operator cue failed", "cue", cue.String(), "error", err)
+			continue
+		}
+		vm.log.Debug("Operator cue played", "cue", cue.String(), "result", out)
+	}
+}
@@ -0,0 +1,138 @@
+package vm
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/zurustar/son-et/pkg/opcode"
+)
+
+func TestParseCues(t *testing.T) {
+	input := `# rehearsal 2026-10-15
+12.500 vol 0.5
+
+  3 seek 1200
+12.5 pause
+0.25   speed 2
+`
+	cues, err := ParseCues(strings.NewReader(input))
+	if err != nil {
+		t.Fatalf("ParseCues failed: %v", err)
+	}
+	want := []Cue{
+		{At: 250 * time.Millisecond, Command: "speed 2"},
+		{At: 3 * time.Second, Command: "seek 1200"},
+		{At: 12500 * time.Millisecond, Command: "vol 0.5"},
+		{At: 12500 * time.Millisecond, Command: "pause"},
+	}
+	if len(cues) != len(want) {
+		t.Fatalf("cues = %v, want %v", cues, want)
+	}
+	for i := range want {
+		if cues[i] != want[i] {
+			t.Errorf("cues[%d] = %v, want %v", i, cues[i], want[i])
+		}
+	}
+	if got := want[2].String(); got != "12.500 vol 0.5" {
+		t.Errorf("String() = %q, want 12.500 vol 0.5", got)
+	}
+}
+
+func TestParseCuesErrors(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{"soon vol 0.5", "line 1: time must be non-negative seconds"},
+		{"# ok\n-1 pause", "line 2: time must be non-negative seconds"},
+		{"1.5\n", "line 1: missing command"},
+	}
+	for _, tt := range tests {
+		if _, err := ParseCues(strings.NewReader(tt.input)); err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("ParseCues(%q) = %v, want %q", tt.input, err, tt.want)
+		}
+	}
+}
+
+// newCueTestVM returns a VM recording cues to the returned buffer, started at the clock's time.
+func newCueTestVM(cues []Cue) (*VM, *mockAudioSystem, *fixedClock, *bytes.Buffer) {
+	clock := &fixedClock{now: time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC)}
+	var record bytes.Buffer
+	vm := New([]opcode.OpCode{}, WithClock(clock), WithCues(cues, &record))
+	audio := newMockAudioSystem()
+	vm.SetAudioSystem(audio)
+	vm.startCues(clock.now)
+	return vm, audio, clock, &record
+}
+
+func TestRecordCues(t *testing.T) {
+	vm, _, clock, record := newCueTestVM(nil)
+
+	clock.now = clock.now.Add(1500 * time.Millisecond)
+	for _, line := range []string{"vol", "VOL 0.5", "help"} {
+		if _, err := vm.RunCommand(line); err != nil {
+			t.Fatalf("RunCommand(%q) failed: %v", line, err)
+		}
+	}
+	clock.now = clock.now.Add(2 * time.Second)
+	if _, err := vm.RunCommand("pause"); err != nil {
+		t.Fatalf("pause failed: %v", err)
+	}
+	if _, err := vm.RunCommand("seek 1 2"); err == nil {
+		t.Fatal("seek 1 2 should fail")
+	}
+
+	// Queries and failed commands are not recorded
+	if got, want := record.String(), "1.500 vol 0.5\n3.500 pause\n"; got != want {
+		t.Errorf("recorded %q, want %q", got, want)
+	}
+}
+
+func TestRecordCuesBeforeStart(t *testing.T) {
+	var record bytes.Buffer
+	vm := New([]opcode.OpCode{}, WithCues(nil, &record))
+	vm.SetAudioSystem(newMockAudioSystem())
+	if _, err := vm.RunCommand("pause"); err != nil {
+		t.Fatalf("pause failed: %v", err)
+	}
+	if record.Len() != 0 {
+		t.Errorf("recorded %q before the run started", record.String())
+	}
+}
+
+func TestPlayCues(t *testing.T) {
+	cues := []Cue{
+		{At: time.Second, Command: "seek 480"},
+		{At: time.Second, Command: "explode"},
+		{At: 3 * time.Second, Command: "vol 0.25"},
+	}
+	vm, audio, clock, record := newCueTestVM(cues)
+
+	vm.playCues(clock.now.Add(500 * time.Millisecond))
+	if len(audio.seeks) != 0 {
+		t.Fatalf("seeks = %v before the cue", audio.seeks)
+	}
+
+	clock.now = clock.now.Add(time.Second)
+	vm.playCues(clock.now)
+	if len(audio.seeks) != 1 || audio.seeks[0] != 480 {
+		t.Errorf("seeks = %v, want [480]", audio.seeks)
+	}
+	vm.playCues(clock.now)
+	if len(audio.seeks) != 1 {
+		t.Errorf("seeks = %v, a cue was played twice", audio.seeks)
+	}
+
+	clock.now = clock.now.Add(5 * time.Second)
+	vm.playCues(clock.now)
+	if audio.gains["master"] != 0.25 {
+		t.Errorf("master gain = %v, want 0.25", audio.gains["master"])
+	}
+
+	// Played cues are recorded as well, failed cues are not
+	if got, want := record.String(), "1.000 seek 480\n6.000 vol 0.25\n"; got != want {
+		t.Errorf("recorded %q, want %q", got, want)
+	}
+}
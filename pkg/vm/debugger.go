@@ -0,0 +1,167 @@
+package vm
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/zurustar/son-et/pkg/opcode"
+)
+
+// Debugger receives the breakpoints reached by DebugBreak("label").
+//
+// Break is called when a sequence reaches DebugBreak; the sequence stays paused
+// until Break returns (or the VM is stopped). Since the VM runs every sequence on
+// one goroutine, the other sequences are paused too, while the window keeps
+// drawing and audio keeps playing. Without a debugger DebugBreak is a no-op.
+type Debugger interface {
+	Break(bp Breakpoint)
+}
+
+// Breakpoint describes where a sequence stopped at DebugBreak.
+type Breakpoint struct {
+	Label    string         // argument of DebugBreak
+	Line     int            // source line number (0 if unknown)
+	Sequence int            // sequence number (0 outside mes(), otherwise the GetMesNo number)
+	Function string         // user function being executed (empty outside functions)
+	Locals   map[string]any // snapshot of the local variables
+	Trace    []TraceEntry   // last statements executed by the sequence, oldest first
+}
+
+// String formats the breakpoint and its local variables (sorted by name), one per line.
+func (bp Breakpoint) String() string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "DebugBreak %q", bp.Label)
+	if bp.Line > 0 {
+		fmt.Fprintf(&sb, " at line %d", bp.Line)
+	}
+	fmt.Fprintf(&sb, " (sequence %d", bp.Sequence)
+	if bp.Function != "" {
+		fmt.Fprintf(&sb, ", function %s", bp.Function)
+	}
+	sb.WriteString(")\n")
+
+	names := make([]string, 0, len(bp.Locals))
+	for name := range bp.Locals {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Fprintf(&sb, "  %s = %v\n", name, bp.Locals[name])
+	}
+	if len(bp.Trace) > 0 {
+		sb.WriteString("Last executed:\n")
+		for _, e := range bp.Trace {
+			fmt.Fprintf(&sb, "  %s\n", e)
+		}
+	}
+	return sb.String()
+}
+
+// WithDebugger attaches a debugger that is called at every DebugBreak.
+func WithDebugger(d Debugger) Option {
+	return func(vm *VM) {
+		vm.debugger = d
+	}
+}
+
+// ConsoleDebugger is a Debugger that prints each breakpoint and its local
+// variables to out and resumes when a line (Enter) is read from in.
+// If in reaches EOF, breakpoints are printed but no longer pause.
+type ConsoleDebugger struct {
+	in  *bufio.Reader
+	out io.Writer
+	mu  sync.Mutex
+}
+
+// NewConsoleDebugger creates a ConsoleDebugger (typically os.Stdin and os.Stderr).
+func NewConsoleDebugger(in io.Reader, out io.Writer) *ConsoleDebugger {
+	return &ConsoleDebugger{in: bufio.NewReader(in), out: out}
+}
+
+// Break prints the breakpoint and waits for Enter.
+func (d *ConsoleDebugger) Break(bp Breakpoint) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	fmt.Fprint(d.out, bp.String())
+	fmt.Fprint(d.out, "Press Enter to continue...")
+	_, _ = d.in.ReadString('\n')
+	fmt.Fprintln(d.out)
+}
+
+// executeDebugBreak executes an OpDebugBreak OpCode.
+// Args: [label, line int]
+//
+// In compat.FILLY97 mode DebugBreak is an extension builtin, so it is executed as
+// an ordinary call and reported as an undefined function.
+func (vm *VM) executeDebugBreak(op opcode.OpCode) (any, error) {
+	if vm.compat.Strict() {
+		args := append([]any{"DebugBreak"}, op.Args[:min(len(op.Args), 1)]...)
+		return vm.executeCall(opcode.OpCode{Cmd: opcode.Call, Args: args})
+	}
+
+	debugBreak := decodeDebugBreak(op)
+	label := ""
+	if debugBreak.hasLabel {
+		val, err := vm.evaluateValue(debugBreak.label)
+		if err != nil {
+			return nil, fmt.Errorf("failed to evaluate DebugBreak label: %w", err)
+		}
+		label = toString(val)
+	}
+	vm.debugBreak(label, debugBreak.line)
+	return nil, nil
+}
+
+// debugBreak pauses the running sequence until the debugger resumes it.
+// Does nothing when no debugger is attached.
+func (vm *VM) debugBreak(label string, line int) {
+	if vm.debugger == nil {
+		vm.log.Debug("DebugBreak ignored (no debugger attached)", "label", label, "line", line)
+		return
+	}
+
+	bp := Breakpoint{
+		Label:    label,
+		Line:     line,
+		Sequence: mainSequenceID,
+		Locals:   vm.localSnapshot(),
+		Trace:    vm.currentTrace(),
+	}
+	if vm.currentHandler != nil {
+		bp.Sequence = vm.currentHandler.Number
+	}
+	if len(vm.callStack) > 0 {
+		bp.Function = vm.callStack[len(vm.callStack)-1].FunctionName
+	}
+	vm.log.Info("DebugBreak: sequence paused", "label", label, "line", line, "sequence", bp.Sequence, "function", bp.Function)
+
+	// Let a timeout or Stop end the pause even if the debugger never returns
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		vm.debugger.Break(bp)
+	}()
+	select {
+	case <-done:
+		vm.log.Info("DebugBreak: sequence resumed", "label", label)
+	case <-vm.ctx.Done():
+	}
+}
+
+// localSnapshot copies the variables of the local scope (empty outside functions and mes()).
+func (vm *VM) localSnapshot() map[string]any {
+	locals := make(map[string]any)
+	if vm.localScope == nil {
+		return locals
+	}
+	for _, name := range vm.localScope.Keys() {
+		if value, ok := vm.localScope.GetLocal(name); ok {
+			locals[name] = value
+		}
+	}
+	return locals
+}
@@ -0,0 +1,155 @@
+package vm
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/zurustar/son-et/pkg/compat"
+	"github.com/zurustar/son-et/pkg/opcode"
+)
+
+// recordingDebugger records the breakpoints it reaches.
+type recordingDebugger struct {
+	breaks []Breakpoint
+}
+
+func (d *recordingDebugger) Break(bp Breakpoint) {
+	d.breaks = append(d.breaks, bp)
+}
+
+// blockingDebugger does not resume until release is closed.
+type blockingDebugger struct {
+	release chan struct{}
+}
+
+func (d *blockingDebugger) Break(bp Breakpoint) {
+	<-d.release
+}
+
+func TestDebugBreak(t *testing.T) {
+	t.Run("reports label, line and locals", func(t *testing.T) {
+		d := &recordingDebugger{}
+		vm := New([]opcode.OpCode{}, WithDebugger(d))
+		vm.localScope = NewScope(vm.globalScope)
+		vm.localScope.SetLocal("count", int64(3))
+		vm.globalScope.Set("g", int64(1))
+		_ = vm.PushStackFrame("update", vm.localScope)
+
+		op := opcode.OpCode{Cmd: opcode.DebugBreak, Args: []any{"loop", 12}}
+		if _, err := vm.Execute(op); err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+
+		if len(d.breaks) != 1 {
+			t.Fatalf("expected 1 breakpoint, got %d", len(d.breaks))
+		}
+		bp := d.breaks[0]
+		if bp.Label != "loop" || bp.Line != 12 || bp.Sequence != mainSequenceID || bp.Function != "update" {
+			t.Errorf("unexpected breakpoint: %+v", bp)
+		}
+		if bp.Locals["count"] != int64(3) {
+			t.Errorf("expected local count=3, got %v", bp.Locals)
+		}
+		if _, ok := bp.Locals["g"]; ok {
+			t.Error("globals should not be reported as locals")
+		}
+	})
+
+	t.Run("label expression and handler sequence", func(t *testing.T) {
+		d := &recordingDebugger{}
+		vm := New([]opcode.OpCode{}, WithDebugger(d))
+		vm.globalScope.Set("name", "fade")
+		vm.currentHandler = &EventHandler{Number: 4}
+
+		op := opcode.OpCode{Cmd: opcode.DebugBreak, Args: []any{opcode.Variable("name"), 5}}
+		_, _ = vm.Execute(op)
+		if len(d.breaks) != 1 || d.breaks[0].Label != "fade" || d.breaks[0].Sequence != 4 {
+			t.Errorf("unexpected breakpoints: %+v", d.breaks)
+		}
+	})
+
+	t.Run("builtin call without line", func(t *testing.T) {
+		d := &recordingDebugger{}
+		vm := New([]opcode.OpCode{}, WithDebugger(d))
+		if _, err := vm.builtins["DebugBreak"](vm, []any{"b"}); err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		if len(d.breaks) != 1 || d.breaks[0].Label != "b" || d.breaks[0].Line != 0 {
+			t.Errorf("unexpected breakpoints: %+v", d.breaks)
+		}
+	})
+
+	t.Run("no-op without debugger", func(t *testing.T) {
+		vm := New([]opcode.OpCode{})
+		op := opcode.OpCode{Cmd: opcode.DebugBreak, Args: []any{"x", 1}}
+		if result, err := vm.Execute(op); err != nil || result != nil {
+			t.Errorf("expected nil, nil; got %v, %v", result, err)
+		}
+	})
+
+	t.Run("cancel releases a paused sequence", func(t *testing.T) {
+		d := &blockingDebugger{release: make(chan struct{})}
+		defer close(d.release)
+		vm := New([]opcode.OpCode{}, WithDebugger(d))
+
+		done := make(chan struct{})
+		go func() {
+			_, _ = vm.Execute(opcode.OpCode{Cmd: opcode.DebugBreak, Args: []any{"x", 1}})
+			close(done)
+		}()
+		select {
+		case <-done:
+			t.Fatal("DebugBreak should pause until the debugger resumes")
+		case <-time.After(20 * time.Millisecond):
+		}
+		// Cancel the context as Stop and the timeout do
+		vm.cancel()
+		select {
+		case <-done:
+		case <-time.After(time.Second):
+			t.Fatal("cancellation should release the paused sequence")
+		}
+	})
+
+	t.Run("undefined in filly97 mode", func(t *testing.T) {
+		d := &recordingDebugger{}
+		vm := New([]opcode.OpCode{}, WithDebugger(d), WithCompatMode(compat.FILLY97))
+		op := opcode.OpCode{Cmd: opcode.DebugBreak, Args: []any{"x", 1}}
+		if _, err := vm.Execute(op); err == nil {
+			t.Error("expected an undefined function error in filly97 mode")
+		}
+		if len(d.breaks) != 0 {
+			t.Errorf("debugger should not be called in filly97 mode, got %+v", d.breaks)
+		}
+	})
+}
+
+func TestBreakpointString(t *testing.T) {
+	bp := Breakpoint{
+		Label:    "loop",
+		Line:     12,
+		Sequence: 2,
+		Function: "update",
+		Locals:   map[string]any{"y": int64(2), "x": "a"},
+	}
+	want := "DebugBreak \"loop\" at line 12 (sequence 2, function update)\n  x = a\n  y = 2\n"
+	if got := bp.String(); got != want {
+		t.Errorf("String() = %q, want %q", got, want)
+	}
+}
+
+func TestConsoleDebugger(t *testing.T) {
+	var out bytes.Buffer
+	d := NewConsoleDebugger(strings.NewReader("\n"), &out)
+	d.Break(Breakpoint{Label: "start", Locals: map[string]any{"n": int64(1)}})
+	if !strings.Contains(out.String(), `DebugBreak "start"`) || !strings.Contains(out.String(), "n = 1") {
+		t.Errorf("unexpected output: %q", out.String())
+	}
+	// After EOF the breakpoint is only printed, without pausing
+	d.Break(Breakpoint{Label: "next"})
+	if !strings.Contains(out.String(), `DebugBreak "next"`) {
+		t.Errorf("unexpected output: %q", out.String())
+	}
+}
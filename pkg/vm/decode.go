@@ -0,0 +1,497 @@
+package vm
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/zurustar/son-et/pkg/opcode"
+)
+
+// OpCode pre-decoding
+//
+// New decodes the whole program once: it sets Kind (Cmd as an integer) and Decoded
+// (the arguments as a typed struct) on a copy of every OpCode, including the ones
+// nested in expressions, blocks, switch cases and function bodies. Execute looks the
+// kind up in the dispatch table and each instruction reads Decoded, so running an
+// OpCode neither compares Cmd strings, nor checks and type-asserts its arguments, nor
+// lower-cases function names. Break, Continue and DefineFunction have no arguments to
+// decode, and WaitAny evaluates its arguments as they are.
+//
+// OpCodes built at run time (such as the handlers of SetTimer) are not decoded; the
+// same decode functions decode them on the spot, so they behave the same.
+
+// dispatchTable holds the function that executes each kind of OpCode
+// (set in init, as the functions refer back to Execute through it).
+var dispatchTable [opcode.NumKinds]func(vm *VM, op opcode.OpCode) (any, error)
+
+func init() {
+	dispatchTable = [opcode.NumKinds]func(vm *VM, op opcode.OpCode) (any, error){
+		opcode.KindAssign:               (*VM).executeAssign,
+		opcode.KindArrayAssign:          (*VM).executeArrayAssign,
+		opcode.KindCall:                 (*VM).executeCall,
+		opcode.KindBinaryOp:             (*VM).executeBinaryOp,
+		opcode.KindUnaryOp:              (*VM).executeUnaryOp,
+		opcode.KindArrayAccess:          (*VM).executeArrayAccess,
+		opcode.KindIf:                   (*VM).executeIf,
+		opcode.KindFor:                  (*VM).executeFor,
+		opcode.KindWhile:                (*VM).executeWhile,
+		opcode.KindSwitch:               (*VM).executeSwitch,
+		opcode.KindBreak:                (*VM).executeBreak,
+		opcode.KindContinue:             (*VM).executeContinue,
+		opcode.KindRegisterEventHandler: (*VM).executeRegisterEventHandler,
+		opcode.KindWait:                 (*VM).executeWait,
+		opcode.KindSetStep:              (*VM).executeSetStep,
+		// Function definitions are processed in collectFunctionDefinitions
+		opcode.KindDefineFunction: func(*VM, opcode.OpCode) (any, error) { return nil, nil },
+		opcode.KindDebugBreak:     (*VM).executeDebugBreak,
+		opcode.KindWaitAny:        (*VM).executeWaitAny,
+		opcode.KindTry:            (*VM).executeTry,
+	}
+}
+
+// opKind returns the kind of an OpCode (computed from Cmd if it was not decoded).
+func opKind(op opcode.OpCode) opcode.Kind {
+	if op.Kind != opcode.KindUnknown {
+		return op.Kind
+	}
+	return op.Cmd.Kind()
+}
+
+// callArgs is a decoded Call.
+type callArgs struct {
+	name     string
+	lower    string // For the case-insensitive lookup of built-in and user functions
+	isReturn bool   // A return statement
+	args     []any  // Argument expressions
+}
+
+// assignArgs is a decoded Assign.
+type assignArgs struct {
+	name  string
+	value any
+}
+
+// arrayAssignArgs is a decoded ArrayAssign.
+type arrayAssignArgs struct {
+	name  string
+	index any
+	value any
+}
+
+// operatorArgs is a decoded BinaryOp or UnaryOp (a UnaryOp has no left operand).
+type operatorArgs struct {
+	operator    string
+	left, right any
+}
+
+// arrayAccessArgs is a decoded ArrayAccess.
+type arrayAccessArgs struct {
+	array any
+	name  string // Name of the array variable ("" if the array is an expression)
+	index any
+}
+
+// ifArgs is a decoded If.
+type ifArgs struct {
+	condition any
+	then      []opcode.OpCode
+	otherwise []opcode.OpCode
+}
+
+// loopArgs is a decoded For or While (a While has no init and post blocks).
+type loopArgs struct {
+	init      []opcode.OpCode
+	condition any // nil for a loop without a condition
+	post      []opcode.OpCode
+	body      []opcode.OpCode
+}
+
+// switchArgs is a decoded Switch.
+type switchArgs struct {
+	value        any
+	cases        []switchCase
+	defaultBlock []opcode.OpCode // nil if there is no default block
+}
+
+// switchCase is a case clause of a decoded Switch.
+type switchCase struct {
+	value any
+	body  []opcode.OpCode
+}
+
+// handlerArgs is a decoded RegisterEventHandler.
+type handlerArgs struct {
+	eventType    EventType
+	body         []opcode.OpCode
+	hasStepBlock bool // The body contains a step block (see containsOpSetStep)
+}
+
+// countArgs is a decoded Wait or SetStep.
+type countArgs struct {
+	count    any
+	hasCount bool
+}
+
+// debugBreakArgs is a decoded DebugBreak.
+type debugBreakArgs struct {
+	label    any
+	hasLabel bool
+	line     int
+}
+
+// tryArgs is a decoded Try.
+type tryArgs struct {
+	try      []opcode.OpCode
+	catch    []opcode.OpCode
+	errorVar string
+}
+
+// decodeCall returns the arguments of a Call (decoding them on the spot if the OpCode was not decoded).
+func decodeCall(op opcode.OpCode) (*callArgs, error) {
+	if c, ok := op.Decoded.(*callArgs); ok {
+		return c, nil
+	}
+	if len(op.Args) < 1 {
+		return nil, fmt.Errorf("OpCall requires at least 1 argument (function name)")
+	}
+	name, ok := op.Args[0].(string)
+	if !ok {
+		return nil, fmt.Errorf("OpCall first argument must be string, got %T", op.Args[0])
+	}
+	return &callArgs{
+		name:     name,
+		lower:    strings.ToLower(name),
+		isReturn: name == "return",
+		args:     op.Args[1:],
+	}, nil
+}
+
+// decodeAssign returns the arguments of an Assign.
+func decodeAssign(op opcode.OpCode) (*assignArgs, error) {
+	if a, ok := op.Decoded.(*assignArgs); ok {
+		return a, nil
+	}
+	if len(op.Args) < 2 {
+		return nil, fmt.Errorf("OpAssign requires 2 arguments, got %d", len(op.Args))
+	}
+	name, ok := op.Args[0].(opcode.Variable)
+	if !ok {
+		return nil, fmt.Errorf("OpAssign first argument must be Variable, got %T", op.Args[0])
+	}
+	return &assignArgs{name: string(name), value: op.Args[1]}, nil
+}
+
+// decodeArrayAssign returns the arguments of an ArrayAssign.
+func decodeArrayAssign(op opcode.OpCode) (*arrayAssignArgs, error) {
+	if a, ok := op.Decoded.(*arrayAssignArgs); ok {
+		return a, nil
+	}
+	if len(op.Args) < 3 {
+		return nil, fmt.Errorf("OpArrayAssign requires 3 arguments, got %d", len(op.Args))
+	}
+	name, ok := op.Args[0].(opcode.Variable)
+	if !ok {
+		return nil, fmt.Errorf("OpArrayAssign first argument must be Variable, got %T", op.Args[0])
+	}
+	return &arrayAssignArgs{name: string(name), index: op.Args[1], value: op.Args[2]}, nil
+}
+
+// decodeBinaryOp returns the arguments of a BinaryOp.
+func decodeBinaryOp(op opcode.OpCode) (*operatorArgs, error) {
+	if o, ok := op.Decoded.(*operatorArgs); ok {
+		return o, nil
+	}
+	if len(op.Args) < 3 {
+		return nil, fmt.Errorf("OpBinaryOp requires 3 arguments, got %d", len(op.Args))
+	}
+	operator, ok := op.Args[0].(string)
+	if !ok {
+		return nil, fmt.Errorf("OpBinaryOp operator must be string, got %T", op.Args[0])
+	}
+	return &operatorArgs{operator: operator, left: op.Args[1], right: op.Args[2]}, nil
+}
+
+// decodeUnaryOp returns the arguments of a UnaryOp (the operand is in right).
+func decodeUnaryOp(op opcode.OpCode) (*operatorArgs, error) {
+	if o, ok := op.Decoded.(*operatorArgs); ok {
+		return o, nil
+	}
+	if len(op.Args) < 2 {
+		return nil, fmt.Errorf("OpUnaryOp requires 2 arguments, got %d", len(op.Args))
+	}
+	operator, ok := op.Args[0].(string)
+	if !ok {
+		return nil, fmt.Errorf("OpUnaryOp operator must be string, got %T", op.Args[0])
+	}
+	return &operatorArgs{operator: operator, right: op.Args[1]}, nil
+}
+
+// decodeArrayAccess returns the arguments of an ArrayAccess.
+func decodeArrayAccess(op opcode.OpCode) (*arrayAccessArgs, error) {
+	if a, ok := op.Decoded.(*arrayAccessArgs); ok {
+		return a, nil
+	}
+	if len(op.Args) < 2 {
+		return nil, fmt.Errorf("OpArrayAccess requires 2 arguments, got %d", len(op.Args))
+	}
+	name, _ := op.Args[0].(opcode.Variable)
+	return &arrayAccessArgs{array: op.Args[0], name: string(name), index: op.Args[1]}, nil
+}
+
+// decodeIf returns the arguments of an If.
+func decodeIf(op opcode.OpCode) (*ifArgs, error) {
+	if a, ok := op.Decoded.(*ifArgs); ok {
+		return a, nil
+	}
+	if len(op.Args) < 2 {
+		return nil, fmt.Errorf("OpIf requires at least 2 arguments, got %d", len(op.Args))
+	}
+	then, ok := op.Args[1].([]opcode.OpCode)
+	if !ok {
+		return nil, fmt.Errorf("OpIf then block must be []OpCode, got %T", op.Args[1])
+	}
+	a := &ifArgs{condition: op.Args[0], then: then}
+	if len(op.Args) >= 3 {
+		if a.otherwise, ok = op.Args[2].([]opcode.OpCode); !ok {
+			return nil, fmt.Errorf("OpIf else block must be []OpCode, got %T", op.Args[2])
+		}
+	}
+	return a, nil
+}
+
+// decodeFor returns the arguments of a For.
+func decodeFor(op opcode.OpCode) (*loopArgs, error) {
+	if a, ok := op.Decoded.(*loopArgs); ok {
+		return a, nil
+	}
+	if len(op.Args) < 4 {
+		return nil, fmt.Errorf("OpFor requires 4 arguments, got %d", len(op.Args))
+	}
+	body, ok := op.Args[3].([]opcode.OpCode)
+	if !ok {
+		return nil, fmt.Errorf("OpFor body must be []OpCode, got %T", op.Args[3])
+	}
+	init, _ := op.Args[0].([]opcode.OpCode)
+	post, _ := op.Args[2].([]opcode.OpCode)
+	return &loopArgs{init: init, condition: op.Args[1], post: post, body: body}, nil
+}
+
+// decodeWhile returns the arguments of a While.
+func decodeWhile(op opcode.OpCode) (*loopArgs, error) {
+	if a, ok := op.Decoded.(*loopArgs); ok {
+		return a, nil
+	}
+	if len(op.Args) < 2 {
+		return nil, fmt.Errorf("OpWhile requires 2 arguments, got %d", len(op.Args))
+	}
+	body, ok := op.Args[1].([]opcode.OpCode)
+	if !ok {
+		return nil, fmt.Errorf("OpWhile body must be []OpCode, got %T", op.Args[1])
+	}
+	return &loopArgs{condition: op.Args[0], body: body}, nil
+}
+
+// decodeSwitch returns the arguments of a Switch.
+// Case clauses that are not map[string]any are skipped.
+func decodeSwitch(op opcode.OpCode) (*switchArgs, error) {
+	if a, ok := op.Decoded.(*switchArgs); ok {
+		return a, nil
+	}
+	if len(op.Args) < 2 {
+		return nil, fmt.Errorf("OpSwitch requires at least 2 arguments, got %d", len(op.Args))
+	}
+	clauses, ok := op.Args[1].([]any)
+	if !ok {
+		return nil, fmt.Errorf("OpSwitch cases must be []any, got %T", op.Args[1])
+	}
+	a := &switchArgs{value: op.Args[0], cases: make([]switchCase, 0, len(clauses))}
+	for _, c := range clauses {
+		clause, ok := c.(map[string]any)
+		if !ok {
+			continue
+		}
+		body, ok := clause["body"].([]opcode.OpCode)
+		if !ok {
+			return nil, fmt.Errorf("case body must be []OpCode, got %T", clause["body"])
+		}
+		a.cases = append(a.cases, switchCase{value: clause["value"], body: body})
+	}
+	if len(op.Args) >= 3 && op.Args[2] != nil {
+		if a.defaultBlock, ok = op.Args[2].([]opcode.OpCode); !ok {
+			return nil, fmt.Errorf("OpSwitch default block must be []OpCode, got %T", op.Args[2])
+		}
+		if a.defaultBlock == nil {
+			a.defaultBlock = []opcode.OpCode{}
+		}
+	}
+	return a, nil
+}
+
+// decodeRegisterEventHandler returns the arguments of a RegisterEventHandler.
+func decodeRegisterEventHandler(op opcode.OpCode) (*handlerArgs, error) {
+	if a, ok := op.Decoded.(*handlerArgs); ok {
+		return a, nil
+	}
+	if len(op.Args) < 2 {
+		return nil, fmt.Errorf("OpRegisterEventHandler requires 2 arguments, got %d", len(op.Args))
+	}
+	eventTypeStr, ok := op.Args[0].(string)
+	if !ok {
+		return nil, fmt.Errorf("OpRegisterEventHandler event type must be string, got %T", op.Args[0])
+	}
+	eventType := EventType(eventTypeStr)
+	switch eventType {
+	case EventTIME, EventMIDI_TIME, EventMIDI_END, EventLBDOWN, EventRBDOWN, EventRBDBLCLK, EventKEY, EventCLICK, EventCHAR, EventUSER, EventFADE_END, EventMIDI_NOTE, EventMIDI_LYRIC, EventPIC_READY, EventTIMER:
+		// Valid event type
+	default:
+		return nil, fmt.Errorf("unknown event type: %s", eventTypeStr)
+	}
+	body, ok := op.Args[1].([]opcode.OpCode)
+	if !ok {
+		return nil, fmt.Errorf("OpRegisterEventHandler body must be []OpCode, got %T", op.Args[1])
+	}
+	return &handlerArgs{eventType: eventType, body: body, hasStepBlock: containsOpSetStep(body)}, nil
+}
+
+// decodeCount returns the arguments of a Wait or SetStep (the count is optional for Wait).
+func decodeCount(op opcode.OpCode) *countArgs {
+	if a, ok := op.Decoded.(*countArgs); ok {
+		return a
+	}
+	if len(op.Args) < 1 {
+		return &countArgs{}
+	}
+	return &countArgs{count: op.Args[0], hasCount: true}
+}
+
+// decodeDebugBreak returns the arguments of a DebugBreak.
+func decodeDebugBreak(op opcode.OpCode) *debugBreakArgs {
+	if a, ok := op.Decoded.(*debugBreakArgs); ok {
+		return a
+	}
+	a := &debugBreakArgs{}
+	if len(op.Args) >= 1 {
+		a.label, a.hasLabel = op.Args[0], true
+	}
+	if len(op.Args) >= 2 {
+		if l, ok := toInt64(op.Args[1]); ok {
+			a.line = int(l)
+		}
+	}
+	return a
+}
+
+// decodeTry returns the arguments of a Try.
+func decodeTry(op opcode.OpCode) (*tryArgs, error) {
+	if a, ok := op.Decoded.(*tryArgs); ok {
+		return a, nil
+	}
+	if len(op.Args) < 3 {
+		return nil, fmt.Errorf("OpTry requires 3 arguments, got %d", len(op.Args))
+	}
+	tryBlock, ok := op.Args[0].([]opcode.OpCode)
+	if !ok && op.Args[0] != nil {
+		return nil, fmt.Errorf("OpTry try block must be []OpCode, got %T", op.Args[0])
+	}
+	catchBlock, ok := op.Args[1].([]opcode.OpCode)
+	if !ok && op.Args[1] != nil {
+		return nil, fmt.Errorf("OpTry catch block must be []OpCode, got %T", op.Args[1])
+	}
+	errorVar, _ := op.Args[2].(opcode.Variable)
+	return &tryArgs{try: tryBlock, catch: catchBlock, errorVar: string(errorVar)}, nil
+}
+
+// decodeOpCodes returns a decoded copy of ops and of every OpCode in it (blocks and
+// expressions). The caller's OpCodes are not modified.
+// OpCodes with malformed arguments are left without Decoded, so that the error is
+// reported when they run, as before.
+func decodeOpCodes(ops []opcode.OpCode) []opcode.OpCode {
+	if ops == nil {
+		return nil
+	}
+	decoded := make([]opcode.OpCode, len(ops))
+	for i, op := range ops {
+		decoded[i] = decodeOpCode(op)
+	}
+	return decoded
+}
+
+// decodeOpCode returns a decoded copy of an OpCode.
+func decodeOpCode(op opcode.OpCode) opcode.OpCode {
+	op.Kind = op.Cmd.Kind()
+	if op.Args != nil {
+		args := make([]any, len(op.Args))
+		for i, arg := range op.Args {
+			args[i] = decodeValue(arg)
+		}
+		op.Args = args
+	}
+
+	var decoded any
+	var err error
+	switch op.Kind {
+	case opcode.KindCall:
+		decoded, err = decodeCall(op)
+	case opcode.KindAssign:
+		decoded, err = decodeAssign(op)
+	case opcode.KindArrayAssign:
+		decoded, err = decodeArrayAssign(op)
+	case opcode.KindBinaryOp:
+		decoded, err = decodeBinaryOp(op)
+	case opcode.KindUnaryOp:
+		decoded, err = decodeUnaryOp(op)
+	case opcode.KindArrayAccess:
+		decoded, err = decodeArrayAccess(op)
+	case opcode.KindIf:
+		decoded, err = decodeIf(op)
+	case opcode.KindFor:
+		decoded, err = decodeFor(op)
+	case opcode.KindWhile:
+		decoded, err = decodeWhile(op)
+	case opcode.KindSwitch:
+		decoded, err = decodeSwitch(op)
+	case opcode.KindRegisterEventHandler:
+		decoded, err = decodeRegisterEventHandler(op)
+	case opcode.KindWait, opcode.KindSetStep:
+		decoded = decodeCount(op)
+	case opcode.KindDebugBreak:
+		decoded = decodeDebugBreak(op)
+	case opcode.KindTry:
+		decoded, err = decodeTry(op)
+	}
+	if err == nil {
+		op.Decoded = decoded
+	}
+	return op
+}
+
+// decodeValue returns a copy of an argument with the OpCodes in it decoded
+// (the case clauses of a switch and the parameter defaults of a function are
+// maps inside a []any).
+func decodeValue(v any) any {
+	switch val := v.(type) {
+	case opcode.OpCode:
+		return decodeOpCode(val)
+	case []opcode.OpCode:
+		return decodeOpCodes(val)
+	case []any:
+		if val == nil {
+			return val
+		}
+		items := make([]any, len(val))
+		for i, item := range val {
+			items[i] = decodeValue(item)
+		}
+		return items
+	case map[string]any:
+		if val == nil {
+			return val
+		}
+		m := make(map[string]any, len(val))
+		for key, item := range val {
+			m[key] = decodeValue(item)
+		}
+		return m
+	}
+	return v
+}
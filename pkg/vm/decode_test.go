@@ -0,0 +1,194 @@
+package vm
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/zurustar/son-et/pkg/opcode"
+)
+
+// decodeTestProgram returns a program with OpCodes nested in expressions, blocks,
+// switch cases and function bodies.
+func decodeTestProgram() []opcode.OpCode {
+	return []opcode.OpCode{
+		{Cmd: opcode.DefineFunction, Args: []any{"f", []any{}, []opcode.OpCode{
+			{Cmd: opcode.Switch, Args: []any{
+				opcode.Variable("x"),
+				[]any{map[string]any{
+					"value": int64(1),
+					"body": []opcode.OpCode{
+						{Cmd: opcode.Call, Args: []any{"StrLen", "abc"}},
+					},
+				}},
+				[]opcode.OpCode{},
+			}},
+		}}},
+		{Cmd: opcode.Assign, Args: []any{
+			opcode.Variable("y"),
+			opcode.OpCode{Cmd: opcode.BinaryOp, Args: []any{"+", int64(1), int64(2)}},
+		}},
+	}
+}
+
+// TestNewDecodesOpCodes tests that New sets the kind and the decoded arguments of every
+// OpCode, including nested ones, on its own copy of the program.
+func TestNewDecodesOpCodes(t *testing.T) {
+	ops := decodeTestProgram()
+	vm := New(ops)
+
+	if !reflect.DeepEqual(ops, decodeTestProgram()) {
+		t.Errorf("New modified the caller's OpCodes: %#v", ops)
+	}
+
+	decoded := vm.opcodes
+	body := decoded[0].Args[2].([]opcode.OpCode)
+	if decoded[0].Kind != opcode.KindDefineFunction || body[0].Kind != opcode.KindSwitch {
+		t.Errorf("kinds = %d, %d, want DefineFunction, Switch", decoded[0].Kind, body[0].Kind)
+	}
+
+	sw, ok := body[0].Decoded.(*switchArgs)
+	if !ok || len(sw.cases) != 1 || sw.value != opcode.Variable("x") || sw.defaultBlock == nil {
+		t.Fatalf("switch was not decoded: %#v", body[0].Decoded)
+	}
+	call, ok := sw.cases[0].body[0].Decoded.(*callArgs)
+	if !ok {
+		t.Fatalf("call in a switch case was not decoded: %#v", sw.cases[0].body[0])
+	}
+	if call.name != "StrLen" || call.lower != "strlen" || call.isReturn || len(call.args) != 1 {
+		t.Errorf("decoded call = %+v", call)
+	}
+
+	assign, ok := decoded[1].Decoded.(*assignArgs)
+	if !ok || assign.name != "y" {
+		t.Fatalf("assignment was not decoded: %#v", decoded[1])
+	}
+	expr, ok := assign.value.(opcode.OpCode)
+	if !ok || expr.Kind != opcode.KindBinaryOp {
+		t.Fatalf("expression in the assignment was not decoded: %#v", assign.value)
+	}
+	if binary, ok := expr.Decoded.(*operatorArgs); !ok || binary.operator != "+" || binary.left != int64(1) {
+		t.Errorf("decoded binary operation = %#v", expr.Decoded)
+	}
+}
+
+// TestDecodeOpCodeKinds tests that the OpCodes with arguments are decoded into their typed form.
+func TestDecodeOpCodeKinds(t *testing.T) {
+	body := []opcode.OpCode{{Cmd: opcode.SetStep, Args: []any{int64(2)}}, {Cmd: opcode.Wait, Args: []any{int64(1)}}}
+	tests := []struct {
+		op   opcode.OpCode
+		want any
+	}{
+		{opcode.OpCode{Cmd: opcode.ArrayAssign, Args: []any{opcode.Variable("a"), int64(1), "v"}}, &arrayAssignArgs{name: "a", index: int64(1), value: "v"}},
+		{opcode.OpCode{Cmd: opcode.UnaryOp, Args: []any{"-", int64(3)}}, &operatorArgs{operator: "-", right: int64(3)}},
+		{opcode.OpCode{Cmd: opcode.ArrayAccess, Args: []any{opcode.Variable("a"), int64(0)}}, &arrayAccessArgs{array: opcode.Variable("a"), name: "a", index: int64(0)}},
+		{opcode.OpCode{Cmd: opcode.If, Args: []any{int64(1), []opcode.OpCode{}}}, &ifArgs{condition: int64(1), then: []opcode.OpCode{}}},
+		{opcode.OpCode{Cmd: opcode.For, Args: []any{nil, nil, nil, []opcode.OpCode{}}}, &loopArgs{body: []opcode.OpCode{}}},
+		{opcode.OpCode{Cmd: opcode.While, Args: []any{int64(1), []opcode.OpCode{}}}, &loopArgs{condition: int64(1), body: []opcode.OpCode{}}},
+		{opcode.OpCode{Cmd: opcode.Wait}, &countArgs{}},
+		{opcode.OpCode{Cmd: opcode.SetStep, Args: []any{int64(4)}}, &countArgs{count: int64(4), hasCount: true}},
+		{opcode.OpCode{Cmd: opcode.DebugBreak, Args: []any{"here", 12}}, &debugBreakArgs{label: "here", hasLabel: true, line: 12}},
+		{opcode.OpCode{Cmd: opcode.Try, Args: []any{nil, nil, opcode.Variable("e")}}, &tryArgs{errorVar: "e"}},
+	}
+	for _, tt := range tests {
+		if got := decodeOpCode(tt.op).Decoded; !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("decode %s = %#v, want %#v", tt.op.Cmd, got, tt.want)
+		}
+	}
+
+	handler := decodeOpCode(opcode.OpCode{Cmd: opcode.RegisterEventHandler, Args: []any{"TIME", body}})
+	if h, ok := handler.Decoded.(*handlerArgs); !ok || h.eventType != EventTIME || !h.hasStepBlock || len(h.body) != 2 || h.body[0].Kind != opcode.KindSetStep {
+		t.Errorf("decoded handler registration = %#v", handler.Decoded)
+	}
+	if op := decodeOpCode(opcode.OpCode{Cmd: opcode.RegisterEventHandler, Args: []any{"NOSUCH", body}}); op.Decoded != nil {
+		t.Errorf("handler for an unknown event was decoded: %#v", op.Decoded)
+	}
+}
+
+// TestExecuteUndecodedOpCode tests that OpCodes built at run time, which were not
+// decoded by New, execute the same as decoded ones.
+func TestExecuteUndecodedOpCode(t *testing.T) {
+	vm := New(nil)
+	assignLen := func(name string) opcode.OpCode {
+		return opcode.OpCode{Cmd: opcode.Assign, Args: []any{
+			opcode.Variable(name),
+			opcode.OpCode{Cmd: opcode.Call, Args: []any{"strlen", "abcd"}},
+		}}
+	}
+
+	if _, err := vm.Execute(assignLen("x")); err != nil {
+		t.Fatalf("undecoded: %v", err)
+	}
+	if _, err := vm.Execute(decodeOpCode(assignLen("y"))); err != nil {
+		t.Fatalf("decoded: %v", err)
+	}
+	for _, name := range []string{"x", "y"} {
+		if got, _ := vm.GetCurrentScope().Get(name); got != int64(4) {
+			t.Errorf("%s = %v, want 4", name, got)
+		}
+	}
+}
+
+// TestExecuteUnknownKind tests that an unknown command is still reported as an error.
+func TestExecuteUnknownKind(t *testing.T) {
+	vm := New(nil)
+	if _, err := vm.Execute(opcode.OpCode{Cmd: "NoSuchCmd"}); err == nil {
+		t.Error("expected an error for an unknown command")
+	}
+}
+
+// TestDecodeInvalidOpCode tests that malformed OpCodes are left undecoded, so the
+// error is reported when they run.
+func TestDecodeInvalidOpCode(t *testing.T) {
+	op := decodeOpCode(opcode.OpCode{Cmd: opcode.Call, Args: []any{int64(1)}})
+	if op.Decoded != nil {
+		t.Errorf("invalid call was decoded: %#v", op.Decoded)
+	}
+	if _, err := New(nil).Execute(op); err == nil {
+		t.Error("expected an error for a call without a function name")
+	}
+}
+
+// benchmarkProgram returns a loop of the statements scripts run most: a for loop with
+// assignments, arithmetic, comparisons, an if/else, array accesses and a built-in call.
+func benchmarkProgram() []opcode.OpCode {
+	i := opcode.Variable("i")
+	binary := func(operator string, left, right any) opcode.OpCode {
+		return opcode.OpCode{Cmd: opcode.BinaryOp, Args: []any{operator, left, right}}
+	}
+	return []opcode.OpCode{{Cmd: opcode.For, Args: []any{
+		[]opcode.OpCode{{Cmd: opcode.Assign, Args: []any{i, int64(0)}}},
+		binary("<", i, int64(1000)),
+		[]opcode.OpCode{{Cmd: opcode.Assign, Args: []any{i, binary("+", i, int64(1))}}},
+		[]opcode.OpCode{
+			{Cmd: opcode.ArrayAssign, Args: []any{opcode.Variable("a"), binary("%", i, int64(16)), i}},
+			{Cmd: opcode.If, Args: []any{
+				binary("==", binary("%", i, int64(2)), int64(0)),
+				[]opcode.OpCode{{Cmd: opcode.Assign, Args: []any{opcode.Variable("n"), opcode.OpCode{Cmd: opcode.Call, Args: []any{"StrLen", "abc"}}}}},
+				[]opcode.OpCode{{Cmd: opcode.Assign, Args: []any{opcode.Variable("n"), opcode.OpCode{Cmd: opcode.ArrayAccess, Args: []any{opcode.Variable("a"), int64(3)}}}}},
+			}},
+		},
+	}}}
+}
+
+// BenchmarkExecuteLoop measures the opcode throughput of benchmarkProgram, pre-decoded
+// by New and undecoded (every OpCode decoded on the spot, as before pre-decoding).
+func BenchmarkExecuteLoop(b *testing.B) {
+	b.Run("predecoded", func(b *testing.B) {
+		vm := New(benchmarkProgram(), WithTraceSize(0))
+		loop := vm.opcodes[0]
+		for b.Loop() {
+			if _, err := vm.Execute(loop); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+	b.Run("undecoded", func(b *testing.B) {
+		vm := New(nil, WithTraceSize(0))
+		loop := benchmarkProgram()[0]
+		for b.Loop() {
+			if _, err := vm.Execute(loop); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
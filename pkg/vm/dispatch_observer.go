@@ -0,0 +1,39 @@
+package vm
+
+// イベント処理の観測（--debug-state-diff）
+// VMは MIDI_TIME イベントのティックを記録し、イベントを1つ処理するたびに観測者に最後のMIDIティックを渡す。
+// 描画側はこれを使い、スプライトの変更がどのMIDIティックで行われたかを記録する（graphics.ObserveState）。
+
+// SetDispatchObserver sets a function called after each dispatched event with the
+// MIDI tick of the last MIDI_TIME event (-1 before the first one).
+// It runs on the VM goroutine while no handler is executing, so the observer sees
+// the state the event left behind. Call it before Run; nil removes the observer.
+func (vm *VM) SetDispatchObserver(fn func(midiTick int)) {
+	vm.dispatchObserver = fn
+}
+
+// SetTickObserver sets a function called with the tick count (see Progress) after each
+// counted tick. It runs on the VM goroutine once the tick's handlers have finished, so the
+// observer sees the state the tick left behind regardless of how fast the VM runs.
+// Call it before Run; nil removes the observer.
+func (vm *VM) SetTickObserver(fn func(ticks int64)) {
+	vm.tickObserver = fn
+}
+
+// MIDITick returns the MIDI tick of the last dispatched MIDI_TIME event,
+// or -1 if none has been dispatched yet.
+func (vm *VM) MIDITick() int {
+	return int(vm.midiTick.Load())
+}
+
+// observeDispatch records the tick of a MIDI_TIME event and notifies the dispatch observer.
+func (vm *VM) observeDispatch(event *Event) {
+	if event.Type == EventMIDI_TIME {
+		if tick, ok := toInt64(event.Params["Tick"]); ok {
+			vm.midiTick.Store(tick)
+		}
+	}
+	if vm.dispatchObserver != nil {
+		vm.dispatchObserver(vm.MIDITick())
+	}
+}
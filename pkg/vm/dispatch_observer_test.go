@@ -0,0 +1,45 @@
+package vm
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/zurustar/son-et/pkg/opcode"
+)
+
+// TestDispatchObserver tests that the observer receives the tick of the last MIDI_TIME event after each event.
+func TestDispatchObserver(t *testing.T) {
+	var ticks []int
+	vm := New([]opcode.OpCode{})
+	vm.SetDispatchObserver(func(tick int) { ticks = append(ticks, tick) })
+	if vm.MIDITick() != -1 {
+		t.Errorf("MIDITick before MIDI_TIME = %d, want -1", vm.MIDITick())
+	}
+
+	dispatchEvents(t, vm,
+		NewEvent(EventTIME),
+		NewEventWithParams(EventMIDI_TIME, map[string]any{"Tick": 480}),
+		NewEvent(EventKEY),
+		NewEventWithParams(EventMIDI_TIME, map[string]any{"Tick": int64(960)}),
+	)
+	if want := []int{-1, 480, 480, 960}; !reflect.DeepEqual(ticks, want) {
+		t.Errorf("observed ticks = %v, want %v", ticks, want)
+	}
+	if vm.MIDITick() != 960 {
+		t.Errorf("MIDITick = %d, want 960", vm.MIDITick())
+	}
+}
+
+// TestTickObserver tests that the observer receives the tick count after each counted tick only.
+func TestTickObserver(t *testing.T) {
+	var ticks []int64
+	vm := New([]opcode.OpCode{})
+	vm.SetTickObserver(func(n int64) { ticks = append(ticks, n) })
+
+	for _, event := range []*Event{NewEvent(EventTIME), NewEvent(EventKEY), NewEvent(EventTIME)} {
+		vm.countTick(event)
+	}
+	if want := []int64{1, 2}; !reflect.DeepEqual(ticks, want) {
+		t.Errorf("observed ticks = %v, want %v", ticks, want)
+	}
+}
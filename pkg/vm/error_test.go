@@ -1,8 +1,11 @@
 package vm
 
 import (
+	"errors"
 	"strings"
 	"testing"
+
+	"github.com/zurustar/son-et/pkg/opcode"
 )
 
 func TestRuntimeError_Error(t *testing.T) {
@@ -123,3 +126,27 @@ func TestErrorHelperFunctions(t *testing.T) {
 		}
 	})
 }
+
+func TestUnsupportedFunctions(t *testing.T) {
+	vm := New([]opcode.OpCode{})
+	if !vm.HasBuiltin("loadpic") || vm.HasBuiltin("MCIPlay") {
+		t.Fatal("HasBuiltin should match registered builtins case-insensitively")
+	}
+
+	call := opcode.OpCode{Cmd: opcode.Call, Args: []any{"MCIPlay", "x"}}
+	_, err := vm.Execute(call)
+	var runtimeErr *RuntimeError
+	if !errors.As(err, &runtimeErr) || runtimeErr.Type != ErrorUndefinedFunc {
+		t.Fatalf("expected an undefined function error, got %v", err)
+	}
+
+	// 再生前に報告済みの関数は0を返して続行する
+	vm.SetUnsupportedFunctions([]string{"mciplay"})
+	result, err := vm.Execute(call)
+	if err != nil || result != int64(0) {
+		t.Errorf("expected 0 and no error, got %v, %v", result, err)
+	}
+	if _, err := vm.Execute(opcode.OpCode{Cmd: opcode.Call, Args: []any{"Other"}}); err == nil {
+		t.Error("functions that were not reported should still be undefined")
+	}
+}
@@ -8,10 +8,12 @@ package vm
 
 import (
 	"errors"
+	"maps"
 	"sort"
 	"sync"
 	"time"
 
+	"github.com/zurustar/son-et/pkg/eventbus"
 	"github.com/zurustar/son-et/pkg/opcode"
 )
 
@@ -56,6 +58,27 @@ const (
 
 	// EventUSER is a custom user-defined event triggered by PostMes().
 	EventUSER EventType = "USER"
+
+	// EventFADE_END is generated when a screen fade started by FadeOut() or FadeIn() completes.
+	// MesP1 is 1 for FadeOut and 0 for FadeIn.
+	EventFADE_END EventType = "FADE_END"
+
+	// EventMIDI_NOTE is generated for each NoteOn message while MIDI is playing.
+	// MesP2 is the MIDI channel (1-16), MesP3 the note number and MesP4 the velocity.
+	EventMIDI_NOTE EventType = "MIDI_NOTE"
+
+	// EventMIDI_LYRIC is generated for each lyric syllable (lyric meta events or the text of a .kar file)
+	// while MIDI is playing. MesP1 is the syllable, MesP2 the MIDI tick, MesP3 the number of
+	// characters of the line sung so far (including the syllable) and MesP4 the whole line.
+	EventMIDI_LYRIC EventType = "MIDI_LYRIC"
+
+	// EventPIC_READY is generated when a picture loaded by LoadPicAsync() has been decoded.
+	// MesP1 is the picture number and MesP2 is 1 on success or 0 if decoding failed.
+	EventPIC_READY EventType = "PIC_READY"
+
+	// EventTIMER is generated when a timer set by SetTimer() is due.
+	// MesP1 is the timer ID.
+	EventTIMER EventType = "TIMER"
 )
 
 // Event represents an event in the event system.
@@ -100,6 +123,28 @@ func NewEventWithParams(eventType EventType, params map[string]any) *Event {
 	}
 }
 
+// NewMIDINoteEvent creates a MIDI_NOTE event for a NoteOn message.
+// channel is the MIDI channel as stored in the file (0-15); scripts see it as 1-16.
+func NewMIDINoteEvent(channel, note, velocity int) *Event {
+	return NewEventWithParams(EventMIDI_NOTE, map[string]any{
+		"MesP1": 0,           // 未使用
+		"MesP2": channel + 1, // MIDIチャンネル（1〜16）
+		"MesP3": note,        // ノート番号（0〜127）
+		"MesP4": velocity,    // ベロシティ（1〜127）
+	})
+}
+
+// NewMIDILyricEvent creates a MIDI_LYRIC event for a lyric syllable.
+// tick is the MIDI tick of the syllable, sung the characters of line sung so far.
+func NewMIDILyricEvent(text string, tick, sung int, line string) *Event {
+	return NewEventWithParams(EventMIDI_LYRIC, map[string]any{
+		"MesP1": text, // 音節
+		"MesP2": tick, // MIDIティック
+		"MesP3": sung, // 行のうち歌った文字数（この音節を含む）
+		"MesP4": line, // 行全体
+	})
+}
+
 // GetParam retrieves a parameter value by name.
 // Returns the value and true if found, or nil and false if not found.
 func (e *Event) GetParam(name string) (any, bool) {
@@ -130,6 +175,7 @@ const DefaultQueueSize = 1000
 type EventQueue struct {
 	events  []*Event
 	maxSize int
+	bus     *eventbus.Bus // 設定されている場合、追加されたイベントの複製を発行する
 	mu      sync.Mutex
 }
 
@@ -152,6 +198,19 @@ func NewEventQueueWithSize(maxSize int) *EventQueue {
 	}
 }
 
+// eventTopicPrefix is the event bus topic prefix for events pushed to the queue.
+// The full topic is the prefix followed by the event type (e.g. "vm.event.TIME").
+const eventTopicPrefix = eventbus.CategoryVM + ".event."
+
+// SetBus sets the event bus that receives a copy of every pushed event.
+// Subscribers get their own copy of the parameters, so they never take events
+// from the queue or observe later changes to them. Passing nil disables publishing.
+func (eq *EventQueue) SetBus(bus *eventbus.Bus) {
+	eq.mu.Lock()
+	defer eq.mu.Unlock()
+	eq.bus = bus
+}
+
 // Push adds an event to the queue.
 // If the event has no timestamp, one is assigned.
 // The queue is kept sorted by timestamp (ascending).
@@ -189,6 +248,10 @@ func (eq *EventQueue) Push(event *Event) {
 	sort.SliceStable(eq.events, func(i, j int) bool {
 		return eq.events[i].Timestamp.Before(eq.events[j].Timestamp)
 	})
+
+	if eq.bus.HasSubscribers() {
+		eq.bus.Publish(eventTopicPrefix+string(event.Type), maps.Clone(event.Params))
+	}
 }
 
 // Pop removes and returns the oldest event from the queue.
@@ -230,6 +293,18 @@ func (eq *EventQueue) Len() int {
 	return len(eq.events)
 }
 
+// HasType reports whether an event of the given type is waiting in the queue.
+func (eq *EventQueue) HasType(eventType EventType) bool {
+	eq.mu.Lock()
+	defer eq.mu.Unlock()
+	for _, e := range eq.events {
+		if e.Type == eventType {
+			return true
+		}
+	}
+	return false
+}
+
 // Clear removes all events from the queue.
 func (eq *EventQueue) Clear() {
 	eq.mu.Lock()
@@ -237,6 +312,21 @@ func (eq *EventQueue) Clear() {
 	eq.events = eq.events[:0]
 }
 
+// TickPolicy selects what a handler does with tick events (TIME, MIDI_TIME) that
+// arrive while it is behind, i.e. while newer ticks of the same type are already queued.
+// It is set per sequence with SetTickPolicy.
+type TickPolicy int
+
+const (
+	// TickProcessAll runs the handler for every tick (the default, as in FILLY).
+	TickProcessAll TickPolicy = iota
+	// TickCoalesce still counts the queued ticks toward Wait, but resumes the handler
+	// only on the latest one, so it catches up with the music in a single run.
+	TickCoalesce
+	// TickDrop discards the queued ticks for the handler; its Wait counts fall behind the music.
+	TickDrop
+)
+
 // EventHandler represents a handler for a specific event type.
 // Handlers are registered via mes() syntax and executed when matching events occur.
 //
@@ -271,6 +361,17 @@ type EventHandler struct {
 	// Requirement 6.2: When OpWait is executed, system pauses execution until next event.
 	WaitCounter int
 
+	// WaitResult is the condition that ended the last WaitAny (0 = the ticks, 1 or more =
+	// the events in argument order), returned by GetWaitResult.
+	WaitResult int
+
+	// waitAny holds the conditions while the handler waits in WaitAny (see waitany.go).
+	waitAny *waitAnyState
+
+	// spawned is set for the sequences started by Spawn, which end when their body
+	// returns (see builtins_spawn.go).
+	spawned bool
+
 	// CurrentPC is the current program counter within the handler's OpCodes.
 	CurrentPC int
 
@@ -288,6 +389,26 @@ type EventHandler struct {
 	// ParentScope is the scope in which the handler was registered.
 	// This allows the handler to access variables from the enclosing scope (like C blocks).
 	ParentScope *Scope
+
+	// Number is the numeric sequence ID assigned by the registry (used by DelMes, FreezeMes, etc.).
+	Number int
+
+	// Filter optionally restricts which events of EventType run the handler.
+	// Events for which Filter returns false are skipped (used by OnKey/OnClick/OnSpriteClick).
+	Filter func(event *Event) bool
+
+	// TickPolicy selects how ticks are handled while the handler is behind (see TickPolicy).
+	TickPolicy TickPolicy
+
+	// DroppedTicks counts the ticks that did not run the handler because of TickPolicy
+	// (returned by GetDroppedTicks so that scripts can detect overload).
+	DroppedTicks int
+
+	// rng is the handler's own Random() stream, created on first use (see rng.go).
+	rng *sequenceStream
+
+	// trace holds the last statements the handler executed, created on first use (see trace.go).
+	trace *traceRing
 }
 
 // NewEventHandler creates a new event handler.
@@ -315,7 +436,18 @@ func NewEventHandler(id string, eventType EventType, opcodes []opcode.OpCode, vm
 // Requirement 6.2: When OpWait is executed, system pauses execution until next event.
 // Requirement 6.3: When event occurs during step execution, system proceeds to next step.
 func (eh *EventHandler) Execute(event *Event) error {
-	if !eh.Active {
+	if !eh.Active || !eh.addressedTo(event) {
+		return nil
+	}
+
+	// While waiting in WaitAny, only the awaited conditions resume the handler (the filter
+	// does not apply, as the handler also receives the events it waits for)
+	if eh.waitAny != nil {
+		if !eh.endWaitAny(event) {
+			return nil
+		}
+	} else if eh.Filter != nil && eh.WaitCounter == 0 && !eh.Filter(event) {
+		// Skip events that do not match the handler's filter
 		return nil
 	}
 
@@ -347,7 +479,7 @@ func (eh *EventHandler) Execute(event *Event) error {
 		eh.VM.localScope = eh.ParentScope
 	}
 
-	// Set event parameters in the VM's scope for access via MesP1, MesP2, MesP3
+	// Set event parameters in the VM's scope for access via MesP1, MesP2, MesP3, MesP4
 	// Requirement 1.6: When handler is executing, system provides access to event-specific parameters.
 	if event.Params != nil {
 		scope := eh.VM.GetCurrentScope()
@@ -360,6 +492,9 @@ func (eh *EventHandler) Execute(event *Event) error {
 		if p3, ok := event.Params["MesP3"]; ok {
 			scope.Set("MesP3", p3)
 		}
+		if p4, ok := event.Params["MesP4"]; ok {
+			scope.Set("MesP4", p4)
+		}
 	}
 
 	// Execute the handler's OpCodes starting from CurrentPC
@@ -395,6 +530,11 @@ func (eh *EventHandler) Execute(event *Event) error {
 			eh.VM.localScope = previousLocalScope
 			return nil
 		}
+
+		// return in the body of a spawned function ends the sequence
+		if _, isReturn := result.(*returnMarker); isReturn && eh.spawned {
+			break
+		}
 	}
 
 	// Handler completed all OpCodes
@@ -424,6 +564,32 @@ func (eh *EventHandler) Execute(event *Event) error {
 	return nil
 }
 
+// skipTick applies the handler's TickPolicy to a tick that arrived while newer ticks
+// are queued. It returns true if the handler must not run for this tick.
+func (eh *EventHandler) skipTick() bool {
+	switch eh.TickPolicy {
+	case TickCoalesce:
+		if eh.WaitCounter > 1 {
+			// Still waiting even without this tick: count it as usual
+			eh.WaitCounter--
+			return true
+		}
+		if w := eh.waitAny; w != nil && w.ticks != 1 {
+			// Waiting in WaitAny and this tick does not end the wait: count it as usual
+			w.ticks = max(w.ticks-1, 0)
+			return true
+		}
+		// The handler would run now: leave it to the latest tick
+		eh.DroppedTicks++
+		return true
+	case TickDrop:
+		eh.DroppedTicks++
+		return true
+	default:
+		return false
+	}
+}
+
 // Remove marks the handler for removal.
 // Requirement 2.9: When del_me is called, system removes currently executing handler.
 // Requirement 2.11: When del_us is called, system removes currently executing handler (same as del_me).
@@ -472,6 +638,7 @@ func (hr *HandlerRegistry) Register(handler *EventHandler) string {
 	// Generate unique ID if not set
 	if handler.ID == "" {
 		handler.ID = generateHandlerID(hr.nextID)
+		handler.Number = hr.nextID
 		hr.nextID++
 	}
 
@@ -625,13 +792,21 @@ func NewEventDispatcher(queue *EventQueue, registry *HandlerRegistry, vm *VM) *E
 func (ed *EventDispatcher) Dispatch(event *Event) error {
 	// ログは削除（頻繁すぎるため）
 
-	// Get all handlers for this event type
+	// Get all handlers for this event type, and the handlers of other types waiting for it in WaitAny
 	handlers := ed.registry.GetHandlers(event.Type)
+	handlers = append(handlers, ed.registry.waitingFor(event.Type)...)
+
+	// A tick with newer ticks of the same type queued behind it means the handlers
+	// are not keeping up; each handler's TickPolicy decides what to do with it.
+	behind := (event.Type == EventTIME || event.Type == EventMIDI_TIME) && ed.queue.HasType(event.Type)
 
 	// Execute handlers in registration order
 	// Requirement 1.5: When multiple handlers are registered for same event type, system executes them in registration order.
 	for _, handler := range handlers {
 		if handler.Active {
+			if behind && handler.skipTick() {
+				continue
+			}
 			if err := handler.Execute(event); err != nil {
 				// Check if this is a fatal error - propagate it up (use errors.As to unwrap wrapped errors)
 				var runtimeErr *RuntimeError
@@ -648,6 +823,11 @@ func (ed *EventDispatcher) Dispatch(event *Event) error {
 	// Cleanup handlers marked for deletion
 	ed.registry.CleanupMarkedHandlers()
 
+	if ed.vm != nil {
+		ed.vm.advanceFlipbooks(event)
+		ed.vm.advanceCrossfades(event)
+		ed.vm.observeDispatch(event)
+	}
 	return nil
 }
 
@@ -682,13 +862,18 @@ func (ed *EventDispatcher) ProcessQueue() error {
 // ProcessOne processes a single event from the queue.
 // Returns false if the queue is empty.
 func (ed *EventDispatcher) ProcessOne() (bool, error) {
+	event, err := ed.ProcessNext()
+	return event != nil, err
+}
+
+// ProcessNext processes a single event from the queue and returns it.
+// Returns nil if the queue is empty.
+func (ed *EventDispatcher) ProcessNext() (*Event, error) {
 	event, ok := ed.queue.Pop()
 	if !ok {
-		return false, nil
+		return nil, nil
 	}
-
-	err := ed.Dispatch(event)
-	return true, err
+	return event, ed.Dispatch(event)
 }
 
 // QueueEvent adds an event to the queue.
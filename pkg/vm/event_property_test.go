@@ -8,6 +8,7 @@ import (
 	"github.com/leanovate/gopter"
 	"github.com/leanovate/gopter/gen"
 	"github.com/leanovate/gopter/prop"
+	"github.com/zurustar/son-et/pkg/eventbus"
 )
 
 // Property-based tests for Event System.
@@ -734,3 +735,32 @@ func TestProperty8_DelAllHandlerRemoval(t *testing.T) {
 
 	properties.TestingRun(t, gopter.ConsoleReporter(false))
 }
+
+// TestEventQueueBusMirror tests that pushed events are published to the event bus
+// without being removed from the queue.
+func TestEventQueueBusMirror(t *testing.T) {
+	bus := eventbus.New()
+	sub := bus.Subscribe("vm.*", 0)
+	vm := New(nil, WithEventBus(bus))
+	queue := vm.GetEventQueue()
+
+	event := NewEventWithParams(EventUSER, map[string]any{"MesP1": int64(3)})
+	queue.Push(event)
+
+	if queue.Len() != 1 {
+		t.Fatalf("queue length = %d, want 1", queue.Len())
+	}
+	if len(sub.C()) != 1 {
+		t.Fatalf("subscription received %d messages, want 1", len(sub.C()))
+	}
+	msg := <-sub.C()
+	if msg.Topic != "vm.event.USER" || msg.Data["MesP1"] != int64(3) {
+		t.Errorf("unexpected message: %+v", msg)
+	}
+
+	// 購読者に渡したパラメータはイベントの複製
+	msg.Data["MesP1"] = int64(9)
+	if event.Params["MesP1"] != int64(3) {
+		t.Error("changing the published data should not modify the queued event")
+	}
+}
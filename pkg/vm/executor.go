@@ -2,10 +2,11 @@
 package vm
 
 import (
+	"context"
 	"errors"
 	"fmt"
+	"log/slog"
 	"math"
-	"strings"
 
 	"github.com/zurustar/son-et/pkg/opcode"
 )
@@ -148,18 +149,14 @@ func isFloat(v any) bool {
 //
 // Requirement 8.2: When OpAssign is executed, system assigns value to specified variable.
 func (vm *VM) executeAssign(op opcode.OpCode) (any, error) {
-	if len(op.Args) < 2 {
-		return nil, fmt.Errorf("OpAssign requires 2 arguments, got %d", len(op.Args))
-	}
-
-	// Get variable name
-	varName, ok := op.Args[0].(opcode.Variable)
-	if !ok {
-		return nil, fmt.Errorf("OpAssign first argument must be Variable, got %T", op.Args[0])
+	assign, err := decodeAssign(op)
+	if err != nil {
+		return nil, err
 	}
+	varName := assign.name
 
 	// Evaluate the value
-	value, err := vm.evaluateValue(op.Args[1])
+	value, err := vm.evaluateValue(assign.value)
 	if err != nil {
 		return nil, fmt.Errorf("failed to evaluate value: %w", err)
 	}
@@ -171,9 +168,14 @@ func (vm *VM) executeAssign(op opcode.OpCode) (any, error) {
 
 	// Set the variable in the current scope
 	// Requirement 9.6: When variable is assigned without prior declaration, system creates it in current scope.
-	vm.GetCurrentScope().Set(string(varName), value)
+	vm.GetCurrentScope().Set(varName, value)
+	if vm.traceSize > 0 {
+		vm.traceStatement(TraceEntry{Cmd: opcode.Assign, Name: varName, Args: []any{value}})
+	}
 
-	vm.log.Debug("Variable assigned", "name", string(varName), "value", value)
+	if vm.log.Enabled(context.Background(), slog.LevelDebug) {
+		vm.log.Debug("Variable assigned", "name", varName, "value", value)
+	}
 	return value, nil
 }
 
@@ -184,18 +186,14 @@ func (vm *VM) executeAssign(op opcode.OpCode) (any, error) {
 // Requirement 8.3: When OpArrayAssign is executed, system assigns value to specified array element.
 // Requirement 19.5: When array index exceeds array size, system automatically expands array.
 func (vm *VM) executeArrayAssign(op opcode.OpCode) (any, error) {
-	if len(op.Args) < 3 {
-		return nil, fmt.Errorf("OpArrayAssign requires 3 arguments, got %d", len(op.Args))
-	}
-
-	// Get array name
-	arrayName, ok := op.Args[0].(opcode.Variable)
-	if !ok {
-		return nil, fmt.Errorf("OpArrayAssign first argument must be Variable, got %T", op.Args[0])
+	assign, err := decodeArrayAssign(op)
+	if err != nil {
+		return nil, err
 	}
+	arrayName := assign.name
 
 	// Evaluate the index
-	indexVal, err := vm.evaluateValue(op.Args[1])
+	indexVal, err := vm.evaluateValue(assign.index)
 	if err != nil {
 		return nil, fmt.Errorf("failed to evaluate index: %w", err)
 	}
@@ -206,19 +204,25 @@ func (vm *VM) executeArrayAssign(op opcode.OpCode) (any, error) {
 
 	// Requirement 19.4: When array index is negative, system logs warning and returns zero.
 	if index < 0 {
-		vm.log.Warn("Negative array index, returning 0", "array", string(arrayName), "index", index)
+		vm.log.Warn("Negative array index, returning 0", "array", arrayName, "index", index)
 		return int64(0), nil
 	}
+	if err := vm.checkSandboxArrayIndex(arrayName, index); err != nil {
+		return nil, err
+	}
 
 	// Evaluate the value
-	value, err := vm.evaluateValue(op.Args[2])
+	value, err := vm.evaluateValue(assign.value)
 	if err != nil {
 		return nil, fmt.Errorf("failed to evaluate value: %w", err)
 	}
+	if vm.traceSize > 0 {
+		vm.traceStatement(TraceEntry{Cmd: opcode.ArrayAssign, Name: arrayName, Args: []any{index, value}})
+	}
 
 	// Get or create the array
 	scope := vm.GetCurrentScope()
-	arrayVal, exists := scope.Get(string(arrayName))
+	arrayVal, exists := scope.Get(arrayName)
 
 	var arr *Array
 	if exists {
@@ -231,17 +235,17 @@ func (vm *VM) executeArrayAssign(op opcode.OpCode) (any, error) {
 			// Legacy slice - convert to Array
 			arr = NewArrayFromSlice(v)
 			// Update the scope with the new Array type
-			scope.Set(string(arrayName), arr)
+			scope.Set(arrayName, arr)
 		default:
 			// Variable exists but is not an array - create new array
 			arr = NewArray(int(index) + 1)
-			scope.Set(string(arrayName), arr)
+			scope.Set(arrayName, arr)
 		}
 	} else {
 		// Create new array
 		// Requirement 19.1: When array is declared, system allocates storage for array.
 		arr = NewArray(int(index) + 1)
-		scope.Set(string(arrayName), arr)
+		scope.Set(arrayName, arr)
 	}
 
 	// Set the value (Array.Set handles expansion and zero initialization)
@@ -251,10 +255,49 @@ func (vm *VM) executeArrayAssign(op opcode.OpCode) (any, error) {
 	// Requirement 19.7: System initializes new array elements to zero.
 	arr.Set(index, value)
 
-	vm.log.Debug("Array element assigned", "array", string(arrayName), "index", index, "value", value)
+	vm.log.Debug("Array element assigned", "array", arrayName, "index", index, "value", value)
 	return value, nil
 }
 
+// callBuiltin calls a built-in function with evaluated arguments.
+// Arguments are first checked against the function's signature (see opcode.Signature):
+// numeric strings are converted for numeric parameters, and an argument of the wrong type
+// is logged with the call's position and the call returns the function's invalid result
+// without running it. Argument counts are left to the function, which handles short forms.
+// (OpCodes carry no source lines; the compiler reports literal arguments with their lines.)
+// Inside a try block, both errors are returned instead, so the catch block handles them.
+// Requirement 11.8: System continues execution after non-fatal errors.
+func (vm *VM) callBuiltin(funcName string, builtin BuiltinFunc, args []any) (any, error) {
+	if sig, ok := opcode.LookupSignature(funcName); ok {
+		converted, err := sig.ConvertArgs(args)
+		if err != nil {
+			var argErr *opcode.ArgError
+			if errors.As(err, &argErr) {
+				argErr.Func = funcName
+			}
+			if vm.tryDepth > 0 {
+				return nil, err
+			}
+			caller := "main"
+			if len(vm.callStack) > 0 {
+				caller = vm.callStack[len(vm.callStack)-1].FunctionName
+			}
+			vm.log.Error("Invalid built-in function argument", "error", err, "caller", caller)
+			return sig.InvalidResult(), nil
+		}
+		args = converted
+	}
+	result, err := builtin(vm, args)
+	if err != nil {
+		if vm.tryDepth > 0 {
+			return nil, fmt.Errorf("%s: %w", funcName, err)
+		}
+		vm.log.Error("Built-in function error", "function", funcName, "error", err)
+		return int64(0), nil
+	}
+	return result, nil
+}
+
 // executeCall executes an OpCall OpCode.
 // OpCall invokes a function with arguments.
 // Args: [functionName, arg1, arg2, ...]
@@ -262,21 +305,17 @@ func (vm *VM) executeArrayAssign(op opcode.OpCode) (any, error) {
 // Requirement 8.4: When OpCall is executed, system calls specified function with arguments.
 // Requirement 10.8: When unknown function is called, system logs error and continues execution.
 func (vm *VM) executeCall(op opcode.OpCode) (any, error) {
-	if len(op.Args) < 1 {
-		return nil, fmt.Errorf("OpCall requires at least 1 argument (function name)")
-	}
-
-	// Get function name
-	funcName, ok := op.Args[0].(string)
-	if !ok {
-		return nil, fmt.Errorf("OpCall first argument must be string, got %T", op.Args[0])
+	call, err := decodeCall(op)
+	if err != nil {
+		return nil, err
 	}
+	funcName := call.name
 
 	// Handle special "return" call
-	if funcName == "return" {
+	if call.isReturn {
 		var returnValue any = int64(0)
-		if len(op.Args) > 1 {
-			val, err := vm.evaluateValue(op.Args[1])
+		if len(call.args) > 0 {
+			val, err := vm.evaluateValue(call.args[0])
 			if err != nil {
 				return nil, fmt.Errorf("failed to evaluate return value: %w", err)
 			}
@@ -291,35 +330,26 @@ func (vm *VM) executeCall(op opcode.OpCode) (any, error) {
 	}
 
 	// Evaluate arguments
-	args := make([]any, 0, len(op.Args)-1)
-	for i := 1; i < len(op.Args); i++ {
-		val, err := vm.evaluateValue(op.Args[i])
+	args := make([]any, 0, len(call.args))
+	for i, arg := range call.args {
+		val, err := vm.evaluateValue(arg)
 		if err != nil {
-			return nil, fmt.Errorf("failed to evaluate argument %d: %w", i, err)
+			return nil, fmt.Errorf("failed to evaluate argument %d: %w", i+1, err)
 		}
 		args = append(args, val)
 	}
+	vm.traceStatement(TraceEntry{Cmd: opcode.Call, Name: funcName, Args: args})
 
 	// Check for built-in function first
 	if builtin, ok := vm.builtins[funcName]; ok {
-		result, err := builtin(vm, args)
-		if err != nil {
-			vm.log.Error("Built-in function error", "function", funcName, "error", err)
-			// Requirement 11.8: System continues execution after non-fatal errors.
-			return int64(0), nil
-		}
-		return result, nil
+		return vm.callBuiltin(funcName, builtin, args)
 	}
 
-	// Check for case-insensitive built-in function match (O(1) via lowercase index).
-	funcNameLower := strings.ToLower(funcName)
+	// Check for case-insensitive built-in function match (O(1) via lowercase index,
+	// with the name lower-cased when the program was decoded).
+	funcNameLower := call.lower
 	if builtin, ok := vm.builtinsLower[funcNameLower]; ok {
-		result, err := builtin(vm, args)
-		if err != nil {
-			vm.log.Error("Built-in function error", "function", funcName, "error", err)
-			return int64(0), nil
-		}
-		return result, nil
+		return vm.callBuiltin(funcName, builtin, args)
 	}
 
 	// Check for user-defined function
@@ -332,6 +362,12 @@ func (vm *VM) executeCall(op opcode.OpCode) (any, error) {
 		return vm.callUserFunction(userFunc, args)
 	}
 
+	// 再生前に互換性レポートで報告済みの未実装関数は、何もせずに0を返す
+	if vm.unsupportedFuncs[funcNameLower] {
+		vm.log.Debug("Unsupported function called, ignoring", "function", funcName)
+		return int64(0), nil
+	}
+
 	// 未定義関数が呼ばれた場合はエラーで終了
 	vm.log.Error("Undefined function called", "function", funcName)
 	return nil, NewUndefinedFunctionError(funcName)
@@ -365,24 +401,7 @@ func (vm *VM) callUserFunction(fn *FunctionDef, args []any) (any, error) {
 
 	// Bind parameters to local scope
 	// Requirement 9.7: When function parameters are passed, system binds them to local scope.
-	for i, param := range fn.Parameters {
-		var value any
-		if i < len(args) {
-			value = args[i]
-		} else if param.HasDefault {
-			value = param.Default
-		} else {
-			value = int64(0)
-		}
-
-		// Requirement 19.8: When array is passed to function, system passes it by reference.
-		if param.IsArray {
-			// Arrays are passed by reference (the slice itself)
-			localScope.SetLocal(param.Name, value)
-		} else {
-			localScope.SetLocal(param.Name, value)
-		}
-	}
+	bindParameters(fn, localScope, args)
 
 	// Push stack frame
 	// Requirement 20.6: System detects stack overflow and reports error.
@@ -406,6 +425,11 @@ func (vm *VM) callUserFunction(fn *FunctionDef, args []any) (any, error) {
 				vm.PopStackFrame()
 				return nil, err
 			}
+			// Inside a try block, the error stops the function and is handled by catch
+			if vm.tryDepth > 0 {
+				vm.PopStackFrame()
+				return nil, err
+			}
 			vm.log.Error("Error in function body", "function", fn.Name, "error", err)
 		}
 
@@ -432,28 +456,44 @@ func (vm *VM) callUserFunction(fn *FunctionDef, args []any) (any, error) {
 	return result, nil
 }
 
+// bindParameters binds the arguments of a call to fn's parameters in scope.
+// Missing arguments take the parameter's default value, or 0.
+func bindParameters(fn *FunctionDef, scope *Scope, args []any) {
+	for i, param := range fn.Parameters {
+		var value any
+		if i < len(args) {
+			value = args[i]
+		} else if param.HasDefault {
+			value = param.Default
+		} else {
+			value = int64(0)
+		}
+
+		// Requirement 19.8: When array is passed to function, system passes it by reference.
+		// Arrays are passed by reference (the slice itself)
+		scope.SetLocal(param.Name, value)
+	}
+}
+
 // executeBinaryOp executes an OpBinaryOp OpCode.
 // OpBinaryOp performs a binary operation (+, -, *, /, %, ==, !=, <, <=, >, >=, &&, ||).
 // Args: [operator, leftOperand, rightOperand]
 //
 // Requirement 8.11: When OpBinaryOp is executed, system evaluates binary operation and returns result.
 func (vm *VM) executeBinaryOp(op opcode.OpCode) (any, error) {
-	if len(op.Args) < 3 {
-		return nil, fmt.Errorf("OpBinaryOp requires 3 arguments, got %d", len(op.Args))
-	}
-
-	operator, ok := op.Args[0].(string)
-	if !ok {
-		return nil, fmt.Errorf("OpBinaryOp operator must be string, got %T", op.Args[0])
+	binary, err := decodeBinaryOp(op)
+	if err != nil {
+		return nil, err
 	}
+	operator := binary.operator
 
 	// Evaluate operands
-	left, err := vm.evaluateValue(op.Args[1])
+	left, err := vm.evaluateValue(binary.left)
 	if err != nil {
 		return nil, fmt.Errorf("failed to evaluate left operand: %w", err)
 	}
 
-	right, err := vm.evaluateValue(op.Args[2])
+	right, err := vm.evaluateValue(binary.right)
 	if err != nil {
 		return nil, fmt.Errorf("failed to evaluate right operand: %w", err)
 	}
@@ -483,7 +523,8 @@ func (vm *VM) executeBinaryOp(op opcode.OpCode) (any, error) {
 // executeArithmeticOp executes arithmetic operations (+, -, *, /, %).
 func (vm *VM) executeArithmeticOp(operator string, left, right any) (any, error) {
 	// Determine if we should use float arithmetic
-	useFloat := isFloat(left) || isFloat(right)
+	// (the original FILLY has integers only; see WithCompatMode)
+	useFloat := !vm.compat.Strict() && (isFloat(left) || isFloat(right))
 
 	if useFloat {
 		leftF, ok := toFloat64(left)
@@ -664,17 +705,14 @@ func (vm *VM) executeLogicalOp(operator string, left, right any) (any, error) {
 //
 // Requirement 8.12: When OpUnaryOp is executed, system evaluates unary operation and returns result.
 func (vm *VM) executeUnaryOp(op opcode.OpCode) (any, error) {
-	if len(op.Args) < 2 {
-		return nil, fmt.Errorf("OpUnaryOp requires 2 arguments, got %d", len(op.Args))
-	}
-
-	operator, ok := op.Args[0].(string)
-	if !ok {
-		return nil, fmt.Errorf("OpUnaryOp operator must be string, got %T", op.Args[0])
+	unary, err := decodeUnaryOp(op)
+	if err != nil {
+		return nil, err
 	}
+	operator := unary.operator
 
 	// Evaluate operand
-	operand, err := vm.evaluateValue(op.Args[1])
+	operand, err := vm.evaluateValue(unary.right)
 	if err != nil {
 		return nil, fmt.Errorf("failed to evaluate operand: %w", err)
 	}
@@ -706,26 +744,23 @@ func (vm *VM) executeUnaryOp(op opcode.OpCode) (any, error) {
 // Requirement 8.13: When OpArrayAccess is executed, system returns value at specified array index.
 // Requirement 11.4: When array index is out of range, system auto-expands array and returns zero.
 func (vm *VM) executeArrayAccess(op opcode.OpCode) (any, error) {
-	if len(op.Args) < 2 {
-		return nil, fmt.Errorf("OpArrayAccess requires 2 arguments, got %d", len(op.Args))
+	access, err := decodeArrayAccess(op)
+	if err != nil {
+		return nil, err
 	}
 
 	// Get array variable name (if available) for auto-expansion
-	var arrayVarName opcode.Variable
-	hasVarName := false
-	if varName, ok := op.Args[0].(opcode.Variable); ok {
-		arrayVarName = varName
-		hasVarName = true
-	}
+	_, hasVarName := access.array.(opcode.Variable)
+	arrayVarName := access.name
 
 	// Evaluate the array (could be a Variable or nested expression)
-	arrayVal, err := vm.evaluateValue(op.Args[0])
+	arrayVal, err := vm.evaluateValue(access.array)
 	if err != nil {
 		return nil, fmt.Errorf("failed to evaluate array: %w", err)
 	}
 
 	// Evaluate the index
-	indexVal, err := vm.evaluateValue(op.Args[1])
+	indexVal, err := vm.evaluateValue(access.index)
 	if err != nil {
 		return nil, fmt.Errorf("failed to evaluate index: %w", err)
 	}
@@ -737,9 +772,12 @@ func (vm *VM) executeArrayAccess(op opcode.OpCode) (any, error) {
 
 	// Requirement 19.4: When array index is negative, system logs warning and returns zero.
 	if index < 0 {
-		vm.log.Warn("Negative array index, returning 0", "array", string(arrayVarName), "index", index)
+		vm.log.Warn("Negative array index, returning 0", "array", arrayVarName, "index", index)
 		return int64(0), nil
 	}
+	if err := vm.checkSandboxArrayIndex(arrayVarName, index); err != nil {
+		return nil, err
+	}
 
 	// Handle Array type (new reference-based array)
 	if arr, ok := arrayVal.(*Array); ok {
@@ -747,7 +785,7 @@ func (vm *VM) executeArrayAccess(op opcode.OpCode) (any, error) {
 		if !found {
 			// Auto-expand array and set default value 0
 			// This ensures subsequent accesses don't trigger expansion
-			vm.log.Debug("Array auto-expanding on read access", "array", string(arrayVarName), "index", index, "oldLength", arr.Len())
+			vm.log.Debug("Array auto-expanding on read access", "array", arrayVarName, "index", index, "oldLength", arr.Len())
 			arr.Set(index, int64(0))
 			return int64(0), nil
 		}
@@ -759,8 +797,8 @@ func (vm *VM) executeArrayAccess(op opcode.OpCode) (any, error) {
 	if hasVarName {
 		// Create new array with the required size
 		newArr := NewArray(int(index) + 1)
-		vm.GetCurrentScope().Set(string(arrayVarName), newArr)
-		vm.log.Debug("Array created on read access", "array", string(arrayVarName), "index", index)
+		vm.GetCurrentScope().Set(arrayVarName, newArr)
+		vm.log.Debug("Array created on read access", "array", arrayVarName, "index", index)
 		return int64(0), nil
 	}
 
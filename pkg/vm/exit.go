@@ -0,0 +1,103 @@
+package vm
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+)
+
+// Exit sequence (OnExit) budget
+const (
+	DefaultExitBudget = 3 * time.Second  // Used when OnExit is called without a budget
+	MaxExitBudget     = 10 * time.Second // Upper limit, so that closing never hangs for long
+)
+
+// registerExitBuiltins registers built-in functions for the exit sequence.
+// The exit sequence lets a title fade out its audio and show a farewell screen when the
+// window is closed or the timeout fires, instead of stopping abruptly.
+func (vm *VM) registerExitBuiltins() {
+	// OnExit: Register the function that runs when the title is closed or times out
+	// OnExit(funcName[, budgetMs]) - the exit sequence runs for at most budgetMs
+	// (default 3000, max 10000). OnExit("") unregisters the function.
+	vm.RegisterBuiltinFunction("OnExit", func(v *VM, args []any) (any, error) {
+		if len(args) < 1 {
+			v.log.Error("OnExit requires at least 1 argument")
+			return nil, nil
+		}
+		name := toString(args[0])
+		budget := DefaultExitBudget
+		if len(args) >= 2 {
+			ms, ok := toInt64(args[1])
+			if !ok || ms <= 0 {
+				v.log.Error("OnExit: budget must be a positive number of milliseconds", "budget", args[1])
+				return nil, nil
+			}
+			budget = min(time.Duration(ms)*time.Millisecond, MaxExitBudget)
+		}
+
+		if name == "" {
+			v.setExitFunction(nil, 0)
+			v.log.Debug("OnExit unregistered")
+			return nil, nil
+		}
+		fn, ok := v.functions[name]
+		if !ok {
+			fn, ok = v.functionsLower[strings.ToLower(name)]
+		}
+		if !ok {
+			v.log.Error("OnExit: undefined function", "function", name)
+			return nil, nil
+		}
+		v.setExitFunction(fn, budget)
+		v.log.Debug("OnExit registered", "function", fn.Name, "budget", budget)
+		return nil, nil
+	})
+}
+
+// setExitFunction sets the function called by the exit sequence (nil = none).
+func (vm *VM) setExitFunction(fn *FunctionDef, budget time.Duration) {
+	vm.mu.Lock()
+	defer vm.mu.Unlock()
+	vm.exitFunc = fn
+	vm.exitBudget = budget
+}
+
+// RequestExit asks the VM to run the script's exit sequence (OnExit), e.g. because the
+// user closed the window. It reports whether the caller should keep the window open until
+// the VM stops; it returns false when the VM is not running or no exit function is registered.
+// The VM stops by itself when the exit sequence ends or its budget runs out.
+func (vm *VM) RequestExit() bool {
+	vm.mu.RLock()
+	defer vm.mu.RUnlock()
+	if !vm.running || vm.exitFunc == nil {
+		return false
+	}
+	vm.exitRequested.Store(true)
+	return true
+}
+
+// startExitSequence calls the exit function, if one is registered and the sequence has not
+// started yet, and gives the event loop a fresh context limited to the exit budget (the run
+// context has either timed out or is replaced so that the budget also applies to a close
+// request). A VM stopped with Stop does not run the exit sequence.
+// Returns whether the sequence was started; must be called from the VM goroutine.
+func (vm *VM) startExitSequence() bool {
+	vm.mu.Lock()
+	fn := vm.exitFunc
+	if fn == nil || vm.exiting || errors.Is(context.Cause(vm.ctx), context.Canceled) {
+		vm.mu.Unlock()
+		return false
+	}
+	vm.exiting = true
+	vm.cancel()
+	vm.ctx, vm.cancel = withClockTimeout(context.Background(), vm.clock, vm.exitBudget)
+	budget := vm.exitBudget
+	vm.mu.Unlock()
+
+	vm.log.Info("Running exit sequence", "function", fn.Name, "budget", budget)
+	if _, err := vm.callUserFunction(fn, []any{}); err != nil {
+		vm.log.Error("Exit function failed", "function", fn.Name, "error", err)
+	}
+	return true
+}
@@ -0,0 +1,183 @@
+package vm
+
+import (
+	"testing"
+	"time"
+
+	"github.com/zurustar/son-et/pkg/opcode"
+)
+
+// newExitTestVM creates a VM whose exit function "onInput" is recorded on each call,
+// with a KEY handler that keeps the event loop running until the VM is stopped.
+func newExitTestVM(t *testing.T, opts ...Option) (*VM, *[][]any) {
+	t.Helper()
+	vm, calls := newInputTestVM(t)
+	for _, opt := range opts {
+		opt(vm)
+	}
+	vm.handlerRegistry.Register(NewEventHandler("", EventKEY, []opcode.OpCode{}, vm, vm.GetCurrentScope()))
+	return vm, calls
+}
+
+// runInBackground starts vm.Run and waits until the VM is running.
+func runInBackground(t *testing.T, vm *VM) <-chan error {
+	t.Helper()
+	done := make(chan error, 1)
+	go func() {
+		done <- vm.Run()
+	}()
+	deadline := time.Now().Add(time.Second)
+	for !vm.IsRunning() {
+		if time.Now().After(deadline) {
+			t.Fatal("VM did not start")
+		}
+		time.Sleep(time.Millisecond)
+	}
+	return done
+}
+
+// waitRun waits for vm.Run to return.
+func waitRun(t *testing.T, done <-chan error) {
+	t.Helper()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Errorf("Run returned error: %v", err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("VM did not stop")
+	}
+}
+
+func TestOnExitRegistration(t *testing.T) {
+	vm, _ := newInputTestVM(t)
+
+	vm.builtins["OnExit"](vm, []any{"ONINPUT"})
+	if vm.exitFunc == nil || vm.exitFunc.Name != "onInput" {
+		t.Fatal("OnExit should register the function (case-insensitive)")
+	}
+	if vm.exitBudget != DefaultExitBudget {
+		t.Errorf("budget = %v, want %v", vm.exitBudget, DefaultExitBudget)
+	}
+
+	vm.builtins["OnExit"](vm, []any{"onInput", int64(60000)})
+	if vm.exitBudget != MaxExitBudget {
+		t.Errorf("budget = %v, want it capped to %v", vm.exitBudget, MaxExitBudget)
+	}
+
+	vm.builtins["OnExit"](vm, []any{""})
+	if vm.exitFunc != nil {
+		t.Error(`OnExit("") should unregister the function`)
+	}
+}
+
+func TestOnExitInvalidArgs(t *testing.T) {
+	vm, _ := newInputTestVM(t)
+
+	// 不正な引数はログに記録して無視し、スクリプトは続行する
+	for _, args := range [][]any{
+		{},
+		{"missing"},
+		{"onInput", int64(0)},
+		{"onInput", "fast"},
+	} {
+		if _, err := vm.builtins["OnExit"](vm, args); err != nil {
+			t.Errorf("OnExit(%v) returned error: %v", args, err)
+		}
+	}
+	if vm.exitFunc != nil {
+		t.Error("invalid calls should not register an exit function")
+	}
+}
+
+func TestExitSequenceOnTimeout(t *testing.T) {
+	vm, calls := newExitTestVM(t, WithTimeout(30*time.Millisecond))
+	vm.builtins["OnExit"](vm, []any{"onInput", int64(50)})
+
+	start := time.Now()
+	if err := vm.Run(); err != nil {
+		t.Fatalf("Run returned error: %v", err)
+	}
+	if len(*calls) != 1 {
+		t.Fatalf("exit function called %d times, want 1", len(*calls))
+	}
+	// タイムアウトの後、終了処理の予算だけ実行を続ける
+	if elapsed := time.Since(start); elapsed < 80*time.Millisecond {
+		t.Errorf("Run returned after %v, expected the exit budget to be used", elapsed)
+	}
+}
+
+func TestExitSequenceOnRequest(t *testing.T) {
+	vm, calls := newExitTestVM(t)
+	vm.builtins["OnExit"](vm, []any{"onInput", int64(50)})
+
+	done := runInBackground(t, vm)
+	if !vm.RequestExit() {
+		t.Fatal("RequestExit should return true when an exit function is registered")
+	}
+	waitRun(t, done)
+
+	if len(*calls) != 1 {
+		t.Errorf("exit function called %d times, want 1", len(*calls))
+	}
+	if vm.RequestExit() {
+		t.Error("RequestExit should return false after the VM stopped")
+	}
+}
+
+func TestExitSequenceStopDuringSequence(t *testing.T) {
+	vm, calls := newExitTestVM(t)
+	vm.builtins["OnExit"](vm, []any{"onInput", int64(MaxExitBudget / time.Millisecond)})
+
+	done := runInBackground(t, vm)
+	vm.RequestExit()
+	deadline := time.Now().Add(time.Second)
+	for !vmExiting(vm) && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+
+	// 終了処理の実行中でも Stop で直ちに停止する
+	start := time.Now()
+	vm.Stop()
+	waitRun(t, done)
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("Stop took %v during the exit sequence", elapsed)
+	}
+	if len(*calls) != 1 {
+		t.Errorf("exit function called %d times, want 1", len(*calls))
+	}
+}
+
+// vmExiting reports whether the exit sequence has started.
+func vmExiting(vm *VM) bool {
+	vm.mu.RLock()
+	defer vm.mu.RUnlock()
+	return vm.exiting
+}
+
+func TestExitSequenceNotRunOnStop(t *testing.T) {
+	vm, calls := newExitTestVM(t)
+	vm.builtins["OnExit"](vm, []any{"onInput"})
+
+	done := runInBackground(t, vm)
+	vm.Stop()
+	waitRun(t, done)
+
+	if len(*calls) != 0 {
+		t.Errorf("Stop should not run the exit function, called %d times", len(*calls))
+	}
+}
+
+func TestRequestExitWithoutExitFunction(t *testing.T) {
+	vm, _ := newExitTestVM(t)
+	if vm.RequestExit() {
+		t.Error("RequestExit should return false when the VM is not running")
+	}
+
+	done := runInBackground(t, vm)
+	if vm.RequestExit() {
+		t.Error("RequestExit should return false without an exit function")
+	}
+	vm.Stop()
+	waitRun(t, done)
+}
@@ -0,0 +1,517 @@
+package vm
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/zurustar/son-et/pkg/graphics"
+)
+
+// Keyframes
+//
+// With WithKeyframeInterval (--keyframe-interval) the event loop copies the engine
+// state every interval of ticks: variables, sequences and their waits, queued events,
+// timers, Random() streams, the MIDI position and the graphics state (pictures,
+// windows, casts and their sprites). Seeking backward (the rewind command, or the
+// chapter command with a chapter already passed) restores the nearest keyframe at or
+// before the target and fast-forwards from there the same way as a chapter seek
+// (see chapter.go), so the script replays exactly as it ran the first time.
+//
+// Files, the value store, WAV playback, MIDI ports other than the main one and the
+// palette, display adjustment, cursor and app window settings are not restored.
+
+// keyframeLimit is how many keyframes are kept; the oldest is released first
+const keyframeLimit = 32
+
+// keyframeState holds the keyframes and a rewind requested by an operator.
+type keyframeState struct {
+	interval time.Duration // ticks between keyframes, in TIME ticks (0 = no keyframes)
+	last     int64         // tick of the last keyframe (used only on the VM goroutine)
+	taken    bool          // a keyframe has been taken (used only on the VM goroutine)
+
+	mu        sync.Mutex
+	frames    []*vmKeyframe  // oldest first
+	requested *rewindRequest // rewind requested by Rewind or SeekChapter, started by the event loop
+}
+
+// rewindRequest selects the keyframe to restore and where the fast-forward stops.
+type rewindRequest struct {
+	ticks   int64  // fast-forward until this tick (when chapter is empty)
+	chapter string // fast-forward until Chapter(chapter)
+	passed  int    // restore a keyframe taken before this many chapters were passed
+}
+
+// accepts reports whether the request can restore kf.
+func (r *rewindRequest) accepts(kf *vmKeyframe) bool {
+	if r.chapter != "" {
+		return kf.passed <= r.passed
+	}
+	return kf.ticks <= r.ticks
+}
+
+// vmKeyframe is a copy of the engine state at a tick.
+type vmKeyframe struct {
+	state    *vmState
+	graphics graphics.Keyframe // nil without a graphics system
+
+	ticks    int64
+	midiTick int64     // MIDI tick of the last dispatched MIDI_TIME event
+	passed   int       // chapters passed
+	now      time.Time // event loop time (virtual while fast-forwarding)
+
+	midiFile     string // MIDI file playing on the main port ("" = none)
+	midiFileTick int    // its last MIDI_TIME tick
+	timerRunning bool
+}
+
+// release releases the graphics keyframe.
+func (kf *vmKeyframe) release() {
+	if kf.graphics != nil {
+		kf.graphics.Release()
+	}
+}
+
+// WithKeyframeInterval keeps a keyframe of the engine state every interval of ticks
+// so that playback can seek backward (--keyframe-interval). 0 disables keyframes.
+func WithKeyframeInterval(interval time.Duration) Option {
+	return func(vm *VM) {
+		vm.keyframes.interval = interval
+	}
+}
+
+// Rewind asks the VM to go back d of ticks: the nearest keyframe at or before the
+// target tick is restored and the script fast-forwards to the target.
+// It may be called from any goroutine; the rewind starts in the event loop.
+func (vm *VM) Rewind(d time.Duration) error {
+	if vm.keyframes.interval <= 0 {
+		return errors.New("keyframes are disabled (start with --keyframe-interval)")
+	}
+	if d <= 0 {
+		return fmt.Errorf("rewind duration must be positive, got %v", d)
+	}
+	target := max(vm.ticks.Load()-int64(d/timeEventInterval), 0)
+
+	k := &vm.keyframes
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	req := &rewindRequest{ticks: target}
+	if k.find(req) == nil {
+		if len(k.frames) == 0 {
+			return errors.New("no keyframe has been taken yet")
+		}
+		return fmt.Errorf("no keyframe at or before tick %d (the oldest is at tick %d)", target, k.frames[0].ticks)
+	}
+	k.requested = req
+	return nil
+}
+
+// requestChapterRewind asks the event loop to go back to Chapter(name), which was
+// passed for the last time as the passed-th chapter. It reports whether a keyframe
+// taken before that exists.
+func (vm *VM) requestChapterRewind(name string, passed int) bool {
+	k := &vm.keyframes
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	req := &rewindRequest{chapter: name, passed: passed}
+	if k.find(req) == nil {
+		return false
+	}
+	k.requested = req
+	return true
+}
+
+// find returns the newest keyframe the request accepts, or nil.
+// The caller must hold k.mu.
+func (k *keyframeState) find(req *rewindRequest) *vmKeyframe {
+	for i := len(k.frames) - 1; i >= 0; i-- {
+		if req.accepts(k.frames[i]) {
+			return k.frames[i]
+		}
+	}
+	return nil
+}
+
+// takeKeyframe keeps a keyframe once per interval of ticks. A keyframe the graphics
+// system cannot take now (during a fade or scene change) is retried on the next call.
+func (vm *VM) takeKeyframe(now time.Time) {
+	k := &vm.keyframes
+	if k.interval <= 0 {
+		return
+	}
+	ticks := vm.ticks.Load()
+	if k.taken && ticks < k.last+max(int64(k.interval/timeEventInterval), 1) {
+		return
+	}
+
+	kf := &vmKeyframe{
+		state:    vm.liveState().clone(newStateCloner()),
+		ticks:    ticks,
+		midiTick: vm.midiTick.Load(),
+		now:      now,
+	}
+	if vm.graphicsSystem != nil {
+		gkf, err := vm.graphicsSystem.CaptureKeyframe()
+		if err != nil {
+			vm.log.Debug("Keyframe postponed", "tick", ticks, "reason", err)
+			return
+		}
+		kf.graphics = gkf
+	}
+	if vm.audioSystem != nil {
+		kf.timerRunning = vm.audioSystem.IsTimerRunning()
+		tick, _ := vm.audioSystem.MIDITickAfter(0)
+		if s := vm.seek; s != nil && tick >= 0 {
+			// MIDI is paused while fast-forwarding, so the tick reached by the fast-forward is the current position
+			tick = s.midiTick
+			if s.midiEnded {
+				tick = -1
+			}
+		}
+		if tick >= 0 {
+			kf.midiFile, kf.midiFileTick = vm.midiFile, tick
+		}
+	}
+	vm.chapters.mu.Lock()
+	kf.passed = len(vm.chapters.passed)
+	vm.chapters.mu.Unlock()
+
+	k.mu.Lock()
+	k.frames = append(k.frames, kf)
+	if len(k.frames) > keyframeLimit {
+		k.frames[0].release()
+		k.frames = k.frames[1:]
+	}
+	k.mu.Unlock()
+	k.taken, k.last = true, ticks
+	vm.log.Debug("Keyframe taken", "tick", ticks, "keyframes", len(k.frames))
+}
+
+// startRewind restores the keyframe of a rewind requested by Rewind or SeekChapter
+// and starts fast-forwarding from it. Keyframes newer than the restored one are released.
+func (vm *VM) startRewind(now time.Time) {
+	k := &vm.keyframes
+	k.mu.Lock()
+	req := k.requested
+	k.requested = nil
+	var kf *vmKeyframe
+	if req != nil {
+		kf = k.find(req)
+	}
+	k.mu.Unlock()
+	if kf == nil {
+		return
+	}
+
+	if vm.audioSystem != nil {
+		vm.audioSystem.Pause()
+	}
+	if vm.graphicsSystem != nil && kf.graphics != nil {
+		if err := vm.graphicsSystem.RestoreKeyframe(kf.graphics); err != nil {
+			vm.log.Error("Failed to restore the graphics keyframe, rewind cancelled", "tick", kf.ticks, "error", err)
+			if vm.audioSystem != nil && vm.seek == nil {
+				vm.audioSystem.Resume()
+			}
+			return
+		}
+	}
+	vm.restoreKeyframe(kf, now)
+
+	k.mu.Lock()
+	for i, frame := range k.frames {
+		if frame == kf {
+			for _, newer := range k.frames[i+1:] {
+				newer.release()
+			}
+			k.frames = k.frames[:i+1]
+			break
+		}
+	}
+	k.mu.Unlock()
+	k.taken, k.last = true, kf.ticks
+
+	s := &chapterSeek{target: req.chapter, start: now}
+	if req.chapter == "" {
+		s.untilTicks = req.ticks
+	}
+	if kf.midiFile != "" {
+		s.midiBase, s.midiTick = kf.midiFileTick, kf.midiFileTick
+	}
+	vm.seek = s
+	vm.log.Info("Rewound to keyframe", "tick", kf.ticks, "target", s.goal())
+}
+
+// restoreKeyframe replaces the VM state and the MIDI position with the keyframe.
+// The keyframe is copied again, so it can be restored again later. Audio must be paused.
+func (vm *VM) restoreKeyframe(kf *vmKeyframe, now time.Time) {
+	st := kf.state.clone(newStateCloner())
+	shift := now.Sub(kf.now)
+	for _, t := range st.timers {
+		t.next = t.next.Add(shift)
+	}
+	vm.install(st)
+	vm.ticks.Store(kf.ticks)
+	vm.midiTick.Store(kf.midiTick)
+	vm.midiFile = kf.midiFile
+	vm.chapters.mu.Lock()
+	vm.chapters.passed = vm.chapters.passed[:min(kf.passed, len(vm.chapters.passed))]
+	vm.chapters.mu.Unlock()
+
+	if vm.audioSystem == nil {
+		return
+	}
+	vm.audioSystem.StopMIDIPort("")
+	if kf.midiFile != "" {
+		if err := vm.audioSystem.PlayMIDI(kf.midiFile); err != nil {
+			vm.log.Warn("Failed to restart MIDI at the keyframe", "file", kf.midiFile, "error", err)
+		} else if kf.midiFileTick > 0 {
+			if err := vm.audioSystem.SkipMIDI(kf.midiFileTick); err != nil {
+				vm.log.Warn("Failed to move MIDI to the keyframe", "tick", kf.midiFileTick, "error", err)
+			}
+		}
+	}
+	if kf.timerRunning {
+		vm.audioSystem.StartTimer()
+	} else {
+		vm.audioSystem.StopTimer()
+	}
+}
+
+// vmState is the script state kept by a keyframe. liveState refers to the
+// running state and clone copies it.
+type vmState struct {
+	globalScope *Scope
+	localScope  *Scope
+	stepCounter int
+
+	handlers      map[EventType][]*EventHandler
+	nextHandlerID int
+	events        []*Event
+
+	timers      map[int]*scriptTimer
+	spriteTicks map[int][]*EventHandler
+	spawned     map[int]*EventHandler
+	flipbooks   map[int]*flipbook
+	crossfades  map[int]*crossfade
+	paths       map[int]*graphics.Path
+	nextPathID  int
+	mainRand    *sequenceStream
+}
+
+// liveState returns the running state. It is called from the event loop between
+// events, when no sequence is executing.
+func (vm *VM) liveState() *vmState {
+	st := &vmState{
+		globalScope: vm.globalScope,
+		localScope:  vm.localScope,
+		stepCounter: vm.stepCounter,
+		timers:      vm.timers,
+		spriteTicks: vm.spriteTicks,
+		spawned:     vm.spawned,
+		flipbooks:   vm.flipbooks,
+		crossfades:  vm.crossfades,
+		paths:       vm.paths,
+		nextPathID:  vm.nextPathID,
+		mainRand:    vm.mainRand,
+	}
+	hr := vm.handlerRegistry
+	hr.mu.RLock()
+	st.handlers = maps.Clone(hr.handlers)
+	st.nextHandlerID = hr.nextID
+	hr.mu.RUnlock()
+
+	eq := vm.eventQueue
+	eq.mu.Lock()
+	st.events = append([]*Event(nil), eq.events...)
+	eq.mu.Unlock()
+	return st
+}
+
+// install replaces the running state with st.
+func (vm *VM) install(st *vmState) {
+	vm.globalScope, vm.localScope, vm.stepCounter = st.globalScope, st.localScope, st.stepCounter
+	vm.timers, vm.spriteTicks, vm.spawned = st.timers, st.spriteTicks, st.spawned
+	vm.flipbooks, vm.crossfades = st.flipbooks, st.crossfades
+	vm.paths, vm.nextPathID = st.paths, st.nextPathID
+	vm.mainRand = st.mainRand
+
+	hr := vm.handlerRegistry
+	hr.mu.Lock()
+	hr.handlers = st.handlers
+	hr.handlersByID = make(map[string]*EventHandler)
+	for _, list := range st.handlers {
+		for _, h := range list {
+			hr.handlersByID[h.ID] = h
+		}
+	}
+	hr.nextID = st.nextHandlerID
+	hr.mu.Unlock()
+
+	eq := vm.eventQueue
+	eq.mu.Lock()
+	eq.events = st.events
+	eq.mu.Unlock()
+}
+
+// clone copies st. Scopes, arrays and sequences shared by several parts of the
+// state are copied once and stay shared in the copy.
+func (st *vmState) clone(c *stateCloner) *vmState {
+	d := *st
+	d.globalScope, d.localScope = c.scope(st.globalScope), c.scope(st.localScope)
+	d.mainRand = st.mainRand.clone()
+
+	d.handlers = make(map[EventType][]*EventHandler, len(st.handlers))
+	for eventType, list := range st.handlers {
+		d.handlers[eventType] = c.handlers(list)
+	}
+	d.events = make([]*Event, len(st.events))
+	for i, e := range st.events {
+		cp := *e
+		cp.Params = maps.Clone(e.Params)
+		d.events[i] = &cp
+	}
+
+	if st.timers != nil {
+		d.timers = make(map[int]*scriptTimer, len(st.timers))
+		for id, t := range st.timers {
+			cp := *t
+			cp.handler = c.handler(t.handler)
+			d.timers[id] = &cp
+		}
+	}
+	if st.spriteTicks != nil {
+		d.spriteTicks = make(map[int][]*EventHandler, len(st.spriteTicks))
+		for castID, list := range st.spriteTicks {
+			d.spriteTicks[castID] = c.handlers(list)
+		}
+	}
+	if st.spawned != nil {
+		d.spawned = make(map[int]*EventHandler, len(st.spawned))
+		for id, h := range st.spawned {
+			d.spawned[id] = c.handler(h)
+		}
+	}
+	if st.flipbooks != nil {
+		d.flipbooks = make(map[int]*flipbook, len(st.flipbooks))
+		for castID, fb := range st.flipbooks {
+			cp := *fb
+			d.flipbooks[castID] = &cp
+		}
+	}
+	if st.crossfades != nil {
+		d.crossfades = make(map[int]*crossfade, len(st.crossfades))
+		for castID, cf := range st.crossfades {
+			cp := *cf
+			d.crossfades[castID] = &cp
+		}
+	}
+	// Paths do not change once defined, so they are shared
+	d.paths = maps.Clone(st.paths)
+	return &d
+}
+
+// stateCloner copies each scope, array and sequence of a state once.
+type stateCloner struct {
+	scopes   map[*Scope]*Scope
+	arrays   map[*Array]*Array
+	sequence map[*EventHandler]*EventHandler
+}
+
+func newStateCloner() *stateCloner {
+	return &stateCloner{
+		scopes:   make(map[*Scope]*Scope),
+		arrays:   make(map[*Array]*Array),
+		sequence: make(map[*EventHandler]*EventHandler),
+	}
+}
+
+// scope copies a scope with its parents.
+func (c *stateCloner) scope(s *Scope) *Scope {
+	if s == nil {
+		return nil
+	}
+	if cp, ok := c.scopes[s]; ok {
+		return cp
+	}
+	cp := &Scope{}
+	c.scopes[s] = cp
+	s.mu.RLock()
+	cp.variables = make(map[string]any, len(s.variables))
+	for name, v := range s.variables {
+		cp.variables[name] = c.value(v)
+	}
+	s.mu.RUnlock()
+	cp.parent = c.scope(s.parent)
+	return cp
+}
+
+// value copies the arrays in a variable's value; other values are immutable.
+func (c *stateCloner) value(v any) any {
+	arr, ok := v.(*Array)
+	if !ok || arr == nil {
+		return v
+	}
+	if cp, ok := c.arrays[arr]; ok {
+		return cp
+	}
+	cp := &Array{}
+	c.arrays[arr] = cp
+	arr.mu.RLock()
+	cp.elements = make([]any, len(arr.elements))
+	for i, e := range arr.elements {
+		cp.elements[i] = c.value(e)
+	}
+	arr.mu.RUnlock()
+	return cp
+}
+
+// handler copies a sequence with its scope, wait and Random() stream.
+// The execution trace starts over in the copy.
+func (c *stateCloner) handler(h *EventHandler) *EventHandler {
+	if h == nil {
+		return nil
+	}
+	if cp, ok := c.sequence[h]; ok {
+		return cp
+	}
+	cp := *h
+	c.sequence[h] = &cp
+	cp.ParentScope = c.scope(h.ParentScope)
+	cp.rng = h.rng.clone()
+	cp.trace = nil
+	if h.waitAny != nil {
+		w := *h.waitAny
+		w.events = append([]EventType(nil), h.waitAny.events...)
+		cp.waitAny = &w
+	}
+	return &cp
+}
+
+func (c *stateCloner) handlers(list []*EventHandler) []*EventHandler {
+	cp := make([]*EventHandler, len(list))
+	for i, h := range list {
+		cp[i] = c.handler(h)
+	}
+	return cp
+}
+
+// goal describes where the fast-forward stops, for log messages.
+func (s *chapterSeek) goal() string {
+	if s.target != "" {
+		return "chapter " + s.target
+	}
+	return fmt.Sprintf("tick %d", s.untilTicks)
+}
+
+// lastPass returns the index in passed of the last pass of a chapter, or -1.
+func lastPass(passed []string, name string) int {
+	for i := len(passed) - 1; i >= 0; i-- {
+		if strings.EqualFold(passed[i], name) {
+			return i
+		}
+	}
+	return -1
+}
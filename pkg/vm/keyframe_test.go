@@ -0,0 +1,260 @@
+package vm
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/zurustar/son-et/pkg/opcode"
+)
+
+// newKeyframeTestVM returns a VM taking a keyframe every 10 ticks (500ms) with a
+// mock audio system playing song.mid and a mock graphics system.
+func newKeyframeTestVM() (*VM, *mockAudioSystem, *mockGraphicsSystem) {
+	vm, audio := newChapterTestVM()
+	WithKeyframeInterval(500 * time.Millisecond)(vm)
+	gs := newMockGraphicsSystem()
+	vm.graphicsSystem = gs
+	audio.StartTimer()
+	audio.PlayMIDI("song.mid")
+	vm.midiFile = "song.mid"
+	return vm, audio, gs
+}
+
+// TestKeyframeRestore tests that a rewind restores variables (with shared arrays),
+// sequences, queued events, timers, Random() streams and the MIDI position, and
+// fast-forwards to the target tick.
+func TestKeyframeRestore(t *testing.T) {
+	vm, audio, gs := newKeyframeTestVM()
+	arr := NewArrayFromSlice([]any{int64(1), int64(2)})
+	vm.globalScope.Set("x", int64(1))
+	vm.globalScope.Set("a", arr)
+	vm.globalScope.Set("b", arr)
+	h := NewEventHandler("", EventTIME, nil, vm, NewScope(vm.globalScope))
+	h.ParentScope.Set("local", "before")
+	vm.handlerRegistry.Register(h)
+	vm.eventQueue.Push(NewEvent(EventTIME))
+	start := time.Now()
+	timerID := vm.registerTimer([]any{"f"}, time.Second, true, start)
+	vm.currentHandler = h
+	wantRand := vm.sequenceRand().clone().Int64N(1 << 40)
+	vm.currentHandler = nil
+	vm.ticks.Store(20)
+
+	vm.takeKeyframe(start)
+	if gs.keyframes != 1 || len(vm.keyframes.frames) != 1 {
+		t.Fatalf("keyframes = %d graphics, %d kept; want 1, 1", gs.keyframes, len(vm.keyframes.frames))
+	}
+	if kf := vm.keyframes.frames[0]; kf.midiFile != "song.mid" || kf.midiFileTick != 0 || !kf.timerRunning {
+		t.Errorf("keyframe audio = %q at %d, timer %v; want song.mid at 0 with the timer", kf.midiFile, kf.midiFileTick, kf.timerRunning)
+	}
+
+	// Change the state, then rewind
+	vm.globalScope.Set("x", int64(2))
+	arr.Set(0, int64(9))
+	h.ParentScope.Set("local", "after")
+	h.CurrentPC, h.WaitCounter = 5, 3
+	vm.currentHandler = h
+	vm.sequenceRand().Int64N(10)
+	vm.currentHandler = nil
+	vm.eventQueue.Clear()
+	vm.handlerRegistry.Unregister(h.ID)
+	vm.ticks.Store(60)
+	audio.StopMIDIPort("")
+
+	if err := vm.Rewind(1500 * time.Millisecond); err != nil {
+		t.Fatal(err)
+	}
+	now := start.Add(3 * time.Second)
+	vm.advanceChapterSeek(now)
+
+	if gs.restoredKeyframes != 1 {
+		t.Errorf("graphics keyframes restored = %d, want 1", gs.restoredKeyframes)
+	}
+	if x, _ := vm.globalScope.Get("x"); x != int64(1) {
+		t.Errorf("x = %v, want 1", x)
+	}
+	a, _ := vm.globalScope.Get("a")
+	b, _ := vm.globalScope.Get("b")
+	if a != b || a == arr {
+		t.Error("expected the arrays to be copied once and stay shared")
+	}
+	if v, _ := a.(*Array).Get(0); v != int64(1) {
+		t.Errorf("a[0] = %v, want 1", v)
+	}
+	handlers := vm.handlerRegistry.GetHandlers(EventTIME)
+	if len(handlers) != 1 || handlers[0] == h {
+		t.Fatalf("handlers = %v, want a copy of the sequence", handlers)
+	}
+	restored := handlers[0]
+	if restored.CurrentPC != 0 || restored.WaitCounter != 0 {
+		t.Errorf("sequence at pc %d waiting %d, want 0, 0", restored.CurrentPC, restored.WaitCounter)
+	}
+	if local, _ := restored.ParentScope.Get("local"); local != "before" {
+		t.Errorf("local = %v, want before", local)
+	}
+	if vm.timers[timerID].handler != vm.handlerRegistry.GetHandlers(EventTIMER)[0] {
+		t.Error("expected the timer to refer to the restored TIMER sequence")
+	}
+	if left := vm.timers[timerID].next.Sub(now); left != time.Second {
+		t.Errorf("timer due %v after the rewind, want 1s", left)
+	}
+	vm.currentHandler = restored
+	if got := vm.sequenceRand().Int64N(1 << 40); got != wantRand {
+		t.Errorf("Random() after the rewind = %d, want %d", got, wantRand)
+	}
+	vm.currentHandler = nil
+
+	if vm.ticks.Load() != 20 || vm.seek == nil || vm.seek.untilTicks != 30 {
+		t.Fatalf("ticks %d, seek %+v; want tick 20 and a fast-forward to tick 30", vm.ticks.Load(), vm.seek)
+	}
+	if audio.midiFile != "song.mid" {
+		t.Errorf("MIDI = %q, want song.mid restarted", audio.midiFile)
+	}
+	if !audio.paused {
+		t.Error("expected audio to be paused while fast-forwarding")
+	}
+
+	// The fast-forward ends at the target tick
+	vm.ticks.Store(30)
+	vm.advanceChapterSeek(now)
+	if vm.seek != nil || audio.paused {
+		t.Errorf("seek = %+v, paused %v; want normal playback at the target tick", vm.seek, audio.paused)
+	}
+}
+
+// TestKeyframeRestoreAgain tests that a keyframe can be restored more than once and
+// that the keyframes after it are dropped.
+func TestKeyframeRestoreAgain(t *testing.T) {
+	vm, _, _ := newKeyframeTestVM()
+	vm.globalScope.Set("x", int64(1))
+	vm.takeKeyframe(time.Now())
+	vm.ticks.Store(10)
+	vm.takeKeyframe(time.Now())
+
+	for range 2 {
+		vm.globalScope.Set("x", int64(2))
+		vm.ticks.Store(15)
+		if err := vm.Rewind(500 * time.Millisecond); err != nil {
+			t.Fatal(err)
+		}
+		vm.startRewind(time.Now())
+		if x, _ := vm.globalScope.Get("x"); x != int64(1) {
+			t.Errorf("x = %v, want 1", x)
+		}
+		if len(vm.keyframes.frames) != 1 || vm.ticks.Load() != 0 {
+			t.Errorf("%d keyframes at tick %d, want the first keyframe only", len(vm.keyframes.frames), vm.ticks.Load())
+		}
+	}
+}
+
+func TestTakeKeyframeInterval(t *testing.T) {
+	vm, _, gs := newKeyframeTestVM()
+	for tick := range int64(25) {
+		vm.ticks.Store(tick)
+		vm.takeKeyframe(time.Now())
+	}
+	if len(vm.keyframes.frames) != 3 || gs.keyframes != 3 {
+		t.Fatalf("%d keyframes, want 3 (ticks 0, 10, 20)", len(vm.keyframes.frames))
+	}
+	for i, kf := range vm.keyframes.frames {
+		if kf.ticks != int64(i*10) {
+			t.Errorf("keyframe %d at tick %d, want %d", i, kf.ticks, i*10)
+		}
+	}
+
+	for tick := int64(30); len(vm.keyframes.frames) < keyframeLimit || tick < 1000; tick += 10 {
+		vm.ticks.Store(tick)
+		vm.takeKeyframe(time.Now())
+	}
+	if len(vm.keyframes.frames) != keyframeLimit {
+		t.Errorf("%d keyframes kept, want %d", len(vm.keyframes.frames), keyframeLimit)
+	}
+
+	off, _ := newChapterTestVM()
+	off.takeKeyframe(time.Now())
+	if len(off.keyframes.frames) != 0 {
+		t.Error("expected no keyframes without WithKeyframeInterval")
+	}
+}
+
+func TestRewindErrors(t *testing.T) {
+	off, _ := newChapterTestVM()
+	if err := off.Rewind(time.Second); err == nil || !strings.Contains(err.Error(), "--keyframe-interval") {
+		t.Errorf("Rewind without keyframes = %v, want a hint about --keyframe-interval", err)
+	}
+
+	vm, _, _ := newKeyframeTestVM()
+	if err := vm.Rewind(time.Second); err == nil {
+		t.Error("expected an error before the first keyframe")
+	}
+	vm.ticks.Store(100)
+	vm.takeKeyframe(time.Now())
+	vm.ticks.Store(120)
+	if err := vm.Rewind(2 * time.Second); err == nil || !strings.Contains(err.Error(), "tick 100") {
+		t.Errorf("Rewind before the oldest keyframe = %v, want an error naming it", err)
+	}
+	if err := vm.Rewind(0); err == nil {
+		t.Error("expected an error for a zero duration")
+	}
+}
+
+// TestSeekChapterRewind tests that a passed chapter is reached by restoring a
+// keyframe taken before it and fast-forwarding until Chapter executes again.
+func TestSeekChapterRewind(t *testing.T) {
+	vm, _, _ := newKeyframeTestVM()
+	vm.reachChapter("intro")
+	vm.takeKeyframe(time.Now())
+	vm.reachChapter("Verse2")
+	vm.ticks.Store(10)
+	vm.takeKeyframe(time.Now())
+
+	if err := vm.SeekChapter("verse2"); err != nil {
+		t.Fatal(err)
+	}
+	vm.advanceChapterSeek(time.Now())
+	if vm.seek == nil || vm.seek.target != "Verse2" || vm.ticks.Load() != 0 {
+		t.Fatalf("seek %+v at tick %d, want a fast-forward to Verse2 from tick 0", vm.seek, vm.ticks.Load())
+	}
+	if got := strings.Join(vm.PassedChapters(), ","); got != "intro" {
+		t.Errorf("PassedChapters() = %s, want intro", got)
+	}
+	vm.reachChapter("Verse2")
+	if vm.seek != nil {
+		t.Error("expected Verse2 to end the fast-forward")
+	}
+
+	// A chapter passed before the oldest keyframe cannot be reached
+	if err := vm.SeekChapter("intro"); err == nil || !strings.Contains(err.Error(), "--chapter") {
+		t.Errorf("SeekChapter(intro) = %v, want a restart hint", err)
+	}
+}
+
+func TestRunCommandRewind(t *testing.T) {
+	vm, _, _ := newKeyframeTestVM()
+	vm.takeKeyframe(time.Now())
+	vm.ticks.Store(40)
+	if out, err := vm.RunCommand("rewind 1.5"); err != nil || out != "rewinding 1.5 seconds" {
+		t.Errorf("rewind 1.5 = %q, %v", out, err)
+	}
+	if req := vm.keyframes.requested; req == nil || req.ticks != 10 {
+		t.Errorf("requested = %+v, want tick 10", req)
+	}
+	for _, line := range []string{"rewind", "rewind -1", "rewind x"} {
+		if _, err := vm.RunCommand(line); err == nil {
+			t.Errorf("%s: expected an error", line)
+		}
+	}
+}
+
+func TestSequenceStreamClone(t *testing.T) {
+	vm := New([]opcode.OpCode{}, WithRandomSeed(7))
+	s := vm.newSequenceRand(1)
+	s.Int64N(100)
+	c := s.clone()
+	for range 5 {
+		if a, b := s.Int64N(1<<40), c.Int64N(1<<40); a != b {
+			t.Fatalf("clone drew %d, want %d", b, a)
+		}
+	}
+}
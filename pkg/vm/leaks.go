@@ -0,0 +1,54 @@
+package vm
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/zurustar/son-et/pkg/graphics"
+)
+
+// Picture leak report
+//
+// The graphics system records the statement that created each picture (LoadPic,
+// CreatePic, ...) and when a visible window or cast last showed it. Pictures hidden
+// for a while are reported as candidates for a forgotten DelPic (the leaks command
+// and GET /memory of the watch API).
+
+// imageSampleInterval is how often the event loop records the pictures shown on screen
+const imageSampleInterval = time.Second
+
+// imageSite names the statement being executed, e.g. `sequence 2: LoadPic("BG.BMP")`.
+// The graphics system calls it when a picture is created.
+func (vm *VM) imageSite() string {
+	sequence := mainSequenceID
+	if vm.currentHandler != nil {
+		sequence = vm.currentHandler.Number
+	}
+	trace := vm.currentTrace()
+	if len(trace) == 0 {
+		return fmt.Sprintf("sequence %d", sequence)
+	}
+	return fmt.Sprintf("sequence %d: %s", sequence, trace[len(trace)-1])
+}
+
+// sampleImages records the pictures shown on screen once per imageSampleInterval.
+func (vm *VM) sampleImages(now time.Time) {
+	if vm.graphicsSystem == nil || now.Sub(vm.lastImageSample) < imageSampleInterval {
+		return
+	}
+	vm.lastImageSample = now
+	vm.graphicsSystem.SampleImageUsage(now)
+}
+
+// ImageReport returns the picture memory and the pictures that no visible window
+// or cast has shown for idle or longer (graphics.DefaultLeakIdle if idle <= 0).
+func (vm *VM) ImageReport(idle time.Duration) (*graphics.ImageReport, error) {
+	if vm.graphicsSystem == nil {
+		return nil, errors.New("graphics is not available")
+	}
+	if idle <= 0 {
+		idle = graphics.DefaultLeakIdle
+	}
+	return vm.graphicsSystem.ImageReport(idle, vm.clock.Now()), nil
+}
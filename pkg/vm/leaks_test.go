@@ -0,0 +1,89 @@
+package vm
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/zurustar/son-et/pkg/graphics"
+	"github.com/zurustar/son-et/pkg/opcode"
+)
+
+// TestImageSite tests that the site names the sequence and the statement being executed.
+func TestImageSite(t *testing.T) {
+	vm := New([]opcode.OpCode{})
+	gs := newMockGraphicsSystem()
+	vm.SetGraphicsSystem(gs)
+	if gs.imageSite == nil {
+		t.Fatal("SetGraphicsSystem did not set the image site")
+	}
+
+	if got := gs.imageSite(); got != "sequence 0" {
+		t.Errorf("site before any statement = %q, want sequence 0", got)
+	}
+	vm.traceStatement(TraceEntry{Cmd: opcode.Call, Name: "LoadPic", Args: []any{"BG.BMP"}})
+	if got := gs.imageSite(); got != `sequence 0: LoadPic("BG.BMP")` {
+		t.Errorf("site = %q", got)
+	}
+
+	handler := NewEventHandler("", EventTIME, nil, vm, vm.GetCurrentScope())
+	vm.currentHandler = handler
+	vm.traceStatement(TraceEntry{Cmd: opcode.Call, Name: "CreatePic", Args: []any{int64(64), int64(32)}})
+	want := fmt.Sprintf("sequence %d: CreatePic(64, 32)", handler.Number)
+	if got := gs.imageSite(); got != want {
+		t.Errorf("site in a handler = %q, want %q", got, want)
+	}
+}
+
+// TestSampleImages tests that the event loop samples the screen once per interval.
+func TestSampleImages(t *testing.T) {
+	vm := New([]opcode.OpCode{})
+	vm.sampleImages(time.Now()) // グラフィックスがなくても何もしない
+
+	gs := newMockGraphicsSystem()
+	vm.SetGraphicsSystem(gs)
+	now := time.Now()
+	vm.sampleImages(now)
+	vm.sampleImages(now.Add(imageSampleInterval / 2))
+	vm.sampleImages(now.Add(imageSampleInterval))
+	if len(gs.imageSamples) != 2 {
+		t.Errorf("sampled %d times, want 2", len(gs.imageSamples))
+	}
+}
+
+func TestImageReport(t *testing.T) {
+	vm := New([]opcode.OpCode{})
+	if _, err := vm.ImageReport(0); err == nil {
+		t.Error("expected an error without a graphics system")
+	}
+
+	gs := newMockGraphicsSystem()
+	vm.SetGraphicsSystem(gs)
+	if _, err := vm.ImageReport(0); err != nil || gs.imageIdle != graphics.DefaultLeakIdle {
+		t.Errorf("ImageReport(0) = %v with idle %v, want the default idle", err, gs.imageIdle)
+	}
+}
+
+func TestRunCommandLeaks(t *testing.T) {
+	vm := New([]opcode.OpCode{})
+	gs := newMockGraphicsSystem()
+	vm.SetGraphicsSystem(gs)
+	gs.CreatePic(64, 32)
+
+	out, err := vm.RunCommand("leaks 2.5")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if gs.imageIdle != 2500*time.Millisecond {
+		t.Errorf("idle = %v, want 2.5s", gs.imageIdle)
+	}
+	if !strings.Contains(out, "pic 0 64x32") {
+		t.Errorf("leaks output does not list the picture:\n%s", out)
+	}
+	for _, line := range []string{"leaks 0", "leaks soon"} {
+		if _, err := vm.RunCommand(line); err == nil {
+			t.Errorf("RunCommand(%q) should fail", line)
+		}
+	}
+}
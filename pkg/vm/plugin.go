@@ -0,0 +1,58 @@
+package vm
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// pluginBuiltins are the builtins registered with RegisterPluginBuiltin.
+// They are registered in every VM created by New, in registration order.
+var pluginBuiltins = struct {
+	sync.RWMutex
+	names []string
+	funcs map[string]BuiltinFunc // keyed by lower-case name
+}{funcs: make(map[string]BuiltinFunc)}
+
+// RegisterPluginBuiltin registers a built-in function implemented outside the engine
+// (such as by a package linked in with a build tag) for every VM created afterwards.
+// Plugin builtins are available in every compatibility mode.
+//
+// Use engine.RegisterBuiltin, which also checks the name and registers the signature
+// that the compiler and the VM check the arguments against.
+func RegisterPluginBuiltin(name string, fn BuiltinFunc) error {
+	if fn == nil {
+		return fmt.Errorf("built-in function %s has no implementation", name)
+	}
+	pluginBuiltins.Lock()
+	defer pluginBuiltins.Unlock()
+	key := strings.ToLower(name)
+	if _, ok := pluginBuiltins.funcs[key]; ok {
+		return fmt.Errorf("%s is already registered", name)
+	}
+	pluginBuiltins.names = append(pluginBuiltins.names, name)
+	pluginBuiltins.funcs[key] = fn
+	return nil
+}
+
+// PluginBuiltins returns the names of the plugin builtins, in registration order.
+func PluginBuiltins() []string {
+	pluginBuiltins.RLock()
+	defer pluginBuiltins.RUnlock()
+	return slices.Clone(pluginBuiltins.names)
+}
+
+// registerPluginBuiltins registers the plugin builtins in the VM.
+// It is called after the standard builtins, which win over a plugin builtin of the same name.
+func (vm *VM) registerPluginBuiltins() {
+	pluginBuiltins.RLock()
+	defer pluginBuiltins.RUnlock()
+	for _, name := range pluginBuiltins.names {
+		if vm.HasBuiltin(name) {
+			vm.log.Warn("Plugin built-in function conflicts with a built-in function", "function", name)
+			continue
+		}
+		vm.RegisterBuiltinFunction(name, pluginBuiltins.funcs[strings.ToLower(name)])
+	}
+}
@@ -0,0 +1,49 @@
+package vm
+
+// Run progress (--progress).
+// It reports the ticks handled, the MIDI tick, the number of registered mes() blocks and
+// the last statement executed, so that a test harness running long headless sessions can
+// tell a stalled run and stop it.
+
+// lastStatement is the statement executed last by any sequence.
+type lastStatement struct {
+	sequence int
+	entry    TraceEntry
+	ok       bool // Whether any statement has been executed
+}
+
+// Progress is a snapshot of how far a run has progressed.
+type Progress struct {
+	Ticks         int64  // Ticks handled so far (counted like --exit-after-ticks)
+	MIDITick      int    // MIDI tick of the last MIDI_TIME event (-1 if none)
+	Sequences     int    // Registered mes() blocks
+	LastSequence  int    // Sequence that executed LastStatement (0 = main)
+	LastStatement string // Statement executed last by any sequence (empty if none or the trace is disabled)
+}
+
+// Progress returns how far the run has progressed.
+// It is safe to call from other goroutines while the VM runs.
+func (vm *VM) Progress() Progress {
+	p := Progress{
+		Ticks:     vm.ticks.Load(),
+		MIDITick:  vm.MIDITick(),
+		Sequences: len(vm.handlerRegistry.GetAllHandlers()),
+	}
+	vm.traceMu.Lock()
+	last := vm.lastTrace
+	vm.traceMu.Unlock()
+	if last.ok {
+		p.LastSequence = last.sequence
+		p.LastStatement = last.entry.String()
+	}
+	return p
+}
+
+// currentSequenceNumber returns the number of the running sequence (mainSequenceID outside
+// event handlers).
+func (vm *VM) currentSequenceNumber() int {
+	if vm.currentHandler != nil {
+		return vm.currentHandler.Number
+	}
+	return mainSequenceID
+}
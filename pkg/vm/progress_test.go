@@ -0,0 +1,54 @@
+package vm
+
+import (
+	"testing"
+
+	"github.com/zurustar/son-et/pkg/opcode"
+)
+
+// TestProgress tests that Progress reports the ticks, MIDI tick, sequences and the last statement.
+func TestProgress(t *testing.T) {
+	vm := New([]opcode.OpCode{})
+	if p := vm.Progress(); p != (Progress{MIDITick: -1}) {
+		t.Errorf("initial Progress = %+v", p)
+	}
+
+	vm.RegisterBuiltinFunction("step", func(v *VM, args []any) (any, error) { return nil, nil })
+	handler := NewEventHandler("", EventTIME, []opcode.OpCode{
+		{Cmd: opcode.Call, Args: []any{"step", int64(7)}},
+	}, vm, nil)
+	vm.handlerRegistry.Register(handler)
+
+	for _, event := range []*Event{
+		NewEvent(EventTIME),
+		NewEvent(EventTIME),
+		NewEventWithParams(EventMIDI_TIME, map[string]any{"Tick": 240}),
+	} {
+		dispatchEvents(t, vm, event)
+		vm.countTick(event)
+	}
+
+	p := vm.Progress()
+	if p.Ticks != 2 {
+		t.Errorf("Ticks = %d, want 2 (MIDI_TIME is not a tick while mes(TIME) exists)", p.Ticks)
+	}
+	if p.MIDITick != 240 || p.Sequences != 1 {
+		t.Errorf("MIDITick, Sequences = %d, %d, want 240, 1", p.MIDITick, p.Sequences)
+	}
+	if p.LastSequence != handler.Number || p.LastStatement != "step(7)" {
+		t.Errorf("last statement = %d %q, want %d \"step(7)\"", p.LastSequence, p.LastStatement, handler.Number)
+	}
+}
+
+// TestCountTickWithoutLimit tests that ticks are counted without a tick limit but never stop the run.
+func TestCountTickWithoutLimit(t *testing.T) {
+	vm := New([]opcode.OpCode{})
+	for i := 0; i < 3; i++ {
+		if vm.countTick(NewEvent(EventTIME)) {
+			t.Fatal("countTick reported the limit without WithTickLimit")
+		}
+	}
+	if vm.countTick(NewEvent(EventKEY)) || vm.Progress().Ticks != 3 {
+		t.Errorf("Ticks = %d, want 3", vm.Progress().Ticks)
+	}
+}
@@ -1,6 +1,7 @@
 package vm
 
 import (
+	"slices"
 	"testing"
 
 	"github.com/zurustar/son-et/pkg/opcode"
@@ -96,3 +97,91 @@ func TestRandomNoArgs(t *testing.T) {
 		t.Error("Random() should return error when called with no arguments")
 	}
 }
+
+// drawRandom draws n numbers from Random(1000) in the sequence of handler (nil = main).
+func drawRandom(t *testing.T, vm *VM, handler *EventHandler, n int) []int64 {
+	t.Helper()
+	vm.currentHandler = handler
+	defer func() { vm.currentHandler = nil }()
+
+	fn := vm.builtins["Random"]
+	values := make([]int64, n)
+	for i := range values {
+		result, err := fn(vm, []any{int64(1000)})
+		if err != nil {
+			t.Fatalf("Random(1000) returned error: %v", err)
+		}
+		values[i] = result.(int64)
+	}
+	return values
+}
+
+// TestRandomSeedReproducible tests that the same seed and project reproduce each sequence's stream.
+func TestRandomSeedReproducible(t *testing.T) {
+	newVM := func(title string, seed uint64) *VM {
+		return New(nil, WithTitlePath("/titles/"+title), WithRandomSeed(seed))
+	}
+
+	a := drawRandom(t, newVM("demo", 42), nil, 10)
+	b := drawRandom(t, newVM("demo", 42), nil, 10)
+	if !slices.Equal(a, b) {
+		t.Errorf("same seed should reproduce: %v vs %v", a, b)
+	}
+	if c := drawRandom(t, newVM("demo", 43), nil, 10); slices.Equal(a, c) {
+		t.Error("a different seed should produce a different stream")
+	}
+	if d := drawRandom(t, newVM("other", 42), nil, 10); slices.Equal(a, d) {
+		t.Error("a different project should produce a different stream")
+	}
+
+	// Where the title is installed (its parent directory) does not affect the results
+	moved := New(nil, WithTitlePath("/elsewhere/demo"), WithRandomSeed(42))
+	if e := drawRandom(t, moved, nil, 10); !slices.Equal(a, e) {
+		t.Errorf("moving the title should not change the stream: %v vs %v", a, e)
+	}
+}
+
+// TestRandomSequencesIndependent tests that each handler has its own stream,
+// so interleaving draws between sequences does not change their results.
+func TestRandomSequencesIndependent(t *testing.T) {
+	newHandlers := func(vm *VM) (*EventHandler, *EventHandler) {
+		h1 := NewEventHandler("", EventTIME, nil, vm, nil)
+		h2 := NewEventHandler("", EventTIME, nil, vm, nil)
+		vm.handlerRegistry.Register(h1)
+		vm.handlerRegistry.Register(h2)
+		return h1, h2
+	}
+
+	// First run: h1 draws 10 numbers, then h2 draws 10
+	vm1 := New(nil, WithRandomSeed(7))
+	h1, h2 := newHandlers(vm1)
+	first1 := drawRandom(t, vm1, h1, 10)
+	first2 := drawRandom(t, vm1, h2, 10)
+
+	// Second run: main and h2 draw first, then h1
+	vm2 := New(nil, WithRandomSeed(7))
+	g1, g2 := newHandlers(vm2)
+	drawRandom(t, vm2, nil, 5)
+	second2 := drawRandom(t, vm2, g2, 10)
+	second1 := drawRandom(t, vm2, g1, 10)
+
+	if !slices.Equal(first1, second1) || !slices.Equal(first2, second2) {
+		t.Error("sequence streams should not depend on interleaving")
+	}
+	if slices.Equal(first1, first2) {
+		t.Error("different sequences should have different streams")
+	}
+}
+
+// TestRandomSeedChosen tests that a run seed is chosen when none is given.
+func TestRandomSeedChosen(t *testing.T) {
+	vm := New(nil)
+	seed := vm.RandomSeed()
+	if vm.RandomSeed() != seed {
+		t.Error("RandomSeed should be stable once chosen")
+	}
+
+	if got := New(nil, WithRandomSeed(5)).RandomSeed(); got != 5 {
+		t.Errorf("RandomSeed() = %d, want 5", got)
+	}
+}
@@ -61,3 +61,28 @@ func TestResolveFilePathConfinement(t *testing.T) {
 		}
 	})
 }
+
+// TestResolveFilePathAssetVars tests that ${NAME} in file names is expanded with the
+// asset variables before the path is confined to the title directory.
+func TestResolveFilePathAssetVars(t *testing.T) {
+	titleDir := t.TempDir()
+	vm := New([]opcode.OpCode{}, WithTitlePath(titleDir), WithAssetVars(map[string]string{
+		"ASSETS": "hires",
+		"ESCAPE": "../..",
+	}))
+
+	got, err := vm.resolveFilePath("${ASSETS}/pic01.bmp")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if want := filepath.Join(titleDir, "hires", "pic01.bmp"); got != want {
+		t.Errorf("resolved path = %q, want %q", got, want)
+	}
+
+	if _, err := vm.resolveFilePath("${UNDEFINED}/pic01.bmp"); err == nil {
+		t.Error("expected error for an undefined variable")
+	}
+	if _, err := vm.resolveFilePath("${ESCAPE}/secret.txt"); err == nil {
+		t.Error("expected error for a variable that escapes the title directory")
+	}
+}
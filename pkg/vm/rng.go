@@ -0,0 +1,92 @@
+package vm
+
+import (
+	"hash/fnv"
+	"math/rand/v2"
+	"path/filepath"
+	"strconv"
+)
+
+// mainSequenceID is the number of the sequence that runs outside event handlers (main and so on).
+// Handler sequence numbers (the Number assigned by HandlerRegistry) start at 1.
+const mainSequenceID = 0
+
+// WithRandomSeed sets the run seed for Random() (--seed).
+//
+// Every sequence — the main script and each mes() handler — draws from its own
+// generator seeded from the project name, the sequence number and the run seed.
+// A sequence's results therefore depend only on how many numbers it has drawn,
+// not on how the sequences interleave in time, so replays and golden-image tests
+// reproduce exactly. Without this option a random run seed is chosen and logged.
+func WithRandomSeed(seed uint64) Option {
+	return func(vm *VM) {
+		vm.runSeed = seed
+		vm.runSeedSet = true
+	}
+}
+
+// RandomSeed returns the run seed used for Random().
+// Passing it to WithRandomSeed (--seed) reproduces the run.
+func (vm *VM) RandomSeed() uint64 {
+	vm.ensureRunSeed()
+	return vm.runSeed
+}
+
+// ensureRunSeed picks a random run seed when none is set and logs it so the run can be reproduced.
+func (vm *VM) ensureRunSeed() {
+	if vm.runSeedSet {
+		return
+	}
+	vm.runSeed = rand.Uint64()
+	vm.runSeedSet = true
+	vm.log.Info("Random seed chosen (pass --seed to reproduce)", "seed", vm.runSeed)
+}
+
+// sequenceStream is the random number generator of a sequence.
+// It keeps its PCG source so that keyframes (keyframe.go) can copy its state mid-run.
+type sequenceStream struct {
+	*rand.Rand
+	pcg *rand.PCG
+}
+
+func newSequenceStream(pcg *rand.PCG) *sequenceStream {
+	return &sequenceStream{Rand: rand.New(pcg), pcg: pcg}
+}
+
+// clone returns a generator that continues with the same numbers.
+func (s *sequenceStream) clone() *sequenceStream {
+	if s == nil {
+		return nil
+	}
+	pcg := *s.pcg
+	return newSequenceStream(&pcg)
+}
+
+// sequenceRand returns the generator of the running sequence, creating it on first use.
+func (vm *VM) sequenceRand() *sequenceStream {
+	if h := vm.currentHandler; h != nil {
+		if h.rng == nil {
+			h.rng = vm.newSequenceRand(h.Number)
+		}
+		return h.rng
+	}
+	if vm.mainRand == nil {
+		vm.mainRand = vm.newSequenceRand(mainSequenceID)
+	}
+	return vm.mainRand
+}
+
+// newSequenceRand creates a generator from the project name, the sequence number and the run seed.
+// The project name is the name of the title directory, not its absolute path, so that
+// the numbers do not depend on where the title is installed.
+func (vm *VM) newSequenceRand(sequenceID int) *sequenceStream {
+	vm.ensureRunSeed()
+
+	h := fnv.New64a()
+	if vm.titlePath != "" {
+		h.Write([]byte(filepath.Base(vm.titlePath)))
+	}
+	h.Write([]byte{0})
+	h.Write([]byte(strconv.Itoa(sequenceID)))
+	return newSequenceStream(rand.NewPCG(h.Sum64(), vm.runSeed))
+}
@@ -0,0 +1,52 @@
+package vm
+
+import (
+	"errors"
+	"fmt"
+)
+
+// SandboxMaxArrayLength is the maximum number of elements of an array in sandbox mode.
+// It keeps an assignment to a huge index from allocating unbounded memory.
+const SandboxMaxArrayLength = 1 << 20
+
+// ErrSandboxViolation is returned for operations that are not permitted in sandbox mode.
+var ErrSandboxViolation = errors.New("operation not permitted in sandbox mode")
+
+// WithSandbox enables sandbox mode for running untrusted titles (--sandbox).
+//
+// In sandbox mode:
+//   - File access from builtins is confined to the title directory, including
+//     symbolic links that point outside it; a title directory is required.
+//   - Shell/MCI/StrMCI passthroughs are refused.
+//   - Arrays are limited to SandboxMaxArrayLength elements.
+//
+// Picture memory and cast counts are capped by the graphics system (graphics.WithSandbox).
+func WithSandbox(enabled bool) Option {
+	return func(vm *VM) {
+		vm.sandbox = enabled
+	}
+}
+
+// IsSandboxed reports whether the VM runs in sandbox mode.
+func (vm *VM) IsSandboxed() bool {
+	return vm.sandbox
+}
+
+// refuseInSandbox logs and reports whether a builtin must not run because sandbox mode is enabled.
+func (vm *VM) refuseInSandbox(builtin string) bool {
+	if !vm.sandbox {
+		return false
+	}
+	vm.log.Warn(builtin+"() refused", "error", ErrSandboxViolation)
+	return true
+}
+
+// checkSandboxArrayIndex returns an error when sandbox mode is enabled and writing index
+// would grow the array beyond SandboxMaxArrayLength.
+func (vm *VM) checkSandboxArrayIndex(name string, index int64) error {
+	if vm.sandbox && index >= SandboxMaxArrayLength {
+		return fmt.Errorf("array %s index %d exceeds the sandbox limit of %d elements: %w",
+			name, index, SandboxMaxArrayLength, ErrSandboxViolation)
+	}
+	return nil
+}
@@ -0,0 +1,146 @@
+package vm
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/zurustar/son-et/pkg/opcode"
+)
+
+// TestSandboxResolveFilePath tests that sandbox mode rejects symlinks leaving the title directory.
+func TestSandboxResolveFilePath(t *testing.T) {
+	titleDir := t.TempDir()
+	outside := t.TempDir()
+
+	if err := os.WriteFile(filepath.Join(titleDir, "SCORE.INI"), []byte("[Score]\nHigh=10\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(outside, "secret.ini"), []byte("[Secret]\nKey=1\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(outside, filepath.Join(titleDir, "link")); err != nil {
+		t.Skipf("symlinks not supported: %v", err)
+	}
+
+	sandboxed := New([]opcode.OpCode{}, WithTitlePath(titleDir), WithSandbox(true))
+	if _, err := sandboxed.resolveFilePath("SCORE.INI"); err != nil {
+		t.Errorf("file inside the title directory should be allowed: %v", err)
+	}
+	if _, err := sandboxed.resolveFilePath("save/new.dat"); err != nil {
+		t.Errorf("new file inside the title directory should be allowed: %v", err)
+	}
+	if _, err := sandboxed.resolveFilePath("link/secret.ini"); !errors.Is(err, ErrSandboxViolation) {
+		t.Errorf("symlink leaving the title directory = %v, want ErrSandboxViolation", err)
+	}
+
+	// Without the sandbox, symbolic links are followed as before
+	normal := New([]opcode.OpCode{}, WithTitlePath(titleDir))
+	if _, err := normal.resolveFilePath("link/secret.ini"); err != nil {
+		t.Errorf("symlink should be followed outside sandbox mode: %v", err)
+	}
+}
+
+// TestSandboxRequiresTitlePath tests that sandbox mode refuses file access without a title directory.
+func TestSandboxRequiresTitlePath(t *testing.T) {
+	vm := New([]opcode.OpCode{}, WithSandbox(true))
+	if _, err := vm.resolveFilePath("data.txt"); !errors.Is(err, ErrSandboxViolation) {
+		t.Errorf("resolveFilePath without title path = %v, want ErrSandboxViolation", err)
+	}
+
+	result, err := vm.builtins["GetIniStr"](vm, []any{"Section", "Key", "default", "/etc/passwd"})
+	if err == nil && result != "default" {
+		t.Errorf("GetIniStr should not read files outside the title, got %v", result)
+	}
+}
+
+// TestSandboxArrayLimit tests that arrays cannot grow beyond SandboxMaxArrayLength.
+func TestSandboxArrayLimit(t *testing.T) {
+	assign := func(vm *VM, index int64) error {
+		_, err := vm.executeArrayAssign(opcode.OpCode{
+			Cmd:  opcode.ArrayAssign,
+			Args: []any{opcode.Variable("arr"), index, int64(1)},
+		})
+		return err
+	}
+
+	vm := New([]opcode.OpCode{}, WithSandbox(true))
+	if err := assign(vm, SandboxMaxArrayLength-1); err != nil {
+		t.Errorf("assignment at the last allowed index failed: %v", err)
+	}
+	if err := assign(vm, SandboxMaxArrayLength); !errors.Is(err, ErrSandboxViolation) {
+		t.Errorf("assignment beyond the limit = %v, want ErrSandboxViolation", err)
+	}
+
+	_, err := vm.executeArrayAccess(opcode.OpCode{
+		Cmd:  opcode.ArrayAccess,
+		Args: []any{opcode.Variable("arr"), int64(SandboxMaxArrayLength)},
+	})
+	if !errors.Is(err, ErrSandboxViolation) {
+		t.Errorf("read beyond the limit = %v, want ErrSandboxViolation", err)
+	}
+}
+
+// TestSandboxRefusesPassthroughs tests that Shell/MCI/StrMCI are refused in sandbox mode.
+func TestSandboxRefusesPassthroughs(t *testing.T) {
+	vm := New([]opcode.OpCode{}, WithSandbox(true))
+	if !vm.IsSandboxed() {
+		t.Fatal("IsSandboxed should be true")
+	}
+
+	for name, want := range map[string]any{"Shell": int64(0), "MCI": int64(0), "StrMCI": ""} {
+		result, err := vm.builtins[name](vm, []any{"notepad.exe"})
+		if err != nil || result != want {
+			t.Errorf("%s = (%v, %v), want (%v, nil)", name, result, err, want)
+		}
+	}
+	if !vm.refuseInSandbox("Shell") {
+		t.Error("refuseInSandbox should refuse in sandbox mode")
+	}
+	if New([]opcode.OpCode{}).refuseInSandbox("Shell") {
+		t.Error("refuseInSandbox should allow outside sandbox mode")
+	}
+}
+
+// TestResolveAssetPath tests that MIDI/WAV files missing from the title directory
+// are looked up in the asset directories of the project manifest.
+func TestResolveAssetPath(t *testing.T) {
+	titleDir := t.TempDir()
+	for _, dir := range []string{"midi", "sound"} {
+		if err := os.Mkdir(filepath.Join(titleDir, dir), 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	files := []string{"TOP.MID", filepath.Join("midi", "BGM.MID"), filepath.Join("sound", "BGM.MID"), filepath.Join("sound", "HIT.WAV")}
+	for _, name := range files {
+		if err := os.WriteFile(filepath.Join(titleDir, name), []byte("x"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	vm := New([]opcode.OpCode{}, WithTitlePath(titleDir), WithAssetDirs("midi", "sound"))
+	tests := []struct {
+		filename string
+		want     string
+	}{
+		{"TOP.MID", filepath.Join(titleDir, "TOP.MID")},
+		{"BGM.MID", filepath.Join(titleDir, "midi", "BGM.MID")}, // 先に指定したディレクトリが優先
+		{"hit.wav", filepath.Join(titleDir, "sound", "hit.wav")},
+		{"NONE.MID", filepath.Join(titleDir, "NONE.MID")}, // 見つからない場合はタイトルのディレクトリ
+	}
+	for _, tt := range tests {
+		got, err := vm.resolveAssetPath(tt.filename)
+		if err != nil {
+			t.Errorf("resolveAssetPath(%q) error: %v", tt.filename, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("resolveAssetPath(%q) = %q, want %q", tt.filename, got, tt.want)
+		}
+	}
+
+	if _, err := vm.resolveAssetPath("../outside.mid"); err == nil {
+		t.Error("resolveAssetPath should reject paths escaping the title directory")
+	}
+}
@@ -0,0 +1,68 @@
+package vm
+
+import (
+	"testing"
+
+	"github.com/zurustar/son-et/pkg/opcode"
+)
+
+// TestSignaturesMatchBuiltins tests that the signature table and the VM's built-ins are in sync.
+func TestSignaturesMatchBuiltins(t *testing.T) {
+	vm := New([]opcode.OpCode{})
+	for _, sig := range opcode.Signatures() {
+		if _, ok := vm.builtins[sig.Name]; !ok {
+			t.Errorf("signature %s has no built-in function", sig.Name)
+		}
+	}
+	for name := range vm.builtins {
+		if _, ok := opcode.LookupSignature(name); !ok {
+			t.Errorf("built-in function %s has no signature", name)
+		}
+	}
+}
+
+// TestExecuteCallConvertsArgs tests that numeric strings are converted before the built-in is called.
+func TestExecuteCallConvertsArgs(t *testing.T) {
+	vm := New([]opcode.OpCode{})
+	var got []any
+	vm.RegisterBuiltinFunction("MovePic", func(v *VM, args []any) (any, error) {
+		got = args
+		return nil, nil
+	})
+
+	_, err := vm.executeCall(opcode.OpCode{Cmd: opcode.Call, Args: []any{"movepic", int64(1), "2", ""}})
+	if err != nil {
+		t.Fatalf("executeCall failed: %v", err)
+	}
+	if len(got) != 3 || got[1] != int64(2) || got[2] != int64(0) {
+		t.Errorf("args = %#v, want numeric strings converted to int64", got)
+	}
+}
+
+// TestExecuteCallInvalidArgs tests that a call with an argument of the wrong type is logged
+// and returns the function's invalid result without calling the built-in.
+func TestExecuteCallInvalidArgs(t *testing.T) {
+	vm := New([]opcode.OpCode{})
+	called := false
+	vm.RegisterBuiltinFunction("LoadPic", func(v *VM, args []any) (any, error) {
+		called = true
+		return int64(1), nil
+	})
+
+	result, err := vm.executeCall(opcode.OpCode{Cmd: opcode.Call, Args: []any{"LoadPic", int64(5)}})
+	if err != nil {
+		t.Fatalf("executeCall should continue the script, got error: %v", err)
+	}
+	if called {
+		t.Error("the built-in should not be called with invalid arguments")
+	}
+	if result != int64(-1) {
+		t.Errorf("result = %v, want -1", result)
+	}
+
+	// 引数の数は組み込み関数に任せる（省略形を扱うため）
+	result, _ = vm.executeCall(opcode.OpCode{Cmd: opcode.Call, Args: []any{"LoadPic"}})
+	if !called || result != int64(1) {
+		t.Errorf("argument counts should be left to the built-in (called=%v, result=%v)", called, result)
+	}
+}
@@ -0,0 +1,237 @@
+package vm
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/zurustar/son-et/pkg/opcode"
+)
+
+// DefaultTraceSize is the number of executed statements kept per sequence.
+const DefaultTraceSize = 32
+
+// maxTraceValueLen is the longest string argument shown in the execution trace (longer strings are shortened).
+const maxTraceValueLen = 40
+
+// TraceEntry is one statement executed by a sequence: a function call with its
+// evaluated arguments, an assignment with the assigned value, or a control
+// statement (Wait, If, For, ...).
+type TraceEntry struct {
+	Cmd      opcode.Cmd
+	Name     string // called function or assigned variable (empty otherwise)
+	Args     []any  // argument values, the assigned value, or the Wait count
+	Function string // user function being executed (empty outside functions)
+}
+
+// String formats the entry, e.g. `MovePic(1, 0, 0, 640, 480, 2, 0, 0)` or `x = 3`.
+func (e TraceEntry) String() string {
+	var sb strings.Builder
+	switch e.Cmd {
+	case opcode.Call:
+		sb.WriteString(e.Name)
+		sb.WriteByte('(')
+		for i, arg := range e.Args {
+			if i > 0 {
+				sb.WriteString(", ")
+			}
+			sb.WriteString(formatTraceValue(arg))
+		}
+		sb.WriteByte(')')
+	case opcode.Assign, opcode.ArrayAssign:
+		sb.WriteString(e.Name)
+		if len(e.Args) == 2 {
+			fmt.Fprintf(&sb, "[%s]", formatTraceValue(e.Args[0]))
+		}
+		if len(e.Args) > 0 {
+			fmt.Fprintf(&sb, " = %s", formatTraceValue(e.Args[len(e.Args)-1]))
+		}
+	case opcode.RegisterEventHandler:
+		if len(e.Args) > 0 {
+			fmt.Fprintf(&sb, "mes(%v)", e.Args[0])
+		}
+	default:
+		sb.WriteString(string(e.Cmd))
+		for _, arg := range e.Args {
+			sb.WriteByte(' ')
+			sb.WriteString(formatTraceValue(arg))
+		}
+	}
+	if e.Function != "" {
+		fmt.Fprintf(&sb, "  (in %s)", e.Function)
+	}
+	return sb.String()
+}
+
+// formatTraceValue formats an argument value for the trace.
+func formatTraceValue(v any) string {
+	switch val := v.(type) {
+	case string:
+		if len(val) > maxTraceValueLen {
+			val = val[:maxTraceValueLen] + "..."
+		}
+		return fmt.Sprintf("%q", val)
+	case *Array:
+		return fmt.Sprintf("array[%d]", val.Len())
+	case opcode.Variable:
+		return string(val)
+	case nil:
+		return "nil"
+	}
+	return fmt.Sprint(v)
+}
+
+// SequenceTrace is the execution trace of one sequence: the main script
+// (Sequence 0) or a mes() handler.
+type SequenceTrace struct {
+	Sequence  int          // sequence number (0 outside mes(), otherwise the GetMesNo number)
+	EventType EventType    // event type of the mes() block (empty for the main sequence)
+	Entries   []TraceEntry // last executed statements, oldest first
+}
+
+// String formats the trace with a header line and one entry per line.
+func (t SequenceTrace) String() string {
+	var sb strings.Builder
+	if t.Sequence == mainSequenceID {
+		sb.WriteString("sequence 0 (main)\n")
+	} else {
+		fmt.Fprintf(&sb, "sequence %d (mes(%s))\n", t.Sequence, t.EventType)
+	}
+	for _, e := range t.Entries {
+		fmt.Fprintf(&sb, "  %s\n", e)
+	}
+	return sb.String()
+}
+
+// traceRing is a ring buffer holding up to len(entries) of the last executed statements.
+type traceRing struct {
+	entries []TraceEntry
+	next    int  // position of the next write
+	full    bool // whether the buffer has wrapped around
+}
+
+func newTraceRing(size int) *traceRing {
+	return &traceRing{entries: make([]TraceEntry, size)}
+}
+
+func (r *traceRing) add(e TraceEntry) {
+	r.entries[r.next] = e
+	r.next++
+	if r.next == len(r.entries) {
+		r.next = 0
+		r.full = true
+	}
+}
+
+// snapshot returns the held statements, oldest first.
+func (r *traceRing) snapshot() []TraceEntry {
+	if !r.full {
+		return append([]TraceEntry(nil), r.entries[:r.next]...)
+	}
+	result := make([]TraceEntry, 0, len(r.entries))
+	result = append(result, r.entries[r.next:]...)
+	return append(result, r.entries[:r.next]...)
+}
+
+// WithTraceSize sets how many executed statements are kept per sequence
+// (DefaultTraceSize by default). n <= 0 disables the execution trace.
+func WithTraceSize(n int) Option {
+	return func(vm *VM) {
+		vm.traceSize = max(n, 0)
+	}
+}
+
+// traceStatement records a statement in the ring buffer of the running sequence.
+func (vm *VM) traceStatement(e TraceEntry) {
+	if vm.traceSize == 0 {
+		return
+	}
+	if len(vm.callStack) > 0 {
+		e.Function = vm.callStack[len(vm.callStack)-1].FunctionName
+	}
+	vm.traceMu.Lock()
+	defer vm.traceMu.Unlock()
+	ring := &vm.mainTrace
+	if vm.currentHandler != nil {
+		ring = &vm.currentHandler.trace
+	}
+	if *ring == nil {
+		*ring = newTraceRing(vm.traceSize)
+	}
+	(*ring).add(e)
+	vm.lastTrace = lastStatement{sequence: vm.currentSequenceNumber(), entry: e, ok: true}
+}
+
+// traceOpCode records statements other than calls and assignments (expressions are not recorded).
+func (vm *VM) traceOpCode(op opcode.OpCode, kind opcode.Kind) {
+	switch kind {
+	case opcode.KindWait, opcode.KindSetStep, opcode.KindWaitAny:
+		vm.traceStatement(TraceEntry{Cmd: op.Cmd, Args: traceLiteralArgs(op.Args)})
+	case opcode.KindRegisterEventHandler:
+		vm.traceStatement(TraceEntry{Cmd: op.Cmd, Args: traceLiteralArgs(op.Args[:min(len(op.Args), 1)])})
+	case opcode.KindIf, opcode.KindFor, opcode.KindWhile, opcode.KindSwitch, opcode.KindBreak, opcode.KindContinue, opcode.KindTry:
+		vm.traceStatement(TraceEntry{Cmd: op.Cmd})
+	}
+}
+
+// traceLiteralArgs keeps only values and variable names of args (nested OpCodes become "...").
+func traceLiteralArgs(args []any) []any {
+	result := make([]any, len(args))
+	for i, arg := range args {
+		switch arg.(type) {
+		case opcode.OpCode, []opcode.OpCode:
+			result[i] = opcode.Variable("...")
+		default:
+			result[i] = arg
+		}
+	}
+	return result
+}
+
+// SequenceTraces returns the last executed statements of every sequence that has
+// executed at least one statement and has not finished, sorted by sequence number.
+// It is safe to call from other goroutines while the VM runs (e.g. after a timeout,
+// to see what a stalled sequence did last).
+func (vm *VM) SequenceTraces() []SequenceTrace {
+	handlers := vm.handlerRegistry.GetAllHandlers()
+	vm.traceMu.Lock()
+	defer vm.traceMu.Unlock()
+
+	var traces []SequenceTrace
+	if vm.mainTrace != nil {
+		traces = append(traces, SequenceTrace{Sequence: mainSequenceID, Entries: vm.mainTrace.snapshot()})
+	}
+	for _, h := range handlers {
+		if h.trace == nil {
+			continue
+		}
+		traces = append(traces, SequenceTrace{Sequence: h.Number, EventType: h.EventType, Entries: h.trace.snapshot()})
+	}
+	sort.Slice(traces, func(i, j int) bool { return traces[i].Sequence < traces[j].Sequence })
+	return traces
+}
+
+// WriteTraces writes the execution trace of every sequence to w (see SequenceTraces).
+func (vm *VM) WriteTraces(w io.Writer) error {
+	for _, t := range vm.SequenceTraces() {
+		if _, err := io.WriteString(w, t.String()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// currentTrace returns the trace of the running sequence (shown by DebugBreak).
+func (vm *VM) currentTrace() []TraceEntry {
+	vm.traceMu.Lock()
+	defer vm.traceMu.Unlock()
+	ring := vm.mainTrace
+	if vm.currentHandler != nil {
+		ring = vm.currentHandler.trace
+	}
+	if ring == nil {
+		return nil
+	}
+	return ring.snapshot()
+}
@@ -0,0 +1,130 @@
+package vm
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/zurustar/son-et/pkg/opcode"
+)
+
+func TestTraceRing(t *testing.T) {
+	r := newTraceRing(3)
+	if got := r.snapshot(); len(got) != 0 {
+		t.Errorf("empty ring snapshot = %v", got)
+	}
+	for i := 1; i <= 5; i++ {
+		r.add(TraceEntry{Cmd: opcode.Call, Name: "f", Args: []any{int64(i)}})
+	}
+	got := r.snapshot()
+	if len(got) != 3 {
+		t.Fatalf("snapshot has %d entries, want 3", len(got))
+	}
+	for i, e := range got {
+		if e.Args[0] != int64(i+3) {
+			t.Errorf("entry %d = %v, want oldest first (3, 4, 5)", i, e)
+		}
+	}
+}
+
+func TestTraceRecordsStatements(t *testing.T) {
+	vm := New([]opcode.OpCode{})
+	vm.globalScope.Set("x", int64(7))
+
+	ops := []opcode.OpCode{
+		{Cmd: opcode.Assign, Args: []any{opcode.Variable("n"), opcode.OpCode{Cmd: opcode.BinaryOp, Args: []any{"+", opcode.Variable("x"), int64(1)}}}},
+		{Cmd: opcode.Call, Args: []any{"StrLen", "hello"}},
+		{Cmd: opcode.Call, Args: []any{"GetLowWord", opcode.Variable("x")}},
+		{Cmd: opcode.Wait, Args: []any{int64(3)}},
+	}
+	for _, op := range ops {
+		if _, err := vm.Execute(op); err != nil {
+			t.Fatalf("Execute(%v) failed: %v", op.Cmd, err)
+		}
+	}
+
+	traces := vm.SequenceTraces()
+	if len(traces) != 1 || traces[0].Sequence != mainSequenceID {
+		t.Fatalf("SequenceTraces = %+v, want the main sequence only", traces)
+	}
+	var lines []string
+	for _, e := range traces[0].Entries {
+		lines = append(lines, e.String())
+	}
+	// Expressions (BinaryOp) are not recorded; arguments are recorded after evaluation
+	want := []string{`n = 8`, `StrLen("hello")`, `GetLowWord(7)`, `Wait 3`}
+	if strings.Join(lines, "|") != strings.Join(want, "|") {
+		t.Errorf("trace = %q, want %q", lines, want)
+	}
+}
+
+func TestTracePerSequence(t *testing.T) {
+	vm := New([]opcode.OpCode{})
+	h := NewEventHandler("", EventTIME, []opcode.OpCode{
+		{Cmd: opcode.Call, Args: []any{"GetLowWord", int64(-2)}},
+		{Cmd: opcode.Wait, Args: []any{int64(1)}},
+	}, vm, nil)
+	vm.handlerRegistry.Register(h)
+
+	if _, err := vm.Execute(opcode.OpCode{Cmd: opcode.Call, Args: []any{"GetLowWord", int64(1)}}); err != nil {
+		t.Fatal(err)
+	}
+	if err := h.Execute(NewEvent(EventTIME)); err != nil {
+		t.Fatal(err)
+	}
+
+	traces := vm.SequenceTraces()
+	if len(traces) != 2 {
+		t.Fatalf("SequenceTraces has %d sequences, want 2", len(traces))
+	}
+	if len(traces[0].Entries) != 1 || traces[0].Entries[0].String() != "GetLowWord(1)" {
+		t.Errorf("main trace = %v", traces[0].Entries)
+	}
+	if traces[1].Sequence != h.Number || traces[1].EventType != EventTIME || len(traces[1].Entries) != 2 {
+		t.Errorf("handler trace = %+v", traces[1])
+	}
+
+	var buf bytes.Buffer
+	if err := vm.WriteTraces(&buf); err != nil {
+		t.Fatal(err)
+	}
+	out := buf.String()
+	for _, s := range []string{"sequence 0 (main)", "(mes(TIME))", "  GetLowWord(-2)", "  Wait 1"} {
+		if !strings.Contains(out, s) {
+			t.Errorf("WriteTraces output missing %q:\n%s", s, out)
+		}
+	}
+}
+
+func TestTraceInFunctionAndDisabled(t *testing.T) {
+	vm := New([]opcode.OpCode{})
+	_ = vm.PushStackFrame("update", NewScope(vm.globalScope))
+	vm.traceStatement(TraceEntry{Cmd: opcode.Call, Name: "MovePic", Args: []any{int64(1), "a long string that is longer than the limit of the trace"}})
+	got := vm.currentTrace()
+	if len(got) != 1 || got[0].Function != "update" {
+		t.Fatalf("currentTrace = %+v", got)
+	}
+	if s := got[0].String(); !strings.HasSuffix(s, `...")  (in update)`) {
+		t.Errorf("String() = %q, want the long string cut and the function", s)
+	}
+
+	off := New([]opcode.OpCode{}, WithTraceSize(0))
+	_, _ = off.Execute(opcode.OpCode{Cmd: opcode.Call, Args: []any{"GetLowWord", int64(1)}})
+	if traces := off.SequenceTraces(); len(traces) != 0 {
+		t.Errorf("WithTraceSize(0) recorded %+v", traces)
+	}
+}
+
+func TestDebugBreakIncludesTrace(t *testing.T) {
+	d := &recordingDebugger{}
+	vm := New([]opcode.OpCode{}, WithDebugger(d))
+	_, _ = vm.Execute(opcode.OpCode{Cmd: opcode.Call, Args: []any{"GetLowWord", int64(3)}})
+	_, _ = vm.Execute(opcode.OpCode{Cmd: opcode.DebugBreak, Args: []any{"here", 4}})
+
+	if len(d.breaks) != 1 || len(d.breaks[0].Trace) != 1 {
+		t.Fatalf("breakpoints = %+v, want the trace of the sequence", d.breaks)
+	}
+	if s := d.breaks[0].String(); !strings.Contains(s, "Last executed:\n  GetLowWord(3)\n") {
+		t.Errorf("Breakpoint.String() = %q", s)
+	}
+}
@@ -0,0 +1,31 @@
+package vm
+
+import (
+	"github.com/zurustar/son-et/pkg/opcode"
+)
+
+// executeTry executes an OpTry OpCode.
+// OpTry runs the try block; an error in it (a failed built-in function such as
+// LoadPic of a missing file, an argument of the wrong type, an undefined function)
+// stops the try block and runs the catch block instead of being logged.
+// The error message is assigned to the error variable in the current scope.
+// Args: [tryBlock []OpCode, catchBlock []OpCode, errorVar Variable]
+func (vm *VM) executeTry(op opcode.OpCode) (any, error) {
+	blocks, err := decodeTry(op)
+	if err != nil {
+		return nil, err
+	}
+
+	vm.tryDepth++
+	result, err := vm.executeBlock(blocks.try)
+	vm.tryDepth--
+	if err == nil {
+		return result, nil
+	}
+
+	vm.log.Debug("Error caught by try", "error", err)
+	if blocks.errorVar != "" {
+		vm.GetCurrentScope().Set(blocks.errorVar, err.Error())
+	}
+	return vm.executeBlock(blocks.catch)
+}
@@ -0,0 +1,156 @@
+package vm
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/zurustar/son-et/pkg/opcode"
+)
+
+// tryOp builds try { tryBlock } catch (errorVar) { catchBlock }
+func tryOp(tryBlock, catchBlock []opcode.OpCode, errorVar string) opcode.OpCode {
+	return opcode.OpCode{Cmd: opcode.Try, Args: []any{tryBlock, catchBlock, opcode.Variable(errorVar)}}
+}
+
+func assignOp(name string, value any) opcode.OpCode {
+	return opcode.OpCode{Cmd: opcode.Assign, Args: []any{opcode.Variable(name), value}}
+}
+
+func callOp(name string, args ...any) opcode.OpCode {
+	return opcode.OpCode{Cmd: opcode.Call, Args: append([]any{name}, args...)}
+}
+
+// newTryVM は失敗する組み込み関数 Fail を登録した VM を作成する
+func newTryVM() *VM {
+	vm := New([]opcode.OpCode{})
+	vm.RegisterBuiltinFunction("Fail", func(v *VM, args []any) (any, error) {
+		return nil, errors.New("file not found")
+	})
+	return vm
+}
+
+func TestExecuteTry(t *testing.T) {
+	t.Run("runs the try block without errors", func(t *testing.T) {
+		vm := newTryVM()
+		_, err := vm.Execute(tryOp(
+			[]opcode.OpCode{assignOp("x", int64(1))},
+			[]opcode.OpCode{assignOp("x", int64(2))},
+			"e",
+		))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if x, _ := vm.GetCurrentScope().Get("x"); x != int64(1) {
+			t.Errorf("x = %v, want 1", x)
+		}
+		if _, ok := vm.GetCurrentScope().Get("e"); ok {
+			t.Error("error variable should not be set without an error")
+		}
+	})
+
+	t.Run("runs the catch block when a built-in function fails", func(t *testing.T) {
+		vm := newTryVM()
+		_, err := vm.Execute(tryOp(
+			[]opcode.OpCode{
+				assignOp("p", callOp("Fail")),
+				assignOp("x", int64(1)), // 失敗した後は実行しない
+			},
+			[]opcode.OpCode{assignOp("p", int64(-1))},
+			"e",
+		))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if p, _ := vm.GetCurrentScope().Get("p"); p != int64(-1) {
+			t.Errorf("p = %v, want -1", p)
+		}
+		if _, ok := vm.GetCurrentScope().Get("x"); ok {
+			t.Error("statements after the failure should not run")
+		}
+		e, _ := vm.GetCurrentScope().Get("e")
+		if s, ok := e.(string); !ok || !strings.Contains(s, "file not found") || !strings.Contains(s, "Fail") {
+			t.Errorf("e = %v, want the error message with the function name", e)
+		}
+		if vm.tryDepth != 0 {
+			t.Errorf("tryDepth = %d after try, want 0", vm.tryDepth)
+		}
+	})
+
+	t.Run("catches errors in nested blocks and user functions", func(t *testing.T) {
+		vm := newTryVM()
+		vm.functions["load"] = &FunctionDef{
+			Name: "load",
+			Body: []opcode.OpCode{
+				{Cmd: opcode.If, Args: []any{int64(1), []opcode.OpCode{callOp("Fail")}}},
+				assignOp("x", int64(1)),
+			},
+		}
+		_, err := vm.Execute(tryOp(
+			[]opcode.OpCode{callOp("load")},
+			[]opcode.OpCode{assignOp("caught", int64(1))},
+			"",
+		))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if c, _ := vm.GetCurrentScope().Get("caught"); c != int64(1) {
+			t.Error("catch block should run")
+		}
+		if _, ok := vm.GetCurrentScope().Get("x"); ok {
+			t.Error("the function should stop at the failure")
+		}
+		if len(vm.callStack) != 0 {
+			t.Errorf("call stack has %d frames after try, want 0", len(vm.callStack))
+		}
+	})
+
+	t.Run("catches undefined functions and argument errors", func(t *testing.T) {
+		for name, call := range map[string]opcode.OpCode{
+			"undefined": callOp("NoSuchFunction"),
+			"argument":  callOp("LoadPic", int64(1)),
+		} {
+			vm := newTryVM()
+			_, err := vm.Execute(tryOp(
+				[]opcode.OpCode{call},
+				[]opcode.OpCode{assignOp("caught", int64(1))},
+				"e",
+			))
+			if err != nil {
+				t.Fatalf("%s: unexpected error: %v", name, err)
+			}
+			if c, _ := vm.GetCurrentScope().Get("caught"); c != int64(1) {
+				t.Errorf("%s: catch block should run", name)
+			}
+		}
+	})
+
+	t.Run("errors outside try are logged and execution continues", func(t *testing.T) {
+		vm := newTryVM()
+		_, err := vm.executeBlock([]opcode.OpCode{
+			callOp("Fail"),
+			assignOp("x", int64(1)),
+		})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if x, _ := vm.GetCurrentScope().Get("x"); x != int64(1) {
+			t.Error("execution should continue after the failure outside try")
+		}
+	})
+
+	t.Run("errors in the catch block are not caught by the same try", func(t *testing.T) {
+		vm := newTryVM()
+		_, err := vm.Execute(tryOp(
+			[]opcode.OpCode{callOp("Fail")},
+			[]opcode.OpCode{callOp("Fail"), assignOp("x", int64(1))},
+			"",
+		))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if x, _ := vm.GetCurrentScope().Get("x"); x != int64(1) {
+			t.Error("the catch block should log the error and continue")
+		}
+	})
+}
@@ -0,0 +1,225 @@
+package vm
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// valueStoreAppDir is the son-et directory created in the user config directory.
+const valueStoreAppDir = "son-et"
+
+// valueStoreSubDir is the subdirectory that holds the key-value store files.
+const valueStoreSubDir = "store"
+
+// maxValueStoreKeyLength is the maximum length in bytes of a SaveValue/LoadValue key.
+const maxValueStoreKeyLength = 256
+
+// valueStoreHashLength is the number of hex digits of the path hash added to a namespace.
+const valueStoreHashLength = 12
+
+// ValueStore is the persistent key-value store scripts access with SaveValue/LoadValue.
+//
+// Each title (project) has one JSON file that keeps high scores, read flags and the like
+// across runs. The files are separated by a per-title namespace; scripts only choose
+// keys and have no say in the file path.
+type ValueStore struct {
+	path   string
+	values map[string]any
+	loaded bool
+	mu     sync.Mutex
+}
+
+// NewValueStore creates a ValueStore saved to the given JSON file.
+// The file is read on first access; a missing file is treated as an empty store.
+func NewValueStore(path string) *ValueStore {
+	return &ValueStore{
+		path:   path,
+		values: make(map[string]any),
+	}
+}
+
+// DefaultValueStoreDir returns the default directory of the store files, under the
+// user config directory of the OS (e.g. ~/.config, ~/Library/Application Support).
+func DefaultValueStoreDir() (string, error) {
+	configDir, err := os.UserConfigDir()
+	if err != nil {
+		return "", fmt.Errorf("cannot determine user config dir: %w", err)
+	}
+	return filepath.Join(configDir, valueStoreAppDir, valueStoreSubDir), nil
+}
+
+// ValueStoreNamespace derives the store file namespace from the title path.
+// A hash of the absolute path is appended, because different titles may share a
+// directory name. Characters that are not safe in file names are replaced with '_'.
+func ValueStoreNamespace(titlePath string) string {
+	if titlePath == "" {
+		return "default"
+	}
+
+	abs, err := filepath.Abs(titlePath)
+	if err != nil {
+		abs = titlePath
+	}
+
+	name := strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '-', r == '_':
+			return r
+		default:
+			return '_'
+		}
+	}, filepath.Base(abs))
+
+	sum := sha256.Sum256([]byte(abs))
+	return name + "-" + hex.EncodeToString(sum[:])[:valueStoreHashLength]
+}
+
+// Path returns the path of the store file.
+func (s *ValueStore) Path() string {
+	return s.path
+}
+
+// Get returns the value for key, or false if there is none.
+func (s *ValueStore) Get(key string) (any, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.loadLocked(); err != nil {
+		return nil, false, err
+	}
+	val, ok := s.values[key]
+	return val, ok, nil
+}
+
+// Set sets the value for key and writes the store file.
+// The value must be an int64, float64 or string.
+func (s *ValueStore) Set(key string, value any) error {
+	if err := validateValueStoreKey(key); err != nil {
+		return err
+	}
+
+	switch v := value.(type) {
+	case int:
+		value = int64(v)
+	case int64, float64, string:
+	default:
+		return fmt.Errorf("unsupported value type for key %q: %T", key, value)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.loadLocked(); err != nil {
+		return err
+	}
+	s.values[key] = value
+	return s.saveLocked()
+}
+
+// validateValueStoreKey checks that the key is not empty and not longer than the maximum.
+func validateValueStoreKey(key string) error {
+	if key == "" {
+		return errors.New("key must not be empty")
+	}
+	if len(key) > maxValueStoreKeyLength {
+		return fmt.Errorf("key too long: %d bytes (max %d)", len(key), maxValueStoreKeyLength)
+	}
+	return nil
+}
+
+// loadLocked reads the store file unless it has already been read.
+// Must be called with s.mu held.
+func (s *ValueStore) loadLocked() error {
+	if s.loaded {
+		return nil
+	}
+
+	data, err := os.ReadFile(s.path)
+	if errors.Is(err, os.ErrNotExist) {
+		s.loaded = true
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read value store: %w", err)
+	}
+
+	// Decode into json.Number to tell integers from floats
+	raw := make(map[string]any)
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	if err := dec.Decode(&raw); err != nil {
+		return fmt.Errorf("failed to parse value store %s: %w", s.path, err)
+	}
+
+	for k, v := range raw {
+		switch val := v.(type) {
+		case json.Number:
+			if i, err := val.Int64(); err == nil {
+				s.values[k] = i
+			} else if f, err := val.Float64(); err == nil {
+				s.values[k] = f
+			}
+		case string:
+			s.values[k] = val
+		}
+	}
+
+	s.loaded = true
+	return nil
+}
+
+// saveLocked writes the store to its file. It writes a temporary file and renames it,
+// so that exiting in the middle of a write does not corrupt the existing file.
+// Must be called with s.mu held.
+func (s *ValueStore) saveLocked() error {
+	if err := os.MkdirAll(filepath.Dir(s.path), 0755); err != nil {
+		return fmt.Errorf("failed to create value store dir: %w", err)
+	}
+
+	data, err := json.MarshalIndent(s.values, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode value store: %w", err)
+	}
+
+	tmp := s.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return fmt.Errorf("failed to write value store: %w", err)
+	}
+	if err := os.Rename(tmp, s.path); err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("failed to write value store: %w", err)
+	}
+	return nil
+}
+
+// getValueStore returns the title's ValueStore, creating it on first use.
+// The file is in the namespace derived from the title path, so a script cannot
+// read or write the values of another title.
+func (vm *VM) getValueStore() (*ValueStore, error) {
+	vm.mu.Lock()
+	defer vm.mu.Unlock()
+
+	if vm.valueStore != nil {
+		return vm.valueStore, nil
+	}
+
+	dir := vm.valueStoreDir
+	if dir == "" {
+		var err error
+		dir, err = DefaultValueStoreDir()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	vm.valueStore = NewValueStore(filepath.Join(dir, ValueStoreNamespace(vm.titlePath)+".json"))
+	return vm.valueStore, nil
+}
@@ -0,0 +1,235 @@
+package vm
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/zurustar/son-et/pkg/opcode"
+)
+
+// helperCreateValueStoreVM creates a VM whose value store lives in a temporary directory.
+func helperCreateValueStoreVM(t *testing.T, titlePath, storeDir string) *VM {
+	t.Helper()
+	return New([]opcode.OpCode{}, WithTitlePath(titlePath), WithValueStoreDir(storeDir))
+}
+
+// --- ValueStore Tests ---
+
+// TestValueStore_SetGet tests that values of each supported type round-trip through the file.
+func TestValueStore_SetGet(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "store.json")
+	store := NewValueStore(path)
+
+	if err := store.Set("int", 42); err != nil {
+		t.Fatalf("Set int failed: %v", err)
+	}
+	if err := store.Set("float", 1.5); err != nil {
+		t.Fatalf("Set float failed: %v", err)
+	}
+	if err := store.Set("str", "hello"); err != nil {
+		t.Fatalf("Set string failed: %v", err)
+	}
+
+	// Read the file again with a new instance
+	reloaded := NewValueStore(path)
+	tests := []struct {
+		key      string
+		expected any
+	}{
+		{"int", int64(42)},
+		{"float", 1.5},
+		{"str", "hello"},
+	}
+	for _, tt := range tests {
+		val, ok, err := reloaded.Get(tt.key)
+		if err != nil {
+			t.Fatalf("Get(%q) failed: %v", tt.key, err)
+		}
+		if !ok {
+			t.Fatalf("Get(%q) should find the key", tt.key)
+		}
+		if val != tt.expected {
+			t.Errorf("Get(%q) = %v (%T), expected %v (%T)", tt.key, val, val, tt.expected, tt.expected)
+		}
+	}
+}
+
+// TestValueStore_GetMissing tests that a missing file and a missing key are not errors.
+func TestValueStore_GetMissing(t *testing.T) {
+	store := NewValueStore(filepath.Join(t.TempDir(), "none", "store.json"))
+
+	val, ok, err := store.Get("nothing")
+	if err != nil {
+		t.Fatalf("Get should not fail for missing file: %v", err)
+	}
+	if ok || val != nil {
+		t.Errorf("Get should report missing key, got %v, %v", val, ok)
+	}
+}
+
+// TestValueStore_InvalidInput tests key validation and unsupported value types.
+func TestValueStore_InvalidInput(t *testing.T) {
+	store := NewValueStore(filepath.Join(t.TempDir(), "store.json"))
+
+	if err := store.Set("", 1); err == nil {
+		t.Error("Set with empty key should fail")
+	}
+	if err := store.Set(strings.Repeat("k", maxValueStoreKeyLength+1), 1); err == nil {
+		t.Error("Set with too long key should fail")
+	}
+	if err := store.Set("arr", NewArray(0)); err == nil {
+		t.Error("Set with array value should fail")
+	}
+}
+
+// TestValueStore_CorruptFile tests that a broken store file is reported as an error.
+func TestValueStore_CorruptFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "store.json")
+	if err := os.WriteFile(path, []byte("{broken"), 0644); err != nil {
+		t.Fatalf("failed to write file: %v", err)
+	}
+
+	store := NewValueStore(path)
+	if _, _, err := store.Get("key"); err == nil {
+		t.Error("Get should fail for a corrupt store file")
+	}
+}
+
+// TestValueStoreNamespace tests that namespaces are stable, file-name safe and distinct per title.
+func TestValueStoreNamespace(t *testing.T) {
+	base := t.TempDir()
+	a := filepath.Join(base, "a", "TITLE")
+	b := filepath.Join(base, "b", "TITLE")
+
+	nsA := ValueStoreNamespace(a)
+	if nsA != ValueStoreNamespace(a) {
+		t.Error("namespace should be stable for the same path")
+	}
+	if nsA == ValueStoreNamespace(b) {
+		t.Error("titles with the same name in different dirs should not share a namespace")
+	}
+	if !strings.HasPrefix(nsA, "TITLE-") {
+		t.Errorf("namespace should start with the title dir name, got %q", nsA)
+	}
+
+	unsafe := ValueStoreNamespace(filepath.Join(base, "my title..x"))
+	if strings.ContainsAny(unsafe, " ./\\") {
+		t.Errorf("namespace should be file-name safe, got %q", unsafe)
+	}
+
+	if got := ValueStoreNamespace(""); got != "default" {
+		t.Errorf("empty title path should use default namespace, got %q", got)
+	}
+}
+
+// --- SaveValue / LoadValue Tests ---
+
+// TestSaveValueLoadValue tests the builtins store and return values.
+func TestSaveValueLoadValue(t *testing.T) {
+	vm := helperCreateValueStoreVM(t, t.TempDir(), t.TempDir())
+
+	if _, err := vm.builtins["SaveValue"](vm, []any{"hiscore", int64(1200)}); err != nil {
+		t.Fatalf("SaveValue returned error: %v", err)
+	}
+	if _, err := vm.builtins["SaveValue"](vm, []any{"name", "PLAYER"}); err != nil {
+		t.Fatalf("SaveValue returned error: %v", err)
+	}
+
+	result, err := vm.builtins["LoadValue"](vm, []any{"hiscore"})
+	if err != nil {
+		t.Fatalf("LoadValue returned error: %v", err)
+	}
+	if result != int64(1200) {
+		t.Errorf("LoadValue(hiscore) = %v, expected 1200", result)
+	}
+
+	result, _ = vm.builtins["LoadValue"](vm, []any{"name"})
+	if result != "PLAYER" {
+		t.Errorf("LoadValue(name) = %v, expected PLAYER", result)
+	}
+}
+
+// TestLoadValue_Default tests the default value for unsaved keys.
+func TestLoadValue_Default(t *testing.T) {
+	vm := helperCreateValueStoreVM(t, t.TempDir(), t.TempDir())
+
+	result, _ := vm.builtins["LoadValue"](vm, []any{"missing"})
+	if result != int64(0) {
+		t.Errorf("LoadValue without default = %v, expected 0", result)
+	}
+
+	result, _ = vm.builtins["LoadValue"](vm, []any{"missing", "none"})
+	if result != "none" {
+		t.Errorf("LoadValue with default = %v, expected none", result)
+	}
+}
+
+// TestSaveValue_PersistsAcrossVMs tests that values survive a VM restart for the same title.
+func TestSaveValue_PersistsAcrossVMs(t *testing.T) {
+	titlePath := t.TempDir()
+	storeDir := t.TempDir()
+
+	first := helperCreateValueStoreVM(t, titlePath, storeDir)
+	first.builtins["SaveValue"](first, []any{"visited", int64(1)})
+
+	second := helperCreateValueStoreVM(t, titlePath, storeDir)
+	result, _ := second.builtins["LoadValue"](second, []any{"visited"})
+	if result != int64(1) {
+		t.Errorf("value should persist across VMs, got %v", result)
+	}
+}
+
+// TestSaveValue_IsolatedPerTitle tests that different titles cannot see each other's values.
+func TestSaveValue_IsolatedPerTitle(t *testing.T) {
+	storeDir := t.TempDir()
+
+	vmA := helperCreateValueStoreVM(t, t.TempDir(), storeDir)
+	vmA.builtins["SaveValue"](vmA, []any{"secret", int64(7)})
+
+	vmB := helperCreateValueStoreVM(t, t.TempDir(), storeDir)
+	result, _ := vmB.builtins["LoadValue"](vmB, []any{"secret", int64(-1)})
+	if result != int64(-1) {
+		t.Errorf("another title should not see the value, got %v", result)
+	}
+}
+
+// TestSaveValue_KeyCannotEscapeStore tests that path-like keys do not create files outside the store.
+func TestSaveValue_KeyCannotEscapeStore(t *testing.T) {
+	storeDir := t.TempDir()
+	vm := helperCreateValueStoreVM(t, t.TempDir(), storeDir)
+
+	vm.builtins["SaveValue"](vm, []any{"../../evil", int64(1)})
+
+	entries, err := os.ReadDir(storeDir)
+	if err != nil {
+		t.Fatalf("failed to read store dir: %v", err)
+	}
+	if len(entries) != 1 {
+		t.Fatalf("expected exactly one store file, got %d", len(entries))
+	}
+	if _, err := os.Stat(filepath.Join(filepath.Dir(storeDir), "evil")); err == nil {
+		t.Error("key should not be used as a file path")
+	}
+
+	result, _ := vm.builtins["LoadValue"](vm, []any{"../../evil"})
+	if result != int64(1) {
+		t.Errorf("path-like key should be stored as a plain key, got %v", result)
+	}
+}
+
+// TestSaveValue_InvalidArgs tests that bad arguments are logged and ignored.
+func TestSaveValue_InvalidArgs(t *testing.T) {
+	vm := helperCreateValueStoreVM(t, t.TempDir(), t.TempDir())
+
+	if _, err := vm.builtins["SaveValue"](vm, []any{"only-key"}); err != nil {
+		t.Errorf("SaveValue with missing value should not return error: %v", err)
+	}
+	if _, err := vm.builtins["SaveValue"](vm, []any{"", int64(1)}); err != nil {
+		t.Errorf("SaveValue with empty key should not return error: %v", err)
+	}
+	if _, err := vm.builtins["LoadValue"](vm, []any{}); err != nil {
+		t.Errorf("LoadValue with no args should not return error: %v", err)
+	}
+}
@@ -13,13 +13,18 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"image"
 	"image/color"
 	"log/slog"
 	"path/filepath"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
+	"github.com/zurustar/son-et/pkg/compat"
+	"github.com/zurustar/son-et/pkg/eventbus"
+	"github.com/zurustar/son-et/pkg/fileutil"
 	"github.com/zurustar/son-et/pkg/graphics"
 	"github.com/zurustar/son-et/pkg/logger"
 	"github.com/zurustar/son-et/pkg/opcode"
@@ -29,6 +34,12 @@ import (
 // Requirement 20.7: System maintains maximum stack depth of 1000 frames.
 const MaxStackDepth = 1000
 
+// Sleep of the event loop while no event is queued
+const (
+	eventLoopIdleSleep = 1 * time.Millisecond
+	powerSaveIdleSleep = 5 * time.Millisecond // WithPowerSave
+)
+
 // Windows MessageBox button type constants (lower 4 bits of flags)
 const (
 	MB_OK                = 0x00 // OK button only
@@ -84,6 +95,8 @@ type VM struct {
 	builtins map[string]BuiltinFunc
 	// Lowercased-name index for case-insensitive builtin lookup (O(1)).
 	builtinsLower map[string]BuiltinFunc
+	// Undefined functions already reported before playback (lowercase); calling them returns 0.
+	unsupportedFuncs map[string]bool
 
 	// Event system
 	eventQueue      *EventQueue
@@ -94,6 +107,10 @@ type VM struct {
 	// File I/O
 	fileHandleTable *FileHandleTable
 
+	// Persistent key-value storage (SaveValue/LoadValue)
+	valueStore    *ValueStore
+	valueStoreDir string // Directory for store files (empty = user config dir)
+
 	// Audio system interface (to avoid import cycle)
 	audioSystem AudioSystemInterface
 
@@ -104,6 +121,9 @@ type VM struct {
 	// Requirement 6.1: When OpSetStep is executed, system initializes step counter.
 	stepCounter int
 
+	// Number of try blocks being executed (errors stop the block instead of being logged)
+	tryDepth int
+
 	// Execution control
 	running bool
 	mu      sync.RWMutex
@@ -111,13 +131,95 @@ type VM struct {
 	// Configuration
 	headless      bool
 	timeout       time.Duration
+	tickLimit     int64        // Stop after this many ticks (--exit-after-ticks, 0 = no limit)
+	powerSave     bool         // Poll the event queue less often when idle (--power-save)
+	ticks         atomic.Int64 // Ticks dispatched so far (read by Progress from other goroutines)
+	timedOut      atomic.Bool  // The run stopped because the timeout expired
 	soundFontPath string
-	titlePath     string // Base path for resolving relative file paths
+	lang          string            // Language returned by GetLang (empty = detect from the environment)
+	locale        string            // Locale of FormatNumber/FormatDate, a BCP 47 tag (empty = detect from the environment)
+	titlePath     string            // Base path for resolving relative file paths
+	assetDirs     []string          // Directories searched for MIDI/WAV files missing from titlePath (project manifest "assets")
+	assetVars     map[string]string // Variables expanded in file names (${NAME}), see WithAssetVars
+	outputDir     string            // Directory that SaveSprite writes to (empty = SaveSprite is disabled)
+	sandbox       bool              // Sandbox mode: confine file access and cap resources (--sandbox)
+	compat        compat.Mode       // Compatibility mode (--compat, see compat.go)
+	debugger      Debugger          // Receives DebugBreak breakpoints (nil = DebugBreak is a no-op)
+
+	// Execution trace (see trace.go)
+	traceSize int           // Statements kept per sequence (0 = no trace)
+	traceMu   sync.Mutex    // Guards the trace rings, which are read from other goroutines
+	mainTrace *traceRing    // Trace of the sequence running outside event handlers
+	lastTrace lastStatement // Statement executed last by any sequence (see Progress)
+
+	// Random() streams (see rng.go)
+	runSeed    uint64          // Run seed shared by all sequences (--seed)
+	runSeedSet bool            // Whether runSeed has been set or chosen
+	mainRand   *sequenceStream // Stream of the sequence running outside event handlers
 
 	// Context for cancellation
 	ctx    context.Context
 	cancel context.CancelFunc
 
+	// Exit sequence (OnExit, see exit.go)
+	exitFunc      *FunctionDef  // Function called when the title is closed or times out
+	exitBudget    time.Duration // How long the exit sequence may run
+	exitRequested atomic.Bool   // RequestExit was called (window closed)
+	exiting       bool          // The exit sequence has started
+
+	// OSC output (see builtins_osc.go)
+	oscSender         OSCSender // nil unless --osc-allow is given
+	oscDisabledWarned bool      // OSCSend without a sender has been logged
+
+	// Image export (see builtins_snapshot.go)
+	saveSpriteWarned bool // SaveSprite without an output directory has been logged
+
+	// Timers set by SetTimer, keyed by timer ID (see builtins_timer.go)
+	timers map[int]*scriptTimer
+
+	// Handlers registered by OnSpriteTick, keyed by cast ID (see builtins_spritetick.go)
+	spriteTicks map[int][]*EventHandler
+
+	// Flipbooks placed by PutFlipbook, keyed by cast ID (see builtins_flipbook.go)
+	flipbooks map[int]*flipbook
+
+	// Casts changing their picture with ChangePicture, keyed by cast ID (see builtins_crossfade.go)
+	crossfades map[int]*crossfade
+
+	// Paths defined by DefinePath, keyed by path ID (see builtins_path.go)
+	paths      map[int]*graphics.Path
+	nextPathID int
+
+	// Sequences started by Spawn, keyed by sequence number (see builtins_spawn.go)
+	spawned map[int]*EventHandler
+
+	// Functions queued by the spawn operator command (see command.go)
+	spawns spawnQueue
+
+	// Operator commands replayed from and recorded to a cue file (see cue.go)
+	cues cueState
+
+	// Chapter markers and the fast-forward to a chapter (see chapter.go)
+	chapters chapterState
+	seek     *chapterSeek // nil unless fast-forwarding
+
+	// Keyframes of the engine state for seeking backward (see keyframe.go)
+	keyframes keyframeState
+	midiFile  string // File last started by PlayMIDI (full path)
+
+	// Last time the pictures shown on screen were sampled for the leak report (see leaks.go)
+	lastImageSample time.Time
+
+	// Source of time (RealClock unless WithClock is given; see clock.go)
+	clock Clock
+
+	// MIDI tick of the last dispatched MIDI_TIME event (-1 before the first one; see dispatch_observer.go)
+	midiTick atomic.Int64
+	// Called after each dispatched event (SetDispatchObserver)
+	dispatchObserver func(midiTick int)
+	// Called after each counted tick (SetTickObserver)
+	tickObserver func(ticks int64)
+
 	// Logger
 	log *slog.Logger
 }
@@ -127,6 +229,8 @@ type VM struct {
 type AudioSystemInterface interface {
 	PlayMIDI(filename string) error
 	PlayWAVE(filename string) error
+	// PlayVoice plays a WAV file on the voice bus, which ducks the music bus while it plays
+	PlayVoice(filename string) error
 	SetMuted(muted bool)
 	Update()
 	Shutdown()
@@ -136,6 +240,43 @@ type AudioSystemInterface interface {
 	IsTimerRunning() bool
 	StartFadeout(duration time.Duration)
 	IsFadingOut() bool
+	Pause()
+	Resume()
+	// Mixer buses ("master", "music", "sfx", "voice"); gain is 0-1
+	SetBusGain(bus string, gain float64) error
+	BusGain(bus string) (float64, error)
+	SetBusMuted(bus string, muted bool) error
+	// Ducking of the music bus while the voice bus plays; SetDucking returns the applied (clamped) depth in dB
+	SetDucking(depth float64, attack, release time.Duration) float64
+	// Playback speed of the TIME timer and MIDI; SetTimeScale returns the applied (clamped) scale
+	SetTimeScale(scale float64) float64
+	TimeScale() float64
+	// Synthesizer quality (reverb/chorus and polyphony, from the next PlayMIDI); a polyphony of 0 keeps the
+	// current one, and the applied (clamped) polyphony is returned
+	SetSynthQuality(effects bool, polyphony int) (int, error)
+	// Audio-visual offset: delay of MIDI_TIME events behind the audio; SetAVOffset returns the applied (clamped) offset
+	SetAVOffset(offset time.Duration) time.Duration
+	AVOffset() time.Duration
+	// A/V calibration click track; AVCalibrationBeat reports when to show the visual beat
+	StartAVCalibration() error
+	StopAVCalibration()
+	AVCalibrationBeat() bool
+	// MetronomeBeat reports when to flash the beat indicator (--metronome-flash): just after a quarter note
+	MetronomeBeat() bool
+	// MIDI ports (several MIDI files at once); "" or "main" is the port used by PlayMIDI
+	PlayMIDIPort(port, filename string) error
+	StopMIDIPort(port string)
+	// MIDIPortTick returns the FILLY tick of the port, or -1 if nothing is playing on it
+	MIDIPortTick(port string) int
+	// SetMIDIClock selects the port whose ticks drive MIDI_TIME events
+	SetMIDIClock(port string)
+	// SeekMIDI jumps forward to a FILLY tick of the main MIDI file (operator console)
+	SeekMIDI(tick int) error
+	// SkipMIDI jumps forward like SeekMIDI without delivering the skipped MIDI_TIME events (chapters)
+	SkipMIDI(tick int) error
+	// MIDITickAfter returns the tick the main MIDI file reaches d after its last MIDI_TIME tick
+	// at normal speed and whether it has ended by then, or -1 if nothing is playing
+	MIDITickAfter(d time.Duration) (int, bool)
 }
 
 // GraphicsSystemInterface defines the interface for graphics system operations.
@@ -143,6 +284,12 @@ type AudioSystemInterface interface {
 type GraphicsSystemInterface interface {
 	// Picture management
 	LoadPic(filename string) (int, error)
+	// LoadPicAsync loads a picture without waiting for it to be decoded.
+	// onDone is called once, from another goroutine, when decoding finishes.
+	LoadPicAsync(filename string, onDone func(picID int, err error)) (int, error)
+	// LoadPicFrames loads each frame of an animated GIF as a picture and returns the
+	// picture IDs with the display time of each frame in 1/100 s.
+	LoadPicFrames(filename string) ([]int, []int, error)
 	CreatePic(width, height int) (int, error)
 	CreatePicFrom(srcID int) (int, error)
 	CreatePicWithSize(srcID, width, height int) (int, error)
@@ -174,13 +321,77 @@ type GraphicsSystemInterface interface {
 	MoveCast(id int, opts ...any) error
 	MoveCastWithOptions(id int, opts ...graphics.CastOption) error
 	DelCast(id int) error
+	// CastAt returns the topmost cast at virtual desktop coordinates, or -1 if none.
+	CastAt(x, y int) int
+
+	// Sprite pools (many small sprites sharing one picture, drawn in a single batch)
+	// CreateSpritePool creates n hidden particles showing srcPicID on dstPicID; transColor may be nil.
+	CreateSpritePool(srcPicID, dstPicID, n int, transColor color.Color) (int, error)
+	SetPoolParticle(poolID, index int, x, y float64, visible bool) error
+	// SetPoolVelocity sets the velocity of a particle, or of every particle if index is negative.
+	SetPoolVelocity(poolID, index int, vx, vy float64) error
+	// StepSpritePool moves every particle by its velocity, wrapping around the destination picture.
+	StepSpritePool(poolID int) error
+	SpritePoolSize(poolID int) int
+	DelSpritePool(poolID int) error
+
+	// Masks (only the masked region of a cast / window is visible; nil removes the mask)
+	SetCastMask(id int, mask *graphics.Mask) error
+	SetWinMask(id int, mask *graphics.Mask) error
+	SetCastShadow(id int, shadow *graphics.Shadow) error
+	SetCastOutline(id int, outline *graphics.Outline) error
+
+	// Drawing part of a cast's image and nine-slice stretching (nil draws the whole image as is)
+	SetCastSourceRect(id int, rect *image.Rectangle) error
+	SetCastNineSlice(id int, ns *graphics.NineSlice) error
+
+	// Hit effects animated every frame (Shake/Flash); a zero duration stops the effect
+	ShakeCast(id, amplitude int, duration time.Duration) error
+	FlashCast(id int, c color.Color, duration time.Duration) error
+
+	// Picture crossfade of a cast (ChangePicture): StartCastCrossfade keeps the cast's current look,
+	// and SetCastCrossfade blends it into the new picture (progress 0 to 1; 1 ends the crossfade)
+	StartCastCrossfade(id int) error
+	SetCastCrossfade(id int, progress float64) error
+
+	// Cast motion along a path animated every frame (MoveAlongPath); a nil path or zero duration stops it
+	MoveCastAlongPath(id int, path *graphics.Path, duration time.Duration, easing graphics.Easing) error
+
+	// Camera panning and zooming the whole scene (SetCamera/CameraMove); a nil camera shows the virtual desktop as is
+	SetCamera(cam *graphics.Camera) error
+	MoveCamera(cam graphics.Camera, duration time.Duration, easing graphics.Easing) error
+
+	// Mouse cursor (a custom cursor follows the mouse and hides the system cursor; nil removes it)
+	SetCursor(c *graphics.Cursor) error
+	SetCursorClick(click *graphics.CursorClick) error
+	SetSystemCursorVisible(visible bool)
+
+	// Application window (the OS window; takes effect in GUI mode only)
+	SetWindowTitle(title string)
+	SetWindowIcon(filename string) error
+	SetWindowSize(width, height int) error
+	// DisplayScale returns the physical pixels per virtual desktop pixel (1 in headless mode).
+	DisplayScale() float64
+
+	// SaveSnapshot writes the composited screen (castID graphics.SnapshotScreen) or a cast's image to a PNG file.
+	SaveSnapshot(castID int, path string) error
+	// CaptureLayers writes each compositing layer of the next frame to PNG files in dir (the layers command).
+	CaptureLayers(dir string) error
 
 	// Text rendering
-	TextWrite(picID, x, y int, text string) error
+	// TextWriteWithLayout draws text with fixed-pitch or proportional metrics
+	// (graphics.PitchDefault decides from the font name) and the given letter and line spacing.
+	TextWriteWithLayout(picID, x, y int, text string, pitch graphics.FontPitch, layout graphics.TextLayout) error
+	// TextWriteHighlighted draws text with its first count characters in highlightColor (karaoke display)
+	TextWriteHighlighted(picID, x, y int, text string, count int, highlightColor any) error
 	SetFont(name string, size int, opts ...any) error
+	// TextExtentWithLayout returns the rendered width and height of text; an empty fontName uses the current font.
+	TextExtentWithLayout(text, fontName string, size int, pitch graphics.FontPitch, layout graphics.TextLayout) (int, int)
 	SetTextColor(c any) error
 	SetBgColor(c any) error
 	SetBackMode(mode int) error
+	// SetTextDirection switches TextWrite between horizontal and vertical (tategaki) text.
+	SetTextDirection(direction graphics.TextDirection) error
 
 	// Drawing primitives
 	DrawLine(picID, x1, y1, x2, y2 int) error
@@ -191,9 +402,42 @@ type GraphicsSystemInterface interface {
 	SetPaintColor(c any) error
 	GetColor(picID, x, y int) (int, error)
 
+	// Screen fade
+	// Fade fades the whole screen to (fadeOut) or from (!fadeOut) color c over duration,
+	// drawn over all sprites. onDone is called once when the fade completes.
+	Fade(fadeOut bool, c any, duration time.Duration, onDone func()) error
+
+	// Render order (windows are ordered among themselves, casts within their window)
+	BringWinToFront(id int) error
+	SendWinToBack(id int) error
+	BringCastToFront(id int) error
+	SendCastToBack(id int) error
+
+	// 256-color palette (palette animation for the 256-color display emulation)
+	SetPaletteColor(index int, c any) error
+	GetPaletteColor(index int) (int, error)
+	CyclePalette(start, count, step int) error
+	ResetPalette()
+
+	// Display adjustment applied to the whole screen as the final pass
+	SetGamma(v float64) error
+	SetBrightness(v float64) error
+	SetContrast(v float64) error
+
 	// Virtual desktop info
 	GetVirtualWidth() int
 	GetVirtualHeight() int
+
+	// Picture memory and leak report (see leaks.go): site names the statement creating
+	// a picture, SampleImageUsage records which pictures visible windows and casts show.
+	SetImageSite(site func() string)
+	SampleImageUsage(now time.Time)
+	ImageReport(idle time.Duration, now time.Time) *graphics.ImageReport
+
+	// Keyframes of the graphics state for seeking backward (see keyframe.go); a keyframe
+	// can be restored any number of times until it is released
+	CaptureKeyframe() (graphics.Keyframe, error)
+	RestoreKeyframe(kf graphics.Keyframe) error
 }
 
 // FunctionDef represents a user-defined function.
@@ -246,6 +490,25 @@ func WithTimeout(timeout time.Duration) Option {
 	}
 }
 
+// WithTickLimit stops the VM after n engine ticks (--exit-after-ticks).
+// A tick is a TIME event, or a MIDI_TIME event while the title has no mes(TIME) handler.
+// Unlike WithTimeout, where the run stops depends only on the script, not on the speed of the host.
+// n <= 0 means no limit.
+func WithTickLimit(n int64) Option {
+	return func(vm *VM) {
+		vm.tickLimit = max(n, 0)
+	}
+}
+
+// WithPowerSave makes the event loop poll the event queue every powerSaveIdleSleep
+// instead of every millisecond while no event is queued (--power-save).
+// Events are handled up to that much later, which is well within a TIME tick (50ms).
+func WithPowerSave(enabled bool) Option {
+	return func(vm *VM) {
+		vm.powerSave = enabled
+	}
+}
+
 // WithLogger sets a custom logger.
 func WithLogger(log *slog.Logger) Option {
 	return func(vm *VM) {
@@ -269,6 +532,40 @@ func WithTitlePath(path string) Option {
 	}
 }
 
+// WithAssetDirs sets directories, relative to the title path, that are searched
+// for MIDI and WAV files not found in the title directory itself.
+// They come from the "assets" list of a project manifest (project.yaml).
+func WithAssetDirs(dirs ...string) Option {
+	return func(vm *VM) {
+		vm.assetDirs = dirs
+	}
+}
+
+// WithAssetVars sets the variables expanded in script file names ("${ASSETS}/pic01.bmp"),
+// from the "vars" of a project manifest and the -A command line flags, so the same
+// script can run against alternate asset sets.
+func WithAssetVars(vars map[string]string) Option {
+	return func(vm *VM) {
+		vm.assetVars = vars
+	}
+}
+
+// WithValueStoreDir sets the directory where SaveValue/LoadValue store files are kept.
+// When not set, the store lives under the user's config directory.
+func WithValueStoreDir(dir string) Option {
+	return func(vm *VM) {
+		vm.valueStoreDir = dir
+	}
+}
+
+// WithEventBus sets the event bus that mirrors events pushed to the event queue.
+// Tools subscribe to "vm.*" topics on the bus; the script's queue is unaffected.
+func WithEventBus(bus *eventbus.Bus) Option {
+	return func(vm *VM) {
+		vm.eventQueue.SetBus(bus)
+	}
+}
+
 // New creates a new VM instance with the given OpCodes and options.
 // It initializes the global scope, built-in functions, and applies configuration options.
 //
@@ -281,6 +578,10 @@ func WithTitlePath(path string) Option {
 func New(opcodes []opcode.OpCode, opts ...Option) *VM {
 	ctx, cancel := context.WithCancel(context.Background())
 
+	// Pre-decode a copy of the program so that Execute dispatches on integers and typed
+	// arguments (the caller's OpCodes are left as they are)
+	opcodes = decodeOpCodes(opcodes)
+
 	vm := &VM{
 		opcodes:         opcodes,
 		pc:              0,
@@ -299,10 +600,13 @@ func New(opcodes []opcode.OpCode, opts ...Option) *VM {
 		headless:        false,
 		timeout:         0,
 		soundFontPath:   "",
+		traceSize:       DefaultTraceSize,
 		ctx:             ctx,
 		cancel:          cancel,
 		log:             logger.GetLogger(),
+		clock:           RealClock,
 	}
+	vm.midiTick.Store(-1)
 
 	// Initialize event dispatcher
 	vm.eventDispatcher = NewEventDispatcher(vm.eventQueue, vm.handlerRegistry, vm)
@@ -318,6 +622,8 @@ func New(opcodes []opcode.OpCode, opts ...Option) *VM {
 
 	// Register default built-in functions
 	vm.registerDefaultBuiltins()
+	vm.removeExtensionBuiltins()
+	vm.registerPluginBuiltins()
 
 	// Register event type constants in global scope
 	// These are used by PostMes() and other functions that reference event types
@@ -336,6 +642,30 @@ func (vm *VM) registerDefaultBuiltins() {
 	vm.registerAudioBuiltins()
 	vm.registerSystemBuiltins()
 	vm.registerFileIOBuiltins()
+	vm.registerInputBuiltins()
+	vm.registerNoteBuiltins()
+	vm.registerKaraokeBuiltins()
+	vm.registerPoolBuiltins()
+	vm.registerMaskBuiltins()
+	vm.registerEffectBuiltins()
+	vm.registerPathBuiltins()
+	vm.registerCameraBuiltins()
+	vm.registerSliceBuiltins()
+	vm.registerMIDIPortBuiltins()
+	vm.registerCursorBuiltins()
+	vm.registerAppWindowBuiltins()
+	vm.registerSysInfoBuiltins()
+	vm.registerLocaleBuiltins()
+	vm.registerSnapshotBuiltins()
+	vm.registerTimerBuiltins()
+	vm.registerSpawnBuiltins()
+	vm.registerSpriteTickBuiltins()
+	vm.registerFlipbookBuiltins()
+	vm.registerCrossfadeBuiltins()
+	vm.registerExitBuiltins()
+	vm.registerWaitAnyBuiltins()
+	vm.registerOSCBuiltins()
+	vm.registerChapterBuiltins()
 }
 
 // RegisterBuiltinFunction registers a built-in function with the given name.
@@ -349,6 +679,27 @@ func (vm *VM) RegisterBuiltinFunction(name string, fn BuiltinFunc) {
 	vm.builtinsLower[strings.ToLower(name)] = fn
 }
 
+// HasBuiltin reports whether name (case-insensitive) is a registered built-in function.
+// Used by the compatibility report (compiler.FindUnsupportedCalls) before playback.
+func (vm *VM) HasBuiltin(name string) bool {
+	vm.mu.RLock()
+	defer vm.mu.RUnlock()
+	_, ok := vm.builtinsLower[strings.ToLower(name)]
+	return ok
+}
+
+// SetUnsupportedFunctions marks functions that the script calls but neither the engine
+// nor the script defines. They have already been reported before playback, so calling
+// them returns 0 instead of stopping execution with an undefined function error.
+func (vm *VM) SetUnsupportedFunctions(names []string) {
+	vm.mu.Lock()
+	defer vm.mu.Unlock()
+	vm.unsupportedFuncs = make(map[string]bool, len(names))
+	for _, name := range names {
+		vm.unsupportedFuncs[strings.ToLower(name)] = true
+	}
+}
+
 // registerEventTypeConstants registers event type constants in the global scope.
 // These constants are used by PostMes() and other functions that reference event types.
 // The values match the messageType parameter expected by PostMes:
@@ -362,16 +713,36 @@ func (vm *VM) RegisterBuiltinFunction(name string, fn BuiltinFunc) {
 //   - USER = 7 (and above for custom user IDs)
 //   - CHAR = 8 (character input event)
 func (vm *VM) registerEventTypeConstants() {
-	vm.globalScope.Set("TIME", int64(0))
-	vm.globalScope.Set("MIDI_TIME", int64(1))
-	vm.globalScope.Set("MIDI_END", int64(2))
-	vm.globalScope.Set("KEY", int64(3))
-	vm.globalScope.Set("CLICK", int64(4))
-	vm.globalScope.Set("LBDOWN", int64(4)) // LBDOWN is same as CLICK
-	vm.globalScope.Set("RBDOWN", int64(5))
-	vm.globalScope.Set("RBDBLCLK", int64(6))
-	vm.globalScope.Set("USER", int64(7))
-	vm.globalScope.Set("CHAR", int64(8))
+	for _, c := range eventTypeConstants {
+		vm.globalScope.Set(c.name, c.value)
+	}
+}
+
+// eventTypeConstants are the global constants registered by registerEventTypeConstants.
+var eventTypeConstants = []struct {
+	name  string
+	value int64
+}{
+	{"TIME", 0},
+	{"MIDI_TIME", 1},
+	{"MIDI_END", 2},
+	{"KEY", 3},
+	{"CLICK", 4},
+	{"LBDOWN", 4}, // LBDOWN is same as CLICK
+	{"RBDOWN", 5},
+	{"RBDBLCLK", 6},
+	{"USER", 7},
+	{"CHAR", 8},
+}
+
+// isEventTypeConstant reports whether name is one of the event type constants.
+func isEventTypeConstant(name string) bool {
+	for _, c := range eventTypeConstants {
+		if c.name == name {
+			return true
+		}
+	}
+	return false
 }
 
 // Run starts the VM execution loop.
@@ -392,6 +763,9 @@ func (vm *VM) Run() error {
 	}
 	vm.running = true
 	vm.mu.Unlock()
+	vm.ticks.Store(0)
+	vm.timedOut.Store(false)
+	vm.startCues(vm.clock.Now())
 
 	defer func() {
 		// Requirement 3.4: VMが停止する場合、開いている全てのファイルを閉じてリソースを解放する。
@@ -399,6 +773,10 @@ func (vm *VM) Run() error {
 
 		vm.mu.Lock()
 		vm.running = false
+		if vm.exiting {
+			// Release the timer of the exit budget
+			vm.cancel()
+		}
 		vm.mu.Unlock()
 	}()
 
@@ -406,7 +784,7 @@ func (vm *VM) Run() error {
 	// Requirement 13.1: When timeout is specified, system terminates execution after specified duration.
 	if vm.timeout > 0 {
 		var timeoutCancel context.CancelFunc
-		vm.ctx, timeoutCancel = context.WithTimeout(vm.ctx, vm.timeout)
+		vm.ctx, timeoutCancel = withClockTimeout(vm.ctx, vm.clock, vm.timeout)
 		defer timeoutCancel()
 	}
 
@@ -417,6 +795,15 @@ func (vm *VM) Run() error {
 		return fmt.Errorf("failed to collect function definitions: %w", err)
 	}
 
+	// --chapter: fast-forward from the start until Chapter(name) executes
+	if start := vm.chapters.start; start != "" {
+		if name, err := vm.findChapter(start); err != nil {
+			vm.log.Error("Cannot start at chapter, playing from the beginning", "error", err)
+		} else {
+			vm.beginChapterSeek(name, vm.clock.Now())
+		}
+	}
+
 	// Call main function if it exists
 	// This is the entry point for FILLY scripts
 	if mainFunc, ok := vm.functions["main"]; ok {
@@ -435,9 +822,10 @@ func (vm *VM) Run() error {
 		// Check for cancellation (timeout or stop)
 		select {
 		case <-vm.ctx.Done():
-			if vm.ctx.Err() == context.DeadlineExceeded {
+			if timedOut(vm.ctx) {
 				// Requirement 13.3: When timeout expires, system logs timeout message.
 				vm.log.Info("VM execution timed out")
+				vm.timedOut.Store(true)
 				return nil
 			}
 			vm.log.Info("VM execution cancelled")
@@ -498,12 +886,25 @@ func (vm *VM) runEventLoop() error {
 	vm.log.Info("Event loop started", "handler_count", vm.handlerRegistry.Count())
 
 	for {
+		// The window was closed: run the exit sequence (OnExit) before stopping
+		if vm.exitRequested.Load() {
+			vm.startExitSequence()
+		}
+
 		// Check for cancellation (timeout or stop)
 		select {
 		case <-vm.ctx.Done():
-			if vm.ctx.Err() == context.DeadlineExceeded {
+			if timedOut(vm.ctx) {
+				if vm.exiting {
+					vm.log.Info("Exit sequence budget expired")
+					return nil
+				}
 				// Requirement 13.3: When timeout expires, system logs timeout message.
 				vm.log.Info("Event loop timed out")
+				vm.timedOut.Store(true)
+				if vm.startExitSequence() {
+					continue
+				}
 				return nil
 			}
 			vm.log.Info("Event loop cancelled")
@@ -516,9 +917,20 @@ func (vm *VM) runEventLoop() error {
 		// Requirement 4.5: When MIDI playback completes, system generates MIDI_END event.
 		vm.UpdateAudio()
 
+		// Start the functions queued by the spawn command, then send TIMER events
+		// for timers set by SetTimer that are due
+		// While fast-forwarding to a chapter, ticks and timers follow the virtual clock
+		now := vm.advanceChapterSeek(vm.clock.Now())
+		vm.takeKeyframe(now)
+		vm.playCues(vm.clock.Now())
+		vm.startSpawns(now)
+		vm.fireTimers(now)
+		vm.sampleImages(now)
+
 		// Process events from the queue
 		// Requirement 14.3: When events are available, system processes them in order.
-		processed, err := vm.eventDispatcher.ProcessOne()
+		event, err := vm.eventDispatcher.ProcessNext()
+		processed := event != nil
 		if err != nil {
 			// Check if this is a fatal error (use errors.As to unwrap wrapped errors)
 			var runtimeErr *RuntimeError
@@ -529,6 +941,12 @@ func (vm *VM) runEventLoop() error {
 			vm.log.Error("Event processing error", "error", err)
 		}
 
+		// --exit-after-ticks: stop right after the last tick has been handled
+		if processed && vm.countTick(event) {
+			vm.log.Info("Tick limit reached, stopping", "ticks", vm.ticks.Load())
+			return nil
+		}
+
 		// If no events were processed, check if we should continue
 		if !processed {
 			// Requirement 14.2: When event queue is empty, system waits for next event.
@@ -546,9 +964,45 @@ func (vm *VM) runEventLoop() error {
 			// Small sleep to prevent busy-waiting
 			// In a real implementation with Ebitengine, this would be handled
 			// by the game loop's Update() method
-			time.Sleep(1 * time.Millisecond)
+			// Fast-forwarding to a chapter does not wait for real time
+			if vm.seek == nil {
+				time.Sleep(vm.idleSleep())
+			}
+		}
+	}
+}
+
+// idleSleep returns how long the event loop sleeps when no event is queued.
+func (vm *VM) idleSleep() time.Duration {
+	if vm.powerSave {
+		return powerSaveIdleSleep
+	}
+	return eventLoopIdleSleep
+}
+
+// countTick counts a dispatched event as a tick (see Progress) and reports
+// whether the tick limit (WithTickLimit) has been reached.
+func (vm *VM) countTick(event *Event) bool {
+	switch event.Type {
+	case EventTIME:
+	case EventMIDI_TIME:
+		// MIDI_TIME drives titles without a mes(TIME) handler; otherwise TIME is the clock
+		if len(vm.handlerRegistry.GetHandlers(EventTIME)) > 0 {
+			return false
 		}
+	default:
+		return false
+	}
+	ticks := vm.ticks.Add(1)
+	if vm.tickObserver != nil {
+		vm.tickObserver(ticks)
 	}
+	return vm.tickLimit > 0 && ticks >= vm.tickLimit
+}
+
+// TimedOut reports whether the last run stopped because the timeout (WithTimeout) expired.
+func (vm *VM) TimedOut() bool {
+	return vm.timedOut.Load()
 }
 
 // collectFunctionDefinitions scans OpCodes for function definitions and registers them.
@@ -639,7 +1093,8 @@ func (vm *VM) Stop() {
 }
 
 // Execute executes a single OpCode and returns the result.
-// This is the main dispatch method that routes OpCodes to their handlers.
+// This is the main dispatch method that routes OpCodes to their handlers
+// through dispatchTable, indexed by the integer kind decoded at load time (see decode.go).
 //
 // Requirement 8.1: When VM receives OpCode sequence, system executes each OpCode in order.
 //
@@ -650,45 +1105,16 @@ func (vm *VM) Stop() {
 //   - any: The result of the OpCode execution (may be nil)
 //   - error: Any error that occurred during execution
 func (vm *VM) Execute(op opcode.OpCode) (any, error) {
-	vm.log.Debug("Executing OpCode", "cmd", op.Cmd, "pc", vm.pc)
-
-	switch op.Cmd {
-	case opcode.Assign:
-		return vm.executeAssign(op)
-	case opcode.ArrayAssign:
-		return vm.executeArrayAssign(op)
-	case opcode.Call:
-		return vm.executeCall(op)
-	case opcode.BinaryOp:
-		return vm.executeBinaryOp(op)
-	case opcode.UnaryOp:
-		return vm.executeUnaryOp(op)
-	case opcode.ArrayAccess:
-		return vm.executeArrayAccess(op)
-	case opcode.If:
-		return vm.executeIf(op)
-	case opcode.For:
-		return vm.executeFor(op)
-	case opcode.While:
-		return vm.executeWhile(op)
-	case opcode.Switch:
-		return vm.executeSwitch(op)
-	case opcode.Break:
-		return vm.executeBreak(op)
-	case opcode.Continue:
-		return vm.executeContinue(op)
-	case opcode.RegisterEventHandler:
-		return vm.executeRegisterEventHandler(op)
-	case opcode.Wait:
-		return vm.executeWait(op)
-	case opcode.SetStep:
-		return vm.executeSetStep(op)
-	case opcode.DefineFunction:
-		// Function definitions are processed in collectFunctionDefinitions
-		return nil, nil
-	default:
-		return nil, fmt.Errorf("unknown OpCode command: %s", op.Cmd)
+	if vm.log.Enabled(context.Background(), slog.LevelDebug) {
+		vm.log.Debug("Executing OpCode", "cmd", op.Cmd, "pc", vm.pc)
 	}
+	kind := opKind(op)
+	vm.traceOpCode(op, kind)
+
+	if execute := dispatchTable[kind]; execute != nil {
+		return execute(vm, op)
+	}
+	return nil, fmt.Errorf("unknown OpCode command: %s", op.Cmd)
 }
 
 // IsRunning returns whether the VM is currently running.
@@ -811,12 +1237,13 @@ type waitMarker struct {
 //
 // Requirement 8.5: When OpIf is executed, system evaluates condition and executes appropriate branch.
 func (vm *VM) executeIf(op opcode.OpCode) (any, error) {
-	if len(op.Args) < 2 {
-		return nil, fmt.Errorf("OpIf requires at least 2 arguments, got %d", len(op.Args))
+	branches, err := decodeIf(op)
+	if err != nil {
+		return nil, err
 	}
 
 	// Evaluate the condition
-	conditionVal, err := vm.evaluateValue(op.Args[0])
+	conditionVal, err := vm.evaluateValue(branches.condition)
 	if err != nil {
 		return nil, fmt.Errorf("failed to evaluate condition: %w", err)
 	}
@@ -825,22 +1252,10 @@ func (vm *VM) executeIf(op opcode.OpCode) (any, error) {
 	vm.log.Debug("If condition evaluated", "condition", condition)
 
 	if condition {
-		// Execute then block
-		thenBlock, ok := op.Args[1].([]opcode.OpCode)
-		if !ok {
-			return nil, fmt.Errorf("OpIf then block must be []OpCode, got %T", op.Args[1])
-		}
-		return vm.executeBlock(thenBlock)
-	} else if len(op.Args) >= 3 {
-		// Execute else block if present
-		elseBlock, ok := op.Args[2].([]opcode.OpCode)
-		if !ok {
-			return nil, fmt.Errorf("OpIf else block must be []OpCode, got %T", op.Args[2])
-		}
-		return vm.executeBlock(elseBlock)
+		return vm.executeBlock(branches.then)
 	}
-
-	return nil, nil
+	// Execute else block if present
+	return vm.executeBlock(branches.otherwise)
 }
 
 // executeFor executes an OpFor OpCode.
@@ -849,31 +1264,24 @@ func (vm *VM) executeIf(op opcode.OpCode) (any, error) {
 //
 // Requirement 8.6: When OpFor is executed, system executes loop with init, condition, increment.
 func (vm *VM) executeFor(op opcode.OpCode) (any, error) {
-	if len(op.Args) < 4 {
-		return nil, fmt.Errorf("OpFor requires 4 arguments, got %d", len(op.Args))
+	loop, err := decodeFor(op)
+	if err != nil {
+		return nil, err
 	}
 
 	// Execute init block
-	if initBlock, ok := op.Args[0].([]opcode.OpCode); ok && len(initBlock) > 0 {
-		if _, err := vm.executeBlock(initBlock); err != nil {
+	if len(loop.init) > 0 {
+		if _, err := vm.executeBlock(loop.init); err != nil {
 			return nil, fmt.Errorf("failed to execute for init: %w", err)
 		}
 	}
 
-	// Get body and post blocks
-	bodyBlock, ok := op.Args[3].([]opcode.OpCode)
-	if !ok {
-		return nil, fmt.Errorf("OpFor body must be []OpCode, got %T", op.Args[3])
-	}
-
-	postBlock, _ := op.Args[2].([]opcode.OpCode)
-
 	// Loop
 	var lastResult any
 	for {
 		// Check condition (if present)
-		if op.Args[1] != nil {
-			conditionVal, err := vm.evaluateValue(op.Args[1])
+		if loop.condition != nil {
+			conditionVal, err := vm.evaluateValue(loop.condition)
 			if err != nil {
 				return nil, fmt.Errorf("failed to evaluate for condition: %w", err)
 			}
@@ -883,7 +1291,7 @@ func (vm *VM) executeFor(op opcode.OpCode) (any, error) {
 		}
 
 		// Execute body
-		result, err := vm.executeBlock(bodyBlock)
+		result, err := vm.executeBlock(loop.body)
 		if err != nil {
 			return nil, fmt.Errorf("failed to execute for body: %w", err)
 		}
@@ -912,8 +1320,8 @@ func (vm *VM) executeFor(op opcode.OpCode) (any, error) {
 		}
 
 		// Execute post block
-		if len(postBlock) > 0 {
-			if _, err := vm.executeBlock(postBlock); err != nil {
+		if len(loop.post) > 0 {
+			if _, err := vm.executeBlock(loop.post); err != nil {
 				return nil, fmt.Errorf("failed to execute for post: %w", err)
 			}
 		}
@@ -928,20 +1336,16 @@ func (vm *VM) executeFor(op opcode.OpCode) (any, error) {
 //
 // Requirement 8.7: When OpWhile is executed, system executes loop while condition is true.
 func (vm *VM) executeWhile(op opcode.OpCode) (any, error) {
-	if len(op.Args) < 2 {
-		return nil, fmt.Errorf("OpWhile requires 2 arguments, got %d", len(op.Args))
-	}
-
-	bodyBlock, ok := op.Args[1].([]opcode.OpCode)
-	if !ok {
-		return nil, fmt.Errorf("OpWhile body must be []OpCode, got %T", op.Args[1])
+	loop, err := decodeWhile(op)
+	if err != nil {
+		return nil, err
 	}
 
 	var lastResult any
 	for {
 		// Check condition
-		if op.Args[0] != nil {
-			conditionVal, err := vm.evaluateValue(op.Args[0])
+		if loop.condition != nil {
+			conditionVal, err := vm.evaluateValue(loop.condition)
 			if err != nil {
 				return nil, fmt.Errorf("failed to evaluate while condition: %w", err)
 			}
@@ -951,7 +1355,7 @@ func (vm *VM) executeWhile(op opcode.OpCode) (any, error) {
 		}
 
 		// Execute body
-		result, err := vm.executeBlock(bodyBlock)
+		result, err := vm.executeBlock(loop.body)
 		if err != nil {
 			return nil, fmt.Errorf("failed to execute while body: %w", err)
 		}
@@ -988,33 +1392,23 @@ func (vm *VM) executeWhile(op opcode.OpCode) (any, error) {
 //
 // Requirement 8.8: When OpSwitch is executed, system evaluates value and executes matching case.
 func (vm *VM) executeSwitch(op opcode.OpCode) (any, error) {
-	if len(op.Args) < 2 {
-		return nil, fmt.Errorf("OpSwitch requires at least 2 arguments, got %d", len(op.Args))
+	sw, err := decodeSwitch(op)
+	if err != nil {
+		return nil, err
 	}
 
 	// Evaluate the switch value
-	switchVal, err := vm.evaluateValue(op.Args[0])
+	switchVal, err := vm.evaluateValue(sw.value)
 	if err != nil {
 		return nil, fmt.Errorf("failed to evaluate switch value: %w", err)
 	}
 
 	vm.log.Debug("Switch value evaluated", "value", switchVal)
 
-	// Get cases
-	cases, ok := op.Args[1].([]any)
-	if !ok {
-		return nil, fmt.Errorf("OpSwitch cases must be []any, got %T", op.Args[1])
-	}
-
 	// Find matching case
-	for _, c := range cases {
-		caseClause, ok := c.(map[string]any)
-		if !ok {
-			continue
-		}
-
+	for _, c := range sw.cases {
 		// Evaluate case value
-		caseVal, err := vm.evaluateValue(caseClause["value"])
+		caseVal, err := vm.evaluateValue(c.value)
 		if err != nil {
 			return nil, fmt.Errorf("failed to evaluate case value: %w", err)
 		}
@@ -1022,11 +1416,7 @@ func (vm *VM) executeSwitch(op opcode.OpCode) (any, error) {
 		// Compare values
 		if vm.valuesEqual(switchVal, caseVal) {
 			// Execute case body
-			caseBody, ok := caseClause["body"].([]opcode.OpCode)
-			if !ok {
-				return nil, fmt.Errorf("case body must be []OpCode, got %T", caseClause["body"])
-			}
-			result, err := vm.executeBlock(caseBody)
+			result, err := vm.executeBlock(c.body)
 			if err != nil {
 				return nil, err
 			}
@@ -1039,12 +1429,8 @@ func (vm *VM) executeSwitch(op opcode.OpCode) (any, error) {
 	}
 
 	// No matching case, execute default if present
-	if len(op.Args) >= 3 && op.Args[2] != nil {
-		defaultBlock, ok := op.Args[2].([]opcode.OpCode)
-		if !ok {
-			return nil, fmt.Errorf("OpSwitch default block must be []OpCode, got %T", op.Args[2])
-		}
-		result, err := vm.executeBlock(defaultBlock)
+	if sw.defaultBlock != nil {
+		result, err := vm.executeBlock(sw.defaultBlock)
 		if err != nil {
 			return nil, err
 		}
@@ -1085,6 +1471,10 @@ func (vm *VM) executeBlock(opcodes []opcode.OpCode) (any, error) {
 	for _, op := range opcodes {
 		result, err := vm.Execute(op)
 		if err != nil {
+			// Inside a try block, the error stops the block and is handled by catch
+			if vm.tryDepth > 0 {
+				return nil, err
+			}
 			// Check if this is a fatal error (use errors.As to unwrap wrapped errors)
 			var runtimeErr *RuntimeError
 			if errors.As(err, &runtimeErr) && runtimeErr.IsFatal() {
@@ -1195,38 +1585,17 @@ func containsOpSetStep(opcodes []opcode.OpCode) bool {
 // Requirement 2.7: When mes(RBDBLCLK) handler is registered, system calls it on right mouse button double-click.
 // Requirement 2.8: When handler is registered inside another handler, system supports nested handler registration.
 func (vm *VM) executeRegisterEventHandler(op opcode.OpCode) (any, error) {
-	if len(op.Args) < 2 {
-		return nil, fmt.Errorf("OpRegisterEventHandler requires 2 arguments, got %d", len(op.Args))
-	}
-
-	// Get event type
-	eventTypeStr, ok := op.Args[0].(string)
-	if !ok {
-		return nil, fmt.Errorf("OpRegisterEventHandler event type must be string, got %T", op.Args[0])
-	}
-
-	// Convert string to EventType
-	eventType := EventType(eventTypeStr)
-
-	// Validate event type
-	switch eventType {
-	case EventTIME, EventMIDI_TIME, EventMIDI_END, EventLBDOWN, EventRBDOWN, EventRBDBLCLK, EventKEY, EventCLICK, EventCHAR, EventUSER:
-		// Valid event type
-	default:
-		return nil, fmt.Errorf("unknown event type: %s", eventTypeStr)
-	}
-
-	// Get body OpCodes
-	bodyOpcodes, ok := op.Args[1].([]opcode.OpCode)
-	if !ok {
-		return nil, fmt.Errorf("OpRegisterEventHandler body must be []OpCode, got %T", op.Args[1])
-	}
-
-	// Check if the handler contains step() blocks
+	// The event type is validated, and the body scanned for step() blocks, when decoding
 	// Requirement 1.1: When an event handler is registered, THE System SHALL scan the handler's OpCodes for OpSetStep
 	// Requirement 1.2: When OpSetStep is found in the handler's OpCodes, THE System SHALL set HasStepBlock to true
 	// Requirement 1.3: When OpSetStep is not found in the handler's OpCodes, THE System SHALL set HasStepBlock to false
-	hasStepBlock := containsOpSetStep(bodyOpcodes)
+	registration, err := decodeRegisterEventHandler(op)
+	if err != nil {
+		return nil, err
+	}
+	eventType := registration.eventType
+	bodyOpcodes := registration.body
+	hasStepBlock := registration.hasStepBlock
 
 	// Create and register the handler with the current scope
 	// This allows the handler to access variables from the enclosing scope (like C blocks)
@@ -1253,8 +1622,8 @@ func (vm *VM) executeWait(op opcode.OpCode) (any, error) {
 
 	// Get the comma count from arguments (number of commas in the step block)
 	commaCount := 1 // Default to 1 if not specified
-	if len(op.Args) >= 1 {
-		waitValue, err := vm.evaluateValue(op.Args[0])
+	if wait := decodeCount(op); wait.hasCount {
+		waitValue, err := vm.evaluateValue(wait.count)
 		if err != nil {
 			return nil, fmt.Errorf("failed to evaluate comma count: %w", err)
 		}
@@ -1303,12 +1672,13 @@ func (vm *VM) executeSetStep(op opcode.OpCode) (any, error) {
 	// The step count represents the number of TIME events to wait per comma in step() blocks.
 	// Since TIME events are generated every 50ms, step(n) means each comma waits n × 50ms.
 	// For example, step(65) means each comma waits 65 × 50ms = 3250ms.
-	if len(op.Args) < 1 {
+	step := decodeCount(op)
+	if !step.hasCount {
 		return nil, fmt.Errorf("OpSetStep requires 1 argument, got %d", len(op.Args))
 	}
 
 	// Evaluate the step value (number of TIME events per comma)
-	stepValue, err := vm.evaluateValue(op.Args[0])
+	stepValue, err := vm.evaluateValue(step.count)
 	if err != nil {
 		return nil, fmt.Errorf("failed to evaluate step value: %w", err)
 	}
@@ -1427,6 +1797,27 @@ func (vm *VM) PushKeyEvent(eventType string, keyCode int) {
 	vm.log.Debug("Keyboard event pushed", "type", eventType, "keyCode", keyCode)
 }
 
+// PushKeyInput pushes a KEY event for a non-character key press to the event queue.
+// keyCode is a Windows virtual-key code, modifiers is a combination of KeyModShift,
+// KeyModCtrl and KeyModAlt, and repeat is true for OS-style auto-repeat presses.
+// This implements the MouseEventPusher interface for the window package.
+func (vm *VM) PushKeyInput(keyCode, modifiers int, repeat bool) {
+	repeatFlag := 0
+	if repeat {
+		repeatFlag = 1
+	}
+
+	event := NewEventWithParams(EventKEY, map[string]any{
+		"MesP1": 0,          // 未使用
+		"MesP2": keyCode,    // 仮想キーコード
+		"MesP3": modifiers,  // 修飾キー（KeyModShift | KeyModCtrl | KeyModAlt）
+		"MesP4": repeatFlag, // キーリピートによる入力なら1
+	})
+
+	vm.eventQueue.Push(event)
+	vm.log.Debug("Key input pushed", "keyCode", keyCode, "modifiers", modifiers, "repeat", repeat)
+}
+
 // SetStepCounter sets the VM's step counter.
 func (vm *VM) SetStepCounter(count int) {
 	vm.stepCounter = count
@@ -1472,6 +1863,9 @@ func (vm *VM) GetAudioSystem() AudioSystemInterface {
 //   - graphicsSys: The graphics system implementing GraphicsSystemInterface
 func (vm *VM) SetGraphicsSystem(graphicsSys GraphicsSystemInterface) {
 	vm.graphicsSystem = graphicsSys
+	if graphicsSys != nil {
+		graphicsSys.SetImageSite(vm.imageSite)
+	}
 	vm.log.Info("Graphics system set")
 }
 
@@ -1511,11 +1905,29 @@ func (vm *VM) PlayMIDI(filename string) error {
 	}
 
 	// Resolve relative path using titlePath (confined to the title directory)
-	fullPath, err := vm.resolveFilePath(filename)
+	fullPath, err := vm.resolveAssetPath(filename)
+	if err != nil {
+		return err
+	}
+	if err := vm.audioSystem.PlayMIDI(fullPath); err != nil {
+		return err
+	}
+	vm.midiFile = fullPath
+	vm.restartChapterSeekMIDI()
+	return nil
+}
+
+// PlayMIDIPort plays a MIDI file on a named MIDI port, alongside the other ports.
+func (vm *VM) PlayMIDIPort(port, filename string) error {
+	if vm.audioSystem == nil {
+		return fmt.Errorf("audio system not initialized")
+	}
+
+	fullPath, err := vm.resolveAssetPath(filename)
 	if err != nil {
 		return err
 	}
-	return vm.audioSystem.PlayMIDI(fullPath)
+	return vm.audioSystem.PlayMIDIPort(port, fullPath)
 }
 
 // PlayWAVE plays a WAV file through the audio system.
@@ -1527,13 +1939,50 @@ func (vm *VM) PlayWAVE(filename string) error {
 	}
 
 	// Resolve relative path using titlePath (confined to the title directory)
-	fullPath, err := vm.resolveFilePath(filename)
+	fullPath, err := vm.resolveAssetPath(filename)
 	if err != nil {
 		return err
 	}
 	return vm.audioSystem.PlayWAVE(fullPath)
 }
 
+// PlayVoice plays a WAV file on the voice bus (narration), resolved like PlayWAVE.
+func (vm *VM) PlayVoice(filename string) error {
+	if vm.audioSystem == nil {
+		return fmt.Errorf("audio system not initialized")
+	}
+	fullPath, err := vm.resolveAssetPath(filename)
+	if err != nil {
+		return err
+	}
+	return vm.audioSystem.PlayVoice(fullPath)
+}
+
+// resolveAssetPath resolves a MIDI/WAV filename like resolveFilePath, and when the
+// file does not exist in the title directory, looks for it in each asset directory
+// in order. If it is found nowhere, the title directory path is returned so the
+// audio system reports the file as missing under its usual name.
+func (vm *VM) resolveAssetPath(filename string) (string, error) {
+	fullPath, err := vm.resolveFilePath(filename)
+	if err != nil || len(vm.assetDirs) == 0 || vm.titlePath == "" || assetExists(fullPath) {
+		return fullPath, err
+	}
+	for _, dir := range vm.assetDirs {
+		candidate, cerr := vm.resolveFilePath(filepath.Join(dir, filename))
+		if cerr == nil && assetExists(candidate) {
+			return candidate, nil
+		}
+	}
+	return fullPath, nil
+}
+
+// assetExists reports whether path names an existing file, ignoring the case of
+// its last element as FILLY titles do.
+func assetExists(path string) bool {
+	_, err := fileutil.FindFileCaseInsensitive(filepath.Dir(path), filepath.Base(path))
+	return err == nil
+}
+
 // resolveFilePath resolves a relative file path against the title directory and
 // confines the result to that directory (path-traversal protection).
 //
@@ -1545,8 +1994,18 @@ func (vm *VM) PlayWAVE(filename string) error {
 //
 // When titlePath is unset (e.g. some tests/dev contexts with no title root),
 // the filename is returned unchanged for backward compatibility.
+// In sandbox mode a title root is required, and symbolic links inside the title
+// directory that point outside it are rejected as well.
 func (vm *VM) resolveFilePath(filename string) (string, error) {
+	filename, err := fileutil.ExpandVars(filename, vm.assetVars)
+	if err != nil {
+		return "", err
+	}
+
 	if vm.titlePath == "" {
+		if vm.sandbox {
+			return "", fmt.Errorf("file access to %q requires a title directory: %w", filename, ErrSandboxViolation)
+		}
 		return filename, nil
 	}
 
@@ -1570,6 +2029,12 @@ func (vm *VM) resolveFilePath(filename string) (string, error) {
 		return "", fmt.Errorf("file path %q escapes the title directory", filename)
 	}
 
+	if vm.sandbox {
+		if err := fileutil.ConfinePath(base, joined); err != nil {
+			return "", fmt.Errorf("file path %q: %w: %w", filename, ErrSandboxViolation, err)
+		}
+	}
+
 	return joined, nil
 }
 
@@ -1589,6 +2054,98 @@ func (vm *VM) StopTimer() {
 	}
 }
 
+// Pause suspends the tick clock (TIME/MIDI_TIME generation) and mutes audio.
+// Used by --pause-on-blur when the window loses focus.
+// Handlers waiting on TIME or MIDI_TIME simply receive no events until Resume.
+func (vm *VM) Pause() {
+	if vm.audioSystem != nil {
+		vm.audioSystem.Pause()
+	}
+	vm.log.Info("VM paused")
+}
+
+// Resume continues the tick clock and audio suspended by Pause.
+func (vm *VM) Resume() {
+	if vm.audioSystem != nil {
+		vm.audioSystem.Resume()
+	}
+	vm.log.Info("VM resumed")
+}
+
+// SetMuted mutes or unmutes all audio output without stopping playback.
+// Used by the pause menu; unlike SetMute in scripts, the mixer buses are left unchanged.
+func (vm *VM) SetMuted(muted bool) {
+	if vm.audioSystem != nil {
+		vm.audioSystem.SetMuted(muted)
+	}
+}
+
+// SetTimeScale sets the playback speed (slow motion / fast forward) of the TIME
+// timer and MIDI playback and returns the applied scale (clamped to 0.25-4).
+// Used by the time scale hotkeys; scripts see fewer or more TIME/MIDI_TIME events
+// per second but their step counts are unchanged. Returns 1 without an audio system.
+func (vm *VM) SetTimeScale(scale float64) float64 {
+	if vm.audioSystem == nil {
+		return 1
+	}
+	applied := vm.audioSystem.SetTimeScale(scale)
+	vm.log.Info("Time scale changed", "scale", applied)
+	return applied
+}
+
+// TimeScale returns the playback speed set by SetTimeScale (1 = normal speed).
+func (vm *VM) TimeScale() float64 {
+	if vm.audioSystem == nil {
+		return 1
+	}
+	return vm.audioSystem.TimeScale()
+}
+
+// SetAVOffset sets how far MIDI_TIME events lag behind the audio (positive values
+// compensate for speaker latency) and returns the applied offset (clamped to ±1s).
+// Used by the calibration screen. Returns 0 without an audio system.
+func (vm *VM) SetAVOffset(offset time.Duration) time.Duration {
+	if vm.audioSystem == nil {
+		return 0
+	}
+	applied := vm.audioSystem.SetAVOffset(offset)
+	vm.log.Info("A/V offset changed", "offset", applied)
+	return applied
+}
+
+// AVOffset returns the offset set by SetAVOffset.
+func (vm *VM) AVOffset() time.Duration {
+	if vm.audioSystem == nil {
+		return 0
+	}
+	return vm.audioSystem.AVOffset()
+}
+
+// StartAVCalibration pauses the title's audio and starts the calibration click track.
+func (vm *VM) StartAVCalibration() error {
+	if vm.audioSystem == nil {
+		return fmt.Errorf("no audio system")
+	}
+	return vm.audioSystem.StartAVCalibration()
+}
+
+// StopAVCalibration stops the calibration click track and resumes the title's audio.
+func (vm *VM) StopAVCalibration() {
+	if vm.audioSystem != nil {
+		vm.audioSystem.StopAVCalibration()
+	}
+}
+
+// AVCalibrationBeat reports whether the calibration screen should show the beat now.
+func (vm *VM) AVCalibrationBeat() bool {
+	return vm.audioSystem != nil && vm.audioSystem.AVCalibrationBeat()
+}
+
+// MetronomeBeat reports whether the beat indicator should be shown now (--metronome-flash).
+func (vm *VM) MetronomeBeat() bool {
+	return vm.audioSystem != nil && vm.audioSystem.MetronomeBeat()
+}
+
 // GetSoundFontPath returns the configured SoundFont path.
 func (vm *VM) GetSoundFontPath() string {
 	return vm.soundFontPath
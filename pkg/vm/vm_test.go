@@ -2,7 +2,9 @@ package vm
 
 import (
 	"fmt"
+	"image"
 	"image/color"
+	"slices"
 	"testing"
 	"time"
 
@@ -1583,8 +1585,96 @@ type mockGraphicsSystem struct {
 	createPicErr   error // Error to return from CreatePic
 	windows        map[int]*mockWindow
 	nextWinID      int
-	capTitleAllCnt int    // Count of CapTitleAll calls
-	lastCapTitle   string // Last title set by CapTitleAll
+	capTitleAllCnt int                        // Count of CapTitleAll calls
+	lastCapTitle   string                     // Last title set by CapTitleAll
+	castAt         func(x, y int) int         // Hit-test result for CastAt (nil returns -1)
+	fades          []mockFade                 // Fades started by Fade
+	palette        map[int]int                // Palette entries set by SetPaletteColor
+	paletteCycles  [][3]int                   // (start, count, step) of CyclePalette calls
+	paletteResets  int                        // Count of ResetPalette calls
+	restacks       []string                   // Render order changes ("win 1 front", "cast 2 back", ...)
+	display        map[string]float64         // Display adjustment values by name ("gamma", "brightness", "contrast")
+	textColor      any                        // Last color set by SetTextColor
+	pools          map[int]*mockPool          // Sprite pools created by CreateSpritePool
+	masks          map[string]*graphics.Mask  // Masks by target ("cast 1", "win 2"); removed masks are deleted
+	shadows        map[int]*graphics.Shadow   // Shadows by cast ID; removed shadows are deleted
+	outlines       map[int]*graphics.Outline  // Outlines by cast ID; removed outlines are deleted
+	sourceRects    map[int]image.Rectangle    // Source rects by cast ID; removed rects are deleted
+	nineSlices     map[int]graphics.NineSlice // Nine-slices by cast ID; removed nine-slices are deleted
+	hitEffects     []mockHitEffect            // Effects started by ShakeCast and FlashCast
+	crossfades     map[int][]float64          // Progress set by SetCastCrossfade by cast ID (StartCastCrossfade adds 0)
+	pathMotions    []mockPathMotion           // Motions started by MoveCastAlongPath
+	cameraMoves    []mockCameraMove           // Cameras set by SetCamera (zero duration) and MoveCamera
+	cursor         *graphics.Cursor           // Custom cursor set by SetCursor
+	cursorClick    *graphics.CursorClick      // Click animation set by SetCursorClick
+	sysCursorOff   bool                       // SetSystemCursorVisible(false) was called
+	appWindow      graphics.AppWindowSettings // OS window settings set by SetWindowTitle/SetWindowIcon/SetWindowSize
+	displayScale   float64                    // Value returned by DisplayScale (0 returns 1)
+	textPitches    []graphics.FontPitch       // Pitches passed to TextWriteWithLayout/TextExtentWithLayout
+	textLayouts    []graphics.TextLayout      // Layouts passed to TextWriteWithLayout/TextExtentWithLayout
+	textDirection  graphics.TextDirection     // Direction set by SetTextDirection
+	highlights     []mockHighlightCall        // Calls to TextWriteHighlighted
+	snapshots      []mockSnapshotCall         // Calls to SaveSnapshot
+	layerDirs      []string                   // Directories passed to CaptureLayers
+	castPics       map[int]int                // Pictures set by MoveCastWithOptions, keyed by cast ID
+	imageSite      func() string              // Function set by SetImageSite
+	imageSamples   []time.Time                // Calls to SampleImageUsage
+	imageIdle      time.Duration              // Idle passed to the last ImageReport
+
+	keyframes         int // Calls to CaptureKeyframe
+	restoredKeyframes int // Calls to RestoreKeyframe
+}
+
+type mockSnapshotCall struct {
+	castID int
+	path   string
+}
+
+type mockHighlightCall struct {
+	picID, x, y int
+	text        string
+	count       int
+	color       any
+}
+
+type mockPool struct {
+	srcPicID, dstPicID int
+	transColor         color.Color
+	particles          []mockParticle
+	steps              int
+}
+
+type mockParticle struct {
+	x, y, vx, vy float64
+	visible      bool
+}
+
+type mockHitEffect struct {
+	name      string // "shake" or "flash"
+	castID    int
+	amplitude int
+	color     color.Color
+	duration  time.Duration
+}
+
+type mockPathMotion struct {
+	castID   int
+	path     *graphics.Path
+	duration time.Duration
+	easing   graphics.Easing
+}
+
+type mockCameraMove struct {
+	camera   *graphics.Camera // nil resets the camera
+	duration time.Duration
+	easing   graphics.Easing
+}
+
+type mockFade struct {
+	fadeOut  bool
+	color    any
+	duration time.Duration
+	onDone   func()
 }
 
 type mockPicture struct {
@@ -1612,6 +1702,36 @@ func (m *mockGraphicsSystem) LoadPic(filename string) (int, error) {
 	return -1, fmt.Errorf("not implemented")
 }
 
+func (m *mockGraphicsSystem) LoadPicAsync(filename string, onDone func(picID int, err error)) (int, error) {
+	if filename == "missing.bmp" {
+		return -1, fmt.Errorf("file not found: %s", filename)
+	}
+	id := m.nextPicID
+	m.nextPicID++
+	m.pictures[id] = &mockPicture{id: id, width: 640, height: 480}
+	if onDone != nil {
+		var err error
+		if filename == "broken.bmp" {
+			err = fmt.Errorf("failed to decode image")
+		}
+		onDone(id, err)
+	}
+	return id, nil
+}
+
+// LoadPicFrames loads 3 frames of 32x16 shown for 10, 20 and 5 (1/100 s)
+func (m *mockGraphicsSystem) LoadPicFrames(filename string) ([]int, []int, error) {
+	if filename == "missing.gif" {
+		return nil, nil, fmt.Errorf("file not found: %s", filename)
+	}
+	var ids []int
+	for range 3 {
+		id, _ := m.CreatePic(32, 16)
+		ids = append(ids, id)
+	}
+	return ids, []int{10, 20, 5}, nil
+}
+
 func (m *mockGraphicsSystem) CreatePic(width, height int) (int, error) {
 	if m.createPicErr != nil {
 		return -1, m.createPicErr
@@ -1757,6 +1877,16 @@ func (m *mockGraphicsSystem) MoveCast(id int, opts ...any) error {
 }
 
 func (m *mockGraphicsSystem) MoveCastWithOptions(id int, opts ...graphics.CastOption) error {
+	cast := graphics.Cast{PicID: -1}
+	for _, opt := range opts {
+		opt(&cast)
+	}
+	if cast.PicID >= 0 {
+		if m.castPics == nil {
+			m.castPics = make(map[int]int)
+		}
+		m.castPics[id] = cast.PicID
+	}
 	return nil
 }
 
@@ -1764,7 +1894,21 @@ func (m *mockGraphicsSystem) DelCast(id int) error {
 	return nil
 }
 
-func (m *mockGraphicsSystem) TextWrite(picID, x, y int, text string) error {
+func (m *mockGraphicsSystem) CastAt(x, y int) int {
+	if m.castAt != nil {
+		return m.castAt(x, y)
+	}
+	return -1
+}
+
+func (m *mockGraphicsSystem) TextWriteWithLayout(picID, x, y int, text string, pitch graphics.FontPitch, layout graphics.TextLayout) error {
+	m.textPitches = append(m.textPitches, pitch)
+	m.textLayouts = append(m.textLayouts, layout)
+	return nil
+}
+
+func (m *mockGraphicsSystem) TextWriteHighlighted(picID, x, y int, text string, count int, highlightColor any) error {
+	m.highlights = append(m.highlights, mockHighlightCall{picID: picID, x: x, y: y, text: text, count: count, color: highlightColor})
 	return nil
 }
 
@@ -1772,7 +1916,17 @@ func (m *mockGraphicsSystem) SetFont(name string, size int, opts ...any) error {
 	return nil
 }
 
+func (m *mockGraphicsSystem) TextExtentWithLayout(text, fontName string, size int, pitch graphics.FontPitch, layout graphics.TextLayout) (int, int) {
+	m.textPitches = append(m.textPitches, pitch)
+	m.textLayouts = append(m.textLayouts, layout)
+	if fontName == "" {
+		size = 16
+	}
+	return len(text) * size / 2, size
+}
+
 func (m *mockGraphicsSystem) SetTextColor(c any) error {
+	m.textColor = c
 	return nil
 }
 
@@ -1784,6 +1938,11 @@ func (m *mockGraphicsSystem) SetBackMode(mode int) error {
 	return nil
 }
 
+func (m *mockGraphicsSystem) SetTextDirection(direction graphics.TextDirection) error {
+	m.textDirection = direction
+	return nil
+}
+
 func (m *mockGraphicsSystem) DrawLine(picID, x1, y1, x2, y2 int) error {
 	return nil
 }
@@ -1810,6 +1969,361 @@ func (m *mockGraphicsSystem) GetColor(picID, x, y int) (int, error) {
 	return 0, nil
 }
 
+func (m *mockGraphicsSystem) Fade(fadeOut bool, c any, duration time.Duration, onDone func()) error {
+	m.fades = append(m.fades, mockFade{fadeOut: fadeOut, color: c, duration: duration, onDone: onDone})
+	return nil
+}
+
+func (m *mockGraphicsSystem) BringWinToFront(id int) error {
+	m.restacks = append(m.restacks, fmt.Sprintf("win %d front", id))
+	return nil
+}
+
+func (m *mockGraphicsSystem) SendWinToBack(id int) error {
+	m.restacks = append(m.restacks, fmt.Sprintf("win %d back", id))
+	return nil
+}
+
+func (m *mockGraphicsSystem) BringCastToFront(id int) error {
+	if id < 0 {
+		return fmt.Errorf("cast not found: %d", id)
+	}
+	m.restacks = append(m.restacks, fmt.Sprintf("cast %d front", id))
+	return nil
+}
+
+func (m *mockGraphicsSystem) SendCastToBack(id int) error {
+	m.restacks = append(m.restacks, fmt.Sprintf("cast %d back", id))
+	return nil
+}
+
+func (m *mockGraphicsSystem) SetPaletteColor(index int, c any) error {
+	if index < 0 || index > 255 {
+		return fmt.Errorf("palette index out of range: %d", index)
+	}
+	if m.palette == nil {
+		m.palette = make(map[int]int)
+	}
+	m.palette[index] = c.(int)
+	return nil
+}
+
+func (m *mockGraphicsSystem) GetPaletteColor(index int) (int, error) {
+	if index < 0 || index > 255 {
+		return 0, fmt.Errorf("palette index out of range: %d", index)
+	}
+	return m.palette[index], nil
+}
+
+func (m *mockGraphicsSystem) CyclePalette(start, count, step int) error {
+	m.paletteCycles = append(m.paletteCycles, [3]int{start, count, step})
+	return nil
+}
+
+func (m *mockGraphicsSystem) ResetPalette() {
+	m.paletteResets++
+	m.palette = nil
+}
+
+func (m *mockGraphicsSystem) setDisplay(name string, v, minV, maxV float64) error {
+	if v < minV || v > maxV {
+		return fmt.Errorf("%s out of range: %g", name, v)
+	}
+	if m.display == nil {
+		m.display = make(map[string]float64)
+	}
+	m.display[name] = v
+	return nil
+}
+
+func (m *mockGraphicsSystem) SetGamma(v float64) error {
+	return m.setDisplay("gamma", v, 0.1, 5)
+}
+
+func (m *mockGraphicsSystem) SetBrightness(v float64) error {
+	return m.setDisplay("brightness", v, -1, 1)
+}
+
+func (m *mockGraphicsSystem) SetContrast(v float64) error {
+	return m.setDisplay("contrast", v, 0, 4)
+}
+
+func (m *mockGraphicsSystem) CreateSpritePool(srcPicID, dstPicID, n int, transColor color.Color) (int, error) {
+	if n <= 0 {
+		return -1, fmt.Errorf("invalid pool size: %d", n)
+	}
+	if m.pools == nil {
+		m.pools = make(map[int]*mockPool)
+	}
+	id := len(m.pools) + 1
+	m.pools[id] = &mockPool{srcPicID: srcPicID, dstPicID: dstPicID, transColor: transColor, particles: make([]mockParticle, n)}
+	return id, nil
+}
+
+func (m *mockGraphicsSystem) mockPoolParticle(poolID, index int) (*mockParticle, error) {
+	pool, ok := m.pools[poolID]
+	if !ok {
+		return nil, fmt.Errorf("sprite pool not found: %d", poolID)
+	}
+	if index < 0 || index >= len(pool.particles) {
+		return nil, fmt.Errorf("index out of range: %d", index)
+	}
+	return &pool.particles[index], nil
+}
+
+func (m *mockGraphicsSystem) SetPoolParticle(poolID, index int, x, y float64, visible bool) error {
+	p, err := m.mockPoolParticle(poolID, index)
+	if err != nil {
+		return err
+	}
+	p.x, p.y, p.visible = x, y, visible
+	return nil
+}
+
+func (m *mockGraphicsSystem) SetPoolVelocity(poolID, index int, vx, vy float64) error {
+	if index < 0 {
+		pool, ok := m.pools[poolID]
+		if !ok {
+			return fmt.Errorf("sprite pool not found: %d", poolID)
+		}
+		for i := range pool.particles {
+			pool.particles[i].vx, pool.particles[i].vy = vx, vy
+		}
+		return nil
+	}
+	p, err := m.mockPoolParticle(poolID, index)
+	if err != nil {
+		return err
+	}
+	p.vx, p.vy = vx, vy
+	return nil
+}
+
+func (m *mockGraphicsSystem) StepSpritePool(poolID int) error {
+	pool, ok := m.pools[poolID]
+	if !ok {
+		return fmt.Errorf("sprite pool not found: %d", poolID)
+	}
+	pool.steps++
+	return nil
+}
+
+func (m *mockGraphicsSystem) SpritePoolSize(poolID int) int {
+	if pool, ok := m.pools[poolID]; ok {
+		return len(pool.particles)
+	}
+	return 0
+}
+
+func (m *mockGraphicsSystem) DelSpritePool(poolID int) error {
+	if _, ok := m.pools[poolID]; !ok {
+		return fmt.Errorf("sprite pool not found: %d", poolID)
+	}
+	delete(m.pools, poolID)
+	return nil
+}
+
+func (m *mockGraphicsSystem) SetCastMask(id int, mask *graphics.Mask) error {
+	return m.setMask(fmt.Sprintf("cast %d", id), mask)
+}
+
+func (m *mockGraphicsSystem) SetWinMask(id int, mask *graphics.Mask) error {
+	return m.setMask(fmt.Sprintf("win %d", id), mask)
+}
+
+func (m *mockGraphicsSystem) SetCastShadow(id int, shadow *graphics.Shadow) error {
+	if m.shadows == nil {
+		m.shadows = make(map[int]*graphics.Shadow)
+	}
+	if shadow == nil {
+		delete(m.shadows, id)
+		return nil
+	}
+	m.shadows[id] = shadow
+	return nil
+}
+
+func (m *mockGraphicsSystem) SetCastOutline(id int, outline *graphics.Outline) error {
+	if m.outlines == nil {
+		m.outlines = make(map[int]*graphics.Outline)
+	}
+	if outline == nil {
+		delete(m.outlines, id)
+		return nil
+	}
+	m.outlines[id] = outline
+	return nil
+}
+
+func (m *mockGraphicsSystem) SetCastSourceRect(id int, rect *image.Rectangle) error {
+	if m.sourceRects == nil {
+		m.sourceRects = make(map[int]image.Rectangle)
+	}
+	if rect == nil {
+		delete(m.sourceRects, id)
+		return nil
+	}
+	m.sourceRects[id] = *rect
+	return nil
+}
+
+func (m *mockGraphicsSystem) SetCastNineSlice(id int, ns *graphics.NineSlice) error {
+	if m.nineSlices == nil {
+		m.nineSlices = make(map[int]graphics.NineSlice)
+	}
+	if ns == nil {
+		delete(m.nineSlices, id)
+		return nil
+	}
+	m.nineSlices[id] = *ns
+	return nil
+}
+
+func (m *mockGraphicsSystem) ShakeCast(id, amplitude int, duration time.Duration) error {
+	m.hitEffects = append(m.hitEffects, mockHitEffect{name: "shake", castID: id, amplitude: amplitude, duration: duration})
+	return nil
+}
+
+func (m *mockGraphicsSystem) FlashCast(id int, c color.Color, duration time.Duration) error {
+	m.hitEffects = append(m.hitEffects, mockHitEffect{name: "flash", castID: id, color: c, duration: duration})
+	return nil
+}
+
+func (m *mockGraphicsSystem) StartCastCrossfade(id int) error {
+	if m.crossfades == nil {
+		m.crossfades = make(map[int][]float64)
+	}
+	m.crossfades[id] = append(m.crossfades[id], 0)
+	return nil
+}
+
+func (m *mockGraphicsSystem) SetCastCrossfade(id int, progress float64) error {
+	if m.crossfades == nil {
+		m.crossfades = make(map[int][]float64)
+	}
+	m.crossfades[id] = append(m.crossfades[id], progress)
+	return nil
+}
+
+func (m *mockGraphicsSystem) MoveCastAlongPath(id int, path *graphics.Path, duration time.Duration, easing graphics.Easing) error {
+	m.pathMotions = append(m.pathMotions, mockPathMotion{castID: id, path: path, duration: duration, easing: easing})
+	return nil
+}
+
+func (m *mockGraphicsSystem) SetCamera(cam *graphics.Camera) error {
+	m.cameraMoves = append(m.cameraMoves, mockCameraMove{camera: cam})
+	return nil
+}
+
+func (m *mockGraphicsSystem) MoveCamera(cam graphics.Camera, duration time.Duration, easing graphics.Easing) error {
+	m.cameraMoves = append(m.cameraMoves, mockCameraMove{camera: &cam, duration: duration, easing: easing})
+	return nil
+}
+
+func (m *mockGraphicsSystem) SetImageSite(site func() string) { m.imageSite = site }
+func (m *mockGraphicsSystem) SampleImageUsage(now time.Time) {
+	m.imageSamples = append(m.imageSamples, now)
+}
+
+// ImageReport reports every picture as hidden since it was created
+func (m *mockGraphicsSystem) ImageReport(idle time.Duration, now time.Time) *graphics.ImageReport {
+	m.imageIdle = idle
+	report := &graphics.ImageReport{Pictures: len(m.pictures), Idle: idle}
+	for id := range m.nextPicID {
+		if p, ok := m.pictures[id]; ok {
+			report.Leaks = append(report.Leaks, graphics.ImageUsage{PicID: id, Width: p.width, Height: p.height})
+		}
+	}
+	return report
+}
+
+// CaptureKeyframe keeps nothing: restoring a keyframe of the mock leaves its state as it is
+func (m *mockGraphicsSystem) CaptureKeyframe() (graphics.Keyframe, error) {
+	m.keyframes++
+	return mockKeyframe{}, nil
+}
+
+func (m *mockGraphicsSystem) RestoreKeyframe(kf graphics.Keyframe) error {
+	m.restoredKeyframes++
+	return nil
+}
+
+type mockKeyframe struct{}
+
+func (mockKeyframe) Release() {}
+
+func (m *mockGraphicsSystem) SetCursor(c *graphics.Cursor) error {
+	if c != nil {
+		if _, ok := m.pictures[c.PicID]; !ok {
+			return fmt.Errorf("picture not found: %d", c.PicID)
+		}
+	}
+	m.cursor = c
+	m.cursorClick = nil
+	return nil
+}
+
+func (m *mockGraphicsSystem) SetCursorClick(click *graphics.CursorClick) error {
+	if m.cursor == nil {
+		return fmt.Errorf("no custom cursor is set")
+	}
+	m.cursorClick = click
+	return nil
+}
+
+func (m *mockGraphicsSystem) SetSystemCursorVisible(visible bool) {
+	m.sysCursorOff = !visible
+}
+
+func (m *mockGraphicsSystem) SetWindowTitle(title string) {
+	m.appWindow.Title = title
+}
+
+func (m *mockGraphicsSystem) SetWindowIcon(filename string) error {
+	if filename == "" {
+		return fmt.Errorf("file not found: %s", filename)
+	}
+	m.appWindow.IconFile = filename
+	return nil
+}
+
+func (m *mockGraphicsSystem) SetWindowSize(width, height int) error {
+	if width <= 0 || height <= 0 {
+		return fmt.Errorf("invalid window size: %dx%d", width, height)
+	}
+	m.appWindow.Width, m.appWindow.Height = width, height
+	return nil
+}
+
+func (m *mockGraphicsSystem) DisplayScale() float64 {
+	if m.displayScale == 0 {
+		return 1
+	}
+	return m.displayScale
+}
+
+func (m *mockGraphicsSystem) SaveSnapshot(castID int, path string) error {
+	m.snapshots = append(m.snapshots, mockSnapshotCall{castID: castID, path: path})
+	return nil
+}
+
+func (m *mockGraphicsSystem) CaptureLayers(dir string) error {
+	m.layerDirs = append(m.layerDirs, dir)
+	return nil
+}
+
+func (m *mockGraphicsSystem) setMask(target string, mask *graphics.Mask) error {
+	if m.masks == nil {
+		m.masks = make(map[string]*graphics.Mask)
+	}
+	if mask == nil {
+		delete(m.masks, target)
+		return nil
+	}
+	m.masks[target] = mask
+	return nil
+}
+
 func (m *mockGraphicsSystem) GetVirtualWidth() int {
 	return 800
 }
@@ -2440,6 +2954,337 @@ func TestVMBuiltinCapTitle(t *testing.T) {
 	})
 }
 
+// TestVMBuiltinTextWidth tests TextWidth/TextHeight text measurement.
+func TestVMBuiltinTextWidth(t *testing.T) {
+	t.Run("measures with the current font", func(t *testing.T) {
+		vm := New([]opcode.OpCode{})
+		vm.SetGraphicsSystem(newMockGraphicsSystem())
+
+		width, err := vm.builtins["TextWidth"](vm, []any{"Hello"})
+		if err != nil {
+			t.Fatalf("TextWidth returned error: %v", err)
+		}
+		if width != int64(40) {
+			t.Errorf("TextWidth = %v, want 40", width)
+		}
+
+		height, _ := vm.builtins["TextHeight"](vm, []any{"Hello"})
+		if height != int64(16) {
+			t.Errorf("TextHeight = %v, want 16", height)
+		}
+	})
+
+	t.Run("measures with the specified font and size", func(t *testing.T) {
+		vm := New([]opcode.OpCode{})
+		vm.SetGraphicsSystem(newMockGraphicsSystem())
+
+		width, _ := vm.builtins["TextWidth"](vm, []any{"Hello", "MS Gothic", int64(24)})
+		if width != int64(60) {
+			t.Errorf("TextWidth = %v, want 60", width)
+		}
+		height, _ := vm.builtins["TextHeight"](vm, []any{"Hello", "MS Gothic", int64(24)})
+		if height != int64(24) {
+			t.Errorf("TextHeight = %v, want 24", height)
+		}
+	})
+
+	t.Run("returns 0 without graphics system or arguments", func(t *testing.T) {
+		vm := New([]opcode.OpCode{})
+		if width, _ := vm.builtins["TextWidth"](vm, []any{"Hello"}); width != int64(0) {
+			t.Errorf("TextWidth without graphics = %v, want 0", width)
+		}
+
+		vm.SetGraphicsSystem(newMockGraphicsSystem())
+		if height, _ := vm.builtins["TextHeight"](vm, []any{}); height != int64(0) {
+			t.Errorf("TextHeight without arguments = %v, want 0", height)
+		}
+	})
+
+	t.Run("passes the pitch", func(t *testing.T) {
+		vm := New([]opcode.OpCode{})
+		gs := newMockGraphicsSystem()
+		vm.SetGraphicsSystem(gs)
+
+		vm.builtins["TextWidth"](vm, []any{"Hello", "MS Gothic", int64(24), "proportional"})
+		vm.builtins["TextHeight"](vm, []any{"Hello", "MS Gothic", int64(24), int64(1)})
+		vm.builtins["TextWidth"](vm, []any{"Hello", "MS Gothic", int64(24), "wide"})
+		vm.builtins["TextWidth"](vm, []any{"Hello"})
+		want := []graphics.FontPitch{graphics.PitchProportional, graphics.PitchFixed, graphics.PitchDefault, graphics.PitchDefault}
+		if !slices.Equal(gs.textPitches, want) {
+			t.Errorf("pitches = %v, want %v", gs.textPitches, want)
+		}
+	})
+}
+
+// TestVMBuiltinTextDirection tests switching between horizontal and vertical text.
+func TestVMBuiltinTextDirection(t *testing.T) {
+	vm := New([]opcode.OpCode{})
+	gs := newMockGraphicsSystem()
+	vm.SetGraphicsSystem(gs)
+
+	vm.builtins["TextDirection"](vm, []any{int64(1)})
+	if gs.textDirection != graphics.TextVertical {
+		t.Errorf("direction = %v, want vertical", gs.textDirection)
+	}
+	vm.builtins["TextDirection"](vm, []any{"horizontal"})
+	if gs.textDirection != graphics.TextHorizontal {
+		t.Errorf("direction = %v, want horizontal", gs.textDirection)
+	}
+	vm.builtins["TextDirection"](vm, []any{"Vertical"})
+	if gs.textDirection != graphics.TextVertical {
+		t.Errorf("direction = %v, want vertical", gs.textDirection)
+	}
+
+	// 不正な値は無視する
+	if _, err := vm.builtins["TextDirection"](vm, []any{int64(2)}); err != nil {
+		t.Errorf("invalid direction should not stop the script: %v", err)
+	}
+	if gs.textDirection != graphics.TextVertical {
+		t.Errorf("invalid direction should keep the current direction, got %v", gs.textDirection)
+	}
+	if _, err := vm.builtins["TextDirection"](vm, []any{}); err == nil {
+		t.Error("TextDirection without arguments should return an error")
+	}
+}
+
+// TestVMBuiltinTextWritePitch tests the optional pitch argument of TextWrite.
+func TestVMBuiltinTextWritePitch(t *testing.T) {
+	vm := New([]opcode.OpCode{})
+	gs := newMockGraphicsSystem()
+	vm.SetGraphicsSystem(gs)
+
+	vm.builtins["TextWrite"](vm, []any{"| a | b |", int64(0), int64(0), int64(0)})
+	vm.builtins["TextWrite"](vm, []any{"| a | b |", int64(0), int64(0), int64(0), "fixed"})
+	vm.builtins["TextWrite"](vm, []any{"| a | b |", int64(0), int64(0), int64(0), int64(2)})
+	vm.builtins["TextWrite"](vm, []any{"| a | b |", int64(0), int64(0), int64(0), int64(5)})
+	want := []graphics.FontPitch{graphics.PitchDefault, graphics.PitchFixed, graphics.PitchProportional, graphics.PitchDefault}
+	if !slices.Equal(gs.textPitches, want) {
+		t.Errorf("pitches = %v, want %v", gs.textPitches, want)
+	}
+}
+
+// TestVMBuiltinTextLayout tests the optional letter and line spacing arguments of TextWrite, TextWidth and TextHeight.
+func TestVMBuiltinTextLayout(t *testing.T) {
+	vm := New([]opcode.OpCode{})
+	gs := newMockGraphicsSystem()
+	vm.SetGraphicsSystem(gs)
+
+	vm.builtins["TextWrite"](vm, []any{"a", int64(0), int64(0), int64(0)})
+	vm.builtins["TextWrite"](vm, []any{"a\nb", int64(0), int64(0), int64(0), int64(0), int64(-1)})
+	vm.builtins["TextWrite"](vm, []any{"a\nb", int64(0), int64(0), int64(0), int64(1), int64(2), int64(-3)})
+	vm.builtins["TextWidth"](vm, []any{"a", "", int64(0), int64(0), int64(4)})
+	vm.builtins["TextHeight"](vm, []any{"a", "", int64(0), int64(0), int64(0), int64(5)})
+	// 数値でない字間は 0 として扱う
+	vm.builtins["TextWrite"](vm, []any{"a", int64(0), int64(0), int64(0), int64(0), "wide"})
+	want := []graphics.TextLayout{
+		{},
+		{Tracking: -1},
+		{Tracking: 2, LineSpacing: -3},
+		{Tracking: 4},
+		{LineSpacing: 5},
+		{},
+	}
+	if !slices.Equal(gs.textLayouts, want) {
+		t.Errorf("layouts = %v, want %v", gs.textLayouts, want)
+	}
+}
+
+// TestVMBuiltinFade tests the FadeOut and FadeIn built-in functions.
+func TestVMBuiltinFade(t *testing.T) {
+	t.Run("FadeOut defaults to black", func(t *testing.T) {
+		vm := New([]opcode.OpCode{})
+		gs := newMockGraphicsSystem()
+		vm.SetGraphicsSystem(gs)
+
+		if _, err := vm.builtins["FadeOut"](vm, []any{int64(20)}); err != nil {
+			t.Fatalf("FadeOut returned error: %v", err)
+		}
+		if len(gs.fades) != 1 {
+			t.Fatalf("expected 1 fade, got %d", len(gs.fades))
+		}
+		fade := gs.fades[0]
+		if !fade.fadeOut {
+			t.Error("FadeOut should fade out")
+		}
+		if fade.color != 0x000000 {
+			t.Errorf("color = %v, want 0x000000", fade.color)
+		}
+		if fade.duration != time.Second {
+			t.Errorf("duration = %v, want 1s (20 ticks)", fade.duration)
+		}
+	})
+
+	t.Run("FadeIn with color", func(t *testing.T) {
+		vm := New([]opcode.OpCode{})
+		gs := newMockGraphicsSystem()
+		vm.SetGraphicsSystem(gs)
+
+		vm.builtins["FadeIn"](vm, []any{int64(4), int64(0xFFFFFF)})
+		if len(gs.fades) != 1 {
+			t.Fatalf("expected 1 fade, got %d", len(gs.fades))
+		}
+		fade := gs.fades[0]
+		if fade.fadeOut {
+			t.Error("FadeIn should not fade out")
+		}
+		if fade.color != 0xFFFFFF {
+			t.Errorf("color = %v, want 0xFFFFFF", fade.color)
+		}
+		if fade.duration != 200*time.Millisecond {
+			t.Errorf("duration = %v, want 200ms", fade.duration)
+		}
+	})
+
+	t.Run("completion pushes FADE_END", func(t *testing.T) {
+		vm := New([]opcode.OpCode{})
+		gs := newMockGraphicsSystem()
+		vm.SetGraphicsSystem(gs)
+
+		vm.builtins["FadeOut"](vm, []any{int64(10)})
+		gs.fades[0].onDone()
+
+		event, ok := vm.eventQueue.Pop()
+		if !ok {
+			t.Fatal("expected FADE_END event in the queue")
+		}
+		if event.Type != EventFADE_END {
+			t.Errorf("event type = %s, want FADE_END", event.Type)
+		}
+		if p1, _ := eventParamInt(event, "MesP1"); p1 != 1 {
+			t.Errorf("MesP1 = %d, want 1 for FadeOut", p1)
+		}
+	})
+
+	t.Run("invalid arguments are ignored", func(t *testing.T) {
+		vm := New([]opcode.OpCode{})
+		if _, err := vm.builtins["FadeOut"](vm, []any{int64(10)}); err != nil {
+			t.Errorf("FadeOut without graphics should not return error: %v", err)
+		}
+
+		gs := newMockGraphicsSystem()
+		vm.SetGraphicsSystem(gs)
+		for _, args := range [][]any{{}, {int64(-1)}, {"abc"}} {
+			result, err := vm.builtins["FadeIn"](vm, args)
+			if err != nil || result != nil {
+				t.Errorf("FadeIn(%v) = (%v, %v), want (nil, nil)", args, result, err)
+			}
+		}
+		if len(gs.fades) != 0 {
+			t.Errorf("no fade should be started, got %d", len(gs.fades))
+		}
+	})
+
+	t.Run("mes(FADE_END) is a valid event type", func(t *testing.T) {
+		vm := New([]opcode.OpCode{})
+		op := opcode.OpCode{
+			Cmd:  opcode.RegisterEventHandler,
+			Args: []any{"FADE_END", []opcode.OpCode{}},
+		}
+		if _, err := vm.executeRegisterEventHandler(op); err != nil {
+			t.Errorf("mes(FADE_END) should be accepted: %v", err)
+		}
+	})
+}
+
+// TestVMBuiltinRenderOrder tests the window and cast render order built-in functions.
+func TestVMBuiltinRenderOrder(t *testing.T) {
+	vm := New([]opcode.OpCode{})
+	gs := newMockGraphicsSystem()
+	vm.SetGraphicsSystem(gs)
+
+	vm.builtins["BringWinToFront"](vm, []any{int64(1)})
+	vm.builtins["SendWinToBack"](vm, []any{int64(2)})
+	vm.builtins["BringCastToFront"](vm, []any{int64(3)})
+	vm.builtins["SendCastToBack"](vm, []any{int64(4)})
+
+	want := []string{"win 1 front", "win 2 back", "cast 3 front", "cast 4 back"}
+	if len(gs.restacks) != len(want) {
+		t.Fatalf("restacks = %v, want %v", gs.restacks, want)
+	}
+	for i := range want {
+		if gs.restacks[i] != want[i] {
+			t.Errorf("restacks[%d] = %q, want %q", i, gs.restacks[i], want[i])
+		}
+	}
+
+	// 存在しないIDや引数の不足はエラーを記録するだけでスクリプトは継続する
+	if _, err := vm.builtins["BringCastToFront"](vm, []any{int64(-1)}); err != nil {
+		t.Errorf("BringCastToFront with unknown ID should not return error: %v", err)
+	}
+	if _, err := vm.builtins["SendWinToBack"](vm, []any{}); err != nil {
+		t.Errorf("SendWinToBack without arguments should not return error: %v", err)
+	}
+	if len(gs.restacks) != len(want) {
+		t.Errorf("invalid calls should not change the render order, got %v", gs.restacks)
+	}
+}
+
+// TestVMBuiltinPalette tests the SetPalette, GetPalette, CyclePalette and ResetPalette built-in functions.
+func TestVMBuiltinPalette(t *testing.T) {
+	vm := New([]opcode.OpCode{})
+	gs := newMockGraphicsSystem()
+	vm.SetGraphicsSystem(gs)
+
+	vm.builtins["SetPalette"](vm, []any{int64(250), int64(0xFF8000)})
+	if got, _ := vm.builtins["GetPalette"](vm, []any{int64(250)}); got != 0xFF8000 {
+		t.Errorf("GetPalette(250) = %v, want 0xFF8000", got)
+	}
+	if got, _ := vm.builtins["GetPalette"](vm, []any{int64(256)}); got != -1 {
+		t.Errorf("GetPalette(256) = %v, want -1", got)
+	}
+
+	// 範囲外の番号はエラーを記録するだけでスクリプトは継続する
+	if _, err := vm.builtins["SetPalette"](vm, []any{int64(-1), int64(0)}); err != nil {
+		t.Errorf("SetPalette with invalid index should not return error: %v", err)
+	}
+
+	vm.builtins["CyclePalette"](vm, []any{int64(10), int64(16)})
+	vm.builtins["CyclePalette"](vm, []any{int64(10), int64(16), int64(-2)})
+	want := [][3]int{{10, 16, 1}, {10, 16, -2}}
+	if len(gs.paletteCycles) != len(want) {
+		t.Fatalf("expected %d CyclePalette calls, got %d", len(want), len(gs.paletteCycles))
+	}
+	for i := range want {
+		if gs.paletteCycles[i] != want[i] {
+			t.Errorf("CyclePalette call %d = %v, want %v", i, gs.paletteCycles[i], want[i])
+		}
+	}
+
+	vm.builtins["ResetPalette"](vm, []any{})
+	if gs.paletteResets != 1 {
+		t.Errorf("ResetPalette calls = %d, want 1", gs.paletteResets)
+	}
+}
+
+// TestVMBuiltinDisplayAdjustment tests the SetGamma, SetBrightness and SetContrast built-in functions.
+func TestVMBuiltinDisplayAdjustment(t *testing.T) {
+	vm := New([]opcode.OpCode{})
+	gs := newMockGraphicsSystem()
+	vm.SetGraphicsSystem(gs)
+
+	vm.builtins["SetGamma"](vm, []any{float64(2.2)})
+	vm.builtins["SetBrightness"](vm, []any{float64(-0.5)})
+	vm.builtins["SetContrast"](vm, []any{int64(2)})
+
+	want := map[string]float64{"gamma": 2.2, "brightness": -0.5, "contrast": 2}
+	for name, v := range want {
+		if gs.display[name] != v {
+			t.Errorf("%s = %v, want %v", name, gs.display[name], v)
+		}
+	}
+
+	// 範囲外の値や数値以外はエラーを記録するだけでスクリプトは継続し、値は変わらない
+	if _, err := vm.builtins["SetBrightness"](vm, []any{float64(3)}); err != nil {
+		t.Errorf("SetBrightness with invalid value should not return error: %v", err)
+	}
+	if _, err := vm.builtins["SetGamma"](vm, []any{"bright"}); err != nil {
+		t.Errorf("SetGamma with invalid value should not return error: %v", err)
+	}
+	if gs.display["brightness"] != -0.5 || gs.display["gamma"] != 2.2 {
+		t.Errorf("invalid values should not change the adjustment: %v", gs.display)
+	}
+}
+
 // TestVMBuiltinMsgBox tests the MsgBox built-in function.
 func TestVMBuiltinMsgBox(t *testing.T) {
 	t.Run("MsgBox is registered as built-in", func(t *testing.T) {
@@ -2626,67 +3471,6 @@ func TestVMBuiltinGetSysTime(t *testing.T) {
 			t.Errorf("expected time between %d and %d, got %d", before, after, timeValue)
 		}
 	})
-
-	t.Run("GetSysTime returns increasing values", func(t *testing.T) {
-		vm := New([]opcode.OpCode{})
-		fn := vm.builtins["GetSysTime"]
-
-		// Get first time
-		result1, err1 := fn(vm, []any{})
-		if err1 != nil {
-			t.Errorf("expected no error, got %v", err1)
-		}
-		time1, _ := result1.(int64)
-
-		// Wait for at least 1 second
-		time.Sleep(1100 * time.Millisecond)
-
-		// Get second time
-		result2, err2 := fn(vm, []any{})
-		if err2 != nil {
-			t.Errorf("expected no error, got %v", err2)
-		}
-		time2, _ := result2.(int64)
-
-		// Second time should be at least 1 second greater than first
-		if time2 <= time1 {
-			t.Errorf("expected time2 (%d) > time1 (%d)", time2, time1)
-		}
-
-		// Difference should be approximately 1 second
-		diff := time2 - time1
-		if diff < 1 || diff > 2 {
-			t.Errorf("expected difference of 1-2 seconds, got %d", diff)
-		}
-	})
-
-	t.Run("GetSysTime can be used for timing", func(t *testing.T) {
-		vm := New([]opcode.OpCode{})
-		fn := vm.builtins["GetSysTime"]
-
-		// Start time
-		start, _ := fn(vm, []any{})
-		startTime := start.(int64)
-
-		// Do some work (sleep for known duration)
-		sleepDuration := 2 * time.Second
-		time.Sleep(sleepDuration)
-
-		// End time
-		end, _ := fn(vm, []any{})
-		endTime := end.(int64)
-
-		// Calculate elapsed time
-		elapsed := endTime - startTime
-
-		// Elapsed should be approximately the sleep duration (in seconds)
-		expectedMin := int64(1) // At least 1 second
-		expectedMax := int64(3) // At most 3 seconds (with tolerance)
-
-		if elapsed < expectedMin || elapsed > expectedMax {
-			t.Errorf("expected elapsed time between %d and %d seconds, got %d seconds", expectedMin, expectedMax, elapsed)
-		}
-	})
 }
 
 // TestContainsOpSetStep tests the containsOpSetStep function.
@@ -3115,3 +3899,136 @@ func TestExecuteRegisterEventHandlerHasStepBlock(t *testing.T) {
 		}
 	})
 }
+
+func TestVMBuiltinLoadPicAsync(t *testing.T) {
+	tests := []struct {
+		filename string
+		wantOK   int
+	}{
+		{"big.bmp", 1},
+		{"broken.bmp", 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.filename, func(t *testing.T) {
+			vm := New([]opcode.OpCode{})
+			vm.SetGraphicsSystem(newMockGraphicsSystem())
+
+			result, err := vm.builtins["LoadPicAsync"](vm, []any{tt.filename})
+			if err != nil {
+				t.Fatalf("LoadPicAsync returned error: %v", err)
+			}
+			event, ok := vm.eventQueue.Pop()
+			if !ok {
+				t.Fatal("expected PIC_READY event in the queue")
+			}
+			if event.Type != EventPIC_READY {
+				t.Errorf("event type = %s, want PIC_READY", event.Type)
+			}
+			if event.Params["MesP1"] != result || event.Params["MesP2"] != tt.wantOK {
+				t.Errorf("params = %v, want MesP1=%v MesP2=%d", event.Params, result, tt.wantOK)
+			}
+		})
+	}
+
+	t.Run("missing file", func(t *testing.T) {
+		vm := New([]opcode.OpCode{})
+		vm.SetGraphicsSystem(newMockGraphicsSystem())
+		result, err := vm.builtins["LoadPicAsync"](vm, []any{"missing.bmp"})
+		if err == nil || result != -1 {
+			t.Errorf("result = %v, %v; want -1 and an error", result, err)
+		}
+		if vm.eventQueue.Len() != 0 {
+			t.Error("no PIC_READY event should be sent for a missing file")
+		}
+	})
+}
+
+func TestVMTickLimit(t *testing.T) {
+	tests := []struct {
+		name     string
+		handlers []EventType
+		events   []EventType
+		limit    int64
+		wantLeft int
+	}{
+		{
+			name:     "TIME ticks",
+			handlers: []EventType{EventTIME},
+			events:   []EventType{EventTIME, EventKEY, EventTIME, EventTIME, EventTIME},
+			limit:    3,
+			wantLeft: 1,
+		},
+		{
+			name:     "MIDI_TIME is ignored while TIME drives the title",
+			handlers: []EventType{EventTIME, EventMIDI_TIME},
+			events:   []EventType{EventMIDI_TIME, EventTIME, EventMIDI_TIME, EventTIME, EventMIDI_TIME},
+			limit:    2,
+			wantLeft: 1,
+		},
+		{
+			name:     "MIDI_TIME ticks without a TIME handler",
+			handlers: []EventType{EventMIDI_TIME},
+			events:   []EventType{EventMIDI_TIME, EventMIDI_TIME, EventMIDI_TIME},
+			limit:    2,
+			wantLeft: 1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			vm := New([]opcode.OpCode{}, WithTickLimit(tt.limit), WithTimeout(2*time.Second))
+			for _, eventType := range tt.handlers {
+				vm.handlerRegistry.Register(NewEventHandler("", eventType, []opcode.OpCode{}, vm, vm.GetCurrentScope()))
+			}
+			for _, eventType := range tt.events {
+				vm.eventQueue.Push(NewEvent(eventType))
+			}
+
+			if err := vm.Run(); err != nil {
+				t.Fatalf("Run returned error: %v", err)
+			}
+			if left := vm.eventQueue.Len(); left != tt.wantLeft {
+				t.Errorf("%d events left in the queue, want %d", left, tt.wantLeft)
+			}
+			if vm.TimedOut() {
+				t.Error("a run stopped by the tick limit should not be reported as timed out")
+			}
+		})
+	}
+}
+
+// TestWithPowerSave tests that power-save mode only lengthens the idle sleep of the
+// event loop, and that queued events are still handled.
+func TestWithPowerSave(t *testing.T) {
+	if got := New([]opcode.OpCode{}).idleSleep(); got != eventLoopIdleSleep {
+		t.Errorf("default idle sleep = %v, want %v", got, eventLoopIdleSleep)
+	}
+	vm := New([]opcode.OpCode{}, WithPowerSave(true), WithTickLimit(2), WithTimeout(2*time.Second))
+	if got := vm.idleSleep(); got != powerSaveIdleSleep {
+		t.Errorf("power-save idle sleep = %v, want %v", got, powerSaveIdleSleep)
+	}
+
+	vm.handlerRegistry.Register(NewEventHandler("", EventTIME, []opcode.OpCode{}, vm, vm.GetCurrentScope()))
+	go func() {
+		for range 2 {
+			time.Sleep(2 * powerSaveIdleSleep)
+			vm.eventQueue.Push(NewEvent(EventTIME))
+		}
+	}()
+	if err := vm.Run(); err != nil {
+		t.Fatalf("Run returned error: %v", err)
+	}
+	if vm.TimedOut() {
+		t.Error("events pushed while idle should be handled in power-save mode")
+	}
+}
+
+func TestVMTimedOut(t *testing.T) {
+	vm := New([]opcode.OpCode{}, WithTimeout(20*time.Millisecond))
+	vm.handlerRegistry.Register(NewEventHandler("", EventKEY, []opcode.OpCode{}, vm, vm.GetCurrentScope()))
+	if err := vm.Run(); err != nil {
+		t.Fatalf("Run returned error: %v", err)
+	}
+	if !vm.TimedOut() {
+		t.Error("TimedOut should report the expired timeout")
+	}
+}
@@ -0,0 +1,169 @@
+// Package vmtest provides test doubles for engine tests.
+//
+// Clock is a simulated vm.Clock: time only moves when the test calls Advance,
+// and every tick that falls in the advanced span is delivered in time order.
+// Tests of Wait, timeouts, SetTimer and TIME events run instantly and always see
+// the same number of ticks, instead of sleeping and allowing for scheduler jitter.
+package vmtest
+
+import (
+	"sync"
+	"time"
+
+	"github.com/zurustar/son-et/pkg/vm"
+)
+
+// Clock は Advance でのみ進む模擬の時計
+// vm.WithClock・audio.WithTimerClock に渡して使う
+type Clock struct {
+	mu      sync.Mutex
+	changed *sync.Cond // ティッカーの作成・停止を BlockUntil に知らせる
+	now     time.Time
+	tickers []*ticker
+}
+
+// 模擬の時計が vm.Clock として使えること
+var _ vm.Clock = (*Clock)(nil)
+
+// NewClock は start の時刻で止まっている時計を作る
+func NewClock(start time.Time) *Clock {
+	c := &Clock{now: start}
+	c.changed = sync.NewCond(&c.mu)
+	return c
+}
+
+// Now は時計の現在の時刻を返す
+func (c *Clock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+// NewTicker は d ごとにティックを送るティッカーを作る（d が0以下の場合は panic する）
+func (c *Clock) NewTicker(d time.Duration) vm.Ticker {
+	if d <= 0 {
+		panic("vmtest: non-positive interval for NewTicker")
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	tk := &ticker{clock: c, c: make(chan time.Time)}
+	tk.start(c.now, d)
+	c.tickers = append(c.tickers, tk)
+	c.changed.Broadcast()
+	return tk
+}
+
+// Advance は時計を d だけ進め、その間に来るティックを時刻の順に送る
+// ティックは受け取られるまで待って送る（実時間の Ticker と違い、取りこぼさない）。
+// そのため、ティッカーを停止せずに受け取りをやめた goroutine があると Advance は戻らない。
+// Advance が戻った時点で最後のティックは受け取られているが、その処理は終わっていないことがある。
+// 処理の結果（キューに入ったイベントなど）を待ってから、次のティックまで進めること
+func (c *Clock) Advance(d time.Duration) {
+	c.mu.Lock()
+	target := c.now.Add(d)
+	for {
+		tk := c.nextTicker(target)
+		if tk == nil {
+			c.now = target
+			c.mu.Unlock()
+			return
+		}
+		c.now = tk.next
+		tk.next = tk.next.Add(tk.period)
+		at, ch, stop := c.now, tk.c, tk.stop
+
+		// 受け取った側が Now・Reset を呼べるよう、ロックを解放して送る
+		c.mu.Unlock()
+		select {
+		case ch <- at:
+		case <-stop:
+		}
+		c.mu.Lock()
+	}
+}
+
+// BlockUntil は動いているティッカーが n 個以上になるまで待つ
+// 別の goroutine がティッカーを作るのを待ってから Advance するのに使う
+func (c *Clock) BlockUntil(n int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for c.activeTickers() < n {
+		c.changed.Wait()
+	}
+}
+
+// nextTicker は target までに次のティックが来る動いているティッカーのうち、最も早いものを返す
+// 呼び出し元は c.mu のロックを保持していること
+func (c *Clock) nextTicker(target time.Time) *ticker {
+	var next *ticker
+	for _, tk := range c.tickers {
+		if !tk.active || tk.next.After(target) {
+			continue
+		}
+		if next == nil || tk.next.Before(next.next) {
+			next = tk
+		}
+	}
+	return next
+}
+
+// activeTickers は動いているティッカーの数を返す
+// 呼び出し元は c.mu のロックを保持していること
+func (c *Clock) activeTickers() int {
+	n := 0
+	for _, tk := range c.tickers {
+		if tk.active {
+			n++
+		}
+	}
+	return n
+}
+
+// ticker は模擬の時計のティッカー
+type ticker struct {
+	clock  *Clock
+	c      chan time.Time
+	period time.Duration
+	next   time.Time
+	active bool
+	stop   chan struct{} // Stop で閉じ、送信中の Advance を止める
+}
+
+// start はティッカーを now から period ごとに動かす
+// 呼び出し元は clock.mu のロックを保持していること
+func (tk *ticker) start(now time.Time, period time.Duration) {
+	tk.period = period
+	tk.next = now.Add(period)
+	if !tk.active {
+		tk.active = true
+		tk.stop = make(chan struct{})
+	}
+}
+
+func (tk *ticker) C() <-chan time.Time {
+	return tk.c
+}
+
+// Reset はティッカーを今から d ごとに動かし直す（停止したティッカーも動き出す）
+func (tk *ticker) Reset(d time.Duration) {
+	if d <= 0 {
+		panic("vmtest: non-positive interval for Ticker.Reset")
+	}
+	c := tk.clock
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	tk.start(c.now, d)
+	c.changed.Broadcast()
+}
+
+// Stop はティッカーを止める（送信中のティックは送らない）
+func (tk *ticker) Stop() {
+	c := tk.clock
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if tk.active {
+		tk.active = false
+		close(tk.stop)
+	}
+	c.changed.Broadcast()
+}
@@ -0,0 +1,310 @@
+package vmtest
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/zurustar/son-et/pkg/compiler"
+	"github.com/zurustar/son-et/pkg/vm"
+)
+
+var epoch = time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// collectTicks は ticker のティックを受け取り、受け取った時刻を返すチャネルに送る
+func collectTicks(tk vm.Ticker) <-chan time.Time {
+	ticks := make(chan time.Time, 100)
+	go func() {
+		for at := range tk.C() {
+			ticks <- at
+		}
+	}()
+	return ticks
+}
+
+// receiveTicks は受け取ったティックを n 個取り出す
+func receiveTicks(t *testing.T, ticks <-chan time.Time, n int) []time.Duration {
+	t.Helper()
+	var got []time.Duration
+	for range n {
+		select {
+		case at := <-ticks:
+			got = append(got, at.Sub(epoch))
+		case <-time.After(time.Second):
+			t.Fatalf("received %d ticks, want %d", len(got), n)
+		}
+	}
+	return got
+}
+
+func TestClockAdvance(t *testing.T) {
+	clock := NewClock(epoch)
+	tk := clock.NewTicker(100 * time.Millisecond)
+	ticks := collectTicks(tk)
+
+	clock.Advance(350 * time.Millisecond)
+	if got := clock.Now().Sub(epoch); got != 350*time.Millisecond {
+		t.Errorf("Now = %v after Advance, want 350ms", got)
+	}
+	got := receiveTicks(t, ticks, 3)
+	want := []time.Duration{100 * time.Millisecond, 200 * time.Millisecond, 300 * time.Millisecond}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("tick %d at %v, want %v", i, got[i], want[i])
+		}
+	}
+
+	// 止めたティッカーはティックを送らず、Advance は待たずに戻る
+	tk.Stop()
+	clock.Advance(time.Second)
+	if len(ticks) != 0 {
+		t.Errorf("stopped ticker delivered %d ticks", len(ticks))
+	}
+}
+
+// TestClockTicksInTimeOrder tests that the ticks of several tickers are delivered in time order.
+func TestClockTicksInTimeOrder(t *testing.T) {
+	clock := NewClock(epoch)
+	a := clock.NewTicker(30 * time.Millisecond)
+	b := clock.NewTicker(50 * time.Millisecond)
+
+	ticks := make(chan time.Time, 100)
+	go func() {
+		for {
+			select {
+			case at := <-a.C():
+				ticks <- at
+			case at := <-b.C():
+				ticks <- at
+			}
+		}
+	}()
+
+	clock.Advance(150 * time.Millisecond)
+	got := receiveTicks(t, ticks, 8)
+	ms := time.Millisecond
+	want := []time.Duration{30 * ms, 50 * ms, 60 * ms, 90 * ms, 100 * ms, 120 * ms, 150 * ms, 150 * ms}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("ticks = %v, want %v", got, want)
+			break
+		}
+	}
+}
+
+func TestClockReset(t *testing.T) {
+	clock := NewClock(epoch)
+	tk := clock.NewTicker(100 * time.Millisecond)
+	ticks := collectTicks(tk)
+
+	// Reset は今の時刻から数え直す
+	clock.Advance(50 * time.Millisecond)
+	tk.Reset(20 * time.Millisecond)
+	clock.Advance(45 * time.Millisecond)
+	got := receiveTicks(t, ticks, 2)
+	if got[0] != 70*time.Millisecond || got[1] != 90*time.Millisecond {
+		t.Errorf("ticks after Reset = %v, want [70ms 90ms]", got)
+	}
+
+	// 止めたティッカーも Reset で動き出す
+	tk.Stop()
+	clock.Advance(time.Second)
+	tk.Reset(10 * time.Millisecond)
+	clock.Advance(10 * time.Millisecond)
+	if got := receiveTicks(t, ticks, 1); got[0] != 1105*time.Millisecond {
+		t.Errorf("tick after restarting = %v, want 1.105s", got[0])
+	}
+}
+
+// TestClockStopUnblocksAdvance tests that stopping a ticker nobody reads releases Advance.
+func TestClockStopUnblocksAdvance(t *testing.T) {
+	clock := NewClock(epoch)
+	tk := clock.NewTicker(10 * time.Millisecond)
+
+	done := make(chan struct{})
+	go func() {
+		clock.Advance(time.Second)
+		close(done)
+	}()
+	tk.Stop()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("Advance did not return after the ticker was stopped")
+	}
+	if got := clock.Now().Sub(epoch); got != time.Second {
+		t.Errorf("Now = %v, want 1s", got)
+	}
+}
+
+func TestClockBlockUntil(t *testing.T) {
+	clock := NewClock(epoch)
+	go clock.NewTicker(time.Second)
+
+	done := make(chan struct{})
+	go func() {
+		clock.BlockUntil(1)
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("BlockUntil did not return after a ticker was created")
+	}
+}
+
+// newClockVM は script をコンパイルし、模擬の時計で動く VM を作る
+func newClockVM(t *testing.T, clock *Clock, script string, opts ...vm.Option) *vm.VM {
+	t.Helper()
+	ops, errs := compiler.Compile(script)
+	if len(errs) > 0 {
+		t.Fatalf("compile failed: %v", errs)
+	}
+	return vm.New(ops, append([]vm.Option{vm.WithClock(clock)}, opts...)...)
+}
+
+// runUntilStopped は VM を実行し、停止するまで時計を step ずつ進める
+func runUntilStopped(t *testing.T, clock *Clock, v *vm.VM, step time.Duration) {
+	t.Helper()
+	done := make(chan error, 1)
+	go func() {
+		done <- v.Run()
+	}()
+	deadline := time.After(5 * time.Second)
+	for {
+		select {
+		case err := <-done:
+			if err != nil {
+				t.Fatalf("Run returned error: %v", err)
+			}
+			return
+		case <-deadline:
+			v.Stop()
+			t.Fatalf("VM did not stop by %v on the simulated clock", clock.Now().Sub(epoch))
+		default:
+		}
+		clock.Advance(step)
+		time.Sleep(time.Millisecond)
+	}
+}
+
+// TestVMTimeout tests that the timeout is measured on the VM's clock.
+func TestVMTimeout(t *testing.T) {
+	clock := NewClock(epoch)
+	v := newClockVM(t, clock, `
+main() {
+	mes(KEY) {
+	}
+}
+`, vm.WithTimeout(time.Hour))
+
+	done := make(chan error, 1)
+	go func() {
+		done <- v.Run()
+	}()
+	clock.BlockUntil(1)
+
+	clock.Advance(time.Hour - time.Millisecond)
+	if v.TimedOut() {
+		t.Fatal("VM timed out before the timeout")
+	}
+	clock.Advance(time.Millisecond)
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatalf("Run returned error: %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		v.Stop()
+		t.Fatal("VM did not stop at the timeout")
+	}
+	if !v.TimedOut() {
+		t.Error("TimedOut should be true")
+	}
+}
+
+// TestVMExitBudget tests that the exit sequence after a timeout runs for its budget on the VM's clock.
+func TestVMExitBudget(t *testing.T) {
+	clock := NewClock(epoch)
+	v := newClockVM(t, clock, `
+int exited;
+main() {
+	OnExit("Bye", 2000);
+	mes(KEY) {
+	}
+}
+Bye() {
+	exited = 1;
+}
+`, vm.WithTimeout(time.Minute))
+
+	runUntilStopped(t, clock, v, time.Second)
+	if !v.TimedOut() {
+		t.Error("TimedOut should be true")
+	}
+	if exited, _ := v.GetGlobalScope().GetLocal("exited"); exited != int64(1) {
+		t.Errorf("exited = %v, want 1 (the exit function should run)", exited)
+	}
+	if elapsed := clock.Now().Sub(epoch); elapsed < time.Minute+2*time.Second {
+		t.Errorf("VM stopped at %v, expected the timeout and the exit budget to pass", elapsed)
+	}
+}
+
+// TestVMSetTimer tests that SetTimer timers follow the VM's clock.
+func TestVMSetTimer(t *testing.T) {
+	clock := NewClock(epoch)
+	v := newClockVM(t, clock, `
+main() {
+	SetTimer(3000, "Done", 0);
+	mes(KEY) {
+	}
+}
+Done(id) {
+	ExitTitle();
+}
+`)
+
+	runUntilStopped(t, clock, v, 100*time.Millisecond)
+	if elapsed := clock.Now().Sub(epoch); elapsed < 3*time.Second {
+		t.Errorf("timer fired at %v, want 3s or later", elapsed)
+	}
+}
+
+// TestVMGetSysTime tests that GetSysTime reads the VM's clock.
+func TestVMGetSysTime(t *testing.T) {
+	tests := []struct {
+		delay int
+		want  int64
+	}{
+		{1100, 1},
+		{2000, 2},
+	}
+	for _, tt := range tests {
+		t.Run(fmt.Sprintf("%dms", tt.delay), func(t *testing.T) {
+			clock := NewClock(epoch)
+			v := newClockVM(t, clock, fmt.Sprintf(`
+int start, end;
+main() {
+	start = GetSysTime();
+	SetTimer(%d, "Done", 0);
+	mes(KEY) {
+	}
+}
+Done(id) {
+	end = GetSysTime();
+	ExitTitle();
+}
+`, tt.delay))
+
+			runUntilStopped(t, clock, v, 100*time.Millisecond)
+			start, _ := v.GetGlobalScope().GetLocal("start")
+			end, _ := v.GetGlobalScope().GetLocal("end")
+			if start != epoch.Unix() {
+				t.Errorf("start = %v, want %d", start, epoch.Unix())
+			}
+			if end != epoch.Unix()+tt.want {
+				t.Errorf("end = %v, want %d (%d seconds after start)", end, epoch.Unix()+tt.want, tt.want)
+			}
+		})
+	}
+}
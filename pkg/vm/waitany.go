@@ -0,0 +1,169 @@
+package vm
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/zurustar/son-et/pkg/opcode"
+)
+
+// WaitAny: wait for whichever of several conditions comes first
+//
+// WaitAny(ticks, "KEY", "USER", "MIDI_END") pauses the sequence and resumes it when
+// ticks events have passed (counted like Wait: TIME events in mes(TIME)) or when any
+// of the named events occurs. GetWaitResult() returns the condition that ended the wait
+// (0 for the ticks, 1 or more for the event in argument order).
+// r = WaitAny(...) compiles to OpWaitAny followed by r = GetWaitResult().
+// It replaces busy loops that count ticks while polling variables set by key and message handlers.
+
+// waitAnyEvents are the events WaitAny can wait for (TIME and MIDI_TIME are used as ticks instead).
+var waitAnyEvents = map[EventType]bool{
+	EventUSER:     true, // messages sent with PostMes
+	EventKEY:      true,
+	EventMIDI_END: true,
+}
+
+// waitAnyState holds what a handler paused in WaitAny is waiting for.
+type waitAnyState struct {
+	ticks  int         // ticks left (0 = do not resume on ticks)
+	events []EventType // events that resume the handler, in WaitAny's argument order
+}
+
+// wants reports whether an event of eventType resumes the handler.
+func (w *waitAnyState) wants(eventType EventType) bool {
+	return slices.Contains(w.events, eventType)
+}
+
+// match reports whether event ends the wait and, if so, the result (0 = the ticks,
+// 1 or more = the position in events). Events of tickType (the handler's own event type) count as ticks.
+func (w *waitAnyState) match(event *Event, tickType EventType) (int, bool) {
+	if i := slices.Index(w.events, event.Type); i >= 0 {
+		return i + 1, true
+	}
+	if event.Type == tickType && w.ticks > 0 {
+		w.ticks--
+		if w.ticks == 0 {
+			return 0, true
+		}
+	}
+	return 0, false
+}
+
+// endWaitAny passes event to a handler paused in WaitAny. When the wait ends, it records
+// the result and returns true.
+func (eh *EventHandler) endWaitAny(event *Event) bool {
+	result, done := eh.waitAny.match(event, eh.EventType)
+	if !done {
+		return false
+	}
+	eh.waitAny = nil
+	eh.WaitResult = result
+	eh.VM.log.Debug("Handler resuming after WaitAny", "handler", eh.ID, "event", event.Type, "result", result)
+	return true
+}
+
+// waitingFor returns the handlers of other event types that wait for eventType in WaitAny,
+// in registration order.
+func (hr *HandlerRegistry) waitingFor(eventType EventType) []*EventHandler {
+	if !waitAnyEvents[eventType] {
+		return nil
+	}
+	hr.mu.RLock()
+	defer hr.mu.RUnlock()
+
+	var result []*EventHandler
+	for t, handlers := range hr.handlers {
+		if t == eventType {
+			continue
+		}
+		for _, h := range handlers {
+			if h.Active && h.waitAny != nil && h.waitAny.wants(eventType) {
+				result = append(result, h)
+			}
+		}
+	}
+	slices.SortFunc(result, func(a, b *EventHandler) int { return a.Number - b.Number })
+	return result
+}
+
+// executeWaitAny executes an OpWaitAny OpCode.
+// Args: [ticks, eventName...]
+//
+// In compat.FILLY97 mode WaitAny is an extension builtin, so it is executed as
+// an ordinary call and reported as an undefined function.
+func (vm *VM) executeWaitAny(op opcode.OpCode) (any, error) {
+	if vm.compat.Strict() {
+		return vm.executeCall(opcode.OpCode{Cmd: opcode.Call, Args: append([]any{"WaitAny"}, op.Args...)})
+	}
+
+	args := make([]any, len(op.Args))
+	for i, arg := range op.Args {
+		val, err := vm.evaluateValue(arg)
+		if err != nil {
+			return nil, fmt.Errorf("failed to evaluate WaitAny argument %d: %w", i+1, err)
+		}
+		args[i] = val
+	}
+	return vm.waitAny(args)
+}
+
+// waitAny starts waiting in the current handler for ticks events of its own type or
+// any of the named events, and returns a wait marker so that the handler pauses.
+// The result is reset to 0 first, so GetWaitResult() returns 0 when there is nothing to wait for.
+func (vm *VM) waitAny(args []any) (any, error) {
+	handler := vm.currentHandler
+	if handler != nil {
+		handler.WaitResult = 0
+	}
+	if len(args) < 1 {
+		vm.log.Error("WaitAny requires at least 1 argument (ticks)")
+		return nil, nil
+	}
+	ticks, ok := toInt64(args[0])
+	if !ok {
+		vm.log.Error("WaitAny ticks must be an integer", "ticks", args[0])
+		return nil, nil
+	}
+	var events []EventType
+	for _, arg := range args[1:] {
+		eventType := EventType(strings.ToUpper(toString(arg)))
+		if !waitAnyEvents[eventType] {
+			vm.log.Error("WaitAny: unknown event (expected KEY, USER or MIDI_END)", "event", arg)
+			continue
+		}
+		events = append(events, eventType)
+	}
+
+	if handler == nil {
+		// Outside an event handler, such as in main(), there is nothing to pause (same as Wait)
+		vm.log.Warn("WaitAny called outside of event handler, ignoring", "ticks", ticks)
+		return nil, nil
+	}
+	if ticks <= 0 && len(events) == 0 {
+		return nil, nil
+	}
+	handler.waitAny = &waitAnyState{ticks: int(max(ticks, 0)), events: events}
+	return &waitMarker{WaitCount: int(max(ticks, 0))}, nil
+}
+
+// registerWaitAnyBuiltins registers the built-in functions for WaitAny.
+func (vm *VM) registerWaitAnyBuiltins() {
+	// WaitAny: Wait until ticks events pass or one of the named events occurs
+	// WaitAny(ticks, "KEY", "USER", "MIDI_END") - ticks = 0 waits for the events only.
+	// The compiler turns WaitAny statements and assignments into OpWaitAny; inside a larger
+	// expression the sequence cannot pause, so the call is refused.
+	vm.RegisterBuiltinFunction("WaitAny", func(v *VM, args []any) (any, error) {
+		v.log.Error("WaitAny must be used as a statement or assigned to a variable (r = WaitAny(...))")
+		return int64(0), nil
+	})
+
+	// GetWaitResult: Get the condition that ended the last WaitAny of the current mes() block
+	// GetWaitResult() - 0 = the ticks passed, 1 or more = the event in WaitAny's argument order
+	vm.RegisterBuiltinFunction("GetWaitResult", func(v *VM, args []any) (any, error) {
+		if v.currentHandler == nil {
+			return int64(0), nil
+		}
+		return int64(v.currentHandler.WaitResult), nil
+	})
+}
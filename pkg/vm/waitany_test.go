@@ -0,0 +1,170 @@
+package vm
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/zurustar/son-et/pkg/opcode"
+)
+
+// newWaitAnyTestHandler registers a handler of eventType that runs WaitAny(args...)
+// and then records GetWaitResult() and MesP1; it returns the handler and the records.
+func newWaitAnyTestHandler(t *testing.T, vm *VM, eventType EventType, args ...any) (*EventHandler, *[][2]int64) {
+	t.Helper()
+	records := &[][2]int64{}
+	vm.RegisterBuiltinFunction("record", func(v *VM, args []any) (any, error) {
+		result, _ := toInt64(args[0])
+		p1, _ := toInt64(args[1])
+		*records = append(*records, [2]int64{result, p1})
+		return nil, nil
+	})
+	handler := NewEventHandler("", eventType, []opcode.OpCode{
+		{Cmd: opcode.WaitAny, Args: args},
+		{Cmd: opcode.Call, Args: []any{"record", opcode.OpCode{Cmd: opcode.Call, Args: []any{"GetWaitResult"}}, opcode.Variable("MesP1")}},
+	}, vm, nil)
+	vm.handlerRegistry.Register(handler)
+	return handler, records
+}
+
+// dispatchEvents dispatches the events in order.
+func dispatchEvents(t *testing.T, vm *VM, events ...*Event) {
+	t.Helper()
+	for _, event := range events {
+		if err := vm.eventDispatcher.Dispatch(event); err != nil {
+			t.Fatalf("Dispatch(%s) failed: %v", event.Type, err)
+		}
+	}
+}
+
+func TestVMBuiltinWaitAnyRegistered(t *testing.T) {
+	vm := New([]opcode.OpCode{})
+	for _, name := range []string{"WaitAny", "GetWaitResult"} {
+		if _, ok := vm.builtins[name]; !ok {
+			t.Errorf("expected %s to be registered as built-in function", name)
+		}
+	}
+}
+
+// TestWaitAnyTicks tests that the handler resumes after the ticks with result 0.
+func TestWaitAnyTicks(t *testing.T) {
+	vm := New([]opcode.OpCode{})
+	handler, records := newWaitAnyTestHandler(t, vm, EventTIME, int64(3), "KEY")
+
+	dispatchEvents(t, vm, NewEvent(EventTIME)) // WaitAny starts waiting
+	dispatchEvents(t, vm, NewEvent(EventTIME), NewEvent(EventTIME))
+	if len(*records) != 0 {
+		t.Fatalf("handler resumed after 2 ticks: %v", *records)
+	}
+	dispatchEvents(t, vm, NewEvent(EventTIME))
+	if want := [][2]int64{{0, 0}}; !reflect.DeepEqual(*records, want) {
+		t.Errorf("records = %v, want %v", *records, want)
+	}
+	if handler.waitAny != nil {
+		t.Error("waitAny should be cleared after the wait ends")
+	}
+}
+
+// TestWaitAnyEvent tests that an awaited event of another type resumes the handler
+// with the event's position and parameters.
+func TestWaitAnyEvent(t *testing.T) {
+	vm := New([]opcode.OpCode{})
+	_, records := newWaitAnyTestHandler(t, vm, EventTIME, int64(100), "midi_end", "KEY")
+
+	dispatchEvents(t, vm, NewEvent(EventTIME), NewEvent(EventTIME))
+	dispatchEvents(t, vm, NewEventWithParams(EventCLICK, map[string]any{"MesP1": 9})) // not awaited
+	dispatchEvents(t, vm, NewEventWithParams(EventKEY, map[string]any{"MesP1": 27}))
+	if want := [][2]int64{{2, 27}}; !reflect.DeepEqual(*records, want) {
+		t.Errorf("records = %v, want %v", *records, want)
+	}
+
+	// The handler waits again from the start on the next tick
+	dispatchEvents(t, vm, NewEvent(EventTIME), NewEvent(EventMIDI_END))
+	if len(*records) != 2 || (*records)[1][0] != 1 {
+		t.Errorf("records = %v, want MIDI_END (1) second", *records)
+	}
+}
+
+// TestWaitAnyEventsOnly tests that ticks = 0 waits for the events only.
+func TestWaitAnyEventsOnly(t *testing.T) {
+	vm := New([]opcode.OpCode{})
+	_, records := newWaitAnyTestHandler(t, vm, EventTIME, int64(0), "USER")
+
+	for i := 0; i < 50; i++ {
+		dispatchEvents(t, vm, NewEvent(EventTIME))
+	}
+	if len(*records) != 0 {
+		t.Fatalf("handler resumed without the event: %v", *records)
+	}
+	dispatchEvents(t, vm, NewEventWithParams(EventUSER, map[string]any{"MesP1": 5}))
+	if want := [][2]int64{{1, 5}}; !reflect.DeepEqual(*records, want) {
+		t.Errorf("records = %v, want %v", *records, want)
+	}
+}
+
+// TestWaitAnyNothingToWait tests that WaitAny(0) and unknown events do not pause.
+func TestWaitAnyNothingToWait(t *testing.T) {
+	vm := New([]opcode.OpCode{})
+	handler, records := newWaitAnyTestHandler(t, vm, EventTIME, int64(0), "TIME", "NOSUCH")
+
+	dispatchEvents(t, vm, NewEvent(EventTIME))
+	if want := [][2]int64{{0, 0}}; !reflect.DeepEqual(*records, want) {
+		t.Errorf("records = %v, want %v", *records, want)
+	}
+	if handler.waitAny != nil {
+		t.Error("waitAny should not be set")
+	}
+}
+
+// TestWaitAnyOutsideHandler tests that WaitAny outside a mes() block is ignored.
+func TestWaitAnyOutsideHandler(t *testing.T) {
+	vm := New([]opcode.OpCode{})
+	result, err := vm.Execute(opcode.OpCode{Cmd: opcode.WaitAny, Args: []any{int64(3), "KEY"}})
+	if result != nil || err != nil {
+		t.Errorf("WaitAny outside a handler = (%v, %v), want (nil, nil)", result, err)
+	}
+	if got, _ := vm.builtins["GetWaitResult"](vm, nil); got != int64(0) {
+		t.Errorf("GetWaitResult outside a handler = %v, want 0", got)
+	}
+}
+
+// TestWaitAnyBuiltinRefused tests that WaitAny inside an expression does not pause.
+func TestWaitAnyBuiltinRefused(t *testing.T) {
+	vm := New([]opcode.OpCode{})
+	if result, err := vm.builtins["WaitAny"](vm, []any{int64(3), "KEY"}); result != int64(0) || err != nil {
+		t.Errorf("WaitAny builtin = (%v, %v), want (0, nil)", result, err)
+	}
+}
+
+// TestWaitAnyWaitingFor tests that only active handlers of other types waiting for the event are returned.
+func TestWaitAnyWaitingFor(t *testing.T) {
+	hr := NewHandlerRegistry()
+	later := &EventHandler{EventType: EventMIDI_TIME, Active: true, waitAny: &waitAnyState{events: []EventType{EventKEY}}}
+	first := &EventHandler{EventType: EventTIME, Active: true, waitAny: &waitAnyState{events: []EventType{EventKEY}}}
+	inactive := &EventHandler{EventType: EventTIME, waitAny: &waitAnyState{events: []EventType{EventKEY}}}
+	own := &EventHandler{EventType: EventKEY, Active: true, waitAny: &waitAnyState{events: []EventType{EventKEY}}}
+	other := &EventHandler{EventType: EventTIME, Active: true, waitAny: &waitAnyState{events: []EventType{EventUSER}}}
+	hr.Register(first)
+	hr.Register(later)
+	hr.Register(inactive)
+	hr.Register(own)
+	hr.Register(other)
+
+	if got := hr.waitingFor(EventKEY); !reflect.DeepEqual(got, []*EventHandler{first, later}) {
+		t.Errorf("waitingFor(KEY) = %v, want the two active handlers in registration order", got)
+	}
+	if got := hr.waitingFor(EventTIME); got != nil {
+		t.Errorf("waitingFor(TIME) = %v, want nil", got)
+	}
+}
+
+// TestSkipTickWaitAny checks that coalesced ticks still count toward the ticks of WaitAny.
+func TestSkipTickWaitAny(t *testing.T) {
+	handler := &EventHandler{TickPolicy: TickCoalesce, waitAny: &waitAnyState{ticks: 3}}
+	if !handler.skipTick() || handler.waitAny.ticks != 2 || handler.DroppedTicks != 0 {
+		t.Errorf("first tick: ticks=%d dropped=%d, want 2 and 0", handler.waitAny.ticks, handler.DroppedTicks)
+	}
+	handler.skipTick()
+	if !handler.skipTick() || handler.waitAny.ticks != 1 || handler.DroppedTicks != 1 {
+		t.Errorf("last tick: ticks=%d dropped=%d, want 1 and 1 (left to the latest tick)", handler.waitAny.ticks, handler.DroppedTicks)
+	}
+}
@@ -0,0 +1,69 @@
+package vm
+
+import (
+	"fmt"
+	"math"
+	"sort"
+)
+
+// WatchedVariable is a global variable of the script shown in the watch panel.
+// Value is the current value: int64, float64, string or *Array.
+type WatchedVariable struct {
+	Name  string
+	Value any
+}
+
+// Numeric reports whether the variable can be edited from the watch panel.
+func (w WatchedVariable) Numeric() bool {
+	switch w.Value.(type) {
+	case int64, float64:
+		return true
+	}
+	return false
+}
+
+// WatchVariables returns the script's global variables sorted by name, for the
+// live watch panel and the remote watch API. The event type constants (TIME,
+// CLICK, ...) registered by the VM itself are left out.
+// It is safe to call from other goroutines while the VM runs.
+func (vm *VM) WatchVariables() []WatchedVariable {
+	names := vm.globalScope.Keys()
+	sort.Strings(names)
+	vars := make([]WatchedVariable, 0, len(names))
+	for _, name := range names {
+		if isEventTypeConstant(name) {
+			continue
+		}
+		if value, ok := vm.globalScope.GetLocal(name); ok {
+			vars = append(vars, WatchedVariable{Name: name, Value: value})
+		}
+	}
+	return vars
+}
+
+// SetWatchedVariable changes a numeric global variable from the watch panel or
+// the remote watch API and returns the stored value. Integer variables stay
+// integers (the value is rounded), so the script sees the type it assigned.
+// Only existing int64/float64 globals can be changed.
+func (vm *VM) SetWatchedVariable(name string, value float64) (any, error) {
+	if math.IsNaN(value) || math.IsInf(value, 0) {
+		return nil, fmt.Errorf("invalid value for %s: %v", name, value)
+	}
+	current, ok := vm.globalScope.GetLocal(name)
+	if !ok || isEventTypeConstant(name) {
+		return nil, fmt.Errorf("global variable not found: %s", name)
+	}
+
+	var stored any
+	switch current.(type) {
+	case int64:
+		stored = int64(math.Round(value))
+	case float64:
+		stored = value
+	default:
+		return nil, fmt.Errorf("global variable %s is not a number (%T)", name, current)
+	}
+	vm.globalScope.SetLocal(name, stored)
+	vm.log.Info("Variable changed from the watch panel", "name", name, "value", stored)
+	return stored, nil
+}
@@ -0,0 +1,80 @@
+package vm
+
+import (
+	"math"
+	"testing"
+
+	"github.com/zurustar/son-et/pkg/opcode"
+)
+
+// TestWatchVariables tests that the watch panel lists the script's globals sorted by name.
+func TestWatchVariables(t *testing.T) {
+	vm := New([]opcode.OpCode{})
+	vm.globalScope.Set("y", int64(20))
+	vm.globalScope.Set("speed", 1.5)
+	vm.globalScope.Set("name", "demo")
+	vm.globalScope.Set("table", NewArray(3))
+
+	vars := vm.WatchVariables()
+	var names []string
+	for _, v := range vars {
+		names = append(names, v.Name)
+	}
+	want := []string{"name", "speed", "table", "y"}
+	if len(names) != len(want) {
+		t.Fatalf("WatchVariables names = %v, want %v (event type constants hidden)", names, want)
+	}
+	for i := range want {
+		if names[i] != want[i] {
+			t.Errorf("WatchVariables names = %v, want %v", names, want)
+			break
+		}
+	}
+
+	numeric := map[string]bool{"name": false, "speed": true, "table": false, "y": true}
+	for _, v := range vars {
+		if v.Numeric() != numeric[v.Name] {
+			t.Errorf("%s: Numeric() = %v, want %v", v.Name, v.Numeric(), numeric[v.Name])
+		}
+	}
+}
+
+// TestSetWatchedVariable tests editing numeric globals from the watch panel.
+func TestSetWatchedVariable(t *testing.T) {
+	vm := New([]opcode.OpCode{})
+	vm.globalScope.Set("x", int64(10))
+	vm.globalScope.Set("speed", 1.5)
+	vm.globalScope.Set("name", "demo")
+
+	// 整数の変数は整数のまま（四捨五入）
+	got, err := vm.SetWatchedVariable("x", 12.6)
+	if err != nil || got != int64(13) {
+		t.Errorf("SetWatchedVariable(x, 12.6) = %v, %v; want 13", got, err)
+	}
+	if v, _ := vm.globalScope.Get("x"); v != int64(13) {
+		t.Errorf("x = %v (%T), want int64 13", v, v)
+	}
+
+	got, err = vm.SetWatchedVariable("speed", 0.25)
+	if err != nil || got != 0.25 {
+		t.Errorf("SetWatchedVariable(speed, 0.25) = %v, %v", got, err)
+	}
+
+	for _, tc := range []struct {
+		name  string
+		value float64
+	}{
+		{"name", 1},        // 数値でない
+		{"missing", 1},     // 存在しない
+		{"TIME", 1},        // イベントタイプの定数
+		{"x", math.NaN()},  // 不正な値
+		{"x", math.Inf(1)}, // 不正な値
+	} {
+		if _, err := vm.SetWatchedVariable(tc.name, tc.value); err == nil {
+			t.Errorf("SetWatchedVariable(%s, %v) should fail", tc.name, tc.value)
+		}
+	}
+	if v, _ := vm.globalScope.Get("TIME"); v != int64(0) {
+		t.Errorf("TIME = %v, want the constant to be unchanged", v)
+	}
+}
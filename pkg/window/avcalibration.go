@@ -0,0 +1,168 @@
+//go:build !nogpu
+
+package window
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"time"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/inpututil"
+	"github.com/hajimehoshi/ebiten/v2/text/v2"
+	"github.com/zurustar/son-et/pkg/logger"
+)
+
+// A/Vオフセットの調整画面のホットキー
+// いずれもKEYイベントとしてスクリプトに渡さないキーを使う
+const (
+	avCalibrationKey    = ebiten.KeyBackslash    // 調整画面を開く・閉じる
+	avOffsetEarlierKey  = ebiten.KeyBracketLeft  // 映像を早める（オフセットを減らす）
+	avOffsetLaterKey    = ebiten.KeyBracketRight // 映像を遅らせる（オフセットを増やす）
+	avOffsetStep        = 5 * time.Millisecond   // 1回の調整量
+	avCalibrationMargin = 16
+)
+
+// 調整画面の表示
+var (
+	avCalibrationBackground = color.RGBA{0x00, 0x00, 0x00, 0xFF}
+	avCalibrationBeatColor  = color.RGBA{0xFF, 0xFF, 0xFF, 0xFF}
+	avCalibrationTextColor  = color.RGBA{0xFF, 0xFF, 0x00, 0xFF}
+)
+
+// AVCalibrator defines the interface for the audio-visual offset (--av-offset) and its calibration screen
+// This is used to decouple the window package from the vm package
+type AVCalibrator interface {
+	// SetAVOffset sets the offset and returns the applied (clamped) offset
+	SetAVOffset(offset time.Duration) time.Duration
+	AVOffset() time.Duration
+	// StartAVCalibration pauses the title and plays a click on every beat
+	StartAVCalibration() error
+	StopAVCalibration()
+	// AVCalibrationBeat reports whether the beat should be shown now (delayed by the offset)
+	AVCalibrationBeat() bool
+}
+
+// SetAVCalibrator sets the target of the A/V calibration screen ("\" opens and closes it,
+// "[" and "]" move the visuals 5ms earlier or later).
+// An offset adjusted on the calibration screen is carried over to the calibrator of the next title.
+// Passing nil disables the calibration screen.
+func (g *Game) SetAVCalibrator(calibrator AVCalibrator) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.avCalibrator = calibrator
+	g.avCalibrating = false
+	if calibrator == nil {
+		return
+	}
+	if g.avOffsetAdjusted {
+		g.avOffset = calibrator.SetAVOffset(g.avOffset)
+	} else {
+		g.avOffset = calibrator.AVOffset()
+	}
+}
+
+// updateAVCalibration は調整画面のホットキーを処理する
+// 調整画面を表示している間は true を返す
+func (g *Game) updateAVCalibration() bool {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.avCalibrator == nil {
+		return false
+	}
+
+	if inpututil.IsKeyJustPressed(avCalibrationKey) {
+		if g.avCalibrating {
+			g.avCalibrator.StopAVCalibration()
+			g.avCalibrating = false
+		} else if err := g.avCalibrator.StartAVCalibration(); err != nil {
+			logger.GetLogger().Warn("Failed to start A/V calibration", "error", err)
+		} else {
+			g.avCalibrating = true
+		}
+		g.forceRedraw = true
+	}
+	if !g.avCalibrating {
+		return false
+	}
+
+	switch {
+	case inpututil.IsKeyJustPressed(avOffsetEarlierKey):
+		g.adjustAVOffset(-avOffsetStep)
+	case inpututil.IsKeyJustPressed(avOffsetLaterKey):
+		g.adjustAVOffset(avOffsetStep)
+	}
+	// 拍の表示は毎フレーム変わる
+	g.forceRedraw = true
+	return true
+}
+
+// adjustAVOffset はA/Vオフセットを delta だけ変更する
+// 呼び出し元は g.mu のロックを保持していること
+func (g *Game) adjustAVOffset(delta time.Duration) {
+	g.avOffset = g.avCalibrator.SetAVOffset(g.avOffset + delta)
+	g.avOffsetAdjusted = true
+	logger.GetLogger().Info("A/V offset adjusted", "offset", g.avOffset)
+}
+
+// isAVCalibrating は調整画面を表示中かを返す
+func (g *Game) isAVCalibrating() bool {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	return g.avCalibrating && g.avCalibrator != nil
+}
+
+// stopAVCalibration は調整画面を閉じる（タイトル終了時）
+// 呼び出し元は g.mu のロックを保持していること
+func (g *Game) stopAVCalibration() {
+	if g.avCalibrating && g.avCalibrator != nil {
+		g.avCalibrator.StopAVCalibration()
+	}
+	g.avCalibrating = false
+	g.avCalibrator = nil
+}
+
+// formatAVOffset はA/Vオフセットの表示文字列を返す（例: "+40ms", "-15ms", "0ms"）
+func formatAVOffset(offset time.Duration) string {
+	ms := offset.Milliseconds()
+	if ms > 0 {
+		return fmt.Sprintf("+%dms", ms)
+	}
+	return fmt.Sprintf("%dms", ms)
+}
+
+// drawAVCalibration は調整画面を描画する
+// 画面中央の四角形が拍に合わせて点滅するので、クリック音と同時に見えるようにオフセットを調整する
+func (g *Game) drawAVCalibration(screen *ebiten.Image) {
+	g.mu.RLock()
+	calibrating := g.avCalibrating
+	calibrator := g.avCalibrator
+	offset := g.avOffset
+	g.mu.RUnlock()
+	if !calibrating || calibrator == nil {
+		return
+	}
+
+	screen.Fill(avCalibrationBackground)
+	bounds := screen.Bounds()
+	if calibrator.AVCalibrationBeat() {
+		size := min(bounds.Dx(), bounds.Dy()) / 3
+		cx, cy := bounds.Min.X+bounds.Dx()/2, bounds.Min.Y+bounds.Dy()/2
+		screen.SubImage(image.Rect(cx-size/2, cy-size/2, cx+size/2, cy+size/2)).(*ebiten.Image).Fill(avCalibrationBeatColor)
+	}
+
+	lines := []string{
+		"A/V offset: " + formatAVOffset(offset),
+		"[ : visuals earlier   ] : visuals later   \\ : close",
+	}
+	y := bounds.Min.Y + avCalibrationMargin
+	for _, line := range lines {
+		_, height := text.Measure(line, defaultFace, 0)
+		op := &text.DrawOptions{}
+		op.GeoM.Translate(float64(bounds.Min.X+avCalibrationMargin), float64(y))
+		op.ColorScale.ScaleWithColor(avCalibrationTextColor)
+		text.Draw(screen, line, defaultFace, op)
+		y += int(height) + avCalibrationMargin/2
+	}
+}
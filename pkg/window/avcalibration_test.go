@@ -0,0 +1,113 @@
+//go:build !nogpu
+
+package window
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+// mockAVCalibrator は設定されたA/Vオフセットを ±1秒に制限して保持する
+type mockAVCalibrator struct {
+	offset      time.Duration
+	calibrating bool
+	startErr    error
+}
+
+func (m *mockAVCalibrator) SetAVOffset(offset time.Duration) time.Duration {
+	m.offset = max(-time.Second, min(time.Second, offset))
+	return m.offset
+}
+
+func (m *mockAVCalibrator) AVOffset() time.Duration { return m.offset }
+
+func (m *mockAVCalibrator) StartAVCalibration() error {
+	if m.startErr != nil {
+		return m.startErr
+	}
+	m.calibrating = true
+	return nil
+}
+
+func (m *mockAVCalibrator) StopAVCalibration()      { m.calibrating = false }
+func (m *mockAVCalibrator) AVCalibrationBeat() bool { return m.calibrating }
+
+func TestSetAVCalibrator(t *testing.T) {
+	game := NewGame(ModeDesktop, nil, 0)
+	first := &mockAVCalibrator{offset: 40 * time.Millisecond}
+	game.SetAVCalibrator(first)
+	if game.avOffset != 40*time.Millisecond {
+		t.Errorf("expected the initial offset of the calibrator, got %v", game.avOffset)
+	}
+
+	// 調整していなければ、次のタイトルは自身のオフセット（--av-offset）を使う
+	second := &mockAVCalibrator{offset: 20 * time.Millisecond}
+	game.SetAVCalibrator(second)
+	if game.avOffset != 20*time.Millisecond || second.offset != 20*time.Millisecond {
+		t.Errorf("expected 20ms, got game %v calibrator %v", game.avOffset, second.offset)
+	}
+
+	// 調整画面で変更した値は次のタイトルに引き継ぐ
+	game.mu.Lock()
+	game.adjustAVOffset(avOffsetStep)
+	game.adjustAVOffset(avOffsetStep)
+	game.mu.Unlock()
+	if second.offset != 30*time.Millisecond {
+		t.Errorf("expected 30ms after two steps, got %v", second.offset)
+	}
+	third := &mockAVCalibrator{}
+	game.SetAVCalibrator(third)
+	if third.offset != 30*time.Millisecond || game.avOffset != 30*time.Millisecond {
+		t.Errorf("expected the adjusted offset to carry over, got %v", third.offset)
+	}
+
+	// 範囲外は制限された値を表示する
+	game.mu.Lock()
+	game.adjustAVOffset(2 * time.Second)
+	game.mu.Unlock()
+	if game.avOffset != time.Second {
+		t.Errorf("expected the clamped offset, got %v", game.avOffset)
+	}
+}
+
+func TestStopAVCalibration(t *testing.T) {
+	game := NewGame(ModeDesktop, nil, 0)
+	calibrator := &mockAVCalibrator{}
+	game.SetAVCalibrator(calibrator)
+	if err := calibrator.StartAVCalibration(); err != nil {
+		t.Fatal(err)
+	}
+	game.avCalibrating = true
+
+	game.mu.Lock()
+	game.stopAVCalibration()
+	game.mu.Unlock()
+	if calibrator.calibrating || game.avCalibrating || game.avCalibrator != nil {
+		t.Error("expected the calibration to stop when the title exits")
+	}
+}
+
+func TestUpdateAVCalibration_Disabled(t *testing.T) {
+	game := NewGame(ModeDesktop, nil, 0)
+	if game.updateAVCalibration() {
+		t.Error("calibration should be unavailable without a calibrator")
+	}
+	game.SetAVCalibrator(&mockAVCalibrator{startErr: errors.New("no audio")})
+	if game.updateAVCalibration() {
+		t.Error("calibration should not be shown before the hotkey is pressed")
+	}
+}
+
+func TestFormatAVOffset(t *testing.T) {
+	for offset, want := range map[time.Duration]string{
+		0:                      "0ms",
+		40 * time.Millisecond:  "+40ms",
+		-15 * time.Millisecond: "-15ms",
+		time.Second:            "+1000ms",
+	} {
+		if got := formatAVOffset(offset); got != want {
+			t.Errorf("formatAVOffset(%v) = %q, want %q", offset, got, want)
+		}
+	}
+}
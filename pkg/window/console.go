@@ -0,0 +1,262 @@
+//go:build !nogpu
+
+package window
+
+import (
+	"errors"
+	"image"
+	"image/color"
+	"strings"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/inpututil"
+	"github.com/hajimehoshi/ebiten/v2/text/v2"
+)
+
+// オペレーターのコンソール（~ キー）
+// ライブで上演するオペレーター向けに、画面上部に開くコンソールから "seek 1200" や "vol 0.5" などの
+// コマンドを実行する。コマンドはウォッチサーバーの POST /command と同じ CommandRunner で実行する。
+// "reload" だけはウィンドウ側で処理し、タイトルを最初から読み込み直す。
+// コンソールを開いている間はキー入力とホットキーをスクリプトに渡さない（タイトルは動き続ける）
+
+// コンソールのホットキー
+// ~ は Shift+` で入力する（Shift なしの ` は時間スケールを等速に戻す）
+const (
+	consoleKey         = ebiten.KeyBackquote // Shift と一緒に押してコンソールを開く・閉じる
+	consoleSubmitKey   = ebiten.KeyEnter     // 入力したコマンドを実行する
+	consoleDeleteKey   = ebiten.KeyBackspace // 1文字消す
+	consoleCloseKey    = ebiten.KeyEscape    // コンソールを閉じる
+	consoleHistPrevKey = ebiten.KeyArrowUp   // 前に実行したコマンドを呼び出す
+	consoleHistNextKey = ebiten.KeyArrowDown // 次に実行したコマンドを呼び出す
+)
+
+// コンソールの表示と履歴
+const (
+	consoleRows       = 10  // 一度に表示する出力の行数
+	consoleMaxLines   = 200 // 保持する出力の行数
+	consoleMaxHistory = 50  // 保持するコマンド履歴の数
+	consoleMargin     = 8
+	consolePrompt     = "> "
+	consoleReload     = "reload"
+)
+
+var (
+	consoleBackground = color.RGBA{0x00, 0x00, 0x00, 0xD0}
+	consoleTextColor  = color.RGBA{0xFF, 0xFF, 0xFF, 0xFF}
+	consoleErrorColor = color.RGBA{0xFF, 0x60, 0x60, 0xFF}
+	consoleInputColor = color.RGBA{0xFF, 0xFF, 0x00, 0xFF}
+)
+
+// errReloadUnavailable はタイトル選択画面がなく reload できない場合のエラー
+var errReloadUnavailable = errors.New("reload is only available when the title was chosen on the selection screen")
+
+// CommandRunner defines the interface for running operator console commands
+// This is used to decouple the window package from the vm package
+type CommandRunner interface {
+	// RunCommand executes one command line and returns the text to show
+	RunCommand(line string) (string, error)
+}
+
+// consoleLine はコンソールに表示する出力の1行
+type consoleLine struct {
+	text  string
+	error bool
+}
+
+// SetCommandRunner sets the target of the operator console ("~" opens and closes it,
+// Enter runs the typed command). Passing nil disables the console.
+func (g *Game) SetCommandRunner(runner CommandRunner) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.commandRunner = runner
+	g.consoleOpen = false
+	g.consoleInput = ""
+}
+
+// updateConsole はコンソールのホットキーと入力を処理する
+// コンソールを表示している間は true を返す（キー入力とホットキーをスクリプトに渡さない）
+func (g *Game) updateConsole() bool {
+	g.mu.Lock()
+	if g.commandRunner == nil {
+		g.mu.Unlock()
+		return false
+	}
+	if inpututil.IsKeyJustPressed(consoleKey) && ebiten.IsKeyPressed(ebiten.KeyShift) {
+		g.consoleOpen = !g.consoleOpen
+		g.consoleInput = ""
+		g.consoleHistoryPos = len(g.consoleHistory)
+		g.forceRedraw = true
+		// 開いた・閉じたフレームの ~ は入力に含めない
+		g.mu.Unlock()
+		return true
+	}
+	if !g.consoleOpen {
+		g.mu.Unlock()
+		return false
+	}
+	g.forceRedraw = true
+
+	if inpututil.IsKeyJustPressed(consoleCloseKey) {
+		g.consoleOpen = false
+		g.mu.Unlock()
+		return true
+	}
+	switch {
+	case isKeyPressedOrRepeated(consoleHistPrevKey):
+		g.recallConsoleHistory(-1)
+	case isKeyPressedOrRepeated(consoleHistNextKey):
+		g.recallConsoleHistory(1)
+	}
+	g.consoleInput = editConsoleInput(g.consoleInput, ebiten.AppendInputChars(nil), isKeyPressedOrRepeated(consoleDeleteKey))
+	if !inpututil.IsKeyJustPressed(consoleSubmitKey) {
+		g.mu.Unlock()
+		return true
+	}
+	line := g.consoleInput
+	g.consoleInput = ""
+	g.mu.Unlock()
+
+	// reload はタイトル選択画面に戻るため、ロックを解放してから実行する
+	g.submitConsoleLine(line)
+	return true
+}
+
+// editConsoleInput は入力中の行に文字を追加し、backspace の場合は最後の1文字を消す
+// ~ と ` はコンソールの開閉に使うため入力しない
+func editConsoleInput(input string, chars []rune, backspace bool) string {
+	if backspace && input != "" {
+		runes := []rune(input)
+		input = string(runes[:len(runes)-1])
+	}
+	for _, r := range chars {
+		if r == '~' || r == '`' || r < ' ' {
+			continue
+		}
+		input += string(r)
+	}
+	return input
+}
+
+// recallConsoleHistory は入力中の行を direction の向きのコマンド履歴で置き換える
+// 最新の履歴より先に進むと入力を空にする
+// 呼び出し元は g.mu のロックを保持していること
+func (g *Game) recallConsoleHistory(direction int) {
+	g.consoleHistoryPos = max(0, min(g.consoleHistoryPos+direction, len(g.consoleHistory)))
+	if g.consoleHistoryPos == len(g.consoleHistory) {
+		g.consoleInput = ""
+		return
+	}
+	g.consoleInput = g.consoleHistory[g.consoleHistoryPos]
+}
+
+// submitConsoleLine はコンソールに入力された1行を実行し、結果を出力に追加する
+func (g *Game) submitConsoleLine(line string) {
+	line = strings.TrimSpace(line)
+	if line == "" {
+		return
+	}
+
+	g.mu.Lock()
+	runner := g.commandRunner
+	g.appendConsoleOutput(consolePrompt+line, false)
+	if n := len(g.consoleHistory); n == 0 || g.consoleHistory[n-1] != line {
+		g.consoleHistory = append(g.consoleHistory, line)
+		if len(g.consoleHistory) > consoleMaxHistory {
+			g.consoleHistory = g.consoleHistory[len(g.consoleHistory)-consoleMaxHistory:]
+		}
+	}
+	g.consoleHistoryPos = len(g.consoleHistory)
+	g.mu.Unlock()
+
+	var out string
+	var err error
+	switch {
+	case strings.EqualFold(line, consoleReload):
+		err = g.reloadTitle()
+		out = "reloaded"
+	case runner != nil:
+		out, err = runner.RunCommand(line)
+	}
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if err != nil {
+		g.appendConsoleOutput(err.Error(), true)
+		return
+	}
+	if out != "" {
+		g.appendConsoleOutput(out, false)
+	}
+}
+
+// appendConsoleOutput はコンソールの出力に text を1行ずつ追加し、古い行を捨てる
+// 呼び出し元は g.mu のロックを保持していること
+func (g *Game) appendConsoleOutput(text string, isError bool) {
+	for _, line := range strings.Split(text, "\n") {
+		g.consoleLines = append(g.consoleLines, consoleLine{text: line, error: isError})
+	}
+	if len(g.consoleLines) > consoleMaxLines {
+		g.consoleLines = g.consoleLines[len(g.consoleLines)-consoleMaxLines:]
+	}
+}
+
+// reloadTitle は実行中のタイトルを終了し、同じタイトルを最初から読み込み直す
+// タイトル選択画面から選んだタイトルのみ読み込み直せる（単一タイトルではエラーを返す）
+func (g *Game) reloadTitle() error {
+	g.mu.RLock()
+	selected := g.selectedTitle
+	hasCallback := g.onTitleSelected != nil
+	g.mu.RUnlock()
+	if selected == nil || !hasCallback {
+		return errReloadUnavailable
+	}
+
+	// returnToSelection でコンソールの実行先はなくなるが、出力と履歴は次のタイトルに引き継ぐ
+	if err := g.returnToSelection(); err != nil {
+		return err
+	}
+	return g.startTitle(selected)
+}
+
+// drawConsole はコンソールを画面上部に描画する
+func (g *Game) drawConsole(screen *ebiten.Image) {
+	g.mu.RLock()
+	open := g.consoleOpen && g.commandRunner != nil
+	input := g.consoleInput
+	lines := g.consoleLines[max(0, len(g.consoleLines)-consoleRows):]
+	g.mu.RUnlock()
+	if !open {
+		return
+	}
+
+	_, lineHeight := text.Measure("M", defaultFace, 0)
+	rowHeight := int(lineHeight) + consoleMargin/2
+	bounds := screen.Bounds()
+	panel := image.Rect(bounds.Min.X, bounds.Min.Y, bounds.Max.X,
+		min(bounds.Min.Y+(consoleRows+1)*rowHeight+consoleMargin*2, bounds.Max.Y))
+	background := ebiten.NewImage(1, 1)
+	defer background.Deallocate()
+	background.Fill(consoleBackground)
+	op := &ebiten.DrawImageOptions{}
+	op.GeoM.Scale(float64(panel.Dx()), float64(panel.Dy()))
+	op.GeoM.Translate(float64(panel.Min.X), float64(panel.Min.Y))
+	screen.DrawImage(background, op)
+
+	// 出力は下詰めにし、最後の行に入力中のコマンドを表示する
+	y := panel.Min.Y + consoleMargin + (consoleRows-len(lines))*rowHeight
+	drawLine := func(line string, c color.Color) {
+		op := &text.DrawOptions{}
+		op.GeoM.Translate(float64(panel.Min.X+consoleMargin), float64(y))
+		op.ColorScale.ScaleWithColor(c)
+		text.Draw(screen, line, defaultFace, op)
+		y += rowHeight
+	}
+	for _, line := range lines {
+		c := consoleTextColor
+		if line.error {
+			c = consoleErrorColor
+		}
+		drawLine(line.text, c)
+	}
+	drawLine(consolePrompt+input+"_", consoleInputColor)
+}
@@ -0,0 +1,174 @@
+//go:build !nogpu
+
+package window
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/zurustar/son-et/pkg/title"
+)
+
+// mockCommandRunner は実行したコマンドを記録し、決められた結果を返す
+type mockCommandRunner struct {
+	lines  []string
+	output string
+	err    error
+}
+
+func (m *mockCommandRunner) RunCommand(line string) (string, error) {
+	m.lines = append(m.lines, line)
+	return m.output, m.err
+}
+
+func newConsoleGame() (*Game, *mockCommandRunner) {
+	runner := &mockCommandRunner{output: "ok"}
+	game := NewGame(ModeDesktop, nil, 0)
+	game.SetCommandRunner(runner)
+	return game, runner
+}
+
+func TestEditConsoleInput(t *testing.T) {
+	tests := []struct {
+		input     string
+		chars     string
+		backspace bool
+		want      string
+	}{
+		{"", "seek 12", false, "seek 12"},
+		{"vol", " 0.5", false, "vol 0.5"},
+		{"vol 0.55", "", true, "vol 0.5"},
+		{"", "", true, ""},
+		{"音量", "", true, "音"},
+		// ~ と ` はコンソールの開閉に使う
+		{"", "~`a\t", false, "a"},
+	}
+	for _, tt := range tests {
+		if got := editConsoleInput(tt.input, []rune(tt.chars), tt.backspace); got != tt.want {
+			t.Errorf("editConsoleInput(%q, %q, %v) = %q, want %q", tt.input, tt.chars, tt.backspace, got, tt.want)
+		}
+	}
+}
+
+func TestSubmitConsoleLine(t *testing.T) {
+	game, runner := newConsoleGame()
+
+	game.submitConsoleLine("  vol 0.5 ")
+	game.submitConsoleLine("")
+	if len(runner.lines) != 1 || runner.lines[0] != "vol 0.5" {
+		t.Fatalf("commands run = %q, want [vol 0.5]", runner.lines)
+	}
+	if len(game.consoleLines) != 2 || game.consoleLines[0].text != "> vol 0.5" || game.consoleLines[1].text != "ok" {
+		t.Errorf("console output = %+v", game.consoleLines)
+	}
+
+	runner.err = errors.New("usage: seek <tick>")
+	game.submitConsoleLine("seek")
+	last := game.consoleLines[len(game.consoleLines)-1]
+	if !last.error || last.text != "usage: seek <tick>" {
+		t.Errorf("error line = %+v", last)
+	}
+
+	// 複数行の出力は1行ずつ表示する
+	runner.err = nil
+	runner.output = "a\nb"
+	game.submitConsoleLine("help")
+	if n := len(game.consoleLines); game.consoleLines[n-2].text != "a" || game.consoleLines[n-1].text != "b" {
+		t.Errorf("multi-line output = %+v", game.consoleLines[n-2:])
+	}
+}
+
+func TestConsoleOutputLimit(t *testing.T) {
+	game, _ := newConsoleGame()
+	game.mu.Lock()
+	defer game.mu.Unlock()
+	for range consoleMaxLines + 5 {
+		game.appendConsoleOutput("line", false)
+	}
+	if len(game.consoleLines) != consoleMaxLines {
+		t.Errorf("kept %d lines, want %d", len(game.consoleLines), consoleMaxLines)
+	}
+}
+
+func TestRecallConsoleHistory(t *testing.T) {
+	game, _ := newConsoleGame()
+	game.submitConsoleLine("seek 100")
+	game.submitConsoleLine("vol 1")
+	game.submitConsoleLine("vol 1") // 同じコマンドを続けても履歴は1つ
+
+	game.mu.Lock()
+	defer game.mu.Unlock()
+	if len(game.consoleHistory) != 2 {
+		t.Fatalf("history = %q", game.consoleHistory)
+	}
+	game.recallConsoleHistory(-1)
+	if game.consoleInput != "vol 1" {
+		t.Errorf("first recall = %q, want vol 1", game.consoleInput)
+	}
+	game.recallConsoleHistory(-1)
+	game.recallConsoleHistory(-1)
+	if game.consoleInput != "seek 100" {
+		t.Errorf("recall past the oldest = %q, want seek 100", game.consoleInput)
+	}
+	game.recallConsoleHistory(1)
+	game.recallConsoleHistory(1)
+	if game.consoleInput != "" {
+		t.Errorf("recall past the newest = %q, want empty", game.consoleInput)
+	}
+}
+
+func TestConsoleReload(t *testing.T) {
+	// タイトル選択画面がない場合は読み込み直せない
+	game, runner := newConsoleGame()
+	game.submitConsoleLine("reload")
+	if len(runner.lines) != 0 {
+		t.Errorf("reload was passed to the command runner: %q", runner.lines)
+	}
+	if last := game.consoleLines[len(game.consoleLines)-1]; !last.error || last.text != errReloadUnavailable.Error() {
+		t.Errorf("reload without a selection screen = %+v", last)
+	}
+
+	// 選んだタイトルをもう一度起動する
+	titles := []title.FillyTitle{{Name: "first"}, {Name: "second"}}
+	game = NewGame(ModeSelection, titles, 0)
+	game.SetHasTitleSelection(true)
+	var started []string
+	game.SetOnTitleSelected(func(t *title.FillyTitle) error {
+		started = append(started, t.Name)
+		game.SetCommandRunner(runner)
+		return nil
+	})
+	exited := 0
+	game.SetOnTitleExit(func() error {
+		exited++
+		return nil
+	})
+	game.selectedTitle = &titles[1]
+	if err := game.startTitle(game.selectedTitle); err != nil {
+		t.Fatal(err)
+	}
+
+	game.submitConsoleLine("RELOAD")
+	if exited != 1 || len(started) != 2 || started[1] != "second" {
+		t.Errorf("reload: exited %d times, started %q", exited, started)
+	}
+	if game.mode != ModeDesktop {
+		t.Errorf("mode after reload = %v, want ModeDesktop", game.mode)
+	}
+	if game.commandRunner != runner {
+		t.Error("expected the reloaded title to set the command runner again")
+	}
+	if last := game.consoleLines[len(game.consoleLines)-1]; last.error || last.text != "reloaded" {
+		t.Errorf("reload output = %+v", last)
+	}
+}
+
+func TestSetCommandRunnerResets(t *testing.T) {
+	game, _ := newConsoleGame()
+	game.consoleOpen = true
+	game.consoleInput = "vol"
+	game.SetCommandRunner(nil)
+	if game.consoleOpen || game.consoleInput != "" || game.updateConsole() {
+		t.Error("expected SetCommandRunner(nil) to close and disable the console")
+	}
+}
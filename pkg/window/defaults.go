@@ -0,0 +1,9 @@
+package window
+
+// OSのウィンドウの既定の設定（タイトルは SetWindowTitle などで変更できる）
+// skelton要件 3.2: ウィンドウサイズは 1024x768 ピクセル
+const (
+	DefaultTitle  = "son-et - FILLY interpreter"
+	DefaultWidth  = 1024
+	DefaultHeight = 768
+)
@@ -0,0 +1,85 @@
+//go:build !nogpu
+
+package window
+
+import (
+	"time"
+
+	"github.com/zurustar/son-et/pkg/logger"
+)
+
+// maxExitWait はタイトルの終了処理（OnExit）を待つ時間の上限
+// VM側で終了処理の予算（最大10秒）を過ぎると停止するが、VMが応答しない場合でも閉じられるようにする
+const maxExitWait = 15 * time.Second
+
+// ExitRequester is implemented by VM runners that run the title's exit sequence (OnExit)
+// before stopping. The window stays open until the VM stops, so that the title can fade out
+// its audio and show a farewell screen.
+// This is used to decouple the window package from the vm package
+type ExitRequester interface {
+	// RequestExit starts the exit sequence and reports whether the window should wait for the VM to stop
+	RequestExit() bool
+}
+
+// beginExit はウィンドウを閉じる操作・タイムアウト・Escキーによるタイトルの終了を始める
+// タイトルが終了処理を登録している場合は true を返し、VMが停止するまでゲームループを続ける。
+// 終了処理の実行中に再度呼び出された場合も true を返す。
+func (g *Game) beginExit() bool {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if g.exiting {
+		return true
+	}
+	if g.mode != ModeDesktop {
+		return false
+	}
+	requester, ok := g.vmRunner.(ExitRequester)
+	if !ok || !requester.RequestExit() {
+		return false
+	}
+	g.exiting = true
+	g.exitDeadline = time.Now().Add(maxExitWait)
+	logger.GetLogger().Info("Running the title's exit sequence before closing")
+	return true
+}
+
+// exitFinished はタイトルの終了処理が終わった（VMが停止した、または待ち時間の上限を過ぎた）かどうかを返す
+func (g *Game) exitFinished() bool {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+
+	if !g.exiting {
+		return false
+	}
+	if g.vmRunner == nil || !g.vmRunner.IsRunning() {
+		return true
+	}
+	if time.Now().After(g.exitDeadline) {
+		logger.GetLogger().Warn("The title's exit sequence did not finish in time, stopping the VM")
+		g.vmRunner.Stop()
+		return true
+	}
+	return false
+}
+
+// markTimedOut はタイムアウトで終了することを記録する
+func (g *Game) markTimedOut() {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.timedOut = true
+}
+
+// TimedOut reports whether the game loop ended because the timeout expired.
+func (g *Game) TimedOut() bool {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	return g.timedOut
+}
+
+// isExiting はタイトルの終了処理の実行中かどうかを返す
+func (g *Game) isExiting() bool {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	return g.exiting
+}
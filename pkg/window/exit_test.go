@@ -0,0 +1,102 @@
+//go:build !nogpu
+
+package window
+
+import (
+	"testing"
+	"time"
+)
+
+// mockExitRunner は終了処理（OnExit）を持つVMを模倣する
+type mockExitRunner struct {
+	running      bool
+	hasExitFunc  bool
+	requestCount int
+	stopCount    int
+}
+
+func (m *mockExitRunner) IsRunning() bool      { return m.running }
+func (m *mockExitRunner) IsFullyStopped() bool { return !m.running }
+func (m *mockExitRunner) Stop()                { m.stopCount++; m.running = false }
+func (m *mockExitRunner) RequestExit() bool {
+	m.requestCount++
+	return m.running && m.hasExitFunc
+}
+
+func TestBeginExitWaitsForExitSequence(t *testing.T) {
+	game := NewGame(ModeDesktop, nil, 0)
+	runner := &mockExitRunner{running: true, hasExitFunc: true}
+	game.SetVMRunner(runner)
+
+	if !game.beginExit() {
+		t.Fatal("beginExit should wait when the title has an exit sequence")
+	}
+	// 2回目以降はVMに再度要求しない
+	if !game.beginExit() || runner.requestCount != 1 {
+		t.Errorf("beginExit should keep waiting without requesting again (requests=%d)", runner.requestCount)
+	}
+	if game.exitFinished() {
+		t.Error("exit should not finish while the VM is running")
+	}
+
+	runner.running = false
+	if !game.exitFinished() {
+		t.Error("exit should finish when the VM stops")
+	}
+	if runner.stopCount != 0 {
+		t.Error("a VM that stopped by itself should not be stopped again")
+	}
+}
+
+func TestBeginExitWithoutExitSequence(t *testing.T) {
+	tests := []struct {
+		name   string
+		mode   Mode
+		runner VMRunnerInterface
+	}{
+		{"no exit function", ModeDesktop, &mockExitRunner{running: true}},
+		{"VM stopped", ModeDesktop, &mockExitRunner{hasExitFunc: true}},
+		{"no VM", ModeDesktop, nil},
+		{"selection screen", ModeSelection, &mockExitRunner{running: true, hasExitFunc: true}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			game := NewGame(tt.mode, nil, 0)
+			if tt.runner != nil {
+				game.SetVMRunner(tt.runner)
+			}
+			if game.beginExit() {
+				t.Error("beginExit should close immediately")
+			}
+			if game.exitFinished() || game.isExiting() {
+				t.Error("no exit sequence should be running")
+			}
+		})
+	}
+}
+
+func TestExitFinishedStopsVMAfterDeadline(t *testing.T) {
+	game := NewGame(ModeDesktop, nil, 0)
+	runner := &mockExitRunner{running: true, hasExitFunc: true}
+	game.SetVMRunner(runner)
+
+	game.beginExit()
+	game.exitDeadline = time.Now().Add(-time.Millisecond)
+	if !game.exitFinished() {
+		t.Fatal("exit should finish after the deadline")
+	}
+	if runner.stopCount != 1 {
+		t.Errorf("expected the VM to be stopped once, got %d", runner.stopCount)
+	}
+}
+
+func TestGameTimedOut(t *testing.T) {
+	game := NewGame(ModeDesktop, nil, time.Millisecond)
+	if game.TimedOut() {
+		t.Fatal("TimedOut should be false before the timeout")
+	}
+	game.markTimedOut()
+	if !game.TimedOut() {
+		t.Error("TimedOut should report the timeout")
+	}
+}
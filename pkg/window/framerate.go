@@ -0,0 +1,199 @@
+//go:build !nogpu
+
+package window
+
+import (
+	"math"
+	"time"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/inpututil"
+)
+
+// フレームレートの既定値と上限
+// TPS（Update の呼び出し回数）と FPS（画面を描き直す回数）は独立して設定できる
+const (
+	DefaultTPS   = ebiten.DefaultTPS // 既定のTPS（60）
+	DefaultFPS   = 60                // 既定のFPS（#info FPS で変更できる）
+	MaxFrameRate = 240               // --tps / --fps / #info FPS の上限
+)
+
+// drawTolerance は描画間隔の許容誤差
+// 垂直同期のゆらぎで描画予定時刻をわずかに過ぎなかった場合に1フレーム遅れないようにする
+const drawTolerance = 2 * time.Millisecond
+
+// ResolveFrameRate は CLI とスクリプトの指定から実際の TPS と FPS を決める
+//
+// FPS はスクリプトの #info FPS（未指定なら DefaultFPS）を --fps で上限制限した値となる。
+// TPS は --tps（未指定なら DefaultTPS）となる。0 以下の値は未指定として扱う。
+// TIMEイベントは実時間（50ms間隔）で発生するため、どの設定でもスクリプトの進行速度は変わらない。
+func ResolveFrameRate(scriptFPS, cliTPS, cliFPS int) (tps, fps int) {
+	tps = DefaultTPS
+	if cliTPS > 0 {
+		tps = min(cliTPS, MaxFrameRate)
+	}
+	fps = DefaultFPS
+	if scriptFPS > 0 {
+		fps = min(scriptFPS, MaxFrameRate)
+	}
+	if cliFPS > 0 && cliFPS < fps {
+		fps = cliFPS
+	}
+	return tps, fps
+}
+
+// ticksFor は時間を指定した TPS でのティック数に変換する（最低1ティック）
+func ticksFor(d time.Duration, tps int) int {
+	if tps <= 0 {
+		tps = DefaultTPS
+	}
+	return max(1, int(math.Round(d.Seconds()*float64(tps))))
+}
+
+// SetFrameRate は TPS と FPS を設定する（ResolveFrameRate の結果を渡す）
+// RunGame の前後どちらでも呼び出せる。タイトルの切り替え時にも再設定する。
+func (g *Game) SetFrameRate(tps, fps int) {
+	ebiten.SetTPS(tps)
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.tps = tps
+	if g.powerSave != nil {
+		g.powerSave.wake()
+	}
+	g.fps = fps
+	g.nextDraw = time.Time{}
+	g.forceRedraw = true
+	// 描画を間引く場合は前回の画面を残す（Draw を省略したフレームも同じ画面を表示する）
+	ebiten.SetScreenClearedEveryFrame(fps <= 0)
+}
+
+// shouldDraw は now の時点で画面を描き直すかを返す
+// FPS が設定されていない場合は毎フレーム描き直す
+func (g *Game) shouldDraw(now time.Time) bool {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.fps <= 0 {
+		return true
+	}
+	if !g.nextDraw.IsZero() && now.Before(g.nextDraw.Add(-drawTolerance)) {
+		return false
+	}
+	interval := time.Second / time.Duration(g.fps)
+	g.nextDraw = g.nextDraw.Add(interval)
+	// 大きく遅れた場合（初回を含む）は追いつこうとせず、現在時刻から数え直す
+	if g.nextDraw.Before(now) {
+		g.nextDraw = now.Add(interval)
+	}
+	return true
+}
+
+// needsRedraw は画面を描き直す必要があるかを返す
+// 前回の画面が残る設定（FPSを指定）で、仮想デスクトップの内容が前回の描画から
+// 変わっていない場合だけ false を返す。
+func (g *Game) needsRedraw() bool {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.fps <= 0 || g.mode != ModeDesktop || g.forceRedraw {
+		g.forceRedraw = false
+		return true
+	}
+	// メトロノームの拍の表示が切り替わる時は、画面に変化がなくても描き直す
+	if g.metronome != nil && g.metronome.MetronomeBeat() != g.metronomeShown {
+		return true
+	}
+	reporter, ok := g.graphicsSystem.(RedrawReporter)
+	if !ok {
+		return true
+	}
+	return reporter.NeedsRedraw()
+}
+
+// SetPowerSave は省電力モード（--power-save、powerSaver を参照）を有効・無効にする
+func (g *Game) SetPowerSave(enabled bool) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if enabled {
+		if g.powerSave == nil {
+			g.powerSave = &powerSaver{}
+		}
+		return
+	}
+	if g.powerSave != nil && g.powerSave.idle {
+		ebiten.SetTPS(g.activeTPS())
+	}
+	g.powerSave = nil
+}
+
+// activeTPS は SetFrameRate で設定した TPS を返す
+// 呼び出し元は g.mu のロックを保持していること
+func (g *Game) activeTPS() int {
+	if g.tps <= 0 {
+		return DefaultTPS
+	}
+	return g.tps
+}
+
+// updatePowerSave は省電力モードで、画面の変化と入力がなくなったら TPS を下げ、あれば元に戻す
+// busy はオーバーレイ（コンソール・ポーズメニューなど）を表示中かどうか
+func (g *Game) updatePowerSave(busy bool) {
+	g.mu.RLock()
+	enabled := g.powerSave != nil
+	g.mu.RUnlock()
+	if !enabled {
+		return
+	}
+	// needsRedraw と同じく、シーンの変化はグラフィックスシステムのダーティトラッキングで判定する
+	active := busy || g.isExiting() || g.sceneChanged()
+	x, y := ebiten.CursorPosition()
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.powerSave == nil {
+		return
+	}
+	moved := g.powerSave.moveCursor(x, y)
+	active = active || moved || inputActive() || g.mode != ModeDesktop
+	idle, changed := g.powerSave.update(time.Now(), active)
+	if !changed {
+		return
+	}
+	if idle {
+		ebiten.SetTPS(min(g.activeTPS(), powerSaveIdleTPS))
+	} else {
+		ebiten.SetTPS(g.activeTPS())
+	}
+}
+
+// sceneChanged は仮想デスクトップの内容が前回の描画から変わったかを返す
+// ダーティトラッキングのないグラフィックスシステムでは常に true を返す
+func (g *Game) sceneChanged() bool {
+	g.mu.RLock()
+	graphicsSystem := g.graphicsSystem
+	forceRedraw := g.forceRedraw
+	g.mu.RUnlock()
+	reporter, ok := graphicsSystem.(RedrawReporter)
+	return forceRedraw || !ok || reporter.NeedsRedraw()
+}
+
+// inputActive はキー・マウスのボタン・ホイール・タッチ・ゲームパッドのボタンの入力があるかを返す
+// 静止中の TPS では、1ティックより短いキーやボタンの押下は取りこぼすことがある
+func inputActive() bool {
+	if len(inpututil.AppendPressedKeys(nil)) > 0 || len(ebiten.AppendTouchIDs(nil)) > 0 {
+		return true
+	}
+	for _, b := range []ebiten.MouseButton{ebiten.MouseButtonLeft, ebiten.MouseButtonRight, ebiten.MouseButtonMiddle} {
+		if ebiten.IsMouseButtonPressed(b) {
+			return true
+		}
+	}
+	if dx, dy := ebiten.Wheel(); dx != 0 || dy != 0 {
+		return true
+	}
+	for _, id := range ebiten.AppendGamepadIDs(nil) {
+		if len(inpututil.AppendPressedGamepadButtons(id, nil)) > 0 {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,147 @@
+//go:build !nogpu
+
+package window
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/inpututil"
+)
+
+// rawGamepadButtonPrefix は配置が標準でないゲームパッドのボタンを番号で指定する名前の接頭辞（"BUTTON0"）
+const rawGamepadButtonPrefix = "BUTTON"
+
+// standardGamepadButtons は入力マップで使用できる標準配置のボタン名
+// 名前はXInput系のゲームパッドの表記に合わせる（A が下、Y が上）
+var standardGamepadButtons = map[string]ebiten.StandardGamepadButton{
+	"A":          ebiten.StandardGamepadButtonRightBottom,
+	"B":          ebiten.StandardGamepadButtonRightRight,
+	"X":          ebiten.StandardGamepadButtonRightLeft,
+	"Y":          ebiten.StandardGamepadButtonRightTop,
+	"LB":         ebiten.StandardGamepadButtonFrontTopLeft,
+	"RB":         ebiten.StandardGamepadButtonFrontTopRight,
+	"LT":         ebiten.StandardGamepadButtonFrontBottomLeft,
+	"RT":         ebiten.StandardGamepadButtonFrontBottomRight,
+	"BACK":       ebiten.StandardGamepadButtonCenterLeft,
+	"START":      ebiten.StandardGamepadButtonCenterRight,
+	"HOME":       ebiten.StandardGamepadButtonCenterCenter,
+	"L3":         ebiten.StandardGamepadButtonLeftStick,
+	"R3":         ebiten.StandardGamepadButtonRightStick,
+	"DPAD_UP":    ebiten.StandardGamepadButtonLeftTop,
+	"DPAD_DOWN":  ebiten.StandardGamepadButtonLeftBottom,
+	"DPAD_LEFT":  ebiten.StandardGamepadButtonLeftLeft,
+	"DPAD_RIGHT": ebiten.StandardGamepadButtonLeftRight,
+}
+
+// KeyBinding はゲームパッドのボタンを押したときに発生させるキー入力
+type KeyBinding struct {
+	KeyCode   int // Windows仮想キーコード
+	Modifiers int // 修飾キーのビットマスク
+}
+
+// InputMap はゲームパッドのボタンとキー入力の対応（--input-map）
+// 標準配置のボタンは標準配置として認識されたゲームパッドにだけ、
+// 番号で指定したボタンはすべてのゲームパッドに適用する。
+type InputMap struct {
+	Standard map[ebiten.StandardGamepadButton]KeyBinding
+	Raw      map[ebiten.GamepadButton]KeyBinding
+}
+
+// inputMapFile は入力マップファイルのJSON形式
+//
+//	{"buttons": {"A": "ENTER", "START": "SPACE", "DPAD_UP": "UP", "BUTTON5": "CTRL+S"}}
+type inputMapFile struct {
+	Buttons map[string]string `json:"buttons"`
+}
+
+// ParseInputMap は入力マップファイル（JSON）を解析する
+// キーの指定は parseKey（OnKey と同じ形式の vm.ParseKeySpec）で解析する。
+// ボタン名は大文字・小文字を区別しない。
+func ParseInputMap(data []byte, parseKey func(spec string) (keyCode, modifiers int, err error)) (*InputMap, error) {
+	var file inputMapFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("invalid input map: %w", err)
+	}
+	if len(file.Buttons) == 0 {
+		return nil, fmt.Errorf("input map has no buttons")
+	}
+
+	m := &InputMap{
+		Standard: make(map[ebiten.StandardGamepadButton]KeyBinding),
+		Raw:      make(map[ebiten.GamepadButton]KeyBinding),
+	}
+	// エラーメッセージが毎回同じになるよう、ボタン名の順に処理する
+	names := make([]string, 0, len(file.Buttons))
+	for name := range file.Buttons {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		code, mods, err := parseKey(file.Buttons[name])
+		if err != nil {
+			return nil, fmt.Errorf("input map button %q: %w", name, err)
+		}
+		binding := KeyBinding{KeyCode: code, Modifiers: mods}
+
+		upper := strings.ToUpper(strings.TrimSpace(name))
+		if button, ok := standardGamepadButtons[upper]; ok {
+			m.Standard[button] = binding
+			continue
+		}
+		if n, ok := strings.CutPrefix(upper, rawGamepadButtonPrefix); ok {
+			if index, err := strconv.Atoi(n); err == nil && index >= 0 && index <= int(ebiten.GamepadButtonMax) {
+				m.Raw[ebiten.GamepadButton(index)] = binding
+				continue
+			}
+		}
+		return nil, fmt.Errorf("input map: unknown gamepad button %q", name)
+	}
+	return m, nil
+}
+
+// SetInputMap sets the gamepad button to key mapping (--input-map).
+// Mapped buttons are delivered to the script as KEY events (and CHAR events for letters),
+// so OnKey handlers and CHAR handlers work with kiosk buttons unchanged.
+// Passing nil disables gamepad input.
+func (g *Game) SetInputMap(m *InputMap) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.inputMap = m
+}
+
+// pushGamepadKey はボタンの押下をキー入力としてVMに伝達する
+// 修飾キーなしの英字はキーボードと同じく CHAR イベント（小文字のASCIIコード）も発生させる
+func pushGamepadKey(eventPusher MouseEventPusher, b KeyBinding, repeat bool) {
+	if !repeat && b.Modifiers == 0 && b.KeyCode >= 'A' && b.KeyCode <= 'Z' {
+		eventPusher.PushKeyEvent("CHAR", b.KeyCode-'A'+'a')
+	}
+	eventPusher.PushKeyInput(b.KeyCode, b.Modifiers, repeat)
+}
+
+// processGamepadInputs は入力マップに従い、接続中のゲームパッドのボタンの押下（リピートを含む）を
+// キー入力としてVMに伝達する
+func processGamepadInputs(m *InputMap, eventPusher MouseEventPusher) {
+	if m == nil {
+		return
+	}
+	tps := ebiten.TPS()
+	for _, id := range ebiten.AppendGamepadIDs(nil) {
+		if ebiten.IsStandardGamepadLayoutAvailable(id) {
+			for button, b := range m.Standard {
+				if fire, repeat := keyPressState(inpututil.StandardGamepadButtonPressDuration(id, button), tps); fire {
+					pushGamepadKey(eventPusher, b, repeat)
+				}
+			}
+		}
+		for button, b := range m.Raw {
+			if fire, repeat := keyPressState(inpututil.GamepadButtonPressDuration(id, button), tps); fire {
+				pushGamepadKey(eventPusher, b, repeat)
+			}
+		}
+	}
+}
@@ -0,0 +1,110 @@
+//go:build !nogpu
+
+package window
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/hajimehoshi/ebiten/v2"
+)
+
+// testParseKey は vm.ParseKeySpec の代わりにテストで使うキー指定の解析
+// "CTRL+<文字>" と1文字のキーだけを受け付ける
+func testParseKey(spec string) (int, int, error) {
+	mods := 0
+	if len(spec) == len("CTRL+")+1 && spec[:len("CTRL+")] == "CTRL+" {
+		mods = modCtrl
+		spec = spec[len("CTRL+"):]
+	}
+	if len(spec) != 1 {
+		return 0, 0, fmt.Errorf("unknown key %q", spec)
+	}
+	return int(spec[0]), mods, nil
+}
+
+// recordingPusher は伝達されたイベントを記録する
+type recordingPusher struct {
+	chars []int
+	keys  []KeyBinding
+}
+
+func (p *recordingPusher) PushMouseEvent(eventType string, windowID, x, y int) {}
+
+func (p *recordingPusher) PushKeyEvent(eventType string, keyCode int) {
+	if eventType == "CHAR" {
+		p.chars = append(p.chars, keyCode)
+	}
+}
+
+func (p *recordingPusher) PushKeyInput(keyCode, modifiers int, repeat bool) {
+	p.keys = append(p.keys, KeyBinding{KeyCode: keyCode, Modifiers: modifiers})
+}
+
+func TestParseInputMap(t *testing.T) {
+	m, err := ParseInputMap([]byte(`{"buttons": {"a": "Z", "START": "1", "DPAD_UP": "U", "BUTTON5": "CTRL+S"}}`), testParseKey)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	wantStandard := map[ebiten.StandardGamepadButton]KeyBinding{
+		ebiten.StandardGamepadButtonRightBottom: {KeyCode: 'Z'},
+		ebiten.StandardGamepadButtonCenterRight: {KeyCode: '1'},
+		ebiten.StandardGamepadButtonLeftTop:     {KeyCode: 'U'},
+	}
+	if len(m.Standard) != len(wantStandard) {
+		t.Errorf("Standard = %v, want %v", m.Standard, wantStandard)
+	}
+	for button, want := range wantStandard {
+		if got := m.Standard[button]; got != want {
+			t.Errorf("Standard[%v] = %+v, want %+v", button, got, want)
+		}
+	}
+	if got := m.Raw[ebiten.GamepadButton5]; len(m.Raw) != 1 || got != (KeyBinding{KeyCode: 'S', Modifiers: modCtrl}) {
+		t.Errorf("Raw = %v, want button 5 -> Ctrl+S", m.Raw)
+	}
+}
+
+func TestParseInputMap_Errors(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+	}{
+		{"不正なJSON", `{"buttons": `},
+		{"ボタンなし", `{"buttons": {}}`},
+		{"未知のボタン", `{"buttons": {"TURBO": "A"}}`},
+		{"番号が範囲外", `{"buttons": {"BUTTON999": "A"}}`},
+		{"番号が負", `{"buttons": {"BUTTON-1": "A"}}`},
+		{"未知のキー", `{"buttons": {"A": "NOSUCHKEY"}}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := ParseInputMap([]byte(tt.data), testParseKey); err == nil {
+				t.Error("expected error, got nil")
+			}
+		})
+	}
+}
+
+func TestPushGamepadKey(t *testing.T) {
+	p := &recordingPusher{}
+	pushGamepadKey(p, KeyBinding{KeyCode: 'A'}, false)
+	pushGamepadKey(p, KeyBinding{KeyCode: 'A'}, true)
+	pushGamepadKey(p, KeyBinding{KeyCode: 'S', Modifiers: modCtrl}, false)
+	pushGamepadKey(p, KeyBinding{KeyCode: 0x0D}, false)
+
+	// 英字は修飾キーなしの押下のときだけ CHAR イベントも発生させる
+	if len(p.chars) != 1 || p.chars[0] != 'a' {
+		t.Errorf("CHAR events = %v, want [97]", p.chars)
+	}
+	if len(p.keys) != 4 {
+		t.Errorf("KEY events = %v, want 4 events", p.keys)
+	}
+}
+
+func TestProcessGamepadInputs_NilMap(t *testing.T) {
+	p := &recordingPusher{}
+	processGamepadInputs(nil, p)
+	if len(p.keys) != 0 || len(p.chars) != 0 {
+		t.Error("expected no events without an input map")
+	}
+}
@@ -0,0 +1,93 @@
+package window
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/zurustar/son-et/pkg/title"
+)
+
+// RunHeadless ヘッドレスモードでタイトル選択を実行
+func RunHeadless(titles []title.FillyTitle, timeout time.Duration, reader io.Reader, writer io.Writer) (*title.FillyTitle, error) {
+	// タイトルが1つの場合は自動選択
+	if len(titles) == 1 {
+		fmt.Fprintf(writer, "Auto-selecting title: %s\n", titles[0].Name)
+		return &titles[0], nil
+	}
+
+	// タイムアウト処理用のコンテキスト
+	ctx := context.Background()
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	// タイトル一覧を表示
+	fmt.Fprintln(writer, "Available FILLY Titles:")
+	for i, t := range titles {
+		fmt.Fprintf(writer, "  %d: %s\n", i+1, t.Name)
+	}
+	fmt.Fprintln(writer)
+
+	// 選択を受け付ける
+	scanner := bufio.NewScanner(reader)
+	resultCh := make(chan *title.FillyTitle)
+	errCh := make(chan error)
+
+	go func() {
+		for {
+			fmt.Fprint(writer, "Select a title (1-", len(titles), ") or 'q' to quit: ")
+			if !scanner.Scan() {
+				if err := scanner.Err(); err != nil {
+					errCh <- fmt.Errorf("failed to read input: %w", err)
+				} else {
+					errCh <- fmt.Errorf("input closed")
+				}
+				return
+			}
+
+			input := strings.TrimSpace(scanner.Text())
+
+			// 終了コマンド
+			if input == "q" || input == "Q" {
+				errCh <- fmt.Errorf("user cancelled")
+				return
+			}
+
+			// 数値に変換
+			num, err := strconv.Atoi(input)
+			if err != nil {
+				fmt.Fprintln(writer, "Invalid input. Please enter a number.")
+				continue
+			}
+
+			// 範囲チェック
+			if num < 1 || num > len(titles) {
+				fmt.Fprintf(writer, "Invalid selection. Please enter a number between 1 and %d.\n", len(titles))
+				continue
+			}
+
+			// 選択されたタイトルを返す
+			selected := &titles[num-1]
+			fmt.Fprintf(writer, "Selected: %s\n", selected.Name)
+			resultCh <- selected
+			return
+		}
+	}()
+
+	// タイムアウトまたは選択完了を待つ
+	select {
+	case <-ctx.Done():
+		return nil, fmt.Errorf("timeout")
+	case err := <-errCh:
+		return nil, err
+	case selected := <-resultCh:
+		return selected, nil
+	}
+}
@@ -0,0 +1,95 @@
+//go:build !nogpu
+
+package window
+
+import (
+	"time"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/inpututil"
+)
+
+// キーリピートのタイミング（現在のTPSでティック数に変換する）
+const (
+	keyRepeatDelay    = 500 * time.Millisecond // 押し続けてからリピートが始まるまで
+	keyRepeatInterval = 50 * time.Millisecond  // リピート間隔
+)
+
+// 修飾キーのビットマスク（vm.KeyModShift / vm.KeyModCtrl / vm.KeyModAlt と同じ値）
+const (
+	modShift = 1
+	modCtrl  = 2
+	modAlt   = 4
+)
+
+// keyInputs はKEYイベントとして通知するキーとWindows仮想キーコードの対応
+// Escキーはタイトル終了に使用するため含めない
+var keyInputs = []struct {
+	key  ebiten.Key
+	code int
+}{
+	{ebiten.KeyBackspace, 0x08}, {ebiten.KeyTab, 0x09}, {ebiten.KeyEnter, 0x0D}, {ebiten.KeySpace, 0x20},
+	{ebiten.KeyPageUp, 0x21}, {ebiten.KeyPageDown, 0x22}, {ebiten.KeyEnd, 0x23}, {ebiten.KeyHome, 0x24},
+	{ebiten.KeyArrowLeft, 0x25}, {ebiten.KeyArrowUp, 0x26}, {ebiten.KeyArrowRight, 0x27}, {ebiten.KeyArrowDown, 0x28},
+	{ebiten.KeyInsert, 0x2D}, {ebiten.KeyDelete, 0x2E},
+	{ebiten.KeyDigit0, 0x30}, {ebiten.KeyDigit1, 0x31}, {ebiten.KeyDigit2, 0x32}, {ebiten.KeyDigit3, 0x33},
+	{ebiten.KeyDigit4, 0x34}, {ebiten.KeyDigit5, 0x35}, {ebiten.KeyDigit6, 0x36}, {ebiten.KeyDigit7, 0x37},
+	{ebiten.KeyDigit8, 0x38}, {ebiten.KeyDigit9, 0x39},
+	{ebiten.KeyA, 0x41}, {ebiten.KeyB, 0x42}, {ebiten.KeyC, 0x43}, {ebiten.KeyD, 0x44},
+	{ebiten.KeyE, 0x45}, {ebiten.KeyF, 0x46}, {ebiten.KeyG, 0x47}, {ebiten.KeyH, 0x48},
+	{ebiten.KeyI, 0x49}, {ebiten.KeyJ, 0x4A}, {ebiten.KeyK, 0x4B}, {ebiten.KeyL, 0x4C},
+	{ebiten.KeyM, 0x4D}, {ebiten.KeyN, 0x4E}, {ebiten.KeyO, 0x4F}, {ebiten.KeyP, 0x50},
+	{ebiten.KeyQ, 0x51}, {ebiten.KeyR, 0x52}, {ebiten.KeyS, 0x53}, {ebiten.KeyT, 0x54},
+	{ebiten.KeyU, 0x55}, {ebiten.KeyV, 0x56}, {ebiten.KeyW, 0x57}, {ebiten.KeyX, 0x58},
+	{ebiten.KeyY, 0x59}, {ebiten.KeyZ, 0x5A},
+	{ebiten.KeyF1, 0x70}, {ebiten.KeyF2, 0x71}, {ebiten.KeyF3, 0x72}, {ebiten.KeyF4, 0x73},
+	{ebiten.KeyF5, 0x74}, {ebiten.KeyF6, 0x75}, {ebiten.KeyF7, 0x76}, {ebiten.KeyF8, 0x77},
+	{ebiten.KeyF9, 0x78}, {ebiten.KeyF10, 0x79}, {ebiten.KeyF11, 0x7A}, {ebiten.KeyF12, 0x7B},
+}
+
+// keyPressState はキーを押し続けているティック数から、このティックで入力を発生させるかを判定する
+// 押した瞬間は press、keyRepeatDelay を超えてからは keyRepeatInterval ごとに repeat となる
+// tps は現在のTPSで、TPSを変更してもリピートの実時間が変わらないようにする
+func keyPressState(duration, tps int) (fire, repeat bool) {
+	delay := ticksFor(keyRepeatDelay, tps)
+	interval := ticksFor(keyRepeatInterval, tps)
+	switch {
+	case duration == 1:
+		return true, false
+	case duration > delay && (duration-delay)%interval == 0:
+		return true, true
+	}
+	return false, false
+}
+
+// currentModifiers は現在押されている修飾キーのビットマスクを返す
+func currentModifiers() int {
+	modifiers := 0
+	if ebiten.IsKeyPressed(ebiten.KeyShift) {
+		modifiers |= modShift
+	}
+	if ebiten.IsKeyPressed(ebiten.KeyControl) {
+		modifiers |= modCtrl
+	}
+	if ebiten.IsKeyPressed(ebiten.KeyAlt) {
+		modifiers |= modAlt
+	}
+	return modifiers
+}
+
+// processKeyInputs はキーの押下（キーリピートを含む）をKEYイベントとしてVMに伝達する
+// OnKeyで登録されたハンドラは、このイベントのキーコード・修飾キー・リピート有無で絞り込まれる
+func processKeyInputs(eventPusher MouseEventPusher) {
+	tps := ebiten.TPS()
+	modifiers := -1
+	for _, k := range keyInputs {
+		fire, repeat := keyPressState(inpututil.KeyPressDuration(k.key), tps)
+		if !fire {
+			continue
+		}
+		if modifiers < 0 {
+			modifiers = currentModifiers()
+		}
+		eventPusher.PushKeyInput(k.code, modifiers, repeat)
+	}
+}
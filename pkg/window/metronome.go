@@ -0,0 +1,58 @@
+//go:build !nogpu
+
+package window
+
+import (
+	"image"
+	"image/color"
+
+	"github.com/hajimehoshi/ebiten/v2"
+)
+
+// メトロノームの拍の表示（画面左上の四角形）
+const (
+	metronomeIndicatorSize   = 16
+	metronomeIndicatorMargin = 8
+)
+
+var metronomeIndicatorColor = color.RGBA{0xFF, 0x40, 0x40, 0xFF}
+
+// MetronomeBeater defines the interface for the beat indicator (--metronome-flash)
+// This is used to decouple the window package from the vm package
+type MetronomeBeater interface {
+	// MetronomeBeat reports whether a quarter note of the MIDI clock has just been heard
+	MetronomeBeat() bool
+}
+
+// SetMetronomeFlash sets the source of the beat indicator, a square flashed in the
+// top-left corner on every quarter note of the MIDI playback. The indicator is not
+// included in recorded frames. Passing nil disables the indicator.
+func (g *Game) SetMetronomeFlash(beater MetronomeBeater) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.metronome = beater
+	g.metronomeShown = false
+}
+
+// drawMetronome は拍の直後に画面左上の四角形を表示する
+func (g *Game) drawMetronome(screen *ebiten.Image) {
+	g.mu.RLock()
+	beater := g.metronome
+	g.mu.RUnlock()
+	if beater == nil {
+		return
+	}
+
+	beat := beater.MetronomeBeat()
+	g.mu.Lock()
+	g.metronomeShown = beat
+	g.mu.Unlock()
+	if !beat {
+		return
+	}
+
+	bounds := screen.Bounds()
+	x, y := bounds.Min.X+metronomeIndicatorMargin, bounds.Min.Y+metronomeIndicatorMargin
+	box := image.Rect(x, y, x+metronomeIndicatorSize, y+metronomeIndicatorSize)
+	screen.SubImage(box).(*ebiten.Image).Fill(metronomeIndicatorColor)
+}
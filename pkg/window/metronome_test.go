@@ -0,0 +1,56 @@
+//go:build !nogpu
+
+package window
+
+import (
+	"testing"
+
+	"github.com/hajimehoshi/ebiten/v2"
+)
+
+// mockMetronome は設定された拍の状態を返す
+type mockMetronome struct {
+	beat bool
+}
+
+func (m *mockMetronome) MetronomeBeat() bool { return m.beat }
+
+func TestDrawMetronome(t *testing.T) {
+	game := NewGame(ModeDesktop, nil, 0)
+	game.SetGraphicsSystem(&mockRedrawGraphics{})
+	game.fps = 60
+	game.needsRedraw() // グラフィックスシステムを設定した直後の描画
+
+	metronome := &mockMetronome{}
+	game.SetMetronomeFlash(metronome)
+	if !game.overlayVisible() {
+		t.Error("expected the indicator to be drawn over the desktop")
+	}
+	if game.needsRedraw() {
+		t.Error("expected an unchanged scene without a beat to be skipped")
+	}
+
+	// 拍の表示が切り替わる時だけ描き直す
+	metronome.beat = true
+	if !game.needsRedraw() {
+		t.Error("expected the beat to be drawn")
+	}
+	screen := ebiten.NewImage(64, 64)
+	game.drawMetronome(screen)
+	if !game.metronomeShown {
+		t.Error("expected the beat to be recorded as shown")
+	}
+	if game.needsRedraw() {
+		t.Error("expected no redraw while the beat is shown")
+	}
+	metronome.beat = false
+	if !game.needsRedraw() {
+		t.Error("expected the end of the beat to be drawn")
+	}
+
+	game.SetMetronomeFlash(nil)
+	game.drawMetronome(screen) // no-op
+	if game.overlayVisible() {
+		t.Error("expected no overlay without the indicator")
+	}
+}
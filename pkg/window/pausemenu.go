@@ -0,0 +1,294 @@
+//go:build !nogpu
+
+package window
+
+import (
+	"image"
+	"image/color"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/inpututil"
+	"github.com/hajimehoshi/ebiten/v2/text/v2"
+	"github.com/zurustar/son-et/pkg/logger"
+)
+
+// ポーズメニュー（Esc キー）
+// Esc キーでシーンの上に Resume / Restart / Mute audio / Quit のメニューを開く。
+// 開いている間はタイトルを一時停止し（--pause-on-blur と同じく時間とオーディオを止める）、入力をスクリプトに渡さない。
+// プロジェクトマニフェストの pause_menu: off で無効にすると、Esc キーは従来どおり直ちにタイトルを終了する。
+
+// ポーズメニューの操作キー
+const (
+	pauseMenuKey       = ebiten.KeyEscape    // メニューを開く・閉じる（Resume と同じ）
+	pauseMenuUpKey     = ebiten.KeyArrowUp   // 上の項目を選ぶ
+	pauseMenuDownKey   = ebiten.KeyArrowDown // 下の項目を選ぶ
+	pauseMenuSelectKey = ebiten.KeyEnter     // 選んだ項目を実行する
+	pauseMenuSpaceKey  = ebiten.KeySpace     // 選んだ項目を実行する（Enter と同じ）
+)
+
+// ポーズメニューの表示
+const (
+	pauseMenuTitle   = "PAUSED"
+	pauseMenuPadding = 24 // パネルの内側の余白
+	pauseMenuRowGap  = 12 // 項目の間隔
+)
+
+// ポーズメニューの既定の色（プロジェクトマニフェストで変更できる）
+var (
+	pauseMenuDimColor        = color.RGBA{0x00, 0x00, 0x00, 0x80} // シーンを暗くする色
+	pauseMenuBackground      = color.RGBA{0x00, 0x00, 0x00, 0xD0}
+	pauseMenuTextColor       = color.RGBA{0xFF, 0xFF, 0xFF, 0xFF}
+	pauseMenuSelectedColor   = color.RGBA{0xFF, 0xFF, 0x00, 0xFF}
+	pauseMenuUnavailableText = color.RGBA{0x80, 0x80, 0x80, 0xFF}
+)
+
+// pauseMenuAction はポーズメニューの項目
+type pauseMenuAction int
+
+const (
+	pauseMenuNone pauseMenuAction = iota
+	pauseMenuResume
+	pauseMenuRestart
+	pauseMenuMute
+	pauseMenuQuit
+)
+
+// pauseMenuItems はメニューの項目（表示順）
+var pauseMenuItems = []pauseMenuAction{pauseMenuResume, pauseMenuRestart, pauseMenuMute, pauseMenuQuit}
+
+// PauseMenuTarget defines the interface for pausing and muting the title from the pause menu
+// This is used to decouple the window package from the vm package
+type PauseMenuTarget interface {
+	Pause()
+	Resume()
+	SetMuted(muted bool)
+}
+
+// PauseMenuStyle is the configuration of the pause menu (pause_menu keys of the project manifest).
+// Nil colors use the defaults.
+type PauseMenuStyle struct {
+	Disabled   bool        // Esc quits the title at once instead of opening the menu
+	Background color.Color // background of the menu panel
+	Selected   color.Color // text of the selected item
+}
+
+// SetPauseMenuTarget sets the title paused and muted by the pause menu (Esc opens it).
+// Audio muted from the menu stays muted for the next title.
+// Passing nil disables the pause menu.
+func (g *Game) SetPauseMenuTarget(target PauseMenuTarget) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.pauseTarget = target
+	g.pauseMenuOpen = false
+	if target != nil && g.pauseMuted {
+		target.SetMuted(true)
+	}
+}
+
+// SetPauseMenuStyle sets the configuration of the pause menu of the running title.
+func (g *Game) SetPauseMenuStyle(style PauseMenuStyle) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.pauseMenuStyle = style
+}
+
+// updatePauseMenu はポーズメニューの操作を処理する
+// メニューを表示している間と、開いた・閉じたフレームは true を返す（Escキーや入力をスクリプトに渡さない）。
+// Restart と Quit はロックを解放してから呼び出し元が実行するため、選んだ項目として返す。
+func (g *Game) updatePauseMenu() (bool, pauseMenuAction) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.pauseTarget == nil || g.pauseMenuStyle.Disabled {
+		return false, pauseMenuNone
+	}
+
+	if !g.pauseMenuOpen {
+		// 終了処理（OnExit）の実行中は Esc キーで直ちに終了する
+		if g.exiting || !inpututil.IsKeyJustPressed(pauseMenuKey) {
+			return false, pauseMenuNone
+		}
+		g.pauseMenuOpen = true
+		g.pauseSelected = 0
+		g.pauseTarget.Pause()
+		g.forceRedraw = true
+		return true, pauseMenuNone
+	}
+
+	switch {
+	case inpututil.IsKeyJustPressed(pauseMenuKey):
+		g.closePauseMenu()
+		return true, pauseMenuNone
+	case inpututil.IsKeyJustPressed(pauseMenuUpKey):
+		g.movePauseSelection(-1)
+	case inpututil.IsKeyJustPressed(pauseMenuDownKey):
+		g.movePauseSelection(1)
+	case inpututil.IsKeyJustPressed(pauseMenuSelectKey), inpututil.IsKeyJustPressed(pauseMenuSpaceKey):
+		return true, g.selectPauseMenuItem()
+	}
+	return true, pauseMenuNone
+}
+
+// movePauseSelection は選択中の項目を direction の向きに移動する（端では反対側に回り込み、選べない項目は飛ばす）
+// 呼び出し元は g.mu のロックを保持していること
+func (g *Game) movePauseSelection(direction int) {
+	n := len(pauseMenuItems)
+	for range n {
+		g.pauseSelected = (g.pauseSelected + direction + n) % n
+		if g.pauseMenuItemAvailable(pauseMenuItems[g.pauseSelected]) {
+			break
+		}
+	}
+	g.forceRedraw = true
+}
+
+// selectPauseMenuItem は選択中の項目を実行する
+// Resume と Mute audio はここで実行し、Restart と Quit は閉じたメニューの項目として返す
+// 呼び出し元は g.mu のロックを保持していること
+func (g *Game) selectPauseMenuItem() pauseMenuAction {
+	action := pauseMenuItems[g.pauseSelected]
+	switch action {
+	case pauseMenuResume:
+		g.closePauseMenu()
+		return pauseMenuNone
+	case pauseMenuMute:
+		g.pauseMuted = !g.pauseMuted
+		g.pauseTarget.SetMuted(g.pauseMuted)
+		g.forceRedraw = true
+		logger.GetLogger().Info("Audio muted from the pause menu", "muted", g.pauseMuted)
+		return pauseMenuNone
+	case pauseMenuQuit:
+		// 終了処理（OnExit）はタイトルの時間を使うため、再開してから終了する
+		g.closePauseMenu()
+		return action
+	}
+	// Restart はタイトルを停止して読み込み直すため、一時停止は解除しない
+	g.pauseMenuOpen = false
+	g.forceRedraw = true
+	return action
+}
+
+// closePauseMenu はメニューを閉じてタイトルを再開する
+// フォーカス喪失で一時停止している場合は、フォーカスが戻るまで再開しない
+// 呼び出し元は g.mu のロックを保持していること
+func (g *Game) closePauseMenu() {
+	g.pauseMenuOpen = false
+	g.forceRedraw = true
+	if !g.focusPaused {
+		g.pauseTarget.Resume()
+	}
+}
+
+// pauseMenuItemAvailable は項目を選べるかを返す（Restart はタイトル選択画面から選んだタイトルのみ）
+// 呼び出し元は g.mu のロックを保持していること
+func (g *Game) pauseMenuItemAvailable(action pauseMenuAction) bool {
+	if action == pauseMenuRestart {
+		return g.selectedTitle != nil && g.onTitleSelected != nil
+	}
+	return true
+}
+
+// runPauseMenuAction はメニューで選んだ Restart と Quit を実行する
+func (g *Game) runPauseMenuAction(action pauseMenuAction) error {
+	switch action {
+	case pauseMenuRestart:
+		if err := g.reloadTitle(); err != nil {
+			logger.GetLogger().Warn("Failed to restart the title", "error", err)
+		}
+	case pauseMenuQuit:
+		return g.quitTitle()
+	}
+	return nil
+}
+
+// pauseMenuLabel は項目の表示名を返す
+func (g *Game) pauseMenuLabel(action pauseMenuAction) string {
+	switch action {
+	case pauseMenuResume:
+		return "Resume"
+	case pauseMenuRestart:
+		return "Restart"
+	case pauseMenuMute:
+		if g.pauseMuted {
+			return "Unmute audio"
+		}
+		return "Mute audio"
+	case pauseMenuQuit:
+		return "Quit"
+	}
+	return ""
+}
+
+// drawPauseMenu はシーンを暗くし、画面中央にポーズメニューを描画する
+func (g *Game) drawPauseMenu(screen *ebiten.Image) {
+	g.mu.RLock()
+	if !g.pauseMenuOpen || g.pauseTarget == nil {
+		g.mu.RUnlock()
+		return
+	}
+	style := g.pauseMenuStyle
+	selected := g.pauseSelected
+	labels := make([]string, len(pauseMenuItems))
+	available := make([]bool, len(pauseMenuItems))
+	for i, action := range pauseMenuItems {
+		labels[i] = g.pauseMenuLabel(action)
+		available[i] = g.pauseMenuItemAvailable(action)
+	}
+	g.mu.RUnlock()
+
+	background := color.Color(pauseMenuBackground)
+	if style.Background != nil {
+		background = style.Background
+	}
+	selectedColor := color.Color(pauseMenuSelectedColor)
+	if style.Selected != nil {
+		selectedColor = style.Selected
+	}
+
+	bounds := screen.Bounds()
+	fillRect(screen, bounds, pauseMenuDimColor)
+
+	_, lineHeight := text.Measure("M", defaultFace, 0)
+	rowHeight := int(lineHeight) + pauseMenuRowGap
+	width := 0
+	for _, line := range append([]string{pauseMenuTitle}, labels...) {
+		w, _ := text.Measure("> "+line, defaultFace, 0)
+		width = max(width, int(w))
+	}
+	panelW := width + pauseMenuPadding*2
+	panelH := (len(labels)+1)*rowHeight + pauseMenuRowGap + pauseMenuPadding*2
+	cx, cy := bounds.Min.X+bounds.Dx()/2, bounds.Min.Y+bounds.Dy()/2
+	panel := image.Rect(cx-panelW/2, cy-panelH/2, cx-panelW/2+panelW, cy-panelH/2+panelH)
+	fillRect(screen, panel, background)
+
+	y := panel.Min.Y + pauseMenuPadding
+	drawLine := func(line string, c color.Color) {
+		op := &text.DrawOptions{}
+		op.GeoM.Translate(float64(panel.Min.X+pauseMenuPadding), float64(y))
+		op.ColorScale.ScaleWithColor(c)
+		text.Draw(screen, line, defaultFace, op)
+		y += rowHeight
+	}
+	drawLine(pauseMenuTitle, pauseMenuTextColor)
+	y += pauseMenuRowGap
+	for i, label := range labels {
+		switch {
+		case !available[i]:
+			drawLine("  "+label, pauseMenuUnavailableText)
+		case i == selected:
+			drawLine("> "+label, selectedColor)
+		default:
+			drawLine("  "+label, pauseMenuTextColor)
+		}
+	}
+}
+
+// fillRect は screen の矩形 r を半透明の色 c で塗る（下の画像と合成する）
+func fillRect(screen *ebiten.Image, r image.Rectangle, c color.Color) {
+	pixel := ebiten.NewImage(1, 1)
+	defer pixel.Deallocate()
+	pixel.Fill(c)
+	op := &ebiten.DrawImageOptions{}
+	op.GeoM.Scale(float64(r.Dx()), float64(r.Dy()))
+	op.GeoM.Translate(float64(r.Min.X), float64(r.Min.Y))
+	screen.DrawImage(pixel, op)
+}
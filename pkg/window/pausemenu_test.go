@@ -0,0 +1,137 @@
+//go:build !nogpu
+
+package window
+
+import (
+	"testing"
+
+	"github.com/zurustar/son-et/pkg/title"
+)
+
+// mockPauseTarget は一時停止とミュートの状態を記録する
+type mockPauseTarget struct {
+	paused  bool
+	resumes int
+	muted   bool
+}
+
+func (m *mockPauseTarget) Pause() { m.paused = true }
+func (m *mockPauseTarget) Resume() {
+	m.paused = false
+	m.resumes++
+}
+func (m *mockPauseTarget) SetMuted(muted bool) { m.muted = muted }
+
+// openPauseMenu はEscキーを押したときと同じようにメニューを開く
+func openPauseMenu(g *Game) {
+	g.pauseMenuOpen = true
+	g.pauseSelected = 0
+	g.pauseTarget.Pause()
+}
+
+func TestPauseMenu_MoveSelection(t *testing.T) {
+	game := NewGame(ModeDesktop, nil, 0)
+	game.SetPauseMenuTarget(&mockPauseTarget{})
+
+	// 単一タイトルの実行では Restart を飛ばす
+	game.movePauseSelection(1)
+	if got := pauseMenuItems[game.pauseSelected]; got != pauseMenuMute {
+		t.Errorf("after down from Resume = %v, want Mute (Restart unavailable)", got)
+	}
+	game.movePauseSelection(-1)
+	game.movePauseSelection(-1)
+	if got := pauseMenuItems[game.pauseSelected]; got != pauseMenuQuit {
+		t.Errorf("after up from Resume = %v, want Quit (wrap around)", got)
+	}
+
+	// タイトル選択画面から選んだタイトルは Restart を選べる
+	game.selectedTitle = &title.FillyTitle{Name: "a"}
+	game.onTitleSelected = func(*title.FillyTitle) error { return nil }
+	game.pauseSelected = 0
+	game.movePauseSelection(1)
+	if got := pauseMenuItems[game.pauseSelected]; got != pauseMenuRestart {
+		t.Errorf("after down from Resume = %v, want Restart", got)
+	}
+}
+
+func TestPauseMenu_Select(t *testing.T) {
+	target := &mockPauseTarget{}
+	game := NewGame(ModeDesktop, nil, 0)
+	game.SetPauseMenuTarget(target)
+
+	openPauseMenu(game)
+	if action := game.selectPauseMenuItem(); action != pauseMenuNone || game.pauseMenuOpen || target.paused {
+		t.Errorf("Resume: action %v, open %v, paused %v; want the menu closed and the title resumed", action, game.pauseMenuOpen, target.paused)
+	}
+
+	// Mute audio はメニューを開いたまま切り替える
+	openPauseMenu(game)
+	game.pauseSelected = 2
+	game.selectPauseMenuItem()
+	if !target.muted || !game.pauseMenuOpen || game.pauseMenuLabel(pauseMenuMute) != "Unmute audio" {
+		t.Errorf("Mute: muted %v, open %v; want muted with the menu open", target.muted, game.pauseMenuOpen)
+	}
+	game.selectPauseMenuItem()
+	if target.muted {
+		t.Error("selecting Unmute audio should unmute")
+	}
+
+	// Quit は再開してから終了処理に渡す（終了処理はタイトルの時間を使う）
+	game.pauseSelected = 3
+	if action := game.selectPauseMenuItem(); action != pauseMenuQuit || target.paused {
+		t.Errorf("Quit: action %v, paused %v; want Quit with the title resumed", action, target.paused)
+	}
+
+	// Restart は一時停止したまま読み込み直す
+	openPauseMenu(game)
+	game.pauseSelected = 1
+	if action := game.selectPauseMenuItem(); action != pauseMenuRestart || !target.paused || game.pauseMenuOpen {
+		t.Errorf("Restart: action %v, paused %v, open %v; want Restart with the title paused", action, target.paused, game.pauseMenuOpen)
+	}
+}
+
+func TestPauseMenu_FocusPause(t *testing.T) {
+	target := &mockPauseTarget{}
+	focused := true
+	game := NewGame(ModeDesktop, nil, 0)
+	game.isFocused = func() bool { return focused }
+	game.SetFocusPauser(target)
+	game.SetPauseMenuTarget(target)
+
+	// メニューを表示中にフォーカスが戻っても再開しない
+	openPauseMenu(game)
+	focused = false
+	game.updateFocusPause()
+	focused = true
+	game.updateFocusPause()
+	if !target.paused {
+		t.Error("the title should stay paused while the pause menu is open")
+	}
+
+	// フォーカスがない間にメニューを閉じても再開しない
+	focused = false
+	game.updateFocusPause()
+	game.closePauseMenu()
+	if !target.paused {
+		t.Error("closing the menu should not resume while the window is unfocused")
+	}
+	focused = true
+	game.updateFocusPause()
+	if target.paused {
+		t.Error("the title should resume when the focus returns")
+	}
+}
+
+func TestSetPauseMenuTarget_KeepsMute(t *testing.T) {
+	game := NewGame(ModeDesktop, nil, 0)
+	game.SetPauseMenuTarget(&mockPauseTarget{})
+	game.pauseSelected = 2
+	game.selectPauseMenuItem()
+
+	// メニューでミュートした状態は次のタイトルに引き継ぐ
+	next := &mockPauseTarget{}
+	game.SetPauseMenuTarget(next)
+	if !next.muted {
+		t.Error("audio muted from the pause menu should stay muted for the next title")
+	}
+}
@@ -0,0 +1,53 @@
+package window
+
+import (
+	"image"
+	"time"
+)
+
+// 省電力モード（--power-save）
+//
+// 仮想デスクトップの内容が変わらず（ダーティトラッキング）入力もない間は TPS を
+// powerSaveIdleTPS まで下げ、Update（入力の受け渡しと描画コマンドの処理）で CPU を起こす回数を減らす。
+// 変化や入力があれば次の Update で元の TPS に戻す。バッテリー駆動のキオスクで、
+// 静止したスライドショーの区間を低い CPU 使用率で表示するためのもの。
+
+// 省電力モードの設定
+const (
+	powerSaveIdleTPS   = 10                     // 静止中の TPS
+	powerSaveIdleDelay = 500 * time.Millisecond // 変化がなくなってから TPS を下げるまでの時間
+)
+
+// powerSaver は省電力モードの状態
+type powerSaver struct {
+	lastActive time.Time   // 最後に画面の変化や入力があった時刻（ゼロの場合はまだ記録していない）
+	idle       bool        // TPS を下げているかどうか
+	cursor     image.Point // 前回のフレームのマウスカーソルの位置
+}
+
+// update は now の時点のフレームを記録し、TPS を下げるべきかを返す
+// active はこのフレームに画面の変化や入力があったかどうか
+// changed は前回の呼び出しから結果が変わった（TPS を設定し直す必要がある）かどうか
+func (p *powerSaver) update(now time.Time, active bool) (idle, changed bool) {
+	if active || p.lastActive.IsZero() {
+		p.lastActive = now
+	}
+	idle = now.Sub(p.lastActive) >= powerSaveIdleDelay
+	changed = idle != p.idle
+	p.idle = idle
+	return idle, changed
+}
+
+// wake は TPS が元に戻されたときに状態を初期化する（SetFrameRate から呼び出す）
+func (p *powerSaver) wake() {
+	p.lastActive = time.Time{}
+	p.idle = false
+}
+
+// moveCursor はマウスカーソルの位置を記録し、前回のフレームから動いたかを返す
+func (p *powerSaver) moveCursor(x, y int) bool {
+	pos := image.Pt(x, y)
+	moved := pos != p.cursor
+	p.cursor = pos
+	return moved
+}
@@ -0,0 +1,69 @@
+package window
+
+import (
+	"testing"
+	"time"
+)
+
+// TestPowerSaverIdle tests that the TPS is lowered only after the scene has been
+// static for powerSaveIdleDelay, and restored on the first change.
+func TestPowerSaverIdle(t *testing.T) {
+	var p powerSaver
+	start := time.Now()
+	step := powerSaveIdleDelay / 5
+
+	if idle, changed := p.update(start, false); idle || changed {
+		t.Fatalf("first frame: idle=%v changed=%v, want active", idle, changed)
+	}
+	for i := 1; i < 5; i++ {
+		if idle, _ := p.update(start.Add(time.Duration(i)*step), false); idle {
+			t.Fatalf("idle after %v, want after %v", time.Duration(i)*step, powerSaveIdleDelay)
+		}
+	}
+	if idle, changed := p.update(start.Add(powerSaveIdleDelay), false); !idle || !changed {
+		t.Fatalf("after the delay: idle=%v changed=%v, want idle and changed", idle, changed)
+	}
+	if idle, changed := p.update(start.Add(2*powerSaveIdleDelay), false); !idle || changed {
+		t.Fatalf("still static: idle=%v changed=%v, want idle and unchanged", idle, changed)
+	}
+
+	// 変化があれば直ちに元の TPS に戻し、再び待ってから下げる
+	now := start.Add(3 * powerSaveIdleDelay)
+	if idle, changed := p.update(now, true); idle || !changed {
+		t.Fatalf("on change: idle=%v changed=%v, want active and changed", idle, changed)
+	}
+	if idle, _ := p.update(now.Add(step), false); idle {
+		t.Error("idle right after a change")
+	}
+}
+
+// TestPowerSaverWake tests that wake restarts the wait after the TPS is reset.
+func TestPowerSaverWake(t *testing.T) {
+	var p powerSaver
+	start := time.Now()
+	p.update(start, false)
+	p.update(start.Add(powerSaveIdleDelay), false)
+
+	p.wake()
+	now := start.Add(2 * powerSaveIdleDelay)
+	if idle, changed := p.update(now, false); idle || changed {
+		t.Errorf("after wake: idle=%v changed=%v, want active and unchanged", idle, changed)
+	}
+	if idle, changed := p.update(now.Add(powerSaveIdleDelay), false); !idle || !changed {
+		t.Errorf("after wake and the delay: idle=%v changed=%v, want idle and changed", idle, changed)
+	}
+}
+
+// TestPowerSaverMoveCursor tests the detection of mouse cursor movement.
+func TestPowerSaverMoveCursor(t *testing.T) {
+	var p powerSaver
+	if !p.moveCursor(10, 20) {
+		t.Error("moving from the origin should be reported")
+	}
+	if p.moveCursor(10, 20) {
+		t.Error("a cursor that did not move should not be reported")
+	}
+	if !p.moveCursor(11, 20) {
+		t.Error("moving by one pixel should be reported")
+	}
+}
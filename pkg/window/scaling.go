@@ -0,0 +1,230 @@
+//go:build !nogpu
+
+package window
+
+import (
+	"fmt"
+	"image/color"
+	"math"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/zurustar/son-et/pkg/logger"
+)
+
+// ScaleMode は仮想デスクトップをウィンドウ（高DPIの画面では物理ピクセル）に拡大する方法
+type ScaleMode int
+
+const (
+	// ScaleAuto は Ebitengine の既定の拡大（整数倍はそのまま、それ以外はピクセルを保ったまま補間する）
+	ScaleAuto ScaleMode = iota
+	// ScaleCrisp は整数倍の最近傍補間で拡大し、余白は黒帯にする（ドット絵をぼかさない）
+	ScaleCrisp
+	// ScaleSmooth は常に線形補間でウィンドウいっぱいに拡大する
+	ScaleSmooth
+)
+
+// scaleModeNames は --scale-mode で指定できる名前
+var scaleModeNames = map[string]ScaleMode{
+	"auto":   ScaleAuto,
+	"crisp":  ScaleCrisp,
+	"smooth": ScaleSmooth,
+}
+
+// ParseScaleMode は --scale-mode の値を解析する
+func ParseScaleMode(s string) (ScaleMode, error) {
+	if s == "" {
+		return ScaleAuto, nil
+	}
+	mode, ok := scaleModeNames[s]
+	if !ok {
+		return ScaleAuto, fmt.Errorf("invalid scale mode: %s (must be auto, crisp, or smooth)", s)
+	}
+	return mode, nil
+}
+
+// String はスケールモードの名前を返す
+func (m ScaleMode) String() string {
+	for name, mode := range scaleModeNames {
+		if mode == m {
+			return name
+		}
+	}
+	return fmt.Sprintf("ScaleMode(%d)", int(m))
+}
+
+// DisplayScaleReceiver is implemented by graphics systems that let scripts query
+// the effective display scale (physical pixels per virtual desktop pixel).
+// Game reports the scale whenever it changes, e.g. when the window is resized
+// or moved to a monitor with another device scale factor.
+type DisplayScaleReceiver interface {
+	SetDisplayScale(scale float64)
+}
+
+// HiresFrameProvider is implemented by graphics systems that draw the scene at
+// a multiple of the virtual desktop resolution with @2x assets.
+// HiresFrame returns the frame drawn by the last Draw, or nil when there is none.
+// Game draws it over the virtual desktop on the final screen, so that remastered
+// assets stay sharp on high-DPI displays.
+type HiresFrameProvider interface {
+	HiresFrame() *ebiten.Image
+}
+
+// screenTransform は仮想デスクトップを最終的な画面（物理ピクセル）に描く変換
+type screenTransform struct {
+	scale            float64
+	offsetX, offsetY float64
+}
+
+// apply は仮想デスクトップの座標を画面の座標に変換する
+func (t screenTransform) apply(x, y float64) (float64, float64) {
+	return x*t.scale + t.offsetX, y*t.scale + t.offsetY
+}
+
+// inverse は画面の座標を仮想デスクトップの座標に変換する
+func (t screenTransform) inverse(x, y float64) (float64, float64) {
+	return (x - t.offsetX) / t.scale, (y - t.offsetY) / t.scale
+}
+
+// crispTransform は screenW x screenH の画面に offW x offH の画像を整数倍で中央に描く変換を返す
+// 画面が画像より小さく整数倍にできない場合は ok=false を返す
+func crispTransform(screenW, screenH, offW, offH int) (t screenTransform, ok bool) {
+	if offW <= 0 || offH <= 0 {
+		return screenTransform{}, false
+	}
+	scale := min(screenW/offW, screenH/offH)
+	if scale < 1 {
+		return screenTransform{}, false
+	}
+	return screenTransform{
+		scale:   float64(scale),
+		offsetX: float64((screenW - offW*scale) / 2),
+		offsetY: float64((screenH - offH*scale) / 2),
+	}, true
+}
+
+// SetScaleMode sets how the virtual desktop is scaled to the window (--scale-mode)
+func (g *Game) SetScaleMode(mode ScaleMode) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.scaleMode = mode
+}
+
+// DrawFinalScreen implements ebiten.FinalScreenDrawer.
+// It draws the virtual desktop with the filter of the scale mode and reports the
+// effective scale to the graphics system.
+func (g *Game) DrawFinalScreen(screen ebiten.FinalScreen, offscreen *ebiten.Image, geoM ebiten.GeoM) {
+	g.mu.RLock()
+	mode := g.scaleMode
+	hires := g.hiresFrame
+	g.mu.RUnlock()
+
+	fit := screenTransform{scale: geoM.Element(0, 0), offsetX: geoM.Element(0, 2), offsetY: geoM.Element(1, 2)}
+	drawn := fit
+
+	switch mode {
+	case ScaleCrisp:
+		b := screen.Bounds()
+		ob := offscreen.Bounds()
+		if t, ok := crispTransform(b.Dx(), b.Dy(), ob.Dx(), ob.Dy()); ok {
+			drawn = t
+			screen.Fill(color.Black)
+			op := &ebiten.DrawImageOptions{Filter: ebiten.FilterNearest}
+			op.GeoM.Scale(t.scale, t.scale)
+			op.GeoM.Translate(t.offsetX, t.offsetY)
+			screen.DrawImage(offscreen, op)
+		} else {
+			ebiten.DefaultDrawFinalScreen(screen, offscreen, geoM)
+		}
+	case ScaleSmooth:
+		op := &ebiten.DrawImageOptions{Filter: ebiten.FilterLinear}
+		op.GeoM = geoM
+		screen.DrawImage(offscreen, op)
+	default:
+		ebiten.DefaultDrawFinalScreen(screen, offscreen, geoM)
+	}
+	if hires != nil {
+		drawHiresFrame(screen, hires, offscreen, drawn)
+	}
+
+	g.setScreenTransform(mode, fit, drawn)
+}
+
+// updateHiresFrame は最終的な画面に重ねる @2x の画面を選ぶ（Draw で描画した後に呼び出す）
+// ゲームループが仮想デスクトップの上に表示を描いている場合は、その表示が隠れないよう重ねない
+func (g *Game) updateHiresFrame() {
+	g.mu.RLock()
+	provider, ok := g.graphicsSystem.(HiresFrameProvider)
+	desktop := g.mode == ModeDesktop
+	g.mu.RUnlock()
+
+	var frame *ebiten.Image
+	if ok && desktop && !g.overlayVisible() {
+		frame = provider.HiresFrame()
+	}
+	g.mu.Lock()
+	g.hiresFrame = frame
+	g.mu.Unlock()
+}
+
+// overlayVisible はゲームループが仮想デスクトップの上に描く表示（時間スケール、メトロノーム、A/Vオフセットの調整画面、
+// ウォッチパネル、ポーズメニュー、コンソール）があるかを返す
+func (g *Game) overlayVisible() bool {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	return (g.timeScaler != nil && g.timeScale != 1) ||
+		g.metronome != nil ||
+		(g.avCalibrating && g.avCalibrator != nil) ||
+		(g.watching && g.watcher != nil) ||
+		(g.pauseMenuOpen && g.pauseTarget != nil) ||
+		(g.consoleOpen && g.commandRunner != nil)
+}
+
+// drawHiresFrame は @2x の画面を、仮想デスクトップを描いた変換（drawn）と同じ位置と大きさで画面に描く
+func drawHiresFrame(screen ebiten.FinalScreen, frame, offscreen *ebiten.Image, drawn screenTransform) {
+	scale := drawn.scale * float64(offscreen.Bounds().Dx()) / float64(frame.Bounds().Dx())
+	op := &ebiten.DrawImageOptions{Filter: hiresFilter(scale)}
+	op.GeoM.Scale(scale, scale)
+	op.GeoM.Translate(drawn.offsetX, drawn.offsetY)
+	screen.DrawImage(frame, op)
+}
+
+// hiresFilter は @2x の画面を scale 倍で描く補間の方法を返す
+// 整数倍の場合はピクセルをそのまま拡大し、それ以外は線形補間で滑らかにする
+func hiresFilter(scale float64) ebiten.Filter {
+	if scale >= 1 && scale == math.Trunc(scale) {
+		return ebiten.FilterNearest
+	}
+	return ebiten.FilterLinear
+}
+
+// setScreenTransform は最終的な画面への変換を記録し、実効スケールが変わったら
+// （グラフィックスシステムが替わった場合を含む）グラフィックスシステムに通知する
+func (g *Game) setScreenTransform(mode ScaleMode, fit, drawn screenTransform) {
+	g.mu.Lock()
+	changed := drawn.scale != g.drawnTransform.scale
+	g.fitTransform, g.drawnTransform = fit, drawn
+	receiver, _ := g.graphicsSystem.(DisplayScaleReceiver)
+	g.mu.Unlock()
+
+	if !changed {
+		return
+	}
+	logger.GetLogger().Info("Display scale changed", "scale", drawn.scale, "mode", mode)
+	if receiver != nil {
+		receiver.SetDisplayScale(drawn.scale)
+	}
+}
+
+// correctCursor は Ebitengine が既定の拡大を前提に求めたカーソル位置を、
+// 実際に描いた変換（整数倍の拡大では余白が異なる）での位置に直す
+func (g *Game) correctCursor(x, y int) (int, int) {
+	g.mu.RLock()
+	fit, drawn := g.fitTransform, g.drawnTransform
+	g.mu.RUnlock()
+	if fit.scale == 0 || drawn.scale == 0 || fit == drawn {
+		return x, y
+	}
+	sx, sy := fit.apply(float64(x), float64(y))
+	vx, vy := drawn.inverse(sx, sy)
+	return int(math.Floor(vx)), int(math.Floor(vy))
+}
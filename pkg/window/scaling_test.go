@@ -0,0 +1,152 @@
+//go:build !nogpu
+
+package window
+
+import (
+	"testing"
+
+	"github.com/hajimehoshi/ebiten/v2"
+)
+
+func TestParseScaleMode(t *testing.T) {
+	for s, want := range map[string]ScaleMode{"": ScaleAuto, "auto": ScaleAuto, "crisp": ScaleCrisp, "smooth": ScaleSmooth} {
+		got, err := ParseScaleMode(s)
+		if err != nil || got != want {
+			t.Errorf("ParseScaleMode(%q) = %v, %v; want %v", s, got, err, want)
+		}
+	}
+	if _, err := ParseScaleMode("sharp"); err == nil {
+		t.Error("expected error for an invalid scale mode")
+	}
+	if got := ScaleCrisp.String(); got != "crisp" {
+		t.Errorf("ScaleCrisp.String() = %q, want crisp", got)
+	}
+}
+
+func TestCrispTransform(t *testing.T) {
+	tests := []struct {
+		name           string
+		screenW        int
+		screenH        int
+		want           screenTransform
+		wantIntegerFit bool
+	}{
+		{"same size", 1024, 768, screenTransform{scale: 1}, true},
+		{"2x high-DPI", 2048, 1536, screenTransform{scale: 2}, true},
+		{"non-integer window is letterboxed", 1920, 1080, screenTransform{scale: 1, offsetX: 448, offsetY: 156}, true},
+		{"wide 2x window", 2560, 1600, screenTransform{scale: 2, offsetX: 256, offsetY: 32}, true},
+		{"smaller than the desktop", 800, 600, screenTransform{}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := crispTransform(tt.screenW, tt.screenH, 1024, 768)
+			if ok != tt.wantIntegerFit || got != tt.want {
+				t.Errorf("crispTransform = %+v, %v; want %+v, %v", got, ok, tt.want, tt.wantIntegerFit)
+			}
+		})
+	}
+}
+
+func TestCorrectCursor(t *testing.T) {
+	g := NewGame(ModeDesktop, nil, 0)
+
+	// まだ描画していない場合や既定の拡大の場合はそのまま
+	if x, y := g.correctCursor(100, 200); x != 100 || y != 200 {
+		t.Errorf("correctCursor before drawing = (%d, %d), want (100, 200)", x, y)
+	}
+
+	// 1920x1080 の画面: 既定の拡大は 1.40625 倍で横に余白、整数倍の拡大は等倍で中央
+	g.setScreenTransform(ScaleCrisp,
+		screenTransform{scale: 1.40625, offsetX: 240},
+		screenTransform{scale: 1, offsetX: 448, offsetY: 156})
+
+	// 画面の中央は仮想デスクトップの中央
+	if x, y := g.correctCursor(512, 384); x != 512 || y != 384 {
+		t.Errorf("correctCursor(center) = (%d, %d), want (512, 384)", x, y)
+	}
+	// 整数倍で描いた仮想デスクトップの左上（画面の (448, 156)）
+	if x, y := g.correctCursor(148, 111); x != 0 || y != 0 {
+		t.Errorf("correctCursor(top-left) = (%d, %d), want (0, 0)", x, y)
+	}
+}
+
+// fakeScaleReceiver はグラフィックスシステムの代わりに通知された実効スケールを記録する
+type fakeScaleReceiver struct {
+	mockRedrawGraphics
+	scales []float64
+}
+
+func (f *fakeScaleReceiver) SetDisplayScale(scale float64) {
+	f.scales = append(f.scales, scale)
+}
+
+func TestSetScreenTransformReportsScale(t *testing.T) {
+	g := NewGame(ModeDesktop, nil, 0)
+	receiver := &fakeScaleReceiver{}
+	g.SetGraphicsSystem(receiver)
+
+	fit := screenTransform{scale: 2}
+	g.setScreenTransform(ScaleAuto, fit, fit)
+	g.setScreenTransform(ScaleAuto, fit, fit)
+	g.setScreenTransform(ScaleAuto, screenTransform{scale: 1.5}, screenTransform{scale: 1.5})
+	if len(receiver.scales) != 2 || receiver.scales[0] != 2 || receiver.scales[1] != 1.5 {
+		t.Errorf("reported scales = %v, want [2 1.5] (only changes)", receiver.scales)
+	}
+
+	// グラフィックスシステムが替わった場合は同じスケールでも通知する
+	next := &fakeScaleReceiver{}
+	g.SetGraphicsSystem(next)
+	g.setScreenTransform(ScaleAuto, screenTransform{scale: 1.5}, screenTransform{scale: 1.5})
+	if len(next.scales) != 1 || next.scales[0] != 1.5 {
+		t.Errorf("reported scales to the new graphics system = %v, want [1.5]", next.scales)
+	}
+}
+
+func TestHiresFilter(t *testing.T) {
+	for _, tt := range []struct {
+		scale float64
+		want  ebiten.Filter
+	}{
+		{1, ebiten.FilterNearest},
+		{2, ebiten.FilterNearest},
+		{0.75, ebiten.FilterLinear},
+		{1.25, ebiten.FilterLinear},
+	} {
+		if got := hiresFilter(tt.scale); got != tt.want {
+			t.Errorf("hiresFilter(%v) = %v, want %v", tt.scale, got, tt.want)
+		}
+	}
+}
+
+// fakeHiresProvider はグラフィックスシステムの代わりに @2x の画面を返す
+type fakeHiresProvider struct {
+	mockRedrawGraphics
+	frame *ebiten.Image
+}
+
+func (f *fakeHiresProvider) HiresFrame() *ebiten.Image { return f.frame }
+
+func TestUpdateHiresFrame(t *testing.T) {
+	g, _ := newConsoleGame()
+	provider := &fakeHiresProvider{frame: &ebiten.Image{}}
+	g.SetGraphicsSystem(provider)
+
+	g.updateHiresFrame()
+	if g.hiresFrame != provider.frame {
+		t.Error("expected the @2x frame of the graphics system in desktop mode")
+	}
+
+	// コンソールは等倍の画面の上に描くため、@2x の画面を重ねない
+	g.consoleOpen = true
+	g.updateHiresFrame()
+	if g.hiresFrame != nil {
+		t.Error("expected no @2x frame while the console is open")
+	}
+	g.consoleOpen = false
+
+	g.mode = ModeSelection
+	g.updateHiresFrame()
+	if g.hiresFrame != nil {
+		t.Error("expected no @2x frame outside desktop mode")
+	}
+}
@@ -0,0 +1,137 @@
+//go:build !nogpu
+
+package window
+
+import (
+	"image"
+	"image/color"
+	"strconv"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/inpututil"
+	"github.com/hajimehoshi/ebiten/v2/text/v2"
+)
+
+// 時間スケール（スロー再生・早送り）のホットキー
+// いずれもKEYイベントとしてスクリプトに渡さないキーを使う
+const (
+	timeScaleSlowerKey = ebiten.KeyMinus     // 1段階遅くする
+	timeScaleFasterKey = ebiten.KeyEqual     // 1段階速くする
+	timeScaleResetKey  = ebiten.KeyBackquote // 等速に戻す
+)
+
+// timeScaleSteps はホットキーで切り替える時間スケールの段階
+var timeScaleSteps = []float64{0.25, 0.5, 1, 2, 4}
+
+// 時間スケール表示（等速以外のとき画面右上に表示する）
+var (
+	timeScaleIndicatorColor      = color.RGBA{0xFF, 0xFF, 0x00, 0xFF}
+	timeScaleIndicatorBackground = color.RGBA{0x00, 0x00, 0x00, 0xFF}
+)
+
+const timeScaleIndicatorMargin = 8
+
+// TimeScaler defines the interface for changing the playback speed (TIME timer and MIDI tempo)
+// This is used to decouple the window package from the vm package
+type TimeScaler interface {
+	// SetTimeScale sets the scale and returns the applied (clamped) scale
+	SetTimeScale(scale float64) float64
+	TimeScale() float64
+}
+
+// SetTimeScaler sets the target of the time scale hotkeys
+// ("-" slower, "=" faster, "`" back to normal speed, 0.25x-4x).
+// Passing nil disables the hotkeys.
+func (g *Game) SetTimeScaler(scaler TimeScaler) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.timeScaler = scaler
+	g.timeScale = 1
+	if scaler != nil {
+		g.timeScale = scaler.TimeScale()
+	}
+	g.forceRedraw = true
+}
+
+// nextTimeScale は現在の時間スケールから1段階遅い・速い段階を返す
+// 段階の間の値からは、その方向で最も近い段階に移る。端ではそのままの値を返す。
+func nextTimeScale(current float64, faster bool) float64 {
+	if faster {
+		for _, step := range timeScaleSteps {
+			if step > current {
+				return step
+			}
+		}
+		return timeScaleSteps[len(timeScaleSteps)-1]
+	}
+	for i := len(timeScaleSteps) - 1; i >= 0; i-- {
+		if timeScaleSteps[i] < current {
+			return timeScaleSteps[i]
+		}
+	}
+	return timeScaleSteps[0]
+}
+
+// updateTimeScale は時間スケールのホットキーを処理する
+func (g *Game) updateTimeScale() {
+	var scale float64
+	switch {
+	case inpututil.IsKeyJustPressed(timeScaleFasterKey):
+		scale = nextTimeScale(g.currentTimeScale(), true)
+	case inpututil.IsKeyJustPressed(timeScaleSlowerKey):
+		scale = nextTimeScale(g.currentTimeScale(), false)
+	case inpututil.IsKeyJustPressed(timeScaleResetKey) && !ebiten.IsKeyPressed(ebiten.KeyShift): // Shift+` はコンソール
+		scale = 1
+	default:
+		return
+	}
+	g.applyTimeScale(scale)
+}
+
+// currentTimeScale は表示中の時間スケールを返す
+func (g *Game) currentTimeScale() float64 {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	return g.timeScale
+}
+
+// applyTimeScale は時間スケールを設定し、表示を更新する
+func (g *Game) applyTimeScale(scale float64) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.timeScaler == nil {
+		return
+	}
+	g.timeScale = g.timeScaler.SetTimeScale(scale)
+	g.forceRedraw = true
+}
+
+// formatTimeScale は時間スケールの表示文字列を返す（例: "x0.25", "x2"）
+func formatTimeScale(scale float64) string {
+	return "x" + strconv.FormatFloat(scale, 'f', -1, 64)
+}
+
+// drawTimeScale は等速以外のとき、画面右上に時間スケールを表示する
+func (g *Game) drawTimeScale(screen *ebiten.Image) {
+	g.mu.RLock()
+	scale := g.timeScale
+	hasScaler := g.timeScaler != nil
+	g.mu.RUnlock()
+	if !hasScaler || scale == 1 {
+		return
+	}
+
+	label := formatTimeScale(scale)
+	width, height := text.Measure(label, defaultFace, 0)
+	bounds := screen.Bounds()
+	x := bounds.Max.X - int(width) - timeScaleIndicatorMargin*2
+	y := bounds.Min.Y + timeScaleIndicatorMargin
+	box := image.Rect(x-timeScaleIndicatorMargin, y-timeScaleIndicatorMargin/2,
+		bounds.Max.X-timeScaleIndicatorMargin, y+int(height)+timeScaleIndicatorMargin/2)
+	screen.SubImage(box).(*ebiten.Image).Fill(timeScaleIndicatorBackground)
+
+	op := &text.DrawOptions{}
+	op.GeoM.Translate(float64(x), float64(y))
+	op.ColorScale.ScaleWithColor(timeScaleIndicatorColor)
+	text.Draw(screen, label, defaultFace, op)
+}
@@ -0,0 +1,264 @@
+//go:build !nogpu
+
+package window
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"strconv"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/inpututil"
+	"github.com/hajimehoshi/ebiten/v2/text/v2"
+	"github.com/zurustar/son-et/pkg/logger"
+)
+
+// 変数ウォッチパネルのホットキー
+// パネルを開くキーはKEYイベントとしてスクリプトに渡さないキーを使う。
+// パネルを開いている間はキー入力をスクリプトに渡さず、矢印キーでパネルを操作する（タイトルは動き続ける）
+const (
+	watchPanelKey    = ebiten.KeySemicolon  // パネルを開く・閉じる
+	watchPrevKey     = ebiten.KeyArrowUp    // 前の変数を選ぶ
+	watchNextKey     = ebiten.KeyArrowDown  // 次の変数を選ぶ
+	watchDecreaseKey = ebiten.KeyArrowLeft  // 選んだ変数を減らす
+	watchIncreaseKey = ebiten.KeyArrowRight // 選んだ変数を増やす
+	watchTraceKey    = ebiten.KeyTab        // 変数と実行トレースの表示を切り替える
+)
+
+// 変数の増減量（Shiftで大きく、Ctrlで小さく（実数の変数のみ）する）
+const (
+	watchStep       = 1
+	watchStepLarge  = 10
+	watchStepSmall  = 0.1
+	watchPanelRows  = 20 // 一度に表示する変数の数
+	watchPanelWidth = 480
+	watchMargin     = 8
+)
+
+// パネルの表示
+var (
+	watchPanelBackground = color.RGBA{0x00, 0x00, 0x00, 0xC0}
+	watchTextColor       = color.RGBA{0xFF, 0xFF, 0xFF, 0xFF}
+	watchReadOnlyColor   = color.RGBA{0x90, 0x90, 0x90, 0xFF}
+	watchSelectedColor   = color.RGBA{0xFF, 0xFF, 0x00, 0xFF}
+)
+
+// WatchVariable is a script variable shown in the watch panel
+type WatchVariable struct {
+	Name    string
+	Value   string  // 表示用の値
+	Numeric bool    // 数値の変数（パネルで変更できる）
+	Number  float64 // Numeric の場合の値
+	Integer bool    // 整数の変数（増減量を1未満にしない）
+}
+
+// VariableWatcher defines the interface for the live variable watch panel
+// This is used to decouple the window package from the vm package
+type VariableWatcher interface {
+	// WatchVariables returns the script's global variables sorted by name
+	WatchVariables() []WatchVariable
+	// SetWatchVariable changes a numeric global variable
+	SetWatchVariable(name string, value float64) error
+	// TraceLines returns the last statements executed by each sequence, one per line
+	TraceLines() []string
+}
+
+// SetVariableWatcher sets the target of the variable watch panel (";" opens and closes it,
+// up/down select a variable and left/right change it while the title keeps running).
+// Passing nil disables the panel.
+func (g *Game) SetVariableWatcher(watcher VariableWatcher) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.watcher = watcher
+	g.watching = false
+	g.watchSelected = 0
+	g.watchTrace = false
+	g.watchTraceTop = 0
+}
+
+// updateWatchPanel はウォッチパネルのホットキーを処理する
+// パネルを表示している間は true を返す（キー入力をスクリプトに渡さない）
+func (g *Game) updateWatchPanel() bool {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.watcher == nil {
+		return false
+	}
+	if inpututil.IsKeyJustPressed(watchPanelKey) {
+		g.watching = !g.watching
+		g.forceRedraw = true
+	}
+	if !g.watching {
+		return false
+	}
+
+	if inpututil.IsKeyJustPressed(watchTraceKey) {
+		g.watchTrace = !g.watchTrace
+	}
+	move := 0
+	switch {
+	case isKeyPressedOrRepeated(watchPrevKey):
+		move = -1
+	case isKeyPressedOrRepeated(watchNextKey):
+		move = 1
+	}
+	if g.watchTrace {
+		g.scrollWatchTrace(move, len(g.watcher.TraceLines()))
+		g.forceRedraw = true
+		return true
+	}
+
+	vars := g.watcher.WatchVariables()
+	g.selectWatchVariable(move, len(vars))
+
+	switch {
+	case isKeyPressedOrRepeated(watchDecreaseKey):
+		g.adjustWatchVariable(vars, -1, ebiten.IsKeyPressed(ebiten.KeyShift), ebiten.IsKeyPressed(ebiten.KeyControl))
+	case isKeyPressedOrRepeated(watchIncreaseKey):
+		g.adjustWatchVariable(vars, 1, ebiten.IsKeyPressed(ebiten.KeyShift), ebiten.IsKeyPressed(ebiten.KeyControl))
+	}
+	// 値はスクリプトの実行で毎フレーム変わりうる
+	g.forceRedraw = true
+	return true
+}
+
+// selectWatchVariable は選択中の変数を move だけ動かし、count 個の変数の範囲に収める
+// 呼び出し元は g.mu のロックを保持していること
+func (g *Game) selectWatchVariable(move, count int) {
+	g.watchSelected = max(0, min(g.watchSelected+move, count-1))
+}
+
+// scrollWatchTrace は実行トレースの表示を move 行だけスクロールし、count 行の範囲に収める
+// 呼び出し元は g.mu のロックを保持していること
+func (g *Game) scrollWatchTrace(move, count int) {
+	g.watchTraceTop = max(0, min(g.watchTraceTop+move, count-watchPanelRows))
+}
+
+// adjustWatchVariable は選択中の変数を direction の向きに増減する（数値でない変数は変更しない）
+// 呼び出し元は g.mu のロックを保持していること
+func (g *Game) adjustWatchVariable(vars []WatchVariable, direction int, shift, ctrl bool) {
+	if g.watchSelected >= len(vars) {
+		return
+	}
+	v := vars[g.watchSelected]
+	if !v.Numeric {
+		return
+	}
+	value := v.Number + float64(direction)*watchStepFor(v.Integer, shift, ctrl)
+	if err := g.watcher.SetWatchVariable(v.Name, value); err != nil {
+		logger.GetLogger().Warn("Failed to change variable", "name", v.Name, "error", err)
+	}
+}
+
+// isKeyPressedOrRepeated はキーが押された瞬間か、押し続けてキーリピートが発生したフレームかを返す
+func isKeyPressedOrRepeated(key ebiten.Key) bool {
+	fire, _ := keyPressState(inpututil.KeyPressDuration(key), ebiten.TPS())
+	return fire
+}
+
+// watchStepFor は変数の増減量を返す
+// Shift で watchStepLarge、Ctrl で watchStepSmall（整数の変数では watchStep）
+func watchStepFor(integer, shift, ctrl bool) float64 {
+	switch {
+	case shift:
+		return watchStepLarge
+	case ctrl && !integer:
+		return watchStepSmall
+	}
+	return watchStep
+}
+
+// watchScroll は selected を表示する範囲の先頭のインデックスを返す（選んだ変数が中央付近に来るようにする）
+func watchScroll(selected, count, rows int) int {
+	if count <= rows {
+		return 0
+	}
+	return max(0, min(selected-rows/2, count-rows))
+}
+
+// formatWatchLine はパネルの1行の表示文字列を返す
+func formatWatchLine(v WatchVariable) string {
+	return fmt.Sprintf("%s = %s", v.Name, v.Value)
+}
+
+// drawWatchPanel はウォッチパネルを画面右側に描画する
+func (g *Game) drawWatchPanel(screen *ebiten.Image) {
+	g.mu.RLock()
+	watching := g.watching
+	watcher := g.watcher
+	selected := g.watchSelected
+	showTrace := g.watchTrace
+	traceTop := g.watchTraceTop
+	g.mu.RUnlock()
+	if !watching || watcher == nil {
+		return
+	}
+
+	var vars []WatchVariable
+	var traceLines []string
+	count := 0
+	if showTrace {
+		traceLines = watcher.TraceLines()
+		count = len(traceLines)
+	} else {
+		vars = watcher.WatchVariables()
+		count = len(vars)
+	}
+	_, lineHeight := text.Measure("M", defaultFace, 0)
+	rowHeight := int(lineHeight) + watchMargin/2
+	bounds := screen.Bounds()
+	rows := min(count, watchPanelRows)
+	x := bounds.Max.X - watchPanelWidth - watchMargin
+	y := bounds.Min.Y + watchMargin
+	height := (rows+2)*rowHeight + watchMargin*2
+	panel := image.Rect(x, y, bounds.Max.X-watchMargin, min(y+height, bounds.Max.Y))
+	background := ebiten.NewImage(1, 1)
+	defer background.Deallocate()
+	background.Fill(watchPanelBackground)
+	op := &ebiten.DrawImageOptions{}
+	op.GeoM.Scale(float64(panel.Dx()), float64(panel.Dy()))
+	op.GeoM.Translate(float64(panel.Min.X), float64(panel.Min.Y))
+	screen.DrawImage(background, op)
+
+	drawLine := func(line string, c color.Color) {
+		op := &text.DrawOptions{}
+		op.GeoM.Translate(float64(x+watchMargin), float64(y+watchMargin))
+		op.ColorScale.ScaleWithColor(c)
+		text.Draw(screen, line, defaultFace, op)
+		y += rowHeight
+	}
+
+	if showTrace {
+		drawLine("TRACE  up/down: scroll  tab: variables", watchTextColor)
+		if len(traceLines) == 0 {
+			drawLine("(nothing executed yet)", watchReadOnlyColor)
+		}
+		for _, line := range traceLines[min(traceTop, len(traceLines)):min(traceTop+rows, len(traceLines))] {
+			drawLine(line, watchTextColor)
+		}
+		return
+	}
+
+	drawLine("WATCH ("+strconv.Itoa(len(vars))+")  arrows: select/change  tab: trace", watchTextColor)
+	if len(vars) == 0 {
+		drawLine("(no global variables)", watchReadOnlyColor)
+		return
+	}
+	start := watchScroll(selected, len(vars), watchPanelRows)
+	for i := start; i < start+rows; i++ {
+		v := vars[i]
+		c := watchTextColor
+		switch {
+		case i == selected:
+			c = watchSelectedColor
+		case !v.Numeric:
+			c = watchReadOnlyColor
+		}
+		prefix := "  "
+		if i == selected {
+			prefix = "> "
+		}
+		drawLine(prefix+formatWatchLine(v), c)
+	}
+}
@@ -0,0 +1,166 @@
+//go:build !nogpu
+
+package window
+
+import (
+	"errors"
+	"math"
+	"testing"
+)
+
+// mockVariableWatcher は変数を名前順の配列で保持する
+type mockVariableWatcher struct {
+	vars  []WatchVariable
+	trace []string
+}
+
+func (m *mockVariableWatcher) WatchVariables() []WatchVariable { return m.vars }
+func (m *mockVariableWatcher) TraceLines() []string            { return m.trace }
+
+func (m *mockVariableWatcher) SetWatchVariable(name string, value float64) error {
+	for i := range m.vars {
+		if m.vars[i].Name == name && m.vars[i].Numeric {
+			m.vars[i].Number = value
+			return nil
+		}
+	}
+	return errors.New("not found")
+}
+
+func newWatchGame() (*Game, *mockVariableWatcher) {
+	watcher := &mockVariableWatcher{vars: []WatchVariable{
+		{Name: "count", Value: "3", Numeric: true, Number: 3, Integer: true},
+		{Name: "name", Value: `"demo"`},
+		{Name: "speed", Value: "1.5", Numeric: true, Number: 1.5},
+	}}
+	game := NewGame(ModeDesktop, nil, 0)
+	game.SetVariableWatcher(watcher)
+	return game, watcher
+}
+
+func TestSelectWatchVariable(t *testing.T) {
+	game, _ := newWatchGame()
+	game.mu.Lock()
+	defer game.mu.Unlock()
+
+	game.selectWatchVariable(-1, 3)
+	if game.watchSelected != 0 {
+		t.Errorf("selection moved above the first variable: %d", game.watchSelected)
+	}
+	game.selectWatchVariable(1, 3)
+	game.selectWatchVariable(1, 3)
+	game.selectWatchVariable(1, 3)
+	if game.watchSelected != 2 {
+		t.Errorf("selection moved past the last variable: %d", game.watchSelected)
+	}
+	// 変数が減った場合（別のタイトルなど）は範囲に収める
+	game.selectWatchVariable(0, 1)
+	if game.watchSelected != 0 {
+		t.Errorf("selection not clamped to the variables: %d", game.watchSelected)
+	}
+	game.selectWatchVariable(0, 0)
+	if game.watchSelected != 0 {
+		t.Errorf("selection with no variables = %d, want 0", game.watchSelected)
+	}
+}
+
+func TestAdjustWatchVariable(t *testing.T) {
+	game, watcher := newWatchGame()
+	game.mu.Lock()
+	defer game.mu.Unlock()
+
+	// 整数の変数: Ctrl でも1ずつ、Shift で10ずつ
+	game.adjustWatchVariable(watcher.vars, 1, false, true)
+	if watcher.vars[0].Number != 4 {
+		t.Errorf("count = %v, want 4", watcher.vars[0].Number)
+	}
+	game.adjustWatchVariable(watcher.vars, -1, true, false)
+	if watcher.vars[0].Number != -6 {
+		t.Errorf("count = %v, want -6", watcher.vars[0].Number)
+	}
+
+	// 数値でない変数は変更しない
+	game.watchSelected = 1
+	game.adjustWatchVariable(watcher.vars, 1, false, false)
+	if watcher.vars[1].Number != 0 {
+		t.Errorf("string variable was changed: %v", watcher.vars[1].Number)
+	}
+
+	// 実数の変数: Ctrl で0.1ずつ
+	game.watchSelected = 2
+	game.adjustWatchVariable(watcher.vars, -1, false, true)
+	if math.Abs(watcher.vars[2].Number-1.4) > 1e-9 {
+		t.Errorf("speed = %v, want 1.4", watcher.vars[2].Number)
+	}
+
+	// 範囲外の選択は無視する
+	game.watchSelected = 5
+	game.adjustWatchVariable(watcher.vars, 1, false, false)
+}
+
+func TestScrollWatchTrace(t *testing.T) {
+	game, _ := newWatchGame()
+	game.mu.Lock()
+	defer game.mu.Unlock()
+
+	// 1画面に収まる場合はスクロールしない
+	game.scrollWatchTrace(1, watchPanelRows)
+	if game.watchTraceTop != 0 {
+		t.Errorf("scrolled a trace that fits the panel: %d", game.watchTraceTop)
+	}
+	for range 10 {
+		game.scrollWatchTrace(1, watchPanelRows+3)
+	}
+	if game.watchTraceTop != 3 {
+		t.Errorf("watchTraceTop = %d, want 3 (last page)", game.watchTraceTop)
+	}
+	game.scrollWatchTrace(-5, watchPanelRows+3)
+	if game.watchTraceTop != 0 {
+		t.Errorf("watchTraceTop = %d, want 0", game.watchTraceTop)
+	}
+}
+
+func TestWatchStepFor(t *testing.T) {
+	tests := []struct {
+		integer, shift, ctrl bool
+		want                 float64
+	}{
+		{false, false, false, watchStep},
+		{false, true, false, watchStepLarge},
+		{false, false, true, watchStepSmall},
+		{true, false, true, watchStep},
+		{true, true, true, watchStepLarge},
+	}
+	for _, tt := range tests {
+		if got := watchStepFor(tt.integer, tt.shift, tt.ctrl); got != tt.want {
+			t.Errorf("watchStepFor(%v, %v, %v) = %v, want %v", tt.integer, tt.shift, tt.ctrl, got, tt.want)
+		}
+	}
+}
+
+func TestWatchScroll(t *testing.T) {
+	tests := []struct {
+		selected, count, rows, want int
+	}{
+		{0, 5, 20, 0},
+		{4, 5, 20, 0},
+		{0, 50, 20, 0},
+		{15, 50, 20, 5},
+		{49, 50, 20, 30},
+	}
+	for _, tt := range tests {
+		if got := watchScroll(tt.selected, tt.count, tt.rows); got != tt.want {
+			t.Errorf("watchScroll(%d, %d, %d) = %d, want %d", tt.selected, tt.count, tt.rows, got, tt.want)
+		}
+	}
+}
+
+func TestSetVariableWatcherResets(t *testing.T) {
+	game, _ := newWatchGame()
+	game.watching = true
+	game.watchSelected = 2
+	game.SetVariableWatcher(&mockVariableWatcher{})
+	if game.watching || game.watchSelected != 0 {
+		t.Errorf("SetVariableWatcher should close the panel, got watching=%v selected=%d", game.watching, game.watchSelected)
+	}
+}
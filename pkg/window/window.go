@@ -1,13 +1,10 @@
+//go:build !nogpu
+
 package window
 
 import (
-	"bufio"
-	"context"
 	"fmt"
 	"image/color"
-	"io"
-	"strconv"
-	"strings"
 	"sync"
 	"time"
 
@@ -65,6 +62,70 @@ type Game struct {
 	hasTitleSelection bool         // タイトル選択画面があるかどうか（複数タイトル時true）
 	onTitleExit       func() error // タイトル終了時のコールバック
 
+	// フォーカス喪失時の一時停止（--pause-on-blur）
+	focusPauser FocusPauser // nilの場合は一時停止しない
+	focusPaused bool        // フォーカス喪失により一時停止中かどうか
+	isFocused   func() bool // フォーカス状態の取得（テスト用に差し替え可能）
+
+	// 時間スケールのホットキー（スロー再生・早送り）
+	timeScaler TimeScaler // nilの場合はホットキーを無効にする
+	timeScale  float64    // 現在の時間スケール（表示用）
+
+	// A/Vオフセットの調整画面（--av-offset）
+	avCalibrator     AVCalibrator  // nilの場合は調整画面を無効にする
+	avCalibrating    bool          // 調整画面を表示中かどうか
+	avOffset         time.Duration // 現在のA/Vオフセット（表示用）
+	avOffsetAdjusted bool          // 調整画面でオフセットを変更したかどうか（次のタイトルに引き継ぐ）
+
+	// メトロノームの拍の表示（--metronome-flash）
+	metronome      MetronomeBeater // nilの場合は表示しない
+	metronomeShown bool            // 前回の描画で拍を表示したかどうか
+
+	// 変数ウォッチパネル
+	watcher       VariableWatcher // nilの場合はパネルを無効にする
+	watching      bool            // パネルを表示中かどうか
+	watchSelected int             // 選択中の変数のインデックス
+	watchTrace    bool            // 変数の代わりに実行トレースを表示中かどうか
+	watchTraceTop int             // 実行トレースの表示の先頭の行
+
+	// オペレーターのコンソール（~ キー）
+	commandRunner     CommandRunner // nilの場合はコンソールを無効にする
+	consoleOpen       bool          // コンソールを表示中かどうか
+	consoleInput      string        // 入力中のコマンド
+	consoleLines      []consoleLine // 表示する出力（タイトルを切り替えても残す）
+	consoleHistory    []string      // 実行したコマンドの履歴
+	consoleHistoryPos int           // 上下キーで呼び出している履歴の位置
+
+	// ポーズメニュー（Esc キー）
+	pauseTarget    PauseMenuTarget // nilの場合はEscキーで直ちに終了する
+	pauseMenuStyle PauseMenuStyle  // プロジェクトマニフェストの設定
+	pauseMenuOpen  bool            // メニューを表示中かどうか
+	pauseSelected  int             // 選択中の項目のインデックス
+	pauseMuted     bool            // メニューで音声をミュートしたか（次のタイトルに引き継ぐ）
+
+	// ゲームパッドのボタンとキー入力の対応（--input-map）
+	inputMap *InputMap // nilの場合はゲームパッドの入力を無視する
+
+	// タイトルの終了処理（OnExit）
+	exiting      bool      // 終了処理の実行中（VMの停止を待っている）
+	exitDeadline time.Time // 終了処理を待つ期限
+	timedOut     bool      // タイムアウトで終了した（終了コードの判定に使用）
+
+	// 描画フレームレート（SetFrameRate）
+	tps         int       // 設定した TPS（0の場合は DefaultTPS）
+	fps         int       // 0の場合は毎フレーム描画する
+	nextDraw    time.Time // 次に画面を描き直す時刻
+	forceRedraw bool      // 次のフレームで変更の有無にかかわらず描き直すかどうか
+
+	// 省電力モード（--power-save）
+	powerSave *powerSaver // nilの場合は TPS を下げない
+
+	// 仮想デスクトップの拡大（--scale-mode）
+	scaleMode      ScaleMode
+	fitTransform   screenTransform // Ebitengine の既定の拡大（カーソル位置はこの変換で求められる）
+	drawnTransform screenTransform // 実際に描いた拡大（scale が0の場合はまだ描いていない）
+	hiresFrame     *ebiten.Image   // 仮想デスクトップに重ねて描く @2x の画面（nil の場合はなし、HiresFrameProvider）
+
 	// Mouse state tracking for event generation
 	lastMouseX int
 	lastMouseY int
@@ -82,6 +143,28 @@ type GraphicsSystemInterface interface {
 	GetVirtualHeight() int
 }
 
+// RedrawReporter is implemented by graphics systems that track whether the
+// scene changed since the last Draw (dirty tracking).
+// Game skips Draw while NeedsRedraw returns false and the previous frame is kept on screen.
+type RedrawReporter interface {
+	NeedsRedraw() bool
+}
+
+// CursorTracker is implemented by graphics systems that draw a custom mouse cursor.
+// Game passes the mouse position in virtual desktop coordinates every frame,
+// with pressed set when the left button was pressed in that frame.
+type CursorTracker interface {
+	TrackMouse(x, y int, pressed bool)
+}
+
+// CameraTransformer is implemented by graphics systems that pan and zoom the
+// scene with a camera (SetCamera/CameraMove).
+// Game converts the mouse position on screen to scene coordinates before pushing
+// mouse events, so that hit-testing works while the camera is zoomed.
+type CameraTransformer interface {
+	SceneFromScreen(x, y int) (int, int)
+}
+
 // VMRunnerInterface defines the interface for VM operations
 type VMRunnerInterface interface {
 	IsRunning() bool
@@ -99,6 +182,14 @@ type EventQueueInterface interface {
 type MouseEventPusher interface {
 	PushMouseEvent(eventType string, windowID, x, y int)
 	PushKeyEvent(eventType string, keyCode int)
+	PushKeyInput(keyCode, modifiers int, repeat bool)
+}
+
+// FocusPauser defines the interface for pausing the VM when the window loses focus
+// This is used to decouple the window package from the vm package
+type FocusPauser interface {
+	Pause()
+	Resume()
 }
 
 // NewGame Gameを作成
@@ -109,6 +200,7 @@ func NewGame(mode Mode, titles []title.FillyTitle, timeout time.Duration) *Game
 		selectedIndex: 0,
 		timeout:       timeout,
 		startTime:     time.Now(),
+		isFocused:     ebiten.IsFocused,
 	}
 }
 
@@ -117,6 +209,9 @@ func (g *Game) SetGraphicsSystem(gs GraphicsSystemInterface) {
 	g.mu.Lock()
 	defer g.mu.Unlock()
 	g.graphicsSystem = gs
+	g.forceRedraw = true
+	// 新しいグラフィックスシステムに次のフレームで実効スケールを通知する
+	g.drawnTransform = screenTransform{}
 }
 
 // SetVMRunner sets the VM runner for desktop mode
@@ -133,6 +228,16 @@ func (g *Game) SetEventPusher(pusher MouseEventPusher) {
 	g.eventPusher = pusher
 }
 
+// SetFocusPauser sets the pauser used when the window loses focus (--pause-on-blur)
+// When set, losing focus pauses the tick clock and mutes audio, and regaining focus resumes them.
+// Passing nil disables the behavior.
+func (g *Game) SetFocusPauser(pauser FocusPauser) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.focusPauser = pauser
+	g.focusPaused = false
+}
+
 // SetVMStartFunc sets the function to start the VM
 // This function will be called on the first Update() call to ensure
 // Ebitengine is fully initialized before VM starts
@@ -186,8 +291,16 @@ func (g *Game) GetTransitionError() error {
 
 // Update ゲームロジックの更新（Ebitengineが毎フレーム呼び出す）
 func (g *Game) Update() error {
-	// タイムアウトチェック
-	if g.timeout > 0 && time.Since(g.startTime) >= g.timeout {
+	// タイムアウト・ウィンドウを閉じる操作のチェック
+	// タイトルが終了処理（OnExit）を登録している場合は、終了処理が終わるまでゲームループを続ける
+	timedOut := g.timeout > 0 && time.Since(g.startTime) >= g.timeout
+	if timedOut {
+		g.markTimedOut()
+	}
+	if (timedOut || ebiten.IsWindowBeingClosed()) && !g.beginExit() {
+		return ebiten.Termination
+	}
+	if g.exitFinished() {
 		return ebiten.Termination
 	}
 
@@ -227,18 +340,9 @@ func (g *Game) updateSelection() error {
 		g.mu.RUnlock()
 
 		if callback != nil {
-			// コールバックを呼び出してVM/GraphicsSystemをセットアップ
-			if err := callback(g.selectedTitle); err != nil {
-				g.mu.Lock()
-				g.transitionError = err
-				g.mu.Unlock()
+			if err := g.startTitle(g.selectedTitle); err != nil {
 				return ebiten.Termination
 			}
-			// デスクトップモードに遷移
-			g.mu.Lock()
-			g.mode = ModeDesktop
-			g.startTime = time.Now() // タイムアウトをリセット
-			g.mu.Unlock()
 			return nil
 		}
 
@@ -268,39 +372,57 @@ func (g *Game) updateDesktop() error {
 	}
 	g.mu.Unlock()
 
-	// Escキーで終了または選択画面に戻る（1回だけ反応）
-	// 要件 2.1: hasTitleSelection=trueの場合、タイトル選択画面に戻る
-	// 要件 3.2: hasTitleSelection=falseの場合、プログラムを終了する
-	if inpututil.IsKeyJustPressed(ebiten.KeyEscape) {
-		g.mu.RLock()
-		hasTitleSelection := g.hasTitleSelection
-		g.mu.RUnlock()
+	// オペレーターのコンソール（表示中はEscキーやホットキーもコンソールが受け取る）
+	consoleOpen := g.updateConsole()
 
-		if hasTitleSelection {
-			// 複数タイトル環境: タイトル選択画面に戻る
-			return g.returnToSelection()
+	// ポーズメニュー（Escキーで開く。表示中はタイトルが一時停止し、キー操作はメニューが受け取る）
+	menuOpen := false
+	if !consoleOpen {
+		var action pauseMenuAction
+		menuOpen, action = g.updatePauseMenu()
+		if action != pauseMenuNone {
+			return g.runPauseMenuAction(action)
 		}
+	}
 
-		// 単一タイトル環境: プログラムを終了
-		g.mu.RLock()
-		vmRunner := g.vmRunner
-		g.mu.RUnlock()
-		if vmRunner != nil {
-			vmRunner.Stop()
-		}
-		return ebiten.Termination
+	// ポーズメニューがない場合は、Escキーで終了または選択画面に戻る（1回だけ反応）
+	if !consoleOpen && !menuOpen && inpututil.IsKeyJustPressed(ebiten.KeyEscape) {
+		return g.quitTitle()
+	}
+
+	// 時間スケールのホットキー（一時停止中も受け付ける）
+	if !consoleOpen && !menuOpen {
+		g.updateTimeScale()
+	}
+
+	// A/Vオフセットの調整画面（表示中はタイトルが一時停止し、入力をVMに渡さない）
+	calibrating := g.isAVCalibrating()
+	if !consoleOpen && !menuOpen {
+		calibrating = g.updateAVCalibration()
+	}
+
+	// 変数ウォッチパネル（表示中もタイトルは動き続けるが、キー入力はVMに渡さない）
+	watching := consoleOpen
+	if !consoleOpen && !menuOpen {
+		watching = g.updateWatchPanel()
 	}
 
 	// VMが完全に停止しても、ユーザーが明示的に終了するまでウィンドウは開いたまま
 	// Escキーまたはウィンドウを閉じることで終了する
 	// 要件変更: タイトル終了後もウィンドウを閉じない
 
-	// マウスイベントを処理
-	// 要件 14.6: マウスイベントをEbitengineから取得し、VMのイベントキューに追加する
-	g.processMouseEvents()
+	// フォーカス状態に応じて一時停止・再開する
+	// 一時停止中・終了処理の実行中は入力イベントをVMに渡さない
+	if !g.updateFocusPause() && !calibrating && !menuOpen && !g.isExiting() {
+		// マウスイベントを処理
+		// 要件 14.6: マウスイベントをEbitengineから取得し、VMのイベントキューに追加する
+		g.processMouseEvents()
 
-	// キーボードイベントを処理
-	g.processKeyboardEvents()
+		// キーボードイベントを処理
+		if !watching {
+			g.processKeyboardEvents()
+		}
+	}
 
 	// GraphicsSystemの更新（コマンドキューの処理）
 	// 要件 14.2: EbitengineのDraw()内で描画コマンドキューを処理する
@@ -314,9 +436,64 @@ func (g *Game) updateDesktop() error {
 		}
 	}
 
+	// 省電力モード: 画面の変化も入力もなければ TPS を下げる
+	g.updatePowerSave(consoleOpen || menuOpen || calibrating || watching)
+
 	return nil
 }
 
+// quitTitle はタイトルを終了し、選択画面に戻るかプログラムを終了する
+// 要件 2.1: hasTitleSelection=trueの場合、タイトル選択画面に戻る
+// 要件 3.2: hasTitleSelection=falseの場合、プログラムを終了する
+func (g *Game) quitTitle() error {
+	g.mu.RLock()
+	hasTitleSelection := g.hasTitleSelection
+	g.mu.RUnlock()
+
+	if hasTitleSelection {
+		// 複数タイトル環境: タイトル選択画面に戻る
+		return g.returnToSelection()
+	}
+
+	// 単一タイトル環境: プログラムを終了
+	// タイトルの終了処理（OnExit）があれば実行する。終了処理の実行中に再度押すと直ちに終了する
+	if !g.isExiting() && g.beginExit() {
+		return nil
+	}
+	g.mu.RLock()
+	vmRunner := g.vmRunner
+	g.mu.RUnlock()
+	if vmRunner != nil {
+		vmRunner.Stop()
+	}
+	return ebiten.Termination
+}
+
+// updateFocusPause はウィンドウのフォーカス状態を確認し、必要に応じてVMを一時停止・再開する
+// 一時停止中の場合は true を返す
+func (g *Game) updateFocusPause() bool {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if g.focusPauser == nil || g.isFocused == nil {
+		return false
+	}
+
+	focused := g.isFocused()
+	switch {
+	case !focused && !g.focusPaused:
+		g.focusPaused = true
+		g.focusPauser.Pause()
+	case focused && g.focusPaused:
+		g.focusPaused = false
+		// ポーズメニューを表示中は、メニューを閉じるまで再開しない
+		if !g.pauseMenuOpen {
+			g.focusPauser.Resume()
+		}
+	}
+	return g.focusPaused
+}
+
 // returnToSelection はデスクトップモードからタイトル選択画面に戻る
 // 要件 2.1, 2.5, 5.1: エスケープキーでタイトル選択画面に戻る
 // 注: 完全な実装はタスク2.2で行う
@@ -341,12 +518,32 @@ func (g *Game) returnToSelection() error {
 	// モードをModeSelectionに変更
 	g.mu.Lock()
 	g.mode = ModeSelection
+	g.forceRedraw = true
 	g.vmStarted = false
 	g.graphicsSystem = nil
 	g.vmRunner = nil
 	g.eventPusher = nil
+	g.focusPauser = nil
+	g.focusPaused = false
+	g.exiting = false
+	g.stopAVCalibration()
+	g.metronome = nil
+	g.watcher = nil
+	g.watching = false
+	g.commandRunner = nil
+	g.consoleOpen = false
+	g.pauseTarget = nil
+	g.pauseMenuOpen = false
 	g.mu.Unlock()
 
+	// タイトルが ShowSysCursor(0) や SetCursor で隠したシステムのカーソルを元に戻す
+	ebiten.SetCursorMode(ebiten.CursorModeVisible)
+
+	// タイトルが SetWindowTitle などで変更したウィンドウの設定を元に戻す
+	ebiten.SetWindowTitle(DefaultTitle)
+	ebiten.SetWindowIcon(nil)
+	ebiten.SetWindowSize(DefaultWidth, DefaultHeight)
+
 	return nil
 }
 
@@ -365,10 +562,22 @@ func (g *Game) processMouseEvents() {
 
 	// マウス座標を取得
 	mouseX, mouseY := ebiten.CursorPosition()
+	mouseX, mouseY = g.correctCursor(mouseX, mouseY)
 
 	// 仮想デスクトップ座標に変換
 	virtualX, virtualY := g.screenToVirtual(mouseX, mouseY, graphicsSystem)
 
+	// カスタムカーソル（SetCursor）をマウスに追従させる
+	if tracker, ok := graphicsSystem.(CursorTracker); ok {
+		tracker.TrackMouse(virtualX, virtualY, inpututil.IsMouseButtonJustPressed(ebiten.MouseButtonLeft))
+	}
+
+	// カメラ（SetCamera）でパン・ズームしている場合は、イベントの座標をシーンの座標に戻す
+	// カーソルは画面に描画するため、変換前の座標を使う
+	if camera, ok := graphicsSystem.(CameraTransformer); ok {
+		virtualX, virtualY = camera.SceneFromScreen(virtualX, virtualY)
+	}
+
 	// 左ボタン押し下げ (LBDOWN)
 	if inpututil.IsMouseButtonJustPressed(ebiten.MouseButtonLeft) {
 		// windowID は 0 (メインウィンドウ) として扱う
@@ -401,6 +610,7 @@ func (g *Game) processMouseEvents() {
 func (g *Game) processKeyboardEvents() {
 	g.mu.RLock()
 	eventPusher := g.eventPusher
+	inputMap := g.inputMap
 	g.mu.RUnlock()
 
 	if eventPusher == nil {
@@ -430,6 +640,12 @@ func (g *Game) processKeyboardEvents() {
 			eventPusher.PushKeyEvent("CHAR", int(k.char))
 		}
 	}
+
+	// 文字以外も含むキー入力をKEYイベントとして送信する（OnKey用）
+	processKeyInputs(eventPusher)
+
+	// 入力マップで割り当てたゲームパッドのボタンもキー入力として送信する
+	processGamepadInputs(inputMap, eventPusher)
 }
 
 // screenToVirtual はスクリーン座標を仮想デスクトップ座標に変換する
@@ -483,8 +699,34 @@ func (g *Game) screenToVirtual(screenX, screenY int, gs GraphicsSystemInterface)
 	return virtualX, virtualY
 }
 
+// startTitle はコールバックでタイトルのVM/GraphicsSystemをセットアップし、デスクトップモードに遷移する
+// セットアップに失敗した場合はエラーを transitionError に記録して返す
+func (g *Game) startTitle(t *title.FillyTitle) error {
+	g.mu.RLock()
+	callback := g.onTitleSelected
+	g.mu.RUnlock()
+
+	if err := callback(t); err != nil {
+		g.mu.Lock()
+		g.transitionError = err
+		g.mu.Unlock()
+		return err
+	}
+	g.mu.Lock()
+	g.mode = ModeDesktop
+	g.startTime = time.Now() // タイムアウトをリセット
+	g.forceRedraw = true
+	g.mu.Unlock()
+	return nil
+}
+
 // Draw 画面描画（Ebitengineが毎フレーム呼び出す）
 func (g *Game) Draw(screen *ebiten.Image) {
+	// 画面に変化がない場合や、FPSの上限を超える場合は描画を省略し、前回の画面をそのまま表示する
+	if !g.needsRedraw() || !g.shouldDraw(time.Now()) {
+		return
+	}
+
 	// skelton要件 3.2: 背景色は #0087C8
 	screen.Fill(backgroundColor)
 
@@ -493,7 +735,15 @@ func (g *Game) Draw(screen *ebiten.Image) {
 		g.drawSelection(screen)
 	case ModeDesktop:
 		g.drawDesktop(screen)
-	}
+		// 時間スケールとメトロノームの表示、調整画面、ウォッチパネル、ポーズメニュー、コンソールは仮想デスクトップの上に重ねる
+		g.drawTimeScale(screen)
+		g.drawMetronome(screen)
+		g.drawAVCalibration(screen)
+		g.drawWatchPanel(screen)
+		g.drawPauseMenu(screen)
+		g.drawConsole(screen)
+	}
+	g.updateHiresFrame()
 }
 
 // drawSelection タイトル選択画面の描画
@@ -554,93 +804,13 @@ func (g *Game) GetSelectedTitle() *title.FillyTitle {
 	return g.selectedTitle
 }
 
-// RunHeadless ヘッドレスモードでタイトル選択を実行
-func RunHeadless(titles []title.FillyTitle, timeout time.Duration, reader io.Reader, writer io.Writer) (*title.FillyTitle, error) {
-	// タイトルが1つの場合は自動選択
-	if len(titles) == 1 {
-		fmt.Fprintf(writer, "Auto-selecting title: %s\n", titles[0].Name)
-		return &titles[0], nil
-	}
-
-	// タイムアウト処理用のコンテキスト
-	ctx := context.Background()
-	if timeout > 0 {
-		var cancel context.CancelFunc
-		ctx, cancel = context.WithTimeout(ctx, timeout)
-		defer cancel()
-	}
-
-	// タイトル一覧を表示
-	fmt.Fprintln(writer, "Available FILLY Titles:")
-	for i, t := range titles {
-		fmt.Fprintf(writer, "  %d: %s\n", i+1, t.Name)
-	}
-	fmt.Fprintln(writer)
-
-	// 選択を受け付ける
-	scanner := bufio.NewScanner(reader)
-	resultCh := make(chan *title.FillyTitle)
-	errCh := make(chan error)
-
-	go func() {
-		for {
-			fmt.Fprint(writer, "Select a title (1-", len(titles), ") or 'q' to quit: ")
-			if !scanner.Scan() {
-				if err := scanner.Err(); err != nil {
-					errCh <- fmt.Errorf("failed to read input: %w", err)
-				} else {
-					errCh <- fmt.Errorf("input closed")
-				}
-				return
-			}
-
-			input := strings.TrimSpace(scanner.Text())
-
-			// 終了コマンド
-			if input == "q" || input == "Q" {
-				errCh <- fmt.Errorf("user cancelled")
-				return
-			}
-
-			// 数値に変換
-			num, err := strconv.Atoi(input)
-			if err != nil {
-				fmt.Fprintln(writer, "Invalid input. Please enter a number.")
-				continue
-			}
-
-			// 範囲チェック
-			if num < 1 || num > len(titles) {
-				fmt.Fprintf(writer, "Invalid selection. Please enter a number between 1 and %d.\n", len(titles))
-				continue
-			}
-
-			// 選択されたタイトルを返す
-			selected := &titles[num-1]
-			fmt.Fprintf(writer, "Selected: %s\n", selected.Name)
-			resultCh <- selected
-			return
-		}
-	}()
-
-	// タイムアウトまたは選択完了を待つ
-	select {
-	case <-ctx.Done():
-		return nil, fmt.Errorf("timeout")
-	case err := <-errCh:
-		return nil, err
-	case selected := <-resultCh:
-		return selected, nil
-	}
-}
-
 // Run GUIモードでウィンドウを実行
 func Run(mode Mode, titles []title.FillyTitle, timeout time.Duration) (*title.FillyTitle, error) {
 	game := NewGame(mode, titles, timeout)
 
 	// ウィンドウ設定
-	ebiten.SetWindowSize(1024, 768)
-	ebiten.SetWindowTitle("son-et - FILLY interpreter")
+	ebiten.SetWindowSize(DefaultWidth, DefaultHeight)
+	ebiten.SetWindowTitle(DefaultTitle)
 	// 要件 8.5: アスペクト比を維持してスケーリングする
 	// 要件 8.6: スケーリング時にレターボックス（黒帯）を表示する
 	// WindowResizingModeEnabledを使用してウィンドウのリサイズを許可
@@ -1,3 +1,5 @@
+//go:build !nogpu
+
 package window
 
 import (
@@ -7,6 +9,7 @@ import (
 	"testing"
 	"time"
 
+	"github.com/hajimehoshi/ebiten/v2"
 	"github.com/zurustar/son-et/pkg/title"
 )
 
@@ -147,152 +150,6 @@ func TestUpdateDesktop_Timeout(t *testing.T) {
 	}
 }
 
-func TestRunHeadless_SingleTitle(t *testing.T) {
-	titles := []title.FillyTitle{
-		{Name: "Title1", Path: "/path/1", IsEmbedded: false},
-	}
-
-	var output strings.Builder
-	input := strings.NewReader("")
-
-	selected, err := RunHeadless(titles, 0, input, &output)
-
-	if err != nil {
-		t.Fatalf("expected no error, got %v", err)
-	}
-
-	if selected == nil {
-		t.Fatal("expected selected title, got nil")
-	}
-
-	if selected.Name != "Title1" {
-		t.Errorf("expected Title1, got %s", selected.Name)
-	}
-
-	if !strings.Contains(output.String(), "Auto-selecting") {
-		t.Error("expected auto-selection message")
-	}
-}
-
-func TestRunHeadless_MultipleTitle_ValidSelection(t *testing.T) {
-	titles := []title.FillyTitle{
-		{Name: "Title1", Path: "/path/1", IsEmbedded: false},
-		{Name: "Title2", Path: "/path/2", IsEmbedded: false},
-		{Name: "Title3", Path: "/path/3", IsEmbedded: false},
-	}
-
-	var output strings.Builder
-	input := strings.NewReader("2\n")
-
-	selected, err := RunHeadless(titles, 0, input, &output)
-
-	if err != nil {
-		t.Fatalf("expected no error, got %v", err)
-	}
-
-	if selected == nil {
-		t.Fatal("expected selected title, got nil")
-	}
-
-	if selected.Name != "Title2" {
-		t.Errorf("expected Title2, got %s", selected.Name)
-	}
-
-	outputStr := output.String()
-	if !strings.Contains(outputStr, "Available FILLY Titles:") {
-		t.Error("expected title list header")
-	}
-	if !strings.Contains(outputStr, "Title1") {
-		t.Error("expected Title1 in list")
-	}
-	if !strings.Contains(outputStr, "Selected: Title2") {
-		t.Error("expected selection confirmation")
-	}
-}
-
-func TestRunHeadless_InvalidThenValid(t *testing.T) {
-	titles := []title.FillyTitle{
-		{Name: "Title1", Path: "/path/1", IsEmbedded: false},
-		{Name: "Title2", Path: "/path/2", IsEmbedded: false},
-	}
-
-	var output strings.Builder
-	// 無効な入力の後に有効な入力
-	input := strings.NewReader("abc\n0\n3\n1\n")
-
-	selected, err := RunHeadless(titles, 0, input, &output)
-
-	if err != nil {
-		t.Fatalf("expected no error, got %v", err)
-	}
-
-	if selected == nil {
-		t.Fatal("expected selected title, got nil")
-	}
-
-	if selected.Name != "Title1" {
-		t.Errorf("expected Title1, got %s", selected.Name)
-	}
-
-	outputStr := output.String()
-	if !strings.Contains(outputStr, "Invalid input") {
-		t.Error("expected invalid input message")
-	}
-	if !strings.Contains(outputStr, "Invalid selection") {
-		t.Error("expected invalid selection message")
-	}
-}
-
-func TestRunHeadless_Quit(t *testing.T) {
-	titles := []title.FillyTitle{
-		{Name: "Title1", Path: "/path/1", IsEmbedded: false},
-		{Name: "Title2", Path: "/path/2", IsEmbedded: false},
-	}
-
-	var output strings.Builder
-	input := strings.NewReader("q\n")
-
-	selected, err := RunHeadless(titles, 0, input, &output)
-
-	if err == nil {
-		t.Fatal("expected error, got nil")
-	}
-
-	if selected != nil {
-		t.Errorf("expected nil, got %v", selected)
-	}
-
-	if !strings.Contains(err.Error(), "cancelled") {
-		t.Errorf("expected 'cancelled' error, got %v", err)
-	}
-}
-
-func TestRunHeadless_Timeout(t *testing.T) {
-	titles := []title.FillyTitle{
-		{Name: "Title1", Path: "/path/1", IsEmbedded: false},
-		{Name: "Title2", Path: "/path/2", IsEmbedded: false},
-	}
-
-	var output strings.Builder
-	// 入力を遅延させるために、入力なしで実行
-	input := strings.NewReader("")
-
-	// 非常に短いタイムアウトを設定
-	selected, err := RunHeadless(titles, 10*time.Millisecond, input, &output)
-
-	if err == nil {
-		t.Fatal("expected timeout error, got nil")
-	}
-
-	if selected != nil {
-		t.Errorf("expected nil, got %v", selected)
-	}
-
-	if !strings.Contains(err.Error(), "timeout") && !strings.Contains(err.Error(), "input closed") {
-		t.Errorf("expected 'timeout' or 'input closed' error, got %v", err)
-	}
-}
-
 // TestSetHasTitleSelection tests the SetHasTitleSelection method
 // Requirements: 2.1, 3.1, 5.1
 func TestSetHasTitleSelection(t *testing.T) {
@@ -676,3 +533,271 @@ func TestReturnToSelection_NilCallback_NoPanic(t *testing.T) {
 	}
 }
 
+// mockFocusPauser はPause/Resumeの呼び出し回数を記録する
+type mockFocusPauser struct {
+	pauseCount  int
+	resumeCount int
+}
+
+func (m *mockFocusPauser) Pause()  { m.pauseCount++ }
+func (m *mockFocusPauser) Resume() { m.resumeCount++ }
+
+func TestUpdateFocusPause(t *testing.T) {
+	game := NewGame(ModeDesktop, nil, 0)
+	pauser := &mockFocusPauser{}
+	game.SetFocusPauser(pauser)
+
+	focused := true
+	game.isFocused = func() bool { return focused }
+
+	// フォーカスがある間は何もしない
+	if game.updateFocusPause() {
+		t.Error("should not be paused while focused")
+	}
+	if pauser.pauseCount != 0 || pauser.resumeCount != 0 {
+		t.Errorf("unexpected calls: pause=%d resume=%d", pauser.pauseCount, pauser.resumeCount)
+	}
+
+	// フォーカス喪失で一時停止（連続したフレームでも1回だけ）
+	focused = false
+	for i := 0; i < 3; i++ {
+		if !game.updateFocusPause() {
+			t.Error("should be paused while unfocused")
+		}
+	}
+	if pauser.pauseCount != 1 {
+		t.Errorf("expected 1 Pause call, got %d", pauser.pauseCount)
+	}
+
+	// フォーカス復帰で再開
+	focused = true
+	if game.updateFocusPause() {
+		t.Error("should resume when focus returns")
+	}
+	if pauser.resumeCount != 1 {
+		t.Errorf("expected 1 Resume call, got %d", pauser.resumeCount)
+	}
+}
+
+func TestUpdateFocusPause_Disabled(t *testing.T) {
+	game := NewGame(ModeDesktop, nil, 0)
+	game.isFocused = func() bool { return false }
+
+	// FocusPauserが未設定の場合は一時停止しない
+	if game.updateFocusPause() {
+		t.Error("should not pause when no FocusPauser is set")
+	}
+}
+
+func TestUpdateDesktop_PausedSkipsInput(t *testing.T) {
+	game := NewGame(ModeDesktop, nil, 0)
+	pauser := &mockFocusPauser{}
+	game.SetFocusPauser(pauser)
+	game.isFocused = func() bool { return false }
+
+	if err := game.updateDesktop(); err != nil {
+		t.Errorf("expected nil, got %v", err)
+	}
+	if pauser.pauseCount != 1 {
+		t.Errorf("expected VM to be paused, got %d Pause calls", pauser.pauseCount)
+	}
+}
+
+func TestKeyPressState(t *testing.T) {
+	keyRepeatDelay := ticksFor(keyRepeatDelay, DefaultTPS)
+	keyRepeatInterval := ticksFor(keyRepeatInterval, DefaultTPS)
+	tests := []struct {
+		duration int
+		fire     bool
+		repeat   bool
+	}{
+		{0, false, false},
+		{1, true, false},
+		{2, false, false},
+		{keyRepeatDelay, false, false},
+		{keyRepeatDelay + 1, false, false},
+		{keyRepeatDelay + keyRepeatInterval, true, true},
+		{keyRepeatDelay + keyRepeatInterval + 1, false, false},
+		{keyRepeatDelay + 2*keyRepeatInterval, true, true},
+	}
+
+	for _, tt := range tests {
+		fire, repeat := keyPressState(tt.duration, DefaultTPS)
+		if fire != tt.fire || repeat != tt.repeat {
+			t.Errorf("keyPressState(%d) = (%v, %v), want (%v, %v)", tt.duration, fire, repeat, tt.fire, tt.repeat)
+		}
+	}
+}
+
+// TestKeyPressState_TPS tests that key repeat keeps the same real-time timing at another TPS.
+func TestKeyPressState_TPS(t *testing.T) {
+	const tps = 30
+	// 30TPSでは0.5秒 = 15ティック、0.05秒は最低1ティック（約0.033秒）となる
+	if fire, _ := keyPressState(15, tps); fire {
+		t.Error("repeat should not start before the delay")
+	}
+	if fire, repeat := keyPressState(16, tps); !fire || !repeat {
+		t.Error("repeat should start one interval after the delay")
+	}
+}
+
+func TestResolveFrameRate(t *testing.T) {
+	tests := []struct {
+		name                      string
+		scriptFPS, cliTPS, cliFPS int
+		wantTPS, wantFPS          int
+	}{
+		{"defaults", 0, 0, 0, DefaultTPS, DefaultFPS},
+		{"script fps", 24, 0, 0, DefaultTPS, 24},
+		{"cli caps script fps", 30, 0, 20, DefaultTPS, 20},
+		{"cli does not raise script fps", 24, 0, 60, DefaultTPS, 24},
+		{"cli tps", 0, 120, 0, 120, DefaultFPS},
+		{"clamped to max", MaxFrameRate + 100, MaxFrameRate + 1, 0, MaxFrameRate, MaxFrameRate},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tps, fps := ResolveFrameRate(tt.scriptFPS, tt.cliTPS, tt.cliFPS)
+			if tps != tt.wantTPS || fps != tt.wantFPS {
+				t.Errorf("ResolveFrameRate = (%d, %d), want (%d, %d)", tps, fps, tt.wantTPS, tt.wantFPS)
+			}
+		})
+	}
+}
+
+func TestShouldDraw(t *testing.T) {
+	game := NewGame(ModeDesktop, nil, 0)
+	game.fps = 30
+
+	start := time.Unix(0, 0)
+	frame := time.Second / 60
+	drawn := 0
+	for i := 0; i < 60; i++ {
+		if game.shouldDraw(start.Add(time.Duration(i) * frame)) {
+			drawn++
+		}
+	}
+	if drawn != 30 {
+		t.Errorf("drew %d of 60 frames at 30 FPS, want 30", drawn)
+	}
+
+	// FPSが未設定の場合は毎フレーム描画する
+	game.fps = 0
+	if !game.shouldDraw(start) || !game.shouldDraw(start) {
+		t.Error("expected every frame to be drawn without an FPS cap")
+	}
+}
+
+// mockRedrawGraphics は変更の有無を報告するグラフィックスシステム
+type mockRedrawGraphics struct {
+	dirty bool
+}
+
+func (m *mockRedrawGraphics) Update() error             { return nil }
+func (m *mockRedrawGraphics) Draw(screen *ebiten.Image) { m.dirty = false }
+func (m *mockRedrawGraphics) Shutdown()                 {}
+func (m *mockRedrawGraphics) GetVirtualWidth() int      { return 1024 }
+func (m *mockRedrawGraphics) GetVirtualHeight() int     { return 768 }
+func (m *mockRedrawGraphics) NeedsRedraw() bool         { return m.dirty }
+
+func TestNeedsRedraw(t *testing.T) {
+	game := NewGame(ModeDesktop, nil, 0)
+	gs := &mockRedrawGraphics{}
+	game.SetGraphicsSystem(gs)
+	game.fps = 60
+
+	// グラフィックスシステムを設定した直後は必ず描画する
+	if !game.needsRedraw() {
+		t.Error("expected the first frame to be drawn")
+	}
+	if game.needsRedraw() {
+		t.Error("expected an unchanged scene to be skipped")
+	}
+	gs.dirty = true
+	if !game.needsRedraw() {
+		t.Error("expected a changed scene to be drawn")
+	}
+
+	// 前回の画面が残らない場合（FPS未設定）は毎フレーム描画する
+	gs.dirty = false
+	game.fps = 0
+	if !game.needsRedraw() {
+		t.Error("expected every frame to be drawn without an FPS cap")
+	}
+}
+
+func TestKeyInputs_NoEscape(t *testing.T) {
+	// Escキーはタイトル終了に予約されているため、KEYイベントとして送らない
+	seen := make(map[int]bool)
+	for _, k := range keyInputs {
+		if k.key == ebiten.KeyEscape {
+			t.Error("Escape must not be reported as a KEY event")
+		}
+		if seen[k.code] {
+			t.Errorf("duplicate key code 0x%X", k.code)
+		}
+		seen[k.code] = true
+	}
+}
+
+// mockTimeScaler は設定された時間スケールを 0.25〜4 に制限して保持する
+type mockTimeScaler struct {
+	scale float64
+}
+
+func (m *mockTimeScaler) SetTimeScale(scale float64) float64 {
+	m.scale = max(0.25, min(4, scale))
+	return m.scale
+}
+
+func (m *mockTimeScaler) TimeScale() float64 { return m.scale }
+
+func TestNextTimeScale(t *testing.T) {
+	tests := []struct {
+		current float64
+		faster  bool
+		want    float64
+	}{
+		{1, true, 2},
+		{2, true, 4},
+		{4, true, 4},
+		{1, false, 0.5},
+		{0.5, false, 0.25},
+		{0.25, false, 0.25},
+		{1.5, true, 2},
+		{1.5, false, 1},
+	}
+	for _, tt := range tests {
+		if got := nextTimeScale(tt.current, tt.faster); got != tt.want {
+			t.Errorf("nextTimeScale(%v, %v) = %v, want %v", tt.current, tt.faster, got, tt.want)
+		}
+	}
+}
+
+func TestApplyTimeScale(t *testing.T) {
+	game := NewGame(ModeDesktop, nil, 0)
+	// 設定されていない場合は何もしない
+	game.applyTimeScale(2)
+	if game.currentTimeScale() != 0 {
+		t.Errorf("expected no time scale without a scaler, got %v", game.currentTimeScale())
+	}
+
+	scaler := &mockTimeScaler{scale: 1}
+	game.SetTimeScaler(scaler)
+	game.applyTimeScale(nextTimeScale(game.currentTimeScale(), true))
+	if scaler.scale != 2 || game.currentTimeScale() != 2 {
+		t.Errorf("expected scale 2, got scaler=%v game=%v", scaler.scale, game.currentTimeScale())
+	}
+	// 表示の切り替えのため描き直す
+	game.fps = 60
+	if !game.needsRedraw() {
+		t.Error("expected a redraw after changing the time scale")
+	}
+}
+
+func TestFormatTimeScale(t *testing.T) {
+	for scale, want := range map[float64]string{0.25: "x0.25", 0.5: "x0.5", 2: "x2", 4: "x4"} {
+		if got := formatTimeScale(scale); got != want {
+			t.Errorf("formatTimeScale(%v) = %q, want %q", scale, got, want)
+		}
+	}
+}